@@ -0,0 +1,410 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/api"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/fieldcrypt"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// newUserCommand builds basin user
+func newUserCommand(app *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "user",
+		Short: "Manage users",
+	}
+
+	var (
+		email, password, firstName, lastName, tenantSlug string
+		admin, superAdmin                                bool
+	)
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a user, optionally as a tenant admin",
+		Long: "Create a user. Without --password the password is read from stdin. With --tenant the\n" +
+			"user joins that tenant as a viewer, or as an admin with --admin.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if admin && tenantSlug == "" {
+				return fmt.Errorf("--admin needs --tenant")
+			}
+			if password == "" {
+				var err error
+				if password, err = readPassword(cmd.InOrStdin(), cmd.ErrOrStderr()); err != nil {
+					return err
+				}
+			}
+			if err := models.NewPasswordPolicy(app.cfg).Validate(password); err != nil {
+				return err
+			}
+			passwordHash, err := models.HashPassword(password)
+			if err != nil {
+				return fmt.Errorf("failed to hash password: %w", err)
+			}
+
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			ctx := cmd.Context()
+
+			var tenant sqlc.Tenant
+			var role sqlc.Role
+			if tenantSlug != "" {
+				if tenant, err = database.Queries.GetTenantBySlug(ctx, tenantSlug); err != nil {
+					return fmt.Errorf("tenant %q not found", tenantSlug)
+				}
+				roleName := "viewer"
+				if admin {
+					roleName = "admin"
+				}
+				role, err = database.Queries.GetRoleByNameAndTenant(ctx, sqlc.GetRoleByNameAndTenantParams{
+					Name:     roleName,
+					TenantID: uuid.NullUUID{UUID: tenant.ID, Valid: true},
+				})
+				if err != nil {
+					return fmt.Errorf("tenant %q has no %s role", tenantSlug, roleName)
+				}
+			}
+
+			tx, err := database.DB.BeginTx(ctx, nil)
+			if err != nil {
+				return err
+			}
+			defer tx.Rollback()
+			q := database.Queries.WithTx(tx)
+
+			user, err := q.CreateUser(ctx, sqlc.CreateUserParams{
+				ID:           uuid.New(),
+				Email:        email,
+				PasswordHash: passwordHash,
+				FirstName:    sql.NullString{String: firstName, Valid: true},
+				LastName:     sql.NullString{String: lastName, Valid: true},
+				TenantID:     uuid.NullUUID{UUID: tenant.ID, Valid: tenantSlug != ""},
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create user: %w", err)
+			}
+
+			if superAdmin {
+				if err := q.SetUserSuperAdmin(ctx, sqlc.SetUserSuperAdminParams{ID: user.ID, IsSuperAdmin: true}); err != nil {
+					return fmt.Errorf("failed to make user a super admin: %w", err)
+				}
+			}
+
+			if tenantSlug != "" {
+				if err := q.AddUserToTenant(ctx, sqlc.AddUserToTenantParams{
+					UserID:   user.ID,
+					TenantID: tenant.ID,
+					RoleID:   uuid.NullUUID{UUID: role.ID, Valid: true},
+				}); err != nil {
+					return fmt.Errorf("failed to add user to tenant: %w", err)
+				}
+				if err := q.AddUserRole(ctx, sqlc.AddUserRoleParams{UserID: user.ID, RoleID: role.ID}); err != nil {
+					return fmt.Errorf("failed to assign role: %w", err)
+				}
+			}
+
+			if err := tx.Commit(); err != nil {
+				return err
+			}
+			rbac.InvalidateUserPermissions(user.ID)
+
+			return printJSON(cmd.OutOrStdout(), map[string]interface{}{
+				"id":             user.ID,
+				"email":          user.Email,
+				"tenant_id":      user.TenantID.UUID,
+				"role":           role.Name,
+				"is_super_admin": superAdmin,
+			})
+		},
+	}
+	create.Flags().StringVar(&email, "email", "", "email address (required)")
+	create.Flags().StringVar(&password, "password", "", "password; read from stdin when omitted")
+	create.Flags().StringVar(&firstName, "first-name", "", "first name")
+	create.Flags().StringVar(&lastName, "last-name", "", "last name")
+	create.Flags().StringVar(&tenantSlug, "tenant", "", "slug of the tenant to join")
+	create.Flags().BoolVar(&admin, "admin", false, "make the user an admin of --tenant")
+	create.Flags().BoolVar(&superAdmin, "super-admin", false, "let the user see and manage every tenant")
+	create.MarkFlagRequired("email")
+
+	var revoke bool
+	grant := &cobra.Command{
+		Use:   "super-admin EMAIL",
+		Short: "Let a user see and manage every tenant",
+		Long: "Make an existing user a super admin, or take it away with --revoke. Super admins see\n" +
+			"every tenant and can suspend, resume and restore tenants.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			ctx := cmd.Context()
+
+			user, err := database.Queries.GetUserByEmail(ctx, args[0])
+			if err != nil {
+				return fmt.Errorf("user %q not found", args[0])
+			}
+			if err := database.Queries.SetUserSuperAdmin(ctx, sqlc.SetUserSuperAdminParams{
+				ID:           user.ID,
+				IsSuperAdmin: !revoke,
+			}); err != nil {
+				return err
+			}
+
+			return printJSON(cmd.OutOrStdout(), map[string]interface{}{
+				"id":             user.ID,
+				"email":          user.Email,
+				"is_super_admin": !revoke,
+			})
+		},
+	}
+	grant.Flags().BoolVar(&revoke, "revoke", false, "take super admin away instead")
+
+	cmd.AddCommand(create, grant)
+	return cmd
+}
+
+// newTenantCommand builds basin tenant
+func newTenantCommand(app *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tenant",
+		Short: "Manage tenants",
+	}
+
+	var req models.CreateTenantRequest
+	var ownerEmail string
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create a tenant with the default roles, permissions and collections",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			ctx := cmd.Context()
+
+			owner, err := database.Queries.GetUserByEmail(ctx, ownerEmail)
+			if err != nil {
+				return fmt.Errorf("owner %q not found", ownerEmail)
+			}
+			if _, err := database.Queries.GetTenantBySlug(ctx, req.Slug); err == nil {
+				return fmt.Errorf("tenant with slug %q already exists", req.Slug)
+			}
+
+			tenant, err := api.NewTenantHandler(database, app.cfg, nil, nil).Provision(ctx, req, owner.ID)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(cmd.OutOrStdout(), map[string]interface{}{
+				"id":    tenant.ID,
+				"name":  tenant.Name,
+				"slug":  tenant.Slug,
+				"owner": owner.Email,
+			})
+		},
+	}
+	create.Flags().StringVar(&req.Name, "name", "", "display name (required)")
+	create.Flags().StringVar(&req.Slug, "slug", "", "URL-friendly identifier (required)")
+	create.Flags().StringVar(&req.Domain, "domain", "", "custom domain")
+	create.Flags().StringVar(&req.Template, "template", "", "collection template: commerce (default), blog, crm, inventory or none")
+	create.Flags().StringVar(&ownerEmail, "owner", "", "email of the existing user who becomes the tenant's admin (required)")
+	create.MarkFlagRequired("name")
+	create.MarkFlagRequired("slug")
+	create.MarkFlagRequired("owner")
+
+	// Suspended and deleted tenants lock out their own admins, so the CLI can bring them back
+	lifecycle := func(use, short string, change func(q *sqlc.Queries) func(ctx context.Context, id uuid.UUID) (sqlc.Tenant, error)) *cobra.Command {
+		return &cobra.Command{
+			Use:   use + " SLUG",
+			Short: short,
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				database, err := app.openDB()
+				if err != nil {
+					return err
+				}
+				defer database.Close()
+				ctx := cmd.Context()
+
+				tenant, err := database.Queries.GetTenantBySlug(ctx, args[0])
+				if err != nil {
+					return fmt.Errorf("tenant %q not found", args[0])
+				}
+				tenant, err = change(database.Queries)(ctx, tenant.ID)
+				if errors.Is(err, sql.ErrNoRows) {
+					return fmt.Errorf("tenant %q is not in a state to %s", args[0], use)
+				}
+				if err != nil {
+					return err
+				}
+
+				return printJSON(cmd.OutOrStdout(), map[string]interface{}{
+					"id":        tenant.ID,
+					"slug":      tenant.Slug,
+					"is_active": tenant.IsActive.Bool,
+				})
+			},
+		}
+	}
+
+	var options api.TenantArchiveOptions
+	var output string
+	export := &cobra.Command{
+		Use:   "export SLUG",
+		Short: "Write a tenant's schema and items to a zip archive",
+		Long: "Write a tenant's schema and items to a zip archive, as GET /tenants/:id/export does.\n" +
+			"With --anonymize the values of PII-tagged fields are replaced by fake ones, so the\n" +
+			"archive can be imported into staging with POST /tenants/import.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+			ctx := cmd.Context()
+
+			// Encrypted PII values are decrypted before they are replaced
+			keys, err := fieldcrypt.Load(app.cfg.FieldEncryptionKeys, app.cfg.FieldEncryptionKeyFile)
+			if err != nil {
+				return fmt.Errorf("failed to load field encryption keys: %w", err)
+			}
+			api.UseFieldEncryption(keys)
+
+			tenant, err := database.Queries.GetTenantBySlug(ctx, args[0])
+			if err != nil {
+				return fmt.Errorf("tenant %q not found", args[0])
+			}
+			items := api.NewItemsHandler(database, app.cfg, events.NewBus())
+			tenants := api.NewTenantHandler(database, app.cfg, nil, items)
+
+			if output == "" {
+				_, err := tenants.ExportArchive(ctx, cmd.OutOrStdout(), tenant, options)
+				return err
+			}
+			file, err := os.Create(output)
+			if err != nil {
+				return err
+			}
+			if _, err := tenants.ExportArchive(ctx, file, tenant, options); err != nil {
+				file.Close()
+				os.Remove(output)
+				return err
+			}
+			return file.Close()
+		},
+	}
+	export.Flags().StringVar(&options.Format, "format", "jsonl", "format of the data files: jsonl or csv")
+	export.Flags().BoolVar(&options.Anonymize, "anonymize", false, "replace the values of PII-tagged fields with fake ones")
+	export.Flags().StringVarP(&output, "output", "o", "", "file to write instead of stdout")
+
+	cmd.AddCommand(
+		create,
+		export,
+		lifecycle("resume", "Lift the suspension of a tenant", func(q *sqlc.Queries) func(context.Context, uuid.UUID) (sqlc.Tenant, error) {
+			return q.ResumeTenant
+		}),
+		lifecycle("restore", "Restore a deleted tenant that has not been purged yet", func(q *sqlc.Queries) func(context.Context, uuid.UUID) (sqlc.Tenant, error) {
+			return q.RestoreTenant
+		}),
+	)
+	return cmd
+}
+
+// newAPIKeyCommand builds basin apikey
+func newAPIKeyCommand(app *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "apikey",
+		Short: "Manage API keys",
+	}
+
+	var (
+		userEmail, name string
+		scopes          []string
+		expiresIn       time.Duration
+	)
+	create := &cobra.Command{
+		Use:   "create",
+		Short: "Create an API key for a user and print its secret",
+		Long:  "Create an API key for a user. The secret is printed once and cannot be recovered.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if expiresIn <= 0 {
+				return fmt.Errorf("--expires-in must be positive")
+			}
+
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+
+			data := map[string]interface{}{
+				"name":       name,
+				"expires_at": time.Now().Add(expiresIn).Format(time.RFC3339),
+			}
+			if len(scopes) > 0 {
+				data["scopes"] = scopes
+			}
+
+			schema, userID, err := app.schemaHandlers(cmd, database, userEmail)
+			if err != nil {
+				return err
+			}
+			key, err := schema.CreateAPIKey(cmd.Context(), userID, data)
+			if err != nil {
+				return err
+			}
+
+			return printJSON(cmd.OutOrStdout(), key)
+		},
+	}
+	create.Flags().StringVar(&userEmail, "user", "", "email of the key's owner (required)")
+	create.Flags().StringVar(&name, "name", "API Key", "name of the key")
+	create.Flags().StringSliceVar(&scopes, "scopes", nil, "comma separated table:action scopes; none means unrestricted")
+	create.Flags().DurationVar(&expiresIn, "expires-in", 365*24*time.Hour, "lifetime of the key")
+	create.MarkFlagRequired("user")
+
+	cmd.AddCommand(create)
+	return cmd
+}
+
+// readPassword reads one line from in, prompting on out
+func readPassword(in io.Reader, out io.Writer) (string, error) {
+	fmt.Fprint(out, "Password: ")
+	line, err := bufio.NewReader(in).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("failed to read password: %w", err)
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// printJSON writes v as indented JSON
+func printJSON(out io.Writer, v interface{}) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
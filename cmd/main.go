@@ -3,18 +3,33 @@ package main
 import (
 	"context"
 	"fmt"
-	"log"
+	"log/slog"
 	"net/http"
 	"os"
 	"os/signal"
-	"path/filepath"
 	"syscall"
 	"time"
 
+	"go-rbac-api/internal/admin"
 	"go-rbac-api/internal/api"
+	"go-rbac-api/internal/apikeys"
+	"go-rbac-api/internal/cachebus"
 	"go-rbac-api/internal/config"
 	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/fieldcrypt"
+	"go-rbac-api/internal/flows"
+	"go-rbac-api/internal/idempotency"
+	"go-rbac-api/internal/jobs"
+	"go-rbac-api/internal/mail"
 	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/migrate"
+	"go-rbac-api/internal/quota"
+	"go-rbac-api/internal/rbac"
+	"go-rbac-api/internal/seed"
+	"go-rbac-api/internal/storage"
+	"go-rbac-api/internal/tenants"
+	"go-rbac-api/internal/webhooks"
 
 	_ "go-rbac-api/docs"
 
@@ -36,104 +51,242 @@ import (
 // @name        Authorization
 // @description  API key for programmatic access (format: Bearer YOUR_API_KEY)
 func main() {
-	log.Println("🚀 === APP STARTING ===")
-	log.Println("Step 1: Loading configuration...")
-
-	// Load configuration
-	cfg, err := config.Load()
-	if err != nil {
-		log.Fatalf("Failed to load config: %v", err)
+	if err := newRootCommand().Execute(); err != nil {
+		fmt.Fprintln(os.Stderr, "error:", err)
+		os.Exit(1)
 	}
+}
 
-	log.Println("✅ Step 1 COMPLETE: Configuration loaded")
-	log.Println("Step 2: Setting Gin mode...")
+// serve runs the API server until it receives SIGINT or SIGTERM
+func serve(cfg *config.Config, logger *slog.Logger) {
+	logger.Info("starting", "deployment_mode", cfg.DeploymentMode, "server_mode", cfg.ServerMode)
 
 	// Set Gin mode
 	gin.SetMode(cfg.ServerMode)
 
-	log.Println("✅ Step 2 COMPLETE: Gin mode set")
-	log.Println("Step 3: Connecting to database...")
-
 	// Initialize database
 	database, err := db.NewDB(cfg)
 	if err != nil {
-		log.Fatalf("Failed to connect to database: %v", err)
+		logger.Error("failed to connect to database", "error", err)
+		os.Exit(1)
 	}
 	defer database.Close()
+	logger.Info("database connected", "driver", database.Dialect().Name(), "host", cfg.DBHost, "database", cfg.DBName)
+
+	// Apply pending migrations; the server does not start on a partially migrated schema
+	if cfg.MigrateOnStartup {
+		if err := runMigrations(database, cfg); err != nil {
+			logger.Error("migrations failed", "error", err)
+			os.Exit(1)
+		}
+	}
+
+	// Seed the database with the fixtures of the configured environment
+	if cfg.SeedOnStartup {
+		if err := runSeeds(database, cfg); err != nil {
+			logger.Warn("database seeding failed; continuing startup (seeding can be run manually later)", "error", err)
+		}
+	}
+
+	// Spatial filters on geo fields are answered by PostGIS when the extension is installed
+	rbac.UsePostGIS(api.DetectPostGIS(context.Background(), database))
+	logger.Info("geo fields", "postgis", rbac.PostGIS())
+
+	// Encrypted fields are sealed with FIELD_ENCRYPTION_KEYS (and FIELD_ENCRYPTION_KEY_FILE)
+	fieldKeys, err := fieldcrypt.Load(cfg.FieldEncryptionKeys, cfg.FieldEncryptionKeyFile)
+	if err != nil {
+		logger.Error("failed to load field encryption keys", "error", err)
+		os.Exit(1)
+	}
+	api.UseFieldEncryption(fieldKeys)
+	logger.Info("encrypted fields", "enabled", fieldKeys != nil)
+
+	// Access tokens are signed with JWT_ALGORITHM; asymmetric public keys are published at
+	// /.well-known/jwks.json
+	signingKeys, err := middleware.LoadSigningKeys(cfg)
+	if err != nil {
+		logger.Error("failed to load JWT signing keys", "error", err)
+		os.Exit(1)
+	}
+	middleware.UseSigningKeys(signingKeys)
+	logger.Info("access tokens signed", "algorithm", signingKeys.Algorithm())
 
-	log.Println("✅ Step 3 COMPLETE: Database connected")
-	log.Println("Step 4: Starting migrations...")
+	// Background workers stop when the server shuts down
+	workerCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
 
-	// Run database migrations automatically
-	log.Println("=== STARTING MIGRATIONS ===")
-	log.Printf("Current working directory: %s", getCurrentDir())
-	log.Printf("Migration directory: %s", filepath.Join(getCurrentDir(), "migrations"))
+	// Item events fan out to webhooks and realtime subscribers
+	eventBus := events.NewBus()
 
-	// List files in migrations directory
-	if err := listMigrationFiles(); err != nil {
-		log.Printf("Warning: Could not list migration files: %v", err)
+	// Cache invalidations are shared with the other instances
+	cacheBus, err := cachebus.Open(cfg, database)
+	if err != nil {
+		logger.Error("failed to open the cache bus", "error", err)
+		os.Exit(1)
+	}
+	defer cacheBus.Close()
+
+	// Permission checks are cached until roles or permissions change (or the TTL passes)
+	if cfg.PermissionCacheEnabled && cfg.PermissionCacheTTL > 0 {
+		permissionCache := rbac.NewPermissionCache(cfg.PermissionCacheTTL)
+		permissionCache.Broadcast(cacheBus.Publisher(cachebus.Permissions))
+		cacheBus.Handle(cachebus.Permissions, permissionCache.HandleInvalidation)
+		rbac.UseCache(permissionCache)
+		eventBus.Subscribe(permissionCache.HandleEvent)
 	}
 
-	log.Println("Running database migrations...")
-	if err := runMigrations(database); err != nil {
-		log.Printf("WARNING: Migrations failed: %v", err)
-		log.Println("Continuing with startup... (migrations can be run manually later)")
-	} else {
-		log.Println("Database migrations completed successfully")
+	// Collections, tenant schemas and data tables are cached until the database announces
+	// a change to them (or the TTL passes)
+	if cfg.SchemaCacheEnabled && cfg.SchemaCacheTTL > 0 {
+		schemaCache := api.NewSchemaCache(cfg.SchemaCacheTTL)
+		schemaCache.Broadcast(cacheBus.Publisher(cachebus.Schema))
+		cacheBus.Handle(cachebus.Schema, schemaCache.HandleInvalidation)
+		api.UseSchemaCache(schemaCache)
+		eventBus.Subscribe(schemaCache.HandleEvent)
+		if err := schemaCache.Warm(workerCtx, database.Queries); err != nil {
+			logger.Warn("failed to warm the schema cache", "error", err)
+		}
+		go func() {
+			if err := schemaCache.Listen(workerCtx, database); err != nil {
+				logger.Error("schema change notifications unavailable; cached schema expires after SCHEMA_CACHE_TTL", "error", err)
+			}
+		}()
 	}
-	log.Println("=== MIGRATIONS COMPLETE ===")
 
-	log.Println("✅ Step 4 COMPLETE: Migrations finished")
-	log.Println("Step 5: Seeding database...")
+	// List responses of collections are cached until one of their items is written
+	if cfg.ItemsCacheEnabled && cfg.ItemsCacheTTL > 0 {
+		responseCache := api.NewResponseCache(cfg.ItemsCacheTTL)
+		responseCache.Broadcast(cacheBus.Publisher(cachebus.Items))
+		cacheBus.Handle(cachebus.Items, responseCache.HandleInvalidation)
+		api.UseResponseCache(responseCache)
+		eventBus.Subscribe(responseCache.HandleEvent)
+	}
 
-	// Seed the database with initial data
-	if err := seedDatabase(database); err != nil {
-		log.Printf("WARNING: Database seeding failed: %v", err)
-		log.Println("Continuing with startup... (seeding can be run manually later)")
-	} else {
-		log.Println("Database seeding completed successfully")
+	// Apply the invalidations of other instances
+	if cacheBus != nil {
+		go func() {
+			if err := cacheBus.Run(workerCtx); err != nil {
+				logger.Error("cache bus unavailable; changes made on other instances take effect after the cache TTLs", "bus", cfg.CacheBus, "error", err)
+			}
+		}()
+	}
+	webhookDispatcher := webhooks.NewDispatcher(database, cfg)
+	eventBus.Subscribe(webhookDispatcher.HandleEvent)
+	go webhookDispatcher.Start(workerCtx)
+
+	realtimeHandler := api.NewRealtimeHandler(database)
+	eventBus.Subscribe(realtimeHandler.HandleEvent)
+
+	// API keys close to expiry are announced as api_key.expiring events, and service
+	// account keys past the rotation period as api_key.rotation_due
+	go apikeys.NewNotifier(database, cfg, eventBus).Start(workerCtx)
+
+	// Deleted tenants are removed once their restore window has passed
+	go tenants.NewPurger(database).Start(workerCtx)
+
+	// Scheduled items of workflow collections are published and archived when due
+	go api.NewWorkflowScheduler(database, eventBus).Start(workerCtx)
+
+	// Data tables get RLS policies generated from permissions, updated as they change
+	if cfg.RLSPoliciesEnabled && !database.Dialect().RowLevelSecurity() {
+		logger.Warn("RLS_POLICIES_ENABLED ignored; the database has no row-level security", "driver", database.Dialect().Name())
+	} else if cfg.RLSPoliciesEnabled {
+		rlsPolicies := api.NewRLSPolicyGenerator(database)
+		rbac.OnPermissionsChanged(rlsPolicies.Queue)
+		eventBus.Subscribe(rlsPolicies.HandleEvent)
+		go rlsPolicies.Start(workerCtx)
 	}
 
-	log.Println("✅ Step 5 COMPLETE: Database seeded")
-	log.Println("Step 6: Initializing handlers...")
+	// Uploaded files go to local disk or S3
+	assetStorage, err := storage.New(cfg)
+	if err != nil {
+		logger.Error("failed to initialize asset storage", "error", err)
+		os.Exit(1)
+	}
+
+	// Async work such as email delivery runs as background jobs, retried on failure
+	jobQueue := jobs.NewQueue(database, cfg)
+
+	// Emails such as password reset links go out through MAIL_DRIVER, from the sender
+	// configured by each tenant. They are queued as jobs and delivered in the background.
+	mailSender, err := mail.New(cfg, logger)
+	if err != nil {
+		logger.Error("failed to initialize mail", "error", err)
+		os.Exit(1)
+	}
+	mailer := mail.NewMailer(mail.NewQueuedSender(mailSender, jobQueue), mail.Address{Name: cfg.MailFromName, Email: cfg.MailFrom}, database.Queries)
 
 	// Initialize handlers
-	authHandler := api.NewAuthHandler(database, cfg)
-	itemsHandler := api.NewItemsHandler(database)
-	tenantHandler := api.NewTenantHandler(database, cfg)
+	authHandler := api.NewAuthHandler(database, cfg, mailer)
+	itemsHandler := api.NewItemsHandler(database, cfg, eventBus)
+	tenantHandler := api.NewTenantHandler(database, cfg, mailer, itemsHandler)
+	assetsHandler := api.NewAssetsHandler(database, cfg, assetStorage, eventBus)
+	oauthHandler, err := api.NewOAuthHandler(database, cfg, mailer)
+	if err != nil {
+		logger.Error("failed to initialize identity providers", "error", err)
+		os.Exit(1)
+	}
 
-	log.Println("✅ Step 6 COMPLETE: Handlers initialized")
-	log.Println("Step 7: Setting up router...")
+	// Access tokens of a trusted external identity provider are accepted next to Basin's
+	externalTokens, err := api.NewExternalTokenAuth(database, cfg, mailer)
+	if err != nil {
+		logger.Error("failed to initialize the external token issuer", "error", err)
+		os.Exit(1)
+	}
+	if externalTokens != nil {
+		middleware.UseExternalTokens(externalTokens)
+		logger.Info("accepting external tokens", "issuer", cfg.ExternalJWTIssuer)
+	}
 
-	// Setup router
-	router := gin.Default()
+	// Tenant flows run on item events, on a schedule or when their trigger URL is called
+	flowRunner := flows.NewRunner(database, cfg, itemsHandler, mailer)
+	eventBus.Subscribe(flowRunner.HandleEvent)
+	go flowRunner.Start(workerCtx)
+	flowsHandler := api.NewFlowsHandler(database, flowRunner)
+	healthHandler := api.NewHealthHandler(database, cfg)
 
-	// Add CORS middleware
-	router.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Origin, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
+	// Large item imports run as jobs; the worker starts once every job kind is registered
+	importsHandler := api.NewImportsHandler(itemsHandler, cfg, jobQueue)
+	exportsHandler := api.NewExportsHandler(itemsHandler, cfg, jobQueue, assetStorage)
 
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(http.StatusNoContent)
-			return
-		}
+	// Tenants are backed up to asset storage on demand and on their schedules
+	backupsHandler := api.NewBackupsHandler(database, tenantHandler, jobQueue, assetStorage)
+	go backupsHandler.Start(workerCtx)
+	go jobQueue.Start(workerCtx)
 
-		c.Next()
-	})
+	// Setup router (request logging replaces gin's default logger)
+	router := gin.New()
+	router.Use(gin.Recovery())
 
-	// Health check endpoint
-	// @Summary      Health Check
-	// @Tags         system
-	// @Produce      json
-	// @Success      200 {object} models.HealthResponse
-	// @Router       /health [get]
-	router.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{
-			"status": "ok",
-			"time":   time.Now().UTC(),
-		})
-	})
+	// Tag every request with an ID (echoed as X-Request-ID and stored in audit logs) and
+	// log it with that ID
+	router.Use(middleware.RequestID(), middleware.RequestLogger(logger))
+
+	// Large JSON and CSV responses are compressed (COMPRESSION_* settings)
+	router.Use(middleware.Compress(cfg))
+
+	// Bound request bodies; bulk writes and schema snapshots may be larger
+	api.UseJSONLimits(cfg.JSONMaxDepth, cfg.JSONStrict)
+	router.Use(middleware.BodyLimit(cfg.MaxBodySize, map[string]int64{
+		"/items/:table/bulk":   cfg.MaxBulkBodySize,
+		"/items/:table/upsert": cfg.MaxBulkBodySize,
+		"/schema/apply":        cfg.MaxBulkBodySize,
+	}))
+
+	// Cross-origin access for browser clients (CORS_* settings)
+	router.Use(middleware.CORS(cfg))
+
+	// The tenant named by the X-Tenant header or the request's host applies even to
+	// public endpoints such as login
+	router.Use(middleware.ResolveTenant(cfg, database))
+
+	// Health checks: a detailed report, and liveness and readiness probes for orchestrators
+	router.GET("/health", healthHandler.Health)
+	router.GET("/health/live", healthHandler.Live)
+	router.GET("/health/ready", healthHandler.Ready)
+
+	// Public keys that verify access tokens, for other services
+	router.GET("/.well-known/jwks.json", authHandler.JWKS)
 
 	// Auth routes
 	auth := router.Group("/auth")
@@ -147,8 +300,40 @@ func main() {
 		protected.Use(middleware.AuthMiddleware(cfg, database))
 		{
 			protected.POST("/switch-tenant", authHandler.SwitchTenant)
+			protected.POST("/change-password", authHandler.ChangePassword)
 			protected.GET("/context", authHandler.GetAuthContext)
 			protected.GET("/tenants", authHandler.GetUserTenants)
+			protected.POST("/logout", authHandler.Logout)
+			protected.GET("/sessions", authHandler.ListSessions)
+			protected.DELETE("/sessions/:id", authHandler.RevokeSession)
+			protected.POST("/impersonate/stop", authHandler.StopImpersonation)
+			protected.POST("/impersonate/:user_id", authHandler.Impersonate)
+		}
+
+		// Password recovery for users who cannot log in
+		password := auth.Group("/password")
+		{
+			password.POST("/request", authHandler.RequestPasswordReset)
+			password.POST("/reset", authHandler.ResetPassword)
+		}
+
+		// Two-factor authentication; verify completes a login, so it needs no token
+		twoFactor := auth.Group("/2fa")
+		{
+			twoFactor.POST("/verify", authHandler.VerifyTwoFactor)
+			twoFactor.GET("", middleware.AuthMiddleware(cfg, database), authHandler.TwoFactorStatus)
+			twoFactor.POST("/enable", middleware.AuthMiddleware(cfg, database), authHandler.EnableTwoFactor)
+			twoFactor.POST("/confirm", middleware.AuthMiddleware(cfg, database), authHandler.ConfirmTwoFactor)
+			twoFactor.POST("/disable", middleware.AuthMiddleware(cfg, database), authHandler.DisableTwoFactor)
+			twoFactor.POST("/recovery-codes", middleware.AuthMiddleware(cfg, database), authHandler.RegenerateRecoveryCodes)
+		}
+
+		// Sign in with external identity providers
+		oauthRoutes := auth.Group("/oauth")
+		{
+			oauthRoutes.GET("", oauthHandler.Providers)
+			oauthRoutes.GET("/:provider", oauthHandler.Login)
+			oauthRoutes.GET("/:provider/callback", oauthHandler.Callback)
 		}
 
 		// User management (protected routes)
@@ -160,15 +345,132 @@ func main() {
 		}
 	}
 
+	// Requests of a tenant count against its requests_per_minute limit
+	rateLimit := middleware.TenantRateLimit(quota.NewChecker(database, quota.Defaults(cfg)))
+
+	// Creates, bulk writes and upserts sent with an Idempotency-Key are answered with the
+	// stored response when retried
+	idempotent := gin.HandlerFunc(func(c *gin.Context) { c.Next() })
+	if cfg.IdempotencyTTL > 0 {
+		idempotencyStore := idempotency.NewStore(database, cfg.IdempotencyTTL)
+		go idempotencyStore.Start(workerCtx)
+		idempotent = middleware.Idempotency(idempotencyStore)
+	}
+
 	// Items routes (protected) - Dynamic table access
 	items := router.Group("/items")
-	items.Use(middleware.AuthMiddleware(cfg, database))
+	if cfg.APIDialect == "directus" {
+		// Responses in Directus envelopes, for Directus SDKs and frontends
+		items.Use(middleware.DirectusDialect())
+	}
+	// Reads without credentials are answered with the permissions of the tenant's public role
+	items.Use(middleware.PublicAuthMiddleware(cfg, database), rateLimit, middleware.AuditTrail(database), middleware.APIKeyScopes())
 	{
-		items.GET("/:table", itemsHandler.GetItems)
-		items.GET("/:table/:id", itemsHandler.GetItem)
-		items.POST("/:table", itemsHandler.CreateItem)
+		items.GET("/:table", middleware.ETag(), itemsHandler.GetItems)
+		items.GET("/:table/:id", middleware.ETag(), itemsHandler.GetItem)
+		items.GET("/:table/slug/:slug", middleware.ETag(), itemsHandler.GetItemBySlug)
+		items.POST("/:table", idempotent, itemsHandler.CreateItem)
 		items.PUT("/:table/:id", itemsHandler.UpdateItem)
+		items.PATCH("/:table/:id", itemsHandler.PatchItem)
 		items.DELETE("/:table/:id", itemsHandler.DeleteItem)
+		items.POST("/:table/:id/restore", itemsHandler.RestoreItem)
+		items.POST("/:table/:id/move", itemsHandler.MoveItem)
+		items.GET("/:table/:id/revisions", itemsHandler.GetItemRevisions)
+		items.POST("/:table/:id/revisions/:revision_id/revert", itemsHandler.RevertItemRevision)
+		items.POST("/:table/:id/rotate", itemsHandler.RotateAPIKey)
+		items.POST("/:table/:id/share", itemsHandler.CreateItemShare)
+		items.GET("/:table/:id/shares", itemsHandler.ListItemShares)
+		items.DELETE("/:table/:id/shares/:share_id", itemsHandler.RevokeItemShare)
+
+		// Bulk operations (single transaction per request)
+		items.POST("/:table/bulk", idempotent, itemsHandler.BulkCreateItems)
+		items.PATCH("/:table/bulk", idempotent, itemsHandler.BulkUpdateItems)
+		items.DELETE("/:table/bulk", idempotent, itemsHandler.BulkDeleteItems)
+
+		// Insert or update keyed by a unique field
+		items.POST("/:table/upsert", idempotent, itemsHandler.UpsertItems)
+
+		// Point-in-time restore from a backup and the revisions recorded since
+		items.POST("/:table/restore", backupsHandler.RestoreItemsAt)
+
+		// CSV and XLSX imports
+		items.POST("/:table/import", importsHandler.ImportItems)
+		items.GET("/:table/import/:id", importsHandler.GetImport)
+
+		// CSV, JSON Lines and XLSX exports
+		items.GET("/:table/export", exportsHandler.ExportItems)
+		items.GET("/:table/export/:id", exportsHandler.GetExport)
+		items.GET("/:table/export/:id/download", exportsHandler.DownloadExport)
+	}
+
+	// Schema snapshots (protected) - move collections, fields, roles and permissions between environments
+	schema := router.Group("/schema")
+	schema.Use(middleware.AuthMiddleware(cfg, database), rateLimit, middleware.AuditTrail(database))
+	{
+		schema.GET("/snapshot", itemsHandler.GetSchemaSnapshot)
+		schema.POST("/apply", itemsHandler.ApplySchemaSnapshot)
+		schema.GET("/sdk", itemsHandler.GetSchemaSDK)
+	}
+
+	// Roles and permissions as a whole (protected)
+	rbacRoutes := router.Group("/rbac")
+	rbacRoutes.Use(middleware.AuthMiddleware(cfg, database), rateLimit, middleware.AuditTrail(database))
+	{
+		rbacRoutes.GET("/matrix", itemsHandler.GetPermissionMatrix)
+		rbacRoutes.PUT("/matrix", itemsHandler.UpdatePermissionMatrix)
+		rbacRoutes.POST("/check", itemsHandler.CheckAccess)
+		rbacRoutes.GET("/rls", itemsHandler.PreviewRLSPolicies)
+	}
+
+	// Collection templates and duplication (protected)
+	collections := router.Group("/collections")
+	collections.Use(middleware.AuthMiddleware(cfg, database), rateLimit, middleware.AuditTrail(database))
+	{
+		collections.GET("/templates", itemsHandler.ListCollectionTemplates)
+		collections.POST("/:id/duplicate", itemsHandler.DuplicateCollection)
+	}
+
+	// Service accounts (protected) - machine users owning the API keys of integrations
+	serviceAccounts := router.Group("/service-accounts")
+	serviceAccounts.Use(middleware.AuthMiddleware(cfg, database), rateLimit, middleware.AuditTrail(database))
+	{
+		serviceAccounts.GET("", itemsHandler.ListServiceAccounts)
+		serviceAccounts.POST("", itemsHandler.CreateServiceAccount)
+		serviceAccounts.GET("/:id", itemsHandler.GetServiceAccount)
+		serviceAccounts.PATCH("/:id", itemsHandler.UpdateServiceAccount)
+		serviceAccounts.DELETE("/:id", itemsHandler.DeleteServiceAccount)
+		serviceAccounts.POST("/:id/keys", itemsHandler.CreateServiceAccountKey)
+	}
+
+	// Privacy erasure (protected, admins only) - erase a person's data across collections
+	privacy := router.Group("/privacy")
+	privacy.Use(middleware.AuthMiddleware(cfg, database), rateLimit)
+	{
+		privacy.POST("/erasure", itemsHandler.EraseSubject)
+		privacy.POST("/erasure/verify", itemsHandler.VerifyErasureReport)
+	}
+
+	// Full-text search across every readable collection (protected)
+	router.GET("/search", middleware.AuthMiddleware(cfg, database), rateLimit, itemsHandler.Search)
+
+	// OpenAPI document of the caller's collections (protected)
+	router.GET("/openapi.json", middleware.AuthMiddleware(cfg, database), rateLimit, middleware.ETag(), itemsHandler.GetOpenAPI)
+
+	// Items shared by link (authenticated by the share token)
+	router.GET("/shared/:token", middleware.ETag(), itemsHandler.GetSharedItem)
+
+	// Incoming webhooks of flows (authenticated by the flow's secret)
+	router.POST("/flows/:id/trigger", flowsHandler.TriggerFlow)
+
+	// Realtime subscriptions (protected, Server-Sent Events)
+	router.GET("/realtime", middleware.QueryTokenAuth(), middleware.AuthMiddleware(cfg, database), realtimeHandler.Subscribe)
+
+	// Asset routes (protected). Downloads accept ?access_token= so files can be used in <img> tags.
+	assets := router.Group("/assets")
+	{
+		assets.POST("", middleware.AuthMiddleware(cfg, database), rateLimit, middleware.AuditTrail(database), assetsHandler.UploadAsset)
+		assets.GET("/:id", middleware.QueryTokenAuth(), middleware.AuthMiddleware(cfg, database), rateLimit, assetsHandler.GetAsset)
+		assets.DELETE("/:id", middleware.AuthMiddleware(cfg, database), rateLimit, middleware.AuditTrail(database), assetsHandler.DeleteAsset)
 	}
 
 	// Tenant routes (protected)
@@ -180,6 +482,23 @@ func main() {
 		tenant.GET("/:id", tenantHandler.GetTenant)
 		tenant.PUT("/:id", tenantHandler.UpdateTenant)
 		tenant.DELETE("/:id", tenantHandler.DeleteTenant)
+		tenant.GET("/:id/usage", tenantHandler.GetTenantUsage)
+		tenant.POST("/:id/suspend", tenantHandler.SuspendTenant)
+		tenant.POST("/:id/resume", tenantHandler.ResumeTenant)
+		tenant.POST("/:id/restore", tenantHandler.RestoreTenant)
+		tenant.GET("/:id/export", tenantHandler.ExportTenant)
+		tenant.POST("/import", tenantHandler.ImportTenant)
+
+		// Backups of the tenant in asset storage
+		tenant.POST("/:id/backups", backupsHandler.CreateBackup)
+		tenant.GET("/:id/backups", backupsHandler.ListBackups)
+		tenant.GET("/:id/backups/schedule", backupsHandler.GetBackupSchedule)
+		tenant.PUT("/:id/backups/schedule", backupsHandler.SetBackupSchedule)
+		tenant.DELETE("/:id/backups/schedule", backupsHandler.DeleteBackupSchedule)
+		tenant.GET("/:id/backups/:backup_id", backupsHandler.GetBackup)
+		tenant.GET("/:id/backups/:backup_id/download", backupsHandler.DownloadBackup)
+		tenant.POST("/:id/backups/:backup_id/restore", backupsHandler.RestoreBackup)
+		tenant.DELETE("/:id/backups/:backup_id", backupsHandler.DeleteBackup)
 
 		// User-tenant management
 		tenant.POST("/:id/users", tenantHandler.AddUserToTenant)
@@ -198,27 +517,80 @@ func main() {
 			"message": "Dynamic auto-generated REST API with Role-Based Access Control on Postgres",
 			"version": "1.0.0",
 			"endpoints": gin.H{
-				"health": "/health",
+				"health": "/health, GET /health/live, GET /health/ready",
+				"jwks":   "GET /.well-known/jwks.json",
 				"auth": gin.H{
-					"login": "POST /auth/login",
-					"me":    "GET /auth/me",
+					"login":           "POST /auth/login",
+					"me":              "GET /auth/me",
+					"change_password": "POST /auth/change-password",
+					"logout":          "POST /auth/logout",
+					"sessions":        "GET /auth/sessions, DELETE /auth/sessions/:id",
+					"impersonate":     "POST /auth/impersonate/:user_id, POST /auth/impersonate/stop",
+					"password_reset":  "POST /auth/password/request, POST /auth/password/reset",
+					"oauth":           "GET /auth/oauth, GET /auth/oauth/:provider, GET /auth/oauth/:provider/callback",
+					"two_factor":      "GET /auth/2fa, POST /auth/2fa/enable, POST /auth/2fa/confirm, POST /auth/2fa/verify, POST /auth/2fa/disable, POST /auth/2fa/recovery-codes",
 				},
 				"items": gin.H{
-					"list":   "GET /items/:table",
-					"get":    "GET /items/:table/:id",
-					"create": "POST /items/:table",
-					"update": "PUT /items/:table/:id",
-					"delete": "DELETE /items/:table/:id",
+					"list":      "GET /items/:table",
+					"get":       "GET /items/:table/:id",
+					"by_slug":   "GET /items/:table/slug/:slug, GET /items/:table/:value?key_field=",
+					"create":    "POST /items/:table",
+					"update":    "PUT /items/:table/:id",
+					"delete":    "DELETE /items/:table/:id",
+					"restore":   "POST /items/:table/:id/restore",
+					"move":      "POST /items/:table/:id/move",
+					"revisions": "GET /items/:table/:id/revisions",
+					"revert":    "POST /items/:table/:id/revisions/:revision_id/revert",
+					"rotate":    "POST /items/api_keys/:id/rotate",
+					"share":     "POST /items/:table/:id/share, GET /items/:table/:id/shares, DELETE /items/:table/:id/shares/:share_id",
+					"shared":    "GET /shared/:token",
+				},
+				"search":   "GET /search?q=",
+				"openapi":  "GET /openapi.json",
+				"flows":    "POST /flows/:id/trigger",
+				"realtime": "GET /realtime?collections=:table",
+				"schema": gin.H{
+					"snapshot": "GET /schema/snapshot",
+					"apply":    "POST /schema/apply",
+					"sdk":      "GET /schema/sdk?lang=ts|go",
+				},
+				"admin": "GET /admin/",
+				"rbac": gin.H{
+					"matrix": "GET /rbac/matrix, PUT /rbac/matrix",
+					"check":  "POST /rbac/check",
+					"rls":    "GET /rbac/rls",
+				},
+				"collections": gin.H{
+					"templates": "GET /collections/templates",
+					"duplicate": "POST /collections/:id/duplicate",
+				},
+				"service_accounts": gin.H{
+					"list":   "GET /service-accounts",
+					"create": "POST /service-accounts",
+					"get":    "GET /service-accounts/:id",
+					"update": "PATCH /service-accounts/:id",
+					"delete": "DELETE /service-accounts/:id",
+					"keys":   "POST /service-accounts/:id/keys",
+				},
+				"privacy": gin.H{
+					"erasure": "POST /privacy/erasure",
+					"verify":  "POST /privacy/erasure/verify",
+				},
+				"assets": gin.H{
+					"upload":   "POST /assets",
+					"download": "GET /assets/:id",
+					"delete":   "DELETE /assets/:id",
 				},
 			},
 			"sample_tables": []string{"customers", "products", "orders"},
-			"default_admin": gin.H{
-				"email":    "admin@example.com",
-				"password": "password",
-			},
 		})
 	})
 
+	// Admin app (its pages sign in through /auth/login like any other client)
+	if cfg.AdminUI {
+		router.GET("/admin/*path", admin.Handler())
+	}
+
 	// Swagger UI and JSON (auto-generated)
 	router.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
@@ -234,203 +606,69 @@ func main() {
 		Handler: router,
 	}
 
-	log.Println("✅ Step 7 COMPLETE: Router setup finished")
-	log.Println("Step 8: Starting server...")
-
 	// Start server in a goroutine
 	go func() {
-		log.Printf("🚀 SERVER STARTED on port %s", port)
+		logger.Info("server started", "port", port)
+		healthHandler.SetStarted(true)
 		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start server: %v", err)
+			logger.Error("failed to start server", "error", err)
+			os.Exit(1)
 		}
 	}()
 
-	log.Println("✅ Step 8 COMPLETE: Server startup initiated")
-	log.Println("🎉 === APP STARTUP COMPLETE ===")
-
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	logger.Info("shutting down server")
+	// Fail readiness first so load balancers stop sending new requests
+	healthHandler.SetStarted(false)
+	stopWorkers()
 
 	// Give outstanding requests a deadline for completion
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		logger.Error("server forced to shut down", "error", err)
+		os.Exit(1)
 	}
 
-	log.Println("Server exited")
+	logger.Info("server exited")
 }
 
-// getCurrentDir returns the current working directory
-func getCurrentDir() string {
-	dir, err := os.Getwd()
+// runSeeds applies the seed files of the configured environment
+func runSeeds(db *db.DB, cfg *config.Config) error {
+	seeds, err := seed.Load(os.DirFS(cfg.SeedsDir), cfg.SeedEnv)
 	if err != nil {
-		return "unknown"
+		return err
 	}
-	return dir
-}
 
-// listMigrationFiles lists all files in the migrations directory for debugging
-func listMigrationFiles() error {
-	migrationDir := "migrations"
-	files, err := os.ReadDir(migrationDir)
+	result, err := seed.New(db, cfg).Run(context.Background(), seeds)
 	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %v", err)
+		return err
 	}
-
-	log.Printf("Found %d files in migrations directory:", len(files))
-	for _, file := range files {
-		log.Printf("  - %s (dir: %t)", file.Name(), file.IsDir())
+	slog.Info("seeding complete", "env", cfg.SeedEnv,
+		"tenants", result.Tenants, "users", result.Users, "schema_changes", result.SchemaChanges, "items", result.Items)
+	if result.AdminPassword != "" {
+		// Shown once; the admin has to replace it on first login
+		slog.Warn("created the initial admin with a generated password; set ADMIN_PASSWORD to choose one",
+			"email", cfg.AdminEmail, "password", result.AdminPassword)
 	}
 	return nil
 }
 
-// seedDatabase seeds the database with initial data
-func seedDatabase(db *db.DB) error {
-	log.Println("Starting database seeding...")
-
-	// Check if seeding has already been done
-	var count int
-	err := db.QueryRow("SELECT COUNT(*) FROM users WHERE email = 'admin@example.com'").Scan(&count)
+// runMigrations applies the pending migrations from the migrations directory
+func runMigrations(db *db.DB, cfg *config.Config) error {
+	migrations, err := migrate.Load(os.DirFS(cfg.MigrationsDir))
 	if err != nil {
-		// Table doesn't exist yet, that's fine for first run
-		log.Println("Users table not found, proceeding with seeding...")
-	} else if count > 0 {
-		log.Println("Database already seeded, skipping...")
-		return nil
+		return err
 	}
 
-	// Create default admin user
-	log.Println("Creating default admin user...")
-	adminPassword := "password" // In production, use environment variable
-	hashedPassword, err := hashPassword(adminPassword)
-	if err != nil {
-		return fmt.Errorf("failed to hash admin password: %v", err)
-	}
-
-	// Insert admin user
-	_, err = db.Exec(`
-		INSERT INTO users (id, email, password_hash, first_name, last_name, is_active, created_at, updated_at)
-		VALUES (
-			gen_random_uuid(),
-			'admin@example.com',
-			$1,
-			'Admin',
-			'User',
-			true,
-			NOW(),
-			NOW()
-		)
-	`, hashedPassword)
-	if err != nil {
-		return fmt.Errorf("failed to create admin user: %v", err)
-	}
-
-	// Create default tenant
-	log.Println("Creating default tenant...")
-	var tenantID string
-	err = db.QueryRow(`
-		INSERT INTO tenants (id, name, description, created_at, updated_at)
-		VALUES (
-			gen_random_uuid(),
-			'Default Tenant',
-			'Default organization for the system',
-			NOW(),
-			NOW()
-		)
-		RETURNING id
-	`).Scan(&tenantID)
+	applied, err := migrate.New(db, migrations).Up(context.Background())
 	if err != nil {
-		return fmt.Errorf("failed to create default tenant: %v", err)
-	}
-
-	// Link admin user to default tenant
-	log.Println("Linking admin user to default tenant...")
-	_, err = db.Exec(`
-		INSERT INTO user_tenants (id, user_id, tenant_id, role, created_at, updated_at)
-		SELECT 
-			gen_random_uuid(),
-			u.id,
-			$1,
-			'admin',
-			NOW(),
-			NOW()
-		FROM users u
-		WHERE u.email = 'admin@example.com'
-	`, tenantID)
-	if err != nil {
-		return fmt.Errorf("failed to link admin user to tenant: %v", err)
-	}
-
-	// Create some sample collections and fields
-	log.Println("Creating sample collections...")
-	_, err = db.Exec(`
-		INSERT INTO collections (id, name, description, tenant_id, created_at, updated_at)
-		VALUES (
-			gen_random_uuid(),
-			'blog_posts',
-			'Sample blog posts collection',
-			$1,
-			NOW(),
-			NOW()
-		)
-	`, tenantID)
-	if err != nil {
-		log.Printf("WARNING: Failed to create sample collection: %v", err)
+		return err
 	}
-
-	log.Println("Database seeding completed successfully!")
-	return nil
-}
-
-// hashPassword hashes a password using bcrypt
-func hashPassword(password string) (string, error) {
-	// For now, return a simple hash. In production, use bcrypt
-	// This is a placeholder - you should implement proper bcrypt hashing
-	return fmt.Sprintf("hashed_%s", password), nil
-}
-
-// runMigrations executes all SQL files in the migrations directory
-func runMigrations(db *db.DB) error {
-	migrationDir := "migrations"
-	files, err := os.ReadDir(migrationDir)
-	if err != nil {
-		return fmt.Errorf("failed to read migrations directory: %v", err)
-	}
-
-	// Sort files to ensure proper order
-	var sqlFiles []string
-	for _, file := range files {
-		if filepath.Ext(file.Name()) == ".sql" {
-			sqlFiles = append(sqlFiles, file.Name())
-		}
-	}
-
-	// Execute migrations in order
-	for _, fileName := range sqlFiles {
-		log.Printf("Executing migration: %s", fileName)
-
-		filePath := filepath.Join(migrationDir, fileName)
-		content, err := os.ReadFile(filePath)
-		if err != nil {
-			log.Printf("WARNING: Could not read migration file %s: %v", fileName, err)
-			continue // Skip this migration but continue with others
-		}
-
-		// Execute the migration
-		_, err = db.Exec(string(content))
-		if err != nil {
-			log.Printf("WARNING: Migration %s failed: %v", fileName, err)
-			log.Printf("Continuing with next migration...")
-			continue // Skip this migration but continue with others
-		}
-
-		log.Printf("Successfully executed migration: %s", fileName)
-	}
-
+	slog.Info("migrations complete", "applied", applied, "available", len(migrations))
 	return nil
 }
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"text/tabwriter"
+
+	"go-rbac-api/internal/migrate"
+
+	"github.com/spf13/cobra"
+)
+
+var migrationNamePattern = regexp.MustCompile(`^[a-z0-9_]+$`)
+
+// newMigrateCommand builds basin migrate and its subcommands
+func newMigrateCommand(app *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply, roll back and inspect database migrations",
+	}
+
+	// withMigrator loads the migrations and connects before running fn
+	withMigrator := func(fn func(cmd *cobra.Command, migrator *migrate.Migrator, args []string) error) func(*cobra.Command, []string) error {
+		return func(cmd *cobra.Command, args []string) error {
+			migrations, err := migrate.Load(os.DirFS(app.cfg.MigrationsDir))
+			if err != nil {
+				return err
+			}
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+
+			return fn(cmd, migrate.New(database, migrations), args)
+		}
+	}
+
+	cmd.AddCommand(
+		&cobra.Command{
+			Use:   "up",
+			Short: "Apply all pending migrations",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(cmd *cobra.Command, migrator *migrate.Migrator, args []string) error {
+				applied, err := migrator.Up(cmd.Context())
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%d migration(s) applied\n", applied)
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "down [N]",
+			Short: "Roll back the last N migrations (default 1)",
+			Args:  cobra.MaximumNArgs(1),
+			RunE: withMigrator(func(cmd *cobra.Command, migrator *migrate.Migrator, args []string) error {
+				steps := 1
+				if len(args) == 1 {
+					n, err := strconv.Atoi(args[0])
+					if err != nil || n < 1 {
+						return fmt.Errorf("down needs a positive number of steps")
+					}
+					steps = n
+				}
+				reverted, err := migrator.Down(cmd.Context(), steps)
+				if err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "%d migration(s) rolled back\n", reverted)
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "status",
+			Short: "List migrations and whether they are applied",
+			Args:  cobra.NoArgs,
+			RunE: withMigrator(func(cmd *cobra.Command, migrator *migrate.Migrator, args []string) error {
+				statuses, err := migrator.Status(cmd.Context())
+				if err != nil {
+					return err
+				}
+				printMigrationStatus(cmd.OutOrStdout(), statuses)
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "force VERSION",
+			Short: "Mark VERSION as applied and clean without running it",
+			Args:  cobra.ExactArgs(1),
+			RunE: withMigrator(func(cmd *cobra.Command, migrator *migrate.Migrator, args []string) error {
+				version, err := strconv.ParseInt(args[0], 10, 64)
+				if err != nil {
+					return fmt.Errorf("invalid version: %s", args[0])
+				}
+				if err := migrator.Force(cmd.Context(), version); err != nil {
+					return err
+				}
+				fmt.Fprintf(cmd.OutOrStdout(), "version %d forced\n", version)
+				return nil
+			}),
+		},
+		&cobra.Command{
+			Use:   "create NAME",
+			Short: "Create empty up and down files for a new migration",
+			Args:  cobra.ExactArgs(1),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				if !migrationNamePattern.MatchString(args[0]) {
+					return fmt.Errorf("migration names use lowercase letters, digits and underscores")
+				}
+				migrations, err := migrate.Load(os.DirFS(app.cfg.MigrationsDir))
+				if err != nil {
+					return err
+				}
+				return createMigration(cmd.OutOrStdout(), app.cfg.MigrationsDir, migrations, args[0])
+			},
+		},
+	)
+
+	return cmd
+}
+
+// printMigrationStatus writes one line per migration
+func printMigrationStatus(out io.Writer, statuses []migrate.Status) {
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATE\tAPPLIED AT")
+	for _, s := range statuses {
+		state, appliedAt := "pending", ""
+		if s.Applied {
+			state = "applied"
+			appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		switch {
+		case s.Dirty:
+			state = "dirty"
+		case s.Applied && s.Up == "":
+			state = "missing file"
+		case s.Modified:
+			state = "modified"
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", s.Version, s.Name, state, appliedAt)
+	}
+	w.Flush()
+}
+
+// createMigration writes empty up and down files numbered after the latest migration
+func createMigration(out io.Writer, dir string, migrations []migrate.Migration, name string) error {
+	version := int64(1)
+	if len(migrations) > 0 {
+		version = migrations[len(migrations)-1].Version + 1
+	}
+
+	upFile := filepath.Join(dir, migrate.FileName(version, name))
+	downFile := filepath.Join(dir, fmt.Sprintf("%03d_%s.down.sql", version, name))
+	if err := os.WriteFile(upFile, []byte("-- "+name+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", upFile, err)
+	}
+	if err := os.WriteFile(downFile, []byte("-- Reverts "+filepath.Base(upFile)+"\n"), 0o644); err != nil {
+		return fmt.Errorf("failed to create %s: %w", downFile, err)
+	}
+
+	fmt.Fprintln(out, "created", upFile)
+	fmt.Fprintln(out, "created", downFile)
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/logging"
+	"go-rbac-api/internal/seed"
+
+	"github.com/spf13/cobra"
+)
+
+// cli holds the configuration and logger shared by every command
+type cli struct {
+	cfg    *config.Config
+	logger *slog.Logger
+}
+
+// newRootCommand builds the basin command. Running it without a subcommand starts the
+// server, like basin serve.
+func newRootCommand() *cobra.Command {
+	app := &cli{}
+
+	root := &cobra.Command{
+		Use:           "basin",
+		Short:         "Basin API server and administration tool",
+		Args:          cobra.NoArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			return app.load(cmd)
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(app.cfg, app.logger)
+			return nil
+		},
+	}
+
+	root.AddCommand(
+		newServeCommand(app),
+		newMigrateCommand(app),
+		newSeedCommand(app),
+		newUserCommand(app),
+		newTenantCommand(app),
+		newAPIKeyCommand(app),
+		newSchemaCommand(app),
+	)
+
+	return root
+}
+
+// load reads the configuration and sets up logging. The server logs to stdout; the
+// administrative commands log to stderr so their output can be piped.
+func (app *cli) load(cmd *cobra.Command) error {
+	cfg, err := config.Load()
+	if err != nil {
+		return fmt.Errorf("failed to load config: %w", err)
+	}
+
+	var w io.Writer = os.Stderr
+	if cmd.Name() == "serve" || !cmd.HasParent() {
+		w = os.Stdout
+	}
+
+	// Structured logging for the whole process; the standard log package is routed
+	// through it as well
+	app.cfg = cfg
+	app.logger = logging.New(cfg, w)
+	slog.SetDefault(app.logger)
+	return nil
+}
+
+// openDB connects to the database for a command
+func (app *cli) openDB() (*db.DB, error) {
+	database, err := db.NewDB(app.cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return database, nil
+}
+
+// newServeCommand builds basin serve
+func newServeCommand(app *cli) *cobra.Command {
+	return &cobra.Command{
+		Use:   "serve",
+		Short: "Run the API server",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			serve(app.cfg, app.logger)
+			return nil
+		},
+	}
+}
+
+// newSeedCommand builds basin seed
+func newSeedCommand(app *cli) *cobra.Command {
+	var env, dir string
+	cmd := &cobra.Command{
+		Use:   "seed",
+		Short: "Create the tenants, users, schemas and items of the seed files",
+		Long: "Create the tenants, users, schemas and items defined in the seed files shared by all\n" +
+			"environments and in those of --env. Records that already exist are left unchanged.",
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if env == "" {
+				env = app.cfg.SeedEnv
+			}
+			if dir == "" {
+				dir = app.cfg.SeedsDir
+			}
+			seeds, err := seed.Load(os.DirFS(dir), env)
+			if err != nil {
+				return err
+			}
+
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+
+			result, err := seed.New(database, app.cfg).Run(cmd.Context(), seeds)
+			fmt.Fprintf(cmd.OutOrStdout(), "created %d tenant(s), %d user(s), %d schema change(s), %d item(s)\n",
+				result.Tenants, result.Users, result.SchemaChanges, result.Items)
+			if result.AdminPassword != "" {
+				fmt.Fprintf(cmd.OutOrStdout(), "initial admin %s has the generated password %s (change it on first login)\n",
+					app.cfg.AdminEmail, result.AdminPassword)
+			}
+			return err
+		},
+	}
+	cmd.Flags().StringVar(&env, "env", "", "seed environment (default: SEED_ENV)")
+	cmd.Flags().StringVar(&dir, "dir", "", "seeds directory (default: SEEDS_DIR)")
+	return cmd
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go-rbac-api/internal/api"
+	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+	"github.com/spf13/cobra"
+)
+
+// newSchemaCommand builds basin schema, the CLI counterpart of /schema/snapshot and
+// /schema/apply. Both act on the tenant of the user given with --user.
+func newSchemaCommand(app *cli) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Export and apply schema snapshots",
+	}
+
+	var userEmail, format, output string
+	snapshot := &cobra.Command{
+		Use:   "snapshot",
+		Short: "Write the collections, fields, roles and permissions of a tenant",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if format == "" {
+				format = snapshotFileFormat(output)
+			}
+			if format != "yaml" && format != "json" {
+				return fmt.Errorf("--format must be yaml or json")
+			}
+
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+
+			schema, userID, err := app.schemaHandlers(cmd, database, userEmail)
+			if err != nil {
+				return err
+			}
+			snapshot, err := schema.Snapshot(cmd.Context(), userID)
+			if err != nil {
+				return err
+			}
+			body, err := snapshot.Encode(format)
+			if err != nil {
+				return err
+			}
+
+			if output == "" {
+				_, err = cmd.OutOrStdout().Write(body)
+				return err
+			}
+			return os.WriteFile(output, body, 0o644)
+		},
+	}
+	snapshot.Flags().StringVar(&userEmail, "user", "", "email of a user of the tenant to export (required)")
+	snapshot.Flags().StringVar(&format, "format", "", "yaml or json (default: from --output, else yaml)")
+	snapshot.Flags().StringVarP(&output, "output", "o", "", "file to write instead of stdout")
+	snapshot.MarkFlagRequired("user")
+
+	var applyEmail, file string
+	var dryRun bool
+	apply := &cobra.Command{
+		Use:   "apply",
+		Short: "Create or update a tenant's schema to match a snapshot",
+		Long:  "Create or update a tenant's schema to match a snapshot. Nothing is deleted.",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			body, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			snapshot, err := api.ParseSchemaSnapshot(body, snapshotFileFormat(file))
+			if err != nil {
+				return err
+			}
+
+			database, err := app.openDB()
+			if err != nil {
+				return err
+			}
+			defer database.Close()
+
+			schema, userID, err := app.schemaHandlers(cmd, database, applyEmail)
+			if err != nil {
+				return err
+			}
+			changes, err := schema.ApplySnapshot(cmd.Context(), userID, snapshot, dryRun)
+
+			out := cmd.OutOrStdout()
+			for _, change := range changes {
+				line := fmt.Sprintf("%s %s %s", change.Action, change.Kind, change.Name)
+				if len(change.Fields) > 0 {
+					line += " (" + strings.Join(change.Fields, ", ") + ")"
+				}
+				fmt.Fprintln(out, line)
+			}
+			if err != nil {
+				return err
+			}
+
+			switch {
+			case len(changes) == 0:
+				fmt.Fprintln(out, "schema is up to date")
+			case dryRun:
+				fmt.Fprintf(out, "%d change(s) would be applied\n", len(changes))
+			default:
+				fmt.Fprintf(out, "%d change(s) applied\n", len(changes))
+			}
+			return nil
+		},
+	}
+	apply.Flags().StringVar(&applyEmail, "user", "", "email of a user of the target tenant (required)")
+	apply.Flags().StringVarP(&file, "file", "f", "", "snapshot file, YAML or JSON by extension (required)")
+	apply.Flags().BoolVar(&dryRun, "dry-run", false, "only list the changes")
+	apply.MarkFlagRequired("user")
+	apply.MarkFlagRequired("file")
+
+	cmd.AddCommand(snapshot, apply)
+	return cmd
+}
+
+// schemaHandlers builds the schema handlers and looks up the acting user
+func (app *cli) schemaHandlers(cmd *cobra.Command, database *db.DB, email string) (*api.SchemaHandlers, uuid.UUID, error) {
+	user, err := database.Queries.GetUserByEmail(cmd.Context(), email)
+	if err != nil {
+		return nil, uuid.Nil, fmt.Errorf("user %q not found", email)
+	}
+
+	items := api.NewItemsHandler(database, app.cfg, events.NewBus())
+	return api.NewSchemaHandlers(items, api.NewItemsUtils(database)), user.ID, nil
+}
+
+// snapshotFileFormat derives the snapshot format from a file name; YAML unless it ends in .json
+func snapshotFileFormat(name string) string {
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		return "json"
+	}
+	return "yaml"
+}
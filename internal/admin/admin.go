@@ -0,0 +1,46 @@
+// Package admin serves Basin's admin app: a single-page application embedded in the binary
+// that browses collections, edits items, shows what each role may do and manages API keys.
+//
+// The app is plain HTML, CSS and JavaScript with no build step. It talks to the same public
+// API as any other client, authenticating with the token of a login, so it can do exactly
+// what the signed-in user's permissions allow.
+package admin
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed ui
+var uiFS embed.FS
+
+// contentSecurityPolicy only lets the app load its own files and call its own server, so
+// item data shown in the app cannot run scripts
+const contentSecurityPolicy = "default-src 'self'; img-src 'self' data:; connect-src 'self'; frame-ancestors 'none'; base-uri 'none'; form-action 'none'"
+
+// Handler serves the files of the admin app from the *path parameter of its route, as in
+// router.GET("/admin/*path", admin.Handler()). The app routes with URL fragments, so any
+// other path is not found.
+func Handler() gin.HandlerFunc {
+	files, err := fs.Sub(uiFS, "ui")
+	if err != nil {
+		panic(err)
+	}
+	fileServer := http.FileServer(http.FS(files))
+
+	return func(c *gin.Context) {
+		c.Header("Content-Security-Policy", contentSecurityPolicy)
+		c.Header("X-Content-Type-Options", "nosniff")
+		c.Header("Referrer-Policy", "no-referrer")
+		// Embedded files have no modification time, so browsers must revalidate them
+		c.Header("Cache-Control", "no-cache")
+
+		req := c.Request.Clone(c.Request.Context())
+		req.URL.Path = "/" + strings.TrimPrefix(c.Param("path"), "/")
+		fileServer.ServeHTTP(c.Writer, req)
+	}
+}
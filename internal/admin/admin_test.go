@@ -0,0 +1,39 @@
+package admin
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/admin/*path", Handler())
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	w := get("/admin/")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "text/html")
+	assert.Contains(t, w.Body.String(), `<script src="app.js" defer></script>`)
+	assert.Contains(t, w.Header().Get("Content-Security-Policy"), "default-src 'self'")
+
+	w = get("/admin/app.js")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("Content-Type"), "javascript")
+
+	w = get("/admin")
+	assert.Equal(t, http.StatusMovedPermanently, w.Code)
+	assert.Equal(t, "/admin/", w.Header().Get("Location"))
+
+	assert.Equal(t, http.StatusNotFound, get("/admin/missing.js").Code)
+	assert.Equal(t, http.StatusNotFound, get("/admin/../admin.go").Code)
+}
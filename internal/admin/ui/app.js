@@ -0,0 +1,604 @@
+// Basin admin app. Routes live in the URL fragment (#/collections/products/<id>) and every
+// page is rendered from API responses with DOM methods, never with innerHTML, so item data
+// is always shown as text.
+"use strict";
+
+const PAGE_SIZE = 25;
+
+const session = {
+  get token() { return sessionStorage.getItem("basin.token"); },
+  get tenant() { return sessionStorage.getItem("basin.tenant") || ""; },
+  save(login) {
+    sessionStorage.setItem("basin.token", login.token);
+    sessionStorage.setItem("basin.tenant", login.tenant_slug || "");
+  },
+  clear() {
+    sessionStorage.removeItem("basin.token");
+    sessionStorage.removeItem("basin.tenant");
+  },
+};
+
+// ApiError carries the status and field errors of a failed request
+class ApiError extends Error {
+  constructor(status, body) {
+    super(errorMessage(status, body));
+    this.status = status;
+    this.fields = {};
+    // Basin lists field errors in errors; the Directus dialect nests them in extensions
+    for (const entry of (body && body.errors) || []) {
+      const field = entry.field || (entry.extensions && entry.extensions.field);
+      if (field) {
+        this.fields[field] = entry.message;
+      }
+    }
+  }
+}
+
+function errorMessage(status, body) {
+  if (body && typeof body.error === "string") {
+    return body.error;
+  }
+  if (body && Array.isArray(body.errors) && body.errors.length > 0 && body.errors[0].message) {
+    return body.errors[0].message;
+  }
+  return "Request failed with status " + status;
+}
+
+// api calls the Basin API and returns the parsed body; 401 responses sign the user out
+async function api(method, path, body) {
+  const headers = {};
+  if (session.token) {
+    headers.Authorization = "Bearer " + session.token;
+  }
+  if (body !== undefined) {
+    headers["Content-Type"] = "application/json";
+  }
+  const response = await fetch(path, {
+    method,
+    headers,
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+  const text = await response.text();
+  let payload;
+  try {
+    payload = text ? JSON.parse(text) : {};
+  } catch (err) {
+    payload = { error: text };
+  }
+  if (response.status === 401 && session.token) {
+    session.clear();
+    location.hash = "#/login";
+  }
+  if (!response.ok) {
+    throw new ApiError(response.status, payload);
+  }
+  return payload;
+}
+
+function query(params) {
+  const search = new URLSearchParams();
+  for (const [key, value] of Object.entries(params)) {
+    if (value !== undefined && value !== null && value !== "") {
+      search.set(key, value);
+    }
+  }
+  const encoded = search.toString();
+  return encoded ? "?" + encoded : "";
+}
+
+// el creates an element; attributes starting with "on" become event listeners and
+// children may be elements, strings or null
+function el(tag, attributes, ...children) {
+  const node = document.createElement(tag);
+  for (const [name, value] of Object.entries(attributes || {})) {
+    if (value === undefined || value === null || value === false) {
+      continue;
+    }
+    if (name.startsWith("on")) {
+      node.addEventListener(name.slice(2), value);
+    } else if (name === "className") {
+      node.className = value;
+    } else if (value === true) {
+      node.setAttribute(name, "");
+    } else {
+      node.setAttribute(name, value);
+    }
+  }
+  for (const child of children.flat()) {
+    if (child !== null && child !== undefined && child !== false) {
+      node.append(child instanceof Node ? child : String(child));
+    }
+  }
+  return node;
+}
+
+function render(...nodes) {
+  const main = document.getElementById("main");
+  main.replaceChildren(...nodes);
+}
+
+function toast(message) {
+  const node = document.getElementById("toast");
+  node.textContent = message;
+  node.hidden = false;
+  clearTimeout(toast.timer);
+  toast.timer = setTimeout(() => { node.hidden = true; }, 4000);
+}
+
+function showError(err) {
+  render(el("p", { className: "error" }, err.message));
+}
+
+function display(value) {
+  if (value === null || value === undefined) {
+    return "";
+  }
+  if (typeof value === "object") {
+    return JSON.stringify(value);
+  }
+  return String(value);
+}
+
+function formatDate(value) {
+  return value ? new Date(value).toLocaleString() : "";
+}
+
+// Login
+
+function loginPage() {
+  const error = el("p", { className: "error" });
+  const challenge = { token: "" };
+  const codeLabel = el("label", { hidden: true }, "Authentication code", el("input", { name: "code", autocomplete: "one-time-code" }));
+
+  const form = el("form", {
+    className: "card login",
+    onsubmit: async (event) => {
+      event.preventDefault();
+      error.textContent = "";
+      const data = new FormData(form);
+      try {
+        let login;
+        if (challenge.token) {
+          login = await api("POST", "/auth/2fa/verify", { challenge_token: challenge.token, code: data.get("code") });
+        } else {
+          login = await api("POST", "/auth/login", {
+            email: data.get("email"),
+            password: data.get("password"),
+            tenant_slug: data.get("tenant") || undefined,
+          });
+        }
+        if (login.two_factor_required) {
+          challenge.token = login.challenge_token;
+          codeLabel.hidden = false;
+          return;
+        }
+        if (login.password_change_required || login.two_factor_setup_required) {
+          error.textContent = "This account must change its password or set up two-factor authentication before using the admin app.";
+          return;
+        }
+        session.save(login);
+        location.hash = "#/collections";
+      } catch (err) {
+        error.textContent = err.message;
+      }
+    },
+  },
+  el("h1", {}, "Sign in to Basin"),
+  el("label", {}, "Email", el("input", { name: "email", type: "email", required: true, autocomplete: "username" })),
+  el("label", {}, "Password", el("input", { name: "password", type: "password", required: true, autocomplete: "current-password" })),
+  el("label", {}, "Tenant ", el("span", { className: "hint" }, "(optional)"), el("input", { name: "tenant", value: session.tenant })),
+  codeLabel,
+  error,
+  el("button", { type: "submit" }, "Sign in"));
+  render(form);
+}
+
+// Collections
+
+async function collectionsPage() {
+  const body = await api("GET", "/items/collections" + query({ limit: 500, sort: "name" }));
+  const rows = (body.data || []).map((collection) => el("tr", {
+    className: "link",
+    onclick: () => { location.hash = "#/collections/" + encodeURIComponent(collection.slug); },
+  },
+  el("td", {}, collection.display_name || collection.name),
+  el("td", {}, collection.slug),
+  el("td", {}, collection.description || "")));
+
+  render(
+    el("div", { className: "toolbar" }, el("h1", {}, "Collections")),
+    rows.length === 0
+      ? el("p", { className: "muted" }, "No collections yet.")
+      : el("table", {}, el("thead", {}, el("tr", {}, el("th", {}, "Name"), el("th", {}, "Slug"), el("th", {}, "Description"))), el("tbody", {}, rows)),
+  );
+}
+
+// loadCollection returns a collection with its fields, in their sort order
+async function loadCollection(slug) {
+  const collections = await api("GET", "/items/collections" + query({ slug }));
+  const collection = (collections.data || [])[0];
+  if (!collection) {
+    throw new Error("Collection " + slug + " not found");
+  }
+  const fields = await api("GET", "/items/fields" + query({ collection_id: collection.id, limit: 500, sort: "sort_order" }));
+  collection.fields = fields.data || [];
+  return collection;
+}
+
+function relationType(field) {
+  return (field.relation_config && field.relation_config.type) || "m2o";
+}
+
+async function itemsPage(slug, offset) {
+  const collection = await loadCollection(slug);
+  const body = await api("GET", "/items/" + encodeURIComponent(slug) + query({ limit: PAGE_SIZE, offset, meta: "total_count" }));
+  const items = body.data || [];
+  const total = body.meta && body.meta.total_count;
+  const columns = collection.fields.filter((field) => field.type !== "json").slice(0, 6).map((field) => field.name);
+
+  const rows = items.map((item) => el("tr", {
+    className: "link",
+    onclick: () => { location.hash = "#/collections/" + encodeURIComponent(slug) + "/" + encodeURIComponent(item.id); },
+  }, columns.map((column) => el("td", { title: display(item[column]) }, display(item[column]))), el("td", {}, formatDate(item.updated_at))));
+
+  const page = (next) => () => { location.hash = "#/collections/" + encodeURIComponent(slug) + "?offset=" + next; };
+  render(
+    el("div", { className: "toolbar" },
+      el("h1", {}, collection.display_name || collection.name),
+      el("button", { type: "button", onclick: () => { location.hash = "#/collections/" + encodeURIComponent(slug) + "/new"; } }, "New item")),
+    items.length === 0
+      ? el("p", { className: "muted" }, "No items.")
+      : el("table", {},
+        el("thead", {}, el("tr", {}, columns.map((column) => el("th", {}, column)), el("th", {}, "Updated"))),
+        el("tbody", {}, rows)),
+    el("div", { className: "pager" },
+      el("button", { type: "button", className: "secondary", disabled: offset === 0, onclick: page(Math.max(0, offset - PAGE_SIZE)) }, "Previous"),
+      el("span", {}, items.length === 0 ? "" : (offset + 1) + "–" + (offset + items.length) + (total !== undefined ? " of " + total : "")),
+      el("button", { type: "button", className: "secondary", disabled: items.length < PAGE_SIZE, onclick: page(offset + PAGE_SIZE) }, "Next")),
+  );
+}
+
+// Item editor
+
+// fieldInput returns the input of a field and functions reading its value back
+function fieldInput(field, value) {
+  const readOnly = field.type === "computed" || (field.type === "relation" && relationType(field) === "o2m");
+  const attributes = { name: field.name, disabled: readOnly };
+
+  switch (field.type) {
+    case "boolean":
+    case "bool": {
+      const input = el("input", Object.assign(attributes, { type: "checkbox", checked: value === true }));
+      return { input, read: () => input.checked };
+    }
+    case "integer":
+    case "int":
+    case "decimal":
+    case "float":
+    case "number": {
+      const input = el("input", Object.assign(attributes, { type: "number", step: "any", value: display(value) }));
+      return { input, read: () => (input.value === "" ? null : Number(input.value)) };
+    }
+    case "datetime": {
+      const local = value ? new Date(new Date(value).getTime() - new Date().getTimezoneOffset() * 60000).toISOString().slice(0, 16) : "";
+      const input = el("input", Object.assign(attributes, { type: "datetime-local", value: local }));
+      return { input, read: () => (input.value === "" ? null : new Date(input.value).toISOString()) };
+    }
+    case "date": {
+      const input = el("input", Object.assign(attributes, { type: "date", value: value ? String(value).slice(0, 10) : "" }));
+      return { input, read: () => input.value || null };
+    }
+    case "text":
+    case "json":
+    case "object": {
+      const json = field.type !== "text";
+      const input = el("textarea", attributes);
+      input.value = json && value !== null && value !== undefined ? JSON.stringify(value, null, 2) : display(value);
+      return {
+        input,
+        read: () => {
+          if (input.value.trim() === "") {
+            return null;
+          }
+          return json ? JSON.parse(input.value) : input.value;
+        },
+      };
+    }
+  }
+
+  if (field.type === "relation" && relationType(field) !== "m2o") {
+    // One-to-many and many-to-many values are lists of related IDs
+    const ids = Array.isArray(value) ? value.map((entry) => (entry && entry.id) || entry) : [];
+    const input = el("input", Object.assign(attributes, { value: ids.join(", "), placeholder: "Comma-separated IDs" }));
+    return { input, read: () => input.value.split(",").map((id) => id.trim()).filter(Boolean) };
+  }
+  const input = el("input", Object.assign(attributes, { value: display(value) }));
+  return { input, read: () => (input.value === "" ? null : input.value) };
+}
+
+async function itemPage(slug, id) {
+  const collection = await loadCollection(slug);
+  const isNew = id === "new";
+  const item = isNew ? {} : (await api("GET", "/items/" + encodeURIComponent(slug) + "/" + encodeURIComponent(id))).data || {};
+  const itemPath = "/items/" + encodeURIComponent(slug) + (isNew ? "" : "/" + encodeURIComponent(id));
+  const listHash = "#/collections/" + encodeURIComponent(slug);
+
+  const inputs = {};
+  const errors = {};
+  const formError = el("p", { className: "error" });
+  const labels = collection.fields.map((field) => {
+    const { input, read } = fieldInput(field, item[field.name]);
+    inputs[field.name] = { field, read, original: JSON.stringify(item[field.name] === undefined ? null : item[field.name]) };
+    errors[field.name] = el("span", { className: "field-error" });
+    return el("label", {},
+      field.display_name || field.name,
+      el("span", { className: "hint" }, " " + field.type + (field.is_required ? ", required" : "")),
+      " ", errors[field.name],
+      input);
+  });
+
+  const form = el("form", {
+    className: "card",
+    onsubmit: async (event) => {
+      event.preventDefault();
+      formError.textContent = "";
+      Object.values(errors).forEach((node) => { node.textContent = ""; });
+
+      const data = {};
+      try {
+        for (const [name, { field, read, original }] of Object.entries(inputs)) {
+          if (field.type === "computed" || (field.type === "relation" && relationType(field) === "o2m")) {
+            continue;
+          }
+          const value = read();
+          // New items send what was filled in, updates only what changed
+          if (isNew ? value !== null : JSON.stringify(value) !== original) {
+            data[name] = value;
+          }
+        }
+      } catch (err) {
+        formError.textContent = "Invalid JSON: " + err.message;
+        return;
+      }
+
+      try {
+        const saved = await api(isNew ? "POST" : "PATCH", itemPath, data);
+        toast(isNew ? "Item created" : "Item saved");
+        const savedID = saved.data && saved.data.id;
+        location.hash = savedID ? listHash + "/" + encodeURIComponent(savedID) : listHash;
+        if (!isNew) {
+          route();
+        }
+      } catch (err) {
+        formError.textContent = err.message;
+        for (const [field, message] of Object.entries(err.fields || {})) {
+          if (errors[field]) {
+            errors[field].textContent = message;
+          }
+        }
+      }
+    },
+  },
+  labels,
+  formError,
+  el("div", { className: "actions" },
+    el("button", { type: "submit" }, isNew ? "Create" : "Save"),
+    el("button", { type: "button", className: "secondary", onclick: () => { location.hash = listHash; } }, "Back"),
+    isNew ? null : el("button", {
+      type: "button",
+      className: "danger",
+      onclick: async () => {
+        if (!confirm("Delete this item?")) {
+          return;
+        }
+        try {
+          await api("DELETE", itemPath);
+          toast("Item deleted");
+          location.hash = listHash;
+        } catch (err) {
+          formError.textContent = err.message;
+        }
+      },
+    }, "Delete")));
+
+  render(
+    el("div", { className: "toolbar" }, el("h1", {}, (collection.display_name || collection.name) + (isNew ? ": new item" : ": " + id))),
+    isNew ? null : el("p", { className: "muted" }, "Created " + formatDate(item.created_at) + ", updated " + formatDate(item.updated_at)),
+    form,
+  );
+}
+
+// Roles and permissions
+
+async function permissionsPage(roleName) {
+  const matrix = (await api("GET", "/rbac/matrix")).data;
+  const selected = roleName || (matrix.roles[0] && matrix.roles[0].name);
+  const granted = (selected && matrix.permissions[selected]) || {};
+  const error = el("p", { className: "error" });
+
+  const select = el("select", {
+    onchange: () => { location.hash = "#/permissions/" + encodeURIComponent(select.value); },
+  }, matrix.roles.map((role) => el("option", { value: role.name, selected: role.name === selected }, role.name + (role.parent ? " (inherits " + role.parent + ")" : ""))));
+
+  const cell = (table, action) => {
+    const permission = granted[table] && granted[table][action];
+    const fields = permission && (
+      (action === "read" && permission.read_fields) ||
+      ((action === "create" || action === "update") && permission.write_fields) ||
+      permission.allowed_fields);
+    const details = [];
+    if (permission) {
+      details.push("Fields: " + (fields && fields.length > 0 ? fields.join(", ") : "*"));
+      if (permission.field_filter) {
+        details.push("Rows: " + JSON.stringify(permission.field_filter));
+      }
+    }
+
+    const checkbox = el("input", {
+      type: "checkbox",
+      checked: Boolean(permission),
+      "aria-label": action + " " + table,
+      onchange: async () => {
+        checkbox.disabled = true;
+        error.textContent = "";
+        try {
+          // Granting a new cell gives every field; existing cells keep their fields and rule
+          await api("PUT", "/rbac/matrix", { permissions: { [selected]: { [table]: { [action]: checkbox.checked ? true : null } } } });
+          toast((checkbox.checked ? "Granted " : "Revoked ") + action + " on " + table);
+          permissionsPage(selected).catch(showError);
+        } catch (err) {
+          checkbox.checked = !checkbox.checked;
+          checkbox.disabled = false;
+          error.textContent = err.message;
+        }
+      },
+    });
+    return el("td", { className: "center", title: details.join("\n") },
+      checkbox,
+      permission && (fields && fields.length > 0 && fields[0] !== "*") ? el("span", { className: "muted" }, " fields") : null,
+      permission && permission.field_filter ? el("span", { className: "muted" }, " rows") : null);
+  };
+
+  render(
+    el("div", { className: "toolbar" }, el("h1", {}, "Roles & Permissions"), select),
+    el("p", { className: "muted" }, selected === "admin"
+      ? "Admins may do everything, whatever their permissions say."
+      : "What the selected role may do on each table. Hover a cell for its fields and row rule."),
+    error,
+    el("table", {},
+      el("thead", {}, el("tr", {}, el("th", {}, "Table"), matrix.actions.map((action) => el("th", { className: "center" }, action)))),
+      el("tbody", {}, matrix.tables.map((table) => el("tr", {}, el("td", {}, table), matrix.actions.map((action) => cell(table, action)))))),
+  );
+}
+
+// API keys
+
+async function apiKeysPage(secret) {
+  const body = await api("GET", "/items/api_keys" + query({ limit: 500, sort: "-created_at" }));
+  const error = el("p", { className: "error" });
+
+  const showSecret = (title, result) => {
+    apiKeysPage({ title, key: result.data && result.data.api_key }).catch(showError);
+  };
+
+  const rows = (body.data || []).map((key) => el("tr", {},
+    el("td", {}, key.name),
+    el("td", {}, key.is_active ? "Active" : "Inactive"),
+    el("td", {}, formatDate(key.expires_at) + (key.expires_soon ? " (soon)" : "")),
+    el("td", {}, formatDate(key.last_used_at) || "Never"),
+    el("td", {},
+      el("button", {
+        type: "button",
+        className: "secondary",
+        onclick: async () => {
+          try {
+            showSecret("New secret of " + key.name, await api("POST", "/items/api_keys/" + encodeURIComponent(key.id) + "/rotate"));
+          } catch (err) {
+            error.textContent = err.message;
+          }
+        },
+      }, "Rotate"),
+      " ",
+      el("button", {
+        type: "button",
+        className: "danger",
+        onclick: async () => {
+          if (!confirm("Delete the API key " + key.name + "?")) {
+            return;
+          }
+          try {
+            await api("DELETE", "/items/api_keys/" + encodeURIComponent(key.id));
+            toast("API key deleted");
+            apiKeysPage().catch(showError);
+          } catch (err) {
+            error.textContent = err.message;
+          }
+        },
+      }, "Delete"))));
+
+  const form = el("form", {
+    className: "card",
+    onsubmit: async (event) => {
+      event.preventDefault();
+      const data = new FormData(form);
+      const expires = data.get("expires_at");
+      try {
+        showSecret("New API key " + data.get("name"), await api("POST", "/items/api_keys", {
+          name: data.get("name"),
+          expires_at: expires ? new Date(expires + "T00:00:00").toISOString() : undefined,
+        }));
+      } catch (err) {
+        error.textContent = err.message;
+      }
+    },
+  },
+  el("label", {}, "Name", el("input", { name: "name", required: true })),
+  el("label", {}, "Expires ", el("span", { className: "hint" }, "(default: in a year)"), el("input", { name: "expires_at", type: "date" })),
+  el("button", { type: "submit" }, "Create API key"));
+
+  render(
+    el("div", { className: "toolbar" }, el("h1", {}, "API Keys")),
+    secret && secret.key ? el("div", { className: "secret" }, el("strong", {}, secret.title + ": "), secret.key, el("p", {}, "Copy it now, it is not shown again.")) : null,
+    error,
+    rows.length === 0
+      ? el("p", { className: "muted" }, "No API keys.")
+      : el("table", {},
+        el("thead", {}, el("tr", {}, el("th", {}, "Name"), el("th", {}, "Status"), el("th", {}, "Expires"), el("th", {}, "Last used"), el("th", {}, ""))),
+        el("tbody", {}, rows)),
+    el("h2", {}, "New API key"),
+    form,
+  );
+}
+
+// Routing
+
+function route() {
+  const [path, search] = (location.hash.slice(1) || "/collections").split("?");
+  const parts = path.split("/").filter(Boolean).map(decodeURIComponent);
+  const params = new URLSearchParams(search || "");
+
+  const signedIn = Boolean(session.token);
+  document.getElementById("header").hidden = !signedIn;
+  document.getElementById("session").textContent = session.tenant;
+  if (!signedIn || parts[0] === "login") {
+    if (signedIn) {
+      location.hash = "#/collections";
+      return;
+    }
+    loginPage();
+    return;
+  }
+  for (const link of document.querySelectorAll("header nav a")) {
+    link.classList.toggle("active", path.startsWith(link.getAttribute("href").slice(1)));
+  }
+
+  let page;
+  switch (parts[0]) {
+    case "collections":
+      if (parts.length === 1) {
+        page = collectionsPage();
+      } else if (parts.length === 2) {
+        page = itemsPage(parts[1], Math.max(0, parseInt(params.get("offset"), 10) || 0));
+      } else {
+        page = itemPage(parts[1], parts[2]);
+      }
+      break;
+    case "permissions":
+      page = permissionsPage(parts[1]);
+      break;
+    case "api-keys":
+      page = apiKeysPage();
+      break;
+    default:
+      location.hash = "#/collections";
+      return;
+  }
+  page.catch(showError);
+}
+
+document.getElementById("logout").addEventListener("click", () => {
+  session.clear();
+  location.hash = "#/login";
+});
+window.addEventListener("hashchange", route);
+route();
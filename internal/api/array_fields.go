@@ -0,0 +1,210 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains array fields: the string[], uuid[] and int[] field types.
+//
+// Array fields store a Postgres array of their element type (TEXT[], UUID[] or BIGINT[])
+// and are written and returned as JSON arrays:
+//
+//	{"name": "tags", "type": "string[]"}
+//	POST /items/products {"tags": ["new", "sale"]}
+//
+// Every element must have the element type, and nulls are refused. validation_rules apply
+// min_items and max_items to the array and the other rules to each element. Item lists
+// filter them with _contains (every value is in the array) and _overlaps (at least one is):
+//
+//	GET /items/products?tags[contains]=new,sale
+//	GET /items/products?filter={"tags":{"_overlaps":["new","sale"]}}
+//
+// Indexed array fields get a GIN index, which serves both operators.
+package api
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// arrayElementTypes maps each array field type to the type of its elements
+var arrayElementTypes = map[string]string{
+	"string[]": "string",
+	"uuid[]":   "uuid",
+	"int[]":    "int",
+}
+
+// arrayColumnTypes maps each array field type to the column type storing it
+var arrayColumnTypes = map[string]string{
+	"string[]": "TEXT[]",
+	"uuid[]":   "UUID[]",
+	"int[]":    "BIGINT[]",
+}
+
+// arrayElementType returns the element type of an array field type, or "" for other types
+func arrayElementType(fieldType string) string {
+	return arrayElementTypes[fieldType]
+}
+
+// parseArrayValue reads the value of an array field, a JSON array or its text, and
+// returns it as the array to store
+func parseArrayValue(fieldType string, value interface{}) (driver.Valuer, error) {
+	if text, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("expected a JSON array: %w", err)
+		}
+	}
+	list, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an array, got %T", value)
+	}
+
+	if arrayElementType(fieldType) == "int" {
+		numbers := make(pq.Int64Array, len(list))
+		for i, element := range list {
+			number, err := arrayInteger(element)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: %w", i, err)
+			}
+			numbers[i] = number
+		}
+		return numbers, nil
+	}
+
+	texts := make(pq.StringArray, len(list))
+	for i, element := range list {
+		text, ok := element.(string)
+		if !ok {
+			return nil, fmt.Errorf("item %d: expected a string, got %T", i, element)
+		}
+		if arrayElementType(fieldType) == "uuid" {
+			id, err := uuid.Parse(text)
+			if err != nil {
+				return nil, fmt.Errorf("item %d: invalid UUID '%s'", i, text)
+			}
+			text = id.String()
+		}
+		texts[i] = text
+	}
+	return texts, nil
+}
+
+// arrayInteger reads an element of an int[] field
+func arrayInteger(element interface{}) (int64, error) {
+	switch v := element.(type) {
+	case float64:
+		if v != math.Trunc(v) || math.Abs(v) > 1<<53 {
+			return 0, fmt.Errorf("expected an integer, got %v", v)
+		}
+		return int64(v), nil
+	case json.Number:
+		return strconv.ParseInt(v.String(), 10, 64)
+	case string:
+		number, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("cannot convert '%s' to integer", v)
+		}
+		return number, nil
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	}
+	return 0, fmt.Errorf("expected an integer, got %T", element)
+}
+
+// decodeArrayColumn decodes the text of a Postgres array read from a column of dbType, as
+// reported by the driver ("_TEXT", "_INT8", ...). It reports false for other columns.
+func decodeArrayColumn(dbType string, raw []byte) (interface{}, bool) {
+	switch dbType {
+	case "_TEXT", "_VARCHAR", "_UUID":
+		var texts pq.StringArray
+		if err := texts.Scan(raw); err != nil {
+			return nil, false
+		}
+		return []string(texts), true
+	case "_INT2", "_INT4", "_INT8":
+		var numbers pq.Int64Array
+		if err := numbers.Scan(raw); err != nil {
+			return nil, false
+		}
+		return []int64(numbers), true
+	}
+	return nil, false
+}
+
+// decodeColumnValue converts a value scanned from a column of dbType for JSON: arrays to
+// slices, JSON to its value and other text to strings
+func decodeColumnValue(dbType string, value interface{}) interface{} {
+	raw, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	if array, ok := decodeArrayColumn(dbType, raw); ok {
+		return array
+	}
+	var jsonValue interface{}
+	if err := json.Unmarshal(raw, &jsonValue); err == nil {
+		return jsonValue
+	}
+	return string(raw)
+}
+
+// arrayFields returns the array fields of a collection by name with their field types
+func (d *DynamicHandlers) arrayFields(ctx context.Context, tenantID uuid.UUID, collectionSlug string) (map[string]string, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT f.name, f.type
+		FROM fields f
+		JOIN collections c ON c.id = f.collection_id
+		WHERE c.slug = $1 AND c.tenant_id = $2 AND f.type = ANY($3)
+	`, collectionSlug, tenantID, pq.Array([]string{"string[]", "uuid[]", "int[]"}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch array fields: %w", err)
+	}
+	defer rows.Close()
+
+	fields := make(map[string]string)
+	for rows.Next() {
+		var name, fieldType string
+		if err := rows.Scan(&name, &fieldType); err != nil {
+			return nil, fmt.Errorf("failed to scan array field: %w", err)
+		}
+		fields[name] = fieldType
+	}
+	return fields, rows.Err()
+}
+
+// encodeStoredValues prepares stored values read back from a revision or an archive, such
+// as the values of a revert, to be written again: arrays of array fields become Postgres
+// arrays, other nested values JSON text (see encodeJSONValues) and values of encrypted
+// fields that are not encrypted yet are encrypted
+func (d *DynamicHandlers) encodeStoredValues(ctx context.Context, tenantID uuid.UUID, collectionSlug string, data map[string]interface{}) (map[string]interface{}, error) {
+	fields, err := d.arrayFields(ctx, tenantID, collectionSlug)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := d.encryptedFields(ctx, tenantID, collectionSlug)
+	if err != nil {
+		return nil, err
+	}
+	if data, err = encryptValues(encrypted, data); err != nil {
+		return nil, err
+	}
+
+	encoded := encodeJSONValues(data)
+	for name, fieldType := range fields {
+		value, ok := data[name]
+		if !ok || value == nil {
+			continue
+		}
+		array, err := parseArrayValue(fieldType, value)
+		if err != nil {
+			return nil, validationError("field '%s': %w", name, err)
+		}
+		encoded[name] = array
+	}
+	return encoded, nil
+}
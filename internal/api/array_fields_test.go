@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseArrayValue(t *testing.T) {
+	tags, err := parseArrayValue("string[]", []interface{}{"new", "sale"})
+	require.NoError(t, err)
+	assert.Equal(t, pq.StringArray{"new", "sale"}, tags)
+
+	ids, err := parseArrayValue("uuid[]", `["0B6C1F1E-2F0A-4A8E-9D55-3F1C2B7A9E10"]`)
+	require.NoError(t, err)
+	assert.Equal(t, pq.StringArray{"0b6c1f1e-2f0a-4a8e-9d55-3f1c2b7a9e10"}, ids)
+
+	numbers, err := parseArrayValue("int[]", []interface{}{float64(1), json.Number("2"), "3"})
+	require.NoError(t, err)
+	assert.Equal(t, pq.Int64Array{1, 2, 3}, numbers)
+
+	empty, err := parseArrayValue("int[]", []interface{}{})
+	require.NoError(t, err)
+	assert.Equal(t, pq.Int64Array{}, empty)
+
+	for fieldType, value := range map[string]interface{}{
+		"string[]": []interface{}{"new", nil},
+		"uuid[]":   []interface{}{"not-a-uuid"},
+		"int[]":    []interface{}{1.5},
+	} {
+		_, err := parseArrayValue(fieldType, value)
+		assert.Error(t, err, fieldType)
+	}
+	_, err = parseArrayValue("string[]", "new")
+	assert.Error(t, err)
+}
+
+func TestDecodeColumnValue(t *testing.T) {
+	assert.Equal(t, []string{"new", "on sale"}, decodeColumnValue("_TEXT", []byte(`{new,"on sale"}`)))
+	assert.Equal(t, []int64{1, 2}, decodeColumnValue("_INT8", []byte(`{1,2}`)))
+	assert.Equal(t, map[string]interface{}{"a": float64(1)}, decodeColumnValue("JSONB", []byte(`{"a": 1}`)))
+	assert.Equal(t, "plain", decodeColumnValue("TEXT", []byte("plain")))
+	assert.Equal(t, int64(5), decodeColumnValue("INT8", int64(5)))
+}
+
+func TestArrayFieldValidation(t *testing.T) {
+	handler := &CollectionsHandler{}
+	field := CollectionField{
+		Type: "string[]",
+		Validation: map[string]interface{}{
+			"max_items": float64(2),
+			"enum":      []interface{}{"new", "sale", "used"},
+		},
+	}
+
+	assert.NoError(t, handler.applyFieldValidation(field, []interface{}{"new", "sale"}))
+
+	err := handler.applyFieldValidation(field, []interface{}{"new", "sale", "used"})
+	var violation *ruleViolation
+	require.True(t, errors.As(err, &violation))
+	assert.Equal(t, "max_items", violation.rule)
+
+	err = handler.applyFieldValidation(field, []interface{}{"new", "old"})
+	require.True(t, errors.As(err, &violation))
+	assert.Equal(t, "enum", violation.rule)
+	assert.Contains(t, err.Error(), "item 1")
+}
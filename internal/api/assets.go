@@ -0,0 +1,538 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the assets endpoints: uploading files, serving them (optionally resized)
+// and deleting them.
+//
+// Asset metadata lives in the assets table and can be listed and edited like any other schema
+// table (GET /items/assets, PUT /items/assets/:id). The file bytes live in the configured
+// storage backend (local disk or S3) under "<tenant_id>/<asset_id><ext>", so tenants never
+// share storage keys. Collections reference assets through fields of type "file".
+//
+// Image assets (JPEG, PNG, GIF) can be resized on the fly:
+//
+//	GET /assets/:id?width=300&height=200&fit=cover
+package api
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/imaging"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/quota"
+	"go-rbac-api/internal/rbac"
+	"go-rbac-api/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	// maxTransformPixels guards against decompression bombs when resizing
+	maxTransformPixels = 50_000_000
+	// multipartOverhead allows for the multipart framing around the uploaded file
+	multipartOverhead = 1 << 20
+)
+
+// extensionPattern limits stored file extensions to short alphanumeric suffixes
+var extensionPattern = regexp.MustCompile(`^\.[a-z0-9]{1,10}$`)
+
+// AssetsHandler handles file uploads and downloads
+type AssetsHandler struct {
+	db            *db.DB
+	cfg           *config.Config
+	storage       storage.Storage
+	policyChecker *rbac.PolicyChecker
+	utils         *ItemsUtils
+	events        *events.Bus
+	quota         *quota.Checker
+}
+
+// NewAssetsHandler creates an AssetsHandler storing files in store
+func NewAssetsHandler(db *db.DB, cfg *config.Config, store storage.Storage, bus *events.Bus) *AssetsHandler {
+	return &AssetsHandler{
+		db:            db,
+		cfg:           cfg,
+		storage:       store,
+		policyChecker: rbac.NewPolicyChecker(db.Reader().Queries),
+		utils:         NewItemsUtils(db),
+		events:        bus,
+		quota:         quota.NewChecker(db, quota.Defaults(cfg)),
+	}
+}
+
+// UploadAsset handles POST /assets requests.
+//
+// The request is multipart/form-data with the file in the "file" part and an optional
+// "title". The MIME type is detected from the file contents.
+//
+// Response Format:
+//   - 201: Created asset metadata
+//   - 400: Missing or unreadable file
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks create permission on assets, or the tenant's storage limit is reached
+//   - 413: File exceeds ASSET_MAX_UPLOAD_SIZE
+//
+// @Summary      Upload an asset
+// @Tags         assets
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Accept       mpfd
+// @Produce      json
+// @Param        file  formData file   true  "File to upload"
+// @Param        title formData string false "Title"
+// @Success      201 {object} models.CreateItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      413 {object} models.ErrorResponse
+// @Router       /assets [post]
+func (h *AssetsHandler) UploadAsset(c *gin.Context) {
+	userID, allowedFields, ok := h.authorize(c, "create")
+	if !ok {
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.AssetMaxUploadSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the maximum size of %d bytes", h.cfg.AssetMaxUploadSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required in the 'file' form field"})
+		return
+	}
+	if fileHeader.Size > h.cfg.AssetMaxUploadSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the maximum size of %d bytes", h.cfg.AssetMaxUploadSize)})
+		return
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+	defer file.Close()
+
+	contentType, err := detectContentType(file, fileHeader)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return
+	}
+
+	// Record image dimensions so clients can lay out images before loading them
+	var width, height sql.NullInt32
+	if imaging.Supported(contentType) {
+		if w, h, err := imaging.Dimensions(file); err == nil {
+			width = sql.NullInt32{Int32: int32(w), Valid: true}
+			height = sql.NullInt32{Int32: int32(h), Valid: true}
+		}
+		if _, err := file.Seek(0, io.SeekStart); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read uploaded file"})
+			return
+		}
+	}
+
+	tenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+	if err := h.quota.CheckStorage(c.Request.Context(), tenantID, fileHeader.Size); err != nil {
+		if !respondQuotaExceeded(c, err) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check the storage limit"})
+		}
+		return
+	}
+
+	assetID := uuid.New()
+	filename := sanitizeFilename(fileHeader.Filename)
+	key := storageKey(tenantID, assetID, filename)
+
+	if err := h.storage.Put(c.Request.Context(), key, file, fileHeader.Size, contentType); err != nil {
+		log.Printf("assets: failed to store %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store file"})
+		return
+	}
+
+	title := strings.TrimSpace(c.PostForm("title"))
+	asset, err := h.db.Queries.CreateAsset(c.Request.Context(), sqlc.CreateAssetParams{
+		ID:               assetID,
+		TenantID:         tenantID,
+		Storage:          h.storage.Name(),
+		StorageKey:       key,
+		FilenameDownload: filename,
+		Title:            sql.NullString{String: title, Valid: title != ""},
+		Type:             contentType,
+		Filesize:         fileHeader.Size,
+		Width:            width,
+		Height:           height,
+		UploadedBy:       uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		// Don't leave an orphaned file behind
+		h.storage.Delete(context.WithoutCancel(c.Request.Context()), key)
+		respondError(c, err, "Failed to create asset")
+		return
+	}
+
+	result := assetToMap(asset)
+	h.publish(c.Request.Context(), events.ItemCreate, userID, tenantID, asset.ID.String(), result)
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": h.policyChecker.FilterFields(result, allowedFields, nil),
+		"meta": gin.H{"table": "assets"},
+	})
+}
+
+// GetAsset handles GET /assets/:id requests.
+//
+// The file is served inline with its stored MIME type. Image assets accept resize
+// parameters; the result is always JPEG for JPEG sources and PNG otherwise.
+//
+// Query Parameters:
+//   - width, height: Target size in pixels (1-4096); one may be omitted to keep the aspect ratio
+//   - fit: cover (default), contain, inside or fill
+//   - quality: JPEG quality 1-100 (default 85)
+//   - download: true to serve as an attachment
+//
+// Response Format:
+//   - 200: File contents
+//   - 400: Invalid resize parameters or asset is not a resizable image
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on assets
+//   - 404: Asset not found
+//
+// @Summary      Download an asset
+// @Tags         assets
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Produce      octet-stream
+// @Param        id           path  string true  "Asset ID"
+// @Param        width        query int    false "Resize width in pixels"
+// @Param        height       query int    false "Resize height in pixels"
+// @Param        fit          query string false "cover, contain, inside or fill"
+// @Param        quality      query int    false "JPEG quality (1-100)"
+// @Param        download     query bool   false "Serve as attachment"
+// @Param        access_token query string false "Access token for clients that cannot set headers (<img> tags)"
+// @Success      200 {file} binary
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /assets/{id} [get]
+func (h *AssetsHandler) GetAsset(c *gin.Context) {
+	userID, _, ok := h.authorize(c, "read")
+	if !ok {
+		return
+	}
+
+	asset, ok := h.getTenantAsset(c, userID)
+	if !ok {
+		return
+	}
+
+	opts, transform, err := parseTransformOptions(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if transform {
+		if !imaging.Supported(asset.Type) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Only JPEG, PNG and GIF images can be resized"})
+			return
+		}
+		if int64(asset.Width.Int32)*int64(asset.Height.Int32) > maxTransformPixels {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Image is too large to resize"})
+			return
+		}
+	}
+
+	reader, err := h.storage.Get(c.Request.Context(), asset.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Asset file not found"})
+			return
+		}
+		log.Printf("assets: failed to read %s: %v", asset.StorageKey, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer reader.Close()
+
+	disposition := "inline"
+	if download, _ := strconv.ParseBool(c.Query("download")); download {
+		disposition = "attachment"
+	}
+
+	headers := map[string]string{
+		"Content-Disposition":     mime.FormatMediaType(disposition, map[string]string{"filename": asset.FilenameDownload}),
+		"Cache-Control":           "private, max-age=86400",
+		"X-Content-Type-Options":  "nosniff",
+		"Content-Security-Policy": "sandbox", // Uploaded HTML/SVG must not run scripts on our origin
+	}
+
+	if !transform {
+		c.DataFromReader(http.StatusOK, asset.Filesize, asset.Type, reader, headers)
+		return
+	}
+
+	data, contentType, err := imaging.Transform(reader, asset.Type, opts)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	c.DataFromReader(http.StatusOK, int64(len(data)), contentType, bytes.NewReader(data), headers)
+}
+
+// DeleteAsset handles DELETE /assets/:id requests.
+//
+// The asset row is deleted first; file fields referencing it are cleared by the database.
+// The stored file is removed afterwards.
+//
+// Response Format:
+//   - 200: Asset deleted
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks delete permission on assets
+//   - 404: Asset not found
+//
+// @Summary      Delete an asset
+// @Tags         assets
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Param        id path string true "Asset ID"
+// @Success      200 {object} models.DeleteItemResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /assets/{id} [delete]
+func (h *AssetsHandler) DeleteAsset(c *gin.Context) {
+	userID, _, ok := h.authorize(c, "delete")
+	if !ok {
+		return
+	}
+
+	asset, ok := h.getTenantAsset(c, userID)
+	if !ok {
+		return
+	}
+
+	if err := h.db.Queries.DeleteAsset(c.Request.Context(), asset.ID); err != nil {
+		respondError(c, err, "Failed to delete asset")
+		return
+	}
+
+	if err := h.storage.Delete(c.Request.Context(), asset.StorageKey); err != nil {
+		// The metadata is gone, so the file is unreachable; log it for cleanup
+		log.Printf("assets: failed to delete file %s: %v", asset.StorageKey, err)
+	}
+
+	h.publish(c.Request.Context(), events.ItemDelete, userID, asset.TenantID, asset.ID.String(), nil)
+
+	c.JSON(http.StatusOK, gin.H{
+		"meta": gin.H{"table": "assets", "id": asset.ID.String()},
+	})
+}
+
+// authorize checks the user's permission for action on assets and writes the error
+// response if it is missing
+func (h *AssetsHandler) authorize(c *gin.Context, action string) (uuid.UUID, []string, bool) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return uuid.Nil, nil, false
+	}
+
+	// Get tenant context from the request
+	tenantID, _ := middleware.GetTenantID(c)
+
+	// Create a context with tenant information
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, allowedFields, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, "assets", action)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return uuid.Nil, nil, false
+	}
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return uuid.Nil, nil, false
+	}
+
+	return userID, allowedFields, true
+}
+
+// getTenantAsset loads the asset named in the URL and makes sure it belongs to the
+// user's tenant. Assets of other tenants are reported as not found.
+func (h *AssetsHandler) getTenantAsset(c *gin.Context, userID uuid.UUID) (sqlc.Asset, bool) {
+	assetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid asset ID"})
+		return sqlc.Asset{}, false
+	}
+
+	tenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return sqlc.Asset{}, false
+	}
+
+	asset, err := h.db.Queries.GetAssetByID(c.Request.Context(), assetID)
+	if err != nil || asset.TenantID != tenantID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Asset not found"})
+		return sqlc.Asset{}, false
+	}
+
+	return asset, true
+}
+
+// publish announces an asset change on the event bus
+func (h *AssetsHandler) publish(ctx context.Context, eventType string, userID, tenantID uuid.UUID, assetID string, data map[string]interface{}) {
+	h.events.Publish(ctx, events.Event{
+		Type:       eventType,
+		TenantID:   tenantID,
+		Collection: "assets",
+		Keys:       []string{assetID},
+		Data:       data,
+		UserID:     userID,
+	})
+}
+
+// detectContentType sniffs the MIME type from the file contents, falling back to the
+// type sent by the client when the contents are not recognised. The file is rewound.
+func detectContentType(file multipart.File, header *multipart.FileHeader) (string, error) {
+	buf := make([]byte, 512)
+	n, err := io.ReadFull(file, buf)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		return "", err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return "", err
+	}
+
+	contentType := http.DetectContentType(buf[:n])
+	if contentType == "application/octet-stream" {
+		if declared := header.Header.Get("Content-Type"); declared != "" {
+			contentType = declared
+		}
+	}
+
+	// Drop parameters such as "; charset=utf-8"
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		contentType = mediaType
+	}
+	return contentType, nil
+}
+
+// parseTransformOptions reads the resize parameters. The second return value is false
+// when no resize was requested.
+func parseTransformOptions(c *gin.Context) (imaging.Options, bool, error) {
+	var opts imaging.Options
+
+	for _, param := range []struct {
+		name   string
+		target *int
+	}{
+		{"width", &opts.Width},
+		{"height", &opts.Height},
+		{"quality", &opts.Quality},
+	} {
+		raw := c.Query(param.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 1 {
+			return opts, false, fmt.Errorf("%s must be a positive integer", param.name)
+		}
+		*param.target = value
+	}
+	opts.Fit = c.Query("fit")
+
+	if opts.Width == 0 && opts.Height == 0 {
+		return opts, false, nil
+	}
+	if err := opts.Validate(); err != nil {
+		return opts, false, err
+	}
+	return opts, true, nil
+}
+
+// storageKey builds the tenant-scoped storage key for an asset
+func storageKey(tenantID, assetID uuid.UUID, filename string) string {
+	ext := strings.ToLower(filepath.Ext(filename))
+	if !extensionPattern.MatchString(ext) {
+		ext = ""
+	}
+	return tenantID.String() + "/" + assetID.String() + ext
+}
+
+// sanitizeFilename strips directories and control characters from a client filename
+func sanitizeFilename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, name)
+
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == "/" {
+		return "file"
+	}
+	if len(name) > 255 {
+		name = name[:255]
+	}
+	return name
+}
+
+// assetToMap converts an asset to its API representation
+func assetToMap(asset sqlc.Asset) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":                asset.ID.String(),
+		"tenant_id":         asset.TenantID.String(),
+		"storage":           asset.Storage,
+		"filename_download": asset.FilenameDownload,
+		"title":             nil,
+		"type":              asset.Type,
+		"filesize":          asset.Filesize,
+		"width":             nil,
+		"height":            nil,
+		"uploaded_by":       nil,
+		"created_at":        asset.CreatedAt.Time,
+		"updated_at":        asset.UpdatedAt.Time,
+	}
+
+	if asset.Title.Valid {
+		result["title"] = asset.Title.String
+	}
+	if asset.Width.Valid {
+		result["width"] = asset.Width.Int32
+		result["height"] = asset.Height.Int32
+	}
+	if asset.UploadedBy.Valid {
+		result["uploaded_by"] = asset.UploadedBy.UUID.String()
+	}
+
+	return result
+}
@@ -0,0 +1,52 @@
+package api
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"go-rbac-api/internal/imaging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSanitizeFilename(t *testing.T) {
+	assert.Equal(t, "photo.jpg", sanitizeFilename("photo.jpg"))
+	assert.Equal(t, "passwd", sanitizeFilename("../../etc/passwd"))
+	assert.Equal(t, "report.pdf", sanitizeFilename(`C:\Users\me\report.pdf`))
+	assert.Equal(t, "name.txt", sanitizeFilename("na\x00me.txt"))
+	assert.Equal(t, "file", sanitizeFilename(""))
+}
+
+func TestStorageKey(t *testing.T) {
+	tenantID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+	assetID := uuid.MustParse("550e8400-e29b-41d4-a716-446655440000")
+
+	assert.Equal(t, tenantID.String()+"/"+assetID.String()+".png", storageKey(tenantID, assetID, "Logo.PNG"))
+	assert.Equal(t, tenantID.String()+"/"+assetID.String(), storageKey(tenantID, assetID, "archive.tar.g$z"))
+	assert.Equal(t, tenantID.String()+"/"+assetID.String(), storageKey(tenantID, assetID, "README"))
+}
+
+func TestParseTransformOptions(t *testing.T) {
+	newContext := func(rawQuery string) *gin.Context {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/assets/1?"+rawQuery, nil)
+		return c
+	}
+
+	_, transform, err := parseTransformOptions(newContext(""))
+	require.NoError(t, err)
+	assert.False(t, transform)
+
+	opts, transform, err := parseTransformOptions(newContext("width=300&fit=contain&quality=70"))
+	require.NoError(t, err)
+	assert.True(t, transform)
+	assert.Equal(t, imaging.Options{Width: 300, Fit: imaging.FitContain, Quality: 70}, opts)
+
+	for _, query := range []string{"width=abc", "width=-5", "height=99999", "width=10&fit=zoom"} {
+		_, _, err := parseTransformOptions(newContext(query))
+		assert.Error(t, err, query)
+	}
+}
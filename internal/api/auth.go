@@ -1,14 +1,20 @@
 package api
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
 	"database/sql"
 	"net/http"
+	"strings"
 
+	"go-rbac-api/internal/audit"
 	"go-rbac-api/internal/config"
 	"go-rbac-api/internal/db"
 	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/mail"
 	"go-rbac-api/internal/middleware"
 	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/rbac"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -19,16 +25,28 @@ type AuthHandler struct {
 	db           *db.DB
 	cfg          *config.Config
 	authProvider *AuthProviderService
+	audit        *audit.Logger
+	mailer       *mail.Mailer
 }
 
-func NewAuthHandler(db *db.DB, cfg *config.Config) *AuthHandler {
+func NewAuthHandler(db *db.DB, cfg *config.Config, mailer *mail.Mailer) *AuthHandler {
 	return &AuthHandler{
 		db:           db,
 		cfg:          cfg,
 		authProvider: NewAuthProviderService(db, cfg),
+		audit:        audit.NewLogger(db),
+		mailer:       mailer,
 	}
 }
 
+// purposeKey derives a signing key from the JWT secret for tokens that are no access
+// tokens (password resets, 2FA challenges, ...), so neither kind can stand in for the other
+func purposeKey(secret, purpose string) []byte {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(purpose))
+	return mac.Sum(nil)
+}
+
 // Login handles POST /auth/login requests
 // @Summary      Login
 // @Tags         auth
@@ -41,39 +59,81 @@ func NewAuthHandler(db *db.DB, cfg *config.Config) *AuthHandler {
 // @Router       /auth/login [post]
 func (h *AuthHandler) Login(c *gin.Context) {
 	var loginReq models.LoginRequest
-	if err := c.ShouldBindJSON(&loginReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	if err := decodeJSON(c, &loginReq, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body")
 		return
 	}
 
+	// On a tenant's domain the login is for that tenant
+	if tenant, ok := middleware.GetRequestTenant(c); ok && loginReq.TenantSlug == "" {
+		loginReq.TenantSlug = tenant.Slug
+	}
+
 	// Get user from database
 	user, err := h.db.Queries.GetUserByEmail(c.Request.Context(), loginReq.Email)
 	if err != nil {
+		h.auditLoginFailure(c, loginReq.Email, uuid.Nil, "unknown email")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
+		return
+	}
+
+	// Service accounts only authenticate with their API keys
+	if user.IsServiceAccount {
+		h.auditLoginFailure(c, loginReq.Email, user.ID, "service account")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
 	// Check if user is active
 	if !user.IsActive.Bool {
+		h.auditLoginFailure(c, loginReq.Email, user.ID, "account disabled")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled"})
 		return
 	}
 
 	// Verify password
 	if !models.CheckPassword(loginReq.Password, user.PasswordHash) {
+		h.auditLoginFailure(c, loginReq.Email, user.ID, "wrong password")
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// With 2FA the password only earns a challenge for the second factor
+	twoFactor, err := middleware.TwoFactorEnabled(c.Request.Context(), h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor authentication"})
+		return
+	}
+	if twoFactor {
+		challenge, expiresAt, err := signTwoFactorChallenge(h.cfg.JWTSecret, user.ID, loginReq.TenantSlug)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		c.JSON(http.StatusOK, models.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresAt:         expiresAt,
+		})
+		return
+	}
+
+	h.completeLogin(c, user, loginReq.TenantSlug, nil)
+}
+
+// completeLogin issues the token of an authenticated user for the requested tenant, or
+// the user's default tenant, and records the login. details are added to the audit entry.
+func (h *AuthHandler) completeLogin(c *gin.Context, user sqlc.User, tenantSlug string, details map[string]interface{}) {
 	// Determine tenant context
 	var tenantID uuid.UUID
-	var tenantSlug string
 	var token string
+	var setupRequired bool
 
-	if loginReq.TenantSlug != "" {
+	if tenantSlug != "" {
 		// User specified a tenant, verify they have access
-		tenant, err := h.db.Queries.GetTenantBySlug(c.Request.Context(), loginReq.TenantSlug)
+		tenant, err := h.db.Queries.GetTenantBySlug(c.Request.Context(), tenantSlug)
 		if err != nil {
+			h.auditLoginFailure(c, user.Email, user.ID, "unknown tenant")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid tenant or no access"})
 			return
 		}
@@ -84,12 +144,17 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			TenantID: tenant.ID,
 		})
 		if err != nil {
+			h.auditLoginFailure(c, user.Email, user.ID, "no access to tenant")
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "No access to specified tenant"})
 			return
 		}
+		if !tenant.IsActive.Bool {
+			h.rejectInactiveTenant(c, user)
+			return
+		}
 
 		// Generate tenant-aware token
-		token, err = middleware.GenerateTokenWithTenant(user, tenant, h.cfg)
+		token, setupRequired, err = h.authProvider.IssueToken(c, user, &tenant)
 		if err != nil {
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 			return
@@ -100,9 +165,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	} else {
 		// No tenant specified, try to get user's default tenant
 		defaultTenant, err := h.db.Queries.GetUserDefaultTenant(c.Request.Context(), user.ID)
+		if err == nil && !defaultTenant.IsActive.Bool {
+			h.rejectInactiveTenant(c, user)
+			return
+		}
 		if err == nil {
 			// User has a default tenant, use it
-			token, err = middleware.GenerateTokenWithTenant(user, defaultTenant, h.cfg)
+			token, setupRequired, err = h.authProvider.IssueToken(c, user, &defaultTenant)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 				return
@@ -111,7 +180,7 @@ func (h *AuthHandler) Login(c *gin.Context) {
 			tenantSlug = defaultTenant.Slug
 		} else {
 			// No default tenant, generate regular token
-			token, err = middleware.GenerateToken(user, h.cfg)
+			token, setupRequired, err = h.authProvider.IssueToken(c, user, nil)
 			if err != nil {
 				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 				return
@@ -119,6 +188,13 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		}
 	}
 
+	entry := middleware.NewAuditEntry(c, audit.ActionLogin)
+	entry.UserID = user.ID
+	entry.TenantID = tenantID
+	entry.StatusCode = http.StatusOK
+	entry.Details = details
+	h.audit.Record(c.Request.Context(), entry)
+
 	// Return response
 	c.JSON(http.StatusOK, models.LoginResponse{
 		Token: token,
@@ -133,9 +209,28 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		},
 		TenantID:   tenantID,
 		TenantSlug: tenantSlug,
+
+		PasswordChangeRequired: user.MustChangePassword,
+		TwoFactorSetupRequired: setupRequired,
 	})
 }
 
+// rejectInactiveTenant refuses a sign-in to a suspended or deleted tenant
+func (h *AuthHandler) rejectInactiveTenant(c *gin.Context, user sqlc.User) {
+	h.auditLoginFailure(c, user.Email, user.ID, "tenant inactive")
+	c.JSON(http.StatusForbidden, gin.H{"error": "Tenant is suspended or deleted", "tenant_inactive": true})
+}
+
+// auditLoginFailure records a rejected login. userID is uuid.Nil when the email is unknown.
+func (h *AuthHandler) auditLoginFailure(c *gin.Context, email string, userID uuid.UUID, reason string) {
+	entry := middleware.NewAuditEntry(c, audit.ActionLoginFailed)
+	entry.UserID = userID
+	entry.Result = audit.ResultFailure
+	entry.StatusCode = http.StatusUnauthorized
+	entry.Details = map[string]interface{}{"email": email, "reason": reason}
+	h.audit.Record(c.Request.Context(), entry)
+}
+
 // SwitchTenant handles POST /auth/switch-tenant requests
 // @Summary      Switch Tenant
 // @Tags         auth
@@ -149,32 +244,27 @@ func (h *AuthHandler) Login(c *gin.Context) {
 // @Router       /auth/switch-tenant [post]
 func (h *AuthHandler) SwitchTenant(c *gin.Context) {
 	var switchReq models.SwitchTenantRequest
-	if err := c.ShouldBindJSON(&switchReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+	if err := decodeJSON(c, &switchReq, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body: "+err.Error())
 		return
 	}
 
-	// Switch to the new tenant
-	authProvider, err := h.authProvider.SwitchTenant(c, switchReq.TenantID)
+	// Check access to the new tenant
+	tenant, err := h.authProvider.SwitchTenant(c, switchReq.TenantID)
 	if err != nil {
 		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
 		return
 	}
 
-	// Generate new token with the new tenant context
-	user, err := h.db.Queries.GetUserByID(c.Request.Context(), authProvider.UserID)
+	userID, _ := middleware.GetUserID(c)
+	user, err := h.db.Queries.GetUserByID(c.Request.Context(), userID)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
 		return
 	}
 
-	tenant, err := h.db.Queries.GetTenantByID(c.Request.Context(), switchReq.TenantID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tenant"})
-		return
-	}
-
-	token, err := middleware.GenerateTokenWithTenant(user, tenant, h.cfg)
+	// The new tenant applies to requests made with the returned token
+	token, setupRequired, err := h.authProvider.IssueToken(c, user, tenant)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
 		return
@@ -185,6 +275,8 @@ func (h *AuthHandler) SwitchTenant(c *gin.Context) {
 		TenantID:   tenant.ID,
 		TenantSlug: tenant.Slug,
 		Message:    "Successfully switched to new tenant",
+
+		TwoFactorSetupRequired: setupRequired,
 	})
 }
 
@@ -221,15 +313,7 @@ func (h *AuthHandler) GetUserTenants(c *gin.Context) {
 
 	var response []models.Tenant
 	for _, tenant := range tenants {
-		response = append(response, models.Tenant{
-			ID:        tenant.ID,
-			Name:      tenant.Name,
-			Slug:      tenant.Slug,
-			Domain:    tenant.Domain.String,
-			IsActive:  tenant.IsActive.Bool,
-			CreatedAt: tenant.CreatedAt.Time,
-			UpdatedAt: tenant.UpdatedAt.Time,
-		})
+		response = append(response, tenantModel(tenant))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -270,6 +354,98 @@ func (h *AuthHandler) Me(c *gin.Context) {
 	})
 }
 
+// ChangePassword handles POST /auth/change-password requests. It is the only route besides
+// /auth/me open to users who must change their password, and returns a new token without
+// that restriction.
+// @Summary      Change password
+// @Tags         auth
+// @Security     BearerAuth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   models.ChangePasswordRequest true "Current and new password"
+// @Success      200   {object} models.ChangePasswordResponse
+// @Failure      400   {object} map[string]string
+// @Failure      401   {object} map[string]string
+// @Router       /auth/change-password [post]
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := decodeJSON(c, &req, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.db.Queries.GetUserByID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if !models.CheckPassword(req.CurrentPassword, user.PasswordHash) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+	if req.NewPassword == req.CurrentPassword {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "New password must differ from the current password"})
+		return
+	}
+	if err := models.NewPasswordPolicy(h.cfg).Validate(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	passwordHash, err := models.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+	if err := h.db.Queries.SetUserPassword(ctx, sqlc.SetUserPasswordParams{
+		ID:           user.ID,
+		PasswordHash: passwordHash,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
+		return
+	}
+	user.MustChangePassword = false
+
+	// Sessions started with the old password end; the caller continues with a new one
+	if err := h.db.Queries.RevokeUserSessions(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke sessions"})
+		return
+	}
+
+	// Keep the tenant of the current token
+	var token string
+	if tenantID, ok := middleware.GetTenantID(c); ok && tenantID != uuid.Nil {
+		tenant, err := h.db.Queries.GetTenantByID(ctx, tenantID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tenant"})
+			return
+		}
+		token, _, err = h.authProvider.IssueToken(c, user, &tenant)
+	} else {
+		token, _, err = h.authProvider.IssueToken(c, user, nil)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionPasswordChange)
+	entry.StatusCode = http.StatusOK
+	h.audit.Record(ctx, entry)
+
+	c.JSON(http.StatusOK, models.ChangePasswordResponse{
+		Message: "Password changed",
+		Token:   token,
+	})
+}
+
 // SignUp handles POST /auth/signup requests
 // @Summary      Sign Up
 // @Tags         auth
@@ -282,8 +458,17 @@ func (h *AuthHandler) Me(c *gin.Context) {
 // @Router       /auth/signup [post]
 func (h *AuthHandler) SignUp(c *gin.Context) {
 	var signUpReq models.SignUpRequest
-	if err := c.ShouldBindJSON(&signUpReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+	if err := decodeJSON(c, &signUpReq, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body: "+err.Error())
+		return
+	}
+	if tenant, ok := middleware.GetRequestTenant(c); ok && signUpReq.TenantSlug == "" {
+		signUpReq.TenantSlug = tenant.Slug
+	}
+
+	// Enforce the password policy
+	if err := models.NewPasswordPolicy(h.cfg).Validate(signUpReq.Password); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -319,6 +504,7 @@ func (h *AuthHandler) SignUp(c *gin.Context) {
 	}
 
 	// Handle tenant creation or joining if specified
+	var welcomeTenantID uuid.UUID
 	if signUpReq.TenantSlug != "" {
 		// Check if tenant exists
 		tenant, err := h.db.Queries.GetTenantBySlug(c.Request.Context(), signUpReq.TenantSlug)
@@ -341,7 +527,13 @@ func (h *AuthHandler) SignUp(c *gin.Context) {
 		// TODO: Add user to tenant with default role
 		// This requires the AddUserToTenant query to be implemented
 		// For now, we'll just create the tenant and user separately
-		_ = tenant // Use tenant variable to avoid linter error
+		welcomeTenantID = tenant.ID
+	}
+
+	// A failed welcome email does not undo the signup
+	name := strings.TrimSpace(signUpReq.FirstName + " " + signUpReq.LastName)
+	if err := h.mailer.Send(c.Request.Context(), welcomeTenantID, user.Email, mail.TemplateWelcome, mail.Data{Name: name}); err != nil {
+		middleware.GetLogger(c).Error("failed to send welcome email", "user_id", user.ID, "error", err)
 	}
 
 	// Return success response
@@ -379,8 +571,8 @@ func (h *AuthHandler) UpdateUser(c *gin.Context) {
 	}
 
 	var updateReq models.UpdateUserRequest
-	if err := c.ShouldBindJSON(&updateReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	if err := decodeJSON(c, &updateReq, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body")
 		return
 	}
 
@@ -458,6 +650,7 @@ func (h *AuthHandler) DeleteUser(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
+	rbac.InvalidateUserPermissions(userID)
 
 	c.JSON(http.StatusOK, models.DeleteUserResponse{
 		Message: "User deleted successfully",
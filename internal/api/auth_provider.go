@@ -1,6 +1,7 @@
 package api
 
 import (
+	"database/sql"
 	"fmt"
 	"net/http"
 	"time"
@@ -28,23 +29,81 @@ func NewAuthProviderService(db *db.DB, cfg *config.Config) *AuthProviderService
 	}
 }
 
-// CreateSession creates a new tenant-scoped authentication session
-func (s *AuthProviderService) CreateSession(userID, tenantID uuid.UUID) (*middleware.Session, error) {
-	sessionID := uuid.New().String()
-	expiresAt := time.Now().Add(s.cfg.JWTExpiry)
+// CreateSession stores a new authentication session of the user, scoped to a tenant
+// unless tenantID is uuid.Nil. Every token is issued for a session.
+func (s *AuthProviderService) CreateSession(c *gin.Context, userID, tenantID uuid.UUID) (*middleware.Session, error) {
+	return s.createSession(c, userID, tenantID, time.Now().Add(s.cfg.JWTExpiry))
+}
 
-	// Store session in database (you could add a sessions table for this)
-	// For now, we'll just return the session object
-	session := &middleware.Session{
-		ID:        sessionID,
+// createSession stores a session that ends at expiresAt
+func (s *AuthProviderService) createSession(c *gin.Context, userID, tenantID uuid.UUID, expiresAt time.Time) (*middleware.Session, error) {
+	record, err := s.db.Queries.CreateSession(c.Request.Context(), sqlc.CreateSessionParams{
 		UserID:    userID,
-		TenantID:  tenantID,
-		CreatedAt: time.Now(),
+		TenantID:  uuid.NullUUID{UUID: tenantID, Valid: tenantID != uuid.Nil},
+		UserAgent: sql.NullString{String: c.Request.UserAgent(), Valid: c.Request.UserAgent() != ""},
+		Ip:        sql.NullString{String: c.ClientIP(), Valid: c.ClientIP() != ""},
 		ExpiresAt: expiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+
+	return &middleware.Session{
+		ID:        record.ID.String(),
+		UserID:    record.UserID,
+		TenantID:  record.TenantID.UUID,
+		CreatedAt: record.CreatedAt.Time,
+		ExpiresAt: record.ExpiresAt,
 		IsActive:  true,
+	}, nil
+}
+
+// IssueToken creates a session and its access token for the user, with or without a
+// tenant. Users of a tenant that requires 2FA who have not enabled it get a token that
+// can only enable it; setupRequired reports that.
+func (s *AuthProviderService) IssueToken(c *gin.Context, user sqlc.User, tenant *sqlc.Tenant) (token string, setupRequired bool, err error) {
+	if tenant == nil {
+		session, err := s.CreateSession(c, user.ID, uuid.Nil)
+		if err != nil {
+			return "", false, err
+		}
+		token, err = middleware.GenerateToken(user, session, s.cfg)
+		return token, false, err
 	}
 
-	return session, nil
+	if middleware.TenantRequiresTwoFactor(*tenant) {
+		enabled, err := middleware.TwoFactorEnabled(c.Request.Context(), s.db, user.ID)
+		if err != nil {
+			return "", false, err
+		}
+		setupRequired = !enabled
+	}
+	session, err := s.CreateSession(c, user.ID, tenant.ID)
+	if err != nil {
+		return "", false, err
+	}
+	if setupRequired {
+		token, err = middleware.GenerateTwoFactorSetupToken(user, *tenant, session, s.cfg)
+		return token, true, err
+	}
+	token, err = middleware.GenerateTokenWithTenant(user, *tenant, session, s.cfg)
+	return token, false, err
+}
+
+// IssueImpersonationToken creates a session of the user and a token for it that lets the
+// instance admin impersonator act as the user in the given tenant, which may be nil. The
+// session lasts ImpersonationExpiry and is listed among the user's sessions.
+func (s *AuthProviderService) IssueImpersonationToken(c *gin.Context, user sqlc.User, tenant *sqlc.Tenant, impersonator uuid.UUID) (string, *middleware.Session, error) {
+	tenantID := uuid.Nil
+	if tenant != nil {
+		tenantID = tenant.ID
+	}
+	session, err := s.createSession(c, user.ID, tenantID, time.Now().Add(s.cfg.ImpersonationExpiry))
+	if err != nil {
+		return "", nil, err
+	}
+	token, err := middleware.GenerateImpersonationToken(user, tenant, impersonator, session, s.cfg)
+	return token, session, err
 }
 
 // GetSession retrieves the current session from the context
@@ -82,8 +141,10 @@ func (s *AuthProviderService) GetCurrentTenant(c *gin.Context) (*sqlc.Tenant, er
 	return &tenant, nil
 }
 
-// SwitchTenant allows a user to switch to a different tenant they have access to
-func (s *AuthProviderService) SwitchTenant(c *gin.Context, newTenantID uuid.UUID) (*middleware.AuthProvider, error) {
+// SwitchTenant checks that the current user may switch to a different tenant and returns
+// it. The request's auth context is left alone: the switch takes effect with the token
+// issued for the new tenant.
+func (s *AuthProviderService) SwitchTenant(c *gin.Context, newTenantID uuid.UUID) (*sqlc.Tenant, error) {
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
 		return nil, fmt.Errorf("user not authenticated")
@@ -103,54 +164,11 @@ func (s *AuthProviderService) SwitchTenant(c *gin.Context, newTenantID uuid.UUID
 	if err != nil {
 		return nil, fmt.Errorf("failed to get tenant: %w", err)
 	}
-
-	// Get user roles and permissions for the new tenant
-	userRoles, err := s.db.Queries.GetUserRoles(c.Request.Context(), userID)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	if !tenant.IsActive.Bool {
+		return nil, fmt.Errorf("tenant is not active")
 	}
 
-	// Check if user is admin
-	isAdmin := false
-	roles := make([]string, 0, len(userRoles))
-	for _, role := range userRoles {
-		roles = append(roles, role.Name)
-		if role.Name == "admin" {
-			isAdmin = true
-		}
-	}
-
-	// Get user permissions for the new tenant
-	var permissions []string
-	userPermissions, err := s.db.Queries.GetPermissionsByUserAndTenant(c.Request.Context(), sqlc.GetPermissionsByUserAndTenantParams{
-		UserID:   userID,
-		TenantID: uuid.NullUUID{UUID: newTenantID, Valid: true},
-	})
-	if err == nil {
-		permissions = make([]string, 0, len(userPermissions))
-		for _, perm := range userPermissions {
-			permissions = append(permissions, fmt.Sprintf("%s:%s", perm.TableName, perm.Action))
-		}
-	}
-
-	// Create new auth provider
-	authProvider := &middleware.AuthProvider{
-		UserID:      userID,
-		TenantID:    newTenantID,
-		TenantSlug:  tenant.Slug,
-		IsAdmin:     isAdmin,
-		Roles:       roles,
-		Permissions: permissions,
-		SessionID:   uuid.New().String(),
-		ExpiresAt:   time.Now().Add(s.cfg.JWTExpiry),
-	}
-
-	// Update context with new tenant info
-	c.Set("auth", authProvider)
-	c.Set("tenant_id", newTenantID)
-	c.Set("tenant_slug", tenant.Slug)
-
-	return authProvider, nil
+	return &tenant, nil
 }
 
 // GetUserTenants retrieves all tenants the current user has access to
@@ -289,9 +307,10 @@ func (s *AuthProviderService) GetAuthContext(c *gin.Context) (map[string]interfa
 			"expires_at": auth.ExpiresAt,
 		},
 		"auth": map[string]interface{}{
-			"is_admin":    auth.IsAdmin,
-			"roles":       auth.Roles,
-			"permissions": auth.Permissions,
+			"is_admin":       auth.IsAdmin,
+			"is_super_admin": auth.IsSuperAdmin,
+			"roles":          auth.Roles,
+			"permissions":    auth.Permissions,
 		},
 	}
 
@@ -3,15 +3,17 @@ package api
 import (
 	"bytes"
 	"encoding/json"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 
 	"go-rbac-api/internal/config"
-	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/mail"
 	"go-rbac-api/internal/models"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -24,11 +26,11 @@ func TestAuthHandler_SignUp(t *testing.T) {
 		JWTSecret: "test-secret-key",
 	}
 
-	// Create a mock database (this will be nil, but we're just testing the handler structure)
-	var mockDB *db.DB
+	// Create a database handle that is never reachable; we're just testing the handler structure
+	mockDB := newOfflineDB(t)
 
 	// Create the auth handler
-	handler := NewAuthHandler(mockDB, cfg)
+	handler := NewAuthHandler(mockDB, cfg, mail.NewMailer(mail.NewLogSender(slog.Default()), mail.Address{}, nil))
 	assert.NotNil(t, handler)
 
 	// Test invalid request body
@@ -80,11 +82,11 @@ func TestAuthHandler_Login(t *testing.T) {
 		JWTSecret: "test-secret-key",
 	}
 
-	// Create a mock database
-	var mockDB *db.DB
+	// Create a database handle that is never reachable
+	mockDB := newOfflineDB(t)
 
 	// Create the auth handler
-	handler := NewAuthHandler(mockDB, cfg)
+	handler := NewAuthHandler(mockDB, cfg, mail.NewMailer(mail.NewLogSender(slog.Default()), mail.Address{}, nil))
 	assert.NotNil(t, handler)
 
 	// Test handler creation and basic structure
@@ -104,11 +106,11 @@ func TestAuthHandler_Me(t *testing.T) {
 		JWTSecret: "test-secret-key",
 	}
 
-	// Create a mock database
-	var mockDB *db.DB
+	// Create a database handle that is never reachable
+	mockDB := newOfflineDB(t)
 
 	// Create the auth handler
-	handler := NewAuthHandler(mockDB, cfg)
+	handler := NewAuthHandler(mockDB, cfg, mail.NewMailer(mail.NewLogSender(slog.Default()), mail.Address{}, nil))
 	assert.NotNil(t, handler)
 
 	// Test handler creation and basic structure
@@ -118,3 +120,37 @@ func TestAuthHandler_Me(t *testing.T) {
 		assert.Equal(t, cfg, handler.cfg)
 	})
 }
+
+func TestAuthHandler_SwitchTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWTSecret: "test-secret-key"}
+	handler := NewAuthHandler(newOfflineDB(t), cfg, mail.NewMailer(mail.NewLogSender(slog.Default()), mail.Address{}, nil))
+	tenantID := uuid.New()
+
+	var after interface{}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.New())
+		c.Set("tenant_id", tenantID)
+		c.Next()
+		after, _ = c.Get("tenant_id")
+	})
+	router.POST("/auth/switch-tenant", handler.SwitchTenant)
+
+	// A denied switch leaves the request's tenant alone
+	body, _ := json.Marshal(models.SwitchTenantRequest{TenantID: uuid.New()})
+	req := httptest.NewRequest("POST", "/auth/switch-tenant", bytes.NewBuffer(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, tenantID, after)
+
+	req = httptest.NewRequest("POST", "/auth/switch-tenant", bytes.NewBufferString(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
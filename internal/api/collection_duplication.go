@@ -0,0 +1,319 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains collection duplication, which copies a collection's structure and
+// optionally its items into a new collection.
+//
+// Collection Endpoints:
+// - GET  /collections/templates      - List the collection templates (see collection_templates.go)
+// - POST /collections/:id/duplicate  - Copy a collection under a new slug
+//
+// The copy gets every field of the original with the same type, flags and configuration.
+// Many-to-many fields get a junction table of their own named <slug>_<field>; o2m fields
+// are aliases and keep pointing at the same related field. With "include_data": true the
+// items are copied with their IDs, timestamps and many-to-many links, so relations from the
+// copy resolve like those of the original. Computed values are derived again rather than
+// copied.
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DuplicateCollectionRequest is the body of POST /collections/:id/duplicate
+type DuplicateCollectionRequest struct {
+	Slug        string `json:"slug" binding:"required"` // Slug of the copy
+	Name        string `json:"name,omitempty"`          // Name of the copy; defaults to the slug
+	DisplayName string `json:"display_name,omitempty"`  // Defaults to the original's display name with " (copy)"
+	IncludeData bool   `json:"include_data,omitempty"`  // Copy the items too, not only the structure
+}
+
+// duplicateFieldData returns the CreateField data copying field into the collection
+// collectionID with the slug slug. Many-to-many fields get a junction of their own.
+func duplicateFieldData(field sqlc.Field, collectionID uuid.UUID, slug string) map[string]interface{} {
+	data := map[string]interface{}{
+		"collection_id": collectionID.String(),
+		"name":          field.Name,
+		"display_name":  field.DisplayName.String,
+		"type":          field.Type,
+		"is_primary":    field.IsPrimary.Bool,
+		"is_required":   field.IsRequired.Bool,
+		"is_unique":     field.IsUnique.Bool,
+		"is_indexed":    field.IsIndexed,
+		"is_encrypted":  field.IsEncrypted,
+		"pii":           field.Pii.String,
+		"default_value": field.DefaultValue.String,
+		"sort_order":    int(field.SortOrder.Int32),
+	}
+	if rules := decodeSnapshotJSON(field.ValidationRules); rules != nil {
+		data["validation_rules"] = rules
+	}
+	if computed := decodeSnapshotJSON(field.ComputedConfig); computed != nil {
+		data["computed_config"] = computed
+	}
+	if relation, ok := decodeSnapshotJSON(field.RelationConfig).(map[string]interface{}); ok {
+		if GetStringFromMap(relation, "type") == RelationManyToMany {
+			relation["junction"] = slug + "_" + field.Name
+		}
+		data["relation_config"] = relation
+	}
+	return data
+}
+
+// duplicatedColumns returns the columns of a data table that hold copyable values: the
+// system columns the collection's flags give it (see collection_flags.go) and the columns of
+// stored fields. Generated columns are left out, as Postgres derives them again.
+func duplicatedColumns(fields []sqlc.Field, flags collectionFlags) []string {
+	columns := flags.systemColumns()
+	for _, field := range fields {
+		if field.Type == "computed" || isAliasField(field.Type, field.RelationConfig) {
+			continue
+		}
+		columns = append(columns, field.Name)
+	}
+	return columns
+}
+
+// copyRowsStatement returns the statement copying columns from one table to another
+func copyRowsStatement(from, to string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = fmt.Sprintf(`"%s"`, column)
+	}
+	list := strings.Join(quoted, ", ")
+	return fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s`, to, list, list, from)
+}
+
+// DuplicateCollection copies the collection itemID and its fields into a new collection and,
+// with include_data, copies its items and many-to-many links. A copy that fails part way
+// is deleted again.
+func (s *SchemaHandlers) DuplicateCollection(ctx context.Context, userID uuid.UUID, itemID string, req DuplicateCollectionRequest) (map[string]interface{}, error) {
+	sourceID, err := uuid.Parse(itemID)
+	if err != nil {
+		return nil, fmt.Errorf("invalid collection ID: %w", err)
+	}
+	userTenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	source, err := s.handler.db.Queries.GetCollection(ctx, sourceID)
+	if err != nil {
+		return nil, fmt.Errorf("collection not found: %w", err)
+	}
+	if source.TenantID.Valid && source.TenantID.UUID != userTenantID {
+		return nil, fmt.Errorf("unauthorized: collection not accessible")
+	}
+	if source.IsSystem.Bool {
+		return nil, fmt.Errorf("system collections cannot be duplicated")
+	}
+	if !rbac.ValidateTableName(req.Slug) {
+		return nil, fmt.Errorf("invalid collection slug '%s'", req.Slug)
+	}
+
+	fields, err := s.handler.db.Queries.GetFieldsByCollection(ctx, uuid.NullUUID{UUID: sourceID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to load fields: %w", err)
+	}
+
+	name := req.Name
+	if name == "" {
+		name = req.Slug
+	}
+	displayName := req.DisplayName
+	if displayName == "" {
+		displayName = source.DisplayName.String + " (copy)"
+	}
+	copied, err := s.CreateCollection(ctx, userID, map[string]interface{}{
+		"name":         name,
+		"slug":         req.Slug,
+		"display_name": displayName,
+		"description":  source.Description.String,
+		"icon":         source.Icon.String,
+		"soft_delete":  source.SoftDelete,
+		"workflow":     source.Workflow,
+		"timestamps":   source.Timestamps,
+		"user_stamps":  source.UserStamps,
+		"sortable":     source.Sortable,
+	})
+	if err != nil {
+		return nil, err
+	}
+	copyID := GetStringFromMap(copied, "id")
+
+	if err := s.duplicateContents(ctx, userID, userTenantID, source, fields, uuid.MustParse(copyID), req); err != nil {
+		if err := s.DeleteCollection(ctx, userID, copyID); err != nil {
+			return nil, fmt.Errorf("failed to delete partial copy %s: %w", req.Slug, err)
+		}
+		return nil, err
+	}
+
+	copied["fields"] = len(fields)
+	return copied, nil
+}
+
+// duplicateContents creates the fields of the copy and, when asked to, copies the items.
+// Computed fields are created last, once the fields they use exist.
+func (s *SchemaHandlers) duplicateContents(ctx context.Context, userID, tenantID uuid.UUID, source sqlc.Collection, fields []sqlc.Field, copyID uuid.UUID, req DuplicateCollectionRequest) error {
+	for _, computed := range []bool{false, true} {
+		for _, field := range fields {
+			if (field.Type == "computed") != computed {
+				continue
+			}
+			if _, err := s.CreateField(ctx, userID, duplicateFieldData(field, copyID, req.Slug)); err != nil {
+				return fmt.Errorf("failed to copy field %s: %w", field.Name, err)
+			}
+		}
+	}
+	if !req.IncludeData {
+		return nil
+	}
+
+	sourceTable, err := s.utils.ResolveDataTable(ctx, tenantID, source.Slug)
+	if err != nil {
+		return err
+	}
+	copyTable, err := s.utils.ResolveDataTable(ctx, tenantID, req.Slug)
+	if err != nil {
+		return err
+	}
+	tenantSchema, err := s.utils.GetTenantSchema(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	dialect := s.utils.dialect()
+	statements := []string{copyRowsStatement(sourceTable.String(), copyTable.String(), duplicatedColumns(fields, flagsOf(source)))}
+	for _, field := range fields {
+		if field.Type != "relation" {
+			continue
+		}
+		relation, ok := decodeSnapshotJSON(field.RelationConfig).(map[string]interface{})
+		if !ok {
+			continue
+		}
+		from, err := parseRelationConfig(relation)
+		if err != nil || from.Type != RelationManyToMany {
+			continue
+		}
+		to := from
+		to.Junction = req.Slug + "_" + field.Name
+		statements = append(statements, copyRowsStatement(from.JunctionTable(dialect, tenantSchema), to.JunctionTable(dialect, tenantSchema),
+			[]string{from.JunctionField, from.RelatedJunctionField, "sort_order", "created_at"}))
+	}
+
+	// Items and links are copied together so the copy never holds part of the data
+	return s.handler.db.InTransaction(ctx, func(tx *db.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to copy items: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// DuplicateCollection handles POST /collections/:id/duplicate requests.
+//
+// Copies a collection and its fields under a new slug. With "include_data": true the
+// items are copied as well, which requires unrestricted read access to the original.
+//
+// Request Body:
+//
+//	{"slug": "products_archive", "include_data": true}
+//
+// Response Format:
+//   - 201: {"data": collection} of the copy, with "fields" counting the copied fields
+//   - 400: Invalid body or slug, or a field could not be copied
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks create permission on collections and fields, or read access to the items
+//   - 404: Collection not found
+//
+// @Summary      Duplicate a collection
+// @Tags         collections
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Copy a collection's fields, and optionally its items, into a new collection.
+// @Param        id    path  string true "Collection ID"
+// @Param        body  body  api.DuplicateCollectionRequest true "Slug of the copy and whether to copy items"
+// @Accept       json
+// @Produce      json
+// @Success      201 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /collections/{id}/duplicate [post]
+func (h *ItemsHandler) DuplicateCollection(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req DuplicateCollectionRequest
+	if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body: "+err.Error())
+		return
+	}
+
+	collectionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid collection ID"})
+		return
+	}
+	source, err := h.db.Queries.GetCollection(c.Request.Context(), collectionID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Collection not found"})
+		return
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+	for _, table := range []string{"collections", "fields"} {
+		allowed, _, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, table, "create")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions: create on " + table})
+			return
+		}
+	}
+	if req.IncludeData {
+		// Items are copied wholesale, so row filters or field limits would be bypassed
+		allowed, allowedFields, rowFilter, err := h.policyChecker.CheckPermissionWithFilter(ctxWithTenant, userID, source.Slug, "read")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !allowed || rowFilter != nil || !Contains(allowedFields, "*") {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Copying items requires unrestricted read access to " + source.Slug})
+			return
+		}
+	}
+
+	copied, err := h.schemaHandlers.DuplicateCollection(c.Request.Context(), userID, collectionID.String(), req)
+	if err != nil {
+		if respondQuotaExceeded(c, err) {
+			return
+		}
+		status := http.StatusBadRequest
+		if strings.HasPrefix(err.Error(), "unauthorized") || strings.HasPrefix(err.Error(), "collection not found") {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{"data": copied})
+}
@@ -0,0 +1,188 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the collection flags that decide which automatic columns a data table has.
+//
+// Every data table has id and tenant_id. By default it also has created_at/updated_at
+// ("timestamps") and created_by/updated_by ("user_stamps"), which Basin fills in on every
+// write. A collection created with "timestamps": false or "user_stamps": false goes without
+// them; both flags are fixed once the collection exists, since turning them off later would
+// throw away the values. A "sortable" collection has a "sort" integer column for manual
+// (drag-and-drop) ordering: new items are placed last unless they are given a position.
+// Turning sortable on adds the column, numbering the existing items; turning it off keeps it.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+)
+
+// sortColumn is the column holding the position of an item in a sortable collection
+const sortColumn = "sort"
+
+// collectionFlags are the options of a collection that add columns to its data table
+type collectionFlags struct {
+	SoftDelete bool // deleted_at
+	Workflow   bool // status, publish_at, unpublish_at
+	Timestamps bool // created_at, updated_at
+	UserStamps bool // created_by, updated_by
+	Sortable   bool // sort
+}
+
+// defaultCollectionFlags are the flags of tables that are not user collections
+var defaultCollectionFlags = collectionFlags{Timestamps: true, UserStamps: true}
+
+// flagsOf returns the flags of a collection
+func flagsOf(collection sqlc.Collection) collectionFlags {
+	return collectionFlags{
+		SoftDelete: collection.SoftDelete,
+		Workflow:   collection.Workflow,
+		Timestamps: collection.Timestamps,
+		UserStamps: collection.UserStamps,
+		Sortable:   collection.Sortable,
+	}
+}
+
+// systemColumns returns the columns of a data table that are not fields
+func (f collectionFlags) systemColumns() []string {
+	columns := []string{"id", "tenant_id"}
+	if f.Timestamps {
+		columns = append(columns, "created_at", "updated_at")
+	}
+	if f.UserStamps {
+		columns = append(columns, "created_by", "updated_by")
+	}
+	if f.Sortable {
+		columns = append(columns, sortColumn)
+	}
+	if f.SoftDelete {
+		columns = append(columns, "deleted_at")
+	}
+	if f.Workflow {
+		columns = append(columns, workflowColumns...)
+	}
+	return columns
+}
+
+// sortValue reads the position of an item, which must be a whole number
+func sortValue(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) <= math.MaxInt32 {
+			return int64(v), nil
+		}
+	case int:
+		return int64(v), nil
+	case int64:
+		return v, nil
+	case json.Number:
+		if position, err := v.Int64(); err == nil {
+			return position, nil
+		}
+	}
+	return 0, errors.New("must be a whole number")
+}
+
+// droppedColumnStatements returns the statements removing the automatic columns a new
+// collection goes without from the data table the database created for it
+func droppedColumnStatements(table DataTable, flags collectionFlags) []string {
+	var columns []string
+	if !flags.Timestamps {
+		columns = append(columns, "created_at", "updated_at")
+	}
+	if !flags.UserStamps {
+		columns = append(columns, "created_by", "updated_by")
+	}
+
+	statements := make([]string, len(columns))
+	for i, column := range columns {
+		statements[i] = fmt.Sprintf(`ALTER TABLE %s DROP COLUMN IF EXISTS %s`, table, column)
+	}
+	return statements
+}
+
+// sortColumnStatements returns the statements adding the sort column to a data table,
+// numbering the existing items in the order they were created
+func sortColumnStatements(table DataTable, flags collectionFlags) []string {
+	order := "id"
+	if flags.Timestamps {
+		order = "created_at, id"
+	}
+	return []string{
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS %s INTEGER`, table, sortColumn),
+		fmt.Sprintf(`UPDATE %s t SET %s = n.position FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY %s) AS position FROM %s) n WHERE t.id = n.id AND t.%s IS NULL`,
+			table, sortColumn, order, table, sortColumn),
+	}
+}
+
+// applyCollectionFlags shapes the data table of a new collection after its flags. Tables
+// that do not exist yet are skipped.
+func (u *ItemsUtils) applyCollectionFlags(ctx context.Context, tenantID uuid.UUID, collection sqlc.Collection) error {
+	table, err := u.ResolveDataTable(ctx, tenantID, collection.Slug)
+	if errors.Is(err, ErrDataTableNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	flags := flagsOf(collection)
+	statements := droppedColumnStatements(table, flags)
+	if flags.Sortable {
+		statements = append(statements, sortColumnStatements(table, flags)...)
+	}
+	for _, statement := range statements {
+		if _, err := u.exec().ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to apply collection flags: %w", err)
+		}
+	}
+	return nil
+}
+
+// EnableSorting adds the sort column to the data table of a collection. It is safe to call
+// repeatedly; tables that do not exist yet are skipped. Collections with a field named sort
+// are refused.
+func (u *ItemsUtils) EnableSorting(ctx context.Context, tenantID uuid.UUID, collection sqlc.Collection) error {
+	fields, err := u.queries().GetFieldsByCollection(ctx, uuid.NullUUID{UUID: collection.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if field.Name == sortColumn {
+			return validationError("field '%s' clashes with the sort column; rename it first", field.Name)
+		}
+	}
+
+	table, err := u.ResolveDataTable(ctx, tenantID, collection.Slug)
+	if errors.Is(err, ErrDataTableNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, statement := range sortColumnStatements(table, flagsOf(collection)) {
+		if _, err := u.exec().ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to add sort column: %w", err)
+		}
+	}
+	return nil
+}
+
+// collectionFlags returns the flags of a collection. Tables that are not user collections
+// (or a failed lookup) get defaultCollectionFlags.
+func (d *DynamicHandlers) collectionFlags(ctx context.Context, tenantID uuid.UUID, collectionSlug string) collectionFlags {
+	var flags collectionFlags
+	query := `SELECT soft_delete, workflow, timestamps, user_stamps, sortable FROM collections WHERE slug = $1 AND tenant_id = $2`
+	err := d.db.QueryRowContext(ctx, query, collectionSlug, tenantID).
+		Scan(&flags.SoftDelete, &flags.Workflow, &flags.Timestamps, &flags.UserStamps, &flags.Sortable)
+	if err != nil {
+		return defaultCollectionFlags
+	}
+	return flags
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionFlagsSystemColumns(t *testing.T) {
+	assert.Equal(t, []string{"id", "tenant_id", "created_at", "updated_at", "created_by", "updated_by"},
+		defaultCollectionFlags.systemColumns())
+	assert.Equal(t, []string{"id", "tenant_id"}, collectionFlags{}.systemColumns())
+	assert.Equal(t, []string{"id", "tenant_id", "created_at", "updated_at", "sort", "deleted_at"},
+		collectionFlags{Timestamps: true, Sortable: true, SoftDelete: true}.systemColumns())
+}
+
+func TestDroppedColumnStatements(t *testing.T) {
+	table := DataTable{Schema: "acme", Name: "data_posts", Layout: LayoutTenantSchema}
+
+	assert.Empty(t, droppedColumnStatements(table, defaultCollectionFlags))
+	assert.Equal(t, []string{
+		`ALTER TABLE "acme"."data_posts" DROP COLUMN IF EXISTS created_by`,
+		`ALTER TABLE "acme"."data_posts" DROP COLUMN IF EXISTS updated_by`,
+	}, droppedColumnStatements(table, collectionFlags{Timestamps: true}))
+	assert.Len(t, droppedColumnStatements(table, collectionFlags{}), 4)
+}
+
+func TestSortColumnStatements(t *testing.T) {
+	table := DataTable{Schema: "acme", Name: "data_posts", Layout: LayoutTenantSchema}
+
+	statements := sortColumnStatements(table, defaultCollectionFlags)
+	assert.Equal(t, `ALTER TABLE "acme"."data_posts" ADD COLUMN IF NOT EXISTS sort INTEGER`, statements[0])
+	assert.Contains(t, statements[1], "ORDER BY created_at, id")
+	assert.Contains(t, sortColumnStatements(table, collectionFlags{})[1], "ORDER BY id)")
+}
+
+func TestInsertStatementFlags(t *testing.T) {
+	userID := uuid.New()
+	data := map[string]interface{}{"title": "Hello"}
+
+	query, values := insertStatement(`"acme"."data_posts"`, userID, data, defaultCollectionFlags)
+	assert.Equal(t, `INSERT INTO "acme"."data_posts" (created_by, updated_by, "title") VALUES ($1, $2, $3)`, query)
+	assert.Equal(t, []interface{}{userID, userID, "Hello"}, values)
+
+	query, values = insertStatement(`"acme"."data_posts"`, userID, data, collectionFlags{Sortable: true})
+	assert.Equal(t, `INSERT INTO "acme"."data_posts" (sort, "title") VALUES ((SELECT COALESCE(MAX(sort), 0) + 1 FROM "acme"."data_posts"), $1)`, query)
+	assert.Equal(t, []interface{}{"Hello"}, values)
+
+	// A given position is kept
+	query, _ = insertStatement(`"acme"."data_posts"`, userID, map[string]interface{}{"sort": 3}, collectionFlags{Sortable: true})
+	assert.Equal(t, `INSERT INTO "acme"."data_posts" ("sort") VALUES ($1)`, query)
+}
+
+func TestStampAssignments(t *testing.T) {
+	userID := uuid.New()
+
+	assignments, args := stampAssignments(defaultCollectionFlags, userID, 3)
+	assert.Equal(t, []string{"updated_at = CURRENT_TIMESTAMP", "updated_by = $3"}, assignments)
+	assert.Equal(t, []interface{}{userID}, args)
+
+	assignments, args = stampAssignments(collectionFlags{Timestamps: true}, userID, 3)
+	assert.Equal(t, []string{"updated_at = CURRENT_TIMESTAMP"}, assignments)
+	assert.Empty(t, args)
+
+	assignments, _ = stampAssignments(collectionFlags{}, userID, 1)
+	assert.Empty(t, assignments)
+}
+
+func TestSortValue(t *testing.T) {
+	for _, value := range []interface{}{float64(4), 4, int64(4), json.Number("4")} {
+		position, err := sortValue(value)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), position)
+	}
+	for _, value := range []interface{}{1.5, "4", nil, true} {
+		_, err := sortValue(value)
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,162 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the rules collection slugs must follow.
+//
+// The slug of a collection names its data table (data_<slug>) and its /items/:table
+// routes, so it must be a lowercase Postgres identifier that fits the data table name. A
+// collection named after a schema table (users, collections, ...) would be shadowed by it in
+// ItemsHandler routing, so those names are reserved, as are the other tables of the public
+// schema and SQL reserved words. Slugs already used by another collection of the tenant are
+// refused up front instead of failing on the unique constraint.
+//
+// A refused slug is answered with 422 and up to three free alternatives under "suggestions".
+package api
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+)
+
+// maxCollectionSlugLength keeps data_<slug> within Postgres' 63-byte identifier limit
+const maxCollectionSlugLength = 63 - len("data_")
+
+// maxCollectionNameSuggestions caps the alternatives offered for a refused slug
+const maxCollectionNameSuggestions = 3
+
+// collectionSlugPattern is the form of collection slugs
+var collectionSlugPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// sqlReservedWords are the SQL keywords Postgres reserves, which are poor table names even quoted
+var sqlReservedWords = []string{
+	"all", "analyse", "analyze", "and", "any", "array", "as", "asc", "asymmetric", "both", "case",
+	"cast", "check", "collate", "column", "constraint", "create", "current_catalog", "current_date",
+	"current_role", "current_time", "current_timestamp", "current_user", "default", "deferrable",
+	"desc", "distinct", "do", "else", "end", "except", "false", "fetch", "for", "foreign", "from",
+	"grant", "group", "having", "in", "initially", "intersect", "into", "lateral", "leading",
+	"limit", "localtime", "localtimestamp", "not", "null", "offset", "on", "only", "or", "order",
+	"placing", "primary", "references", "returning", "select", "session_user", "some", "symmetric",
+	"table", "then", "to", "trailing", "true", "union", "unique", "user", "using", "variadic",
+	"when", "where", "window", "with",
+}
+
+// CollectionNameError reports a slug a new collection cannot have, with free alternatives
+type CollectionNameError struct {
+	Slug        string   // The refused slug
+	Reason      string   // Why it was refused
+	Suggestions []string // Slugs that would be accepted
+}
+
+func (e *CollectionNameError) Error() string {
+	return fmt.Sprintf("collection slug '%s' %s", e.Slug, e.Reason)
+}
+
+// collectionSlugProblem returns why slug cannot name a collection by its form alone, or ""
+func collectionSlugProblem(slug string) string {
+	switch {
+	case slug == "":
+		return "is empty"
+	case len(slug) > maxCollectionSlugLength:
+		return fmt.Sprintf("is longer than %d characters", maxCollectionSlugLength)
+	case !collectionSlugPattern.MatchString(slug):
+		return "must start with a lowercase letter and contain only lowercase letters, digits and underscores"
+	case strings.HasPrefix(slug, "pg_"):
+		return "uses the pg_ prefix Postgres reserves"
+	case Contains(schemaTableNames, slug):
+		return "is reserved for a schema table"
+	case Contains(sqlReservedWords, slug):
+		return "is a reserved SQL word"
+	}
+	return ""
+}
+
+// normalizeCollectionSlug turns name into the closest slug of the right form
+func normalizeCollectionSlug(name string) string {
+	var b strings.Builder
+	underscore := false
+	for _, char := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case (char >= 'a' && char <= 'z') || (char >= '0' && char <= '9'):
+			b.WriteRune(char)
+			underscore = false
+		case !underscore && b.Len() > 0:
+			b.WriteByte('_')
+			underscore = true
+		}
+	}
+
+	slug := strings.TrimRight(b.String(), "_")
+	if slug == "" {
+		return ""
+	}
+	if slug[0] >= '0' && slug[0] <= '9' {
+		slug = "c_" + slug
+	}
+	if len(slug) > maxCollectionSlugLength {
+		slug = strings.TrimRight(slug[:maxCollectionSlugLength], "_")
+	}
+	return slug
+}
+
+// suggestCollectionSlugs returns up to maxCollectionNameSuggestions slugs close to slug that
+// have the right form and are not taken
+func suggestCollectionSlugs(slug string, taken func(string) bool) []string {
+	base := normalizeCollectionSlug(slug)
+	if base == "" {
+		base = "collection"
+	}
+	candidates := []string{base, base + "_items", "app_" + base, base + "_2", base + "_3"}
+
+	suggestions := []string{}
+	for _, candidate := range candidates {
+		if len(suggestions) == maxCollectionNameSuggestions {
+			break
+		}
+		if candidate == slug || Contains(suggestions, candidate) || collectionSlugProblem(candidate) != "" || taken(candidate) {
+			continue
+		}
+		suggestions = append(suggestions, candidate)
+	}
+	return suggestions
+}
+
+// checkCollectionSlug refuses slugs a new collection of the tenant cannot have: malformed or
+// reserved ones, the names of public tables and slugs of existing collections
+func (s *SchemaHandlers) checkCollectionSlug(ctx context.Context, tenantID uuid.UUID, slug string) error {
+	reason := collectionSlugProblem(slug)
+	if reason == "" && s.publicTableExists(ctx, slug) {
+		reason = "is reserved for a system table"
+	}
+	if reason == "" && s.collectionExists(ctx, tenantID, slug) {
+		reason = "is already used by another collection"
+	}
+	if reason == "" {
+		return nil
+	}
+
+	taken := func(candidate string) bool {
+		return s.publicTableExists(ctx, candidate) || s.collectionExists(ctx, tenantID, candidate)
+	}
+	return validationError("%w", &CollectionNameError{Slug: slug, Reason: reason, Suggestions: suggestCollectionSlugs(slug, taken)})
+}
+
+// publicTableExists reports whether the public schema has a table named name, which must
+// have the form of a slug
+func (s *SchemaHandlers) publicTableExists(ctx context.Context, name string) bool {
+	var exists bool
+	err := s.handler.db.QueryRowContext(ctx, "SELECT to_regclass($1) IS NOT NULL", "public."+name).Scan(&exists)
+	return err == nil && exists
+}
+
+// collectionExists reports whether the tenant has a collection with the given slug
+func (s *SchemaHandlers) collectionExists(ctx context.Context, tenantID uuid.UUID, slug string) bool {
+	_, err := s.handler.db.Queries.GetCollectionByNameAndTenant(ctx, sqlc.GetCollectionByNameAndTenantParams{
+		Slug:     slug,
+		TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
+	})
+	return err == nil
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCollectionSlugProblem(t *testing.T) {
+	for _, slug := range []string{"products", "blog_posts", "v2_orders", strings.Repeat("a", maxCollectionSlugLength)} {
+		assert.Empty(t, collectionSlugProblem(slug), slug)
+	}
+
+	tests := map[string]string{
+		"": "is empty",
+		strings.Repeat("a", maxCollectionSlugLength+1): "is longer than 58 characters",
+		"Products":    "must start with a lowercase letter",
+		"2024_orders": "must start with a lowercase letter",
+		"blog-posts":  "must start with a lowercase letter",
+		"pg_stats":    "pg_ prefix",
+		"users":       "schema table",
+		"collections": "schema table",
+		"order":       "reserved SQL word",
+	}
+	for slug, want := range tests {
+		assert.Contains(t, collectionSlugProblem(slug), want, slug)
+	}
+}
+
+func TestNormalizeCollectionSlug(t *testing.T) {
+	assert.Equal(t, "blog_posts", normalizeCollectionSlug("Blog Posts"))
+	assert.Equal(t, "blog_posts", normalizeCollectionSlug("  blog--posts!"))
+	assert.Equal(t, "c_2024_orders", normalizeCollectionSlug("2024 Orders"))
+	assert.Equal(t, "", normalizeCollectionSlug("!!!"))
+	assert.Len(t, normalizeCollectionSlug(strings.Repeat("ab", 40)), maxCollectionSlugLength)
+}
+
+func TestSuggestCollectionSlugs(t *testing.T) {
+	none := func(string) bool { return false }
+
+	// Reserved names are never suggested back
+	assert.Equal(t, []string{"users_items", "app_users", "users_2"}, suggestCollectionSlugs("users", none))
+	assert.Equal(t, []string{"blog_posts", "blog_posts_items", "app_blog_posts"}, suggestCollectionSlugs("Blog Posts", none))
+
+	// Taken slugs are skipped
+	taken := func(slug string) bool { return slug == "posts_items" || slug == "app_posts" }
+	assert.Equal(t, []string{"posts_2", "posts_3"}, suggestCollectionSlugs("posts", taken))
+
+	assert.Equal(t, []string{"collection", "collection_items", "app_collection"}, suggestCollectionSlugs("!!!", none))
+}
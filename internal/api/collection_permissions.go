@@ -0,0 +1,126 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the permissions new collections start with.
+//
+// Without permissions a new collection answers 403 to everyone, its creator included, until
+// someone grants them. Creating a collection therefore grants the tenant's admin role
+// create, read, update and delete on it, together with the permissions of
+// COLLECTION_PERMISSION_PRESET: "role:action" entries such as "editor:create" or
+// "viewer:read", where "*" stands for every action. The permissions are created in the
+// transaction of the collection; roles the tenant does not have are skipped.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// presetPermission is a role and an action of a permission preset
+type presetPermission struct {
+	role   string
+	action string
+}
+
+// parsePermissionPreset reads COLLECTION_PERMISSION_PRESET entries of the form "role:action"
+func parsePermissionPreset(entries []string) ([]presetPermission, error) {
+	var preset []presetPermission
+	for _, entry := range entries {
+		role, action, ok := strings.Cut(entry, ":")
+		role, action = strings.TrimSpace(role), strings.TrimSpace(action)
+		if !ok || role == "" || (action != "*" && !Contains(permissionActions, action)) {
+			return nil, fmt.Errorf("invalid permission preset '%s', expected role:action", entry)
+		}
+		if action == "*" {
+			for _, action := range permissionActions {
+				preset = append(preset, presetPermission{role: role, action: action})
+			}
+			continue
+		}
+		preset = append(preset, presetPermission{role: role, action: action})
+	}
+	return preset, nil
+}
+
+// collectionPermissionGrants returns the permissions a new collection starts with: every
+// action for the admin role and the preset, without duplicates
+func collectionPermissionGrants(preset []presetPermission) []presetPermission {
+	grants := make([]presetPermission, 0, len(permissionActions)+len(preset))
+	seen := make(map[presetPermission]bool)
+	for _, action := range permissionActions {
+		grant := presetPermission{role: "admin", action: action}
+		seen[grant] = true
+		grants = append(grants, grant)
+	}
+	for _, grant := range preset {
+		if !seen[grant] {
+			seen[grant] = true
+			grants = append(grants, grant)
+		}
+	}
+	return grants
+}
+
+// seedCollectionPermissions grants the permissions a new collection starts with, keeping
+// the ones that already exist
+func (u *ItemsUtils) seedCollectionPermissions(ctx context.Context, tenantID uuid.UUID, collection string, presetEntries []string) error {
+	preset, err := parsePermissionPreset(presetEntries)
+	if err != nil {
+		return err
+	}
+	queries := u.queries()
+
+	roles := make(map[string]*sqlc.Role)
+	existing := make(map[presetPermission]bool)
+	for _, grant := range collectionPermissionGrants(preset) {
+		role, loaded := roles[grant.role]
+		if !loaded {
+			found, err := queries.GetRoleByNameAndTenant(ctx, sqlc.GetRoleByNameAndTenantParams{
+				Name:     grant.role,
+				TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
+			})
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("failed to get role %s: %w", grant.role, err)
+			}
+			if err == nil {
+				role = &found
+				permissions, err := queries.GetPermissionsByRoleAndTable(ctx, sqlc.GetPermissionsByRoleAndTableParams{
+					RoleID:    uuid.NullUUID{UUID: role.ID, Valid: true},
+					TableName: collection,
+				})
+				if err != nil {
+					return err
+				}
+				for _, permission := range permissions {
+					existing[presetPermission{role: grant.role, action: permission.Action}] = true
+				}
+			}
+			roles[grant.role] = role
+		}
+		if role == nil || existing[grant] {
+			continue
+		}
+
+		if _, err := queries.CreatePermission(ctx, sqlc.CreatePermissionParams{
+			ID:            uuid.New(),
+			RoleID:        uuid.NullUUID{UUID: role.ID, Valid: true},
+			TableName:     collection,
+			Action:        grant.action,
+			FieldFilter:   pqtype.NullRawMessage{Valid: false},
+			AllowedFields: []string{"*"},
+			TenantID:      uuid.NullUUID{UUID: tenantID, Valid: true},
+			Scope:         rbac.ScopeAll,
+		}); err != nil {
+			return fmt.Errorf("failed to create permission %s:%s for role %s: %w", collection, grant.action, grant.role, err)
+		}
+		existing[grant] = true
+	}
+	return nil
+}
@@ -0,0 +1,38 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePermissionPreset(t *testing.T) {
+	preset, err := parsePermissionPreset([]string{"editor:*", " viewer : read "})
+	require.NoError(t, err)
+	assert.Len(t, preset, len(permissionActions)+1)
+	assert.Equal(t, presetPermission{role: "viewer", action: "read"}, preset[len(preset)-1])
+
+	for _, entry := range []string{"editor", ":read", "editor:publish"} {
+		_, err := parsePermissionPreset([]string{entry})
+		assert.Error(t, err, entry)
+	}
+}
+
+func TestCollectionPermissionGrants(t *testing.T) {
+	grants := collectionPermissionGrants([]presetPermission{
+		{role: "admin", action: "read"},
+		{role: "viewer", action: "read"},
+		{role: "viewer", action: "read"},
+	})
+
+	assert.Equal(t, []presetPermission{
+		{role: "admin", action: "create"},
+		{role: "admin", action: "read"},
+		{role: "admin", action: "update"},
+		{role: "admin", action: "delete"},
+		{role: "admin", action: "purge"},
+		{role: "admin", action: "decrypt"},
+		{role: "viewer", action: "read"},
+	}, grants, "admin actions first, without duplicates")
+}
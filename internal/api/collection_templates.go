@@ -0,0 +1,263 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains collection templates: ready-made sets of collections a tenant can start
+// from, picked with "template" when the tenant is created.
+//
+// Templates:
+// - "commerce"  - customers, products and orders (the default)
+// - "blog"      - authors, categories and posts
+// - "crm"       - companies, contacts and deals
+// - "inventory" - suppliers, warehouses and the items in stock
+// - "none"      - no collections
+//
+// Templates describe their collections and fields the way schema snapshots do, so a listed
+// template can be copied into a snapshot and applied to an existing tenant.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// DefaultCollectionTemplate is the template of tenants created without one
+const DefaultCollectionTemplate = "commerce"
+
+// CollectionTemplate is a named set of collections to start a tenant from
+type CollectionTemplate struct {
+	Name        string               `json:"name"`
+	Description string               `json:"description"`
+	Collections []SnapshotCollection `json:"collections"`
+}
+
+// collectionTemplates is the template library, in the order it is listed
+var collectionTemplates = []CollectionTemplate{
+	{
+		Name:        "commerce",
+		Description: "Customers, products and their orders",
+		Collections: []SnapshotCollection{
+			{Slug: "customers", Name: "Customers", DisplayName: "Customers", Description: "Customer information and contact details", Icon: "👥",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "email", DisplayName: "Email", Type: "string", IsRequired: true, SortOrder: 2},
+					{Name: "phone", DisplayName: "Phone", Type: "string", SortOrder: 3},
+					{Name: "address", DisplayName: "Address", Type: "text", SortOrder: 4},
+				}},
+			{Slug: "products", Name: "Products", DisplayName: "Products", Description: "Product catalog and inventory", Icon: "📦",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Product Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "description", DisplayName: "Description", Type: "text", SortOrder: 2},
+					{Name: "price", DisplayName: "Price", Type: "decimal", IsRequired: true, SortOrder: 3},
+					{Name: "sku", DisplayName: "SKU", Type: "string", IsRequired: true, SortOrder: 4},
+					{Name: "stock", DisplayName: "Stock Quantity", Type: "integer", SortOrder: 5},
+				}},
+			{Slug: "orders", Name: "Orders", DisplayName: "Orders", Description: "Customer orders and transactions", Icon: "📋",
+				Fields: []SnapshotField{
+					{Name: "order_number", DisplayName: "Order Number", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "customer_id", DisplayName: "Customer", Type: "uuid", IsRequired: true, SortOrder: 2},
+					{Name: "total_amount", DisplayName: "Total Amount", Type: "decimal", IsRequired: true, SortOrder: 3},
+					{Name: "status", DisplayName: "Status", Type: "string", IsRequired: true, SortOrder: 4},
+					{Name: "order_date", DisplayName: "Order Date", Type: "datetime", IsRequired: true, SortOrder: 5},
+				}},
+		},
+	},
+	{
+		Name:        "blog",
+		Description: "Posts written by authors and filed under categories",
+		Collections: []SnapshotCollection{
+			{Slug: "authors", Name: "Authors", DisplayName: "Authors", Description: "People who write posts", Icon: "✍️",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "email", DisplayName: "Email", Type: "string", IsUnique: true, SortOrder: 2},
+					{Name: "bio", DisplayName: "Bio", Type: "text", SortOrder: 3},
+				}},
+			{Slug: "categories", Name: "Categories", DisplayName: "Categories", Description: "Topics posts are filed under", Icon: "🏷️",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "slug", DisplayName: "Slug", Type: "string", IsRequired: true, IsUnique: true, SortOrder: 2},
+					{Name: "description", DisplayName: "Description", Type: "text", SortOrder: 3},
+				}},
+			{Slug: "posts", Name: "Posts", DisplayName: "Posts", Description: "Articles and their publication state", Icon: "📝",
+				Fields: []SnapshotField{
+					{Name: "title", DisplayName: "Title", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "slug", DisplayName: "Slug", Type: "string", IsRequired: true, IsUnique: true, SortOrder: 2},
+					{Name: "body", DisplayName: "Body", Type: "text", SortOrder: 3},
+					{Name: "author_id", DisplayName: "Author", Type: "uuid", SortOrder: 4},
+					{Name: "category_id", DisplayName: "Category", Type: "uuid", SortOrder: 5},
+					{Name: "status", DisplayName: "Status", Type: "string", IsRequired: true, DefaultValue: "draft", SortOrder: 6},
+					{Name: "published_at", DisplayName: "Published At", Type: "datetime", IsIndexed: true, SortOrder: 7},
+				}},
+		},
+	},
+	{
+		Name:        "crm",
+		Description: "Companies, their contacts and the deals in progress",
+		Collections: []SnapshotCollection{
+			{Slug: "companies", Name: "Companies", DisplayName: "Companies", Description: "Organisations you do business with", Icon: "🏢",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "website", DisplayName: "Website", Type: "string", SortOrder: 2},
+					{Name: "industry", DisplayName: "Industry", Type: "string", SortOrder: 3},
+				}},
+			{Slug: "contacts", Name: "Contacts", DisplayName: "Contacts", Description: "People at the companies", Icon: "📇",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "email", DisplayName: "Email", Type: "string", IsIndexed: true, SortOrder: 2},
+					{Name: "phone", DisplayName: "Phone", Type: "string", SortOrder: 3},
+					{Name: "company_id", DisplayName: "Company", Type: "uuid", SortOrder: 4},
+				}},
+			{Slug: "deals", Name: "Deals", DisplayName: "Deals", Description: "Opportunities and where they stand", Icon: "🤝",
+				Fields: []SnapshotField{
+					{Name: "title", DisplayName: "Title", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "company_id", DisplayName: "Company", Type: "uuid", SortOrder: 2},
+					{Name: "contact_id", DisplayName: "Contact", Type: "uuid", SortOrder: 3},
+					{Name: "value", DisplayName: "Value", Type: "decimal", SortOrder: 4},
+					{Name: "stage", DisplayName: "Stage", Type: "string", IsRequired: true, DefaultValue: "lead", SortOrder: 5},
+					{Name: "close_date", DisplayName: "Close Date", Type: "datetime", SortOrder: 6},
+				}},
+		},
+	},
+	{
+		Name:        "inventory",
+		Description: "Stock items, where they are kept and who supplies them",
+		Collections: []SnapshotCollection{
+			{Slug: "suppliers", Name: "Suppliers", DisplayName: "Suppliers", Description: "Companies that supply stock", Icon: "🚚",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "email", DisplayName: "Email", Type: "string", SortOrder: 2},
+					{Name: "phone", DisplayName: "Phone", Type: "string", SortOrder: 3},
+				}},
+			{Slug: "warehouses", Name: "Warehouses", DisplayName: "Warehouses", Description: "Places stock is kept", Icon: "🏭",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "address", DisplayName: "Address", Type: "text", SortOrder: 2},
+				}},
+			{Slug: "items", Name: "Items", DisplayName: "Items", Description: "Items and how many are on hand", Icon: "📦",
+				Fields: []SnapshotField{
+					{Name: "name", DisplayName: "Name", Type: "string", IsRequired: true, IsPrimary: true, SortOrder: 1},
+					{Name: "sku", DisplayName: "SKU", Type: "string", IsRequired: true, IsUnique: true, SortOrder: 2},
+					{Name: "quantity", DisplayName: "Quantity", Type: "integer", IsRequired: true, DefaultValue: "0", SortOrder: 3},
+					{Name: "reorder_level", DisplayName: "Reorder Level", Type: "integer", SortOrder: 4},
+					{Name: "supplier_id", DisplayName: "Supplier", Type: "uuid", SortOrder: 5},
+					{Name: "warehouse_id", DisplayName: "Warehouse", Type: "uuid", SortOrder: 6},
+				}},
+		},
+	},
+	{
+		Name:        "none",
+		Description: "No collections",
+		Collections: []SnapshotCollection{},
+	},
+}
+
+// FindCollectionTemplate returns the template named name, or the default one when name is empty
+func FindCollectionTemplate(name string) (CollectionTemplate, error) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		name = DefaultCollectionTemplate
+	}
+	for _, template := range collectionTemplates {
+		if template.Name == name {
+			return template, nil
+		}
+	}
+	names := make([]string, len(collectionTemplates))
+	for i, template := range collectionTemplates {
+		names[i] = template.Name
+	}
+	return CollectionTemplate{}, fmt.Errorf("unknown collection template '%s'; available: %s", name, strings.Join(names, ", "))
+}
+
+// createDefaultCollections creates the collections of a template for a new tenant
+func (h *TenantHandler) createDefaultCollections(ctx context.Context, tx *db.Tx, tenantID uuid.UUID, creatorUserID uuid.UUID, templateName string) error {
+	template, err := FindCollectionTemplate(templateName)
+	if err != nil {
+		return err
+	}
+
+	queries := tx.Queries
+	utils := NewItemsUtils(h.db).withTx(tx)
+	for _, collectionData := range template.Collections {
+		collectionID := uuid.New()
+		collection, err := queries.CreateCollection(ctx, sqlc.CreateCollectionParams{
+			ID:          collectionID,
+			Name:        collectionData.Name, // Display name (e.g., "Customers")
+			Slug:        collectionData.Slug, // URL-friendly slug (e.g., "customers")
+			DisplayName: sql.NullString{String: collectionData.DisplayName, Valid: true},
+			Description: sql.NullString{String: collectionData.Description, Valid: true},
+			Icon:        sql.NullString{String: collectionData.Icon, Valid: true},
+			IsSystem:    sql.NullBool{Bool: false, Valid: true},
+			TenantID:    uuid.NullUUID{UUID: tenantID, Valid: true},
+			CreatedBy:   uuid.NullUUID{UUID: creatorUserID, Valid: true},
+			SoftDelete:  collectionData.SoftDelete,
+			Workflow:    collectionData.Workflow,
+			Timestamps:  true,
+			UserStamps:  true,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create collection %s: %w", collectionData.Slug, err)
+		}
+		if err := utils.CreateDataTable(ctx, &collection); err != nil {
+			return err
+		}
+
+		for _, fieldData := range collectionData.Fields {
+			_, err := queries.CreateField(ctx, sqlc.CreateFieldParams{
+				ID:           uuid.New(),
+				CollectionID: uuid.NullUUID{UUID: collectionID, Valid: true},
+				Name:         fieldData.Name,
+				DisplayName:  sql.NullString{String: fieldData.DisplayName, Valid: true},
+				Type:         fieldData.Type,
+				IsPrimary:    sql.NullBool{Bool: fieldData.IsPrimary, Valid: true},
+				IsRequired:   sql.NullBool{Bool: fieldData.IsRequired, Valid: true},
+				IsUnique:     sql.NullBool{Bool: fieldData.IsUnique, Valid: true},
+				DefaultValue: sql.NullString{String: fieldData.DefaultValue, Valid: fieldData.DefaultValue != ""},
+				SortOrder:    sql.NullInt32{Int32: int32(fieldData.SortOrder), Valid: true},
+				TenantID:     uuid.NullUUID{UUID: tenantID, Valid: true},
+				IsIndexed:    fieldData.IsIndexed,
+			})
+			if err != nil {
+				return fmt.Errorf("failed to create field %s of collection %s: %w", fieldData.Name, collectionData.Slug, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// ListCollectionTemplates handles GET /collections/templates requests.
+//
+// Lists the collection templates a tenant can be created from with "template".
+//
+// Response Format:
+//   - 200: {"data": [templates], "meta": {"default": "commerce", "count": n}}
+//   - 401: Missing or invalid authentication token
+//
+// @Summary      List collection templates
+// @Tags         collections
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  List the templates POST /tenants accepts as "template", with the collections and fields each creates.
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} models.ErrorResponse
+// @Router       /collections/templates [get]
+func (h *ItemsHandler) ListCollectionTemplates(c *gin.Context) {
+	if _, exists := middleware.GetUserID(c); !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": collectionTemplates,
+		"meta": gin.H{"default": DefaultCollectionTemplate, "count": len(collectionTemplates)},
+	})
+}
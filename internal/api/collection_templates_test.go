@@ -0,0 +1,142 @@
+package api
+
+import (
+	"database/sql"
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFindCollectionTemplate(t *testing.T) {
+	t.Run("Default", func(t *testing.T) {
+		template, err := FindCollectionTemplate("")
+		require.NoError(t, err)
+		assert.Equal(t, DefaultCollectionTemplate, template.Name)
+
+		var slugs []string
+		for _, collection := range template.Collections {
+			slugs = append(slugs, collection.Slug)
+		}
+		assert.Equal(t, []string{"customers", "products", "orders"}, slugs)
+	})
+
+	t.Run("By Name", func(t *testing.T) {
+		template, err := FindCollectionTemplate(" CRM ")
+		require.NoError(t, err)
+		assert.Equal(t, "crm", template.Name)
+	})
+
+	t.Run("None", func(t *testing.T) {
+		template, err := FindCollectionTemplate("none")
+		require.NoError(t, err)
+		assert.Empty(t, template.Collections)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		_, err := FindCollectionTemplate("wiki")
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "blog")
+	})
+}
+
+func TestCollectionTemplatesAreValid(t *testing.T) {
+	names := make(map[string]bool)
+	for _, template := range collectionTemplates {
+		assert.False(t, names[template.Name], "template %s is listed twice", template.Name)
+		names[template.Name] = true
+
+		slugs := make(map[string]bool)
+		for _, collection := range template.Collections {
+			assert.True(t, rbac.ValidateTableName(collection.Slug), "%s: invalid slug %s", template.Name, collection.Slug)
+			assert.Empty(t, collectionSlugProblem(collection.Slug), "%s: reserved slug %s", template.Name, collection.Slug)
+			assert.False(t, slugs[collection.Slug], "%s: collection %s is listed twice", template.Name, collection.Slug)
+			slugs[collection.Slug] = true
+
+			fields := make(map[string]bool)
+			primary := 0
+			for _, field := range collection.Fields {
+				assert.True(t, validFieldName(field.Name), "%s.%s: invalid field name", collection.Slug, field.Name)
+				assert.False(t, fields[field.Name], "%s.%s is listed twice", collection.Slug, field.Name)
+				fields[field.Name] = true
+				if field.IsPrimary {
+					primary++
+				}
+			}
+			assert.Equal(t, 1, primary, "%s should have one primary field", collection.Slug)
+		}
+	}
+	assert.True(t, names[DefaultCollectionTemplate])
+}
+
+func TestDuplicateFieldData(t *testing.T) {
+	copyID := uuid.MustParse("5f0c6d1e-8a77-4c39-a1e4-1b2c3d4e5f60")
+
+	t.Run("Stored Field", func(t *testing.T) {
+		field := sqlc.Field{
+			Name:            "sku",
+			DisplayName:     sql.NullString{String: "SKU", Valid: true},
+			Type:            "string",
+			IsRequired:      sql.NullBool{Bool: true, Valid: true},
+			IsUnique:        sql.NullBool{Bool: true, Valid: true},
+			SortOrder:       sql.NullInt32{Int32: 4, Valid: true},
+			ValidationRules: pqtype.NullRawMessage{RawMessage: []byte(`{"max_length": 32}`), Valid: true},
+		}
+		data := duplicateFieldData(field, copyID, "products_copy")
+		assert.Equal(t, copyID.String(), data["collection_id"])
+		assert.Equal(t, "sku", data["name"])
+		assert.Equal(t, true, data["is_unique"])
+		assert.Equal(t, 4, data["sort_order"])
+		assert.Equal(t, map[string]interface{}{"max_length": float64(32)}, data["validation_rules"])
+		assert.NotContains(t, data, "relation_config")
+	})
+
+	t.Run("Many To Many Gets Its Own Junction", func(t *testing.T) {
+		field := sqlc.Field{
+			Name:           "tags",
+			Type:           "relation",
+			RelationConfig: pqtype.NullRawMessage{RawMessage: []byte(`{"type": "m2m", "related_collection": "tags", "junction": "products_tags"}`), Valid: true},
+		}
+		data := duplicateFieldData(field, copyID, "products_copy")
+		relation := data["relation_config"].(map[string]interface{})
+		assert.Equal(t, "products_copy_tags", relation["junction"])
+		assert.Equal(t, "tags", relation["related_collection"])
+	})
+
+	t.Run("Many To One Is Kept", func(t *testing.T) {
+		field := sqlc.Field{
+			Name:           "category",
+			Type:           "relation",
+			RelationConfig: pqtype.NullRawMessage{RawMessage: []byte(`{"related_collection": "categories"}`), Valid: true},
+		}
+		data := duplicateFieldData(field, copyID, "products_copy")
+		assert.Equal(t, map[string]interface{}{"related_collection": "categories"}, data["relation_config"])
+	})
+}
+
+func TestDuplicatedColumns(t *testing.T) {
+	fields := []sqlc.Field{
+		{Name: "price", Type: "number"},
+		{Name: "total", Type: "computed"},
+		{Name: "reviews", Type: "relation", RelationConfig: pqtype.NullRawMessage{RawMessage: []byte(`{"type": "o2m", "related_collection": "reviews", "related_field": "product"}`), Valid: true}},
+		{Name: "category", Type: "relation", RelationConfig: pqtype.NullRawMessage{RawMessage: []byte(`{"related_collection": "categories"}`), Valid: true}},
+	}
+
+	assert.Equal(t, []string{"id", "tenant_id", "created_at", "updated_at", "created_by", "updated_by", "price", "category"},
+		duplicatedColumns(fields, defaultCollectionFlags))
+	assert.Contains(t, duplicatedColumns(fields, collectionFlags{SoftDelete: true}), "deleted_at")
+	assert.Subset(t, duplicatedColumns(fields, collectionFlags{Workflow: true}), []string{"status", "publish_at", "unpublish_at"})
+	assert.Equal(t, []string{"id", "tenant_id", "sort", "price", "category"},
+		duplicatedColumns(fields, collectionFlags{Sortable: true}))
+}
+
+func TestCopyRowsStatement(t *testing.T) {
+	assert.Equal(t,
+		`INSERT INTO "acme".data_copy ("id", "price") SELECT "id", "price" FROM "acme".data_products`,
+		copyRowsStatement(`"acme".data_products`, `"acme".data_copy`, []string{"id", "price"}))
+}
@@ -12,15 +12,24 @@ package api
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"go-rbac-api/internal/db"
 	sqlc "go-rbac-api/internal/db/sqlc"
 
 	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
 )
 
 // CollectionField represents a field definition from the fields table
@@ -41,6 +50,11 @@ type Collection struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	TenantID    uuid.UUID `json:"tenant_id"`
+	SoftDelete  bool      `json:"soft_delete"`
+	Workflow    bool      `json:"workflow"`
+	Timestamps  bool      `json:"timestamps"`
+	UserStamps  bool      `json:"user_stamps"`
+	Sortable    bool      `json:"sortable"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -87,29 +101,50 @@ func NewCollectionsHandler(db *db.DB, utils *ItemsUtils, dynamicHandlers *Dynami
 
 // GetCollection retrieves a collection definition by name
 func (ch *CollectionsHandler) GetCollection(ctx context.Context, tenantID uuid.UUID, collectionSlug string) (*Collection, error) {
+	if collection, ok := defaultSchemaCache.Collection(tenantID, collectionSlug); ok {
+		if collection == nil {
+			return nil, notFoundError("collection '%s' not found", collectionSlug)
+		}
+		return collection, nil
+	}
+
 	// Use SQLC generated query for better type safety
+	generation := defaultSchemaCache.Generation()
 	dbCollection, err := ch.db.Queries.GetCollectionByNameAndTenant(ctx, sqlc.GetCollectionByNameAndTenantParams{
-		Name:     collectionSlug, // This now refers to the slug field
+		Slug:     collectionSlug,
 		TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
 	})
 
-	if err != nil {
-		return nil, fmt.Errorf("collection not found: %w", err)
+	if err == sql.ErrNoRows {
+		defaultSchemaCache.SetCollection(generation, tenantID, collectionSlug, nil)
+		return nil, notFoundError("collection '%s' not found", collectionSlug)
 	}
-
-	// Convert SQLC model to our Collection struct
-	collection := &Collection{
-		ID:          dbCollection.ID,
-		Name:        dbCollection.Name,
-		Description: dbCollection.Description.String,
-		TenantID:    dbCollection.TenantID.UUID,
-		CreatedAt:   dbCollection.CreatedAt.Time,
-		UpdatedAt:   dbCollection.UpdatedAt.Time,
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
 	}
 
+	collection := collectionFromRow(dbCollection)
+	defaultSchemaCache.SetCollection(generation, tenantID, collectionSlug, collection)
 	return collection, nil
 }
 
+// collectionFromRow converts a SQLC model to our Collection struct
+func collectionFromRow(row sqlc.Collection) *Collection {
+	return &Collection{
+		ID:          row.ID,
+		Name:        row.Name,
+		Description: row.Description.String,
+		TenantID:    row.TenantID.UUID,
+		SoftDelete:  row.SoftDelete,
+		Workflow:    row.Workflow,
+		Timestamps:  row.Timestamps,
+		UserStamps:  row.UserStamps,
+		Sortable:    row.Sortable,
+		CreatedAt:   row.CreatedAt.Time,
+		UpdatedAt:   row.UpdatedAt.Time,
+	}
+}
+
 // GetCollectionFields retrieves all fields for a collection
 func (ch *CollectionsHandler) GetCollectionFields(ctx context.Context, collectionID uuid.UUID) ([]CollectionField, error) {
 	query := `
@@ -163,6 +198,19 @@ func (ch *CollectionsHandler) GetCollectionFields(ctx context.Context, collectio
 
 // ValidateCollectionData validates data against collection field definitions
 func (ch *CollectionsHandler) ValidateCollectionData(ctx context.Context, tenantID uuid.UUID, collectionName string, data map[string]interface{}) error {
+	return ch.validateCollectionData(ctx, tenantID, collectionName, data, true)
+}
+
+// ValidateCollectionChanges validates the fields of a partial update. Unlike
+// ValidateCollectionData, required fields may be missing; they only fail when set to null.
+func (ch *CollectionsHandler) ValidateCollectionChanges(ctx context.Context, tenantID uuid.UUID, collectionName string, data map[string]interface{}) error {
+	return ch.validateCollectionData(ctx, tenantID, collectionName, data, false)
+}
+
+// validateCollectionData validates data against the field definitions, checking that
+// every required field is present when complete is set. Every invalid field is reported,
+// as FieldErrors wrapped in an ErrValidation error.
+func (ch *CollectionsHandler) validateCollectionData(ctx context.Context, tenantID uuid.UUID, collectionName string, data map[string]interface{}, complete bool) error {
 	// Get collection definition
 	collection, err := ch.GetCollection(ctx, tenantID, collectionName)
 	if err != nil {
@@ -175,22 +223,66 @@ func (ch *CollectionsHandler) ValidateCollectionData(ctx context.Context, tenant
 		return fmt.Errorf("field validation failed: %w", err)
 	}
 
+	if fieldErrors := ch.fieldErrors(ctx, tenantID, collection, fields, data, complete); len(fieldErrors) > 0 {
+		return validationError("%w", fieldErrors)
+	}
+	return nil
+}
+
+// fieldErrors checks data against the fields of collection and returns a FieldError for
+// every violation, in field name order
+func (ch *CollectionsHandler) fieldErrors(ctx context.Context, tenantID uuid.UUID, collection *Collection, fields []CollectionField, data map[string]interface{}, complete bool) FieldErrors {
 	// Create field map for quick lookup
 	fieldMap := make(map[string]CollectionField)
 	for _, field := range fields {
 		fieldMap[field.Name] = field
 	}
 
+	var fieldErrors FieldErrors
+	add := func(field, code, message string) {
+		fieldErrors = append(fieldErrors, FieldError{Field: field, Code: code, Message: message})
+	}
+
 	// Validate each provided field
 	for fieldName, value := range data {
+		// Workflow columns are written like fields (see content_workflow.go)
+		if collection.Workflow && isWorkflowColumn(fieldName) {
+			if err := validateWorkflowValue(fieldName, value); err != nil {
+				add(fieldName, FieldCodeInvalidValue, err.Error())
+			}
+			continue
+		}
+
+		// So is the position of an item in a sortable collection (see collection_flags.go)
+		if collection.Sortable && fieldName == sortColumn {
+			if _, err := sortValue(value); err != nil {
+				add(fieldName, FieldCodeInvalidValue, err.Error())
+			}
+			continue
+		}
+
 		field, exists := fieldMap[fieldName]
 		if !exists {
-			return fmt.Errorf("field '%s' is not defined in collection '%s'", fieldName, collectionName)
+			add(fieldName, FieldCodeUnknown, "no such field in this collection")
+			continue
+		}
+
+		// One-to-many relations are aliases with no column of their own
+		if field.Type == "relation" && GetStringFromMap(field.Options, "type") == RelationOneToMany {
+			add(fieldName, FieldCodeReadOnly, "one-to-many relations cannot be written directly")
+			continue
+		}
+
+		// Computed fields are generated by the database
+		if field.Type == "computed" {
+			add(fieldName, FieldCodeReadOnly, "computed fields cannot be written")
+			continue
 		}
 
 		// Validate required fields
 		if field.IsRequired && (value == nil || value == "") {
-			return fmt.Errorf("field '%s' is required", fieldName)
+			add(fieldName, FieldCodeRequired, "a value is required")
+			continue
 		}
 
 		// Skip validation for nil/empty values (unless required)
@@ -200,24 +292,53 @@ func (ch *CollectionsHandler) ValidateCollectionData(ctx context.Context, tenant
 
 		// Validate field type
 		if err := ch.validateFieldType(field, value); err != nil {
-			return fmt.Errorf("field '%s' validation failed: %w", fieldName, err)
+			add(fieldName, FieldCodeInvalidType, err.Error())
+			continue
 		}
 
 		// Apply field-specific validation rules
 		if err := ch.applyFieldValidation(field, value); err != nil {
-			return fmt.Errorf("field '%s' validation failed: %w", fieldName, err)
+			code := FieldCodeInvalidValue
+			var violation *ruleViolation
+			if errors.As(err, &violation) {
+				code = violation.rule
+			}
+			add(fieldName, code, err.Error())
+			continue
+		}
+
+		// File fields may only reference the tenant's own assets
+		if field.Type == "file" {
+			if err := ch.validateAssetReference(ctx, tenantID, value); err != nil {
+				add(fieldName, FieldCodeInvalidReference, err.Error())
+			}
 		}
 	}
 
 	// Check for missing required fields
 	for _, field := range fields {
-		if field.IsRequired {
+		if complete && field.IsRequired && field.Type != "computed" {
 			if _, provided := data[field.Name]; !provided {
-				return fmt.Errorf("required field '%s' is missing", field.Name)
+				add(field.Name, FieldCodeRequired, "a value is required")
 			}
 		}
 	}
 
+	sort.SliceStable(fieldErrors, func(i, j int) bool { return fieldErrors[i].Field < fieldErrors[j].Field })
+	return fieldErrors
+}
+
+// validateAssetReference checks that value is the ID of an asset owned by tenantID
+func (ch *CollectionsHandler) validateAssetReference(ctx context.Context, tenantID uuid.UUID, value interface{}) error {
+	assetID, err := uuid.Parse(fmt.Sprint(value))
+	if err != nil {
+		return fmt.Errorf("invalid asset ID '%v'", value)
+	}
+
+	asset, err := ch.db.Queries.GetAssetByID(ctx, assetID)
+	if err != nil || asset.TenantID != tenantID {
+		return fmt.Errorf("asset '%s' not found", assetID)
+	}
 	return nil
 }
 
@@ -308,6 +429,30 @@ func (ch *CollectionsHandler) validateFieldType(field CollectionField, value int
 			return fmt.Errorf("expected date/time, got %T", value)
 		}
 
+	case "point", "geometry":
+		if _, err := parseGeoValue(field.Type, value); err != nil {
+			return err
+		}
+
+	case "string[]", "uuid[]", "int[]":
+		if _, err := parseArrayValue(field.Type, value); err != nil {
+			return err
+		}
+
+	case "uuid", "relation", "file":
+		// Many-to-many relations hold the related items' IDs, many-to-one the related item's ID
+		if field.Type == "relation" && GetStringFromMap(field.Options, "type") == RelationManyToMany {
+			_, err := relatedIDList(value)
+			return err
+		}
+		str, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected UUID string, got %T", value)
+		}
+		if _, err := uuid.Parse(str); err != nil {
+			return fmt.Errorf("invalid UUID '%s'", str)
+		}
+
 	default:
 		// Unknown field type - accept any value
 		return nil
@@ -316,30 +461,43 @@ func (ch *CollectionsHandler) validateFieldType(field CollectionField, value int
 	return nil
 }
 
-// applyFieldValidation applies field-specific validation rules
+// applyFieldValidation applies field-specific validation rules.
+//
+// validation_rules may hold:
+//   - min_length, max_length: characters of string values
+//   - min, max: number values
+//   - pattern: a regular expression string values must match
+//   - format: "email", "url" or "uuid"
+//   - min_items, max_items: elements of array values
+//   - enum: the values allowed
+//   - messages: error messages replacing the default ones, keyed by rule name
+//
+// The other rules of array fields apply to each element.
 func (ch *CollectionsHandler) applyFieldValidation(field CollectionField, value interface{}) error {
 	if field.Validation == nil {
 		return nil
 	}
+	rules := field.Validation
+
+	if elementType := arrayElementType(field.Type); elementType != "" {
+		return ch.applyArrayValidation(field, elementType, value)
+	}
 
 	// Apply length validation for strings
 	if field.Type == "string" || field.Type == "text" {
 		if str, ok := value.(string); ok {
-			if minLength, exists := field.Validation["min_length"]; exists {
-				if min, ok := minLength.(float64); ok && len(str) < int(min) {
-					return fmt.Errorf("minimum length is %d characters", int(min))
-				}
+			length := utf8.RuneCountInString(str)
+			if min, ok := rules["min_length"].(float64); ok && length < int(min) {
+				return ruleError(rules, "min_length", "minimum length is %d characters", int(min))
 			}
-			if maxLength, exists := field.Validation["max_length"]; exists {
-				if max, ok := maxLength.(float64); ok && len(str) > int(max) {
-					return fmt.Errorf("maximum length is %d characters", int(max))
-				}
+			if max, ok := rules["max_length"].(float64); ok && length > int(max) {
+				return ruleError(rules, "max_length", "maximum length is %d characters", int(max))
 			}
 		}
 	}
 
 	// Apply range validation for numbers
-	if field.Type == "integer" || field.Type == "float" {
+	if isNumberField(field.Type) {
 		var num float64
 		switch v := value.(type) {
 		case int:
@@ -360,35 +518,206 @@ func (ch *CollectionsHandler) applyFieldValidation(field CollectionField, value
 			return fmt.Errorf("expected number, got %T", value)
 		}
 
-		if min, exists := field.Validation["min"]; exists {
-			if minVal, ok := min.(float64); ok && num < minVal {
-				return fmt.Errorf("minimum value is %f", minVal)
-			}
+		if minVal, ok := rules["min"].(float64); ok && num < minVal {
+			return ruleError(rules, "min", "minimum value is %v", minVal)
+		}
+		if maxVal, ok := rules["max"].(float64); ok && num > maxVal {
+			return ruleError(rules, "max", "maximum value is %v", maxVal)
 		}
-		if max, exists := field.Validation["max"]; exists {
-			if maxVal, ok := max.(float64); ok && num > maxVal {
-				return fmt.Errorf("maximum value is %f", maxVal)
+	}
+
+	// Apply pattern and format validation for strings
+	if str, ok := value.(string); ok {
+		if pattern, ok := rules["pattern"].(string); ok && pattern != "" {
+			re, err := compileValidationPattern(pattern)
+			if err != nil {
+				return err
 			}
+			if !re.MatchString(str) {
+				return ruleError(rules, "pattern", "value must match pattern: %s", pattern)
+			}
+		}
+		if format, ok := rules["format"].(string); ok && !matchesFormat(format, str) {
+			return ruleError(rules, "format", "value must be a valid %s", format)
 		}
 	}
 
-	// Apply pattern validation for strings
-	if field.Type == "string" {
-		if pattern, exists := field.Validation["pattern"]; exists {
-			if regexPattern, ok := pattern.(string); ok {
-				// Basic pattern matching (you might want to use regexp package for more complex patterns)
-				if str, ok := value.(string); ok && strings.Contains(regexPattern, "@") && !strings.Contains(str, "@") {
-					return fmt.Errorf("value must match pattern: %s", regexPattern)
-				}
+	// Apply the list of allowed values, compared by their string form as numbers may
+	// arrive as strings
+	if allowed, ok := rules["enum"].([]interface{}); ok {
+		found := false
+		for _, candidate := range allowed {
+			if fmt.Sprint(candidate) == fmt.Sprint(value) {
+				found = true
+				break
 			}
 		}
+		if !found {
+			return ruleError(rules, "enum", "value must be one of %s", enumList(allowed))
+		}
 	}
 
+	// Apply item count validation for arrays
+	if items, ok := value.([]interface{}); ok {
+		if min, ok := rules["min_items"].(float64); ok && len(items) < int(min) {
+			return ruleError(rules, "min_items", "at least %d items are required", int(min))
+		}
+		if max, ok := rules["max_items"].(float64); ok && len(items) > int(max) {
+			return ruleError(rules, "max_items", "at most %d items are allowed", int(max))
+		}
+	}
+
+	return nil
+}
+
+// applyArrayValidation applies the validation rules of an array field: min_items and
+// max_items to the array and the other rules to each element
+func (ch *CollectionsHandler) applyArrayValidation(field CollectionField, elementType string, value interface{}) error {
+	if text, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return fmt.Errorf("expected a JSON array: %w", err)
+		}
+	}
+	items, ok := value.([]interface{})
+	if !ok {
+		return fmt.Errorf("expected an array, got %T", value)
+	}
+
+	rules := field.Validation
+	if min, ok := rules["min_items"].(float64); ok && len(items) < int(min) {
+		return ruleError(rules, "min_items", "at least %d items are required", int(min))
+	}
+	if max, ok := rules["max_items"].(float64); ok && len(items) > int(max) {
+		return ruleError(rules, "max_items", "at most %d items are allowed", int(max))
+	}
+
+	element := field
+	element.Type = elementType
+	for i, item := range items {
+		if err := ch.applyFieldValidation(element, item); err != nil {
+			return fmt.Errorf("item %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// enumList formats the values of an "enum" rule for error messages
+func enumList(values []interface{}) string {
+	parts := make([]string, len(values))
+	for i, value := range values {
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// validationFormats are the formats the "format" rule accepts
+var validationFormats = []string{"email", "url", "uuid"}
+
+// validationPatterns caches compiled "pattern" rules by their source
+var validationPatterns sync.Map
+
+// compileValidationPattern compiles the regular expression of a "pattern" rule
+func compileValidationPattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := validationPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid validation pattern '%s': %w", pattern, err)
+	}
+	validationPatterns.Store(pattern, re)
+	return re, nil
+}
+
+// matchesFormat reports whether value has format
+func matchesFormat(format, value string) bool {
+	switch format {
+	case "email":
+		address, err := mail.ParseAddress(value)
+		return err == nil && address.Address == value
+	case "url":
+		parsed, err := url.ParseRequestURI(value)
+		return err == nil && parsed.Scheme != "" && parsed.Host != ""
+	case "uuid":
+		_, err := uuid.Parse(value)
+		return err == nil
+	default:
+		return false
+	}
+}
+
+// ruleViolation is a value failing a validation rule
+type ruleViolation struct {
+	rule    string // The rule's name, e.g. "max_length"
+	message string
+}
+
+func (v *ruleViolation) Error() string {
+	return v.message
+}
+
+// ruleError returns the custom message of rule from the rules' messages, or the default one
+func ruleError(rules map[string]interface{}, rule, format string, args ...interface{}) error {
+	if messages, ok := rules["messages"].(map[string]interface{}); ok {
+		if message, ok := messages[rule].(string); ok && message != "" {
+			return &ruleViolation{rule: rule, message: message}
+		}
+	}
+	return &ruleViolation{rule: rule, message: fmt.Sprintf(format, args...)}
+}
+
+// isNumberField reports whether a field type holds numbers
+func isNumberField(fieldType string) bool {
+	switch fieldType {
+	case "number", "integer", "int", "float", "decimal":
+		return true
+	default:
+		return false
+	}
+}
+
+// checkValidationRules rejects validation_rules that could never be applied: patterns that
+// do not compile, unknown formats and enums that are not lists of values
+func checkValidationRules(raw pqtype.NullRawMessage) error {
+	if !raw.Valid {
+		return nil
+	}
+	var rules map[string]interface{}
+	if err := json.Unmarshal(raw.RawMessage, &rules); err != nil {
+		return fmt.Errorf("validation_rules must be an object")
+	}
+	if pattern, ok := rules["pattern"].(string); ok {
+		if _, err := compileValidationPattern(pattern); err != nil {
+			return err
+		}
+	}
+	if format, ok := rules["format"]; ok && !Contains(validationFormats, fmt.Sprint(format)) {
+		return fmt.Errorf("validation_rules.format must be one of %s", strings.Join(validationFormats, ", "))
+	}
+	if enum, ok := rules["enum"]; ok {
+		values, isList := enum.([]interface{})
+		if !isList || len(values) == 0 {
+			return fmt.Errorf("validation_rules.enum must be a non-empty list of values")
+		}
+	}
 	return nil
 }
 
 // ConvertFieldValues converts field values to appropriate types based on field definitions
+// and fills in the defaults of missing fields
 func (ch *CollectionsHandler) ConvertFieldValues(ctx context.Context, tenantID uuid.UUID, collectionName string, data map[string]interface{}) (map[string]interface{}, error) {
+	return ch.convertFieldValues(ctx, tenantID, collectionName, data, true)
+}
+
+// ConvertFieldChanges converts the field values of a partial update, leaving missing
+// fields out
+func (ch *CollectionsHandler) ConvertFieldChanges(ctx context.Context, tenantID uuid.UUID, collectionName string, data map[string]interface{}) (map[string]interface{}, error) {
+	return ch.convertFieldValues(ctx, tenantID, collectionName, data, false)
+}
+
+// convertFieldValues converts data, adding field defaults for missing fields when
+// applyDefaults is set
+func (ch *CollectionsHandler) convertFieldValues(ctx context.Context, tenantID uuid.UUID, collectionName string, data map[string]interface{}, applyDefaults bool) (map[string]interface{}, error) {
 	// Get collection and field definitions
 	collection, err := ch.GetCollection(ctx, tenantID, collectionName)
 	if err != nil {
@@ -410,6 +739,20 @@ func (ch *CollectionsHandler) ConvertFieldValues(ctx context.Context, tenantID u
 
 	// Convert each field value
 	for fieldName, value := range data {
+		// Workflow values were checked by ValidateCollectionData and are stored as given
+		if collection.Workflow && isWorkflowColumn(fieldName) {
+			converted[fieldName] = value
+			continue
+		}
+		if collection.Sortable && fieldName == sortColumn {
+			position, err := sortValue(value)
+			if err != nil {
+				return nil, validationError("failed to convert field '%s': %w", fieldName, err)
+			}
+			converted[fieldName] = position
+			continue
+		}
+
 		field, exists := fieldMap[fieldName]
 		if !exists {
 			// Skip unknown fields
@@ -419,7 +762,7 @@ func (ch *CollectionsHandler) ConvertFieldValues(ctx context.Context, tenantID u
 		// Convert value based on field type
 		convertedValue, err := ch.convertFieldValue(field, value)
 		if err != nil {
-			return nil, fmt.Errorf("failed to convert field '%s': %w", fieldName, err)
+			return nil, validationError("failed to convert field '%s': %w", fieldName, err)
 		}
 
 		converted[fieldName] = convertedValue
@@ -427,7 +770,7 @@ func (ch *CollectionsHandler) ConvertFieldValues(ctx context.Context, tenantID u
 
 	// Add default values for missing fields
 	for _, field := range fields {
-		if _, exists := converted[field.Name]; !exists && field.Default != nil {
+		if _, exists := converted[field.Name]; applyDefaults && !exists && field.Default != nil {
 			converted[field.Name] = field.Default
 		}
 	}
@@ -541,6 +884,12 @@ func (ch *CollectionsHandler) convertFieldValue(field CollectionField, value int
 			return nil, fmt.Errorf("cannot convert %T to date", value)
 		}
 
+	case "point", "geometry":
+		return parseGeoValue(field.Type, value)
+
+	case "string[]", "uuid[]", "int[]":
+		return parseArrayValue(field.Type, value)
+
 	default:
 		// Unknown type - return as-is
 		return value, nil
@@ -567,18 +916,19 @@ func (ch *CollectionsHandler) CreateCollectionItem(ctx context.Context, userID u
 	}
 
 	// Create the item using dynamic handlers
-	err = ch.dynamicHandlers.CreateDynamicItem(ctx, userID, collectionName, convertedData)
+	itemID, err := ch.dynamicHandlers.CreateDynamicItem(ctx, userID, collectionName, convertedData)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create item: %w", err)
 	}
 
-	return convertedData, nil
+	return withID(convertedData, itemID), nil
 }
 
-// GetCollectionItem retrieves a specific item from a collection
-func (ch *CollectionsHandler) GetCollectionItem(ctx context.Context, userID uuid.UUID, collectionName string, itemID string) (map[string]interface{}, error) {
+// GetCollectionItem retrieves a specific item from a collection. Trashed items are only
+// returned when includeDeleted is set.
+func (ch *CollectionsHandler) GetCollectionItem(ctx context.Context, userID uuid.UUID, collectionName string, itemID string, includeDeleted bool) (map[string]interface{}, error) {
 	// Get the item using dynamic handlers
-	item, err := ch.dynamicHandlers.GetDynamicItem(ctx, userID, collectionName, itemID)
+	item, err := ch.dynamicHandlers.GetDynamicItem(ctx, userID, collectionName, itemID, includeDeleted)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get item: %w", err)
 	}
@@ -614,6 +964,78 @@ func (ch *CollectionsHandler) UpdateCollectionItem(ctx context.Context, userID u
 	return convertedData, nil
 }
 
+// PatchCollectionItem merges data into an item of a collection: only the fields in data
+// are validated and written, and a null value clears a field
+func (ch *CollectionsHandler) PatchCollectionItem(ctx context.Context, userID uuid.UUID, collectionName string, itemID string, data map[string]interface{}) (map[string]interface{}, error) {
+	// Get user's tenant
+	userTenantID, err := ch.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	if err := ch.ValidateCollectionChanges(ctx, userTenantID, collectionName, data); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+
+	convertedData, err := ch.ConvertFieldChanges(ctx, userTenantID, collectionName, data)
+	if err != nil {
+		return nil, fmt.Errorf("field conversion failed: %w", err)
+	}
+
+	if err := ch.dynamicHandlers.UpdateDynamicItem(ctx, userID, collectionName, itemID, convertedData); err != nil {
+		return nil, fmt.Errorf("failed to update item: %w", err)
+	}
+
+	return convertedData, nil
+}
+
+// ReplaceCollectionItem replaces an item of a collection with data: writable fields
+// missing from data are reset to their default, or null. allowedFields limits the fields
+// that are reset to those the caller may write.
+func (ch *CollectionsHandler) ReplaceCollectionItem(ctx context.Context, userID uuid.UUID, collectionName string, itemID string, data map[string]interface{}, allowedFields []string) (map[string]interface{}, error) {
+	// Get user's tenant
+	userTenantID, err := ch.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	collection, err := ch.GetCollection(ctx, userTenantID, collectionName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	fields, err := ch.GetCollectionFields(ctx, collection.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get fields: %w", err)
+	}
+
+	return ch.UpdateCollectionItem(ctx, userID, collectionName, itemID, replacementData(fields, data, allowedFields))
+}
+
+// replacementData returns data with every writable field it is missing set to the field's
+// default (nil when it has none). Computed fields and one-to-many relations have no
+// column to reset, and fields outside allowedFields are left alone.
+func replacementData(fields []CollectionField, data map[string]interface{}, allowedFields []string) map[string]interface{} {
+	allFields := len(allowedFields) == 0 || Contains(allowedFields, "*")
+
+	replacement := make(map[string]interface{}, len(fields))
+	for key, value := range data {
+		replacement[key] = value
+	}
+	for _, field := range fields {
+		if _, provided := data[field.Name]; provided {
+			continue
+		}
+		if field.Type == "computed" || (field.Type == "relation" && GetStringFromMap(field.Options, "type") == RelationOneToMany) {
+			continue
+		}
+		if !allFields && !Contains(allowedFields, field.Name) {
+			continue
+		}
+		replacement[field.Name] = field.Default
+	}
+	return replacement
+}
+
 // DeleteCollectionItem deletes an item from a collection
 func (ch *CollectionsHandler) DeleteCollectionItem(ctx context.Context, userID uuid.UUID, collectionName string, itemID string) error {
 	// Delete the item using dynamic handlers
@@ -624,3 +1046,123 @@ func (ch *CollectionsHandler) DeleteCollectionItem(ctx context.Context, userID u
 
 	return nil
 }
+
+// PurgeCollectionItem permanently deletes an item from a collection, bypassing the trash
+func (ch *CollectionsHandler) PurgeCollectionItem(ctx context.Context, userID uuid.UUID, collectionName string, itemID string) error {
+	if err := ch.dynamicHandlers.PurgeDynamicItem(ctx, userID, collectionName, itemID); err != nil {
+		return fmt.Errorf("failed to purge item: %w", err)
+	}
+
+	return nil
+}
+
+// RestoreCollectionItem moves a soft-deleted item out of the trash
+func (ch *CollectionsHandler) RestoreCollectionItem(ctx context.Context, userID uuid.UUID, collectionName string, itemID string) error {
+	if err := ch.dynamicHandlers.RestoreDynamicItem(ctx, userID, collectionName, itemID); err != nil {
+		return fmt.Errorf("failed to restore item: %w", err)
+	}
+
+	return nil
+}
+
+// BulkCreateCollectionItems validates and converts every item against the collection
+// schema before inserting them all in one transaction. Validation failures are
+// reported with the zero-based index of the offending item and nothing is written.
+func (ch *CollectionsHandler) BulkCreateCollectionItems(ctx context.Context, userID uuid.UUID, collectionName string, items []map[string]interface{}) ([]map[string]interface{}, error) {
+	convertedItems, err := ch.prepareBulkItems(ctx, userID, collectionName, items)
+	if err != nil {
+		return nil, err
+	}
+
+	itemIDs, err := ch.dynamicHandlers.BulkCreateDynamicItems(ctx, userID, collectionName, convertedItems)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create items: %w", err)
+	}
+
+	for i, item := range convertedItems {
+		convertedItems[i] = withID(item, itemIDs[i])
+	}
+	return convertedItems, nil
+}
+
+// BulkUpdateCollectionItems validates and converts every item against the collection
+// schema before applying all updates in one transaction.
+func (ch *CollectionsHandler) BulkUpdateCollectionItems(ctx context.Context, userID uuid.UUID, collectionName string, itemIDs []string, items []map[string]interface{}) ([]map[string]interface{}, error) {
+	convertedItems, err := ch.prepareBulkItems(ctx, userID, collectionName, items)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.dynamicHandlers.BulkUpdateDynamicItems(ctx, userID, collectionName, itemIDs, convertedItems); err != nil {
+		return nil, fmt.Errorf("failed to update items: %w", err)
+	}
+
+	return convertedItems, nil
+}
+
+// UpsertCollectionItems inserts or updates items of a collection keyed by the unique field
+// key, in one transaction. Items that update an existing item are validated as partial
+// updates; items that are inserted are validated as complete items and get field defaults.
+func (ch *CollectionsHandler) UpsertCollectionItems(ctx context.Context, userID uuid.UUID, collectionName, key string, items []map[string]interface{}) ([]upsertResult, error) {
+	// Get user's tenant
+	userTenantID, err := ch.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	changes := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		if err := ch.ValidateCollectionChanges(ctx, userTenantID, collectionName, item); err != nil {
+			return nil, wrapError(err, "item %d", i)
+		}
+		if changes[i], err = ch.ConvertFieldChanges(ctx, userTenantID, collectionName, item); err != nil {
+			return nil, wrapError(err, "item %d", i)
+		}
+	}
+
+	prepareInsert := func(i int) (map[string]interface{}, error) {
+		if err := ch.ValidateCollectionData(ctx, userTenantID, collectionName, items[i]); err != nil {
+			return nil, fmt.Errorf("validation failed: %w", err)
+		}
+		return ch.ConvertFieldValues(ctx, userTenantID, collectionName, items[i])
+	}
+
+	results, err := ch.dynamicHandlers.UpsertDynamicItems(ctx, userID, collectionName, key, changes, prepareInsert)
+	if err != nil {
+		return nil, fmt.Errorf("failed to upsert items: %w", err)
+	}
+	return results, nil
+}
+
+// BulkDeleteCollectionItems deletes the given items from a collection in one transaction
+func (ch *CollectionsHandler) BulkDeleteCollectionItems(ctx context.Context, userID uuid.UUID, collectionName string, itemIDs []string) error {
+	if err := ch.dynamicHandlers.BulkDeleteDynamicItems(ctx, userID, collectionName, itemIDs); err != nil {
+		return fmt.Errorf("failed to delete items: %w", err)
+	}
+
+	return nil
+}
+
+// prepareBulkItems runs schema validation and type conversion for each item of a bulk request
+func (ch *CollectionsHandler) prepareBulkItems(ctx context.Context, userID uuid.UUID, collectionName string, items []map[string]interface{}) ([]map[string]interface{}, error) {
+	// Get user's tenant
+	userTenantID, err := ch.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	convertedItems := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		if err := ch.ValidateCollectionData(ctx, userTenantID, collectionName, item); err != nil {
+			return nil, wrapError(err, "item %d", i)
+		}
+
+		convertedData, err := ch.ConvertFieldValues(ctx, userTenantID, collectionName, item)
+		if err != nil {
+			return nil, wrapError(err, "item %d", i)
+		}
+		convertedItems[i] = convertedData
+	}
+
+	return convertedItems, nil
+}
@@ -12,7 +12,7 @@ func TestCollectionsHandler_ValidateCollectionData(t *testing.T) {
 	// This is a basic test to verify the handler structure
 	// In a real implementation, you'd want to mock the database and test actual validation logic
 
-	handler := &CollectionsHandler{}
+	handler := &CollectionsHandler{db: newOfflineDB(t)}
 
 	// Test basic structure
 	assert.NotNil(t, handler)
@@ -31,7 +31,7 @@ func TestCollectionsHandler_ValidateCollectionData(t *testing.T) {
 }
 
 func TestCollectionsHandler_ConvertFieldValues(t *testing.T) {
-	handler := &CollectionsHandler{}
+	handler := &CollectionsHandler{db: newOfflineDB(t)}
 
 	// Test basic structure
 	assert.NotNil(t, handler)
@@ -96,6 +96,13 @@ func TestCollectionsHandler_validateFieldType(t *testing.T) {
 	// Invalid string
 	err = handler.validateFieldType(field, "maybe")
 	assert.Error(t, err)
+
+	// Many-to-many relations take arrays of IDs
+	field = CollectionField{Type: "relation", Options: map[string]interface{}{"type": "m2m", "related_collection": "tags", "junction": "products_tags"}}
+	assert.NoError(t, handler.validateFieldType(field, []interface{}{"6e68062f-c4c6-42df-9e01-e2d1081664f4"}))
+	assert.NoError(t, handler.validateFieldType(field, []interface{}{}))
+	assert.Error(t, handler.validateFieldType(field, "6e68062f-c4c6-42df-9e01-e2d1081664f4"))
+	assert.Error(t, handler.validateFieldType(field, []interface{}{"not-a-uuid"}))
 }
 
 func TestCollectionsHandler_convertFieldValue(t *testing.T) {
@@ -196,3 +203,127 @@ func TestCollectionsHandler_applyFieldValidation(t *testing.T) {
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), "maximum value")
 }
+
+func TestCollectionsHandler_applyFieldValidationRules(t *testing.T) {
+	handler := &CollectionsHandler{}
+
+	t.Run("Pattern", func(t *testing.T) {
+		field := CollectionField{Type: "string", Validation: map[string]interface{}{"pattern": `^SKU-[0-9]{4}$`}}
+		assert.NoError(t, handler.applyFieldValidation(field, "SKU-1234"))
+		assert.Error(t, handler.applyFieldValidation(field, "sku-12345"))
+
+		field.Validation["pattern"] = "(["
+		assert.Error(t, handler.applyFieldValidation(field, "anything"))
+	})
+
+	t.Run("Formats", func(t *testing.T) {
+		cases := map[string][2]string{
+			"email": {"jane@example.com", "Jane <jane@example.com>"},
+			"url":   {"https://example.com/path", "example.com"},
+			"uuid":  {uuid.NewString(), "not-a-uuid"},
+		}
+		for format, values := range cases {
+			field := CollectionField{Type: "string", Validation: map[string]interface{}{"format": format}}
+			assert.NoError(t, handler.applyFieldValidation(field, values[0]), format)
+			assert.Error(t, handler.applyFieldValidation(field, values[1]), format)
+		}
+	})
+
+	t.Run("Item Counts", func(t *testing.T) {
+		field := CollectionField{Type: "json", Validation: map[string]interface{}{"min_items": float64(1), "max_items": float64(2)}}
+		assert.NoError(t, handler.applyFieldValidation(field, []interface{}{"a"}))
+		assert.Error(t, handler.applyFieldValidation(field, []interface{}{}))
+		assert.Error(t, handler.applyFieldValidation(field, []interface{}{"a", "b", "c"}))
+	})
+
+	t.Run("Enum", func(t *testing.T) {
+		field := CollectionField{Type: "string", Validation: map[string]interface{}{"enum": []interface{}{"draft", "published"}}}
+		assert.NoError(t, handler.applyFieldValidation(field, "draft"))
+		assert.EqualError(t, handler.applyFieldValidation(field, "archived"), "value must be one of draft, published")
+
+		field = CollectionField{Type: "integer", Validation: map[string]interface{}{"enum": []interface{}{float64(1), float64(2)}}}
+		assert.NoError(t, handler.applyFieldValidation(field, "2"))
+		assert.Error(t, handler.applyFieldValidation(field, 3))
+	})
+
+	t.Run("Number Fields", func(t *testing.T) {
+		field := CollectionField{Type: "number", Validation: map[string]interface{}{"max": float64(10)}}
+		assert.NoError(t, handler.applyFieldValidation(field, float64(10)))
+		assert.EqualError(t, handler.applyFieldValidation(field, float64(11)), "maximum value is 10")
+	})
+
+	t.Run("Custom Messages", func(t *testing.T) {
+		field := CollectionField{Type: "string", Validation: map[string]interface{}{
+			"pattern":  `^[A-Z]`,
+			"format":   "email",
+			"messages": map[string]interface{}{"pattern": "must start with a capital letter"},
+		}}
+		assert.EqualError(t, handler.applyFieldValidation(field, "lower@example.com"), "must start with a capital letter")
+		assert.EqualError(t, handler.applyFieldValidation(field, "Upper"), "value must be a valid email")
+	})
+}
+
+func TestCollectionsHandler_fieldErrors(t *testing.T) {
+	handler := &CollectionsHandler{}
+	collection := &Collection{Name: "Products"}
+	fields := []CollectionField{
+		{Name: "name", Type: "string", IsRequired: true, Validation: map[string]interface{}{"max_length": float64(5)}},
+		{Name: "price", Type: "integer"},
+		{Name: "sku", Type: "string", IsRequired: true},
+		{Name: "total", Type: "computed"},
+	}
+	data := map[string]interface{}{
+		"name":   "Standing desk",
+		"price":  "cheap",
+		"total":  10,
+		"colour": "red",
+	}
+
+	fieldErrors := handler.fieldErrors(context.Background(), uuid.New(), collection, fields, data, true)
+	assert.Equal(t, FieldErrors{
+		{Field: "colour", Code: FieldCodeUnknown, Message: "no such field in this collection"},
+		{Field: "name", Code: "max_length", Message: "maximum length is 5 characters"},
+		{Field: "price", Code: FieldCodeInvalidType, Message: "cannot convert string 'cheap' to integer"},
+		{Field: "sku", Code: FieldCodeRequired, Message: "a value is required"},
+		{Field: "total", Code: FieldCodeReadOnly, Message: "computed fields cannot be written"},
+	}, fieldErrors)
+
+	// Partial updates may leave required fields out
+	fieldErrors = handler.fieldErrors(context.Background(), uuid.New(), collection, fields, map[string]interface{}{"name": "Desk"}, false)
+	assert.Empty(t, fieldErrors)
+}
+
+func TestCheckValidationRules(t *testing.T) {
+	assert.NoError(t, checkValidationRules(GetJSONFromMap(map[string]interface{}{}, "validation_rules")))
+	assert.NoError(t, checkValidationRules(GetJSONFromMap(map[string]interface{}{
+		"validation_rules": map[string]interface{}{"pattern": `^\d+$`, "format": "url"},
+	}, "validation_rules")))
+	assert.Error(t, checkValidationRules(GetJSONFromMap(map[string]interface{}{
+		"validation_rules": map[string]interface{}{"pattern": "(["},
+	}, "validation_rules")))
+	assert.Error(t, checkValidationRules(GetJSONFromMap(map[string]interface{}{
+		"validation_rules": map[string]interface{}{"format": "phone"},
+	}, "validation_rules")))
+	assert.Error(t, checkValidationRules(GetJSONFromMap(map[string]interface{}{
+		"validation_rules": map[string]interface{}{"enum": "draft"},
+	}, "validation_rules")))
+}
+
+func TestReplacementData(t *testing.T) {
+	fields := []CollectionField{
+		{Name: "title", Type: "string", IsRequired: true},
+		{Name: "status", Type: "string", Default: "draft"},
+		{Name: "notes", Type: "text"},
+		{Name: "slug", Type: "computed"},
+		{Name: "comments", Type: "relation", Options: map[string]interface{}{"type": RelationOneToMany}},
+		{Name: "internal", Type: "text"},
+	}
+	data := map[string]interface{}{"title": "Hello"}
+
+	replacement := replacementData(fields, data, []string{"title", "status", "notes", "slug", "comments"})
+	assert.Equal(t, map[string]interface{}{"title": "Hello", "status": "draft", "notes": nil}, replacement)
+	assert.Equal(t, map[string]interface{}{"title": "Hello"}, data, "input must not be modified")
+
+	replacement = replacementData(fields, data, []string{"*"})
+	assert.Contains(t, replacement, "internal")
+}
@@ -0,0 +1,233 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains computed fields: fields whose value Postgres derives from other fields
+// of the same item.
+//
+// Computed fields have the type "computed" and describe their value in computed_config:
+//
+//	{"expression": "price * quantity", "type": "number"}
+//	{"expression": "upper(first_name) || ' ' || upper(last_name)", "type": "text"}
+//
+// The field's column is a generated column (GENERATED ALWAYS AS ... STORED), so the value
+// is kept up to date on every write and reads, filters and sorting treat it like any other
+// column. Computed fields are read-only: items that set them are rejected.
+//
+// Expressions are written in a small language that is translated to SQL token by token:
+// field names, numbers, 'quoted strings', true/false/null, the operators + - * / % || and
+// comparisons, and/or/not, parentheses and the functions in computedFunctions. Only fields
+// with a column of their own that are not computed themselves can be used. A field used by
+// a computed field cannot be renamed or deleted until the computed field no longer uses it.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/sqlc-dev/pqtype"
+)
+
+// computedFunctions are the functions computed expressions may call. Generated columns
+// only accept immutable functions.
+var computedFunctions = []string{"abs", "round", "ceil", "floor", "trunc", "coalesce", "nullif",
+	"greatest", "least", "lower", "upper", "length", "trim", "substr"}
+
+// computedResultTypes are the field types a computed value can have
+var computedResultTypes = []string{"number", "text", "boolean", "date", "datetime"}
+
+// Tokens of computed expressions
+var (
+	expressionNumberPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?`)
+	expressionWordPattern   = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*`)
+	expressionOperators     = []string{"||", "<=", ">=", "<>", "!=", "+", "-", "*", "/", "%", "<", ">", "=", ",", "(", ")"}
+	expressionKeywords      = []string{"and", "or", "not", "true", "false", "null"}
+)
+
+// ComputedConfig describes how a computed field derives its value
+type ComputedConfig struct {
+	Expression string `json:"expression"` // Expression over other fields, e.g. "price * quantity"
+	Type       string `json:"type"`       // Field type of the value: "number" (default), "text", "boolean", "date" or "datetime"
+}
+
+// parseComputedConfig reads a computed config from a field's computed_config options
+func parseComputedConfig(options map[string]interface{}) (ComputedConfig, error) {
+	cfg := ComputedConfig{
+		Expression: strings.TrimSpace(GetStringFromMap(options, "expression")),
+		Type:       GetStringFromMap(options, "type"),
+	}
+	if cfg.Type == "" {
+		cfg.Type = "number"
+	}
+
+	if cfg.Expression == "" {
+		return cfg, fmt.Errorf("computed_config.expression is required for computed fields")
+	}
+	if !Contains(computedResultTypes, cfg.Type) {
+		return cfg, fmt.Errorf("computed_config.type must be one of %s", strings.Join(computedResultTypes, ", "))
+	}
+	if _, err := tokenizeExpression(cfg.Expression); err != nil {
+		return cfg, err
+	}
+	return cfg, nil
+}
+
+// decodeComputedConfig reads the stored computed config of a field
+func decodeComputedConfig(raw pqtype.NullRawMessage) (ComputedConfig, error) {
+	var options map[string]interface{}
+	if raw.Valid {
+		if err := json.Unmarshal(raw.RawMessage, &options); err != nil {
+			return ComputedConfig{}, fmt.Errorf("invalid computed_config: %w", err)
+		}
+	}
+	return parseComputedConfig(options)
+}
+
+// raw returns the config as stored in the fields table
+func (cfg ComputedConfig) raw() pqtype.NullRawMessage {
+	raw, _ := json.Marshal(cfg)
+	return pqtype.NullRawMessage{RawMessage: raw, Valid: true}
+}
+
+// columnDefinition compiles the expression against columns, the columns computed fields may
+// use, into the definition of the field's generated column
+func (cfg ComputedConfig) columnDefinition(columns []string) (string, error) {
+	tokens, err := tokenizeExpression(cfg.Expression)
+	if err != nil {
+		return "", err
+	}
+
+	parts := make([]string, len(tokens))
+	for i, token := range tokens {
+		if token.column && !Contains(columns, token.text) {
+			return "", fmt.Errorf("computed expression uses '%s', which is not a field it can use", token.text)
+		}
+		parts[i] = token.sql()
+	}
+	return fmt.Sprintf("%s GENERATED ALWAYS AS (%s) STORED", dataColumnType(cfg.Type), strings.Join(parts, " ")), nil
+}
+
+// uses reports whether the expression uses the field named column
+func (cfg ComputedConfig) uses(column string) bool {
+	tokens, _ := tokenizeExpression(cfg.Expression)
+	for _, token := range tokens {
+		if token.column && token.text == column {
+			return true
+		}
+	}
+	return false
+}
+
+// expressionToken is one token of a computed expression
+type expressionToken struct {
+	text   string
+	column bool // A field name, quoted when translated
+}
+
+// sql returns the token as it appears in the generated column
+func (t expressionToken) sql() string {
+	if t.column {
+		return fmt.Sprintf(`"%s"`, t.text)
+	}
+	return t.text
+}
+
+// tokenizeExpression splits a computed expression into tokens, rejecting anything outside
+// the expression language
+func tokenizeExpression(expression string) ([]expressionToken, error) {
+	var tokens []expressionToken
+	depth := 0
+	rest := strings.TrimSpace(expression)
+
+	for rest != "" {
+		var token expressionToken
+		switch {
+		case rest[0] == '\'':
+			end := 1
+			for {
+				next := strings.IndexByte(rest[end:], '\'')
+				if next < 0 {
+					return nil, fmt.Errorf("computed expression has an unterminated string")
+				}
+				end += next + 1
+				if !strings.HasPrefix(rest[end:], "'") {
+					break
+				}
+				end++ // '' is an escaped quote
+			}
+			token.text = rest[:end]
+		case expressionNumberPattern.MatchString(rest):
+			token.text = expressionNumberPattern.FindString(rest)
+		case expressionWordPattern.MatchString(rest):
+			word := expressionWordPattern.FindString(rest)
+			lower := strings.ToLower(word)
+			switch {
+			case Contains(expressionKeywords, lower):
+				token.text = strings.ToUpper(lower)
+			case strings.HasPrefix(strings.TrimSpace(rest[len(word):]), "("):
+				if !Contains(computedFunctions, lower) {
+					return nil, fmt.Errorf("computed expression calls unsupported function '%s'", word)
+				}
+				token.text = lower
+			default:
+				token.text, token.column = word, true
+			}
+		default:
+			for _, operator := range expressionOperators {
+				if strings.HasPrefix(rest, operator) {
+					token.text = operator
+					break
+				}
+			}
+			if token.text == "" {
+				return nil, fmt.Errorf("computed expression has an unexpected character '%c'", rest[0])
+			}
+			switch token.text {
+			case "(":
+				depth++
+			case ")":
+				if depth--; depth < 0 {
+					return nil, fmt.Errorf("computed expression has unbalanced parentheses")
+				}
+			}
+		}
+		tokens = append(tokens, token)
+		rest = strings.TrimSpace(rest[len(token.text):])
+	}
+
+	if len(tokens) == 0 {
+		return nil, fmt.Errorf("computed expression is empty")
+	}
+	if depth != 0 {
+		return nil, fmt.Errorf("computed expression has unbalanced parentheses")
+	}
+	return tokens, nil
+}
+
+// computedColumns returns the fields of a collection that computed fields may use: those
+// with a column of their own that are not computed themselves, leaving out exclude
+func computedColumns(fields []sqlc.Field, exclude string) []string {
+	var columns []string
+	for _, field := range fields {
+		if field.Name == exclude || field.Type == "computed" || isAliasField(field.Type, field.RelationConfig) {
+			continue
+		}
+		columns = append(columns, field.Name)
+	}
+	return columns
+}
+
+// computedDependents returns the computed fields among fields that use the field named column
+func computedDependents(fields []sqlc.Field, column string) []string {
+	var dependents []string
+	for _, field := range fields {
+		if field.Type != "computed" || field.Name == column {
+			continue
+		}
+		if cfg, err := decodeComputedConfig(field.ComputedConfig); err == nil && cfg.uses(column) {
+			dependents = append(dependents, field.Name)
+		}
+	}
+	return dependents
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseComputedConfig(t *testing.T) {
+	cfg, err := parseComputedConfig(map[string]interface{}{"expression": " price * quantity "})
+	require.NoError(t, err)
+	assert.Equal(t, ComputedConfig{Expression: "price * quantity", Type: "number"}, cfg)
+
+	_, err = parseComputedConfig(map[string]interface{}{})
+	assert.Error(t, err)
+	_, err = parseComputedConfig(map[string]interface{}{"expression": "price", "type": "json"})
+	assert.Error(t, err)
+	_, err = parseComputedConfig(map[string]interface{}{"expression": "price; DROP TABLE users"})
+	assert.Error(t, err)
+}
+
+func TestComputedConfig_ColumnDefinition(t *testing.T) {
+	columns := []string{"price", "quantity", "first_name"}
+
+	t.Run("Arithmetic", func(t *testing.T) {
+		cfg := ComputedConfig{Expression: "round(price * quantity, 2)", Type: "number"}
+		definition, err := cfg.columnDefinition(columns)
+		require.NoError(t, err)
+		assert.Equal(t, `NUMERIC GENERATED ALWAYS AS (round ( "price" * "quantity" , 2 )) STORED`, definition)
+	})
+
+	t.Run("Strings And Keywords", func(t *testing.T) {
+		cfg := ComputedConfig{Expression: "UPPER(first_name) || 'it''s' || NULL", Type: "text"}
+		definition, err := cfg.columnDefinition(columns)
+		require.NoError(t, err)
+		assert.Equal(t, `TEXT GENERATED ALWAYS AS (upper ( "first_name" ) || 'it''s' || NULL) STORED`, definition)
+	})
+
+	t.Run("Rejected Expressions", func(t *testing.T) {
+		for _, expression := range []string{
+			"total * 2",          // Not a usable field
+			"now()",              // Unsupported function
+			"(price * quantity",  // Unbalanced
+			"price * quantity)",  // Unbalanced
+			"'unterminated",      // Unterminated string
+			"price::text",        // Casts are not part of the language
+			"price -- comment",   // Nor are comments
+			`"price" * quantity`, // Nor quoted identifiers
+		} {
+			cfg := ComputedConfig{Expression: expression, Type: "number"}
+			_, err := cfg.columnDefinition(columns)
+			assert.Error(t, err, expression)
+		}
+	})
+}
+
+func TestComputedDependents(t *testing.T) {
+	config := func(expression string) pqtype.NullRawMessage {
+		return ComputedConfig{Expression: expression, Type: "number"}.raw()
+	}
+	fields := []sqlc.Field{
+		{Name: "price", Type: "number"},
+		{Name: "quantity", Type: "number"},
+		{Name: "total", Type: "computed", ComputedConfig: config("price * quantity")},
+		{Name: "double_price", Type: "computed", ComputedConfig: config("price * 2")},
+	}
+
+	assert.Equal(t, []string{"total", "double_price"}, computedDependents(fields, "price"))
+	assert.Equal(t, []string{"total"}, computedDependents(fields, "quantity"))
+	assert.Empty(t, computedDependents(fields, "total"))
+	assert.Equal(t, []string{"price", "quantity"}, computedColumns(fields, ""))
+}
@@ -0,0 +1,370 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the content workflow of collections with "workflow": true.
+//
+// Items of workflow collections have three Basin-managed columns, written like fields:
+// - status       - "draft", "published" or "archived"; new items start as drafts
+// - publish_at   - when a draft is published; cleared once it has been
+// - unpublish_at - when a published item is archived; cleared once it has been
+//
+// The WorkflowScheduler carries out the scheduled changes and publishes item.update events
+// for them. Turning the workflow on adds the columns, marks existing items published, and
+// limits the tenant's viewer role to published items: its read permission on the collection
+// gets a status rule, and editors get a read permission of their own so they keep seeing
+// drafts. Turning it off keeps the columns and permissions.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// Workflow statuses of items
+const (
+	StatusDraft     = "draft"
+	StatusPublished = "published"
+	StatusArchived  = "archived"
+)
+
+// workflowPollInterval is how often the scheduler looks for due items
+const workflowPollInterval = time.Minute
+
+// workflowColumns are the columns the workflow adds to a data table
+var workflowColumns = []string{"status", "publish_at", "unpublish_at"}
+
+// publishedOnlyRule is the field_filter limiting viewers to published items
+var publishedOnlyRule = json.RawMessage(`{"status": {"_eq": "published"}}`)
+
+// isWorkflowColumn reports whether name is one of the workflow columns
+func isWorkflowColumn(name string) bool {
+	return Contains(workflowColumns, name)
+}
+
+// validateWorkflowValue checks a value written to a workflow column
+func validateWorkflowValue(column string, value interface{}) error {
+	if column == "status" {
+		status, _ := value.(string)
+		if status != StatusDraft && status != StatusPublished && status != StatusArchived {
+			return fmt.Errorf("must be one of %s, %s or %s", StatusDraft, StatusPublished, StatusArchived)
+		}
+		return nil
+	}
+
+	// publish_at and unpublish_at are cleared with null
+	if value == nil {
+		return nil
+	}
+	text, ok := value.(string)
+	if !ok {
+		return errors.New("must be an RFC 3339 timestamp")
+	}
+	if _, err := time.Parse(time.RFC3339, text); err != nil {
+		return errors.New("must be an RFC 3339 timestamp")
+	}
+	return nil
+}
+
+// workflowColumnStatements returns the statements adding the workflow columns to a data
+// table. Existing rows become published, so turning the workflow on hides nothing.
+func workflowColumnStatements(table DataTable, collectionID uuid.UUID) []string {
+	return []string{
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT '%s' CHECK (status IN ('%s', '%s', '%s'))`,
+			table, StatusPublished, StatusDraft, StatusPublished, StatusArchived),
+		fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN status SET DEFAULT '%s'`, table, StatusDraft),
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS publish_at TIMESTAMP WITH TIME ZONE`, table),
+		fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS unpublish_at TIMESTAMP WITH TIME ZONE`, table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "workflow_%s_idx" ON %s (status)`, strings.ReplaceAll(collectionID.String(), "-", ""), table),
+	}
+}
+
+// EnableWorkflow adds the workflow columns to the data table of a collection. It is safe to
+// call repeatedly; tables that do not exist yet are skipped. Collections with fields named
+// like a workflow column are refused.
+func (u *ItemsUtils) EnableWorkflow(ctx context.Context, tenantID uuid.UUID, collection sqlc.Collection) error {
+	fields, err := u.queries().GetFieldsByCollection(ctx, uuid.NullUUID{UUID: collection.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+	for _, field := range fields {
+		if isWorkflowColumn(field.Name) {
+			return fmt.Errorf("field '%s' clashes with the workflow column of the same name; rename it first", field.Name)
+		}
+	}
+
+	table, err := u.ResolveDataTable(ctx, tenantID, collection.Slug)
+	if errors.Is(err, ErrDataTableNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return u.transaction(ctx, func(utils *ItemsUtils) error {
+		for _, statement := range workflowColumnStatements(table, collection.ID) {
+			if _, err := utils.tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to add workflow columns: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// withPublishedOnlyRule returns filter combined with publishedOnlyRule, and whether that
+// changed it. Filters that already hold the rule are returned as they are.
+func withPublishedOnlyRule(filter pqtype.NullRawMessage) (json.RawMessage, bool, error) {
+	if !filter.Valid || len(filter.RawMessage) == 0 || string(filter.RawMessage) == "null" || string(filter.RawMessage) == "{}" {
+		return publishedOnlyRule, true, nil
+	}
+	var existing, published interface{}
+	if err := json.Unmarshal(filter.RawMessage, &existing); err != nil {
+		return nil, false, err
+	}
+	json.Unmarshal(publishedOnlyRule, &published)
+
+	if reflect.DeepEqual(existing, published) {
+		return filter.RawMessage, false, nil
+	}
+	if group, ok := existing.(map[string]interface{}); ok && len(group) == 1 {
+		rules, _ := group["_and"].([]interface{})
+		for _, rule := range rules {
+			if reflect.DeepEqual(rule, published) {
+				return filter.RawMessage, false, nil
+			}
+		}
+	}
+
+	combined, err := json.Marshal(map[string]interface{}{"_and": []interface{}{existing, published}})
+	return combined, true, err
+}
+
+// readPermission returns the read permission of a role on table, or nil when it has none
+func readPermission(ctx context.Context, queries *sqlc.Queries, roleID uuid.UUID, table string) (*sqlc.Permission, error) {
+	permissions, err := queries.GetPermissionsByRoleAndTable(ctx, sqlc.GetPermissionsByRoleAndTableParams{
+		RoleID:    uuid.NullUUID{UUID: roleID, Valid: true},
+		TableName: table,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, permission := range permissions {
+		if permission.Action == "read" {
+			return &permission, nil
+		}
+	}
+	return nil, nil
+}
+
+// applyWorkflowPermissions limits the tenant's viewer role to published items of a
+// collection. Editors inherit from viewers, so they get a read permission with the viewer's
+// fields but no status rule. Tenants without the default roles are left alone.
+func (u *ItemsUtils) applyWorkflowPermissions(ctx context.Context, tenantID uuid.UUID, collection string) error {
+	queries := u.queries()
+	viewer, err := queries.GetRoleByNameAndTenant(ctx, sqlc.GetRoleByNameAndTenantParams{
+		Name:     "viewer",
+		TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get viewer role: %w", err)
+	}
+
+	// The viewer's read permission, with the status rule added
+	viewerRead, err := readPermission(ctx, queries, viewer.ID, collection)
+	if err != nil {
+		return err
+	}
+	fields := []string{"*"}
+	if viewerRead != nil {
+		filter, changed, err := withPublishedOnlyRule(viewerRead.FieldFilter)
+		if err != nil {
+			return fmt.Errorf("invalid field filter on %s/read: %w", collection, err)
+		}
+		if changed {
+			if _, err := queries.UpdatePermission(ctx, sqlc.UpdatePermissionParams{
+				ID:              viewerRead.ID,
+				FieldFilter:     pqtype.NullRawMessage{RawMessage: filter, Valid: true},
+				AllowedFields:   viewerRead.AllowedFields,
+				ReadFields:      viewerRead.ReadFields,
+				WriteFields:     viewerRead.WriteFields,
+				Scope:           viewerRead.Scope,
+				FieldTransforms: viewerRead.FieldTransforms,
+			}); err != nil {
+				return fmt.Errorf("failed to update viewer permission: %w", err)
+			}
+		}
+		fields = rbac.PermittedFields(*viewerRead)
+	} else {
+		if _, err := queries.CreatePermission(ctx, sqlc.CreatePermissionParams{
+			ID:            uuid.New(),
+			RoleID:        uuid.NullUUID{UUID: viewer.ID, Valid: true},
+			TableName:     collection,
+			Action:        "read",
+			FieldFilter:   pqtype.NullRawMessage{RawMessage: publishedOnlyRule, Valid: true},
+			AllowedFields: fields,
+			TenantID:      uuid.NullUUID{UUID: tenantID, Valid: true},
+			Scope:         rbac.ScopeAll,
+		}); err != nil {
+			return fmt.Errorf("failed to create viewer permission: %w", err)
+		}
+	}
+
+	// Editors keep seeing drafts through a read permission of their own
+	editor, err := queries.GetRoleByNameAndTenant(ctx, sqlc.GetRoleByNameAndTenantParams{
+		Name:     "editor",
+		TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
+	})
+	if err == nil {
+		editorRead, err := readPermission(ctx, queries, editor.ID, collection)
+		if err != nil {
+			return err
+		}
+		if editorRead == nil {
+			if _, err := queries.CreatePermission(ctx, sqlc.CreatePermissionParams{
+				ID:            uuid.New(),
+				RoleID:        uuid.NullUUID{UUID: editor.ID, Valid: true},
+				TableName:     collection,
+				Action:        "read",
+				AllowedFields: fields,
+				TenantID:      uuid.NullUUID{UUID: tenantID, Valid: true},
+				Scope:         rbac.ScopeAll,
+			}); err != nil {
+				return fmt.Errorf("failed to create editor permission: %w", err)
+			}
+		}
+	} else if !errors.Is(err, sql.ErrNoRows) {
+		return fmt.Errorf("failed to get editor role: %w", err)
+	}
+
+	rbac.PermissionsChanged(tenantID)
+	return nil
+}
+
+// workflowTransition is a scheduled status change: items in status from whose column has
+// passed move to status to, and the column is cleared
+type workflowTransition struct {
+	from, to, column string
+}
+
+// workflowTransitions are the status changes the scheduler makes
+var workflowTransitions = []workflowTransition{
+	{from: StatusDraft, to: StatusPublished, column: "publish_at"},
+	{from: StatusPublished, to: StatusArchived, column: "unpublish_at"},
+}
+
+// statement returns the UPDATE carrying out the transition on table, returning the IDs of
+// the changed items. updated_at is only touched in tables that have timestamps.
+func (t workflowTransition) statement(table string, timestamps bool) string {
+	touch := ""
+	if timestamps {
+		touch = ", updated_at = NOW()"
+	}
+	return fmt.Sprintf(`UPDATE %s SET status = '%s', "%s" = NULL%s WHERE status = '%s' AND "%s" <= NOW() RETURNING id`,
+		table, t.to, t.column, touch, t.from, t.column)
+}
+
+// WorkflowScheduler publishes and archives items of workflow collections when they are due
+type WorkflowScheduler struct {
+	db     *db.DB
+	utils  *ItemsUtils
+	events *events.Bus
+}
+
+// NewWorkflowScheduler creates a WorkflowScheduler publishing item.update events to bus
+func NewWorkflowScheduler(db *db.DB, bus *events.Bus) *WorkflowScheduler {
+	return &WorkflowScheduler{db: db, utils: NewItemsUtils(db), events: bus}
+}
+
+// Start runs the scheduler until ctx is cancelled
+func (s *WorkflowScheduler) Start(ctx context.Context) {
+	ticker := time.NewTicker(workflowPollInterval)
+	defer ticker.Stop()
+
+	for {
+		s.RunDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunDue makes every status change that is due and returns how many items it changed
+func (s *WorkflowScheduler) RunDue(ctx context.Context) int {
+	collections, err := s.db.Queries.GetWorkflowCollections(ctx)
+	if err != nil {
+		slog.Error("failed to load workflow collections", "error", err)
+		return 0
+	}
+
+	changed := 0
+	for _, collection := range collections {
+		if ctx.Err() != nil {
+			break
+		}
+		table, err := s.utils.ResolveDataTable(ctx, collection.TenantID.UUID, collection.Slug)
+		if errors.Is(err, ErrDataTableNotFound) {
+			continue
+		}
+		if err != nil {
+			slog.Error("failed to resolve workflow table", "tenant_id", collection.TenantID.UUID, "collection", collection.Slug, "error", err)
+			continue
+		}
+
+		for _, transition := range workflowTransitions {
+			keys, err := s.apply(ctx, table.String(), collection.Timestamps, transition)
+			if err != nil {
+				slog.Error("failed to change scheduled item statuses", "tenant_id", collection.TenantID.UUID, "collection", collection.Slug, "status", transition.to, "error", err)
+				continue
+			}
+			if len(keys) == 0 {
+				continue
+			}
+			changed += len(keys)
+			s.events.Publish(ctx, events.Event{
+				Type:       events.ItemUpdate,
+				TenantID:   collection.TenantID.UUID,
+				Collection: collection.Slug,
+				Keys:       keys,
+				Data:       map[string]interface{}{"status": transition.to, transition.column: nil},
+			})
+		}
+	}
+	return changed
+}
+
+// apply carries out one transition on table and returns the IDs of the changed items
+func (s *WorkflowScheduler) apply(ctx context.Context, table string, timestamps bool, transition workflowTransition) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, transition.statement(table, timestamps))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		keys = append(keys, id)
+	}
+	return keys, rows.Err()
+}
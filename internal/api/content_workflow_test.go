@@ -0,0 +1,81 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateWorkflowValue(t *testing.T) {
+	for _, status := range []string{StatusDraft, StatusPublished, StatusArchived} {
+		assert.NoError(t, validateWorkflowValue("status", status))
+	}
+	assert.Error(t, validateWorkflowValue("status", "pending"))
+	assert.Error(t, validateWorkflowValue("status", nil))
+
+	assert.NoError(t, validateWorkflowValue("publish_at", "2026-05-01T09:00:00Z"))
+	assert.NoError(t, validateWorkflowValue("unpublish_at", nil))
+	assert.Error(t, validateWorkflowValue("publish_at", "tomorrow"))
+	assert.Error(t, validateWorkflowValue("unpublish_at", 1700000000))
+}
+
+func TestWorkflowColumnStatements(t *testing.T) {
+	collectionID := uuid.MustParse("5f0c6d1e-8a77-4c39-a1e4-1b2c3d4e5f60")
+	table := DataTable{Schema: "acme", Name: "data_posts", Layout: LayoutTenantSchema}
+
+	statements := workflowColumnStatements(table, collectionID)
+	require.Len(t, statements, 5)
+	// Existing rows are published; new ones start as drafts
+	assert.Contains(t, statements[0], `ALTER TABLE "acme"."data_posts" ADD COLUMN IF NOT EXISTS status TEXT NOT NULL DEFAULT 'published'`)
+	assert.Equal(t, `ALTER TABLE "acme"."data_posts" ALTER COLUMN status SET DEFAULT 'draft'`, statements[1])
+	assert.Equal(t, `CREATE INDEX IF NOT EXISTS "workflow_5f0c6d1e8a774c39a1e41b2c3d4e5f60_idx" ON "acme"."data_posts" (status)`, statements[4])
+}
+
+func TestWithPublishedOnlyRule(t *testing.T) {
+	decode := func(raw json.RawMessage) interface{} {
+		var rule interface{}
+		require.NoError(t, json.Unmarshal(raw, &rule))
+		return rule
+	}
+
+	t.Run("No Filter", func(t *testing.T) {
+		rule, changed, err := withPublishedOnlyRule(pqtype.NullRawMessage{})
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, decode(publishedOnlyRule), decode(rule))
+	})
+
+	t.Run("Existing Filter", func(t *testing.T) {
+		rule, changed, err := withPublishedOnlyRule(pqtype.NullRawMessage{RawMessage: []byte(`{"region": "eu"}`), Valid: true})
+		require.NoError(t, err)
+		assert.True(t, changed)
+		assert.Equal(t, decode([]byte(`{"_and": [{"region": "eu"}, {"status": {"_eq": "published"}}]}`)), decode(rule))
+
+		// Applying the rule again leaves the filter alone
+		_, changed, err = withPublishedOnlyRule(pqtype.NullRawMessage{RawMessage: rule, Valid: true})
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+
+	t.Run("Already Published Only", func(t *testing.T) {
+		_, changed, err := withPublishedOnlyRule(pqtype.NullRawMessage{RawMessage: []byte(`{"status":{"_eq":"published"}}`), Valid: true})
+		require.NoError(t, err)
+		assert.False(t, changed)
+	})
+}
+
+func TestWorkflowTransitionStatement(t *testing.T) {
+	assert.Equal(t,
+		`UPDATE "acme"."data_posts" SET status = 'published', "publish_at" = NULL, updated_at = NOW() WHERE status = 'draft' AND "publish_at" <= NOW() RETURNING id`,
+		workflowTransitions[0].statement(`"acme"."data_posts"`, true))
+	assert.Equal(t,
+		`UPDATE "acme"."data_posts" SET status = 'archived', "unpublish_at" = NULL, updated_at = NOW() WHERE status = 'published' AND "unpublish_at" <= NOW() RETURNING id`,
+		workflowTransitions[1].statement(`"acme"."data_posts"`, true))
+	assert.Equal(t,
+		`UPDATE "acme"."data_posts" SET status = 'published', "publish_at" = NULL WHERE status = 'draft' AND "publish_at" <= NOW() RETURNING id`,
+		workflowTransitions[0].statement(`"acme"."data_posts"`, false))
+}
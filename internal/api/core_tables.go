@@ -626,3 +626,259 @@ func UpdateAPIKey(c *gin.Context) {
 func DeleteAPIKey(c *gin.Context) {
 	// This is just for Swagger documentation
 }
+
+// =============================================================================
+// WEBHOOKS MANAGEMENT
+// =============================================================================
+
+// GetWebhooks handles GET /items/webhooks requests
+// @Summary      List webhooks
+// @Tags         webhooks
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Retrieve the webhooks of the current tenant. Signing secrets are never included. Requires authentication and webhook read permissions.
+// @Param        limit    query  int    false "Limit (max 500)"
+// @Param        offset   query  int    false "Offset"
+// @Param        is_active query bool   false "Filter by active status"
+// @Produce      json
+// @Success      200 {object} models.ItemsListResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/webhooks [get]
+func GetWebhooks(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// CreateWebhook handles POST /items/webhooks requests
+// @Summary      Create webhook
+// @Tags         webhooks
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Create a webhook that receives signed POST requests for item events. The signing secret is generated unless provided and is only returned in this response. Requires authentication and webhook creation permissions.
+// @Param        body body map[string]interface{} true "Webhook data (name, url, events: ['item.create','item.update','item.delete'|'*'], collections, secret, is_active)"
+// @Accept       json
+// @Produce      json
+// @Success      201 {object} models.CreateItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/webhooks [post]
+func CreateWebhook(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// UpdateWebhook handles PUT /items/webhooks/:id requests
+// @Summary      Update webhook
+// @Tags         webhooks
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Update a webhook's name, URL, events, collections or active status. Requires authentication and webhook update permissions.
+// @Param        id   path      string true "Webhook ID (UUID)"
+// @Param        body body map[string]interface{} true "Webhook data to update"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} models.UpdateItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/webhooks/{id} [put]
+func UpdateWebhook(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// DeleteWebhook handles DELETE /items/webhooks/:id requests
+// @Summary      Delete webhook
+// @Tags         webhooks
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Delete a webhook and its delivery log. Requires authentication and webhook deletion permissions.
+// @Param        id   path      string true "Webhook ID (UUID)"
+// @Produce      json
+// @Success      200 {object} models.DeleteItemResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/webhooks/{id} [delete]
+func DeleteWebhook(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// GetWebhookDeliveries handles GET /items/webhook_deliveries requests
+// @Summary      List webhook deliveries
+// @Tags         webhooks
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Retrieve the delivery log of the current tenant's webhooks, including status, attempts and the last response. Read-only. Requires authentication and webhook delivery read permissions.
+// @Param        webhook_id query string false "Filter by webhook ID"
+// @Param        status     query string false "Filter by status (pending, processing, success, failed)"
+// @Param        event      query string false "Filter by event"
+// @Produce      json
+// @Success      200 {object} models.ItemsListResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/webhook_deliveries [get]
+func GetWebhookDeliveries(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// =============================================================================
+// FLOWS
+// =============================================================================
+
+// GetFlows handles GET /items/flows requests
+// @Summary      List flows
+// @Tags         flows
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Retrieve the automations of the current tenant. Trigger secrets are never included. Requires authentication and flow read permissions.
+// @Param        trigger   query string false "Filter by trigger (event, schedule, webhook)"
+// @Param        is_active query bool   false "Filter by active status"
+// @Produce      json
+// @Success      200 {object} models.ItemsListResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/flows [get]
+func GetFlows(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// CreateFlow handles POST /items/flows requests
+// @Summary      Create flow
+// @Tags         flows
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Create an automation that runs a list of operations (request, create_item, send_email, template) when its trigger fires. The trigger secret is generated unless provided and is only returned in this response. Requires authentication and flow creation permissions.
+// @Param        body body map[string]interface{} true "Flow data (name, description, trigger: 'event'|'schedule'|'webhook', trigger_options: {events, collections} or {interval}, operations: [{key, type, options}], is_active)"
+// @Accept       json
+// @Produce      json
+// @Success      201 {object} models.CreateItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/flows [post]
+func CreateFlow(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// UpdateFlow handles PUT /items/flows/:id requests
+// @Summary      Update flow
+// @Tags         flows
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Update a flow's name, description, trigger, operations or active status. Requires authentication and flow update permissions.
+// @Param        id   path      string true "Flow ID (UUID)"
+// @Param        body body map[string]interface{} true "Flow data to update"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} models.UpdateItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/flows/{id} [put]
+func UpdateFlow(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// DeleteFlow handles DELETE /items/flows/:id requests
+// @Summary      Delete flow
+// @Tags         flows
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Delete a flow and its run log. Requires authentication and flow deletion permissions.
+// @Param        id   path      string true "Flow ID (UUID)"
+// @Produce      json
+// @Success      200 {object} models.DeleteItemResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/flows/{id} [delete]
+func DeleteFlow(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// GetFlowRuns handles GET /items/flow_runs requests
+// @Summary      List flow runs
+// @Tags         flows
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Retrieve the run log of the current tenant's flows: the trigger payload, status and the result of every operation. Read-only. Requires authentication and flow run read permissions.
+// @Param        flow_id query string false "Filter by flow ID"
+// @Param        status  query string false "Filter by status (pending, processing, success, failed)"
+// @Param        trigger query string false "Filter by trigger"
+// @Produce      json
+// @Success      200 {object} models.ItemsListResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/flow_runs [get]
+func GetFlowRuns(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// =============================================================================
+// JOBS
+// =============================================================================
+
+// GetJobs handles GET /items/jobs requests
+// @Summary      List background jobs
+// @Tags         jobs
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Retrieve the current tenant's background jobs, such as queued emails, with their status, attempts and last error. Jobs that failed every attempt have the status dead. Payloads are not returned. Restricted to admins.
+// @Param        kind   query string false "Filter by kind (e.g. mail.send)"
+// @Param        status query string false "Filter by status (pending, processing, completed, dead)"
+// @Produce      json
+// @Success      200 {object} models.ItemsListResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/jobs [get]
+func GetJobs(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// RetryJob handles PUT /items/jobs/:id requests
+// @Summary      Retry dead job
+// @Tags         jobs
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Move a dead job back into the queue with a fresh set of attempts by setting its status to pending. Other changes are rejected. Requires job update permissions.
+// @Param        id   path      string true "Job ID (UUID)"
+// @Param        body body map[string]interface{} true "{\"status\": \"pending\"}"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} models.UpdateItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/jobs/{id} [put]
+func RetryJob(c *gin.Context) {
+	// This is just for Swagger documentation
+}
+
+// =============================================================================
+// AUDIT LOGS
+// =============================================================================
+
+// GetAuditLogs handles GET /items/audit_logs requests
+// @Summary      List audit logs
+// @Tags         audit
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Retrieve the current tenant's audit trail: logins, failed logins, API key use and every item write with actor, IP, request ID and result. Read-only and restricted to admins.
+// @Param        action     query string false "Filter by action (login, login_failed, api_key, api_key_failed, create, update, delete, ...)"
+// @Param        user_id    query string false "Filter by actor"
+// @Param        table_name query string false "Filter by table"
+// @Param        result     query string false "Filter by result (success, failure)"
+// @Param        request_id query string false "Filter by request ID"
+// @Param        sort       query string false "Sort field (e.g. created_at)"
+// @Param        order      query string false "ASC or DESC"
+// @Produce      json
+// @Success      200 {object} models.ItemsListResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/audit_logs [get]
+func GetAuditLogs(c *gin.Context) {
+	// This is just for Swagger documentation
+}
@@ -4,21 +4,40 @@
 package api
 
 import (
+	"bytes"
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"regexp"
 	"strings"
+	"time"
 
 	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/quota"
+	"go-rbac-api/internal/rbac"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// Revision actions stored in revisions.action
+const (
+	revisionCreate = "create"
+	revisionUpdate = "update"
+	revisionDelete = "delete"
 )
 
 // DynamicHandlers provides CRUD operations for tenant-specific data tables.
 //
-// In Basin's architecture, each tenant has their own PostgreSQL schema containing
-// dynamically created data tables (e.g., "tenant_abc.data_products"). These tables
-// store the actual user data based on collections and fields defined in the schema.
+// In Basin's architecture, each collection has a dynamically created data table (e.g.,
+// "tenant_abc".data_products) holding the actual user data based on the collections and
+// fields defined in the schema.
 //
 // This handler manages operations on these dynamic tables:
 // - Respects tenant isolation (users can only access their tenant's data)
@@ -27,14 +46,18 @@ import (
 // - Provides proper error handling for missing tables/data
 //
 // Key Features:
-// - Tenant-aware table resolution (schema.data_tablename format)
+// - Tenant-aware table resolution through the TableResolver (see table_resolver.go)
 // - RLS context setting for secure data access
 // - Dynamic INSERT/UPDATE/DELETE query generation
 // - Proper transaction handling and error reporting
 // - Table existence validation before operations
+// - Publishes item.create/update/delete events after every successful write
+// - Records a revision (old and new values) in the same transaction as every write
 type DynamicHandlers struct {
-	db    *db.DB      // Database connection for direct queries
-	utils *ItemsUtils // Utility functions for tenant/table management
+	db     *db.DB         // Database connection for direct queries
+	utils  *ItemsUtils    // Utility functions for tenant/table management
+	events *events.Bus    // Receives item change events (webhooks, realtime)
+	quota  *quota.Checker // Tenant limit on items per collection (nil for none)
 }
 
 // NewDynamicHandlers creates a new DynamicHandlers instance with required dependencies.
@@ -42,106 +65,70 @@ type DynamicHandlers struct {
 // Parameters:
 //   - db: Database connection for direct queries
 //   - utils: ItemsUtils instance providing utility functions
+//   - bus: Event bus that receives item change events (may be nil)
 //
 // Returns:
 //   - *DynamicHandlers: Configured dynamic handler ready for use
 //
 // Example:
 //
-//	dynamicHandler := NewDynamicHandlers(db, utils)
-//	err := dynamicHandler.CreateDynamicItem(ctx, userID, "products", productData)
-func NewDynamicHandlers(db *db.DB, utils *ItemsUtils) *DynamicHandlers {
+//	dynamicHandler := NewDynamicHandlers(db, utils, bus)
+//	id, err := dynamicHandler.CreateDynamicItem(ctx, userID, "products", productData)
+func NewDynamicHandlers(db *db.DB, utils *ItemsUtils, bus *events.Bus) *DynamicHandlers {
 	return &DynamicHandlers{
-		db:    db,
-		utils: utils,
+		db:     db,
+		utils:  utils,
+		events: bus,
 	}
 }
 
-// CreateDynamicItem creates a new item in a dynamic data table
-func (d *DynamicHandlers) CreateDynamicItem(ctx context.Context, userID uuid.UUID, collectionSlug string, data map[string]interface{}) error {
-	// Get tenant ID
-	userTenantID, err := d.utils.GetUserTenantID(ctx, userID)
+// CreateDynamicItem creates a new item in a dynamic data table and returns its ID
+func (d *DynamicHandlers) CreateDynamicItem(ctx context.Context, userID uuid.UUID, collectionSlug string, data map[string]interface{}) (string, error) {
+	fullTableName, tenantID, err := d.resolveDataTable(ctx, userID, collectionSlug)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	// Get the actual data table name from the collections table
-	var dataTableName string
-	query := `SELECT data_table_name FROM collections WHERE slug = $1 AND tenant_id = $2`
-	err = d.db.QueryRowContext(ctx, query, collectionSlug, userTenantID).Scan(&dataTableName)
+	links, err := d.junctionWriter(ctx, tenantID, collectionSlug)
 	if err != nil {
-		return fmt.Errorf("collection not found: %w", err)
+		return "", err
 	}
-
-	// Use the data schema
-	fullTableName := fmt.Sprintf(`data.%s`, dataTableName)
-
-	// Check if table exists
-	tableExists, err := d.utils.TableExists(fullTableName)
+	encrypted, err := d.encryptedFields(ctx, tenantID, collectionSlug)
 	if err != nil {
-		return err
+		return "", err
 	}
-
-	if !tableExists {
-		return fmt.Errorf("table %s does not exist", fullTableName)
+	if data, err = encryptValues(encrypted, data); err != nil {
+		return "", err
 	}
 
-	// Build INSERT query dynamically
-	var columns []string
-	var placeholders []string
-	var values []interface{}
-
-	// Add standard columns
-	columns = append(columns, "created_by", "updated_by")
-	placeholders = append(placeholders, "$1", "$2")
-	values = append(values, userID, userID)
-
-	paramIndex := 3
-	for key, value := range data {
-		if key != "id" && key != "created_at" && key != "updated_at" {
-			columns = append(columns, fmt.Sprintf(`"%s"`, key))
-			placeholders = append(placeholders, fmt.Sprintf("$%d", paramIndex))
-			values = append(values, value)
-			paramIndex++
+	flags := d.collectionFlags(ctx, tenantID, collectionSlug)
+	var itemID string
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		if err := d.checkItemQuota(ctx, tx, tenantID, fullTableName, 1); err != nil {
+			return err
 		}
-	}
-
-	query := fmt.Sprintf(
-		"INSERT INTO %s (%s) VALUES (%s)",
-		fullTableName,
-		strings.Join(columns, ", "),
-		strings.Join(placeholders, ", "),
-	)
-
-	_, err = d.db.ExecContext(ctx, query, values...)
-	return err
-}
-
-// GetDynamicItem retrieves a specific item from a dynamic data table by ID
-func (d *DynamicHandlers) GetDynamicItem(ctx context.Context, userID uuid.UUID, tableName string, itemID string) (map[string]interface{}, error) {
-	// Get tenant schema
-	userTenantID, err := d.utils.GetUserTenantID(ctx, userID)
+		itemID, err = d.insertItem(ctx, tx, fullTableName, userID, data, flags, links)
+		if err != nil {
+			return err
+		}
+		return d.recordRevision(ctx, tx, tenantID, userID, collectionSlug, itemID, revisionCreate, nil, withID(data, itemID))
+	})
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 
-	tenantSchema, err := d.utils.GetTenantSchema(ctx, userTenantID)
-	if err != nil {
-		return nil, err
-	}
-
-	dataTableName := fmt.Sprintf(`"%s".data_%s`, tenantSchema, tableName)
+	d.publish(ctx, events.ItemCreate, userID, tenantID, collectionSlug, []string{itemID}, withID(data, itemID))
+	return itemID, nil
+}
 
-	// Check if table exists
-	tableExists, err := d.utils.TableExists(dataTableName)
+// GetDynamicItem retrieves a specific item from a dynamic data table by ID. Items in the
+// trash of a soft-delete collection are only returned when includeDeleted is set.
+func (d *DynamicHandlers) GetDynamicItem(ctx context.Context, userID uuid.UUID, tableName string, itemID string, includeDeleted bool) (map[string]interface{}, error) {
+	dataTableName, userTenantID, err := d.resolveDataTable(ctx, userID, tableName)
 	if err != nil {
 		return nil, err
 	}
 
-	if !tableExists {
-		return nil, fmt.Errorf("table %s does not exist", dataTableName)
-	}
-
 	// Set user context for RLS
 	_, err = d.db.Exec("SELECT set_user_context($1)", userID)
 	if err != nil {
@@ -150,7 +137,12 @@ func (d *DynamicHandlers) GetDynamicItem(ctx context.Context, userID uuid.UUID,
 
 	// Query the item
 	query := fmt.Sprintf("SELECT * FROM %s WHERE id = $1", dataTableName)
-	rows, err := d.db.QueryContext(ctx, query, itemID)
+	if !includeDeleted {
+		query += liveRowsOnly(d.softDeleteEnabled(ctx, userTenantID, tableName))
+	}
+	ruleCondition, args := rowFilterCondition(ctx, tableName, 2)
+	query += ruleCondition
+	rows, err := d.db.Reader().QueryContext(ctx, query, append([]interface{}{itemID}, args...)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query item: %w", err)
 	}
@@ -171,7 +163,7 @@ func (d *DynamicHandlers) GetDynamicItem(ctx context.Context, userID uuid.UUID,
 
 	// Check if we have a row
 	if !rows.Next() {
-		return nil, fmt.Errorf("item not found")
+		return nil, notFoundError("item not found")
 	}
 
 	// Scan the row
@@ -180,22 +172,17 @@ func (d *DynamicHandlers) GetDynamicItem(ctx context.Context, userID uuid.UUID,
 		return nil, fmt.Errorf("failed to scan row: %w", err)
 	}
 
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get column types: %w", err)
+	}
+
 	// Convert to map
 	result := make(map[string]interface{})
 	for i, col := range columns {
-		val := values[i]
-		if val != nil {
-			// Handle JSONB columns
-			if jsonBytes, ok := val.([]byte); ok {
-				var jsonData interface{}
-				if err := json.Unmarshal(jsonBytes, &jsonData); err == nil {
-					result[col] = jsonData
-				} else {
-					result[col] = string(jsonBytes)
-				}
-			} else {
-				result[col] = val
-			}
+		if values[i] != nil {
+			// Handle JSONB and array columns
+			result[col] = decodeColumnValue(columnTypes[i].DatabaseTypeName(), values[i])
 		}
 	}
 
@@ -204,108 +191,1042 @@ func (d *DynamicHandlers) GetDynamicItem(ctx context.Context, userID uuid.UUID,
 
 // UpdateDynamicItem updates an existing item in a dynamic data table
 func (d *DynamicHandlers) UpdateDynamicItem(ctx context.Context, userID uuid.UUID, tableName string, itemID string, data map[string]interface{}) error {
-	// Get tenant schema
-	userTenantID, err := d.utils.GetUserTenantID(ctx, userID)
+	dataTableName, tenantID, err := d.resolveDataTable(ctx, userID, tableName)
+	if err != nil {
+		return err
+	}
+
+	links, err := d.junctionWriter(ctx, tenantID, tableName)
+	if err != nil {
+		return err
+	}
+	encrypted, err := d.encryptedFields(ctx, tenantID, tableName)
+	if err != nil {
+		return err
+	}
+	if data, err = encryptValues(encrypted, data); err != nil {
+		return err
+	}
+
+	flags := d.collectionFlags(ctx, tenantID, tableName)
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		return d.updateItem(ctx, tx, dataTableName, tenantID, userID, tableName, itemID, data, flags, links)
+	})
 	if err != nil {
 		return err
 	}
 
-	tenantSchema, err := d.utils.GetTenantSchema(ctx, userTenantID)
+	d.publish(ctx, events.ItemUpdate, userID, tenantID, tableName, []string{itemID}, withID(data, itemID))
+	return nil
+}
+
+// DeleteDynamicItem deletes an item from a dynamic data table. In a soft-delete collection
+// the item is moved to the trash (deleted_at is set) instead of being removed.
+func (d *DynamicHandlers) DeleteDynamicItem(ctx context.Context, userID uuid.UUID, tableName string, itemID string) error {
+	dataTableName, tenantID, err := d.resolveDataTable(ctx, userID, tableName)
 	if err != nil {
 		return err
 	}
 
-	dataTableName := fmt.Sprintf(`"%s".data_%s`, tenantSchema, tableName)
+	softDelete := d.softDeleteEnabled(ctx, tenantID, tableName)
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		return d.deleteItem(ctx, tx, dataTableName, tenantID, userID, tableName, itemID, softDelete)
+	})
+	if err != nil {
+		return err
+	}
+
+	d.publish(ctx, events.ItemDelete, userID, tenantID, tableName, []string{itemID}, nil)
+	return nil
+}
 
-	// Check if table exists
-	exists, err := d.utils.TableExists(dataTableName)
+// PurgeDynamicItem permanently deletes an item, whether or not it is in the trash
+func (d *DynamicHandlers) PurgeDynamicItem(ctx context.Context, userID uuid.UUID, tableName string, itemID string) error {
+	dataTableName, tenantID, err := d.resolveDataTable(ctx, userID, tableName)
 	if err != nil {
-		return fmt.Errorf("failed to check table existence: %w", err)
+		return err
 	}
-	if !exists {
-		return fmt.Errorf("table %s does not exist", dataTableName)
+
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		return d.deleteItem(ctx, tx, dataTableName, tenantID, userID, tableName, itemID, false)
+	})
+	if err != nil {
+		return err
 	}
 
-	// Set user context for RLS
-	_, err = d.db.Exec("SELECT set_user_context($1)", userID)
+	d.publish(ctx, events.ItemDelete, userID, tenantID, tableName, []string{itemID}, nil)
+	return nil
+}
+
+// RestoreDynamicItem brings a soft-deleted item back out of the trash
+func (d *DynamicHandlers) RestoreDynamicItem(ctx context.Context, userID uuid.UUID, tableName string, itemID string) error {
+	dataTableName, tenantID, err := d.resolveDataTable(ctx, userID, tableName)
 	if err != nil {
-		return fmt.Errorf("failed to set user context: %w", err)
+		return err
 	}
 
-	// Build dynamic UPDATE query
-	if len(data) == 0 {
-		return fmt.Errorf("no data provided for update")
+	flags := d.collectionFlags(ctx, tenantID, tableName)
+	if !flags.SoftDelete {
+		return fmt.Errorf("soft delete is not enabled for collection %s", tableName)
 	}
 
-	setParts := make([]string, 0, len(data))
-	args := make([]interface{}, 0, len(data)+1)
-	argIndex := 1
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		var deletedAt time.Time
+		ruleCondition, args := rowFilterCondition(ctx, tableName, 2)
+		query := fmt.Sprintf("SELECT deleted_at FROM %s WHERE id = $1 AND deleted_at IS NOT NULL%s FOR UPDATE", dataTableName, ruleCondition)
+		if err := tx.QueryRowContext(ctx, query, append([]interface{}{itemID}, args...)...).Scan(&deletedAt); err != nil {
+			if err == sql.ErrNoRows {
+				return notFoundError("item not found in trash")
+			}
+			return fmt.Errorf("failed to restore item: %w", err)
+		}
 
-	for field, value := range data {
-		if field != "id" && field != "created_at" && field != "created_by" {
-			setParts = append(setParts, fmt.Sprintf(`"%s" = $%d`, field, argIndex))
-			args = append(args, value)
-			argIndex++
+		setParts, args := stampAssignments(flags, userID, 2)
+		query = fmt.Sprintf("UPDATE %s SET %s WHERE id = $1", dataTableName, strings.Join(append([]string{"deleted_at = NULL"}, setParts...), ", "))
+		if _, err := tx.ExecContext(ctx, query, append([]interface{}{itemID}, args...)...); err != nil {
+			return fmt.Errorf("failed to restore item: %w", err)
 		}
+
+		return d.recordRevision(ctx, tx, tenantID, userID, tableName, itemID, revisionUpdate,
+			map[string]interface{}{"deleted_at": deletedAt}, map[string]interface{}{"deleted_at": nil})
+	})
+	if err != nil {
+		return err
 	}
 
-	query := fmt.Sprintf("UPDATE %s SET %s, updated_at = CURRENT_TIMESTAMP, updated_by = $%d WHERE id = $%d",
-		dataTableName, strings.Join(setParts, ", "), argIndex, argIndex+1)
-	args = append(args, userID, itemID)
+	d.publish(ctx, events.ItemUpdate, userID, tenantID, tableName, []string{itemID}, map[string]interface{}{"id": itemID, "deleted_at": nil})
+	return nil
+}
 
-	// Execute update
-	result, err := d.db.Exec(query, args...)
+// BulkCreateDynamicItems inserts every item into a dynamic data table inside a single
+// transaction. Either all rows are written or, on the first failure, none are.
+// The returned IDs are in the same order as items; the returned error names the
+// zero-based index of the item that failed.
+func (d *DynamicHandlers) BulkCreateDynamicItems(ctx context.Context, userID uuid.UUID, collectionSlug string, items []map[string]interface{}) ([]string, error) {
+	fullTableName, userTenantID, err := d.resolveDataTable(ctx, userID, collectionSlug)
 	if err != nil {
-		return fmt.Errorf("failed to update item: %w", err)
+		return nil, err
 	}
 
-	rowsAffected, err := result.RowsAffected()
+	links, err := d.junctionWriter(ctx, userTenantID, collectionSlug)
 	if err != nil {
-		return fmt.Errorf("failed to get rows affected: %w", err)
+		return nil, err
+	}
+	encrypted, err := d.encryptedFields(ctx, userTenantID, collectionSlug)
+	if err != nil {
+		return nil, err
+	}
+	items, err = encryptItems(encrypted, items)
+	if err != nil {
+		return nil, err
 	}
 
-	if rowsAffected == 0 {
-		return fmt.Errorf("item not found or no changes made")
+	flags := d.collectionFlags(ctx, userTenantID, collectionSlug)
+	itemIDs := make([]string, len(items))
+	err = d.inTransaction(ctx, userID, userTenantID, func(tx *sql.Tx) error {
+		if err := d.checkItemQuota(ctx, tx, userTenantID, fullTableName, len(items)); err != nil {
+			return err
+		}
+		for i, item := range items {
+			itemID, err := d.insertItem(ctx, tx, fullTableName, userID, item, flags, links)
+			if err != nil {
+				return wrapError(err, "item %d", i)
+			}
+			if err := d.recordRevision(ctx, tx, userTenantID, userID, collectionSlug, itemID, revisionCreate, nil, withID(item, itemID)); err != nil {
+				return wrapError(err, "item %d", i)
+			}
+			itemIDs[i] = itemID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	payload := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		payload[i] = withID(item, itemIDs[i])
+	}
+	d.publish(ctx, events.ItemCreate, userID, userTenantID, collectionSlug, itemIDs, payload)
+
+	return itemIDs, nil
+}
+
+// upsertResult reports what an upsert did with one item
+type upsertResult struct {
+	ID      string
+	Created bool                   // false when an existing item was updated
+	Data    map[string]interface{} // The values written
+}
+
+// UpsertDynamicItems inserts or updates items keyed by the unique column key inside a
+// single transaction: an item whose key value matches an existing row updates that row
+// (only the given fields change), any other item is inserted. prepareInsert, when set,
+// returns the values to insert for item i, such as the item with field defaults filled in.
+func (d *DynamicHandlers) UpsertDynamicItems(ctx context.Context, userID uuid.UUID, tableName, key string, items []map[string]interface{}, prepareInsert func(i int) (map[string]interface{}, error)) ([]upsertResult, error) {
+	if !columnNamePattern.MatchString(key) {
+		return nil, validationError("invalid upsert field %q", key)
+	}
+
+	dataTableName, tenantID, err := d.resolveDataTable(ctx, userID, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	// ON CONFLICT needs a unique index on exactly this column
+	unique, err := d.db.Dialect().UniqueColumn(ctx, d.db, dataTableName, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check upsert field: %w", err)
+	}
+	if !unique {
+		return nil, validationError("field '%s' is not unique", key)
+	}
+
+	links, err := d.junctionWriter(ctx, tenantID, tableName)
+	if err != nil {
+		return nil, err
+	}
+	encrypted, err := d.encryptedFields(ctx, tenantID, tableName)
+	if err != nil {
+		return nil, err
+	}
+	items, err = encryptItems(encrypted, items)
+	if err != nil {
+		return nil, err
+	}
+
+	flags := d.collectionFlags(ctx, tenantID, tableName)
+	results := make([]upsertResult, len(items))
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		for i, item := range items {
+			insertData := func() (map[string]interface{}, error) {
+				if prepareInsert == nil {
+					return item, nil
+				}
+				data, err := prepareInsert(i)
+				if err != nil {
+					return nil, err
+				}
+				return encryptValues(encrypted, data)
+			}
+			result, err := d.upsertItem(ctx, tx, dataTableName, tenantID, userID, tableName, key, item, insertData, flags, links)
+			if err != nil {
+				return wrapError(err, "item %d", i)
+			}
+			results[i] = result
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var createdIDs, updatedIDs []string
+	var created, updated []map[string]interface{}
+	for _, result := range results {
+		if result.Created {
+			createdIDs = append(createdIDs, result.ID)
+			created = append(created, withID(result.Data, result.ID))
+		} else {
+			updatedIDs = append(updatedIDs, result.ID)
+			updated = append(updated, withID(result.Data, result.ID))
+		}
+	}
+	if len(created) > 0 {
+		d.publish(ctx, events.ItemCreate, userID, tenantID, tableName, createdIDs, created)
+	}
+	if len(updated) > 0 {
+		d.publish(ctx, events.ItemUpdate, userID, tenantID, tableName, updatedIDs, updated)
+	}
+
+	return results, nil
+}
+
+// columnNamePattern matches the column names imported rows may use
+var columnNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// ImportDynamicItems writes exported rows into a collection inside a single transaction,
+// keeping their IDs and timestamps so relations between imported items still hold. Rows
+// are moved to the collection's tenant. No revisions or events are recorded, as for a
+// restore.
+func (d *DynamicHandlers) ImportDynamicItems(ctx context.Context, userID uuid.UUID, collectionSlug string, rows []map[string]interface{}) error {
+	fullTableName, tenantID, err := d.resolveDataTable(ctx, userID, collectionSlug)
+	if err != nil {
+		return err
+	}
+
+	return d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		return d.reinsertRows(ctx, tx, fullTableName, tenantID, collectionSlug, rows)
+	})
+}
+
+// RestoreDynamicItems replaces every item of a collection, trashed ones included, with the
+// rows of an archive data file inside a single transaction, so readers see either the old
+// items or the restored ones. ext is the file type (.jsonl or .csv). It returns how many
+// items were restored. As for an import, no revisions or events are recorded.
+func (d *DynamicHandlers) RestoreDynamicItems(ctx context.Context, userID uuid.UUID, collectionSlug string, r io.Reader, ext string) (int, error) {
+	fullTableName, tenantID, err := d.resolveDataTable(ctx, userID, collectionSlug)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		query := fmt.Sprintf("DELETE FROM %s WHERE tenant_id = $1", fullTableName)
+		if _, err := tx.ExecContext(ctx, query, tenantID); err != nil {
+			return fmt.Errorf("failed to clear items: %w", err)
+		}
+		count, err = readArchiveRows(r, ext, tenantImportBatchSize, func(rows []map[string]interface{}) error {
+			return d.reinsertRows(ctx, tx, fullTableName, tenantID, collectionSlug, rows)
+		})
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	defaultResponseCache.invalidateCollection(collectionSlug)
+	return count, nil
+}
+
+// reinsertRows writes exported rows into a data table as they are, within the item quota
+func (d *DynamicHandlers) reinsertRows(ctx context.Context, tx *sql.Tx, fullTableName string, tenantID uuid.UUID, collectionSlug string, rows []map[string]interface{}) error {
+	if err := d.checkItemQuota(ctx, tx, tenantID, fullTableName, len(rows)); err != nil {
+		return err
+	}
+	for i, row := range rows {
+		for column := range row {
+			if !columnNamePattern.MatchString(column) {
+				return validationError("item %d: invalid column name %q", i, column)
+			}
+		}
+		row, err := d.encodeStoredValues(ctx, tenantID, collectionSlug, row)
+		if err != nil {
+			return wrapError(err, "item %d", i)
+		}
+		row["tenant_id"] = tenantID
+		if err := d.reinsertRow(ctx, tx, fullTableName, row); err != nil {
+			return wrapError(err, "item %d", i)
+		}
+	}
+	return nil
+}
+
+// BulkUpdateDynamicItems applies a set of partial updates, keyed by item ID, inside a
+// single transaction. Every item must exist; a missing item rolls back the whole batch.
+func (d *DynamicHandlers) BulkUpdateDynamicItems(ctx context.Context, userID uuid.UUID, tableName string, itemIDs []string, items []map[string]interface{}) error {
+	if len(itemIDs) != len(items) {
+		return validationError("expected %d item IDs, got %d", len(items), len(itemIDs))
+	}
+
+	dataTableName, userTenantID, err := d.resolveDataTable(ctx, userID, tableName)
+	if err != nil {
+		return err
+	}
+
+	links, err := d.junctionWriter(ctx, userTenantID, tableName)
+	if err != nil {
+		return err
+	}
+	encrypted, err := d.encryptedFields(ctx, userTenantID, tableName)
+	if err != nil {
+		return err
+	}
+	items, err = encryptItems(encrypted, items)
+	if err != nil {
+		return err
 	}
 
+	flags := d.collectionFlags(ctx, userTenantID, tableName)
+	err = d.inTransaction(ctx, userID, userTenantID, func(tx *sql.Tx) error {
+		for i, item := range items {
+			if err := d.updateItem(ctx, tx, dataTableName, userTenantID, userID, tableName, itemIDs[i], item, flags, links); err != nil {
+				return wrapError(err, "item %d (%s)", i, itemIDs[i])
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	payload := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		payload[i] = withID(item, itemIDs[i])
+	}
+	d.publish(ctx, events.ItemUpdate, userID, userTenantID, tableName, itemIDs, payload)
+
 	return nil
 }
 
-// DeleteDynamicItem deletes an item from a dynamic data table
-func (d *DynamicHandlers) DeleteDynamicItem(ctx context.Context, userID uuid.UUID, tableName string, itemID string) error {
-	// Get tenant schema
+// BulkDeleteDynamicItems deletes (or, in a soft-delete collection, trashes) the given items
+// inside a single transaction. Every item must exist; a missing item rolls back the whole batch.
+func (d *DynamicHandlers) BulkDeleteDynamicItems(ctx context.Context, userID uuid.UUID, tableName string, itemIDs []string) error {
+	dataTableName, userTenantID, err := d.resolveDataTable(ctx, userID, tableName)
+	if err != nil {
+		return err
+	}
+
+	softDelete := d.softDeleteEnabled(ctx, userTenantID, tableName)
+	err = d.inTransaction(ctx, userID, userTenantID, func(tx *sql.Tx) error {
+		for i, itemID := range itemIDs {
+			if err := d.deleteItem(ctx, tx, dataTableName, userTenantID, userID, tableName, itemID, softDelete); err != nil {
+				return wrapError(err, "item %d (%s)", i, itemID)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	d.publish(ctx, events.ItemDelete, userID, userTenantID, tableName, itemIDs, nil)
+	return nil
+}
+
+// RevertDynamicItem undoes the change recorded in a revision: a created item is deleted,
+// an update has its previous values written back, and a deleted item is restored from the
+// trash or, if it was removed for good, re-inserted from the revision's snapshot. The
+// revert is itself recorded as a new revision.
+func (d *DynamicHandlers) RevertDynamicItem(ctx context.Context, userID uuid.UUID, tableName string, revision sqlc.Revision) error {
+	itemID := revision.ItemID.String()
+
+	switch revision.Action {
+	case revisionCreate:
+		return d.DeleteDynamicItem(ctx, userID, tableName, itemID)
+
+	case revisionUpdate:
+		values, err := decodeRevisionData(revision.OldData)
+		if err != nil {
+			return err
+		}
+
+		// Reverting a restore moves the item back to the trash
+		if _, ok := values["deleted_at"]; ok && len(values) == 1 {
+			return d.DeleteDynamicItem(ctx, userID, tableName, itemID)
+		}
+
+		// Audit columns are maintained by the update itself
+		delete(values, "updated_at")
+		delete(values, "updated_by")
+		tenantID, err := d.utils.GetUserTenantID(ctx, userID)
+		if err != nil {
+			return err
+		}
+		encoded, err := d.encodeStoredValues(ctx, tenantID, tableName, values)
+		if err != nil {
+			return err
+		}
+		return d.UpdateDynamicItem(ctx, userID, tableName, itemID, encoded)
+
+	case revisionDelete:
+		dataTableName, tenantID, err := d.resolveDataTable(ctx, userID, tableName)
+		if err != nil {
+			return err
+		}
+
+		// A soft-deleted item that is still in the trash only needs restoring
+		if d.softDeleteEnabled(ctx, tenantID, tableName) {
+			err := d.RestoreDynamicItem(ctx, userID, tableName, itemID)
+			if err == nil || !errors.Is(err, ErrNotFound) {
+				return err
+			}
+		}
+
+		snapshot, err := decodeRevisionData(revision.OldData)
+		if err != nil {
+			return err
+		}
+		// The row comes back live even if it was purged from the trash
+		if _, ok := snapshot["deleted_at"]; ok {
+			snapshot["deleted_at"] = nil
+		}
+
+		encoded, err := d.encodeStoredValues(ctx, tenantID, tableName, snapshot)
+		if err != nil {
+			return err
+		}
+		err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+			if err := d.reinsertRow(ctx, tx, dataTableName, encoded); err != nil {
+				return err
+			}
+			return d.recordRevision(ctx, tx, tenantID, userID, tableName, itemID, revisionCreate, nil, snapshot)
+		})
+		if err != nil {
+			return err
+		}
+
+		d.publish(ctx, events.ItemCreate, userID, tenantID, tableName, []string{itemID}, snapshot)
+		return nil
+
+	default:
+		return fmt.Errorf("unsupported revision action %q", revision.Action)
+	}
+}
+
+// junctionWriter writes the many-to-many fields of a collection's items to their junction
+// tables. A nil writer belongs to a collection without such fields and passes data through.
+type junctionWriter struct {
+	dialect      db.Dialect
+	tenantSchema string
+	relations    map[string]RelationConfig
+}
+
+// junctionWriter returns the writer for a collection, or nil when it has no many-to-many
+// fields
+func (d *DynamicHandlers) junctionWriter(ctx context.Context, tenantID uuid.UUID, collection string) (*junctionWriter, error) {
+	relations, err := manyToManyFields(ctx, d.db, tenantID, collection)
+	if err != nil || len(relations) == 0 {
+		return nil, err
+	}
+	tenantSchema, err := d.utils.GetTenantSchema(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return &junctionWriter{dialect: d.utils.dialect(), tenantSchema: tenantSchema, relations: relations}, nil
+}
+
+// split separates the columns of an item's row from the related IDs of its many-to-many
+// fields
+func (w *junctionWriter) split(data map[string]interface{}) (map[string]interface{}, map[string][]string, error) {
+	if w == nil {
+		return data, nil, nil
+	}
+
+	row := make(map[string]interface{}, len(data))
+	related := make(map[string][]string)
+	for key, value := range data {
+		if _, ok := w.relations[key]; !ok {
+			row[key] = value
+			continue
+		}
+		ids, err := relatedIDList(value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("field '%s': %w", key, err)
+		}
+		related[key] = ids
+	}
+	return row, related, nil
+}
+
+// write replaces the links of an item for every field in related
+func (w *junctionWriter) write(ctx context.Context, tx *sql.Tx, itemID string, related map[string][]string) error {
+	for name, ids := range related {
+		cfg := w.relations[name]
+		if err := writeJunction(ctx, tx, cfg.JunctionTable(w.dialect, w.tenantSchema), cfg, itemID, ids); err != nil {
+			return fmt.Errorf("field '%s': %w", name, err)
+		}
+	}
+	return nil
+}
+
+// snapshot adds the current links of the fields in related to before, so that a revision
+// can bring them back
+func (w *junctionWriter) snapshot(ctx context.Context, tx *sql.Tx, itemID string, related map[string][]string, before map[string]interface{}) error {
+	for name := range related {
+		cfg := w.relations[name]
+		links, err := readJunction(ctx, tx, cfg.JunctionTable(w.dialect, w.tenantSchema), cfg, []string{itemID})
+		if err != nil {
+			return err
+		}
+		ids := links[itemID]
+		if ids == nil {
+			ids = []string{}
+		}
+		before[name] = ids
+	}
+	return nil
+}
+
+// sqlExecutor is the subset of *sql.DB and *sql.Tx used to write dynamic rows, so the
+// same statement builders serve single-item requests and transactional bulk requests.
+type sqlExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// publish emits an item event on the bus after a successful write
+func (d *DynamicHandlers) publish(ctx context.Context, eventType string, userID, tenantID uuid.UUID, collection string, keys []string, data interface{}) {
+	d.events.Publish(ctx, events.Event{
+		Type:       eventType,
+		TenantID:   tenantID,
+		Collection: collection,
+		Keys:       keys,
+		Data:       data,
+		UserID:     userID,
+	})
+}
+
+// withID returns a copy of data with its "id" set, for event payloads
+func withID(data map[string]interface{}, itemID string) map[string]interface{} {
+	result := make(map[string]interface{}, len(data)+1)
+	for key, value := range data {
+		result[key] = value
+	}
+	result["id"] = itemID
+	return result
+}
+
+// inTransaction runs fn inside a transaction with the RLS user context applied.
+// set_user_context uses transaction-local settings, so it is issued on the same tx.
+func (d *DynamicHandlers) inTransaction(ctx context.Context, userID, tenantID uuid.UUID, fn func(tx *sql.Tx) error) error {
+	return d.db.InTransaction(ctx, func(tx *db.Tx) error {
+		if _, err := tx.ExecContext(ctx, "SELECT set_user_context($1, $2)", userID, tenantID); err != nil {
+			return fmt.Errorf("failed to set user context: %w", err)
+		}
+		return fn(tx.Tx)
+	})
+}
+
+// checkItemQuota fails when adding items would take a collection past the tenant's
+// max_items_per_collection. The rows are counted inside tx, next to the insert.
+func (d *DynamicHandlers) checkItemQuota(ctx context.Context, tx *sql.Tx, tenantID uuid.UUID, fullTableName string, adding int) error {
+	limits, err := d.quota.Limits(ctx, tenantID)
+	if err != nil || limits.MaxItemsPerCollection == 0 {
+		return err
+	}
+	count, err := quota.CountItems(ctx, tx, fullTableName)
+	if err != nil {
+		return fmt.Errorf("failed to count items: %w", err)
+	}
+	return quota.Check(quota.LimitItemsPerCollection, int64(limits.MaxItemsPerCollection), count, int64(adding))
+}
+
+// resolveDataTable returns the data table of a collection for reads and writes, along with
+// the tenant it belongs to. See table_resolver.go for the layouts it resolves.
+func (d *DynamicHandlers) resolveDataTable(ctx context.Context, userID uuid.UUID, collectionSlug string) (string, uuid.UUID, error) {
 	userTenantID, err := d.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+
+	table, err := d.utils.ResolveDataTable(ctx, userTenantID, collectionSlug)
+	if err != nil {
+		return "", uuid.Nil, err
+	}
+	return table.String(), userTenantID, nil
+}
+
+// insertItem inserts an item along with its many-to-many links and returns its ID
+func (d *DynamicHandlers) insertItem(ctx context.Context, tx *sql.Tx, fullTableName string, userID uuid.UUID, data map[string]interface{}, flags collectionFlags, links *junctionWriter) (string, error) {
+	row, related, err := links.split(data)
+	if err != nil {
+		return "", err
+	}
+	itemID, err := d.insertRow(ctx, tx, fullTableName, userID, row, flags)
+	if err != nil {
+		return "", err
+	}
+	return itemID, links.write(ctx, tx, itemID, related)
+}
+
+// updateItem applies a partial update to one item, including its many-to-many links, and
+// records the previous values of the changed fields as a revision
+func (d *DynamicHandlers) updateItem(ctx context.Context, tx *sql.Tx, dataTableName string, tenantID, userID uuid.UUID, collection, itemID string, data map[string]interface{}, flags collectionFlags, links *junctionWriter) error {
+	if len(data) == 0 {
+		return validationError("no data provided for update")
+	}
+
+	before, err := d.snapshotRow(ctx, tx, dataTableName, collection, itemID, flags.SoftDelete)
 	if err != nil {
 		return err
 	}
+	// The row is locked, so it cannot change between this check and the update
+	if err := checkUpdatePrecondition(ctx, before); err != nil {
+		return err
+	}
 
-	tenantSchema, err := d.utils.GetTenantSchema(ctx, userTenantID)
+	row, related, err := links.split(data)
 	if err != nil {
 		return err
 	}
+	if err := links.snapshot(ctx, tx, itemID, related, before); err != nil {
+		return err
+	}
+
+	if err := d.updateRow(ctx, tx, dataTableName, userID, itemID, row, flags); err != nil {
+		return err
+	}
+	if err := links.write(ctx, tx, itemID, related); err != nil {
+		return err
+	}
+
+	oldValues := make(map[string]interface{}, len(data))
+	for field := range data {
+		oldValues[field] = before[field]
+	}
+	return d.recordRevision(ctx, tx, tenantID, userID, collection, itemID, revisionUpdate, oldValues, data)
+}
+
+// upsertItem updates the row whose key column equals the item's key value, or inserts the
+// values returned by insertData when there is none. The insert uses ON CONFLICT DO NOTHING,
+// so an item inserted concurrently by another request is updated instead.
+func (d *DynamicHandlers) upsertItem(ctx context.Context, tx *sql.Tx, dataTableName string, tenantID, userID uuid.UUID, collection, key string, item map[string]interface{}, insertData func() (map[string]interface{}, error), flags collectionFlags, links *junctionWriter) (upsertResult, error) {
+	value := item[key]
+	if value == nil {
+		return upsertResult{}, validationError("'%s' is required to upsert an item", key)
+	}
+
+	// A second pass only happens after losing an insert race
+	for attempt := 0; attempt < 2; attempt++ {
+		var itemID string
+		query := fmt.Sprintf(`SELECT id FROM %s WHERE "%s" = $1 FOR UPDATE`, dataTableName, key)
+		err := tx.QueryRowContext(ctx, query, value).Scan(&itemID)
+		if err != nil && err != sql.ErrNoRows {
+			return upsertResult{}, fmt.Errorf("failed to look up item: %w", err)
+		}
+
+		if itemID != "" {
+			if err := d.updateItem(ctx, tx, dataTableName, tenantID, userID, collection, itemID, item, flags, links); err != nil {
+				return upsertResult{}, wrapError(err, "item %s with %s %v", itemID, key, value)
+			}
+			return upsertResult{ID: itemID, Data: item}, nil
+		}
+
+		data, err := insertData()
+		if err != nil {
+			return upsertResult{}, err
+		}
+		if err := d.checkItemQuota(ctx, tx, tenantID, dataTableName, 1); err != nil {
+			return upsertResult{}, err
+		}
+		row, related, err := links.split(data)
+		if err != nil {
+			return upsertResult{}, err
+		}
+
+		insert, values := insertStatement(dataTableName, userID, row, flags)
+		insert += fmt.Sprintf(` ON CONFLICT ("%s") DO NOTHING RETURNING id`, key)
+		err = tx.QueryRowContext(ctx, insert, values...).Scan(&itemID)
+		if err == sql.ErrNoRows {
+			continue // Inserted by someone else in the meantime
+		}
+		if err != nil {
+			return upsertResult{}, err
+		}
+
+		if err := links.write(ctx, tx, itemID, related); err != nil {
+			return upsertResult{}, err
+		}
+		if err := d.recordRevision(ctx, tx, tenantID, userID, collection, itemID, revisionCreate, nil, withID(data, itemID)); err != nil {
+			return upsertResult{}, err
+		}
+		return upsertResult{ID: itemID, Created: true, Data: data}, nil
+	}
 
-	dataTableName := fmt.Sprintf(`"%s".data_%s`, tenantSchema, tableName)
+	return upsertResult{}, conflictError("item with %s %v was changed concurrently; try again", key, value)
+}
 
-	// Check if table exists
-	exists, err := d.utils.TableExists(dataTableName)
+// deleteItem deletes (or trashes) one item and records the removed row as a revision
+func (d *DynamicHandlers) deleteItem(ctx context.Context, tx *sql.Tx, dataTableName string, tenantID, userID uuid.UUID, collection, itemID string, softDelete bool) error {
+	before, err := d.snapshotRow(ctx, tx, dataTableName, collection, itemID, softDelete)
 	if err != nil {
-		return fmt.Errorf("failed to check table existence: %w", err)
+		return err
 	}
-	if !exists {
-		return fmt.Errorf("table %s does not exist", dataTableName)
+
+	if err := d.deleteRow(ctx, tx, dataTableName, itemID, softDelete); err != nil {
+		return err
 	}
 
-	// Set user context for RLS
-	_, err = d.db.Exec("SELECT set_user_context($1)", userID)
+	return d.recordRevision(ctx, tx, tenantID, userID, collection, itemID, revisionDelete, before, nil)
+}
+
+// snapshotRow reads every column of an item and locks the row until the transaction ends.
+// Every update and delete starts here, so rows outside the caller's row filter are
+// reported as missing and never written.
+func (d *DynamicHandlers) snapshotRow(ctx context.Context, exec db.Executor, dataTableName, collection string, itemID string, softDelete bool) (map[string]interface{}, error) {
+	ruleCondition, args := rowFilterCondition(ctx, collection, 2)
+	args = append([]interface{}{itemID}, args...)
+	if !d.db.Dialect().RowJSON() {
+		return snapshotColumns(ctx, exec, dataTableName, liveRowsOnly(softDelete)+ruleCondition, args)
+	}
+	query := fmt.Sprintf("SELECT to_jsonb(t) FROM %s t WHERE id = $1%s%s FOR UPDATE", dataTableName, liveRowsOnly(softDelete), ruleCondition)
+
+	var raw []byte
+	if err := exec.QueryRowContext(ctx, query, args...).Scan(&raw); err != nil {
+		if err == sql.ErrNoRows {
+			return nil, notFoundError("item not found")
+		}
+		return nil, fmt.Errorf("failed to read item: %w", err)
+	}
+
+	return decodeRevisionData(pqtype.NullRawMessage{RawMessage: raw, Valid: true})
+}
+
+// snapshotColumns is snapshotRow for databases without to_jsonb: the row is read column by
+// column and goes through JSON, so that its values are those to_jsonb would give
+func snapshotColumns(ctx context.Context, exec db.Executor, dataTableName, conditions string, args []interface{}) (map[string]interface{}, error) {
+	rows, err := exec.QueryContext(ctx, fmt.Sprintf("SELECT * FROM %s t WHERE id = $1%s FOR UPDATE", dataTableName, conditions), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item: %w", err)
+	}
+	defer rows.Close()
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read item: %w", err)
+		}
+		return nil, notFoundError("item not found")
+	}
+	columns, err := rows.Columns()
 	if err != nil {
-		return fmt.Errorf("failed to set user context: %w", err)
+		return nil, fmt.Errorf("failed to read item: %w", err)
+	}
+	row, err := scanRowToMap(rows, columns)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item: %w", err)
+	}
+
+	raw, err := json.Marshal(row)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read item: %w", err)
+	}
+	return decodeRevisionData(pqtype.NullRawMessage{RawMessage: raw, Valid: true})
+}
+
+// recordRevision stores one entry of an item's change history on the write's transaction
+func (d *DynamicHandlers) recordRevision(ctx context.Context, tx *sql.Tx, tenantID, userID uuid.UUID, collection, itemID, action string, oldData, newData map[string]interface{}) error {
+	id, err := uuid.Parse(itemID)
+	if err != nil {
+		return validationError("invalid item ID %q: %w", itemID, err)
+	}
+
+	oldJSON, err := encodeRevisionData(oldData)
+	if err != nil {
+		return err
+	}
+	newJSON, err := encodeRevisionData(newData)
+	if err != nil {
+		return err
+	}
+
+	_, err = d.db.Queries.WithTx(tx).CreateRevision(ctx, sqlc.CreateRevisionParams{
+		TenantID:   tenantID,
+		Collection: collection,
+		ItemID:     id,
+		Action:     action,
+		OldData:    oldJSON,
+		NewData:    newJSON,
+		UserID:     uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record revision: %w", err)
+	}
+	return nil
+}
+
+// encodeRevisionData converts revision values to a nullable JSONB column value
+func encodeRevisionData(data map[string]interface{}) (pqtype.NullRawMessage, error) {
+	if data == nil {
+		return pqtype.NullRawMessage{}, nil
+	}
+
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return pqtype.NullRawMessage{}, fmt.Errorf("failed to encode revision data: %w", err)
+	}
+	return pqtype.NullRawMessage{RawMessage: raw, Valid: true}, nil
+}
+
+// decodeRevisionData parses a revision's JSONB values. Numbers are kept as json.Number so
+// large integers survive a revert unchanged.
+func decodeRevisionData(raw pqtype.NullRawMessage) (map[string]interface{}, error) {
+	if !raw.Valid {
+		return nil, fmt.Errorf("revision has no data to revert to")
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw.RawMessage))
+	decoder.UseNumber()
+
+	var data map[string]interface{}
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode revision data: %w", err)
+	}
+	return data, nil
+}
+
+// encodeJSONValues returns a copy of data with nested objects and arrays encoded as JSON
+// text, which is how JSON columns must be passed to the driver
+func encodeJSONValues(data map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		switch value.(type) {
+		case map[string]interface{}, []interface{}:
+			if raw, err := json.Marshal(value); err == nil {
+				value = string(raw)
+			}
+		}
+		result[key] = value
+	}
+	return result
+}
+
+// softDeleteEnabled reports whether the collection keeps deleted items in a trash. Tables
+// that are not user collections (or a failed lookup) use hard deletes.
+func (d *DynamicHandlers) softDeleteEnabled(ctx context.Context, tenantID uuid.UUID, collectionSlug string) bool {
+	return d.collectionFlags(ctx, tenantID, collectionSlug).SoftDelete
+}
+
+// liveRowsOnly returns the WHERE clause suffix that hides trashed rows of a soft-delete table
+func liveRowsOnly(softDelete bool) string {
+	if softDelete {
+		return " AND deleted_at IS NULL"
+	}
+	return ""
+}
+
+// rowFilterCondition returns the row filter stored in ctx for collection as a WHERE clause
+// suffix (" AND (...)") with placeholders numbered from paramIndex, or "" when the caller
+// is not restricted
+func rowFilterCondition(ctx context.Context, collection string, paramIndex int) (string, []interface{}) {
+	condition, args := rbac.RowFilterFromContext(ctx, collection).SQL(paramIndex)
+	if condition == "" {
+		return "", nil
+	}
+	return " AND " + condition, args
+}
+
+// insertRow builds and executes the INSERT for a single item and returns the new item's ID
+func (d *DynamicHandlers) insertRow(ctx context.Context, exec sqlExecutor, fullTableName string, userID uuid.UUID, data map[string]interface{}, flags collectionFlags) (string, error) {
+	query, values := insertStatement(fullTableName, userID, data, flags)
+
+	var itemID string
+	if err := exec.QueryRowContext(ctx, query+" RETURNING id", values...).Scan(&itemID); err != nil {
+		return "", err
+	}
+	return itemID, nil
+}
+
+// insertStatement builds the INSERT of an item, without a RETURNING clause. Items of a
+// sortable collection without a sort value are placed last.
+func insertStatement(fullTableName string, userID uuid.UUID, data map[string]interface{}, flags collectionFlags) (string, []interface{}) {
+	// Build INSERT query dynamically
+	var columns []string
+	var placeholders []string
+	var values []interface{}
+
+	// Add standard columns
+	if flags.UserStamps {
+		columns = append(columns, "created_by", "updated_by")
+		placeholders = append(placeholders, "$1", "$2")
+		values = append(values, userID, userID)
+	}
+	if _, ok := data[sortColumn]; flags.Sortable && !ok {
+		columns = append(columns, sortColumn)
+		placeholders = append(placeholders, fmt.Sprintf("(SELECT COALESCE(MAX(%s), 0) + 1 FROM %s)", sortColumn, fullTableName))
 	}
 
-	// Execute delete
+	paramIndex := len(values) + 1
+	for key, value := range data {
+		if key != "id" && key != "created_at" && key != "updated_at" {
+			columns = append(columns, fmt.Sprintf(`"%s"`, key))
+			placeholders = append(placeholders, fmt.Sprintf("$%d", paramIndex))
+			values = append(values, value)
+			paramIndex++
+		}
+	}
+
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES (%s)",
+		fullTableName,
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "),
+	)
+	return query, values
+}
+
+// reinsertRow writes a previously deleted row back with all of its original columns
+func (d *DynamicHandlers) reinsertRow(ctx context.Context, exec sqlExecutor, dataTableName string, row map[string]interface{}) error {
+	columns := make([]string, 0, len(row))
+	placeholders := make([]string, 0, len(row))
+	values := make([]interface{}, 0, len(row))
+
+	for column, value := range row {
+		columns = append(columns, fmt.Sprintf(`"%s"`, column))
+		placeholders = append(placeholders, fmt.Sprintf("$%d", len(values)+1))
+		values = append(values, value)
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		dataTableName, strings.Join(columns, ", "), strings.Join(placeholders, ", "))
+	if _, err := exec.ExecContext(ctx, query, values...); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "unique_violation" {
+			return conflictError("item already exists")
+		}
+		return fmt.Errorf("failed to re-create item: %w", err)
+	}
+	return nil
+}
+
+// updateRow builds and executes the UPDATE for a single item. Trashed items of a
+// soft-delete table are treated as missing.
+func (d *DynamicHandlers) updateRow(ctx context.Context, exec sqlExecutor, dataTableName string, userID uuid.UUID, itemID string, data map[string]interface{}, flags collectionFlags) error {
+	// Build dynamic UPDATE query
+	setParts := make([]string, 0, len(data)+2)
+	args := make([]interface{}, 0, len(data)+1)
+	argIndex := 1
+
+	for field, value := range data {
+		if field != "id" && field != "created_at" && field != "created_by" && field != "deleted_at" {
+			setParts = append(setParts, fmt.Sprintf(`"%s" = $%d`, field, argIndex))
+			args = append(args, value)
+			argIndex++
+		}
+	}
+
+	stamps, stampArgs := stampAssignments(flags, userID, argIndex)
+	setParts = append(setParts, stamps...)
+	args = append(args, stampArgs...)
+	if len(setParts) == 0 {
+		return validationError("no data provided for update")
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $%d%s",
+		dataTableName, strings.Join(setParts, ", "), len(args)+1, liveRowsOnly(flags.SoftDelete))
+	args = append(args, itemID)
+
+	// Execute update
+	result, err := exec.ExecContext(ctx, query, args...)
+	if err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	if rowsAffected == 0 {
+		return notFoundError("item not found or no changes made")
+	}
+
+	return nil
+}
+
+// stampAssignments returns the SET assignments recording who changed an item and when, as
+// far as the collection keeps track, with the user ID as parameter paramIndex
+func stampAssignments(flags collectionFlags, userID uuid.UUID, paramIndex int) ([]string, []interface{}) {
+	var assignments []string
+	var args []interface{}
+	if flags.Timestamps {
+		assignments = append(assignments, "updated_at = CURRENT_TIMESTAMP")
+	}
+	if flags.UserStamps {
+		assignments = append(assignments, fmt.Sprintf("updated_by = $%d", paramIndex))
+		args = append(args, userID)
+	}
+	return assignments, args
+}
+
+// deleteRow executes the DELETE for a single item, or moves it to the trash when softDelete is set
+func (d *DynamicHandlers) deleteRow(ctx context.Context, exec sqlExecutor, dataTableName string, itemID string, softDelete bool) error {
 	query := fmt.Sprintf("DELETE FROM %s WHERE id = $1", dataTableName)
-	result, err := d.db.Exec(query, itemID)
+	if softDelete {
+		query = fmt.Sprintf("UPDATE %s SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL", dataTableName)
+	}
+	result, err := exec.ExecContext(ctx, query, itemID)
 	if err != nil {
-		return fmt.Errorf("failed to delete item: %w", err)
+		// Relations with a restrict rule may still point at the item (see relation_rules.go)
+		return d.restrictedDelete(ctx, fmt.Errorf("failed to delete item: %w", err))
 	}
 
 	rowsAffected, err := result.RowsAffected()
@@ -314,7 +1235,7 @@ func (d *DynamicHandlers) DeleteDynamicItem(ctx context.Context, userID uuid.UUI
 	}
 
 	if rowsAffected == 0 {
-		return fmt.Errorf("item not found")
+		return notFoundError("item not found")
 	}
 
 	return nil
@@ -0,0 +1,41 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLiveRowsOnly(t *testing.T) {
+	assert.Equal(t, " AND deleted_at IS NULL", liveRowsOnly(true))
+	assert.Equal(t, "", liveRowsOnly(false))
+}
+
+func TestItemsHandler_RestoreRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &ItemsHandler{}
+	router := gin.New()
+	router.POST("/items/:table/:id/restore", handler.RestoreItem)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"Invalid Table Name", "/items/bad-name!/6e68062f-c4c6-42df-9e01-e2d1081664f4/restore", http.StatusBadRequest},
+		{"Invalid Item ID", "/items/products/not-a-uuid/restore", http.StatusBadRequest},
+		{"Unauthenticated", "/items/products/6e68062f-c4c6-42df-9e01-e2d1081664f4/restore", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, nil))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,249 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the typed errors of item writes and the error envelope they are
+// answered with.
+//
+// Failures below the handlers are returned as one of four kinds, each with its own status
+// and machine-readable code:
+//
+//	ErrNotFound   - 404 not_found          (missing item or collection)
+//	ErrConflict   - 409 conflict           (unique violations, concurrent changes, restricted deletes)
+//	ErrValidation - 422 validation_failed  (bad field values, constraint violations)
+//	ErrForbidden  - 403 forbidden          (writes refused by the database)
+//
+// Postgres errors are classified by SQLSTATE, so unique violations and the like need no
+// wrapping. Anything else is answered with 500 internal_error and a generic message; the
+// underlying error is logged rather than returned. Every error response has the shape
+// {"error": "<message>", "code": "<code>"}; failed collection validation adds every
+// invalid field as "errors": [{"field", "code", "message"}], and a delete blocked by an
+// on_delete restrict rule names the relation as "restricted_by": {"collection", "field"}.
+// A refused collection slug lists free alternatives as "suggestions".
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+)
+
+// Machine-readable codes of error responses
+const (
+	CodeNotFound      = "not_found"
+	CodeConflict      = "conflict"
+	CodeValidation    = "validation_failed"
+	CodeForbidden     = "forbidden"
+	CodeQuotaExceeded = "quota_exceeded"
+	CodeInternal      = "internal_error"
+
+	CodePayloadTooLarge = "payload_too_large" // Request body larger than MAX_BODY_SIZE
+	CodeInvalidBody     = "invalid_body"      // Malformed or too deeply nested request body
+
+	CodeIdempotencyKeyReused  = "idempotency_key_reused"  // Idempotency-Key sent again with a different request
+	CodeInvalidIdempotencyKey = "invalid_idempotency_key" // Idempotency-Key longer than 255 characters
+)
+
+// Error kinds. Match them with errors.Is.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForbidden  = errors.New("forbidden")
+)
+
+// errorKinds maps each error kind to its response status and code
+var errorKinds = []struct {
+	kind   error
+	status int
+	code   string
+}{
+	{ErrNotFound, http.StatusNotFound, CodeNotFound},
+	{ErrConflict, http.StatusConflict, CodeConflict},
+	{ErrValidation, http.StatusUnprocessableEntity, CodeValidation},
+	{ErrForbidden, http.StatusForbidden, CodeForbidden},
+}
+
+// Codes of field errors. Values failing a validation rule use the rule's name instead,
+// e.g. "max_length" or "pattern".
+const (
+	FieldCodeUnknown          = "unknown_field"
+	FieldCodeReadOnly         = "read_only"
+	FieldCodeRequired         = "required"
+	FieldCodeInvalidType      = "invalid_type"
+	FieldCodeInvalidValue     = "invalid_value"
+	FieldCodeInvalidReference = "invalid_reference"
+)
+
+// FieldError is one invalid field of a write
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FieldErrors are all invalid fields of a write. Validation errors wrapping them are
+// answered with the list under "errors".
+type FieldErrors []FieldError
+
+func (e FieldErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, fieldErr := range e {
+		messages[i] = fmt.Sprintf("field '%s': %s", fieldErr.Field, fieldErr.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// kindError is an error of one kind. Its message is returned to the client as is.
+type kindError struct {
+	kind    error
+	message string
+	err     error // Wrapped cause, if any
+}
+
+func (e *kindError) Error() string {
+	return e.message
+}
+
+func (e *kindError) Unwrap() []error {
+	if e.err == nil {
+		return []error{e.kind}
+	}
+	return []error{e.kind, e.err}
+}
+
+// newKindError formats a message like fmt.Errorf, keeping a %w operand as the cause
+func newKindError(kind error, format string, args ...interface{}) error {
+	err := fmt.Errorf(format, args...)
+	return &kindError{kind: kind, message: err.Error(), err: errors.Unwrap(err)}
+}
+
+// notFoundError returns an ErrNotFound error with a formatted message
+func notFoundError(format string, args ...interface{}) error {
+	return newKindError(ErrNotFound, format, args...)
+}
+
+// conflictError returns an ErrConflict error with a formatted message
+func conflictError(format string, args ...interface{}) error {
+	return newKindError(ErrConflict, format, args...)
+}
+
+// validationError returns an ErrValidation error with a formatted message
+func validationError(format string, args ...interface{}) error {
+	return newKindError(ErrValidation, format, args...)
+}
+
+// forbiddenError returns an ErrForbidden error with a formatted message
+func forbiddenError(format string, args ...interface{}) error {
+	return newKindError(ErrForbidden, format, args...)
+}
+
+// wrapError prefixes err's message with a formatted context, such as the index of an item
+// in a bulk request, keeping its kind. Errors of no known kind are wrapped with fmt.Errorf.
+func wrapError(err error, format string, args ...interface{}) error {
+	prefix := fmt.Sprintf(format, args...)
+	kindErr, ok := asKindError(err)
+	if !ok {
+		return fmt.Errorf("%s: %w", prefix, err)
+	}
+	return &kindError{kind: kindErr.kind, message: prefix + ": " + kindErr.message, err: err}
+}
+
+// asKindError returns the outermost error of a known kind in err's chain. Postgres errors
+// caused by the request's data and missing data tables count as such errors.
+func asKindError(err error) (*kindError, bool) {
+	var kindErr *kindError
+	if errors.As(err, &kindErr) {
+		return kindErr, true
+	}
+	if errors.Is(err, ErrDataTableNotFound) {
+		return &kindError{kind: ErrNotFound, message: "collection not found", err: err}, true
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		kindErr, ok := databaseError(pqErr).(*kindError)
+		return kindErr, ok
+	}
+	return nil, false
+}
+
+// classifyError returns the status, code and client message for err. Errors of no known
+// kind are 500 internal_error with an empty message.
+func classifyError(err error) (int, string, string) {
+	kindErr, ok := asKindError(err)
+	for _, kind := range errorKinds {
+		switch {
+		case ok && kindErr.kind == kind.kind:
+			return kind.status, kind.code, kindErr.message
+		case !ok && errors.Is(err, kind.kind):
+			return kind.status, kind.code, err.Error()
+		}
+	}
+	return http.StatusInternalServerError, CodeInternal, ""
+}
+
+// databaseError converts a Postgres error caused by the request's data to an error of
+// the matching kind, worded without the names of internal tables. Other errors are
+// returned unchanged.
+func databaseError(err *pq.Error) error {
+	switch err.Code.Name() {
+	case "unique_violation":
+		if err.Detail != "" {
+			return conflictError("%s", err.Detail)
+		}
+		return conflictError("an item with this value already exists")
+	case "foreign_key_violation":
+		// Deletes fail while other items still point at the row, writes when they point nowhere
+		if strings.Contains(err.Detail, "is still referenced") {
+			return conflictError("other items still point at this item")
+		}
+		return validationError("a related item does not exist")
+	case "not_null_violation":
+		return validationError("field '%s' is required", err.Column)
+	case "check_violation":
+		return validationError("value violates check constraint '%s'", err.Constraint)
+	case "undefined_column":
+		return validationError("%s", err.Message)
+	case "insufficient_privilege":
+		return forbiddenError("%s", err.Message)
+	}
+	// Class 22 is data exceptions: invalid input syntax, out of range values and the like
+	if err.Code.Class() == "22" {
+		return validationError("%s", err.Message)
+	}
+	return err
+}
+
+// respondError answers a failed request with the status and code of err's kind. message
+// says what failed (e.g. "Failed to update item"); client errors append their own text,
+// while internal errors are logged and answered with message alone. Tenant limits keep
+// the fields of respondQuotaExceeded.
+func respondError(c *gin.Context, err error, message string) {
+	if respondQuotaExceeded(c, err) {
+		return
+	}
+
+	status, code, detail := classifyError(err)
+	if status == http.StatusInternalServerError {
+		middleware.GetLogger(c).Error(message, "error", err)
+		c.JSON(status, gin.H{"error": message, "code": code})
+		return
+	}
+	body := gin.H{"error": message + ": " + detail, "code": code}
+	var fieldErrors FieldErrors
+	if errors.As(err, &fieldErrors) {
+		body["errors"] = fieldErrors
+	}
+	var restriction *DeleteRestriction
+	if errors.As(err, &restriction) {
+		body["restricted_by"] = restriction
+	}
+	var nameErr *CollectionNameError
+	if errors.As(err, &nameErr) {
+		body["suggestions"] = nameErr.Suggestions
+	}
+	c.JSON(status, body)
+}
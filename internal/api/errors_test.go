@@ -0,0 +1,132 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-rbac-api/internal/quota"
+
+	"github.com/gin-gonic/gin"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		wantStatus  int
+		wantCode    string
+		wantMessage string
+	}{
+		{"Not Found", fmt.Errorf("failed to update item: %w", notFoundError("item not found")), http.StatusNotFound, CodeNotFound, "item not found"},
+		{"Conflict", conflictError("item already exists"), http.StatusConflict, CodeConflict, "item already exists"},
+		{"Validation", validationError("field '%s' is required", "name"), http.StatusUnprocessableEntity, CodeValidation, "field 'name' is required"},
+		{"Forbidden", forbiddenError("not allowed"), http.StatusForbidden, CodeForbidden, "not allowed"},
+		{"Bare Kind", fmt.Errorf("lookup: %w", ErrNotFound), http.StatusNotFound, CodeNotFound, "lookup: not found"},
+		{"Missing Data Table", fmt.Errorf("collection posts: %w", ErrDataTableNotFound), http.StatusNotFound, CodeNotFound, "collection not found"},
+		{"Unique Violation", fmt.Errorf("failed to create item: %w", &pq.Error{Code: "23505", Detail: "Key (sku)=(A-1) already exists."}), http.StatusConflict, CodeConflict, "Key (sku)=(A-1) already exists."},
+		{"Missing Related Item", &pq.Error{Code: "23503", Detail: `Key (customer)=(6e68062f-c4c6-42df-9e01-e2d1081664f4) is not present in table "data_customers".`}, http.StatusUnprocessableEntity, CodeValidation, "a related item does not exist"},
+		{"Still Referenced", &pq.Error{Code: "23503", Detail: `Key (id)=(6e68062f-c4c6-42df-9e01-e2d1081664f4) is still referenced from table "data_orders".`}, http.StatusConflict, CodeConflict, "other items still point at this item"},
+		{"Not Null Violation", &pq.Error{Code: "23502", Column: "title"}, http.StatusUnprocessableEntity, CodeValidation, "field 'title' is required"},
+		{"Invalid Input", &pq.Error{Code: "22P02", Message: `invalid input syntax for type uuid: "abc"`}, http.StatusUnprocessableEntity, CodeValidation, `invalid input syntax for type uuid: "abc"`},
+		{"Other Database Error", &pq.Error{Code: "57014", Message: "canceling statement due to statement timeout"}, http.StatusInternalServerError, CodeInternal, ""},
+		{"Unknown", errors.New("connection refused"), http.StatusInternalServerError, CodeInternal, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, code, message := classifyError(tt.err)
+			assert.Equal(t, tt.wantStatus, status)
+			assert.Equal(t, tt.wantCode, code)
+			assert.Equal(t, tt.wantMessage, message)
+		})
+	}
+}
+
+func TestKindError_Unwrap(t *testing.T) {
+	cause := errors.New("bad pattern")
+	err := validationError("field 'sku' validation failed: %w", cause)
+
+	assert.EqualError(t, err, "field 'sku' validation failed: bad pattern")
+	assert.ErrorIs(t, err, ErrValidation)
+	assert.ErrorIs(t, err, cause)
+	assert.NotErrorIs(t, err, ErrNotFound)
+}
+
+func TestWrapError(t *testing.T) {
+	err := wrapError(fmt.Errorf("validation failed: %w", validationError("field 'name' is required")), "item %d", 3)
+	status, code, message := classifyError(err)
+	assert.Equal(t, http.StatusUnprocessableEntity, status)
+	assert.Equal(t, CodeValidation, code)
+	assert.Equal(t, "item 3: field 'name' is required", message)
+
+	// Database errors take the kind they are classified as
+	err = wrapError(&pq.Error{Code: "23505", Detail: "Key (sku)=(A-1) already exists."}, "item %d", 1)
+	assert.ErrorIs(t, err, ErrConflict)
+	assert.EqualError(t, err, "item 1: Key (sku)=(A-1) already exists.")
+
+	// Other errors are wrapped as they are
+	cause := errors.New("connection refused")
+	err = wrapError(cause, "item %d", 0)
+	assert.EqualError(t, err, "item 0: connection refused")
+	assert.ErrorIs(t, err, cause)
+}
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	respond := func(err error) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("PATCH", "/items/products/1", nil)
+		respondError(c, err, "Failed to update item")
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	w, body := respond(notFoundError("item not found"))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, map[string]interface{}{"error": "Failed to update item: item not found", "code": CodeNotFound}, body)
+
+	// Internal errors do not leak their text
+	w, body = respond(errors.New(`pq: relation "t_products" does not exist`))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	assert.Equal(t, map[string]interface{}{"error": "Failed to update item", "code": CodeInternal}, body)
+
+	// Field errors are listed alongside the message
+	fieldErrors := FieldErrors{
+		{Field: "name", Code: FieldCodeRequired, Message: "a value is required"},
+		{Field: "price", Code: "min", Message: "minimum value is 1"},
+	}
+	w, body = respond(fmt.Errorf("validation failed: %w", validationError("%w", fieldErrors)))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "Failed to update item: field 'name': a value is required; field 'price': minimum value is 1", body["error"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"field": "name", "code": "required", "message": "a value is required"},
+		map[string]interface{}{"field": "price", "code": "min", "message": "minimum value is 1"},
+	}, body["errors"])
+
+	// Deletes blocked by a restrict rule name the relation
+	w, body = respond(fmt.Errorf("failed to delete item: %w", conflictError("%w", &DeleteRestriction{Collection: "orders", Field: "customer"})))
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, map[string]interface{}{"collection": "orders", "field": "customer"}, body["restricted_by"])
+
+	// Refused collection slugs come with alternatives
+	w, body = respond(validationError("%w", &CollectionNameError{Slug: "users", Reason: "is reserved for a schema table", Suggestions: []string{"app_users"}}))
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Equal(t, "Failed to update item: collection slug 'users' is reserved for a schema table", body["error"])
+	assert.Equal(t, []interface{}{"app_users"}, body["suggestions"])
+
+	w, body = respond(fmt.Errorf("item 0: %w", &quota.ExceededError{Limit: quota.LimitItemsPerCollection, Max: 10}))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Equal(t, CodeQuotaExceeded, body["code"])
+	assert.Equal(t, quota.LimitItemsPerCollection, body["limit"])
+}
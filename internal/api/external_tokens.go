@@ -0,0 +1,129 @@
+package api
+
+import (
+	"crypto/sha256"
+	"errors"
+	"net/http"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/audit"
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/mail"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/oauth"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// ExternalTokenAuth resolves access tokens of the external issuer (EXTERNAL_JWT_ISSUER) to
+// Basin users the way OAuth sign ins are resolved: the identity is linked to the user with
+// its verified email or provisioned in OAUTH_DEFAULT_TENANT, and the roles its claims map to
+// through EXTERNAL_JWT_ROLE_MAPPING are granted. A token is resolved, and its sign in
+// audited, on first use; later requests with it only load the user.
+type ExternalTokenAuth struct {
+	issuer *oauth.ExternalIssuer
+	oauth  *OAuthHandler
+
+	mu       sync.Mutex
+	resolved map[[sha256.Size]byte]resolvedToken
+}
+
+// resolvedToken is the user a token was resolved to
+type resolvedToken struct {
+	userID    uuid.UUID
+	expiresAt time.Time
+}
+
+// NewExternalTokenAuth returns the external token authentication configured in cfg, or
+// nil when no external issuer is
+func NewExternalTokenAuth(db *db.DB, cfg *config.Config, mailer *mail.Mailer) (*ExternalTokenAuth, error) {
+	issuer, err := oauth.NewExternalIssuer(cfg)
+	if issuer == nil || err != nil {
+		return nil, err
+	}
+	roles, err := oauth.ParseRoleMapping(cfg.ExternalJWTRoleClaim, cfg.ExternalJWTRoleMapping, cfg.OAuthDefaultRoles)
+	if err != nil {
+		return nil, err
+	}
+	handler, err := NewOAuthHandler(db, cfg, mailer)
+	if err != nil {
+		return nil, err
+	}
+	handler.roles = roles
+
+	return &ExternalTokenAuth{
+		issuer:   issuer,
+		oauth:    handler,
+		resolved: make(map[[sha256.Size]byte]resolvedToken),
+	}, nil
+}
+
+// Issues reports whether a token claims to come from the external issuer
+func (a *ExternalTokenAuth) Issues(token string) bool {
+	return a.issuer.Issues(token)
+}
+
+// Authenticate verifies a token of the external issuer and returns the user it acts as
+func (a *ExternalTokenAuth) Authenticate(c *gin.Context, token string) (sqlc.User, time.Time, error) {
+	ctx := c.Request.Context()
+	key := sha256.Sum256([]byte(token))
+	if resolved, ok := a.lookup(key); ok {
+		user, err := a.oauth.db.Queries.GetUserByID(ctx, resolved.userID)
+		return user, resolved.expiresAt, err
+	}
+
+	identity, expiresAt, err := a.issuer.Verify(ctx, token)
+	if err != nil {
+		return sqlc.User{}, time.Time{}, err
+	}
+	user, created, err := a.oauth.resolveUser(ctx, identity)
+	if errors.Is(err, errOAuthUnverifiedEmail) || errors.Is(err, errOAuthNoAccount) {
+		a.oauth.auditFailure(c, identity, uuid.Nil, err.Error())
+	}
+	if err != nil {
+		return sqlc.User{}, time.Time{}, err
+	}
+	if !user.IsActive.Bool {
+		a.oauth.auditFailure(c, identity, user.ID, "account disabled")
+		return sqlc.User{}, time.Time{}, errors.New("user account is disabled")
+	}
+	a.oauth.onboard(ctx, middleware.GetLogger(c), user, identity, created)
+
+	entry := middleware.NewAuditEntry(c, audit.ActionLogin)
+	entry.UserID = user.ID
+	entry.TenantID = user.TenantID.UUID
+	entry.StatusCode = http.StatusOK
+	entry.Details = map[string]interface{}{"provider": oauth.ProviderExternal, "provisioned": created}
+	a.oauth.audit.Record(ctx, entry)
+
+	a.remember(key, resolvedToken{userID: user.ID, expiresAt: expiresAt})
+	return user, expiresAt, nil
+}
+
+// lookup returns the user a token was resolved to before, until the token expires
+func (a *ExternalTokenAuth) lookup(key [sha256.Size]byte) (resolvedToken, bool) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	resolved, ok := a.resolved[key]
+	if !ok || time.Now().After(resolved.expiresAt) {
+		return resolvedToken{}, false
+	}
+	return resolved, true
+}
+
+// remember stores a resolved token, dropping the expired ones
+func (a *ExternalTokenAuth) remember(key [sha256.Size]byte, resolved resolvedToken) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	for k, r := range a.resolved {
+		if now.After(r.expiresAt) {
+			delete(a.resolved, k)
+		}
+	}
+	a.resolved[key] = resolved
+}
@@ -0,0 +1,190 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains encrypted fields: string and text fields whose values are encrypted at rest.
+//
+// A field created with "is_encrypted": true keeps its values AES-GCM encrypted in its column
+// (see the fieldcrypt package), with the keys of FIELD_ENCRYPTION_KEYS or
+// FIELD_ENCRYPTION_KEY_FILE. DynamicHandlers encrypt the values on every write, so revisions
+// and item events only ever hold the encrypted form. Item reads, write responses, exports,
+// search hits and realtime events decrypt them for callers with the "decrypt" permission on
+// the collection; everyone else, and every share link, reads a masked value:
+//
+//	{"name": "iban", "type": "string", "is_encrypted": true}
+//	GET /items/customers/:id  ->  {"iban": "********"}  // without decrypt
+//
+// Encrypted values cannot be compared in SQL, so encrypted fields cannot be unique,
+// indexed or searched, and the option is fixed once the field exists.
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"go-rbac-api/internal/fieldcrypt"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+)
+
+// decryptAction is the permission action that reveals the values of encrypted fields
+const decryptAction = "decrypt"
+
+// maskedValue replaces the values of encrypted fields for callers who may not decrypt them
+const maskedValue = "********"
+
+// fieldKeys are the keys encrypted fields are sealed with; nil until configured
+var fieldKeys *fieldcrypt.Keyring
+
+// UseFieldEncryption sets the keys of encrypted fields. Without keys, encrypted fields
+// cannot be created or written. Call it once at startup.
+func UseFieldEncryption(keys *fieldcrypt.Keyring) {
+	fieldKeys = keys
+}
+
+// checkEncryptedField refuses encrypted fields that cannot work: other types than string
+// and text, unique or indexed ones, and any while no key is configured
+func checkEncryptedField(fieldType string, unique, indexed bool) error {
+	switch {
+	case fieldType != "string" && fieldType != "text":
+		return validationError("only string and text fields can be encrypted")
+	case unique || indexed:
+		return validationError("encrypted fields cannot be unique or indexed")
+	case fieldKeys == nil:
+		return validationError("encrypted fields need FIELD_ENCRYPTION_KEYS or FIELD_ENCRYPTION_KEY_FILE")
+	}
+	return nil
+}
+
+// encryptedFields returns the names of the encrypted fields of a collection
+func (d *DynamicHandlers) encryptedFields(ctx context.Context, tenantID uuid.UUID, collectionSlug string) ([]string, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT f.name
+		FROM fields f
+		JOIN collections c ON c.id = f.collection_id
+		WHERE c.slug = $1 AND c.tenant_id = $2 AND f.is_encrypted
+	`, collectionSlug, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch encrypted fields: %w", err)
+	}
+	defer rows.Close()
+
+	var fields []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to scan encrypted field: %w", err)
+		}
+		fields = append(fields, name)
+	}
+	return fields, rows.Err()
+}
+
+// encryptValues returns data with the values of the encrypted fields encrypted. Values that
+// are encrypted already, such as those written back by a revert, are kept as they are.
+func encryptValues(fields []string, data map[string]interface{}) (map[string]interface{}, error) {
+	if len(fields) == 0 {
+		return data, nil
+	}
+
+	result := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		result[key] = value
+	}
+	for _, name := range fields {
+		value, ok := data[name]
+		if !ok || value == nil {
+			continue
+		}
+		text, ok := value.(string)
+		if !ok {
+			return nil, validationError("field '%s': expected a string, got %T", name, value)
+		}
+		if fieldcrypt.IsEncrypted(text) {
+			continue
+		}
+		sealed, err := fieldKeys.Encrypt(text)
+		if err != nil {
+			return nil, fmt.Errorf("field '%s': %w", name, err)
+		}
+		result[name] = sealed
+	}
+	return result, nil
+}
+
+// encryptItems returns items with the values of the encrypted fields encrypted (see
+// encryptValues). Errors name the zero-based index of the item.
+func encryptItems(fields []string, items []map[string]interface{}) ([]map[string]interface{}, error) {
+	if len(fields) == 0 {
+		return items, nil
+	}
+
+	result := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		encrypted, err := encryptValues(fields, item)
+		if err != nil {
+			return nil, wrapError(err, "item %d", i)
+		}
+		result[i] = encrypted
+	}
+	return result, nil
+}
+
+// revealValues replaces the encrypted values of items in place: with their plaintext when
+// decrypt is set, with maskedValue otherwise
+func revealValues(fields []string, items []map[string]interface{}, decrypt bool) error {
+	for _, item := range items {
+		for _, name := range fields {
+			text, ok := item[name].(string)
+			if !ok {
+				continue
+			}
+			if !decrypt {
+				item[name] = maskedValue
+				continue
+			}
+			if !fieldcrypt.IsEncrypted(text) {
+				continue // Such as a column default, filled in by the database
+			}
+			plaintext, err := fieldKeys.Decrypt(text)
+			if err != nil {
+				return fmt.Errorf("field '%s': %w", name, err)
+			}
+			item[name] = plaintext
+		}
+	}
+	return nil
+}
+
+// revealEncrypted decrypts or masks the encrypted fields of items read from a collection,
+// depending on whether the caller has the decrypt permission on it
+func (h *ItemsHandler) revealEncrypted(ctx context.Context, userID uuid.UUID, tableName string, items []map[string]interface{}) error {
+	tenantID, err := h.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	return revealEncrypted(ctx, h.dynamicHandlers, h.policyChecker, userID, tenantID, tableName, items)
+}
+
+// revealEncrypted decrypts or masks the encrypted fields of items read from a collection of
+// tenantID. Expanded relations reveal the related items the same way.
+func revealEncrypted(ctx context.Context, d *DynamicHandlers, policyChecker *rbac.PolicyChecker, userID, tenantID uuid.UUID, tableName string, items []map[string]interface{}) error {
+	fields, decrypt, err := encryptionAccess(ctx, d, policyChecker, userID, tenantID, tableName)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+	return revealValues(fields, items, decrypt)
+}
+
+// encryptionAccess returns the encrypted fields of a collection of tenantID and whether
+// userID may decrypt them, for readers that reveal rows one at a time
+func encryptionAccess(ctx context.Context, d *DynamicHandlers, policyChecker *rbac.PolicyChecker, userID, tenantID uuid.UUID, tableName string) ([]string, bool, error) {
+	fields, err := d.encryptedFields(ctx, tenantID, tableName)
+	if err != nil || len(fields) == 0 {
+		return nil, false, err
+	}
+
+	decrypt, _, err := policyChecker.CheckPermission(context.WithValue(ctx, "tenant_id", tenantID), userID, tableName, decryptAction)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to check decrypt permission: %w", err)
+	}
+	return fields, decrypt, nil
+}
@@ -0,0 +1,73 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"go-rbac-api/internal/fieldcrypt"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// useTestFieldKeys configures a field encryption key for the duration of a test
+func useTestFieldKeys(t *testing.T) {
+	keys, err := fieldcrypt.NewKeyring([][]byte{bytes.Repeat([]byte{7}, fieldcrypt.KeySize)})
+	require.NoError(t, err)
+	UseFieldEncryption(keys)
+	t.Cleanup(func() { UseFieldEncryption(nil) })
+}
+
+func TestCheckEncryptedField(t *testing.T) {
+	assert.Error(t, checkEncryptedField("string", false, false), "no key configured")
+
+	useTestFieldKeys(t)
+	assert.NoError(t, checkEncryptedField("string", false, false))
+	assert.NoError(t, checkEncryptedField("text", false, false))
+	assert.Error(t, checkEncryptedField("number", false, false))
+	assert.Error(t, checkEncryptedField("string", true, false))
+	assert.Error(t, checkEncryptedField("string", false, true))
+}
+
+func TestEncryptValues(t *testing.T) {
+	useTestFieldKeys(t)
+	data := map[string]interface{}{"name": "Ada", "iban": "DE89 3704 0044 0532 0130 00", "notes": nil}
+
+	encrypted, err := encryptValues([]string{"iban", "notes"}, data)
+	require.NoError(t, err)
+	assert.Equal(t, "Ada", encrypted["name"])
+	assert.Nil(t, encrypted["notes"])
+	assert.True(t, fieldcrypt.IsEncrypted(encrypted["iban"].(string)))
+	assert.Equal(t, "DE89 3704 0044 0532 0130 00", data["iban"], "the input is left alone")
+
+	again, err := encryptValues([]string{"iban"}, encrypted)
+	require.NoError(t, err)
+	assert.Equal(t, encrypted["iban"], again["iban"], "encrypted values are kept")
+
+	_, err = encryptValues([]string{"iban"}, map[string]interface{}{"iban": 42})
+	assert.Error(t, err)
+
+	_, err = encryptItems([]string{"iban"}, []map[string]interface{}{{"iban": "x"}, {"iban": true}})
+	assert.ErrorContains(t, err, "item 1")
+}
+
+func TestRevealValues(t *testing.T) {
+	useTestFieldKeys(t)
+	encrypted, err := encryptValues([]string{"iban"}, map[string]interface{}{"iban": "DE89"})
+	require.NoError(t, err)
+
+	fresh := func() []map[string]interface{} {
+		return []map[string]interface{}{{"iban": encrypted["iban"], "name": "Ada"}, {"iban": nil}}
+	}
+
+	items := fresh()
+	require.NoError(t, revealValues([]string{"iban"}, items, true))
+	assert.Equal(t, "DE89", items[0]["iban"])
+	assert.Nil(t, items[1]["iban"])
+
+	items = fresh()
+	require.NoError(t, revealValues([]string{"iban"}, items, false))
+	assert.Equal(t, maskedValue, items[0]["iban"])
+	assert.Equal(t, "Ada", items[0]["name"])
+	assert.Nil(t, items[1]["iban"], "missing values are not masked")
+}
@@ -0,0 +1,160 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains field indexes: the UNIQUE constraints of unique fields and the indexes
+// of fields with is_indexed, kept on the field's column in its data table.
+//
+// Both are named after the field's ID rather than its name, so they follow the column
+// through renames: field_<id>_key for the constraint and field_<id>_idx for the index.
+// Json fields are indexed with GIN over the value cast to jsonb, every other type with a
+// btree. Field responses report whether the database has them as unique_status and
+// index_status:
+// - "none"    - the field does not ask for one
+// - "ready"   - it exists and is in use
+// - "invalid" - it exists but Postgres marked it invalid; recreate it by toggling the flag
+// - "missing" - the field asks for one that does not exist
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Index statuses reported in field responses
+const (
+	indexStatusNone    = "none"
+	indexStatusReady   = "ready"
+	indexStatusInvalid = "invalid"
+	indexStatusMissing = "missing"
+)
+
+// fieldIndexName returns the name of the index of an indexed field
+func fieldIndexName(fieldID uuid.UUID) string {
+	return "field_" + strings.ReplaceAll(fieldID.String(), "-", "") + "_idx"
+}
+
+// fieldUniqueName returns the name of the UNIQUE constraint of a unique field
+func fieldUniqueName(fieldID uuid.UUID) string {
+	return "field_" + strings.ReplaceAll(fieldID.String(), "-", "") + "_key"
+}
+
+// fieldIndexMethod returns the index method suiting a field type
+func fieldIndexMethod(fieldType string) string {
+	switch fieldType {
+	case "json", "object", "string[]", "uuid[]", "int[]":
+		return "gin"
+	default:
+		return "btree"
+	}
+}
+
+// fieldIndexes are the statements keeping the constraint and index of one field in step
+// with its is_unique and is_indexed flags. drop runs before any change to the column and
+// create after it.
+type fieldIndexes struct {
+	drop   []string
+	create []string
+}
+
+// planFieldIndexes works out the index statements for a field of table in tenantSchema
+// whose column ends up named column with fieldType. wasUnique and wasIndexed are the flags
+// before the change; retyped tells that the column type changes, which the GIN index of a
+// json field cannot survive.
+func planFieldIndexes(tenantSchema, table string, fieldID uuid.UUID, column, fieldType string, wasUnique, unique, wasIndexed, indexed, retyped bool) fieldIndexes {
+	var plan fieldIndexes
+
+	switch {
+	case wasUnique && !unique:
+		plan.drop = append(plan.drop, fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT IF EXISTS "%s"`, table, fieldUniqueName(fieldID)))
+	case unique && !wasUnique:
+		plan.create = append(plan.create, fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT "%s" UNIQUE ("%s")`, table, fieldUniqueName(fieldID), column))
+	}
+
+	if wasIndexed && (!indexed || retyped) {
+		plan.drop = append(plan.drop, fmt.Sprintf(`DROP INDEX IF EXISTS "%s"."%s"`, tenantSchema, fieldIndexName(fieldID)))
+	}
+	if indexed && (!wasIndexed || retyped) {
+		plan.create = append(plan.create, createFieldIndexStatement(table, fieldID, column, fieldType))
+	}
+	return plan
+}
+
+// createFieldIndexStatement returns the CREATE INDEX statement of an indexed field
+func createFieldIndexStatement(table string, fieldID uuid.UUID, column, fieldType string) string {
+	if isGeoField(fieldType) {
+		return geoIndexStatement(table, fieldIndexName(fieldID), column)
+	}
+	method := fieldIndexMethod(fieldType)
+	expression := fmt.Sprintf(`"%s"`, column)
+	if method == "gin" && arrayElementType(fieldType) == "" {
+		expression = fmt.Sprintf(`("%s"::jsonb)`, column)
+	}
+	return fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s" ON %s USING %s (%s)`, fieldIndexName(fieldID), table, method, expression)
+}
+
+// indexStatus reports the status of the index named name, given whether the field asks for
+// it and the indexes of the tenant schema by name with their validity
+func indexStatus(indexes map[string]bool, name string, wanted bool) string {
+	if !wanted {
+		return indexStatusNone
+	}
+	valid, ok := indexes[name]
+	switch {
+	case !ok:
+		return indexStatusMissing
+	case !valid:
+		return indexStatusInvalid
+	default:
+		return indexStatusReady
+	}
+}
+
+// addIndexStatus adds unique_status and index_status to a field in its API representation.
+// Fields whose row leaves out their ID or flags are left as they are.
+func addIndexStatus(indexes map[string]bool, field map[string]interface{}) {
+	fieldID, err := uuid.Parse(fmt.Sprint(field["id"]))
+	if err != nil {
+		return
+	}
+	if unique, ok := field["is_unique"].(bool); ok {
+		field["unique_status"] = indexStatus(indexes, fieldUniqueName(fieldID), unique)
+	}
+	if indexed, ok := field["is_indexed"].(bool); ok {
+		field["index_status"] = indexStatus(indexes, fieldIndexName(fieldID), indexed)
+	}
+}
+
+// GetFieldIndexes returns the field indexes and constraints in the given schemas by name,
+// with whether the database considers them valid
+func (u *ItemsUtils) GetFieldIndexes(ctx context.Context, schemas ...string) (map[string]bool, error) {
+	indexes, err := u.dialect().Indexes(ctx, u.exec(), "field_", schemas)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list field indexes: %w", err)
+	}
+	return indexes, nil
+}
+
+// addFieldIndexStatus adds unique_status and index_status to rows of the fields table. The
+// statuses are best effort; without the tenant's indexes the rows are left as they are.
+func (h *ItemsHandler) addFieldIndexStatus(ctx context.Context, tableName string, userID uuid.UUID, rows []map[string]interface{}) {
+	if tableName != "fields" || len(rows) == 0 {
+		return
+	}
+	tenantID, err := h.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return
+	}
+	tenantSchema, err := h.utils.GetTenantSchema(ctx, tenantID)
+	if err != nil {
+		return
+	}
+	// Data tables of either layout may hold the tenant's field columns (see table_resolver.go)
+	indexes, err := h.utils.GetFieldIndexes(ctx, tenantSchema, dataSchema)
+	if err != nil {
+		return
+	}
+	for _, row := range rows {
+		addIndexStatus(indexes, row)
+	}
+}
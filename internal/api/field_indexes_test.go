@@ -0,0 +1,72 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPlanFieldIndexes(t *testing.T) {
+	fieldID := uuid.MustParse("0b6c1f1e-2f0a-4a8e-9d55-3f1c2b7a9e10")
+	table := `"acme".data_products`
+
+	t.Run("New Unique And Indexed Field", func(t *testing.T) {
+		plan := planFieldIndexes("acme", table, fieldID, "sku", "string", false, true, false, true, false)
+		assert.Empty(t, plan.drop)
+		assert.Equal(t, []string{
+			`ALTER TABLE "acme".data_products ADD CONSTRAINT "field_0b6c1f1e2f0a4a8e9d553f1c2b7a9e10_key" UNIQUE ("sku")`,
+			`CREATE INDEX IF NOT EXISTS "field_0b6c1f1e2f0a4a8e9d553f1c2b7a9e10_idx" ON "acme".data_products USING btree ("sku")`,
+		}, plan.create)
+	})
+
+	t.Run("Flags Removed", func(t *testing.T) {
+		plan := planFieldIndexes("acme", table, fieldID, "sku", "string", true, false, true, false, false)
+		assert.Empty(t, plan.create)
+		assert.Equal(t, []string{
+			`ALTER TABLE "acme".data_products DROP CONSTRAINT IF EXISTS "field_0b6c1f1e2f0a4a8e9d553f1c2b7a9e10_key"`,
+			`DROP INDEX IF EXISTS "acme"."field_0b6c1f1e2f0a4a8e9d553f1c2b7a9e10_idx"`,
+		}, plan.drop)
+	})
+
+	t.Run("Unchanged", func(t *testing.T) {
+		plan := planFieldIndexes("acme", table, fieldID, "sku", "string", true, true, true, true, false)
+		assert.Empty(t, plan.drop)
+		assert.Empty(t, plan.create)
+	})
+
+	t.Run("Retyped Index Is Rebuilt", func(t *testing.T) {
+		plan := planFieldIndexes("acme", table, fieldID, "attributes", "json", false, false, true, true, true)
+		assert.Len(t, plan.drop, 1)
+		assert.Equal(t, []string{
+			`CREATE INDEX IF NOT EXISTS "field_0b6c1f1e2f0a4a8e9d553f1c2b7a9e10_idx" ON "acme".data_products USING gin (("attributes"::jsonb))`,
+		}, plan.create)
+	})
+
+	t.Run("Array Field", func(t *testing.T) {
+		plan := planFieldIndexes("acme", table, fieldID, "tags", "string[]", false, false, false, true, false)
+		assert.Equal(t, []string{
+			`CREATE INDEX IF NOT EXISTS "field_0b6c1f1e2f0a4a8e9d553f1c2b7a9e10_idx" ON "acme".data_products USING gin ("tags")`,
+		}, plan.create)
+	})
+}
+
+func TestAddIndexStatus(t *testing.T) {
+	fieldID := uuid.New()
+	indexes := map[string]bool{fieldIndexName(fieldID): false}
+
+	field := map[string]interface{}{"id": fieldID.String(), "is_unique": true, "is_indexed": true}
+	addIndexStatus(indexes, field)
+	assert.Equal(t, indexStatusMissing, field["unique_status"])
+	assert.Equal(t, indexStatusInvalid, field["index_status"])
+
+	indexes[fieldIndexName(fieldID)] = true
+	field = map[string]interface{}{"id": fieldID.String(), "is_unique": false, "is_indexed": true}
+	addIndexStatus(indexes, field)
+	assert.Equal(t, indexStatusNone, field["unique_status"])
+	assert.Equal(t, indexStatusReady, field["index_status"])
+
+	field = map[string]interface{}{"name": "sku", "is_indexed": true}
+	addIndexStatus(indexes, field)
+	assert.NotContains(t, field, "index_status")
+}
@@ -0,0 +1,242 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains field migrations: keeping a field's column in its data table in step
+// when UpdateField renames the field or changes its type.
+//
+// A rename becomes ALTER TABLE ... RENAME COLUMN and a type change ALTER COLUMN ... TYPE
+// with a USING clause that converts the stored values. Conversions that keep every value
+// (anything to text, date to datetime, boolean to number) run straight away. Conversions
+// that may lose data - values that do not convert become NULL, or precision is dropped -
+// are only applied when the update carries "confirm": true; without it UpdateField fails
+// with a DestructiveChangeError describing what would be lost. Conversions without a
+// sensible mapping (a date to a number) are refused.
+//
+// Deleting a field removes its column as FIELD_DELETE_MODE says: "drop" drops it and
+// "archive" renames it to _deleted_<name>, which reads leave out. Archiving the same name
+// again replaces the earlier archive.
+//
+// The statements run in one transaction with the update of the field's metadata, so a
+// conversion that fails leaves both the schema and the data as they were.
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// reservedColumns are the system columns of every data table, which fields cannot be named
+var reservedColumns = []string{"id", "tenant_id", "created_at", "updated_at", "created_by", "updated_by", "deleted_at"}
+
+// archivedColumnPrefix starts the names of columns kept after their field was deleted
+const archivedColumnPrefix = "_deleted_"
+
+// Column types of data tables, as returned by dataColumnType
+const (
+	columnText      = "TEXT"
+	columnNumeric   = "NUMERIC"
+	columnBoolean   = "BOOLEAN"
+	columnDate      = "DATE"
+	columnTimestamp = "TIMESTAMP WITH TIME ZONE"
+	columnUUID      = "UUID"
+)
+
+// Patterns that values must match to convert from text
+const (
+	numericPattern = `^[-+]?([0-9]+\.?[0-9]*|\.[0-9]+)([eE][-+]?[0-9]+)?$`
+	datePattern    = `^[0-9]{4}-[0-9]{2}-[0-9]{2}`
+	uuidPattern    = `^[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`
+)
+
+// DestructiveChangeError reports a field change that may lose data and was not confirmed
+type DestructiveChangeError struct {
+	Field  string // Name of the field
+	Reason string // What the change does to existing values
+}
+
+func (e *DestructiveChangeError) Error() string {
+	return fmt.Sprintf("changing field '%s' %s and must be confirmed", e.Field, e.Reason)
+}
+
+// respondDestructiveChange answers with 409 when err is an unconfirmed destructive field
+// change and reports whether it did
+func respondDestructiveChange(c *gin.Context, err error) bool {
+	var destructive *DestructiveChangeError
+	if !errors.As(err, &destructive) {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{
+		"error":            destructive.Error(),
+		"field":            destructive.Field,
+		"confirm_required": true,
+	})
+	return true
+}
+
+// fieldMigration is the change a field update makes to the field's column
+type fieldMigration struct {
+	column       string // Current column name
+	rename       string // New column name, or "" when unchanged
+	columnType   string // New column type, or "" when unchanged
+	using        string // Expression converting the old values to columnType
+	defaultValue string // Default to put back after a type change, or ""
+	destructive  string // What the conversion does to existing values, or "" when it keeps them
+	recompute    string // New generated column definition of a computed field, or ""
+}
+
+// changesColumn reports whether the migration alters the data table at all
+func (m fieldMigration) changesColumn() bool {
+	return m.rename != "" || m.columnType != "" || m.recompute != ""
+}
+
+// statements returns the ALTER TABLE statements applying the migration to table
+func (m fieldMigration) statements(table string) []string {
+	// Generated columns are replaced, under their new name if they are renamed
+	if m.recompute != "" {
+		column := m.column
+		if m.rename != "" {
+			column = m.rename
+		}
+		return []string{
+			fmt.Sprintf(`ALTER TABLE %s DROP COLUMN "%s"`, table, m.column),
+			fmt.Sprintf(`ALTER TABLE %s ADD COLUMN "%s" %s`, table, column, m.recompute),
+		}
+	}
+
+	var statements []string
+	if m.columnType != "" {
+		// The old default may not convert, so it is set again once the type has changed
+		statements = append(statements,
+			fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN "%s" DROP DEFAULT`, table, m.column),
+			fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN "%s" TYPE %s USING %s`, table, m.column, m.columnType, m.using))
+		if m.defaultValue != "" {
+			statements = append(statements, fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN "%s" SET DEFAULT '%s'`,
+				table, m.column, strings.ReplaceAll(m.defaultValue, "'", "''")))
+		}
+	}
+	if m.rename != "" {
+		statements = append(statements, fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN "%s" TO "%s"`, table, m.column, m.rename))
+	}
+	return statements
+}
+
+// planFieldMigration works out how the column of field changes when the field is renamed
+// to name and given fieldType and relationConfig. Relation fields without a column (o2m and
+// m2m) only change their metadata.
+func planFieldMigration(field sqlc.Field, name, fieldType string, relationConfig pqtype.NullRawMessage, defaultValue string) (fieldMigration, error) {
+	migration := fieldMigration{column: field.Name, defaultValue: defaultValue}
+
+	if name != field.Name {
+		if !validFieldName(name) {
+			return migration, fmt.Errorf("invalid field name '%s'", name)
+		}
+		migration.rename = name
+	}
+
+	hadColumn := !isAliasField(field.Type, field.RelationConfig)
+	hasColumn := !isAliasField(fieldType, relationConfig)
+	if hadColumn != hasColumn {
+		return migration, fmt.Errorf("field '%s' cannot switch between a column and a one-to-many or many-to-many relation; delete and recreate it", field.Name)
+	}
+	if !hasColumn {
+		migration.rename = ""
+		return migration, nil
+	}
+	if (field.Type == "computed") != (fieldType == "computed") {
+		return migration, fmt.Errorf("field '%s' cannot switch between computed and stored values; delete and recreate it", field.Name)
+	}
+
+	from, to := dataColumnType(field.Type), dataColumnType(fieldType)
+	if from == to {
+		return migration, nil
+	}
+	if field.Type == "file" || fieldType == "file" {
+		return migration, fmt.Errorf("field '%s' cannot change from %s to %s; file fields keep their type", field.Name, field.Type, fieldType)
+	}
+
+	using, destructive, err := convertColumn(field.Name, from, to)
+	if err != nil {
+		return migration, fmt.Errorf("field '%s' cannot change from %s to %s: %w", field.Name, field.Type, fieldType, err)
+	}
+	migration.columnType, migration.using, migration.destructive = to, using, destructive
+	return migration, nil
+}
+
+// convertColumn returns the USING expression converting column from one column type to
+// another and, when the conversion may lose data, what it does to existing values
+func convertColumn(column, from, to string) (string, string, error) {
+	value := fmt.Sprintf(`"%s"`, column)
+	trimmed := fmt.Sprintf(`btrim("%s")`, column)
+
+	switch {
+	case to == columnText:
+		return value + "::TEXT", "", nil
+	case strings.HasSuffix(from, "[]") && to == "TEXT[]":
+		return value + "::TEXT[]", "", nil
+	case from == columnDate && to == columnTimestamp:
+		return value + "::TIMESTAMP WITH TIME ZONE", "", nil
+	case from == columnBoolean && to == columnNumeric:
+		return fmt.Sprintf("CASE WHEN %s THEN 1 WHEN NOT %s THEN 0 END", value, value), "", nil
+	case from == columnText && to == columnNumeric:
+		return fmt.Sprintf("CASE WHEN %s ~ '%s' THEN %s::NUMERIC END", trimmed, numericPattern, trimmed),
+			"turns values that are not numbers into null", nil
+	case from == columnText && to == columnBoolean:
+		return fmt.Sprintf("CASE WHEN lower(%s) IN ('true', 't', 'yes', 'on', '1') THEN true WHEN lower(%s) IN ('false', 'f', 'no', 'off', '0') THEN false END", trimmed, trimmed),
+			"turns values other than true/false, yes/no, on/off and 1/0 into null", nil
+	case from == columnNumeric && to == columnBoolean:
+		return fmt.Sprintf("%s <> 0", value), "turns every number other than 0 into true", nil
+	case from == columnText && to == columnDate:
+		return fmt.Sprintf("CASE WHEN %s ~ '%s' THEN left(%s, 10)::DATE END", trimmed, datePattern, trimmed),
+			"turns values that are not dates into null and drops any time of day", nil
+	case from == columnText && to == columnTimestamp:
+		return fmt.Sprintf("CASE WHEN %s ~ '%s' THEN %s::TIMESTAMP WITH TIME ZONE END", trimmed, datePattern, trimmed),
+			"turns values that are not dates into null", nil
+	case from == columnTimestamp && to == columnDate:
+		return value + "::DATE", "drops the time of day", nil
+	case from == columnText && to == columnUUID:
+		return fmt.Sprintf("CASE WHEN lower(%s) ~ '%s' THEN %s::UUID END", trimmed, uuidPattern, trimmed),
+			"turns values that are not UUIDs into null", nil
+	default:
+		return "", "", fmt.Errorf("no conversion from %s to %s", from, to)
+	}
+}
+
+// deleteColumnStatements returns the ALTER TABLE statements removing column from table
+// when its field is deleted, dropping or archiving it by mode
+func deleteColumnStatements(table, column, mode string) []string {
+	if mode != "archive" {
+		return []string{fmt.Sprintf(`ALTER TABLE %s DROP COLUMN IF EXISTS "%s"`, table, column)}
+	}
+	archived := archivedColumnPrefix + column
+	return []string{
+		fmt.Sprintf(`ALTER TABLE %s DROP COLUMN IF EXISTS "%s"`, table, archived),
+		// Required fields are NOT NULL, which new rows could no longer satisfy
+		fmt.Sprintf(`ALTER TABLE %s ALTER COLUMN "%s" DROP NOT NULL`, table, column),
+		fmt.Sprintf(`ALTER TABLE %s RENAME COLUMN "%s" TO "%s"`, table, column, archived),
+	}
+}
+
+// validFieldName reports whether name can be the column of a field
+func validFieldName(name string) bool {
+	return columnNamePattern.MatchString(name) && !Contains(reservedColumns, name) &&
+		!strings.HasPrefix(name, archivedColumnPrefix)
+}
+
+// isAliasField reports whether a field has no column of its own: o2m and m2m relations
+func isAliasField(fieldType string, relationConfig pqtype.NullRawMessage) bool {
+	if fieldType != "relation" || !relationConfig.Valid {
+		return false
+	}
+	var options map[string]interface{}
+	if err := json.Unmarshal(relationConfig.RawMessage, &options); err != nil {
+		return false
+	}
+	relationType := GetStringFromMap(options, "type")
+	return relationType == RelationOneToMany || relationType == RelationManyToMany
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanFieldMigration(t *testing.T) {
+	field := sqlc.Field{Name: "price", Type: "string"}
+
+	t.Run("Rename", func(t *testing.T) {
+		migration, err := planFieldMigration(field, "amount", "string", pqtype.NullRawMessage{}, "")
+		require.NoError(t, err)
+		assert.True(t, migration.changesColumn())
+		assert.Equal(t, []string{`ALTER TABLE data.products RENAME COLUMN "price" TO "amount"`}, migration.statements("data.products"))
+	})
+
+	t.Run("Invalid And Reserved Names", func(t *testing.T) {
+		_, err := planFieldMigration(field, "Bad Name", "string", pqtype.NullRawMessage{}, "")
+		assert.Error(t, err)
+		_, err = planFieldMigration(field, "created_at", "string", pqtype.NullRawMessage{}, "")
+		assert.Error(t, err)
+	})
+
+	t.Run("Destructive Type Change", func(t *testing.T) {
+		migration, err := planFieldMigration(field, "price", "number", pqtype.NullRawMessage{}, "0")
+		require.NoError(t, err)
+		assert.Equal(t, columnNumeric, migration.columnType)
+		assert.NotEmpty(t, migration.destructive)
+
+		statements := migration.statements("data.products")
+		require.Len(t, statements, 3)
+		assert.Contains(t, statements[0], "DROP DEFAULT")
+		assert.Contains(t, statements[1], "TYPE NUMERIC USING")
+		assert.Equal(t, `ALTER TABLE data.products ALTER COLUMN "price" SET DEFAULT '0'`, statements[2])
+	})
+
+	t.Run("Same Column Type", func(t *testing.T) {
+		migration, err := planFieldMigration(field, "price", "text", pqtype.NullRawMessage{}, "")
+		require.NoError(t, err)
+		assert.False(t, migration.changesColumn())
+	})
+
+	t.Run("Alias Fields Keep No Column", func(t *testing.T) {
+		m2m := pqtype.NullRawMessage{RawMessage: []byte(`{"type": "m2m", "related_collection": "tags"}`), Valid: true}
+		_, err := planFieldMigration(field, "price", "relation", m2m, "")
+		assert.Error(t, err)
+
+		tags := sqlc.Field{Name: "tags", Type: "relation", RelationConfig: m2m}
+		migration, err := planFieldMigration(tags, "labels", "relation", m2m, "")
+		require.NoError(t, err)
+		assert.False(t, migration.changesColumn())
+	})
+
+	t.Run("File Fields Keep Their Type", func(t *testing.T) {
+		_, err := planFieldMigration(sqlc.Field{Name: "photo", Type: "file"}, "photo", "string", pqtype.NullRawMessage{}, "")
+		assert.Error(t, err)
+	})
+}
+
+func TestConvertColumn(t *testing.T) {
+	using, destructive, err := convertColumn("count", columnNumeric, columnText)
+	require.NoError(t, err)
+	assert.Equal(t, `"count"::TEXT`, using)
+	assert.Empty(t, destructive)
+
+	_, destructive, err = convertColumn("due", columnDate, columnTimestamp)
+	require.NoError(t, err)
+	assert.Empty(t, destructive)
+
+	_, destructive, err = convertColumn("due", columnTimestamp, columnDate)
+	require.NoError(t, err)
+	assert.NotEmpty(t, destructive)
+
+	_, _, err = convertColumn("due", columnDate, columnNumeric)
+	assert.Error(t, err)
+
+	using, destructive, err = convertColumn("ids", dataColumnType("uuid[]"), dataColumnType("string[]"))
+	require.NoError(t, err)
+	assert.Equal(t, `"ids"::TEXT[]`, using)
+	assert.Empty(t, destructive)
+
+	_, _, err = convertColumn("tags", dataColumnType("string[]"), dataColumnType("int[]"))
+	assert.Error(t, err)
+}
+
+func TestDestructiveChangeError(t *testing.T) {
+	err := &DestructiveChangeError{Field: "price", Reason: "turns values that are not numbers into null"}
+	assert.Equal(t, "changing field 'price' turns values that are not numbers into null and must be confirmed", err.Error())
+}
+
+func TestDeleteColumnStatements(t *testing.T) {
+	assert.Equal(t, []string{`ALTER TABLE data.products DROP COLUMN IF EXISTS "price"`},
+		deleteColumnStatements("data.products", "price", "drop"))
+
+	assert.Equal(t, []string{
+		`ALTER TABLE data.products DROP COLUMN IF EXISTS "_deleted_price"`,
+		`ALTER TABLE data.products ALTER COLUMN "price" DROP NOT NULL`,
+		`ALTER TABLE data.products RENAME COLUMN "price" TO "_deleted_price"`,
+	}, deleteColumnStatements("data.products", "price", "archive"))
+}
+
+func TestValidFieldName(t *testing.T) {
+	assert.True(t, validFieldName("price"))
+	assert.True(t, validFieldName("_internal"))
+	assert.False(t, validFieldName("tenant_id"))
+	assert.False(t, validFieldName("_deleted_price"))
+	assert.False(t, validFieldName("price; DROP TABLE"))
+}
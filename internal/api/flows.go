@@ -0,0 +1,344 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains flows, the tenant automations run by the flows package.
+//
+// Flows are managed through /items/flows like other schema tables, and their run log is
+// read through /items/flow_runs. A flow created with the webhook trigger is started with:
+// - POST /flows/:id/trigger - Queue a run; the secret goes in X-Basin-Flow-Secret
+//
+// The request body (JSON, optional) and query parameters become the trigger payload.
+// Operations that create items act as the user who created the flow, with that user's
+// permissions.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/flows"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxFlowTriggerBody limits the request body of POST /flows/:id/trigger
+const maxFlowTriggerBody = 1 << 20
+
+// Flow Operations
+
+// CreateFlow creates a flow for the user's tenant. A secret for the trigger endpoint is
+// generated unless one is provided; it is only returned in this response.
+func (s *SchemaHandlers) CreateFlow(ctx context.Context, userID uuid.UUID, data map[string]interface{}) (map[string]interface{}, error) {
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	name := GetStringFromMap(data, "name")
+	if name == "" {
+		return nil, fmt.Errorf("name is required")
+	}
+
+	trigger := GetStringFromMap(data, "trigger")
+	triggerOptions := flowJSONFromMap(data, "trigger_options", "{}")
+	options, err := flows.ParseTrigger(trigger, triggerOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	operations := flowJSONFromMap(data, "operations", "[]")
+	if _, err := flows.ParseOperations(operations); err != nil {
+		return nil, err
+	}
+
+	secret := GetStringFromMap(data, "secret")
+	if secret == "" {
+		if secret, err = generateFlowSecret(); err != nil {
+			return nil, fmt.Errorf("failed to generate flow secret: %w", err)
+		}
+	}
+
+	isActive := true
+	if activeVal, ok := data["is_active"].(bool); ok {
+		isActive = activeVal
+	}
+
+	description := GetStringFromMap(data, "description")
+	flow, err := s.handler.db.Queries.CreateFlow(ctx, sqlc.CreateFlowParams{
+		TenantID:       tenantID,
+		Name:           name,
+		Description:    sql.NullString{String: description, Valid: description != ""},
+		Trigger:        trigger,
+		TriggerOptions: triggerOptions,
+		Operations:     operations,
+		Secret:         secret,
+		IsActive:       sql.NullBool{Bool: isActive, Valid: true},
+		NextRunAt:      nextFlowRun(trigger, options, isActive, sql.NullTime{}, time.Now()),
+		CreatedBy:      uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := flowToMap(flow)
+	result["secret"] = flow.Secret // Only returned on creation!
+	return result, nil
+}
+
+// UpdateFlow updates a flow of the user's tenant. The secret cannot be changed; delete
+// and recreate the flow to rotate it.
+func (s *SchemaHandlers) UpdateFlow(ctx context.Context, userID uuid.UUID, itemID string, data map[string]interface{}) (map[string]interface{}, error) {
+	existing, err := s.getTenantFlow(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := sqlc.UpdateFlowParams{
+		ID:             existing.ID,
+		Name:           existing.Name,
+		Description:    existing.Description,
+		Trigger:        existing.Trigger,
+		TriggerOptions: existing.TriggerOptions,
+		Operations:     existing.Operations,
+		IsActive:       existing.IsActive,
+	}
+
+	if name := GetStringFromMap(data, "name"); name != "" {
+		params.Name = name
+	}
+	if _, ok := data["description"]; ok {
+		description := GetStringFromMap(data, "description")
+		params.Description = sql.NullString{String: description, Valid: description != ""}
+	}
+	if _, ok := data["trigger"]; ok {
+		params.Trigger = GetStringFromMap(data, "trigger")
+	}
+	if _, ok := data["trigger_options"]; ok {
+		params.TriggerOptions = flowJSONFromMap(data, "trigger_options", "{}")
+	}
+	options, err := flows.ParseTrigger(params.Trigger, params.TriggerOptions)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := data["operations"]; ok {
+		params.Operations = flowJSONFromMap(data, "operations", "[]")
+		if _, err := flows.ParseOperations(params.Operations); err != nil {
+			return nil, err
+		}
+	}
+	if activeVal, ok := data["is_active"].(bool); ok {
+		params.IsActive = sql.NullBool{Bool: activeVal, Valid: true}
+	}
+
+	// A schedule keeps its next run unless the trigger changed or the flow was paused
+	current := existing.NextRunAt
+	previous, _ := flows.ParseTrigger(existing.Trigger, existing.TriggerOptions)
+	if params.Trigger != existing.Trigger || options.Interval != previous.Interval || !existing.IsActive.Bool {
+		current = sql.NullTime{}
+	}
+	params.NextRunAt = nextFlowRun(params.Trigger, options, params.IsActive.Bool, current, time.Now())
+
+	flow, err := s.handler.db.Queries.UpdateFlow(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return flowToMap(flow), nil
+}
+
+// DeleteFlow deletes a flow of the user's tenant along with its run log
+func (s *SchemaHandlers) DeleteFlow(ctx context.Context, userID uuid.UUID, itemID string) error {
+	existing, err := s.getTenantFlow(ctx, userID, itemID)
+	if err != nil {
+		return err
+	}
+
+	return s.handler.db.Queries.DeleteFlow(ctx, existing.ID)
+}
+
+// getTenantFlow loads a flow and makes sure it belongs to the user's tenant
+func (s *SchemaHandlers) getTenantFlow(ctx context.Context, userID uuid.UUID, itemID string) (sqlc.Flow, error) {
+	flowID, err := uuid.Parse(itemID)
+	if err != nil {
+		return sqlc.Flow{}, fmt.Errorf("invalid flow ID: %w", err)
+	}
+
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return sqlc.Flow{}, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	flow, err := s.handler.db.Queries.GetFlowByID(ctx, flowID)
+	if err != nil || flow.TenantID != tenantID {
+		return sqlc.Flow{}, fmt.Errorf("flow not found")
+	}
+
+	return flow, nil
+}
+
+// nextFlowRun returns the next_run_at of a flow: current, or one interval from now if
+// it is not set, for active schedule flows and NULL for everything else
+func nextFlowRun(trigger string, options flows.TriggerOptions, active bool, current sql.NullTime, now time.Time) sql.NullTime {
+	if trigger != flows.TriggerSchedule || !active {
+		return sql.NullTime{}
+	}
+	if current.Valid {
+		return current
+	}
+	return sql.NullTime{Time: flows.NextRun(options, now), Valid: true}
+}
+
+// flowJSONFromMap reads a JSON option of a flow, falling back to empty when it is
+// missing or null
+func flowJSONFromMap(data map[string]interface{}, key, empty string) json.RawMessage {
+	raw := GetJSONFromMap(data, key)
+	if !raw.Valid {
+		return json.RawMessage(empty)
+	}
+	return raw.RawMessage
+}
+
+// generateFlowSecret generates a random secret for the trigger endpoint of a flow
+func generateFlowSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "flow_" + hex.EncodeToString(bytes), nil
+}
+
+// flowToMap converts a flow to its API representation, without the secret
+func flowToMap(flow sqlc.Flow) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":              flow.ID.String(),
+		"tenant_id":       flow.TenantID.String(),
+		"name":            flow.Name,
+		"description":     nil,
+		"trigger":         flow.Trigger,
+		"trigger_options": flow.TriggerOptions,
+		"operations":      flow.Operations,
+		"is_active":       flow.IsActive.Bool,
+		"next_run_at":     nil,
+		"created_at":      flow.CreatedAt.Time,
+		"updated_at":      flow.UpdatedAt.Time,
+	}
+	if flow.Description.Valid {
+		result["description"] = flow.Description.String
+	}
+	if flow.NextRunAt.Valid {
+		result["next_run_at"] = flow.NextRunAt.Time
+	}
+	return result
+}
+
+// CreateFlowItem creates an item in a collection for a create_item operation of a flow.
+// The item is created as userID in tenantID, with that user's create permission and
+// field restrictions.
+func (h *ItemsHandler) CreateFlowItem(ctx context.Context, tenantID, userID uuid.UUID, collection string, data map[string]interface{}) (map[string]interface{}, error) {
+	if !rbac.ValidateTableName(collection) || h.isSchemaTable(collection) {
+		return nil, fmt.Errorf("flows can only create items in collections")
+	}
+
+	ctx = WithTenant(ctx, tenantID)
+	ctxWithTenant := context.WithValue(ctx, "tenant_id", tenantID)
+
+	hasPermission, allowedFields, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, collection, "create")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if !hasPermission {
+		return nil, fmt.Errorf("the flow's owner may not create items in %s", collection)
+	}
+
+	return h.collectionsHandler.CreateCollectionItem(ctx, userID, collection, h.policyChecker.FilterFields(data, allowedFields, nil))
+}
+
+// FlowsHandler serves the trigger endpoint of webhook flows
+type FlowsHandler struct {
+	db     *db.DB
+	runner *flows.Runner
+}
+
+// NewFlowsHandler creates a FlowsHandler that queues runs with runner
+func NewFlowsHandler(db *db.DB, runner *flows.Runner) *FlowsHandler {
+	return &FlowsHandler{db: db, runner: runner}
+}
+
+// TriggerFlow handles POST /flows/:id/trigger
+// @Summary      Trigger flow
+// @Tags         flows
+// @Description  Queue a run of an active flow with the webhook trigger. The JSON body and query parameters are available to its operations as .trigger.body and .trigger.query.
+// @Param        id                  path   string true  "Flow ID (UUID)"
+// @Param        X-Basin-Flow-Secret header string true  "Secret returned when the flow was created"
+// @Param        body                body   object false "Trigger payload"
+// @Accept       json
+// @Produce      json
+// @Success      202 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /flows/{id}/trigger [post]
+func (h *FlowsHandler) TriggerFlow(c *gin.Context) {
+	flowID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid flow ID"})
+		return
+	}
+
+	flow, err := h.db.Queries.GetFlowByID(c.Request.Context(), flowID)
+	if err != nil || flow.Trigger != flows.TriggerWebhook || !flow.IsActive.Bool {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Flow not found"})
+		return
+	}
+
+	secret := c.GetHeader("X-Basin-Flow-Secret")
+	if secret == "" || subtle.ConstantTimeCompare([]byte(secret), []byte(flow.Secret)) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid flow secret"})
+		return
+	}
+
+	raw, err := io.ReadAll(io.LimitReader(c.Request.Body, maxFlowTriggerBody+1))
+	if err != nil || len(raw) > maxFlowTriggerBody {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Request body is too large"})
+		return
+	}
+	var body interface{}
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &body); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+			return
+		}
+	}
+
+	query := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		if len(values) > 0 {
+			query[key] = values[0]
+		}
+	}
+
+	run, err := h.runner.Trigger(c.Request.Context(), flow, map[string]interface{}{
+		"body":        body,
+		"query":       query,
+		"received_at": time.Now().UTC(),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to queue flow run"})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"data": gin.H{"run_id": run.ID, "status": run.Status},
+		"meta": gin.H{"flow": flow.ID},
+	})
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/flows"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextFlowRun(t *testing.T) {
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	hourly := flows.TriggerOptions{Interval: "1h"}
+	scheduled := sql.NullTime{Time: now.Add(10 * time.Minute), Valid: true}
+
+	assert.Equal(t, sql.NullTime{Time: now.Add(time.Hour), Valid: true}, nextFlowRun(flows.TriggerSchedule, hourly, true, sql.NullTime{}, now))
+	assert.Equal(t, scheduled, nextFlowRun(flows.TriggerSchedule, hourly, true, scheduled, now))
+	assert.False(t, nextFlowRun(flows.TriggerSchedule, hourly, false, scheduled, now).Valid)
+	assert.False(t, nextFlowRun(flows.TriggerWebhook, hourly, true, scheduled, now).Valid)
+}
+
+func TestFlowToMap(t *testing.T) {
+	flow := sqlc.Flow{
+		ID:             uuid.New(),
+		Name:           "Escalate",
+		Trigger:        flows.TriggerWebhook,
+		TriggerOptions: json.RawMessage(`{}`),
+		Operations:     json.RawMessage(`[]`),
+		Secret:         "flow_abc",
+		IsActive:       sql.NullBool{Bool: true, Valid: true},
+	}
+
+	result := flowToMap(flow)
+	assert.NotContains(t, result, "secret")
+	assert.Nil(t, result["description"])
+	assert.Nil(t, result["next_run_at"])
+	assert.Equal(t, true, result["is_active"])
+}
+
+func TestFlowJSONFromMap(t *testing.T) {
+	data := map[string]interface{}{"operations": []interface{}{map[string]interface{}{"key": "a"}}, "trigger_options": nil}
+	assert.JSONEq(t, `[{"key": "a"}]`, string(flowJSONFromMap(data, "operations", "[]")))
+	assert.Equal(t, json.RawMessage("{}"), flowJSONFromMap(data, "trigger_options", "{}"))
+}
@@ -0,0 +1,191 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains geo fields: the point and geometry field types.
+//
+// Geo fields store GeoJSON geometries in a JSONB column, longitude first:
+//   - "point"    - a Point; also accepted as [lng, lat] or {"lat": ..., "lng": ...}
+//   - "geometry" - any GeoJSON geometry: Point, LineString, Polygon, their Multi* forms and
+//     GeometryCollection
+//
+// Values are always returned as GeoJSON. Item lists filter them with _within_radius and
+// _intersects (see rbac/spatial.go):
+//
+//	GET /items/stores?location[within_radius]=52.52,13.405,1000
+//	GET /items/zones?filter={"area":{"_intersects":{"type":"Point","coordinates":[13.405,52.52]}}}
+//
+// The PostGIS extension is optional and detected at startup. With it, both operators are
+// answered by PostGIS and indexed geo fields get a GiST index on their geography. Without
+// it, _within_radius measures the distance to points only, _intersects is refused and
+// indexed geo fields get a GIN index.
+package api
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+
+	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/rbac"
+)
+
+// geoJSONTypes are the GeoJSON geometry types geometry fields accept
+var geoJSONTypes = []string{"Point", "MultiPoint", "LineString", "MultiLineString", "Polygon", "MultiPolygon", "GeometryCollection"}
+
+// isGeoField reports whether a field type holds GeoJSON geometries
+func isGeoField(fieldType string) bool {
+	return fieldType == "point" || fieldType == "geometry"
+}
+
+// geoValue is the GeoJSON geometry of a geo field, written to its JSONB column as JSON
+type geoValue map[string]interface{}
+
+// Value implements driver.Valuer
+func (g geoValue) Value() (driver.Value, error) {
+	raw, err := json.Marshal(map[string]interface{}(g))
+	if err != nil {
+		return nil, err
+	}
+	return string(raw), nil
+}
+
+// parseGeoValue reads the value of a geo field as a GeoJSON geometry and checks it
+func parseGeoValue(fieldType string, value interface{}) (geoValue, error) {
+	if text, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("invalid GeoJSON: %w", err)
+		}
+	}
+
+	var geometry map[string]interface{}
+	switch v := value.(type) {
+	case geoValue:
+		geometry = v
+	case map[string]interface{}:
+		geometry = v
+		if _, ok := v["type"]; !ok && fieldType == "point" {
+			// {"lat": ..., "lng": ...}
+			geometry = map[string]interface{}{"type": "Point", "coordinates": []interface{}{v["lng"], v["lat"]}}
+		}
+	case []interface{}:
+		if fieldType != "point" {
+			return nil, fmt.Errorf("expected a GeoJSON geometry, got an array")
+		}
+		geometry = map[string]interface{}{"type": "Point", "coordinates": v}
+	default:
+		return nil, fmt.Errorf("expected a GeoJSON geometry, got %T", value)
+	}
+
+	geometryType, _ := geometry["type"].(string)
+	if fieldType == "point" && geometryType != "Point" {
+		return nil, fmt.Errorf("expected a Point, got %q", geometryType)
+	}
+	if err := checkGeometry(geometry); err != nil {
+		return nil, err
+	}
+	return geoValue(geometry), nil
+}
+
+// checkGeometry checks that a GeoJSON geometry is well formed
+func checkGeometry(geometry map[string]interface{}) error {
+	geometryType, _ := geometry["type"].(string)
+	if !Contains(geoJSONTypes, geometryType) {
+		return fmt.Errorf("unsupported geometry type %q", geometryType)
+	}
+
+	if geometryType == "GeometryCollection" {
+		members, ok := geometry["geometries"].([]interface{})
+		if !ok {
+			return fmt.Errorf("GeometryCollection needs geometries")
+		}
+		for i, member := range members {
+			object, ok := member.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("geometries[%d]: expected a geometry", i)
+			}
+			if err := checkGeometry(object); err != nil {
+				return fmt.Errorf("geometries[%d]: %w", i, err)
+			}
+		}
+		return nil
+	}
+
+	// Nesting of positions in the coordinates of each type
+	depth := map[string]int{"Point": 0, "MultiPoint": 1, "LineString": 1, "MultiLineString": 2, "Polygon": 2, "MultiPolygon": 3}[geometryType]
+	if err := checkCoordinates(geometryType, geometry["coordinates"], depth); err != nil {
+		return fmt.Errorf("%s: %w", geometryType, err)
+	}
+	return nil
+}
+
+// checkCoordinates checks coordinates nested depth levels above positions. Line strings
+// need two positions and polygon rings four, with the last one closing the ring.
+func checkCoordinates(geometryType string, coordinates interface{}, depth int) error {
+	if depth == 0 {
+		return checkPosition(coordinates)
+	}
+
+	list, ok := coordinates.([]interface{})
+	if !ok {
+		return fmt.Errorf("expected an array of coordinates")
+	}
+	for _, item := range list {
+		if err := checkCoordinates(geometryType, item, depth-1); err != nil {
+			return err
+		}
+	}
+
+	ring := depth == 1 && (geometryType == "Polygon" || geometryType == "MultiPolygon")
+	line := depth == 1 && (geometryType == "LineString" || geometryType == "MultiLineString")
+	switch {
+	case ring && len(list) < 4:
+		return fmt.Errorf("a ring needs at least 4 positions")
+	case ring && fmt.Sprint(list[0]) != fmt.Sprint(list[len(list)-1]):
+		return fmt.Errorf("a ring must end at its first position")
+	case line && len(list) < 2:
+		return fmt.Errorf("a line needs at least 2 positions")
+	}
+	return nil
+}
+
+// checkPosition checks a [lng, lat] position, optionally with an altitude
+func checkPosition(value interface{}) error {
+	position, ok := value.([]interface{})
+	if !ok || len(position) < 2 || len(position) > 3 {
+		return fmt.Errorf("expected a [longitude, latitude] position")
+	}
+	numbers := make([]float64, len(position))
+	for i, coordinate := range position {
+		switch number := coordinate.(type) {
+		case float64:
+			numbers[i] = number
+		case json.Number:
+			f, err := number.Float64()
+			if err != nil {
+				return fmt.Errorf("expected a [longitude, latitude] position of numbers")
+			}
+			numbers[i] = f
+		default:
+			return fmt.Errorf("expected a [longitude, latitude] position of numbers")
+		}
+	}
+	if numbers[0] < -180 || numbers[0] > 180 || numbers[1] < -90 || numbers[1] > 90 {
+		return fmt.Errorf("position [%v, %v] is outside -180..180 longitude and -90..90 latitude", numbers[0], numbers[1])
+	}
+	return nil
+}
+
+// DetectPostGIS reports whether the database has the PostGIS extension installed
+func DetectPostGIS(ctx context.Context, database *db.DB) bool {
+	var installed bool
+	err := database.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_extension WHERE extname = 'postgis')").Scan(&installed)
+	return err == nil && installed
+}
+
+// geoIndexStatement returns the CREATE INDEX statement of an indexed geo field: a GiST
+// index on its geography with PostGIS, a GIN index on its JSON without
+func geoIndexStatement(table, name, column string) string {
+	if rbac.PostGIS() {
+		return fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s" ON %s USING gist (%s)`, name, table, rbac.SpatialExpression(column))
+	}
+	return fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "%s" ON %s USING gin ("%s")`, name, table, column)
+}
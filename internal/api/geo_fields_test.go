@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go-rbac-api/internal/rbac"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseGeoValue(t *testing.T) {
+	berlin := geoValue{"type": "Point", "coordinates": []interface{}{13.405, 52.52}}
+
+	for _, value := range []interface{}{
+		map[string]interface{}{"type": "Point", "coordinates": []interface{}{13.405, 52.52}},
+		`{"type": "Point", "coordinates": [13.405, 52.52]}`,
+		[]interface{}{13.405, 52.52},
+		map[string]interface{}{"lat": 52.52, "lng": 13.405},
+		map[string]interface{}{"type": "Point", "coordinates": []interface{}{json.Number("13.405"), json.Number("52.52")}},
+	} {
+		point, err := parseGeoValue("point", value)
+		require.NoError(t, err, value)
+		assert.Equal(t, "Point", point["type"])
+	}
+
+	raw, err := berlin.Value()
+	require.NoError(t, err)
+	assert.Equal(t, `{"coordinates":[13.405,52.52],"type":"Point"}`, raw)
+
+	polygon := `{"type": "Polygon", "coordinates": [[[13.4, 52.5], [13.41, 52.5], [13.41, 52.53], [13.4, 52.5]]]}`
+	_, err = parseGeoValue("geometry", polygon)
+	assert.NoError(t, err)
+	_, err = parseGeoValue("geometry", `{"type": "GeometryCollection", "geometries": [`+polygon+`, {"type": "LineString", "coordinates": [[0, 0], [1, 1]]}]}`)
+	assert.NoError(t, err)
+
+	for fieldType, value := range map[string]interface{}{
+		"point":    polygon,
+		"geometry": []interface{}{13.405, 52.52},
+	} {
+		_, err := parseGeoValue(fieldType, value)
+		assert.Error(t, err, value)
+	}
+	for _, value := range []interface{}{
+		`{"type": "Point", "coordinates": [200, 52.52]}`,
+		`{"type": "Point", "coordinates": ["13.405", "52.52"]}`,
+		`{"type": "Circle", "coordinates": [13.405, 52.52]}`,
+		`{"type": "LineString", "coordinates": [[0, 0]]}`,
+		`{"type": "Polygon", "coordinates": [[[0, 0], [1, 0], [1, 1], [0, 1]]]}`,
+		`{"type": "GeometryCollection"}`,
+		"not json",
+		42.0,
+	} {
+		_, err := parseGeoValue("geometry", value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestGeoIndexStatement(t *testing.T) {
+	assert.Equal(t, `CREATE INDEX IF NOT EXISTS "field_1_idx" ON "acme"."data_stores" USING gin ("location")`,
+		geoIndexStatement(`"acme"."data_stores"`, "field_1_idx", "location"))
+
+	rbac.UsePostGIS(true)
+	defer rbac.UsePostGIS(false)
+	assert.Equal(t, `CREATE INDEX IF NOT EXISTS "field_1_idx" ON "acme"."data_stores" USING gist ((ST_GeomFromGeoJSON("location")::geography))`,
+		geoIndexStatement(`"acme"."data_stores"`, "field_1_idx", "location"))
+}
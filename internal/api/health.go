@@ -0,0 +1,276 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the health checks, which report whether the instance can serve requests.
+//
+// Orchestrators probe two endpoints:
+// - GET /health/live  - the process is up; restart the instance when it fails
+// - GET /health/ready - the instance can take traffic; route none to it while it fails
+//
+// Readiness answers 503 until the server listens, while the database is unreachable, a
+// migration is pending or the schema cache is still warming up, and once the instance
+// starts shutting down.
+//
+// GET /health reports every check in detail:
+// - database     - the primary answers a ping
+// - pool         - how many of the primary's connections are in use (DB_MAX_OPEN_CONNS)
+// - replicas     - each read replica answers a ping (only when DATABASE_REPLICA_URL is set)
+// - migrations   - no migration in MIGRATIONS_DIR is pending, dirty or modified
+// - schema_cache - when the cached schema metadata was last refreshed
+//
+// The overall status is "down" (503) when the primary is unreachable or a migration is
+// dirty, "degraded" when any other check fails and "ok" otherwise.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/migrate"
+	"go-rbac-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Health statuses, of the instance and of each check
+const (
+	HealthOK       = "ok"
+	HealthDegraded = "degraded"
+	HealthDown     = "down"
+)
+
+const (
+	healthTimeout           = 2 * time.Second // How long the checks of one request may take
+	poolSaturationThreshold = 0.9             // Share of open connections in use that degrades the pool
+)
+
+// schemaCacheStatus is implemented by caches of schema metadata
+type schemaCacheStatus interface {
+	LastRefresh() time.Time
+}
+
+// HealthHandler answers health checks
+type HealthHandler struct {
+	db            *db.DB
+	migrator      *migrate.Migrator // nil when the migration files could not be loaded
+	migrationsErr error             // Why the migration files could not be loaded
+	schemaCache   schemaCacheStatus // nil until schema metadata is cached
+	started       atomic.Bool       // Set once the server listens, cleared when it shuts down
+}
+
+// NewHealthHandler creates a HealthHandler checking database against the migrations in
+// cfg.MigrationsDir, and the schema cache if one is installed
+func NewHealthHandler(database *db.DB, cfg *config.Config) *HealthHandler {
+	h := &HealthHandler{db: database}
+	if defaultSchemaCache != nil {
+		h.schemaCache = defaultSchemaCache
+	}
+	migrations, err := migrate.Load(os.DirFS(cfg.MigrationsDir))
+	if err != nil {
+		h.migrationsErr = err
+	} else {
+		h.migrator = migrate.New(database, migrations)
+	}
+	return h
+}
+
+// Health handles GET /health requests
+// @Summary      Health Check
+// @Tags         system
+// @Description  Report database connectivity, connection pool saturation, read replicas, pending migrations and the schema cache. Answers 503 when the database is unreachable or a migration is dirty.
+// @Produce      json
+// @Success      200 {object} models.HealthResponse
+// @Failure      503 {object} models.HealthResponse
+// @Router       /health [get]
+func (h *HealthHandler) Health(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthTimeout)
+	defer cancel()
+
+	report := h.check(ctx)
+	status := http.StatusOK
+	if report.Status == HealthDown {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// SetStarted marks the instance as started, or with false as shutting down. Only started
+// instances are ready.
+func (h *HealthHandler) SetStarted(started bool) {
+	h.started.Store(started)
+}
+
+// Live handles GET /health/live requests
+// @Summary      Liveness probe
+// @Tags         system
+// @Description  Answer 200 while the process is up. Checks no dependencies.
+// @Produce      json
+// @Success      200 {object} models.HealthResponse
+// @Router       /health/live [get]
+func (h *HealthHandler) Live(c *gin.Context) {
+	c.JSON(http.StatusOK, models.HealthResponse{Status: HealthOK, Time: time.Now().UTC()})
+}
+
+// Ready handles GET /health/ready requests
+// @Summary      Readiness probe
+// @Tags         system
+// @Description  Answer 200 once the instance has started, the database is reachable, every migration is applied and the schema cache is warm, and 503 while any of these fails or the instance is shutting down.
+// @Produce      json
+// @Success      200 {object} models.HealthResponse
+// @Failure      503 {object} models.HealthResponse
+// @Router       /health/ready [get]
+func (h *HealthHandler) Ready(c *gin.Context) {
+	ctx, cancel := context.WithTimeout(c.Request.Context(), healthTimeout)
+	defer cancel()
+
+	report := h.readiness(ctx)
+	status := http.StatusOK
+	if report.Status != HealthOK {
+		status = http.StatusServiceUnavailable
+	}
+	c.JSON(status, report)
+}
+
+// readiness runs the checks an instance must pass to receive traffic. Any check that is
+// not ok, degraded ones included, makes the instance not ready.
+func (h *HealthHandler) readiness(ctx context.Context) models.HealthResponse {
+	server := gin.H{"status": HealthOK}
+	if !h.started.Load() {
+		server = gin.H{"status": HealthDown, "error": "not started or shutting down"}
+	}
+	checks := gin.H{
+		"server":       server,
+		"database":     h.checkDatabase(ctx),
+		"migrations":   h.checkMigrations(ctx),
+		"schema_cache": h.checkSchemaCache(),
+	}
+
+	status := HealthOK
+	for _, check := range checks {
+		if check.(gin.H)["status"] != HealthOK {
+			status = HealthDown
+		}
+	}
+	return models.HealthResponse{Status: status, Time: time.Now().UTC(), Checks: checks}
+}
+
+// check runs every check and combines their statuses
+func (h *HealthHandler) check(ctx context.Context) models.HealthResponse {
+	database := h.checkDatabase(ctx)
+	pool := poolHealth(h.db.Stats())
+	migrations := h.checkMigrations(ctx)
+	checks := gin.H{
+		"database":     database,
+		"pool":         pool,
+		"migrations":   migrations,
+		"schema_cache": h.checkSchemaCache(),
+	}
+	statuses := []interface{}{database["status"], pool["status"], migrations["status"]}
+
+	if replicas := h.db.Reader().CheckReplicas(ctx); len(replicas) > 0 {
+		results := make([]gin.H, len(replicas))
+		for i, replica := range replicas {
+			results[i] = replicaHealth(replica)
+			statuses = append(statuses, results[i]["status"])
+		}
+		checks["replicas"] = results
+	}
+
+	return models.HealthResponse{
+		Status: overallHealth(statuses),
+		Time:   time.Now().UTC(),
+		Checks: checks,
+	}
+}
+
+// checkDatabase pings the primary
+func (h *HealthHandler) checkDatabase(ctx context.Context) gin.H {
+	start := time.Now()
+	if err := h.db.PingContext(ctx); err != nil {
+		return gin.H{"status": HealthDown, "error": "unreachable"}
+	}
+	return gin.H{"status": HealthOK, "latency_ms": time.Since(start).Milliseconds()}
+}
+
+// checkMigrations reports the migrations still to be applied
+func (h *HealthHandler) checkMigrations(ctx context.Context) gin.H {
+	if h.migrator == nil {
+		return gin.H{"status": HealthDegraded, "error": h.migrationsErr.Error()}
+	}
+	todo, err := h.migrator.Pending(ctx)
+	switch {
+	case errors.Is(err, migrate.ErrDirty):
+		return gin.H{"status": HealthDown, "error": err.Error()}
+	case err != nil:
+		return gin.H{"status": HealthDegraded, "error": err.Error()}
+	case len(todo) > 0:
+		return gin.H{"status": HealthDegraded, "pending": len(todo)}
+	}
+	return gin.H{"status": HealthOK, "pending": 0}
+}
+
+// checkSchemaCache reports when the schema cache was last refreshed. A cache that has
+// never been filled is still warming up.
+func (h *HealthHandler) checkSchemaCache() gin.H {
+	if h.schemaCache == nil {
+		return gin.H{"status": HealthOK, "enabled": false}
+	}
+	refreshed := h.schemaCache.LastRefresh()
+	if refreshed.IsZero() {
+		return gin.H{"status": HealthDegraded, "enabled": true, "error": "warming up"}
+	}
+	return gin.H{"status": HealthOK, "enabled": true, "last_refresh": refreshed}
+}
+
+// poolHealth reports the use of a connection pool. A pool with nearly every connection
+// in use makes requests wait for one.
+func poolHealth(stats sql.DBStats) gin.H {
+	result := gin.H{
+		"status":           HealthOK,
+		"max_open":         stats.MaxOpenConnections,
+		"open":             stats.OpenConnections,
+		"in_use":           stats.InUse,
+		"idle":             stats.Idle,
+		"wait_count":       stats.WaitCount,
+		"wait_duration_ms": stats.WaitDuration.Milliseconds(),
+	}
+	// Without a limit the pool cannot saturate
+	if stats.MaxOpenConnections > 0 {
+		saturation := float64(stats.InUse) / float64(stats.MaxOpenConnections)
+		result["saturation"] = saturation
+		if saturation >= poolSaturationThreshold {
+			result["status"] = HealthDegraded
+		}
+	}
+	return result
+}
+
+// replicaHealth reports a read replica. Reads fall back to the primary while a replica
+// is down, so a replica never takes the instance down.
+func replicaHealth(replica db.ReplicaHealth) gin.H {
+	result := poolHealth(replica.Stats)
+	if !replica.Up {
+		result["status"] = HealthDegraded
+		result["error"] = "unreachable"
+	}
+	return result
+}
+
+// overallHealth is the worst of statuses
+func overallHealth(statuses []interface{}) string {
+	overall := HealthOK
+	for _, status := range statuses {
+		switch {
+		case status == HealthDown:
+			return HealthDown
+		case status == HealthDegraded:
+			overall = HealthDegraded
+		}
+	}
+	return overall
+}
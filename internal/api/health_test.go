@@ -0,0 +1,118 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/db"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPoolHealth(t *testing.T) {
+	tests := []struct {
+		name           string
+		stats          sql.DBStats
+		wantStatus     string
+		wantSaturation interface{}
+	}{
+		{"Idle", sql.DBStats{MaxOpenConnections: 25, OpenConnections: 2, Idle: 2}, HealthOK, 0.0},
+		{"Busy", sql.DBStats{MaxOpenConnections: 20, OpenConnections: 10, InUse: 10}, HealthOK, 0.5},
+		{"Saturated", sql.DBStats{MaxOpenConnections: 10, OpenConnections: 10, InUse: 9, WaitCount: 4}, HealthDegraded, 0.9},
+		{"Unlimited", sql.DBStats{OpenConnections: 500, InUse: 500}, HealthOK, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := poolHealth(tt.stats)
+			assert.Equal(t, tt.wantStatus, result["status"])
+			assert.Equal(t, tt.wantSaturation, result["saturation"])
+			assert.Equal(t, tt.stats.InUse, result["in_use"])
+		})
+	}
+}
+
+func TestReplicaHealth(t *testing.T) {
+	up := replicaHealth(db.ReplicaHealth{Up: true, Stats: sql.DBStats{MaxOpenConnections: 10}})
+	assert.Equal(t, HealthOK, up["status"])
+	assert.NotContains(t, up, "error")
+
+	// A replica that is down degrades the instance without taking it down
+	down := replicaHealth(db.ReplicaHealth{Error: errors.New("dial tcp: connection refused")})
+	assert.Equal(t, HealthDegraded, down["status"])
+	assert.Equal(t, "unreachable", down["error"])
+}
+
+func TestOverallHealth(t *testing.T) {
+	assert.Equal(t, HealthOK, overallHealth([]interface{}{HealthOK, HealthOK}))
+	assert.Equal(t, HealthDegraded, overallHealth([]interface{}{HealthOK, HealthDegraded}))
+	assert.Equal(t, HealthDown, overallHealth([]interface{}{HealthDegraded, HealthDown, HealthOK}))
+	assert.Equal(t, HealthOK, overallHealth(nil))
+}
+
+type fakeSchemaCache time.Time
+
+func (f fakeSchemaCache) LastRefresh() time.Time { return time.Time(f) }
+
+func TestHealthHandler_Checks(t *testing.T) {
+	h := &HealthHandler{migrationsErr: errors.New("open migrations: no such file or directory")}
+	assert.Equal(t, HealthDegraded, h.checkMigrations(context.Background())["status"])
+
+	assert.Equal(t, map[string]interface{}{"status": HealthOK, "enabled": false}, map[string]interface{}(h.checkSchemaCache()))
+
+	// A cache that was never filled is not warm yet
+	h.schemaCache = fakeSchemaCache(time.Time{})
+	assert.Equal(t, HealthDegraded, h.checkSchemaCache()["status"])
+
+	refreshed := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	h.schemaCache = fakeSchemaCache(refreshed)
+	assert.Equal(t, HealthOK, h.checkSchemaCache()["status"])
+	assert.Equal(t, refreshed, h.checkSchemaCache()["last_refresh"])
+}
+
+func TestHealthHandler_Probes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	conn, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=postgres dbname=basin sslmode=disable connect_timeout=1")
+	require.NoError(t, err)
+	defer conn.Close()
+	h := &HealthHandler{db: &db.DB{DB: conn}, migrationsErr: errors.New("no migrations")}
+
+	probe := func(handler gin.HandlerFunc) (*httptest.ResponseRecorder, map[string]interface{}) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/health", nil)
+		handler(c)
+
+		var body map[string]interface{}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w, body
+	}
+
+	// The process is alive even when nothing else works
+	w, body := probe(h.Live)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, HealthOK, body["status"])
+
+	w, body = probe(h.Ready)
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Equal(t, HealthDown, body["status"])
+	checks := body["checks"].(map[string]interface{})
+	for _, name := range []string{"server", "database", "migrations"} {
+		assert.NotEqual(t, HealthOK, checks[name].(map[string]interface{})["status"], name)
+	}
+
+	h.SetStarted(true)
+	_, body = probe(h.Ready)
+	checks = body["checks"].(map[string]interface{})
+	assert.Equal(t, HealthOK, checks["server"].(map[string]interface{})["status"])
+	assert.Equal(t, HealthDown, body["status"], "the database is still unreachable")
+}
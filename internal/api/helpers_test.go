@@ -0,0 +1,24 @@
+package api
+
+import (
+	"database/sql"
+	"testing"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+)
+
+// newOfflineDB returns a DB handle that points at a port nothing listens on.
+// Handlers can be constructed against it and every query fails fast with a
+// connection error, which is what the structural unit tests expect.
+func newOfflineDB(t *testing.T) *db.DB {
+	t.Helper()
+
+	conn, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=test dbname=test sslmode=disable connect_timeout=1")
+	if err != nil {
+		t.Fatalf("failed to open offline database: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	return &db.DB{DB: conn, Queries: sqlc.New(conn)}
+}
@@ -0,0 +1,147 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains user impersonation.
+//
+// Impersonation Endpoints:
+// - POST /auth/impersonate/:user_id - Get a short-lived token acting as a user (instance admins)
+// - POST /auth/impersonate/stop     - End the impersonation of the request's token
+//
+// Impersonation lets support reproduce a user's permission issues without their password.
+// The token belongs to a session of the user that lasts IMPERSONATION_EXPIRY and names the
+// admin in its impersonator claim, which every audit entry made with it records.
+package api
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+
+	"go-rbac-api/internal/audit"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Impersonate handles POST /auth/impersonate/:user_id requests
+// @Summary      Impersonate a user
+// @Description  Instance admins get a short-lived token that acts as the user in their home tenant. Instance admins cannot be impersonated.
+// @Tags         auth
+// @Produce      json
+// @Param        user_id path   string true "User ID"
+// @Success      200   {object} models.ImpersonationResponse
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /auth/impersonate/{user_id} [post]
+func (h *AuthHandler) Impersonate(c *gin.Context) {
+	if !c.GetBool("is_super_admin") {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only instance admins can impersonate users"})
+		return
+	}
+	targetID, err := uuid.Parse(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return
+	}
+	adminID, _ := middleware.GetUserID(c)
+	if targetID == adminID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot impersonate yourself"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.db.Queries.GetUserByID(ctx, targetID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user"})
+		return
+	}
+	// An impersonation must not grant more than its admin already has
+	if user.IsSuperAdmin {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Instance admins cannot be impersonated"})
+		return
+	}
+	if !user.IsActive.Bool {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "User account is disabled"})
+		return
+	}
+
+	var tenant *sqlc.Tenant
+	if user.TenantID.Valid {
+		home, err := h.db.Queries.GetTenantByID(ctx, user.TenantID.UUID)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tenant"})
+			return
+		}
+		if !home.IsActive.Bool {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The user's tenant is suspended or deleted"})
+			return
+		}
+		tenant = &home
+	}
+
+	token, session, err := h.authProvider.IssueImpersonationToken(c, user, tenant, adminID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+	sessionID, _ := uuid.Parse(session.ID)
+
+	entry := middleware.NewAuditEntry(c, audit.ActionImpersonate)
+	entry.StatusCode = http.StatusOK
+	entry.Details = map[string]interface{}{
+		"target_user_id": user.ID,
+		"session_id":     sessionID,
+		"expires_at":     session.ExpiresAt,
+	}
+	h.audit.Record(ctx, entry)
+
+	response := models.ImpersonationResponse{
+		Token:     token,
+		UserID:    user.ID,
+		Email:     user.Email,
+		SessionID: sessionID,
+		ExpiresAt: session.ExpiresAt,
+	}
+	if tenant != nil {
+		response.TenantID = &tenant.ID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// StopImpersonation handles POST /auth/impersonate/stop requests
+// @Summary      Stop impersonating
+// @Description  Revokes the session of the impersonation token making the request.
+// @Tags         auth
+// @Produce      json
+// @Success      200   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /auth/impersonate/stop [post]
+func (h *AuthHandler) StopImpersonation(c *gin.Context) {
+	_, impersonating := middleware.GetImpersonatorID(c)
+	sessionID, ok := currentSessionID(c)
+	if !impersonating || !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Not an impersonation token"})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	if _, err := h.db.Queries.RevokeSession(c.Request.Context(), sqlc.RevokeSessionParams{ID: sessionID, UserID: userID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to stop impersonating"})
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionImpersonateStop)
+	entry.StatusCode = http.StatusOK
+	entry.Details = map[string]interface{}{"session_id": sessionID}
+	h.audit.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Impersonation stopped"})
+}
@@ -0,0 +1,522 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains share links, which let anyone holding one read a single item without
+// an account.
+//
+// Share Endpoints:
+// - POST   /items/:table/:id/share             - Create a share link for an item
+// - GET    /items/:table/:id/shares            - List the share links of an item
+// - DELETE /items/:table/:id/shares/:share_id  - Revoke a share link
+// - GET    /shared/:token                      - Read the shared item (no authentication)
+//
+// Share tokens are JWTs signed with a key derived from JWT_SECRET, so they cannot be used
+// as access tokens. Each names a row of item_shares holding the fields it reveals, which
+// makes it revocable before it expires. Links are only created for collections.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// itemSharePurpose marks share tokens
+const itemSharePurpose = "item_share"
+
+// itemShareClaims identify one share (ID) of one item (Subject)
+type itemShareClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// itemShareRequest is the body of POST /items/:table/:id/share
+type itemShareRequest struct {
+	Fields    []string   `json:"fields"`     // Defaults to every field the creator may read
+	ExpiresAt *time.Time `json:"expires_at"` // Omitted for a link that does not expire
+}
+
+// signItemShareToken creates the token of a share link
+func signItemShareToken(secret string, share sqlc.ItemShare) (string, error) {
+	claims := itemShareClaims{
+		Purpose: itemSharePurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:       share.ID.String(),
+			Subject:  share.ItemID.String(),
+			IssuedAt: jwt.NewNumericDate(share.CreatedAt),
+		},
+	}
+	if share.ExpiresAt.Valid {
+		claims.ExpiresAt = jwt.NewNumericDate(share.ExpiresAt.Time)
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(purposeKey(secret, itemSharePurpose))
+}
+
+// parseItemShareToken checks a share token and returns the share and item it names
+func parseItemShareToken(secret, token string) (shareID, itemID uuid.UUID, err error) {
+	var claims itemShareClaims
+	_, err = jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return purposeKey(secret, itemSharePurpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	if claims.Purpose != itemSharePurpose {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("not a share token")
+	}
+
+	if shareID, err = uuid.Parse(claims.ID); err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid share ID")
+	}
+	if itemID, err = uuid.Parse(claims.Subject); err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid item ID")
+	}
+	return shareID, itemID, nil
+}
+
+// shareFields resolves the fields a share link reveals: the ones asked for, which must be
+// fields of the item the creator may read, or every field the creator may read. Fields the
+// creator only reads redacted (see rbac.FieldTransforms) are never shared.
+func shareFields(requested, allowedFields []string, transforms rbac.FieldTransforms, item map[string]interface{}) ([]string, error) {
+	allowsAll := len(allowedFields) == 0 || Contains(allowedFields, "*")
+	if len(requested) == 0 {
+		if len(transforms) == 0 {
+			if allowsAll {
+				return []string{"*"}, nil
+			}
+			return allowedFields, nil
+		}
+
+		fields := make([]string, 0, len(item))
+		for field := range item {
+			if _, redacted := transforms[field]; !redacted && (allowsAll || Contains(allowedFields, field)) {
+				fields = append(fields, field)
+			}
+		}
+		sort.Strings(fields)
+		return fields, nil
+	}
+
+	fields := make([]string, 0, len(requested))
+	for _, field := range requested {
+		if _, exists := item[field]; !exists {
+			return nil, validationError("unknown field %q", field)
+		}
+		if !allowsAll && !Contains(allowedFields, field) {
+			return nil, forbiddenError("field %q cannot be shared", field)
+		}
+		if _, redacted := transforms[field]; redacted {
+			return nil, forbiddenError("field %q is redacted and cannot be shared", field)
+		}
+		if !Contains(fields, field) {
+			fields = append(fields, field)
+		}
+	}
+	return fields, nil
+}
+
+// itemShareActive reports whether a share link still grants access at now
+func itemShareActive(share sqlc.ItemShare, now time.Time) bool {
+	return !share.RevokedAt.Valid && (!share.ExpiresAt.Valid || now.Before(share.ExpiresAt.Time))
+}
+
+// itemShareToMap converts a share to its API representation
+func itemShareToMap(share sqlc.ItemShare) map[string]interface{} {
+	return map[string]interface{}{
+		"id":           share.ID,
+		"collection":   share.Collection,
+		"item_id":      share.ItemID,
+		"fields":       share.Fields,
+		"created_by":   share.CreatedBy,
+		"expires_at":   nullTimePtr(share.ExpiresAt),
+		"revoked_at":   nullTimePtr(share.RevokedAt),
+		"last_used_at": nullTimePtr(share.LastUsedAt),
+		"created_at":   share.CreatedAt,
+		"active":       itemShareActive(share, time.Now()),
+	}
+}
+
+// parseShareRequest checks the table and item of a share request
+func (h *ItemsHandler) parseShareRequest(c *gin.Context) (string, uuid.UUID, bool) {
+	tableName := c.Param("table")
+	if !rbac.ValidateTableName(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table name"})
+		return "", uuid.Nil, false
+	}
+
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return "", uuid.Nil, false
+	}
+
+	if h.isSchemaTable(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Share links are only supported on collections"})
+		return "", uuid.Nil, false
+	}
+	return tableName, itemID, true
+}
+
+// checkShareAccess checks the user may read the table and the item, returning the fields
+// they may read and the item or answering the request
+func (h *ItemsHandler) checkShareAccess(c *gin.Context, userID uuid.UUID, tableName string, itemID uuid.UUID) ([]string, map[string]interface{}, bool) {
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, allowedFields, rowFilter, transforms, err := h.policyChecker.CheckPermissionWithTransforms(ctxWithTenant, userID, tableName, "read")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return nil, nil, false
+	}
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return nil, nil, false
+	}
+
+	withRowFilter(c, tableName, rowFilter)
+	withFieldTransforms(c, tableName, transforms)
+	item, err := h.dynamicHandlers.GetDynamicItem(c.Request.Context(), userID, tableName, itemID.String(), false)
+	if err != nil {
+		respondError(c, err, "Failed to fetch item")
+		return nil, nil, false
+	}
+	return allowedFields, item, true
+}
+
+// CreateItemShare handles POST /items/:table/:id/share requests.
+//
+// Creates a link granting read access to the item, limited to the fields named in the body
+// (by default every field the caller may read) and optionally expiring. The caller needs
+// read permission on the table and must be able to read the item. The token is only
+// returned here.
+//
+// Response Format:
+//   - 201: {"data": {"id": "...", "token": "...", "url": "/shared/<token>", "fields": [...], ...}}
+//   - 400: Invalid table name, item ID or request body, or a schema table
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission, or names a field they may not read
+//   - 404: Item not found
+//   - 422: Unknown field or an expiry in the past
+//
+// @Summary      Create a share link
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Create a signed link granting read access to one item without an account. Body: {"fields": [...], "expires_at": "RFC 3339 time"}, both optional.
+// @Param        table  path  string true "Collection name"
+// @Param        id     path  string true "Item ID"
+// @Param        body   body  map[string]interface{} false "Fields and expiry"
+// @Accept       json
+// @Produce      json
+// @Success      201 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
+// @Router       /items/{table}/{id}/share [post]
+func (h *ItemsHandler) CreateItemShare(c *gin.Context) {
+	tableName, itemID, ok := h.parseShareRequest(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req itemShareRequest
+	if c.Request.ContentLength > 0 {
+		if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+			respondBodyError(c, err, "Invalid request body")
+			return
+		}
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		respondError(c, validationError("expires_at must be in the future"), "Invalid share")
+		return
+	}
+
+	allowedFields, item, ok := h.checkShareAccess(c, userID, tableName, itemID)
+	if !ok {
+		return
+	}
+	fields, err := shareFields(req.Fields, allowedFields, rbac.FieldTransformsFromContext(c.Request.Context(), tableName), item)
+	if err != nil {
+		respondError(c, err, "Invalid share")
+		return
+	}
+
+	tenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+
+	params := sqlc.CreateItemShareParams{
+		TenantID:   tenantID,
+		Collection: tableName,
+		ItemID:     itemID,
+		Fields:     fields,
+		CreatedBy:  userID,
+	}
+	if req.ExpiresAt != nil {
+		params.ExpiresAt = sql.NullTime{Time: *req.ExpiresAt, Valid: true}
+	}
+	share, err := h.db.Queries.CreateItemShare(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share"})
+		return
+	}
+
+	token, err := signItemShareToken(h.cfg.JWTSecret, share)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign share"})
+		return
+	}
+	result := itemShareToMap(share)
+	result["token"] = token
+	result["url"] = "/shared/" + token
+	c.JSON(http.StatusCreated, gin.H{"data": result})
+}
+
+// ListItemShares handles GET /items/:table/:id/shares requests.
+//
+// Lists the share links of an item, newest first, including revoked and expired ones.
+// Tokens are not listed. Requires read permission on the table and the item.
+//
+// Response Format:
+//   - 200: {"data": [...], "meta": {"count": 2}}
+//   - 400: Invalid table name or item ID, or a schema table
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission
+//   - 404: Item not found
+//
+// @Summary      List share links
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  List the share links of an item with their fields, expiry, revocation and last use.
+// @Param        table  path  string true "Collection name"
+// @Param        id     path  string true "Item ID"
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/{table}/{id}/shares [get]
+func (h *ItemsHandler) ListItemShares(c *gin.Context) {
+	tableName, itemID, ok := h.parseShareRequest(c)
+	if !ok {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if _, _, ok := h.checkShareAccess(c, userID, tableName, itemID); !ok {
+		return
+	}
+
+	tenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+	shares, err := h.db.Queries.ListItemShares(c.Request.Context(), sqlc.ListItemSharesParams{
+		TenantID:   tenantID,
+		Collection: tableName,
+		ItemID:     itemID,
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch shares"})
+		return
+	}
+
+	results := make([]map[string]interface{}, len(shares))
+	for i, share := range shares {
+		results[i] = itemShareToMap(share)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": results, "meta": gin.H{"count": len(results)}})
+}
+
+// RevokeItemShare handles DELETE /items/:table/:id/shares/:share_id requests.
+//
+// Revokes a share link, so its token stops working at once. The creator of a link can
+// always revoke it; anyone else needs update permission on the table.
+//
+// Response Format:
+//   - 200: {"data": {...}} with the revoked share
+//   - 400: Invalid table name, item ID or share ID, or a schema table
+//   - 401: Missing or invalid authentication token
+//   - 403: User neither created the link nor may update the table
+//   - 404: Share not found or already revoked
+//
+// @Summary      Revoke a share link
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Revoke a share link of an item. Allowed to its creator and to users with update permission on the table.
+// @Param        table     path  string true "Collection name"
+// @Param        id        path  string true "Item ID"
+// @Param        share_id  path  string true "Share ID"
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/{table}/{id}/shares/{share_id} [delete]
+func (h *ItemsHandler) RevokeItemShare(c *gin.Context) {
+	tableName, itemID, ok := h.parseShareRequest(c)
+	if !ok {
+		return
+	}
+
+	shareID, err := uuid.Parse(c.Param("share_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid share ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	tenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+
+	// The share must belong to this item; other tenants' shares look like missing ones
+	share, err := h.db.Queries.GetItemShare(c.Request.Context(), shareID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (share.TenantID != tenantID || share.Collection != tableName || share.ItemID != itemID || share.RevokedAt.Valid)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share"})
+		return
+	}
+
+	if share.CreatedBy != userID {
+		ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+		allowed, _, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, tableName, "update")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+			return
+		}
+	}
+
+	revoked, err := h.db.Queries.RevokeItemShare(c.Request.Context(), sqlc.RevokeItemShareParams{ID: share.ID, TenantID: tenantID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share"})
+		return
+	}
+	if revoked == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share not found"})
+		return
+	}
+
+	share.RevokedAt = sql.NullTime{Time: time.Now(), Valid: true}
+	c.JSON(http.StatusOK, gin.H{"data": itemShareToMap(share)})
+}
+
+// GetSharedItem handles GET /shared/:token requests.
+//
+// Returns the item a share link grants access to, limited to the link's fields. No
+// authentication is needed; revoked and expired links, links of inactive tenants and
+// links to deleted items all answer 404.
+//
+// Response Format:
+//   - 200: {"data": {...}, "meta": {"table": "posts", "id": "...", "expires_at": null}}
+//   - 404: Invalid, expired or revoked link, or the item is gone
+//
+// @Summary      Read a shared item
+// @Tags         items
+// @Description  Read the item of a share link without an account.
+// @Param        token  path  string true "Share token"
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /shared/{token} [get]
+func (h *ItemsHandler) GetSharedItem(c *gin.Context) {
+	notFound := gin.H{"error": "Invalid or expired share link", "code": CodeNotFound}
+
+	shareID, itemID, err := parseItemShareToken(h.cfg.JWTSecret, c.Param("token"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, notFound)
+		return
+	}
+
+	ctx := c.Request.Context()
+	share, err := h.db.Queries.GetItemShare(ctx, shareID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && (share.ItemID != itemID || !itemShareActive(share, time.Now()))) {
+		c.JSON(http.StatusNotFound, notFound)
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share"})
+		return
+	}
+	if err := middleware.CheckTenantActive(ctx, h.db, share.TenantID); err != nil {
+		c.JSON(http.StatusNotFound, notFound)
+		return
+	}
+
+	// The item is read in the share's tenant, as its creator
+	item, err := h.dynamicHandlers.GetDynamicItem(WithTenant(ctx, share.TenantID), share.CreatedBy, share.Collection, itemID.String(), false)
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrDataTableNotFound) {
+		c.JSON(http.StatusNotFound, notFound)
+		return
+	}
+	if err != nil {
+		respondError(c, err, "Failed to fetch item")
+		return
+	}
+
+	// Share links never decrypt: encrypted fields are masked for everyone holding the link
+	encrypted, err := h.dynamicHandlers.encryptedFields(ctx, share.TenantID, share.Collection)
+	if err != nil {
+		respondError(c, err, "Failed to fetch item")
+		return
+	}
+	if err := revealValues(encrypted, []map[string]interface{}{item}, false); err != nil {
+		respondError(c, err, "Failed to fetch item")
+		return
+	}
+
+	if err := h.db.Queries.TouchItemShare(ctx, share.ID); err != nil {
+		middleware.GetLogger(c).Warn("failed to record share use", "share_id", share.ID, "error", err)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"data": h.policyChecker.FilterFields(item, share.Fields, nil),
+		"meta": gin.H{
+			"table":      share.Collection,
+			"id":         itemID,
+			"expires_at": nullTimePtr(share.ExpiresAt),
+		},
+	})
+}
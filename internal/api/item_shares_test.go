@@ -0,0 +1,78 @@
+package api
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemShareToken(t *testing.T) {
+	share := sqlc.ItemShare{ID: uuid.New(), ItemID: uuid.New(), CreatedAt: time.Now()}
+
+	token, err := signItemShareToken("secret", share)
+	require.NoError(t, err)
+	shareID, itemID, err := parseItemShareToken("secret", token)
+	require.NoError(t, err)
+	assert.Equal(t, share.ID, shareID)
+	assert.Equal(t, share.ItemID, itemID)
+
+	_, _, err = parseItemShareToken("other-secret", token)
+	assert.Error(t, err)
+
+	// Password reset tokens are signed with another key
+	reset, err := signPasswordResetToken("secret", sqlc.PasswordReset{ID: share.ID, UserID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, _, err = parseItemShareToken("secret", reset)
+	assert.Error(t, err)
+
+	share.ExpiresAt = sql.NullTime{Time: time.Now().Add(-time.Minute), Valid: true}
+	expired, err := signItemShareToken("secret", share)
+	require.NoError(t, err)
+	_, _, err = parseItemShareToken("secret", expired)
+	assert.Error(t, err)
+}
+
+func TestShareFields(t *testing.T) {
+	item := map[string]interface{}{"id": "1", "title": "Hello", "body": "...", "secret": "x"}
+
+	fields, err := shareFields(nil, []string{"*"}, nil, item)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"*"}, fields)
+
+	fields, err = shareFields(nil, []string{"id", "title"}, nil, item)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "title"}, fields)
+
+	fields, err = shareFields([]string{"title", "body", "title"}, []string{"*"}, nil, item)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"title", "body"}, fields)
+
+	_, err = shareFields([]string{"secret"}, []string{"id", "title"}, nil, item)
+	assert.ErrorIs(t, err, ErrForbidden)
+
+	_, err = shareFields([]string{"missing"}, []string{"*"}, nil, item)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	redacted := rbac.FieldTransforms{"body": rbac.TransformHash}
+	fields, err = shareFields(nil, []string{"*"}, redacted, item)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"id", "secret", "title"}, fields)
+
+	_, err = shareFields([]string{"title", "body"}, []string{"*"}, redacted, item)
+	assert.ErrorIs(t, err, ErrForbidden)
+}
+
+func TestItemShareActive(t *testing.T) {
+	now := time.Now()
+	assert.True(t, itemShareActive(sqlc.ItemShare{}, now))
+	assert.True(t, itemShareActive(sqlc.ItemShare{ExpiresAt: sql.NullTime{Time: now.Add(time.Hour), Valid: true}}, now))
+	assert.False(t, itemShareActive(sqlc.ItemShare{ExpiresAt: sql.NullTime{Time: now.Add(-time.Hour), Valid: true}}, now))
+	assert.False(t, itemShareActive(sqlc.ItemShare{RevokedAt: sql.NullTime{Time: now, Valid: true}}, now))
+}
@@ -14,8 +14,11 @@
 // - GET    /items/:table     - List all items in a table (with filtering/pagination)
 // - GET    /items/:table/:id - Get a specific item by ID
 // - POST   /items/:table     - Create a new item in a table
-// - PUT    /items/:table/:id - Update an existing item
+// - PUT    /items/:table/:id - Update an existing item (?replace=true resets missing fields)
+// - PATCH  /items/:table/:id - Change only the given fields of an item
 // - DELETE /items/:table/:id - Delete an item by ID
+// - POST/PATCH/DELETE /items/:table/bulk - Bulk create/update/delete in one transaction (see items_bulk.go)
+// - POST   /items/:table/upsert - Insert or update items keyed by a unique field (see items_upsert.go)
 //
 // The API automatically handles:
 // - Multi-tenant data isolation
@@ -32,13 +35,17 @@ package api
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
-	"strconv"
 	"strings"
+	"time"
 
+	"go-rbac-api/internal/config"
 	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/events"
 	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/quota"
 	"go-rbac-api/internal/rbac"
 
 	"github.com/gin-gonic/gin"
@@ -60,7 +67,10 @@ import (
 // 4. Formats responses consistently for the API
 //
 // Supported Table Types:
-//   - Schema Tables: collections, fields, users, roles, permissions, api_keys
+//   - Schema Tables: collections, fields, users, roles, permissions, api_keys, webhooks,
+//     webhook_deliveries (read-only), flows, flow_runs (read-only), jobs (admins only;
+//     dead jobs can be retried), assets (upload and delete through /assets), audit_logs
+//     (read-only, admins only)
 //     → Delegated to SchemaHandlers for structured CRUD with business logic
 //   - Dynamic Tables: tenant-specific data tables (e.g., products, orders)
 //     → Delegated to DynamicHandlers for flexible, schema-driven operations
@@ -73,11 +83,15 @@ import (
 // - Comprehensive error handling with proper HTTP status codes
 type ItemsHandler struct {
 	db                 *db.DB              // Database connection pool for direct queries
+	cfg                *config.Config      // Application configuration
 	policyChecker      *rbac.PolicyChecker // RBAC policy evaluation engine
 	utils              *ItemsUtils         // Utility functions for common operations
 	schemaHandlers     *SchemaHandlers     // Handler for schema management tables
 	dynamicHandlers    *DynamicHandlers    // Handler for dynamic tenant data tables
 	collectionsHandler *CollectionsHandler // Handler for user-created collections
+	relationExpander   *RelationExpander   // Resolves ?fields=relation.* into nested objects
+	events             *events.Bus         // Receives item change events (webhooks, realtime)
+	quota              *quota.Checker      // Tenant limits on collections, items and API keys
 }
 
 // NewItemsHandler creates a fully configured ItemsHandler with all required dependencies.
@@ -88,26 +102,33 @@ type ItemsHandler struct {
 //
 // Parameters:
 //   - db: Database connection pool that will be shared across all handlers
+//   - cfg: Application configuration (password policy, feature settings)
+//   - bus: Event bus that receives item change events from every write path
 //
 // Returns:
 //   - *ItemsHandler: Fully configured handler ready to process HTTP requests
 //
 // Example:
 //
-//	handler := NewItemsHandler(dbConnection)
+//	handler := NewItemsHandler(dbConnection, cfg, events.NewBus())
 //	router.GET("/items/:table", handler.GetItems)
 //	router.POST("/items/:table", handler.CreateItem)
-func NewItemsHandler(db *db.DB) *ItemsHandler {
+func NewItemsHandler(db *db.DB, cfg *config.Config, bus *events.Bus) *ItemsHandler {
 	handler := &ItemsHandler{
 		db:            db,
-		policyChecker: rbac.NewPolicyChecker(db.Queries),
+		cfg:           cfg,
+		policyChecker: rbac.NewPolicyChecker(db.Reader().Queries),
+		events:        bus,
+		quota:         quota.NewChecker(db, quota.Defaults(cfg)),
 	}
 
 	// Initialize utility and handler components
 	handler.utils = NewItemsUtils(db)
 	handler.schemaHandlers = NewSchemaHandlers(handler, handler.utils)
-	handler.dynamicHandlers = NewDynamicHandlers(db, handler.utils)
+	handler.dynamicHandlers = NewDynamicHandlers(db, handler.utils, bus)
+	handler.dynamicHandlers.quota = handler.quota
 	handler.collectionsHandler = NewCollectionsHandler(db, handler.utils, handler.dynamicHandlers)
+	handler.relationExpander = NewRelationExpander(db, handler.utils, handler.collectionsHandler, handler.policyChecker)
 
 	return handler
 }
@@ -154,21 +175,21 @@ func NewItemsHandler(db *db.DB) *ItemsHandler {
 // @Tags         items
 // @Security     BearerAuth
 // @Security     ApiKeyAuth
-// @Description  Retrieve a paginated list of items from any dynamic table in the system. This endpoint works with both core schema tables (users, roles, permissions, collections, fields, api-keys) and custom dynamic tables (e.g., blog_posts, customers, products). The API automatically adapts to the table's schema, applying filters, sorting, and pagination. Requires authentication via JWT Bearer token or API key.
+// @Description  Retrieve a paginated list of items from any dynamic table in the system. This endpoint works with both core schema tables (users, roles, permissions, collections, fields, api-keys) and custom dynamic tables (e.g., blog_posts, customers, products). The API automatically adapts to the table's schema, applying filters, sorting, and pagination. Requires authentication via JWT Bearer token or API key, except for collections readable by the tenant's public role.
 // @Param        table    path   string true  "Table name (e.g., 'users', 'blog_posts', 'customers')"
-// @Param        limit    query  int    false "Limit (max 500, default 25)"
+// @Param        limit    query  int    false "Limit (max 500, default 50)"
 // @Param        offset   query  int    false "Offset for pagination"
 // @Param        page     query  int    false "Page number (1-based, alternative to offset)"
 // @Param        per_page query  int    false "Items per page (alternative to limit)"
-// @Param        sort     query  string false "Sort field (e.g., 'created_at', 'name', 'email')"
-// @Param        order    query  string false "Sort order: ASC or DESC (default: DESC)"
-// @Param        filter   query  string false "JSON filter object for advanced filtering"
-// @Param        limit    query  int    false "Limit"
-// @Param        offset   query  int    false "Offset"
-// @Param        page     query  int    false "Page (1-based)"
-// @Param        per_page query  int    false "Per page"
-// @Param        sort     query  string false "Sort field"
-// @Param        order    query  string false "ASC or DESC"
+// @Param        sort     query  string false "Comma-separated sort fields, - prefix for descending (e.g., '-created_at,name')"
+// @Param        order    query  string false "Direction of sort fields without a prefix: ASC or DESC (default: ASC)"
+// @Param        filter   query  string false "Filter rule as JSON or in brackets (e.g., filter[price][_gte]=10)"
+// @Param        deep     query  string false "Options of expanded relations (e.g., deep[orders][_limit]=5, _filter, _sort)"
+// @Param        fields   query  string false "Fields to return (e.g., 'id,name,price'); dotted paths expand relations (e.g., '*,customer.*')"
+// @Param        cursor   query  string false "Opaque cursor from meta.next_cursor (alternative to offset/page)"
+// @Param        meta     query  string false "Counts to compute: 'total_count', 'filter_count' or '*' for both"
+// @Param        include_deleted query bool false "Include soft-deleted items (soft-delete collections only)"
+// @Param        search   query  string false "Full-text search over the collection's string and text fields"
 // @Produce      json
 // @Success      200 {object} models.ItemsListResponse
 // @Failure      400 {object} models.ErrorResponse
@@ -184,8 +205,8 @@ func (h *ItemsHandler) GetItems(c *gin.Context) {
 		return
 	}
 
-	// Get user ID from context
-	userID, exists := middleware.GetUserID(c)
+	// Get user ID from context; public requests read as the tenant's public role
+	userID, exists := h.readingUser(c, tableName)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
@@ -198,26 +219,37 @@ func (h *ItemsHandler) GetItems(c *gin.Context) {
 	// Create a context with tenant information
 	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
 
-	hasPermission, allowedFields, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, tableName, "read")
+	hasPermission, allowedFields, rowFilter, transforms, err := h.policyChecker.CheckPermissionWithTransforms(ctxWithTenant, userID, tableName, "read")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
 		return
 	}
 
-	if !hasPermission {
+	if !hasPermission || (adminOnlyTables[tableName] && !c.GetBool("is_admin")) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
+	withRowFilter(c, tableName, rowFilter)
+	withFieldTransforms(c, tableName, transforms)
 
-	// Route to appropriate handler based on table type
-	if h.isSchemaTable(tableName) {
-		h.handleSchemaTableQuery(c, tableName, userID, allowedFields)
+	// Check if this is a user-created collection; its lists may be answered from the
+	// response cache
+	if !h.isSchemaTable(tableName) && h.isUserCollection(c.Request.Context(), userID, tableName) {
+		h.cachedQuery(c, tableName, userID, allowedFields, rowFilter, func() {
+			h.handleUserCollectionQuery(c, tableName, userID, allowedFields)
+		})
 		return
 	}
 
-	// Check if this is a user-created collection
-	if h.isUserCollection(c.Request.Context(), userID, tableName) {
-		h.handleUserCollectionQuery(c, tableName, userID, allowedFields)
+	// Only collections have the field definitions search works from
+	if c.Query("search") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search is only supported on collections"})
+		return
+	}
+
+	// Route to appropriate handler based on table type
+	if h.isSchemaTable(tableName) {
+		h.handleSchemaTableQuery(c, tableName, userID, allowedFields)
 		return
 	}
 
@@ -225,24 +257,35 @@ func (h *ItemsHandler) GetItems(c *gin.Context) {
 	h.handleDynamicTableQuery(c, tableName, userID, allowedFields)
 }
 
-// GetItem handles GET /items/:table/:id requests
+// GetItem handles GET /items/:table/:id requests. With ?key_field=<unique field> the id is
+// the value of that field instead (see items_lookup.go).
 // @Summary      Get item from dynamic table
 // @Tags         items
 // @Security     BearerAuth
 // @Security     ApiKeyAuth
-// @Description  Retrieve a specific item by ID from any dynamic table in the system. This endpoint works with both core schema tables and custom dynamic tables. Requires authentication via JWT Bearer token or API key.
+// @Description  Retrieve a specific item by ID from any dynamic table in the system. This endpoint works with both core schema tables and custom dynamic tables. Requires authentication via JWT Bearer token or API key, except for collections readable by the tenant's public role.
 // @Param        table   path      string true  "Table name (e.g., 'users', 'blog_posts', 'customers')"
-// @Param        id      path      string true  "Item ID"
+// @Param        id      path      string true  "Item ID, or the value of key_field"
+// @Param        key_field query   string false "Unique field to look the item up by (collections only)"
+// @Param        fields  query     string false "Fields to return (e.g., 'id,name,price'); dotted paths expand relations (e.g., '*,customer.*')"
+// @Param        include_deleted query bool false "Return the item even if it is soft-deleted"
 // @Produce      json
 // @Success      200 {object} models.ItemResponse
 // @Failure      400 {object} models.ErrorResponse
 // @Failure      401 {object} models.ErrorResponse
 // @Failure      403 {object} models.ErrorResponse
 // @Failure      404 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
 // @Router       /items/{table}/{id} [get]
 func (h *ItemsHandler) GetItem(c *gin.Context) {
+	h.getItem(c, c.Query("key_field"), c.Param("id"))
+}
+
+// getItem answers a read of one item, found by its ID or, when keyField is set, by the value
+// of that unique field
+func (h *ItemsHandler) getItem(c *gin.Context, keyField, key string) {
 	tableName := c.Param("table")
-	itemID := c.Param("id")
+	itemID := key
 
 	// Validate table name
 	if !rbac.ValidateTableName(tableName) {
@@ -251,13 +294,13 @@ func (h *ItemsHandler) GetItem(c *gin.Context) {
 	}
 
 	// Validate item ID
-	if _, err := uuid.Parse(itemID); err != nil {
+	if _, err := uuid.Parse(itemID); err != nil && keyField == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
 		return
 	}
 
-	// Get user ID from context
-	userID, exists := middleware.GetUserID(c)
+	// Get user ID from context; public requests read as the tenant's public role
+	userID, exists := h.readingUser(c, tableName)
 	if !exists {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
 		return
@@ -270,16 +313,26 @@ func (h *ItemsHandler) GetItem(c *gin.Context) {
 	// Create a context with tenant information
 	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
 
-	hasPermission, allowedFields, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, tableName, "read")
+	hasPermission, allowedFields, rowFilter, transforms, err := h.policyChecker.CheckPermissionWithTransforms(ctxWithTenant, userID, tableName, "read")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
 		return
 	}
 
-	if !hasPermission {
+	if !hasPermission || (adminOnlyTables[tableName] && !c.GetBool("is_admin")) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
+	withRowFilter(c, tableName, rowFilter)
+	withFieldTransforms(c, tableName, transforms)
+
+	// Lookups by a unique field resolve the item's ID first
+	if keyField != "" {
+		if itemID, err = h.lookupItemID(c, tableName, userID, keyField, key, allowedFields); err != nil {
+			respondError(c, err, "Failed to fetch item")
+			return
+		}
+	}
 
 	// Check if this is a user collection and route accordingly
 	if h.isUserCollection(c.Request.Context(), userID, tableName) {
@@ -287,19 +340,35 @@ func (h *ItemsHandler) GetItem(c *gin.Context) {
 		return
 	}
 
-	// Build query with WHERE clause
-	query := rbac.BuildSelectQuery(tableName, allowedFields) + " WHERE id = $1"
+	// Build query with WHERE clause, reading only the fields asked for, of the tenant the
+	// request acts in
+	selection := parseFieldSelection(c.Query("fields"))
+	args := []interface{}{itemID}
+	query := rbac.BuildSelectQuery(tableName, h.selectedColumns(selection, allowedFields, nil)) + " WHERE id = $1"
+	if h.isSchemaTable(tableName) {
+		scope, scopeArgs, err := h.schemaTableScope(c.Request.Context(), tableName, userID, len(args)+1)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+			return
+		}
+		if scope != "" {
+			query += " AND " + scope
+			args = append(args, scopeArgs...)
+		}
+	}
+	ruleCondition, ruleArgs := rowFilterCondition(c.Request.Context(), tableName, len(args)+1)
+	query += ruleCondition
 
 	// Execute query
-	rows, err := h.db.Query(query, itemID)
+	rows, err := h.db.Reader().QueryContext(c.Request.Context(), query, append(args, ruleArgs...)...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item"})
+		respondError(c, err, "Failed to fetch item")
 		return
 	}
 	defer rows.Close()
 
 	if !rows.Next() {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found", "code": CodeNotFound})
 		return
 	}
 
@@ -344,7 +413,11 @@ func (h *ItemsHandler) GetItem(c *gin.Context) {
 	}
 
 	// Apply field filtering
-	filteredRow := h.policyChecker.FilterFields(row, allowedFields)
+	filteredRow := h.policyChecker.FilterFields(row, allowedFields, rbac.FieldTransformsFromContext(c.Request.Context(), tableName))
+	redactSecrets(tableName, filteredRow)
+	h.flagExpiringAPIKey(tableName, filteredRow)
+	h.addFieldIndexStatus(c.Request.Context(), tableName, userID, []map[string]interface{}{filteredRow})
+	filteredRow = selection.project(filteredRow)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": filteredRow,
@@ -370,6 +443,10 @@ func (h *ItemsHandler) GetItem(c *gin.Context) {
 //   - JSON object containing the data for the new item
 //   - Fields are automatically filtered based on user permissions
 //
+// With ?upsert=<field> an existing item with the same value of that unique field is
+// updated instead (see items_upsert.go). In collections, a one-to-many field may hold an
+// array of new related items, created in the same transaction (see nested_writes.go).
+//
 // Authentication & Authorization:
 //   - Requires valid JWT token in Authorization header
 //   - User must have "create" permission for the specified table
@@ -378,9 +455,11 @@ func (h *ItemsHandler) GetItem(c *gin.Context) {
 //
 // Response Format:
 //   - 201: Success with created item data and metadata
-//   - 400: Invalid table name, malformed JSON, or validation errors
+//   - 400: Invalid table name or malformed JSON
 //   - 401: Missing or invalid authentication token
 //   - 403: User lacks permission to create in this table
+//   - 409: A unique field already has the given value
+//   - 422: Validation errors
 //   - 500: Internal server error during creation
 //
 // @Summary      Create item in dynamic table
@@ -389,15 +468,25 @@ func (h *ItemsHandler) GetItem(c *gin.Context) {
 // @Security     ApiKeyAuth
 // @Description  Create a new item in any dynamic table in the system. This endpoint works with both core schema tables and custom dynamic tables. The item structure depends on the table's schema (fields, validation rules, etc.). Requires authentication via JWT Bearer token or API key.
 // @Param        table   path      string true  "Table name (e.g., 'users', 'blog_posts', 'customers')"
+// @Param        upsert  query     string false "Unique field to match an existing item on; that item is updated instead (200)"
 // @Param        body    body      map[string]interface{} true "Item data"
 // @Accept       json
 // @Produce      json
+// @Success      200 {object} models.CreateItemResponse
 // @Success      201 {object} models.CreateItemResponse
 // @Failure      400 {object} models.ErrorResponse
 // @Failure      401 {object} models.ErrorResponse
 // @Failure      403 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
 // @Router       /items/{table} [post]
 func (h *ItemsHandler) CreateItem(c *gin.Context) {
+	// ?upsert=<field> updates the item with the same value of that field instead, if any
+	if c.Query("upsert") != "" {
+		h.upsertItem(c)
+		return
+	}
+
 	tableName := c.Param("table")
 
 	// Validate and authenticate request
@@ -423,7 +512,7 @@ func (h *ItemsHandler) CreateItem(c *gin.Context) {
 		return
 	}
 
-	filteredData := h.policyChecker.FilterFields(requestData, allowedFields)
+	filteredData := h.policyChecker.FilterFields(requestData, allowedFields, nil)
 
 	// Route to appropriate handler based on table type
 	if h.isSchemaTable(tableName) {
@@ -438,18 +527,32 @@ func (h *ItemsHandler) CreateItem(c *gin.Context) {
 	}
 
 	// Handle dynamic data tables
-	err = h.dynamicHandlers.CreateDynamicItem(c.Request.Context(), userID, tableName, filteredData)
+	itemID, err := h.dynamicHandlers.CreateDynamicItem(c.Request.Context(), userID, tableName, filteredData)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create item: " + err.Error()})
+		respondError(c, err, "Failed to create item")
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"data": filteredData,
+		"data": withID(filteredData, itemID),
 		"meta": gin.H{"table": tableName},
 	})
 }
 
+// updateMode is how an update treats the fields missing from the request body
+type updateMode int
+
+const (
+	// updateFull (PUT) validates the body as a whole item: required fields must be present,
+	// missing fields with a default are reset to it and other missing fields keep their value
+	updateFull updateMode = iota
+	// updateReplace (PUT ?replace=true) resets every writable field missing from the body
+	// to its default, or null
+	updateReplace
+	// updateMerge (PATCH) only changes the fields in the body; null clears a field
+	updateMerge
+)
+
 // UpdateItem handles PUT /items/:table/:id requests with delegation to specialized handlers.
 //
 // This endpoint provides the core "update item" functionality for Basin's generic API,
@@ -462,9 +565,15 @@ func (h *ItemsHandler) CreateItem(c *gin.Context) {
 //   - table: Name of the table containing the item to update
 //   - id: UUID of the item to update
 //
+// Query Parameters:
+//   - replace: "true" replaces the whole item; collections only
+//
 // Request Body:
-//   - JSON object containing the fields to update
-//   - Only provided fields will be updated (partial updates supported)
+//   - JSON object containing the item's fields
+//   - In collections the body is validated as a complete item, so required fields must be
+//     present. Missing fields with a default are reset to it; other missing fields keep
+//     their value. With ?replace=true every missing field is reset to its default or null.
+//   - Use PATCH to change only some fields
 //   - Fields are automatically filtered based on user permissions
 //
 // Authentication & Authorization:
@@ -479,16 +588,21 @@ func (h *ItemsHandler) CreateItem(c *gin.Context) {
 //   - 401: Missing or invalid authentication token
 //   - 403: User lacks permission to update in this table
 //   - 404: Item not found or not accessible to user
+//   - 409: A unique field already has the given value, or the item changed since the
+//     _version or If-Unmodified-Since the client sent
+//   - 422: Validation errors
 //   - 500: Internal server error during update
 //
 // @Summary      Update item in dynamic table
 // @Tags         items
 // @Security     BearerAuth
 // @Security     ApiKeyAuth
-// @Description  Update an existing item in any dynamic table in the system. This endpoint works with both core schema tables and custom dynamic tables. Only the fields provided in the request body will be updated. Requires authentication via JWT Bearer token or API key.
+// @Description  Update an existing item in any dynamic table in the system. This endpoint works with both core schema tables and custom dynamic tables. In collections the body is validated as a complete item; with replace=true, fields missing from the body are reset to their default or null. Use PATCH to change only some fields. Requires authentication via JWT Bearer token or API key.
 // @Param        table   path      string true  "Table name (e.g., 'users', 'blog_posts', 'customers')"
 // @Param        id      path      string true  "Item ID"
-// @Param        body    body      map[string]interface{} true "Item data to update"
+// @Param        replace query     bool   false "Reset fields missing from the body (collections only)"
+// @Param        If-Unmodified-Since header string false "Refuse the update with 409 if the item changed after this time"
+// @Param        body    body      map[string]interface{} true "Item data; _version (the item's updated_at) refuses the update with 409 if the item changed since"
 // @Accept       json
 // @Produce      json
 // @Success      200 {object} models.UpdateItemResponse
@@ -496,8 +610,48 @@ func (h *ItemsHandler) CreateItem(c *gin.Context) {
 // @Failure      401 {object} map[string]string
 // @Failure      403 {object} map[string]string
 // @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      422 {object} map[string]string
 // @Router       /items/{table}/{id} [put]
 func (h *ItemsHandler) UpdateItem(c *gin.Context) {
+	mode := updateFull
+	if c.Query("replace") == "true" {
+		mode = updateReplace
+	}
+	h.updateItem(c, mode)
+}
+
+// PatchItem handles PATCH /items/:table/:id requests.
+//
+// Only the fields in the request body are validated and written; fields missing from the
+// body are left untouched and an explicit null clears a field. Permissions and responses
+// are the same as for PUT.
+//
+// @Summary      Partially update item in dynamic table
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Change some fields of an existing item. Fields missing from the body are left untouched; null clears a field (required fields cannot be cleared). Requires authentication via JWT Bearer token or API key.
+// @Param        table   path      string true  "Table name (e.g., 'users', 'blog_posts', 'customers')"
+// @Param        id      path      string true  "Item ID"
+// @Param        If-Unmodified-Since header string false "Refuse the update with 409 if the item changed after this time"
+// @Param        body    body      map[string]interface{} true "Fields to change; _version (the item's updated_at) refuses the update with 409 if the item changed since"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} models.UpdateItemResponse
+// @Failure      400 {object} map[string]string
+// @Failure      401 {object} map[string]string
+// @Failure      403 {object} map[string]string
+// @Failure      404 {object} map[string]string
+// @Failure      409 {object} map[string]string
+// @Failure      422 {object} map[string]string
+// @Router       /items/{table}/{id} [patch]
+func (h *ItemsHandler) PatchItem(c *gin.Context) {
+	h.updateItem(c, updateMerge)
+}
+
+// updateItem performs the validation, permission checks and routing shared by PUT and PATCH
+func (h *ItemsHandler) updateItem(c *gin.Context, mode updateMode) {
 	tableName := c.Param("table")
 	itemID := c.Param("id")
 
@@ -513,6 +667,13 @@ func (h *ItemsHandler) UpdateItem(c *gin.Context) {
 		return
 	}
 
+	// The item version the change is based on, if the client sent one
+	precondition, err := parseUpdatePrecondition(c.Request, requestData)
+	if err != nil {
+		respondError(c, err, "Invalid precondition")
+		return
+	}
+
 	// Check permissions and filter data
 	// Get tenant context from the request
 	tenantID, _ := middleware.GetTenantID(c)
@@ -520,7 +681,7 @@ func (h *ItemsHandler) UpdateItem(c *gin.Context) {
 	// Create a context with tenant information
 	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
 
-	hasPermission, allowedFields, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, tableName, "update")
+	hasPermission, allowedFields, rowFilter, err := h.policyChecker.CheckPermissionWithFilter(ctxWithTenant, userID, tableName, "update")
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
 		return
@@ -529,25 +690,40 @@ func (h *ItemsHandler) UpdateItem(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
+	withRowFilter(c, tableName, rowFilter)
 
-	filteredData := h.policyChecker.FilterFields(requestData, allowedFields)
+	filteredData := h.policyChecker.FilterFields(requestData, allowedFields, nil)
 
-	// Route to appropriate handler based on table type
+	// Route to appropriate handler based on table type. Schema tables and plain data
+	// tables only ever change the fields that are given.
 	if h.isSchemaTable(tableName) {
+		if mode == updateReplace {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "replace is only supported for collections"})
+			return
+		}
+		if precondition != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "_version and If-Unmodified-Since are only supported for collections and data tables"})
+			return
+		}
 		h.handleSchemaTableUpdate(c, tableName, userID, itemID, filteredData)
 		return
 	}
+	c.Request = c.Request.WithContext(withUpdatePrecondition(c.Request.Context(), precondition))
 
 	// Check if this is a user-created collection
 	if h.isUserCollection(c.Request.Context(), userID, tableName) {
-		h.handleUserCollectionUpdate(c, tableName, userID, itemID, filteredData)
+		h.handleUserCollectionUpdate(c, tableName, userID, itemID, filteredData, allowedFields, mode)
+		return
+	}
+	if mode == updateReplace {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "replace is only supported for collections"})
 		return
 	}
 
 	// Handle dynamic data tables
 	err = h.dynamicHandlers.UpdateDynamicItem(c.Request.Context(), userID, tableName, itemID, filteredData)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update item: " + err.Error()})
+		respondError(c, err, "Failed to update item")
 		return
 	}
 
@@ -583,14 +759,17 @@ func (h *ItemsHandler) UpdateItem(c *gin.Context) {
 //   - 404: Item not found or not accessible to user
 //   - 500: Internal server error during deletion
 //
-// Note: Deletions may cascade to related data depending on table relationships
+// Note: Deletions may cascade to related data depending on table relationships.
+// In collections with soft delete enabled the item is moved to the trash instead;
+// ?permanent=true removes it for good and requires the "purge" permission.
 // @Summary      Delete item from dynamic table
 // @Tags         items
 // @Security     BearerAuth
 // @Security     ApiKeyAuth
-// @Description  Delete an item from any dynamic table in the system. This endpoint works with both core schema tables and custom dynamic tables. The deletion is permanent and cannot be undone. Requires authentication via JWT Bearer token or API key.
-// @Param        table   path      string true  "Table name (e.g., 'users', 'blog_posts', 'customers')"
-// @Param        id      path      string true  "Item ID"
+// @Description  Delete an item from any dynamic table in the system. This endpoint works with both core schema tables and custom dynamic tables. In collections with soft delete enabled the item is moved to the trash and can be restored; pass permanent=true (requires the purge permission) to delete it for good. Requires authentication via JWT Bearer token or API key.
+// @Param        table     path      string true  "Table name (e.g., 'users', 'blog_posts', 'customers')"
+// @Param        id        path      string true  "Item ID"
+// @Param        permanent query     bool   false "Skip the trash and delete permanently (requires purge permission)"
 // @Produce      json
 // @Success      200 {object} models.DeleteItemResponse
 // @Failure      400 {object} models.ErrorResponse
@@ -626,7 +805,14 @@ func (h *ItemsHandler) DeleteItem(c *gin.Context) {
 	// Create a context with tenant information
 	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
 
-	hasPermission, _, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, tableName, "delete")
+	// Permanent deletes of trashed items are a separate permission
+	permanent := c.Query("permanent") == "true"
+	action := "delete"
+	if permanent {
+		action = "purge"
+	}
+
+	hasPermission, _, rowFilter, err := h.policyChecker.CheckPermissionWithFilter(ctxWithTenant, userID, tableName, action)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
 		return
@@ -636,6 +822,7 @@ func (h *ItemsHandler) DeleteItem(c *gin.Context) {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
 		return
 	}
+	withRowFilter(c, tableName, rowFilter)
 
 	// Route to appropriate handler based on table type
 	if h.isSchemaTable(tableName) {
@@ -645,14 +832,14 @@ func (h *ItemsHandler) DeleteItem(c *gin.Context) {
 
 	// Check if this is a user-created collection
 	if h.isUserCollection(c.Request.Context(), userID, tableName) {
-		h.handleUserCollectionDelete(c, tableName, userID, itemID)
+		h.handleUserCollectionDelete(c, tableName, userID, itemID, permanent)
 		return
 	}
 
 	// Handle dynamic data tables
 	err = h.dynamicHandlers.DeleteDynamicItem(c.Request.Context(), userID, tableName, itemID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete item: " + err.Error()})
+		respondError(c, err, "Failed to delete item")
 		return
 	}
 
@@ -661,6 +848,93 @@ func (h *ItemsHandler) DeleteItem(c *gin.Context) {
 	})
 }
 
+// RestoreItem handles POST /items/:table/:id/restore requests
+//
+// Moves a soft-deleted item out of the trash. Only collections with soft delete
+// enabled keep deleted items; restoring requires update permission.
+//
+// Response Format:
+//   - 200: Item restored
+//   - 400: Invalid table name or item ID, or soft delete is not enabled
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks permission to update this table
+//   - 404: Item not found in the trash
+//
+// @Summary      Restore a soft-deleted item
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Restore an item that was deleted from a collection with soft delete enabled. Requires update permission on the collection.
+// @Param        table   path      string true  "Collection name"
+// @Param        id      path      string true  "Item ID"
+// @Produce      json
+// @Success      200 {object} models.DeleteItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/{table}/{id}/restore [post]
+func (h *ItemsHandler) RestoreItem(c *gin.Context) {
+	tableName := c.Param("table")
+	itemID := c.Param("id")
+
+	// Validate inputs
+	if !rbac.ValidateTableName(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table name"})
+		return
+	}
+
+	if _, err := uuid.Parse(itemID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	// Get user ID and check permissions
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Get tenant context from the request
+	tenantID, _ := middleware.GetTenantID(c)
+
+	// Create a context with tenant information
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, _, rowFilter, err := h.policyChecker.CheckPermissionWithFilter(ctxWithTenant, userID, tableName, "update")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+	withRowFilter(c, tableName, rowFilter)
+
+	// Only user collections can have soft delete enabled
+	if h.isSchemaTable(tableName) || !h.isUserCollection(c.Request.Context(), userID, tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Soft delete is not enabled for this table"})
+		return
+	}
+
+	err = h.collectionsHandler.RestoreCollectionItem(c.Request.Context(), userID, tableName, itemID)
+	if err != nil {
+		if strings.Contains(err.Error(), "soft delete is not enabled") {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Soft delete is not enabled for this collection"})
+			return
+		}
+		respondError(c, err, "Failed to restore item")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"meta": gin.H{"table": tableName, "id": itemID, "type": "collection", "restored": true},
+	})
+}
+
 // Helper methods for request validation and routing
 
 // validateCreateUpdateRequest handles common validation for create and update requests
@@ -680,17 +954,19 @@ func (h *ItemsHandler) validateCreateUpdateRequest(c *gin.Context, tableName, op
 
 	// Parse request body
 	var requestData map[string]interface{}
-	if err := c.ShouldBindJSON(&requestData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	if err := decodeJSON(c, &requestData, BodyItems); err != nil {
+		respondBodyError(c, err, "Invalid request body")
 		return uuid.Nil, nil, fmt.Errorf("invalid request body")
 	}
 
 	return userID, requestData, nil
 }
 
+// schemaTableNames are the schema management tables served under /items
+var schemaTableNames = []string{"collections", "fields", "users", "roles", "permissions", "api_keys", "webhooks", "webhook_deliveries", "flows", "flow_runs", "jobs", "assets", "audit_logs"}
+
 // isSchemaTable checks if a table is a schema management table
 func (h *ItemsHandler) isSchemaTable(tableName string) bool {
-	schemaTableNames := []string{"collections", "fields", "users", "roles", "permissions", "api_keys"}
 	for _, name := range schemaTableNames {
 		if tableName == name {
 			return true
@@ -699,6 +975,85 @@ func (h *ItemsHandler) isSchemaTable(tableName string) bool {
 	return false
 }
 
+// adminOnlyTables can only be read by admins, whatever permissions other roles are granted
+var adminOnlyTables = map[string]bool{"audit_logs": true, "jobs": true}
+
+// secretColumns lists schema table columns that generic reads never return
+var secretColumns = map[string][]string{
+	"webhooks": {"secret"},
+	"flows":    {"secret"},
+	"api_keys": {"key_hash", "previous_key_hash"},
+	"jobs":     {"payload"}, // Queued emails carry password reset and invite links
+}
+
+// redactSecrets removes the secret columns of tableName from row in place
+func redactSecrets(tableName string, row map[string]interface{}) {
+	for _, column := range secretColumns[tableName] {
+		delete(row, column)
+	}
+}
+
+// flagExpiringAPIKey adds expires_soon to an api_keys row that includes its expiry, so
+// clients can warn about keys that need rotating
+func (h *ItemsHandler) flagExpiringAPIKey(tableName string, row map[string]interface{}) {
+	if tableName != "api_keys" {
+		return
+	}
+	if expiresAt, ok := row["expires_at"].(time.Time); ok {
+		row["expires_soon"] = apiKeyExpiresSoon(expiresAt, h.cfg.APIKeyExpiryWarning, time.Now())
+	} else if _, selected := row["expires_at"]; selected {
+		row["expires_soon"] = false
+	}
+}
+
+// withRowFilter makes the caller's row filter for tableName available to the data access
+// code that handles the rest of the request
+func withRowFilter(c *gin.Context, tableName string, rowFilter *rbac.RowFilter) {
+	c.Request = c.Request.WithContext(rbac.WithRowFilter(c.Request.Context(), tableName, rowFilter))
+}
+
+// withFieldTransforms makes the caller's field transforms for tableName available to the
+// code that reads and returns the rest of the request's items
+func withFieldTransforms(c *gin.Context, tableName string, transforms rbac.FieldTransforms) {
+	c.Request = c.Request.WithContext(rbac.WithFieldTransforms(c.Request.Context(), tableName, transforms))
+}
+
+// checkSchemaRowFilter verifies that a schema table row is within the caller's row filter
+// before it is written. Rows outside the filter are reported as missing. It returns false
+// after writing an error response.
+func (h *ItemsHandler) checkSchemaRowFilter(c *gin.Context, tableName, itemID string) bool {
+	ruleCondition, args := rowFilterCondition(c.Request.Context(), tableName, 2)
+	if ruleCondition == "" {
+		return true
+	}
+
+	var exists bool
+	query := fmt.Sprintf("SELECT EXISTS (SELECT 1 FROM %s WHERE id = $1%s)", tableName, ruleCondition)
+	if err := h.db.QueryRowContext(c.Request.Context(), query, append([]interface{}{itemID}, args...)...).Scan(&exists); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false
+	}
+	if !exists {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+		return false
+	}
+	return true
+}
+
+// readingUser returns the user a read is made for: the authenticated user or, for a public
+// request to a collection, rbac.PublicUser, whose lookups resolve to the request's tenant
+func (h *ItemsHandler) readingUser(c *gin.Context, tableName string) (uuid.UUID, bool) {
+	if userID, ok := middleware.GetUserID(c); ok {
+		return userID, true
+	}
+	if !middleware.IsPublicRequest(c) || h.isSchemaTable(tableName) {
+		return uuid.Nil, false
+	}
+	tenantID, _ := middleware.GetTenantID(c)
+	c.Request = c.Request.WithContext(WithTenant(c.Request.Context(), tenantID))
+	return rbac.PublicUser, true
+}
+
 // isUserCollection checks if a table is a user-created collection
 func (h *ItemsHandler) isUserCollection(ctx context.Context, userID uuid.UUID, tableName string) bool {
 	// Get user's tenant
@@ -726,16 +1081,31 @@ func (h *ItemsHandler) handleSchemaTableCreate(c *gin.Context, tableName string,
 		result, err = h.schemaHandlers.CreateUser(c.Request.Context(), userID, data)
 	case "api_keys":
 		result, err = h.schemaHandlers.CreateAPIKey(c.Request.Context(), userID, data)
+	case "webhooks":
+		result, err = h.schemaHandlers.CreateWebhook(c.Request.Context(), userID, data)
+	case "flows":
+		result, err = h.schemaHandlers.CreateFlow(c.Request.Context(), userID, data)
+	case "assets":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assets are uploaded with POST /assets"})
+		return
+	case "audit_logs":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audit logs are read-only"})
+		return
+	case "jobs":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Jobs are queued by the server"})
+		return
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported schema table for creation"})
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create " + tableName + ": " + err.Error()})
+		respondError(c, err, "Failed to create "+tableName)
 		return
 	}
 
+	h.schemaHandlers.publishSchemaEvent(c.Request.Context(), events.ItemCreate, userID, tableName, "", result)
+
 	c.JSON(http.StatusCreated, gin.H{
 		"data": result,
 		"meta": gin.H{"table": tableName},
@@ -744,6 +1114,10 @@ func (h *ItemsHandler) handleSchemaTableCreate(c *gin.Context, tableName string,
 
 // handleSchemaTableUpdate routes update requests for schema management tables
 func (h *ItemsHandler) handleSchemaTableUpdate(c *gin.Context, tableName string, userID uuid.UUID, itemID string, data map[string]interface{}) {
+	if !h.checkSchemaRowFilter(c, tableName, itemID) {
+		return
+	}
+
 	var result map[string]interface{}
 	var err error
 
@@ -756,16 +1130,32 @@ func (h *ItemsHandler) handleSchemaTableUpdate(c *gin.Context, tableName string,
 		result, err = h.schemaHandlers.UpdateUser(c.Request.Context(), userID, itemID, data)
 	case "api_keys":
 		result, err = h.schemaHandlers.UpdateAPIKey(c.Request.Context(), userID, itemID, data)
+	case "webhooks":
+		result, err = h.schemaHandlers.UpdateWebhook(c.Request.Context(), userID, itemID, data)
+	case "flows":
+		result, err = h.schemaHandlers.UpdateFlow(c.Request.Context(), userID, itemID, data)
+	case "assets":
+		result, err = h.schemaHandlers.UpdateAsset(c.Request.Context(), userID, itemID, data)
+	case "jobs":
+		result, err = h.schemaHandlers.RetryJob(c.Request.Context(), userID, itemID, data)
+	case "audit_logs":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audit logs are read-only"})
+		return
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported schema table for updates"})
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update " + tableName + ": " + err.Error()})
+		if respondDestructiveChange(c, err) {
+			return
+		}
+		respondError(c, err, "Failed to update "+tableName)
 		return
 	}
 
+	h.schemaHandlers.publishSchemaEvent(c.Request.Context(), events.ItemUpdate, userID, tableName, itemID, result)
+
 	c.JSON(http.StatusOK, gin.H{
 		"data": result,
 		"meta": gin.H{"table": tableName, "id": itemID},
@@ -774,10 +1164,34 @@ func (h *ItemsHandler) handleSchemaTableUpdate(c *gin.Context, tableName string,
 
 // handleUserCollectionCreate routes create requests for user-created collections
 func (h *ItemsHandler) handleUserCollectionCreate(c *gin.Context, tableName string, userID uuid.UUID, data map[string]interface{}) {
+	// One-to-many fields may carry new related items (see nested_writes.go)
+	tenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to create collection item")
+		return
+	}
+	data, writes, err := h.collectionsHandler.splitNestedWrites(c.Request.Context(), tenantID, tableName, data)
+	if err != nil {
+		respondError(c, err, "Failed to create collection item")
+		return
+	}
+
 	// Create the item using collections handler
-	result, err := h.collectionsHandler.CreateCollectionItem(c.Request.Context(), userID, tableName, data)
+	var result map[string]interface{}
+	if len(writes) > 0 {
+		if !h.authorizeNestedWrites(c, userID, writes) {
+			return
+		}
+		result, err = h.collectionsHandler.CreateNestedCollectionItem(c.Request.Context(), userID, tableName, data, writes)
+	} else {
+		result, err = h.collectionsHandler.CreateCollectionItem(c.Request.Context(), userID, tableName, data)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to create collection item: " + err.Error()})
+		respondError(c, err, "Failed to create collection item")
+		return
+	}
+	if err := h.revealEncrypted(c.Request.Context(), userID, tableName, []map[string]interface{}{result}); err != nil {
+		respondError(c, err, "Failed to create collection item")
 		return
 	}
 
@@ -788,11 +1202,24 @@ func (h *ItemsHandler) handleUserCollectionCreate(c *gin.Context, tableName stri
 }
 
 // handleUserCollectionUpdate routes update requests for user-created collections
-func (h *ItemsHandler) handleUserCollectionUpdate(c *gin.Context, tableName string, userID uuid.UUID, itemID string, data map[string]interface{}) {
+func (h *ItemsHandler) handleUserCollectionUpdate(c *gin.Context, tableName string, userID uuid.UUID, itemID string, data map[string]interface{}, allowedFields []string, mode updateMode) {
 	// Update the item using collections handler
-	result, err := h.collectionsHandler.UpdateCollectionItem(c.Request.Context(), userID, tableName, itemID, data)
+	var result map[string]interface{}
+	var err error
+	switch mode {
+	case updateMerge:
+		result, err = h.collectionsHandler.PatchCollectionItem(c.Request.Context(), userID, tableName, itemID, data)
+	case updateReplace:
+		result, err = h.collectionsHandler.ReplaceCollectionItem(c.Request.Context(), userID, tableName, itemID, data, allowedFields)
+	default:
+		result, err = h.collectionsHandler.UpdateCollectionItem(c.Request.Context(), userID, tableName, itemID, data)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to update collection item: " + err.Error()})
+		respondError(c, err, "Failed to update collection item")
+		return
+	}
+	if err := h.revealEncrypted(c.Request.Context(), userID, tableName, []map[string]interface{}{result}); err != nil {
+		respondError(c, err, "Failed to update collection item")
 		return
 	}
 
@@ -803,11 +1230,16 @@ func (h *ItemsHandler) handleUserCollectionUpdate(c *gin.Context, tableName stri
 }
 
 // handleUserCollectionDelete routes delete requests for user-created collections
-func (h *ItemsHandler) handleUserCollectionDelete(c *gin.Context, tableName string, userID uuid.UUID, itemID string) {
+func (h *ItemsHandler) handleUserCollectionDelete(c *gin.Context, tableName string, userID uuid.UUID, itemID string, permanent bool) {
 	// Delete the item using collections handler
-	err := h.collectionsHandler.DeleteCollectionItem(c.Request.Context(), userID, tableName, itemID)
+	var err error
+	if permanent {
+		err = h.collectionsHandler.PurgeCollectionItem(c.Request.Context(), userID, tableName, itemID)
+	} else {
+		err = h.collectionsHandler.DeleteCollectionItem(c.Request.Context(), userID, tableName, itemID)
+	}
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to delete collection item: " + err.Error()})
+		respondError(c, err, "Failed to delete collection item")
 		return
 	}
 
@@ -819,18 +1251,27 @@ func (h *ItemsHandler) handleUserCollectionDelete(c *gin.Context, tableName stri
 // handleUserCollectionGetItem handles getting a specific item from a user collection
 func (h *ItemsHandler) handleUserCollectionGetItem(c *gin.Context, tableName string, userID uuid.UUID, itemID string, allowedFields []string) {
 	// Get the item using collections handler
-	item, err := h.collectionsHandler.GetCollectionItem(c.Request.Context(), userID, tableName, itemID)
+	includeDeleted := c.Query("include_deleted") == "true"
+	item, err := h.collectionsHandler.GetCollectionItem(c.Request.Context(), userID, tableName, itemID, includeDeleted)
 	if err != nil {
-		if strings.Contains(err.Error(), "item not found") {
-			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
-		} else {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch item"})
-		}
+		respondError(c, err, "Failed to fetch item")
 		return
 	}
 
-	// Apply field filtering
-	filteredItem := h.policyChecker.FilterFields(item, allowedFields)
+	// Encrypted fields are decrypted for callers allowed to, masked for everyone else
+	if err := h.revealEncrypted(c.Request.Context(), userID, tableName, []map[string]interface{}{item}); err != nil {
+		respondError(c, err, "Failed to fetch item")
+		return
+	}
+
+	// Apply field filtering and redaction
+	filteredItem := h.policyChecker.FilterFields(item, allowedFields, rbac.FieldTransformsFromContext(c.Request.Context(), tableName))
+
+	// Expand requested relations into nested objects
+	if !h.expandRelations(c, userID, tableName, []map[string]interface{}{filteredItem}, allowedFields) {
+		return
+	}
+	filteredItem = parseFieldSelection(c.Query("fields")).project(filteredItem)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": filteredItem,
@@ -845,6 +1286,10 @@ func (h *ItemsHandler) handleUserCollectionGetItem(c *gin.Context, tableName str
 
 // handleSchemaTableDelete routes delete requests for schema management tables
 func (h *ItemsHandler) handleSchemaTableDelete(c *gin.Context, tableName string, userID uuid.UUID, itemID string) {
+	if !h.checkSchemaRowFilter(c, tableName, itemID) {
+		return
+	}
+
 	var err error
 
 	switch tableName {
@@ -856,108 +1301,125 @@ func (h *ItemsHandler) handleSchemaTableDelete(c *gin.Context, tableName string,
 		err = h.schemaHandlers.DeleteUser(c.Request.Context(), userID, itemID)
 	case "api_keys":
 		err = h.schemaHandlers.DeleteAPIKey(c.Request.Context(), userID, itemID)
+	case "webhooks":
+		err = h.schemaHandlers.DeleteWebhook(c.Request.Context(), userID, itemID)
+	case "flows":
+		err = h.schemaHandlers.DeleteFlow(c.Request.Context(), userID, itemID)
+	case "assets":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Assets are deleted with DELETE /assets/:id"})
+		return
+	case "jobs":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Completed jobs are deleted by the server once JOB_RETENTION has passed"})
+		return
+	case "audit_logs":
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Audit logs are read-only"})
+		return
 	default:
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Unsupported schema table for deletion"})
 		return
 	}
 
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete " + tableName + ": " + err.Error()})
+		respondError(c, err, "Failed to delete "+tableName)
 		return
 	}
 
+	h.schemaHandlers.publishSchemaEvent(c.Request.Context(), events.ItemDelete, userID, tableName, itemID, nil)
+
 	c.JSON(http.StatusOK, gin.H{
 		"meta": gin.H{"table": tableName, "id": itemID},
 	})
 }
 
+// schemaTableScope returns the condition limiting a schema table to the rows of the tenant
+// the request acts in, with its placeholder numbered paramIndex. API keys have no tenant and
+// are limited to the user's own. Users without a tenant get no condition.
+func (h *ItemsHandler) schemaTableScope(ctx context.Context, tableName string, userID uuid.UUID, paramIndex int) (string, []interface{}, error) {
+	if tableName == "api_keys" {
+		return fmt.Sprintf("user_id = $%d", paramIndex), []interface{}{userID}, nil
+	}
+
+	tenantID, err := h.utils.GetUserTenantID(ctx, userID)
+	if err != nil || tenantID == uuid.Nil {
+		return "", nil, err
+	}
+	return fmt.Sprintf("tenant_id = $%d", paramIndex), []interface{}{tenantID}, nil
+}
+
 // handleSchemaTableQuery handles queries for schema management tables
 func (h *ItemsHandler) handleSchemaTableQuery(c *gin.Context, tableName string, userID uuid.UUID, allowedFields []string) {
-	query := rbac.BuildSelectQuery(tableName, allowedFields)
+	transforms := rbac.FieldTransformsFromContext(c.Request.Context(), tableName)
+	page, err := parsePagination(c, allowedFields, transforms)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	selection := parseFieldSelection(c.Query("fields"))
+	query := rbac.BuildSelectQuery(tableName, page.selectFields(h.selectedColumns(selection, allowedFields, nil)))
 
 	var queryParams []interface{}
 	var whereConditions []string
 	paramIndex := 1
 
-	// Handle tenant filtering for different schema tables
-	if tableName == "api_keys" {
-		// API keys table doesn't have tenant_id, filter by user_id instead
-		whereConditions = append(whereConditions, fmt.Sprintf("user_id = $%d", paramIndex))
-		queryParams = append(queryParams, userID)
-		paramIndex++
-	} else {
-		// Add tenant filtering for multi-tenant support
-		userTenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
-			return
-		}
-
-		if userTenantID != uuid.Nil {
-			whereConditions = append(whereConditions, fmt.Sprintf("tenant_id = $%d", paramIndex))
-			queryParams = append(queryParams, userTenantID)
-			paramIndex++
-		}
+	// Only rows of the tenant the request acts in
+	scope, scopeArgs, err := h.schemaTableScope(c.Request.Context(), tableName, userID, paramIndex)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
 	}
-
-	// Add query parameter filtering (exclude special params)
-	queryValues := c.Request.URL.Query()
-	for key, values := range queryValues {
-		if key == "limit" || key == "offset" || key == "page" || key == "per_page" || key == "sort" || key == "order" {
-			continue
-		}
-		if len(values) > 0 && values[0] != "" {
-			if Contains(allowedFields, key) {
-				whereConditions = append(whereConditions, fmt.Sprintf("%s = $%d", key, paramIndex))
-				queryParams = append(queryParams, values[0])
-				paramIndex++
-			}
-		}
+	if scope != "" {
+		whereConditions = append(whereConditions, scope)
+		queryParams = append(queryParams, scopeArgs...)
+		paramIndex += len(scopeArgs)
 	}
 
-	// Add WHERE clause if we have conditions
-	if len(whereConditions) > 0 {
-		query += " WHERE " + strings.Join(whereConditions, " AND ")
+	// Row-level permission rules scope the table like the tenant does
+	if condition, args := rbac.RowFilterFromContext(c.Request.Context(), tableName).SQL(paramIndex); condition != "" {
+		whereConditions = append(whereConditions, condition)
+		queryParams = append(queryParams, args...)
+		paramIndex += len(args)
 	}
 
-	// Sorting
-	if sortField := c.Query("sort"); sortField != "" && Contains(allowedFields, sortField) {
-		order := strings.ToUpper(c.DefaultQuery("order", "ASC"))
-		if order != "ASC" && order != "DESC" {
-			order = "ASC"
-		}
-		query += fmt.Sprintf(" ORDER BY \"%s\" %s", sortField, order)
-	}
+	baseConditions := append([]string{}, whereConditions...)
+	baseParams := append([]interface{}{}, queryParams...)
 
-	// Pagination
-	limit := 50
-	if v := c.Query("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
-			limit = n
-		}
+	// Field filters from the query parameters
+	filter, err := queryFilter(c, tableName, allowedFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if v := c.Query("per_page"); v != "" { // alias
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
-			limit = n
-		}
+	if condition, args := filter.SQL(paramIndex); condition != "" {
+		whereConditions = append(whereConditions, condition)
+		queryParams = append(queryParams, args...)
+		paramIndex += len(args)
 	}
-	offset := 0
-	if v := c.Query("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
-		}
+
+	// Tenant scoping and row rules are the only conditions that apply to the total count
+	counts, err := h.countItems(c.Request.Context(), page, tableName, baseConditions, baseParams, whereConditions, queryParams)
+	if err != nil {
+		respondError(c, err, "Failed to count items")
+		return
 	}
-	if v := c.Query("page"); v != "" { // 1-based
-		if n, err := strconv.Atoi(v); err == nil && n > 1 {
-			offset = (n - 1) * limit
-		}
+
+	// Continue after the cursor, if any
+	if condition, args := page.keysetCondition(paramIndex); condition != "" {
+		whereConditions = append(whereConditions, condition)
+		queryParams = append(queryParams, args...)
+	}
+
+	// Add WHERE clause if we have conditions
+	if len(whereConditions) > 0 {
+		query += " WHERE " + strings.Join(whereConditions, " AND ")
 	}
 
-	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	// Sorting and pagination
+	query += page.orderAndLimitClause()
 
-	rows, err := h.db.Query(query, queryParams...)
+	rows, err := h.db.Reader().QueryContext(c.Request.Context(), query, queryParams...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch data"})
+		respondError(c, err, "Failed to fetch data")
 		return
 	}
 	defer rows.Close()
@@ -966,18 +1428,25 @@ func (h *ItemsHandler) handleSchemaTableQuery(c *gin.Context, tableName string,
 	results := h.utils.ScanRowsToMaps(rows)
 	filteredResults := make([]map[string]interface{}, len(results))
 	for i, result := range results {
-		filteredResults[i] = h.policyChecker.FilterFields(result, allowedFields)
+		filteredResults[i] = h.policyChecker.FilterFields(result, allowedFields, transforms)
+		redactSecrets(tableName, filteredResults[i])
+		h.flagExpiringAPIKey(tableName, filteredResults[i])
+	}
+	h.addFieldIndexStatus(c.Request.Context(), tableName, userID, filteredResults)
+	for i := range filteredResults {
+		filteredResults[i] = selection.project(filteredResults[i])
+	}
+
+	meta := gin.H{
+		"table": tableName,
+		"count": len(filteredResults),
+		"type":  "schema",
 	}
+	page.writeMeta(meta, results, counts)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": filteredResults,
-		"meta": gin.H{
-			"table":  tableName,
-			"count":  len(filteredResults),
-			"limit":  limit,
-			"offset": offset,
-			"type":   "schema",
-		},
+		"meta": meta,
 	})
 }
 
@@ -997,15 +1466,6 @@ func (h *ItemsHandler) handleUserCollectionQuery(c *gin.Context, tableName strin
 		return
 	}
 
-	// Get tenant schema
-	tenantSchema, err := h.utils.GetTenantSchema(c.Request.Context(), userTenantID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tenant schema"})
-		return
-	}
-
-	dataTableName := tenantSchema + ".data_" + tableName
-
 	// Set user context for RLS
 	_, err = h.db.Exec("SELECT set_user_context($1)", userID)
 	if err != nil {
@@ -1013,14 +1473,15 @@ func (h *ItemsHandler) handleUserCollectionQuery(c *gin.Context, tableName strin
 		return
 	}
 
-	// Check if the data table exists
-	tableExists, err := h.utils.TableExists(dataTableName)
-	if err != nil {
+	// Find the data table, whichever layout it has
+	table, err := h.utils.ResolveDataTable(c.Request.Context(), userTenantID, tableName)
+	if err != nil && !errors.Is(err, ErrDataTableNotFound) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check table existence"})
 		return
 	}
+	dataTableName := table.String()
 
-	if !tableExists {
+	if err != nil {
 		// Table doesn't exist - return empty result
 		c.JSON(http.StatusOK, gin.H{
 			"data": []map[string]interface{}{},
@@ -1035,69 +1496,138 @@ func (h *ItemsHandler) handleUserCollectionQuery(c *gin.Context, tableName strin
 		return
 	}
 
-	// Build query based on allowed fields for data table
-	query := rbac.BuildSelectQueryWithTenant(tenantSchema, tableName, allowedFields)
+	transforms := rbac.FieldTransformsFromContext(c.Request.Context(), tableName)
+	page, err := parsePagination(c, allowedFields, transforms)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Sorting
-	if sortField := c.Query("sort"); sortField != "" && Contains(allowedFields, sortField) {
-		order := strings.ToUpper(c.DefaultQuery("order", "ASC"))
-		if order != "ASC" && order != "DESC" {
-			order = "ASC"
-		}
-		query += fmt.Sprintf(" ORDER BY \"%s\" %s", sortField, order)
+	// Hide the trash unless it was asked for
+	var conditions []string
+	if collection.SoftDelete && c.Query("include_deleted") != "true" {
+		conditions = append(conditions, "deleted_at IS NULL")
 	}
 
-	// Pagination
-	limit := 50
-	if v := c.Query("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
-			limit = n
-		}
+	// Only rows matching the caller's row-level rules
+	ruleCondition, queryParams := rbac.RowFilterFromContext(c.Request.Context(), tableName).SQL(1)
+	if ruleCondition != "" {
+		conditions = append(conditions, ruleCondition)
 	}
-	if v := c.Query("per_page"); v != "" { // alias
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
-			limit = n
+	baseConditions := append([]string{}, conditions...)
+	baseParams := append([]interface{}{}, queryParams...)
+
+	// Only rows whose readable text fields match the search, if any
+	if search := strings.TrimSpace(c.Query("search")); search != "" {
+		if !h.db.Dialect().FullTextSearch() {
+			c.JSON(http.StatusBadRequest, gin.H{"error": errSearchUnsupported.Error()})
+			return
 		}
-	}
-	offset := 0
-	if v := c.Query("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
+		fields, err := h.db.Queries.GetFieldsByCollection(c.Request.Context(), uuid.NullUUID{UUID: collection.ID, Valid: true})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get collection fields"})
+			return
+		}
+		columns := searchColumns(fields, allowedFields, rbac.FieldTransformsFromContext(c.Request.Context(), tableName))
+		if len(columns) == 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Collection has no searchable fields"})
+			return
 		}
+		conditions = append(conditions, searchCondition(columns, len(queryParams)+1))
+		queryParams = append(queryParams, search)
+	}
+
+	// Field filters from the query parameters
+	filter, err := queryFilter(c, tableName, allowedFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if condition, args := filter.SQL(len(queryParams) + 1); condition != "" {
+		conditions = append(conditions, condition)
+		queryParams = append(queryParams, args...)
+	}
+
+	// The trash and row rules apply to the total count, the search and filters only to
+	// filter_count
+	counts, err := h.countItems(c.Request.Context(), page, dataTableName, baseConditions, baseParams, conditions, queryParams)
+	if err != nil {
+		respondError(c, err, "Failed to count items")
+		return
 	}
-	if v := c.Query("page"); v != "" { // 1-based
-		if n, err := strconv.Atoi(v); err == nil && n > 1 {
-			offset = (n - 1) * limit
+
+	// Read only the fields asked for. Relations without a column of their own are filled
+	// in by expandRelations.
+	selection := parseFieldSelection(c.Query("fields"))
+	var aliasFields []string
+	if selection.columns() != nil {
+		fields, err := h.db.Queries.GetFieldsByCollection(c.Request.Context(), uuid.NullUUID{UUID: collection.ID, Valid: true})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get collection fields"})
+			return
+		}
+		for _, field := range fields {
+			if isAliasField(field.Type, field.RelationConfig) {
+				aliasFields = append(aliasFields, field.Name)
+			}
 		}
 	}
+	query := rbac.BuildSelectQuery(dataTableName, page.selectFields(h.selectedColumns(selection, allowedFields, aliasFields)))
 
-	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	// Continue after the cursor, if any
+	condition, cursorParams := page.keysetCondition(len(queryParams) + 1)
+	if condition != "" {
+		conditions = append(conditions, condition)
+		queryParams = append(queryParams, cursorParams...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	// Sorting and pagination
+	query += page.orderAndLimitClause()
 
 	// Execute query
-	rows, err := h.db.Query(query)
+	rows, err := h.db.Reader().QueryContext(c.Request.Context(), query, queryParams...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch data"})
+		respondError(c, err, "Failed to fetch data")
 		return
 	}
 	defer rows.Close()
 
 	// Process results
 	results := h.utils.ScanRowsToMaps(rows)
+
+	// Encrypted fields are decrypted for callers allowed to, masked for everyone else
+	if err := h.revealEncrypted(c.Request.Context(), userID, tableName, results); err != nil {
+		respondError(c, err, "Failed to fetch data")
+		return
+	}
+
 	filteredResults := make([]map[string]interface{}, len(results))
 	for i, result := range results {
-		filteredResults[i] = h.policyChecker.FilterFields(result, allowedFields)
+		filteredResults[i] = h.policyChecker.FilterFields(result, allowedFields, transforms)
+	}
+
+	// Expand requested relations into nested objects
+	if !h.expandRelations(c, userID, tableName, filteredResults, allowedFields) {
+		return
+	}
+	for i := range filteredResults {
+		filteredResults[i] = selection.project(filteredResults[i])
+	}
+
+	meta := gin.H{
+		"table":      tableName,
+		"count":      len(filteredResults),
+		"type":       "collection",
+		"collection": collection.Name,
 	}
+	page.writeMeta(meta, results, counts)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": filteredResults,
-		"meta": gin.H{
-			"table":      tableName,
-			"count":      len(filteredResults),
-			"limit":      limit,
-			"offset":     offset,
-			"type":       "collection",
-			"collection": collection.Name,
-		},
+		"meta": meta,
 	})
 }
 
@@ -1110,14 +1640,6 @@ func (h *ItemsHandler) handleDynamicTableQuery(c *gin.Context, tableName string,
 		return
 	}
 
-	tenantSchema, err := h.utils.GetTenantSchema(c.Request.Context(), userTenantID)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get tenant schema"})
-		return
-	}
-
-	dataTableName := tenantSchema + ".data_" + tableName
-
 	// Set user context for RLS
 	_, err = h.db.Exec("SELECT set_user_context($1)", userID)
 	if err != nil {
@@ -1125,14 +1647,15 @@ func (h *ItemsHandler) handleDynamicTableQuery(c *gin.Context, tableName string,
 		return
 	}
 
-	// Check if the data table exists
-	tableExists, err := h.utils.TableExists(dataTableName)
-	if err != nil {
+	// Find the data table, whichever layout it has
+	table, err := h.utils.ResolveDataTable(c.Request.Context(), userTenantID, tableName)
+	if err != nil && !errors.Is(err, ErrDataTableNotFound) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check table existence"})
 		return
 	}
+	dataTableName := table.String()
 
-	if !tableExists {
+	if err != nil {
 		// Table doesn't exist - return empty result
 		c.JSON(http.StatusOK, gin.H{
 			"data": []map[string]interface{}{},
@@ -1146,48 +1669,61 @@ func (h *ItemsHandler) handleDynamicTableQuery(c *gin.Context, tableName string,
 		return
 	}
 
-	// Build query based on allowed fields for data table
-	query := rbac.BuildSelectQueryWithTenant(tenantSchema, tableName, allowedFields)
+	transforms := rbac.FieldTransformsFromContext(c.Request.Context(), tableName)
+	page, err := parsePagination(c, allowedFields, transforms)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 
-	// Sorting
-	if sortField := c.Query("sort"); sortField != "" && Contains(allowedFields, sortField) {
-		order := strings.ToUpper(c.DefaultQuery("order", "ASC"))
-		if order != "ASC" && order != "DESC" {
-			order = "ASC"
-		}
-		query += fmt.Sprintf(" ORDER BY \"%s\" %s", sortField, order)
+	// Only rows matching the caller's row-level rules
+	var conditions []string
+	ruleCondition, queryParams := rbac.RowFilterFromContext(c.Request.Context(), tableName).SQL(1)
+	if ruleCondition != "" {
+		conditions = append(conditions, ruleCondition)
 	}
+	baseConditions := append([]string{}, conditions...)
+	baseParams := append([]interface{}{}, queryParams...)
 
-	// Pagination
-	limit := 50
-	if v := c.Query("limit"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
-			limit = n
-		}
+	// Field filters from the query parameters
+	filter, err := queryFilter(c, tableName, allowedFields)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
 	}
-	if v := c.Query("per_page"); v != "" { // alias
-		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= 500 {
-			limit = n
-		}
+	if condition, args := filter.SQL(len(queryParams) + 1); condition != "" {
+		conditions = append(conditions, condition)
+		queryParams = append(queryParams, args...)
 	}
-	offset := 0
-	if v := c.Query("offset"); v != "" {
-		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
-			offset = n
-		}
+
+	// Row rules apply to the total count, the filters only to filter_count
+	counts, err := h.countItems(c.Request.Context(), page, dataTableName, baseConditions, baseParams, conditions, queryParams)
+	if err != nil {
+		respondError(c, err, "Failed to count items")
+		return
 	}
-	if v := c.Query("page"); v != "" { // 1-based
-		if n, err := strconv.Atoi(v); err == nil && n > 1 {
-			offset = (n - 1) * limit
-		}
+
+	// Build query based on the allowed fields asked for
+	selection := parseFieldSelection(c.Query("fields"))
+	query := rbac.BuildSelectQuery(dataTableName, page.selectFields(h.selectedColumns(selection, allowedFields, nil)))
+
+	// Continue after the cursor, if any
+	condition, cursorParams := page.keysetCondition(len(queryParams) + 1)
+	if condition != "" {
+		conditions = append(conditions, condition)
+		queryParams = append(queryParams, cursorParams...)
+	}
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
 	}
 
-	query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, offset)
+	// Sorting and pagination
+	query += page.orderAndLimitClause()
 
 	// Execute query
-	rows, err := h.db.Query(query)
+	rows, err := h.db.Reader().QueryContext(c.Request.Context(), query, queryParams...)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch data"})
+		respondError(c, err, "Failed to fetch data")
 		return
 	}
 	defer rows.Close()
@@ -1196,17 +1732,78 @@ func (h *ItemsHandler) handleDynamicTableQuery(c *gin.Context, tableName string,
 	results := h.utils.ScanRowsToMaps(rows)
 	filteredResults := make([]map[string]interface{}, len(results))
 	for i, result := range results {
-		filteredResults[i] = h.policyChecker.FilterFields(result, allowedFields)
+		filteredResults[i] = selection.project(h.policyChecker.FilterFields(result, allowedFields, transforms))
+	}
+
+	meta := gin.H{
+		"table": tableName,
+		"count": len(filteredResults),
+		"type":  "data",
 	}
+	page.writeMeta(meta, results, counts)
 
 	c.JSON(http.StatusOK, gin.H{
 		"data": filteredResults,
-		"meta": gin.H{
-			"table":  tableName,
-			"count":  len(filteredResults),
-			"limit":  limit,
-			"offset": offset,
-			"type":   "data",
-		},
+		"meta": meta,
 	})
 }
+
+// selectedColumns returns the columns to read for the fields query parameter: the fields
+// it names that the caller may read, or allowedFields when it selects every field. The
+// ID is always read, for relations and cursors; aliasFields (one-to-many and many-to-many
+// relations) have no column and are left out. Callers trim the items to the selection
+// with fieldSelection.project.
+func (h *ItemsHandler) selectedColumns(selection *fieldSelection, allowedFields, aliasFields []string) []string {
+	requested := selection.columns()
+	if requested == nil {
+		return allowedFields
+	}
+
+	columns := []string{"id"}
+	for _, field := range h.policyChecker.ProjectFields(allowedFields, requested) {
+		if field != "id" && !Contains(aliasFields, field) {
+			columns = append(columns, field)
+		}
+	}
+	return columns
+}
+
+// expandRelations fills in the related IDs of many-to-many fields and applies the relation
+// part of the fields query parameter, narrowed by the deep parameter, to items in place.
+// It returns false after writing an error response if either fails.
+func (h *ItemsHandler) expandRelations(c *gin.Context, userID uuid.UUID, tableName string, items []map[string]interface{}, allowedFields []string) bool {
+	selection := parseFieldSelection(c.Query("fields"))
+	deep, err := queryObjectParam(c.Request.URL.Query(), "deep")
+	if err == nil {
+		err = selection.applyDeep(deep)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return false
+	}
+
+	userTenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return false
+	}
+
+	if err := h.relationExpander.LoadManyToMany(c.Request.Context(), userTenantID, tableName, items, allowedFields, selection); err != nil {
+		respondError(c, err, "Failed to load relations")
+		return false
+	}
+	if !selection.hasRelations() {
+		return true
+	}
+
+	// Permission checks on related collections use the request's tenant context
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	if err := h.relationExpander.Expand(ctxWithTenant, userID, userTenantID, tableName, items, selection); err != nil {
+		respondError(c, err, "Failed to expand relations")
+		return false
+	}
+
+	return true
+}
@@ -0,0 +1,132 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the API key rotation endpoint.
+//
+// API Key Endpoints:
+// - POST /items/api_keys/:id/rotate - Issue a new secret for a key, keeping its metadata
+//
+// After a rotation the previous secret keeps working for a grace period
+// (API_KEY_ROTATION_GRACE_PERIOD by default) so integrations can be updated without
+// downtime. Keys close to expiry are flagged with expires_soon and announced once with an
+// api_key.expiring event.
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxRotationGracePeriod caps how long a replaced secret may stay valid
+const maxRotationGracePeriod = 30 * 24 * time.Hour
+
+// rotateAPIKeyRequest is the optional body of a rotation request
+type rotateAPIKeyRequest struct {
+	GracePeriod *string `json:"grace_period"` // Go duration such as "1h"; "0s" revokes the old secret at once
+}
+
+// RotateAPIKey handles POST /items/:table/:id/rotate requests for the api_keys table.
+//
+// Generates a new secret for the key and returns it once. Name, scopes, expiry and owner
+// are kept. The previous secret remains valid for the grace period given in the body, or
+// the configured default.
+//
+// Response Format:
+//   - 200: Key rotated; the response carries the new secret in api_key
+//   - 400: Not the api_keys table, invalid key ID or grace period
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks update permission on api_keys or does not own the key
+//   - 404: API key not found
+//
+// @Summary      Rotate an API key
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Issue a new secret for an API key. The old secret keeps working for the grace period (default API_KEY_ROTATION_GRACE_PERIOD, at most 720h).
+// @Param        id    path  string true  "API key ID"
+// @Param        body  body  object false "Optional {\"grace_period\": \"1h\"}"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} models.ItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/api_keys/{id}/rotate [post]
+func (h *ItemsHandler) RotateAPIKey(c *gin.Context) {
+	tableName := c.Param("table")
+	if tableName != "api_keys" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only API keys can be rotated"})
+		return
+	}
+	itemID := c.Param("id")
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	gracePeriod := h.cfg.APIKeyRotationGracePeriod
+	var req rotateAPIKeyRequest
+	if c.Request.ContentLength > 0 {
+		if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+			respondBodyError(c, err, "Invalid request body")
+			return
+		}
+	}
+	if req.GracePeriod != nil {
+		parsed, err := time.ParseDuration(*req.GracePeriod)
+		if err != nil || parsed < 0 || parsed > maxRotationGracePeriod {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("grace_period must be a duration between 0s and %s", maxRotationGracePeriod)})
+			return
+		}
+		gracePeriod = parsed
+	}
+
+	// Get tenant context from the request
+	tenantID, _ := middleware.GetTenantID(c)
+
+	// Create a context with tenant information
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, _, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, tableName, "update")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	result, err := h.schemaHandlers.RotateAPIKey(c.Request.Context(), userID, itemID, gracePeriod)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "invalid API key ID"):
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid API key ID"})
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, gin.H{"error": "API key not found"})
+		case strings.Contains(err.Error(), "unauthorized"):
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		default:
+			respondError(c, err, "Failed to rotate API key")
+		}
+		return
+	}
+
+	// Subscribers see the rotation as an update (the new secret is never broadcast)
+	h.schemaHandlers.publishSchemaEvent(c.Request.Context(), events.ItemUpdate, userID, tableName, itemID, result)
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+		"meta": gin.H{"table": tableName, "id": itemID, "rotated": true, "grace_period": gracePeriod.String()},
+	})
+}
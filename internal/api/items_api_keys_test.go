@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyExpiresSoon(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	warning := 7 * 24 * time.Hour
+
+	assert.True(t, apiKeyExpiresSoon(now.Add(24*time.Hour), warning, now))
+	assert.True(t, apiKeyExpiresSoon(now.Add(warning), warning, now))
+	assert.False(t, apiKeyExpiresSoon(now.Add(30*24*time.Hour), warning, now))
+	assert.False(t, apiKeyExpiresSoon(now.Add(-time.Hour), warning, now), "expired keys are not expiring soon")
+}
+
+func TestItemsHandler_FlagExpiringAPIKey(t *testing.T) {
+	handler := &ItemsHandler{cfg: &config.Config{APIKeyExpiryWarning: 24 * time.Hour}}
+
+	row := map[string]interface{}{"id": "1", "expires_at": time.Now().Add(time.Hour)}
+	handler.flagExpiringAPIKey("api_keys", row)
+	assert.Equal(t, true, row["expires_soon"])
+
+	row = map[string]interface{}{"id": "2", "expires_at": nil}
+	handler.flagExpiringAPIKey("api_keys", row)
+	assert.Equal(t, false, row["expires_soon"])
+
+	// Rows without the expiry (projections, other tables) are left alone
+	row = map[string]interface{}{"id": "3"}
+	handler.flagExpiringAPIKey("api_keys", row)
+	assert.NotContains(t, row, "expires_soon")
+
+	row = map[string]interface{}{"expires_at": time.Now()}
+	handler.flagExpiringAPIKey("webhooks", row)
+	assert.NotContains(t, row, "expires_soon")
+}
+
+func TestItemsHandler_RotateAPIKeyValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &ItemsHandler{cfg: &config.Config{APIKeyRotationGracePeriod: time.Hour}}
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.New())
+		c.Next()
+	})
+	router.POST("/items/:table/:id/rotate", handler.RotateAPIKey)
+
+	keyID := "6e68062f-c4c6-42df-9e01-e2d1081664f4"
+	tests := []struct {
+		name string
+		path string
+		body string
+		want int
+	}{
+		{"Other Table", "/items/webhooks/" + keyID + "/rotate", "", http.StatusBadRequest},
+		{"Negative Grace Period", "/items/api_keys/" + keyID + "/rotate", `{"grace_period": "-1h"}`, http.StatusBadRequest},
+		{"Grace Period Too Long", "/items/api_keys/" + keyID + "/rotate", `{"grace_period": "1000h"}`, http.StatusBadRequest},
+		{"Invalid Grace Period", "/items/api_keys/" + keyID + "/rotate", `{"grace_period": "soon"}`, http.StatusBadRequest},
+		{"Malformed Body", "/items/api_keys/" + keyID + "/rotate", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
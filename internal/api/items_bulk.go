@@ -0,0 +1,309 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the bulk item endpoints, which accept arrays of items and apply them
+// to a collection inside a single database transaction.
+//
+// Bulk Endpoints:
+// - POST   /items/:table/bulk - Create many items at once
+// - PATCH  /items/:table/bulk - Update many items at once (each item carries its "id")
+// - DELETE /items/:table/bulk - Delete many items at once (body is an array of IDs)
+//
+// Bulk requests are all-or-nothing: if any item fails validation or the database rejects
+// any statement, the whole transaction is rolled back and nothing is written.
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxBulkItems caps the number of items accepted by a single bulk request
+const maxBulkItems = 5000
+
+// BulkCreateItems handles POST /items/:table/bulk requests.
+//
+// The request body is a JSON array of item objects. Every item is filtered against the
+// caller's allowed fields and, for user collections, validated against the collection
+// schema before any row is written. All inserts run in one transaction.
+//
+// Response Format:
+//   - 201: Success with the created items and a count
+//   - 400: Invalid table name, malformed body, or empty or oversized batch
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks permission to create in this table
+//   - 409: An item repeats the value of a unique field
+//   - 422: Validation errors, reported with the index of the item
+//   - 500: Internal server error; no items were created
+//
+// @Summary      Bulk create items
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Create many items in a dynamic table in a single transaction. Either every item is created or none are.
+// @Param        table   path      string true  "Table name (e.g., 'products', 'customers')"
+// @Param        body    body      []map[string]interface{} true "Items to create"
+// @Accept       json
+// @Produce      json
+// @Success      201 {object} models.ItemsListResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/{table}/bulk [post]
+func (h *ItemsHandler) BulkCreateItems(c *gin.Context) {
+	tableName := c.Param("table")
+
+	userID, allowedFields, ok := h.authorizeBulkRequest(c, tableName, "create")
+	if !ok {
+		return
+	}
+
+	var items []map[string]interface{}
+	if err := decodeJSON(c, &items, BodyItems); err != nil {
+		respondBodyError(c, err, "Invalid request body: expected an array of items")
+		return
+	}
+	if !validateBulkSize(c, len(items)) {
+		return
+	}
+
+	filteredItems := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		filteredItems[i] = h.policyChecker.FilterFields(item, allowedFields, nil)
+	}
+
+	var (
+		result []map[string]interface{}
+		err    error
+	)
+	if h.isUserCollection(c.Request.Context(), userID, tableName) {
+		result, err = h.collectionsHandler.BulkCreateCollectionItems(c.Request.Context(), userID, tableName, filteredItems)
+	} else {
+		var itemIDs []string
+		itemIDs, err = h.dynamicHandlers.BulkCreateDynamicItems(c.Request.Context(), userID, tableName, filteredItems)
+		result = make([]map[string]interface{}, len(itemIDs))
+		for i, itemID := range itemIDs {
+			result[i] = withID(filteredItems[i], itemID)
+		}
+	}
+	if err != nil {
+		respondError(c, err, "Failed to create items")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": result,
+		"meta": gin.H{"table": tableName, "count": len(result)},
+	})
+}
+
+// BulkUpdateItems handles PATCH /items/:table/bulk requests.
+//
+// The request body is a JSON array of item objects, each of which must include the "id"
+// of the item to update. Only the provided fields are changed. All updates run in one
+// transaction and a missing item rolls back the whole batch.
+//
+// Response Format:
+//   - 200: Success with the applied changes and a count
+//   - 400: Invalid table name, malformed body, or missing/invalid IDs
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks permission to update in this table
+//   - 404: An item does not exist
+//   - 422: Validation errors, reported with the index of the item
+//
+// @Summary      Bulk update items
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Update many items in a dynamic table in a single transaction. Each item must include its "id". Either every update is applied or none are.
+// @Param        table   path      string true  "Table name (e.g., 'products', 'customers')"
+// @Param        body    body      []map[string]interface{} true "Items to update, each with an id"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} models.ItemsListResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/{table}/bulk [patch]
+func (h *ItemsHandler) BulkUpdateItems(c *gin.Context) {
+	tableName := c.Param("table")
+
+	userID, allowedFields, ok := h.authorizeBulkRequest(c, tableName, "update")
+	if !ok {
+		return
+	}
+
+	var items []map[string]interface{}
+	if err := decodeJSON(c, &items, BodyItems); err != nil {
+		respondBodyError(c, err, "Invalid request body: expected an array of items")
+		return
+	}
+	if !validateBulkSize(c, len(items)) {
+		return
+	}
+
+	itemIDs := make([]string, len(items))
+	filteredItems := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		itemID := GetStringFromMap(item, "id")
+		if _, err := uuid.Parse(itemID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid or missing item ID at index %d", i)})
+			return
+		}
+		itemIDs[i] = itemID
+
+		filtered := h.policyChecker.FilterFields(item, allowedFields, nil)
+		delete(filtered, "id")
+		filteredItems[i] = filtered
+	}
+
+	var err error
+	if h.isUserCollection(c.Request.Context(), userID, tableName) {
+		filteredItems, err = h.collectionsHandler.BulkUpdateCollectionItems(c.Request.Context(), userID, tableName, itemIDs, filteredItems)
+	} else {
+		err = h.dynamicHandlers.BulkUpdateDynamicItems(c.Request.Context(), userID, tableName, itemIDs, filteredItems)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to update items")
+		return
+	}
+
+	result := make([]map[string]interface{}, len(filteredItems))
+	for i, item := range filteredItems {
+		result[i] = map[string]interface{}{"id": itemIDs[i]}
+		for key, value := range item {
+			result[i][key] = value
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+		"meta": gin.H{"table": tableName, "count": len(result)},
+	})
+}
+
+// BulkDeleteItems handles DELETE /items/:table/bulk requests.
+//
+// The request body is a JSON array of item IDs. All deletes run in one transaction and a
+// missing item rolls back the whole batch.
+//
+// Response Format:
+//   - 200: Success with the deleted IDs and a count
+//   - 400: Invalid table name, malformed body, or invalid IDs
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks permission to delete from this table
+//
+// @Summary      Bulk delete items
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Delete many items from a dynamic table in a single transaction. Either every item is deleted or none are.
+// @Param        table   path      string true  "Table name (e.g., 'products', 'customers')"
+// @Param        body    body      []string true "IDs of the items to delete"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} models.DeleteItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/{table}/bulk [delete]
+func (h *ItemsHandler) BulkDeleteItems(c *gin.Context) {
+	tableName := c.Param("table")
+
+	userID, _, ok := h.authorizeBulkRequest(c, tableName, "delete")
+	if !ok {
+		return
+	}
+
+	var itemIDs []string
+	if err := decodeJSON(c, &itemIDs, BodyItems); err != nil {
+		respondBodyError(c, err, "Invalid request body: expected an array of item IDs")
+		return
+	}
+	if !validateBulkSize(c, len(itemIDs)) {
+		return
+	}
+
+	for i, itemID := range itemIDs {
+		if _, err := uuid.Parse(itemID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid item ID at index %d", i)})
+			return
+		}
+	}
+
+	var err error
+	if h.isUserCollection(c.Request.Context(), userID, tableName) {
+		err = h.collectionsHandler.BulkDeleteCollectionItems(c.Request.Context(), userID, tableName, itemIDs)
+	} else {
+		err = h.dynamicHandlers.BulkDeleteDynamicItems(c.Request.Context(), userID, tableName, itemIDs)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to delete items")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"meta": gin.H{"table": tableName, "ids": itemIDs, "count": len(itemIDs)},
+	})
+}
+
+// authorizeBulkRequest performs the validation, authentication and permission checks shared
+// by the bulk endpoints. On failure the error response has already been written.
+func (h *ItemsHandler) authorizeBulkRequest(c *gin.Context, tableName, action string) (uuid.UUID, []string, bool) {
+	// Validate table name
+	if !rbac.ValidateTableName(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table name"})
+		return uuid.Nil, nil, false
+	}
+
+	// Schema tables carry per-row business logic and are not supported in bulk
+	if h.isSchemaTable(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bulk operations are not supported for schema tables"})
+		return uuid.Nil, nil, false
+	}
+
+	// Get user ID from context
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return uuid.Nil, nil, false
+	}
+
+	// Get tenant context from the request
+	tenantID, _ := middleware.GetTenantID(c)
+
+	// Create a context with tenant information
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, allowedFields, rowFilter, transforms, err := h.policyChecker.CheckPermissionWithTransforms(ctxWithTenant, userID, tableName, action)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return uuid.Nil, nil, false
+	}
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return uuid.Nil, nil, false
+	}
+	withRowFilter(c, tableName, rowFilter)
+	withFieldTransforms(c, tableName, transforms)
+
+	return userID, allowedFields, true
+}
+
+// validateBulkSize rejects empty batches and batches larger than maxBulkItems
+func validateBulkSize(c *gin.Context, size int) bool {
+	if size == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Bulk request must contain at least one item"})
+		return false
+	}
+	if size > maxBulkItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Bulk request exceeds the maximum of %d items", maxBulkItems)})
+		return false
+	}
+	return true
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemsHandler_BulkRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &ItemsHandler{}
+	router := gin.New()
+	router.POST("/items/:table/bulk", handler.BulkCreateItems)
+	router.DELETE("/items/:table/bulk", handler.BulkDeleteItems)
+
+	t.Run("Schema Tables Rejected", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/items/collections/bulk", bytes.NewBufferString(`[{"name":"a"}]`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "schema tables")
+	})
+
+	t.Run("Invalid Table Name", func(t *testing.T) {
+		req := httptest.NewRequest("DELETE", "/items/bad-name!/bulk", bytes.NewBufferString(`[]`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Unauthenticated", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/items/products/bulk", bytes.NewBufferString(`[{"name":"a"}]`))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+}
+
+func TestValidateBulkSize(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name string
+		size int
+		ok   bool
+	}{
+		{"empty", 0, false},
+		{"single", 1, true},
+		{"at limit", maxBulkItems, true},
+		{"over limit", maxBulkItems + 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+
+			assert.Equal(t, tt.ok, validateBulkSize(c, tt.size))
+			if !tt.ok {
+				assert.Equal(t, http.StatusBadRequest, w.Code)
+			}
+		})
+	}
+}
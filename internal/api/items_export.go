@@ -0,0 +1,612 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the item export endpoints, which download every item of a collection
+// or data table as CSV, JSON Lines or XLSX.
+//
+// Export Endpoints:
+// - GET /items/:table/export                  - Export the items (?format=csv|jsonl|xlsx)
+// - GET /items/:table/export/:id              - Progress of an export running in the background
+// - GET /items/:table/export/:id/download     - Download the file of a finished background export
+//
+// An export holds every row a GET /items/:table request would page through: the caller's
+// row-level rules, ?search= and ?include_deleted= apply, rows are ordered by ?sort= and
+// ?order=, and only the fields the caller may read are written. Exports of up to
+// exportSyncMaxRows rows are streamed in the response. Larger exports are written to
+// asset storage by a background job and kept for JOB_RETENTION.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/jobs"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+	"go-rbac-api/internal/spreadsheet"
+	"go-rbac-api/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	exportJobKind       = "items.export"
+	exportExpireJobKind = "items.export.expire"
+	exportSyncMaxRows   = 10000 // Larger exports run in the background
+	exportProgressEvery = 5000  // Rows written between progress updates of a background export
+	formatJSONLines     = "jsonl"
+)
+
+// exportContentTypes maps each export format to its content type
+var exportContentTypes = map[string]string{
+	spreadsheet.FormatCSV:  "text/csv; charset=utf-8",
+	formatJSONLines:        "application/x-ndjson",
+	spreadsheet.FormatXLSX: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+}
+
+// errExportInvalid is wrapped by errors caused by the export request itself
+var errExportInvalid = errors.New("invalid export")
+
+// exportOptions are the query options of an export, kept on background export jobs
+type exportOptions struct {
+	Format         string `json:"format"`
+	Search         string `json:"search,omitempty"`
+	IncludeDeleted bool   `json:"include_deleted,omitempty"`
+	Sort           string `json:"sort"`  // Sort expression, as in ?sort=
+	Order          string `json:"order"` // Direction of the ID tie-breaker
+}
+
+// exportProgress is how far a background export has got
+type exportProgress struct {
+	Total    int64 `json:"total"`
+	Exported int64 `json:"exported"`
+}
+
+// exportJobPayload is the payload of an export job
+type exportJobPayload struct {
+	UserID  uuid.UUID     `json:"user_id"`
+	Table   string        `json:"table"`
+	Options exportOptions `json:"options"`
+}
+
+// exportExpirePayload is the payload of the job that deletes an export file
+type exportExpirePayload struct {
+	Key string `json:"key"`
+}
+
+// exportSource is the unpaginated query of an export
+type exportSource struct {
+	table      string // Data table; empty when it does not exist yet
+	conditions []string
+	args       []interface{}
+	transforms rbac.FieldTransforms // Redactions of the caller's read permission
+	encrypted  []string             // Encrypted fields, revealed to callers who may decrypt them
+	decrypt    bool
+}
+
+// ExportsHandler exports the items of collections and data tables
+type ExportsHandler struct {
+	items   *ItemsHandler
+	cfg     *config.Config
+	queue   *jobs.Queue
+	storage storage.Storage
+}
+
+// NewExportsHandler creates an ExportsHandler that reads through items, runs large exports
+// on queue and keeps their files in store
+func NewExportsHandler(items *ItemsHandler, cfg *config.Config, queue *jobs.Queue, store storage.Storage) *ExportsHandler {
+	h := &ExportsHandler{items: items, cfg: cfg, queue: queue, storage: store}
+	queue.Register(exportJobKind, h.runExportJob)
+	queue.Register(exportExpireJobKind, h.runExpireJob)
+	return h
+}
+
+// ExportItems handles GET /items/:table/export requests.
+//
+// Response Format:
+//   - 200: The export file, as an attachment named <table>.<format>
+//   - 202: Large export, queued; meta.progress_url reports its progress
+//   - 400: Invalid table name, format or search
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks permission to read this table
+//
+// @Summary      Export items
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Export every item of a collection or data table the caller may read, limited to the fields they may read. CSV and XLSX files start with a header row; JSON Lines files hold one object per line. Exports of more than 10000 rows are written in the background and downloaded from /items/{table}/export/{id}/download once finished.
+// @Param        table   path      string true  "Table name (e.g., 'products', 'customers')"
+// @Param        format  query     string false "csv (default), jsonl or xlsx"
+// @Param        search  query     string false "Full-text search over the collection's text fields"
+// @Param        sort    query     string false "Comma-separated fields to sort by, - prefix for descending (default 'id')"
+// @Param        order   query     string false "asc (default) or desc"
+// @Param        include_deleted query bool false "Include soft-deleted items"
+// @Produce      text/csv
+// @Produce      json
+// @Success      200 {file} file
+// @Success      202 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/{table}/export [get]
+func (h *ExportsHandler) ExportItems(c *gin.Context) {
+	tableName := c.Param("table")
+
+	// Schema tables are read through their own handlers, which exports do not go through
+	if h.items.isSchemaTable(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Exports are not supported for schema tables"})
+		return
+	}
+
+	userID, allowedFields, ok := h.items.authorizeBulkRequest(c, tableName, "read")
+	if !ok {
+		return
+	}
+
+	format := strings.ToLower(c.DefaultQuery("format", spreadsheet.FormatCSV))
+	if _, ok := exportContentTypes[format]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be csv, jsonl or xlsx"})
+		return
+	}
+
+	page, err := parsePagination(c, allowedFields, rbac.FieldTransformsFromContext(c.Request.Context(), tableName))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	opts := exportOptions{
+		Format:         format,
+		Search:         strings.TrimSpace(c.Query("search")),
+		IncludeDeleted: c.Query("include_deleted") == "true",
+		Sort:           page.sortExpression(),
+		Order:          page.Order,
+	}
+
+	ctx := c.Request.Context()
+	source, err := h.exportSource(ctx, userID, tableName, allowedFields, opts)
+	if err != nil {
+		if errors.Is(err, errExportInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export items"})
+		return
+	}
+
+	var total int64
+	if source.table != "" {
+		total, err = h.items.countRows(ctx, source.table, source.conditions, source.args)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to count items"})
+			return
+		}
+	}
+	if total > exportSyncMaxRows {
+		h.queueExport(c, userID, tableName, opts, total)
+		return
+	}
+
+	c.Header("Content-Disposition", exportDisposition(tableName, format))
+	c.Header("Content-Type", exportContentTypes[format])
+	c.Header("X-Content-Type-Options", "nosniff")
+	c.Status(http.StatusOK)
+
+	// The status is sent with the first row, so a failure from here on can only be logged
+	if _, err := h.writeExport(ctx, c.Writer, source, allowedFields, opts, nil); err != nil {
+		middleware.GetLogger(c).Error("export failed", "table", tableName, "error", err)
+	}
+}
+
+// queueExport hands a large export to the job queue and responds with its progress URL
+func (h *ExportsHandler) queueExport(c *gin.Context, userID uuid.UUID, tableName string, opts exportOptions, total int64) {
+	tenantID, err := h.items.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+
+	job, err := h.queue.Enqueue(c.Request.Context(), jobs.NewJob{
+		Kind:     exportJobKind,
+		TenantID: tenantID,
+		Payload:  exportJobPayload{UserID: userID, Table: tableName, Options: opts},
+	})
+	if err != nil {
+		respondError(c, err, "Failed to queue export")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"data": gin.H{"id": job.ID, "status": job.Status, "progress": exportProgress{Total: total}},
+		"meta": gin.H{"table": tableName, "progress_url": fmt.Sprintf("/items/%s/export/%s", tableName, job.ID)},
+	})
+}
+
+// GetExport handles GET /items/:table/export/:id requests.
+//
+// @Summary      Get export progress
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Report the progress of a background export started by the caller: its status (pending, processing, completed or failed), the rows exported so far and, once completed, the URL to download the file from.
+// @Param        table   path      string true  "Table name"
+// @Param        id      path      string true  "Export ID (UUID)"
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/{table}/export/{id} [get]
+func (h *ExportsHandler) GetExport(c *gin.Context) {
+	tableName := c.Param("table")
+
+	userID, _, ok := h.items.authorizeBulkRequest(c, tableName, "read")
+	if !ok {
+		return
+	}
+
+	job, ok := h.items.getUserJob(c, exportJobKind, "Export", userID, tableName)
+	if !ok {
+		return
+	}
+
+	result := userJobToMap(job)
+	result["download_url"] = nil
+	if job.Status == jobs.StatusCompleted {
+		result["download_url"] = fmt.Sprintf("/items/%s/export/%s/download", tableName, job.ID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": result,
+		"meta": gin.H{"table": tableName},
+	})
+}
+
+// DownloadExport handles GET /items/:table/export/:id/download requests.
+//
+// @Summary      Download an export
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Download the file of a completed background export started by the caller.
+// @Param        table   path      string true  "Table name"
+// @Param        id      path      string true  "Export ID (UUID)"
+// @Produce      text/csv
+// @Success      200 {file} file
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Router       /items/{table}/export/{id}/download [get]
+func (h *ExportsHandler) DownloadExport(c *gin.Context) {
+	tableName := c.Param("table")
+
+	userID, _, ok := h.items.authorizeBulkRequest(c, tableName, "read")
+	if !ok {
+		return
+	}
+
+	job, ok := h.items.getUserJob(c, exportJobKind, "Export", userID, tableName)
+	if !ok {
+		return
+	}
+	if job.Status != jobs.StatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Export has not completed"})
+		return
+	}
+
+	var payload exportJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid export"})
+		return
+	}
+
+	key := exportStorageKey(job.TenantID.UUID, job.ID, payload.Options.Format)
+	reader, err := h.storage.Get(c.Request.Context(), key)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Export file has expired"})
+			return
+		}
+		middleware.GetLogger(c).Error("failed to read export file", "job_id", job.ID, "key", key, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, -1, exportContentTypes[payload.Options.Format], reader, map[string]string{
+		"Content-Disposition":    exportDisposition(tableName, payload.Options.Format),
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// runExportJob writes a queued export to storage as the user who requested it, with that
+// user's current permissions
+func (h *ExportsHandler) runExportJob(ctx context.Context, job sqlc.Job) error {
+	var payload exportJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid export payload: %w", err)
+	}
+	if !job.TenantID.Valid {
+		return fmt.Errorf("export has no tenant")
+	}
+	opts := payload.Options
+	if _, ok := exportContentTypes[opts.Format]; !ok {
+		return fmt.Errorf("unsupported export format '%s'", opts.Format)
+	}
+
+	ctx = WithTenant(ctx, job.TenantID.UUID)
+	ctxWithTenant := context.WithValue(ctx, "tenant_id", job.TenantID.UUID)
+
+	hasPermission, allowedFields, rowFilter, transforms, err := h.items.policyChecker.CheckPermissionWithTransforms(ctxWithTenant, payload.UserID, payload.Table, "read")
+	if err != nil {
+		return fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if !hasPermission {
+		return fmt.Errorf("the user who started the export may no longer read %s", payload.Table)
+	}
+	ctx = rbac.WithRowFilter(ctx, payload.Table, rowFilter)
+	ctx = rbac.WithFieldTransforms(ctx, payload.Table, transforms)
+
+	source, err := h.exportSource(ctx, payload.UserID, payload.Table, allowedFields, opts)
+	if err != nil {
+		return err
+	}
+
+	progress := exportProgress{}
+	if source.table != "" {
+		if progress.Total, err = h.items.countRows(ctx, source.table, source.conditions, source.args); err != nil {
+			return err
+		}
+	}
+	report := func(exported int64) {
+		progress.Exported = exported
+		if err := h.queue.SetProgress(ctx, job.ID, progress); err != nil {
+			slog.Warn("failed to record export progress", "tenant_id", job.TenantID.UUID, "job_id", job.ID, "error", err)
+		}
+	}
+	report(0)
+
+	// Storage needs the size up front, so the file is written to disk first
+	file, err := os.CreateTemp("", "basin-export-*")
+	if err != nil {
+		return fmt.Errorf("failed to create export file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	exported, err := h.writeExport(ctx, file, source, allowedFields, opts, report)
+	if err != nil {
+		return err
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to read export file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read export file: %w", err)
+	}
+
+	key := exportStorageKey(job.TenantID.UUID, job.ID, opts.Format)
+	if err := h.storage.Put(ctx, key, file, size, exportContentTypes[opts.Format]); err != nil {
+		return fmt.Errorf("failed to store export file: %w", err)
+	}
+	report(exported)
+
+	// The file is removed once the job itself is due for cleanup
+	if h.cfg.JobRetention > 0 {
+		_, err := h.queue.Enqueue(ctx, jobs.NewJob{
+			Kind:     exportExpireJobKind,
+			TenantID: job.TenantID.UUID,
+			Payload:  exportExpirePayload{Key: key},
+			RunAt:    time.Now().Add(h.cfg.JobRetention),
+		})
+		if err != nil {
+			slog.Error("failed to schedule export file removal", "tenant_id", job.TenantID.UUID, "job_id", job.ID, "key", key, "error", err)
+		}
+	}
+	return nil
+}
+
+// runExpireJob deletes the file of a background export
+func (h *ExportsHandler) runExpireJob(ctx context.Context, job sqlc.Job) error {
+	var payload exportExpirePayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid export expiry payload: %w", err)
+	}
+	return h.storage.Delete(ctx, payload.Key)
+}
+
+// exportSource builds the unpaginated query of an export: the conditions a list request
+// with the same options would use. The row filter and field transforms must already be in
+// ctx.
+func (h *ExportsHandler) exportSource(ctx context.Context, userID uuid.UUID, tableName string, allowedFields []string, opts exportOptions) (exportSource, error) {
+	tenantID, err := h.items.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return exportSource{}, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	var collection *Collection
+	if h.items.isUserCollection(ctx, userID, tableName) {
+		if collection, err = h.items.collectionsHandler.GetCollection(ctx, tenantID, tableName); err != nil {
+			return exportSource{}, fmt.Errorf("failed to get collection: %w", err)
+		}
+	}
+	if opts.Search != "" && collection == nil {
+		return exportSource{}, fmt.Errorf("%w: search is only supported on collections", errExportInvalid)
+	}
+
+	table, err := h.items.utils.ResolveDataTable(ctx, tenantID, tableName)
+	if errors.Is(err, ErrDataTableNotFound) {
+		return exportSource{}, nil // Nothing has been stored yet
+	}
+	if err != nil {
+		return exportSource{}, fmt.Errorf("failed to check table existence: %w", err)
+	}
+	source := exportSource{table: table.String(), transforms: rbac.FieldTransformsFromContext(ctx, tableName)}
+	if collection != nil {
+		source.encrypted, source.decrypt, err = encryptionAccess(ctx, h.items.dynamicHandlers, h.items.policyChecker, userID, tenantID, tableName)
+		if err != nil {
+			return exportSource{}, err
+		}
+	}
+
+	// Hide the trash unless it was asked for
+	if collection != nil && collection.SoftDelete && !opts.IncludeDeleted {
+		source.conditions = append(source.conditions, "deleted_at IS NULL")
+	}
+
+	// Only rows matching the caller's row-level rules
+	ruleCondition, ruleParams := rbac.RowFilterFromContext(ctx, tableName).SQL(1)
+	if ruleCondition != "" {
+		source.conditions = append(source.conditions, ruleCondition)
+		source.args = append(source.args, ruleParams...)
+	}
+
+	// Only rows whose readable text fields match the search, if any
+	if opts.Search != "" {
+		if !h.items.db.Dialect().FullTextSearch() {
+			return exportSource{}, fmt.Errorf("%w: %v", errExportInvalid, errSearchUnsupported)
+		}
+		fields, err := h.items.db.Queries.GetFieldsByCollection(ctx, uuid.NullUUID{UUID: collection.ID, Valid: true})
+		if err != nil {
+			return exportSource{}, fmt.Errorf("failed to get collection fields: %w", err)
+		}
+		columns := searchColumns(fields, allowedFields, source.transforms)
+		if len(columns) == 0 {
+			return exportSource{}, fmt.Errorf("%w: collection has no searchable fields", errExportInvalid)
+		}
+		source.conditions = append(source.conditions, searchCondition(columns, len(source.args)+1))
+		source.args = append(source.args, opts.Search)
+	}
+
+	return source, nil
+}
+
+// writeExport writes every row of source to w in the export's format, calling report (if
+// set) every exportProgressEvery rows. It returns the number of rows written.
+func (h *ExportsHandler) writeExport(ctx context.Context, w io.Writer, source exportSource, allowedFields []string, opts exportOptions, report func(int64)) (int64, error) {
+	if source.table == "" {
+		// CSV and XLSX exports still get a valid, empty file
+		if opts.Format == formatJSONLines {
+			return 0, nil
+		}
+		writer, err := spreadsheet.NewWriter(opts.Format, w)
+		if err != nil {
+			return 0, err
+		}
+		return 0, writer.Close()
+	}
+
+	query := rbac.BuildSelectQuery(source.table, allowedFields)
+	if len(source.conditions) > 0 {
+		query += " WHERE " + strings.Join(source.conditions, " AND ")
+	}
+	// Sort fields that are no longer readable are dropped; those now redacted are refused
+	sort, order, err := parseSort(opts.Sort, opts.Order, allowedFields, source.transforms)
+	if err != nil {
+		return 0, err
+	}
+	query += (&pagination{Sort: sort, Order: order}).orderClause()
+
+	rows, err := h.items.db.QueryContext(ctx, query, source.args...)
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch data: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch data: %w", err)
+	}
+	header := exportColumns(columns)
+
+	write, closeWriter, err := exportRowWriter(opts.Format, w, header)
+	if err != nil {
+		return 0, err
+	}
+
+	var count int64
+	for rows.Next() {
+		row, err := scanRowToMap(rows, columns)
+		if err != nil {
+			return count, fmt.Errorf("failed to read row: %w", err)
+		}
+		if err := revealValues(source.encrypted, []map[string]interface{}{row}, source.decrypt); err != nil {
+			return count, fmt.Errorf("failed to read row: %w", err)
+		}
+		if err := write(h.items.policyChecker.FilterFields(row, allowedFields, source.transforms)); err != nil {
+			return count, fmt.Errorf("failed to write row: %w", err)
+		}
+
+		count++
+		if report != nil && count%exportProgressEvery == 0 {
+			report(count)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return count, fmt.Errorf("failed to fetch data: %w", err)
+	}
+	return count, closeWriter()
+}
+
+// exportRowWriter returns functions that write one row in the given format and finish
+// the file. CSV and XLSX files start with a header row of columns.
+func exportRowWriter(format string, w io.Writer, columns []string) (func(map[string]interface{}) error, func() error, error) {
+	if format == formatJSONLines {
+		encoder := json.NewEncoder(w)
+		return func(row map[string]interface{}) error { return encoder.Encode(row) }, func() error { return nil }, nil
+	}
+
+	writer, err := spreadsheet.NewWriter(format, w)
+	if err != nil {
+		return nil, nil, err
+	}
+	header := make([]interface{}, len(columns))
+	for i, column := range columns {
+		header[i] = column
+	}
+	if err := writer.Write(header); err != nil {
+		return nil, nil, err
+	}
+
+	values := make([]interface{}, len(columns))
+	write := func(row map[string]interface{}) error {
+		for i, column := range columns {
+			values[i] = row[column]
+		}
+		return writer.Write(values)
+	}
+	return write, writer.Close, nil
+}
+
+// exportColumns returns the columns of an export query that are written to the file,
+// leaving out the archived columns of deleted fields
+func exportColumns(columns []string) []string {
+	var result []string
+	for _, column := range columns {
+		if !strings.HasPrefix(column, archivedColumnPrefix) {
+			result = append(result, column)
+		}
+	}
+	return result
+}
+
+// exportStorageKey is where the file of a background export is stored
+func exportStorageKey(tenantID, jobID uuid.UUID, format string) string {
+	return fmt.Sprintf("%s/exports/%s.%s", tenantID, jobID, format)
+}
+
+// exportDisposition is the Content-Disposition header of an export file
+func exportDisposition(tableName, format string) string {
+	return mime.FormatMediaType("attachment", map[string]string{"filename": tableName + "." + format})
+}
@@ -0,0 +1,62 @@
+package api
+
+import (
+	"bytes"
+	"testing"
+
+	"go-rbac-api/internal/spreadsheet"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExportRowWriter(t *testing.T) {
+	rows := []map[string]interface{}{
+		{"id": "1", "name": "Desk, oak", "price": 120.5},
+		{"id": "2", "name": "Lamp", "price": nil},
+	}
+	columns := exportColumns([]string{"id", "name", "_deleted_sku", "price"})
+	assert.Equal(t, []string{"id", "name", "price"}, columns)
+
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{spreadsheet.FormatCSV, "id,name,price\n1,\"Desk, oak\",120.5\n2,Lamp,\n"},
+		{formatJSONLines, "{\"id\":\"1\",\"name\":\"Desk, oak\",\"price\":120.5}\n{\"id\":\"2\",\"name\":\"Lamp\",\"price\":null}\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			var buf bytes.Buffer
+			write, finish, err := exportRowWriter(tt.format, &buf, columns)
+			require.NoError(t, err)
+			for _, row := range rows {
+				require.NoError(t, write(row))
+			}
+			require.NoError(t, finish())
+			assert.Equal(t, tt.want, buf.String())
+		})
+	}
+}
+
+func TestExportRowWriter_XLSX(t *testing.T) {
+	var buf bytes.Buffer
+	write, finish, err := exportRowWriter(spreadsheet.FormatXLSX, &buf, []string{"name", "price"})
+	require.NoError(t, err)
+	require.NoError(t, write(map[string]interface{}{"name": "Desk", "price": int64(120)}))
+	require.NoError(t, finish())
+
+	rows, err := spreadsheet.Read(spreadsheet.FormatXLSX, buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"name", "price"}, {"Desk", "120"}}, rows)
+}
+
+func TestExportStorageKey(t *testing.T) {
+	tenantID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+	jobID := uuid.MustParse("9b2f6a1e-8d4c-4f7a-b3e2-1c5d7e9f0a12")
+
+	assert.Equal(t, "6e68062f-c4c6-42df-9e01-e2d1081664f4/exports/9b2f6a1e-8d4c-4f7a-b3e2-1c5d7e9f0a12.xlsx", exportStorageKey(tenantID, jobID, "xlsx"))
+	assert.Equal(t, `attachment; filename=products.csv`, exportDisposition("products", "csv"))
+}
@@ -0,0 +1,281 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the filtering of item lists by query parameters.
+//
+// Any query parameter naming a readable field filters the list by it:
+//
+//	GET /items/products?status=published&price[gte]=10&tags[in]=new,sale
+//
+// A bare field compares for equality; a bracketed suffix picks another operator: eq, neq,
+// gt, gte, lt, lte, in and nin (comma-separated lists), null and nnull (true or false),
+// for array fields contains and overlaps (comma-separated lists), and for geo fields
+// within_radius (lat,lng,radius in meters) and intersects (a GeoJSON geometry).
+// Filters are compiled to the same conditions as row-level permission rules, so the
+// rule variables ($CURRENT_USER, $CURRENT_TENANT and $NOW) may be used as values.
+//
+// The filter parameter takes a whole rule, as Directus clients send it: either JSON
+// (filter={"_or":[{"status":{"_eq":"draft"}},{"owner":{"_eq":"$CURRENT_USER"}}]}) or in
+// brackets (filter[status][_eq]=draft, filter[_or][0][status][_eq]=draft). It is combined
+// with the field parameters, and every field it names must be readable.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+// queryFilterOperators are the operators that may follow a field in brackets
+var queryFilterOperators = map[string]bool{
+	"eq": true, "neq": true, "gt": true, "gte": true, "lt": true, "lte": true,
+	"in": true, "nin": true, "null": true, "nnull": true,
+	"contains": true, "overlaps": true, "within_radius": true, "intersects": true,
+}
+
+// queryFilter compiles the filters in the request's query parameters for the fields of
+// tableName the caller may read in clear. It returns nil when there are none.
+func queryFilter(c *gin.Context, tableName string, allowedFields []string) (*rbac.RowFilter, error) {
+	userID, _ := middleware.GetUserID(c)
+	tenantID, _ := middleware.GetTenantID(c)
+	filter, err := parseQueryFilters(c.Request.URL.Query(), allowedFields, rbac.RuleVars{
+		UserID:   userID,
+		TenantID: tenantID,
+		Now:      time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := checkRedactedFilter(filter, rbac.FieldTransformsFromContext(c.Request.Context(), tableName)); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+// checkRedactedFilter refuses filters on fields the caller only reads redacted, which would
+// otherwise reveal their values one guess at a time
+func checkRedactedFilter(filter *rbac.RowFilter, transforms rbac.FieldTransforms) error {
+	for _, column := range filter.Columns() {
+		if _, redacted := transforms[column]; redacted {
+			return fmt.Errorf("cannot filter on redacted field '%s'", column)
+		}
+	}
+	return nil
+}
+
+// parseQueryFilters compiles field filters from query values. Reserved list parameters,
+// empty values and fields missing from allowedFields are ignored; unknown operators and
+// malformed values are errors.
+func parseQueryFilters(values url.Values, allowedFields []string, vars rbac.RuleVars) (*rbac.RowFilter, error) {
+	// Sort keys so the generated SQL is stable
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var rules []interface{}
+	rule, err := queryObjectParam(values, "filter")
+	if err != nil {
+		return nil, err
+	}
+	if len(rule) > 0 {
+		rules = append(rules, rule)
+	}
+
+	for _, key := range keys {
+		if reservedQueryParams[key] || strings.HasPrefix(key, "filter[") || strings.HasPrefix(key, "deep[") {
+			continue
+		}
+
+		field, operator := key, "eq"
+		if open := strings.IndexByte(key, '['); open >= 0 {
+			if !strings.HasSuffix(key, "]") {
+				return nil, fmt.Errorf("invalid filter '%s'", key)
+			}
+			field = key[:open]
+			operator = strings.TrimPrefix(key[open+1:len(key)-1], "_")
+			if !queryFilterOperators[operator] {
+				return nil, fmt.Errorf("unsupported filter operator '%s' on '%s'", operator, field)
+			}
+		}
+		if field == "" || !rbac.ValidateTableName(field) || !Contains(allowedFields, field) {
+			continue
+		}
+
+		for _, value := range values[key] {
+			if value == "" {
+				continue
+			}
+			rules = append(rules, map[string]interface{}{field: map[string]interface{}{"_" + operator: value}})
+		}
+	}
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	return compileFilterRule(map[string]interface{}{"_and": rules}, allowedFields, vars)
+}
+
+// queryObjectParam reads an object parameter such as filter or deep, sent as JSON or in
+// brackets, or returns nil when it is missing
+func queryObjectParam(values url.Values, name string) (map[string]interface{}, error) {
+	if raw := values.Get(name); raw != "" {
+		var object map[string]interface{}
+		if err := json.Unmarshal([]byte(raw), &object); err != nil {
+			return nil, fmt.Errorf("%s must be a JSON object", name)
+		}
+		return object, nil
+	}
+	return parseBracketParam(values, name)
+}
+
+// compileFilterRule compiles a filter rule sent by a client. Values arriving as strings
+// are converted where an operator needs another type, and every field the rule names
+// must be in allowedFields, so a filter cannot probe unreadable fields.
+func compileFilterRule(rule map[string]interface{}, allowedFields []string, vars rbac.RuleVars) (*rbac.RowFilter, error) {
+	normalizeFilterValues(rule)
+	raw, err := json.Marshal(rule)
+	if err != nil {
+		return nil, err
+	}
+	filter, err := rbac.CompileRowFilter(raw, vars)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+	for _, column := range filter.Columns() {
+		if !Contains(allowedFields, column) {
+			return nil, fmt.Errorf("cannot filter on field '%s'", column)
+		}
+	}
+	return filter, nil
+}
+
+// normalizeFilterValues converts comma-separated _in, _nin, _contains and _overlaps values
+// to arrays, "true"/"false" _null and _nnull values to booleans, "lat,lng,radius"
+// _within_radius values to objects and GeoJSON text of _intersects to objects, in place
+func normalizeFilterValues(rule map[string]interface{}) {
+	for key, value := range rule {
+		switch v := value.(type) {
+		case map[string]interface{}:
+			normalizeFilterValues(v)
+		case []interface{}:
+			for _, item := range v {
+				if nested, ok := item.(map[string]interface{}); ok {
+					normalizeFilterValues(nested)
+				}
+			}
+		case string:
+			switch key {
+			case "_in", "_nin", "_contains", "_overlaps":
+				list := []interface{}{}
+				for _, item := range strings.Split(v, ",") {
+					list = append(list, strings.TrimSpace(item))
+				}
+				rule[key] = list
+			case "_null", "_nnull":
+				if v == "true" || v == "false" {
+					rule[key] = v == "true"
+				}
+			case "_within_radius":
+				if parts := strings.Split(v, ","); len(parts) == 3 {
+					rule[key] = map[string]interface{}{
+						"lat":    strings.TrimSpace(parts[0]),
+						"lng":    strings.TrimSpace(parts[1]),
+						"radius": strings.TrimSpace(parts[2]),
+					}
+				}
+			case "_intersects":
+				var geometry map[string]interface{}
+				if err := json.Unmarshal([]byte(v), &geometry); err == nil {
+					rule[key] = geometry
+				}
+			}
+		}
+	}
+}
+
+// parseBracketParam collects the query parameters named like name[a][b]=value into nested
+// objects ({"a": {"b": "value"}}). Objects whose keys are all indexes, as in
+// filter[_or][0][status][_eq], become arrays. It returns nil when there are none.
+func parseBracketParam(values url.Values, name string) (map[string]interface{}, error) {
+	var root map[string]interface{}
+	for key, list := range values {
+		if !strings.HasPrefix(key, name+"[") || len(list) == 0 {
+			continue
+		}
+
+		var path []string
+		for rest := key[len(name):]; rest != ""; {
+			end := strings.IndexByte(rest, ']')
+			if rest[0] != '[' || end < 1 {
+				return nil, fmt.Errorf("invalid parameter '%s'", key)
+			}
+			path = append(path, rest[1:end])
+			rest = rest[end+1:]
+		}
+
+		if root == nil {
+			root = map[string]interface{}{}
+		}
+		node := root
+		for i, segment := range path {
+			if i == len(path)-1 {
+				if _, exists := node[segment]; exists {
+					return nil, fmt.Errorf("invalid parameter '%s'", key)
+				}
+				node[segment] = list[len(list)-1]
+				break
+			}
+			child, exists := node[segment]
+			if !exists {
+				child = map[string]interface{}{}
+				node[segment] = child
+			}
+			object, ok := child.(map[string]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid parameter '%s'", key)
+			}
+			node = object
+		}
+	}
+	if root == nil {
+		return nil, nil
+	}
+	rule, ok := indexedToArrays(root).(map[string]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%s must be an object", name)
+	}
+	return rule, nil
+}
+
+// indexedToArrays replaces the objects under value whose keys are 0..n-1 with arrays
+func indexedToArrays(value interface{}) interface{} {
+	object, ok := value.(map[string]interface{})
+	if !ok {
+		return value
+	}
+	for key, child := range object {
+		object[key] = indexedToArrays(child)
+	}
+
+	list := make([]interface{}, len(object))
+	for key, child := range object {
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index >= len(list) {
+			return object
+		}
+		list[index] = child
+	}
+	if len(list) == 0 {
+		return object
+	}
+	return list
+}
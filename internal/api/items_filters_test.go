@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/url"
+	"testing"
+
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQueryFilters(t *testing.T) {
+	values, err := url.ParseQuery("status=published&price[gte]=10&tags[in]=new,sale&archived_at[null]=true&limit=5&secret=x&name=")
+	require.NoError(t, err)
+
+	filter, err := parseQueryFilters(values, []string{"status", "price", "tags", "archived_at", "name"}, rbac.RuleVars{})
+	require.NoError(t, err)
+	condition, args := filter.SQL(3)
+	assert.Equal(t, `("archived_at" IS NULL AND "price" >= $3 AND "status" = $4 AND "tags" IN ($5, $6))`, condition)
+	assert.Equal(t, []interface{}{"10", "published", "new", "sale"}, args)
+
+	t.Run("No Filters", func(t *testing.T) {
+		values, _ := url.ParseQuery("sort=-name&secret=x")
+		filter, err := parseQueryFilters(values, []string{"name"}, rbac.RuleVars{})
+		require.NoError(t, err)
+		assert.Nil(t, filter)
+	})
+
+	t.Run("Variables", func(t *testing.T) {
+		userID := uuid.New()
+		values, _ := url.ParseQuery("owner_id=$CURRENT_USER")
+		filter, err := parseQueryFilters(values, []string{"*"}, rbac.RuleVars{UserID: userID})
+		require.NoError(t, err)
+		_, args := filter.SQL(1)
+		assert.Equal(t, []interface{}{userID.String()}, args)
+	})
+
+	t.Run("Filter Parameter", func(t *testing.T) {
+		for _, query := range []string{
+			`filter={"_or":[{"status":{"_eq":"draft"}},{"tags":{"_in":["new"]}}]}&price[lt]=5`,
+			"filter[_or][0][status][_eq]=draft&filter[_or][1][tags][_in]=new&price[lt]=5",
+		} {
+			values, err := url.ParseQuery(query)
+			require.NoError(t, err)
+			filter, err := parseQueryFilters(values, []string{"status", "price", "tags"}, rbac.RuleVars{})
+			require.NoError(t, err, query)
+			condition, args := filter.SQL(1)
+			assert.Equal(t, `(("status" = $1 OR "tags" IN ($2)) AND "price" < $3)`, condition, query)
+			assert.Equal(t, []interface{}{"draft", "new", "5"}, args, query)
+		}
+
+		values, _ := url.ParseQuery("filter[secret][_eq]=x")
+		_, err := parseQueryFilters(values, []string{"status"}, rbac.RuleVars{})
+		assert.Error(t, err, "unreadable fields cannot be filtered on")
+	})
+
+	t.Run("Arrays", func(t *testing.T) {
+		values, _ := url.ParseQuery("tags[contains]=new,sale&sizes[overlaps]=38")
+		filter, err := parseQueryFilters(values, []string{"tags", "sizes"}, rbac.RuleVars{})
+		require.NoError(t, err)
+		condition, args := filter.SQL(1)
+		assert.Equal(t, `("sizes" && $1 AND "tags" @> $2)`, condition)
+		assert.Equal(t, []interface{}{`{"38"}`, `{"new","sale"}`}, args)
+	})
+
+	t.Run("Spatial", func(t *testing.T) {
+		values, _ := url.ParseQuery("location[within_radius]=52.52, 13.405, 1000")
+		filter, err := parseQueryFilters(values, []string{"location"}, rbac.RuleVars{})
+		require.NoError(t, err)
+		_, args := filter.SQL(1)
+		assert.Equal(t, []interface{}{13.405, 52.52, 52.52, float64(1000)}, args)
+
+		values, _ = url.ParseQuery(`area[intersects]={"type":"Point","coordinates":[13.405,52.52]}`)
+		_, err = parseQueryFilters(values, []string{"area"}, rbac.RuleVars{})
+		assert.ErrorContains(t, err, "PostGIS")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, query := range []string{"price[like]=1", "price[gte=1", "price[null]=yes", "filter={", "filter[0]=x", "filter[a][_eq]=1&filter[a]=2"} {
+			values, _ := url.ParseQuery(query)
+			_, err := parseQueryFilters(values, []string{"*"}, rbac.RuleVars{})
+			assert.Error(t, err, query)
+		}
+	})
+}
+
+func TestCheckRedactedFilter(t *testing.T) {
+	values, _ := url.ParseQuery("email=jane@example.com&name=Jane")
+	filter, err := parseQueryFilters(values, []string{"*"}, rbac.RuleVars{})
+	require.NoError(t, err)
+
+	assert.NoError(t, checkRedactedFilter(filter, nil))
+	assert.NoError(t, checkRedactedFilter(filter, rbac.FieldTransforms{"phone": rbac.TransformLast4}))
+	assert.ErrorContains(t, checkRedactedFilter(filter, rbac.FieldTransforms{"email": rbac.TransformMaskEmail}), "redacted field 'email'")
+	assert.NoError(t, checkRedactedFilter(nil, rbac.FieldTransforms{"email": rbac.TransformMaskEmail}))
+}
@@ -0,0 +1,483 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the item import endpoints, which load CSV and XLSX files into a
+// collection.
+//
+// Import Endpoints:
+// - POST /items/:table/import     - Import a file (multipart form: "file", optional "mapping")
+// - GET  /items/:table/import/:id - Progress of an import running in the background
+//
+// The first row of the file names the columns. "mapping" is a JSON object from column
+// names to field names; without one, columns are matched to fields by name. Columns the
+// mapping leaves out (or maps to "") are skipped, and empty cells are left out of the
+// item so field defaults apply.
+//
+// Every row is validated against the collection before anything is written, and all
+// validation errors are reported together with their row numbers. Files of up to
+// importBatchSize rows are imported during the request in one transaction. Larger files
+// are imported by a background job in batches of importBatchSize rows, each in its own
+// transaction: if a batch fails, the batches before it stay imported.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/jobs"
+	"go-rbac-api/internal/rbac"
+	"go-rbac-api/internal/spreadsheet"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	importJobKind   = "items.import"
+	importBatchSize = 1000   // Rows written per transaction; smaller files are imported during the request
+	maxImportRows   = 100000 // Rows accepted per file
+	maxImportErrors = 50     // Validation errors reported per import
+)
+
+// errImportInvalid is returned when rows of an import fail validation; the rows and
+// their errors are in the import's progress
+var errImportInvalid = errors.New("rows failed validation")
+
+// importError is a validation error of one row
+type importError struct {
+	Row   int    `json:"row"` // Row number in the file, the header being row 1
+	Error string `json:"error"`
+}
+
+// importProgress is how far an import has got; background imports store it on their job
+type importProgress struct {
+	Total     int           `json:"total"`
+	Validated int           `json:"validated"`
+	Imported  int           `json:"imported"`
+	Errors    []importError `json:"errors,omitempty"`
+}
+
+// importJobPayload is the payload of an import job: the rows of the file, already
+// mapped to fields, and their row numbers in the file
+type importJobPayload struct {
+	UserID uuid.UUID                `json:"user_id"`
+	Table  string                   `json:"table"`
+	Items  []map[string]interface{} `json:"items"`
+	Rows   []int                    `json:"rows"`
+}
+
+// ImportsHandler imports CSV and XLSX files into collections
+type ImportsHandler struct {
+	items *ItemsHandler
+	cfg   *config.Config
+	queue *jobs.Queue
+}
+
+// NewImportsHandler creates an ImportsHandler that writes through items and runs large
+// imports on queue
+func NewImportsHandler(items *ItemsHandler, cfg *config.Config, queue *jobs.Queue) *ImportsHandler {
+	h := &ImportsHandler{items: items, cfg: cfg, queue: queue}
+	queue.Register(importJobKind, h.runImportJob)
+	return h
+}
+
+// ImportItems handles POST /items/:table/import requests.
+//
+// Response Format:
+//   - 201: Small file, imported; data.imported is the number of items created
+//   - 202: Large file, queued; meta.progress_url reports its progress
+//   - 400: Invalid table name, unreadable file or mapping, or validation errors (in "errors")
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks permission to create in this table
+//   - 413: File exceeds IMPORT_MAX_SIZE
+//
+// @Summary      Import items
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Import the rows of a CSV or XLSX file (first worksheet) as items. The first row names the columns; the optional mapping maps column names to field names. Every row is validated before anything is written. Files with more than 1000 rows are imported in the background.
+// @Param        table   path      string true  "Table name (e.g., 'products', 'customers')"
+// @Param        file    formData  file   true  "CSV or XLSX file"
+// @Param        mapping formData  string false "JSON object mapping column names to field names"
+// @Accept       multipart/form-data
+// @Produce      json
+// @Success      201 {object} map[string]interface{}
+// @Success      202 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      413 {object} models.ErrorResponse
+// @Router       /items/{table}/import [post]
+func (h *ImportsHandler) ImportItems(c *gin.Context) {
+	tableName := c.Param("table")
+
+	userID, allowedFields, ok := h.items.authorizeBulkRequest(c, tableName, "create")
+	if !ok {
+		return
+	}
+
+	rows, ok := h.readUpload(c)
+	if !ok {
+		return
+	}
+
+	var mapping map[string]string
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &mapping); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "mapping must be a JSON object of column names to field names"})
+			return
+		}
+	}
+
+	items, rowNumbers, err := importItemsFromRows(rows, mapping)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	for i, item := range items {
+		items[i] = h.items.policyChecker.FilterFields(item, allowedFields, nil)
+	}
+
+	ctx := c.Request.Context()
+	if len(items) > importBatchSize {
+		h.queueImport(c, userID, tableName, items, rowNumbers)
+		return
+	}
+
+	progress := importProgress{Total: len(items)}
+	if err := h.importItems(ctx, userID, tableName, items, rowNumbers, &progress, func() {}); err != nil {
+		if errors.Is(err, errImportInvalid) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Import failed: " + err.Error(), "errors": progress.Errors})
+			return
+		}
+		if respondQuotaExceeded(c, err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Import failed: " + err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"data": gin.H{"imported": progress.Imported},
+		"meta": gin.H{"table": tableName, "count": progress.Imported},
+	})
+}
+
+// readUpload reads the rows of the uploaded file. On failure the error response has
+// already been written.
+func (h *ImportsHandler) readUpload(c *gin.Context) ([][]string, bool) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.ImportMaxSize+multipartOverhead)
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the maximum size of %d bytes", h.cfg.ImportMaxSize)})
+			return nil, false
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required in the 'file' form field"})
+		return nil, false
+	}
+	if fileHeader.Size > h.cfg.ImportMaxSize {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the maximum size of %d bytes", h.cfg.ImportMaxSize)})
+		return nil, false
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return nil, false
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded file"})
+		return nil, false
+	}
+
+	format, err := spreadsheet.DetectFormat(fileHeader.Filename, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	rows, err := spreadsheet.Read(format, data)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return nil, false
+	}
+	return rows, true
+}
+
+// queueImport hands a large import to the job queue and responds with its progress URL
+func (h *ImportsHandler) queueImport(c *gin.Context, userID uuid.UUID, tableName string, items []map[string]interface{}, rowNumbers []int) {
+	tenantID, err := h.items.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+
+	// A failed import may have written some batches, so it is not retried
+	job, err := h.queue.Enqueue(c.Request.Context(), jobs.NewJob{
+		Kind:        importJobKind,
+		TenantID:    tenantID,
+		Payload:     importJobPayload{UserID: userID, Table: tableName, Items: items, Rows: rowNumbers},
+		MaxAttempts: 1,
+	})
+	if err != nil {
+		respondError(c, err, "Failed to queue import")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"data": gin.H{"id": job.ID, "status": job.Status, "progress": importProgress{Total: len(items)}},
+		"meta": gin.H{"table": tableName, "progress_url": fmt.Sprintf("/items/%s/import/%s", tableName, job.ID)},
+	})
+}
+
+// GetImport handles GET /items/:table/import/:id requests.
+//
+// @Summary      Get import progress
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Report the progress of a background import started by the caller: its status (pending, processing, completed or failed), the rows validated and imported, validation errors and the error that stopped it.
+// @Param        table   path      string true  "Table name"
+// @Param        id      path      string true  "Import ID (UUID)"
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /items/{table}/import/{id} [get]
+func (h *ImportsHandler) GetImport(c *gin.Context) {
+	tableName := c.Param("table")
+
+	userID, _, ok := h.items.authorizeBulkRequest(c, tableName, "create")
+	if !ok {
+		return
+	}
+
+	job, ok := h.items.getUserJob(c, importJobKind, "Import", userID, tableName)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": userJobToMap(job),
+		"meta": gin.H{"table": tableName},
+	})
+}
+
+// getUserJob loads the job named by the :id parameter, making sure it is of kind and was
+// started by userID on tableName. noun names the job in error responses, which have
+// already been written on failure.
+func (h *ItemsHandler) getUserJob(c *gin.Context, kind, noun string, userID uuid.UUID, tableName string) (sqlc.Job, bool) {
+	jobID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid " + strings.ToLower(noun) + " ID"})
+		return sqlc.Job{}, false
+	}
+
+	job, err := h.db.Queries.GetJobByID(c.Request.Context(), jobID)
+	if err != nil || job.Kind != kind {
+		c.JSON(http.StatusNotFound, gin.H{"error": noun + " not found"})
+		return sqlc.Job{}, false
+	}
+	var payload struct {
+		UserID uuid.UUID `json:"user_id"`
+		Table  string    `json:"table"`
+	}
+	if err := json.Unmarshal(job.Payload, &payload); err != nil || payload.UserID != userID || payload.Table != tableName {
+		c.JSON(http.StatusNotFound, gin.H{"error": noun + " not found"})
+		return sqlc.Job{}, false
+	}
+	return job, true
+}
+
+// userJobToMap converts an import or export job to its API representation
+func userJobToMap(job sqlc.Job) map[string]interface{} {
+	status := job.Status
+	if status == jobs.StatusDead {
+		status = "failed"
+	}
+
+	result := map[string]interface{}{
+		"id":           job.ID.String(),
+		"status":       status,
+		"progress":     job.Progress,
+		"error":        nil,
+		"created_at":   job.CreatedAt.Time,
+		"completed_at": nil,
+	}
+	if job.LastError.Valid && status != jobs.StatusCompleted {
+		result["error"] = job.LastError.String
+	}
+	if job.CompletedAt.Valid {
+		result["completed_at"] = job.CompletedAt.Time
+	}
+	return result
+}
+
+// runImportJob imports the rows of a queued import as the user who uploaded them, with
+// that user's current permissions
+func (h *ImportsHandler) runImportJob(ctx context.Context, job sqlc.Job) error {
+	var payload importJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid import payload: %w", err)
+	}
+	if !job.TenantID.Valid {
+		return fmt.Errorf("import has no tenant")
+	}
+
+	ctx = WithTenant(ctx, job.TenantID.UUID)
+	ctxWithTenant := context.WithValue(ctx, "tenant_id", job.TenantID.UUID)
+
+	hasPermission, allowedFields, rowFilter, err := h.items.policyChecker.CheckPermissionWithFilter(ctxWithTenant, payload.UserID, payload.Table, "create")
+	if err != nil {
+		return fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if !hasPermission {
+		return fmt.Errorf("the user who started the import may no longer create items in %s", payload.Table)
+	}
+	ctx = rbac.WithRowFilter(ctx, payload.Table, rowFilter)
+
+	for i, item := range payload.Items {
+		payload.Items[i] = h.items.policyChecker.FilterFields(item, allowedFields, nil)
+	}
+
+	progress := importProgress{Total: len(payload.Items)}
+	report := func() {
+		if err := h.queue.SetProgress(ctx, job.ID, progress); err != nil {
+			slog.Warn("failed to record import progress", "tenant_id", job.TenantID.UUID, "job_id", job.ID, "error", err)
+		}
+	}
+
+	err = h.importItems(ctx, payload.UserID, payload.Table, payload.Items, payload.Rows, &progress, report)
+	report()
+	return err
+}
+
+// importItems validates every item and then creates them in batches of importBatchSize,
+// calling report as progress is made. rowNumbers holds the file row of each item for
+// error messages. Validation errors are collected in progress and returned as
+// errImportInvalid before anything is written.
+func (h *ImportsHandler) importItems(ctx context.Context, userID uuid.UUID, tableName string, items []map[string]interface{}, rowNumbers []int, progress *importProgress, report func()) error {
+	rowNumber := func(i int) int {
+		if i < len(rowNumbers) {
+			return rowNumbers[i]
+		}
+		return i + 2
+	}
+
+	isCollection := h.items.isUserCollection(ctx, userID, tableName)
+
+	if isCollection {
+		tenantID, err := h.items.utils.GetUserTenantID(ctx, userID)
+		if err != nil {
+			return fmt.Errorf("failed to get user tenant: %w", err)
+		}
+
+		collections := h.items.collectionsHandler
+		for i, item := range items {
+			converted, err := func() (map[string]interface{}, error) {
+				if err := collections.ValidateCollectionData(ctx, tenantID, tableName, item); err != nil {
+					return nil, err
+				}
+				return collections.ConvertFieldValues(ctx, tenantID, tableName, item)
+			}()
+			if err != nil {
+				if len(progress.Errors) < maxImportErrors {
+					progress.Errors = append(progress.Errors, importError{Row: rowNumber(i), Error: err.Error()})
+				}
+			} else {
+				items[i] = converted
+			}
+
+			progress.Validated = i + 1
+			if progress.Validated%importBatchSize == 0 {
+				report()
+			}
+		}
+		if len(progress.Errors) > 0 {
+			return errImportInvalid
+		}
+	} else {
+		progress.Validated = len(items)
+	}
+	report()
+
+	for start := 0; start < len(items); start += importBatchSize {
+		end := min(start+importBatchSize, len(items))
+		if _, err := h.items.dynamicHandlers.BulkCreateDynamicItems(ctx, userID, tableName, items[start:end]); err != nil {
+			return fmt.Errorf("rows %d to %d: %w", rowNumber(start), rowNumber(end-1), err)
+		}
+
+		progress.Imported = end
+		report()
+	}
+	return nil
+}
+
+// importItemsFromRows turns the rows of a file into items, returning the file row number
+// of each. The first row names the columns; mapping, when given, maps column names to
+// field names and leaves out the columns it does not list. Empty cells and blank rows
+// are left out.
+func importItemsFromRows(rows [][]string, mapping map[string]string) ([]map[string]interface{}, []int, error) {
+	if len(rows) == 0 {
+		return nil, nil, fmt.Errorf("the file is empty")
+	}
+	if len(rows)-1 > maxImportRows {
+		return nil, nil, fmt.Errorf("the file has more than %d rows", maxImportRows)
+	}
+
+	header := rows[0]
+	fields := make([]string, len(header))
+	seen := make(map[string]string, len(header))
+	for i, column := range header {
+		field := column
+		if mapping != nil {
+			field = mapping[column]
+		}
+		if field == "" {
+			continue
+		}
+		if !columnNamePattern.MatchString(field) {
+			return nil, nil, fmt.Errorf("column '%s' does not map to a valid field name", column)
+		}
+		if previous, ok := seen[field]; ok {
+			return nil, nil, fmt.Errorf("columns '%s' and '%s' both map to field '%s'", previous, column, field)
+		}
+		seen[field] = column
+		fields[i] = field
+	}
+	if len(seen) == 0 {
+		return nil, nil, fmt.Errorf("no columns map to fields")
+	}
+
+	items := make([]map[string]interface{}, 0, len(rows)-1)
+	rowNumbers := make([]int, 0, len(rows)-1)
+	for r, row := range rows[1:] {
+		item := make(map[string]interface{}, len(seen))
+		for i, value := range row {
+			if i < len(fields) && fields[i] != "" && value != "" {
+				item[fields[i]] = value
+			}
+		}
+		if len(item) == 0 {
+			continue // Blank row
+		}
+		items = append(items, item)
+		rowNumbers = append(rowNumbers, r+2)
+	}
+	if len(items) == 0 {
+		return nil, nil, fmt.Errorf("the file has no rows to import")
+	}
+	return items, rowNumbers, nil
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/jobs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestImportItemsFromRows(t *testing.T) {
+	rows := [][]string{
+		{"name", "price", "notes"},
+		{"Desk", "120", ""},
+		{"", "", ""},
+		{"Lamp"},
+	}
+
+	items, rowNumbers, err := importItemsFromRows(rows, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{
+		{"name": "Desk", "price": "120"},
+		{"name": "Lamp"},
+	}, items)
+	assert.Equal(t, []int{2, 4}, rowNumbers)
+
+	items, _, err = importItemsFromRows(rows, map[string]string{"name": "title", "price": "amount"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"title": "Desk", "amount": "120"}, items[0])
+}
+
+func TestImportItemsFromRows_Errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		rows    [][]string
+		mapping map[string]string
+		wantErr string
+	}{
+		{"Empty File", nil, nil, "file is empty"},
+		{"Header Only", [][]string{{"name"}}, nil, "no rows to import"},
+		{"Invalid Field", [][]string{{"Product Name"}, {"Desk"}}, nil, "valid field name"},
+		{"Duplicate Field", [][]string{{"a", "b"}, {"1", "2"}}, map[string]string{"a": "name", "b": "name"}, "both map to field 'name'"},
+		{"Nothing Mapped", [][]string{{"a"}, {"1"}}, map[string]string{"b": "name"}, "no columns map"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := importItemsFromRows(tt.rows, tt.mapping)
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestUserJobToMap(t *testing.T) {
+	job := sqlc.Job{
+		Status:    jobs.StatusDead,
+		Progress:  json.RawMessage(`{"total": 5000, "validated": 5000, "imported": 2000}`),
+		LastError: sql.NullString{String: "rows 2002 to 3001: duplicate key", Valid: true},
+	}
+
+	result := userJobToMap(job)
+	assert.Equal(t, "failed", result["status"])
+	assert.Equal(t, "rows 2002 to 3001: duplicate key", result["error"])
+	assert.Nil(t, result["completed_at"])
+	assert.NotContains(t, result, "payload")
+}
@@ -0,0 +1,136 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains item lookups by a unique field instead of the item's ID.
+//
+// Content sites address pages by slug rather than UUID. In a collection with a unique field,
+// an item can be read by the field's value:
+//
+//	GET /items/posts/slug/hello-world          # the unique field named slug
+//	GET /items/products/SKU-0042?key_field=sku # any unique field
+//
+// Both answer like GET /items/:table/:id, with the same permissions, row filters, trash
+// handling, fields and deep parameters. Only unique fields qualify, so a value names at most
+// one item, and the caller must be allowed to read the field.
+package api
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// slugField is the unique field GET /items/:table/slug/:slug looks items up by
+const slugField = "slug"
+
+// GetItemBySlug handles GET /items/:table/slug/:slug requests.
+//
+// Reads the item of a collection whose unique "slug" field has the given value.
+//
+// Response Format:
+//   - 200: Same as GET /items/:table/:id
+//   - 400: Invalid table name, or not a collection
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on the table or its slug field
+//   - 404: No item with this slug
+//   - 422: The collection has no unique slug field
+//
+// @Summary      Get item by slug
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Retrieve the item of a collection whose unique slug field has the given value. Use ?key_field= on GET /items/{table}/{id} for other unique fields.
+// @Param        table   path      string true  "Collection name"
+// @Param        slug    path      string true  "Value of the item's slug field"
+// @Param        fields  query     string false "Fields to return (e.g., 'id,title'); dotted paths expand relations (e.g., '*,author.*')"
+// @Param        include_deleted query bool false "Return the item even if it is soft-deleted"
+// @Produce      json
+// @Success      200 {object} models.ItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
+// @Router       /items/{table}/slug/{slug} [get]
+func (h *ItemsHandler) GetItemBySlug(c *gin.Context) {
+	h.getItem(c, slugField, c.Param("slug"))
+}
+
+// lookupItemID returns the ID of the collection item whose unique field keyField holds key
+func (h *ItemsHandler) lookupItemID(c *gin.Context, tableName string, userID uuid.UUID, keyField, key string, allowedFields []string) (string, error) {
+	ctx := c.Request.Context()
+	if h.isSchemaTable(tableName) || !h.isUserCollection(ctx, userID, tableName) {
+		return "", validationError("items can only be looked up by field in collections")
+	}
+	if len(allowedFields) > 0 && !Contains(allowedFields, keyField) {
+		return "", forbiddenError("field '%s' is not readable", keyField)
+	}
+	if _, redacted := rbac.FieldTransformsFromContext(ctx, tableName)[keyField]; redacted {
+		return "", forbiddenError("field '%s' is redacted and cannot look items up", keyField)
+	}
+	if err := h.checkKeyField(ctx, userID, tableName, keyField); err != nil {
+		return "", err
+	}
+
+	return h.dynamicHandlers.FindDynamicItemID(ctx, userID, tableName, keyField, key, c.Query("include_deleted") == "true")
+}
+
+// checkKeyField refuses fields of a collection that cannot look items up: missing ones and
+// ones that are not unique
+func (h *ItemsHandler) checkKeyField(ctx context.Context, userID uuid.UUID, tableName, keyField string) error {
+	tenantID, err := h.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return err
+	}
+	collection, err := h.collectionsHandler.GetCollection(ctx, tenantID, tableName)
+	if err != nil {
+		return err
+	}
+	fields, err := h.db.Queries.GetFieldsByCollection(ctx, uuid.NullUUID{UUID: collection.ID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to get fields: %w", err)
+	}
+
+	for _, field := range fields {
+		if field.Name != keyField {
+			continue
+		}
+		if !field.IsUnique.Bool {
+			return validationError("field '%s' is not unique; items can only be looked up by unique fields", keyField)
+		}
+		return nil
+	}
+	return validationError("collection %s has no field '%s'", tableName, keyField)
+}
+
+// FindDynamicItemID returns the ID of the item whose column holds value, within the caller's
+// row filter. Items in the trash of a soft-delete collection are only found when
+// includeDeleted is set.
+func (d *DynamicHandlers) FindDynamicItemID(ctx context.Context, userID uuid.UUID, tableName, column, value string, includeDeleted bool) (string, error) {
+	if !columnNamePattern.MatchString(column) {
+		return "", validationError("invalid field %q", column)
+	}
+	dataTableName, tenantID, err := d.resolveDataTable(ctx, userID, tableName)
+	if err != nil {
+		return "", err
+	}
+
+	query := fmt.Sprintf(`SELECT id FROM %s WHERE "%s" = $1`, dataTableName, column)
+	if !includeDeleted {
+		query += liveRowsOnly(d.softDeleteEnabled(ctx, tenantID, tableName))
+	}
+	ruleCondition, args := rowFilterCondition(ctx, tableName, 2)
+	query += ruleCondition
+
+	var itemID string
+	if err := d.db.Reader().QueryRowContext(ctx, query, append([]interface{}{value}, args...)...).Scan(&itemID); err != nil {
+		if strings.Contains(err.Error(), "no rows") {
+			return "", notFoundError("item not found")
+		}
+		return "", fmt.Errorf("failed to look up item: %w", err)
+	}
+	return itemID, nil
+}
@@ -0,0 +1,46 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindDynamicItemIDRejectsInvalidField(t *testing.T) {
+	d := &DynamicHandlers{}
+	_, err := d.FindDynamicItemID(context.Background(), uuid.New(), "posts", `slug" OR 1=1 --`, "hello", false)
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestItemsHandler_LookupRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &ItemsHandler{}
+	router := gin.New()
+	router.GET("/items/:table/:id", handler.GetItem)
+	router.GET("/items/:table/slug/:slug", handler.GetItemBySlug)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"Invalid Table Name", "/items/bad-name!/slug/hello-world", http.StatusBadRequest},
+		{"Unauthenticated Slug", "/items/posts/slug/hello-world", http.StatusUnauthorized},
+		{"Invalid Item ID", "/items/posts/hello-world", http.StatusBadRequest},
+		{"Unauthenticated Key Field", "/items/products/SKU-0042?key_field=sku", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
@@ -0,0 +1,232 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the manual ordering of items in sortable collections.
+//
+// POST /items/:table/:id/move {"before": id} or {"after": id} places an item next to
+// another one. The items from the new position on move down one place, all in one
+// transaction, so admin UIs can implement drag and drop without renumbering every row
+// themselves. Items without a position yet are numbered first, after the positioned ones.
+// The moved item gets a revision and an item.update event; the items that shift do not.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// moveItemRequest is the body of POST /items/:table/:id/move; exactly one of its fields is set
+type moveItemRequest struct {
+	Before string `json:"before"` // Item to place the moved item in front of
+	After  string `json:"after"`  // Item to place the moved item behind
+}
+
+// anchor returns the item the move is relative to, and whether the moved item goes after it
+func (r moveItemRequest) anchor() (uuid.UUID, bool, error) {
+	if (r.Before == "") == (r.After == "") {
+		return uuid.Nil, false, validationError("exactly one of before and after is required")
+	}
+	anchor, after := r.Before, false
+	if r.After != "" {
+		anchor, after = r.After, true
+	}
+	anchorID, err := uuid.Parse(anchor)
+	if err != nil {
+		return uuid.Nil, false, validationError("invalid item ID %q", anchor)
+	}
+	return anchorID, after, nil
+}
+
+// movePosition returns the position an item moved next to an item at anchorPosition takes
+func movePosition(anchorPosition int64, after bool) int64 {
+	if after {
+		return anchorPosition + 1
+	}
+	return anchorPosition
+}
+
+// shiftStatement returns the statement moving every item from a position on ($1) down one
+// place, except the moved item ($2)
+func shiftStatement(table string) string {
+	return fmt.Sprintf(`UPDATE %s SET %s = %s + 1 WHERE %s >= $1 AND id <> $2`, table, sortColumn, sortColumn, sortColumn)
+}
+
+// renumberStatement returns the statement numbering every item of a table from 1 in its
+// current order, placing items without a position last
+func renumberStatement(table string, flags collectionFlags) string {
+	order := sortColumn + " NULLS LAST, id"
+	if flags.Timestamps {
+		order = sortColumn + " NULLS LAST, created_at, id"
+	}
+	return fmt.Sprintf(`UPDATE %s t SET %s = n.position FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY %s) AS position FROM %s) n WHERE t.id = n.id`,
+		table, sortColumn, order, table)
+}
+
+// MoveDynamicItem places an item of a sortable collection right before or after another one
+// and returns its new position. Both items must be within the caller's row filter.
+func (d *DynamicHandlers) MoveDynamicItem(ctx context.Context, userID uuid.UUID, tableName, itemID, anchorID string, after bool) (int64, error) {
+	if itemID == anchorID {
+		return 0, validationError("an item cannot be moved next to itself")
+	}
+
+	dataTableName, tenantID, err := d.resolveDataTable(ctx, userID, tableName)
+	if err != nil {
+		return 0, err
+	}
+	flags := d.collectionFlags(ctx, tenantID, tableName)
+	if !flags.Sortable {
+		return 0, validationError("collection %s is not sortable", tableName)
+	}
+
+	var position int64
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		// Moves, and inserts placing items last, wait for each other
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("LOCK TABLE %s IN SHARE ROW EXCLUSIVE MODE", dataTableName)); err != nil {
+			return fmt.Errorf("failed to lock items: %w", err)
+		}
+
+		before, err := d.snapshotRow(ctx, tx, dataTableName, tableName, itemID, flags.SoftDelete)
+		if err != nil {
+			return err
+		}
+		anchor, err := d.snapshotRow(ctx, tx, dataTableName, tableName, anchorID, flags.SoftDelete)
+		if err != nil {
+			return wrapError(err, "item %s to move next to", anchorID)
+		}
+
+		anchorPosition, err := sortValue(anchor[sortColumn])
+		if err != nil {
+			// The anchor has no position yet; number every item and read it again
+			if _, err := tx.ExecContext(ctx, renumberStatement(dataTableName, flags)); err != nil {
+				return fmt.Errorf("failed to number items: %w", err)
+			}
+			query := fmt.Sprintf("SELECT %s FROM %s WHERE id = $1", sortColumn, dataTableName)
+			if err := tx.QueryRowContext(ctx, query, anchorID).Scan(&anchorPosition); err != nil {
+				return fmt.Errorf("failed to read item position: %w", err)
+			}
+		}
+		position = movePosition(anchorPosition, after)
+
+		if _, err := tx.ExecContext(ctx, shiftStatement(dataTableName), position, itemID); err != nil {
+			return fmt.Errorf("failed to shift items: %w", err)
+		}
+		stamps, args := stampAssignments(flags, userID, 3)
+		setParts := append([]string{fmt.Sprintf("%s = $1", sortColumn)}, stamps...)
+		query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $2", dataTableName, strings.Join(setParts, ", "))
+		if _, err := tx.ExecContext(ctx, query, append([]interface{}{position, itemID}, args...)...); err != nil {
+			return fmt.Errorf("failed to move item: %w", err)
+		}
+
+		return d.recordRevision(ctx, tx, tenantID, userID, tableName, itemID, revisionUpdate,
+			map[string]interface{}{sortColumn: before[sortColumn]}, map[string]interface{}{sortColumn: position})
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	d.publish(ctx, events.ItemUpdate, userID, tenantID, tableName, []string{itemID}, map[string]interface{}{"id": itemID, sortColumn: position})
+	return position, nil
+}
+
+// MoveItem handles POST /items/:table/:id/move requests.
+//
+// Places an item of a sortable collection right before or after another item, shifting the
+// items in between. Requires update permission on the collection, including the sort field.
+//
+// Request Body:
+//
+//	{"after": "<item id>"}
+//
+// Response Format:
+//   - 200: {"data": {"id": "...", "sort": 4}, "meta": {"table": "...", "id": "..."}}
+//   - 400: Invalid table name, item ID or body, or not a collection
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks update permission on the table or its sort field
+//   - 404: Item, or the item to move it next to, not found
+//   - 422: Neither or both of before and after, or the collection is not sortable
+//
+// @Summary      Move an item
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Place an item of a sortable collection before or after another item. Body: {"before": id} or {"after": id}.
+// @Param        table  path  string true "Collection name"
+// @Param        id     path  string true "Item ID"
+// @Param        body   body  map[string]interface{} true "The item to move next to"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
+// @Router       /items/{table}/{id}/move [post]
+func (h *ItemsHandler) MoveItem(c *gin.Context) {
+	tableName := c.Param("table")
+	itemID := c.Param("id")
+
+	if !rbac.ValidateTableName(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table name"})
+		return
+	}
+	if _, err := uuid.Parse(itemID); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req moveItemRequest
+	if err := decodeJSON(c, &req, BodyItems); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	anchorID, after, err := req.anchor()
+	if err != nil {
+		respondError(c, err, "Invalid move")
+		return
+	}
+
+	if h.isSchemaTable(tableName) || !h.isUserCollection(c.Request.Context(), userID, tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only items of collections can be moved"})
+		return
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, allowedFields, rowFilter, err := h.policyChecker.CheckPermissionWithFilter(ctxWithTenant, userID, tableName, "update")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+	if !hasPermission || (len(allowedFields) > 0 && !Contains(allowedFields, sortColumn)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+	withRowFilter(c, tableName, rowFilter)
+
+	position, err := h.dynamicHandlers.MoveDynamicItem(c.Request.Context(), userID, tableName, itemID, anchorID.String(), after)
+	if err != nil {
+		respondError(c, err, "Failed to move item")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"id": itemID, sortColumn: position},
+		"meta": gin.H{"table": tableName, "id": itemID},
+	})
+}
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMoveItemRequestAnchor(t *testing.T) {
+	id := uuid.New()
+
+	anchor, after, err := moveItemRequest{After: id.String()}.anchor()
+	require.NoError(t, err)
+	assert.Equal(t, id, anchor)
+	assert.True(t, after)
+
+	anchor, after, err = moveItemRequest{Before: id.String()}.anchor()
+	require.NoError(t, err)
+	assert.Equal(t, id, anchor)
+	assert.False(t, after)
+
+	for _, req := range []moveItemRequest{{}, {Before: id.String(), After: id.String()}, {After: "not-a-uuid"}} {
+		_, _, err := req.anchor()
+		assert.ErrorIs(t, err, ErrValidation)
+	}
+}
+
+func TestMovePosition(t *testing.T) {
+	assert.Equal(t, int64(3), movePosition(3, false))
+	assert.Equal(t, int64(4), movePosition(3, true))
+}
+
+func TestMoveStatements(t *testing.T) {
+	assert.Equal(t,
+		`UPDATE "acme"."data_tasks" SET sort = sort + 1 WHERE sort >= $1 AND id <> $2`,
+		shiftStatement(`"acme"."data_tasks"`))
+	assert.Contains(t, renumberStatement(`"acme"."data_tasks"`, defaultCollectionFlags), "ORDER BY sort NULLS LAST, created_at, id")
+	assert.Contains(t, renumberStatement(`"acme"."data_tasks"`, collectionFlags{}), "ORDER BY sort NULLS LAST, id)")
+}
+
+func TestItemsHandler_MoveRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &ItemsHandler{}
+	router := gin.New()
+	router.POST("/items/:table/:id/move", handler.MoveItem)
+
+	tests := []struct {
+		name string
+		path string
+		want int
+	}{
+		{"Invalid Table Name", "/items/bad-name!/6e68062f-c4c6-42df-9e01-e2d1081664f4/move", http.StatusBadRequest},
+		{"Invalid Item ID", "/items/tasks/not-a-uuid/move", http.StatusBadRequest},
+		{"Unauthenticated", "/items/tasks/6e68062f-c4c6-42df-9e01-e2d1081664f4/move", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest("POST", tt.path, nil))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
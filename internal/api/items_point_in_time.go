@@ -0,0 +1,523 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains point-in-time restores, which bring the items of one collection, or one
+// item, back to how they were at a chosen time without touching the rest of the tenant.
+//
+// Point-in-Time Restore Endpoint:
+// - POST /items/:table/restore?at=<timestamp> - Restore a collection's items as they were at the timestamp
+//
+// A restore starts from the newest backup of the tenant (see tenant_backups.go) completed
+// by the restore point and replays the collection's revisions recorded from the start of
+// that backup up to the restore point: creates add the item, updates set the changed fields
+// and deletes remove the item, or move it to the trash in a soft-delete collection.
+// Revisions already contained in the backup replay to the values it holds, so the overlap
+// is harmless. The collection is then brought to the replayed state in one transaction.
+// Only items that differ are written, each with a revision of its own, so the restore shows
+// up in the items' history and can itself be rolled back by a later restore.
+//
+// With &id=<item_id> only that item is restored. Writes that record no revisions (imports
+// and backup restores) are not replayed, and the links of many-to-many fields are left as
+// they are. Values of fields deleted since are dropped; fields created since keep their
+// values. Only admins of the tenant restore items to a point in time.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// itemChanges lists the IDs of the items a reset wrote
+type itemChanges struct {
+	Created []string
+	Updated []string
+	Deleted []string
+}
+
+// RestoreItemsAt handles POST /items/:table/restore requests.
+//
+// Brings the items of a collection back to how they were at the time in ?at=, an RFC 3339
+// timestamp, from the newest backup completed by then and the revisions recorded since.
+// With ?id= only that item is restored.
+//
+// Response Format:
+//   - 200: Items restored, with the backup used and the items created, updated and deleted
+//   - 400: Invalid table name, item ID or timestamp, or a schema table
+//   - 401: Missing or invalid authentication token
+//   - 403: User is not an admin of the tenant
+//   - 404: Collection not found, or the backup archive is missing from storage
+//   - 409: No backup was completed by the timestamp, or its archive is corrupt
+//
+// @Summary      Restore a collection to a point in time
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Restore the items of a collection, or one item, to how they were at a timestamp, starting from the newest tenant backup completed by then and replaying the revisions recorded since. Other collections are left alone. Requires an admin of the tenant.
+// @Param        table   path      string true  "Collection name"
+// @Param        at      query     string true  "Restore point (RFC 3339)"
+// @Param        id      query     string false "Restore only this item"
+// @Produce      json
+// @Success      200 {object} models.PointInTimeRestoreResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Router       /items/{table}/restore [post]
+func (h *BackupsHandler) RestoreItemsAt(c *gin.Context) {
+	tableName := c.Param("table")
+	if !rbac.ValidateTableName(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table name"})
+		return
+	}
+	if h.tenants.items.isSchemaTable(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revisions are not recorded for schema tables"})
+		return
+	}
+
+	auth, ok := middleware.GetAuthProvider(c)
+	if !ok || !(auth.IsAdmin || auth.IsSuperAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can restore items to a point in time"})
+		return
+	}
+
+	at, err := parseRestorePoint(c.Query("at"), time.Now())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	var itemID string
+	if value := c.Query("id"); value != "" {
+		id, err := uuid.Parse(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+			return
+		}
+		itemID = id.String()
+	}
+
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.Request.Context()
+	tenantID, err := h.tenants.items.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+
+	backup, err := h.db.Queries.GetLatestCompletedTenantBackup(ctx, sqlc.GetLatestCompletedTenantBackupParams{
+		TenantID:    tenantID,
+		CompletedAt: sql.NullTime{Time: at, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusConflict, gin.H{"error": "No backup of the tenant was completed by " + at.Format(time.RFC3339)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backups"})
+		return
+	}
+
+	archive, cleanup, err := h.openBackup(ctx, backup)
+	if err != nil {
+		respondOpenBackupError(c, backup, err)
+		return
+	}
+	defer cleanup()
+
+	items, replayed, err := h.itemsAt(ctx, tenantID, archive, backup, tableName, itemID, at)
+	if err != nil {
+		respondError(c, err, "Failed to replay revisions")
+		return
+	}
+	changes, err := h.tenants.items.dynamicHandlers.ResetDynamicItems(ctx, userID, tableName, itemID, items)
+	if err != nil {
+		respondError(c, err, "Restore failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, models.PointInTimeRestoreResponse{
+		Message:   "Items restored successfully",
+		At:        at,
+		Backup:    backupModel(backup),
+		Revisions: replayed,
+		Created:   len(changes.Created),
+		Updated:   len(changes.Updated),
+		Deleted:   len(changes.Deleted),
+	})
+}
+
+// itemsAt returns the items of a collection at the restore point, keyed by ID: the backed
+// up items with the revisions recorded from the start of the backup up to at replayed on
+// top. With itemID only that item is returned. It also returns how many revisions were
+// replayed.
+func (h *BackupsHandler) itemsAt(ctx context.Context, tenantID uuid.UUID, archive *tenantArchive, backup sqlc.TenantBackup, collectionSlug, itemID string, at time.Time) (map[string]map[string]interface{}, int, error) {
+	items := make(map[string]map[string]interface{})
+
+	// A collection missing from the backup was created after it and starts out empty
+	for _, collection := range archive.manifest.Collections {
+		if collection.Slug != collectionSlug {
+			continue
+		}
+		file, err := archive.files[collection.File].Open()
+		if err != nil {
+			return nil, 0, err
+		}
+		_, err = readArchiveRows(file, path.Ext(collection.File), tenantImportBatchSize, func(rows []map[string]interface{}) error {
+			for _, row := range rows {
+				id := fmt.Sprint(row["id"])
+				if itemID == "" || id == itemID {
+					items[id] = row
+				}
+			}
+			return nil
+		})
+		file.Close()
+		if err != nil {
+			return nil, 0, fmt.Errorf("items of %s: %w", collectionSlug, err)
+		}
+	}
+
+	revisions, err := h.db.Queries.ListCollectionRevisions(ctx, sqlc.ListCollectionRevisionsParams{
+		TenantID:    tenantID,
+		Collection:  collectionSlug,
+		CreatedAt:   backup.CreatedAt,
+		CreatedAt_2: sql.NullTime{Time: at, Valid: true},
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to list revisions: %w", err)
+	}
+	if itemID != "" {
+		var own []sqlc.Revision
+		for _, revision := range revisions {
+			if revision.ItemID.String() == itemID {
+				own = append(own, revision)
+			}
+		}
+		revisions = own
+	}
+
+	softDelete := h.tenants.items.dynamicHandlers.softDeleteEnabled(ctx, tenantID, collectionSlug)
+	if err := replayRevisions(items, revisions, softDelete); err != nil {
+		return nil, 0, err
+	}
+	return items, len(revisions), nil
+}
+
+// replayRevisions applies revisions, oldest first, to items keyed by ID. Items created by a
+// revision take its time and user as their audit stamps, and updates move the stamps of
+// items that have them. An update of an item that is not there is skipped: the item was
+// written without revisions, by an import or a restore.
+func replayRevisions(items map[string]map[string]interface{}, revisions []sqlc.Revision, softDelete bool) error {
+	for _, revision := range revisions {
+		id := revision.ItemID.String()
+		at := revision.CreatedAt.Time.UTC().Format(time.RFC3339Nano)
+		var user interface{}
+		if revision.UserID.Valid {
+			user = revision.UserID.UUID.String()
+		}
+		item, exists := items[id]
+
+		switch revision.Action {
+		case revisionCreate:
+			values, err := decodeRevisionData(revision.NewData)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				item = map[string]interface{}{"created_at": at, "created_by": user, "updated_at": at, "updated_by": user}
+				items[id] = item
+			}
+			for field, value := range values {
+				item[field] = value
+			}
+			item["id"] = id
+
+		case revisionUpdate:
+			if !exists {
+				continue
+			}
+			values, err := decodeRevisionData(revision.NewData)
+			if err != nil {
+				return err
+			}
+			for field, value := range values {
+				item[field] = value
+			}
+			if _, ok := item["updated_at"]; ok {
+				item["updated_at"] = at
+			}
+			if _, ok := item["updated_by"]; ok {
+				item["updated_by"] = user
+			}
+
+		case revisionDelete:
+			// Deleting a live item of a soft-delete collection trashes it
+			if deletedAt, ok := item["deleted_at"]; softDelete && ok && deletedAt == nil {
+				item["deleted_at"] = at
+				continue
+			}
+			delete(items, id)
+
+		default:
+			return fmt.Errorf("unsupported revision action %q", revision.Action)
+		}
+	}
+	return nil
+}
+
+// parseRestorePoint parses the ?at= timestamp of a point-in-time restore, which must not
+// lie after now
+func parseRestorePoint(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, fmt.Errorf("at is required: the time to restore to, as an RFC 3339 timestamp")
+	}
+	at, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid at %q: expected an RFC 3339 timestamp", value)
+	}
+	if at.After(now) {
+		return time.Time{}, fmt.Errorf("at %s is in the future", value)
+	}
+	return at, nil
+}
+
+// ResetDynamicItems brings the items of a collection, or the one item itemID, to items,
+// keyed by ID, inside a single transaction: items not in it are deleted, missing ones are
+// re-created with their IDs and the others are updated where their values differ. Every
+// write records a revision and publishes an event, as any other write does. Values of
+// columns the data table no longer has are dropped.
+func (d *DynamicHandlers) ResetDynamicItems(ctx context.Context, userID uuid.UUID, collectionSlug, itemID string, items map[string]map[string]interface{}) (itemChanges, error) {
+	fullTableName, tenantID, err := d.resolveDataTable(ctx, userID, collectionSlug)
+	if err != nil {
+		return itemChanges{}, err
+	}
+
+	var changes itemChanges
+	var created, updated []map[string]interface{}
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		current, columns, err := d.lockItemRows(ctx, tx, fullTableName, tenantID, itemID)
+		if err != nil {
+			return err
+		}
+
+		// Deletes come first, so they make room under the item quota
+		for _, id := range itemIDs(current) {
+			if _, ok := items[id]; ok {
+				continue
+			}
+			if err := d.deleteRow(ctx, tx, fullTableName, id, false); err != nil {
+				return wrapError(err, "item %s", id)
+			}
+			if err := d.recordRevision(ctx, tx, tenantID, userID, collectionSlug, id, revisionDelete, current[id], nil); err != nil {
+				return err
+			}
+			changes.Deleted = append(changes.Deleted, id)
+		}
+
+		var missing []string
+		for _, id := range itemIDs(items) {
+			before, ok := current[id]
+			if !ok {
+				missing = append(missing, id)
+				continue
+			}
+			oldValues, newValues := diffRow(before, keepColumns(items[id], columns))
+			if len(newValues) == 0 {
+				continue
+			}
+			encoded, err := d.encodeStoredValues(ctx, tenantID, collectionSlug, newValues)
+			if err != nil {
+				return wrapError(err, "item %s", id)
+			}
+			if err := setColumns(ctx, tx, fullTableName, id, encoded); err != nil {
+				return wrapError(err, "item %s", id)
+			}
+			if err := d.recordRevision(ctx, tx, tenantID, userID, collectionSlug, id, revisionUpdate, oldValues, newValues); err != nil {
+				return err
+			}
+			changes.Updated = append(changes.Updated, id)
+			updated = append(updated, withID(newValues, id))
+		}
+
+		if len(missing) == 0 {
+			return nil
+		}
+		if err := d.checkItemQuota(ctx, tx, tenantID, fullTableName, len(missing)); err != nil {
+			return err
+		}
+		for _, id := range missing {
+			row := keepColumns(items[id], columns)
+			encoded, err := d.encodeStoredValues(ctx, tenantID, collectionSlug, row)
+			if err != nil {
+				return wrapError(err, "item %s", id)
+			}
+			encoded["tenant_id"] = tenantID
+			if err := d.reinsertRow(ctx, tx, fullTableName, encoded); err != nil {
+				return wrapError(err, "item %s", id)
+			}
+			if err := d.recordRevision(ctx, tx, tenantID, userID, collectionSlug, id, revisionCreate, nil, row); err != nil {
+				return err
+			}
+			changes.Created = append(changes.Created, id)
+			created = append(created, row)
+		}
+		return nil
+	})
+	if err != nil {
+		return itemChanges{}, err
+	}
+
+	if len(changes.Deleted) > 0 {
+		d.publish(ctx, events.ItemDelete, userID, tenantID, collectionSlug, changes.Deleted, nil)
+	}
+	if len(changes.Updated) > 0 {
+		d.publish(ctx, events.ItemUpdate, userID, tenantID, collectionSlug, changes.Updated, updated)
+	}
+	if len(changes.Created) > 0 {
+		d.publish(ctx, events.ItemCreate, userID, tenantID, collectionSlug, changes.Created, created)
+	}
+	return changes, nil
+}
+
+// lockItemRows reads and locks the rows of a collection's items, or of the one item itemID,
+// keyed by ID, with their values as an archive holds them. It also returns the columns an
+// item may set, which leave out tenant_id and the columns of deleted fields.
+func (d *DynamicHandlers) lockItemRows(ctx context.Context, tx *sql.Tx, fullTableName string, tenantID uuid.UUID, itemID string) (map[string]map[string]interface{}, map[string]bool, error) {
+	query := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $1", fullTableName)
+	args := []interface{}{tenantID}
+	if itemID != "" {
+		query += " AND id = $2"
+		args = append(args, itemID)
+	}
+	rows, err := tx.QueryContext(ctx, query+" FOR UPDATE", args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read items: %w", err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, nil, err
+	}
+	settable := make(map[string]bool, len(columns))
+	for _, column := range columns {
+		if column != "tenant_id" && !strings.HasPrefix(column, archivedColumnPrefix) {
+			settable[column] = true
+		}
+	}
+
+	items := make(map[string]map[string]interface{})
+	for rows.Next() {
+		row, err := scanRowToMap(rows, columns)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to scan item: %w", err)
+		}
+		// Exported rows went through JSON; these do too, so equal values compare equal
+		raw, err := json.Marshal(row)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to encode item: %w", err)
+		}
+		if row, err = decodeRevisionData(pqtype.NullRawMessage{RawMessage: raw, Valid: true}); err != nil {
+			return nil, nil, err
+		}
+		items[fmt.Sprint(row["id"])] = row
+	}
+	return items, settable, rows.Err()
+}
+
+// setColumns writes values to the columns of one item as they are, audit columns included
+func setColumns(ctx context.Context, exec sqlExecutor, dataTableName, itemID string, values map[string]interface{}) error {
+	setParts := make([]string, 0, len(values))
+	args := []interface{}{itemID}
+	for column, value := range values {
+		args = append(args, value)
+		setParts = append(setParts, fmt.Sprintf(`"%s" = $%d`, column, len(args)))
+	}
+
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE id = $1", dataTableName, strings.Join(setParts, ", "))
+	if _, err := exec.ExecContext(ctx, query, args...); err != nil {
+		return fmt.Errorf("failed to update item: %w", err)
+	}
+	return nil
+}
+
+// keepColumns returns the values of row whose columns are in columns
+func keepColumns(row map[string]interface{}, columns map[string]bool) map[string]interface{} {
+	kept := make(map[string]interface{}, len(row))
+	for column, value := range row {
+		if columns[column] {
+			kept[column] = value
+		}
+	}
+	return kept
+}
+
+// diffRow returns the values of the fields of target that differ from before: the old
+// values and the new ones. The ID is never part of the difference.
+func diffRow(before, target map[string]interface{}) (map[string]interface{}, map[string]interface{}) {
+	oldValues := make(map[string]interface{})
+	newValues := make(map[string]interface{})
+	for field, value := range target {
+		if field == "id" || sameValue(before[field], value) {
+			continue
+		}
+		oldValues[field] = before[field]
+		newValues[field] = value
+	}
+	return oldValues, newValues
+}
+
+// sameValue reports whether two values decoded from JSON are equal. Timestamps written
+// with different offsets or precision are equal when they name the same instant, and
+// numbers when they have the same value.
+func sameValue(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+	switch a := a.(type) {
+	case string:
+		b, ok := b.(string)
+		if !ok {
+			return false
+		}
+		at, errA := time.Parse(time.RFC3339Nano, a)
+		bt, errB := time.Parse(time.RFC3339Nano, b)
+		return errA == nil && errB == nil && at.Equal(bt)
+	case json.Number:
+		b, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, errA := a.Float64()
+		bf, errB := b.Float64()
+		return errA == nil && errB == nil && af == bf
+	}
+	return false
+}
+
+// itemIDs returns the keys of items in order
+func itemIDs(items map[string]map[string]interface{}) []string {
+	ids := make([]string, 0, len(items))
+	for id := range items {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
@@ -0,0 +1,143 @@
+package api
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func testRevision(t *testing.T, itemID uuid.UUID, action string, at time.Time, newData map[string]interface{}) sqlc.Revision {
+	t.Helper()
+	data, err := encodeRevisionData(newData)
+	require.NoError(t, err)
+	return sqlc.Revision{
+		ID:        uuid.New(),
+		ItemID:    itemID,
+		Action:    action,
+		NewData:   data,
+		CreatedAt: sql.NullTime{Time: at, Valid: true},
+	}
+}
+
+func TestReplayRevisions(t *testing.T) {
+	start := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+	kept, edited, removed, added := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	userID := uuid.New()
+
+	items := map[string]map[string]interface{}{
+		kept.String():    {"id": kept.String(), "name": "Kept", "updated_at": "2026-02-01T00:00:00Z"},
+		edited.String():  {"id": edited.String(), "name": "Before", "price": json.Number("10"), "updated_at": "2026-02-01T00:00:00Z", "updated_by": nil},
+		removed.String(): {"id": removed.String(), "name": "Removed"},
+	}
+
+	update := testRevision(t, edited, revisionUpdate, start.Add(time.Minute), map[string]interface{}{"name": "After"})
+	update.UserID = uuid.NullUUID{UUID: userID, Valid: true}
+	revisions := []sqlc.Revision{
+		update,
+		testRevision(t, removed, revisionDelete, start.Add(2*time.Minute), nil),
+		testRevision(t, added, revisionCreate, start.Add(3*time.Minute), map[string]interface{}{"name": "Added"}),
+		// Changes to items written without revisions are skipped
+		testRevision(t, uuid.New(), revisionUpdate, start.Add(4*time.Minute), map[string]interface{}{"name": "Imported"}),
+	}
+	require.NoError(t, replayRevisions(items, revisions, false))
+
+	require.Len(t, items, 3)
+	assert.Equal(t, "Kept", items[kept.String()]["name"])
+	assert.Equal(t, "2026-02-01T00:00:00Z", items[kept.String()]["updated_at"])
+	assert.NotContains(t, items[kept.String()], "updated_by")
+
+	assert.Equal(t, "After", items[edited.String()]["name"])
+	assert.Equal(t, json.Number("10"), items[edited.String()]["price"])
+	assert.Equal(t, "2026-03-01T02:01:00Z", items[edited.String()]["updated_at"])
+	assert.Equal(t, userID.String(), items[edited.String()]["updated_by"])
+
+	assert.NotContains(t, items, removed.String())
+	assert.Equal(t, map[string]interface{}{
+		"id":         added.String(),
+		"name":       "Added",
+		"created_at": "2026-03-01T02:03:00Z",
+		"created_by": nil,
+		"updated_at": "2026-03-01T02:03:00Z",
+		"updated_by": nil,
+	}, items[added.String()])
+
+	t.Run("Soft Delete", func(t *testing.T) {
+		items := map[string]map[string]interface{}{
+			kept.String():    {"id": kept.String(), "deleted_at": nil},
+			removed.String(): {"id": removed.String(), "deleted_at": "2026-02-01T00:00:00Z"},
+		}
+		revisions := []sqlc.Revision{
+			testRevision(t, kept, revisionDelete, start, nil),
+			testRevision(t, removed, revisionDelete, start, nil), // Purged from the trash
+		}
+		require.NoError(t, replayRevisions(items, revisions, true))
+		require.Len(t, items, 1)
+		assert.Equal(t, "2026-03-01T02:00:00Z", items[kept.String()]["deleted_at"])
+	})
+
+	t.Run("Unknown Action", func(t *testing.T) {
+		revision := testRevision(t, kept, "merge", start, nil)
+		assert.ErrorContains(t, replayRevisions(items, []sqlc.Revision{revision}, false), `unsupported revision action "merge"`)
+	})
+
+	t.Run("Invalid Data", func(t *testing.T) {
+		revision := testRevision(t, kept, revisionUpdate, start, nil)
+		revision.NewData = pqtype.NullRawMessage{RawMessage: []byte(`[1]`), Valid: true}
+		assert.Error(t, replayRevisions(items, []sqlc.Revision{revision}, false))
+	})
+}
+
+func TestParseRestorePoint(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	at, err := parseRestorePoint("2026-03-01T10:30:00+01:00", now)
+	require.NoError(t, err)
+	assert.True(t, at.Equal(time.Date(2026, 3, 1, 9, 30, 0, 0, time.UTC)))
+
+	_, err = parseRestorePoint("", now)
+	assert.ErrorContains(t, err, "at is required")
+	_, err = parseRestorePoint("yesterday", now)
+	assert.ErrorContains(t, err, "RFC 3339")
+	_, err = parseRestorePoint("2026-03-01T12:00:01Z", now)
+	assert.ErrorContains(t, err, "in the future")
+}
+
+func TestDiffRow(t *testing.T) {
+	before := map[string]interface{}{
+		"id":         "a",
+		"name":       "Old",
+		"price":      json.Number("10.50"),
+		"tags":       []interface{}{"x", "y"},
+		"updated_at": "2026-03-01T02:00:00.5+00:00",
+	}
+	target := map[string]interface{}{
+		"id":         "a",
+		"name":       "New",
+		"price":      json.Number("10.5"),
+		"tags":       []interface{}{"x", "y"},
+		"updated_at": "2026-03-01T02:00:00.500Z",
+		"note":       "Added",
+	}
+
+	oldValues, newValues := diffRow(before, target)
+	assert.Equal(t, map[string]interface{}{"name": "Old", "note": nil}, oldValues)
+	assert.Equal(t, map[string]interface{}{"name": "New", "note": "Added"}, newValues)
+
+	oldValues, newValues = diffRow(before, before)
+	assert.Empty(t, oldValues)
+	assert.Empty(t, newValues)
+}
+
+func TestKeepColumns(t *testing.T) {
+	row := map[string]interface{}{"id": "a", "name": "Kept", "tenant_id": "t", "retired": "Dropped"}
+	kept := keepColumns(row, map[string]bool{"id": true, "name": true, "created_at": true})
+	assert.Equal(t, map[string]interface{}{"id": "a", "name": "Kept"}, kept)
+}
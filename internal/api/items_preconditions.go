@@ -0,0 +1,95 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the preconditions of item updates (optimistic concurrency control).
+//
+// A client editing an item can say which state of the item its change is based on, either
+// with a `_version` field in the body (the item's updated_at as it was read) or with an
+// If-Unmodified-Since header (e.g. the Last-Modified of GET /items/:table/:id). The item is
+// locked and checked before it is written, so when someone else changed it in the
+// meantime the update is refused with 409 instead of silently overwriting their change.
+package api
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// versionField is the body field naming the item version an update is based on
+const versionField = "_version"
+
+// updatePrecondition is the state an item must still be in for an update to apply
+type updatePrecondition struct {
+	version         time.Time // Exact updated_at, from _version
+	hasVersion      bool
+	unmodifiedSince time.Time // From If-Unmodified-Since, to the second
+	hasUnmodified   bool
+}
+
+type updatePreconditionKey struct{}
+
+// parseUpdatePrecondition reads the precondition of an update from the _version field of
+// data, which it removes, and the If-Unmodified-Since header. It returns nil when the
+// request has neither.
+func parseUpdatePrecondition(r *http.Request, data map[string]interface{}) (*updatePrecondition, error) {
+	var precondition updatePrecondition
+
+	if raw, ok := data[versionField]; ok {
+		delete(data, versionField)
+		value, _ := raw.(string)
+		version, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, validationError("%s must be the item's updated_at timestamp", versionField)
+		}
+		precondition.version, precondition.hasVersion = version, true
+	}
+
+	if header := r.Header.Get("If-Unmodified-Since"); header != "" {
+		since, err := http.ParseTime(header)
+		if err != nil {
+			return nil, validationError("If-Unmodified-Since must be an HTTP date")
+		}
+		precondition.unmodifiedSince, precondition.hasUnmodified = since, true
+	}
+
+	if !precondition.hasVersion && !precondition.hasUnmodified {
+		return nil, nil
+	}
+	return &precondition, nil
+}
+
+// withUpdatePrecondition returns a context carrying precondition for the item updates
+// further down the call chain
+func withUpdatePrecondition(ctx context.Context, precondition *updatePrecondition) context.Context {
+	if precondition == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, updatePreconditionKey{}, precondition)
+}
+
+// checkUpdatePrecondition returns a conflict error when the precondition in ctx does not
+// hold for row, the item as it is about to be updated
+func checkUpdatePrecondition(ctx context.Context, row map[string]interface{}) error {
+	precondition, _ := ctx.Value(updatePreconditionKey{}).(*updatePrecondition)
+	if precondition == nil {
+		return nil
+	}
+
+	value, _ := row["updated_at"].(string)
+	updatedAt, err := time.Parse(time.RFC3339Nano, value)
+	if err != nil {
+		if precondition.hasVersion {
+			return conflictError("item has no version to compare %s with", versionField)
+		}
+		return nil
+	}
+
+	if precondition.hasVersion && !updatedAt.Equal(precondition.version) {
+		return conflictError("item was changed since version %s (now %s)",
+			precondition.version.Format(time.RFC3339Nano), updatedAt.Format(time.RFC3339Nano))
+	}
+	if precondition.hasUnmodified && updatedAt.Truncate(time.Second).After(precondition.unmodifiedSince) {
+		return conflictError("item was changed at %s, after %s",
+			updatedAt.Format(time.RFC3339Nano), precondition.unmodifiedSince.Format(http.TimeFormat))
+	}
+	return nil
+}
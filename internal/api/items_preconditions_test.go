@@ -0,0 +1,80 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseUpdatePrecondition(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPatch, "/items/products/1", nil)
+	data := map[string]interface{}{"price": 12}
+	precondition, err := parseUpdatePrecondition(req, data)
+	require.NoError(t, err)
+	assert.Nil(t, precondition, "no precondition sent")
+
+	data[versionField] = "2026-10-17T09:30:12.482913Z"
+	precondition, err = parseUpdatePrecondition(req, data)
+	require.NoError(t, err)
+	require.NotNil(t, precondition)
+	assert.True(t, precondition.hasVersion)
+	assert.NotContains(t, data, versionField, "_version is not written to the item")
+
+	req.Header.Set("If-Unmodified-Since", "Sat, 17 Oct 2026 09:30:12 GMT")
+	precondition, err = parseUpdatePrecondition(req, map[string]interface{}{})
+	require.NoError(t, err)
+	assert.True(t, precondition.hasUnmodified)
+
+	_, err = parseUpdatePrecondition(req, map[string]interface{}{versionField: 3})
+	assert.ErrorIs(t, err, ErrValidation)
+	req.Header.Set("If-Unmodified-Since", "yesterday")
+	_, err = parseUpdatePrecondition(req, map[string]interface{}{})
+	assert.ErrorIs(t, err, ErrValidation)
+}
+
+func TestCheckUpdatePrecondition(t *testing.T) {
+	// Rows are read with to_jsonb, which writes timestamps with an offset
+	row := map[string]interface{}{"id": "1", "updated_at": "2026-10-17T09:30:12.482913+00:00"}
+
+	tests := []struct {
+		name            string
+		version         string
+		unmodifiedSince string
+		wantConflict    bool
+	}{
+		{"Same Version", "2026-10-17T09:30:12.482913Z", "", false},
+		{"Same Version Other Zone", "2026-10-17T11:30:12.482913+02:00", "", false},
+		{"Changed Since Version", "2026-10-17T09:30:12.482912Z", "", true},
+		{"Unmodified Since Last-Modified", "", "Sat, 17 Oct 2026 09:30:12 GMT", false},
+		{"Unmodified Since Later", "", "Sat, 17 Oct 2026 10:00:00 GMT", false},
+		{"Modified Since", "", "Sat, 17 Oct 2026 09:30:11 GMT", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPatch, "/items/products/1", nil)
+			data := map[string]interface{}{}
+			if tt.version != "" {
+				data[versionField] = tt.version
+			}
+			if tt.unmodifiedSince != "" {
+				req.Header.Set("If-Unmodified-Since", tt.unmodifiedSince)
+			}
+			precondition, err := parseUpdatePrecondition(req, data)
+			require.NoError(t, err)
+
+			err = checkUpdatePrecondition(withUpdatePrecondition(context.Background(), precondition), row)
+			if tt.wantConflict {
+				assert.ErrorIs(t, err, ErrConflict)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+
+	assert.NoError(t, checkUpdatePrecondition(context.Background(), row), "no precondition")
+}
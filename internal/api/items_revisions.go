@@ -0,0 +1,317 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the item revision endpoints, which expose the change history recorded
+// for every create, update and delete of an item and allow a change to be rolled back.
+//
+// Revision Endpoints:
+// - GET  /items/:table/:id/revisions                      - List an item's revisions, newest first
+// - POST /items/:table/:id/revisions/:revision_id/revert  - Undo the change made by one revision
+//
+// Revisions are recorded for user collections and dynamic data tables. Schema tables
+// (collections, fields, users, ...) are not versioned.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// revertAction maps a revision action to the permission needed to undo it: reverting a
+// create deletes the item, reverting a delete re-creates it.
+var revertAction = map[string]string{
+	revisionCreate: "delete",
+	revisionUpdate: "update",
+	revisionDelete: "create",
+}
+
+// GetItemRevisions handles GET /items/:table/:id/revisions requests.
+//
+// Returns the item's change history, newest first. Old and new values are limited to the
+// fields the caller may read.
+//
+// Response Format:
+//   - 200: Success with the revisions
+//   - 400: Invalid table name or item ID, or a schema table
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks permission to read this table
+//
+// @Summary      List item revisions
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  List the recorded changes (action, old and new values, user and time) of an item, newest first. Requires read permission on the table.
+// @Param        table   path   string true  "Table name"
+// @Param        id      path   string true  "Item ID"
+// @Param        limit   query  int    false "Limit (max 500, default 50)"
+// @Param        offset  query  int    false "Offset for pagination"
+// @Produce      json
+// @Success      200 {object} models.ItemsListResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/{table}/{id}/revisions [get]
+func (h *ItemsHandler) GetItemRevisions(c *gin.Context) {
+	tableName := c.Param("table")
+	itemID, ok := h.parseRevisionRequest(c, tableName)
+	if !ok {
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	// Get tenant context from the request
+	tenantID, _ := middleware.GetTenantID(c)
+
+	// Create a context with tenant information
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, allowedFields, rowFilter, transforms, err := h.policyChecker.CheckPermissionWithTransforms(ctxWithTenant, userID, tableName, "read")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+
+	// Under row-level rules the history is only shown for items the caller can read
+	if rowFilter != nil {
+		withRowFilter(c, tableName, rowFilter)
+		if _, err := h.dynamicHandlers.GetDynamicItem(c.Request.Context(), userID, tableName, itemID.String(), true); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+	}
+
+	userTenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+
+	page, err := parsePagination(c, nil, nil)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revisions, err := h.db.Queries.ListItemRevisions(c.Request.Context(), sqlc.ListItemRevisionsParams{
+		TenantID:   userTenantID,
+		Collection: tableName,
+		ItemID:     itemID,
+		Limit:      int32(page.Limit),
+		Offset:     int32(page.Offset),
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revisions"})
+		return
+	}
+
+	results := make([]map[string]interface{}, len(revisions))
+	for i, revision := range revisions {
+		results[i] = h.revisionToMap(tableName, revision, allowedFields, transforms)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": results,
+		"meta": gin.H{
+			"table": tableName,
+			"id":    itemID,
+			"count": len(results),
+		},
+	})
+}
+
+// RevertItemRevision handles POST /items/:table/:id/revisions/:revision_id/revert requests.
+//
+// Undoes the change recorded in the revision: a created item is deleted, an update has its
+// previous values written back, and a deleted item is restored. The permission required is
+// the one for the undoing write (delete, update or create respectively). The revert is
+// recorded as a new revision and publishes the usual item events.
+//
+// Response Format:
+//   - 200: Change reverted
+//   - 400: Invalid table name, item ID or revision ID
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks the permission needed to undo the change
+//   - 404: Revision or item not found
+//   - 409: A deleted item cannot be re-created because its ID is in use
+//
+// @Summary      Revert an item revision
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Undo the change recorded in a revision. Reverting a create deletes the item, reverting an update restores the previous values and reverting a delete brings the item back.
+// @Param        table        path  string true "Table name"
+// @Param        id           path  string true "Item ID"
+// @Param        revision_id  path  string true "Revision ID"
+// @Produce      json
+// @Success      200 {object} models.DeleteItemResponse
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Router       /items/{table}/{id}/revisions/{revision_id}/revert [post]
+func (h *ItemsHandler) RevertItemRevision(c *gin.Context) {
+	tableName := c.Param("table")
+	itemID, ok := h.parseRevisionRequest(c, tableName)
+	if !ok {
+		return
+	}
+
+	revisionID, err := uuid.Parse(c.Param("revision_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid revision ID"})
+		return
+	}
+
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	userTenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+
+	// The revision must belong to this item; other tenants' revisions look like missing ones
+	revision, err := h.db.Queries.GetRevisionByID(c.Request.Context(), revisionID)
+	if err == sql.ErrNoRows || (err == nil && (revision.TenantID != userTenantID || revision.Collection != tableName || revision.ItemID != itemID)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch revision"})
+		return
+	}
+
+	action, supported := revertAction[revision.Action]
+	if !supported {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revision cannot be reverted"})
+		return
+	}
+
+	// Get tenant context from the request
+	tenantID, _ := middleware.GetTenantID(c)
+
+	// Create a context with tenant information
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, allowedFields, rowFilter, err := h.policyChecker.CheckPermissionWithFilter(ctxWithTenant, userID, tableName, action)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return
+	}
+
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return
+	}
+	withRowFilter(c, tableName, rowFilter)
+
+	// Writing back old values is subject to the same field restrictions as an update
+	if revision.Action == revisionUpdate && !Contains(allowedFields, "*") {
+		oldValues, err := decodeRevisionData(revision.OldData)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read revision"})
+			return
+		}
+		for field := range oldValues {
+			if field != "updated_at" && field != "updated_by" && !Contains(allowedFields, field) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions for field '" + field + "'"})
+				return
+			}
+		}
+	}
+
+	err = h.dynamicHandlers.RevertDynamicItem(c.Request.Context(), userID, tableName, revision)
+	if err != nil {
+		respondError(c, err, "Failed to revert revision")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"meta": gin.H{"table": tableName, "id": itemID, "revision": revisionID, "reverted": true},
+	})
+}
+
+// parseRevisionRequest validates the table and item ID of a revision request and rejects
+// schema tables, which are not versioned
+func (h *ItemsHandler) parseRevisionRequest(c *gin.Context, tableName string) (uuid.UUID, bool) {
+	if !rbac.ValidateTableName(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid table name"})
+		return uuid.Nil, false
+	}
+
+	itemID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid item ID"})
+		return uuid.Nil, false
+	}
+
+	if h.isSchemaTable(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Revisions are not recorded for schema tables"})
+		return uuid.Nil, false
+	}
+
+	return itemID, true
+}
+
+// revisionToMap converts a revision to its API representation with the old and new values
+// limited to allowedFields and redacted by transforms
+func (h *ItemsHandler) revisionToMap(tableName string, revision sqlc.Revision, allowedFields []string, transforms rbac.FieldTransforms) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":         revision.ID,
+		"collection": revision.Collection,
+		"item":       revision.ItemID,
+		"action":     revision.Action,
+		"user_id":    nil,
+		"created_at": revision.CreatedAt.Time,
+	}
+	if revision.UserID.Valid {
+		result["user_id"] = revision.UserID.UUID
+	}
+
+	result["old_data"] = h.filterRevisionData(tableName, revision.OldData, allowedFields, transforms)
+	result["new_data"] = h.filterRevisionData(tableName, revision.NewData, allowedFields, transforms)
+
+	return result
+}
+
+// filterRevisionData decodes one side of a revision and applies field permissions and
+// secret redaction to it; missing data is returned as nil
+func (h *ItemsHandler) filterRevisionData(tableName string, data pqtype.NullRawMessage, allowedFields []string, transforms rbac.FieldTransforms) map[string]interface{} {
+	if !data.Valid {
+		return nil
+	}
+
+	values, err := decodeRevisionData(data)
+	if err != nil {
+		return nil
+	}
+
+	filtered := make(map[string]interface{}, len(values))
+	for field, value := range h.policyChecker.FilterFields(values, allowedFields, transforms) {
+		filtered[field] = value
+	}
+	redactSecrets(tableName, filtered)
+	return filtered
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemsHandler_RevisionRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &ItemsHandler{}
+	router := gin.New()
+	router.GET("/items/:table/:id/revisions", handler.GetItemRevisions)
+	router.POST("/items/:table/:id/revisions/:revision_id/revert", handler.RevertItemRevision)
+
+	itemID := "6e68062f-c4c6-42df-9e01-e2d1081664f4"
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		want   int
+	}{
+		{"Invalid Table Name", "GET", "/items/bad-name!/" + itemID + "/revisions", http.StatusBadRequest},
+		{"Invalid Item ID", "GET", "/items/products/not-a-uuid/revisions", http.StatusBadRequest},
+		{"Schema Table", "GET", "/items/collections/" + itemID + "/revisions", http.StatusBadRequest},
+		{"Unauthenticated", "GET", "/items/products/" + itemID + "/revisions", http.StatusUnauthorized},
+		{"Invalid Revision ID", "POST", "/items/products/" + itemID + "/revisions/nope/revert", http.StatusBadRequest},
+		{"Unauthenticated Revert", "POST", "/items/products/" + itemID + "/revisions/" + itemID + "/revert", http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+			assert.Equal(t, tt.want, w.Code)
+		})
+	}
+}
+
+func TestRevisionDataRoundTrip(t *testing.T) {
+	data := map[string]interface{}{"count": int64(9007199254740993), "name": "Widget", "tags": nil}
+
+	encoded, err := encodeRevisionData(data)
+	require.NoError(t, err)
+	assert.True(t, encoded.Valid)
+
+	decoded, err := decodeRevisionData(encoded)
+	require.NoError(t, err)
+	assert.Equal(t, json.Number("9007199254740993"), decoded["count"])
+	assert.Equal(t, "Widget", decoded["name"])
+	assert.Contains(t, decoded, "tags")
+
+	empty, err := encodeRevisionData(nil)
+	require.NoError(t, err)
+	assert.False(t, empty.Valid)
+
+	_, err = decodeRevisionData(pqtype.NullRawMessage{})
+	assert.Error(t, err)
+}
+
+func TestEncodeJSONValues(t *testing.T) {
+	values := encodeJSONValues(map[string]interface{}{
+		"meta":  map[string]interface{}{"a": json.Number("1")},
+		"tags":  []interface{}{"x"},
+		"title": "Hello",
+	})
+
+	assert.Equal(t, `{"a":1}`, values["meta"])
+	assert.Equal(t, `["x"]`, values["tags"])
+	assert.Equal(t, "Hello", values["title"])
+}
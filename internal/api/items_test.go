@@ -0,0 +1,90 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/dbtest"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleSchemaTableUpdate_ErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := dbtest.SQLite(t)
+	handler := NewItemsHandler(database, &config.Config{}, nil)
+	adminID := uuid.MustParse("38eae290-37b8-46a7-82ee-ae842d85c894")
+	tenantID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+
+	collection, err := database.Queries.CreateCollection(context.Background(), sqlc.CreateCollectionParams{
+		ID:         uuid.New(),
+		Name:       "posts",
+		Slug:       "posts",
+		TenantID:   uuid.NullUUID{UUID: tenantID, Valid: true},
+		UserStamps: true,
+	})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		itemID string
+		data   map[string]interface{}
+		status int
+		code   string
+	}{
+		{"user_stamps cannot change", collection.ID.String(), map[string]interface{}{"user_stamps": false}, http.StatusUnprocessableEntity, CodeValidation},
+		{"invalid ID", "not-a-uuid", map[string]interface{}{}, http.StatusUnprocessableEntity, CodeValidation},
+		{"missing collection", uuid.NewString(), map[string]interface{}{}, http.StatusNotFound, CodeNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			c, _ := gin.CreateTestContext(w)
+			c.Request = httptest.NewRequest(http.MethodPatch, "/items/collections/"+tt.itemID, nil)
+
+			handler.handleSchemaTableUpdate(c, "collections", adminID, tt.itemID, tt.data)
+
+			assert.Equal(t, tt.status, w.Code)
+			var body map[string]interface{}
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.code, body["code"])
+		})
+	}
+}
+
+func TestHandleSchemaTableCreate_UnstampedCollectionWithOwnScope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := dbtest.SQLite(t)
+	handler := NewItemsHandler(database, &config.Config{}, nil)
+	adminID := uuid.MustParse("38eae290-37b8-46a7-82ee-ae842d85c894")
+	tenantID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+
+	// Left behind by a deleted collection of the same slug
+	_, err := database.Queries.CreatePermission(context.Background(), sqlc.CreatePermissionParams{
+		ID:        uuid.New(),
+		RoleID:    uuid.NullUUID{UUID: uuid.MustParse("550e8400-e29b-41d4-a716-446655440002"), Valid: true},
+		TableName: "notes",
+		Action:    "update",
+		TenantID:  uuid.NullUUID{UUID: tenantID, Valid: true},
+		Scope:     rbac.ScopeOwn,
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/items/collections", nil)
+
+	handler.handleSchemaTableCreate(c, "collections", adminID, map[string]interface{}{"name": "notes", "user_stamps": false})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.Contains(t, w.Body.String(), "scope own")
+}
@@ -0,0 +1,228 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the upsert endpoints, which insert or update items keyed by a unique
+// field so sync integrations do not need to read before they write.
+//
+// Upsert Endpoints:
+// - POST /items/:table/upsert?on=sku - Upsert many items in one transaction (body: array of items)
+// - POST /items/:table?upsert=sku    - Upsert a single item (body: one item)
+//
+// The field named by on/upsert must have a unique constraint (is_unique on collection
+// fields) and every item must set it. An item whose value matches an existing item
+// updates it, changing only the given fields; any other item is created. Upserts need
+// both the create and the update permission, and only fields writable under both are kept.
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Upsert actions reported for each item
+const (
+	upsertCreated = "created"
+	upsertUpdated = "updated"
+)
+
+// UpsertItems handles POST /items/:table/upsert requests.
+//
+// Response Format:
+//   - 200: Each item with its ID and whether it was created or updated
+//   - 400: Invalid table name, field or body
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks permission to create or update in this table
+//   - 422: The field is not unique, or validation errors
+//
+// @Summary      Upsert items
+// @Tags         items
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Insert or update many items in one transaction, matched on a unique field. Items whose field value matches an existing item update it (only the given fields change); other items are created. Requires both create and update permission.
+// @Param        table   path      string true  "Table name (e.g., 'products', 'customers')"
+// @Param        on      query     string true  "Unique field to match items on (e.g., 'sku')"
+// @Param        body    body      []map[string]interface{} true "Items to upsert, each with the unique field set"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /items/{table}/upsert [post]
+func (h *ItemsHandler) UpsertItems(c *gin.Context) {
+	tableName := c.Param("table")
+	key := c.Query("on")
+
+	userID, allowedFields, ok := h.authorizeUpsert(c, tableName, key)
+	if !ok {
+		return
+	}
+
+	var items []map[string]interface{}
+	if err := decodeJSON(c, &items, BodyItems); err != nil {
+		respondBodyError(c, err, "Invalid request body: expected an array of items")
+		return
+	}
+	if !validateBulkSize(c, len(items)) {
+		return
+	}
+
+	results, ok := h.upsertItems(c, userID, tableName, key, items, allowedFields)
+	if !ok {
+		return
+	}
+
+	data := make([]gin.H, len(results))
+	created := 0
+	for i, result := range results {
+		data[i] = gin.H{"id": result.ID, "action": upsertAction(result), "item": withID(result.Data, result.ID)}
+		if result.Created {
+			created++
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": data,
+		"meta": gin.H{"table": tableName, "on": key, "count": len(results), "created": created, "updated": len(results) - created},
+	})
+}
+
+// upsertItem handles POST /items/:table?upsert=<field> requests, which upsert a single
+// item. It answers 201 when the item was created and 200 when it was updated.
+func (h *ItemsHandler) upsertItem(c *gin.Context) {
+	tableName := c.Param("table")
+	key := c.Query("upsert")
+
+	userID, allowedFields, ok := h.authorizeUpsert(c, tableName, key)
+	if !ok {
+		return
+	}
+
+	var item map[string]interface{}
+	if err := decodeJSON(c, &item, BodyItems); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+
+	results, ok := h.upsertItems(c, userID, tableName, key, []map[string]interface{}{item}, allowedFields)
+	if !ok {
+		return
+	}
+
+	status := http.StatusOK
+	if results[0].Created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{
+		"data": withID(results[0].Data, results[0].ID),
+		"meta": gin.H{"table": tableName, "on": key, "action": upsertAction(results[0])},
+	})
+}
+
+// authorizeUpsert checks an upsert request: the table and field names, and the create and
+// update permissions. It returns the fields writable under both. On failure the error
+// response has already been written.
+func (h *ItemsHandler) authorizeUpsert(c *gin.Context, tableName, key string) (uuid.UUID, []string, bool) {
+	if h.isSchemaTable(tableName) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Upserts are not supported for schema tables"})
+		return uuid.Nil, nil, false
+	}
+	if !columnNamePattern.MatchString(key) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid unique field to match items on is required"})
+		return uuid.Nil, nil, false
+	}
+
+	// Existing items are updated within the caller's update rules
+	userID, updateFields, ok := h.authorizeBulkRequest(c, tableName, "update")
+	if !ok {
+		return uuid.Nil, nil, false
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	hasPermission, createFields, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, tableName, "create")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return uuid.Nil, nil, false
+	}
+	if !hasPermission {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+		return uuid.Nil, nil, false
+	}
+
+	allowedFields := commonFields(createFields, updateFields)
+	if allowedFields != nil && !Contains(allowedFields, key) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Field '" + key + "' cannot be written"})
+		return uuid.Nil, nil, false
+	}
+	return userID, allowedFields, true
+}
+
+// upsertItems filters items to allowedFields and upserts them. On failure the error
+// response has already been written.
+func (h *ItemsHandler) upsertItems(c *gin.Context, userID uuid.UUID, tableName, key string, items []map[string]interface{}, allowedFields []string) ([]upsertResult, bool) {
+	filteredItems := make([]map[string]interface{}, len(items))
+	for i, item := range items {
+		filteredItems[i] = h.policyChecker.FilterFields(item, allowedFields, nil)
+	}
+
+	var (
+		results []upsertResult
+		err     error
+	)
+	if h.isUserCollection(c.Request.Context(), userID, tableName) {
+		results, err = h.collectionsHandler.UpsertCollectionItems(c.Request.Context(), userID, tableName, key, filteredItems)
+	} else {
+		results, err = h.dynamicHandlers.UpsertDynamicItems(c.Request.Context(), userID, tableName, key, filteredItems, nil)
+	}
+	if err != nil {
+		respondError(c, err, "Failed to upsert items")
+		return nil, false
+	}
+
+	// The values written are reported like a read: encrypted fields decrypted or masked
+	written := make([]map[string]interface{}, len(results))
+	for i := range results {
+		written[i] = results[i].Data
+	}
+	if err := h.revealEncrypted(c.Request.Context(), userID, tableName, written); err != nil {
+		respondError(c, err, "Failed to upsert items")
+		return nil, false
+	}
+	return results, true
+}
+
+// upsertAction names what an upsert did with an item
+func upsertAction(result upsertResult) string {
+	if result.Created {
+		return upsertCreated
+	}
+	return upsertUpdated
+}
+
+// commonFields returns the fields allowed by both permissions. nil means every field, as
+// does "*" (or no list) in either input.
+func commonFields(a, b []string) []string {
+	allA := len(a) == 0 || Contains(a, "*")
+	allB := len(b) == 0 || Contains(b, "*")
+	switch {
+	case allA && allB:
+		return nil
+	case allA:
+		return b
+	case allB:
+		return a
+	}
+
+	common := []string{}
+	for _, field := range a {
+		if Contains(b, field) {
+			common = append(common, field)
+		}
+	}
+	return common
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestItemsHandler_UpsertRequestValidation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	handler := &ItemsHandler{}
+	router := gin.New()
+	router.POST("/items/:table", handler.CreateItem)
+	router.POST("/items/:table/upsert", handler.UpsertItems)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"Schema Tables Rejected", "/items/users/upsert?on=email", http.StatusBadRequest, "schema tables"},
+		{"Missing Field", "/items/products/upsert", http.StatusBadRequest, "unique field"},
+		{"Invalid Field", "/items/products/upsert?on=sku;drop", http.StatusBadRequest, "unique field"},
+		{"Unauthenticated", "/items/products/upsert?on=sku", http.StatusUnauthorized, ""},
+		{"Single Item Unauthenticated", "/items/products?upsert=sku", http.StatusUnauthorized, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("POST", tt.path, bytes.NewBufferString(`[{"sku":"A-1"}]`))
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantCode, w.Code)
+			assert.Contains(t, w.Body.String(), tt.wantBody)
+		})
+	}
+}
+
+func TestCommonFields(t *testing.T) {
+	assert.Nil(t, commonFields(nil, []string{"*"}))
+	assert.Equal(t, []string{"name", "sku"}, commonFields([]string{"*"}, []string{"name", "sku"}))
+	assert.Equal(t, []string{"sku"}, commonFields([]string{"name", "sku"}, []string{"sku", "price"}))
+	assert.Equal(t, []string{}, commonFields([]string{"name"}, []string{"price"}))
+}
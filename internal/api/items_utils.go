@@ -6,13 +6,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
 	"go-rbac-api/internal/db"
 	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
 
 	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
 )
 
 // ItemsUtils provides utility functions for database operations, data conversion,
@@ -27,7 +30,9 @@ import (
 // All methods are designed to be thread-safe and can be used concurrently
 // across multiple HTTP requests.
 type ItemsUtils struct {
-	db *db.DB // Database connection pool for executing queries
+	db     *db.DB         // Database connection pool for executing queries
+	tx     *db.Tx         // Transaction the utils run on instead, when bound with withTx
+	tables *TableResolver // Finds the data tables of collections
 }
 
 // NewItemsUtils creates a new ItemsUtils instance with the provided database connection.
@@ -43,7 +48,52 @@ type ItemsUtils struct {
 //	utils := NewItemsUtils(dbConnection)
 //	results := utils.ScanRowsToMaps(rows)
 func NewItemsUtils(db *db.DB) *ItemsUtils {
-	return &ItemsUtils{db: db}
+	utils := &ItemsUtils{db: db}
+	utils.tables = NewTableResolver(utils)
+	return utils
+}
+
+// withTx returns utils whose queries and statements run on tx, so schema changes made
+// through them commit or roll back with the rest of the transaction
+func (u *ItemsUtils) withTx(tx *db.Tx) *ItemsUtils {
+	utils := &ItemsUtils{db: u.db, tx: tx}
+	utils.tables = NewTableResolver(utils)
+	return utils
+}
+
+// exec returns where the utils run statements: their transaction, or the pool
+func (u *ItemsUtils) exec() db.Executor {
+	if u.tx != nil {
+		return u.tx
+	}
+	return u.db
+}
+
+// dialect returns the dialect of the utils' database
+func (u *ItemsUtils) dialect() db.Dialect {
+	if u.db == nil {
+		return db.Postgres
+	}
+	return u.db.Dialect()
+}
+
+// queries returns the generated queries, bound to the utils' transaction if they have one
+func (u *ItemsUtils) queries() *sqlc.Queries {
+	if u.tx != nil {
+		return u.tx.Queries
+	}
+	return u.db.Queries
+}
+
+// transaction runs fn with utils bound to a transaction: the utils' own when they have
+// one, otherwise a new one that commits when fn succeeds
+func (u *ItemsUtils) transaction(ctx context.Context, fn func(utils *ItemsUtils) error) error {
+	if u.tx != nil {
+		return fn(u)
+	}
+	return u.db.InTransaction(ctx, func(tx *db.Tx) error {
+		return fn(u.withTx(tx))
+	})
 }
 
 // ScanRowsToMaps converts SQL result rows into a slice of string-keyed maps for JSON serialization.
@@ -51,6 +101,7 @@ func NewItemsUtils(db *db.DB) *ItemsUtils {
 // This method handles the complex task of converting database rows with unknown column types
 // into Go maps that can be easily serialized to JSON. It properly handles:
 // - JSON/JSONB columns (unmarshals to native Go types)
+// - Array columns (converts to slices)
 // - NULL values (converts to Go nil)
 // - Binary data (converts to strings when not valid JSON)
 // - All standard SQL types
@@ -78,52 +129,53 @@ func (u *ItemsUtils) ScanRowsToMaps(rows *sql.Rows) []map[string]interface{} {
 
 	var results []map[string]interface{}
 	for rows.Next() {
-		// Create a slice of interface{} to hold the values
-		values := make([]interface{}, len(columns))
-		valuePtrs := make([]interface{}, len(columns))
-		for i := range values {
-			valuePtrs[i] = &values[i]
-		}
-
-		// Scan the row
-		if err := rows.Scan(valuePtrs...); err != nil {
+		row, err := scanRowToMap(rows, columns)
+		if err != nil {
 			continue
 		}
-
-		// Convert to map
-		row := make(map[string]interface{})
-		for i, col := range columns {
-			val := values[i]
-			if val != nil {
-				// Handle specific types
-				switch v := val.(type) {
-				case []byte:
-					// Try to unmarshal as JSON, fallback to string
-					var jsonVal interface{}
-					if err := json.Unmarshal(v, &jsonVal); err == nil {
-						row[col] = jsonVal
-					} else {
-						row[col] = string(v)
-					}
-				default:
-					row[col] = v
-				}
-			} else {
-				row[col] = nil
-			}
-		}
-
 		results = append(results, row)
 	}
 
 	return results
 }
 
+// scanRowToMap scans the current row into a map keyed by column name, converting values
+// the way ScanRowsToMaps does
+func scanRowToMap(rows *sql.Rows, columns []string) (map[string]interface{}, error) {
+	// Create a slice of interface{} to hold the values
+	values := make([]interface{}, len(columns))
+	valuePtrs := make([]interface{}, len(columns))
+	for i := range values {
+		valuePtrs[i] = &values[i]
+	}
+
+	// Scan the row
+	if err := rows.Scan(valuePtrs...); err != nil {
+		return nil, err
+	}
+	columnTypes, err := rows.ColumnTypes()
+	if err != nil {
+		return nil, err
+	}
+
+	// Convert to map
+	row := make(map[string]interface{})
+	for i, col := range columns {
+		// Columns of deleted fields are kept out of every read
+		if strings.HasPrefix(col, archivedColumnPrefix) {
+			continue
+		}
+		// Arrays become slices and JSON its value, falling back to strings
+		row[col] = decodeColumnValue(columnTypes[i].DatabaseTypeName(), values[i])
+	}
+	return row, nil
+}
+
 // TableExists checks whether a specified table exists in the database.
 //
 // This method supports both simple table names and schema-qualified table names.
-// It uses the PostgreSQL information_schema to safely check table existence
-// without risking SQL injection attacks.
+// It asks the database's catalog (information_schema in PostgreSQL) to safely check
+// table existence without risking SQL injection attacks.
 //
 // Parameters:
 //   - tableName: Table name to check. Can be "table_name" or "schema.table_name"
@@ -173,23 +225,16 @@ func (u *ItemsUtils) TableExists(tableName string) (bool, error) {
 		}
 	}
 
-	query := `
-		SELECT EXISTS (
-			SELECT FROM information_schema.tables 
-			WHERE table_schema = $1
-			AND table_name = $2
-		)
-	`
-	var exists bool
-	err := u.db.QueryRow(query, schemaName, actualTableName).Scan(&exists)
-	return exists, err
+	return u.dialect().TableExists(context.Background(), u.db, schemaName, actualTableName)
 }
 
 // GetUserTenantID retrieves the tenant ID associated with a specific user.
 //
-// In Basin's multi-tenant architecture, each user belongs to exactly one tenant.
-// This method is essential for enforcing tenant isolation and ensuring users
-// can only access data within their own tenant's scope.
+// Users have a home tenant and may be members of others. Requests act in the tenant of
+// their token, or the one named by X-Tenant (see middleware.ActingTenant), and that tenant
+// is returned; otherwise, as for API keys, the user's home tenant is. This method is
+// essential for enforcing tenant isolation and ensuring users can only access data within
+// the tenant they act in.
 //
 // Parameters:
 //   - ctx: Request context for cancellation and timeout handling
@@ -206,15 +251,32 @@ func (u *ItemsUtils) TableExists(tableName string) (bool, error) {
 //	    return fmt.Errorf("user not found or no tenant assigned: %w", err)
 //	}
 func (u *ItemsUtils) GetUserTenantID(ctx context.Context, userID uuid.UUID) (uuid.UUID, error) {
+	if tenantID, ok := ctx.Value(tenantOverrideKey{}).(uuid.UUID); ok {
+		return tenantID, nil
+	}
+	if tenantID, ok := middleware.ActingTenant(ctx); ok {
+		return tenantID, nil
+	}
+
 	query := `SELECT tenant_id FROM users WHERE id = $1`
 	var tenantID uuid.UUID
-	err := u.db.QueryRowContext(ctx, query, userID).Scan(&tenantID)
+	err := u.exec().QueryRowContext(ctx, query, userID).Scan(&tenantID)
 	if err != nil {
 		return uuid.Nil, err
 	}
 	return tenantID, nil
 }
 
+// tenantOverrideKey is the context key of the tenant set with WithTenant
+type tenantOverrideKey struct{}
+
+// WithTenant returns a context in which GetUserTenantID resolves every user to tenantID,
+// so schema and item operations write to that tenant instead of the acting user's home
+// tenant (as when importing into a new tenant)
+func WithTenant(ctx context.Context, tenantID uuid.UUID) context.Context {
+	return context.WithValue(ctx, tenantOverrideKey{}, tenantID)
+}
+
 // GetTenantSchema retrieves the database schema name for a specific tenant.
 //
 // Each tenant in Basin has its own PostgreSQL schema to isolate their data tables.
@@ -234,72 +296,196 @@ func (u *ItemsUtils) GetUserTenantID(ctx context.Context, userID uuid.UUID) (uui
 //	schema, err := utils.GetTenantSchema(ctx, tenantUUID)
 //	tableName := fmt.Sprintf("%s.data_products", schema) // "tenant_abc.data_products"
 func (u *ItemsUtils) GetTenantSchema(ctx context.Context, tenantID uuid.UUID) (string, error) {
+	// Inside a transaction the tenant may not be committed yet, so the cache is bypassed
+	if u.tx == nil {
+		if schema, ok := defaultSchemaCache.TenantSchema(tenantID); ok {
+			return schema, nil
+		}
+	}
+
+	generation := defaultSchemaCache.Generation()
 	query := `SELECT slug FROM tenants WHERE id = $1`
 	var schema string
-	err := u.db.QueryRowContext(ctx, query, tenantID).Scan(&schema)
+	err := u.exec().QueryRowContext(ctx, query, tenantID).Scan(&schema)
 	if err != nil {
 		return "main", err // Fallback to main schema
 	}
+	if u.tx == nil {
+		defaultSchemaCache.SetTenantSchema(generation, tenantID, schema)
+	}
 	return schema, nil
 }
 
-// addColumnToDataTable adds a column to a data table when a field is created
-func (u *ItemsUtils) AddColumnToDataTable(ctx context.Context, tenantID uuid.UUID, collectionName string, field sqlc.Field) error {
-	// Get tenant schema
-	tenantSchema, err := u.GetTenantSchema(ctx, tenantID)
-	if err != nil {
-		return err
+// CreateDataTable creates the data table of a new collection on databases that do not
+// create it themselves (see db.Dialect.CreatesDataTables), with the columns and indexes
+// the trigger of the base schema gives it in Postgres, and records its name
+func (u *ItemsUtils) CreateDataTable(ctx context.Context, collection *sqlc.Collection) error {
+	dialect := u.dialect()
+	if dialect.CreatesDataTables() {
+		return nil
 	}
 
-	// For table existence check, use unquoted schema name
-	unquotedTableName := tenantSchema + ".data_" + collectionName
-	// For ALTER TABLE, use quoted schema name
-	quotedTableName := "\"" + tenantSchema + "\".data_" + collectionName
+	tenantID := collection.TenantID.UUID
+	name := collection.Slug + "-data-" + tenantID.String()
+	table := DataTable{Schema: dataSchema, Name: name, Layout: LayoutDataSchema, dialect: dialect}
+	statements := []string{
+		fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+			id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+			created_by UUID,
+			updated_by UUID,
+			created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+			tenant_id UUID DEFAULT '%s'
+		)`, table, tenantID),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_%s_tenant_id" ON %s (tenant_id)`, name, table),
+		fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_%s_created_at" ON %s (created_at)`, name, table),
+	}
+	for _, statement := range statements {
+		if _, err := u.exec().ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to create data table: %w", err)
+		}
+	}
 
-	// Check if table exists
-	tableExists, err := u.TableExists(unquotedTableName)
+	if _, err := u.exec().ExecContext(ctx, `UPDATE collections SET data_table_name = $1 WHERE id = $2`, name, collection.ID); err != nil {
+		return fmt.Errorf("failed to record data table: %w", err)
+	}
+	collection.DataTableName = name
+	return nil
+}
+
+// addColumnToDataTable adds a column to a data table when a field is created, along with
+// the UNIQUE constraint and index the field asks for
+func (u *ItemsUtils) AddColumnToDataTable(ctx context.Context, tenantID uuid.UUID, collectionSlug string, field sqlc.Field) error {
+	table, err := u.ResolveDataTable(ctx, tenantID, collectionSlug)
 	if err != nil {
 		return err
 	}
+	quotedTableName := table.String()
+
+	// Build the ALTER TABLE query
+	alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN "%s" %s`, quotedTableName, field.Name, u.dialect().ColumnType(dataColumnType(field.Type)))
+
+	if field.Type == "computed" {
+		// Computed fields are generated columns, which take no constraint or default of their own
+		definition, err := u.ComputedColumnDefinition(ctx, field)
+		if err != nil {
+			return err
+		}
+		alterQuery = fmt.Sprintf(`ALTER TABLE %s ADD COLUMN "%s" %s`, quotedTableName, field.Name, definition)
+	} else {
+		// Add NOT NULL constraint if required
+		if field.IsRequired.Bool {
+			alterQuery += " NOT NULL"
+		}
 
-	if !tableExists {
-		return fmt.Errorf("data table %s does not exist", unquotedTableName)
+		// Add default value if provided
+		if field.DefaultValue.Valid && field.DefaultValue.String != "" {
+			alterQuery += fmt.Sprintf(" DEFAULT '%s'", field.DefaultValue.String)
+		}
 	}
 
-	// Build ALTER TABLE statement
-	var columnType string
-	switch field.Type {
+	// Add the constraint and index of unique and indexed fields
+	indexes := planFieldIndexes(table.Schema, quotedTableName, field.ID, field.Name, field.Type,
+		false, field.IsUnique.Bool, false, field.IsIndexed, false)
+
+	// Execute the statements together so a failing index leaves no column behind
+	return u.transaction(ctx, func(utils *ItemsUtils) error {
+		for _, statement := range append([]string{alterQuery}, indexes.create...) {
+			if _, err := utils.tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to add column to data table: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// ComputedColumnDefinition returns the generated column definition of a computed field,
+// compiled against the other fields of its collection
+func (u *ItemsUtils) ComputedColumnDefinition(ctx context.Context, field sqlc.Field) (string, error) {
+	cfg, err := decodeComputedConfig(field.ComputedConfig)
+	if err != nil {
+		return "", err
+	}
+	siblings, err := u.queries().GetFieldsByCollection(ctx, field.CollectionID)
+	if err != nil {
+		return "", err
+	}
+	return cfg.columnDefinition(computedColumns(siblings, field.Name))
+}
+
+// dataColumnType returns the column definition that stores a field type in a data table
+func dataColumnType(fieldType string) string {
+	switch fieldType {
 	case "text":
-		columnType = "TEXT"
+		return "TEXT"
 	case "number":
-		columnType = "NUMERIC"
+		return "NUMERIC"
 	case "boolean":
-		columnType = "BOOLEAN"
+		return "BOOLEAN"
 	case "date":
-		columnType = "DATE"
+		return "DATE"
 	case "datetime":
-		columnType = "TIMESTAMP WITH TIME ZONE"
+		return "TIMESTAMP WITH TIME ZONE"
+	case "uuid", "relation":
+		// Many-to-one relations store the related item's ID
+		return "UUID"
+	case "file":
+		// File fields store an asset ID; the reference is cleared when the asset is deleted
+		return "UUID REFERENCES public.assets(id) ON DELETE SET NULL"
+	case "point", "geometry":
+		// Geo fields store GeoJSON
+		return "JSONB"
+	case "string[]", "uuid[]", "int[]":
+		return arrayColumnTypes[fieldType]
 	default:
-		columnType = "TEXT"
+		return "TEXT"
 	}
+}
 
-	// Build the ALTER TABLE query
-	alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN "%s" %s`, quotedTableName, field.Name, columnType)
+// CreateJunctionTable creates the junction table of a many-to-many relation in the tenant's
+// schema. The table may already exist when the other side of the relation created it.
+func (u *ItemsUtils) CreateJunctionTable(ctx context.Context, tenantID uuid.UUID, cfg RelationConfig) error {
+	tenantSchema, err := u.GetTenantSchema(ctx, tenantID)
+	if err != nil {
+		return err
+	}
 
-	// Add NOT NULL constraint if required
-	if field.IsRequired.Bool {
-		alterQuery += " NOT NULL"
+	table := cfg.JunctionTable(u.dialect(), tenantSchema)
+	createQuery := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %s (
+		"%s" UUID NOT NULL,
+		"%s" UUID NOT NULL,
+		sort_order INTEGER NOT NULL DEFAULT 0,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		PRIMARY KEY ("%s", "%s")
+	)`, table, cfg.JunctionField, cfg.RelatedJunctionField, cfg.JunctionField, cfg.RelatedJunctionField)
+	if _, err := u.exec().ExecContext(ctx, createQuery); err != nil {
+		return fmt.Errorf("failed to create junction table: %w", err)
 	}
 
-	// Add default value if provided
-	if field.DefaultValue.Valid && field.DefaultValue.String != "" {
-		alterQuery += fmt.Sprintf(" DEFAULT '%s'", field.DefaultValue.String)
+	// The primary key serves this side; the index serves reads from the other side
+	indexQuery := fmt.Sprintf(`CREATE INDEX IF NOT EXISTS "idx_junction_%s_%s" ON %s ("%s")`,
+		cfg.Junction, cfg.RelatedJunctionField, table, cfg.RelatedJunctionField)
+	if _, err := u.exec().ExecContext(ctx, indexQuery); err != nil {
+		return fmt.Errorf("failed to index junction table: %w", err)
 	}
 
-	// Execute the ALTER TABLE statement
-	_, err = u.db.ExecContext(ctx, alterQuery)
+	return nil
+}
+
+// EnableSoftDelete adds the deleted_at column used by soft-delete collections to a data
+// table. It is safe to call repeatedly; tables that do not exist yet are skipped.
+func (u *ItemsUtils) EnableSoftDelete(ctx context.Context, tenantID uuid.UUID, collectionSlug string) error {
+	table, err := u.ResolveDataTable(ctx, tenantID, collectionSlug)
+	if errors.Is(err, ErrDataTableNotFound) {
+		return nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to add column to data table: %w", err)
+		return err
+	}
+
+	alterQuery := fmt.Sprintf(`ALTER TABLE %s ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE`, table)
+	if _, err := u.exec().ExecContext(ctx, alterQuery); err != nil {
+		return fmt.Errorf("failed to add deleted_at column: %w", err)
 	}
 
 	return nil
@@ -393,6 +579,51 @@ func GetIntFromMap(data map[string]interface{}, key string) int {
 	return 0
 }
 
+// GetStringSliceFromMap extracts a list of strings from a map, accepting both []string and
+// JSON-unmarshaled []interface{} values. The second result is false when the key is missing
+// or any element is not a string.
+//
+// Example:
+//
+//	data := map[string]interface{}{"events": []interface{}{"item.create", "item.delete"}}
+//	events, ok := GetStringSliceFromMap(data, "events") // Returns ["item.create", "item.delete"], true
+func GetStringSliceFromMap(data map[string]interface{}, key string) ([]string, bool) {
+	switch val := data[key].(type) {
+	case []string:
+		return val, true
+	case []interface{}:
+		result := make([]string, 0, len(val))
+		for _, item := range val {
+			str, ok := item.(string)
+			if !ok {
+				return nil, false
+			}
+			result = append(result, str)
+		}
+		return result, true
+	}
+	return nil, false
+}
+
+// GetJSONFromMap marshals a nested JSON value (object or array) from a map for storage
+// in a JSONB column. Missing keys and nil values produce an invalid (NULL) message.
+//
+// Example:
+//
+//	data := map[string]interface{}{"relation_config": map[string]interface{}{"related_collection": "customers"}}
+//	cfg := GetJSONFromMap(data, "relation_config") // Valid, RawMessage = {"related_collection":"customers"}
+func GetJSONFromMap(data map[string]interface{}, key string) pqtype.NullRawMessage {
+	val, ok := data[key]
+	if !ok || val == nil {
+		return pqtype.NullRawMessage{}
+	}
+	raw, err := json.Marshal(val)
+	if err != nil {
+		return pqtype.NullRawMessage{}
+	}
+	return pqtype.NullRawMessage{RawMessage: raw, Valid: true}
+}
+
 // Contains checks if a slice contains a specific string with wildcard support.
 //
 // This function is primarily used for checking if a field name is allowed in RBAC
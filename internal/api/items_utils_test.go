@@ -0,0 +1,85 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"testing"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestItemsUtils_withTx(t *testing.T) {
+	pool := &db.DB{Queries: sqlc.New(nil)}
+	utils := NewItemsUtils(pool)
+	assert.Equal(t, db.Executor(pool), utils.exec())
+	assert.Same(t, pool.Queries, utils.queries())
+
+	tx := &db.Tx{Queries: sqlc.New(nil)}
+	bound := utils.withTx(tx)
+	assert.Equal(t, db.Executor(tx), bound.exec())
+	assert.Same(t, tx.Queries, bound.queries())
+	assert.NotSame(t, utils.tables, bound.tables, "tables resolved inside the transaction stay with it")
+	assert.Nil(t, utils.tx, "binding returns a copy")
+
+	// Utils bound to a transaction keep running on it
+	var ran *ItemsUtils
+	require.NoError(t, bound.transaction(context.Background(), func(utils *ItemsUtils) error {
+		ran = utils
+		return nil
+	}))
+	assert.Same(t, bound, ran)
+}
+
+func TestItemsUtils_SQLiteDataTables(t *testing.T) {
+	database := dbtest.SQLite(t)
+	utils := NewItemsUtils(database)
+	ctx := context.Background()
+	tenantID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+
+	collection, err := database.Queries.CreateCollection(ctx, sqlc.CreateCollectionParams{
+		ID:         uuid.New(),
+		Name:       "posts",
+		Slug:       "posts",
+		TenantID:   uuid.NullUUID{UUID: tenantID, Valid: true},
+		Timestamps: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, utils.CreateDataTable(ctx, &collection))
+	assert.Equal(t, "posts-data-"+tenantID.String(), collection.DataTableName)
+
+	table, err := utils.ResolveDataTable(ctx, tenantID, "posts")
+	require.NoError(t, err)
+	assert.Equal(t, LayoutDataSchema, table.Layout)
+	assert.Equal(t, `"data__posts-data-`+tenantID.String()+`"`, table.String())
+
+	field, err := database.Queries.CreateField(ctx, sqlc.CreateFieldParams{
+		ID:           uuid.New(),
+		CollectionID: uuid.NullUUID{UUID: collection.ID, Valid: true},
+		Name:         "title",
+		Type:         "text",
+		IsUnique:     sql.NullBool{Bool: true, Valid: true},
+		TenantID:     uuid.NullUUID{UUID: tenantID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.NoError(t, utils.AddColumnToDataTable(ctx, tenantID, "posts", field))
+
+	// Rows get the defaults of the data table, and unique fields stay unique
+	insert := `INSERT INTO ` + table.String() + ` (title) VALUES ($1)`
+	_, err = database.ExecContext(ctx, insert, "Hello")
+	require.NoError(t, err)
+	var rowTenant uuid.UUID
+	require.NoError(t, database.QueryRowContext(ctx, `SELECT tenant_id FROM `+table.String()).Scan(&rowTenant))
+	assert.Equal(t, tenantID, rowTenant)
+
+	_, err = database.ExecContext(ctx, insert, "Hello")
+	status, code, _ := classifyError(err)
+	assert.Equal(t, http.StatusConflict, status)
+	assert.Equal(t, CodeConflict, code)
+}
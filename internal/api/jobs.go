@@ -0,0 +1,90 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the admin view of the background job queue run by the jobs package.
+//
+// Jobs are listed through GET /items/jobs like other schema tables; their payloads are
+// never returned. Jobs cannot be created or deleted through the API. A dead job, one that
+// failed every attempt, is queued again with:
+// - PUT /items/jobs/:id {"status": "pending"}
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/jobs"
+
+	"github.com/google/uuid"
+)
+
+// Job Operations
+
+// RetryJob queues a dead job of the user's tenant again with a fresh set of attempts.
+// Setting the status to pending is the only change allowed.
+func (s *SchemaHandlers) RetryJob(ctx context.Context, userID uuid.UUID, itemID string, data map[string]interface{}) (map[string]interface{}, error) {
+	if len(data) != 1 || GetStringFromMap(data, "status") != jobs.StatusPending {
+		return nil, fmt.Errorf("jobs can only be retried, by setting status to '%s'", jobs.StatusPending)
+	}
+
+	existing, err := s.getTenantJob(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	job, err := s.handler.db.Queries.RetryJob(ctx, existing.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("only dead jobs can be retried; this job is %s", existing.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return jobToMap(job), nil
+}
+
+// getTenantJob loads a job and makes sure it belongs to the user's tenant
+func (s *SchemaHandlers) getTenantJob(ctx context.Context, userID uuid.UUID, itemID string) (sqlc.Job, error) {
+	jobID, err := uuid.Parse(itemID)
+	if err != nil {
+		return sqlc.Job{}, fmt.Errorf("invalid job ID: %w", err)
+	}
+
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return sqlc.Job{}, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	job, err := s.handler.db.Queries.GetJobByID(ctx, jobID)
+	if err != nil || !job.TenantID.Valid || job.TenantID.UUID != tenantID {
+		return sqlc.Job{}, fmt.Errorf("job not found")
+	}
+
+	return job, nil
+}
+
+// jobToMap converts a job to its API representation, without the payload
+func jobToMap(job sqlc.Job) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":           job.ID.String(),
+		"tenant_id":    job.TenantID.UUID.String(),
+		"kind":         job.Kind,
+		"status":       job.Status,
+		"attempts":     job.Attempts,
+		"max_attempts": job.MaxAttempts,
+		"run_at":       job.RunAt,
+		"progress":     job.Progress,
+		"last_error":   nil,
+		"completed_at": nil,
+		"created_at":   job.CreatedAt.Time,
+		"updated_at":   job.UpdatedAt.Time,
+	}
+	if job.LastError.Valid {
+		result["last_error"] = job.LastError.String
+	}
+	if job.CompletedAt.Valid {
+		result["completed_at"] = job.CompletedAt.Time
+	}
+	return result
+}
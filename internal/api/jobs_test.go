@@ -0,0 +1,41 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryJob_OnlyStatusPending(t *testing.T) {
+	s := &SchemaHandlers{}
+	for _, data := range []map[string]interface{}{
+		{"status": "completed"},
+		{"status": "pending", "max_attempts": 10},
+		{},
+	} {
+		_, err := s.RetryJob(context.Background(), uuid.New(), uuid.NewString(), data)
+		assert.ErrorContains(t, err, "can only be retried")
+	}
+}
+
+func TestJobToMap(t *testing.T) {
+	job := sqlc.Job{
+		ID:        uuid.New(),
+		TenantID:  uuid.NullUUID{UUID: uuid.New(), Valid: true},
+		Kind:      "mail.send",
+		Payload:   json.RawMessage(`{"Text": "reset link"}`),
+		Status:    "dead",
+		LastError: sql.NullString{String: "smtp unavailable", Valid: true},
+	}
+
+	result := jobToMap(job)
+	assert.NotContains(t, result, "payload")
+	assert.Equal(t, "smtp unavailable", result["last_error"])
+	assert.Nil(t, result["completed_at"])
+}
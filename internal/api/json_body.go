@@ -0,0 +1,135 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the decoding of JSON request bodies.
+//
+// Bodies are bounded in size by the BodyLimit middleware (MAX_BODY_SIZE) and in nesting
+// by JSON_MAX_DEPTH, so a giant or deeply nested payload is refused before it is turned
+// into maps. Request structs of the kinds listed in JSON_STRICT are decoded strictly:
+// fields they do not define are rejected instead of ignored. Item payloads are maps, so
+// their fields are checked against the collection instead (unknown_field).
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+)
+
+// Kinds of request bodies, which JSON_STRICT names to decode them strictly
+const (
+	BodyItems = "items" // Item payloads of /items
+	BodyAuth  = "auth"  // Sign in, sign up, password and two-factor requests
+	BodyAdmin = "admin" // Tenant, member, collection and API key management requests
+)
+
+// defaultJSONMaxDepth is the nesting limit until UseJSONLimits is called
+const defaultJSONMaxDepth = 32
+
+// errJSONTooDeep is returned for bodies nested deeper than the limit
+var errJSONTooDeep = errors.New("request body is nested too deeply")
+
+// jsonLimits applies to every decoded body
+var jsonLimits = struct {
+	maxDepth int
+	strict   map[string]bool
+}{maxDepth: defaultJSONMaxDepth}
+
+// UseJSONLimits sets how deeply request bodies may nest (0 for no limit) and which kinds
+// of bodies are decoded strictly. Call it once at startup.
+func UseJSONLimits(maxDepth int, strictKinds []string) {
+	jsonLimits.maxDepth = maxDepth
+	jsonLimits.strict = make(map[string]bool, len(strictKinds))
+	for _, kind := range strictKinds {
+		jsonLimits.strict[kind] = true
+	}
+}
+
+// decodeJSON decodes the request body into v like ShouldBindJSON, including the binding
+// validation of structs, after checking its nesting. Bodies of strict kinds may only
+// contain fields v defines.
+func decodeJSON(c *gin.Context, v interface{}, kind string) error {
+	if c.Request.Body == nil {
+		return errors.New("request body is empty")
+	}
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		return err
+	}
+	if err := checkJSONDepth(body, jsonLimits.maxDepth); err != nil {
+		return err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	if jsonLimits.strict[kind] {
+		decoder.DisallowUnknownFields()
+	}
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if binding.Validator == nil {
+		return nil
+	}
+	return binding.Validator.ValidateStruct(v)
+}
+
+// respondBodyError answers a body decodeJSON refused: with 413 when it is too large, and
+// otherwise with 400 and message
+func respondBodyError(c *gin.Context, err error, message string) {
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{
+			"error": fmt.Sprintf("Request body exceeds the maximum size of %d bytes", tooLarge.Limit),
+			"code":  CodePayloadTooLarge,
+		})
+	case errors.Is(err, errJSONTooDeep):
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": fmt.Sprintf("Request body is nested more than %d levels deep", jsonLimits.maxDepth),
+			"code":  CodeInvalidBody,
+		})
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": message, "code": CodeInvalidBody})
+	}
+}
+
+// checkJSONDepth returns errJSONTooDeep when objects and arrays in data nest deeper than
+// maxDepth. Brackets inside strings do not count; malformed JSON is left to the decoder.
+func checkJSONDepth(data []byte, maxDepth int) error {
+	if maxDepth <= 0 {
+		return nil
+	}
+
+	depth := 0
+	inString, escaped := false, false
+	for _, b := range data {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case b == '\\':
+				escaped = true
+			case b == '"':
+				inString = false
+			}
+			continue
+		}
+
+		switch b {
+		case '"':
+			inString = true
+		case '{', '[':
+			depth++
+			if depth > maxDepth {
+				return errJSONTooDeep
+			}
+		case '}', ']':
+			depth--
+		}
+	}
+	return nil
+}
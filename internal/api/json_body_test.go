@@ -0,0 +1,101 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckJSONDepth(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{"Flat", `{"name":"basin"}`, false},
+		{"At Limit", `{"a":{"b":[1]}}`, false},
+		{"Too Deep", `{"a":{"b":[[1]]}}`, true},
+		{"Brackets In Strings", `{"a":"[[[[{{{{","b":"\"[[[["}`, false},
+		{"Siblings", `[{"a":[1]},{"b":[2]},{"c":[3]}]`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkJSONDepth([]byte(tt.data), 3)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, errJSONTooDeep)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+	assert.NoError(t, checkJSONDepth([]byte(strings.Repeat("[", 100)), 0), "0 disables the limit")
+}
+
+func TestDecodeJSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	defer UseJSONLimits(defaultJSONMaxDepth, nil)
+
+	type loginRequest struct {
+		Email string `json:"email" binding:"required"`
+	}
+	decode := func(body string, v interface{}, kind string) (*httptest.ResponseRecorder, error) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body))
+		err := decodeJSON(c, v, kind)
+		if err != nil {
+			respondBodyError(c, err, "Invalid request body")
+		}
+		return w, err
+	}
+
+	UseJSONLimits(4, []string{BodyAuth})
+
+	var item map[string]interface{}
+	_, err := decode(`{"title":"Hello","tags":["a"]}`, &item, BodyItems)
+	require.NoError(t, err)
+	assert.Equal(t, "Hello", item["title"])
+
+	// Binding validation still applies
+	var login loginRequest
+	_, err = decode(`{}`, &login, BodyAdmin)
+	assert.Error(t, err)
+
+	// Strict kinds reject unknown fields, others ignore them
+	_, err = decode(`{"email":"a@example.com","admin":true}`, &login, BodyAuth)
+	assert.Error(t, err)
+	_, err = decode(`{"email":"a@example.com","admin":true}`, &login, BodyAdmin)
+	assert.NoError(t, err)
+
+	// Deeply nested bodies are refused before decoding
+	w, err := decode(`{"a":{"b":{"c":{"d":{"e":1}}}}}`, &item, BodyItems)
+	assert.ErrorIs(t, err, errJSONTooDeep)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var response map[string]interface{}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+	assert.Equal(t, CodeInvalidBody, response["code"])
+}
+
+func TestRespondBodyError_TooLarge(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/", strings.NewReader(strings.Repeat(" ", 20)+"{}"))
+	c.Request.Body = http.MaxBytesReader(w, c.Request.Body, 10)
+
+	var item map[string]interface{}
+	err := decodeJSON(c, &item, BodyItems)
+	require.Error(t, err)
+	respondBodyError(c, err, "Invalid request body")
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), CodePayloadTooLarge)
+	assert.Contains(t, w.Body.String(), "10 bytes")
+}
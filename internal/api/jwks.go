@@ -0,0 +1,32 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file publishes the keys that verify access tokens.
+//
+// JWKS Endpoints:
+// - GET /.well-known/jwks.json - Public keys of the access token signing keys (no auth)
+//
+// With JWT_ALGORITHM=RS256 or EdDSA other services can validate Basin tokens with these
+// keys, picking the one named by the token's kid header. HS256 tokens are verified with the
+// shared secret, so the set is empty.
+package api
+
+import (
+	"net/http"
+
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+)
+
+// JWKS handles GET /.well-known/jwks.json requests
+// @Summary      JSON Web Key Set
+// @Description  Public keys that verify access tokens signed with RS256 or EdDSA; empty with HS256.
+// @Tags         auth
+// @Produce      json
+// @Success      200 {object} middleware.JWKSet
+// @Router       /.well-known/jwks.json [get]
+func (h *AuthHandler) JWKS(c *gin.Context) {
+	// Keys are added well before they sign and dropped after their tokens expire, so a
+	// cached set stays usable
+	c.Header("Cache-Control", "public, max-age=300")
+	c.JSON(http.StatusOK, middleware.CurrentSigningKeys(h.cfg).JWKS())
+}
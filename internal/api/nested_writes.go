@@ -0,0 +1,300 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains nested writes: creating an item together with the items of its
+// one-to-many relations.
+//
+// A one-to-many field has no column of its own (see relations.go), so writing it directly
+// is refused. When an item is created, though, the field may hold an array of new related
+// items:
+//
+//	POST /items/orders
+//	{"customer": "…", "items": [{"product": "…", "quantity": 2}, {"product": "…", "quantity": 1}]}
+//
+// Every related item is validated against its own collection and inserted in the
+// transaction of the parent, with the relation's related_field (order_id) pointing at the
+// new parent; the client never sends it. The caller needs create permission on each
+// related collection, whose field permissions apply to the related items. Nesting is one
+// level deep, and the response carries the created related items, with their IDs, under
+// the field they were sent in.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// nestedWrite holds the new related items sent in one one-to-many field of a new item
+type nestedWrite struct {
+	field    string                   // One-to-many field of the parent collection
+	relation RelationConfig           // Relation the field describes
+	items    []map[string]interface{} // Related items to create
+}
+
+// nestedItems reads the value of a one-to-many field of a new item as the objects of the
+// related items to create
+func nestedItems(value interface{}) ([]map[string]interface{}, error) {
+	values, ok := value.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("expected an array of items, got %T", value)
+	}
+	if len(values) > maxBulkItems {
+		return nil, fmt.Errorf("at most %d items can be created at once", maxBulkItems)
+	}
+
+	items := make([]map[string]interface{}, len(values))
+	for i, value := range values {
+		item, ok := value.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("item %d: expected an object, got %T", i, value)
+		}
+		items[i] = item
+	}
+	return items, nil
+}
+
+// splitNestedWrites separates the one-to-many fields of a new item from its own values.
+// Items without such fields are returned unchanged, with no writes.
+func (ch *CollectionsHandler) splitNestedWrites(ctx context.Context, tenantID uuid.UUID, collectionName string, data map[string]interface{}) (map[string]interface{}, []nestedWrite, error) {
+	collection, err := ch.GetCollection(ctx, tenantID, collectionName)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields, err := ch.GetCollectionFields(ctx, collection.ID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var writes []nestedWrite
+	var fieldErrors FieldErrors
+	for _, field := range fields {
+		value, ok := data[field.Name]
+		if !ok || field.Type != "relation" || GetStringFromMap(field.Options, "type") != RelationOneToMany {
+			continue
+		}
+		relation, err := parseRelationConfig(field.Options)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.Name, Code: FieldCodeInvalidValue, Message: err.Error()})
+			continue
+		}
+		items, err := nestedItems(value)
+		if err != nil {
+			fieldErrors = append(fieldErrors, FieldError{Field: field.Name, Code: FieldCodeInvalidType, Message: err.Error()})
+			continue
+		}
+		writes = append(writes, nestedWrite{field: field.Name, relation: relation, items: items})
+	}
+	if len(fieldErrors) > 0 {
+		return nil, nil, validationError("%w", fieldErrors)
+	}
+	if len(writes) == 0 {
+		return data, nil, nil
+	}
+
+	own := make(map[string]interface{}, len(data))
+	for key, value := range data {
+		own[key] = value
+	}
+	for _, write := range writes {
+		delete(own, write.field)
+	}
+	return own, writes, nil
+}
+
+// CreateNestedCollectionItem creates an item together with the related items of its
+// one-to-many fields, all validated before any is written and inserted in one transaction.
+// The result holds the created related items under their fields.
+func (ch *CollectionsHandler) CreateNestedCollectionItem(ctx context.Context, userID uuid.UUID, collectionName string, data map[string]interface{}, writes []nestedWrite) (map[string]interface{}, error) {
+	userTenantID, err := ch.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	if err := ch.ValidateCollectionData(ctx, userTenantID, collectionName, data); err != nil {
+		return nil, fmt.Errorf("validation failed: %w", err)
+	}
+	convertedData, err := ch.ConvertFieldValues(ctx, userTenantID, collectionName, data)
+	if err != nil {
+		return nil, fmt.Errorf("field conversion failed: %w", err)
+	}
+
+	converted := make([]nestedWrite, len(writes))
+	for w, write := range writes {
+		converted[w] = nestedWrite{field: write.field, relation: write.relation, items: make([]map[string]interface{}, len(write.items))}
+		relatedField := write.relation.RelatedField
+		for i, item := range write.items {
+			if _, ok := item[relatedField]; ok {
+				return nil, validationError("%s[%d]: field '%s' is filled in with the new item", write.field, i, relatedField)
+			}
+
+			// The parent has no ID yet; a stand-in lets a required foreign key validate
+			item = withValue(item, relatedField, uuid.Nil.String())
+			if err := ch.ValidateCollectionData(ctx, userTenantID, write.relation.RelatedCollection, item); err != nil {
+				return nil, wrapError(err, "%s[%d]", write.field, i)
+			}
+			convertedItem, err := ch.ConvertFieldValues(ctx, userTenantID, write.relation.RelatedCollection, item)
+			if err != nil {
+				return nil, wrapError(err, "%s[%d]", write.field, i)
+			}
+			delete(convertedItem, relatedField)
+			converted[w].items[i] = convertedItem
+		}
+	}
+
+	itemID, relatedIDs, err := ch.dynamicHandlers.CreateNestedDynamicItem(ctx, userID, collectionName, convertedData, converted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create item: %w", err)
+	}
+
+	result := withID(convertedData, itemID)
+	for w, write := range converted {
+		items := make([]map[string]interface{}, len(write.items))
+		for i, item := range write.items {
+			items[i] = withID(withValue(item, write.relation.RelatedField, itemID), relatedIDs[w][i])
+		}
+		result[write.field] = items
+	}
+	return result, nil
+}
+
+// withValue returns a copy of data with key set to value
+func withValue(data map[string]interface{}, key string, value interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		result[k] = v
+	}
+	result[key] = value
+	return result
+}
+
+// nestedTarget is the data table the related items of one nested write go to
+type nestedTarget struct {
+	table     string
+	flags     collectionFlags
+	links     *junctionWriter
+	encrypted []string // Encrypted fields
+}
+
+// CreateNestedDynamicItem inserts an item and the related items of writes in one
+// transaction, pointing each related item at the new item. It returns the ID of the item
+// and the IDs of the related items, in the order of writes.
+func (d *DynamicHandlers) CreateNestedDynamicItem(ctx context.Context, userID uuid.UUID, collectionSlug string, data map[string]interface{}, writes []nestedWrite) (string, [][]string, error) {
+	fullTableName, tenantID, err := d.resolveDataTable(ctx, userID, collectionSlug)
+	if err != nil {
+		return "", nil, err
+	}
+	links, err := d.junctionWriter(ctx, tenantID, collectionSlug)
+	if err != nil {
+		return "", nil, err
+	}
+	encrypted, err := d.encryptedFields(ctx, tenantID, collectionSlug)
+	if err != nil {
+		return "", nil, err
+	}
+	if data, err = encryptValues(encrypted, data); err != nil {
+		return "", nil, err
+	}
+	flags := d.collectionFlags(ctx, tenantID, collectionSlug)
+
+	targets := make([]nestedTarget, len(writes))
+	for w, write := range writes {
+		table, err := d.utils.ResolveDataTable(ctx, tenantID, write.relation.RelatedCollection)
+		if err != nil {
+			return "", nil, wrapError(err, "field '%s'", write.field)
+		}
+		targetLinks, err := d.junctionWriter(ctx, tenantID, write.relation.RelatedCollection)
+		if err != nil {
+			return "", nil, err
+		}
+		targetEncrypted, err := d.encryptedFields(ctx, tenantID, write.relation.RelatedCollection)
+		if err != nil {
+			return "", nil, err
+		}
+		targets[w] = nestedTarget{
+			table:     table.String(),
+			flags:     d.collectionFlags(ctx, tenantID, write.relation.RelatedCollection),
+			links:     targetLinks,
+			encrypted: targetEncrypted,
+		}
+	}
+
+	var itemID string
+	relatedIDs := make([][]string, len(writes))
+	payloads := make([][]map[string]interface{}, len(writes))
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		if err := d.checkItemQuota(ctx, tx, tenantID, fullTableName, 1); err != nil {
+			return err
+		}
+		itemID, err = d.insertItem(ctx, tx, fullTableName, userID, data, flags, links)
+		if err != nil {
+			return err
+		}
+		if err := d.recordRevision(ctx, tx, tenantID, userID, collectionSlug, itemID, revisionCreate, nil, withID(data, itemID)); err != nil {
+			return err
+		}
+
+		for w, write := range writes {
+			target := targets[w]
+			if err := d.checkItemQuota(ctx, tx, tenantID, target.table, len(write.items)); err != nil {
+				return wrapError(err, "field '%s'", write.field)
+			}
+			relatedIDs[w] = make([]string, len(write.items))
+			payloads[w] = make([]map[string]interface{}, len(write.items))
+			for i, item := range write.items {
+				item, err := encryptValues(target.encrypted, withValue(item, write.relation.RelatedField, itemID))
+				if err != nil {
+					return wrapError(err, "%s[%d]", write.field, i)
+				}
+				relatedID, err := d.insertItem(ctx, tx, target.table, userID, item, target.flags, target.links)
+				if err != nil {
+					return wrapError(err, "%s[%d]", write.field, i)
+				}
+				payloads[w][i] = withID(item, relatedID)
+				if err := d.recordRevision(ctx, tx, tenantID, userID, write.relation.RelatedCollection, relatedID, revisionCreate, nil, payloads[w][i]); err != nil {
+					return wrapError(err, "%s[%d]", write.field, i)
+				}
+				relatedIDs[w][i] = relatedID
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return "", nil, err
+	}
+
+	d.publish(ctx, events.ItemCreate, userID, tenantID, collectionSlug, []string{itemID}, withID(data, itemID))
+	for w, write := range writes {
+		d.publish(ctx, events.ItemCreate, userID, tenantID, write.relation.RelatedCollection, relatedIDs[w], payloads[w])
+	}
+	return itemID, relatedIDs, nil
+}
+
+// authorizeNestedWrites checks the caller's create permission on the collection of every
+// nested write and drops the fields the caller may not write from its items. It responds
+// and returns false when a permission is missing.
+func (h *ItemsHandler) authorizeNestedWrites(c *gin.Context, userID uuid.UUID, writes []nestedWrite) bool {
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	for w, write := range writes {
+		hasPermission, allowedFields, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, write.relation.RelatedCollection, "create")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return false
+		}
+		if !hasPermission {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Insufficient permissions to create items in %s", write.relation.RelatedCollection)})
+			return false
+		}
+		for i, item := range write.items {
+			writes[w].items[i] = h.policyChecker.FilterFields(item, allowedFields, nil)
+		}
+	}
+	return true
+}
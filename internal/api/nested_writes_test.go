@@ -0,0 +1,37 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNestedItems(t *testing.T) {
+	items, err := nestedItems([]interface{}{
+		map[string]interface{}{"quantity": float64(2)},
+		map[string]interface{}{"quantity": float64(1)},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []map[string]interface{}{{"quantity": float64(2)}, {"quantity": float64(1)}}, items)
+
+	items, err = nestedItems([]interface{}{})
+	require.NoError(t, err)
+	assert.Empty(t, items)
+
+	for _, value := range []interface{}{"not an array", map[string]interface{}{}, []interface{}{"id"}, nil} {
+		_, err := nestedItems(value)
+		assert.Error(t, err)
+	}
+
+	_, err = nestedItems(make([]interface{}, maxBulkItems+1))
+	assert.ErrorContains(t, err, "at most")
+}
+
+func TestWithValue(t *testing.T) {
+	data := map[string]interface{}{"quantity": 2}
+	result := withValue(data, "order_id", "abc")
+
+	assert.Equal(t, map[string]interface{}{"quantity": 2, "order_id": "abc"}, result)
+	assert.NotContains(t, data, "order_id")
+}
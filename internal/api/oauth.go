@@ -0,0 +1,486 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains sign in with external identity providers (Google, GitHub and generic
+// OpenID Connect).
+//
+// OAuth Endpoints:
+// - GET /auth/oauth                     - List the enabled providers
+// - GET /auth/oauth/:provider           - Redirect to the provider's sign in page
+// - GET /auth/oauth/:provider/callback  - Finish the sign in and issue a Basin token
+//
+// An identity signs in as the user it was linked to before. The first time, it is linked
+// to the user with the same (verified) email, or a new user is provisioned in
+// OAUTH_DEFAULT_TENANT. Roles come from OAUTH_DEFAULT_ROLES for new users and from
+// OAUTH_ROLE_MAPPING, which is applied again on every sign in; roles are never removed.
+package api
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/audit"
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/mail"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/oauth"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+const (
+	oauthStateCookie  = "basin_oauth"
+	oauthStatePurpose = "oauth_state"
+	oauthStateTTL     = 10 * time.Minute
+)
+
+var (
+	errOAuthUnverifiedEmail = errors.New("the identity provider did not confirm an email address for this account")
+	errOAuthNoAccount       = errors.New("no account is linked to this identity")
+)
+
+// oauthStateClaims are kept in a cookie between the redirect to the provider and the
+// callback. The state is also sent to the provider; the PKCE verifier never is.
+type oauthStateClaims struct {
+	Purpose  string `json:"purpose"`
+	Provider string `json:"provider"`
+	State    string `json:"state"`
+	Verifier string `json:"verifier"`
+	jwt.RegisteredClaims
+}
+
+// OAuthHandler signs users in with external identity providers
+type OAuthHandler struct {
+	db           *db.DB
+	cfg          *config.Config
+	providers    map[string]*oauth.Provider
+	roles        oauth.RoleMapping
+	authProvider *AuthProviderService
+	audit        *audit.Logger
+	mailer       *mail.Mailer
+}
+
+// NewOAuthHandler creates an OAuthHandler for the providers configured in cfg
+func NewOAuthHandler(db *db.DB, cfg *config.Config, mailer *mail.Mailer) (*OAuthHandler, error) {
+	roles, err := oauth.ParseRoleMapping(cfg.OAuthRoleClaim, cfg.OAuthRoleMapping, cfg.OAuthDefaultRoles)
+	if err != nil {
+		return nil, err
+	}
+	return &OAuthHandler{
+		db:           db,
+		cfg:          cfg,
+		providers:    oauth.Providers(cfg),
+		roles:        roles,
+		authProvider: NewAuthProviderService(db, cfg),
+		audit:        audit.NewLogger(db),
+		mailer:       mailer,
+	}, nil
+}
+
+// signOAuthState creates the state cookie value
+func signOAuthState(secret, provider, state, verifier string) (string, error) {
+	claims := oauthStateClaims{
+		Purpose:  oauthStatePurpose,
+		Provider: provider,
+		State:    state,
+		Verifier: verifier,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(oauthStateTTL)),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(purposeKey(secret, oauthStatePurpose))
+}
+
+// parseOAuthState checks a state cookie against the provider and state of a callback
+func parseOAuthState(secret, cookie, provider, state string) (oauthStateClaims, error) {
+	var claims oauthStateClaims
+	_, err := jwt.ParseWithClaims(cookie, &claims, func(token *jwt.Token) (interface{}, error) {
+		return purposeKey(secret, oauthStatePurpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return oauthStateClaims{}, err
+	}
+	if claims.Purpose != oauthStatePurpose || claims.Provider != provider {
+		return oauthStateClaims{}, fmt.Errorf("state is for another provider")
+	}
+	if state == "" || subtle.ConstantTimeCompare([]byte(claims.State), []byte(state)) != 1 {
+		return oauthStateClaims{}, fmt.Errorf("state mismatch")
+	}
+	return claims, nil
+}
+
+// redirectURL is the callback URL registered with the provider
+func (h *OAuthHandler) redirectURL(c *gin.Context, provider string) string {
+	base := strings.TrimSuffix(h.cfg.OAuthRedirectBaseURL, "/")
+	if base == "" {
+		scheme := "http"
+		if c.Request.TLS != nil || c.GetHeader("X-Forwarded-Proto") == "https" {
+			scheme = "https"
+		}
+		base = scheme + "://" + c.Request.Host
+	}
+	return base + "/auth/oauth/" + provider + "/callback"
+}
+
+// setStateCookie stores (or with an empty value, clears) the state cookie
+func (h *OAuthHandler) setStateCookie(c *gin.Context, value string) {
+	maxAge := int(oauthStateTTL.Seconds())
+	if value == "" {
+		maxAge = -1
+	}
+	http.SetCookie(c.Writer, &http.Cookie{
+		Name:     oauthStateCookie,
+		Value:    value,
+		Path:     "/auth/oauth",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   c.Request.TLS != nil || h.cfg.ServerMode == "release",
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// Providers handles GET /auth/oauth requests
+// @Summary      List identity providers
+// @Tags         auth
+// @Produce      json
+// @Success      200   {object} map[string]interface{}
+// @Router       /auth/oauth [get]
+func (h *OAuthHandler) Providers(c *gin.Context) {
+	c.JSON(http.StatusOK, gin.H{"providers": oauth.Names(h.providers)})
+}
+
+// Login handles GET /auth/oauth/:provider requests
+// @Summary      Sign in with an identity provider
+// @Tags         auth
+// @Param        provider  path  string true "Provider (google, github or oidc)"
+// @Success      302
+// @Failure      404   {object} map[string]string
+// @Router       /auth/oauth/{provider} [get]
+func (h *OAuthHandler) Login(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+
+	state, verifier := oauth.NewVerifier(), oauth.NewVerifier()
+	cookie, err := signOAuthState(h.cfg.JWTSecret, provider.Name, state, verifier)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start sign in"})
+		return
+	}
+	authURL, err := provider.AuthCodeURL(c.Request.Context(), state, verifier, h.redirectURL(c, provider.Name))
+	if err != nil {
+		middleware.GetLogger(c).Error("identity provider unavailable", "provider", provider.Name, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Identity provider unavailable"})
+		return
+	}
+
+	h.setStateCookie(c, cookie)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// Callback handles GET /auth/oauth/:provider/callback requests
+// @Summary      Finish signing in with an identity provider
+// @Tags         auth
+// @Produce      json
+// @Param        provider  path  string true "Provider (google, github or oidc)"
+// @Param        code      query string true "Authorization code"
+// @Param        state     query string true "State from the sign in redirect"
+// @Success      200   {object} models.LoginResponse
+// @Failure      400   {object} map[string]string
+// @Failure      401   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Router       /auth/oauth/{provider}/callback [get]
+func (h *OAuthHandler) Callback(c *gin.Context) {
+	provider, ok := h.providers[c.Param("provider")]
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Unknown identity provider"})
+		return
+	}
+	ctx := c.Request.Context()
+	logger := middleware.GetLogger(c)
+
+	// The state cookie is single-use
+	cookie, _ := c.Cookie(oauthStateCookie)
+	h.setStateCookie(c, "")
+
+	if providerError := c.Query("error"); providerError != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Sign in was denied by the identity provider", "provider_error": providerError})
+		return
+	}
+	state, err := parseOAuthState(h.cfg.JWTSecret, cookie, provider.Name, c.Query("state"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired sign in state"})
+		return
+	}
+
+	identity, err := provider.Authenticate(ctx, c.Query("code"), state.Verifier, h.redirectURL(c, provider.Name))
+	if err != nil {
+		logger.Warn("identity provider sign in failed", "provider", provider.Name, "error", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to authenticate with the identity provider"})
+		return
+	}
+
+	user, created, err := h.resolveUser(ctx, identity)
+	switch {
+	case errors.Is(err, errOAuthUnverifiedEmail), errors.Is(err, errOAuthNoAccount):
+		h.auditFailure(c, identity, uuid.Nil, err.Error())
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		logger.Error("failed to resolve identity", "provider", provider.Name, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if !user.IsActive.Bool {
+		h.auditFailure(c, identity, user.ID, "account disabled")
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Account is disabled"})
+		return
+	}
+
+	h.onboard(ctx, logger, user, identity, created)
+
+	// Users with 2FA finish with POST /auth/2fa/verify, like a password login
+	enabled, err := middleware.TwoFactorEnabled(ctx, h.db, user.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+	if enabled {
+		challenge, expiresAt, err := signTwoFactorChallenge(h.cfg.JWTSecret, user.ID, "")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+			return
+		}
+		if h.cfg.OAuthSuccessURL != "" {
+			fragment := url.Values{"challenge_token": {challenge}}
+			c.Redirect(http.StatusFound, h.cfg.OAuthSuccessURL+"#"+fragment.Encode())
+			return
+		}
+		c.JSON(http.StatusOK, models.TwoFactorChallengeResponse{
+			TwoFactorRequired: true,
+			ChallengeToken:    challenge,
+			ExpiresAt:         expiresAt,
+		})
+		return
+	}
+
+	// Sign in to the user's default tenant, like a password login without a tenant
+	var token string
+	var setupRequired bool
+	var tenantID uuid.UUID
+	var tenantSlug string
+	tenant, err := h.db.Queries.GetUserDefaultTenant(ctx, user.ID)
+	if err == nil && !tenant.IsActive.Bool {
+		h.auditFailure(c, identity, user.ID, "tenant inactive")
+		c.JSON(http.StatusForbidden, gin.H{"error": "Tenant is suspended or deleted", "tenant_inactive": true})
+		return
+	}
+	if err == nil {
+		token, setupRequired, err = h.authProvider.IssueToken(c, user, &tenant)
+		tenantID, tenantSlug = tenant.ID, tenant.Slug
+	} else {
+		token, _, err = h.authProvider.IssueToken(c, user, nil)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionLogin)
+	entry.UserID = user.ID
+	entry.TenantID = tenantID
+	entry.StatusCode = http.StatusOK
+	entry.Details = map[string]interface{}{"provider": provider.Name, "provisioned": created}
+	h.audit.Record(ctx, entry)
+
+	// Browser sign ins end on the frontend, which reads the token from the fragment
+	if h.cfg.OAuthSuccessURL != "" {
+		fragment := url.Values{"token": {token}, "tenant_slug": {tenantSlug}}
+		c.Redirect(http.StatusFound, h.cfg.OAuthSuccessURL+"#"+fragment.Encode())
+		return
+	}
+
+	c.JSON(http.StatusOK, models.LoginResponse{
+		Token: token,
+		User: models.User{
+			ID:        user.ID,
+			Email:     user.Email,
+			FirstName: user.FirstName.String,
+			LastName:  user.LastName.String,
+			IsActive:  user.IsActive.Bool,
+			CreatedAt: user.CreatedAt.Time,
+			UpdatedAt: user.UpdatedAt.Time,
+		},
+		TenantID:   tenantID,
+		TenantSlug: tenantSlug,
+
+		PasswordChangeRequired: user.MustChangePassword,
+		TwoFactorSetupRequired: setupRequired,
+	})
+}
+
+// resolveUser finds the user an identity signs in as, linking or provisioning one the
+// first time. created reports a provisioned user.
+func (h *OAuthHandler) resolveUser(ctx context.Context, identity oauth.Identity) (user sqlc.User, created bool, err error) {
+	queries := h.db.Queries
+	email := sql.NullString{String: identity.Email, Valid: identity.Email != ""}
+
+	link, err := queries.GetUserIdentity(ctx, sqlc.GetUserIdentityParams{Provider: identity.Provider, Subject: identity.Subject})
+	if err == nil {
+		if err := queries.TouchUserIdentity(ctx, sqlc.TouchUserIdentityParams{ID: link.ID, Email: email}); err != nil {
+			return sqlc.User{}, false, err
+		}
+		user, err = queries.GetUserByID(ctx, link.UserID)
+		return user, false, err
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return sqlc.User{}, false, err
+	}
+
+	// Linking by email is only safe when the provider vouches for the address
+	if identity.Email == "" || !identity.EmailVerified {
+		return sqlc.User{}, false, errOAuthUnverifiedEmail
+	}
+	user, err = queries.GetUserByEmail(ctx, identity.Email)
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if !h.cfg.OAuthAutoProvision {
+			return sqlc.User{}, false, errOAuthNoAccount
+		}
+		if user, err = h.provision(ctx, identity); err != nil {
+			return sqlc.User{}, false, err
+		}
+		created = true
+	case err != nil:
+		return sqlc.User{}, false, err
+	case user.IsServiceAccount:
+		// Service accounts have no human behind them to sign in
+		return sqlc.User{}, false, errOAuthNoAccount
+	}
+
+	link, err = queries.CreateUserIdentity(ctx, sqlc.CreateUserIdentityParams{
+		UserID:   user.ID,
+		Provider: identity.Provider,
+		Subject:  identity.Subject,
+		Email:    email,
+	})
+	if err != nil {
+		return sqlc.User{}, false, fmt.Errorf("failed to link identity: %w", err)
+	}
+	if err := queries.TouchUserIdentity(ctx, sqlc.TouchUserIdentityParams{ID: link.ID, Email: email}); err != nil {
+		return sqlc.User{}, false, err
+	}
+	return user, created, nil
+}
+
+// onboard grants the roles the identity's claims map to, which can change at the provider
+// and so are granted on every sign in, and welcomes provisioned users with the default roles
+func (h *OAuthHandler) onboard(ctx context.Context, logger *slog.Logger, user sqlc.User, identity oauth.Identity, created bool) {
+	roles := h.roles.MappedRoles(identity)
+	if created {
+		roles = append(append([]string{}, h.roles.Defaults...), roles...)
+	}
+	if err := h.grantRoles(ctx, user.ID, roles); err != nil {
+		logger.Warn("failed to grant identity provider roles", "user_id", user.ID, "error", err)
+	}
+	if created {
+		name := strings.TrimSpace(identity.FirstName + " " + identity.LastName)
+		if err := h.mailer.Send(ctx, user.TenantID.UUID, user.Email, mail.TemplateWelcome, mail.Data{Name: name}); err != nil {
+			logger.Error("failed to send welcome email", "user_id", user.ID, "error", err)
+		}
+	}
+}
+
+// provision creates a user for an identity. The user has no usable password until it
+// sets one through a password reset.
+func (h *OAuthHandler) provision(ctx context.Context, identity oauth.Identity) (sqlc.User, error) {
+	passwordHash, err := models.HashPassword(oauth.NewVerifier())
+	if err != nil {
+		return sqlc.User{}, err
+	}
+
+	var homeTenant uuid.NullUUID
+	if tenant, err := h.db.Queries.GetTenantBySlug(ctx, h.cfg.OAuthDefaultTenant); err == nil {
+		homeTenant = uuid.NullUUID{UUID: tenant.ID, Valid: true}
+	}
+
+	return h.db.Queries.CreateUser(ctx, sqlc.CreateUserParams{
+		ID:           uuid.New(),
+		Email:        identity.Email,
+		PasswordHash: passwordHash,
+		FirstName:    sql.NullString{String: identity.FirstName, Valid: identity.FirstName != ""},
+		LastName:     sql.NullString{String: identity.LastName, Valid: identity.LastName != ""},
+		TenantID:     homeTenant,
+	})
+}
+
+// grantRoles gives the user the named roles of OAUTH_DEFAULT_TENANT, making it a member
+// first if needed. Roles the tenant does not have are skipped.
+func (h *OAuthHandler) grantRoles(ctx context.Context, userID uuid.UUID, names []string) error {
+	if len(names) == 0 {
+		return nil
+	}
+	queries := h.db.Queries
+
+	tenant, err := queries.GetTenantBySlug(ctx, h.cfg.OAuthDefaultTenant)
+	if err != nil {
+		return fmt.Errorf("default tenant '%s': %w", h.cfg.OAuthDefaultTenant, err)
+	}
+
+	var roleIDs []uuid.UUID
+	for _, name := range names {
+		role, err := queries.GetRoleByNameAndTenant(ctx, sqlc.GetRoleByNameAndTenantParams{
+			Name:     name,
+			TenantID: uuid.NullUUID{UUID: tenant.ID, Valid: true},
+		})
+		if err != nil {
+			continue
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+	if len(roleIDs) == 0 {
+		return fmt.Errorf("tenant '%s' has none of the roles %v", tenant.Slug, names)
+	}
+
+	if _, err := queries.GetUserTenant(ctx, sqlc.GetUserTenantParams{UserID: userID, TenantID: tenant.ID}); err != nil {
+		if err := queries.AddUserToTenant(ctx, sqlc.AddUserToTenantParams{
+			UserID:   userID,
+			TenantID: tenant.ID,
+			RoleID:   uuid.NullUUID{UUID: roleIDs[0], Valid: true},
+		}); err != nil {
+			return err
+		}
+	}
+	for _, roleID := range roleIDs {
+		if err := queries.AddUserRole(ctx, sqlc.AddUserRoleParams{UserID: userID, RoleID: roleID}); err != nil {
+			return err
+		}
+	}
+	rbac.InvalidateUserPermissions(userID)
+	return nil
+}
+
+// auditFailure records a rejected identity provider sign in
+func (h *OAuthHandler) auditFailure(c *gin.Context, identity oauth.Identity, userID uuid.UUID, reason string) {
+	entry := middleware.NewAuditEntry(c, audit.ActionLoginFailed)
+	entry.UserID = userID
+	entry.Result = audit.ResultFailure
+	entry.StatusCode = http.StatusForbidden
+	entry.Details = map[string]interface{}{"provider": identity.Provider, "email": identity.Email, "reason": reason}
+	h.audit.Record(c.Request.Context(), entry)
+}
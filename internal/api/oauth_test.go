@@ -0,0 +1,91 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/mail"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOAuthState(t *testing.T) {
+	cookie, err := signOAuthState("secret", "google", "state-1", "verifier-1")
+	require.NoError(t, err)
+
+	claims, err := parseOAuthState("secret", cookie, "google", "state-1")
+	require.NoError(t, err)
+	assert.Equal(t, "verifier-1", claims.Verifier)
+
+	_, err = parseOAuthState("secret", cookie, "google", "state-2")
+	assert.Error(t, err)
+	_, err = parseOAuthState("secret", cookie, "github", "state-1")
+	assert.Error(t, err)
+	_, err = parseOAuthState("other-secret", cookie, "google", "state-1")
+	assert.Error(t, err)
+	_, err = parseOAuthState("secret", "", "google", "")
+	assert.Error(t, err)
+}
+
+func TestOAuthHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{
+		JWTSecret:            "test-secret-key",
+		OAuthRedirectBaseURL: "https://api.example.com/",
+		OAuthGoogleClientID:  "google-client",
+		OAuthRoleClaim:       "groups",
+	}
+	handler, err := NewOAuthHandler(newOfflineDB(t), cfg, mail.NewMailer(mail.NewLogSender(slog.Default()), mail.Address{}, nil))
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.GET("/auth/oauth", handler.Providers)
+	router.GET("/auth/oauth/:provider", handler.Login)
+	router.GET("/auth/oauth/:provider/callback", handler.Callback)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oauth", nil))
+	assert.JSONEq(t, `{"providers": ["google"]}`, w.Body.String())
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oauth/github", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	// The redirect carries the state that the cookie remembers
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oauth/google", nil))
+	require.Equal(t, http.StatusFound, w.Code)
+	location, err := url.Parse(w.Header().Get("Location"))
+	require.NoError(t, err)
+	assert.Equal(t, "accounts.google.com", location.Host)
+	assert.Equal(t, "https://api.example.com/auth/oauth/google/callback", location.Query().Get("redirect_uri"))
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.True(t, cookies[0].HttpOnly)
+	_, err = parseOAuthState(cfg.JWTSecret, cookies[0].Value, "google", location.Query().Get("state"))
+	assert.NoError(t, err)
+
+	// Callbacks without the matching state are rejected before contacting the provider
+	req := httptest.NewRequest(http.MethodGet, "/auth/oauth/google/callback?code=x&state=forged", nil)
+	req.AddCookie(cookies[0])
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Invalid or expired sign in state")
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/auth/oauth/google/callback?error=access_denied", nil))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "access_denied")
+
+	cfg.OAuthRoleMapping = []string{"broken"}
+	_, err = NewOAuthHandler(newOfflineDB(t), cfg, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,539 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the OpenAPI document generated from a tenant's collections.
+//
+// The Swagger docs under /swagger describe items as generic objects, since collections are
+// defined at runtime. GET /openapi.json describes the item routes of each collection of
+// the caller's tenant instead, with a schema per collection built from its fields: types,
+// required fields, enums and the other validation rules. Code generators can turn it into
+// typed clients.
+//
+// The document is built for the caller: collections they may not read are left out, as
+// are fields outside their permissions and operations they may not perform.
+package api
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// openAPIVersion is the OpenAPI version of generated documents
+const openAPIVersion = "3.0.3"
+
+// openAPICollection is a collection along with what the caller may do with it. The field
+// lists are those of the caller's permissions, nil when the action is not permitted.
+type openAPICollection struct {
+	SnapshotCollection
+	ReadFields   []string
+	CreateFields []string
+	UpdateFields []string
+	Delete       bool
+}
+
+// openAPIFieldFormats maps the formats of validation rules to OpenAPI formats
+var openAPIFieldFormats = map[string]string{"email": "email", "url": "uri", "uuid": "uuid"}
+
+// openAPIValidationKeywords maps validation rules to the JSON Schema keywords expressing them
+var openAPIValidationKeywords = map[string]string{
+	"min_length": "minLength",
+	"max_length": "maxLength",
+	"min":        "minimum",
+	"max":        "maximum",
+	"pattern":    "pattern",
+	"enum":       "enum",
+	"min_items":  "minItems",
+	"max_items":  "maxItems",
+}
+
+// buildOpenAPIDocument returns the OpenAPI document of the item routes of collections
+func buildOpenAPIDocument(collections []openAPICollection) map[string]interface{} {
+	sort.Slice(collections, func(i, j int) bool { return collections[i].Slug < collections[j].Slug })
+
+	schemas := map[string]interface{}{}
+	paths := map[string]interface{}{}
+	taken := map[string]bool{}
+	for _, collection := range collections {
+		if collection.ReadFields == nil {
+			continue
+		}
+		name := openAPISchemaName(collection.Slug, taken)
+		ref := func(suffix string) map[string]interface{} {
+			return map[string]interface{}{"$ref": "#/components/schemas/" + name + suffix}
+		}
+		tag := []string{collection.Slug}
+
+		schemas[name] = openAPIItemSchema(collection)
+		listPath := map[string]interface{}{
+			"get": map[string]interface{}{
+				"tags":        tag,
+				"summary":     "List " + collection.Name,
+				"operationId": "list" + name,
+				"parameters":  openAPIListParameters(),
+				"responses": map[string]interface{}{
+					"200": openAPIResponse("A page of items", map[string]interface{}{
+						"type": "array", "items": ref(""),
+					}),
+				},
+			},
+		}
+		itemPath := map[string]interface{}{
+			"parameters": []interface{}{map[string]interface{}{"$ref": "#/components/parameters/ItemID"}},
+			"get": map[string]interface{}{
+				"tags":        tag,
+				"summary":     "Get an item of " + collection.Name,
+				"operationId": "get" + name,
+				"parameters":  []interface{}{map[string]interface{}{"$ref": "#/components/parameters/Fields"}},
+				"responses": map[string]interface{}{
+					"200": openAPIResponse("The item", ref("")),
+					"404": map[string]interface{}{"$ref": "#/components/responses/Error"},
+				},
+			},
+		}
+
+		if collection.CreateFields != nil {
+			schemas[name+"Create"] = openAPIInputSchema(collection, collection.CreateFields, true)
+			listPath["post"] = map[string]interface{}{
+				"tags":        tag,
+				"summary":     "Create an item of " + collection.Name,
+				"operationId": "create" + name,
+				"requestBody": openAPIRequestBody(ref("Create")),
+				"responses": map[string]interface{}{
+					"201": openAPIResponse("The created item", ref("")),
+					"422": map[string]interface{}{"$ref": "#/components/responses/Error"},
+				},
+			}
+		}
+		if collection.UpdateFields != nil {
+			schemas[name+"Update"] = openAPIInputSchema(collection, collection.UpdateFields, false)
+			updateResponses := map[string]interface{}{
+				"200": openAPIResponse("The updated item", ref("")),
+				"404": map[string]interface{}{"$ref": "#/components/responses/Error"},
+				"409": map[string]interface{}{"$ref": "#/components/responses/Error"},
+				"422": map[string]interface{}{"$ref": "#/components/responses/Error"},
+			}
+			itemPath["put"] = map[string]interface{}{
+				"tags":        tag,
+				"summary":     "Update an item of " + collection.Name,
+				"operationId": "update" + name,
+				"requestBody": openAPIRequestBody(ref("Update")),
+				"responses":   updateResponses,
+			}
+			itemPath["patch"] = map[string]interface{}{
+				"tags":        tag,
+				"summary":     "Patch an item of " + collection.Name,
+				"operationId": "patch" + name,
+				"requestBody": openAPIRequestBody(ref("Update")),
+				"responses":   updateResponses,
+			}
+		}
+		if collection.Delete {
+			itemPath["delete"] = map[string]interface{}{
+				"tags":        tag,
+				"summary":     "Delete an item of " + collection.Name,
+				"operationId": "delete" + name,
+				"responses": map[string]interface{}{
+					"200": map[string]interface{}{"description": "The item was deleted"},
+					"404": map[string]interface{}{"$ref": "#/components/responses/Error"},
+				},
+			}
+		}
+
+		paths["/items/"+collection.Slug] = listPath
+		paths["/items/"+collection.Slug+"/{id}"] = itemPath
+	}
+
+	schemas["Error"] = map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"error": map[string]interface{}{"type": "string"},
+			"code":  map[string]interface{}{"type": "string"},
+			"errors": map[string]interface{}{
+				"type": "array",
+				"items": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"field":   map[string]interface{}{"type": "string"},
+						"code":    map[string]interface{}{"type": "string"},
+						"message": map[string]interface{}{"type": "string"},
+					},
+				},
+			},
+		},
+	}
+
+	return map[string]interface{}{
+		"openapi": openAPIVersion,
+		"info": map[string]interface{}{
+			"title":       "Basin API",
+			"version":     "1.0.0",
+			"description": "Item routes of the collections of your tenant, generated from their fields.",
+		},
+		"security": []interface{}{map[string]interface{}{"BearerAuth": []string{}}},
+		"paths":    paths,
+		"components": map[string]interface{}{
+			"schemas": schemas,
+			"securitySchemes": map[string]interface{}{
+				"BearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer", "description": "JWT or API key"},
+			},
+			"parameters": openAPIParameters(),
+			"responses": map[string]interface{}{
+				"Error": map[string]interface{}{
+					"description": "The request failed",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": map[string]interface{}{"$ref": "#/components/schemas/Error"},
+						},
+					},
+				},
+			},
+		},
+	}
+}
+
+// openAPISchemaName turns a collection slug into a unique schema name: order_items
+// becomes OrderItems
+func openAPISchemaName(slug string, taken map[string]bool) string {
+	var name strings.Builder
+	for _, part := range strings.FieldsFunc(slug, func(r rune) bool { return r == '_' || r == '-' }) {
+		name.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	base := name.String()
+	if base == "" || (base[0] >= '0' && base[0] <= '9') {
+		base = "Collection" + base
+	}
+
+	candidate := base
+	for i := 2; taken[candidate] || candidate == "Error"; i++ {
+		candidate = base + strconv.Itoa(i)
+	}
+	taken[candidate] = true
+	return candidate
+}
+
+// openAPIItemSchema returns the schema of the collection's items as the caller reads them
+func openAPIItemSchema(collection openAPICollection) map[string]interface{} {
+	properties := map[string]interface{}{
+		"id": map[string]interface{}{"type": "string", "format": "uuid", "readOnly": true},
+	}
+	system := map[string]map[string]interface{}{}
+	if flagOn(collection.Timestamps) {
+		system["created_at"] = map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true}
+		system["updated_at"] = map[string]interface{}{"type": "string", "format": "date-time", "readOnly": true}
+	}
+	if collection.Sortable {
+		system[sortColumn] = openAPISortSchema
+	}
+	if collection.SoftDelete {
+		system["deleted_at"] = map[string]interface{}{"type": "string", "format": "date-time", "nullable": true, "readOnly": true}
+	}
+	for column, schema := range openAPIWorkflowSchemas(collection) {
+		system[column] = schema
+	}
+	for column, schema := range system {
+		if Contains(collection.ReadFields, column) {
+			properties[column] = schema
+		}
+	}
+
+	for _, field := range collection.Fields {
+		if Contains(collection.ReadFields, field.Name) {
+			properties[field.Name] = openAPIFieldSchema(field)
+		}
+	}
+
+	return map[string]interface{}{
+		"type":       "object",
+		"title":      openAPITitle(collection.SnapshotCollection),
+		"properties": properties,
+		"required":   []string{"id"},
+	}
+}
+
+// openAPIInputSchema returns the schema of a create (complete) or update body. Computed
+// fields and one-to-many relations cannot be written and are left out.
+func openAPIInputSchema(collection openAPICollection, writeFields []string, complete bool) map[string]interface{} {
+	properties := map[string]interface{}{}
+	var required []string
+	for column, schema := range openAPIWorkflowSchemas(collection) {
+		if Contains(writeFields, column) {
+			properties[column] = schema
+		}
+	}
+	if collection.Sortable && Contains(writeFields, sortColumn) {
+		properties[sortColumn] = openAPISortSchema
+	}
+	for _, field := range collection.Fields {
+		if !Contains(writeFields, field.Name) || field.Type == "computed" || openAPIRelationType(field) == RelationOneToMany {
+			continue
+		}
+		schema := openAPIFieldSchema(field)
+		delete(schema, "readOnly")
+		properties[field.Name] = schema
+		if complete && field.IsRequired && field.DefaultValue == "" {
+			required = append(required, field.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(required) > 0 {
+		sort.Strings(required)
+		schema["required"] = required
+	}
+	return schema
+}
+
+// openAPISortSchema is the schema of the sort column of sortable collections
+var openAPISortSchema = map[string]interface{}{"type": "integer", "description": "Position in manual ordering; new items go last"}
+
+// openAPIWorkflowSchemas returns the schemas of the workflow columns of workflow collections
+func openAPIWorkflowSchemas(collection openAPICollection) map[string]map[string]interface{} {
+	if !collection.Workflow {
+		return nil
+	}
+	return map[string]map[string]interface{}{
+		"status":       {"type": "string", "enum": []string{StatusDraft, StatusPublished, StatusArchived}},
+		"publish_at":   {"type": "string", "format": "date-time", "nullable": true},
+		"unpublish_at": {"type": "string", "format": "date-time", "nullable": true},
+	}
+}
+
+// openAPIFieldSchema returns the schema of a field's values
+func openAPIFieldSchema(field SnapshotField) map[string]interface{} {
+	schema := openAPITypeSchema(field.Type)
+	switch field.Type {
+	case "relation":
+		switch openAPIRelationType(field) {
+		case RelationOneToMany:
+			schema = map[string]interface{}{"type": "array", "items": openAPITypeSchema("uuid"), "readOnly": true}
+		case RelationManyToMany:
+			schema = map[string]interface{}{"type": "array", "items": openAPITypeSchema("uuid")}
+		}
+	case "computed":
+		config, _ := field.ComputedConfig.(map[string]interface{})
+		resultType := GetStringFromMap(config, "type")
+		if resultType == "" {
+			resultType = "number"
+		}
+		schema = openAPITypeSchema(resultType)
+		schema["readOnly"] = true
+	}
+
+	if field.DisplayName != "" {
+		schema["title"] = field.DisplayName
+	}
+	if !field.IsRequired && schema["type"] != nil {
+		schema["nullable"] = true
+	}
+
+	rules, _ := field.ValidationRules.(map[string]interface{})
+	for rule, keyword := range openAPIValidationKeywords {
+		if value, ok := rules[rule]; ok {
+			schema[keyword] = value
+		}
+	}
+	if format, ok := openAPIFieldFormats[GetStringFromMap(rules, "format")]; ok {
+		schema["format"] = format
+	}
+	return schema
+}
+
+// openAPITypeSchema returns the schema of the values of a field type
+func openAPITypeSchema(fieldType string) map[string]interface{} {
+	switch fieldType {
+	case "string", "text":
+		return map[string]interface{}{"type": "string"}
+	case "integer", "int":
+		return map[string]interface{}{"type": "integer"}
+	case "number", "float", "decimal":
+		return map[string]interface{}{"type": "number"}
+	case "boolean", "bool":
+		return map[string]interface{}{"type": "boolean"}
+	case "date":
+		return map[string]interface{}{"type": "string", "format": "date"}
+	case "datetime":
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	case "uuid", "file", "relation":
+		return map[string]interface{}{"type": "string", "format": "uuid"}
+	case "point", "geometry":
+		return map[string]interface{}{"type": "object", "description": "GeoJSON geometry"}
+	case "string[]", "uuid[]", "int[]":
+		return map[string]interface{}{"type": "array", "items": openAPITypeSchema(arrayElementType(fieldType))}
+	}
+	// json and unknown types hold any value
+	return map[string]interface{}{}
+}
+
+// openAPIRelationType returns the relation type of a relation field, or ""
+func openAPIRelationType(field SnapshotField) string {
+	if field.Type != "relation" {
+		return ""
+	}
+	config, _ := field.RelationConfig.(map[string]interface{})
+	if relationType := GetStringFromMap(config, "type"); relationType != "" {
+		return relationType
+	}
+	return RelationManyToOne
+}
+
+// openAPITitle returns the display name of a collection
+func openAPITitle(collection SnapshotCollection) string {
+	if collection.DisplayName != "" {
+		return collection.DisplayName
+	}
+	return collection.Name
+}
+
+// openAPIResponse returns a JSON response whose data holds schema
+func openAPIResponse(description string, schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"description": description,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{
+				"schema": map[string]interface{}{
+					"type": "object",
+					"properties": map[string]interface{}{
+						"data": schema,
+						"meta": map[string]interface{}{"type": "object", "additionalProperties": true},
+					},
+				},
+			},
+		},
+	}
+}
+
+// openAPIRequestBody returns a required JSON request body of schema
+func openAPIRequestBody(schema map[string]interface{}) map[string]interface{} {
+	return map[string]interface{}{
+		"required": true,
+		"content": map[string]interface{}{
+			"application/json": map[string]interface{}{"schema": schema},
+		},
+	}
+}
+
+// openAPIListParameters returns references to the query parameters of item lists
+func openAPIListParameters() []interface{} {
+	names := []string{"Limit", "Offset", "Page", "Sort", "Fields", "Filter", "Search", "Meta", "Cursor"}
+	parameters := make([]interface{}, len(names))
+	for i, name := range names {
+		parameters[i] = map[string]interface{}{"$ref": "#/components/parameters/" + name}
+	}
+	return parameters
+}
+
+// openAPIParameters returns the shared parameters of the item routes
+func openAPIParameters() map[string]interface{} {
+	query := func(name, schemaType, description string) map[string]interface{} {
+		return map[string]interface{}{
+			"name": name, "in": "query", "required": false, "description": description,
+			"schema": map[string]interface{}{"type": schemaType},
+		}
+	}
+	return map[string]interface{}{
+		"ItemID": map[string]interface{}{
+			"name": "id", "in": "path", "required": true,
+			"schema": map[string]interface{}{"type": "string", "format": "uuid"},
+		},
+		"Limit":  query("limit", "integer", "Items per page (max 500, default 50)"),
+		"Offset": query("offset", "integer", "Items to skip"),
+		"Page":   query("page", "integer", "Page number (1-based)"),
+		"Sort":   query("sort", "string", "Comma-separated fields, - prefix for descending"),
+		"Fields": query("fields", "string", "Fields to return; dotted paths expand relations"),
+		"Filter": query("filter", "string", "Filter rule as JSON"),
+		"Search": query("search", "string", "Full-text search over the text fields"),
+		"Meta":   query("meta", "string", "total_count, filter_count or *"),
+		"Cursor": query("cursor", "string", "Cursor from meta.next_cursor"),
+	}
+}
+
+// GetOpenAPI handles GET /openapi.json requests.
+//
+// Generates the OpenAPI 3 document of the item routes of the caller's collections, with a
+// schema per collection built from its fields. Only what the caller may access is
+// described.
+//
+// Response Format:
+//   - 200: The OpenAPI document
+//   - 401: Missing or invalid authentication token
+//
+// @Summary      OpenAPI document of the tenant's collections
+// @Tags         schema
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Generate an OpenAPI 3 document describing the item routes of the collections the caller may read, with typed schemas.
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} models.ErrorResponse
+// @Router       /openapi.json [get]
+func (h *ItemsHandler) GetOpenAPI(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	collections, err := h.openAPICollections(c, userID)
+	if err != nil {
+		respondError(c, err, "Failed to generate OpenAPI document")
+		return
+	}
+	c.JSON(http.StatusOK, buildOpenAPIDocument(collections))
+}
+
+// openAPICollections loads the collections of the user's tenant with the user's
+// permissions on each
+func (h *ItemsHandler) openAPICollections(c *gin.Context, userID uuid.UUID) ([]openAPICollection, error) {
+	userTenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, _, err := h.schemaHandlers.loadSchema(c.Request.Context(), userTenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	var collections []openAPICollection
+	for _, collection := range snapshot.Collections {
+		entry := openAPICollection{SnapshotCollection: collection}
+		for _, action := range []string{"read", "create", "update", "delete"} {
+			allowed, fields, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, collection.Slug, action)
+			if err != nil {
+				return nil, err
+			}
+			if !allowed {
+				continue
+			}
+			if fields == nil {
+				fields = []string{}
+			}
+			switch action {
+			case "read":
+				entry.ReadFields = fields
+			case "create":
+				entry.CreateFields = fields
+			case "update":
+				entry.UpdateFields = fields
+			case "delete":
+				entry.Delete = true
+			}
+		}
+		if entry.ReadFields != nil {
+			collections = append(collections, entry)
+		}
+	}
+	return collections, nil
+}
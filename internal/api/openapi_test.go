@@ -0,0 +1,136 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOpenAPIFieldSchema(t *testing.T) {
+	tests := []struct {
+		name  string
+		field SnapshotField
+		want  map[string]interface{}
+	}{
+		{
+			name:  "Required String",
+			field: SnapshotField{Name: "title", Type: "string", IsRequired: true, DisplayName: "Title"},
+			want:  map[string]interface{}{"type": "string", "title": "Title"},
+		},
+		{
+			name:  "Optional Integer",
+			field: SnapshotField{Name: "stock", Type: "integer"},
+			want:  map[string]interface{}{"type": "integer", "nullable": true},
+		},
+		{
+			name:  "Datetime",
+			field: SnapshotField{Name: "due", Type: "datetime", IsRequired: true},
+			want:  map[string]interface{}{"type": "string", "format": "date-time"},
+		},
+		{
+			name:  "JSON",
+			field: SnapshotField{Name: "data", Type: "json"},
+			want:  map[string]interface{}{},
+		},
+		{
+			name: "Validation Rules",
+			field: SnapshotField{Name: "status", Type: "string", IsRequired: true, ValidationRules: map[string]interface{}{
+				"enum": []interface{}{"new", "done"}, "max_length": 10.0, "messages": map[string]interface{}{},
+			}},
+			want: map[string]interface{}{"type": "string", "enum": []interface{}{"new", "done"}, "maxLength": 10.0},
+		},
+		{
+			name:  "Format",
+			field: SnapshotField{Name: "site", Type: "string", IsRequired: true, ValidationRules: map[string]interface{}{"format": "url"}},
+			want:  map[string]interface{}{"type": "string", "format": "uri"},
+		},
+		{
+			name:  "Many To One",
+			field: SnapshotField{Name: "author", Type: "relation", IsRequired: true, RelationConfig: map[string]interface{}{"table": "users"}},
+			want:  map[string]interface{}{"type": "string", "format": "uuid"},
+		},
+		{
+			name:  "One To Many",
+			field: SnapshotField{Name: "comments", Type: "relation", IsRequired: true, RelationConfig: map[string]interface{}{"type": "o2m"}},
+			want: map[string]interface{}{"type": "array", "readOnly": true,
+				"items": map[string]interface{}{"type": "string", "format": "uuid"}},
+		},
+		{
+			name:  "Computed",
+			field: SnapshotField{Name: "total", Type: "computed", IsRequired: true, ComputedConfig: map[string]interface{}{"type": "text"}},
+			want:  map[string]interface{}{"type": "string", "readOnly": true},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, openAPIFieldSchema(tt.field))
+		})
+	}
+}
+
+func TestBuildOpenAPIDocument(t *testing.T) {
+	fields := []SnapshotField{
+		{Name: "title", Type: "string", IsRequired: true},
+		{Name: "views", Type: "integer", IsRequired: true, DefaultValue: "0"},
+		{Name: "secret", Type: "string"},
+		{Name: "score", Type: "computed", ComputedConfig: map[string]interface{}{"type": "number"}},
+	}
+	document := buildOpenAPIDocument([]openAPICollection{
+		{
+			SnapshotCollection: SnapshotCollection{Slug: "blog_posts", Name: "blog_posts", Workflow: true, Fields: fields},
+			ReadFields:         []string{"id", "title", "views", "score", "status"},
+			CreateFields:       []string{"*"},
+		},
+		{
+			SnapshotCollection: SnapshotCollection{Slug: "hidden", Name: "hidden"},
+			CreateFields:       []string{"*"},
+		},
+	})
+
+	// Round-trip through JSON to inspect the document as clients see it
+	raw, err := json.Marshal(document)
+	require.NoError(t, err)
+	var doc struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage        `json:"paths"`
+		Components struct{ Schemas map[string]json.RawMessage } `json:"components"`
+	}
+	require.NoError(t, json.Unmarshal(raw, &doc))
+
+	assert.Equal(t, openAPIVersion, doc.OpenAPI)
+	assert.Contains(t, doc.Paths, "/items/blog_posts")
+	assert.Contains(t, doc.Paths["/items/blog_posts"], "post")
+	assert.NotContains(t, doc.Paths["/items/blog_posts/{id}"], "patch", "no update permission")
+	assert.NotContains(t, doc.Paths["/items/blog_posts/{id}"], "delete", "no delete permission")
+	assert.NotContains(t, doc.Paths, "/items/hidden", "unreadable collections are left out")
+
+	var item struct {
+		Properties map[string]interface{} `json:"properties"`
+	}
+	require.NoError(t, json.Unmarshal(doc.Components.Schemas["BlogPosts"], &item))
+	assert.Contains(t, item.Properties, "status")
+	assert.Contains(t, item.Properties, "score")
+	assert.NotContains(t, item.Properties, "secret", "unreadable fields are left out")
+	assert.NotContains(t, item.Properties, "created_at")
+
+	var create struct {
+		Properties map[string]interface{} `json:"properties"`
+		Required   []string               `json:"required"`
+	}
+	require.NoError(t, json.Unmarshal(doc.Components.Schemas["BlogPostsCreate"], &create))
+	assert.Equal(t, []string{"title"}, create.Required, "fields with defaults are optional")
+	assert.Contains(t, create.Properties, "secret")
+	assert.Contains(t, create.Properties, "publish_at")
+	assert.NotContains(t, create.Properties, "score", "computed fields cannot be written")
+}
+
+func TestOpenAPISchemaName(t *testing.T) {
+	taken := map[string]bool{}
+	assert.Equal(t, "OrderItems", openAPISchemaName("order_items", taken))
+	assert.Equal(t, "OrderItems2", openAPISchemaName("order-items", taken))
+	assert.Equal(t, "Error2", openAPISchemaName("error", taken))
+	assert.Equal(t, "Collection2024", openAPISchemaName("2024", taken))
+}
@@ -0,0 +1,450 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the list pagination helpers shared by every GET /items/:table handler.
+//
+// Two pagination styles are supported:
+//
+//   - Offset pagination: ?limit=&offset= or ?per_page=&page= (1-based). Simple, but the
+//     database still walks every skipped row, so deep pages on large tables get slow.
+//   - Cursor (keyset) pagination: every full page returns meta.next_cursor; pass it back as
+//     ?cursor= to fetch the next page. The cursor encodes the sort column value and ID of the
+//     last row, so the next page is a cheap index range scan regardless of depth.
+//
+// Results are ordered by ?sort=, a comma-separated list of fields each optionally prefixed
+// with - for descending order (e.g. sort=-created_at,name), with the row ID as a final
+// tie-breaker, so pages are deterministic. Unprefixed fields use the direction of ?order=. Counts are only computed when asked for with ?meta=, because
+// COUNT(*) over a large table is as expensive as a deep offset: total_count counts every
+// item the caller may see, filter_count those also matching the request's filters and
+// search (before pagination), and * asks for both.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageLimit = 50  // Page size when no limit is given
+	maxPageLimit     = 500 // Largest page size a client may request
+	maxSortFields    = 5   // Most fields a list can be sorted by, besides the ID
+)
+
+// reservedQueryParams are list parameters that control the response shape and are never
+// treated as column filters
+var reservedQueryParams = map[string]bool{
+	"limit": true, "offset": true, "page": true, "per_page": true,
+	"sort": true, "order": true, "cursor": true, "meta": true, "fields": true,
+	"include_deleted": true, "search": true, "access_token": true,
+	"filter": true, "deep": true,
+}
+
+// pagination holds the parsed paging, sorting and meta options of a list request
+type pagination struct {
+	Limit       int
+	Offset      int
+	Sort        []sortKey // Columns to sort by, before the ID
+	Order       string    // Direction of the ID tie-breaker: ASC or DESC
+	Cursor      *cursor   // Set when the request continues from a cursor
+	TotalCount  bool      // Whether meta.total_count was requested
+	FilterCount bool      // Whether meta.filter_count was requested
+}
+
+// sortKey is one column of a list's ordering
+type sortKey struct {
+	Field string `json:"f"`
+	Desc  bool   `json:"d,omitempty"`
+}
+
+// itemCounts are the counts of a list requested with ?meta=
+type itemCounts struct {
+	Total    int64 // Items the caller may see
+	Filtered int64 // Of those, the items matching the filters and search
+}
+
+// cursor is the decoded form of an opaque ?cursor= token
+type cursor struct {
+	Sort   []sortKey     `json:"s,omitempty"`
+	Order  string        `json:"o"`
+	Values []interface{} `json:"v,omitempty"` // Values of the Sort columns in the last row
+	ID     string        `json:"id"`
+}
+
+// parsePagination reads limit/offset/page/per_page, sort/order, cursor and meta from the
+// query string. Sorting is only honoured for fields the caller is allowed to read, and is
+// refused on fields in transforms, which the caller only reads redacted.
+func parsePagination(c *gin.Context, allowedFields []string, transforms rbac.FieldTransforms) (*pagination, error) {
+	p := &pagination{Limit: defaultPageLimit, Order: "ASC"}
+
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= maxPageLimit {
+			p.Limit = n
+		}
+	}
+	if v := c.Query("per_page"); v != "" { // alias
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= maxPageLimit {
+			p.Limit = n
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n >= 0 {
+			p.Offset = n
+		}
+	}
+	if v := c.Query("page"); v != "" { // 1-based
+		if n, err := strconv.Atoi(v); err == nil && n > 1 {
+			p.Offset = (n - 1) * p.Limit
+		}
+	}
+
+	var err error
+	if p.Sort, p.Order, err = parseSort(c.Query("sort"), c.Query("order"), allowedFields, transforms); err != nil {
+		return nil, err
+	}
+
+	for _, option := range strings.Split(c.Query("meta"), ",") {
+		switch strings.TrimSpace(option) {
+		case "total_count":
+			p.TotalCount = true
+		case "filter_count":
+			p.FilterCount = true
+		case "*":
+			p.TotalCount, p.FilterCount = true, true
+		}
+	}
+
+	if token := c.Query("cursor"); token != "" {
+		if c.Query("offset") != "" || c.Query("page") != "" {
+			return nil, fmt.Errorf("cursor cannot be combined with offset or page")
+		}
+
+		cur, err := decodeCursor(token)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range cur.Sort {
+			if !Contains(allowedFields, key.Field) {
+				return nil, fmt.Errorf("invalid cursor")
+			}
+		}
+		if err := checkRedactedSort(cur.Sort, transforms); err != nil {
+			return nil, err
+		}
+
+		// The cursor carries the ordering it was issued for
+		p.Cursor = cur
+		p.Sort = cur.Sort
+		p.Order = cur.Order
+		p.Offset = 0
+	}
+
+	return p, nil
+}
+
+// parseSort reads a sort expression such as "-created_at,name". Fields without a sign are
+// sorted in the direction of order (ASC unless "desc"). Fields the caller may not read
+// are ignored, and so is everything after "id", which is unique. It returns the sort keys
+// and the direction of the ID tie-breaker: that of "id" when it is listed, otherwise that
+// of the last key. Sorting on a field in transforms is an error.
+func parseSort(expression, order string, allowedFields []string, transforms rbac.FieldTransforms) ([]sortKey, string, error) {
+	defaultDesc := strings.EqualFold(strings.TrimSpace(order), "DESC")
+	idDesc := defaultDesc
+
+	var keys []sortKey
+	for _, part := range strings.Split(expression, ",") {
+		// A + prefix arrives as a space unless it is escaped
+		part = strings.TrimSpace(part)
+		desc := defaultDesc
+		if strings.HasPrefix(part, "-") {
+			desc, part = true, part[1:]
+		} else if strings.HasPrefix(part, "+") {
+			desc, part = false, part[1:]
+		}
+		if part == "" || !rbac.ValidateTableName(part) {
+			continue
+		}
+		if part == "id" {
+			idDesc = desc
+			break
+		}
+		if !Contains(allowedFields, part) || containsSortField(keys, part) {
+			continue
+		}
+		keys = append(keys, sortKey{Field: part, Desc: desc})
+		idDesc = desc
+		if len(keys) == maxSortFields {
+			break
+		}
+	}
+
+	if err := checkRedactedSort(keys, transforms); err != nil {
+		return nil, "", err
+	}
+	if idDesc {
+		return keys, "DESC", nil
+	}
+	return keys, "ASC", nil
+}
+
+// checkRedactedSort refuses orderings on fields the caller only reads redacted: the row
+// order and the values carried by next_cursor would both reveal them
+func checkRedactedSort(keys []sortKey, transforms rbac.FieldTransforms) error {
+	for _, key := range keys {
+		if _, redacted := transforms[key.Field]; redacted {
+			return fmt.Errorf("cannot sort on redacted field '%s'", key.Field)
+		}
+	}
+	return nil
+}
+
+// containsSortField reports whether keys already sort by field
+func containsSortField(keys []sortKey, field string) bool {
+	for _, key := range keys {
+		if key.Field == field {
+			return true
+		}
+	}
+	return false
+}
+
+// sortExpression formats the ordering like the sort parameter, with every direction
+// explicit. parseSort reads it back with p.Order as the order.
+func (p *pagination) sortExpression() string {
+	parts := make([]string, len(p.Sort))
+	for i, key := range p.Sort {
+		parts[i] = key.Field
+		if key.Desc {
+			parts[i] = "-" + key.Field
+		} else {
+			parts[i] = "+" + key.Field
+		}
+	}
+	return strings.Join(parts, ",")
+}
+
+// selectFields returns allowedFields plus the columns needed to build the next cursor.
+// Callers strip the extra columns again with FilterFields.
+func (p *pagination) selectFields(allowedFields []string) []string {
+	if len(allowedFields) == 0 || Contains(allowedFields, "*") {
+		return allowedFields
+	}
+
+	fields := append([]string{}, allowedFields...)
+	if !Contains(fields, "id") {
+		fields = append(fields, "id")
+	}
+	for _, key := range p.Sort {
+		if !Contains(fields, key.Field) {
+			fields = append(fields, key.Field)
+		}
+	}
+	return fields
+}
+
+// keysetCondition returns the WHERE condition that continues after the cursor, using
+// placeholders starting at paramIndex, or an empty string when no cursor is set. Columns
+// sorted in one direction are compared as a row; mixed directions are spelled out column
+// by column.
+func (p *pagination) keysetCondition(paramIndex int) (string, []interface{}) {
+	if p.Cursor == nil {
+		return "", nil
+	}
+
+	columns := make([]string, 0, len(p.Sort)+1)
+	operators := make([]string, 0, len(p.Sort)+1)
+	args := make([]interface{}, 0, len(p.Sort)+1)
+	sameDirection := true
+	for i, key := range p.Sort {
+		columns = append(columns, fmt.Sprintf(`"%s"`, key.Field))
+		operators = append(operators, keysetOperator(key.Desc))
+		args = append(args, cursorParam(p.Cursor.Values[i]))
+		sameDirection = sameDirection && key.Desc == (p.Order == "DESC")
+	}
+	columns = append(columns, "id")
+	operators = append(operators, keysetOperator(p.Order == "DESC"))
+	args = append(args, p.Cursor.ID)
+
+	placeholders := make([]string, len(columns))
+	for i := range columns {
+		placeholders[i] = fmt.Sprintf("$%d", paramIndex+i)
+	}
+
+	if len(columns) == 1 {
+		return fmt.Sprintf("id %s %s", operators[0], placeholders[0]), args
+	}
+	if sameDirection {
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), operators[0], strings.Join(placeholders, ", ")), args
+	}
+
+	// (a > $1) OR (a = $1 AND b < $2) OR (a = $1 AND b = $2 AND id > $3)
+	alternatives := make([]string, len(columns))
+	for i := range columns {
+		terms := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			terms = append(terms, fmt.Sprintf("%s = %s", columns[j], placeholders[j]))
+		}
+		terms = append(terms, fmt.Sprintf("%s %s %s", columns[i], operators[i], placeholders[i]))
+		alternatives[i] = "(" + strings.Join(terms, " AND ") + ")"
+	}
+	return "(" + strings.Join(alternatives, " OR ") + ")", args
+}
+
+// keysetOperator returns the comparison selecting the rows after a value in a direction
+func keysetOperator(desc bool) string {
+	if desc {
+		return "<"
+	}
+	return ">"
+}
+
+// orderClause returns the ORDER BY suffix for the query
+func (p *pagination) orderClause() string {
+	parts := make([]string, 0, len(p.Sort)+1)
+	for _, key := range p.Sort {
+		direction := "ASC"
+		if key.Desc {
+			direction = "DESC"
+		}
+		parts = append(parts, fmt.Sprintf(`"%s" %s`, key.Field, direction))
+	}
+	parts = append(parts, "id "+p.Order)
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+// orderAndLimitClause returns the ORDER BY / LIMIT / OFFSET suffix for the query
+func (p *pagination) orderAndLimitClause() string {
+	return p.orderClause() + fmt.Sprintf(" LIMIT %d OFFSET %d", p.Limit, p.Offset)
+}
+
+// nextCursor builds the cursor for the page after rows, or "" when this is the last page
+func (p *pagination) nextCursor(rows []map[string]interface{}) string {
+	if len(rows) < p.Limit || len(rows) == 0 {
+		return ""
+	}
+
+	last := rows[len(rows)-1]
+	id, exists := last["id"]
+	if !exists || id == nil {
+		return ""
+	}
+
+	cur := cursor{Sort: p.Sort, Order: p.Order, ID: fmt.Sprint(id)}
+	for _, key := range p.Sort {
+		value := last[key.Field]
+		if t, ok := value.(time.Time); ok {
+			value = t.Format(time.RFC3339Nano)
+		}
+		cur.Values = append(cur.Values, value)
+	}
+
+	token, err := encodeCursor(cur)
+	if err != nil {
+		return ""
+	}
+	return token
+}
+
+// writeMeta adds the pagination entries to a list response's meta object. rows must be the
+// unfiltered query results so the cursor can read the ID and sort columns.
+func (p *pagination) writeMeta(meta map[string]interface{}, rows []map[string]interface{}, counts itemCounts) {
+	meta["limit"] = p.Limit
+	meta["offset"] = p.Offset
+	if next := p.nextCursor(rows); next != "" {
+		meta["next_cursor"] = next
+	}
+	if p.TotalCount {
+		meta["total_count"] = counts.Total
+	}
+	if p.FilterCount {
+		meta["filter_count"] = counts.Filtered
+	}
+}
+
+// countItems computes the counts requested with ?meta=. baseConditions and baseArgs scope
+// table to the items the caller may see; conditions and args add the request's filters
+// to them and must start with baseConditions and baseArgs.
+func (h *ItemsHandler) countItems(ctx context.Context, p *pagination, table string, baseConditions []string, baseArgs []interface{}, conditions []string, args []interface{}) (itemCounts, error) {
+	var counts itemCounts
+	var err error
+	if p.TotalCount {
+		if counts.Total, err = h.countRows(ctx, table, baseConditions, baseArgs); err != nil {
+			return counts, err
+		}
+	}
+	if p.FilterCount {
+		// Without filters both counts are the same
+		if p.TotalCount && len(conditions) == len(baseConditions) {
+			counts.Filtered = counts.Total
+		} else if counts.Filtered, err = h.countRows(ctx, table, conditions, args); err != nil {
+			return counts, err
+		}
+	}
+	return counts, nil
+}
+
+// countRows returns the number of rows in table matching the given conditions
+func (h *ItemsHandler) countRows(ctx context.Context, table string, conditions []string, args []interface{}) (int64, error) {
+	query := "SELECT COUNT(*) FROM " + table
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+
+	var count int64
+	if err := h.db.Reader().QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count rows: %w", err)
+	}
+	return count, nil
+}
+
+// encodeCursor serialises a cursor into an opaque URL-safe token
+func encodeCursor(cur cursor) (string, error) {
+	raw, err := json.Marshal(cur)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(raw), nil
+}
+
+// decodeCursor parses a token produced by encodeCursor
+func decodeCursor(token string) (*cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+
+	// Keep numbers as json.Number so large integers survive the round trip
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var cur cursor
+	if err := decoder.Decode(&cur); err != nil {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	if cur.ID == "" || (cur.Order != "ASC" && cur.Order != "DESC") ||
+		len(cur.Values) != len(cur.Sort) || len(cur.Sort) > maxSortFields {
+		return nil, fmt.Errorf("invalid cursor")
+	}
+	for _, key := range cur.Sort {
+		if key.Field == "id" || !rbac.ValidateTableName(key.Field) {
+			return nil, fmt.Errorf("invalid cursor")
+		}
+	}
+
+	return &cur, nil
+}
+
+// cursorParam converts a decoded cursor value into a query parameter
+func cursorParam(value interface{}) interface{} {
+	if number, ok := value.(json.Number); ok {
+		return number.String()
+	}
+	return value
+}
@@ -0,0 +1,179 @@
+package api
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newPaginationContext(rawQuery string) *gin.Context {
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items/products?"+rawQuery, nil)
+	return c
+}
+
+func TestParsePagination(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		p, err := parsePagination(newPaginationContext(""), []string{"*"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, defaultPageLimit, p.Limit)
+		assert.Equal(t, 0, p.Offset)
+		assert.Empty(t, p.Sort)
+		assert.Equal(t, "ASC", p.Order)
+		assert.False(t, p.TotalCount)
+		assert.Equal(t, " ORDER BY id ASC LIMIT 50 OFFSET 0", p.orderAndLimitClause())
+	})
+
+	t.Run("Page And Sort", func(t *testing.T) {
+		p, err := parsePagination(newPaginationContext("per_page=20&page=3&sort=name&order=desc&meta=total_count"), []string{"*"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, 20, p.Limit)
+		assert.Equal(t, 40, p.Offset)
+		assert.True(t, p.TotalCount)
+		assert.Equal(t, ` ORDER BY "name" DESC, id DESC LIMIT 20 OFFSET 40`, p.orderAndLimitClause())
+	})
+
+	t.Run("Multiple Fields", func(t *testing.T) {
+		p, err := parsePagination(newPaginationContext("sort=-created_at,name,-created_at,bogus%20field"), []string{"*"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []sortKey{{Field: "created_at", Desc: true}, {Field: "name"}}, p.Sort)
+		assert.Equal(t, ` ORDER BY "created_at" DESC, "name" ASC, id ASC`, p.orderClause())
+		assert.Equal(t, "-created_at,+name", p.sortExpression())
+
+		// Unsigned fields follow ?order=, and id ends the list
+		p, err = parsePagination(newPaginationContext("sort=%2Bprice,stock,-id,name&order=desc"), []string{"*"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, ` ORDER BY "price" ASC, "stock" DESC, id DESC`, p.orderClause())
+	})
+
+	t.Run("Counts", func(t *testing.T) {
+		p, err := parsePagination(newPaginationContext("meta=filter_count"), []string{"*"}, nil)
+		require.NoError(t, err)
+		assert.False(t, p.TotalCount)
+		assert.True(t, p.FilterCount)
+
+		p, err = parsePagination(newPaginationContext("meta=*"), []string{"*"}, nil)
+		require.NoError(t, err)
+		assert.True(t, p.TotalCount)
+		assert.True(t, p.FilterCount)
+	})
+
+	t.Run("Sort Requires Read Access", func(t *testing.T) {
+		p, err := parsePagination(newPaginationContext("sort=secret"), []string{"id", "name"}, nil)
+		require.NoError(t, err)
+		assert.Empty(t, p.Sort)
+
+		p, err = parsePagination(newPaginationContext("sort=-secret,name"), []string{"id", "name"}, nil)
+		require.NoError(t, err)
+		assert.Equal(t, []sortKey{{Field: "name"}}, p.Sort)
+	})
+
+	t.Run("Sort Refuses Redacted Fields", func(t *testing.T) {
+		transforms := rbac.FieldTransforms{"email": rbac.TransformMaskEmail}
+
+		_, err := parsePagination(newPaginationContext("sort=-email"), []string{"*"}, transforms)
+		assert.ErrorContains(t, err, "redacted field 'email'")
+		_, err = parsePagination(newPaginationContext("sort=name,email"), []string{"*"}, transforms)
+		assert.ErrorContains(t, err, "redacted field 'email'")
+
+		// A cursor issued before the field was redacted, or forged, is refused as well
+		token, err := encodeCursor(cursor{Sort: []sortKey{{Field: "email"}}, Order: "ASC", Values: []interface{}{"alice@example.com"}, ID: "a"})
+		require.NoError(t, err)
+		_, err = parsePagination(newPaginationContext("cursor="+token), []string{"*"}, transforms)
+		assert.ErrorContains(t, err, "redacted field 'email'")
+
+		// Cursors of the orderings still allowed carry no redacted value
+		p, err := parsePagination(newPaginationContext("sort=name&limit=1"), []string{"*"}, transforms)
+		require.NoError(t, err)
+		token = p.nextCursor([]map[string]interface{}{{"id": "a", "name": "Alice", "email": "alice@example.com"}})
+		require.NotEmpty(t, token)
+		raw, err := base64.RawURLEncoding.DecodeString(token)
+		require.NoError(t, err)
+		assert.NotContains(t, string(raw), "alice@example.com")
+	})
+
+	t.Run("Cursor Rejects Offset", func(t *testing.T) {
+		token, err := encodeCursor(cursor{Order: "ASC", ID: "a"})
+		require.NoError(t, err)
+
+		_, err = parsePagination(newPaginationContext("cursor="+token+"&offset=10"), []string{"*"}, nil)
+		assert.Error(t, err)
+	})
+
+	t.Run("Invalid Cursor", func(t *testing.T) {
+		_, err := parsePagination(newPaginationContext("cursor=not-a-cursor"), []string{"*"}, nil)
+		assert.Error(t, err)
+
+		token, err := encodeCursor(cursor{Sort: []sortKey{{Field: "name"}}, Order: "ASC", ID: "a"})
+		require.NoError(t, err)
+		_, err = parsePagination(newPaginationContext("cursor="+token), []string{"*"}, nil)
+		assert.Error(t, err, "a value is needed for every sort field")
+	})
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	first := &pagination{Limit: 2, Sort: []sortKey{{Field: "created_at", Desc: true}}, Order: "DESC"}
+	rows := []map[string]interface{}{
+		{"id": "11111111-1111-1111-1111-111111111111", "created_at": created.Add(time.Hour)},
+		{"id": "22222222-2222-2222-2222-222222222222", "created_at": created},
+	}
+
+	token := first.nextCursor(rows)
+	require.NotEmpty(t, token)
+
+	next, err := parsePagination(newPaginationContext("cursor="+token), []string{"*"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []sortKey{{Field: "created_at", Desc: true}}, next.Sort)
+	assert.Equal(t, "DESC", next.Order)
+
+	condition, args := next.keysetCondition(2)
+	assert.Equal(t, `("created_at", id) < ($2, $3)`, condition)
+	assert.Equal(t, []interface{}{created.Format(time.RFC3339Nano), "22222222-2222-2222-2222-222222222222"}, args)
+
+	t.Run("Last Page Has No Cursor", func(t *testing.T) {
+		assert.Empty(t, first.nextCursor(rows[:1]))
+	})
+
+	t.Run("Mixed Directions", func(t *testing.T) {
+		p := &pagination{Limit: 1, Sort: []sortKey{{Field: "status"}, {Field: "created_at", Desc: true}}, Order: "DESC"}
+		token := p.nextCursor([]map[string]interface{}{{"id": "x", "status": "open", "created_at": created}})
+
+		next, err := parsePagination(newPaginationContext("cursor="+token), []string{"*"}, nil)
+		require.NoError(t, err)
+		condition, args := next.keysetCondition(1)
+		assert.Equal(t, `(("status" > $1) OR ("status" = $1 AND "created_at" < $2) OR ("status" = $1 AND "created_at" = $2 AND id < $3))`, condition)
+		assert.Equal(t, []interface{}{"open", created.Format(time.RFC3339Nano), "x"}, args)
+	})
+
+	t.Run("Numbers Keep Precision", func(t *testing.T) {
+		p := &pagination{Limit: 1, Sort: []sortKey{{Field: "views"}}, Order: "ASC"}
+		token := p.nextCursor([]map[string]interface{}{{"id": "x", "views": int64(9007199254740993)}})
+
+		cur, err := decodeCursor(token)
+		require.NoError(t, err)
+		assert.Equal(t, "9007199254740993", cursorParam(cur.Values[0]))
+	})
+}
+
+func TestWriteMeta(t *testing.T) {
+	p := &pagination{Limit: 1, Offset: 0, Order: "ASC", TotalCount: true}
+	meta := map[string]interface{}{}
+	p.writeMeta(meta, []map[string]interface{}{{"id": "a"}}, itemCounts{Total: 42, Filtered: 7})
+
+	assert.Equal(t, 1, meta["limit"])
+	assert.Equal(t, int64(42), meta["total_count"])
+	assert.NotContains(t, meta, "filter_count", "not requested")
+	assert.NotEmpty(t, meta["next_cursor"])
+
+	p.FilterCount = true
+	p.writeMeta(meta, nil, itemCounts{Total: 42, Filtered: 7})
+	assert.Equal(t, int64(7), meta["filter_count"])
+}
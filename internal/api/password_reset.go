@@ -0,0 +1,217 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains password recovery for users who cannot log in.
+//
+// Password Endpoints:
+// - POST /auth/password/request - Email a reset link to the account
+// - POST /auth/password/reset   - Set a new password with the token from the link
+//
+// Reset tokens are JWTs signed with a key derived from JWT_SECRET, so they cannot be used
+// as access tokens. Each names a row of password_resets, which makes it single-use:
+// resetting the password marks it, and every other open link of the user, as used.
+package api
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"go-rbac-api/internal/audit"
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/mail"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+)
+
+// passwordResetPurpose marks reset tokens
+const passwordResetPurpose = "password_reset"
+
+// passwordResetClaims identify one password reset (ID) of one user (Subject)
+type passwordResetClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+// signPasswordResetToken creates the token sent to the user for a reset
+func signPasswordResetToken(secret string, reset sqlc.PasswordReset) (string, error) {
+	claims := passwordResetClaims{
+		Purpose: passwordResetPurpose,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        reset.ID.String(),
+			Subject:   reset.UserID.String(),
+			ExpiresAt: jwt.NewNumericDate(reset.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(purposeKey(secret, passwordResetPurpose))
+}
+
+// parsePasswordResetToken checks a reset token and returns the reset and user it names
+func parsePasswordResetToken(secret, token string) (resetID, userID uuid.UUID, err error) {
+	var claims passwordResetClaims
+	_, err = jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return purposeKey(secret, passwordResetPurpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return uuid.Nil, uuid.Nil, err
+	}
+	if claims.Purpose != passwordResetPurpose {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("not a password reset token")
+	}
+
+	if resetID, err = uuid.Parse(claims.ID); err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid reset ID")
+	}
+	if userID, err = uuid.Parse(claims.Subject); err != nil {
+		return uuid.Nil, uuid.Nil, fmt.Errorf("invalid user ID")
+	}
+	return resetID, userID, nil
+}
+
+// passwordResetData fills the password reset template for a reset token
+func passwordResetData(cfg *config.Config, token string, expiresAt time.Time) mail.Data {
+	link := token
+	if cfg.PasswordResetURL != "" {
+		link = cfg.PasswordResetURL + "?token=" + url.QueryEscape(token)
+	}
+	return mail.Data{
+		Link:      link,
+		ExpiresAt: expiresAt.UTC().Format(time.RFC1123),
+	}
+}
+
+// RequestPasswordReset handles POST /auth/password/request requests. The response is
+// the same whether or not the account exists.
+// @Summary      Request a password reset
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   models.PasswordResetRequest true "Account email"
+// @Success      202   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Router       /auth/password/request [post]
+func (h *AuthHandler) RequestPasswordReset(c *gin.Context) {
+	var req models.PasswordResetRequest
+	if err := decodeJSON(c, &req, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+
+	accepted := gin.H{"message": "If the account exists, a password reset link has been sent"}
+	ctx := c.Request.Context()
+	logger := middleware.GetLogger(c)
+
+	user, err := h.db.Queries.GetUserByEmail(ctx, req.Email)
+	if err != nil || !user.IsActive.Bool || user.IsServiceAccount {
+		c.JSON(http.StatusAccepted, accepted)
+		return
+	}
+
+	// A new link replaces the ones sent before
+	if err := h.db.Queries.InvalidatePasswordResets(ctx, user.ID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create password reset"})
+		return
+	}
+	reset, err := h.db.Queries.CreatePasswordReset(ctx, sqlc.CreatePasswordResetParams{
+		UserID:    user.ID,
+		ExpiresAt: time.Now().Add(h.cfg.PasswordResetTTL),
+		Ip:        sql.NullString{String: c.ClientIP(), Valid: true},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create password reset"})
+		return
+	}
+	token, err := signPasswordResetToken(h.cfg.JWTSecret, reset)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create password reset"})
+		return
+	}
+
+	// Delivery problems are not reported to the caller, who may not own the account
+	data := passwordResetData(h.cfg, token, reset.ExpiresAt)
+	if err := h.mailer.Send(ctx, user.TenantID.UUID, user.Email, mail.TemplatePasswordReset, data); err != nil {
+		logger.Error("failed to send password reset email", "user_id", user.ID, "error", err)
+	}
+
+	c.JSON(http.StatusAccepted, accepted)
+}
+
+// ResetPassword handles POST /auth/password/reset requests
+// @Summary      Reset a password
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   models.ResetPasswordRequest true "Reset token and new password"
+// @Success      200   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Router       /auth/password/reset [post]
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := decodeJSON(c, &req, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+
+	invalid := gin.H{"error": "Invalid or expired reset token"}
+	ctx := c.Request.Context()
+
+	resetID, userID, err := parsePasswordResetToken(h.cfg.JWTSecret, req.Token)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, invalid)
+		return
+	}
+	reset, err := h.db.Queries.GetPasswordReset(ctx, resetID)
+	if err != nil || reset.UserID != userID || reset.UsedAt.Valid {
+		c.JSON(http.StatusBadRequest, invalid)
+		return
+	}
+
+	// Check the password before using up the token so a rejected password can be retried
+	if err := models.NewPasswordPolicy(h.cfg).Validate(req.NewPassword); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	passwordHash, err := models.HashPassword(req.NewPassword)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+		return
+	}
+
+	// Only one request can use the token
+	used, err := h.db.Queries.UsePasswordReset(ctx, reset.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if used == 0 {
+		c.JSON(http.StatusBadRequest, invalid)
+		return
+	}
+
+	if err := h.db.Queries.SetUserPassword(ctx, sqlc.SetUserPasswordParams{
+		ID:           userID,
+		PasswordHash: passwordHash,
+	}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err := h.db.Queries.InvalidatePasswordResets(ctx, userID); err != nil {
+		middleware.GetLogger(c).Warn("failed to invalidate password resets", "user_id", userID, "error", err)
+	}
+	if err := h.db.Queries.RevokeUserSessions(ctx, userID); err != nil {
+		middleware.GetLogger(c).Warn("failed to revoke sessions", "user_id", userID, "error", err)
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionPasswordReset)
+	entry.UserID = userID
+	entry.StatusCode = http.StatusOK
+	h.audit.Record(ctx, entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset"})
+}
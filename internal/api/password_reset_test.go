@@ -0,0 +1,99 @@
+package api
+
+import (
+	"bytes"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/mail"
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPasswordResetToken(t *testing.T) {
+	reset := sqlc.PasswordReset{ID: uuid.New(), UserID: uuid.New(), ExpiresAt: time.Now().Add(time.Hour)}
+
+	token, err := signPasswordResetToken("secret", reset)
+	require.NoError(t, err)
+
+	resetID, userID, err := parsePasswordResetToken("secret", token)
+	require.NoError(t, err)
+	assert.Equal(t, reset.ID, resetID)
+	assert.Equal(t, reset.UserID, userID)
+
+	_, _, err = parsePasswordResetToken("other-secret", token)
+	assert.Error(t, err)
+
+	reset.ExpiresAt = time.Now().Add(-time.Minute)
+	expired, err := signPasswordResetToken("secret", reset)
+	require.NoError(t, err)
+	_, _, err = parsePasswordResetToken("secret", expired)
+	assert.Error(t, err)
+
+	// Access tokens are signed with a different key and are no reset tokens
+	cfg := &config.Config{JWTSecret: "secret", JWTExpiry: time.Hour}
+	session := &middleware.Session{ID: uuid.New().String(), ExpiresAt: time.Now().Add(cfg.JWTExpiry)}
+	access, err := middleware.GenerateToken(sqlc.User{ID: reset.UserID, Email: "a@example.com"}, session, cfg)
+	require.NoError(t, err)
+	_, _, err = parsePasswordResetToken("secret", access)
+	assert.Error(t, err)
+}
+
+func TestPasswordResetMessage(t *testing.T) {
+	expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	msg, err := mail.Render(mail.TemplatePasswordReset, passwordResetData(&config.Config{PasswordResetURL: "https://app.example.com/reset"}, "a.b+c", expiresAt))
+	require.NoError(t, err)
+	assert.Equal(t, "Reset your password", msg.Subject)
+	assert.Contains(t, msg.Text, "https://app.example.com/reset?token=a.b%2Bc")
+	assert.Contains(t, msg.Text, "Wed, 02 Jan 2030 03:04:05 UTC")
+	assert.Contains(t, msg.HTML, `href="https://app.example.com/reset?token=a.b%2Bc"`)
+
+	msg, err = mail.Render(mail.TemplatePasswordReset, passwordResetData(&config.Config{}, "a.b+c", expiresAt))
+	require.NoError(t, err)
+	assert.Contains(t, msg.Text, "\n\na.b+c\n\n")
+}
+
+func TestAuthHandler_ResetPassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWTSecret: "test-secret-key"}
+	handler := NewAuthHandler(newOfflineDB(t), cfg, mail.NewMailer(mail.NewLogSender(slog.Default()), mail.Address{}, nil))
+
+	router := gin.New()
+	router.POST("/auth/password/reset", handler.ResetPassword)
+	router.POST("/auth/password/request", handler.RequestPasswordReset)
+
+	tests := []struct {
+		name   string
+		path   string
+		body   string
+		status int
+		error  string
+	}{
+		{"missing new password", "/auth/password/reset", `{"token": "x"}`, http.StatusBadRequest, "Invalid request body"},
+		{"forged token", "/auth/password/reset", `{"token": "x.y.z", "new_password": "long-enough"}`, http.StatusBadRequest, "Invalid or expired reset token"},
+		{"invalid email", "/auth/password/request", `{"email": "nope"}`, http.StatusBadRequest, "Invalid request body"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, tt.path, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.error), w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,93 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains PII tags: the kind of personal data a field declares it holds.
+//
+// A field created or updated with "pii" names the kind of personal data in it:
+//
+//	{"name": "email", "type": "string", "pii": "email"}
+//	{"name": "customer_ref", "type": "string", "pii": "identifier"}
+//
+// Tags do not change how values are stored or read. They tell privacy erasure (see
+// privacy_erasure.go) where a person's data lives: email fields and identifier fields find
+// the person's items, and every tagged field of those items is cleared. Clearing sets a
+// value to NULL, so tags go on fields with a column of their own, and required tagged
+// fields must be string or text fields that can take a placeholder instead.
+package api
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// PII kinds a field can be tagged with
+const (
+	PIIEmail      = "email"
+	PIIName       = "name"
+	PIIPhone      = "phone"
+	PIIAddress    = "address"
+	PIIIP         = "ip"
+	PIIDate       = "date"       // Such as a birth date
+	PIIIdentifier = "identifier" // The person's ID in another system, or a user ID
+	PIIOther      = "other"
+)
+
+// validPIIKind reports whether kind is a known PII kind
+func validPIIKind(kind string) bool {
+	switch kind {
+	case PIIEmail, PIIName, PIIPhone, PIIAddress, PIIIP, PIIDate, PIIIdentifier, PIIOther:
+		return true
+	}
+	return false
+}
+
+// checkPIIField refuses PII tags that erasure could not clear: unknown kinds, tags on
+// relation and computed fields, and required fields that cannot hold a placeholder.
+// An empty kind is no tag.
+func checkPIIField(kind, fieldType string, required bool) error {
+	switch {
+	case kind == "":
+		return nil
+	case !validPIIKind(kind):
+		return validationError("unknown pii kind '%s': must be email, name, phone, address, ip, date, identifier or other", kind)
+	case fieldType == "relation" || fieldType == "computed":
+		return validationError("%s fields cannot be tagged as pii; tag the fields holding the data", fieldType)
+	case required && fieldType != "string" && fieldType != "text":
+		return validationError("required pii fields must be string or text fields, so erasure can replace their values")
+	}
+	return nil
+}
+
+// piiField is a field tagged with a PII kind
+type piiField struct {
+	Name      string
+	Kind      string
+	Type      string
+	Required  bool
+	Encrypted bool
+}
+
+// piiFields returns the PII-tagged fields of a collection in field order
+func (d *DynamicHandlers) piiFields(ctx context.Context, tenantID uuid.UUID, collectionSlug string) ([]piiField, error) {
+	rows, err := d.db.QueryContext(ctx, `
+		SELECT f.name, f.pii, f.type, COALESCE(f.is_required, false), f.is_encrypted
+		FROM fields f
+		JOIN collections c ON c.id = f.collection_id
+		WHERE c.slug = $1 AND c.tenant_id = $2 AND f.pii IS NOT NULL
+		ORDER BY f.sort_order, f.name
+	`, collectionSlug, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch pii fields: %w", err)
+	}
+	defer rows.Close()
+
+	var fields []piiField
+	for rows.Next() {
+		var field piiField
+		if err := rows.Scan(&field.Name, &field.Kind, &field.Type, &field.Required, &field.Encrypted); err != nil {
+			return nil, fmt.Errorf("failed to scan pii field: %w", err)
+		}
+		fields = append(fields, field)
+	}
+	return fields, rows.Err()
+}
@@ -0,0 +1,20 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckPIIField(t *testing.T) {
+	assert.NoError(t, checkPIIField("", "relation", true), "no tag")
+	assert.NoError(t, checkPIIField(PIIEmail, "string", true))
+	assert.NoError(t, checkPIIField(PIIName, "text", false))
+	assert.NoError(t, checkPIIField(PIIDate, "date", false))
+	assert.NoError(t, checkPIIField(PIIIdentifier, "uuid", false))
+
+	assert.ErrorContains(t, checkPIIField("ssn", "string", false), "unknown pii kind")
+	assert.Error(t, checkPIIField(PIIEmail, "relation", false))
+	assert.Error(t, checkPIIField(PIIOther, "computed", false))
+	assert.ErrorContains(t, checkPIIField(PIIDate, "date", true), "string or text")
+}
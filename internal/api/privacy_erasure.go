@@ -0,0 +1,481 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains privacy erasure: removing a person's data from a tenant on request.
+//
+// Privacy Erasure Endpoints:
+// - POST /privacy/erasure        - Erase or anonymize a person's items across collections
+// - POST /privacy/erasure/verify - Check the signature of an erasure report
+//
+// The person is named by email, by user ID or both; a user ID of a user with an account
+// adds that user's email. Items are found through the PII tags of fields (see
+// pii_fields.go): an item belongs to the person when one of its email fields holds the
+// email (in any case) or one of its identifier fields holds the user ID. Encrypted fields
+// cannot be compared, so they never find items, but they are cleared like the others.
+//
+// In "delete" mode the items are removed for good, trash or not. In "anonymize" mode the
+// items stay and their PII fields are cleared: set to NULL, or for required fields to a
+// placeholder such as erased-<item id>@erased.invalid. Either way the revisions of the
+// items are deleted, since they keep copies of the erased values, and created_by and
+// updated_by stop pointing at the user in every collection. Everything happens in one
+// transaction; with "dry_run" nothing is written and the report shows what would be.
+//
+// The response is a report of what was erased, signed with HMAC-SHA256 so it can be kept
+// as a record and checked later. It names the email by its SHA-256 hash only. The user
+// account itself is not touched; delete it with DELETE /auth/users/:id.
+package api
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/audit"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Erasure modes
+const (
+	ErasureDelete    = "delete"
+	ErasureAnonymize = "anonymize"
+)
+
+const (
+	// erasureReportPurpose derives the key erasure reports are signed with
+	erasureReportPurpose = "privacy-erasure-report"
+
+	// erasedDomain is the domain of placeholder emails; .invalid is reserved (RFC 2606)
+	erasedDomain = "erased.invalid"
+)
+
+// ErasureRequest is the body of POST /privacy/erasure
+type ErasureRequest struct {
+	Email  string `json:"email,omitempty"`
+	UserID string `json:"user_id,omitempty"`
+	Mode   string `json:"mode"`              // delete or anonymize
+	DryRun bool   `json:"dry_run,omitempty"` // Report without erasing anything
+}
+
+// ErasureReport records what an erasure found and did
+type ErasureReport struct {
+	ID          uuid.UUID          `json:"id"`
+	TenantID    uuid.UUID          `json:"tenant_id"`
+	Subject     ErasureSubject     `json:"subject"`
+	Mode        string             `json:"mode"`
+	DryRun      bool               `json:"dry_run"`
+	RequestedBy uuid.UUID          `json:"requested_by"`
+	CompletedAt time.Time          `json:"completed_at"`
+	Collections []ErasedCollection `json:"collections"` // Only collections where something was found
+	Items       int                `json:"items"`       // Items erased across all collections
+}
+
+// ErasureSubject identifies the person whose data was erased
+type ErasureSubject struct {
+	EmailSHA256 string `json:"email_sha256,omitempty"` // Of the lowercased email
+	UserID      string `json:"user_id,omitempty"`
+}
+
+// ErasedCollection is what an erasure did in one collection
+type ErasedCollection struct {
+	Collection string   `json:"collection"`
+	Fields     []string `json:"fields,omitempty"`   // PII fields cleared (anonymize mode)
+	ItemIDs    []string `json:"item_ids,omitempty"` // Items deleted or anonymized
+	Revisions  int64    `json:"revisions"`          // Revisions deleted along with them
+	Unlinked   int64    `json:"unlinked"`           // Items no longer created or updated by the user
+}
+
+// SignedErasureReport is an erasure report with its signature
+type SignedErasureReport struct {
+	Report    ErasureReport `json:"report"`
+	Signature string        `json:"signature"` // Hex HMAC-SHA256 of the report's JSON
+}
+
+// erasureSubject is the person an erasure looks for
+type erasureSubject struct {
+	Email  string // Lowercased; empty when unknown
+	UserID string // Empty when unknown
+}
+
+// parseErasureRequest validates an erasure request and returns the subject it names
+func parseErasureRequest(req ErasureRequest) (erasureSubject, error) {
+	subject := erasureSubject{Email: strings.ToLower(strings.TrimSpace(req.Email))}
+	if req.UserID != "" {
+		id, err := uuid.Parse(req.UserID)
+		if err != nil {
+			return subject, validationError("invalid user_id %q", req.UserID)
+		}
+		subject.UserID = id.String()
+	}
+
+	switch {
+	case subject.Email == "" && subject.UserID == "":
+		return subject, validationError("email or user_id is required")
+	case subject.Email != "" && !strings.Contains(subject.Email, "@"):
+		return subject, validationError("invalid email %q", req.Email)
+	case req.Mode != ErasureDelete && req.Mode != ErasureAnonymize:
+		return subject, validationError("mode must be delete or anonymize")
+	}
+	return subject, nil
+}
+
+// EraseSubject handles POST /privacy/erasure requests. Only admins of the tenant may erase
+// data, since erasure reaches every collection regardless of permissions.
+//
+// Response Format:
+//   - 200: {"data": {"report": {...}, "signature": "..."}}
+//   - 400: Invalid body, or an item could not be erased (such as a restricted delete)
+//   - 401: Missing or invalid authentication token
+//   - 403: User is not an admin of the tenant
+//
+// @Summary      Erase a person's data
+// @Tags         privacy
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Delete or anonymize the items holding a person's data, found through PII field tags, and return a signed report.
+// @Param        body  body  api.ErasureRequest true "Email or user ID of the person, and the mode"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} api.SignedErasureReport
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /privacy/erasure [post]
+func (h *ItemsHandler) EraseSubject(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	auth, ok := middleware.GetAuthProvider(c)
+	if !ok || !(auth.IsAdmin || auth.IsSuperAdmin) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins of the tenant can erase personal data"})
+		return
+	}
+	tenantID, _ := middleware.GetTenantID(c)
+
+	var req ErasureRequest
+	if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body: "+err.Error())
+		return
+	}
+	subject, err := parseErasureRequest(req)
+	if err != nil {
+		respondError(c, err, "Invalid erasure request")
+		return
+	}
+
+	ctx := c.Request.Context()
+	subject, err = h.dynamicHandlers.resolveErasureSubject(ctx, tenantID, subject)
+	if err != nil {
+		respondError(c, err, "Failed to look up user")
+		return
+	}
+	collections, err := h.dynamicHandlers.eraseSubject(ctx, userID, tenantID, subject, req.Mode, req.DryRun)
+	if err != nil {
+		respondError(c, err, "Failed to erase personal data")
+		return
+	}
+
+	report := ErasureReport{
+		ID:          uuid.New(),
+		TenantID:    tenantID,
+		Subject:     ErasureSubject{UserID: subject.UserID},
+		Mode:        req.Mode,
+		DryRun:      req.DryRun,
+		RequestedBy: userID,
+		CompletedAt: time.Now().UTC(),
+		Collections: collections,
+	}
+	if subject.Email != "" {
+		sum := sha256.Sum256([]byte(subject.Email))
+		report.Subject.EmailSHA256 = hex.EncodeToString(sum[:])
+	}
+	for _, collection := range collections {
+		report.Items += len(collection.ItemIDs)
+	}
+
+	signature, err := signErasureReport(h.cfg.JWTSecret, report)
+	if err != nil {
+		respondError(c, err, "Failed to sign erasure report")
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionPrivacyErasure)
+	entry.StatusCode = http.StatusOK
+	entry.Details = map[string]interface{}{"report_id": report.ID, "mode": report.Mode, "dry_run": report.DryRun, "items": report.Items, "signature": signature}
+	audit.NewLogger(h.db).Record(ctx, entry)
+
+	c.JSON(http.StatusOK, gin.H{"data": SignedErasureReport{Report: report, Signature: signature}})
+}
+
+// VerifyErasureReport handles POST /privacy/erasure/verify requests, telling whether a
+// report is unchanged since this instance signed it
+//
+// @Summary      Verify an erasure report
+// @Tags         privacy
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        body  body  api.SignedErasureReport true "Report and signature as returned by POST /privacy/erasure"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Router       /privacy/erasure/verify [post]
+func (h *ItemsHandler) VerifyErasureReport(c *gin.Context) {
+	var signed SignedErasureReport
+	if err := decodeJSON(c, &signed, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body: "+err.Error())
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": gin.H{"valid": verifyErasureReport(h.cfg.JWTSecret, signed)}})
+}
+
+// signErasureReport returns the hex HMAC-SHA256 of a report's JSON
+func signErasureReport(secret string, report ErasureReport) (string, error) {
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode erasure report: %w", err)
+	}
+	mac := hmac.New(sha256.New, purposeKey(secret, erasureReportPurpose))
+	mac.Write(raw)
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+// verifyErasureReport reports whether signed carries the signature of its report
+func verifyErasureReport(secret string, signed SignedErasureReport) bool {
+	expected, err := signErasureReport(secret, signed.Report)
+	if err != nil {
+		return false
+	}
+	return hmac.Equal([]byte(expected), []byte(strings.ToLower(signed.Signature)))
+}
+
+// resolveErasureSubject adds the email of the subject's user account, if it has one in
+// the tenant, to the subject
+func (d *DynamicHandlers) resolveErasureSubject(ctx context.Context, tenantID uuid.UUID, subject erasureSubject) (erasureSubject, error) {
+	if subject.UserID == "" || subject.Email != "" {
+		return subject, nil
+	}
+	user, err := d.db.Queries.GetUserByID(ctx, uuid.MustParse(subject.UserID))
+	if errors.Is(err, sql.ErrNoRows) {
+		return subject, nil // Users deleted before their data are still found by identifier
+	}
+	if err != nil {
+		return subject, err
+	}
+	if user.TenantID.UUID != tenantID {
+		_, err := d.db.Queries.GetUserTenant(ctx, sqlc.GetUserTenantParams{UserID: user.ID, TenantID: tenantID})
+		if errors.Is(err, sql.ErrNoRows) {
+			return subject, nil // The email of another tenant's user is not given away
+		}
+		if err != nil {
+			return subject, err
+		}
+	}
+	subject.Email = strings.ToLower(user.Email)
+	return subject, nil
+}
+
+// eraseSubject deletes or anonymizes the subject's items in every collection of the
+// tenant in one transaction, and returns what was erased per collection. With dryRun the
+// items are only looked up.
+func (d *DynamicHandlers) eraseSubject(ctx context.Context, userID, tenantID uuid.UUID, subject erasureSubject, mode string, dryRun bool) ([]ErasedCollection, error) {
+	collections, err := d.db.Queries.GetCollectionsByTenant(ctx, uuid.NullUUID{UUID: tenantID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list collections: %w", err)
+	}
+
+	var erased []ErasedCollection
+	var updates [][]map[string]interface{}
+	err = d.inTransaction(ctx, userID, tenantID, func(tx *sql.Tx) error {
+		erased, updates = []ErasedCollection{}, nil
+		for _, collection := range collections {
+			result, values, err := d.eraseInCollection(ctx, tx, userID, tenantID, collection, subject, mode, dryRun)
+			if err != nil {
+				return wrapError(err, "collection %s", collection.Slug)
+			}
+			if len(result.ItemIDs) > 0 || result.Unlinked > 0 {
+				erased = append(erased, result)
+				updates = append(updates, values)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for i, result := range erased {
+		switch {
+		case dryRun || len(result.ItemIDs) == 0:
+		case mode == ErasureDelete:
+			d.publish(ctx, events.ItemDelete, userID, tenantID, result.Collection, result.ItemIDs, nil)
+		default:
+			d.publish(ctx, events.ItemUpdate, userID, tenantID, result.Collection, result.ItemIDs, updates[i])
+		}
+	}
+	return erased, nil
+}
+
+// eraseInCollection erases the subject's items in one collection on tx. In anonymize mode
+// it also returns the values written to each item, for the item.update event.
+func (d *DynamicHandlers) eraseInCollection(ctx context.Context, tx *sql.Tx, userID, tenantID uuid.UUID, collection sqlc.Collection, subject erasureSubject, mode string, dryRun bool) (ErasedCollection, []map[string]interface{}, error) {
+	result := ErasedCollection{Collection: collection.Slug}
+
+	table, err := d.utils.ResolveDataTable(ctx, tenantID, collection.Slug)
+	if errors.Is(err, ErrDataTableNotFound) {
+		return result, nil, nil
+	}
+	if err != nil {
+		return result, nil, err
+	}
+	fields, err := d.piiFields(ctx, tenantID, collection.Slug)
+	if err != nil {
+		return result, nil, err
+	}
+	flags := flagsOf(collection)
+
+	if condition, args := erasureMatch(fields, subject, 2); condition != "" {
+		query := fmt.Sprintf("SELECT id FROM %s WHERE tenant_id = $1 AND (%s) ORDER BY id FOR UPDATE", table, condition)
+		rows, err := tx.QueryContext(ctx, query, append([]interface{}{tenantID}, args...)...)
+		if err != nil {
+			return result, nil, fmt.Errorf("failed to find items: %w", err)
+		}
+		for rows.Next() {
+			var itemID string
+			if err := rows.Scan(&itemID); err != nil {
+				rows.Close()
+				return result, nil, fmt.Errorf("failed to scan item: %w", err)
+			}
+			result.ItemIDs = append(result.ItemIDs, itemID)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return result, nil, fmt.Errorf("failed to find items: %w", err)
+		}
+	}
+	if mode == ErasureAnonymize && len(result.ItemIDs) > 0 {
+		for _, field := range fields {
+			result.Fields = append(result.Fields, field.Name)
+		}
+	}
+
+	if dryRun {
+		if flags.UserStamps && subject.UserID != "" {
+			query := fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE created_by = $1 OR updated_by = $1", table)
+			if err := tx.QueryRowContext(ctx, query, subject.UserID).Scan(&result.Unlinked); err != nil {
+				return result, nil, fmt.Errorf("failed to count items of the user: %w", err)
+			}
+		}
+		return result, nil, nil
+	}
+
+	var encrypted []string
+	for _, field := range fields {
+		if field.Encrypted {
+			encrypted = append(encrypted, field.Name)
+		}
+	}
+	// Trashed items are erased too, so the update must not skip them
+	writeFlags := flags
+	writeFlags.SoftDelete = false
+
+	queries := d.db.Queries.WithTx(tx)
+	var updates []map[string]interface{}
+	for _, itemID := range result.ItemIDs {
+		if mode == ErasureDelete {
+			err = d.deleteRow(ctx, tx, table.String(), itemID, false)
+		} else {
+			var values map[string]interface{}
+			if values, err = encryptValues(encrypted, anonymizedValues(fields, itemID)); err == nil {
+				err = d.updateRow(ctx, tx, table.String(), userID, itemID, values, writeFlags)
+				updates = append(updates, withID(values, itemID))
+			}
+		}
+		if err != nil {
+			return result, nil, wrapError(err, "item %s", itemID)
+		}
+
+		deleted, err := queries.DeleteItemRevisions(ctx, sqlc.DeleteItemRevisionsParams{
+			TenantID:   tenantID,
+			Collection: collection.Slug,
+			ItemID:     uuid.MustParse(itemID),
+		})
+		if err != nil {
+			return result, nil, fmt.Errorf("failed to delete revisions of item %s: %w", itemID, err)
+		}
+		result.Revisions += deleted
+	}
+
+	if flags.UserStamps && subject.UserID != "" {
+		query := fmt.Sprintf(`UPDATE %s SET
+			created_by = CASE WHEN created_by = $1 THEN NULL ELSE created_by END,
+			updated_by = CASE WHEN updated_by = $1 THEN NULL ELSE updated_by END
+			WHERE created_by = $1 OR updated_by = $1`, table)
+		unlinked, err := tx.ExecContext(ctx, query, subject.UserID)
+		if err != nil {
+			return result, nil, fmt.Errorf("failed to unlink items of the user: %w", err)
+		}
+		result.Unlinked, _ = unlinked.RowsAffected()
+	}
+	return result, updates, nil
+}
+
+// erasureMatch returns the condition matching the subject's items through the email and
+// identifier fields, with its parameters numbered from paramIndex. The condition is empty
+// when no field can find the subject.
+func erasureMatch(fields []piiField, subject erasureSubject, paramIndex int) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	emailParam, userParam := 0, 0
+
+	for _, field := range fields {
+		if field.Encrypted {
+			continue
+		}
+		switch {
+		case field.Kind == PIIEmail && subject.Email != "":
+			if emailParam == 0 {
+				emailParam = paramIndex + len(args)
+				args = append(args, subject.Email)
+			}
+			conditions = append(conditions, fmt.Sprintf(`lower("%s"::text) = $%d`, field.Name, emailParam))
+		case field.Kind == PIIIdentifier && subject.UserID != "":
+			if userParam == 0 {
+				userParam = paramIndex + len(args)
+				args = append(args, subject.UserID)
+			}
+			conditions = append(conditions, fmt.Sprintf(`"%s"::text = $%d`, field.Name, userParam))
+		}
+	}
+	return strings.Join(conditions, " OR "), args
+}
+
+// anonymizedValues returns the values clearing the PII fields of an item: NULL, or a
+// placeholder naming the item for required fields, which may also be unique
+func anonymizedValues(fields []piiField, itemID string) map[string]interface{} {
+	values := make(map[string]interface{}, len(fields))
+	for _, field := range fields {
+		switch {
+		case !field.Required:
+			values[field.Name] = nil
+		case field.Kind == PIIEmail:
+			values[field.Name] = fmt.Sprintf("erased-%s@%s", itemID, erasedDomain)
+		default:
+			values[field.Name] = "erased-" + itemID
+		}
+	}
+	return values
+}
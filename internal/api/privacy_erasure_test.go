@@ -0,0 +1,93 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseErasureRequest(t *testing.T) {
+	userID := uuid.New()
+
+	subject, err := parseErasureRequest(ErasureRequest{Email: " Ada@Example.com ", Mode: ErasureDelete})
+	require.NoError(t, err)
+	assert.Equal(t, erasureSubject{Email: "ada@example.com"}, subject)
+
+	subject, err = parseErasureRequest(ErasureRequest{UserID: userID.String(), Mode: ErasureAnonymize})
+	require.NoError(t, err)
+	assert.Equal(t, erasureSubject{UserID: userID.String()}, subject)
+
+	for name, req := range map[string]ErasureRequest{
+		"no subject":   {Mode: ErasureDelete},
+		"bad user ID":  {UserID: "42", Mode: ErasureDelete},
+		"bad email":    {Email: "ada", Mode: ErasureDelete},
+		"missing mode": {Email: "ada@example.com"},
+		"unknown mode": {Email: "ada@example.com", Mode: "forget"},
+	} {
+		_, err := parseErasureRequest(req)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestErasureMatch(t *testing.T) {
+	fields := []piiField{
+		{Name: "email", Kind: PIIEmail},
+		{Name: "name", Kind: PIIName},
+		{Name: "backup_email", Kind: PIIEmail},
+		{Name: "secret_email", Kind: PIIEmail, Encrypted: true},
+		{Name: "account", Kind: PIIIdentifier},
+	}
+	subject := erasureSubject{Email: "ada@example.com", UserID: "6f1c0d3e-8d7a-4a4e-9a57-2d9f8d1c2b3a"}
+
+	condition, args := erasureMatch(fields, subject, 2)
+	assert.Equal(t, `lower("email"::text) = $2 OR lower("backup_email"::text) = $2 OR "account"::text = $3`, condition)
+	assert.Equal(t, []interface{}{subject.Email, subject.UserID}, args)
+
+	condition, args = erasureMatch(fields, erasureSubject{UserID: subject.UserID}, 2)
+	assert.Equal(t, `"account"::text = $2`, condition)
+	assert.Equal(t, []interface{}{subject.UserID}, args)
+
+	condition, args = erasureMatch(fields[:2], erasureSubject{UserID: subject.UserID}, 2)
+	assert.Empty(t, condition, "nothing can find the subject")
+	assert.Empty(t, args)
+}
+
+func TestAnonymizedValues(t *testing.T) {
+	itemID := "0b6c9f1e-5a43-4f0e-8a52-41a0c9a7e6d1"
+	values := anonymizedValues([]piiField{
+		{Name: "email", Kind: PIIEmail, Required: true},
+		{Name: "name", Kind: PIIName, Required: true},
+		{Name: "phone", Kind: PIIPhone},
+	}, itemID)
+
+	assert.Equal(t, map[string]interface{}{
+		"email": "erased-" + itemID + "@erased.invalid",
+		"name":  "erased-" + itemID,
+		"phone": nil,
+	}, values)
+}
+
+func TestErasureReportSignature(t *testing.T) {
+	report := ErasureReport{
+		ID:          uuid.New(),
+		TenantID:    uuid.New(),
+		Subject:     ErasureSubject{EmailSHA256: "abc"},
+		Mode:        ErasureDelete,
+		RequestedBy: uuid.New(),
+		CompletedAt: time.Now().UTC(),
+		Collections: []ErasedCollection{{Collection: "customers", ItemIDs: []string{uuid.NewString()}, Revisions: 3}},
+		Items:       1,
+	}
+
+	signature, err := signErasureReport("secret", report)
+	require.NoError(t, err)
+	assert.True(t, verifyErasureReport("secret", SignedErasureReport{Report: report, Signature: signature}))
+	assert.False(t, verifyErasureReport("other secret", SignedErasureReport{Report: report, Signature: signature}))
+
+	tampered := report
+	tampered.Items = 0
+	assert.False(t, verifyErasureReport("secret", SignedErasureReport{Report: tampered, Signature: signature}))
+}
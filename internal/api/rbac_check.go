@@ -0,0 +1,261 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the access check, which explains how a permission check is decided.
+//
+// RBAC Endpoints:
+// - POST /rbac/check - Can a user do an action on a table, and optionally on one item?
+//
+// The answer names the role and permission that granted access, the fields it grants and the
+// row rule that limits it, so a denied request can be debugged without reading the policies.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"net/http"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Reasons an access check gives for its answer
+const (
+	accessReasonAdmin        = "admin"         // The user holds the admin role
+	accessReasonPermission   = "permission"    // A permission of one of the user's roles grants it
+	accessReasonNoPermission = "no_permission" // None of the user's roles has a permission for it
+	accessReasonInvalidRule  = "invalid_rule"  // The matching permission's row rule does not compile
+	accessReasonRowFilter    = "row_filter"    // The item is outside the permission's row rule
+	accessReasonItemNotFound = "item_not_found"
+)
+
+// accessCheckRequest is the body of POST /rbac/check
+type accessCheckRequest struct {
+	UserID *uuid.UUID `json:"user_id"` // Defaults to the caller
+	Table  string     `json:"table"`
+	Action string     `json:"action"`
+	ItemID *uuid.UUID `json:"item_id"`
+}
+
+// accessItem is whether the item of an access check exists and is within the row rule
+type accessItem struct {
+	ID      uuid.UUID `json:"id"`
+	Exists  bool      `json:"exists"`
+	Matches bool      `json:"matches"`
+}
+
+// validate checks the table and action of the request
+func (r accessCheckRequest) validate() error {
+	if r.Table == "" || !rbac.ValidateTableName(r.Table) {
+		return validationError("invalid table %q", r.Table)
+	}
+	if !Contains(permissionActions, r.Action) {
+		return validationError("invalid action %q: must be one of create, read, update, delete, purge or decrypt", r.Action)
+	}
+	return nil
+}
+
+// accessReason names why an explanation allows or denies access
+func accessReason(explanation *rbac.Explanation) string {
+	switch {
+	case explanation.Admin:
+		return accessReasonAdmin
+	case explanation.RuleError != nil:
+		return accessReasonInvalidRule
+	case explanation.Allowed:
+		return accessReasonPermission
+	default:
+		return accessReasonNoPermission
+	}
+}
+
+// accessResponse renders an explanation, and the item check if there was one
+func accessResponse(userID uuid.UUID, req accessCheckRequest, explanation *rbac.Explanation, item *accessItem) gin.H {
+	allowed, reason := explanation.Allowed, accessReason(explanation)
+	if allowed && item != nil {
+		if !item.Exists {
+			allowed, reason = false, accessReasonItemNotFound
+		} else if !item.Matches {
+			allowed, reason = false, accessReasonRowFilter
+		}
+	}
+
+	roles := make([]string, 0, len(explanation.Roles))
+	for _, role := range explanation.Roles {
+		roles = append(roles, role.Name)
+	}
+	data := gin.H{
+		"allowed":    allowed,
+		"reason":     reason,
+		"user_id":    userID,
+		"table":      req.Table,
+		"action":     req.Action,
+		"roles":      roles,
+		"role":       nil,
+		"permission": nil,
+		"fields":     explanation.Fields,
+		"transforms": explanation.Transforms,
+		"row_filter": nil,
+	}
+	if explanation.Role != nil {
+		data["role"] = explanation.Role.Name
+	}
+	if permission := explanation.Permission; permission != nil {
+		data["permission"] = accessPermission(permission)
+		if rule := rbac.PermissionRule(*permission); rule != nil {
+			rowFilter := gin.H{"rule": rule}
+			if explanation.RuleError != nil {
+				rowFilter["error"] = explanation.RuleError.Error()
+			} else if explanation.RowFilter != nil {
+				condition, args := explanation.RowFilter.SQL(1)
+				rowFilter["sql"], rowFilter["args"] = condition, args
+			}
+			data["row_filter"] = rowFilter
+		}
+	}
+	if item != nil {
+		data["item"] = item
+	}
+	return data
+}
+
+// accessPermission renders the permission that decided an access check
+func accessPermission(permission *sqlc.Permission) gin.H {
+	rendered := gin.H{
+		"id":               permission.ID,
+		"allowed_fields":   permission.AllowedFields,
+		"read_fields":      permission.ReadFields,
+		"write_fields":     permission.WriteFields,
+		"field_filter":     nil,
+		"scope":            permission.Scope,
+		"field_transforms": nil,
+	}
+	if permission.FieldFilter.Valid {
+		rendered["field_filter"] = json.RawMessage(permission.FieldFilter.RawMessage)
+	}
+	if permission.FieldTransforms.Valid {
+		rendered["field_transforms"] = json.RawMessage(permission.FieldTransforms.RawMessage)
+	}
+	return rendered
+}
+
+// checkItemAccess looks up an item of a collection and whether the row filter admits it
+func (h *ItemsHandler) checkItemAccess(ctx context.Context, tenantID uuid.UUID, slug string, itemID uuid.UUID, filter *rbac.RowFilter) (*accessItem, error) {
+	if h.isSchemaTable(slug) {
+		return nil, validationError("item checks are only supported on collections")
+	}
+	table, err := h.utils.ResolveDataTable(ctx, tenantID, slug)
+	if err != nil {
+		return nil, err
+	}
+
+	item := &accessItem{ID: itemID}
+	query := "SELECT EXISTS(SELECT 1 FROM " + table.String() + " WHERE id = $1)"
+	if err := h.db.Reader().QueryRowContext(ctx, query, itemID).Scan(&item.Exists); err != nil {
+		return nil, wrapError(err, "failed to look up item")
+	}
+	if !item.Exists || filter == nil {
+		item.Matches = item.Exists
+		return item, nil
+	}
+
+	condition, args := filter.SQL(2)
+	query = "SELECT EXISTS(SELECT 1 FROM " + table.String() + " WHERE id = $1 AND " + condition + ")"
+	if err := h.db.Reader().QueryRowContext(ctx, query, append([]interface{}{itemID}, args...)...).Scan(&item.Matches); err != nil {
+		return nil, wrapError(err, "failed to evaluate row rule")
+	}
+	return item, nil
+}
+
+// CheckAccess handles POST /rbac/check requests.
+//
+// Explains whether a user of the caller's tenant may do an action on a table, and on one item
+// of it when item_id is given. Permissions are read from the database, bypassing the cache
+// and the scopes of the caller's API key. Checking another user needs read permission on
+// roles and permissions.
+//
+// Response Format:
+//   - 200: {"data": {"allowed": true, "reason": "permission", "role": "editor", "permission": {...}, "fields": [...], "row_filter": {...}}}
+//   - 400: Invalid request body
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on roles or permissions
+//   - 404: User or collection not found
+//   - 422: Invalid table or action
+//
+// @Summary      Explain a permission check
+// @Tags         rbac
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Whether a user may do an action on a table (or one item), with the role, permission, fields and row rule that decided it. Body: {"user_id"?, "table", "action", "item_id"?}.
+// @Param        body body map[string]interface{} true "Access to check"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
+// @Router       /rbac/check [post]
+func (h *ItemsHandler) CheckAccess(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req accessCheckRequest
+	if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	if err := req.validate(); err != nil {
+		respondError(c, err, "Invalid access check")
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID, ok := middleware.GetTenantID(c)
+	if !ok || tenantID == uuid.Nil {
+		var err error
+		if tenantID, err = h.utils.GetUserTenantID(ctx, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve tenant"})
+			return
+		}
+	}
+
+	target := userID
+	if req.UserID != nil && *req.UserID != userID {
+		if !h.checkMatrixPermissions(c, userID, "read") {
+			return
+		}
+		target = *req.UserID
+		_, err := h.db.Queries.GetUserTenant(ctx, sqlc.GetUserTenantParams{UserID: target, TenantID: tenantID})
+		if err == sql.ErrNoRows {
+			respondError(c, notFoundError("user not found"), "User not found")
+			return
+		}
+		if err != nil {
+			respondError(c, err, "Failed to look up user")
+			return
+		}
+	}
+
+	explanation, err := h.policyChecker.ExplainPermission(ctx, target, tenantID, req.Table, req.Action)
+	if err != nil {
+		respondError(c, err, "Failed to check permissions")
+		return
+	}
+
+	var item *accessItem
+	if req.ItemID != nil {
+		if item, err = h.checkItemAccess(ctx, tenantID, req.Table, *req.ItemID, explanation.RowFilter); err != nil {
+			respondError(c, err, "Failed to check item")
+			return
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"data": accessResponse(target, req, explanation, item)})
+}
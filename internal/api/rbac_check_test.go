@@ -0,0 +1,68 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccessCheckRequestValidate(t *testing.T) {
+	assert.NoError(t, accessCheckRequest{Table: "products", Action: "read"}.validate())
+	for _, action := range []string{"create", "update", "delete", "purge", "decrypt"} {
+		assert.NoError(t, accessCheckRequest{Table: "products", Action: action}.validate(), action)
+	}
+	assert.ErrorIs(t, accessCheckRequest{Table: "bad table", Action: "read"}.validate(), ErrValidation)
+	assert.ErrorIs(t, accessCheckRequest{Action: "read"}.validate(), ErrValidation)
+	assert.ErrorIs(t, accessCheckRequest{Table: "products", Action: "publish"}.validate(), ErrValidation)
+}
+
+func TestAccessResponse(t *testing.T) {
+	userID := uuid.New()
+	req := accessCheckRequest{Table: "products", Action: "read"}
+	editor := sqlc.Role{ID: uuid.New(), Name: "editor"}
+	rule := json.RawMessage(`{"owner_id": {"_eq": "$CURRENT_USER"}}`)
+	filter, err := rbac.CompileRowFilter(rule, rbac.RuleVars{UserID: userID})
+	require.NoError(t, err)
+	permission := sqlc.Permission{ID: uuid.New(), TableName: "products", Action: "read", ReadFields: []string{"name"}, FieldFilter: pqtype.NullRawMessage{RawMessage: rule, Valid: true}}
+	granted := &rbac.Explanation{Allowed: true, Roles: []sqlc.Role{editor}, Role: &editor, Permission: &permission, Fields: []string{"name"}, RowFilter: filter}
+
+	data := accessResponse(userID, req, granted, nil)
+	assert.Equal(t, true, data["allowed"])
+	assert.Equal(t, accessReasonPermission, data["reason"])
+	assert.Equal(t, []string{"editor"}, data["roles"])
+	assert.Equal(t, "editor", data["role"])
+	assert.NotContains(t, data, "item")
+	rowFilter := data["row_filter"].(gin.H)
+	assert.Contains(t, rowFilter["sql"], "owner_id")
+	assert.Equal(t, []interface{}{userID.String()}, rowFilter["args"])
+
+	data = accessResponse(userID, req, granted, &accessItem{ID: uuid.New(), Exists: true})
+	assert.Equal(t, false, data["allowed"])
+	assert.Equal(t, accessReasonRowFilter, data["reason"])
+
+	data = accessResponse(userID, req, granted, &accessItem{ID: uuid.New()})
+	assert.Equal(t, accessReasonItemNotFound, data["reason"])
+
+	data = accessResponse(userID, req, &rbac.Explanation{Roles: []sqlc.Role{editor}}, nil)
+	assert.Equal(t, false, data["allowed"])
+	assert.Equal(t, accessReasonNoPermission, data["reason"])
+	assert.Nil(t, data["role"])
+
+	broken := &rbac.Explanation{Roles: []sqlc.Role{editor}, Role: &editor, Permission: &permission, RuleError: errors.New("unknown operator")}
+	data = accessResponse(userID, req, broken, nil)
+	assert.Equal(t, accessReasonInvalidRule, data["reason"])
+	assert.Equal(t, "unknown operator", data["row_filter"].(gin.H)["error"])
+
+	admin := sqlc.Role{ID: uuid.New(), Name: "admin"}
+	data = accessResponse(userID, req, &rbac.Explanation{Allowed: true, Admin: true, Roles: []sqlc.Role{admin}, Role: &admin, Fields: []string{"*"}}, nil)
+	assert.Equal(t, accessReasonAdmin, data["reason"])
+}
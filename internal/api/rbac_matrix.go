@@ -0,0 +1,462 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the permission matrix, which shows and edits a tenant's permissions as a
+// whole instead of row by row through /items/permissions.
+//
+// RBAC Endpoints:
+// - GET /rbac/matrix - Every permission of the tenant by role, table and action
+// - PUT /rbac/matrix - Grant, change and revoke many permissions in one transaction
+//
+// The matrix is keyed by role name, table and action:
+//
+//	{"permissions": {"editor": {"products": {"read": {"read_fields": ["name", "price"]}, "delete": null}}}}
+//
+// A PUT only touches the cells it names: an object sets the permission, true grants it on
+// every field and null (or false) revokes it.
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// permissionActions are the actions permissions grant
+var permissionActions = []string{"create", "read", "update", "delete", "purge", decryptAction}
+
+// MatrixRole is a role of the permission matrix
+type MatrixRole struct {
+	ID     uuid.UUID `json:"id"`
+	Name   string    `json:"name"`
+	Parent string    `json:"parent,omitempty"` // Name of the role it inherits from
+}
+
+// MatrixPermission is a cell of the permission matrix: the fields a role may use for an
+// action on a table and the rules limiting its rows. Without field lists every field is
+// granted; read_fields and write_fields override allowed_fields for reads and writes.
+// Scope own limits the permission to rows the user created, and field_transforms of a read
+// permission return fields redacted (see rbac.FieldTransforms).
+type MatrixPermission struct {
+	AllowedFields   []string             `json:"allowed_fields,omitempty"`
+	ReadFields      []string             `json:"read_fields,omitempty"`
+	WriteFields     []string             `json:"write_fields,omitempty"`
+	FieldFilter     interface{}          `json:"field_filter,omitempty"`
+	Scope           string               `json:"scope,omitempty"` // all (the default) or own
+	FieldTransforms rbac.FieldTransforms `json:"field_transforms,omitempty"`
+}
+
+// PermissionMatrix is every permission of a tenant, by role name, table and action
+type PermissionMatrix struct {
+	Roles       []MatrixRole                                       `json:"roles"`
+	Tables      []string                                           `json:"tables"`
+	Actions     []string                                           `json:"actions"`
+	Permissions map[string]map[string]map[string]*MatrixPermission `json:"permissions"`
+}
+
+// matrixUpdateRequest is the body of PUT /rbac/matrix
+type matrixUpdateRequest struct {
+	Permissions map[string]map[string]map[string]json.RawMessage `json:"permissions"`
+}
+
+// matrixChange sets or, when permission is nil, revokes one cell of the matrix
+type matrixChange struct {
+	role       string
+	table      string
+	action     string
+	permission *MatrixPermission
+}
+
+// parseMatrixUpdate validates the cells of a matrix update against the tenant's roles and
+// collections without user stamps, and returns them as changes, sorted by role, table and
+// action
+func parseMatrixUpdate(cells map[string]map[string]map[string]json.RawMessage, roles map[string]uuid.UUID, unstamped map[string]bool) ([]matrixChange, error) {
+	var changes []matrixChange
+	for role, tables := range cells {
+		if _, ok := roles[role]; !ok {
+			return nil, validationError("unknown role '%s'", role)
+		}
+		for table, actions := range tables {
+			if !rbac.ValidateTableName(table) {
+				return nil, validationError("invalid table name '%s'", table)
+			}
+			for action, raw := range actions {
+				if !Contains(permissionActions, action) {
+					return nil, validationError("%s/%s/%s: action must be create, read, update, delete, purge or decrypt", role, table, action)
+				}
+				permission, err := parseMatrixCell(raw)
+				if err == nil && permission != nil {
+					err = checkPermissionScope(table, permission.Scope, unstamped)
+				}
+				if err == nil && permission != nil {
+					err = checkFieldTransforms(action, permission.FieldTransforms)
+				}
+				if err != nil {
+					return nil, wrapError(err, "%s/%s/%s", role, table, action)
+				}
+				changes = append(changes, matrixChange{role: role, table: table, action: action, permission: permission})
+			}
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		a, b := changes[i], changes[j]
+		if a.role != b.role {
+			return a.role < b.role
+		}
+		if a.table != b.table {
+			return a.table < b.table
+		}
+		return a.action < b.action
+	})
+	return changes, nil
+}
+
+// parseMatrixCell reads the value of a cell: null or false revokes (nil), true grants every
+// field and an object sets field lists and a row rule
+func parseMatrixCell(raw json.RawMessage) (*MatrixPermission, error) {
+	var granted bool
+	if err := json.Unmarshal(raw, &granted); err == nil {
+		if !granted {
+			return nil, nil
+		}
+		return &MatrixPermission{}, nil
+	}
+
+	var permission MatrixPermission
+	if err := strictUnmarshal(raw, &permission); err != nil {
+		return nil, validationError("permission must be true, false, null or an object with allowed_fields, read_fields, write_fields, field_filter, scope and field_transforms")
+	}
+	for _, fields := range [][]string{permission.AllowedFields, permission.ReadFields, permission.WriteFields} {
+		for _, field := range fields {
+			if field != "*" && !rbac.ValidateTableName(field) {
+				return nil, validationError("invalid field name '%s'", field)
+			}
+		}
+	}
+	if permission.FieldFilter != nil {
+		rule, _ := json.Marshal(permission.FieldFilter)
+		if _, err := rbac.CompileRowFilter(rule, rbac.RuleVars{}); err != nil {
+			return nil, validationError("invalid field_filter: %s", err)
+		}
+	}
+	return &permission, nil
+}
+
+// permissionScope returns the scope a permission is stored with, all by default
+func permissionScope(scope string) string {
+	if scope == "" {
+		return rbac.ScopeAll
+	}
+	return scope
+}
+
+// checkPermissionScope rejects unknown scopes, and own-scoped permissions on schema tables
+// and on unstamped, the collections created with "user_stamps": false, whose rows have no
+// owner
+func checkPermissionScope(table, scope string, unstamped map[string]bool) error {
+	if scope == "" {
+		return nil
+	}
+	if !rbac.ValidScope(scope) {
+		return validationError("scope must be all or own")
+	}
+	if scope == rbac.ScopeOwn && Contains(schemaTableNames, table) {
+		return validationError("scope own is only supported on collections")
+	}
+	if scope == rbac.ScopeOwn && unstamped[table] {
+		return validationError("scope own needs the created_by of user stamps, which collection '%s' goes without", table)
+	}
+	return nil
+}
+
+// unstampedCollections returns the slugs of the collections of a snapshot created without
+// user stamps
+func unstampedCollections(collections []SnapshotCollection) map[string]bool {
+	unstamped := make(map[string]bool)
+	for _, collection := range collections {
+		if !flagOn(collection.UserStamps) {
+			unstamped[collection.Slug] = true
+		}
+	}
+	return unstamped
+}
+
+// checkFieldTransforms rejects unknown transforms, invalid field names and transforms on
+// other actions than read
+func checkFieldTransforms(action string, transforms rbac.FieldTransforms) error {
+	if len(transforms) == 0 {
+		return nil
+	}
+	if action != "read" {
+		return validationError("field_transforms only apply to read permissions")
+	}
+	raw, _ := json.Marshal(transforms)
+	if _, err := rbac.ParseFieldTransforms(raw); err != nil {
+		return validationError("invalid field_transforms: %s", err)
+	}
+	return nil
+}
+
+// strictUnmarshal decodes JSON, rejecting unknown fields
+func strictUnmarshal(raw json.RawMessage, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+// PermissionMatrix returns every permission of the user's tenant. Tables are the tenant's
+// collections, the schema tables and any other table a permission names.
+func (s *SchemaHandlers) PermissionMatrix(ctx context.Context, userID uuid.UUID) (*PermissionMatrix, error) {
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, index, err := s.loadSchema(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	matrix := &PermissionMatrix{
+		Roles:       []MatrixRole{},
+		Actions:     permissionActions,
+		Permissions: map[string]map[string]map[string]*MatrixPermission{},
+	}
+	for _, role := range snapshot.Roles {
+		matrix.Roles = append(matrix.Roles, MatrixRole{ID: index.roles[role.Name], Name: role.Name, Parent: role.Parent})
+		matrix.Permissions[role.Name] = map[string]map[string]*MatrixPermission{}
+	}
+
+	tables := map[string]bool{}
+	for _, collection := range snapshot.Collections {
+		tables[collection.Slug] = true
+	}
+	for _, table := range schemaTableNames {
+		tables[table] = true
+	}
+	for _, permission := range snapshot.Permissions {
+		tables[permission.Table] = true
+		byTable := matrix.Permissions[permission.Role]
+		if byTable[permission.Table] == nil {
+			byTable[permission.Table] = map[string]*MatrixPermission{}
+		}
+		byTable[permission.Table][permission.Action] = &MatrixPermission{
+			AllowedFields:   permission.AllowedFields,
+			ReadFields:      permission.ReadFields,
+			WriteFields:     permission.WriteFields,
+			FieldFilter:     permission.FieldFilter,
+			Scope:           permission.Scope,
+			FieldTransforms: permission.FieldTransforms,
+		}
+	}
+	for table := range tables {
+		matrix.Tables = append(matrix.Tables, table)
+	}
+	sort.Strings(matrix.Tables)
+
+	return matrix, nil
+}
+
+// UpdatePermissionMatrix applies changes to the permissions of the user's tenant in one
+// transaction, so either every change is made or none is
+func (s *SchemaHandlers) UpdatePermissionMatrix(ctx context.Context, userID uuid.UUID, cells map[string]map[string]map[string]json.RawMessage) ([]matrixChange, error) {
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, index, err := s.loadSchema(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	changes, err := parseMatrixUpdate(cells, index.roles, unstampedCollections(snapshot.Collections))
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.handler.db.InTransaction(ctx, func(tx *db.Tx) error {
+		for _, change := range changes {
+			if err := applyMatrixChange(ctx, tx.Queries, tenantID, index, change); err != nil {
+				return wrapError(err, "failed to set %s/%s/%s", change.role, change.table, change.action)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	rbac.PermissionsChanged(tenantID)
+	return changes, nil
+}
+
+// applyMatrixChange creates, updates or deletes the permission of one cell
+func applyMatrixChange(ctx context.Context, queries *sqlc.Queries, tenantID uuid.UUID, index *schemaIndex, change matrixChange) error {
+	existing, exists := index.permissions[change.role+":"+change.table+":"+change.action]
+	if change.permission == nil {
+		if !exists {
+			return nil
+		}
+		return queries.DeletePermission(ctx, existing)
+	}
+
+	fieldFilter, err := encodeSnapshotJSON(change.permission.FieldFilter)
+	if err != nil {
+		return err
+	}
+	transforms, err := encodeFieldTransforms(change.permission.FieldTransforms)
+	if err != nil {
+		return err
+	}
+	if exists {
+		_, err := queries.UpdatePermission(ctx, sqlc.UpdatePermissionParams{
+			ID:              existing,
+			FieldFilter:     fieldFilter,
+			AllowedFields:   change.permission.AllowedFields,
+			ReadFields:      change.permission.ReadFields,
+			WriteFields:     change.permission.WriteFields,
+			Scope:           permissionScope(change.permission.Scope),
+			FieldTransforms: transforms,
+		})
+		return err
+	}
+	_, err = queries.CreatePermission(ctx, sqlc.CreatePermissionParams{
+		ID:              uuid.New(),
+		RoleID:          uuid.NullUUID{UUID: index.roles[change.role], Valid: true},
+		TableName:       change.table,
+		Action:          change.action,
+		FieldFilter:     fieldFilter,
+		AllowedFields:   change.permission.AllowedFields,
+		TenantID:        uuid.NullUUID{UUID: tenantID, Valid: true},
+		ReadFields:      change.permission.ReadFields,
+		WriteFields:     change.permission.WriteFields,
+		Scope:           permissionScope(change.permission.Scope),
+		FieldTransforms: transforms,
+	})
+	return err
+}
+
+// checkMatrixPermissions checks the user may read roles and do actions on permissions,
+// answering the request otherwise
+func (h *ItemsHandler) checkMatrixPermissions(c *gin.Context, userID uuid.UUID, actions ...string) bool {
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	checks := [][2]string{{"roles", "read"}}
+	for _, action := range actions {
+		checks = append(checks, [2]string{"permissions", action})
+	}
+	for _, check := range checks {
+		allowed, _, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, check[0], check[1])
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return false
+		}
+		if !allowed {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions: " + check[1] + " on " + check[0]})
+			return false
+		}
+	}
+	return true
+}
+
+// GetPermissionMatrix handles GET /rbac/matrix requests.
+//
+// Returns every permission of the caller's tenant by role name, table and action, with the
+// roles, the tables permissions may name and the actions.
+//
+// Response Format:
+//   - 200: {"data": {"roles": [...], "tables": [...], "actions": [...], "permissions": {...}}}
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on roles or permissions
+//
+// @Summary      Get the permission matrix
+// @Tags         rbac
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Every permission of the tenant by role, table and action, with field lists and row rules.
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /rbac/matrix [get]
+func (h *ItemsHandler) GetPermissionMatrix(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if !h.checkMatrixPermissions(c, userID, "read") {
+		return
+	}
+
+	matrix, err := h.schemaHandlers.PermissionMatrix(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to load permissions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": matrix})
+}
+
+// UpdatePermissionMatrix handles PUT /rbac/matrix requests.
+//
+// Sets the cells of the matrix named in the body, in one transaction: an object sets a
+// permission, true grants it on every field and null or false revokes it. Cells that are
+// not named are left unchanged.
+//
+// Response Format:
+//   - 200: The updated matrix, with meta.changed counting the cells named
+//   - 400: Invalid request body
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on roles or create, update or delete on permissions
+//   - 422: Unknown role, invalid table, action, field list or row rule
+//
+// @Summary      Update the permission matrix
+// @Tags         rbac
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Grant, change and revoke permissions atomically. Body: {"permissions": {"<role>": {"<table>": {"<action>": {...} | true | null}}}}.
+// @Param        body body map[string]interface{} true "Permissions to set"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
+// @Router       /rbac/matrix [put]
+func (h *ItemsHandler) UpdatePermissionMatrix(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req matrixUpdateRequest
+	if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	if !h.checkMatrixPermissions(c, userID, "create", "update", "delete") {
+		return
+	}
+
+	changes, err := h.schemaHandlers.UpdatePermissionMatrix(c.Request.Context(), userID, req.Permissions)
+	if err != nil {
+		respondError(c, err, "Failed to update permissions")
+		return
+	}
+	matrix, err := h.schemaHandlers.PermissionMatrix(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to load permissions")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": matrix, "meta": gin.H{"changed": len(changes)}})
+}
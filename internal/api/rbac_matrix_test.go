@@ -0,0 +1,69 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMatrixUpdate(t *testing.T) {
+	roles := map[string]uuid.UUID{"editor": uuid.New(), "viewer": uuid.New()}
+	parse := func(body string) ([]matrixChange, error) {
+		var req matrixUpdateRequest
+		require.NoError(t, json.Unmarshal([]byte(body), &req))
+		return parseMatrixUpdate(req.Permissions, roles, map[string]bool{"events": true})
+	}
+
+	changes, err := parse(`{"permissions": {
+		"viewer": {"products": {"read": {"read_fields": ["name", "price"], "field_filter": {"status": {"_eq": "published"}}}}},
+		"editor": {"products": {"update": true, "delete": null, "create": false}}
+	}}`)
+	require.NoError(t, err)
+	require.Len(t, changes, 4)
+
+	// Sorted by role, table and action
+	assert.Equal(t, matrixChange{role: "editor", table: "products", action: "create"}, changes[0])
+	assert.Nil(t, changes[1].permission, "null revokes")
+	assert.Equal(t, "update", changes[2].action)
+	assert.Equal(t, &MatrixPermission{}, changes[2].permission, "true grants every field")
+	assert.Equal(t, []string{"name", "price"}, changes[3].permission.ReadFields)
+	assert.NotNil(t, changes[3].permission.FieldFilter)
+
+	changes, err = parse(`{"permissions": {"editor": {"products": {"update": {"scope": "own"}}}}}`)
+	require.NoError(t, err)
+	assert.Equal(t, rbac.ScopeOwn, changes[0].permission.Scope)
+
+	changes, err = parse(`{"permissions": {"viewer": {"customers": {"read": {"field_transforms": {"email": "mask_email"}}}}}}`)
+	require.NoError(t, err)
+	assert.Equal(t, rbac.FieldTransforms{"email": rbac.TransformMaskEmail}, changes[0].permission.FieldTransforms)
+
+	// Permanent deletes of trashed items are granted on their own
+	changes, err = parse(`{"permissions": {"editor": {"products": {"purge": true}}}}`)
+	require.NoError(t, err)
+	assert.Equal(t, "purge", changes[0].action)
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, body := range []string{
+			`{"permissions": {"owner": {"products": {"read": true}}}}`,
+			`{"permissions": {"editor": {"bad table": {"read": true}}}}`,
+			`{"permissions": {"editor": {"products": {"publish": true}}}}`,
+			`{"permissions": {"editor": {"products": {"read": "yes"}}}}`,
+			`{"permissions": {"editor": {"products": {"read": {"fields": ["name"]}}}}}`,
+			`{"permissions": {"editor": {"products": {"read": {"read_fields": ["na me"]}}}}}`,
+			`{"permissions": {"editor": {"products": {"read": {"field_filter": {"price": {"_like": 1}}}}}}}`,
+			`{"permissions": {"editor": {"products": {"read": {"scope": "team"}}}}}`,
+			`{"permissions": {"editor": {"users": {"read": {"scope": "own"}}}}}`,
+			`{"permissions": {"editor": {"events": {"update": {"scope": "own"}}}}}`,
+			`{"permissions": {"editor": {"customers": {"read": {"field_transforms": {"email": "rot13"}}}}}}`,
+			`{"permissions": {"editor": {"customers": {"update": {"field_transforms": {"email": "hash"}}}}}}`,
+		} {
+			_, err := parse(body)
+			assert.ErrorIs(t, err, ErrValidation, body)
+		}
+	})
+}
@@ -0,0 +1,390 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the realtime endpoint, which streams item events to subscribed clients
+// over Server-Sent Events (SSE).
+//
+// Clients open a long-lived request and name the collections they want to follow:
+//
+//	GET /realtime?collections=orders,customers&events=item.create,item.update
+//
+// Read permission is checked for every requested collection when the stream opens, and
+// each event's data is filtered down to the fields the client may read, exactly as
+// GET /items/:table would. Under row-level permission rules, create and update events are
+// only delivered for items whose payload matches the rules; an update that does not
+// include the rule's columns is therefore not delivered. Browsers' EventSource cannot set
+// headers, so the access token may also be passed as ?access_token=.
+//
+// Stream format:
+//
+//	event: ready
+//	data: {"collections":["orders","customers"]}
+//
+//	id: 42
+//	event: item.create
+//	data: {"event":"item.create","collection":"orders","keys":["..."],"data":{...},"timestamp":"..."}
+//
+// Comment lines (": ping") are sent periodically to keep proxies from closing idle streams.
+// Clients that fall too far behind receive an "overflow" event and are disconnected; they
+// should reconnect and re-fetch the data they display.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	realtimeBufferSize  = 64               // Messages queued per client before it is dropped
+	realtimeHeartbeat   = 25 * time.Second // Interval between keep-alive comments
+	maxRealtimeChannels = 50               // Collections a single stream may follow
+)
+
+// realtimeMessage is one event already filtered for a specific client
+type realtimeMessage struct {
+	id    uint64
+	event string
+	data  []byte
+}
+
+// realtimeClient is one open stream
+type realtimeClient struct {
+	userID        uuid.UUID
+	tenantID      uuid.UUID
+	allowedFields map[string][]string             // Collection -> fields the client may read
+	rowFilters    map[string]*rbac.RowFilter      // Collection -> row-level rule, if any
+	transforms    map[string]rbac.FieldTransforms // Collection -> field redactions, if any
+	decrypt       map[string]bool                 // Collection -> whether encrypted fields are revealed
+	eventTypes    map[string]bool                 // Event filter; empty means every event
+	send          chan realtimeMessage
+	lagged        chan struct{}
+	lagOnce       sync.Once
+}
+
+// wants reports whether the client subscribed to event
+func (rc *realtimeClient) wants(event events.Event) bool {
+	if rc.tenantID != event.TenantID {
+		return false
+	}
+	if _, subscribed := rc.allowedFields[event.Collection]; !subscribed {
+		return false
+	}
+	return len(rc.eventTypes) == 0 || rc.eventTypes[event.Type]
+}
+
+// RealtimeHandler keeps track of open streams and fans item events out to them
+type RealtimeHandler struct {
+	utils           *ItemsUtils
+	dynamicHandlers *DynamicHandlers
+	policyChecker   *rbac.PolicyChecker
+
+	mu      sync.RWMutex
+	clients map[*realtimeClient]struct{}
+	nextID  atomic.Uint64
+}
+
+// NewRealtimeHandler creates a RealtimeHandler. Subscribe its HandleEvent method to the
+// event bus to start receiving events.
+func NewRealtimeHandler(db *db.DB) *RealtimeHandler {
+	utils := NewItemsUtils(db)
+	return &RealtimeHandler{
+		utils:           utils,
+		dynamicHandlers: NewDynamicHandlers(db, utils, nil),
+		policyChecker:   rbac.NewPolicyChecker(db.Reader().Queries),
+		clients:         make(map[*realtimeClient]struct{}),
+	}
+}
+
+// Subscribe handles GET /realtime requests.
+//
+// The response is an SSE stream that stays open until the client disconnects.
+//
+// Response Format:
+//   - 200: Event stream
+//   - 400: Missing or invalid collections or events
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on one of the collections
+//
+// @Summary      Subscribe to realtime item events
+// @Tags         realtime
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Open a Server-Sent Events stream of create/update/delete events for the given collections. Event data is filtered by the caller's read permissions.
+// @Param        collections  query  string true  "Comma-separated collections to follow"
+// @Param        events       query  string false "Comma-separated events (item.create, item.update, item.delete); default all"
+// @Param        access_token query  string false "Access token for clients that cannot set headers (EventSource)"
+// @Produce      text/event-stream
+// @Success      200 {string} string "event stream"
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /realtime [get]
+func (h *RealtimeHandler) Subscribe(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	collections := splitList(c.Query("collections"))
+	if len(collections) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one collection is required"})
+		return
+	}
+	if len(collections) > maxRealtimeChannels {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("A stream may follow at most %d collections", maxRealtimeChannels)})
+		return
+	}
+
+	eventTypes := make(map[string]bool)
+	for _, eventType := range splitList(c.Query("events")) {
+		if eventType != events.ItemCreate && eventType != events.ItemUpdate && eventType != events.ItemDelete {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Unsupported event '%s'", eventType)})
+			return
+		}
+		eventTypes[eventType] = true
+	}
+
+	// Get tenant context from the request
+	tenantID, _ := middleware.GetTenantID(c)
+
+	// Create a context with tenant information
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	allowedFields := make(map[string][]string, len(collections))
+	rowFilters := make(map[string]*rbac.RowFilter)
+	transforms := make(map[string]rbac.FieldTransforms)
+	decrypt := make(map[string]bool)
+	for _, collection := range collections {
+		if !rbac.ValidateTableName(collection) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("Invalid collection name '%s'", collection)})
+			return
+		}
+
+		hasPermission, fields, rowFilter, fieldTransforms, err := h.policyChecker.CheckPermissionWithTransforms(ctxWithTenant, userID, collection, "read")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+		if !hasPermission {
+			c.JSON(http.StatusForbidden, gin.H{"error": fmt.Sprintf("Insufficient permissions for collection '%s'", collection)})
+			return
+		}
+		allowedFields[collection] = fields
+		if rowFilter != nil {
+			rowFilters[collection] = rowFilter
+		}
+		if fieldTransforms != nil {
+			transforms[collection] = fieldTransforms
+		}
+		if decrypt[collection], _, err = h.policyChecker.CheckPermission(ctxWithTenant, userID, collection, decryptAction); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+			return
+		}
+	}
+
+	// Events are tagged with the tenant the write was made in
+	userTenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+
+	client := &realtimeClient{
+		userID:        userID,
+		tenantID:      userTenantID,
+		allowedFields: allowedFields,
+		rowFilters:    rowFilters,
+		transforms:    transforms,
+		decrypt:       decrypt,
+		eventTypes:    eventTypes,
+		send:          make(chan realtimeMessage, realtimeBufferSize),
+		lagged:        make(chan struct{}),
+	}
+	h.register(client)
+	defer h.unregister(client)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable proxy buffering (nginx)
+	c.Status(http.StatusOK)
+
+	ready, _ := json.Marshal(gin.H{"collections": collections})
+	writeSSE(c, realtimeMessage{event: "ready", data: ready})
+
+	heartbeat := time.NewTicker(realtimeHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-client.lagged:
+			writeSSE(c, realtimeMessage{event: "overflow", data: []byte(`{"error":"client too slow, events were dropped"}`)})
+			return
+		case msg := <-client.send:
+			writeSSE(c, msg)
+		case <-heartbeat.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+			c.Writer.Flush()
+		}
+	}
+}
+
+// HandleEvent forwards an item event to every matching stream. It never blocks: a client
+// whose buffer is full is flagged as lagging and disconnected.
+func (h *RealtimeHandler) HandleEvent(ctx context.Context, event events.Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	// Item events hold the encrypted values; the fields are looked up once a stream wants it
+	var encrypted []string
+	encryptedLoaded := false
+
+	for client := range h.clients {
+		if !client.wants(event) {
+			continue
+		}
+		if !encryptedLoaded {
+			var err error
+			if encrypted, err = h.dynamicHandlers.encryptedFields(ctx, event.TenantID, event.Collection); err != nil {
+				slog.Warn("failed to fetch encrypted fields of realtime event", "tenant_id", event.TenantID, "collection", event.Collection, "error", err)
+				return
+			}
+			encryptedLoaded = true
+		}
+
+		visible, ok := matchRowFilter(event, client.rowFilters[event.Collection])
+		if !ok {
+			continue
+		}
+
+		data, err := json.Marshal(gin.H{
+			"event":      visible.Type,
+			"collection": visible.Collection,
+			"keys":       visible.Keys,
+			"data":       h.filterEventData(visible, client.allowedFields[visible.Collection], client.transforms[visible.Collection], encrypted, client.decrypt[visible.Collection]),
+			"timestamp":  event.Timestamp,
+		})
+		if err != nil {
+			continue
+		}
+
+		select {
+		case client.send <- realtimeMessage{id: h.nextID.Add(1), event: event.Type, data: data}:
+		default:
+			client.lagOnce.Do(func() { close(client.lagged) })
+		}
+	}
+}
+
+// matchRowFilter narrows an event to the items that satisfy a client's row filter. It
+// returns false when no item of the event is visible. Delete events carry no data and are
+// passed through with their keys.
+func matchRowFilter(event events.Event, rowFilter *rbac.RowFilter) (events.Event, bool) {
+	if rowFilter == nil {
+		return event, true
+	}
+
+	switch data := event.Data.(type) {
+	case map[string]interface{}:
+		return event, rowFilter.Matches(data)
+	case []map[string]interface{}:
+		var keys []string
+		var items []map[string]interface{}
+		for i, item := range data {
+			if rowFilter.Matches(item) {
+				if i < len(event.Keys) {
+					keys = append(keys, event.Keys[i])
+				}
+				items = append(items, item)
+			}
+		}
+		event.Keys, event.Data = keys, items
+		return event, len(items) > 0
+	default:
+		return event, event.Type == events.ItemDelete
+	}
+}
+
+// filterEventData applies field permissions and redactions to an event's item payload(s),
+// and decrypts or masks the encrypted fields. The event data is shared by every subscriber,
+// so items are copied rather than modified in place.
+func (h *RealtimeHandler) filterEventData(event events.Event, allowedFields []string, transforms rbac.FieldTransforms, encrypted []string, decrypt bool) interface{} {
+	switch data := event.Data.(type) {
+	case map[string]interface{}:
+		return h.filterEventItem(event.Collection, data, allowedFields, transforms, encrypted, decrypt)
+	case []map[string]interface{}:
+		filtered := make([]map[string]interface{}, len(data))
+		for i, item := range data {
+			filtered[i] = h.filterEventItem(event.Collection, item, allowedFields, transforms, encrypted, decrypt)
+		}
+		return filtered
+	default:
+		return nil
+	}
+}
+
+// filterEventItem returns a copy of item limited to allowedFields, redacted by transforms,
+// with secrets redacted and encrypted fields revealed (see revealValues)
+func (h *RealtimeHandler) filterEventItem(collection string, item map[string]interface{}, allowedFields []string, transforms rbac.FieldTransforms, encrypted []string, decrypt bool) map[string]interface{} {
+	filtered := make(map[string]interface{}, len(item))
+	for key, value := range h.policyChecker.FilterFields(item, allowedFields, transforms) {
+		filtered[key] = value
+	}
+	redactSecrets(collection, filtered)
+	if err := revealValues(encrypted, []map[string]interface{}{filtered}, decrypt); err != nil {
+		// A value that cannot be decrypted is masked rather than sent encrypted
+		_ = revealValues(encrypted, []map[string]interface{}{filtered}, false)
+	}
+	return filtered
+}
+
+// register adds a stream to the fan-out set
+func (h *RealtimeHandler) register(client *realtimeClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.clients[client] = struct{}{}
+}
+
+// unregister removes a stream from the fan-out set
+func (h *RealtimeHandler) unregister(client *realtimeClient) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.clients, client)
+}
+
+// writeSSE writes one message in text/event-stream format and flushes it
+func writeSSE(c *gin.Context, msg realtimeMessage) {
+	if msg.id != 0 {
+		fmt.Fprintf(c.Writer, "id: %d\n", msg.id)
+	}
+	fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", msg.event, msg.data)
+	c.Writer.Flush()
+}
+
+// splitList splits a comma-separated query parameter, dropping empty entries and duplicates
+func splitList(raw string) []string {
+	var result []string
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part != "" && !Contains(result, part) {
+			result = append(result, part)
+		}
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"go-rbac-api/internal/dbtest"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRealtimeClient(tenantID uuid.UUID, allowedFields map[string][]string, eventTypes map[string]bool) *realtimeClient {
+	return &realtimeClient{
+		tenantID:      tenantID,
+		allowedFields: allowedFields,
+		eventTypes:    eventTypes,
+		send:          make(chan realtimeMessage, 1),
+		lagged:        make(chan struct{}),
+	}
+}
+
+func TestRealtimeHandleEvent(t *testing.T) {
+	h := NewRealtimeHandler(dbtest.SQLite(t))
+	tenantID := uuid.New()
+
+	limited := newTestRealtimeClient(tenantID, map[string][]string{"orders": {"id", "total"}}, nil)
+	createsOnly := newTestRealtimeClient(tenantID, map[string][]string{"orders": {"*"}}, map[string]bool{events.ItemCreate: true})
+	otherTenant := newTestRealtimeClient(uuid.New(), map[string][]string{"orders": {"*"}}, nil)
+	otherCollection := newTestRealtimeClient(tenantID, map[string][]string{"customers": {"*"}}, nil)
+	for _, client := range []*realtimeClient{limited, createsOnly, otherTenant, otherCollection} {
+		h.register(client)
+	}
+
+	h.HandleEvent(context.Background(), events.Event{
+		Type:       events.ItemUpdate,
+		TenantID:   tenantID,
+		Collection: "orders",
+		Keys:       []string{"1"},
+		Data:       map[string]interface{}{"id": "1", "total": 10, "notes": "private"},
+	})
+
+	require.Len(t, limited.send, 1)
+	msg := <-limited.send
+	assert.Equal(t, events.ItemUpdate, msg.event)
+
+	var payload struct {
+		Collection string                 `json:"collection"`
+		Data       map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(msg.data, &payload))
+	assert.Equal(t, "orders", payload.Collection)
+	assert.Equal(t, map[string]interface{}{"id": "1", "total": float64(10)}, payload.Data)
+
+	assert.Empty(t, createsOnly.send)
+	assert.Empty(t, otherTenant.send)
+	assert.Empty(t, otherCollection.send)
+}
+
+func TestRealtimeSlowClientIsDropped(t *testing.T) {
+	h := NewRealtimeHandler(dbtest.SQLite(t))
+	tenantID := uuid.New()
+
+	client := newTestRealtimeClient(tenantID, map[string][]string{"orders": {"*"}}, nil)
+	h.register(client)
+
+	event := events.Event{Type: events.ItemDelete, TenantID: tenantID, Collection: "orders", Keys: []string{"1"}}
+	h.HandleEvent(context.Background(), event)
+	h.HandleEvent(context.Background(), event)
+	h.HandleEvent(context.Background(), event)
+
+	assert.Len(t, client.send, 1)
+	select {
+	case <-client.lagged:
+	default:
+		t.Fatal("expected client to be flagged as lagging")
+	}
+}
+
+func TestRealtimeFilterEventData_Encrypted(t *testing.T) {
+	useTestFieldKeys(t)
+	h := &RealtimeHandler{policyChecker: rbac.NewPolicyChecker(nil)}
+	encrypted, err := encryptValues([]string{"iban"}, map[string]interface{}{"iban": "DE89"})
+	require.NoError(t, err)
+	event := events.Event{Type: events.ItemUpdate, Collection: "customers", Data: map[string]interface{}{"id": "1", "iban": encrypted["iban"]}}
+
+	masked := h.filterEventData(event, []string{"*"}, nil, []string{"iban"}, false)
+	assert.Equal(t, map[string]interface{}{"id": "1", "iban": maskedValue}, masked)
+
+	revealed := h.filterEventData(event, []string{"*"}, nil, []string{"iban"}, true)
+	assert.Equal(t, map[string]interface{}{"id": "1", "iban": "DE89"}, revealed)
+
+	assert.Equal(t, encrypted["iban"], event.Data.(map[string]interface{})["iban"], "the shared event data is left as it is")
+}
+
+func TestSplitList(t *testing.T) {
+	assert.Equal(t, []string{"orders", "customers"}, splitList(" orders,,customers,orders "))
+	assert.Nil(t, splitList(""))
+}
+
+func TestMatchRowFilter(t *testing.T) {
+	userID := uuid.New()
+	filter, err := rbac.CompileRowFilter(json.RawMessage(`{"owner": "$CURRENT_USER"}`), rbac.RuleVars{UserID: userID})
+	require.NoError(t, err)
+
+	single := events.Event{Type: events.ItemUpdate, Keys: []string{"1"}, Data: map[string]interface{}{"id": "1", "owner": userID.String()}}
+	_, ok := matchRowFilter(single, filter)
+	assert.True(t, ok)
+
+	partial := events.Event{Type: events.ItemUpdate, Keys: []string{"1"}, Data: map[string]interface{}{"id": "1", "total": 5}}
+	_, ok = matchRowFilter(partial, filter)
+	assert.False(t, ok, "payload without the rule's columns must not be delivered")
+
+	bulk := events.Event{Type: events.ItemCreate, Keys: []string{"1", "2"}, Data: []map[string]interface{}{
+		{"id": "1", "owner": uuid.New().String()},
+		{"id": "2", "owner": userID.String()},
+	}}
+	visible, ok := matchRowFilter(bulk, filter)
+	require.True(t, ok)
+	assert.Equal(t, []string{"2"}, visible.Keys)
+	assert.Len(t, visible.Data, 1)
+
+	deleted := events.Event{Type: events.ItemDelete, Keys: []string{"1"}}
+	_, ok = matchRowFilter(deleted, filter)
+	assert.True(t, ok)
+
+	_, ok = matchRowFilter(partial, nil)
+	assert.True(t, ok)
+}
@@ -0,0 +1,172 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the on-delete rules of many-to-one relations.
+//
+// A many-to-one field with "on_delete" in its relation_config gets a foreign key from its
+// column to the related collection's data table, named field_<id>_fkey after the field's ID
+// so that it follows the column through renames:
+// - "cascade"  - deleting the related item deletes the items pointing at it
+// - "set_null" - deleting the related item clears the field; the field cannot be required
+// - "restrict" - the related item cannot be deleted while items point at it
+//
+// Postgres enforces the rules, so they hold for every delete, including bulk deletes and
+// ones made outside the API. They act when an item is removed for good: moving an item to
+// the trash of a soft-delete collection leaves the items pointing at it alone. Items
+// deleted or cleared by a rule get no revision or event of their own. A delete blocked by
+// restrict is answered with 409 and names the blocking relation under "restricted_by".
+// Fields without a rule have no foreign key, as before.
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// On-delete rules of many-to-one relations
+const (
+	OnDeleteCascade  = "cascade"
+	OnDeleteSetNull  = "set_null"
+	OnDeleteRestrict = "restrict"
+)
+
+// onDeleteActions maps each on-delete rule to its foreign key action
+var onDeleteActions = map[string]string{
+	OnDeleteCascade:  "CASCADE",
+	OnDeleteSetNull:  "SET NULL",
+	OnDeleteRestrict: "RESTRICT",
+}
+
+// foreignKeyPrefix and foreignKeySuffix surround the field ID in the name of a rule's foreign key
+const (
+	foreignKeyPrefix = "field_"
+	foreignKeySuffix = "_fkey"
+)
+
+// fieldForeignKeyName returns the name of the foreign key of a relation field with a rule
+func fieldForeignKeyName(fieldID uuid.UUID) string {
+	return foreignKeyPrefix + strings.ReplaceAll(fieldID.String(), "-", "") + foreignKeySuffix
+}
+
+// fieldIDOfForeignKey returns the ID of the field a foreign key belongs to
+func fieldIDOfForeignKey(constraint string) (uuid.UUID, bool) {
+	if !strings.HasPrefix(constraint, foreignKeyPrefix) || !strings.HasSuffix(constraint, foreignKeySuffix) {
+		return uuid.Nil, false
+	}
+	fieldID, err := uuid.Parse(strings.TrimSuffix(strings.TrimPrefix(constraint, foreignKeyPrefix), foreignKeySuffix))
+	return fieldID, err == nil
+}
+
+// relationRule returns the relation of a field when it is a many-to-one relation with an
+// on-delete rule, and the zero config otherwise
+func relationRule(fieldType string, raw pqtype.NullRawMessage) RelationConfig {
+	if fieldType != "relation" || !raw.Valid {
+		return RelationConfig{}
+	}
+	var options map[string]interface{}
+	if err := json.Unmarshal(raw.RawMessage, &options); err != nil {
+		return RelationConfig{}
+	}
+	cfg, err := parseRelationConfig(options)
+	if err != nil || cfg.Type != RelationManyToOne || cfg.OnDelete == "" {
+		return RelationConfig{}
+	}
+	return cfg
+}
+
+// checkRelationRule refuses rules a field cannot have
+func checkRelationRule(cfg RelationConfig, required bool) error {
+	if cfg.OnDelete == OnDeleteSetNull && required {
+		return validationError("on_delete set_null needs a field that is not required")
+	}
+	return nil
+}
+
+// foreignKeyStatement returns the statement adding the foreign key of a rule to column of table
+func foreignKeyStatement(table string, fieldID uuid.UUID, column, relatedTable, rule string) string {
+	return fmt.Sprintf(`ALTER TABLE %s ADD CONSTRAINT "%s" FOREIGN KEY ("%s") REFERENCES %s (id) ON DELETE %s`,
+		table, fieldForeignKeyName(fieldID), column, relatedTable, onDeleteActions[rule])
+}
+
+// planRelationRule works out the statements taking the foreign key of a field from the rule
+// of relation from to the one of relation to, in relatedTable. retyped tells that the column
+// type changes, which the foreign key has to make way for.
+func planRelationRule(table string, fieldID uuid.UUID, column, relatedTable string, from, to RelationConfig, retyped bool) fieldIndexes {
+	var plan fieldIndexes
+	changed := from != to || retyped
+	if from.OnDelete != "" && changed {
+		plan.drop = append(plan.drop, fmt.Sprintf(`ALTER TABLE %s DROP CONSTRAINT IF EXISTS "%s"`, table, fieldForeignKeyName(fieldID)))
+	}
+	if to.OnDelete != "" && changed {
+		plan.create = append(plan.create, foreignKeyStatement(table, fieldID, column, relatedTable, to.OnDelete))
+	}
+	return plan
+}
+
+// relationRuleStatements resolves the related data table of a rule and plans its foreign key
+func (u *ItemsUtils) relationRuleStatements(ctx context.Context, tenantID uuid.UUID, table string, fieldID uuid.UUID, column string, from, to RelationConfig, retyped bool) (fieldIndexes, error) {
+	var relatedTable string
+	if to.OnDelete != "" {
+		related, err := u.ResolveDataTable(ctx, tenantID, to.RelatedCollection)
+		if err != nil {
+			return fieldIndexes{}, wrapError(err, "related collection %s", to.RelatedCollection)
+		}
+		relatedTable = related.String()
+	}
+	return planRelationRule(table, fieldID, column, relatedTable, from, to, retyped), nil
+}
+
+// AddRelationRule adds the foreign key of a new many-to-one field with an on-delete rule
+func (u *ItemsUtils) AddRelationRule(ctx context.Context, tenantID uuid.UUID, collectionSlug string, fieldID uuid.UUID, column string, relation RelationConfig) error {
+	table, err := u.ResolveDataTable(ctx, tenantID, collectionSlug)
+	if err != nil {
+		return err
+	}
+	plan, err := u.relationRuleStatements(ctx, tenantID, table.String(), fieldID, column, RelationConfig{}, relation, false)
+	if err != nil {
+		return err
+	}
+	for _, statement := range plan.create {
+		if _, err := u.exec().ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("failed to add on_delete rule: %w", err)
+		}
+	}
+	return nil
+}
+
+// DeleteRestriction names the relation whose restrict rule blocked a delete
+type DeleteRestriction struct {
+	Collection string `json:"collection"` // Collection of the items still pointing at the item
+	Field      string `json:"field"`      // Their relation field
+}
+
+func (r *DeleteRestriction) Error() string {
+	return fmt.Sprintf("items of %s still point at it through field '%s', whose on_delete rule is restrict", r.Collection, r.Field)
+}
+
+// restrictedDelete turns the foreign key violation of a delete blocked by a restrict rule
+// into a conflict naming the relation. Other errors are returned unchanged.
+func (d *DynamicHandlers) restrictedDelete(ctx context.Context, err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code.Name() != "foreign_key_violation" {
+		return err
+	}
+	fieldID, ok := fieldIDOfForeignKey(pqErr.Constraint)
+	if !ok {
+		return err
+	}
+	field, lookupErr := d.db.Queries.GetField(ctx, fieldID)
+	if lookupErr != nil {
+		return err
+	}
+	collection, lookupErr := d.db.Queries.GetCollection(ctx, field.CollectionID.UUID)
+	if lookupErr != nil {
+		return err
+	}
+	return conflictError("%w", &DeleteRestriction{Collection: collection.Slug, Field: field.Name})
+}
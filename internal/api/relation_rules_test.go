@@ -0,0 +1,73 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFieldForeignKeyName(t *testing.T) {
+	fieldID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+	name := fieldForeignKeyName(fieldID)
+	assert.Equal(t, "field_6e68062fc4c642df9e01e2d1081664f4_fkey", name)
+
+	parsed, ok := fieldIDOfForeignKey(name)
+	assert.True(t, ok)
+	assert.Equal(t, fieldID, parsed)
+
+	for _, constraint := range []string{"field_6e68062fc4c642df9e01e2d1081664f4_key", "orders_customer_fkey", "field_nope_fkey"} {
+		_, ok := fieldIDOfForeignKey(constraint)
+		assert.False(t, ok, constraint)
+	}
+}
+
+func TestRelationRule(t *testing.T) {
+	raw := func(config string) pqtype.NullRawMessage {
+		return pqtype.NullRawMessage{RawMessage: []byte(config), Valid: true}
+	}
+
+	rule := relationRule("relation", raw(`{"related_collection": "customers", "on_delete": "cascade"}`))
+	assert.Equal(t, RelationConfig{Type: RelationManyToOne, RelatedCollection: "customers", OnDelete: OnDeleteCascade}, rule)
+
+	assert.Zero(t, relationRule("relation", raw(`{"related_collection": "customers"}`)))
+	assert.Zero(t, relationRule("uuid", raw(`{"related_collection": "customers", "on_delete": "cascade"}`)))
+	assert.Zero(t, relationRule("relation", pqtype.NullRawMessage{}))
+}
+
+func TestCheckRelationRule(t *testing.T) {
+	assert.ErrorIs(t, checkRelationRule(RelationConfig{OnDelete: OnDeleteSetNull}, true), ErrValidation)
+	assert.NoError(t, checkRelationRule(RelationConfig{OnDelete: OnDeleteSetNull}, false))
+	assert.NoError(t, checkRelationRule(RelationConfig{OnDelete: OnDeleteCascade}, true))
+}
+
+func TestPlanRelationRule(t *testing.T) {
+	fieldID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+	table, related := `"acme"."data_orders"`, `"acme"."data_customers"`
+	cascade := RelationConfig{Type: RelationManyToOne, RelatedCollection: "customers", OnDelete: OnDeleteCascade}
+	restrict := RelationConfig{Type: RelationManyToOne, RelatedCollection: "customers", OnDelete: OnDeleteRestrict}
+
+	plan := planRelationRule(table, fieldID, "customer", related, RelationConfig{}, cascade, false)
+	assert.Empty(t, plan.drop)
+	assert.Equal(t, []string{
+		`ALTER TABLE "acme"."data_orders" ADD CONSTRAINT "field_6e68062fc4c642df9e01e2d1081664f4_fkey" FOREIGN KEY ("customer") REFERENCES "acme"."data_customers" (id) ON DELETE CASCADE`,
+	}, plan.create)
+
+	// Changing the rule replaces the foreign key
+	plan = planRelationRule(table, fieldID, "customer", related, cascade, restrict, false)
+	assert.Equal(t, []string{`ALTER TABLE "acme"."data_orders" DROP CONSTRAINT IF EXISTS "field_6e68062fc4c642df9e01e2d1081664f4_fkey"`}, plan.drop)
+	assert.Len(t, plan.create, 1)
+	assert.Contains(t, plan.create[0], "ON DELETE RESTRICT")
+
+	// An unchanged rule is left alone unless the column is retyped
+	assert.Zero(t, planRelationRule(table, fieldID, "customer", related, restrict, restrict, false))
+	plan = planRelationRule(table, fieldID, "customer", related, restrict, restrict, true)
+	assert.Len(t, plan.drop, 1)
+	assert.Len(t, plan.create, 1)
+
+	// Removing the rule drops it
+	plan = planRelationRule(table, fieldID, "customer", "", restrict, RelationConfig{}, false)
+	assert.Len(t, plan.drop, 1)
+	assert.Empty(t, plan.create)
+}
@@ -0,0 +1,797 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains relational reads: expanding "relation" fields into nested objects when
+// a client asks for them with the fields query parameter.
+//
+// Relation fields are configured through the field's relation_config:
+//
+//	{"type": "m2o", "related_collection": "customers"}
+//	{"type": "o2m", "related_collection": "orders", "related_field": "customer_id"}
+//	{"type": "m2m", "related_collection": "tags", "junction": "products_tags"}
+//
+// A many-to-one (m2o) field stores the related item's ID in its own column. A one-to-many
+// (o2m) field is an alias with no column of its own; it collects the items of the related
+// collection whose related_field points back at the parent. A many-to-many (m2m) field has
+// no column either: its links live in a junction table, created with the field, that pairs
+// junction_field (default "item_id") with related_junction_field (default "related_id").
+// The other side of the relation may name the same junction with the columns swapped.
+//
+// Many-to-many fields read and write as arrays of related item IDs, in order; writing one
+// replaces the item's links in the same transaction as the item. Links to items that no
+// longer exist are skipped when read.
+//
+// A many-to-one field may also set "on_delete" to "cascade", "set_null" or "restrict"; the
+// column then gets a foreign key acting on deletes of the related item (see relation_rules.go).
+//
+// Clients request nested data with dotted paths:
+//
+//	GET /items/orders?fields=*,customer.*
+//	GET /items/customers/:id?fields=*,orders.id,orders.total,orders.customer.name
+//
+// The deep parameter narrows the items of an expanded relation, in JSON or in brackets:
+//
+//	GET /items/customers?fields=*,orders.*&deep[orders][_filter][status][_eq]=paid&deep[orders][_sort]=-total&deep[orders][_limit]=5
+//
+// _filter is a filter rule on the related items, _sort a sort expression, and _limit the
+// most related items kept per item. Options of nested relations go under the relation's
+// name (deep[orders][lines][_limit]=3).
+//
+// Each relation is resolved with one batched query per level (WHERE id = ANY($1)) instead of
+// a SQL JOIN, so one-to-many relations never multiply parent rows. The caller must hold
+// "read" permission on the related collection; otherwise the relation is left unexpanded.
+// Field-level permissions of the related collection are applied to the nested objects.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// maxRelationDepth limits how many levels of nested relations a single request may expand
+const maxRelationDepth = 3
+
+// Relation types supported by relation fields
+const (
+	RelationManyToOne  = "m2o"
+	RelationOneToMany  = "o2m"
+	RelationManyToMany = "m2m"
+)
+
+// Default junction columns of many-to-many relations
+const (
+	defaultJunctionField        = "item_id"
+	defaultRelatedJunctionField = "related_id"
+)
+
+// RelationConfig describes how a relation field links two collections
+type RelationConfig struct {
+	Type                 string `json:"type"`                   // "m2o" (default), "o2m" or "m2m"
+	RelatedCollection    string `json:"related_collection"`     // Slug of the related collection
+	RelatedField         string `json:"related_field"`          // o2m only: column on the related collection pointing back
+	Junction             string `json:"junction"`               // m2m only: name of the junction table
+	JunctionField        string `json:"junction_field"`         // m2m only: junction column holding this item's ID
+	RelatedJunctionField string `json:"related_junction_field"` // m2m only: junction column holding the related item's ID
+	OnDelete             string `json:"on_delete"`              // m2o only: what deleting the related item does, or "" for nothing
+}
+
+// JunctionTable returns the qualified junction table of a many-to-many relation. The name
+// is lowercased, as Postgres does with the unquoted name junction tables were created with.
+func (cfg RelationConfig) JunctionTable(dialect db.Dialect, tenantSchema string) string {
+	return dialect.Table(tenantSchema, "junction_"+strings.ToLower(cfg.Junction))
+}
+
+// parseRelationConfig reads a relation config from a field's relation_config options
+func parseRelationConfig(options map[string]interface{}) (RelationConfig, error) {
+	cfg := RelationConfig{
+		Type:                 GetStringFromMap(options, "type"),
+		RelatedCollection:    GetStringFromMap(options, "related_collection"),
+		RelatedField:         GetStringFromMap(options, "related_field"),
+		Junction:             GetStringFromMap(options, "junction"),
+		JunctionField:        GetStringFromMap(options, "junction_field"),
+		RelatedJunctionField: GetStringFromMap(options, "related_junction_field"),
+		OnDelete:             GetStringFromMap(options, "on_delete"),
+	}
+	if cfg.Type == "" {
+		cfg.Type = RelationManyToOne
+	}
+
+	if cfg.RelatedCollection == "" || !rbac.ValidateTableName(cfg.RelatedCollection) {
+		return cfg, fmt.Errorf("relation_config.related_collection must be a valid collection name")
+	}
+
+	switch cfg.Type {
+	case RelationManyToOne:
+	case RelationOneToMany:
+		if cfg.RelatedField == "" || !rbac.ValidateTableName(cfg.RelatedField) {
+			return cfg, fmt.Errorf("relation_config.related_field is required for o2m relations")
+		}
+	case RelationManyToMany:
+		if cfg.Junction == "" || !rbac.ValidateTableName(cfg.Junction) {
+			return cfg, fmt.Errorf("relation_config.junction is required for m2m relations")
+		}
+		if cfg.JunctionField == "" {
+			cfg.JunctionField = defaultJunctionField
+		}
+		if cfg.RelatedJunctionField == "" {
+			cfg.RelatedJunctionField = defaultRelatedJunctionField
+		}
+		if !rbac.ValidateTableName(cfg.JunctionField) || !rbac.ValidateTableName(cfg.RelatedJunctionField) ||
+			cfg.JunctionField == cfg.RelatedJunctionField {
+			return cfg, fmt.Errorf("relation_config.junction_field and related_junction_field must be two different column names")
+		}
+	default:
+		return cfg, fmt.Errorf("unsupported relation type '%s'", cfg.Type)
+	}
+
+	if cfg.OnDelete != "" {
+		if cfg.Type != RelationManyToOne {
+			return cfg, fmt.Errorf("relation_config.on_delete is only supported on m2o relations")
+		}
+		if _, ok := onDeleteActions[cfg.OnDelete]; !ok {
+			return cfg, fmt.Errorf("relation_config.on_delete must be one of cascade, set_null or restrict")
+		}
+	}
+
+	return cfg, nil
+}
+
+// sqlQuerier is the subset of *sql.DB and *sql.Tx used to read relation metadata and links
+type sqlQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// fieldSelection is the parsed form of the fields query parameter
+type fieldSelection struct {
+	all    bool                       // "*" was requested at this level
+	fields []string                   // Explicitly requested fields at this level
+	nested map[string]*fieldSelection // Relation name -> selection inside the relation
+	query  relationQuery              // Deep options, for the selection of a relation
+}
+
+// relationQuery holds the deep options of an expanded relation
+type relationQuery struct {
+	filter map[string]interface{} // _filter: rule the related items must match
+	sort   string                 // _sort: sort expression of the related items
+	limit  int                    // _limit: most related items per item; 0 for all
+}
+
+// parseFieldSelection parses a fields parameter such as "*,customer.*,customer.address.city".
+// An empty parameter selects all fields with no relations expanded.
+func parseFieldSelection(raw string) *fieldSelection {
+	sel := &fieldSelection{nested: map[string]*fieldSelection{}}
+	if strings.TrimSpace(raw) == "" {
+		sel.all = true
+		return sel
+	}
+
+	for _, part := range strings.Split(raw, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		sel.add(strings.Split(part, "."), 0)
+	}
+
+	return sel
+}
+
+// add records a dotted path in the selection, ignoring paths deeper than maxRelationDepth
+func (s *fieldSelection) add(path []string, depth int) {
+	if len(path) == 1 {
+		if path[0] == "*" {
+			s.all = true
+		} else if !Contains(s.fields, path[0]) {
+			s.fields = append(s.fields, path[0])
+		}
+		return
+	}
+
+	if depth >= maxRelationDepth {
+		return
+	}
+
+	child, exists := s.nested[path[0]]
+	if !exists {
+		child = &fieldSelection{nested: map[string]*fieldSelection{}}
+		s.nested[path[0]] = child
+	}
+	child.add(path[1:], depth+1)
+}
+
+// columns returns the top-level fields the selection names, including the relations it
+// expands, or nil when it selects every field
+func (s *fieldSelection) columns() []string {
+	if s.all {
+		return nil
+	}
+	columns := append([]string{}, s.fields...)
+	for relation := range s.nested {
+		if !Contains(columns, relation) {
+			columns = append(columns, relation)
+		}
+	}
+	sort.Strings(columns[len(s.fields):])
+	return columns
+}
+
+// applyDeep sets the deep options of the relations in the selection from a deep parameter
+// such as {"orders": {"_limit": 5, "lines": {"_sort": "-id"}}}. Relations the selection
+// does not expand are ignored.
+func (s *fieldSelection) applyDeep(deep map[string]interface{}) error {
+	for relation, value := range deep {
+		options, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("deep options of '%s' must be an object", relation)
+		}
+		child := s.nested[relation]
+		if child == nil {
+			continue
+		}
+
+		for key, option := range options {
+			var err error
+			switch key {
+			case "_filter":
+				filter, ok := option.(map[string]interface{})
+				if !ok {
+					return fmt.Errorf("deep _filter of '%s' must be an object", relation)
+				}
+				child.query.filter = filter
+			case "_sort":
+				child.query.sort, err = deepSort(option)
+			case "_limit":
+				child.query.limit, err = deepLimit(option)
+			default:
+				if strings.HasPrefix(key, "_") {
+					return fmt.Errorf("unsupported deep option '%s'", key)
+				}
+				err = child.applyDeep(map[string]interface{}{key: option})
+			}
+			if err != nil {
+				return fmt.Errorf("deep options of '%s': %w", relation, err)
+			}
+		}
+	}
+	return nil
+}
+
+// deepSort reads a _sort option: a sort expression or, in JSON, an array of fields
+func deepSort(option interface{}) (string, error) {
+	switch v := option.(type) {
+	case string:
+		return v, nil
+	case []interface{}:
+		fields := make([]string, len(v))
+		for i, field := range v {
+			fields[i] = fmt.Sprint(field)
+		}
+		return strings.Join(fields, ","), nil
+	}
+	return "", fmt.Errorf("_sort must be a list of fields")
+}
+
+// deepLimit reads a _limit option; -1 keeps every related item, as does 0
+func deepLimit(option interface{}) (int, error) {
+	var limit int
+	switch v := option.(type) {
+	case float64:
+		limit = int(v)
+	case string:
+		parsed, err := strconv.Atoi(v)
+		if err != nil {
+			return 0, fmt.Errorf("_limit must be a number")
+		}
+		limit = parsed
+	default:
+		return 0, fmt.Errorf("_limit must be a number")
+	}
+	if limit < 0 {
+		return 0, nil
+	}
+	return limit, nil
+}
+
+// limited returns at most limit related items, or all when limit is 0
+func (q relationQuery) limited(children []map[string]interface{}) []map[string]interface{} {
+	if q.limit > 0 && len(children) > q.limit {
+		return children[:q.limit]
+	}
+	return children
+}
+
+// hasRelations reports whether any relation expansion was requested
+func (s *fieldSelection) hasRelations() bool {
+	return len(s.nested) > 0
+}
+
+// project trims an item down to the selected fields. Expanded relations are always kept.
+func (s *fieldSelection) project(item map[string]interface{}) map[string]interface{} {
+	if s.all {
+		return item
+	}
+
+	projected := make(map[string]interface{}, len(s.fields)+len(s.nested))
+	for _, field := range s.fields {
+		if value, exists := item[field]; exists {
+			projected[field] = value
+		}
+	}
+	for relation := range s.nested {
+		if value, exists := item[relation]; exists {
+			projected[relation] = value
+		}
+	}
+	return projected
+}
+
+// RelationExpander resolves relation fields into nested objects for read requests
+type RelationExpander struct {
+	db                 *db.DB
+	utils              *ItemsUtils
+	collectionsHandler *CollectionsHandler
+	policyChecker      *rbac.PolicyChecker
+}
+
+// NewRelationExpander creates a RelationExpander with the shared handler dependencies
+func NewRelationExpander(db *db.DB, utils *ItemsUtils, collectionsHandler *CollectionsHandler, policyChecker *rbac.PolicyChecker) *RelationExpander {
+	return &RelationExpander{
+		db:                 db,
+		utils:              utils,
+		collectionsHandler: collectionsHandler,
+		policyChecker:      policyChecker,
+	}
+}
+
+// Expand replaces the relation fields named in sel with nested objects (m2o) or arrays of
+// objects (o2m and m2m) on every item, in place. ctx must carry the tenant used for permission checks.
+func (e *RelationExpander) Expand(ctx context.Context, userID, tenantID uuid.UUID, collectionSlug string, items []map[string]interface{}, sel *fieldSelection) error {
+	if len(items) == 0 || !sel.hasRelations() {
+		return nil
+	}
+
+	collection, err := e.collectionsHandler.GetCollection(ctx, tenantID, collectionSlug)
+	if err != nil {
+		return err
+	}
+
+	fields, err := e.collectionsHandler.GetCollectionFields(ctx, collection.ID)
+	if err != nil {
+		return err
+	}
+
+	tenantSchema, err := e.utils.GetTenantSchema(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to get tenant schema: %w", err)
+	}
+
+	for relationName, childSel := range sel.nested {
+		var field *CollectionField
+		for i := range fields {
+			if fields[i].Name == relationName && fields[i].Type == "relation" {
+				field = &fields[i]
+				break
+			}
+		}
+		if field == nil {
+			// Not a relation of this collection - leave the value untouched
+			continue
+		}
+
+		cfg, err := parseRelationConfig(field.Options)
+		if err != nil {
+			return fmt.Errorf("relation '%s': %w", relationName, err)
+		}
+
+		hasPermission, allowedFields, rowFilter, transforms, err := e.policyChecker.CheckPermissionWithTransforms(ctx, userID, cfg.RelatedCollection, "read")
+		if err != nil {
+			return fmt.Errorf("failed to check permissions for '%s': %w", cfg.RelatedCollection, err)
+		}
+		if !hasPermission {
+			// No read access to the related collection - keep the raw foreign key
+			continue
+		}
+
+		relatedCtx := rbac.WithFieldTransforms(ctx, cfg.RelatedCollection, transforms)
+		switch cfg.Type {
+		case RelationOneToMany:
+			err = e.expandOneToMany(relatedCtx, userID, tenantID, relationName, cfg, allowedFields, rowFilter, items, childSel)
+		case RelationManyToMany:
+			err = e.expandManyToMany(relatedCtx, userID, tenantID, tenantSchema, relationName, cfg, allowedFields, rowFilter, items, childSel)
+		default:
+			err = e.expandManyToOne(relatedCtx, userID, tenantID, relationName, cfg, allowedFields, rowFilter, items, childSel)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to expand relation '%s': %w", relationName, err)
+		}
+	}
+
+	return nil
+}
+
+// expandManyToOne replaces each item's foreign key with the related object
+func (e *RelationExpander) expandManyToOne(ctx context.Context, userID, tenantID uuid.UUID, relationName string, cfg RelationConfig, allowedFields []string, rowFilter *rbac.RowFilter, items []map[string]interface{}, sel *fieldSelection) error {
+	ids := collectRelationKeys(items, relationName)
+	if len(ids) == 0 {
+		return nil
+	}
+
+	related, err := e.fetchRelated(ctx, userID, tenantID, cfg.RelatedCollection, "id", ids, allowedFields, rowFilter, sel)
+	if err != nil {
+		return err
+	}
+
+	byID := make(map[string]map[string]interface{}, len(related))
+	for _, row := range related {
+		byID[fmt.Sprint(row.key)] = row.data
+	}
+
+	for _, item := range items {
+		if value, exists := item[relationName]; exists && value != nil {
+			// Dangling references expand to null rather than leaking the stale ID
+			item[relationName] = byID[fmt.Sprint(value)]
+		}
+	}
+
+	return nil
+}
+
+// expandOneToMany attaches the array of related items that point back at each item
+func (e *RelationExpander) expandOneToMany(ctx context.Context, userID, tenantID uuid.UUID, relationName string, cfg RelationConfig, allowedFields []string, rowFilter *rbac.RowFilter, items []map[string]interface{}, sel *fieldSelection) error {
+	ids := collectRelationKeys(items, "id")
+	if len(ids) == 0 {
+		return nil
+	}
+
+	related, err := e.fetchRelated(ctx, userID, tenantID, cfg.RelatedCollection, cfg.RelatedField, ids, allowedFields, rowFilter, sel)
+	if err != nil {
+		return err
+	}
+
+	byParent := make(map[string][]map[string]interface{})
+	for _, row := range related {
+		parentID := fmt.Sprint(row.key)
+		byParent[parentID] = append(byParent[parentID], row.data)
+	}
+
+	for _, item := range items {
+		children := byParent[fmt.Sprint(item["id"])]
+		if children == nil {
+			children = []map[string]interface{}{}
+		}
+		item[relationName] = sel.query.limited(children)
+	}
+
+	return nil
+}
+
+// expandManyToMany attaches the array of items linked to each item through the junction
+// table, in link order
+func (e *RelationExpander) expandManyToMany(ctx context.Context, userID, tenantID uuid.UUID, tenantSchema, relationName string, cfg RelationConfig, allowedFields []string, rowFilter *rbac.RowFilter, items []map[string]interface{}, sel *fieldSelection) error {
+	links, err := readJunction(ctx, e.db, cfg.JunctionTable(e.utils.dialect(), tenantSchema), cfg, collectRelationKeys(items, "id"))
+	if err != nil {
+		return err
+	}
+
+	var relatedIDs []string
+	seen := make(map[string]bool)
+	for _, ids := range links {
+		for _, id := range ids {
+			if !seen[id] {
+				seen[id] = true
+				relatedIDs = append(relatedIDs, id)
+			}
+		}
+	}
+
+	byID := make(map[string]map[string]interface{})
+	rank := make(map[string]int)
+	if len(relatedIDs) > 0 {
+		related, err := e.fetchRelated(ctx, userID, tenantID, cfg.RelatedCollection, "id", relatedIDs, allowedFields, rowFilter, sel)
+		if err != nil {
+			return err
+		}
+		for i, row := range related {
+			byID[fmt.Sprint(row.key)] = row.data
+			rank[fmt.Sprint(row.key)] = i
+		}
+	}
+
+	for _, item := range items {
+		linked := append([]string{}, links[fmt.Sprint(item["id"])]...)
+		if sel.query.sort != "" {
+			// A requested sort replaces the link order
+			sort.SliceStable(linked, func(i, j int) bool { return rank[linked[i]] < rank[linked[j]] })
+		}
+		children := []map[string]interface{}{}
+		for _, id := range linked {
+			if child, ok := byID[id]; ok {
+				children = append(children, child)
+			}
+		}
+		item[relationName] = sel.query.limited(children)
+	}
+
+	return nil
+}
+
+// LoadManyToMany sets the many-to-many fields of every item to the IDs of the linked items,
+// in place. Fields outside allowedFields or the selection are left out. Expand later
+// replaces the IDs of expanded fields with the items, as it does for many-to-one keys.
+func (e *RelationExpander) LoadManyToMany(ctx context.Context, tenantID uuid.UUID, collectionSlug string, items []map[string]interface{}, allowedFields []string, sel *fieldSelection) error {
+	if len(items) == 0 {
+		return nil
+	}
+
+	relations, err := manyToManyFields(ctx, e.db, tenantID, collectionSlug)
+	if err != nil || len(relations) == 0 {
+		return err
+	}
+
+	tenantSchema, err := e.utils.GetTenantSchema(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("failed to get tenant schema: %w", err)
+	}
+
+	ids := collectRelationKeys(items, "id")
+	for name, cfg := range relations {
+		if !fieldAllowed(allowedFields, name) || !(sel.all || Contains(sel.fields, name) || sel.nested[name] != nil) {
+			continue
+		}
+		links, err := readJunction(ctx, e.db, cfg.JunctionTable(e.utils.dialect(), tenantSchema), cfg, ids)
+		if err != nil {
+			return fmt.Errorf("failed to load relation '%s': %w", name, err)
+		}
+		for _, item := range items {
+			linked := links[fmt.Sprint(item["id"])]
+			if linked == nil {
+				linked = []string{}
+			}
+			item[name] = linked
+		}
+	}
+
+	return nil
+}
+
+// fieldAllowed reports whether a field passes a field-level permission list
+func fieldAllowed(allowedFields []string, name string) bool {
+	return len(allowedFields) == 0 || Contains(allowedFields, "*") || Contains(allowedFields, name)
+}
+
+// manyToManyFields returns the many-to-many relation fields of a collection by name
+func manyToManyFields(ctx context.Context, q sqlQuerier, tenantID uuid.UUID, collectionSlug string) (map[string]RelationConfig, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT f.name, f.relation_config
+		FROM fields f
+		JOIN collections c ON c.id = f.collection_id
+		WHERE c.slug = $1 AND c.tenant_id = $2 AND f.type = 'relation' AND f.relation_config->>'type' = $3
+	`, collectionSlug, tenantID, RelationManyToMany)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch relation fields: %w", err)
+	}
+	defer rows.Close()
+
+	relations := make(map[string]RelationConfig)
+	for rows.Next() {
+		var name string
+		var raw []byte
+		if err := rows.Scan(&name, &raw); err != nil {
+			return nil, fmt.Errorf("failed to scan relation field: %w", err)
+		}
+		var options map[string]interface{}
+		if err := json.Unmarshal(raw, &options); err != nil {
+			return nil, fmt.Errorf("relation '%s': invalid relation_config: %w", name, err)
+		}
+		cfg, err := parseRelationConfig(options)
+		if err != nil {
+			return nil, fmt.Errorf("relation '%s': %w", name, err)
+		}
+		relations[name] = cfg
+	}
+	return relations, rows.Err()
+}
+
+// readJunction returns the IDs linked to each of itemIDs through the junction table of
+// cfg, in link order
+func readJunction(ctx context.Context, q sqlQuerier, table string, cfg RelationConfig, itemIDs []string) (map[string][]string, error) {
+	links := make(map[string][]string)
+	if len(itemIDs) == 0 {
+		return links, nil
+	}
+
+	query := fmt.Sprintf(`SELECT "%s", "%s" FROM %s WHERE "%s" = ANY($1::uuid[]) ORDER BY sort_order`,
+		cfg.JunctionField, cfg.RelatedJunctionField, table, cfg.JunctionField)
+	rows, err := q.QueryContext(ctx, query, pq.Array(itemIDs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query junction %s: %w", cfg.Junction, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var itemID, relatedID string
+		if err := rows.Scan(&itemID, &relatedID); err != nil {
+			return nil, fmt.Errorf("failed to scan junction %s: %w", cfg.Junction, err)
+		}
+		links[itemID] = append(links[itemID], relatedID)
+	}
+	return links, rows.Err()
+}
+
+// writeJunction replaces the links of one item in the junction table of cfg with relatedIDs
+func writeJunction(ctx context.Context, exec sqlExecutor, table string, cfg RelationConfig, itemID string, relatedIDs []string) error {
+	query := fmt.Sprintf(`DELETE FROM %s WHERE "%s" = $1`, table, cfg.JunctionField)
+	if _, err := exec.ExecContext(ctx, query, itemID); err != nil {
+		return fmt.Errorf("failed to update junction %s: %w", cfg.Junction, err)
+	}
+	if len(relatedIDs) == 0 {
+		return nil
+	}
+
+	query = fmt.Sprintf(`INSERT INTO %s ("%s", "%s", sort_order)
+		SELECT $1, link.id, link.position FROM unnest($2::uuid[]) WITH ORDINALITY AS link(id, position)
+		ON CONFLICT DO NOTHING`, table, cfg.JunctionField, cfg.RelatedJunctionField)
+	if _, err := exec.ExecContext(ctx, query, itemID, pq.Array(relatedIDs)); err != nil {
+		return fmt.Errorf("failed to update junction %s: %w", cfg.Junction, err)
+	}
+	return nil
+}
+
+// relatedIDList reads the value written to a many-to-many field: an array of item IDs (or
+// its JSON text, as reverted revisions carry it), or null for no links
+func relatedIDList(value interface{}) ([]string, error) {
+	if text, ok := value.(string); ok {
+		if err := json.Unmarshal([]byte(text), &value); err != nil {
+			return nil, fmt.Errorf("expected an array of IDs, got string")
+		}
+	}
+
+	var values []interface{}
+	switch v := value.(type) {
+	case nil:
+		return []string{}, nil
+	case []interface{}:
+		values = v
+	case []string:
+		for _, id := range v {
+			values = append(values, id)
+		}
+	default:
+		return nil, fmt.Errorf("expected an array of IDs, got %T", value)
+	}
+
+	ids := make([]string, 0, len(values))
+	for _, value := range values {
+		str, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected UUID string, got %T", value)
+		}
+		if _, err := uuid.Parse(str); err != nil {
+			return nil, fmt.Errorf("invalid UUID '%s'", str)
+		}
+		ids = append(ids, str)
+	}
+	return ids, nil
+}
+
+// relatedRow is a fetched related item along with the value of the column it was matched on
+type relatedRow struct {
+	key  interface{}
+	data map[string]interface{}
+}
+
+// fetchRelated loads the related items whose keyColumn is in keys, expands their own nested
+// relations, and applies field and row permissions, the field transforms in ctx and the
+// requested projection. Related items outside the caller's row filter are treated as missing.
+func (e *RelationExpander) fetchRelated(ctx context.Context, userID, tenantID uuid.UUID, relatedCollection, keyColumn string, keys []string, allowedFields []string, rowFilter *rbac.RowFilter, sel *fieldSelection) ([]relatedRow, error) {
+	transforms := rbac.FieldTransformsFromContext(ctx, relatedCollection)
+
+	// Always select the key column and id so rows can be matched and expanded further
+	selectFields := allowedFields
+	if !Contains(allowedFields, "*") && len(allowedFields) > 0 {
+		selectFields = append([]string{}, allowedFields...)
+		for _, required := range []string{"id", keyColumn} {
+			if !Contains(selectFields, required) {
+				selectFields = append(selectFields, required)
+			}
+		}
+	}
+
+	table, err := e.utils.ResolveDataTable(ctx, tenantID, relatedCollection)
+	if errors.Is(err, ErrDataTableNotFound) {
+		// A collection without a data table has no items to relate to
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Trashed items are not related to anything until they are restored
+	query := rbac.BuildSelectQuery(table.String(), selectFields) +
+		fmt.Sprintf(` WHERE "%s" = ANY($1::uuid[])`, keyColumn) +
+		liveRowsOnly(e.collectionsHandler.dynamicHandlers.softDeleteEnabled(ctx, tenantID, relatedCollection))
+	args := []interface{}{pq.Array(keys)}
+	if condition, ruleArgs := rowFilter.SQL(2); condition != "" {
+		query += " AND " + condition
+		args = append(args, ruleArgs...)
+	}
+
+	// Deep options of the relation
+	if sel.query.filter != nil {
+		filter, err := compileFilterRule(sel.query.filter, allowedFields, rbac.RuleVars{UserID: userID, TenantID: tenantID, Now: time.Now()})
+		if err == nil {
+			err = checkRedactedFilter(filter, transforms)
+		}
+		if err != nil {
+			return nil, validationError("deep _filter of %s: %s", relatedCollection, err)
+		}
+		if condition, filterArgs := filter.SQL(len(args) + 1); condition != "" {
+			query += " AND " + condition
+			args = append(args, filterArgs...)
+		}
+	}
+	if sel.query.sort != "" {
+		sortKeys, order, err := parseSort(sel.query.sort, "", allowedFields, transforms)
+		if err != nil {
+			return nil, validationError("deep _sort of %s: %s", relatedCollection, err)
+		}
+		query += (&pagination{Sort: sortKeys, Order: order}).orderClause()
+	}
+
+	rows, err := e.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s: %w", relatedCollection, err)
+	}
+	defer rows.Close()
+
+	results := e.utils.ScanRowsToMaps(rows)
+	if err := revealEncrypted(ctx, e.collectionsHandler.dynamicHandlers, e.policyChecker, userID, tenantID, relatedCollection, results); err != nil {
+		return nil, err
+	}
+
+	// Capture match keys before permissions or projection can remove the key column
+	related := make([]relatedRow, len(results))
+	for i, result := range results {
+		related[i] = relatedRow{key: result[keyColumn], data: result}
+	}
+
+	if err := e.Expand(ctx, userID, tenantID, relatedCollection, results, sel); err != nil {
+		return nil, err
+	}
+
+	for i := range related {
+		related[i].data = sel.project(e.policyChecker.FilterFields(related[i].data, allowedFields, transforms))
+	}
+
+	return related, nil
+}
+
+// collectRelationKeys returns the distinct non-empty values of key across items
+func collectRelationKeys(items []map[string]interface{}, key string) []string {
+	seen := make(map[string]bool)
+	var keys []string
+	for _, item := range items {
+		value, exists := item[key]
+		if !exists || value == nil {
+			continue
+		}
+		id := fmt.Sprint(value)
+		if _, err := uuid.Parse(id); err != nil || seen[id] {
+			continue
+		}
+		seen[id] = true
+		keys = append(keys, id)
+	}
+	return keys
+}
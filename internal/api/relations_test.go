@@ -0,0 +1,251 @@
+package api
+
+import (
+	"context"
+	"database/sql"
+	"net/url"
+	"testing"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/dbtest"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldSelection(t *testing.T) {
+	t.Run("Empty Selects Everything", func(t *testing.T) {
+		sel := parseFieldSelection("")
+		assert.True(t, sel.all)
+		assert.False(t, sel.hasRelations())
+	})
+
+	t.Run("Nested Relations", func(t *testing.T) {
+		sel := parseFieldSelection("*, customer.*, customer.address.city, items.sku")
+		assert.True(t, sel.all)
+		assert.True(t, sel.hasRelations())
+
+		customer := sel.nested["customer"]
+		if assert.NotNil(t, customer) {
+			assert.True(t, customer.all)
+			assert.Equal(t, []string{"city"}, customer.nested["address"].fields)
+		}
+
+		items := sel.nested["items"]
+		if assert.NotNil(t, items) {
+			assert.False(t, items.all)
+			assert.Equal(t, []string{"sku"}, items.fields)
+		}
+	})
+
+	t.Run("Depth Is Capped", func(t *testing.T) {
+		sel := parseFieldSelection("a.b.c.d.e")
+		assert.NotNil(t, sel.nested["a"].nested["b"].nested["c"])
+		assert.Empty(t, sel.nested["a"].nested["b"].nested["c"].nested)
+		assert.Empty(t, sel.nested["a"].nested["b"].nested["c"].fields)
+	})
+}
+
+func TestFieldSelection_Project(t *testing.T) {
+	sel := parseFieldSelection("name,customer.name")
+	item := map[string]interface{}{
+		"id":       "1",
+		"name":     "Order 1",
+		"total":    10,
+		"customer": map[string]interface{}{"name": "Ada"},
+	}
+
+	projected := sel.project(item)
+	assert.Equal(t, map[string]interface{}{
+		"name":     "Order 1",
+		"customer": map[string]interface{}{"name": "Ada"},
+	}, projected)
+}
+
+func TestFieldSelection_ApplyDeep(t *testing.T) {
+	values, err := url.ParseQuery("deep[orders][_limit]=5&deep[orders][_sort]=-total&deep[orders][lines][_filter][qty][_gt]=1&deep[ignored][_limit]=1")
+	require.NoError(t, err)
+	deep, err := queryObjectParam(values, "deep")
+	require.NoError(t, err)
+
+	sel := parseFieldSelection("*,orders.*,orders.lines.*")
+	require.NoError(t, sel.applyDeep(deep))
+	orders := sel.nested["orders"]
+	assert.Equal(t, relationQuery{sort: "-total", limit: 5}, orders.query)
+	assert.Equal(t, map[string]interface{}{"qty": map[string]interface{}{"_gt": "1"}}, orders.nested["lines"].query.filter)
+
+	children := []map[string]interface{}{{"id": "1"}, {"id": "2"}, {"id": "3"}, {"id": "4"}, {"id": "5"}, {"id": "6"}}
+	assert.Len(t, orders.query.limited(children), 5)
+
+	assert.Error(t, sel.applyDeep(map[string]interface{}{"orders": map[string]interface{}{"_page": 2.0}}))
+	assert.Error(t, sel.applyDeep(map[string]interface{}{"orders": map[string]interface{}{"_limit": "many"}}))
+}
+
+func TestSelectedColumns(t *testing.T) {
+	handler := &ItemsHandler{policyChecker: rbac.NewPolicyChecker(nil)}
+
+	tests := []struct {
+		name          string
+		fields        string
+		allowedFields []string
+		want          []string
+	}{
+		{"No Projection", "", []string{"*"}, []string{"*"}},
+		{"Star", "*,customer.*", []string{"name", "price"}, []string{"name", "price"}},
+		{"Projection", "name,price", []string{"*"}, []string{"id", "name", "price"}},
+		{"Only Readable Fields", "name,cost", []string{"id", "name", "price"}, []string{"id", "name"}},
+		{"Relations", "name,customer.name,tags,orders.total", []string{"*"}, []string{"id", "name", "tags", "customer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// orders is a one-to-many relation, which has no column
+			got := handler.selectedColumns(parseFieldSelection(tt.fields), tt.allowedFields, []string{"orders"})
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestParseRelationConfig(t *testing.T) {
+	cfg, err := parseRelationConfig(map[string]interface{}{"related_collection": "customers"})
+	assert.NoError(t, err)
+	assert.Equal(t, RelationManyToOne, cfg.Type)
+
+	_, err = parseRelationConfig(map[string]interface{}{"type": "o2m", "related_collection": "orders"})
+	assert.Error(t, err, "o2m requires related_field")
+
+	cfg, err = parseRelationConfig(map[string]interface{}{"type": "o2m", "related_collection": "orders", "related_field": "customer_id"})
+	assert.NoError(t, err)
+	assert.Equal(t, "customer_id", cfg.RelatedField)
+
+	_, err = parseRelationConfig(map[string]interface{}{"related_collection": "bad-name"})
+	assert.Error(t, err)
+
+	_, err = parseRelationConfig(map[string]interface{}{"type": "m2m", "related_collection": "tags"})
+	assert.Error(t, err, "m2m requires junction")
+
+	cfg, err = parseRelationConfig(map[string]interface{}{"type": "m2m", "related_collection": "tags", "junction": "products_tags"})
+	assert.NoError(t, err)
+	assert.Equal(t, "item_id", cfg.JunctionField)
+	assert.Equal(t, "related_id", cfg.RelatedJunctionField)
+	assert.Equal(t, `"acme"."junction_products_tags"`, cfg.JunctionTable(db.Postgres, "acme"))
+
+	_, err = parseRelationConfig(map[string]interface{}{
+		"type": "m2m", "related_collection": "tags", "junction": "products_tags", "related_junction_field": "item_id",
+	})
+	assert.Error(t, err, "junction columns must differ")
+
+	_, err = parseRelationConfig(map[string]interface{}{"type": "m2x", "related_collection": "tags"})
+	assert.Error(t, err)
+
+	cfg, err = parseRelationConfig(map[string]interface{}{"related_collection": "customers", "on_delete": "restrict"})
+	assert.NoError(t, err)
+	assert.Equal(t, OnDeleteRestrict, cfg.OnDelete)
+
+	_, err = parseRelationConfig(map[string]interface{}{"related_collection": "customers", "on_delete": "no_action"})
+	assert.Error(t, err, "unknown on_delete rule")
+
+	_, err = parseRelationConfig(map[string]interface{}{"type": "o2m", "related_collection": "orders", "related_field": "customer_id", "on_delete": "cascade"})
+	assert.Error(t, err, "on_delete is for m2o relations")
+}
+
+func TestRelatedIDList(t *testing.T) {
+	id := "6e68062f-c4c6-42df-9e01-e2d1081664f4"
+
+	ids, err := relatedIDList([]interface{}{id})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{id}, ids)
+
+	// null clears the links
+	ids, err = relatedIDList(nil)
+	assert.NoError(t, err)
+	assert.Empty(t, ids)
+
+	// Reverted revisions carry the array as JSON text
+	ids, err = relatedIDList(`["` + id + `"]`)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{id}, ids)
+
+	_, err = relatedIDList([]interface{}{42})
+	assert.Error(t, err)
+	_, err = relatedIDList(id)
+	assert.Error(t, err)
+}
+
+func TestJunctionWriter_Split(t *testing.T) {
+	id := "6e68062f-c4c6-42df-9e01-e2d1081664f4"
+	data := map[string]interface{}{"name": "Lamp", "tags": []interface{}{id}}
+
+	// Collections without many-to-many fields pass data through
+	var none *junctionWriter
+	row, related, err := none.split(data)
+	assert.NoError(t, err)
+	assert.Equal(t, data, row)
+	assert.Empty(t, related)
+
+	writer := &junctionWriter{relations: map[string]RelationConfig{"tags": {Type: RelationManyToMany, Junction: "products_tags"}}}
+	row, related, err = writer.split(data)
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{"name": "Lamp"}, row)
+	assert.Equal(t, map[string][]string{"tags": {id}}, related)
+
+	_, _, err = writer.split(map[string]interface{}{"tags": "not-an-array"})
+	assert.Error(t, err)
+}
+
+func TestCollectRelationKeys(t *testing.T) {
+	items := []map[string]interface{}{
+		{"customer": "6e68062f-c4c6-42df-9e01-e2d1081664f4"},
+		{"customer": "6e68062f-c4c6-42df-9e01-e2d1081664f4"},
+		{"customer": nil},
+		{"customer": "not-a-uuid"},
+		{},
+	}
+
+	assert.Equal(t, []string{"6e68062f-c4c6-42df-9e01-e2d1081664f4"}, collectRelationKeys(items, "customer"))
+}
+
+func TestRelationExpander_SkipsTrashedItems(t *testing.T) {
+	database := dbtest.SQLite(t)
+	utils := NewItemsUtils(database)
+	ctx := context.Background()
+	tenantID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+
+	collection, err := database.Queries.CreateCollection(ctx, sqlc.CreateCollectionParams{
+		ID:         uuid.New(),
+		Name:       "authors",
+		Slug:       "authors",
+		TenantID:   uuid.NullUUID{UUID: tenantID, Valid: true},
+		SoftDelete: true,
+	})
+	require.NoError(t, err)
+	require.NoError(t, utils.CreateDataTable(ctx, &collection))
+	require.NoError(t, utils.EnableSoftDelete(ctx, tenantID, "authors"))
+	field, err := database.Queries.CreateField(ctx, sqlc.CreateFieldParams{
+		ID:           uuid.New(),
+		CollectionID: uuid.NullUUID{UUID: collection.ID, Valid: true},
+		Name:         "name",
+		Type:         "text",
+		IsUnique:     sql.NullBool{Valid: true},
+		TenantID:     uuid.NullUUID{UUID: tenantID, Valid: true},
+	})
+	require.NoError(t, err)
+	require.NoError(t, utils.AddColumnToDataTable(ctx, tenantID, "authors", field))
+
+	table, err := utils.ResolveDataTable(ctx, tenantID, "authors")
+	require.NoError(t, err)
+	live, trashed := uuid.New(), uuid.New()
+	_, err = database.ExecContext(ctx, `INSERT INTO `+table.String()+` (id, name) VALUES ($1, 'Ada'), ($2, 'Grace')`, live, trashed)
+	require.NoError(t, err)
+	_, err = database.ExecContext(ctx, `UPDATE `+table.String()+` SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1`, trashed)
+	require.NoError(t, err)
+
+	expander := NewRelationExpander(database, utils, NewCollectionsHandler(database, utils, NewDynamicHandlers(database, utils, nil)), rbac.NewPolicyChecker(database.Queries))
+	related, err := expander.fetchRelated(ctx, uuid.New(), tenantID, "authors", "id", []string{live.String(), trashed.String()}, []string{"*"}, nil, parseFieldSelection(""))
+	require.NoError(t, err)
+	require.Len(t, related, 1, "a relation to a trashed item expands to nothing")
+	assert.Equal(t, "Ada", related[0].data["name"])
+}
@@ -0,0 +1,272 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the ResponseCache, which keeps recent GET /items/:table responses of
+// collections so hot list queries are answered without touching the database.
+//
+// A response is cached per tenant, user, permissions and query string, and is only looked
+// up after the permission check, so a cached list never reaches a caller who could not
+// read it or shows fields the caller may no longer see. Entries of a collection are
+// dropped when one of its items is written, here through item events and on other
+// instances through the cache bus. Writes that bypass the API (plain SQL, computed fields
+// looking up other collections) show after ITEMS_CACHE_TTL.
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	maxResponseCacheEntries = 10000   // Bounds the number of cached responses
+	maxCachedResponseSize   = 1 << 20 // Larger responses are not cached
+)
+
+// collectionKeySpace is the namespace of the UUIDs that name collections on the cache bus
+var collectionKeySpace = uuid.MustParse("5b1d3f0e-8d4a-4c1e-9a43-2f6b7c0d9e11")
+
+// CollectionKey names a collection on the cache bus, which identifies entries by UUID
+func CollectionKey(slug string) uuid.UUID {
+	return uuid.NewSHA1(collectionKeySpace, []byte(slug))
+}
+
+// cachedResponse is one cached list response
+type cachedResponse struct {
+	collection  uuid.UUID // CollectionKey of the listed collection
+	contentType string
+	body        []byte
+	expires     time.Time
+}
+
+// ResponseCache keeps list responses of collections for a short time. Like the
+// SchemaCache, responses are only stored if no invalidation happened while they were
+// computed.
+//
+// A nil *ResponseCache is valid and caches nothing.
+type ResponseCache struct {
+	ttl       time.Duration
+	now       func() time.Time
+	broadcast func(collection uuid.UUID) // Announces invalidations to other instances; may be nil
+
+	mu         sync.RWMutex
+	entries    map[string]cachedResponse
+	generation uint64
+}
+
+// NewResponseCache creates a cache whose responses live for ttl
+func NewResponseCache(ttl time.Duration) *ResponseCache {
+	return &ResponseCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]cachedResponse),
+	}
+}
+
+// defaultResponseCache is used by every ItemsHandler; nil disables caching
+var defaultResponseCache *ResponseCache
+
+// UseResponseCache installs the response cache. Call it once at startup; passing nil
+// disables caching.
+func UseResponseCache(cache *ResponseCache) {
+	defaultResponseCache = cache
+}
+
+// Broadcast sets the function that announces invalidations to other instances, with the
+// CollectionKey of the written collection or uuid.Nil when every entry was dropped. Call
+// it at startup, before the cache is used.
+func (rc *ResponseCache) Broadcast(fn func(collection uuid.UUID)) {
+	if rc == nil {
+		return
+	}
+	rc.broadcast = fn
+}
+
+// Generation returns the number of invalidations so far
+func (rc *ResponseCache) Generation() uint64 {
+	if rc == nil {
+		return 0
+	}
+
+	rc.mu.RLock()
+	defer rc.mu.RUnlock()
+	return rc.generation
+}
+
+// get returns a live cached response
+func (rc *ResponseCache) get(key string) (cachedResponse, bool) {
+	if rc == nil {
+		return cachedResponse{}, false
+	}
+
+	rc.mu.RLock()
+	response, ok := rc.entries[key]
+	rc.mu.RUnlock()
+
+	if !ok || rc.now().After(response.expires) {
+		return cachedResponse{}, false
+	}
+	return response, true
+}
+
+// set stores a response computed since generation
+func (rc *ResponseCache) set(generation uint64, key string, response cachedResponse) {
+	if rc == nil || len(response.body) > maxCachedResponseSize {
+		return
+	}
+
+	now := rc.now()
+	response.expires = now.Add(rc.ttl)
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.generation != generation {
+		return
+	}
+
+	// Keep the map from growing with responses nobody asks for again
+	if len(rc.entries) >= maxResponseCacheEntries {
+		for k, entry := range rc.entries {
+			if now.After(entry.expires) {
+				delete(rc.entries, k)
+			}
+		}
+		if len(rc.entries) >= maxResponseCacheEntries {
+			rc.entries = make(map[string]cachedResponse)
+		}
+	}
+	rc.entries[key] = response
+}
+
+// HandleInvalidation drops the responses of a collection, given its CollectionKey, or
+// every response for uuid.Nil. Register it with the cache bus.
+func (rc *ResponseCache) HandleInvalidation(collection uuid.UUID) {
+	if rc == nil {
+		return
+	}
+
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generation++
+	if collection == uuid.Nil {
+		rc.entries = make(map[string]cachedResponse)
+		return
+	}
+	for key, entry := range rc.entries {
+		if entry.collection == collection {
+			delete(rc.entries, key)
+		}
+	}
+}
+
+// HandleEvent drops the responses of a collection when one of its items is written
+// through the API, and every response when collections or fields change, here and on
+// every other instance. Subscribe it to the event bus.
+func (rc *ResponseCache) HandleEvent(ctx context.Context, event events.Event) {
+	if rc == nil || event.Collection == "" {
+		return
+	}
+
+	collection := CollectionKey(event.Collection)
+	if schemaTables[event.Collection] {
+		collection = uuid.Nil
+	}
+	rc.HandleInvalidation(collection)
+	if rc.broadcast != nil {
+		rc.broadcast(collection)
+	}
+}
+
+// invalidateCollection drops the responses of a collection here and on every other
+// instance, for writes that publish no item events
+func (rc *ResponseCache) invalidateCollection(slug string) {
+	if rc == nil {
+		return
+	}
+
+	collection := CollectionKey(slug)
+	rc.HandleInvalidation(collection)
+	if rc.broadcast != nil {
+		rc.broadcast(collection)
+	}
+}
+
+// responseRecorder passes a response through while keeping a copy of its body
+type responseRecorder struct {
+	gin.ResponseWriter
+	body []byte
+}
+
+func (r *responseRecorder) Write(data []byte) (int, error) {
+	r.body = append(r.body, data...)
+	return r.ResponseWriter.Write(data)
+}
+
+func (r *responseRecorder) WriteString(data string) (int, error) {
+	r.body = append(r.body, data...)
+	return r.ResponseWriter.WriteString(data)
+}
+
+// responseCacheKey identifies a list response. Lists whose rows depend on the time
+// ($NOW rules) or on other collections (expanded relations) are not cached.
+func responseCacheKey(c *gin.Context, tableName string, userID uuid.UUID, allowedFields []string, rowFilter *rbac.RowFilter) (string, bool) {
+	if rowFilter.Volatile() || strings.Contains(c.Query("fields"), ".") {
+		return "", false
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	fields := append([]string(nil), allowedFields...)
+	sort.Strings(fields)
+	condition, args := rowFilter.SQL(1)
+	return strings.Join([]string{
+		tenantID.String(),
+		userID.String(),
+		tableName,
+		strings.Join(fields, ","),
+		condition,
+		fmt.Sprint(args),
+		fmt.Sprint(rbac.FieldTransformsFromContext(c.Request.Context(), tableName)),
+		c.Request.URL.Query().Encode(),
+	}, "\x00"), true
+}
+
+// cachedQuery answers a list request from the response cache, or runs query and caches
+// its response
+func (h *ItemsHandler) cachedQuery(c *gin.Context, tableName string, userID uuid.UUID, allowedFields []string, rowFilter *rbac.RowFilter, query func()) {
+	cache := defaultResponseCache
+	key, ok := responseCacheKey(c, tableName, userID, allowedFields, rowFilter)
+	if cache == nil || !ok {
+		query()
+		return
+	}
+
+	if response, ok := cache.get(key); ok {
+		c.Header("X-Cache", "HIT")
+		c.Data(http.StatusOK, response.contentType, response.body)
+		return
+	}
+
+	generation := cache.Generation()
+	recorder := &responseRecorder{ResponseWriter: c.Writer}
+	c.Writer = recorder
+	c.Header("X-Cache", "MISS")
+	query()
+	c.Writer = recorder.ResponseWriter
+
+	if c.Writer.Status() == http.StatusOK {
+		cache.set(generation, key, cachedResponse{
+			collection:  CollectionKey(tableName),
+			contentType: c.Writer.Header().Get("Content-Type"),
+			body:        recorder.body,
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Now()
+	cache := NewResponseCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	var announced []uuid.UUID
+	cache.Broadcast(func(collection uuid.UUID) { announced = append(announced, collection) })
+
+	fill := func() {
+		cache.set(cache.Generation(), "products", cachedResponse{collection: CollectionKey("products"), body: []byte("[]")})
+		cache.set(cache.Generation(), "orders", cachedResponse{collection: CollectionKey("orders"), body: []byte("[]")})
+	}
+	cached := func(key string) bool {
+		_, ok := cache.get(key)
+		return ok
+	}
+
+	fill()
+	assert.True(t, cached("products"))
+
+	// Writes drop the responses of their collection, here and on other instances
+	cache.HandleEvent(context.Background(), events.Event{Type: events.ItemCreate, Collection: "products"})
+	assert.False(t, cached("products"))
+	assert.True(t, cached("orders"))
+	assert.Equal(t, []uuid.UUID{CollectionKey("products")}, announced)
+
+	// Schema changes drop everything
+	fill()
+	cache.HandleEvent(context.Background(), events.Event{Type: events.ItemUpdate, Collection: "fields"})
+	assert.False(t, cached("orders"))
+	assert.Equal(t, uuid.Nil, announced[1])
+
+	// Invalidations of other instances are not announced again
+	fill()
+	cache.HandleInvalidation(CollectionKey("orders"))
+	assert.True(t, cached("products"))
+	assert.False(t, cached("orders"))
+	assert.Len(t, announced, 2)
+
+	// Responses computed across an invalidation are not stored
+	generation := cache.Generation()
+	cache.HandleInvalidation(uuid.Nil)
+	cache.set(generation, "products", cachedResponse{body: []byte("[]")})
+	assert.False(t, cached("products"))
+
+	// Large responses are not stored and entries expire after the TTL
+	cache.set(cache.Generation(), "large", cachedResponse{body: make([]byte, maxCachedResponseSize+1)})
+	assert.False(t, cached("large"))
+	fill()
+	now = now.Add(2 * time.Minute)
+	assert.False(t, cached("products"))
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	userID := uuid.New()
+	key := func(target string, allowedFields []string, rowFilter *rbac.RowFilter) (string, bool) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, target, nil)
+		return responseCacheKey(c, "products", userID, allowedFields, rowFilter)
+	}
+
+	base, ok := key("/items/products?limit=10&sort=name", []string{"name", "id"}, nil)
+	require.True(t, ok)
+
+	// The order of query parameters and fields does not matter
+	same, _ := key("/items/products?sort=name&limit=10", []string{"id", "name"}, nil)
+	assert.Equal(t, base, same)
+
+	// Other queries, permissions and rules do
+	other, _ := key("/items/products?limit=20&sort=name", []string{"id", "name"}, nil)
+	assert.NotEqual(t, base, other)
+	other, _ = key("/items/products?limit=10&sort=name", []string{"id"}, nil)
+	assert.NotEqual(t, base, other)
+	filter, err := rbac.CompileRowFilter([]byte(`{"status":{"_eq":"published"}}`), rbac.RuleVars{})
+	require.NoError(t, err)
+	other, _ = key("/items/products?limit=10&sort=name", []string{"id", "name"}, filter)
+	assert.NotEqual(t, base, other)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest(http.MethodGet, "/items/products?limit=10&sort=name", nil)
+	withFieldTransforms(c, "products", rbac.FieldTransforms{"name": rbac.TransformHash})
+	other, _ = responseCacheKey(c, "products", userID, []string{"id", "name"}, nil)
+	assert.NotEqual(t, base, other)
+
+	// Lists depending on the time or on other collections are not cached
+	volatile, err := rbac.CompileRowFilter([]byte(`{"publish_at":{"_lte":"$NOW"}}`), rbac.RuleVars{Now: time.Now()})
+	require.NoError(t, err)
+	_, ok = key("/items/products", nil, volatile)
+	assert.False(t, ok)
+	_, ok = key("/items/products?fields=*,customer.*", nil, nil)
+	assert.False(t, ok)
+}
+
+func TestItemsHandler_cachedQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	previous := defaultResponseCache
+	UseResponseCache(NewResponseCache(time.Minute))
+	defer UseResponseCache(previous)
+
+	h := &ItemsHandler{}
+	userID := uuid.New()
+	queries := 0
+	list := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/items/products", nil)
+		h.cachedQuery(c, "products", userID, []string{"id"}, nil, func() {
+			queries++
+			c.JSON(http.StatusOK, gin.H{"data": []gin.H{{"id": "1"}}})
+		})
+		return w
+	}
+
+	first := list()
+	assert.Equal(t, "MISS", first.Header().Get("X-Cache"))
+	second := list()
+	assert.Equal(t, "HIT", second.Header().Get("X-Cache"))
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+	assert.Equal(t, 1, queries)
+
+	defaultResponseCache.HandleEvent(context.Background(), events.Event{Collection: "products"})
+	assert.Equal(t, "MISS", list().Header().Get("X-Cache"))
+	assert.Equal(t, 2, queries)
+}
@@ -0,0 +1,387 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the RLS policy generator, which turns a tenant's permissions into
+// Postgres row-level security policies on the data tables of its collections, so clients
+// reading the database directly (reporting tools, SQL consoles) see the rows the API would
+// show them once they call set_user_context.
+//
+// RBAC Endpoints:
+// - GET /rbac/rls - The policies the tenant's permissions compile to, as SQL (dry run)
+//
+// Each data table gets a restrictive basin_tenant policy (data schema layout, whose tables
+// hold every tenant's rows), a basin_admin policy for admins and one policy per permission
+// on the collection, named basin_<action>_<role id>, which admits the rows of the
+// permission's row rule to users holding the role. They replace the tenant_isolation_policy
+// the data table trigger creates. Postgres does not apply policies to the table owner, the
+// role the API connects as, so the API keeps enforcing permissions in its own queries.
+//
+// With RLS_POLICIES_ENABLED the policies of every tenant are put in place at startup and
+// again whenever its roles, permissions or collections change.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// rlsPolicyPrefix starts the names of generated policies; other policies are left alone
+const rlsPolicyPrefix = "basin_"
+
+// legacyTenantPolicy is the policy of the data table trigger, replaced by basin_tenant
+const legacyTenantPolicy = "tenant_isolation_policy"
+
+// rlsTenantCondition limits rows to the tenant set by set_user_context
+const rlsTenantCondition = `tenant_id = NULLIF(current_setting('app.current_tenant_id', true), '')::uuid`
+
+// rlsCommands maps permission actions to the commands their policies apply to
+var rlsCommands = map[string]string{
+	"create": "INSERT",
+	"read":   "SELECT",
+	"update": "UPDATE",
+	"delete": "DELETE",
+}
+
+// rlsTables are the tables whose item events can change a tenant's policies
+var rlsTables = map[string]bool{
+	"permissions": true,
+	"roles":       true,
+	"collections": true,
+}
+
+// RLSPolicy is one generated policy. Using limits the rows a command sees; Check limits the
+// rows it writes (inserts only have a check, updates check against Using).
+type RLSPolicy struct {
+	Name        string `json:"name"`
+	Command     string `json:"command"`        // ALL, SELECT, INSERT, UPDATE or DELETE
+	Role        string `json:"role,omitempty"` // Role whose permission the policy enforces
+	Restrictive bool   `json:"restrictive,omitempty"`
+	Using       string `json:"using,omitempty"`
+	Check       string `json:"check,omitempty"`
+}
+
+// SQL returns the statement creating the policy on table
+func (p RLSPolicy) SQL(table DataTable) string {
+	statement := fmt.Sprintf(`CREATE POLICY "%s" ON %s`, p.Name, table)
+	if p.Restrictive {
+		statement += " AS RESTRICTIVE"
+	}
+	statement += " FOR " + p.Command
+	if p.Using != "" {
+		statement += " USING (" + p.Using + ")"
+	}
+	if p.Check != "" {
+		statement += " WITH CHECK (" + p.Check + ")"
+	}
+	return statement
+}
+
+// RLSTablePlan is the policies of one collection's data table and the statements that put
+// them in place
+type RLSTablePlan struct {
+	Collection string      `json:"collection"`
+	Table      string      `json:"table"`
+	Policies   []RLSPolicy `json:"policies"`
+	Statements []string    `json:"statements"`
+	Warnings   []string    `json:"warnings,omitempty"` // Permissions left out, such as ones with an invalid rule
+}
+
+// planRLSPolicies builds the policies of a collection's data table from the tenant's
+// permissions. roleNames names the tenant's roles; existing are the names of the policies
+// on the table now, of which the generated and legacy ones are dropped.
+func planRLSPolicies(collection string, table DataTable, permissions []sqlc.Permission, roleNames map[uuid.UUID]string, existing []string) RLSTablePlan {
+	plan := RLSTablePlan{Collection: collection, Table: table.String(), Policies: []RLSPolicy{}}
+
+	if table.Layout == LayoutDataSchema {
+		plan.Policies = append(plan.Policies, RLSPolicy{Name: "basin_tenant", Command: "ALL", Restrictive: true, Using: rlsTenantCondition, Check: rlsTenantCondition})
+	}
+	plan.Policies = append(plan.Policies, RLSPolicy{Name: "basin_admin", Command: "ALL", Using: "basin_is_admin()", Check: "basin_is_admin()"})
+
+	var granted []RLSPolicy
+	for _, permission := range permissions {
+		command, ok := rlsCommands[permission.Action]
+		if permission.TableName != collection || !ok || !permission.RoleID.Valid {
+			continue
+		}
+		roleID := permission.RoleID.UUID
+		role := roleNames[roleID]
+		if role == "" {
+			role = roleID.String()
+		}
+
+		// An invalid rule denies access in the API; leaving the policy out does the same
+		filter, err := rbac.CompilePolicyRule(rbac.PermissionRule(permission))
+		if err != nil {
+			plan.Warnings = append(plan.Warnings, fmt.Sprintf("%s/%s: invalid field filter: %v", role, permission.Action, err))
+			continue
+		}
+		condition := fmt.Sprintf("basin_has_role('%s')", roleID)
+		if filter != nil {
+			condition += " AND " + filter.PolicySQL()
+		}
+
+		policy := RLSPolicy{
+			Name:    rlsPolicyPrefix + permission.Action + "_" + strings.ReplaceAll(roleID.String(), "-", ""),
+			Command: command,
+			Role:    role,
+		}
+		if command == "INSERT" {
+			policy.Check = condition
+		} else {
+			policy.Using = condition
+		}
+		granted = append(granted, policy)
+	}
+	sort.Slice(granted, func(i, j int) bool { return granted[i].Name < granted[j].Name })
+	plan.Policies = append(plan.Policies, granted...)
+
+	plan.Statements = append(plan.Statements, fmt.Sprintf("ALTER TABLE %s ENABLE ROW LEVEL SECURITY", table))
+	for _, name := range existing {
+		if strings.HasPrefix(name, rlsPolicyPrefix) || name == legacyTenantPolicy {
+			plan.Statements = append(plan.Statements, fmt.Sprintf(`DROP POLICY IF EXISTS "%s" ON %s`, name, table))
+		}
+	}
+	for _, policy := range plan.Policies {
+		plan.Statements = append(plan.Statements, policy.SQL(table))
+	}
+	return plan
+}
+
+// RLSPolicyGenerator creates and updates the RLS policies of data tables from permissions
+type RLSPolicyGenerator struct {
+	db    *db.DB
+	utils *ItemsUtils
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]bool // Tenants whose policies are due
+	wake    chan struct{}
+}
+
+// NewRLSPolicyGenerator creates an RLSPolicyGenerator
+func NewRLSPolicyGenerator(db *db.DB) *RLSPolicyGenerator {
+	return &RLSPolicyGenerator{
+		db:      db,
+		utils:   NewItemsUtils(db),
+		pending: make(map[uuid.UUID]bool),
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// Plan returns the policies of the tenant's collections, or of the one collection named,
+// without applying them
+func (g *RLSPolicyGenerator) Plan(ctx context.Context, tenantID uuid.UUID, collection string) ([]RLSTablePlan, error) {
+	queries := g.db.Queries
+	tenant := uuid.NullUUID{UUID: tenantID, Valid: true}
+
+	collections, err := queries.GetCollectionsByTenant(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load collections: %w", err)
+	}
+	roles, err := queries.GetRolesByTenant(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+	roleNames := make(map[uuid.UUID]string, len(roles))
+	for _, role := range roles {
+		roleNames[role.ID] = role.Name
+	}
+	permissions, err := queries.GetPermissionsByTenant(ctx, tenant)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+
+	plans := []RLSTablePlan{}
+	found := false
+	for _, c := range collections {
+		if collection != "" && c.Slug != collection {
+			continue
+		}
+		found = true
+
+		table, err := g.utils.ResolveDataTable(ctx, tenantID, c.Slug)
+		if errors.Is(err, ErrDataTableNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		existing, err := g.tablePolicies(ctx, table)
+		if err != nil {
+			return nil, err
+		}
+		plans = append(plans, planRLSPolicies(c.Slug, table, permissions, roleNames, existing))
+	}
+	if collection != "" && !found {
+		return nil, notFoundError("Collection '%s' not found", collection)
+	}
+	return plans, nil
+}
+
+// tablePolicies returns the names of the policies on a table; databases without row-level
+// security have none
+func (g *RLSPolicyGenerator) tablePolicies(ctx context.Context, table DataTable) ([]string, error) {
+	if !g.db.Dialect().RowLevelSecurity() {
+		return nil, nil
+	}
+	rows, err := g.db.QueryContext(ctx, `SELECT policyname FROM pg_policies WHERE schemaname = $1 AND tablename = $2 ORDER BY policyname`, table.Schema, table.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load policies of %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("failed to load policies of %s: %w", table, err)
+		}
+		names = append(names, name)
+	}
+	return names, rows.Err()
+}
+
+// Apply puts the policies of every collection of a tenant in place. Each table is updated
+// in a transaction of its own, so a rule naming a missing column only fails its table; such
+// failures are logged, and the error is that of planning the policies.
+func (g *RLSPolicyGenerator) Apply(ctx context.Context, tenantID uuid.UUID) error {
+	plans, err := g.Plan(ctx, tenantID, "")
+	if err != nil {
+		return err
+	}
+
+	for _, plan := range plans {
+		for _, warning := range plan.Warnings {
+			slog.Warn("permission left out of RLS policies", "tenant_id", tenantID, "collection", plan.Collection, "warning", warning)
+		}
+		err := g.db.InTransaction(ctx, func(tx *db.Tx) error {
+			for _, statement := range plan.Statements {
+				if _, err := tx.ExecContext(ctx, statement); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			slog.Error("failed to apply RLS policies", "tenant_id", tenantID, "collection", plan.Collection, "error", err)
+		}
+	}
+	return nil
+}
+
+// Queue schedules the policies of a tenant to be updated by the worker. Register it with
+// rbac.OnPermissionsChanged.
+func (g *RLSPolicyGenerator) Queue(tenantID uuid.UUID) {
+	if tenantID == uuid.Nil {
+		return
+	}
+	g.mu.Lock()
+	g.pending[tenantID] = true
+	g.mu.Unlock()
+
+	select {
+	case g.wake <- struct{}{}:
+	default:
+	}
+}
+
+// HandleEvent queues the tenant when its roles, permissions or collections are written
+// through the API. Subscribe it to the event bus.
+func (g *RLSPolicyGenerator) HandleEvent(ctx context.Context, event events.Event) {
+	if rlsTables[event.Collection] {
+		g.Queue(event.TenantID)
+	}
+}
+
+// Start updates the policies of every tenant, then of queued tenants until ctx is
+// cancelled
+func (g *RLSPolicyGenerator) Start(ctx context.Context) {
+	tenants, err := g.db.Queries.GetAllTenants(ctx)
+	if err != nil {
+		slog.Error("failed to load tenants for RLS policies", "error", err)
+	}
+	for _, tenant := range tenants {
+		g.Queue(tenant.ID)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-g.wake:
+		}
+
+		g.mu.Lock()
+		due := g.pending
+		g.pending = make(map[uuid.UUID]bool)
+		g.mu.Unlock()
+
+		for tenantID := range due {
+			if err := g.Apply(ctx, tenantID); err != nil {
+				slog.Error("failed to plan RLS policies", "tenant_id", tenantID, "error", err)
+			}
+		}
+	}
+}
+
+// PreviewRLSPolicies handles GET /rbac/rls requests.
+//
+// Returns the RLS policies the permissions of the caller's tenant compile to, per data
+// table, with the SQL statements that put them in place. Nothing is applied.
+//
+// Query Parameters:
+//   - collection: Only preview the policies of this collection
+//
+// Response Format:
+//   - 200: {"data": [{"collection": "...", "table": "...", "policies": [...], "statements": [...]}], "meta": {"enabled": false}}
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on roles or permissions
+//   - 404: The collection does not exist
+//
+// @Summary      Preview RLS policies
+// @Tags         rbac
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  The Postgres row-level security policies generated from the tenant's permissions, as SQL. meta.enabled tells whether RLS_POLICIES_ENABLED applies them.
+// @Param        collection query string false "Collection slug"
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /rbac/rls [get]
+func (h *ItemsHandler) PreviewRLSPolicies(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if !h.checkMatrixPermissions(c, userID, "read") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID, err := h.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		respondError(c, err, "Failed to get user tenant")
+		return
+	}
+	plans, err := NewRLSPolicyGenerator(h.db).Plan(ctx, tenantID, c.Query("collection"))
+	if err != nil {
+		respondError(c, err, "Failed to generate policies")
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": plans, "meta": gin.H{"enabled": h.cfg.RLSPoliciesEnabled}})
+}
@@ -0,0 +1,76 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPlanRLSPolicies(t *testing.T) {
+	viewer := uuid.MustParse("11111111-2222-3333-4444-555555555555")
+	editor := uuid.New()
+	roleNames := map[uuid.UUID]string{viewer: "viewer", editor: "editor"}
+	permission := func(role uuid.UUID, table, action, scope, filter string) sqlc.Permission {
+		p := sqlc.Permission{RoleID: uuid.NullUUID{UUID: role, Valid: true}, TableName: table, Action: action, Scope: scope}
+		if filter != "" {
+			p.FieldFilter = pqtype.NullRawMessage{RawMessage: json.RawMessage(filter), Valid: true}
+		}
+		return p
+	}
+	permissions := []sqlc.Permission{
+		permission(viewer, "posts", "read", rbac.ScopeAll, `{"status": "published"}`),
+		permission(editor, "posts", "create", rbac.ScopeOwn, ""),
+		permission(editor, "posts", "update", rbac.ScopeOwn, ""),
+		permission(editor, "posts", "delete", rbac.ScopeAll, `{"status": {"_like": "x"}}`),
+		permission(editor, "posts", "purge", rbac.ScopeAll, ""),
+		permission(editor, "pages", "read", rbac.ScopeAll, ""),
+	}
+	table := DataTable{Schema: dataSchema, Name: "posts-data-1", Layout: LayoutDataSchema}
+
+	plan := planRLSPolicies("posts", table, permissions, roleNames, []string{"basin_read_old", "custom", legacyTenantPolicy})
+
+	names := make([]string, len(plan.Policies))
+	for i, policy := range plan.Policies {
+		names[i] = policy.Name
+	}
+	require.Len(t, names, 5, "no policies for other tables, unknown actions or invalid rules")
+	assert.Equal(t, []string{"basin_tenant", "basin_admin"}, names[:2])
+	assert.True(t, plan.Policies[0].Restrictive)
+
+	read := plan.Policies[3] // Sorted by name: create, read, update
+	assert.Equal(t, "basin_read_11111111222233334444555555555555", read.Name)
+	assert.Equal(t, "SELECT", read.Command)
+	assert.Equal(t, "viewer", read.Role)
+	assert.Equal(t, `basin_has_role('11111111-2222-3333-4444-555555555555') AND "status" = 'published'`, read.Using)
+
+	for _, policy := range plan.Policies {
+		switch policy.Command {
+		case "INSERT":
+			assert.Empty(t, policy.Using)
+			assert.NotContains(t, policy.Check, "created_by", "new rows have no owner yet")
+		case "UPDATE":
+			assert.Contains(t, policy.Using, `"created_by" = NULLIF(current_setting('app.current_user_id', true), '')::uuid`)
+		}
+	}
+	require.Len(t, plan.Warnings, 1)
+	assert.Contains(t, plan.Warnings[0], "editor/delete")
+
+	assert.Equal(t, `ALTER TABLE "data"."posts-data-1" ENABLE ROW LEVEL SECURITY`, plan.Statements[0])
+	assert.Equal(t, `DROP POLICY IF EXISTS "basin_read_old" ON "data"."posts-data-1"`, plan.Statements[1])
+	assert.Equal(t, `DROP POLICY IF EXISTS "tenant_isolation_policy" ON "data"."posts-data-1"`, plan.Statements[2])
+	assert.Equal(t, `CREATE POLICY "basin_tenant" ON "data"."posts-data-1" AS RESTRICTIVE FOR ALL USING (`+rlsTenantCondition+`) WITH CHECK (`+rlsTenantCondition+`)`, plan.Statements[3])
+	assert.Len(t, plan.Statements, 3+len(plan.Policies))
+
+	t.Run("Tenant Schema", func(t *testing.T) {
+		plan := planRLSPolicies("posts", DataTable{Schema: "acme", Name: "data_posts", Layout: LayoutTenantSchema}, nil, roleNames, nil)
+		require.Len(t, plan.Policies, 1, "the schema only holds the tenant's rows")
+		assert.Equal(t, "basin_admin", plan.Policies[0].Name)
+	})
+}
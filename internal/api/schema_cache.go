@@ -0,0 +1,304 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the SchemaCache, which keeps the schema metadata that nearly every
+// item request looks up in memory:
+// - collections by tenant and slug (CollectionsHandler.GetCollection, isUserCollection)
+// - the schema of each tenant (ItemsUtils.GetTenantSchema)
+// - the data table of each collection (ItemsUtils.ResolveDataTable)
+//
+// Entries are dropped per tenant when its collections or fields change: through item
+// events for changes made by this instance, through the cache bus for changes made by
+// other instances, and through notifications on the basin_schema channel (see
+// migrations/025_schema_notify.sql) for changes made by any instance, the CLI or plain
+// SQL. SCHEMA_CACHE_TTL bounds how long an entry is kept should a notification be missed.
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// schemaChannel is the channel the database announces schema changes on
+const schemaChannel = "basin_schema"
+
+// Tables whose changes alter a tenant's cached schema metadata
+var schemaTables = map[string]bool{
+	"collections": true,
+	"fields":      true,
+}
+
+// schemaEntry is the cached metadata of one tenant. A nil collection records that the
+// tenant has no collection with that slug.
+type schemaEntry struct {
+	schema      string // Empty until looked up
+	collections map[string]*Collection
+	tables      map[string]DataTable
+	expires     time.Time
+}
+
+// SchemaCache keeps schema metadata per tenant. Lookups record the cache's generation
+// before they query the database and only store their result if no invalidation has
+// happened since, so a result loaded just before a change never outlives it.
+//
+// A nil *SchemaCache is valid and caches nothing.
+type SchemaCache struct {
+	ttl       time.Duration
+	now       func() time.Time
+	broadcast func(tenantID uuid.UUID) // Announces invalidations to other instances; may be nil
+
+	mu          sync.RWMutex
+	tenants     map[uuid.UUID]*schemaEntry
+	generation  uint64
+	lastRefresh time.Time
+}
+
+// NewSchemaCache creates a cache whose entries live for ttl
+func NewSchemaCache(ttl time.Duration) *SchemaCache {
+	return &SchemaCache{
+		ttl:     ttl,
+		now:     time.Now,
+		tenants: make(map[uuid.UUID]*schemaEntry),
+	}
+}
+
+// defaultSchemaCache is used by every handler; nil disables caching
+var defaultSchemaCache *SchemaCache
+
+// UseSchemaCache installs the schema cache. Call it once at startup, before creating
+// handlers; passing nil disables caching.
+func UseSchemaCache(cache *SchemaCache) {
+	defaultSchemaCache = cache
+}
+
+// Generation returns the number of invalidations so far. Pass it to the set methods
+// along with the result of a lookup started afterwards.
+func (sc *SchemaCache) Generation() uint64 {
+	if sc == nil {
+		return 0
+	}
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.generation
+}
+
+// entry returns the live entry of a tenant; the caller holds the lock
+func (sc *SchemaCache) entry(tenantID uuid.UUID) *schemaEntry {
+	entry, ok := sc.tenants[tenantID]
+	if !ok || sc.now().After(entry.expires) {
+		return nil
+	}
+	return entry
+}
+
+// update runs fn on the entry of a tenant, creating it if needed, unless the cache was
+// invalidated after generation
+func (sc *SchemaCache) update(generation uint64, tenantID uuid.UUID, fn func(entry *schemaEntry)) {
+	if sc == nil {
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.generation != generation {
+		return
+	}
+	entry := sc.entry(tenantID)
+	if entry == nil {
+		entry = &schemaEntry{
+			collections: make(map[string]*Collection),
+			tables:      make(map[string]DataTable),
+			expires:     sc.now().Add(sc.ttl),
+		}
+		sc.tenants[tenantID] = entry
+	}
+	fn(entry)
+	sc.lastRefresh = sc.now()
+}
+
+// Collection returns a copy of the cached collection, or nil if the tenant has no
+// collection with that slug. ok is false when the collection is not cached.
+func (sc *SchemaCache) Collection(tenantID uuid.UUID, slug string) (collection *Collection, ok bool) {
+	if sc == nil {
+		return nil, false
+	}
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	entry := sc.entry(tenantID)
+	if entry == nil {
+		return nil, false
+	}
+	cached, ok := entry.collections[slug]
+	if !ok || cached == nil {
+		return nil, ok
+	}
+	copied := *cached
+	return &copied, true
+}
+
+// SetCollection caches a collection, or with nil that the tenant has none with that slug
+func (sc *SchemaCache) SetCollection(generation uint64, tenantID uuid.UUID, slug string, collection *Collection) {
+	if collection != nil {
+		copied := *collection
+		collection = &copied
+	}
+	sc.update(generation, tenantID, func(entry *schemaEntry) {
+		entry.collections[slug] = collection
+	})
+}
+
+// TenantSchema returns the cached schema of a tenant
+func (sc *SchemaCache) TenantSchema(tenantID uuid.UUID) (string, bool) {
+	if sc == nil {
+		return "", false
+	}
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	entry := sc.entry(tenantID)
+	if entry == nil || entry.schema == "" {
+		return "", false
+	}
+	return entry.schema, true
+}
+
+// SetTenantSchema caches the schema of a tenant
+func (sc *SchemaCache) SetTenantSchema(generation uint64, tenantID uuid.UUID, schema string) {
+	sc.update(generation, tenantID, func(entry *schemaEntry) {
+		entry.schema = schema
+	})
+}
+
+// DataTable returns the cached data table of a collection
+func (sc *SchemaCache) DataTable(tenantID uuid.UUID, slug string) (DataTable, bool) {
+	if sc == nil {
+		return DataTable{}, false
+	}
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	entry := sc.entry(tenantID)
+	if entry == nil {
+		return DataTable{}, false
+	}
+	table, ok := entry.tables[slug]
+	return table, ok
+}
+
+// SetDataTable caches the data table of a collection
+func (sc *SchemaCache) SetDataTable(generation uint64, tenantID uuid.UUID, slug string, table DataTable) {
+	sc.update(generation, tenantID, func(entry *schemaEntry) {
+		entry.tables[slug] = table
+	})
+}
+
+// InvalidateTenant drops the entries of one tenant
+func (sc *SchemaCache) InvalidateTenant(tenantID uuid.UUID) {
+	if sc == nil {
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	delete(sc.tenants, tenantID)
+}
+
+// Invalidate drops every entry
+func (sc *SchemaCache) Invalidate() {
+	if sc == nil {
+		return
+	}
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	sc.generation++
+	sc.tenants = make(map[uuid.UUID]*schemaEntry)
+}
+
+// LastRefresh returns when the cache last loaded metadata from the database, or the zero
+// time if it never has
+func (sc *SchemaCache) LastRefresh() time.Time {
+	if sc == nil {
+		return time.Time{}
+	}
+
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.lastRefresh
+}
+
+// Warm loads the schema and collections of every tenant
+func (sc *SchemaCache) Warm(ctx context.Context, queries *sqlc.Queries) error {
+	generation := sc.Generation()
+	tenants, err := queries.GetAllTenants(ctx)
+	if err != nil {
+		return err
+	}
+	collections, err := queries.GetCollections(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, tenant := range tenants {
+		sc.SetTenantSchema(generation, tenant.ID, tenant.Slug)
+	}
+	for _, collection := range collections {
+		if collection.TenantID.Valid {
+			sc.SetCollection(generation, collection.TenantID.UUID, collection.Slug, collectionFromRow(collection))
+		}
+	}
+	return nil
+}
+
+// Broadcast sets the function that announces invalidations to other instances. Call it
+// at startup, before the cache is used.
+func (sc *SchemaCache) Broadcast(fn func(tenantID uuid.UUID)) {
+	if sc == nil {
+		return
+	}
+	sc.broadcast = fn
+}
+
+// HandleEvent drops the entries of a tenant when its collections or fields are written
+// through the API, here and on every other instance. Subscribe it to the event bus.
+func (sc *SchemaCache) HandleEvent(ctx context.Context, event events.Event) {
+	if !schemaTables[event.Collection] {
+		return
+	}
+	sc.HandleInvalidation(event.TenantID)
+	if sc != nil && sc.broadcast != nil {
+		sc.broadcast(event.TenantID)
+	}
+}
+
+// Listen drops entries as the database announces schema changes, until ctx is done. When
+// the listening connection had to be re-established every entry is dropped, since
+// changes may have been missed.
+func (sc *SchemaCache) Listen(ctx context.Context, database *db.DB) error {
+	return database.Listen(ctx, schemaChannel, func(payload string, reconnected bool) {
+		if reconnected {
+			sc.Invalidate()
+			return
+		}
+		tenantID, _ := uuid.Parse(payload)
+		sc.HandleInvalidation(tenantID)
+	})
+}
+
+// HandleInvalidation drops the entries of a tenant, or every entry for changes that
+// concern no single tenant. Register it with the cache bus.
+func (sc *SchemaCache) HandleInvalidation(tenantID uuid.UUID) {
+	if tenantID == uuid.Nil {
+		sc.Invalidate()
+		return
+	}
+	sc.InvalidateTenant(tenantID)
+}
@@ -0,0 +1,127 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSchemaCache(t *testing.T) {
+	now := time.Now()
+	cache := NewSchemaCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	assert.True(t, cache.LastRefresh().IsZero())
+
+	tenantID, otherTenantID := uuid.New(), uuid.New()
+	products := &Collection{ID: uuid.New(), Name: "Products", TenantID: tenantID}
+
+	_, ok := cache.Collection(tenantID, "products")
+	assert.False(t, ok)
+
+	generation := cache.Generation()
+	cache.SetCollection(generation, tenantID, "products", products)
+	cache.SetCollection(generation, tenantID, "missing", nil)
+	cache.SetTenantSchema(generation, tenantID, "acme")
+	cache.SetDataTable(generation, tenantID, "products", DataTable{Schema: "acme", Name: "data_products", Layout: LayoutTenantSchema})
+	cache.SetTenantSchema(generation, otherTenantID, "globex")
+	assert.Equal(t, now, cache.LastRefresh())
+
+	collection, ok := cache.Collection(tenantID, "products")
+	assert.True(t, ok)
+	assert.Equal(t, products, collection)
+
+	// Callers get copies they may change
+	collection.Name = "Changed"
+	collection, _ = cache.Collection(tenantID, "products")
+	assert.Equal(t, "Products", collection.Name)
+
+	// Missing collections are cached as such
+	collection, ok = cache.Collection(tenantID, "missing")
+	assert.True(t, ok)
+	assert.Nil(t, collection)
+
+	schema, ok := cache.TenantSchema(tenantID)
+	assert.True(t, ok)
+	assert.Equal(t, "acme", schema)
+	table, ok := cache.DataTable(tenantID, "products")
+	assert.True(t, ok)
+	assert.Equal(t, `"acme"."data_products"`, table.String())
+
+	// Invalidating a tenant leaves other tenants alone
+	cache.InvalidateTenant(tenantID)
+	_, ok = cache.Collection(tenantID, "products")
+	assert.False(t, ok)
+	_, ok = cache.TenantSchema(otherTenantID)
+	assert.True(t, ok)
+
+	// Lookups started before an invalidation are not stored
+	cache.SetCollection(generation, tenantID, "products", products)
+	_, ok = cache.Collection(tenantID, "products")
+	assert.False(t, ok)
+
+	// Entries expire after the TTL
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.TenantSchema(otherTenantID)
+	assert.False(t, ok)
+}
+
+func TestSchemaCache_HandleEvent(t *testing.T) {
+	cache := NewSchemaCache(time.Minute)
+	tenantID, otherTenantID := uuid.New(), uuid.New()
+	fill := func() {
+		cache.SetTenantSchema(cache.Generation(), tenantID, "acme")
+		cache.SetTenantSchema(cache.Generation(), otherTenantID, "globex")
+	}
+	cached := func(tenantID uuid.UUID) bool {
+		_, ok := cache.TenantSchema(tenantID)
+		return ok
+	}
+
+	var announced []uuid.UUID
+	cache.Broadcast(func(tenantID uuid.UUID) { announced = append(announced, tenantID) })
+
+	// Item events leave the cache alone
+	fill()
+	cache.HandleEvent(context.Background(), events.Event{TenantID: tenantID, Collection: "products"})
+	assert.True(t, cached(tenantID))
+
+	// Field and collection changes drop their tenant, here and on other instances
+	cache.HandleEvent(context.Background(), events.Event{TenantID: tenantID, Collection: "fields"})
+	assert.False(t, cached(tenantID))
+	assert.True(t, cached(otherTenantID))
+	assert.Equal(t, []uuid.UUID{tenantID}, announced)
+
+	// Invalidations of other instances are not announced again
+	fill()
+	cache.HandleInvalidation(otherTenantID)
+	assert.True(t, cached(tenantID))
+	assert.False(t, cached(otherTenantID))
+	assert.Len(t, announced, 1)
+
+	// Changes that concern no single tenant drop everything
+	fill()
+	cache.HandleInvalidation(uuid.Nil)
+	assert.False(t, cached(tenantID))
+	assert.False(t, cached(otherTenantID))
+}
+
+func TestNilSchemaCache(t *testing.T) {
+	var cache *SchemaCache
+	tenantID := uuid.New()
+
+	cache.SetCollection(cache.Generation(), tenantID, "products", &Collection{})
+	_, ok := cache.Collection(tenantID, "products")
+	assert.False(t, ok)
+	_, ok = cache.TenantSchema(tenantID)
+	assert.False(t, ok)
+	_, ok = cache.DataTable(tenantID, "products")
+	assert.False(t, ok)
+	cache.InvalidateTenant(tenantID)
+	cache.Invalidate()
+	assert.True(t, cache.LastRefresh().IsZero())
+}
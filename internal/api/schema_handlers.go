@@ -9,10 +9,18 @@ import (
 	"crypto/sha256"
 	"database/sql"
 	"encoding/hex"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strings"
 	"time"
 
+	"go-rbac-api/internal/db"
 	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/rbac"
 
 	"github.com/google/uuid"
 	"github.com/sqlc-dev/pqtype"
@@ -25,6 +33,7 @@ import (
 // - Fields: Define the structure and validation rules for collection data
 // - Users: Manage user accounts with tenant isolation
 // - API Keys: Provide programmatic access with proper security
+// - Webhooks: Signed HTTP callbacks for item events
 //
 // All operations respect tenant boundaries and RBAC permissions. Schema changes
 // automatically propagate to the underlying database structure, creating or modifying
@@ -69,6 +78,9 @@ func (s *SchemaHandlers) CreateCollection(ctx context.Context, userID uuid.UUID,
 	if err != nil {
 		return nil, err
 	}
+	if err := s.handler.quota.CheckCollections(ctx, userTenantID); err != nil {
+		return nil, err
+	}
 
 	// Generate ID if not provided
 	collectionID := uuid.New()
@@ -78,29 +90,105 @@ func (s *SchemaHandlers) CreateCollection(ctx context.Context, userID uuid.UUID,
 		}
 	}
 
-	// Create collection using sqlc
-	collection, err := s.handler.db.Queries.CreateCollection(ctx, sqlc.CreateCollectionParams{
-		ID:          collectionID,
-		Name:        data["name"].(string),
-		DisplayName: sql.NullString{String: GetStringFromMap(data, "display_name"), Valid: true},
-		Description: sql.NullString{String: GetStringFromMap(data, "description"), Valid: true},
-		Icon:        sql.NullString{String: GetStringFromMap(data, "icon"), Valid: true},
-		IsSystem:    sql.NullBool{Bool: GetBoolFromMap(data, "is_system"), Valid: true},
-		TenantID:    uuid.NullUUID{UUID: userTenantID, Valid: true},
-		CreatedBy:   uuid.NullUUID{UUID: userID, Valid: true},
+	// Default the slug to the collection name
+	slug := GetStringFromMap(data, "slug")
+	if slug == "" {
+		slug = GetStringFromMap(data, "name")
+	}
+	if err := s.checkCollectionSlug(ctx, userTenantID, slug); err != nil {
+		return nil, err
+	}
+
+	// Timestamps and user stamps are kept unless turned off
+	timestamps, userStamps := true, true
+	if value, ok := data["timestamps"].(bool); ok {
+		timestamps = value
+	}
+	if value, ok := data["user_stamps"].(bool); ok {
+		userStamps = value
+	}
+	if !userStamps {
+		if err := s.checkNoOwnScope(ctx, userTenantID, slug); err != nil {
+			return nil, err
+		}
+	}
+
+	// The collection, its data table columns and its permissions are created together
+	var collection sqlc.Collection
+	err = s.handler.db.InTransaction(ctx, func(tx *db.Tx) error {
+		utils := s.utils.withTx(tx)
+
+		// Create collection using sqlc
+		collection, err = tx.CreateCollection(ctx, sqlc.CreateCollectionParams{
+			ID:          collectionID,
+			Name:        data["name"].(string),
+			Slug:        slug,
+			DisplayName: sql.NullString{String: GetStringFromMap(data, "display_name"), Valid: true},
+			Description: sql.NullString{String: GetStringFromMap(data, "description"), Valid: true},
+			Icon:        sql.NullString{String: GetStringFromMap(data, "icon"), Valid: true},
+			IsSystem:    sql.NullBool{Bool: GetBoolFromMap(data, "is_system"), Valid: true},
+			TenantID:    uuid.NullUUID{UUID: userTenantID, Valid: true},
+			CreatedBy:   uuid.NullUUID{UUID: userID, Valid: true},
+			SoftDelete:  GetBoolFromMap(data, "soft_delete"),
+			Workflow:    GetBoolFromMap(data, "workflow"),
+			Timestamps:  timestamps,
+			UserStamps:  userStamps,
+			Sortable:    GetBoolFromMap(data, "sortable"),
+		})
+		if err != nil {
+			return err
+		}
+		if err := utils.CreateDataTable(ctx, &collection); err != nil {
+			return err
+		}
+
+		// The data table starts with every automatic column; drop the ones turned off
+		if err := utils.applyCollectionFlags(ctx, userTenantID, collection); err != nil {
+			return err
+		}
+
+		// Admins, and the roles of the preset, can use the collection right away
+		if err := utils.seedCollectionPermissions(ctx, userTenantID, collection.Slug, s.handler.cfg.CollectionPermissionPreset); err != nil {
+			return err
+		}
+
+		// Soft-delete collections mark deleted items with deleted_at instead of removing them
+		if collection.SoftDelete {
+			if err := utils.EnableSoftDelete(ctx, userTenantID, collection.Slug); err != nil {
+				return err
+			}
+		}
+
+		// Workflow collections move items from draft to published (see content_workflow.go)
+		if collection.Workflow {
+			if err := utils.EnableWorkflow(ctx, userTenantID, collection); err != nil {
+				return err
+			}
+			if err := utils.applyWorkflowPermissions(ctx, userTenantID, collection.Slug); err != nil {
+				return err
+			}
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
+	rbac.PermissionsChanged(userTenantID)
 
 	// Convert to map
 	result := map[string]interface{}{
 		"id":           collection.ID.String(),
 		"name":         collection.Name,
+		"slug":         collection.Slug,
 		"display_name": collection.DisplayName.String,
 		"description":  collection.Description.String,
 		"icon":         collection.Icon.String,
 		"is_system":    collection.IsSystem.Bool,
+		"soft_delete":  collection.SoftDelete,
+		"workflow":     collection.Workflow,
+		"timestamps":   collection.Timestamps,
+		"user_stamps":  collection.UserStamps,
+		"sortable":     collection.Sortable,
 		"tenant_id":    collection.TenantID.UUID.String(),
 		"created_by":   collection.CreatedBy.UUID.String(),
 		"created_at":   collection.CreatedAt.Time,
@@ -110,12 +198,28 @@ func (s *SchemaHandlers) CreateCollection(ctx context.Context, userID uuid.UUID,
 	return result, nil
 }
 
+// checkNoOwnScope refuses a collection without user stamps while own-scoped permissions
+// on its slug remain, such as those of a deleted collection of the same slug: they match
+// rows by created_by
+func (s *SchemaHandlers) checkNoOwnScope(ctx context.Context, tenantID uuid.UUID, slug string) error {
+	permissions, err := s.handler.db.Queries.GetPermissionsByTenant(ctx, uuid.NullUUID{UUID: tenantID, Valid: true})
+	if err != nil {
+		return fmt.Errorf("failed to fetch permissions: %w", err)
+	}
+	for _, permission := range permissions {
+		if permission.TableName == slug && permission.Scope == rbac.ScopeOwn {
+			return validationError("user_stamps cannot be turned off while permissions on '%s' have scope own", slug)
+		}
+	}
+	return nil
+}
+
 // UpdateCollection updates an existing collection
 func (s *SchemaHandlers) UpdateCollection(ctx context.Context, userID uuid.UUID, itemID string, data map[string]interface{}) (map[string]interface{}, error) {
 	// Parse item ID
 	collectionID, err := uuid.Parse(itemID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid collection ID: %w", err)
+		return nil, validationError("invalid collection ID: %w", err)
 	}
 
 	// Get tenant ID for filtering
@@ -127,12 +231,12 @@ func (s *SchemaHandlers) UpdateCollection(ctx context.Context, userID uuid.UUID,
 	// Get existing collection
 	existingCollection, err := s.handler.db.Queries.GetCollection(ctx, collectionID)
 	if err != nil {
-		return nil, fmt.Errorf("collection not found: %w", err)
+		return nil, notFoundError("collection not found: %w", err)
 	}
 
 	// Check tenant access
 	if existingCollection.TenantID.Valid && existingCollection.TenantID.UUID != userTenantID {
-		return nil, fmt.Errorf("unauthorized: collection not accessible")
+		return nil, forbiddenError("unauthorized: collection not accessible")
 	}
 
 	// Extract fields with defaults
@@ -151,13 +255,77 @@ func (s *SchemaHandlers) UpdateCollection(ctx context.Context, userID uuid.UUID,
 		icon = sql.NullString{String: iconVal, Valid: true}
 	}
 
-	// Update collection using sqlc
-	updatedCollection, err := s.handler.db.Queries.UpdateCollection(ctx, sqlc.UpdateCollectionParams{
-		ID:          collectionID,
-		DisplayName: displayName,
-		Description: description,
-		Icon:        icon,
-		UpdatedBy:   uuid.NullUUID{UUID: userID, Valid: true},
+	softDelete := existingCollection.SoftDelete
+	if softDeleteVal, ok := data["soft_delete"].(bool); ok {
+		softDelete = softDeleteVal
+	}
+
+	workflow := existingCollection.Workflow
+	if workflowVal, ok := data["workflow"].(bool); ok {
+		workflow = workflowVal
+	}
+	enablingWorkflow := workflow && !existingCollection.Workflow
+
+	// Dropping the automatic columns would lose their values, adding them would leave them
+	// empty for existing items
+	if value, ok := data["timestamps"].(bool); ok && value != existingCollection.Timestamps {
+		return nil, validationError("timestamps can only be set when the collection is created")
+	}
+	if value, ok := data["user_stamps"].(bool); ok && value != existingCollection.UserStamps {
+		return nil, validationError("user_stamps can only be set when the collection is created")
+	}
+
+	sortable := existingCollection.Sortable
+	if sortableVal, ok := data["sortable"].(bool); ok {
+		sortable = sortableVal
+	}
+
+	var updatedCollection sqlc.Collection
+	err = s.handler.db.InTransaction(ctx, func(tx *db.Tx) error {
+		utils := s.utils.withTx(tx)
+
+		// Add the deleted_at column before any item can be soft-deleted. Turning soft delete
+		// off keeps the column, but trashed items then show up in reads again until purged.
+		if softDelete && !existingCollection.SoftDelete {
+			if err := utils.EnableSoftDelete(ctx, userTenantID, existingCollection.Slug); err != nil {
+				return err
+			}
+		}
+
+		// Likewise the workflow columns, which stay when the workflow is turned off again
+		if enablingWorkflow {
+			if err := utils.EnableWorkflow(ctx, userTenantID, existingCollection); err != nil {
+				return err
+			}
+		}
+
+		// And the sort column, numbering the existing items
+		if sortable && !existingCollection.Sortable {
+			if err := utils.EnableSorting(ctx, userTenantID, existingCollection); err != nil {
+				return err
+			}
+		}
+
+		// Update collection using sqlc
+		updatedCollection, err = tx.UpdateCollection(ctx, sqlc.UpdateCollectionParams{
+			ID:          collectionID,
+			DisplayName: displayName,
+			Description: description,
+			Icon:        icon,
+			UpdatedBy:   uuid.NullUUID{UUID: userID, Valid: true},
+			SoftDelete:  softDelete,
+			Workflow:    workflow,
+			Timestamps:  existingCollection.Timestamps,
+			UserStamps:  existingCollection.UserStamps,
+			Sortable:    sortable,
+		})
+		if err != nil {
+			return err
+		}
+		if enablingWorkflow {
+			return utils.applyWorkflowPermissions(ctx, userTenantID, updatedCollection.Slug)
+		}
+		return nil
 	})
 	if err != nil {
 		return nil, err
@@ -170,6 +338,11 @@ func (s *SchemaHandlers) UpdateCollection(ctx context.Context, userID uuid.UUID,
 		"display_name": updatedCollection.DisplayName.String,
 		"description":  updatedCollection.Description.String,
 		"icon":         updatedCollection.Icon.String,
+		"soft_delete":  updatedCollection.SoftDelete,
+		"workflow":     updatedCollection.Workflow,
+		"timestamps":   updatedCollection.Timestamps,
+		"user_stamps":  updatedCollection.UserStamps,
+		"sortable":     updatedCollection.Sortable,
 		"tenant_id":    nil,
 		"created_by":   nil,
 		"updated_by":   nil,
@@ -195,7 +368,7 @@ func (s *SchemaHandlers) DeleteCollection(ctx context.Context, userID uuid.UUID,
 	// Parse item ID
 	collectionID, err := uuid.Parse(itemID)
 	if err != nil {
-		return fmt.Errorf("invalid collection ID: %w", err)
+		return validationError("invalid collection ID: %w", err)
 	}
 
 	// Get tenant ID for filtering
@@ -207,12 +380,12 @@ func (s *SchemaHandlers) DeleteCollection(ctx context.Context, userID uuid.UUID,
 	// Get existing collection to check access
 	existingCollection, err := s.handler.db.Queries.GetCollection(ctx, collectionID)
 	if err != nil {
-		return fmt.Errorf("collection not found: %w", err)
+		return notFoundError("collection not found: %w", err)
 	}
 
 	// Check tenant access
 	if existingCollection.TenantID.Valid && existingCollection.TenantID.UUID != userTenantID {
-		return fmt.Errorf("unauthorized: collection not accessible")
+		return forbiddenError("unauthorized: collection not accessible")
 	}
 
 	// Delete collection using sqlc (this will trigger the database trigger to drop the data table)
@@ -240,48 +413,114 @@ func (s *SchemaHandlers) CreateField(ctx context.Context, userID uuid.UUID, data
 	// Parse collection_id
 	collectionID, err := uuid.Parse(data["collection_id"].(string))
 	if err != nil {
-		return nil, fmt.Errorf("invalid collection_id")
+		return nil, validationError("invalid collection_id")
 	}
 
 	// Get collection info to check if it's a system collection
 	collection, err := s.handler.db.Queries.GetCollection(ctx, collectionID)
 	if err != nil {
-		return nil, fmt.Errorf("collection not found: %w", err)
+		return nil, notFoundError("collection not found: %w", err)
 	}
 
 	// Check tenant access
 	if collection.TenantID.Valid && collection.TenantID.UUID != userTenantID {
-		return nil, fmt.Errorf("unauthorized: collection not accessible")
-	}
-
-	// Create field using sqlc
-	field, err := s.handler.db.Queries.CreateField(ctx, sqlc.CreateFieldParams{
-		ID:              fieldID,
-		CollectionID:    uuid.NullUUID{UUID: collectionID, Valid: true},
-		Name:            data["name"].(string),
-		DisplayName:     sql.NullString{String: GetStringFromMap(data, "display_name"), Valid: true},
-		Type:            data["type"].(string),
-		IsPrimary:       sql.NullBool{Bool: GetBoolFromMap(data, "is_primary"), Valid: true},
-		IsRequired:      sql.NullBool{Bool: GetBoolFromMap(data, "is_required"), Valid: true},
-		IsUnique:        sql.NullBool{Bool: GetBoolFromMap(data, "is_unique"), Valid: true},
-		DefaultValue:    sql.NullString{String: GetStringFromMap(data, "default_value"), Valid: true},
-		ValidationRules: pqtype.NullRawMessage{},
-		RelationConfig:  pqtype.NullRawMessage{},
-		SortOrder:       sql.NullInt32{Int32: int32(GetIntFromMap(data, "sort_order")), Valid: true},
-		TenantID:        uuid.NullUUID{UUID: userTenantID, Valid: true},
-	})
-	if err != nil {
+		return nil, forbiddenError("unauthorized: collection not accessible")
+	}
+
+	if name := GetStringFromMap(data, "name"); !validFieldName(name) {
+		return nil, fmt.Errorf("invalid field name '%s'", name)
+	} else if collection.Workflow && isWorkflowColumn(name) {
+		return nil, fmt.Errorf("field name '%s' is used by the content workflow", name)
+	}
+
+	// Relation fields must describe what they point at
+	relationConfig := GetJSONFromMap(data, "relation_config")
+	var relation RelationConfig
+	if GetStringFromMap(data, "type") == "relation" {
+		options, _ := data["relation_config"].(map[string]interface{})
+		if relation, err = parseRelationConfig(options); err != nil {
+			return nil, err
+		}
+		if err := checkRelationRule(relation, GetBoolFromMap(data, "is_required")); err != nil {
+			return nil, err
+		}
+	}
+
+	validationRules := GetJSONFromMap(data, "validation_rules")
+	if err := checkValidationRules(validationRules); err != nil {
+		return nil, err
+	}
+
+	// Computed fields describe the expression their value comes from
+	var computedConfig pqtype.NullRawMessage
+	if GetStringFromMap(data, "type") == "computed" {
+		options, _ := data["computed_config"].(map[string]interface{})
+		computed, err := parseComputedConfig(options)
+		if err != nil {
+			return nil, err
+		}
+		computedConfig = computed.raw()
+	}
+
+	// Encrypted fields hold values the database cannot compare
+	isEncrypted := GetBoolFromMap(data, "is_encrypted")
+	if isEncrypted {
+		if err := checkEncryptedField(GetStringFromMap(data, "type"), GetBoolFromMap(data, "is_unique"), GetBoolFromMap(data, "is_indexed")); err != nil {
+			return nil, err
+		}
+	}
+
+	pii := GetStringFromMap(data, "pii")
+	if err := checkPIIField(pii, GetStringFromMap(data, "type"), GetBoolFromMap(data, "is_required")); err != nil {
 		return nil, err
 	}
 
-	// If this is not a system collection, update the data table structure
-	if !collection.IsSystem.Bool {
-		err = s.utils.AddColumnToDataTable(ctx, userTenantID, collection.Name, field)
+	// The field record and its column are created together
+	var field sqlc.Field
+	err = s.handler.db.InTransaction(ctx, func(tx *db.Tx) error {
+		utils := s.utils.withTx(tx)
+
+		// Create field using sqlc
+		field, err = tx.CreateField(ctx, sqlc.CreateFieldParams{
+			ID:              fieldID,
+			CollectionID:    uuid.NullUUID{UUID: collectionID, Valid: true},
+			Name:            data["name"].(string),
+			DisplayName:     sql.NullString{String: GetStringFromMap(data, "display_name"), Valid: true},
+			Type:            data["type"].(string),
+			IsPrimary:       sql.NullBool{Bool: GetBoolFromMap(data, "is_primary"), Valid: true},
+			IsRequired:      sql.NullBool{Bool: GetBoolFromMap(data, "is_required"), Valid: true},
+			IsUnique:        sql.NullBool{Bool: GetBoolFromMap(data, "is_unique"), Valid: true},
+			DefaultValue:    sql.NullString{String: GetStringFromMap(data, "default_value"), Valid: true},
+			ValidationRules: validationRules,
+			RelationConfig:  relationConfig,
+			SortOrder:       sql.NullInt32{Int32: int32(GetIntFromMap(data, "sort_order")), Valid: true},
+			TenantID:        uuid.NullUUID{UUID: userTenantID, Valid: true},
+			IsIndexed:       GetBoolFromMap(data, "is_indexed"),
+			ComputedConfig:  computedConfig,
+			IsEncrypted:     isEncrypted,
+			Pii:             sql.NullString{String: pii, Valid: pii != ""},
+		})
 		if err != nil {
-			// If we fail to add the column, we should delete the field record to maintain consistency
-			s.handler.db.Queries.DeleteField(ctx, fieldID)
-			return nil, fmt.Errorf("failed to add column to data table: %w", err)
+			return err
 		}
+
+		// If this is not a system collection, update the data table structure.
+		// One-to-many relations are aliases resolved from the related collection and have no column;
+		// many-to-many relations keep their links in a junction table instead.
+		switch {
+		case collection.IsSystem.Bool || relation.Type == RelationOneToMany:
+			return nil
+		case relation.Type == RelationManyToMany:
+			return utils.CreateJunctionTable(ctx, userTenantID, relation)
+		default:
+			if err := utils.AddColumnToDataTable(ctx, userTenantID, collection.Slug, field); err != nil {
+				return err
+			}
+			return utils.AddRelationRule(ctx, userTenantID, collection.Slug, field.ID, field.Name, relation)
+		}
+	})
+	if err != nil {
+		return nil, err
 	}
 
 	// Convert to map
@@ -294,22 +533,30 @@ func (s *SchemaHandlers) CreateField(ctx context.Context, userID uuid.UUID, data
 		"is_primary":    field.IsPrimary.Bool,
 		"is_required":   field.IsRequired.Bool,
 		"is_unique":     field.IsUnique.Bool,
+		"is_indexed":    field.IsIndexed,
+		"is_encrypted":  field.IsEncrypted,
+		"pii":           field.Pii.String,
 		"default_value": field.DefaultValue.String,
 		"sort_order":    field.SortOrder.Int32,
 		"tenant_id":     field.TenantID.UUID.String(),
 		"created_at":    field.CreatedAt.Time,
 		"updated_at":    field.UpdatedAt.Time,
 	}
+	s.handler.addFieldIndexStatus(ctx, "fields", userID, []map[string]interface{}{result})
+	if isSearchableField(field.Type) {
+		s.refreshSearchIndex(ctx, userTenantID, field.CollectionID)
+	}
 
 	return result, nil
 }
 
-// UpdateField updates an existing field
+// UpdateField updates an existing field. Renames and type changes are applied to the
+// field's column in the same transaction; see field_migrations.go.
 func (s *SchemaHandlers) UpdateField(ctx context.Context, userID uuid.UUID, itemID string, data map[string]interface{}) (map[string]interface{}, error) {
 	// Parse item ID
 	fieldID, err := uuid.Parse(itemID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid field ID: %w", err)
+		return nil, validationError("invalid field ID: %w", err)
 	}
 
 	// Get tenant ID for filtering
@@ -321,15 +568,20 @@ func (s *SchemaHandlers) UpdateField(ctx context.Context, userID uuid.UUID, item
 	// Get existing field
 	existingField, err := s.handler.db.Queries.GetField(ctx, fieldID)
 	if err != nil {
-		return nil, fmt.Errorf("field not found: %w", err)
+		return nil, notFoundError("field not found: %w", err)
 	}
 
 	// Check tenant access
 	if existingField.TenantID.Valid && existingField.TenantID.UUID != userTenantID {
-		return nil, fmt.Errorf("unauthorized: field not accessible")
+		return nil, forbiddenError("unauthorized: field not accessible")
 	}
 
 	// Extract fields with defaults
+	name := existingField.Name
+	if nameVal, ok := data["name"].(string); ok && nameVal != "" {
+		name = nameVal
+	}
+
 	displayName := existingField.DisplayName
 	if displayVal, ok := data["display_name"].(string); ok {
 		displayName = sql.NullString{String: displayVal, Valid: true}
@@ -355,6 +607,31 @@ func (s *SchemaHandlers) UpdateField(ctx context.Context, userID uuid.UUID, item
 		isUnique = sql.NullBool{Bool: uniqueVal, Valid: true}
 	}
 
+	isIndexed := existingField.IsIndexed
+	if indexedVal, ok := data["is_indexed"].(bool); ok {
+		isIndexed = indexedVal
+	}
+
+	// Values already written stay as they are, so encryption cannot be turned on or off
+	if encryptedVal, ok := data["is_encrypted"].(bool); ok && encryptedVal != existingField.IsEncrypted {
+		return nil, validationError("is_encrypted cannot be changed once the field exists")
+	}
+	if existingField.IsEncrypted && (fieldType != existingField.Type || isUnique.Bool != existingField.IsUnique.Bool || isIndexed != existingField.IsIndexed) {
+		if err := checkEncryptedField(fieldType, isUnique.Bool, isIndexed); err != nil {
+			return nil, err
+		}
+	}
+
+	// An empty or null pii removes the tag
+	pii := existingField.Pii
+	if piiVal, ok := data["pii"]; ok {
+		kind, _ := piiVal.(string)
+		pii = sql.NullString{String: kind, Valid: kind != ""}
+	}
+	if err := checkPIIField(pii.String, fieldType, isRequired.Bool); err != nil {
+		return nil, err
+	}
+
 	defaultValue := existingField.DefaultValue
 	if defVal, ok := data["default_value"].(string); ok {
 		defaultValue = sql.NullString{String: defVal, Valid: true}
@@ -365,18 +642,141 @@ func (s *SchemaHandlers) UpdateField(ctx context.Context, userID uuid.UUID, item
 		sortOrder = sql.NullInt32{Int32: int32(sortInt), Valid: true}
 	}
 
-	// Update field using sqlc
-	updatedField, err := s.handler.db.Queries.UpdateField(ctx, sqlc.UpdateFieldParams{
-		ID:              fieldID,
-		DisplayName:     displayName,
-		Type:            fieldType,
-		IsPrimary:       isPrimary,
-		IsRequired:      isRequired,
-		IsUnique:        isUnique,
-		DefaultValue:    defaultValue,
-		ValidationRules: existingField.ValidationRules,
-		RelationConfig:  existingField.RelationConfig,
-		SortOrder:       sortOrder,
+	validationRules := existingField.ValidationRules
+	if _, ok := data["validation_rules"]; ok {
+		validationRules = GetJSONFromMap(data, "validation_rules")
+		if err := checkValidationRules(validationRules); err != nil {
+			return nil, err
+		}
+	}
+
+	relationConfig := existingField.RelationConfig
+	if options, ok := data["relation_config"].(map[string]interface{}); ok {
+		if fieldType == "relation" {
+			if _, err := parseRelationConfig(options); err != nil {
+				return nil, err
+			}
+		}
+		relationConfig = GetJSONFromMap(data, "relation_config")
+	}
+
+	computedConfig := existingField.ComputedConfig
+	recompute := false
+	if options, ok := data["computed_config"].(map[string]interface{}); ok && fieldType == "computed" {
+		computed, err := parseComputedConfig(options)
+		if err != nil {
+			return nil, err
+		}
+		previous, _ := decodeComputedConfig(existingField.ComputedConfig)
+		computedConfig, recompute = computed.raw(), computed != previous
+	}
+
+	// On-delete rules of many-to-one relations live in a foreign key (see relation_rules.go)
+	fromRule, toRule := relationRule(existingField.Type, existingField.RelationConfig), relationRule(fieldType, relationConfig)
+	if err := checkRelationRule(toRule, isRequired.Bool); err != nil {
+		return nil, err
+	}
+
+	// Renames and type changes carry the column of the data table along
+	migration, err := planFieldMigration(existingField, name, fieldType, relationConfig, defaultValue.String)
+	if err != nil {
+		return nil, err
+	}
+	if recompute && existingField.Type == "computed" {
+		// A new expression replaces the generated column
+		changed := existingField
+		changed.ComputedConfig = computedConfig
+		if migration.recompute, err = s.utils.ComputedColumnDefinition(ctx, changed); err != nil {
+			return nil, err
+		}
+	}
+	if migration.destructive != "" && !GetBoolFromMap(data, "confirm") {
+		return nil, &DestructiveChangeError{Field: existingField.Name, Reason: migration.destructive}
+	}
+	if name != existingField.Name {
+		siblings, err := s.handler.db.Queries.GetFieldsByCollection(ctx, existingField.CollectionID)
+		if err != nil {
+			return nil, err
+		}
+		for _, sibling := range siblings {
+			if sibling.Name == name {
+				return nil, fmt.Errorf("field '%s' already exists", name)
+			}
+		}
+		if dependents := computedDependents(siblings, existingField.Name); len(dependents) > 0 {
+			return nil, fmt.Errorf("field '%s' is used by computed fields %s", existingField.Name, strings.Join(dependents, ", "))
+		}
+		if isWorkflowColumn(name) {
+			collection, err := s.handler.db.Queries.GetCollection(ctx, existingField.CollectionID.UUID)
+			if err == nil && collection.Workflow {
+				return nil, fmt.Errorf("field name '%s' is used by the content workflow", name)
+			}
+		}
+	}
+
+	// Columns of alias fields and system collections do not exist to migrate or index
+	var statements []string
+	indexChanged := isUnique.Bool != existingField.IsUnique.Bool || isIndexed != existingField.IsIndexed
+	ruleChanged := fromRule != toRule
+	if (migration.changesColumn() || indexChanged || ruleChanged) && !isAliasField(fieldType, relationConfig) {
+		collection, err := s.handler.db.Queries.GetCollection(ctx, existingField.CollectionID.UUID)
+		if err != nil {
+			return nil, notFoundError("collection not found: %w", err)
+		}
+		if !collection.IsSystem.Bool {
+			table, err := s.utils.ResolveDataTable(ctx, userTenantID, collection.Slug)
+			if err != nil {
+				return nil, err
+			}
+			dataTable := table.String()
+			// A replaced generated column loses its constraint and index
+			recreated := migration.columnType != "" || migration.recompute != ""
+			indexes := planFieldIndexes(table.Schema, dataTable, fieldID, name, fieldType,
+				existingField.IsUnique.Bool && migration.recompute == "", isUnique.Bool, existingField.IsIndexed, isIndexed, recreated)
+			rules, err := s.utils.relationRuleStatements(ctx, userTenantID, dataTable, fieldID, name, fromRule, toRule, migration.columnType != "")
+			if err != nil {
+				return nil, err
+			}
+			statements = append(append(indexes.drop, rules.drop...), migration.statements(dataTable)...)
+			statements = append(append(statements, indexes.create...), rules.create...)
+			// The search index is rebuilt once the column has changed
+			if isSearchableField(existingField.Type) && migration.changesColumn() {
+				statements = append([]string{dropSearchIndexStatement(table.Schema, collection.ID)}, statements...)
+			}
+		}
+	}
+
+	var updatedField sqlc.Field
+	err = s.handler.db.InTransaction(ctx, func(tx *db.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to migrate column of field '%s': %w", existingField.Name, err)
+			}
+		}
+		if name != existingField.Name {
+			if err := tx.RenameField(ctx, sqlc.RenameFieldParams{ID: fieldID, Name: name}); err != nil {
+				return err
+			}
+		}
+
+		// Update field using sqlc
+		updatedField, err = tx.UpdateField(ctx, sqlc.UpdateFieldParams{
+			ID:              fieldID,
+			DisplayName:     displayName,
+			Type:            fieldType,
+			IsPrimary:       isPrimary,
+			IsRequired:      isRequired,
+			IsUnique:        isUnique,
+			DefaultValue:    defaultValue,
+			ValidationRules: validationRules,
+			RelationConfig:  relationConfig,
+			SortOrder:       sortOrder,
+			IsIndexed:       isIndexed,
+			ComputedConfig:  computedConfig,
+			IsEncrypted:     existingField.IsEncrypted,
+			Pii:             pii,
+		})
+		return err
 	})
 	if err != nil {
 		return nil, err
@@ -392,6 +792,9 @@ func (s *SchemaHandlers) UpdateField(ctx context.Context, userID uuid.UUID, item
 		"is_primary":    updatedField.IsPrimary.Bool,
 		"is_required":   updatedField.IsRequired.Bool,
 		"is_unique":     updatedField.IsUnique.Bool,
+		"is_indexed":    updatedField.IsIndexed,
+		"is_encrypted":  updatedField.IsEncrypted,
+		"pii":           updatedField.Pii.String,
 		"default_value": updatedField.DefaultValue.String,
 		"sort_order":    updatedField.SortOrder.Int32,
 		"tenant_id":     nil,
@@ -405,16 +808,22 @@ func (s *SchemaHandlers) UpdateField(ctx context.Context, userID uuid.UUID, item
 	if updatedField.TenantID.Valid {
 		result["tenant_id"] = updatedField.TenantID.UUID.String()
 	}
+	s.handler.addFieldIndexStatus(ctx, "fields", userID, []map[string]interface{}{result})
+	searchable := isSearchableField(existingField.Type) || isSearchableField(fieldType)
+	if searchable && (name != existingField.Name || fieldType != existingField.Type) {
+		s.refreshSearchIndex(ctx, userTenantID, existingField.CollectionID)
+	}
 
 	return result, nil
 }
 
-// DeleteField deletes a field
+// DeleteField deletes a field. Its column is dropped or archived as FIELD_DELETE_MODE says,
+// in the same transaction as the field record.
 func (s *SchemaHandlers) DeleteField(ctx context.Context, userID uuid.UUID, itemID string) error {
 	// Parse item ID
 	fieldID, err := uuid.Parse(itemID)
 	if err != nil {
-		return fmt.Errorf("invalid field ID: %w", err)
+		return validationError("invalid field ID: %w", err)
 	}
 
 	// Get tenant ID for filtering
@@ -426,16 +835,63 @@ func (s *SchemaHandlers) DeleteField(ctx context.Context, userID uuid.UUID, item
 	// Get existing field to check access
 	existingField, err := s.handler.db.Queries.GetField(ctx, fieldID)
 	if err != nil {
-		return fmt.Errorf("field not found: %w", err)
+		return notFoundError("field not found: %w", err)
 	}
 
 	// Check tenant access
 	if existingField.TenantID.Valid && existingField.TenantID.UUID != userTenantID {
-		return fmt.Errorf("unauthorized: field not accessible")
+		return forbiddenError("unauthorized: field not accessible")
 	}
 
-	// Delete field using sqlc
-	return s.handler.db.Queries.DeleteField(ctx, fieldID)
+	// Computed fields would lose a column they are generated from
+	siblings, err := s.handler.db.Queries.GetFieldsByCollection(ctx, existingField.CollectionID)
+	if err != nil {
+		return err
+	}
+	if dependents := computedDependents(siblings, existingField.Name); len(dependents) > 0 {
+		return fmt.Errorf("field '%s' is used by computed fields %s", existingField.Name, strings.Join(dependents, ", "))
+	}
+
+	// Alias fields have no column, and system collections no data table
+	var statements []string
+	if existingField.CollectionID.Valid && !isAliasField(existingField.Type, existingField.RelationConfig) {
+		collection, err := s.handler.db.Queries.GetCollection(ctx, existingField.CollectionID.UUID)
+		if err != nil {
+			return notFoundError("collection not found: %w", err)
+		}
+		if !collection.IsSystem.Bool {
+			table, err := s.utils.ResolveDataTable(ctx, userTenantID, collection.Slug)
+			if err != nil && !errors.Is(err, ErrDataTableNotFound) {
+				return err
+			}
+			// Without a data table there is no column to remove
+			if err == nil {
+				dataTable := table.String()
+				// Archived columns keep no constraint, index or foreign key
+				indexes := planFieldIndexes(table.Schema, dataTable, fieldID, existingField.Name, existingField.Type,
+					existingField.IsUnique.Bool, false, existingField.IsIndexed, false, false)
+				rules := planRelationRule(dataTable, fieldID, existingField.Name, "",
+					relationRule(existingField.Type, existingField.RelationConfig), RelationConfig{}, false)
+				statements = append(append(indexes.drop, rules.drop...), deleteColumnStatements(dataTable, existingField.Name, s.handler.cfg.FieldDeleteMode)...)
+			}
+		}
+	}
+
+	err = s.handler.db.InTransaction(ctx, func(tx *db.Tx) error {
+		for _, statement := range statements {
+			if _, err := tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to remove column of field '%s': %w", existingField.Name, err)
+			}
+		}
+		return tx.DeleteField(ctx, fieldID)
+	})
+	if err != nil {
+		return err
+	}
+	if isSearchableField(existingField.Type) {
+		s.refreshSearchIndex(ctx, userTenantID, existingField.CollectionID)
+	}
+	return nil
 }
 
 // User Operations
@@ -456,11 +912,15 @@ func (s *SchemaHandlers) CreateUser(ctx context.Context, userID uuid.UUID, data
 		}
 	}
 
-	// Hash password if provided
-	passwordHash := ""
-	if password, ok := data["password"].(string); ok {
-		// TODO: Hash this properly
-		passwordHash = password
+	// Users created through the API must have a password that satisfies the policy
+	password := GetStringFromMap(data, "password")
+	if err := models.NewPasswordPolicy(s.handler.cfg).Validate(password); err != nil {
+		return nil, err
+	}
+
+	passwordHash, err := models.HashPassword(password)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
 	}
 
 	// Create user using sqlc
@@ -496,7 +956,7 @@ func (s *SchemaHandlers) UpdateUser(ctx context.Context, userID uuid.UUID, itemI
 	// Parse item ID
 	targetUserID, err := uuid.Parse(itemID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid user ID: %w", err)
+		return nil, validationError("invalid user ID: %w", err)
 	}
 
 	// Get tenant ID for filtering
@@ -508,12 +968,12 @@ func (s *SchemaHandlers) UpdateUser(ctx context.Context, userID uuid.UUID, itemI
 	// Get existing user
 	existingUser, err := s.handler.db.Queries.GetUserByID(ctx, targetUserID)
 	if err != nil {
-		return nil, fmt.Errorf("user not found: %w", err)
+		return nil, notFoundError("user not found: %w", err)
 	}
 
 	// Check tenant access
 	if existingUser.TenantID.Valid && existingUser.TenantID.UUID != userTenantID {
-		return nil, fmt.Errorf("unauthorized: user not accessible")
+		return nil, forbiddenError("unauthorized: user not accessible")
 	}
 
 	// Extract fields with defaults
@@ -573,12 +1033,12 @@ func (s *SchemaHandlers) DeleteUser(ctx context.Context, userID uuid.UUID, itemI
 	// Parse item ID
 	targetUserID, err := uuid.Parse(itemID)
 	if err != nil {
-		return fmt.Errorf("invalid user ID: %w", err)
+		return validationError("invalid user ID: %w", err)
 	}
 
 	// Prevent self-deletion
 	if targetUserID == userID {
-		return fmt.Errorf("cannot delete your own user account")
+		return validationError("cannot delete your own user account")
 	}
 
 	// Get tenant ID for filtering
@@ -590,12 +1050,12 @@ func (s *SchemaHandlers) DeleteUser(ctx context.Context, userID uuid.UUID, itemI
 	// Get existing user to check access
 	existingUser, err := s.handler.db.Queries.GetUserByID(ctx, targetUserID)
 	if err != nil {
-		return fmt.Errorf("user not found: %w", err)
+		return notFoundError("user not found: %w", err)
 	}
 
 	// Check tenant access
 	if existingUser.TenantID.Valid && existingUser.TenantID.UUID != userTenantID {
-		return fmt.Errorf("unauthorized: user not accessible")
+		return forbiddenError("unauthorized: user not accessible")
 	}
 
 	// Delete user using sqlc
@@ -614,6 +1074,15 @@ func (s *SchemaHandlers) CreateAPIKey(ctx context.Context, userID uuid.UUID, dat
 		}
 	}
 
+	// Keys act in, and count against the limit of, their user's home tenant
+	targetUser, err := s.handler.db.Queries.GetUserByID(ctx, targetUserID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.handler.quota.CheckAPIKeys(ctx, targetUser.TenantID.UUID); err != nil {
+		return nil, err
+	}
+
 	// Generate a secure API key
 	apiKey, err := s.generateAPIKey()
 	if err != nil {
@@ -623,11 +1092,12 @@ func (s *SchemaHandlers) CreateAPIKey(ctx context.Context, userID uuid.UUID, dat
 	// Hash the API key for storage
 	keyHash := s.hashAPIKey(apiKey)
 
-	// Set expiration (default 1 year from now, or use provided value)
-	expiresAt := time.Now().AddDate(1, 0, 0)
+	// Set expiration (default 1 year from now, or use provided value). Keys of service
+	// accounts do not expire by default; the rotation policy reminds to rotate them instead.
+	expiresAt := sql.NullTime{Time: time.Now().AddDate(1, 0, 0), Valid: !targetUser.IsServiceAccount}
 	if expStr, ok := data["expires_at"].(string); ok {
 		if parsedTime, err := time.Parse(time.RFC3339, expStr); err == nil {
-			expiresAt = parsedTime
+			expiresAt = sql.NullTime{Time: parsedTime, Valid: true}
 		}
 	}
 
@@ -637,29 +1107,30 @@ func (s *SchemaHandlers) CreateAPIKey(ctx context.Context, userID uuid.UUID, dat
 		name = nameStr
 	}
 
+	// Scopes limit the key to part of its user's permissions (none means unrestricted)
+	scopes, err := apiKeyScopesFromMap(data)
+	if err != nil {
+		return nil, err
+	}
+	if !rbac.ScopesCover(rbac.ScopesFromContext(ctx), scopes) {
+		return nil, forbiddenError("unauthorized: scopes exceed those of the requesting API key")
+	}
+
 	// Create API key using sqlc
 	createdKey, err := s.handler.db.Queries.CreateAPIKey(ctx, sqlc.CreateAPIKeyParams{
 		UserID:    targetUserID,
 		Name:      name,
 		KeyHash:   keyHash,
-		ExpiresAt: sql.NullTime{Time: expiresAt, Valid: true},
+		ExpiresAt: expiresAt,
+		Scopes:    scopes,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to map (include the plain API key only in creation response)
-	result := map[string]interface{}{
-		"id":           createdKey.ID.String(),
-		"user_id":      createdKey.UserID.String(),
-		"name":         createdKey.Name,
-		"api_key":      apiKey, // Only returned on creation!
-		"is_active":    createdKey.IsActive.Bool,
-		"expires_at":   createdKey.ExpiresAt.Time,
-		"last_used_at": nil,
-		"created_at":   createdKey.CreatedAt.Time,
-		"updated_at":   createdKey.UpdatedAt.Time,
-	}
+	// Include the plain API key only in the creation response
+	result := s.apiKeyToMap(createdKey)
+	result["api_key"] = apiKey
 
 	return result, nil
 }
@@ -669,13 +1140,13 @@ func (s *SchemaHandlers) UpdateAPIKey(ctx context.Context, userID uuid.UUID, ite
 	// Parse item ID
 	apiKeyID, err := uuid.Parse(itemID)
 	if err != nil {
-		return nil, fmt.Errorf("invalid API key ID: %w", err)
+		return nil, validationError("invalid API key ID: %w", err)
 	}
 
 	// Check if user owns this API key (unless admin)
 	existingKey, err := s.handler.db.Queries.GetAPIKeyByID(ctx, apiKeyID)
 	if err != nil {
-		return nil, fmt.Errorf("API key not found: %w", err)
+		return nil, notFoundError("API key not found: %w", err)
 	}
 
 	// Only allow users to update their own keys (unless admin)
@@ -683,7 +1154,7 @@ func (s *SchemaHandlers) UpdateAPIKey(ctx context.Context, userID uuid.UUID, ite
 		// Check if user is admin
 		hasAdminAccess, _, _ := s.handler.policyChecker.CheckPermission(ctx, userID, "users", "read")
 		if !hasAdminAccess {
-			return nil, fmt.Errorf("unauthorized: can only update your own API keys")
+			return nil, forbiddenError("unauthorized: can only update your own API keys")
 		}
 	}
 
@@ -705,33 +1176,75 @@ func (s *SchemaHandlers) UpdateAPIKey(ctx context.Context, userID uuid.UUID, ite
 		}
 	}
 
+	scopes := existingKey.Scopes
+	if _, ok := data["scopes"]; ok {
+		if scopes, err = apiKeyScopesFromMap(data); err != nil {
+			return nil, err
+		}
+		if !rbac.ScopesCover(rbac.ScopesFromContext(ctx), scopes) {
+			return nil, forbiddenError("unauthorized: scopes exceed those of the requesting API key")
+		}
+	}
+
 	// Update API key using sqlc
 	updatedKey, err := s.handler.db.Queries.UpdateAPIKey(ctx, sqlc.UpdateAPIKeyParams{
 		ID:        apiKeyID,
 		Name:      name,
 		IsActive:  sql.NullBool{Bool: isActive, Valid: true},
 		ExpiresAt: expiresAt,
+		Scopes:    scopes,
 	})
 	if err != nil {
 		return nil, err
 	}
 
-	// Convert to map (don't include the actual API key in updates)
-	result := map[string]interface{}{
-		"id":           updatedKey.ID.String(),
-		"user_id":      updatedKey.UserID.String(),
-		"name":         updatedKey.Name,
-		"is_active":    updatedKey.IsActive.Bool,
-		"expires_at":   updatedKey.ExpiresAt.Time,
-		"last_used_at": nil,
-		"created_at":   updatedKey.CreatedAt.Time,
-		"updated_at":   updatedKey.UpdatedAt.Time,
+	// The secret itself is never returned after creation
+	return s.apiKeyToMap(updatedKey), nil
+}
+
+// RotateAPIKey replaces the secret of an API key while keeping its name, scopes and
+// expiry. The previous secret keeps working for gracePeriod so clients can switch over;
+// a zero grace period revokes it immediately. The new secret is only returned here.
+func (s *SchemaHandlers) RotateAPIKey(ctx context.Context, userID uuid.UUID, itemID string, gracePeriod time.Duration) (map[string]interface{}, error) {
+	apiKeyID, err := uuid.Parse(itemID)
+	if err != nil {
+		return nil, validationError("invalid API key ID: %w", err)
 	}
 
-	if updatedKey.LastUsedAt.Valid {
-		result["last_used_at"] = updatedKey.LastUsedAt.Time
+	existingKey, err := s.handler.db.Queries.GetAPIKeyByID(ctx, apiKeyID)
+	if err != nil {
+		return nil, notFoundError("API key not found: %w", err)
 	}
 
+	// Only allow users to rotate their own keys (unless admin)
+	if existingKey.UserID != userID {
+		hasAdminAccess, _, _ := s.handler.policyChecker.CheckPermission(ctx, userID, "users", "read")
+		if !hasAdminAccess {
+			return nil, forbiddenError("unauthorized: can only rotate your own API keys")
+		}
+	}
+
+	// A scoped key may only rotate keys it could have created
+	if !rbac.ScopesCover(rbac.ScopesFromContext(ctx), existingKey.Scopes) {
+		return nil, forbiddenError("unauthorized: scopes exceed those of the requesting API key")
+	}
+
+	apiKey, err := s.generateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+
+	rotatedKey, err := s.handler.db.Queries.RotateAPIKey(ctx, sqlc.RotateAPIKeyParams{
+		ID:                   apiKeyID,
+		KeyHash:              s.hashAPIKey(apiKey),
+		PreviousKeyExpiresAt: sql.NullTime{Time: time.Now().Add(gracePeriod), Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := s.apiKeyToMap(rotatedKey)
+	result["api_key"] = apiKey
 	return result, nil
 }
 
@@ -740,13 +1253,13 @@ func (s *SchemaHandlers) DeleteAPIKey(ctx context.Context, userID uuid.UUID, ite
 	// Parse item ID
 	apiKeyID, err := uuid.Parse(itemID)
 	if err != nil {
-		return fmt.Errorf("invalid API key ID: %w", err)
+		return validationError("invalid API key ID: %w", err)
 	}
 
 	// Check if user owns this API key (unless admin)
 	existingKey, err := s.handler.db.Queries.GetAPIKeyByID(ctx, apiKeyID)
 	if err != nil {
-		return fmt.Errorf("API key not found: %w", err)
+		return notFoundError("API key not found: %w", err)
 	}
 
 	// Only allow users to delete their own keys (unless admin)
@@ -754,7 +1267,7 @@ func (s *SchemaHandlers) DeleteAPIKey(ctx context.Context, userID uuid.UUID, ite
 		// Check if user is admin
 		hasAdminAccess, _, _ := s.handler.policyChecker.CheckPermission(ctx, userID, "users", "read")
 		if !hasAdminAccess {
-			return fmt.Errorf("unauthorized: can only delete your own API keys")
+			return forbiddenError("unauthorized: can only delete your own API keys")
 		}
 	}
 
@@ -762,6 +1275,357 @@ func (s *SchemaHandlers) DeleteAPIKey(ctx context.Context, userID uuid.UUID, ite
 	return s.handler.db.Queries.DeleteAPIKey(ctx, apiKeyID)
 }
 
+// apiKeyToMap converts an API key to its API representation without any secret
+func (s *SchemaHandlers) apiKeyToMap(key sqlc.ApiKey) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":                      key.ID.String(),
+		"user_id":                 key.UserID.String(),
+		"name":                    key.Name,
+		"is_active":               key.IsActive.Bool,
+		"expires_at":              nil,
+		"expires_soon":            false,
+		"scopes":                  scopesOrEmpty(key.Scopes),
+		"previous_key_expires_at": nil,
+		"last_used_at":            nil,
+		"rotated_at":              nil,
+		"created_at":              key.CreatedAt.Time,
+		"updated_at":              key.UpdatedAt.Time,
+	}
+
+	if key.ExpiresAt.Valid {
+		result["expires_at"] = key.ExpiresAt.Time
+		result["expires_soon"] = apiKeyExpiresSoon(key.ExpiresAt.Time, s.handler.cfg.APIKeyExpiryWarning, time.Now())
+	}
+	// Only report the grace period while the old secret still works
+	if key.PreviousKeyExpiresAt.Valid && key.PreviousKeyExpiresAt.Time.After(time.Now()) {
+		result["previous_key_expires_at"] = key.PreviousKeyExpiresAt.Time
+	}
+	if key.LastUsedAt.Valid {
+		result["last_used_at"] = key.LastUsedAt.Time
+	}
+	if key.RotatedAt.Valid {
+		result["rotated_at"] = key.RotatedAt.Time
+	}
+
+	return result
+}
+
+// apiKeyExpiresSoon reports whether a key that has not expired yet will expire within
+// the warning window
+func apiKeyExpiresSoon(expiresAt time.Time, warning time.Duration, now time.Time) bool {
+	return expiresAt.After(now) && !expiresAt.After(now.Add(warning))
+}
+
+// Webhook Operations
+
+// webhookEvents are the event names a webhook may subscribe to
+var webhookEvents = []string{events.ItemCreate, events.ItemUpdate, events.ItemDelete, events.APIKeyExpiring, events.APIKeyRotationDue, "*"}
+
+// CreateWebhook creates a webhook for the user's tenant. A signing secret is generated
+// unless one is provided; it is only returned in this response.
+func (s *SchemaHandlers) CreateWebhook(ctx context.Context, userID uuid.UUID, data map[string]interface{}) (map[string]interface{}, error) {
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	name := GetStringFromMap(data, "name")
+	if name == "" {
+		return nil, validationError("name is required")
+	}
+
+	webhookURL := GetStringFromMap(data, "url")
+	if err := validateWebhookURL(webhookURL); err != nil {
+		return nil, err
+	}
+
+	hookEvents, ok := GetStringSliceFromMap(data, "events")
+	if !ok || len(hookEvents) == 0 {
+		hookEvents = []string{"*"}
+	}
+	if err := validateWebhookEvents(hookEvents); err != nil {
+		return nil, err
+	}
+
+	collections, err := webhookCollectionsFromMap(data)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := GetStringFromMap(data, "secret")
+	if secret == "" {
+		if secret, err = s.generateWebhookSecret(); err != nil {
+			return nil, fmt.Errorf("failed to generate webhook secret: %w", err)
+		}
+	}
+
+	isActive := true
+	if activeVal, ok := data["is_active"].(bool); ok {
+		isActive = activeVal
+	}
+
+	hook, err := s.handler.db.Queries.CreateWebhook(ctx, sqlc.CreateWebhookParams{
+		TenantID:    tenantID,
+		Name:        name,
+		Url:         webhookURL,
+		Events:      hookEvents,
+		Collections: collections,
+		Secret:      secret,
+		IsActive:    sql.NullBool{Bool: isActive, Valid: true},
+		CreatedBy:   uuid.NullUUID{UUID: userID, Valid: true},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	result := webhookToMap(hook)
+	result["secret"] = hook.Secret // Only returned on creation!
+	return result, nil
+}
+
+// UpdateWebhook updates a webhook of the user's tenant. The secret cannot be changed;
+// delete and recreate the webhook to rotate it.
+func (s *SchemaHandlers) UpdateWebhook(ctx context.Context, userID uuid.UUID, itemID string, data map[string]interface{}) (map[string]interface{}, error) {
+	existing, err := s.getTenantWebhook(ctx, userID, itemID)
+	if err != nil {
+		return nil, err
+	}
+
+	params := sqlc.UpdateWebhookParams{
+		ID:          existing.ID,
+		Name:        existing.Name,
+		Url:         existing.Url,
+		Events:      existing.Events,
+		Collections: existing.Collections,
+		IsActive:    existing.IsActive,
+	}
+
+	if name := GetStringFromMap(data, "name"); name != "" {
+		params.Name = name
+	}
+	if _, ok := data["url"]; ok {
+		params.Url = GetStringFromMap(data, "url")
+		if err := validateWebhookURL(params.Url); err != nil {
+			return nil, err
+		}
+	}
+	if _, ok := data["events"]; ok {
+		hookEvents, ok := GetStringSliceFromMap(data, "events")
+		if !ok || len(hookEvents) == 0 {
+			return nil, validationError("events must be a non-empty list")
+		}
+		if err := validateWebhookEvents(hookEvents); err != nil {
+			return nil, err
+		}
+		params.Events = hookEvents
+	}
+	if _, ok := data["collections"]; ok {
+		if params.Collections, err = webhookCollectionsFromMap(data); err != nil {
+			return nil, err
+		}
+	}
+	if activeVal, ok := data["is_active"].(bool); ok {
+		params.IsActive = sql.NullBool{Bool: activeVal, Valid: true}
+	}
+
+	hook, err := s.handler.db.Queries.UpdateWebhook(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return webhookToMap(hook), nil
+}
+
+// DeleteWebhook deletes a webhook of the user's tenant along with its delivery log
+func (s *SchemaHandlers) DeleteWebhook(ctx context.Context, userID uuid.UUID, itemID string) error {
+	existing, err := s.getTenantWebhook(ctx, userID, itemID)
+	if err != nil {
+		return err
+	}
+
+	return s.handler.db.Queries.DeleteWebhook(ctx, existing.ID)
+}
+
+// getTenantWebhook loads a webhook and makes sure it belongs to the user's tenant
+func (s *SchemaHandlers) getTenantWebhook(ctx context.Context, userID uuid.UUID, itemID string) (sqlc.Webhook, error) {
+	webhookID, err := uuid.Parse(itemID)
+	if err != nil {
+		return sqlc.Webhook{}, validationError("invalid webhook ID: %w", err)
+	}
+
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return sqlc.Webhook{}, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	hook, err := s.handler.db.Queries.GetWebhookByID(ctx, webhookID)
+	if err != nil || hook.TenantID != tenantID {
+		return sqlc.Webhook{}, fmt.Errorf("webhook not found")
+	}
+
+	return hook, nil
+}
+
+// generateWebhookSecret generates a random signing secret for a webhook
+func (s *SchemaHandlers) generateWebhookSecret() (string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(bytes), nil
+}
+
+// webhookToMap converts a webhook to its API representation, without the secret
+func webhookToMap(hook sqlc.Webhook) map[string]interface{} {
+	return map[string]interface{}{
+		"id":          hook.ID.String(),
+		"tenant_id":   hook.TenantID.String(),
+		"name":        hook.Name,
+		"url":         hook.Url,
+		"events":      hook.Events,
+		"collections": hook.Collections,
+		"is_active":   hook.IsActive.Bool,
+		"created_at":  hook.CreatedAt.Time,
+		"updated_at":  hook.UpdatedAt.Time,
+	}
+}
+
+// Asset Operations
+
+// UpdateAsset updates the metadata of an asset of the user's tenant. The file itself is
+// immutable; upload a new asset to replace it.
+func (s *SchemaHandlers) UpdateAsset(ctx context.Context, userID uuid.UUID, itemID string, data map[string]interface{}) (map[string]interface{}, error) {
+	assetID, err := uuid.Parse(itemID)
+	if err != nil {
+		return nil, validationError("invalid asset ID: %w", err)
+	}
+
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user tenant: %w", err)
+	}
+
+	existing, err := s.handler.db.Queries.GetAssetByID(ctx, assetID)
+	if err != nil || existing.TenantID != tenantID {
+		return nil, notFoundError("asset not found")
+	}
+
+	params := sqlc.UpdateAssetParams{
+		ID:               existing.ID,
+		Title:            existing.Title,
+		FilenameDownload: existing.FilenameDownload,
+	}
+	if _, ok := data["title"]; ok {
+		title := GetStringFromMap(data, "title")
+		params.Title = sql.NullString{String: title, Valid: title != ""}
+	}
+	if filename := GetStringFromMap(data, "filename_download"); filename != "" {
+		params.FilenameDownload = sanitizeFilename(filename)
+	}
+
+	asset, err := s.handler.db.Queries.UpdateAsset(ctx, params)
+	if err != nil {
+		return nil, err
+	}
+
+	return assetToMap(asset), nil
+}
+
+// validateWebhookURL requires an absolute http(s) URL
+func validateWebhookURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return validationError("url must be an absolute http or https URL")
+	}
+	return nil
+}
+
+// validateWebhookEvents rejects unknown event names
+func validateWebhookEvents(hookEvents []string) error {
+	for _, event := range hookEvents {
+		if !slices.Contains(webhookEvents, event) {
+			return fmt.Errorf("unsupported webhook event '%s'", event)
+		}
+	}
+	return nil
+}
+
+// webhookCollectionsFromMap reads the optional collection filter of a webhook
+func webhookCollectionsFromMap(data map[string]interface{}) ([]string, error) {
+	if data["collections"] == nil {
+		return []string{}, nil
+	}
+
+	collections, ok := GetStringSliceFromMap(data, "collections")
+	if !ok {
+		return nil, validationError("collections must be a list of collection names")
+	}
+	for _, collection := range collections {
+		if collection != "*" && !rbac.ValidateTableName(collection) {
+			return nil, fmt.Errorf("invalid collection name '%s'", collection)
+		}
+	}
+	return collections, nil
+}
+
+// apiKeyScopesFromMap reads the optional scopes of an API key; missing or null scopes
+// leave the key unrestricted
+func apiKeyScopesFromMap(data map[string]interface{}) ([]string, error) {
+	if data["scopes"] == nil {
+		return nil, nil
+	}
+
+	scopes, ok := GetStringSliceFromMap(data, "scopes")
+	if !ok {
+		return nil, validationError("scopes must be a list of table:action strings")
+	}
+	return rbac.NormalizeScopes(scopes)
+}
+
+// scopesOrEmpty returns the scopes of a key for API responses, as an empty list when the
+// key is unrestricted
+func scopesOrEmpty(scopes []string) []string {
+	if scopes == nil {
+		return []string{}
+	}
+	return scopes
+}
+
+// Schema Events
+
+// publishSchemaEvent emits an item event for a write to a schema table, so webhooks and
+// other subscribers see changes to users, collections, fields etc. like any other item.
+func (s *SchemaHandlers) publishSchemaEvent(ctx context.Context, eventType string, userID uuid.UUID, tableName, itemID string, data map[string]interface{}) {
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return
+	}
+
+	if itemID == "" {
+		itemID = GetStringFromMap(data, "id")
+	}
+
+	// Never broadcast credentials
+	var payload map[string]interface{}
+	if data != nil {
+		payload = make(map[string]interface{}, len(data))
+		for key, value := range data {
+			if key != "api_key" && key != "secret" && key != "password" && key != "password_hash" {
+				payload[key] = value
+			}
+		}
+	}
+
+	s.handler.events.Publish(ctx, events.Event{
+		Type:       eventType,
+		TenantID:   tenantID,
+		Collection: tableName,
+		Keys:       []string{itemID},
+		Data:       payload,
+		UserID:     userID,
+	})
+}
+
 // Helper methods for API key generation
 
 // generateAPIKey generates a secure random API key
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"go/parser"
+	"go/token"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// sdkTestCollections returns a collection the caller may read and create, with an enum,
+// an optional field, a datetime and a computed field
+func sdkTestCollections() []openAPICollection {
+	return []openAPICollection{{
+		SnapshotCollection: SnapshotCollection{Slug: "blog_posts", Name: "blog_posts", DisplayName: "Blog Posts", Fields: []SnapshotField{
+			{Name: "title", Type: "string", IsRequired: true},
+			{Name: "state", Type: "string", IsRequired: true, ValidationRules: map[string]interface{}{"enum": []interface{}{"new", "done"}}},
+			{Name: "owner_id", Type: "uuid"},
+			{Name: "due", Type: "datetime"},
+			{Name: "score", Type: "computed", ComputedConfig: map[string]interface{}{"type": "number"}},
+		}},
+		ReadFields:   []string{"*"},
+		CreateFields: []string{"*"},
+	}}
+}
+
+func TestGenerateTypeScriptSDK(t *testing.T) {
+	source := generateTypeScriptSDK(sdkTestCollections())
+
+	assert.Contains(t, source, "export interface BlogPosts {\n  readonly id: string;\n  title: string;\n")
+	assert.Contains(t, source, `  state: "new" | "done";`)
+	assert.Contains(t, source, "  owner_id: string | null;")
+	assert.Contains(t, source, "  readonly score: number | null;")
+	assert.Contains(t, source, "export interface BlogPostsCreate {\n  title: string;\n")
+	assert.Contains(t, source, "  due?: string | null;")
+	assert.NotContains(t, source, "BlogPostsUpdate", "no update permission")
+	assert.Contains(t, source, "export interface CreateInputs {\n  blog_posts: BlogPostsCreate;\n}")
+	assert.Contains(t, source, "export type DeletableCollection = never;")
+	assert.Contains(t, source, "export class BasinClient {")
+}
+
+func TestGenerateGoSDK(t *testing.T) {
+	source, err := generateGoSDK(sdkTestCollections(), "blog")
+	require.NoError(t, err)
+
+	_, err = parser.ParseFile(token.NewFileSet(), "basin.go", source, 0)
+	require.NoError(t, err, string(source))
+
+	code := string(source)
+	assert.Contains(t, code, "package blog\n")
+	assert.Contains(t, code, "\t\"time\"\n")
+	assert.Contains(t, code, "OwnerID *string    `json:\"owner_id,omitempty\"`")
+	assert.Contains(t, code, "Due     *time.Time `json:\"due,omitempty\"`")
+	assert.Contains(t, code, "func (c *Client) ListBlogPosts(ctx context.Context, query url.Values) ([]BlogPosts, Meta, error)")
+	assert.Contains(t, code, "func (c *Client) CreateBlogPosts(ctx context.Context, input BlogPostsCreate) (*BlogPosts, error)")
+	assert.NotContains(t, code, "UpdateBlogPosts", "no update permission")
+	assert.NotContains(t, code, "DeleteBlogPosts", "no delete permission")
+}
+
+func TestGoFieldName(t *testing.T) {
+	taken := map[string]bool{}
+	assert.Equal(t, "OwnerID", goFieldName("owner_id", taken))
+	assert.Equal(t, "OwnerID2", goFieldName("owner-id", taken))
+	assert.Equal(t, "ImageURL", goFieldName("image_url", taken))
+	assert.Equal(t, "Field2024", goFieldName("2024", taken))
+}
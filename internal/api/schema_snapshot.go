@@ -0,0 +1,882 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains schema snapshots, which move a tenant's schema between environments.
+//
+// Schema Endpoints:
+// - GET  /schema/snapshot  - Export collections, fields, roles and permissions (?format=yaml)
+// - POST /schema/apply     - Apply a snapshot to the caller's tenant (?dry_run=true to preview)
+//
+// Snapshots identify records by name rather than ID: collections by slug, fields by
+// collection slug and name, roles by name and permissions by role, table and action.
+// Applying a snapshot creates what is missing and updates what differs, so applying the same
+// snapshot twice changes nothing the second time. Records missing from the snapshot are
+// left alone, and existing roles are not modified.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"gopkg.in/yaml.v3"
+)
+
+// SchemaSnapshotVersion is the snapshot format written by this version of Basin
+const SchemaSnapshotVersion = 1
+
+// schemaSnapshotTables are the tables a snapshot reads and writes, used for permission checks
+var schemaSnapshotTables = []string{"collections", "fields", "roles", "permissions"}
+
+// SchemaSnapshot is a portable description of a tenant's schema
+type SchemaSnapshot struct {
+	Version     int                  `json:"version" yaml:"version"`
+	Collections []SnapshotCollection `json:"collections" yaml:"collections"`
+	Roles       []SnapshotRole       `json:"roles" yaml:"roles"`
+	Permissions []SnapshotPermission `json:"permissions" yaml:"permissions"`
+}
+
+// SnapshotCollection describes a collection and its fields
+type SnapshotCollection struct {
+	Slug        string          `json:"slug" yaml:"slug"`
+	Name        string          `json:"name" yaml:"name"`
+	DisplayName string          `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Description string          `json:"description,omitempty" yaml:"description,omitempty"`
+	Icon        string          `json:"icon,omitempty" yaml:"icon,omitempty"`
+	IsSystem    bool            `json:"is_system,omitempty" yaml:"is_system,omitempty"`
+	SoftDelete  bool            `json:"soft_delete,omitempty" yaml:"soft_delete,omitempty"`
+	Workflow    bool            `json:"workflow,omitempty" yaml:"workflow,omitempty"`
+	Timestamps  *bool           `json:"timestamps,omitempty" yaml:"timestamps,omitempty"`   // Only set when off
+	UserStamps  *bool           `json:"user_stamps,omitempty" yaml:"user_stamps,omitempty"` // Only set when off
+	Sortable    bool            `json:"sortable,omitempty" yaml:"sortable,omitempty"`
+	Fields      []SnapshotField `json:"fields,omitempty" yaml:"fields,omitempty"`
+}
+
+// SnapshotField describes a field of a collection
+type SnapshotField struct {
+	Name            string      `json:"name" yaml:"name"`
+	DisplayName     string      `json:"display_name,omitempty" yaml:"display_name,omitempty"`
+	Type            string      `json:"type" yaml:"type"`
+	IsPrimary       bool        `json:"is_primary,omitempty" yaml:"is_primary,omitempty"`
+	IsRequired      bool        `json:"is_required,omitempty" yaml:"is_required,omitempty"`
+	IsUnique        bool        `json:"is_unique,omitempty" yaml:"is_unique,omitempty"`
+	IsIndexed       bool        `json:"is_indexed,omitempty" yaml:"is_indexed,omitempty"`
+	IsEncrypted     bool        `json:"is_encrypted,omitempty" yaml:"is_encrypted,omitempty"`
+	PII             string      `json:"pii,omitempty" yaml:"pii,omitempty"`
+	DefaultValue    string      `json:"default_value,omitempty" yaml:"default_value,omitempty"`
+	SortOrder       int         `json:"sort_order,omitempty" yaml:"sort_order,omitempty"`
+	ValidationRules interface{} `json:"validation_rules,omitempty" yaml:"validation_rules,omitempty"`
+	RelationConfig  interface{} `json:"relation_config,omitempty" yaml:"relation_config,omitempty"`
+	ComputedConfig  interface{} `json:"computed_config,omitempty" yaml:"computed_config,omitempty"`
+}
+
+// SnapshotRole describes a role; Parent names the role it inherits from
+type SnapshotRole struct {
+	Name        string `json:"name" yaml:"name"`
+	Description string `json:"description,omitempty" yaml:"description,omitempty"`
+	Parent      string `json:"parent,omitempty" yaml:"parent,omitempty"`
+}
+
+// SnapshotPermission describes one permission of a role
+type SnapshotPermission struct {
+	Role            string               `json:"role" yaml:"role"`
+	Table           string               `json:"table" yaml:"table"`
+	Action          string               `json:"action" yaml:"action"`
+	AllowedFields   []string             `json:"allowed_fields,omitempty" yaml:"allowed_fields,omitempty"`
+	ReadFields      []string             `json:"read_fields,omitempty" yaml:"read_fields,omitempty"`
+	WriteFields     []string             `json:"write_fields,omitempty" yaml:"write_fields,omitempty"`
+	FieldFilter     interface{}          `json:"field_filter,omitempty" yaml:"field_filter,omitempty"`
+	Scope           string               `json:"scope,omitempty" yaml:"scope,omitempty"` // own, or empty for all
+	FieldTransforms rbac.FieldTransforms `json:"field_transforms,omitempty" yaml:"field_transforms,omitempty"`
+}
+
+// SchemaChange is one difference between a tenant's schema and a snapshot
+type SchemaChange struct {
+	Action string   `json:"action"`           // create or update
+	Kind   string   `json:"kind"`             // role, collection, field or permission
+	Name   string   `json:"name"`             // e.g. editor, products, products.price, editor:products:read
+	Fields []string `json:"fields,omitempty"` // Attributes that differ, for updates
+
+	role       *SnapshotRole
+	collection *SnapshotCollection
+	field      *SnapshotField
+	permission *SnapshotPermission
+}
+
+// schemaIndex maps the names used in a snapshot to the tenant's record IDs
+type schemaIndex struct {
+	roles       map[string]uuid.UUID
+	collections map[string]uuid.UUID
+	fields      map[string]uuid.UUID // collection slug + "." + field name
+	permissions map[string]uuid.UUID // role + ":" + table + ":" + action
+}
+
+// ParseSchemaSnapshot decodes a snapshot in the given format ("yaml" or "json") and checks it
+func ParseSchemaSnapshot(data []byte, format string) (*SchemaSnapshot, error) {
+	var snapshot SchemaSnapshot
+	var err error
+	if format == "yaml" {
+		err = yaml.Unmarshal(data, &snapshot)
+	} else {
+		err = json.Unmarshal(data, &snapshot)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid snapshot: %w", err)
+	}
+	if err := snapshot.Validate(); err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
+
+// Encode writes the snapshot as YAML or JSON
+func (snapshot *SchemaSnapshot) Encode(format string) ([]byte, error) {
+	if format == "yaml" {
+		return yaml.Marshal(snapshot)
+	}
+	return json.MarshalIndent(snapshot, "", "  ")
+}
+
+// Validate checks that every record of the snapshot can be identified and applied
+func (snapshot *SchemaSnapshot) Validate() error {
+	if snapshot.Version != SchemaSnapshotVersion {
+		return fmt.Errorf("invalid snapshot: unsupported version %d (expected %d)", snapshot.Version, SchemaSnapshotVersion)
+	}
+
+	roles := make(map[string]bool)
+	for _, role := range snapshot.Roles {
+		if role.Name == "" {
+			return fmt.Errorf("invalid snapshot: role without a name")
+		}
+		if roles[role.Name] {
+			return fmt.Errorf("invalid snapshot: duplicate role %s", role.Name)
+		}
+		roles[role.Name] = true
+	}
+
+	collections := make(map[string]bool)
+	for _, collection := range snapshot.Collections {
+		if collection.Slug == "" || collection.Name == "" {
+			return fmt.Errorf("invalid snapshot: collections need a slug and a name")
+		}
+		if collections[collection.Slug] {
+			return fmt.Errorf("invalid snapshot: duplicate collection %s", collection.Slug)
+		}
+		collections[collection.Slug] = true
+
+		fields := make(map[string]bool)
+		for _, field := range collection.Fields {
+			if field.Name == "" || field.Type == "" {
+				return fmt.Errorf("invalid snapshot: fields of %s need a name and a type", collection.Slug)
+			}
+			if fields[field.Name] {
+				return fmt.Errorf("invalid snapshot: duplicate field %s.%s", collection.Slug, field.Name)
+			}
+			fields[field.Name] = true
+		}
+	}
+
+	unstamped := unstampedCollections(snapshot.Collections)
+	permissions := make(map[string]bool)
+	for _, permission := range snapshot.Permissions {
+		switch permission.Action {
+		case "create", "read", "update", "delete", decryptAction:
+		default:
+			return fmt.Errorf("invalid snapshot: invalid action %q for %s on %s", permission.Action, permission.Role, permission.Table)
+		}
+		if permission.Role == "" || permission.Table == "" {
+			return fmt.Errorf("invalid snapshot: permissions need a role and a table")
+		}
+		key := permissionKey(permission)
+		if permissions[key] {
+			return fmt.Errorf("invalid snapshot: duplicate permission %s", key)
+		}
+		if err := checkPermissionScope(permission.Table, permission.Scope, unstamped); err != nil {
+			return fmt.Errorf("invalid snapshot: permission %s: %w", key, err)
+		}
+		if err := checkFieldTransforms(permission.Action, permission.FieldTransforms); err != nil {
+			return fmt.Errorf("invalid snapshot: permission %s: %w", key, err)
+		}
+		permissions[key] = true
+	}
+
+	return nil
+}
+
+// Snapshot exports the schema of the user's tenant
+func (s *SchemaHandlers) Snapshot(ctx context.Context, userID uuid.UUID) (*SchemaSnapshot, error) {
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	snapshot, _, err := s.loadSchema(ctx, tenantID)
+	return snapshot, err
+}
+
+// ApplySnapshot brings the user's tenant in line with snapshot and returns the changes
+// made, or only computes them when dryRun is set. Changes are applied in order (roles,
+// collections, fields, permissions) and not in one transaction; after a failure the
+// snapshot can simply be applied again.
+func (s *SchemaHandlers) ApplySnapshot(ctx context.Context, userID uuid.UUID, snapshot *SchemaSnapshot, dryRun bool) ([]SchemaChange, error) {
+	tenantID, err := s.utils.GetUserTenantID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	current, index, err := s.loadSchema(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	changes := diffSchema(current, snapshot)
+	if err := checkPermissionRoles(current, snapshot); err != nil {
+		return nil, err
+	}
+	if dryRun || len(changes) == 0 {
+		return changes, nil
+	}
+
+	for i, change := range changes {
+		if err := s.applyChange(ctx, userID, tenantID, index, change); err != nil {
+			return changes[:i], fmt.Errorf("failed to %s %s %s: %w", change.Action, change.Kind, change.Name, err)
+		}
+	}
+
+	// Roles and permissions may have changed
+	rbac.PermissionsChanged(tenantID)
+	return changes, nil
+}
+
+// loadSchema reads the tenant's schema as a snapshot, together with the IDs of its records
+func (s *SchemaHandlers) loadSchema(ctx context.Context, tenantID uuid.UUID) (*SchemaSnapshot, *schemaIndex, error) {
+	queries := s.handler.db.Queries
+	tenant := uuid.NullUUID{UUID: tenantID, Valid: true}
+	snapshot := &SchemaSnapshot{
+		Version:     SchemaSnapshotVersion,
+		Collections: []SnapshotCollection{},
+		Roles:       []SnapshotRole{},
+		Permissions: []SnapshotPermission{},
+	}
+	index := &schemaIndex{
+		roles:       make(map[string]uuid.UUID),
+		collections: make(map[string]uuid.UUID),
+		fields:      make(map[string]uuid.UUID),
+		permissions: make(map[string]uuid.UUID),
+	}
+
+	roles, err := queries.GetRolesByTenant(ctx, tenant)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load roles: %w", err)
+	}
+	roleNames := make(map[uuid.UUID]string, len(roles))
+	for _, role := range roles {
+		roleNames[role.ID] = role.Name
+		index.roles[role.Name] = role.ID
+	}
+	for _, role := range roles {
+		snapshot.Roles = append(snapshot.Roles, SnapshotRole{
+			Name:        role.Name,
+			Description: role.Description.String,
+			Parent:      roleNames[role.ParentID.UUID],
+		})
+	}
+
+	collections, err := queries.GetCollectionsByTenant(ctx, tenant)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load collections: %w", err)
+	}
+	for _, collection := range collections {
+		index.collections[collection.Slug] = collection.ID
+		entry := SnapshotCollection{
+			Slug:        collection.Slug,
+			Name:        collection.Name,
+			DisplayName: collection.DisplayName.String,
+			Description: collection.Description.String,
+			Icon:        collection.Icon.String,
+			IsSystem:    collection.IsSystem.Bool,
+			SoftDelete:  collection.SoftDelete,
+			Workflow:    collection.Workflow,
+			Timestamps:  flagUnlessOn(collection.Timestamps),
+			UserStamps:  flagUnlessOn(collection.UserStamps),
+			Sortable:    collection.Sortable,
+		}
+
+		fields, err := queries.GetFieldsByCollection(ctx, uuid.NullUUID{UUID: collection.ID, Valid: true})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load fields of %s: %w", collection.Slug, err)
+		}
+		for _, field := range fields {
+			index.fields[collection.Slug+"."+field.Name] = field.ID
+			entry.Fields = append(entry.Fields, SnapshotField{
+				Name:            field.Name,
+				DisplayName:     field.DisplayName.String,
+				Type:            field.Type,
+				IsPrimary:       field.IsPrimary.Bool,
+				IsRequired:      field.IsRequired.Bool,
+				IsUnique:        field.IsUnique.Bool,
+				IsIndexed:       field.IsIndexed,
+				IsEncrypted:     field.IsEncrypted,
+				PII:             field.Pii.String,
+				DefaultValue:    field.DefaultValue.String,
+				SortOrder:       int(field.SortOrder.Int32),
+				ValidationRules: decodeSnapshotJSON(field.ValidationRules),
+				RelationConfig:  decodeSnapshotJSON(field.RelationConfig),
+				ComputedConfig:  decodeSnapshotJSON(field.ComputedConfig),
+			})
+		}
+		snapshot.Collections = append(snapshot.Collections, entry)
+	}
+
+	permissions, err := queries.GetPermissionsByTenant(ctx, tenant)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to load permissions: %w", err)
+	}
+	for _, permission := range permissions {
+		roleName, ok := roleNames[permission.RoleID.UUID]
+		if !ok {
+			// Permissions of roles outside the tenant cannot be expressed by name
+			continue
+		}
+		entry := SnapshotPermission{
+			Role:          roleName,
+			Table:         permission.TableName,
+			Action:        permission.Action,
+			AllowedFields: permission.AllowedFields,
+			ReadFields:    permission.ReadFields,
+			WriteFields:   permission.WriteFields,
+			FieldFilter:   decodeSnapshotJSON(permission.FieldFilter),
+		}
+		if permission.Scope != rbac.ScopeAll {
+			entry.Scope = permission.Scope
+		}
+		if permission.FieldTransforms.Valid {
+			entry.FieldTransforms, _ = rbac.ParseFieldTransforms(permission.FieldTransforms.RawMessage)
+		}
+		index.permissions[permissionKey(entry)] = permission.ID
+		snapshot.Permissions = append(snapshot.Permissions, entry)
+	}
+	sort.SliceStable(snapshot.Permissions, func(i, j int) bool {
+		return permissionKey(snapshot.Permissions[i]) < permissionKey(snapshot.Permissions[j])
+	})
+
+	return snapshot, index, nil
+}
+
+// diffSchema lists the changes that turn current into desired. Roles come first, ordered so
+// that parents are created before the roles inheriting from them, then collections, fields
+// and permissions.
+func diffSchema(current, desired *SchemaSnapshot) []SchemaChange {
+	var changes []SchemaChange
+
+	existingRoles := make(map[string]bool, len(current.Roles))
+	for _, role := range current.Roles {
+		existingRoles[role.Name] = true
+	}
+	pending := make([]*SnapshotRole, 0, len(desired.Roles))
+	for i := range desired.Roles {
+		if !existingRoles[desired.Roles[i].Name] {
+			pending = append(pending, &desired.Roles[i])
+		}
+	}
+	for len(pending) > 0 {
+		var next []*SnapshotRole
+		for _, role := range pending {
+			if role.Parent != "" && !existingRoles[role.Parent] && roleListed(pending, role.Parent) {
+				next = append(next, role)
+				continue
+			}
+			existingRoles[role.Name] = true
+			changes = append(changes, SchemaChange{Action: "create", Kind: "role", Name: role.Name, role: role})
+		}
+		if len(next) == len(pending) {
+			// A parent cycle; create the rest in the given order and let the database decide
+			for _, role := range next {
+				changes = append(changes, SchemaChange{Action: "create", Kind: "role", Name: role.Name, role: role})
+			}
+			break
+		}
+		pending = next
+	}
+
+	currentCollections := make(map[string]SnapshotCollection, len(current.Collections))
+	for _, collection := range current.Collections {
+		currentCollections[collection.Slug] = collection
+	}
+	var fieldChanges []SchemaChange
+	for i := range desired.Collections {
+		want := &desired.Collections[i]
+		have, exists := currentCollections[want.Slug]
+		if !exists {
+			changes = append(changes, SchemaChange{Action: "create", Kind: "collection", Name: want.Slug, collection: want})
+		} else if differing := differentAttributes(collectionAttributes(have), collectionAttributes(*want)); len(differing) > 0 {
+			changes = append(changes, SchemaChange{Action: "update", Kind: "collection", Name: want.Slug, Fields: differing, collection: want})
+		}
+
+		currentFields := make(map[string]SnapshotField, len(have.Fields))
+		for _, field := range have.Fields {
+			currentFields[field.Name] = field
+		}
+		for j := range want.Fields {
+			field := &want.Fields[j]
+			name := want.Slug + "." + field.Name
+			existing, exists := currentFields[field.Name]
+			if !exists {
+				fieldChanges = append(fieldChanges, SchemaChange{Action: "create", Kind: "field", Name: name, collection: want, field: field})
+			} else if differing := differentAttributes(existing, *field); len(differing) > 0 {
+				fieldChanges = append(fieldChanges, SchemaChange{Action: "update", Kind: "field", Name: name, Fields: differing, collection: want, field: field})
+			}
+		}
+	}
+	changes = append(changes, fieldChanges...)
+
+	currentPermissions := make(map[string]SnapshotPermission, len(current.Permissions))
+	for _, permission := range current.Permissions {
+		currentPermissions[permissionKey(permission)] = permission
+	}
+	for i := range desired.Permissions {
+		want := &desired.Permissions[i]
+		key := permissionKey(*want)
+		have, exists := currentPermissions[key]
+		if !exists {
+			changes = append(changes, SchemaChange{Action: "create", Kind: "permission", Name: key, permission: want})
+		} else if differing := differentAttributes(have, *want); len(differing) > 0 {
+			changes = append(changes, SchemaChange{Action: "update", Kind: "permission", Name: key, Fields: differing, permission: want})
+		}
+	}
+
+	return changes
+}
+
+// checkPermissionRoles makes sure every permission and parent refers to a role that exists
+// or is part of the snapshot
+func checkPermissionRoles(current, desired *SchemaSnapshot) error {
+	known := make(map[string]bool)
+	for _, role := range current.Roles {
+		known[role.Name] = true
+	}
+	for _, role := range desired.Roles {
+		known[role.Name] = true
+	}
+	for _, role := range desired.Roles {
+		if role.Parent != "" && !known[role.Parent] {
+			return fmt.Errorf("invalid snapshot: parent role %s of %s does not exist", role.Parent, role.Name)
+		}
+	}
+	for _, permission := range desired.Permissions {
+		if !known[permission.Role] {
+			return fmt.Errorf("invalid snapshot: role %s of permission %s does not exist", permission.Role, permissionKey(permission))
+		}
+	}
+	return nil
+}
+
+// applyChange performs one change through the same code paths as the items API, so data
+// tables and columns are created along with the metadata
+func (s *SchemaHandlers) applyChange(ctx context.Context, userID, tenantID uuid.UUID, index *schemaIndex, change SchemaChange) error {
+	queries := s.handler.db.Queries
+
+	switch change.Kind {
+	case "role":
+		role := change.role
+		var parentID uuid.NullUUID
+		if role.Parent != "" {
+			parentID = uuid.NullUUID{UUID: index.roles[role.Parent], Valid: true}
+		}
+		created, err := queries.CreateRole(ctx, sqlc.CreateRoleParams{
+			ID:          uuid.New(),
+			Name:        role.Name,
+			Description: sql.NullString{String: role.Description, Valid: role.Description != ""},
+			TenantID:    uuid.NullUUID{UUID: tenantID, Valid: true},
+			ParentID:    parentID,
+		})
+		if err != nil {
+			return err
+		}
+		index.roles[role.Name] = created.ID
+
+	case "collection":
+		collection := change.collection
+		data := map[string]interface{}{
+			"display_name": collection.DisplayName,
+			"description":  collection.Description,
+			"icon":         collection.Icon,
+			"soft_delete":  collection.SoftDelete,
+			"workflow":     collection.Workflow,
+			"timestamps":   flagOn(collection.Timestamps),
+			"user_stamps":  flagOn(collection.UserStamps),
+			"sortable":     collection.Sortable,
+		}
+		if change.Action == "update" {
+			_, err := s.UpdateCollection(ctx, userID, index.collections[collection.Slug].String(), data)
+			return err
+		}
+		data["name"] = collection.Name
+		data["slug"] = collection.Slug
+		data["is_system"] = collection.IsSystem
+		created, err := s.CreateCollection(ctx, userID, data)
+		if err != nil {
+			return err
+		}
+		index.collections[collection.Slug] = uuid.MustParse(GetStringFromMap(created, "id"))
+
+	case "field":
+		field := change.field
+		data := map[string]interface{}{
+			"display_name":  field.DisplayName,
+			"type":          field.Type,
+			"is_primary":    field.IsPrimary,
+			"is_required":   field.IsRequired,
+			"is_unique":     field.IsUnique,
+			"is_indexed":    field.IsIndexed,
+			"is_encrypted":  field.IsEncrypted,
+			"pii":           field.PII,
+			"default_value": field.DefaultValue,
+			"sort_order":    field.SortOrder,
+		}
+		if field.ValidationRules != nil {
+			data["validation_rules"] = field.ValidationRules
+		}
+		if field.RelationConfig != nil {
+			data["relation_config"] = field.RelationConfig
+		}
+		if field.ComputedConfig != nil {
+			data["computed_config"] = field.ComputedConfig
+		}
+		if change.Action == "update" {
+			_, err := s.UpdateField(ctx, userID, index.fields[change.Name].String(), data)
+			return err
+		}
+		data["collection_id"] = index.collections[change.collection.Slug].String()
+		data["name"] = field.Name
+		created, err := s.CreateField(ctx, userID, data)
+		if err != nil {
+			return err
+		}
+		index.fields[change.Name] = uuid.MustParse(GetStringFromMap(created, "id"))
+
+	case "permission":
+		permission := change.permission
+		fieldFilter, err := encodeSnapshotJSON(permission.FieldFilter)
+		if err != nil {
+			return err
+		}
+		transforms, err := encodeFieldTransforms(permission.FieldTransforms)
+		if err != nil {
+			return err
+		}
+		if change.Action == "update" {
+			_, err := queries.UpdatePermission(ctx, sqlc.UpdatePermissionParams{
+				ID:              index.permissions[change.Name],
+				FieldFilter:     fieldFilter,
+				AllowedFields:   permission.AllowedFields,
+				ReadFields:      permission.ReadFields,
+				WriteFields:     permission.WriteFields,
+				Scope:           permissionScope(permission.Scope),
+				FieldTransforms: transforms,
+			})
+			return err
+		}
+		created, err := queries.CreatePermission(ctx, sqlc.CreatePermissionParams{
+			ID:              uuid.New(),
+			RoleID:          uuid.NullUUID{UUID: index.roles[permission.Role], Valid: true},
+			TableName:       permission.Table,
+			Action:          permission.Action,
+			FieldFilter:     fieldFilter,
+			AllowedFields:   permission.AllowedFields,
+			TenantID:        uuid.NullUUID{UUID: tenantID, Valid: true},
+			ReadFields:      permission.ReadFields,
+			WriteFields:     permission.WriteFields,
+			Scope:           permissionScope(permission.Scope),
+			FieldTransforms: transforms,
+		})
+		if err != nil {
+			return err
+		}
+		index.permissions[change.Name] = created.ID
+	}
+
+	return nil
+}
+
+// collectionAttributes drops the fields of a collection, which are compared one by one
+func collectionAttributes(collection SnapshotCollection) SnapshotCollection {
+	collection.Fields = nil
+	collection.Timestamps = flagUnlessOn(flagOn(collection.Timestamps))
+	collection.UserStamps = flagUnlessOn(flagOn(collection.UserStamps))
+	return collection
+}
+
+// flagUnlessOn returns a collection flag that is on by default as it is stored in a
+// snapshot: nil when it is on
+func flagUnlessOn(on bool) *bool {
+	if on {
+		return nil
+	}
+	return &on
+}
+
+// flagOn reads a collection flag that is on by default from a snapshot
+func flagOn(flag *bool) bool {
+	return flag == nil || *flag
+}
+
+// differentAttributes returns the JSON names of the attributes that differ between two
+// snapshot records, sorted. Values are compared in their JSON form, so numbers decoded from
+// YAML and JSON compare equal.
+func differentAttributes(current, desired interface{}) []string {
+	a, b := attributeMap(current), attributeMap(desired)
+
+	var differing []string
+	for key := range b {
+		if a[key] != b[key] {
+			differing = append(differing, key)
+		}
+	}
+	for key := range a {
+		if _, exists := b[key]; !exists {
+			differing = append(differing, key)
+		}
+	}
+	sort.Strings(differing)
+	return differing
+}
+
+// attributeMap renders each attribute of a record as JSON
+func attributeMap(record interface{}) map[string]string {
+	raw, _ := json.Marshal(record)
+	var fields map[string]json.RawMessage
+	json.Unmarshal(raw, &fields)
+
+	result := make(map[string]string, len(fields))
+	for key, value := range fields {
+		// Re-encoding normalizes the order of object keys
+		var decoded interface{}
+		json.Unmarshal(value, &decoded)
+		normalized, _ := json.Marshal(decoded)
+		result[key] = string(normalized)
+	}
+	return result
+}
+
+// roleListed reports whether a role of that name is in roles
+func roleListed(roles []*SnapshotRole, name string) bool {
+	for _, role := range roles {
+		if role.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// permissionKey identifies a permission within a tenant
+func permissionKey(permission SnapshotPermission) string {
+	return permission.Role + ":" + permission.Table + ":" + permission.Action
+}
+
+// decodeSnapshotJSON turns a JSONB column into a plain value for the snapshot
+func decodeSnapshotJSON(raw pqtype.NullRawMessage) interface{} {
+	if !raw.Valid || len(raw.RawMessage) == 0 {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(raw.RawMessage, &value); err != nil {
+		return nil
+	}
+	return value
+}
+
+// encodeSnapshotJSON turns a snapshot value into a JSONB column value
+func encodeSnapshotJSON(value interface{}) (pqtype.NullRawMessage, error) {
+	if value == nil {
+		return pqtype.NullRawMessage{}, nil
+	}
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return pqtype.NullRawMessage{}, fmt.Errorf("invalid JSON value: %w", err)
+	}
+	return pqtype.NullRawMessage{RawMessage: raw, Valid: true}, nil
+}
+
+// encodeFieldTransforms turns field transforms into their column value, null when there
+// are none
+func encodeFieldTransforms(transforms rbac.FieldTransforms) (pqtype.NullRawMessage, error) {
+	if len(transforms) == 0 {
+		return pqtype.NullRawMessage{}, nil
+	}
+	return encodeSnapshotJSON(transforms)
+}
+
+// snapshotFormat picks YAML or JSON from ?format= or the given content type header
+func snapshotFormat(c *gin.Context, header string) string {
+	if format := c.Query("format"); format != "" {
+		return strings.ToLower(format)
+	}
+	if strings.Contains(c.GetHeader(header), "yaml") {
+		return "yaml"
+	}
+	return "json"
+}
+
+// checkSchemaPermissions verifies the caller may perform every action on the snapshot tables
+func (h *ItemsHandler) checkSchemaPermissions(c *gin.Context, userID uuid.UUID, actions ...string) bool {
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	for _, table := range schemaSnapshotTables {
+		for _, action := range actions {
+			allowed, _, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, table, action)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+				return false
+			}
+			if !allowed {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions: " + action + " on " + table})
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// GetSchemaSnapshot handles GET /schema/snapshot requests.
+//
+// Exports the collections, fields, roles and permissions of the caller's tenant. The body
+// is the snapshot itself, so it can be saved to a file and applied elsewhere.
+//
+// Response Format:
+//   - 200: The snapshot as JSON, or YAML with ?format=yaml or Accept: application/yaml
+//   - 400: Unknown format
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on collections, fields, roles or permissions
+//
+// @Summary      Export the schema
+// @Tags         schema
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Export the tenant's collections, fields, roles and permissions as a snapshot that /schema/apply accepts.
+// @Param        format  query  string false "json (default) or yaml"
+// @Produce      json
+// @Produce      application/yaml
+// @Success      200 {object} api.SchemaSnapshot
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /schema/snapshot [get]
+func (h *ItemsHandler) GetSchemaSnapshot(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	format := snapshotFormat(c, "Accept")
+	if format != "json" && format != "yaml" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be json or yaml"})
+		return
+	}
+
+	if !h.checkSchemaPermissions(c, userID, "read") {
+		return
+	}
+
+	snapshot, err := h.schemaHandlers.Snapshot(c.Request.Context(), userID)
+	if err != nil {
+		respondError(c, err, "Failed to export schema")
+		return
+	}
+
+	if format == "yaml" {
+		body, err := snapshot.Encode("yaml")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to encode schema"})
+			return
+		}
+		c.Data(http.StatusOK, "application/yaml", body)
+		return
+	}
+	c.JSON(http.StatusOK, snapshot)
+}
+
+// ApplySchemaSnapshot handles POST /schema/apply requests.
+//
+// Applies a snapshot to the caller's tenant: missing roles, collections, fields and
+// permissions are created and differing ones updated. Nothing is deleted. With
+// ?dry_run=true the changes are only listed.
+//
+// Response Format:
+//   - 200: {"data": {"changes": [...]}, "meta": {"dry_run": bool, "count": n}}
+//   - 400: Invalid snapshot
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks create or update permission on collections, fields, roles or permissions
+//   - 500: A change failed; the response lists the changes applied before it
+//
+// @Summary      Apply a schema snapshot
+// @Tags         schema
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Create or update collections, fields, roles and permissions to match a snapshot. Send YAML with Content-Type: application/yaml.
+// @Param        dry_run  query  bool   false "Only report the changes"
+// @Param        body     body   api.SchemaSnapshot true "Snapshot from /schema/snapshot"
+// @Accept       json
+// @Accept       application/yaml
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      500 {object} models.ErrorResponse
+// @Router       /schema/apply [post]
+func (h *ItemsHandler) ApplySchemaSnapshot(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	dryRun := c.Query("dry_run") == "true"
+	actions := []string{"create", "update"}
+	if dryRun {
+		actions = []string{"read"}
+	}
+	if !h.checkSchemaPermissions(c, userID, actions...) {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	snapshot, err := ParseSchemaSnapshot(body, snapshotFormat(c, "Content-Type"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	changes, err := h.schemaHandlers.ApplySnapshot(c.Request.Context(), userID, snapshot, dryRun)
+	if changes == nil {
+		changes = []SchemaChange{}
+	}
+	if err != nil {
+		status := http.StatusInternalServerError
+		if strings.HasPrefix(err.Error(), "invalid snapshot") {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, gin.H{"error": err.Error(), "data": gin.H{"changes": changes}})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": gin.H{"changes": changes},
+		"meta": gin.H{"dry_run": dryRun, "count": len(changes)},
+	})
+}
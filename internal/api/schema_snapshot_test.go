@@ -0,0 +1,110 @@
+package api
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSnapshotYAML = `
+version: 1
+roles:
+  - name: auditor
+    parent: reviewer
+  - name: reviewer
+    description: Reviews content
+collections:
+  - slug: products
+    name: products
+    display_name: Products
+    fields:
+      - name: price
+        type: number
+        is_required: true
+        validation_rules:
+          min: 0
+permissions:
+  - role: auditor
+    table: products
+    action: read
+    allowed_fields: ["*"]
+`
+
+func TestParseSchemaSnapshot(t *testing.T) {
+	snapshot, err := ParseSchemaSnapshot([]byte(testSnapshotYAML), "yaml")
+	require.NoError(t, err)
+	require.Len(t, snapshot.Collections, 1)
+	assert.Equal(t, "price", snapshot.Collections[0].Fields[0].Name)
+	assert.Equal(t, []string{"*"}, snapshot.Permissions[0].AllowedFields)
+
+	// JSON round trip
+	body, err := snapshot.Encode("json")
+	require.NoError(t, err)
+	decoded, err := ParseSchemaSnapshot(body, "json")
+	require.NoError(t, err)
+	assert.Empty(t, diffSchema(decoded, snapshot))
+
+	invalid := map[string]string{
+		"unsupported version": `{"version": 2}`,
+		"invalid action":      `{"version": 1, "permissions": [{"role": "a", "table": "t", "action": "write"}]}`,
+		"duplicate field":     `{"version": 1, "collections": [{"slug": "p", "name": "p", "fields": [{"name": "x", "type": "text"}, {"name": "x", "type": "text"}]}]}`,
+		"collections need":    `{"version": 1, "collections": [{"name": "p"}]}`,
+		"goes without":        `{"version": 1, "collections": [{"slug": "p", "name": "p", "user_stamps": false}], "permissions": [{"role": "a", "table": "p", "action": "update", "scope": "own"}]}`,
+	}
+	for message, body := range invalid {
+		_, err := ParseSchemaSnapshot([]byte(body), "json")
+		assert.ErrorContains(t, err, message)
+	}
+}
+
+func TestDiffSchema(t *testing.T) {
+	desired, err := ParseSchemaSnapshot([]byte(testSnapshotYAML), "yaml")
+	require.NoError(t, err)
+
+	// Against an empty tenant everything is created, parents before children
+	changes := diffSchema(&SchemaSnapshot{Version: 1}, desired)
+	var names []string
+	for _, change := range changes {
+		assert.Equal(t, "create", change.Action)
+		names = append(names, change.Kind+" "+change.Name)
+	}
+	assert.Equal(t, []string{
+		"role reviewer",
+		"role auditor",
+		"collection products",
+		"field products.price",
+		"permission auditor:products:read",
+	}, names)
+
+	// Values decoded from the database (JSON) equal those from YAML
+	current, err := ParseSchemaSnapshot([]byte(`{
+		"version": 1,
+		"roles": [{"name": "auditor", "parent": "reviewer"}, {"name": "reviewer", "description": "Reviews content"}],
+		"collections": [{"slug": "products", "name": "products", "display_name": "Items",
+			"fields": [{"name": "price", "type": "number", "is_required": true, "validation_rules": {"min": 0.0}}]}],
+		"permissions": [{"role": "auditor", "table": "products", "action": "read", "allowed_fields": ["id"]}]
+	}`), "json")
+	require.NoError(t, err)
+
+	changes = diffSchema(current, desired)
+	require.Len(t, changes, 2)
+	assert.Equal(t, SchemaChange{Action: "update", Kind: "collection", Name: "products", Fields: []string{"display_name"}}, withoutTargets(changes[0]))
+	assert.Equal(t, SchemaChange{Action: "update", Kind: "permission", Name: "auditor:products:read", Fields: []string{"allowed_fields"}}, withoutTargets(changes[1]))
+
+	// Applying the snapshot to itself changes nothing
+	assert.Empty(t, diffSchema(desired, desired))
+}
+
+func TestCheckPermissionRoles(t *testing.T) {
+	desired := &SchemaSnapshot{Version: 1, Permissions: []SnapshotPermission{{Role: "ghost", Table: "products", Action: "read"}}}
+	assert.ErrorContains(t, checkPermissionRoles(&SchemaSnapshot{}, desired), "role ghost")
+
+	current := &SchemaSnapshot{Roles: []SnapshotRole{{Name: "ghost"}}}
+	assert.NoError(t, checkPermissionRoles(current, desired))
+}
+
+// withoutTargets clears the unexported pointers so changes can be compared
+func withoutTargets(change SchemaChange) SchemaChange {
+	return SchemaChange{Action: change.Action, Kind: change.Kind, Name: change.Name, Fields: change.Fields}
+}
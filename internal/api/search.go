@@ -0,0 +1,411 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains full-text search over the text fields of collections: the ?search=
+// parameter of GET /items/:table and the global GET /search endpoint.
+//
+// A collection's search document is the string and text fields of its items, joined in
+// field name order and parsed with the "simple" text search configuration. Each collection
+// keeps a GIN index over that document, named search_<collection id>_idx, which is rebuilt
+// whenever a searchable field is added, changed or removed.
+//
+// Queries use websearch_to_tsquery syntax: words must all match, "quoted phrases" match in
+// order, "or" gives alternatives and a leading - excludes a word. Callers only search the
+// fields they may read in clear; when those are fewer than all searchable fields the
+// document is built from the readable fields alone and the index cannot be used.
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// searchConfig is the text search configuration of search documents and queries. The
+// "simple" configuration lowercases words without stemming, so it suits any language.
+const searchConfig = "simple"
+
+const (
+	defaultSearchLimit = 5  // Hits per collection returned by GET /search by default
+	maxSearchLimit     = 50 // Most hits per collection a client may request
+)
+
+// Columns GET /search adds to each hit row, named so no field can clash with them
+const (
+	searchRankColumn       = "__search_rank"
+	searchHighlightsColumn = "__search_highlights"
+	searchTotalColumn      = "__search_total"
+	searchQueryAlias       = "__search_query"
+)
+
+// isSearchableField reports whether fields of fieldType are part of the search document
+func isSearchableField(fieldType string) bool {
+	return fieldType == "string" || fieldType == "text"
+}
+
+// searchColumns returns the columns of the searchable fields of a collection in name order.
+// With allowedFields other than "*", only the fields listed there are returned. Fields the
+// caller reads redacted are left out, so searches cannot probe their values.
+func searchColumns(fields []sqlc.Field, allowedFields []string, transforms rbac.FieldTransforms) []string {
+	all := len(allowedFields) == 0 || Contains(allowedFields, "*")
+	var columns []string
+	for _, field := range fields {
+		if _, redacted := transforms[field.Name]; redacted {
+			continue
+		}
+		if isSearchableField(field.Type) && !field.IsEncrypted && (all || Contains(allowedFields, field.Name)) {
+			columns = append(columns, field.Name)
+		}
+	}
+	sort.Strings(columns)
+	return columns
+}
+
+// searchDocument returns the tsvector expression over columns. The search index is built
+// on this expression, so it must be written the same way wherever it is used.
+func searchDocument(columns []string) string {
+	parts := make([]string, len(columns))
+	for i, column := range columns {
+		parts[i] = fmt.Sprintf(`coalesce("%s", '')`, column)
+	}
+	return fmt.Sprintf("to_tsvector('%s', %s)", searchConfig, strings.Join(parts, ` || ' ' || `))
+}
+
+// errSearchUnsupported is returned when the database has no full-text search
+var errSearchUnsupported = errors.New("full-text search needs Postgres")
+
+// searchCondition returns the condition matching rows whose document over columns matches
+// the search text bound at paramIndex
+func searchCondition(columns []string, paramIndex int) string {
+	return fmt.Sprintf("%s @@ websearch_to_tsquery('%s', $%d)", searchDocument(columns), searchConfig, paramIndex)
+}
+
+// searchIndexName returns the name of the search index of a collection
+func searchIndexName(collectionID uuid.UUID) string {
+	return "search_" + strings.ReplaceAll(collectionID.String(), "-", "") + "_idx"
+}
+
+// dropSearchIndexStatement returns the statement dropping the search index of a collection
+// whose data table is in schema
+func dropSearchIndexStatement(schema string, collectionID uuid.UUID) string {
+	return fmt.Sprintf(`DROP INDEX IF EXISTS "%s"."%s"`, schema, searchIndexName(collectionID))
+}
+
+// searchIndexStatements returns the statements replacing the search index of a collection
+// with one over columns. Collections without searchable fields get no index.
+func searchIndexStatements(table DataTable, collectionID uuid.UUID, columns []string) []string {
+	statements := []string{dropSearchIndexStatement(table.Schema, collectionID)}
+	if len(columns) > 0 {
+		statements = append(statements, fmt.Sprintf(`CREATE INDEX "%s" ON %s USING gin (%s)`,
+			searchIndexName(collectionID), table, searchDocument(columns)))
+	}
+	return statements
+}
+
+// RefreshSearchIndex rebuilds the search index of a collection over its current searchable
+// fields. Collections without a data table, and databases without full-text search, are
+// left alone.
+func (u *ItemsUtils) RefreshSearchIndex(ctx context.Context, tenantID uuid.UUID, collection sqlc.Collection) error {
+	if !u.dialect().FullTextSearch() {
+		return nil
+	}
+	table, err := u.ResolveDataTable(ctx, tenantID, collection.Slug)
+	if errors.Is(err, ErrDataTableNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fields, err := u.queries().GetFieldsByCollection(ctx, uuid.NullUUID{UUID: collection.ID, Valid: true})
+	if err != nil {
+		return err
+	}
+
+	return u.transaction(ctx, func(utils *ItemsUtils) error {
+		for _, statement := range searchIndexStatements(table, collection.ID, searchColumns(fields, nil, nil)) {
+			if _, err := utils.tx.ExecContext(ctx, statement); err != nil {
+				return fmt.Errorf("failed to build search index: %w", err)
+			}
+		}
+		return nil
+	})
+}
+
+// refreshSearchIndex rebuilds the search index of the collection a searchable field belongs
+// to after the field changed. Search keeps working without the index, only slower, so a
+// failure is logged rather than undoing the field change.
+func (s *SchemaHandlers) refreshSearchIndex(ctx context.Context, tenantID uuid.UUID, collectionID uuid.NullUUID) {
+	if !collectionID.Valid {
+		return
+	}
+	collection, err := s.handler.db.Queries.GetCollection(ctx, collectionID.UUID)
+	if err != nil || collection.IsSystem.Bool {
+		return
+	}
+	if err := s.utils.RefreshSearchIndex(ctx, tenantID, collection); err != nil {
+		slog.Warn("failed to rebuild search index", "tenant_id", tenantID, "collection", collection.Slug, "error", err)
+	}
+}
+
+// buildSearchQuery returns the query for the best matching rows of table, ranked by how
+// well they match the search text in $1. selectFields are the columns to return, all when
+// it holds "*"; the result also has the rank, the total number of matches and the
+// highlighted matching columns. conditions must number their parameters from $2.
+func buildSearchQuery(table string, selectFields, columns, conditions []string, limit int) string {
+	selection := table + ".*"
+	if !Contains(selectFields, "*") {
+		quoted := make([]string, len(selectFields))
+		for i, field := range selectFields {
+			quoted[i] = fmt.Sprintf(`%s."%s"`, table, field)
+		}
+		selection = strings.Join(quoted, ", ")
+	}
+
+	// Highlights of the columns that match on their own
+	highlights := make([]string, len(columns))
+	for i, column := range columns {
+		highlights[i] = fmt.Sprintf(`'%s', CASE WHEN %s @@ %s THEN ts_headline('%s', "%s", %s, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2') END`,
+			column, searchDocument([]string{column}), searchQueryAlias, searchConfig, column, searchQueryAlias)
+	}
+
+	document := searchDocument(columns)
+	where := append([]string{fmt.Sprintf("%s @@ %s", document, searchQueryAlias)}, conditions...)
+
+	return fmt.Sprintf(`SELECT %s, ts_rank(%s, %s) AS "%s", jsonb_strip_nulls(jsonb_build_object(%s)) AS "%s", count(*) OVER () AS "%s"
+		FROM %s, websearch_to_tsquery('%s', $1) AS %s
+		WHERE %s
+		ORDER BY "%s" DESC, %s."id"
+		LIMIT %d`,
+		selection, document, searchQueryAlias, searchRankColumn, strings.Join(highlights, ", "), searchHighlightsColumn, searchTotalColumn,
+		table, searchConfig, searchQueryAlias,
+		strings.Join(where, " AND "),
+		searchRankColumn, table,
+		limit)
+}
+
+// SearchHit is one matching item in GET /search results
+type SearchHit struct {
+	ID         interface{}            `json:"id"`
+	Rank       float64                `json:"rank"`
+	Highlights map[string]string      `json:"highlights"`
+	Item       map[string]interface{} `json:"item"`
+}
+
+// SearchGroup holds the hits of one collection in GET /search results
+type SearchGroup struct {
+	Collection string      `json:"collection"`
+	Name       string      `json:"name"`
+	Total      int64       `json:"total"`
+	Hits       []SearchHit `json:"hits"`
+}
+
+// searchHit turns a row of a search query into a hit, keeping only allowedFields of the item
+// and redacting it by transforms
+func (h *ItemsHandler) searchHit(row map[string]interface{}, allowedFields []string, transforms rbac.FieldTransforms) SearchHit {
+	hit := SearchHit{ID: row["id"], Highlights: map[string]string{}}
+
+	if rank, ok := row[searchRankColumn].(float64); ok {
+		hit.Rank = rank
+	}
+	// ScanRowsToMaps has already decoded the highlights object
+	if highlights, ok := row[searchHighlightsColumn].(map[string]interface{}); ok {
+		for column, fragment := range highlights {
+			hit.Highlights[column] = fmt.Sprint(fragment)
+		}
+	}
+
+	delete(row, searchRankColumn)
+	delete(row, searchHighlightsColumn)
+	delete(row, searchTotalColumn)
+	hit.Item = h.policyChecker.FilterFields(row, allowedFields, transforms)
+	return hit
+}
+
+// searchTotal reads the total number of matches from the first row of a search query
+func searchTotal(rows []map[string]interface{}) int64 {
+	if len(rows) == 0 {
+		return 0
+	}
+	total, _ := strconv.ParseInt(fmt.Sprint(rows[0][searchTotalColumn]), 10, 64)
+	return total
+}
+
+// searchCollection runs a search over one collection for the caller. It returns nil when the
+// caller may not read the collection, or may read none of its searchable fields.
+func (h *ItemsHandler) searchCollection(ctx context.Context, userID, tenantID uuid.UUID, collection sqlc.Collection, text string, limit int) (*SearchGroup, error) {
+	allowed, allowedFields, rowFilter, transforms, err := h.policyChecker.CheckPermissionWithTransforms(ctx, userID, collection.Slug, "read")
+	if err != nil {
+		return nil, fmt.Errorf("failed to check permissions: %w", err)
+	}
+	if !allowed {
+		return nil, nil
+	}
+
+	fields, err := h.db.Queries.GetFieldsByCollection(ctx, uuid.NullUUID{UUID: collection.ID, Valid: true})
+	if err != nil {
+		return nil, err
+	}
+	columns := searchColumns(fields, allowedFields, transforms)
+	if len(columns) == 0 {
+		return nil, nil
+	}
+
+	table, err := h.utils.ResolveDataTable(ctx, tenantID, collection.Slug)
+	if errors.Is(err, ErrDataTableNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// Hide the trash and rows outside the caller's row-level rules
+	var conditions []string
+	if collection.SoftDelete {
+		conditions = append(conditions, "deleted_at IS NULL")
+	}
+	ruleCondition, params := rowFilter.SQL(2)
+	if ruleCondition != "" {
+		conditions = append(conditions, ruleCondition)
+	}
+
+	selectFields := allowedFields
+	if len(selectFields) == 0 {
+		selectFields = []string{"*"}
+	} else if !Contains(selectFields, "*") && !Contains(selectFields, "id") {
+		selectFields = append([]string{"id"}, selectFields...)
+	}
+
+	query := buildSearchQuery(table.String(), selectFields, columns, conditions, limit)
+	rows, err := h.db.QueryContext(ctx, query, append([]interface{}{text}, params...)...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search %s: %w", collection.Slug, err)
+	}
+	defer rows.Close()
+	results := h.utils.ScanRowsToMaps(rows)
+	if err := revealEncrypted(ctx, h.dynamicHandlers, h.policyChecker, userID, tenantID, collection.Slug, results); err != nil {
+		return nil, err
+	}
+
+	group := &SearchGroup{
+		Collection: collection.Slug,
+		Name:       collection.Name,
+		Total:      searchTotal(results),
+		Hits:       make([]SearchHit, 0, len(results)),
+	}
+	for _, row := range results {
+		group.Hits = append(group.Hits, h.searchHit(row, allowedFields, transforms))
+	}
+	return group, nil
+}
+
+// Search handles GET /search requests.
+//
+// Searches the text fields of every collection the caller may read and returns the best
+// matching items of each, grouped by collection. Collections without matches are left out.
+//
+// Query Parameters:
+//   - q: Search text in websearch syntax (required)
+//   - collections: Comma-separated collection slugs to limit the search to
+//   - limit: Hits per collection (default 5, max 50)
+//
+// Response Format:
+//   - 200: {"data": [{"collection", "name", "total", "hits": [{"id", "rank", "highlights", "item"}]}]}
+//   - 400: Missing search text
+//   - 401: Missing or invalid authentication token
+//
+// @Summary      Search across collections
+// @Tags         search
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Full-text search over the string and text fields of all readable collections, with hits grouped by collection and matches highlighted with <mark>.
+// @Param        q           query string true  "Search text; supports \"phrases\", or, and -exclusions"
+// @Param        collections query string false "Comma-separated collection slugs to search"
+// @Param        limit       query int    false "Hits per collection (max 50, default 5)"
+// @Produce      json
+// @Success      200 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Router       /search [get]
+func (h *ItemsHandler) Search(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	text := strings.TrimSpace(c.Query("q"))
+	if text == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search text (q) is required"})
+		return
+	}
+	if !h.db.Dialect().FullTextSearch() {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errSearchUnsupported.Error()})
+		return
+	}
+
+	limit := defaultSearchLimit
+	if v := c.Query("limit"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 && n <= maxSearchLimit {
+			limit = n
+		}
+	}
+
+	var only map[string]bool
+	if v := c.Query("collections"); v != "" {
+		only = make(map[string]bool)
+		for _, slug := range strings.Split(v, ",") {
+			only[strings.TrimSpace(slug)] = true
+		}
+	}
+
+	userTenantID, err := h.utils.GetUserTenantID(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get user tenant"})
+		return
+	}
+	collections, err := h.db.Queries.GetCollectionsByTenant(c.Request.Context(), uuid.NullUUID{UUID: userTenantID, Valid: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list collections"})
+		return
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	groups := []SearchGroup{}
+	var totalHits int64
+	for _, collection := range collections {
+		if collection.IsSystem.Bool || (only != nil && !only[collection.Slug]) {
+			continue
+		}
+		group, err := h.searchCollection(ctxWithTenant, userID, userTenantID, collection, text, limit)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search collections"})
+			return
+		}
+		if group == nil || group.Total == 0 {
+			continue
+		}
+		groups = append(groups, *group)
+		totalHits += group.Total
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"data": groups,
+		"meta": gin.H{
+			"query":       text,
+			"collections": len(groups),
+			"total":       totalHits,
+		},
+	})
+}
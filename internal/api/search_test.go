@@ -0,0 +1,95 @@
+package api
+
+import (
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSearchColumns(t *testing.T) {
+	fields := []sqlc.Field{
+		{Name: "title", Type: "string"},
+		{Name: "price", Type: "number"},
+		{Name: "body", Type: "text"},
+		{Name: "meta", Type: "json"},
+		{Name: "author", Type: "string"},
+	}
+
+	assert.Equal(t, []string{"author", "body", "title"}, searchColumns(fields, nil, nil))
+	assert.Equal(t, []string{"author", "body", "title"}, searchColumns(fields, []string{"*"}, nil))
+	assert.Equal(t, []string{"title"}, searchColumns(fields, []string{"id", "title", "price"}, nil))
+	assert.Empty(t, searchColumns(fields, []string{"id", "price"}, nil))
+	assert.Equal(t, []string{"author", "title"}, searchColumns(fields, []string{"*"}, rbac.FieldTransforms{"body": rbac.TransformHash}), "redacted fields are not searched")
+}
+
+func TestSearchDocument(t *testing.T) {
+	assert.Equal(t,
+		`to_tsvector('simple', coalesce("body", '') || ' ' || coalesce("title", ''))`,
+		searchDocument([]string{"body", "title"}))
+	assert.Equal(t,
+		`to_tsvector('simple', coalesce("title", '')) @@ websearch_to_tsquery('simple', $3)`,
+		searchCondition([]string{"title"}, 3))
+}
+
+func TestSearchIndexStatements(t *testing.T) {
+	collectionID := uuid.MustParse("5f0c6d1e-8a77-4c39-a1e4-1b2c3d4e5f60")
+	table := DataTable{Schema: "acme", Name: "data_posts", Layout: LayoutTenantSchema}
+
+	t.Run("Searchable Fields", func(t *testing.T) {
+		statements := searchIndexStatements(table, collectionID, []string{"body", "title"})
+		require.Len(t, statements, 2)
+		assert.Equal(t, `DROP INDEX IF EXISTS "acme"."search_5f0c6d1e8a774c39a1e41b2c3d4e5f60_idx"`, statements[0])
+		assert.Equal(t,
+			`CREATE INDEX "search_5f0c6d1e8a774c39a1e41b2c3d4e5f60_idx" ON "acme"."data_posts" USING gin (`+searchDocument([]string{"body", "title"})+`)`,
+			statements[1])
+	})
+
+	t.Run("No Searchable Fields", func(t *testing.T) {
+		statements := searchIndexStatements(table, collectionID, nil)
+		assert.Equal(t, []string{`DROP INDEX IF EXISTS "acme"."search_5f0c6d1e8a774c39a1e41b2c3d4e5f60_idx"`}, statements)
+	})
+}
+
+func TestBuildSearchQuery(t *testing.T) {
+	t.Run("All Fields", func(t *testing.T) {
+		query := buildSearchQuery(`"acme"."data_posts"`, []string{"*"}, []string{"body", "title"}, []string{"deleted_at IS NULL"}, 5)
+		assert.Contains(t, query, `SELECT "acme"."data_posts".*, ts_rank(`)
+		assert.Contains(t, query, `FROM "acme"."data_posts", websearch_to_tsquery('simple', $1) AS __search_query`)
+		assert.Contains(t, query, searchDocument([]string{"body", "title"})+" @@ __search_query AND deleted_at IS NULL")
+		assert.Contains(t, query, `'title', CASE WHEN `+searchDocument([]string{"title"})+` @@ __search_query THEN ts_headline(`)
+		assert.Contains(t, query, `ORDER BY "__search_rank" DESC, "acme"."data_posts"."id"`)
+		assert.Contains(t, query, "LIMIT 5")
+	})
+
+	t.Run("Allowed Fields", func(t *testing.T) {
+		query := buildSearchQuery(`"acme"."data_posts"`, []string{"id", "title"}, []string{"title"}, nil, 10)
+		assert.Contains(t, query, `SELECT "acme"."data_posts"."id", "acme"."data_posts"."title", ts_rank(`)
+		assert.NotContains(t, query, `"body"`)
+	})
+}
+
+func TestSearchHit(t *testing.T) {
+	h := &ItemsHandler{policyChecker: rbac.NewPolicyChecker(nil)}
+	rows := []map[string]interface{}{{
+		"id":                   "a1",
+		"title":                "Red shoes",
+		"body":                 "hidden",
+		searchRankColumn:       0.25,
+		searchHighlightsColumn: map[string]interface{}{"title": "<mark>Red</mark> shoes"},
+		searchTotalColumn:      int64(12),
+	}}
+
+	assert.Equal(t, int64(12), searchTotal(rows))
+	assert.Equal(t, int64(0), searchTotal(nil))
+
+	hit := h.searchHit(rows[0], []string{"id", "title"}, nil)
+	assert.Equal(t, "a1", hit.ID)
+	assert.Equal(t, 0.25, hit.Rank)
+	assert.Equal(t, map[string]string{"title": "<mark>Red</mark> shoes"}, hit.Highlights)
+	assert.Equal(t, map[string]interface{}{"id": "a1", "title": "Red shoes"}, hit.Item)
+}
@@ -0,0 +1,618 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains service accounts, the machine users integrations act as.
+//
+// Service Account Endpoints:
+// - GET    /service-accounts          - List the tenant's service accounts with their roles and keys
+// - POST   /service-accounts          - Create a service account
+// - GET    /service-accounts/:id      - Get one service account
+// - PATCH  /service-accounts/:id      - Rename, (de)activate or change the roles of an account
+// - DELETE /service-accounts/:id      - Delete an account and its keys
+// - POST   /service-accounts/:id/keys - Issue an API key for an account
+//
+// A service account is a user flagged is_service_account, so its roles, API keys and the
+// created_by of the items it writes work like a person's, but it belongs to the tenant
+// rather than an employee. It has no usable password: password logins, password resets
+// and identity provider sign ins are refused, and it only authenticates with its keys.
+// Its keys do not expire unless asked to; instead SERVICE_ACCOUNT_KEY_ROTATION sets how
+// long one may go without rotation before an api_key.rotation_due event reminds to rotate
+// it (POST /items/api_keys/:id/rotate).
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/apikeys"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/oauth"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+// serviceAccountDomain is the domain of the generated email addresses of service
+// accounts; .invalid is reserved (RFC 2606), so no mail can reach them
+const serviceAccountDomain = "service-account.invalid"
+
+// serviceAccountSlugPattern matches the runs of characters left out of generated emails
+var serviceAccountSlugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// serviceAccountRequest is the body of POST and PATCH /service-accounts
+type serviceAccountRequest struct {
+	Name     *string  `json:"name"`
+	Roles    []string `json:"roles"`     // Names of the tenant's roles; omitted keeps them on PATCH
+	IsActive *bool    `json:"is_active"` // PATCH only
+}
+
+// serviceAccountEmail generates the unique email address of a service account
+func serviceAccountEmail(name string, id uuid.UUID) string {
+	slug := strings.Trim(serviceAccountSlugPattern.ReplaceAllString(strings.ToLower(name), "-"), "-")
+	if len(slug) > 40 {
+		slug = strings.TrimRight(slug[:40], "-")
+	}
+	if slug == "" {
+		slug = "service-account"
+	}
+	return fmt.Sprintf("%s.%s@%s", slug, strings.ReplaceAll(id.String(), "-", "")[:8], serviceAccountDomain)
+}
+
+// checkServiceAccountPermission answers with 403 unless the user may perform action on
+// table in the request's tenant
+func (h *ItemsHandler) checkServiceAccountPermission(c *gin.Context, userID uuid.UUID, table, action string) bool {
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+
+	allowed, _, err := h.policyChecker.CheckPermission(ctxWithTenant, userID, table, action)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check permissions"})
+		return false
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions: " + action + " on " + table})
+		return false
+	}
+	return true
+}
+
+// loadServiceAccount returns the service account of the :id parameter, answering with 404
+// for users that are not service accounts of the request's tenant
+func (h *ItemsHandler) loadServiceAccount(c *gin.Context) (sqlc.User, bool) {
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid service account ID"})
+		return sqlc.User{}, false
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	account, err := h.db.Queries.GetUserByID(c.Request.Context(), accountID)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service account"})
+		return sqlc.User{}, false
+	}
+	if err != nil || !account.IsServiceAccount || account.TenantID.UUID != tenantID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Service account not found"})
+		return sqlc.User{}, false
+	}
+	return account, true
+}
+
+// canManageAdmin reports whether the caller may give or take away the admin role
+func canManageAdmin(c *gin.Context) bool {
+	auth, ok := middleware.GetAuthProvider(c)
+	return ok && (auth.IsAdmin || auth.IsSuperAdmin)
+}
+
+// resolveServiceAccountRoles looks up the named roles of the tenant. Only admins may hand
+// out the admin role.
+func (h *ItemsHandler) resolveServiceAccountRoles(c *gin.Context, tenantID uuid.UUID, names []string) ([]sqlc.Role, error) {
+	roles := make([]sqlc.Role, 0, len(names))
+	for _, name := range names {
+		role, err := h.db.Queries.GetRoleByNameAndTenant(c.Request.Context(), sqlc.GetRoleByNameAndTenantParams{
+			Name:     name,
+			TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, validationError("unknown role %q", name)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if role.Name == "admin" && !canManageAdmin(c) {
+			return nil, forbiddenError("only admins can grant the admin role")
+		}
+		roles = append(roles, role)
+	}
+	return roles, nil
+}
+
+// serviceAccountResponse loads the roles and keys of an account
+func (h *ItemsHandler) serviceAccountResponse(ctx context.Context, account sqlc.User) (models.ServiceAccount, error) {
+	roles, err := h.db.Queries.GetUserTenantRoles(ctx, sqlc.GetUserTenantRolesParams{
+		UserID:   account.ID,
+		TenantID: account.TenantID.UUID,
+	})
+	if err != nil {
+		return models.ServiceAccount{}, err
+	}
+	keys, err := h.db.Queries.GetAPIKeysByUser(ctx, account.ID)
+	if err != nil {
+		return models.ServiceAccount{}, err
+	}
+
+	response := models.ServiceAccount{
+		ID:        account.ID,
+		Name:      account.FirstName.String,
+		Email:     account.Email,
+		IsActive:  account.IsActive.Bool,
+		Roles:     make([]string, 0, len(roles)),
+		Keys:      make([]models.ServiceAccountKey, 0, len(keys)),
+		CreatedAt: account.CreatedAt.Time,
+		UpdatedAt: account.UpdatedAt.Time,
+	}
+	for _, role := range roles {
+		response.Roles = append(response.Roles, role.Name)
+	}
+	for _, key := range keys {
+		response.Keys = append(response.Keys, serviceAccountKey(key, h.cfg.ServiceAccountKeyRotation))
+	}
+	return response, nil
+}
+
+// serviceAccountKey converts a key of a service account to its API representation, with
+// the time it is due for rotation under the rotation period (none when 0)
+func serviceAccountKey(key sqlc.ApiKey, rotation time.Duration) models.ServiceAccountKey {
+	response := models.ServiceAccountKey{
+		ID:         key.ID,
+		Name:       key.Name,
+		IsActive:   key.IsActive.Bool,
+		Scopes:     scopesOrEmpty(key.Scopes),
+		ExpiresAt:  nullTimePtr(key.ExpiresAt),
+		LastUsedAt: nullTimePtr(key.LastUsedAt),
+		RotatedAt:  nullTimePtr(key.RotatedAt),
+		CreatedAt:  key.CreatedAt.Time,
+	}
+	if rotation > 0 {
+		due := apikeys.LastRotatedAt(key).Add(rotation)
+		response.RotationDueAt = &due
+	}
+	return response
+}
+
+// ListServiceAccounts handles GET /service-accounts requests.
+//
+// Response Format:
+//   - 200: {"data": [{"id": "...", "name": "...", "roles": [...], "keys": [...], ...}]}
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks read permission on users
+//
+// @Summary      List service accounts
+// @Tags         service-accounts
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Produce      json
+// @Success      200 {array}  models.ServiceAccount
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Router       /service-accounts [get]
+func (h *ItemsHandler) ListServiceAccounts(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if !h.checkServiceAccountPermission(c, userID, "users", "read") {
+		return
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	accounts, err := h.db.Queries.ListServiceAccounts(c.Request.Context(), uuid.NullUUID{UUID: tenantID, Valid: true})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list service accounts"})
+		return
+	}
+
+	response := make([]models.ServiceAccount, 0, len(accounts))
+	for _, account := range accounts {
+		item, err := h.serviceAccountResponse(c.Request.Context(), account)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list service accounts"})
+			return
+		}
+		response = append(response, item)
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// CreateServiceAccount handles POST /service-accounts requests.
+//
+// Creates a service account of the request's tenant with the named roles. The account gets
+// a generated email address and no usable password; issue it a key with
+// POST /service-accounts/:id/keys.
+//
+// Response Format:
+//   - 201: {"data": {"id": "...", "name": "...", "email": "...", "roles": [...], "keys": []}}
+//   - 400: Invalid request body
+//   - 401: Missing or invalid authentication token
+//   - 403: User lacks create permission on users, or grants admin without being an admin
+//   - 422: Missing name or unknown role
+//
+// @Summary      Create a service account
+// @Tags         service-accounts
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Body: {"name": "Importer", "roles": ["editor"]}
+// @Param        body body map[string]interface{} true "Name and roles"
+// @Accept       json
+// @Produce      json
+// @Success      201 {object} models.ServiceAccount
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      401 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
+// @Router       /service-accounts [post]
+func (h *ItemsHandler) CreateServiceAccount(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req serviceAccountRequest
+	if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	if req.Name == nil || strings.TrimSpace(*req.Name) == "" {
+		respondError(c, validationError("name is required"), "Invalid service account")
+		return
+	}
+	if !h.checkServiceAccountPermission(c, userID, "users", "create") {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID, _ := middleware.GetTenantID(c)
+	roles, err := h.resolveServiceAccountRoles(c, tenantID, req.Roles)
+	if err != nil {
+		respondError(c, err, "Invalid service account")
+		return
+	}
+
+	// The password is random and never revealed, so it cannot be used
+	passwordHash, err := models.HashPassword(oauth.NewVerifier())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service account"})
+		return
+	}
+
+	name := strings.TrimSpace(*req.Name)
+	accountID := uuid.New()
+	var account sqlc.User
+	err = h.db.InTransaction(ctx, func(tx *db.Tx) error {
+		if account, err = tx.CreateServiceAccount(ctx, sqlc.CreateServiceAccountParams{
+			ID:           accountID,
+			Email:        serviceAccountEmail(name, accountID),
+			PasswordHash: passwordHash,
+			FirstName:    sql.NullString{String: name, Valid: true},
+			TenantID:     uuid.NullUUID{UUID: tenantID, Valid: true},
+		}); err != nil {
+			return err
+		}
+
+		membership := sqlc.AddUserToTenantParams{UserID: accountID, TenantID: tenantID}
+		if len(roles) > 0 {
+			membership.RoleID = uuid.NullUUID{UUID: roles[0].ID, Valid: true}
+		}
+		if err := tx.AddUserToTenant(ctx, membership); err != nil {
+			return err
+		}
+		for _, role := range roles {
+			if err := tx.AddUserRole(ctx, sqlc.AddUserRoleParams{UserID: accountID, RoleID: role.ID}); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create service account"})
+		return
+	}
+
+	response, err := h.serviceAccountResponse(ctx, account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service account"})
+		return
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": response})
+}
+
+// GetServiceAccount handles GET /service-accounts/:id requests.
+//
+// @Summary      Get a service account
+// @Tags         service-accounts
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        id path string true "Service account ID"
+// @Produce      json
+// @Success      200 {object} models.ServiceAccount
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /service-accounts/{id} [get]
+func (h *ItemsHandler) GetServiceAccount(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if !h.checkServiceAccountPermission(c, userID, "users", "read") {
+		return
+	}
+	account, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	response, err := h.serviceAccountResponse(c.Request.Context(), account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service account"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// UpdateServiceAccount handles PATCH /service-accounts/:id requests.
+//
+// Renames the account, activates or deactivates it, or replaces its roles with the ones
+// named. A deactivated account's keys are rejected until it is activated again.
+//
+// Response Format:
+//   - 200: {"data": {...}} The updated account
+//   - 400: Invalid ID or request body
+//   - 403: User lacks update permission on users, or changes admin without being an admin
+//   - 404: No service account with that ID in the tenant
+//   - 422: Empty name or unknown role
+//
+// @Summary      Update a service account
+// @Tags         service-accounts
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Body: {"name": "...", "roles": ["viewer"], "is_active": false}, all optional
+// @Param        id   path string true "Service account ID"
+// @Param        body body map[string]interface{} true "Changes"
+// @Accept       json
+// @Produce      json
+// @Success      200 {object} models.ServiceAccount
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      422 {object} models.ErrorResponse
+// @Router       /service-accounts/{id} [patch]
+func (h *ItemsHandler) UpdateServiceAccount(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	var req serviceAccountRequest
+	if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	if req.Name != nil && strings.TrimSpace(*req.Name) == "" {
+		respondError(c, validationError("name cannot be empty"), "Invalid service account")
+		return
+	}
+	if !h.checkServiceAccountPermission(c, userID, "users", "update") {
+		return
+	}
+	account, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+
+	ctx := c.Request.Context()
+	tenantID := account.TenantID.UUID
+	var roles []sqlc.Role
+	var current []sqlc.Role
+	if req.Roles != nil {
+		var err error
+		if roles, err = h.resolveServiceAccountRoles(c, tenantID, req.Roles); err != nil {
+			respondError(c, err, "Invalid service account")
+			return
+		}
+		if current, err = h.db.Queries.GetUserTenantRoles(ctx, sqlc.GetUserTenantRolesParams{UserID: account.ID, TenantID: tenantID}); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load roles"})
+			return
+		}
+		// Taking admin away needs an admin as much as handing it out
+		for _, role := range current {
+			if role.Name == "admin" && !canManageAdmin(c) {
+				respondError(c, forbiddenError("only admins can change the roles of an admin"), "Invalid service account")
+				return
+			}
+		}
+	}
+
+	name := account.FirstName
+	if req.Name != nil {
+		name = sql.NullString{String: strings.TrimSpace(*req.Name), Valid: true}
+	}
+	isActive := account.IsActive
+	if req.IsActive != nil {
+		isActive = sql.NullBool{Bool: *req.IsActive, Valid: true}
+	}
+
+	err := h.db.InTransaction(ctx, func(tx *db.Tx) error {
+		var err error
+		if account, err = tx.UpdateUser(ctx, sqlc.UpdateUserParams{
+			ID:        account.ID,
+			Email:     account.Email,
+			FirstName: name,
+			LastName:  account.LastName,
+			IsActive:  isActive,
+		}); err != nil {
+			return err
+		}
+		if req.Roles == nil {
+			return nil
+		}
+
+		keep := make(map[uuid.UUID]bool, len(roles))
+		for _, role := range roles {
+			keep[role.ID] = true
+			if err := tx.AddUserRole(ctx, sqlc.AddUserRoleParams{UserID: account.ID, RoleID: role.ID}); err != nil {
+				return err
+			}
+		}
+		for _, role := range current {
+			// Global roles are not the tenant's to take away
+			if !keep[role.ID] && role.TenantID.Valid {
+				if err := tx.RemoveUserRole(ctx, sqlc.RemoveUserRoleParams{UserID: account.ID, RoleID: role.ID}); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update service account"})
+		return
+	}
+	rbac.InvalidateUserPermissions(account.ID)
+
+	response, err := h.serviceAccountResponse(ctx, account)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load service account"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"data": response})
+}
+
+// DeleteServiceAccount handles DELETE /service-accounts/:id requests. The account's keys
+// stop working at once; items it created keep its ID in created_by. Accounts that own
+// collections or webhooks cannot be deleted, only deactivated.
+//
+// @Summary      Delete a service account
+// @Tags         service-accounts
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Param        id path string true "Service account ID"
+// @Produce      json
+// @Success      200 {object} map[string]string
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Failure      409 {object} models.ErrorResponse
+// @Router       /service-accounts/{id} [delete]
+func (h *ItemsHandler) DeleteServiceAccount(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+	if !h.checkServiceAccountPermission(c, userID, "users", "delete") {
+		return
+	}
+	account, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+	if account.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A service account cannot delete itself"})
+		return
+	}
+
+	if err := h.db.Queries.DeleteUser(c.Request.Context(), account.ID); err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code.Name() == "foreign_key_violation" {
+			c.JSON(http.StatusConflict, gin.H{"error": "The service account still owns collections or webhooks; deactivate it instead"})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete service account"})
+		return
+	}
+	rbac.InvalidateUserPermissions(account.ID)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Service account deleted"})
+}
+
+// CreateServiceAccountKey handles POST /service-accounts/:id/keys requests.
+//
+// Issues an API key acting as the service account. The body is that of
+// POST /items/api_keys; without expires_at the key does not expire. The secret is only
+// returned here.
+//
+// Response Format:
+//   - 201: {"data": {"id": "...", "api_key": "...", "rotation_due_at": "...", ...}}
+//   - 400: Invalid ID, request body or scopes
+//   - 403: User lacks create permission on api_keys
+//   - 404: No service account with that ID in the tenant
+//   - 429: The tenant's API key limit is reached
+//
+// @Summary      Issue a service account key
+// @Tags         service-accounts
+// @Security     BearerAuth
+// @Security     ApiKeyAuth
+// @Description  Body: {"name": "...", "scopes": ["products:read"], "expires_at": "RFC 3339 time"}, all optional
+// @Param        id   path string true "Service account ID"
+// @Param        body body map[string]interface{} false "Key settings"
+// @Accept       json
+// @Produce      json
+// @Success      201 {object} map[string]interface{}
+// @Failure      400 {object} models.ErrorResponse
+// @Failure      403 {object} models.ErrorResponse
+// @Failure      404 {object} models.ErrorResponse
+// @Router       /service-accounts/{id}/keys [post]
+func (h *ItemsHandler) CreateServiceAccountKey(c *gin.Context) {
+	userID, exists := middleware.GetUserID(c)
+	if !exists {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "User not authenticated"})
+		return
+	}
+
+	data := map[string]interface{}{}
+	if c.Request.ContentLength > 0 {
+		if err := decodeJSON(c, &data, BodyAdmin); err != nil {
+			respondBodyError(c, err, "Invalid request body")
+			return
+		}
+	}
+	if !h.checkServiceAccountPermission(c, userID, "api_keys", "create") {
+		return
+	}
+	account, ok := h.loadServiceAccount(c)
+	if !ok {
+		return
+	}
+	data["user_id"] = account.ID.String()
+	if _, ok := data["name"].(string); !ok {
+		data["name"] = account.FirstName.String
+	}
+
+	tenantID, _ := middleware.GetTenantID(c)
+	ctxWithTenant := context.WithValue(c.Request.Context(), "tenant_id", tenantID)
+	result, err := h.schemaHandlers.CreateAPIKey(ctxWithTenant, userID, data)
+	if err != nil {
+		respondError(c, err, "Failed to create API key")
+		return
+	}
+	if h.cfg.ServiceAccountKeyRotation > 0 {
+		if createdAt, ok := result["created_at"].(time.Time); ok {
+			result["rotation_due_at"] = createdAt.Add(h.cfg.ServiceAccountKeyRotation)
+		}
+	}
+	c.JSON(http.StatusCreated, gin.H{"data": result})
+}
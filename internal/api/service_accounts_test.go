@@ -0,0 +1,48 @@
+package api
+
+import (
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServiceAccountEmail(t *testing.T) {
+	id := uuid.MustParse("0a1b2c3d-4e5f-6789-abcd-ef0123456789")
+
+	assert.Equal(t, "shop-sync.0a1b2c3d@service-account.invalid", serviceAccountEmail("  Shop Sync!  ", id))
+	assert.Equal(t, "service-account.0a1b2c3d@service-account.invalid", serviceAccountEmail("Привет", id))
+
+	long := serviceAccountEmail(strings.Repeat("importer ", 10), id)
+	local, _, _ := strings.Cut(long, "@")
+	assert.LessOrEqual(t, len(local), 40+9)
+	assert.False(t, strings.Contains(long, "-."), "no trailing dash before the id")
+}
+
+func TestServiceAccountKey(t *testing.T) {
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	key := sqlc.ApiKey{
+		ID:        uuid.New(),
+		Name:      "Importer",
+		IsActive:  sql.NullBool{Bool: true, Valid: true},
+		CreatedAt: sql.NullTime{Time: created, Valid: true},
+	}
+
+	response := serviceAccountKey(key, 90*24*time.Hour)
+	assert.Nil(t, response.ExpiresAt, "service account keys do not expire by default")
+	assert.Equal(t, []string{}, response.Scopes)
+	require.NotNil(t, response.RotationDueAt)
+	assert.Equal(t, created.Add(90*24*time.Hour), *response.RotationDueAt)
+
+	rotated := created.Add(60 * 24 * time.Hour)
+	key.RotatedAt = sql.NullTime{Time: rotated, Valid: true}
+	assert.Equal(t, rotated.Add(90*24*time.Hour), *serviceAccountKey(key, 90*24*time.Hour).RotationDueAt)
+
+	assert.Nil(t, serviceAccountKey(key, 0).RotationDueAt, "no due date without a rotation policy")
+}
@@ -0,0 +1,136 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains session management.
+//
+// Session Endpoints:
+// - POST   /auth/logout       - End the session of the request's token
+// - GET    /auth/sessions     - List the current user's active sessions
+// - DELETE /auth/sessions/:id - End one of the current user's sessions
+//
+// Every token belongs to a row of sessions. AuthMiddleware rejects the tokens of revoked
+// sessions, so logging out takes effect immediately instead of when the token expires.
+package api
+
+import (
+	"net/http"
+
+	"go-rbac-api/internal/audit"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// currentSessionID returns the session of the request's token; API keys have none
+func currentSessionID(c *gin.Context) (uuid.UUID, bool) {
+	auth, ok := middleware.GetAuthProvider(c)
+	if !ok || c.GetString("auth_type") != "jwt" {
+		return uuid.Nil, false
+	}
+	sessionID, err := uuid.Parse(auth.SessionID)
+	return sessionID, err == nil
+}
+
+// Logout handles POST /auth/logout requests
+// @Summary      Logout
+// @Tags         auth
+// @Produce      json
+// @Success      200   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /auth/logout [post]
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionID, ok := currentSessionID(c)
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only tokens from a login can be logged out"})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	if _, err := h.db.Queries.RevokeSession(c.Request.Context(), sqlc.RevokeSessionParams{ID: sessionID, UserID: userID}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionLogout)
+	entry.StatusCode = http.StatusOK
+	h.audit.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// ListSessions handles GET /auth/sessions requests
+// @Summary      List sessions
+// @Tags         auth
+// @Produce      json
+// @Success      200   {array}  models.Session
+// @Security     BearerAuth
+// @Router       /auth/sessions [get]
+func (h *AuthHandler) ListSessions(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	current, _ := currentSessionID(c)
+
+	sessions, err := h.db.Queries.ListUserSessions(c.Request.Context(), userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to list sessions"})
+		return
+	}
+
+	response := make([]models.Session, 0, len(sessions))
+	for _, session := range sessions {
+		item := models.Session{
+			ID:         session.ID,
+			UserAgent:  session.UserAgent.String,
+			IP:         session.Ip.String,
+			Current:    session.ID == current,
+			CreatedAt:  session.CreatedAt.Time,
+			LastSeenAt: session.LastSeenAt.Time,
+			ExpiresAt:  session.ExpiresAt,
+		}
+		if session.TenantID.Valid {
+			tenantID := session.TenantID.UUID
+			item.TenantID = &tenantID
+		}
+		response = append(response, item)
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// RevokeSession handles DELETE /auth/sessions/:id requests
+// @Summary      Revoke a session
+// @Tags         auth
+// @Produce      json
+// @Param        id    path   string true "Session ID"
+// @Success      200   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /auth/sessions/{id} [delete]
+func (h *AuthHandler) RevokeSession(c *gin.Context) {
+	sessionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid session ID"})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	// Only the user's own sessions can be revoked
+	revoked, err := h.db.Queries.RevokeSession(c.Request.Context(), sqlc.RevokeSessionParams{ID: sessionID, UserID: userID})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke session"})
+		return
+	}
+	if revoked == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Session not found"})
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionSessionRevoke)
+	entry.StatusCode = http.StatusOK
+	entry.Details = map[string]interface{}{"session_id": sessionID}
+	h.audit.Record(c.Request.Context(), entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Session revoked"})
+}
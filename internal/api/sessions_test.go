@@ -0,0 +1,73 @@
+package api
+
+import (
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/mail"
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCurrentSessionID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	sessionID := uuid.New()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	_, ok := currentSessionID(c)
+	assert.False(t, ok)
+
+	c.Set("auth", &middleware.AuthProvider{SessionID: sessionID.String()})
+	c.Set("auth_type", "jwt")
+	got, ok := currentSessionID(c)
+	assert.True(t, ok)
+	assert.Equal(t, sessionID, got)
+
+	// The session ID of an API key is the key's ID, which is no session
+	c.Set("auth_type", "api_key")
+	_, ok = currentSessionID(c)
+	assert.False(t, ok)
+}
+
+func TestAuthHandler_Sessions(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWTSecret: "test-secret-key"}
+	handler := NewAuthHandler(newOfflineDB(t), cfg, mail.NewMailer(mail.NewLogSender(slog.Default()), mail.Address{}, nil))
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("user_id", uuid.New())
+		c.Set("auth", &middleware.AuthProvider{SessionID: uuid.New().String()})
+		c.Set("auth_type", "api_key")
+	})
+	router.POST("/auth/logout", handler.Logout)
+	router.DELETE("/auth/sessions/:id", handler.RevokeSession)
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		status int
+		error  string
+	}{
+		{"logout with an API key", http.MethodPost, "/auth/logout", http.StatusBadRequest, "Only tokens from a login"},
+		{"invalid session ID", http.MethodDelete, "/auth/sessions/nope", http.StatusBadRequest, "Invalid session ID"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.error), w.Body.String())
+		})
+	}
+}
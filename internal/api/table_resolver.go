@@ -0,0 +1,158 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the TableResolver, which finds the data table holding a collection's items.
+//
+// Data tables exist in two layouts:
+// - Tenant schema - "<tenant slug>".data_<collection slug>, used by item reads and schema changes
+// - Data schema   - data."<collection slug>-data-<tenant id>", named in collections.data_table_name
+//
+// The data schema layout is what the database trigger on collections creates; item writes
+// used to go there while everything else used the tenant schema.
+//
+// The resolver prefers the tenant schema and falls back to the data schema, so every item
+// read and write, schema change and export of a collection reaches the same table whichever
+// layout the collection was created with.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"go-rbac-api/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// Data table layouts reported in DataTable.Layout
+const (
+	LayoutTenantSchema = "tenant_schema"
+	LayoutDataSchema   = "data_schema"
+)
+
+// dataSchema is the schema of data tables in the data schema layout
+const dataSchema = "data"
+
+// ErrDataTableNotFound is returned for collections without a data table in either layout
+var ErrDataTableNotFound = errors.New("data table does not exist")
+
+// DataTable is the physical table holding a collection's items
+type DataTable struct {
+	Schema string // Schema of the table
+	Name   string // Table name within the schema
+	Layout string // LayoutTenantSchema or LayoutDataSchema
+
+	dialect db.Dialect // Database the table is in; Postgres when unset
+}
+
+// String returns the quoted, schema-qualified table name for use in queries
+func (t DataTable) String() string {
+	if t.dialect == nil {
+		return db.Postgres.Table(t.Schema, t.Name)
+	}
+	return t.dialect.Table(t.Schema, t.Name)
+}
+
+// tableCatalog answers the lookups the resolver makes against the database
+type tableCatalog interface {
+	// tenantSchema returns the schema of a tenant's data tables
+	tenantSchema(ctx context.Context, tenantID uuid.UUID) (string, error)
+	// recordedTable returns collections.data_table_name of a collection, or "" when unset
+	recordedTable(ctx context.Context, tenantID uuid.UUID, collection string) (string, error)
+	// tableExists reports whether schema.name exists
+	tableExists(ctx context.Context, schema, name string) (bool, error)
+}
+
+// TableResolver resolves collections to their data tables
+type TableResolver struct {
+	catalog tableCatalog
+	dialect db.Dialect // Dialect of the tables resolved
+}
+
+// NewTableResolver creates a TableResolver looking tables up through utils
+func NewTableResolver(utils *ItemsUtils) *TableResolver {
+	return &TableResolver{catalog: utils, dialect: utils.dialect()}
+}
+
+// Resolve returns the data table of the collection with slug collection in tenantID, or an
+// error wrapping ErrDataTableNotFound when the collection has none
+func (r *TableResolver) Resolve(ctx context.Context, tenantID uuid.UUID, collection string) (DataTable, error) {
+	candidates, err := r.candidates(ctx, tenantID, collection)
+	if err != nil {
+		return DataTable{}, err
+	}
+	for _, table := range candidates {
+		exists, err := r.catalog.tableExists(ctx, table.Schema, table.Name)
+		if err != nil {
+			return DataTable{}, fmt.Errorf("failed to check table existence: %w", err)
+		}
+		if exists {
+			return table, nil
+		}
+	}
+	return DataTable{}, fmt.Errorf("collection %s: %w", collection, ErrDataTableNotFound)
+}
+
+// candidates lists the tables a collection's items may be in, in order of preference
+func (r *TableResolver) candidates(ctx context.Context, tenantID uuid.UUID, collection string) ([]DataTable, error) {
+	tenantSchema, err := r.catalog.tenantSchema(ctx, tenantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tenant schema: %w", err)
+	}
+	candidates := []DataTable{{Schema: tenantSchema, Name: "data_" + collection, Layout: LayoutTenantSchema, dialect: r.dialect}}
+
+	recorded, err := r.catalog.recordedTable(ctx, tenantID, collection)
+	if err != nil {
+		return nil, err
+	}
+	if recorded != "" {
+		candidates = append(candidates, DataTable{Schema: dataSchema, Name: recorded, Layout: LayoutDataSchema, dialect: r.dialect})
+	}
+	return candidates, nil
+}
+
+// ResolveDataTable returns the data table of a collection; see TableResolver.Resolve.
+// Tables found outside a transaction are cached; missing ones are looked up every time,
+// as they may be created at any moment.
+func (u *ItemsUtils) ResolveDataTable(ctx context.Context, tenantID uuid.UUID, collection string) (DataTable, error) {
+	if u.tx != nil {
+		return u.tables.Resolve(ctx, tenantID, collection)
+	}
+	if table, ok := defaultSchemaCache.DataTable(tenantID, collection); ok {
+		return table, nil
+	}
+
+	generation := defaultSchemaCache.Generation()
+	table, err := u.tables.Resolve(ctx, tenantID, collection)
+	if err != nil {
+		return DataTable{}, err
+	}
+	defaultSchemaCache.SetDataTable(generation, tenantID, collection, table)
+	return table, nil
+}
+
+// tenantSchema implements tableCatalog
+func (u *ItemsUtils) tenantSchema(ctx context.Context, tenantID uuid.UUID) (string, error) {
+	return u.GetTenantSchema(ctx, tenantID)
+}
+
+// recordedTable implements tableCatalog
+func (u *ItemsUtils) recordedTable(ctx context.Context, tenantID uuid.UUID, collection string) (string, error) {
+	var name sql.NullString
+	err := u.exec().QueryRowContext(ctx,
+		`SELECT data_table_name FROM collections WHERE slug = $1 AND tenant_id = $2`,
+		collection, tenantID,
+	).Scan(&name)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to look up collection: %w", err)
+	}
+	return name.String, nil
+}
+
+// tableExists implements tableCatalog
+func (u *ItemsUtils) tableExists(ctx context.Context, schema, name string) (bool, error) {
+	return u.dialect().TableExists(ctx, u.exec(), schema, name)
+}
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeTableCatalog is a tableCatalog over fixed schemas and tables
+type fakeTableCatalog struct {
+	schemas  map[uuid.UUID]string
+	recorded map[string]string // collection slug -> collections.data_table_name
+	tables   map[string]bool   // schema + "." + name
+}
+
+func (f *fakeTableCatalog) tenantSchema(ctx context.Context, tenantID uuid.UUID) (string, error) {
+	schema, ok := f.schemas[tenantID]
+	if !ok {
+		return "main", errors.New("tenant not found")
+	}
+	return schema, nil
+}
+
+func (f *fakeTableCatalog) recordedTable(ctx context.Context, tenantID uuid.UUID, collection string) (string, error) {
+	return f.recorded[collection], nil
+}
+
+func (f *fakeTableCatalog) tableExists(ctx context.Context, schema, name string) (bool, error) {
+	return f.tables[schema+"."+name], nil
+}
+
+func TestTableResolver(t *testing.T) {
+	tenantID := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+	legacyName := "customers-data-6e68062f-c4c6-42df-9e01-e2d1081664f4"
+
+	catalog := &fakeTableCatalog{
+		schemas:  map[uuid.UUID]string{tenantID: "acme"},
+		recorded: map[string]string{"customers": legacyName, "products": "products-data-6e68062f-c4c6-42df-9e01-e2d1081664f4"},
+		tables:   map[string]bool{},
+	}
+	resolver := &TableResolver{catalog: catalog}
+	ctx := context.Background()
+
+	t.Run("Tenant Schema Layout", func(t *testing.T) {
+		catalog.tables = map[string]bool{"acme.data_orders": true}
+		table, err := resolver.Resolve(ctx, tenantID, "orders")
+		require.NoError(t, err)
+		assert.Equal(t, DataTable{Schema: "acme", Name: "data_orders", Layout: LayoutTenantSchema}, table)
+		assert.Equal(t, `"acme"."data_orders"`, table.String())
+	})
+
+	t.Run("Data Schema Layout", func(t *testing.T) {
+		catalog.tables = map[string]bool{"data." + legacyName: true}
+		table, err := resolver.Resolve(ctx, tenantID, "customers")
+		require.NoError(t, err)
+		assert.Equal(t, LayoutDataSchema, table.Layout)
+		assert.Equal(t, `"data"."customers-data-6e68062f-c4c6-42df-9e01-e2d1081664f4"`, table.String())
+	})
+
+	t.Run("Tenant Schema Preferred", func(t *testing.T) {
+		catalog.tables = map[string]bool{"acme.data_customers": true, "data." + legacyName: true}
+		table, err := resolver.Resolve(ctx, tenantID, "customers")
+		require.NoError(t, err)
+		assert.Equal(t, LayoutTenantSchema, table.Layout)
+	})
+
+	t.Run("Recorded Table Missing", func(t *testing.T) {
+		catalog.tables = map[string]bool{}
+		_, err := resolver.Resolve(ctx, tenantID, "products")
+		assert.ErrorIs(t, err, ErrDataTableNotFound)
+	})
+
+	t.Run("Unknown Collection", func(t *testing.T) {
+		_, err := resolver.Resolve(ctx, tenantID, "invoices")
+		assert.ErrorIs(t, err, ErrDataTableNotFound)
+	})
+
+	t.Run("Unknown Tenant", func(t *testing.T) {
+		_, err := resolver.Resolve(ctx, uuid.New(), "orders")
+		require.Error(t, err)
+		assert.False(t, errors.Is(err, ErrDataTableNotFound))
+	})
+}
@@ -0,0 +1,870 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains tenant backups: tenant archives (see tenant_export.go) kept in asset
+// storage, written on demand or on a schedule and restored in place.
+//
+// Tenant Backup Endpoints:
+// - POST   /tenants/:id/backups                     - Back up the tenant now
+// - GET    /tenants/:id/backups                     - List the tenant's backups, newest first
+// - GET    /tenants/:id/backups/:backup_id          - Get a backup
+// - GET    /tenants/:id/backups/:backup_id/download - Download the archive of a backup
+// - POST   /tenants/:id/backups/:backup_id/restore  - Restore a backup into the tenant
+// - DELETE /tenants/:id/backups/:backup_id          - Delete a backup and its archive
+// - GET    /tenants/:id/backups/schedule            - Get the backup schedule
+// - PUT    /tenants/:id/backups/schedule            - Back up every interval, keeping the last retain
+// - DELETE /tenants/:id/backups/schedule            - Stop scheduled backups
+//
+// Backups are written by background jobs: a backup is pending until its archive is stored
+// and failed once the job has given up. Completed backups keep the size and SHA-256 of
+// their archive, which is checked again before a restore. When a scheduled backup
+// completes, scheduled backups beyond the schedule's retain count are deleted; manual
+// backups are kept until they are deleted.
+//
+// A restore brings the tenant back to the backup. The backup's schema is applied (see
+// schema_snapshot.go), then the items of every collection in the backup are replaced by
+// the backed up ones, in one transaction per collection. Collections and other records
+// created since the backup are left alone. With "collections" only the named collections
+// and their fields are restored. Point-in-time restores of one collection also start from a
+// backup (see items_point_in_time.go).
+//
+// Only admins of the tenant (signed in to it) and super admins manage its backups. The
+// archives of purged tenants are left in storage.
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net/http"
+	"os"
+	"path"
+	"time"
+
+	"go-rbac-api/internal/audit"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/jobs"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/storage"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+const (
+	backupJobKind = "tenants.backup"
+
+	// Backup triggers stored in tenant_backups.trigger
+	backupTriggerManual   = "manual"
+	backupTriggerSchedule = "schedule"
+
+	// Backup statuses stored in tenant_backups.status
+	backupStatusPending   = "pending"
+	backupStatusCompleted = "completed"
+	backupStatusFailed    = "failed"
+
+	minBackupInterval   = time.Hour   // Shortest interval of a backup schedule
+	defaultBackupRetain = 7           // Scheduled backups kept when a schedule names no count
+	maxBackupRetain     = 100         // Most scheduled backups a schedule may keep
+	backupPollInterval  = time.Minute // How often due schedules are looked for
+	backupScheduleBatch = 20          // Schedules started per pass
+)
+
+// errBackupCorrupt is returned for archives that no longer match their checksum
+var errBackupCorrupt = errors.New("backup archive does not match its checksum")
+
+// backupJobPayload is the payload of a backup job
+type backupJobPayload struct {
+	BackupID uuid.UUID `json:"backup_id"`
+}
+
+// BackupsHandler backs up tenants to asset storage and restores them
+type BackupsHandler struct {
+	db      *db.DB
+	tenants *TenantHandler // Writes and reads the archives
+	queue   *jobs.Queue
+	storage storage.Storage
+}
+
+// NewBackupsHandler creates a BackupsHandler that writes archives through tenants on
+// queue and keeps them in store
+func NewBackupsHandler(db *db.DB, tenants *TenantHandler, queue *jobs.Queue, store storage.Storage) *BackupsHandler {
+	h := &BackupsHandler{db: db, tenants: tenants, queue: queue, storage: store}
+	queue.Register(backupJobKind, h.runBackupJob)
+	return h
+}
+
+// CreateBackup handles POST /tenants/:id/backups requests. The backup is written in the
+// background; it is pending until its archive is stored.
+// @Summary      Back up a tenant
+// @Tags         tenants
+// @Produce      json
+// @Param        id    path     string true "Tenant ID"
+// @Success      202   {object} models.TenantBackup
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups [post]
+func (h *BackupsHandler) CreateBackup(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	backup, err := h.startBackup(c.Request.Context(), tenant.ID, backupTriggerManual, userID)
+	if err != nil {
+		middleware.GetLogger(c).Error("failed to start backup", "tenant_id", tenant.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start backup"})
+		return
+	}
+	c.JSON(http.StatusAccepted, backupModel(backup))
+}
+
+// ListBackups handles GET /tenants/:id/backups requests
+// @Summary      List tenant backups
+// @Tags         tenants
+// @Produce      json
+// @Param        id    path     string true "Tenant ID"
+// @Success      200   {array}  models.TenantBackup
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups [get]
+func (h *BackupsHandler) ListBackups(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+
+	backups, err := h.db.Queries.ListTenantBackups(c.Request.Context(), tenant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backups"})
+		return
+	}
+	response := make([]models.TenantBackup, 0, len(backups))
+	for _, backup := range backups {
+		response = append(response, backupModel(backup))
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+// GetBackup handles GET /tenants/:id/backups/:backup_id requests
+// @Summary      Get a tenant backup
+// @Tags         tenants
+// @Produce      json
+// @Param        id        path     string true "Tenant ID"
+// @Param        backup_id path     string true "Backup ID"
+// @Success      200       {object} models.TenantBackup
+// @Failure      400       {object} map[string]string
+// @Failure      403       {object} map[string]string
+// @Failure      404       {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups/{backup_id} [get]
+func (h *BackupsHandler) GetBackup(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+	backup, ok := h.getBackup(c, tenant.ID)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, backupModel(backup))
+}
+
+// DownloadBackup handles GET /tenants/:id/backups/:backup_id/download requests. The
+// archive can be imported as a new tenant with POST /tenants/import.
+// @Summary      Download a tenant backup
+// @Tags         tenants
+// @Produce      application/zip
+// @Param        id        path     string true "Tenant ID"
+// @Param        backup_id path     string true "Backup ID"
+// @Success      200
+// @Failure      400       {object} map[string]string
+// @Failure      403       {object} map[string]string
+// @Failure      404       {object} map[string]string
+// @Failure      409       {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups/{backup_id}/download [get]
+func (h *BackupsHandler) DownloadBackup(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+	backup, ok := h.getBackup(c, tenant.ID)
+	if !ok {
+		return
+	}
+	if backup.Status != backupStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Backup has not completed"})
+		return
+	}
+
+	reader, err := h.storage.Get(c.Request.Context(), backup.StorageKey)
+	if err != nil {
+		if errors.Is(err, storage.ErrNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Backup archive is missing from storage"})
+			return
+		}
+		middleware.GetLogger(c).Error("failed to read backup", "backup_id", backup.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read backup"})
+		return
+	}
+	defer reader.Close()
+
+	c.DataFromReader(http.StatusOK, backup.Size, "application/zip", reader, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": backupFilename(tenant, backup)}),
+	})
+}
+
+// RestoreBackup handles POST /tenants/:id/backups/:backup_id/restore requests. The body
+// is optional; "collections" limits the restore to the named collections.
+// @Summary      Restore a tenant backup
+// @Tags         tenants
+// @Accept       json
+// @Produce      json
+// @Param        id        path     string true  "Tenant ID"
+// @Param        backup_id path     string true  "Backup ID"
+// @Param        request   body     models.TenantBackupRestoreRequest false "Collections to restore"
+// @Success      200       {object} models.TenantBackupRestoreResponse
+// @Failure      400       {object} map[string]string
+// @Failure      403       {object} map[string]string
+// @Failure      404       {object} map[string]string
+// @Failure      409       {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups/{backup_id}/restore [post]
+func (h *BackupsHandler) RestoreBackup(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+	backup, ok := h.getBackup(c, tenant.ID)
+	if !ok {
+		return
+	}
+	if backup.Status != backupStatusCompleted {
+		c.JSON(http.StatusConflict, gin.H{"error": "Backup has not completed"})
+		return
+	}
+
+	var req models.TenantBackupRestoreRequest
+	if c.Request.ContentLength > 0 {
+		if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+			respondBodyError(c, err, "Invalid request body")
+			return
+		}
+	}
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.Request.Context()
+
+	archive, cleanup, err := h.openBackup(ctx, backup)
+	if err != nil {
+		respondOpenBackupError(c, backup, err)
+		return
+	}
+	defer cleanup()
+
+	changes, items, err := h.restoreArchive(WithTenant(ctx, tenant.ID), userID, archive, req.Collections)
+	if err != nil {
+		middleware.GetLogger(c).Warn("backup restore failed", "tenant_id", tenant.ID, "backup_id", backup.ID, "error", err)
+		if !respondQuotaExceeded(c, err) {
+			respondError(c, err, "Restore failed")
+		}
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionBackupRestore)
+	entry.TenantID = tenant.ID
+	entry.StatusCode = http.StatusOK
+	entry.Details = map[string]interface{}{"backup_id": backup.ID, "schema_changes": changes, "items": items}
+	audit.NewLogger(h.db).Record(ctx, entry)
+
+	c.JSON(http.StatusOK, models.TenantBackupRestoreResponse{
+		Message:       "Backup restored successfully",
+		Backup:        backupModel(backup),
+		SchemaChanges: changes,
+		Items:         items,
+	})
+}
+
+// DeleteBackup handles DELETE /tenants/:id/backups/:backup_id requests
+// @Summary      Delete a tenant backup
+// @Tags         tenants
+// @Produce      json
+// @Param        id        path     string true "Tenant ID"
+// @Param        backup_id path     string true "Backup ID"
+// @Success      200       {object} map[string]string
+// @Failure      400       {object} map[string]string
+// @Failure      403       {object} map[string]string
+// @Failure      404       {object} map[string]string
+// @Failure      409       {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups/{backup_id} [delete]
+func (h *BackupsHandler) DeleteBackup(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+	backup, ok := h.getBackup(c, tenant.ID)
+	if !ok {
+		return
+	}
+	// The job would store the archive after the row is gone
+	if backup.Status == backupStatusPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "Backup is still running"})
+		return
+	}
+
+	if err := h.deleteBackup(c.Request.Context(), backup); err != nil {
+		middleware.GetLogger(c).Error("failed to delete backup", "backup_id", backup.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete backup"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup deleted successfully"})
+}
+
+// GetBackupSchedule handles GET /tenants/:id/backups/schedule requests
+// @Summary      Get the backup schedule of a tenant
+// @Tags         tenants
+// @Produce      json
+// @Param        id    path     string true "Tenant ID"
+// @Success      200   {object} models.TenantBackupSchedule
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups/schedule [get]
+func (h *BackupsHandler) GetBackupSchedule(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+
+	schedule, err := h.db.Queries.GetTenantBackupSchedule(c.Request.Context(), tenant.ID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant has no backup schedule"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backup schedule"})
+		return
+	}
+	c.JSON(http.StatusOK, backupScheduleModel(schedule))
+}
+
+// SetBackupSchedule handles PUT /tenants/:id/backups/schedule requests. The first
+// scheduled backup runs one interval from now.
+// @Summary      Set the backup schedule of a tenant
+// @Tags         tenants
+// @Accept       json
+// @Produce      json
+// @Param        id      path     string true "Tenant ID"
+// @Param        request body     models.TenantBackupScheduleRequest true "Interval and retained backups"
+// @Success      200     {object} models.TenantBackupSchedule
+// @Failure      400     {object} map[string]string
+// @Failure      403     {object} map[string]string
+// @Failure      404     {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups/schedule [put]
+func (h *BackupsHandler) SetBackupSchedule(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+
+	var req models.TenantBackupScheduleRequest
+	if err := decodeJSON(c, &req, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	interval, retain, err := parseBackupSchedule(req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+
+	schedule, err := h.db.Queries.UpsertTenantBackupSchedule(c.Request.Context(), sqlc.UpsertTenantBackupScheduleParams{
+		TenantID:  tenant.ID,
+		Interval:  req.Interval,
+		Retain:    int32(retain),
+		NextRunAt: time.Now().Add(interval),
+		CreatedBy: uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+	})
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save backup schedule"})
+		return
+	}
+	c.JSON(http.StatusOK, backupScheduleModel(schedule))
+}
+
+// DeleteBackupSchedule handles DELETE /tenants/:id/backups/schedule requests. Backups
+// already taken are kept.
+// @Summary      Stop scheduled backups of a tenant
+// @Tags         tenants
+// @Produce      json
+// @Param        id    path     string true "Tenant ID"
+// @Success      200   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/backups/schedule [delete]
+func (h *BackupsHandler) DeleteBackupSchedule(c *gin.Context) {
+	tenant, ok := h.backupTenant(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.db.Queries.DeleteTenantBackupSchedule(c.Request.Context(), tenant.ID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete backup schedule"})
+		return
+	}
+	if deleted == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant has no backup schedule"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Backup schedule deleted successfully"})
+}
+
+// backupTenant returns the tenant of a backup request once the caller may manage its
+// backups, or responds with the reason they may not
+func (h *BackupsHandler) backupTenant(c *gin.Context) (sqlc.Tenant, bool) {
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+		return sqlc.Tenant{}, false
+	}
+	auth, ok := middleware.GetAuthProvider(c)
+	if !ok || !(auth.IsSuperAdmin || (auth.IsAdmin && auth.TenantID == tenantID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins of the tenant can manage its backups"})
+		return sqlc.Tenant{}, false
+	}
+
+	tenant, err := h.db.Queries.GetTenantByID(c.Request.Context(), tenantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return sqlc.Tenant{}, false
+	}
+	return tenant, true
+}
+
+// getBackup returns the backup named in the request, or responds with 404
+func (h *BackupsHandler) getBackup(c *gin.Context, tenantID uuid.UUID) (sqlc.TenantBackup, bool) {
+	backupID, err := uuid.Parse(c.Param("backup_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid backup ID"})
+		return sqlc.TenantBackup{}, false
+	}
+
+	backup, err := h.db.Queries.GetTenantBackup(c.Request.Context(), sqlc.GetTenantBackupParams{ID: backupID, TenantID: tenantID})
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup not found"})
+		return sqlc.TenantBackup{}, false
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch backup"})
+		return sqlc.TenantBackup{}, false
+	}
+	return backup, true
+}
+
+// startBackup records a pending backup of a tenant and queues the job that writes it.
+// userID is uuid.Nil for scheduled backups.
+func (h *BackupsHandler) startBackup(ctx context.Context, tenantID uuid.UUID, trigger string, userID uuid.UUID) (sqlc.TenantBackup, error) {
+	backupID := uuid.New()
+	backup, err := h.db.Queries.CreateTenantBackup(ctx, sqlc.CreateTenantBackupParams{
+		ID:         backupID,
+		TenantID:   tenantID,
+		Trigger:    trigger,
+		StorageKey: backupStorageKey(tenantID, backupID),
+		CreatedBy:  uuid.NullUUID{UUID: userID, Valid: userID != uuid.Nil},
+	})
+	if err != nil {
+		return sqlc.TenantBackup{}, fmt.Errorf("failed to record backup: %w", err)
+	}
+
+	_, err = h.queue.Enqueue(ctx, jobs.NewJob{Kind: backupJobKind, TenantID: tenantID, Payload: backupJobPayload{BackupID: backupID}})
+	if err != nil {
+		if err := h.db.Queries.DeleteTenantBackup(ctx, backupID); err != nil {
+			slog.Error("failed to delete unqueued backup", "tenant_id", tenantID, "backup_id", backupID, "error", err)
+		}
+		return sqlc.TenantBackup{}, err
+	}
+	return backup, nil
+}
+
+// runBackupJob writes the archive of a pending backup to storage. A failure leaves the
+// backup pending for the retry, or marks it failed after the last attempt.
+func (h *BackupsHandler) runBackupJob(ctx context.Context, job sqlc.Job) error {
+	var payload backupJobPayload
+	if err := json.Unmarshal(job.Payload, &payload); err != nil {
+		return fmt.Errorf("invalid backup payload: %w", err)
+	}
+	if !job.TenantID.Valid {
+		return fmt.Errorf("backup has no tenant")
+	}
+
+	backup, err := h.db.Queries.GetTenantBackup(ctx, sqlc.GetTenantBackupParams{ID: payload.BackupID, TenantID: job.TenantID.UUID})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil // Deleted with its tenant
+	}
+	if err != nil {
+		return fmt.Errorf("failed to load backup: %w", err)
+	}
+	if backup.Status != backupStatusPending {
+		return nil
+	}
+
+	if err := h.writeBackup(ctx, backup); err != nil {
+		status := backupStatusPending
+		if job.Attempts >= job.MaxAttempts {
+			status = backupStatusFailed
+		}
+		if err := h.db.Queries.FailTenantBackup(ctx, sqlc.FailTenantBackupParams{
+			ID:     backup.ID,
+			Status: status,
+			Error:  sql.NullString{String: err.Error(), Valid: true},
+		}); err != nil {
+			slog.Error("failed to record backup failure", "tenant_id", backup.TenantID, "backup_id", backup.ID, "error", err)
+		}
+		return err
+	}
+
+	if backup.Trigger == backupTriggerSchedule {
+		h.pruneBackups(ctx, backup.TenantID)
+	}
+	return nil
+}
+
+// writeBackup exports the tenant of a backup, stores the archive and completes the backup
+func (h *BackupsHandler) writeBackup(ctx context.Context, backup sqlc.TenantBackup) error {
+	tenant, err := h.db.Queries.GetTenantByID(ctx, backup.TenantID)
+	if err != nil {
+		return fmt.Errorf("failed to load tenant: %w", err)
+	}
+
+	// Storage needs the size up front, so the archive is written to disk first
+	file, err := os.CreateTemp("", "basin-backup-*")
+	if err != nil {
+		return fmt.Errorf("failed to create backup file: %w", err)
+	}
+	defer os.Remove(file.Name())
+	defer file.Close()
+
+	hash := sha256.New()
+	manifest, err := h.tenants.ExportArchive(ctx, io.MultiWriter(file, hash), tenant, TenantArchiveOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to export tenant: %w", err)
+	}
+	size, err := file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return fmt.Errorf("failed to read backup file: %w", err)
+	}
+	if err := h.storage.Put(ctx, backup.StorageKey, file, size, "application/zip"); err != nil {
+		return fmt.Errorf("failed to store backup: %w", err)
+	}
+
+	items := 0
+	for _, collection := range manifest.Collections {
+		items += collection.Items
+	}
+	_, err = h.db.Queries.CompleteTenantBackup(ctx, sqlc.CompleteTenantBackupParams{
+		ID:          backup.ID,
+		Size:        size,
+		Checksum:    sql.NullString{String: hex.EncodeToString(hash.Sum(nil)), Valid: true},
+		Collections: int32(len(manifest.Collections)),
+		Items:       int32(items),
+	})
+	return err
+}
+
+// pruneBackups deletes the scheduled backups of a tenant beyond its schedule's retain count
+func (h *BackupsHandler) pruneBackups(ctx context.Context, tenantID uuid.UUID) {
+	schedule, err := h.db.Queries.GetTenantBackupSchedule(ctx, tenantID)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			slog.Error("failed to load backup schedule", "tenant_id", tenantID, "error", err)
+		}
+		return
+	}
+
+	expired, err := h.db.Queries.GetExpiredScheduledBackups(ctx, sqlc.GetExpiredScheduledBackupsParams{TenantID: tenantID, Offset: schedule.Retain})
+	if err != nil {
+		slog.Error("failed to load expired backups", "tenant_id", tenantID, "error", err)
+		return
+	}
+	for _, backup := range expired {
+		if err := h.deleteBackup(ctx, backup); err != nil {
+			slog.Error("failed to delete expired backup", "tenant_id", tenantID, "backup_id", backup.ID, "error", err)
+		}
+	}
+}
+
+// deleteBackup removes the archive of a backup, then the backup itself
+func (h *BackupsHandler) deleteBackup(ctx context.Context, backup sqlc.TenantBackup) error {
+	if err := h.storage.Delete(ctx, backup.StorageKey); err != nil {
+		return err
+	}
+	return h.db.Queries.DeleteTenantBackup(ctx, backup.ID)
+}
+
+// Start backs up tenants on their schedules until ctx is cancelled
+func (h *BackupsHandler) Start(ctx context.Context) {
+	ticker := time.NewTicker(backupPollInterval)
+	defer ticker.Stop()
+
+	for {
+		h.scheduleDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// scheduleDue starts a backup of every tenant whose schedule is due. The schedule is moved
+// forward first, so only one instance starts each backup; slots missed while no instance
+// was running are skipped.
+func (h *BackupsHandler) scheduleDue(ctx context.Context) {
+	schedules, err := h.db.Queries.GetDueTenantBackupSchedules(ctx, backupScheduleBatch)
+	if err != nil {
+		slog.Error("failed to load due backup schedules", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, schedule := range schedules {
+		interval, err := parseBackupInterval(schedule.Interval)
+		if err != nil {
+			slog.Warn("skipping invalid backup schedule", "tenant_id", schedule.TenantID, "error", err)
+			continue
+		}
+
+		advanced, err := h.db.Queries.AdvanceTenantBackupSchedule(ctx, sqlc.AdvanceTenantBackupScheduleParams{
+			TenantID:    schedule.TenantID,
+			NextRunAt:   schedule.NextRunAt,
+			NextRunAt_2: now.Add(interval),
+		})
+		if err != nil {
+			slog.Error("failed to advance backup schedule", "tenant_id", schedule.TenantID, "error", err)
+			continue
+		}
+		if advanced == 0 {
+			continue // Another instance took this backup
+		}
+
+		if _, err := h.startBackup(ctx, schedule.TenantID, backupTriggerSchedule, uuid.Nil); err != nil {
+			slog.Error("failed to start scheduled backup", "tenant_id", schedule.TenantID, "error", err)
+		}
+	}
+}
+
+// openBackup copies the archive of a backup to a temporary file, checks it against the
+// backup's checksum and opens it. cleanup removes the file.
+func (h *BackupsHandler) openBackup(ctx context.Context, backup sqlc.TenantBackup) (archive *tenantArchive, cleanup func(), err error) {
+	reader, err := h.storage.Get(ctx, backup.StorageKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer reader.Close()
+
+	file, err := os.CreateTemp("", "basin-restore-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create restore file: %w", err)
+	}
+	cleanup = func() {
+		file.Close()
+		os.Remove(file.Name())
+	}
+
+	hash := sha256.New()
+	size, err := io.Copy(io.MultiWriter(file, hash), reader)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to read backup: %w", err)
+	}
+	if backup.Checksum.Valid && hex.EncodeToString(hash.Sum(nil)) != backup.Checksum.String {
+		cleanup()
+		return nil, nil, errBackupCorrupt
+	}
+	if archive, err = readTenantArchive(file, size); err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	return archive, cleanup, nil
+}
+
+// respondOpenBackupError responds to a backup whose archive could not be opened
+func respondOpenBackupError(c *gin.Context, backup sqlc.TenantBackup, err error) {
+	switch {
+	case errors.Is(err, storage.ErrNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Backup archive is missing from storage"})
+	case errors.Is(err, errBackupCorrupt):
+		c.JSON(http.StatusConflict, gin.H{"error": "Backup archive does not match its checksum"})
+	default:
+		middleware.GetLogger(c).Error("failed to read backup", "backup_id", backup.ID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read backup"})
+	}
+}
+
+// restoreArchive applies the schema of an archive to the tenant in ctx (see WithTenant)
+// and replaces the items of its collections, or only of the collections named in only.
+// It returns the number of schema changes and the restored items per collection.
+func (h *BackupsHandler) restoreArchive(ctx context.Context, userID uuid.UUID, archive *tenantArchive, only []string) (int, map[string]int, error) {
+	collections, snapshot, err := selectArchiveCollections(archive, only)
+	if err != nil {
+		return 0, nil, err
+	}
+	changes, err := h.tenants.items.schemaHandlers.ApplySnapshot(ctx, userID, snapshot, false)
+	if err != nil {
+		return len(changes), nil, err
+	}
+
+	items := make(map[string]int)
+	for _, collection := range collections {
+		file, err := archive.files[collection.File].Open()
+		if err != nil {
+			return len(changes), items, err
+		}
+		count, err := h.tenants.items.dynamicHandlers.RestoreDynamicItems(ctx, userID, collection.Slug, file, path.Ext(collection.File))
+		file.Close()
+		if errors.Is(err, ErrDataTableNotFound) && collection.Items == 0 {
+			// Nothing was ever stored in the collection, then or now
+			err = nil
+		}
+		if err != nil {
+			return len(changes), items, fmt.Errorf("items of %s: %w", collection.Slug, err)
+		}
+		items[collection.Slug] = count
+	}
+	return len(changes), items, nil
+}
+
+// selectArchiveCollections returns the collections of an archive named in only, with a
+// snapshot of their schema alone, or every collection and the whole schema when only is
+// empty
+func selectArchiveCollections(archive *tenantArchive, only []string) ([]TenantArchiveCollection, *SchemaSnapshot, error) {
+	if len(only) == 0 {
+		return archive.manifest.Collections, archive.schema, nil
+	}
+
+	files := make(map[string]TenantArchiveCollection, len(archive.manifest.Collections))
+	for _, collection := range archive.manifest.Collections {
+		files[collection.Slug] = collection
+	}
+	schemas := make(map[string]SnapshotCollection, len(archive.schema.Collections))
+	for _, collection := range archive.schema.Collections {
+		schemas[collection.Slug] = collection
+	}
+
+	// Roles and permissions are left as they are
+	snapshot := &SchemaSnapshot{
+		Version:     archive.schema.Version,
+		Collections: []SnapshotCollection{},
+		Roles:       []SnapshotRole{},
+		Permissions: []SnapshotPermission{},
+	}
+	var collections []TenantArchiveCollection
+	seen := make(map[string]bool, len(only))
+	for _, slug := range only {
+		collection, ok := files[slug]
+		if !ok {
+			return nil, nil, validationError("collection '%s' is not in the backup", slug)
+		}
+		if seen[slug] {
+			continue
+		}
+		seen[slug] = true
+		collections = append(collections, collection)
+		if schema, ok := schemas[slug]; ok {
+			snapshot.Collections = append(snapshot.Collections, schema)
+		}
+	}
+	return collections, snapshot, nil
+}
+
+// parseBackupSchedule validates a schedule request and returns its interval and retain
+// count, defaulting to defaultBackupRetain
+func parseBackupSchedule(req models.TenantBackupScheduleRequest) (time.Duration, int, error) {
+	interval, err := parseBackupInterval(req.Interval)
+	if err != nil {
+		return 0, 0, err
+	}
+	retain := req.Retain
+	if retain == 0 {
+		retain = defaultBackupRetain
+	}
+	if retain < 1 || retain > maxBackupRetain {
+		return 0, 0, fmt.Errorf("retain must be between 1 and %d", maxBackupRetain)
+	}
+	return interval, retain, nil
+}
+
+// parseBackupInterval parses the interval of a backup schedule
+func parseBackupInterval(value string) (time.Duration, error) {
+	interval, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("interval must be a duration such as \"24h\"")
+	}
+	if interval < minBackupInterval {
+		return 0, fmt.Errorf("interval must be at least %s", minBackupInterval)
+	}
+	return interval, nil
+}
+
+// backupStorageKey is where the archive of a backup is stored
+func backupStorageKey(tenantID, backupID uuid.UUID) string {
+	return fmt.Sprintf("%s/backups/%s.zip", tenantID, backupID)
+}
+
+// backupFilename is the name a backup is downloaded as
+func backupFilename(tenant sqlc.Tenant, backup sqlc.TenantBackup) string {
+	return fmt.Sprintf("%s-backup-%s.zip", tenant.Slug, backup.CreatedAt.Time.UTC().Format("20060102-150405"))
+}
+
+// backupModel converts a backup row to its API representation
+func backupModel(backup sqlc.TenantBackup) models.TenantBackup {
+	model := models.TenantBackup{
+		ID:          backup.ID,
+		TenantID:    backup.TenantID,
+		Trigger:     backup.Trigger,
+		Status:      backup.Status,
+		Size:        backup.Size,
+		Checksum:    backup.Checksum.String,
+		Collections: int(backup.Collections),
+		Items:       int(backup.Items),
+		Error:       backup.Error.String,
+		CreatedAt:   backup.CreatedAt.Time,
+		CompletedAt: nullTimePtr(backup.CompletedAt),
+	}
+	if backup.CreatedBy.Valid {
+		model.CreatedBy = &backup.CreatedBy.UUID
+	}
+	return model
+}
+
+// backupScheduleModel converts a backup schedule row to its API representation
+func backupScheduleModel(schedule sqlc.TenantBackupSchedule) models.TenantBackupSchedule {
+	return models.TenantBackupSchedule{
+		Interval:  schedule.Interval,
+		Retain:    int(schedule.Retain),
+		NextRunAt: schedule.NextRunAt,
+	}
+}
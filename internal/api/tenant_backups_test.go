@@ -0,0 +1,122 @@
+package api
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBackupSchedule(t *testing.T) {
+	tests := []struct {
+		name     string
+		req      models.TenantBackupScheduleRequest
+		interval time.Duration
+		retain   int
+		err      string
+	}{
+		{"Daily", models.TenantBackupScheduleRequest{Interval: "24h"}, 24 * time.Hour, defaultBackupRetain, ""},
+		{"Retain Set", models.TenantBackupScheduleRequest{Interval: "6h", Retain: 28}, 6 * time.Hour, 28, ""},
+		{"Too Often", models.TenantBackupScheduleRequest{Interval: "30m"}, 0, 0, "at least 1h0m0s"},
+		{"Not A Duration", models.TenantBackupScheduleRequest{Interval: "daily"}, 0, 0, "duration"},
+		{"Retain Negative", models.TenantBackupScheduleRequest{Interval: "24h", Retain: -1}, 0, 0, "between 1 and 100"},
+		{"Retain Too Many", models.TenantBackupScheduleRequest{Interval: "24h", Retain: 101}, 0, 0, "between 1 and 100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			interval, retain, err := parseBackupSchedule(tt.req)
+			if tt.err != "" {
+				assert.ErrorContains(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.interval, interval)
+			assert.Equal(t, tt.retain, retain)
+		})
+	}
+}
+
+func TestSelectArchiveCollections(t *testing.T) {
+	archive := &tenantArchive{
+		manifest: TenantArchiveManifest{Collections: []TenantArchiveCollection{
+			{Slug: "customers", File: "data/customers.jsonl", Items: 3},
+			{Slug: "orders", File: "data/orders.jsonl", Items: 5},
+		}},
+		schema: &SchemaSnapshot{
+			Version: SchemaSnapshotVersion,
+			Collections: []SnapshotCollection{
+				{Slug: "customers", Name: "Customers"},
+				{Slug: "orders", Name: "Orders"},
+			},
+			Roles:       []SnapshotRole{{Name: "editor"}},
+			Permissions: []SnapshotPermission{{Role: "editor", Table: "orders", Action: "read"}},
+		},
+	}
+
+	t.Run("Everything", func(t *testing.T) {
+		collections, snapshot, err := selectArchiveCollections(archive, nil)
+		require.NoError(t, err)
+		assert.Len(t, collections, 2)
+		assert.Same(t, archive.schema, snapshot)
+	})
+
+	t.Run("Named Collections", func(t *testing.T) {
+		collections, snapshot, err := selectArchiveCollections(archive, []string{"orders", "orders"})
+		require.NoError(t, err)
+		require.Len(t, collections, 1)
+		assert.Equal(t, "orders", collections[0].Slug)
+		require.Len(t, snapshot.Collections, 1)
+		assert.Equal(t, "Orders", snapshot.Collections[0].Name)
+		assert.Empty(t, snapshot.Roles)
+		assert.Empty(t, snapshot.Permissions)
+	})
+
+	t.Run("Unknown Collection", func(t *testing.T) {
+		_, _, err := selectArchiveCollections(archive, []string{"invoices"})
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.ErrorContains(t, err, "'invoices' is not in the backup")
+	})
+}
+
+func TestBackupModel(t *testing.T) {
+	tenantID, backupID, userID := uuid.New(), uuid.New(), uuid.New()
+	created := time.Date(2026, 3, 1, 2, 0, 0, 0, time.UTC)
+
+	backup := sqlc.TenantBackup{
+		ID:          backupID,
+		TenantID:    tenantID,
+		Trigger:     backupTriggerManual,
+		Status:      backupStatusCompleted,
+		StorageKey:  backupStorageKey(tenantID, backupID),
+		Size:        2048,
+		Checksum:    sql.NullString{String: "abc", Valid: true},
+		Collections: 2,
+		Items:       8,
+		CreatedBy:   uuid.NullUUID{UUID: userID, Valid: true},
+		CreatedAt:   sql.NullTime{Time: created, Valid: true},
+		CompletedAt: sql.NullTime{Time: created.Add(time.Minute), Valid: true},
+	}
+	model := backupModel(backup)
+	assert.Equal(t, int64(2048), model.Size)
+	assert.Equal(t, "abc", model.Checksum)
+	assert.Equal(t, 8, model.Items)
+	require.NotNil(t, model.CreatedBy)
+	assert.Equal(t, userID, *model.CreatedBy)
+	require.NotNil(t, model.CompletedAt)
+
+	assert.Equal(t, tenantID.String()+"/backups/"+backupID.String()+".zip", backup.StorageKey)
+	assert.Equal(t, "acme-backup-20260301-020000.zip", backupFilename(sqlc.Tenant{Slug: "acme"}, backup))
+
+	backup.CreatedBy = uuid.NullUUID{}
+	backup.CompletedAt = sql.NullTime{}
+	model = backupModel(backup)
+	assert.Nil(t, model.CreatedBy)
+	assert.Nil(t, model.CompletedAt)
+}
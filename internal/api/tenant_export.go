@@ -0,0 +1,645 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains tenant export and import, which move a whole tenant (schema and data)
+// between instances or into a backup.
+//
+// Tenant Archive Endpoints:
+// - GET  /tenants/:id/export - Download the tenant as a zip archive (?format=csv for CSV data)
+// - POST /tenants/import     - Create a new tenant from an archive (multipart "file")
+//
+// basin tenant export writes the same archive from the command line.
+//
+// An archive holds manifest.json (the tenant and its collections), schema.json (a schema
+// snapshot, see schema_snapshot.go) and one data/<collection>.jsonl or .csv file per
+// collection. Items keep their IDs, so relations between them survive an import. Users,
+// memberships, API keys and asset files are not part of the archive; the importing user
+// becomes the admin of the new tenant.
+//
+// With ?anonymize=true the values of PII-tagged fields (see pii_fields.go) are replaced by
+// realistic fake ones (see the fakedata package), so the archive can be imported into a
+// staging instance. Equal values get equal fakes within one archive, so the same email in
+// two collections still matches; fields without a tag are copied as they are.
+package api
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/fakedata"
+	"go-rbac-api/internal/fieldcrypt"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+)
+
+const (
+	// TenantArchiveVersion is the archive format written by this version of Basin
+	TenantArchiveVersion = 1
+
+	tenantArchiveManifest = "manifest.json"
+	tenantArchiveSchema   = "schema.json"
+
+	// tenantImportBatchSize is how many items are written per transaction on import
+	tenantImportBatchSize = 1000
+)
+
+// TenantArchiveManifest describes the contents of a tenant archive
+type TenantArchiveManifest struct {
+	Version     int                       `json:"version"`
+	ExportedAt  time.Time                 `json:"exported_at"`
+	Anonymized  bool                      `json:"anonymized,omitempty"` // PII fields hold fake values
+	Tenant      TenantArchiveTenant       `json:"tenant"`
+	Collections []TenantArchiveCollection `json:"collections"`
+}
+
+// TenantArchiveTenant is the exported tenant
+type TenantArchiveTenant struct {
+	Name     string          `json:"name"`
+	Slug     string          `json:"slug"`
+	Domain   string          `json:"domain,omitempty"`
+	Settings json.RawMessage `json:"settings,omitempty"`
+}
+
+// TenantArchiveCollection names the data file of one collection
+type TenantArchiveCollection struct {
+	Slug  string `json:"slug"`
+	File  string `json:"file"` // data/<slug>.jsonl or data/<slug>.csv
+	Items int    `json:"items"`
+}
+
+// tenantArchive is an archive opened for import
+type tenantArchive struct {
+	manifest TenantArchiveManifest
+	schema   *SchemaSnapshot
+	files    map[string]*zip.File
+}
+
+// TenantArchiveOptions are the options of a tenant export
+type TenantArchiveOptions struct {
+	Format    string // Data files as jsonl (the default) or csv
+	Anonymize bool   // Replace the values of PII-tagged fields with fake ones
+}
+
+// tenantExport is a tenant archive ready to be written
+type tenantExport struct {
+	manifest    TenantArchiveManifest
+	snapshot    *SchemaSnapshot
+	tenantID    uuid.UUID
+	collections []sqlc.Collection
+	format      string
+	pii         map[string]map[string]string // PII kind by collection and field, when anonymizing
+	fakes       *fakedata.Generator
+}
+
+// ExportTenant handles GET /tenants/:id/export requests. Only admins of the tenant (signed
+// in to it) and super admins can export it. The archive is streamed, so an error after the first byte ends
+// it early; such an archive has no manifest and is rejected by the import.
+// @Summary      Export a tenant
+// @Tags         tenants
+// @Produce      application/zip
+// @Param        id        path   string true  "Tenant ID"
+// @Param        format    query  string false "Data format: jsonl (default) or csv"
+// @Param        anonymize query  bool   false "Replace the values of PII-tagged fields with fake ones"
+// @Success      200
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/export [get]
+func (h *TenantHandler) ExportTenant(c *gin.Context) {
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+		return
+	}
+	options := TenantArchiveOptions{Format: c.DefaultQuery("format", "jsonl"), Anonymize: c.Query("anonymize") == "true"}
+	if options.Format != "jsonl" && options.Format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be jsonl or csv"})
+		return
+	}
+	auth, ok := middleware.GetAuthProvider(c)
+	if !ok || !(auth.IsSuperAdmin || (auth.IsAdmin && auth.TenantID == tenantID)) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins of the tenant can export it"})
+		return
+	}
+	ctx := c.Request.Context()
+	logger := middleware.GetLogger(c)
+
+	tenant, err := h.db.Queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return
+	}
+	export, err := h.newTenantExport(ctx, tenant, options)
+	if err != nil {
+		logger.Error("tenant export failed", "tenant_id", tenantID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to export tenant"})
+		return
+	}
+
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, export.filename()))
+	c.Status(http.StatusOK)
+	if err := h.writeTenantExport(ctx, c.Writer, export); err != nil {
+		logger.Error("tenant export failed", "tenant_id", tenantID, "error", err)
+	}
+}
+
+// ExportArchive writes the archive of a tenant to w, as GET /tenants/:id/export does, and
+// returns its manifest
+func (h *TenantHandler) ExportArchive(ctx context.Context, w io.Writer, tenant sqlc.Tenant, options TenantArchiveOptions) (TenantArchiveManifest, error) {
+	if options.Format == "" {
+		options.Format = "jsonl"
+	}
+	if options.Format != "jsonl" && options.Format != "csv" {
+		return TenantArchiveManifest{}, fmt.Errorf("format must be jsonl or csv")
+	}
+	export, err := h.newTenantExport(ctx, tenant, options)
+	if err != nil {
+		return TenantArchiveManifest{}, err
+	}
+	if err := h.writeTenantExport(ctx, w, export); err != nil {
+		return TenantArchiveManifest{}, err
+	}
+	return export.manifest, nil
+}
+
+// newTenantExport loads the schema and collections of a tenant to export
+func (h *TenantHandler) newTenantExport(ctx context.Context, tenant sqlc.Tenant, options TenantArchiveOptions) (*tenantExport, error) {
+	snapshot, _, err := h.items.schemaHandlers.loadSchema(ctx, tenant.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export schema: %w", err)
+	}
+	collections, err := h.db.Queries.GetCollectionsByTenant(ctx, uuid.NullUUID{UUID: tenant.ID, Valid: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to export collections: %w", err)
+	}
+
+	export := &tenantExport{
+		manifest: TenantArchiveManifest{
+			Version:    TenantArchiveVersion,
+			ExportedAt: time.Now().UTC(),
+			Anonymized: options.Anonymize,
+			Tenant: TenantArchiveTenant{
+				Name:     tenant.Name,
+				Slug:     tenant.Slug,
+				Domain:   tenant.Domain.String,
+				Settings: tenant.Settings.RawMessage,
+			},
+			Collections: []TenantArchiveCollection{},
+		},
+		snapshot:    snapshot,
+		tenantID:    tenant.ID,
+		collections: collections,
+		format:      options.Format,
+	}
+	if options.Anonymize {
+		// Every export gets fakes of its own, so they cannot be matched across exports
+		if export.fakes, err = fakedata.NewRandom(); err != nil {
+			return nil, err
+		}
+		export.pii = snapshotPIIFields(snapshot)
+	}
+	return export, nil
+}
+
+// filename is the name the archive is downloaded as
+func (e *tenantExport) filename() string {
+	return fmt.Sprintf("%s-%s.zip", e.manifest.Tenant.Slug, e.manifest.ExportedAt.Format("20060102-150405"))
+}
+
+// writeTenantExport writes the data files, schema and manifest of an export to w
+func (h *TenantHandler) writeTenantExport(ctx context.Context, w io.Writer, export *tenantExport) error {
+	archive := zip.NewWriter(w)
+	for _, collection := range export.collections {
+		file := "data/" + collection.Slug + "." + export.format
+		count, err := h.exportCollection(ctx, archive, file, export, collection)
+		if err != nil {
+			return fmt.Errorf("collection %s: %w", collection.Slug, err)
+		}
+		export.manifest.Collections = append(export.manifest.Collections, TenantArchiveCollection{Slug: collection.Slug, File: file, Items: count})
+	}
+	if err := writeArchiveJSON(archive, tenantArchiveSchema, export.snapshot); err != nil {
+		return err
+	}
+	if err := writeArchiveJSON(archive, tenantArchiveManifest, export.manifest); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// snapshotPIIFields returns the PII kinds of the tagged fields of a snapshot, by collection
+// and field
+func snapshotPIIFields(snapshot *SchemaSnapshot) map[string]map[string]string {
+	pii := make(map[string]map[string]string)
+	for _, collection := range snapshot.Collections {
+		for _, field := range collection.Fields {
+			if field.PII == "" {
+				continue
+			}
+			if pii[collection.Slug] == nil {
+				pii[collection.Slug] = make(map[string]string)
+			}
+			pii[collection.Slug][field.Name] = field.PII
+		}
+	}
+	return pii
+}
+
+// anonymizeRow replaces the values of the PII fields of a row with fakes, in place.
+// Encrypted values are decrypted first when the keys are at hand, so equal values still
+// get equal fakes.
+func anonymizeRow(fakes *fakedata.Generator, fields map[string]string, row map[string]interface{}) {
+	for name, kind := range fields {
+		value, ok := row[name]
+		if !ok {
+			continue
+		}
+		if text, isText := value.(string); isText && fieldcrypt.IsEncrypted(text) && fieldKeys != nil {
+			if plaintext, err := fieldKeys.Decrypt(text); err == nil {
+				value = plaintext
+			}
+		}
+		row[name] = fakes.Value(kind, value)
+	}
+}
+
+// exportCollection writes every item of a collection, trashed ones included, to one file
+// of the archive and returns how many there were
+func (h *TenantHandler) exportCollection(ctx context.Context, archive *zip.Writer, name string, export *tenantExport, collection sqlc.Collection) (int, error) {
+	w, err := archive.Create(name)
+	if err != nil {
+		return 0, err
+	}
+
+	tenantID := export.tenantID
+	table, err := h.items.utils.ResolveDataTable(ctx, tenantID, collection.Slug)
+	if errors.Is(err, ErrDataTableNotFound) {
+		// A collection without a data table exports as an empty file
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	order := "id"
+	if collection.Timestamps {
+		order = "created_at, id"
+	}
+	query := fmt.Sprintf("SELECT * FROM %s WHERE tenant_id = $1 ORDER BY %s", table, order)
+	rows, err := h.db.QueryContext(ctx, query, tenantID)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	columns, err := rows.Columns()
+	if err != nil {
+		return 0, err
+	}
+
+	var csvWriter *csv.Writer
+	encoder := json.NewEncoder(w)
+	if export.format == "csv" {
+		csvWriter = csv.NewWriter(w)
+		if err := csvWriter.Write(columns); err != nil {
+			return 0, err
+		}
+	}
+
+	count := 0
+	for rows.Next() {
+		row, err := scanRowToMap(rows, columns)
+		if err != nil {
+			return count, err
+		}
+		if fields := export.pii[collection.Slug]; len(fields) > 0 {
+			anonymizeRow(export.fakes, fields, row)
+		}
+		if csvWriter != nil {
+			record := make([]string, len(columns))
+			for i, column := range columns {
+				record[i] = csvValue(row[column])
+			}
+			err = csvWriter.Write(record)
+		} else {
+			err = encoder.Encode(row)
+		}
+		if err != nil {
+			return count, err
+		}
+		count++
+	}
+	if err := rows.Err(); err != nil {
+		return count, err
+	}
+	if csvWriter != nil {
+		csvWriter.Flush()
+		return count, csvWriter.Error()
+	}
+	return count, nil
+}
+
+// csvValue formats a column value for a CSV cell. NULL becomes an empty cell, JSON values
+// are written as JSON.
+func csvValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case map[string]interface{}, []interface{}:
+		encoded, _ := json.Marshal(v)
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// writeArchiveJSON adds a JSON file to the archive
+func writeArchiveJSON(archive *zip.Writer, name string, value interface{}) error {
+	w, err := archive.Create(name)
+	if err != nil {
+		return err
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(value)
+}
+
+// ImportTenant handles POST /tenants/import requests. The archive comes in the "file" part
+// of a multipart form; "name" and "slug" replace those of the exported tenant. The new
+// tenant is provisioned like POST /tenants, then the schema and the items are written. When
+// that fails the new tenant is deleted again.
+// @Summary      Import a tenant
+// @Tags         tenants
+// @Accept       mpfd
+// @Produce      json
+// @Param        file  formData file   true  "Archive from GET /tenants/{id}/export"
+// @Param        name  formData string false "Name of the new tenant"
+// @Param        slug  formData string false "Slug of the new tenant"
+// @Success      201   {object} models.TenantImportResponse
+// @Failure      400   {object} map[string]string
+// @Failure      409   {object} map[string]string
+// @Failure      413   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/import [post]
+func (h *TenantHandler) ImportTenant(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.Request.Context()
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, h.cfg.TenantImportMaxSize+multipartOverhead)
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("Archive exceeds the maximum size of %d bytes", h.cfg.TenantImportMaxSize)})
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "An archive is required in the 'file' form field"})
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to read uploaded archive"})
+		return
+	}
+	defer file.Close()
+
+	archive, err := readTenantArchive(file, fileHeader.Size)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	req := models.CreateTenantRequest{
+		Name: strings.TrimSpace(c.DefaultPostForm("name", archive.manifest.Tenant.Name)),
+		Slug: strings.TrimSpace(c.DefaultPostForm("slug", archive.manifest.Tenant.Slug)),
+	}
+	if req.Name == "" || req.Slug == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The tenant needs a name and a slug"})
+		return
+	}
+	if _, err := h.db.Queries.GetTenantBySlug(ctx, req.Slug); err == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "Tenant slug already exists"})
+		return
+	}
+
+	tenant, err := h.Provision(ctx, req, userID)
+	if err != nil {
+		respondError(c, err, "Failed to create tenant")
+		return
+	}
+
+	changes, items, err := h.importArchive(WithTenant(ctx, tenant.ID), userID, tenant, archive)
+	if err != nil {
+		middleware.GetLogger(c).Warn("tenant import failed", "tenant_id", tenant.ID, "error", err)
+		if err := h.db.Queries.DeleteTenant(context.Background(), tenant.ID); err != nil {
+			middleware.GetLogger(c).Error("failed to delete partially imported tenant", "tenant_id", tenant.ID, "error", err)
+		}
+		rbac.InvalidatePermissions()
+		if !respondQuotaExceeded(c, err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Import failed: " + err.Error()})
+		}
+		return
+	}
+	rbac.PermissionsChanged(tenant.ID)
+
+	c.JSON(http.StatusCreated, models.TenantImportResponse{
+		Message:       "Tenant imported successfully",
+		Tenant:        tenantModel(tenant),
+		SchemaChanges: changes,
+		Items:         items,
+	})
+}
+
+// importArchive writes the settings, schema and items of an archive into a provisioned
+// tenant. ctx must target the tenant (see WithTenant).
+func (h *TenantHandler) importArchive(ctx context.Context, userID uuid.UUID, tenant sqlc.Tenant, archive *tenantArchive) (int, map[string]int, error) {
+	// Limits are not copied, so an archive cannot raise them
+	settings, err := withoutTenantSetting(archive.manifest.Tenant.Settings, "limits")
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(settings) > 0 {
+		if _, err := h.db.Queries.UpdateTenant(ctx, sqlc.UpdateTenantParams{
+			ID:       tenant.ID,
+			Name:     tenant.Name,
+			Slug:     tenant.Slug,
+			Domain:   tenant.Domain,
+			Settings: pqtype.NullRawMessage{RawMessage: settings, Valid: true},
+		}); err != nil {
+			return 0, nil, fmt.Errorf("failed to copy settings: %w", err)
+		}
+	}
+
+	changes, err := h.items.schemaHandlers.ApplySnapshot(ctx, userID, archive.schema, false)
+	if err != nil {
+		return len(changes), nil, err
+	}
+
+	items := make(map[string]int)
+	for _, collection := range archive.manifest.Collections {
+		file, err := archive.files[collection.File].Open()
+		if err != nil {
+			return len(changes), items, err
+		}
+		count, err := readArchiveRows(file, path.Ext(collection.File), tenantImportBatchSize, func(rows []map[string]interface{}) error {
+			return h.items.dynamicHandlers.ImportDynamicItems(ctx, userID, collection.Slug, rows)
+		})
+		file.Close()
+		items[collection.Slug] = count
+		if err != nil {
+			return len(changes), items, fmt.Errorf("items of %s: %w", collection.Slug, err)
+		}
+	}
+	return len(changes), items, nil
+}
+
+// readTenantArchive opens an archive and checks its manifest and schema
+func readTenantArchive(r io.ReaderAt, size int64) (*tenantArchive, error) {
+	reader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("not a zip archive")
+	}
+	archive := &tenantArchive{files: make(map[string]*zip.File)}
+	for _, file := range reader.File {
+		archive.files[file.Name] = file
+	}
+
+	manifest, err := readArchiveFile(archive.files, tenantArchiveManifest)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(manifest, &archive.manifest); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", tenantArchiveManifest, err)
+	}
+	if archive.manifest.Version < 1 || archive.manifest.Version > TenantArchiveVersion {
+		return nil, fmt.Errorf("unsupported archive version %d", archive.manifest.Version)
+	}
+
+	schema, err := readArchiveFile(archive.files, tenantArchiveSchema)
+	if err != nil {
+		return nil, err
+	}
+	if archive.schema, err = ParseSchemaSnapshot(schema, "json"); err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", tenantArchiveSchema, err)
+	}
+
+	for _, collection := range archive.manifest.Collections {
+		ext := path.Ext(collection.File)
+		if ext != ".jsonl" && ext != ".csv" {
+			return nil, fmt.Errorf("collection %s: unsupported data file %q", collection.Slug, collection.File)
+		}
+		if _, ok := archive.files[collection.File]; !ok {
+			return nil, fmt.Errorf("collection %s: %s is missing", collection.Slug, collection.File)
+		}
+	}
+	return archive, nil
+}
+
+// readArchiveFile returns the contents of a file of the archive
+func readArchiveFile(files map[string]*zip.File, name string) ([]byte, error) {
+	file, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%s is missing", name)
+	}
+	r, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return io.ReadAll(r)
+}
+
+// readArchiveRows decodes the items of a .jsonl or .csv data file and passes them to fn in
+// batches of up to batchSize. It returns how many items were passed. Empty CSV cells are
+// NULL.
+func readArchiveRows(r io.Reader, ext string, batchSize int, fn func(rows []map[string]interface{}) error) (int, error) {
+	total := 0
+	batch := make([]map[string]interface{}, 0, batchSize)
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := fn(batch); err != nil {
+			return err
+		}
+		total += len(batch)
+		batch = make([]map[string]interface{}, 0, batchSize)
+		return nil
+	}
+	add := func(row map[string]interface{}) error {
+		batch = append(batch, row)
+		if len(batch) == batchSize {
+			return flush()
+		}
+		return nil
+	}
+
+	switch ext {
+	case ".jsonl":
+		decoder := json.NewDecoder(r)
+		decoder.UseNumber()
+		for line := 1; ; line++ {
+			var row map[string]interface{}
+			err := decoder.Decode(&row)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return total, fmt.Errorf("line %d: %w", line, err)
+			}
+			if err := add(row); err != nil {
+				return total, err
+			}
+		}
+	case ".csv":
+		reader := csv.NewReader(r)
+		header, err := reader.Read()
+		if err == io.EOF {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, err
+		}
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return total, err
+			}
+			row := make(map[string]interface{}, len(header))
+			for i, column := range header {
+				if record[i] == "" {
+					row[column] = nil
+				} else {
+					row[column] = record[i]
+				}
+			}
+			if err := add(row); err != nil {
+				return total, err
+			}
+		}
+	default:
+		return 0, fmt.Errorf("unsupported data file type %s", ext)
+	}
+
+	return total, flush()
+}
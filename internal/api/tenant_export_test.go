@@ -0,0 +1,115 @@
+package api
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"go-rbac-api/internal/fakedata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadArchiveRows(t *testing.T) {
+	var batches [][]map[string]interface{}
+	collect := func(rows []map[string]interface{}) error {
+		batches = append(batches, rows)
+		return nil
+	}
+
+	jsonl := `{"id": "a", "price": 10, "tags": ["x"]}
+{"id": "b", "price": null}
+{"id": "c"}
+`
+	count, err := readArchiveRows(strings.NewReader(jsonl), ".jsonl", 2, collect)
+	require.NoError(t, err)
+	assert.Equal(t, 3, count)
+	require.Len(t, batches, 2)
+	assert.Equal(t, json.Number("10"), batches[0][0]["price"])
+	assert.Nil(t, batches[0][1]["price"])
+	assert.Equal(t, "c", batches[1][0]["id"])
+
+	batches = nil
+	count, err = readArchiveRows(strings.NewReader("id,price\na,10\nb,\n"), ".csv", 10, collect)
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+	require.Len(t, batches, 1)
+	assert.Equal(t, "10", batches[0][0]["price"])
+	assert.Nil(t, batches[0][1]["price"])
+
+	_, err = readArchiveRows(strings.NewReader("{\"id\": \"a\"}\nnot json\n"), ".jsonl", 10, collect)
+	assert.ErrorContains(t, err, "line 2")
+}
+
+func TestReadTenantArchive(t *testing.T) {
+	build := func(files map[string]string) *bytes.Reader {
+		var buf bytes.Buffer
+		w := zip.NewWriter(&buf)
+		for name, body := range files {
+			f, err := w.Create(name)
+			require.NoError(t, err)
+			_, err = f.Write([]byte(body))
+			require.NoError(t, err)
+		}
+		require.NoError(t, w.Close())
+		return bytes.NewReader(buf.Bytes())
+	}
+	manifest := `{"version": 1, "tenant": {"name": "Acme", "slug": "acme"},
+		"collections": [{"slug": "products", "file": "data/products.jsonl", "items": 1}]}`
+	schema := `{"version": 1}`
+
+	r := build(map[string]string{
+		tenantArchiveManifest: manifest,
+		tenantArchiveSchema:   schema,
+		"data/products.jsonl": `{"id": "a"}`,
+	})
+	archive, err := readTenantArchive(r, r.Size())
+	require.NoError(t, err)
+	assert.Equal(t, "acme", archive.manifest.Tenant.Slug)
+	assert.Len(t, archive.manifest.Collections, 1)
+
+	invalid := map[string]map[string]string{
+		"manifest.json is missing":       {tenantArchiveSchema: schema},
+		"unsupported archive version":    {tenantArchiveManifest: `{"version": 2}`, tenantArchiveSchema: schema},
+		"data/products.jsonl is missing": {tenantArchiveManifest: manifest, tenantArchiveSchema: schema},
+	}
+	for message, files := range invalid {
+		r := build(files)
+		_, err := readTenantArchive(r, r.Size())
+		assert.ErrorContains(t, err, message)
+	}
+
+	_, err = readTenantArchive(strings.NewReader("not a zip"), 9)
+	assert.ErrorContains(t, err, "not a zip archive")
+}
+
+func TestAnonymizeRow(t *testing.T) {
+	snapshot := &SchemaSnapshot{Collections: []SnapshotCollection{
+		{Slug: "customers", Fields: []SnapshotField{
+			{Name: "email", Type: "string", PII: PIIEmail},
+			{Name: "name", Type: "string", PII: PIIName},
+			{Name: "plan", Type: "string"},
+		}},
+		{Slug: "orders", Fields: []SnapshotField{{Name: "customer_email", Type: "string", PII: PIIEmail}}},
+		{Slug: "products", Fields: []SnapshotField{{Name: "title", Type: "string"}}},
+	}}
+	pii := snapshotPIIFields(snapshot)
+	assert.Equal(t, map[string]map[string]string{
+		"customers": {"email": PIIEmail, "name": PIIName},
+		"orders":    {"customer_email": PIIEmail},
+	}, pii)
+
+	fakes := fakedata.New(bytes.Repeat([]byte{3}, fakedata.SeedSize))
+	customer := map[string]interface{}{"email": "ada@example.com", "name": "Ada Lovelace", "plan": "pro"}
+	order := map[string]interface{}{"customer_email": "ADA@example.com"}
+	anonymizeRow(fakes, pii["customers"], customer)
+	anonymizeRow(fakes, pii["orders"], order)
+
+	assert.NotEqual(t, "ada@example.com", customer["email"])
+	assert.NotEqual(t, "Ada Lovelace", customer["name"])
+	assert.Equal(t, "pro", customer["plan"], "untagged fields are kept")
+	assert.Equal(t, customer["email"], order["customer_email"], "equal emails still match")
+}
@@ -3,13 +3,19 @@ package api
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
+	"time"
 
 	"go-rbac-api/internal/config"
 	"go-rbac-api/internal/db"
 	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/mail"
 	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/quota"
+	"go-rbac-api/internal/rbac"
 
 	"go-rbac-api/internal/middleware"
 
@@ -19,17 +25,73 @@ import (
 )
 
 type TenantHandler struct {
-	db  *db.DB
-	cfg *config.Config
+	db     *db.DB
+	cfg    *config.Config
+	mailer *mail.Mailer
+	quota  *quota.Checker
+	items  *ItemsHandler // Writes the schema and items of imported tenants
 }
 
-func NewTenantHandler(db *db.DB, cfg *config.Config) *TenantHandler {
+// NewTenantHandler creates a TenantHandler. mailer may be nil when no invites are sent,
+// and items may be nil when no tenants are imported, as in the admin CLI.
+func NewTenantHandler(db *db.DB, cfg *config.Config, mailer *mail.Mailer, items *ItemsHandler) *TenantHandler {
 	return &TenantHandler{
-		db:  db,
-		cfg: cfg,
+		db:     db,
+		cfg:    cfg,
+		mailer: mailer,
+		quota:  quota.NewChecker(db, quota.Defaults(cfg)),
+		items:  items,
 	}
 }
 
+// requireSuperAdmin answers with 403 unless the caller is a super admin and reports
+// whether they are
+func requireSuperAdmin(c *gin.Context) bool {
+	if auth, ok := middleware.GetAuthProvider(c); ok && auth.IsSuperAdmin {
+		return true
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Super admin access required"})
+	return false
+}
+
+// requireTenantAdmin answers with 403 unless the caller is an admin of the tenant or a
+// super admin and reports whether they are. Admins of other tenants are not.
+func (h *TenantHandler) requireTenantAdmin(c *gin.Context, tenantID uuid.UUID) bool {
+	auth, ok := middleware.GetAuthProvider(c)
+	if ok && (auth.IsSuperAdmin || (auth.IsAdmin && auth.TenantID == tenantID)) {
+		return true
+	}
+	if ok {
+		// The caller may administer the tenant while signed in to another one
+		roles, err := h.db.Queries.GetUserTenantRoles(c.Request.Context(), sqlc.GetUserTenantRolesParams{
+			UserID:   auth.UserID,
+			TenantID: tenantID,
+		})
+		if err == nil {
+			for _, role := range roles {
+				if role.Name == "admin" {
+					return true
+				}
+			}
+		}
+	}
+	c.JSON(http.StatusForbidden, gin.H{"error": "Tenant admin access required"})
+	return false
+}
+
+// isTenantMember reports whether the caller belongs to the tenant or is a super admin
+func (h *TenantHandler) isTenantMember(c *gin.Context, tenantID uuid.UUID) bool {
+	auth, ok := middleware.GetAuthProvider(c)
+	if !ok {
+		return false
+	}
+	if auth.IsSuperAdmin {
+		return true
+	}
+	_, err := h.db.Queries.GetUserTenant(c.Request.Context(), sqlc.GetUserTenantParams{UserID: auth.UserID, TenantID: tenantID})
+	return err == nil
+}
+
 // CreateTenant handles POST /tenants requests with full initialization
 // @Summary      Create Tenant
 // @Tags         tenants
@@ -42,8 +104,12 @@ func NewTenantHandler(db *db.DB, cfg *config.Config) *TenantHandler {
 // @Router       /tenants [post]
 func (h *TenantHandler) CreateTenant(c *gin.Context) {
 	var createReq models.CreateTenantRequest
-	if err := c.ShouldBindJSON(&createReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body: " + err.Error()})
+	if err := decodeJSON(c, &createReq, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body: "+err.Error())
+		return
+	}
+	if _, err := FindCollectionTemplate(createReq.Template); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
 
@@ -54,6 +120,10 @@ func (h *TenantHandler) CreateTenant(c *gin.Context) {
 		return
 	}
 
+	if h.domainTaken(c, createReq.Domain, uuid.Nil) {
+		return
+	}
+
 	// Get the creating user
 	userID, exists := middleware.GetUserID(c)
 	if !exists {
@@ -61,58 +131,57 @@ func (h *TenantHandler) CreateTenant(c *gin.Context) {
 		return
 	}
 
-	// Generate UUID for new tenant
-	tenantID := uuid.New()
-
-	// Start a database transaction for atomicity
-	tx, err := h.db.DB.BeginTx(c.Request.Context(), nil)
+	tenant, err := h.Provision(c.Request.Context(), createReq, userID)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start transaction"})
+		respondError(c, err, "Failed to create tenant")
 		return
 	}
-	defer tx.Rollback()
 
-	// Create tenant in database
-	tenant, err := h.db.Queries.CreateTenant(c.Request.Context(), sqlc.CreateTenantParams{
-		ID:       tenantID,
-		Name:     createReq.Name,
-		Slug:     createReq.Slug,
-		Domain:   sql.NullString{String: createReq.Domain, Valid: createReq.Domain != ""},
-		Settings: pqtype.NullRawMessage{Valid: false},
+	// Return success response
+	c.JSON(http.StatusCreated, models.TenantResponse{
+		Message: "Tenant created and initialized successfully",
+		Tenant:  tenantModel(tenant),
 	})
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create tenant"})
-		return
-	}
+}
 
-	// Initialize tenant with default roles, permissions, and collections
-	if err := h.initializeTenant(c.Request.Context(), tenantID, userID); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to initialize tenant: " + err.Error()})
-		return
-	}
+// Provision creates a tenant and initializes it with the default roles, permissions and
+// collections, making owner its admin. Callers check that the slug is free.
+func (h *TenantHandler) Provision(ctx context.Context, req models.CreateTenantRequest, ownerID uuid.UUID) (sqlc.Tenant, error) {
+	// Generate UUID for new tenant
+	tenantID := uuid.New()
 
-	// Commit the transaction
-	if err := tx.Commit(); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to commit transaction"})
-		return
-	}
+	// The tenant and everything it is initialized with are created together
+	var tenant sqlc.Tenant
+	err := h.db.InTransaction(ctx, func(tx *db.Tx) error {
+		// Create tenant in database
+		var err error
+		tenant, err = tx.CreateTenant(ctx, sqlc.CreateTenantParams{
+			ID:       tenantID,
+			Name:     req.Name,
+			Slug:     req.Slug,
+			Domain:   sql.NullString{String: req.Domain, Valid: req.Domain != ""},
+			Settings: pqtype.NullRawMessage{Valid: false},
+		})
+		if err != nil {
+			return fmt.Errorf("Failed to create tenant")
+		}
 
-	// Return success response
-	c.JSON(http.StatusCreated, models.TenantResponse{
-		Message: "Tenant created and initialized successfully",
-		Tenant: models.Tenant{
-			ID:        tenant.ID,
-			Name:      tenant.Name,
-			Slug:      tenant.Slug,
-			Domain:    tenant.Domain.String,
-			IsActive:  tenant.IsActive.Bool,
-			CreatedAt: tenant.CreatedAt.Time,
-			UpdatedAt: tenant.UpdatedAt.Time,
-		},
+		// Initialize tenant with default roles, permissions, and collections
+		if err := h.initializeTenant(ctx, tx, tenantID, ownerID, req.Template); err != nil {
+			return fmt.Errorf("Failed to initialize tenant: %w", err)
+		}
+		return nil
 	})
+	if err != nil {
+		return sqlc.Tenant{}, err
+	}
+	rbac.InvalidateUserPermissions(ownerID)
+
+	return tenant, nil
 }
 
-// GetTenants handles GET /tenants requests
+// GetTenants handles GET /tenants requests, listing the tenants of the caller (every
+// tenant for super admins)
 // @Summary      Get All Tenants
 // @Tags         tenants
 // @Produce      json
@@ -120,7 +189,20 @@ func (h *TenantHandler) CreateTenant(c *gin.Context) {
 // @Failure      500 {object} map[string]string
 // @Router       /tenants [get]
 func (h *TenantHandler) GetTenants(c *gin.Context) {
-	tenants, err := h.db.Queries.GetAllTenants(c.Request.Context())
+	auth, ok := middleware.GetAuthProvider(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+
+	// Super admins see every tenant, everyone else the tenants they belong to
+	var tenants []sqlc.Tenant
+	var err error
+	if auth.IsSuperAdmin {
+		tenants, err = h.db.Queries.GetAllTenants(c.Request.Context())
+	} else {
+		tenants, err = h.db.Queries.GetUserTenants(c.Request.Context(), auth.UserID)
+	}
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tenants"})
 		return
@@ -128,15 +210,7 @@ func (h *TenantHandler) GetTenants(c *gin.Context) {
 
 	var response []models.Tenant
 	for _, tenant := range tenants {
-		response = append(response, models.Tenant{
-			ID:        tenant.ID,
-			Name:      tenant.Name,
-			Slug:      tenant.Slug,
-			Domain:    tenant.Domain.String,
-			IsActive:  tenant.IsActive.Bool,
-			CreatedAt: tenant.CreatedAt.Time,
-			UpdatedAt: tenant.UpdatedAt.Time,
-		})
+		response = append(response, tenantModel(tenant))
 	}
 
 	c.JSON(http.StatusOK, response)
@@ -158,21 +232,19 @@ func (h *TenantHandler) GetTenant(c *gin.Context) {
 		return
 	}
 
+	// Tenants of others are reported as missing
+	if !h.isTenantMember(c, tenantID) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return
+	}
+
 	tenant, err := h.db.Queries.GetTenantByID(c.Request.Context(), tenantID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
 		return
 	}
 
-	c.JSON(http.StatusOK, models.Tenant{
-		ID:        tenant.ID,
-		Name:      tenant.Name,
-		Slug:      tenant.Slug,
-		Domain:    tenant.Domain.String,
-		IsActive:  tenant.IsActive.Bool,
-		CreatedAt: tenant.CreatedAt.Time,
-		UpdatedAt: tenant.UpdatedAt.Time,
-	})
+	c.JSON(http.StatusOK, tenantModel(tenant))
 }
 
 // UpdateTenant handles PUT /tenants/:id requests
@@ -193,9 +265,13 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 		return
 	}
 
+	if !h.requireTenantAdmin(c, tenantID) {
+		return
+	}
+
 	var updateReq models.UpdateTenantRequest
-	if err := c.ShouldBindJSON(&updateReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	if err := decodeJSON(c, &updateReq, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body")
 		return
 	}
 
@@ -214,9 +290,44 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 		existingTenant.Slug = *updateReq.Slug
 	}
 	if updateReq.Domain != nil {
+		if h.domainTaken(c, *updateReq.Domain, tenantID) {
+			return
+		}
 		existingTenant.Domain.String = *updateReq.Domain
 		existingTenant.Domain.Valid = *updateReq.Domain != ""
 	}
+	if updateReq.Mail != nil {
+		if err := updateReq.Mail.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings, err := withTenantSetting(existingTenant.Settings.RawMessage, "mail", *updateReq.Mail)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		existingTenant.Settings = pqtype.NullRawMessage{RawMessage: settings, Valid: true}
+	}
+	if updateReq.RequireTwoFactor != nil {
+		settings, err := withTenantSetting(existingTenant.Settings.RawMessage, "require_2fa", *updateReq.RequireTwoFactor)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		existingTenant.Settings = pqtype.NullRawMessage{RawMessage: settings, Valid: true}
+	}
+	if updateReq.Limits != nil {
+		if err := updateReq.Limits.Validate(); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		settings, err := withTenantSetting(existingTenant.Settings.RawMessage, "limits", *updateReq.Limits)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		existingTenant.Settings = pqtype.NullRawMessage{RawMessage: settings, Valid: true}
+	}
 
 	// Update tenant in database
 	updatedTenant, err := h.db.Queries.UpdateTenant(c.Request.Context(), sqlc.UpdateTenantParams{
@@ -231,24 +342,19 @@ func (h *TenantHandler) UpdateTenant(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, models.Tenant{
-		ID:        updatedTenant.ID,
-		Name:      updatedTenant.Name,
-		Slug:      updatedTenant.Slug,
-		Domain:    updatedTenant.Domain.String,
-		IsActive:  updatedTenant.IsActive.Bool,
-		CreatedAt: updatedTenant.CreatedAt.Time,
-		UpdatedAt: updatedTenant.UpdatedAt.Time,
-	})
+	c.JSON(http.StatusOK, tenantModel(updatedTenant))
 }
 
-// DeleteTenant handles DELETE /tenants/:id requests
+// DeleteTenant handles DELETE /tenants/:id requests. The tenant is deactivated and purged
+// after TENANT_PURGE_AFTER, until when POST /tenants/:id/restore brings it back; with a
+// zero TENANT_PURGE_AFTER it is removed immediately.
 // @Summary      Delete Tenant
 // @Tags         tenants
 // @Produce      json
 // @Param        id    path     string true "Tenant ID"
 // @Success      200   {object} models.DeleteTenantResponse
 // @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
 // @Failure      404   {object} map[string]string
 // @Router       /tenants/{id} [delete]
 func (h *TenantHandler) DeleteTenant(c *gin.Context) {
@@ -257,14 +363,34 @@ func (h *TenantHandler) DeleteTenant(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
 		return
 	}
+	if !h.requireTenantAdmin(c, tenantID) {
+		return
+	}
 
 	// Check if tenant exists
-	_, err = h.db.Queries.GetTenantByID(c.Request.Context(), tenantID)
-	if err != nil {
+	tenant, err := h.db.Queries.GetTenantByID(c.Request.Context(), tenantID)
+	if err != nil || tenant.DeletedAt.Valid {
 		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
 		return
 	}
 
+	if h.cfg.TenantPurgeAfter > 0 {
+		tenant, err = h.db.Queries.SoftDeleteTenant(c.Request.Context(), sqlc.SoftDeleteTenantParams{
+			ID:      tenantID,
+			PurgeAt: sql.NullTime{Time: time.Now().Add(h.cfg.TenantPurgeAfter), Valid: true},
+		})
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete tenant"})
+			return
+		}
+
+		c.JSON(http.StatusOK, models.DeleteTenantResponse{
+			Message: "Tenant deleted; it can be restored until it is purged",
+			PurgeAt: nullTimePtr(tenant.PurgeAt),
+		})
+		return
+	}
+
 	// Delete tenant
 	err = h.db.Queries.DeleteTenant(c.Request.Context(), tenantID)
 	if err != nil {
@@ -272,6 +398,9 @@ func (h *TenantHandler) DeleteTenant(c *gin.Context) {
 		return
 	}
 
+	// The tenant's roles and permissions are gone with it
+	rbac.InvalidatePermissions()
+
 	c.JSON(http.StatusOK, models.DeleteTenantResponse{
 		Message: "Tenant deleted successfully",
 	})
@@ -295,9 +424,13 @@ func (h *TenantHandler) AddUserToTenant(c *gin.Context) {
 		return
 	}
 
+	if !h.requireTenantAdmin(c, tenantID) {
+		return
+	}
+
 	var addReq models.AddUserToTenantRequest
-	if err := c.ShouldBindJSON(&addReq); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+	if err := decodeJSON(c, &addReq, BodyAdmin); err != nil {
+		respondBodyError(c, err, "Invalid request body")
 		return
 	}
 
@@ -309,12 +442,23 @@ func (h *TenantHandler) AddUserToTenant(c *gin.Context) {
 	}
 
 	// Verify user exists
-	_, err = h.db.Queries.GetUserByID(c.Request.Context(), addReq.UserID)
+	user, err := h.db.Queries.GetUserByID(c.Request.Context(), addReq.UserID)
 	if err != nil {
 		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
 		return
 	}
 
+	// The membership role is the user's role in this tenant, so it must be one of its roles
+	role, err := h.db.Queries.GetRoleByID(c.Request.Context(), addReq.RoleID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Role not found"})
+		return
+	}
+	if role.TenantID.Valid && role.TenantID.UUID != tenantID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Role belongs to another tenant"})
+		return
+	}
+
 	// Add user to tenant
 	err = h.db.Queries.AddUserToTenant(c.Request.Context(), sqlc.AddUserToTenantParams{
 		UserID:   addReq.UserID,
@@ -325,12 +469,75 @@ func (h *TenantHandler) AddUserToTenant(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add user to tenant"})
 		return
 	}
+	rbac.InvalidateUserPermissions(addReq.UserID)
+
+	h.sendInvite(c, tenantID, user)
 
 	c.JSON(http.StatusOK, models.UserTenantResponse{
 		Message: "User added to tenant successfully",
 	})
 }
 
+// sendInvite tells a user they were added to a tenant. Failures are only logged.
+func (h *TenantHandler) sendInvite(c *gin.Context, tenantID uuid.UUID, user sqlc.User) {
+	if h.mailer == nil {
+		return
+	}
+	data := mail.Data{
+		Name:      strings.TrimSpace(user.FirstName.String + " " + user.LastName.String),
+		InvitedBy: c.GetString("email"),
+	}
+	if err := h.mailer.Send(c.Request.Context(), tenantID, user.Email, mail.TemplateInvite, data); err != nil {
+		middleware.GetLogger(c).Error("failed to send invite email", "user_id", user.ID, "tenant_id", tenantID, "error", err)
+	}
+}
+
+// domainTaken answers with 409 when another tenant than tenantID serves domain and reports
+// whether it did. Requests to a domain resolve to its tenant, so domains are unique.
+func (h *TenantHandler) domainTaken(c *gin.Context, domain string, tenantID uuid.UUID) bool {
+	if domain == "" {
+		return false
+	}
+	other, err := h.db.Queries.GetTenantByDomain(c.Request.Context(), domain)
+	if err != nil || other.ID == tenantID {
+		return false
+	}
+	c.JSON(http.StatusConflict, gin.H{"error": "Domain is already used by another tenant"})
+	return true
+}
+
+// withTenantSetting stores one key of a tenant's settings JSON, keeping the other settings
+func withTenantSetting(settings json.RawMessage, key string, value interface{}) (json.RawMessage, error) {
+	all := make(map[string]json.RawMessage)
+	if len(settings) > 0 {
+		if err := json.Unmarshal(settings, &all); err != nil {
+			return nil, fmt.Errorf("invalid tenant settings: %w", err)
+		}
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	all[key] = encoded
+	return json.Marshal(all)
+}
+
+// withoutTenantSetting returns the tenant settings without one top-level key
+func withoutTenantSetting(settings json.RawMessage, key string) (json.RawMessage, error) {
+	if len(settings) == 0 {
+		return nil, nil
+	}
+	all := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(settings, &all); err != nil {
+		return nil, fmt.Errorf("invalid tenant settings: %w", err)
+	}
+	delete(all, key)
+	if len(all) == 0 {
+		return nil, nil
+	}
+	return json.Marshal(all)
+}
+
 // RemoveUserFromTenant handles DELETE /tenants/:id/users/:user_id requests
 // @Summary      Remove User from Tenant
 // @Tags         tenants
@@ -353,6 +560,10 @@ func (h *TenantHandler) RemoveUserFromTenant(c *gin.Context) {
 		return
 	}
 
+	if !h.requireTenantAdmin(c, tenantID) {
+		return
+	}
+
 	// Remove user from tenant
 	err = h.db.Queries.RemoveUserFromTenant(c.Request.Context(), sqlc.RemoveUserFromTenantParams{
 		UserID:   userID,
@@ -362,6 +573,7 @@ func (h *TenantHandler) RemoveUserFromTenant(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove user from tenant"})
 		return
 	}
+	rbac.InvalidateUserPermissions(userID)
 
 	c.JSON(http.StatusOK, models.UserTenantResponse{
 		Message: "User removed from tenant successfully",
@@ -420,23 +632,27 @@ func (h *TenantHandler) JoinTenant(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to join tenant"})
 		return
 	}
+	rbac.InvalidateUserPermissions(userID)
 
 	c.JSON(http.StatusOK, models.UserTenantResponse{
 		Message: "Successfully joined tenant",
 	})
 }
 
-// initializeTenant sets up a new tenant with default roles, permissions, and collections
-func (h *TenantHandler) initializeTenant(ctx context.Context, tenantID uuid.UUID, creatorUserID uuid.UUID) error {
+// initializeTenant sets up a new tenant with default roles, permissions, and collections,
+// running every query on tx
+func (h *TenantHandler) initializeTenant(ctx context.Context, tx *db.Tx, tenantID uuid.UUID, creatorUserID uuid.UUID, template string) error {
+	queries := tx.Queries
+
 	// 1. Create default roles
-	roles, err := h.createDefaultRoles(ctx, tenantID)
+	roles, err := h.createDefaultRoles(ctx, queries, tenantID)
 	if err != nil {
 		return fmt.Errorf("failed to create default roles: %w", err)
 	}
 
 	// 2. Add creator as admin to the tenant
 	adminRole := roles["admin"]
-	if err := h.db.Queries.AddUserToTenant(ctx, sqlc.AddUserToTenantParams{
+	if err := queries.AddUserToTenant(ctx, sqlc.AddUserToTenantParams{
 		UserID:   creatorUserID,
 		TenantID: tenantID,
 		RoleID:   uuid.NullUUID{UUID: adminRole.ID, Valid: true},
@@ -445,7 +661,7 @@ func (h *TenantHandler) initializeTenant(ctx context.Context, tenantID uuid.UUID
 	}
 
 	// 3. Add admin role to user_roles table
-	if err := h.db.Queries.AddUserRole(ctx, sqlc.AddUserRoleParams{
+	if err := queries.AddUserRole(ctx, sqlc.AddUserRoleParams{
 		UserID: creatorUserID,
 		RoleID: adminRole.ID,
 	}); err != nil {
@@ -453,39 +669,51 @@ func (h *TenantHandler) initializeTenant(ctx context.Context, tenantID uuid.UUID
 	}
 
 	// 4. Create default permissions for system tables
-	if err := h.createDefaultPermissions(ctx, tenantID, roles); err != nil {
+	if err := h.createDefaultPermissions(ctx, queries, tenantID, roles); err != nil {
 		return fmt.Errorf("failed to create default permissions: %w", err)
 	}
 
-	// 5. Create default collections
-	if err := h.createDefaultCollections(ctx, tenantID, creatorUserID); err != nil {
+	// 5. Create the collections of the chosen template (see collection_templates.go)
+	if err := h.createDefaultCollections(ctx, tx, tenantID, creatorUserID, template); err != nil {
 		return fmt.Errorf("failed to create default collections: %w", err)
 	}
 
 	return nil
 }
 
-// createDefaultRoles creates the standard roles for a new tenant
-func (h *TenantHandler) createDefaultRoles(ctx context.Context, tenantID uuid.UUID) (map[string]sqlc.Role, error) {
+// createDefaultRoles creates the standard roles for a new tenant as a hierarchy in which
+// each role inherits the permissions of the one below it:
+// viewer <- editor <- manager <- admin
+// plus the public role, which starts without permissions, for requests without credentials
+func (h *TenantHandler) createDefaultRoles(ctx context.Context, queries *sqlc.Queries, tenantID uuid.UUID) (map[string]sqlc.Role, error) {
 	roles := make(map[string]sqlc.Role)
 
+	// Parents are listed before the roles that inherit from them
 	defaultRoles := []struct {
 		name        string
 		description string
+		parent      string
 	}{
-		{"admin", "Full system access and management"},
-		{"manager", "Can manage users, content, and settings"},
-		{"editor", "Can create and edit content"},
-		{"viewer", "Can view content and data"},
+		{"viewer", "Can view content and data", ""},
+		{"editor", "Can create and edit content", "viewer"},
+		{"manager", "Can manage users, content, and settings", "editor"},
+		{"admin", "Full system access and management", "manager"},
+		{rbac.PublicRole, "Requests made without credentials", ""},
 	}
 
 	for _, roleData := range defaultRoles {
+		var parentID uuid.NullUUID
+		if roleData.parent != "" {
+			parentID = uuid.NullUUID{UUID: roles[roleData.parent].ID, Valid: true}
+		}
+
 		roleID := uuid.New()
-		role, err := h.db.Queries.CreateRole(ctx, sqlc.CreateRoleParams{
+		role, err := queries.CreateRole(ctx, sqlc.CreateRoleParams{
 			ID:          roleID,
 			Name:        roleData.name,
 			Description: sql.NullString{String: roleData.description, Valid: true},
 			TenantID:    uuid.NullUUID{UUID: tenantID, Valid: true},
+			ParentID:    parentID,
 		})
 		if err != nil {
 			return nil, fmt.Errorf("failed to create role %s: %w", roleData.name, err)
@@ -497,19 +725,19 @@ func (h *TenantHandler) createDefaultRoles(ctx context.Context, tenantID uuid.UU
 }
 
 // createDefaultPermissions creates standard permissions for system tables
-func (h *TenantHandler) createDefaultPermissions(ctx context.Context, tenantID uuid.UUID, roles map[string]sqlc.Role) error {
+func (h *TenantHandler) createDefaultPermissions(ctx context.Context, queries *sqlc.Queries, tenantID uuid.UUID, roles map[string]sqlc.Role) error {
 	// System tables that need permissions
 	systemTables := []string{
 		"users", "roles", "permissions", "collections", "fields",
 		"tenants", "api_keys", "user_tenants", "user_roles",
 	}
 
-	// Define role permissions
+	// Define role permissions. Roles inherit from their parent (see createDefaultRoles),
+	// so each role only lists what it adds.
 	rolePermissions := map[string][]string{
-		"admin":   {"create", "read", "update", "delete"},
-		"manager": {"create", "read", "update"},
-		"editor":  {"create", "read", "update"},
-		"viewer":  {"read"},
+		"admin":  {"delete"},
+		"editor": {"create", "update"},
+		"viewer": {"read"},
 	}
 
 	for roleName, role := range roles {
@@ -521,7 +749,7 @@ func (h *TenantHandler) createDefaultPermissions(ctx context.Context, tenantID u
 		for _, table := range systemTables {
 			for _, action := range permissions {
 				permissionID := uuid.New()
-				_, err := h.db.Queries.CreatePermission(ctx, sqlc.CreatePermissionParams{
+				_, err := queries.CreatePermission(ctx, sqlc.CreatePermissionParams{
 					ID:            permissionID,
 					RoleID:        uuid.NullUUID{UUID: role.ID, Valid: true},
 					TableName:     table,
@@ -529,6 +757,7 @@ func (h *TenantHandler) createDefaultPermissions(ctx context.Context, tenantID u
 					FieldFilter:   pqtype.NullRawMessage{Valid: false},
 					AllowedFields: []string{"*"}, // Full field access for system tables
 					TenantID:      uuid.NullUUID{UUID: tenantID, Valid: true},
+					Scope:         rbac.ScopeAll,
 				})
 				if err != nil {
 					return fmt.Errorf("failed to create permission %s:%s for role %s: %w",
@@ -540,154 +769,3 @@ func (h *TenantHandler) createDefaultPermissions(ctx context.Context, tenantID u
 
 	return nil
 }
-
-// createDefaultCollections creates some useful default collections for the tenant
-func (h *TenantHandler) createDefaultCollections(ctx context.Context, tenantID uuid.UUID, creatorUserID uuid.UUID) error {
-	defaultCollections := []struct {
-		name        string
-		displayName string
-		description string
-		icon        string
-	}{
-		{
-			name:        "customers",
-			displayName: "Customers",
-			description: "Customer information and contact details",
-			icon:        "👥",
-		},
-		{
-			name:        "products",
-			displayName: "Products",
-			description: "Product catalog and inventory",
-			icon:        "📦",
-		},
-		{
-			name:        "orders",
-			displayName: "Orders",
-			description: "Customer orders and transactions",
-			icon:        "📋",
-		},
-	}
-
-	for _, collectionData := range defaultCollections {
-		collectionID := uuid.New()
-		_, err := h.db.Queries.CreateCollection(ctx, sqlc.CreateCollectionParams{
-			ID:          collectionID,
-			Name:        collectionData.displayName, // Display name (e.g., "Customers")
-			Slug:        collectionData.name,        // URL-friendly slug (e.g., "customers")
-			DisplayName: sql.NullString{String: collectionData.displayName, Valid: true},
-			Description: sql.NullString{String: collectionData.description, Valid: true},
-			Icon:        sql.NullString{String: collectionData.icon, Valid: true},
-			IsSystem:    sql.NullBool{Bool: false, Valid: true},
-			TenantID:    uuid.NullUUID{UUID: tenantID, Valid: true},
-			CreatedBy:   uuid.NullUUID{UUID: creatorUserID, Valid: true},
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create collection %s: %w", collectionData.name, err)
-		}
-
-		// Add default fields for each collection
-		if err := h.createDefaultFields(ctx, collectionID, collectionData.name, tenantID); err != nil {
-			return fmt.Errorf("failed to create fields for collection %s: %w", collectionData.name, err)
-		}
-	}
-
-	return nil
-}
-
-// createDefaultFields creates standard fields for a collection
-func (h *TenantHandler) createDefaultFields(ctx context.Context, collectionID uuid.UUID, collectionName string, tenantID uuid.UUID) error {
-	// Define default fields based on collection type
-	var defaultFields []struct {
-		name        string
-		displayName string
-		type_       string
-		isRequired  bool
-		isPrimary   bool
-		sortOrder   int32
-	}
-
-	switch collectionName {
-	case "customers":
-		defaultFields = []struct {
-			name        string
-			displayName string
-			type_       string
-			isRequired  bool
-			isPrimary   bool
-			sortOrder   int32
-		}{
-			{"name", "Name", "string", true, true, 1},
-			{"email", "Email", "string", true, false, 2},
-			{"phone", "Phone", "string", false, false, 3},
-			{"address", "Address", "text", false, false, 4},
-		}
-	case "products":
-		defaultFields = []struct {
-			name        string
-			displayName string
-			type_       string
-			isRequired  bool
-			isPrimary   bool
-			sortOrder   int32
-		}{
-			{"name", "Product Name", "string", true, true, 1},
-			{"description", "Description", "text", false, false, 2},
-			{"price", "Price", "decimal", true, false, 3},
-			{"sku", "SKU", "string", true, false, 4},
-			{"stock", "Stock Quantity", "integer", false, false, 5},
-		}
-	case "orders":
-		defaultFields = []struct {
-			name        string
-			displayName string
-			type_       string
-			isRequired  bool
-			isPrimary   bool
-			sortOrder   int32
-		}{
-			{"order_number", "Order Number", "string", true, true, 1},
-			{"customer_id", "Customer", "uuid", true, false, 2},
-			{"total_amount", "Total Amount", "decimal", true, false, 3},
-			{"status", "Status", "string", true, false, 4},
-			{"order_date", "Order Date", "datetime", true, false, 5},
-		}
-	default:
-		// Generic fields for any collection
-		defaultFields = []struct {
-			name        string
-			displayName string
-			type_       string
-			isRequired  bool
-			isPrimary   bool
-			sortOrder   int32
-		}{
-			{"name", "Name", "string", true, true, 1},
-			{"description", "Description", "text", false, false, 2},
-		}
-	}
-
-	for _, fieldData := range defaultFields {
-		fieldID := uuid.New()
-		_, err := h.db.Queries.CreateField(ctx, sqlc.CreateFieldParams{
-			ID:              fieldID,
-			CollectionID:    uuid.NullUUID{UUID: collectionID, Valid: true},
-			Name:            fieldData.name,
-			DisplayName:     sql.NullString{String: fieldData.displayName, Valid: true},
-			Type:            fieldData.type_,
-			IsPrimary:       sql.NullBool{Bool: fieldData.isPrimary, Valid: true},
-			IsRequired:      sql.NullBool{Bool: fieldData.isRequired, Valid: true},
-			IsUnique:        sql.NullBool{Bool: false, Valid: true},
-			DefaultValue:    sql.NullString{Valid: false},
-			ValidationRules: pqtype.NullRawMessage{Valid: false},
-			RelationConfig:  pqtype.NullRawMessage{Valid: false},
-			SortOrder:       sql.NullInt32{Int32: fieldData.sortOrder, Valid: true},
-			TenantID:        uuid.NullUUID{UUID: tenantID, Valid: true},
-		})
-		if err != nil {
-			return fmt.Errorf("failed to create field %s: %w", fieldData.name, err)
-		}
-	}
-
-	return nil
-}
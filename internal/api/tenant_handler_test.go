@@ -0,0 +1,125 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/dbtest"
+	"go-rbac-api/internal/middleware"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantHandler_RequiresTenantAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewTenantHandler(newOfflineDB(t), &config.Config{TenantPurgeAfter: time.Hour}, nil, nil)
+	tenantID := uuid.New()
+
+	callers := []struct {
+		name      string
+		auth      *middleware.AuthProvider
+		forbidden bool
+	}{
+		{"admin of another tenant", &middleware.AuthProvider{UserID: uuid.New(), TenantID: uuid.New(), IsAdmin: true}, true},
+		{"editor of the tenant", &middleware.AuthProvider{UserID: uuid.New(), TenantID: tenantID, Roles: []string{"editor"}}, true},
+		{"admin of the tenant", &middleware.AuthProvider{UserID: uuid.New(), TenantID: tenantID, IsAdmin: true}, false},
+		{"super admin", &middleware.AuthProvider{UserID: uuid.New(), TenantID: uuid.New(), IsSuperAdmin: true}, false},
+	}
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, "/tenants/" + tenantID.String(), `{"name":"Renamed"}`},
+		{http.MethodDelete, "/tenants/" + tenantID.String(), ""},
+		{http.MethodPost, "/tenants/" + tenantID.String() + "/users", `{"user_id":"` + uuid.NewString() + `"}`},
+		{http.MethodDelete, "/tenants/" + tenantID.String() + "/users/" + uuid.NewString(), ""},
+	}
+
+	for _, caller := range callers {
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set("auth", caller.auth) })
+		router.PUT("/tenants/:id", handler.UpdateTenant)
+		router.DELETE("/tenants/:id", handler.DeleteTenant)
+		router.POST("/tenants/:id/users", handler.AddUserToTenant)
+		router.DELETE("/tenants/:id/users/:user_id", handler.RemoveUserFromTenant)
+
+		for _, tt := range requests {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+			if caller.forbidden {
+				assert.Equal(t, http.StatusForbidden, w.Code, "%s: %s %s", caller.name, tt.method, tt.path)
+			} else {
+				assert.NotEqual(t, http.StatusForbidden, w.Code, "%s: %s %s", caller.name, tt.method, tt.path)
+			}
+		}
+	}
+}
+
+func TestTenantHandler_GetTenantHidesOtherTenants(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewTenantHandler(newOfflineDB(t), &config.Config{}, nil, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("auth", &middleware.AuthProvider{UserID: uuid.New(), TenantID: uuid.New(), IsAdmin: true})
+	})
+	router.GET("/tenants/:id", handler.GetTenant)
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/tenants/"+uuid.NewString(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTenantHandler_AddUserToTenantChecksRoleTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := dbtest.SQLite(t)
+	handler := NewTenantHandler(database, &config.Config{}, nil, nil)
+	ctx := context.Background()
+
+	adminOfMain := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+	other, err := database.Queries.CreateTenant(ctx, sqlc.CreateTenantParams{ID: uuid.New(), Name: "Other", Slug: "other"})
+	require.NoError(t, err)
+	editor, err := database.Queries.CreateRole(ctx, sqlc.CreateRoleParams{ID: uuid.New(), Name: "editor", TenantID: uuid.NullUUID{UUID: other.ID, Valid: true}})
+	require.NoError(t, err)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("auth", &middleware.AuthProvider{UserID: uuid.New(), TenantID: other.ID, IsAdmin: true})
+	})
+	router.POST("/tenants/:id/users", handler.AddUserToTenant)
+	add := func(roleID uuid.UUID) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		body := `{"user_id":"38eae290-37b8-46a7-82ee-ae842d85c894","tenant_id":"` + other.ID.String() + `","role_id":"` + roleID.String() + `"}`
+		req := httptest.NewRequest(http.MethodPost, "/tenants/"+other.ID.String()+"/users", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	// The admin role of the main tenant would make the user an admin of this one
+	w := add(adminOfMain)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "another tenant")
+
+	w = add(editor.ID)
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	roles, err := database.Queries.GetUserTenantRoles(ctx, sqlc.GetUserTenantRolesParams{
+		UserID:   uuid.MustParse("38eae290-37b8-46a7-82ee-ae842d85c894"),
+		TenantID: other.ID,
+	})
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, "editor", roles[0].Name)
+}
@@ -0,0 +1,141 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains the tenant lifecycle: suspension, deletion and restoring deleted tenants.
+//
+// Tenant Lifecycle Endpoints:
+// - POST   /tenants/:id/suspend - Block sign-ins and API access of the tenant's members
+// - POST   /tenants/:id/resume  - Lift a suspension
+// - DELETE /tenants/:id         - Delete the tenant; it is purged after TENANT_PURGE_AFTER
+// - POST   /tenants/:id/restore - Bring back a deleted tenant before it is purged
+//
+// Suspending, resuming and restoring are up to super admins; tenant admins can delete
+// their tenant.
+//
+// Suspended and deleted tenants are inactive: tokens and API keys of the tenant are
+// rejected with 403 and their members cannot sign in to it. Their data stays untouched
+// until a deleted tenant is purged by the tenants.Purger.
+package api
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// tenantModel converts a tenant row to its API representation
+func tenantModel(tenant sqlc.Tenant) models.Tenant {
+	return models.Tenant{
+		ID:          tenant.ID,
+		Name:        tenant.Name,
+		Slug:        tenant.Slug,
+		Domain:      tenant.Domain.String,
+		IsActive:    tenant.IsActive.Bool,
+		Status:      tenantStatus(tenant),
+		SuspendedAt: nullTimePtr(tenant.SuspendedAt),
+		DeletedAt:   nullTimePtr(tenant.DeletedAt),
+		PurgeAt:     nullTimePtr(tenant.PurgeAt),
+		CreatedAt:   tenant.CreatedAt.Time,
+		UpdatedAt:   tenant.UpdatedAt.Time,
+	}
+}
+
+// tenantStatus reports where a tenant is in its lifecycle. Deletion wins over suspension.
+func tenantStatus(tenant sqlc.Tenant) string {
+	switch {
+	case tenant.DeletedAt.Valid:
+		return models.TenantStatusDeleted
+	case tenant.SuspendedAt.Valid:
+		return models.TenantStatusSuspended
+	default:
+		return models.TenantStatusActive
+	}
+}
+
+// nullTimePtr returns the time of t, or nil when it is NULL
+func nullTimePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
+
+// SuspendTenant handles POST /tenants/:id/suspend requests. Members of a suspended tenant
+// cannot sign in to it and its tokens and API keys are rejected until it is resumed.
+// @Summary      Suspend a tenant
+// @Tags         tenants
+// @Produce      json
+// @Param        id    path     string true "Tenant ID"
+// @Success      200   {object} models.Tenant
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/suspend [post]
+func (h *TenantHandler) SuspendTenant(c *gin.Context) {
+	h.changeTenantLifecycle(c, h.db.Queries.SuspendTenant)
+}
+
+// ResumeTenant handles POST /tenants/:id/resume requests, lifting a suspension
+// @Summary      Resume a suspended tenant
+// @Tags         tenants
+// @Produce      json
+// @Param        id    path     string true "Tenant ID"
+// @Success      200   {object} models.Tenant
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/resume [post]
+func (h *TenantHandler) ResumeTenant(c *gin.Context) {
+	h.changeTenantLifecycle(c, h.db.Queries.ResumeTenant)
+}
+
+// RestoreTenant handles POST /tenants/:id/restore requests. A deleted tenant can be
+// restored until it is purged; a tenant that was suspended before it was deleted stays
+// suspended.
+// @Summary      Restore a deleted tenant
+// @Tags         tenants
+// @Produce      json
+// @Param        id    path     string true "Tenant ID"
+// @Success      200   {object} models.Tenant
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/restore [post]
+func (h *TenantHandler) RestoreTenant(c *gin.Context) {
+	h.changeTenantLifecycle(c, h.db.Queries.RestoreTenant)
+}
+
+// changeTenantLifecycle applies one lifecycle query to the tenant of the request. The
+// queries only match tenants in the right state (not deleted, or deleted for a restore),
+// so no match is answered with 404.
+func (h *TenantHandler) changeTenantLifecycle(c *gin.Context, change func(ctx context.Context, id uuid.UUID) (sqlc.Tenant, error)) {
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+		return
+	}
+	if !requireSuperAdmin(c) {
+		return
+	}
+
+	tenant, err := change(c.Request.Context(), tenantID)
+	if errors.Is(err, sql.ErrNoRows) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update tenant"})
+		return
+	}
+
+	c.JSON(http.StatusOK, tenantModel(tenant))
+}
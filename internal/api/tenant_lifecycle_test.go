@@ -0,0 +1,82 @@
+package api
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTenantModel(t *testing.T) {
+	now := time.Now()
+	tenant := sqlc.Tenant{ID: uuid.New(), Name: "Acme", Slug: "acme", IsActive: sql.NullBool{Bool: true, Valid: true}}
+
+	model := tenantModel(tenant)
+	assert.Equal(t, models.TenantStatusActive, model.Status)
+	assert.Nil(t, model.DeletedAt)
+
+	tenant.IsActive.Bool = false
+	tenant.SuspendedAt = sql.NullTime{Time: now, Valid: true}
+	assert.Equal(t, models.TenantStatusSuspended, tenantModel(tenant).Status)
+
+	// Deletion wins over suspension
+	tenant.DeletedAt = sql.NullTime{Time: now, Valid: true}
+	tenant.PurgeAt = sql.NullTime{Time: now.Add(time.Hour), Valid: true}
+	model = tenantModel(tenant)
+	assert.Equal(t, models.TenantStatusDeleted, model.Status)
+	if assert.NotNil(t, model.PurgeAt) {
+		assert.Equal(t, now.Add(time.Hour), *model.PurgeAt)
+	}
+}
+
+func TestTenantHandler_LifecycleRequiresSuperAdmin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	handler := NewTenantHandler(newOfflineDB(t), &config.Config{TenantPurgeAfter: time.Hour}, nil, nil)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		c.Set("auth", &middleware.AuthProvider{UserID: uuid.New(), Roles: []string{"editor"}})
+	})
+	router.POST("/tenants/:id/suspend", handler.SuspendTenant)
+	router.POST("/tenants/:id/resume", handler.ResumeTenant)
+	router.POST("/tenants/:id/restore", handler.RestoreTenant)
+	router.DELETE("/tenants/:id", handler.DeleteTenant)
+
+	tenantID := uuid.New().String()
+	requests := []struct {
+		method string
+		path   string
+		status int
+	}{
+		{http.MethodPost, "/tenants/" + tenantID + "/suspend", http.StatusForbidden},
+		{http.MethodPost, "/tenants/" + tenantID + "/resume", http.StatusForbidden},
+		{http.MethodPost, "/tenants/" + tenantID + "/restore", http.StatusForbidden},
+		{http.MethodDelete, "/tenants/" + tenantID, http.StatusForbidden},
+		{http.MethodPost, "/tenants/not-a-uuid/restore", http.StatusBadRequest},
+	}
+	for _, tt := range requests {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		assert.Equal(t, tt.status, w.Code, "%s %s", tt.method, tt.path)
+	}
+
+	// Admins of the tenant itself cannot lift a suspension either
+	admin := gin.New()
+	admin.Use(func(c *gin.Context) {
+		c.Set("auth", &middleware.AuthProvider{UserID: uuid.New(), TenantID: uuid.MustParse(tenantID), IsAdmin: true})
+	})
+	admin.POST("/tenants/:id/resume", handler.ResumeTenant)
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/tenants/"+tenantID+"/resume", nil))
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
@@ -0,0 +1,90 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains tenant usage reporting and the answer to requests that would pass a
+// tenant limit.
+//
+// Usage Endpoints:
+// - GET /tenants/:id/usage - Current consumption of the tenant next to its limits
+//
+// Limits default to the TENANT_* settings and can be set per tenant with {"limits": {...}}
+// in PUT /tenants/:id. Creating a collection, item, API key or asset past a limit is
+// answered with 403 and the name of the limit; requests past requests_per_minute get 429.
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/quota"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// respondQuotaExceeded answers with 403 when err is a tenant limit and reports whether it did
+func respondQuotaExceeded(c *gin.Context, err error) bool {
+	var exceeded *quota.ExceededError
+	if !errors.As(err, &exceeded) {
+		return false
+	}
+	c.JSON(http.StatusForbidden, gin.H{
+		"error": exceeded.Error(),
+		"code":  CodeQuotaExceeded,
+		"limit": exceeded.Limit,
+		"max":   exceeded.Max,
+	})
+	return true
+}
+
+// GetTenantUsage handles GET /tenants/:id/usage requests. Members of the tenant and
+// super admins can see it.
+// @Summary      Tenant usage and limits
+// @Tags         tenants
+// @Produce      json
+// @Param        id    path     string true "Tenant ID"
+// @Success      200   {object} models.TenantUsageResponse
+// @Failure      400   {object} map[string]string
+// @Failure      404   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /tenants/{id}/usage [get]
+func (h *TenantHandler) GetTenantUsage(c *gin.Context) {
+	tenantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid tenant ID"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	// Other tenants are reported as missing
+	auth, ok := middleware.GetAuthProvider(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authentication required"})
+		return
+	}
+	if !auth.IsSuperAdmin {
+		if _, err := h.db.Queries.GetUserTenant(ctx, sqlc.GetUserTenantParams{UserID: auth.UserID, TenantID: tenantID}); err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+			return
+		}
+	}
+
+	limits, err := h.quota.Limits(ctx, tenantID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+		return
+	}
+	usage, err := h.quota.Usage(ctx, tenantID)
+	if err != nil {
+		middleware.GetLogger(c).Error("failed to load tenant usage", "tenant_id", tenantID, "error", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load tenant usage"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TenantUsageResponse{
+		TenantID: tenantID,
+		Limits:   limits,
+		Usage:    usage,
+	})
+}
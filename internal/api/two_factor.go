@@ -0,0 +1,493 @@
+// Package api provides HTTP handlers for the Basin API's dynamic database access functionality.
+// This file contains TOTP two-factor authentication.
+//
+// Two-Factor Endpoints:
+// - GET  /auth/2fa                - Whether 2FA is on and how many recovery codes are left
+// - POST /auth/2fa/enable         - Create a TOTP secret (and QR code) for an authenticator app
+// - POST /auth/2fa/confirm        - Turn 2FA on with a first code; returns the recovery codes
+// - POST /auth/2fa/disable        - Turn 2FA off with the password and a code
+// - POST /auth/2fa/recovery-codes - Replace the recovery codes
+// - POST /auth/2fa/verify         - Complete a login challenge with a code or a recovery code
+//
+// With 2FA on, POST /auth/login answers with a short-lived challenge token instead of an
+// access token. Tenants can require 2FA ({"require_2fa": true} in PUT /tenants/:id); their
+// users without it get a token that can only enable it.
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/audit"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/middleware"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/totp"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/skip2/go-qrcode"
+)
+
+const (
+	twoFactorChallengePurpose = "2fa_challenge"
+	twoFactorChallengeTTL     = 5 * time.Minute
+
+	// After this many wrong codes in a row, codes are refused for twoFactorLockout
+	twoFactorMaxFailures = 5
+	twoFactorLockout     = 15 * time.Minute
+
+	recoveryCodeCount = 10
+)
+
+var (
+	errTwoFactorInvalid = errors.New("invalid two-factor code")
+	errTwoFactorLocked  = errors.New("too many invalid two-factor codes, try again later")
+)
+
+// twoFactorChallengeClaims identify the user (Subject) who passed the password step of a
+// login and the tenant the login asked for
+type twoFactorChallengeClaims struct {
+	Purpose    string `json:"purpose"`
+	TenantSlug string `json:"tenant_slug,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// signTwoFactorChallenge creates the challenge token of a login that needs a second factor
+func signTwoFactorChallenge(secret string, userID uuid.UUID, tenantSlug string) (string, time.Time, error) {
+	expiresAt := time.Now().Add(twoFactorChallengeTTL)
+	claims := twoFactorChallengeClaims{
+		Purpose:    twoFactorChallengePurpose,
+		TenantSlug: tenantSlug,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+		},
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(purposeKey(secret, twoFactorChallengePurpose))
+	return token, expiresAt, err
+}
+
+// parseTwoFactorChallenge checks a challenge token and returns its user and tenant
+func parseTwoFactorChallenge(secret, token string) (uuid.UUID, string, error) {
+	var claims twoFactorChallengeClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (interface{}, error) {
+		return purposeKey(secret, twoFactorChallengePurpose), nil
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}), jwt.WithExpirationRequired())
+	if err != nil {
+		return uuid.Nil, "", err
+	}
+	if claims.Purpose != twoFactorChallengePurpose {
+		return uuid.Nil, "", fmt.Errorf("not a two-factor challenge")
+	}
+	userID, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, "", fmt.Errorf("invalid user ID")
+	}
+	return userID, claims.TenantSlug, nil
+}
+
+// newRecoveryCodes creates a set of recovery codes such as "k3x9-2mfq"
+func newRecoveryCodes() ([]string, error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	for i := range codes {
+		b := make([]byte, 5)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		code := strings.ToLower(encoding.EncodeToString(b))
+		codes[i] = code[:4] + "-" + code[4:]
+	}
+	return codes, nil
+}
+
+// hashRecoveryCode hashes a recovery code the way it is stored, ignoring case, spaces
+// and dashes. Codes are random, so a plain SHA-256 is enough (like API keys).
+func hashRecoveryCode(code string) string {
+	normalized := strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
+
+// replaceRecoveryCodes stores a new set of recovery codes for the user
+func (h *AuthHandler) replaceRecoveryCodes(ctx context.Context, userID uuid.UUID) ([]string, error) {
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := h.db.Queries.DeleteRecoveryCodes(ctx, userID); err != nil {
+		return nil, err
+	}
+	for _, code := range codes {
+		if err := h.db.Queries.CreateRecoveryCode(ctx, sqlc.CreateRecoveryCodeParams{
+			UserID:   userID,
+			CodeHash: hashRecoveryCode(code),
+		}); err != nil {
+			return nil, err
+		}
+	}
+	return codes, nil
+}
+
+// checkSecondFactor accepts a TOTP code or a recovery code of a user with 2FA on and
+// returns which one was used. Every code works only once.
+func (h *AuthHandler) checkSecondFactor(ctx context.Context, twoFactor sqlc.UserTwoFactor, code, recoveryCode string) (string, error) {
+	queries := h.db.Queries
+	if twoFactor.FailedAttempts >= twoFactorMaxFailures && twoFactor.LastFailedAt.Valid &&
+		time.Since(twoFactor.LastFailedAt.Time) < twoFactorLockout {
+		return "", errTwoFactorLocked
+	}
+
+	if recoveryCode != "" {
+		used, err := queries.UseRecoveryCode(ctx, sqlc.UseRecoveryCodeParams{
+			UserID:   twoFactor.UserID,
+			CodeHash: hashRecoveryCode(recoveryCode),
+		})
+		if err != nil {
+			return "", err
+		}
+		if used == 1 {
+			return "recovery_code", nil
+		}
+	} else if step, ok := totp.Validate(twoFactor.Secret, code, time.Now()); ok {
+		// A code seen before (or an older one) is a replay
+		used, err := queries.UseTwoFactorStep(ctx, sqlc.UseTwoFactorStepParams{
+			UserID:       twoFactor.UserID,
+			LastUsedStep: step,
+		})
+		if err != nil {
+			return "", err
+		}
+		if used == 1 {
+			return "totp", nil
+		}
+	}
+
+	if err := queries.RecordTwoFactorFailure(ctx, twoFactor.UserID); err != nil {
+		return "", err
+	}
+	return "", errTwoFactorInvalid
+}
+
+// TwoFactorStatus handles GET /auth/2fa requests
+// @Summary      Two-factor authentication status
+// @Tags         auth
+// @Produce      json
+// @Success      200   {object} models.TwoFactorStatusResponse
+// @Security     BearerAuth
+// @Router       /auth/2fa [get]
+func (h *AuthHandler) TwoFactorStatus(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.Request.Context()
+
+	enabled, err := middleware.TwoFactorEnabled(ctx, h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load two-factor authentication"})
+		return
+	}
+	status := models.TwoFactorStatusResponse{Enabled: enabled}
+	if enabled {
+		if status.RecoveryCodesLeft, err = h.db.Queries.CountRecoveryCodes(ctx, userID); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load two-factor authentication"})
+			return
+		}
+	}
+	if tenantID, ok := middleware.GetTenantID(c); ok && tenantID != uuid.Nil {
+		if tenant, err := h.db.Queries.GetTenantByID(ctx, tenantID); err == nil {
+			status.RequiredByTenant = middleware.TenantRequiresTwoFactor(tenant)
+		}
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// EnableTwoFactor handles POST /auth/2fa/enable requests. The new secret is pending until
+// it is confirmed with a code.
+// @Summary      Start enabling two-factor authentication
+// @Tags         auth
+// @Produce      json
+// @Success      200   {object} models.TwoFactorEnableResponse
+// @Failure      409   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /auth/2fa/enable [post]
+func (h *AuthHandler) EnableTwoFactor(c *gin.Context) {
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.Request.Context()
+
+	enabled, err := middleware.TwoFactorEnabled(ctx, h.db, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	if enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+
+	secret, err := totp.GenerateSecret()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	if _, err := h.db.Queries.SetTwoFactorSecret(ctx, sqlc.SetTwoFactorSecretParams{UserID: userID, Secret: secret}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+
+	uri := totp.URI(h.cfg.TOTPIssuer, c.GetString("email"), secret)
+	png, err := qrcode.Encode(uri, qrcode.Medium, 256)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create QR code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models.TwoFactorEnableResponse{
+		Secret:     secret,
+		OTPAuthURL: uri,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(png),
+	})
+}
+
+// ConfirmTwoFactor handles POST /auth/2fa/confirm requests
+// @Summary      Turn on two-factor authentication
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   models.TwoFactorConfirmRequest true "Code from the authenticator app"
+// @Success      200   {object} models.TwoFactorConfirmResponse
+// @Failure      400   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /auth/2fa/confirm [post]
+func (h *AuthHandler) ConfirmTwoFactor(c *gin.Context) {
+	var req models.TwoFactorConfirmRequest
+	if err := decodeJSON(c, &req, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.Request.Context()
+
+	twoFactor, err := h.db.Queries.GetTwoFactor(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Call POST /auth/2fa/enable first"})
+		return
+	}
+	if twoFactor.Enabled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Two-factor authentication is already enabled"})
+		return
+	}
+	step, ok := totp.Validate(twoFactor.Secret, req.Code, time.Now())
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": errTwoFactorInvalid.Error()})
+		return
+	}
+
+	if err := h.db.Queries.EnableTwoFactor(ctx, sqlc.EnableTwoFactorParams{UserID: userID, LastUsedStep: step}); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to enable two-factor authentication"})
+		return
+	}
+	codes, err := h.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recovery codes"})
+		return
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionTwoFactorEnable)
+	entry.StatusCode = http.StatusOK
+	h.audit.Record(ctx, entry)
+
+	response := models.TwoFactorConfirmResponse{
+		Message:       "Two-factor authentication enabled",
+		RecoveryCodes: codes,
+	}
+
+	// A token that was limited to this setup is replaced with a full one
+	if auth, ok := middleware.GetAuthProvider(c); ok && auth.TwoFactorSetupRequired {
+		user, err := h.db.Queries.GetUserByID(ctx, userID)
+		if err == nil {
+			var tenant sqlc.Tenant
+			if tenant, err = h.db.Queries.GetTenantByID(ctx, auth.TenantID); err == nil {
+				response.Token, _, err = h.authProvider.IssueToken(c, user, &tenant)
+			}
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		if sessionID, ok := currentSessionID(c); ok {
+			if _, err := h.db.Queries.RevokeSession(ctx, sqlc.RevokeSessionParams{ID: sessionID, UserID: userID}); err != nil {
+				middleware.GetLogger(c).Warn("failed to revoke setup session", "session_id", sessionID, "error", err)
+			}
+		}
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// DisableTwoFactor handles POST /auth/2fa/disable requests
+// @Summary      Turn off two-factor authentication
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   models.TwoFactorDisableRequest true "Password and a code"
+// @Success      200   {object} map[string]string
+// @Failure      400   {object} map[string]string
+// @Failure      403   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /auth/2fa/disable [post]
+func (h *AuthHandler) DisableTwoFactor(c *gin.Context) {
+	var req models.TwoFactorDisableRequest
+	if err := decodeJSON(c, &req, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.Request.Context()
+
+	if tenantID, ok := middleware.GetTenantID(c); ok && tenantID != uuid.Nil {
+		if tenant, err := h.db.Queries.GetTenantByID(ctx, tenantID); err == nil && middleware.TenantRequiresTwoFactor(tenant) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "The tenant requires two-factor authentication"})
+			return
+		}
+	}
+
+	user, err := h.db.Queries.GetUserByID(ctx, userID)
+	if err != nil || !models.CheckPassword(req.Password, user.PasswordHash) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Password is incorrect"})
+		return
+	}
+	twoFactor, err := h.db.Queries.GetTwoFactor(ctx, userID)
+	if err != nil || !twoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	if _, err := h.checkSecondFactor(ctx, twoFactor, req.Code, req.Code); err != nil {
+		h.respondSecondFactorError(c, err)
+		return
+	}
+
+	if err := h.db.Queries.DeleteTwoFactor(ctx, userID); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to disable two-factor authentication"})
+		return
+	}
+	if err := h.db.Queries.DeleteRecoveryCodes(ctx, userID); err != nil {
+		middleware.GetLogger(c).Warn("failed to delete recovery codes", "user_id", userID, "error", err)
+	}
+
+	entry := middleware.NewAuditEntry(c, audit.ActionTwoFactorDisable)
+	entry.StatusCode = http.StatusOK
+	h.audit.Record(ctx, entry)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Two-factor authentication disabled"})
+}
+
+// RegenerateRecoveryCodes handles POST /auth/2fa/recovery-codes requests
+// @Summary      Replace the recovery codes
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   models.TwoFactorConfirmRequest true "Code from the authenticator app"
+// @Success      200   {object} models.TwoFactorConfirmResponse
+// @Failure      400   {object} map[string]string
+// @Security     BearerAuth
+// @Router       /auth/2fa/recovery-codes [post]
+func (h *AuthHandler) RegenerateRecoveryCodes(c *gin.Context) {
+	var req models.TwoFactorConfirmRequest
+	if err := decodeJSON(c, &req, BodyAuth); err != nil {
+		respondBodyError(c, err, "Invalid request body")
+		return
+	}
+	userID, _ := middleware.GetUserID(c)
+	ctx := c.Request.Context()
+
+	twoFactor, err := h.db.Queries.GetTwoFactor(ctx, userID)
+	if err != nil || !twoFactor.Enabled {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Two-factor authentication is not enabled"})
+		return
+	}
+	// Only the authenticator can replace the codes, so a leaked code cannot renew itself
+	if _, err := h.checkSecondFactor(ctx, twoFactor, req.Code, ""); err != nil {
+		h.respondSecondFactorError(c, err)
+		return
+	}
+
+	codes, err := h.replaceRecoveryCodes(ctx, userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create recovery codes"})
+		return
+	}
+	c.JSON(http.StatusOK, models.TwoFactorConfirmResponse{
+		Message:       "Recovery codes replaced",
+		RecoveryCodes: codes,
+	})
+}
+
+// VerifyTwoFactor handles POST /auth/2fa/verify requests, the second step of a login
+// @Summary      Complete a login with a second factor
+// @Tags         auth
+// @Accept       json
+// @Produce      json
+// @Param        body  body   models.TwoFactorVerifyRequest true "Challenge token and code"
+// @Success      200   {object} models.LoginResponse
+// @Failure      400   {object} map[string]string
+// @Failure      401   {object} map[string]string
+// @Failure      429   {object} map[string]string
+// @Router       /auth/2fa/verify [post]
+func (h *AuthHandler) VerifyTwoFactor(c *gin.Context) {
+	var req models.TwoFactorVerifyRequest
+	if err := decodeJSON(c, &req, BodyAuth); err != nil || (req.Code == "") == (req.RecoveryCode == "") {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Send the challenge_token and either a code or a recovery_code"})
+		return
+	}
+	ctx := c.Request.Context()
+
+	userID, tenantSlug, err := parseTwoFactorChallenge(h.cfg.JWTSecret, req.ChallengeToken)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+	user, err := h.db.Queries.GetUserByID(ctx, userID)
+	if err != nil || !user.IsActive.Bool {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+	twoFactor, err := h.db.Queries.GetTwoFactor(ctx, userID)
+	if err != nil || !twoFactor.Enabled {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired challenge"})
+		return
+	}
+
+	method, err := h.checkSecondFactor(ctx, twoFactor, req.Code, req.RecoveryCode)
+	if err != nil {
+		if errors.Is(err, errTwoFactorInvalid) || errors.Is(err, errTwoFactorLocked) {
+			h.auditLoginFailure(c, user.Email, user.ID, err.Error())
+		}
+		h.respondSecondFactorError(c, err)
+		return
+	}
+
+	h.completeLogin(c, user, tenantSlug, map[string]interface{}{"second_factor": method})
+}
+
+// respondSecondFactorError answers a rejected second factor
+func (h *AuthHandler) respondSecondFactorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, errTwoFactorLocked):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	case errors.Is(err, errTwoFactorInvalid):
+		c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to check two-factor code"})
+	}
+}
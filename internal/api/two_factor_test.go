@@ -0,0 +1,114 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/mail"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTwoFactorChallenge(t *testing.T) {
+	userID := uuid.New()
+
+	token, expiresAt, err := signTwoFactorChallenge("secret", userID, "acme")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(twoFactorChallengeTTL), expiresAt, time.Second)
+
+	gotUser, tenantSlug, err := parseTwoFactorChallenge("secret", token)
+	require.NoError(t, err)
+	assert.Equal(t, userID, gotUser)
+	assert.Equal(t, "acme", tenantSlug)
+
+	_, _, err = parseTwoFactorChallenge("other-secret", token)
+	assert.Error(t, err)
+
+	// Password reset tokens are signed with another key
+	reset, err := signPasswordResetToken("secret", sqlc.PasswordReset{ID: uuid.New(), UserID: userID, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, _, err = parseTwoFactorChallenge("secret", reset)
+	assert.Error(t, err)
+
+	// The purpose claim is checked too
+	forged, err := jwt.NewWithClaims(jwt.SigningMethodHS256, twoFactorChallengeClaims{
+		Purpose: "other",
+		RegisteredClaims: jwt.RegisteredClaims{
+			Subject:   userID.String(),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Minute)),
+		},
+	}).SignedString(purposeKey("secret", twoFactorChallengePurpose))
+	require.NoError(t, err)
+	_, _, err = parseTwoFactorChallenge("secret", forged)
+	assert.Error(t, err)
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	codes, err := newRecoveryCodes()
+	require.NoError(t, err)
+	require.Len(t, codes, recoveryCodeCount)
+
+	seen := make(map[string]bool)
+	for _, code := range codes {
+		assert.Regexp(t, `^[a-z2-7]{4}-[a-z2-7]{4}$`, code)
+		assert.False(t, seen[code], "duplicate code %s", code)
+		seen[code] = true
+	}
+
+	// Codes may be typed without the dash, in upper case or with spaces
+	hash := hashRecoveryCode("abcd-efgh")
+	assert.Equal(t, hash, hashRecoveryCode("ABCDEFGH"))
+	assert.Equal(t, hash, hashRecoveryCode(" abcd efgh "))
+	assert.NotEqual(t, hash, hashRecoveryCode("abcd-efgi"))
+}
+
+func TestWithTenantSetting(t *testing.T) {
+	updated, err := withTenantSetting(json.RawMessage(`{"mail": {"from_email": "a@example.com"}}`), "require_2fa", true)
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"mail": {"from_email": "a@example.com"}, "require_2fa": true}`, string(updated))
+}
+
+func TestAuthHandler_VerifyTwoFactor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cfg := &config.Config{JWTSecret: "test-secret-key"}
+	handler := NewAuthHandler(newOfflineDB(t), cfg, mail.NewMailer(mail.NewLogSender(slog.Default()), mail.Address{}, nil))
+
+	router := gin.New()
+	router.POST("/auth/2fa/verify", handler.VerifyTwoFactor)
+
+	tests := []struct {
+		name   string
+		body   string
+		status int
+		error  string
+	}{
+		{"missing challenge", `{"code": "123456"}`, http.StatusBadRequest, "challenge_token"},
+		{"no code", `{"challenge_token": "x"}`, http.StatusBadRequest, "either a code or a recovery_code"},
+		{"both codes", `{"challenge_token": "x", "code": "123456", "recovery_code": "abcd-efgh"}`, http.StatusBadRequest, "either a code or a recovery_code"},
+		{"forged challenge", `{"challenge_token": "x.y.z", "code": "123456"}`, http.StatusUnauthorized, "Invalid or expired challenge"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodPost, "/auth/2fa/verify", bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", "application/json")
+			router.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.status, w.Code)
+			assert.True(t, strings.Contains(w.Body.String(), tt.error), w.Body.String())
+		})
+	}
+}
@@ -0,0 +1,177 @@
+// Package apikeys runs background tasks for the API key lifecycle.
+//
+// The Notifier periodically looks for active keys that will expire within the configured
+// warning window and publishes one api_key.expiring event per key on the event bus, so
+// webhooks and realtime subscribers can remind the owner to rotate it. Keys are claimed in
+// the database before the event is published, so running several instances does not
+// announce a key twice. Changing a key's expires_at re-arms the warning.
+//
+// Keys of service accounts usually never expire, so they follow a rotation policy instead:
+// once a key has gone SERVICE_ACCOUNT_KEY_ROTATION without being rotated, one
+// api_key.rotation_due event is published for it. Rotating the key re-arms the reminder.
+package apikeys
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"time"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	pollInterval   = 15 * time.Minute // How often expiring keys are looked for
+	claimBatchSize = 100              // Keys claimed per pass
+)
+
+// Notifier publishes expiry warnings and rotation reminders for API keys
+type Notifier struct {
+	db       *db.DB
+	bus      *events.Bus
+	warning  time.Duration
+	rotation time.Duration
+}
+
+// NewNotifier creates a Notifier using the API key settings from cfg
+func NewNotifier(db *db.DB, cfg *config.Config, bus *events.Bus) *Notifier {
+	return &Notifier{db: db, bus: bus, warning: cfg.APIKeyExpiryWarning, rotation: cfg.ServiceAccountKeyRotation}
+}
+
+// Start runs the notifier until ctx is cancelled. A non-positive warning window or rotation
+// period disables that check; the notifier stops when both are.
+func (n *Notifier) Start(ctx context.Context) {
+	if n.warning <= 0 && n.rotation <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		if n.warning > 0 {
+			n.notifyDue(ctx)
+		}
+		if n.rotation > 0 {
+			n.notifyRotationDue(ctx)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// notifyDue claims and announces expiring keys until none are left
+func (n *Notifier) notifyDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		keys, err := n.db.Queries.ClaimExpiringAPIKeys(ctx, sqlc.ClaimExpiringAPIKeysParams{
+			ExpiresAt: sql.NullTime{Time: time.Now().Add(n.warning), Valid: true},
+			Limit:     claimBatchSize,
+		})
+		if err != nil {
+			log.Printf("apikeys: failed to claim expiring keys: %v", err)
+			return
+		}
+
+		for _, key := range keys {
+			n.publish(ctx, key, func(tenantID uuid.UUID) events.Event {
+				return ExpiringEvent(key, tenantID)
+			})
+		}
+		if len(keys) < claimBatchSize {
+			return
+		}
+	}
+}
+
+// notifyRotationDue claims and announces service account keys past the rotation period
+func (n *Notifier) notifyRotationDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		keys, err := n.db.Queries.ClaimRotationDueAPIKeys(ctx, sqlc.ClaimRotationDueAPIKeysParams{
+			RotatedAt: sql.NullTime{Time: time.Now().Add(-n.rotation), Valid: true},
+			Limit:     claimBatchSize,
+		})
+		if err != nil {
+			log.Printf("apikeys: failed to claim keys due for rotation: %v", err)
+			return
+		}
+
+		for _, key := range keys {
+			n.publish(ctx, key, func(tenantID uuid.UUID) events.Event {
+				return RotationDueEvent(key, tenantID, n.rotation)
+			})
+		}
+		if len(keys) < claimBatchSize {
+			return
+		}
+	}
+}
+
+// publish announces one key to the owner's tenant with the event build returns
+func (n *Notifier) publish(ctx context.Context, key sqlc.ApiKey, build func(tenantID uuid.UUID) events.Event) {
+	user, err := n.db.Queries.GetUserByID(ctx, key.UserID)
+	if err != nil {
+		log.Printf("apikeys: failed to load owner of key %s: %v", key.ID, err)
+		return
+	}
+	if !user.TenantID.Valid {
+		return
+	}
+
+	n.bus.Publish(ctx, build(user.TenantID.UUID))
+}
+
+// ExpiringEvent builds the api_key.expiring event for key. The payload never contains the
+// key's secret or hashes.
+func ExpiringEvent(key sqlc.ApiKey, tenantID uuid.UUID) events.Event {
+	return events.Event{
+		Type:       events.APIKeyExpiring,
+		TenantID:   tenantID,
+		Collection: "api_keys",
+		Keys:       []string{key.ID.String()},
+		Data: map[string]interface{}{
+			"id":         key.ID.String(),
+			"user_id":    key.UserID.String(),
+			"name":       key.Name,
+			"expires_at": key.ExpiresAt.Time,
+		},
+		UserID: key.UserID,
+	}
+}
+
+// RotationDueEvent builds the api_key.rotation_due event for a service account key that has
+// gone unrotated for longer than period. The payload never contains the key's secret or
+// hashes.
+func RotationDueEvent(key sqlc.ApiKey, tenantID uuid.UUID, period time.Duration) events.Event {
+	return events.Event{
+		Type:       events.APIKeyRotationDue,
+		TenantID:   tenantID,
+		Collection: "api_keys",
+		Keys:       []string{key.ID.String()},
+		Data: map[string]interface{}{
+			"id":              key.ID.String(),
+			"user_id":         key.UserID.String(),
+			"name":            key.Name,
+			"last_rotated_at": LastRotatedAt(key),
+			"rotation_due_at": LastRotatedAt(key).Add(period),
+		},
+		UserID: key.UserID,
+	}
+}
+
+// LastRotatedAt returns when a key last got a new secret, which is its creation for a key
+// that was never rotated
+func LastRotatedAt(key sqlc.ApiKey) time.Time {
+	if key.RotatedAt.Valid {
+		return key.RotatedAt.Time
+	}
+	return key.CreatedAt.Time
+}
@@ -0,0 +1,64 @@
+package apikeys
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpiringEvent(t *testing.T) {
+	tenantID := uuid.New()
+	key := sqlc.ApiKey{
+		ID:              uuid.New(),
+		UserID:          uuid.New(),
+		Name:            "Shop sync",
+		KeyHash:         "current-hash",
+		PreviousKeyHash: sql.NullString{String: "old-hash", Valid: true},
+		ExpiresAt:       sql.NullTime{Time: time.Now().Add(48 * time.Hour), Valid: true},
+	}
+
+	event := ExpiringEvent(key, tenantID)
+	assert.Equal(t, events.APIKeyExpiring, event.Type)
+	assert.Equal(t, tenantID, event.TenantID)
+	assert.Equal(t, "api_keys", event.Collection)
+	assert.Equal(t, []string{key.ID.String()}, event.Keys)
+
+	payload, err := json.Marshal(event)
+	require.NoError(t, err)
+	assert.NotContains(t, string(payload), "hash")
+	assert.Contains(t, string(payload), "Shop sync")
+}
+
+func TestRotationDueEvent(t *testing.T) {
+	created := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	key := sqlc.ApiKey{
+		ID:        uuid.New(),
+		UserID:    uuid.New(),
+		Name:      "Importer",
+		KeyHash:   "current-hash",
+		CreatedAt: sql.NullTime{Time: created, Valid: true},
+	}
+	period := 90 * 24 * time.Hour
+
+	event := RotationDueEvent(key, uuid.New(), period)
+	assert.Equal(t, events.APIKeyRotationDue, event.Type)
+	data := event.Data.(map[string]interface{})
+	assert.Equal(t, created, data["last_rotated_at"], "a key that was never rotated counts from its creation")
+	assert.Equal(t, created.Add(period), data["rotation_due_at"])
+
+	rotated := created.Add(30 * 24 * time.Hour)
+	key.RotatedAt = sql.NullTime{Time: rotated, Valid: true}
+	assert.Equal(t, rotated, LastRotatedAt(key))
+
+	payload, err := json.Marshal(RotationDueEvent(key, uuid.New(), period))
+	require.NoError(t, err)
+	assert.NotContains(t, string(payload), "hash")
+}
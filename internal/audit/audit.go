@@ -0,0 +1,151 @@
+// Package audit records security-relevant activity in the audit_logs table.
+//
+// Two kinds of entries are written: authentication events (logins, failed logins and API
+// key use) recorded by the auth handler and middleware, and one entry per write request
+// to the item and asset APIs recorded by middleware.AuditTrail. Privacy erasures add one
+// entry each, naming their signed report, and so do restores of tenant backups. Every
+// entry carries the actor, tenant, table, action, client IP, request ID and whether the
+// request succeeded.
+//
+// Audit writes never fail the request they describe: errors are logged and dropped.
+package audit
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+)
+
+// Results stored in audit_logs.result
+const (
+	ResultSuccess = "success"
+	ResultFailure = "failure"
+)
+
+// Authentication and account actions. Item writes use the write's own action (create,
+// update, ...).
+const (
+	ActionLogin        = "login"
+	ActionLoginFailed  = "login_failed"
+	ActionLogout       = "logout"
+	ActionAPIKey       = "api_key"
+	ActionAPIKeyFailed = "api_key_failed"
+
+	ActionPasswordChange = "password_change"
+	ActionPasswordReset  = "password_reset"
+
+	ActionTwoFactorEnable  = "two_factor_enable"
+	ActionTwoFactorDisable = "two_factor_disable"
+
+	ActionSessionRevoke = "session_revoke"
+
+	ActionImpersonate     = "impersonate"
+	ActionImpersonateStop = "impersonate_stop"
+
+	ActionPrivacyErasure = "privacy_erasure"
+
+	ActionBackupRestore = "backup_restore"
+)
+
+// Entry is one audited event. Zero values are stored as NULL.
+type Entry struct {
+	TenantID   uuid.UUID // Falls back to the user's home tenant when unset
+	UserID     uuid.UUID
+	APIKeyID   uuid.UUID
+	Action     string
+	Table      string
+	ItemID     string
+	Result     string // ResultSuccess or ResultFailure
+	StatusCode int
+	IP         string
+	UserAgent  string
+	RequestID  string
+	Details    map[string]interface{} // Extra context, e.g. the email of a failed login
+
+	// ImpersonatorID is the instance admin acting as UserID; it is stored in the details
+	ImpersonatorID uuid.UUID
+}
+
+// Logger writes audit entries to the database
+type Logger struct {
+	db *db.DB
+}
+
+// NewLogger creates a Logger
+func NewLogger(db *db.DB) *Logger {
+	return &Logger{db: db}
+}
+
+// Record stores an entry. A nil Logger is a no-op so components can be constructed
+// without one in tests. The write outlives a cancelled request context.
+func (l *Logger) Record(ctx context.Context, entry Entry) {
+	if l == nil || l.db == nil {
+		return
+	}
+
+	params, err := entry.params()
+	if err != nil {
+		log.Printf("audit: failed to encode %s entry: %v", entry.Action, err)
+		return
+	}
+
+	if err := l.db.Queries.CreateAuditLog(context.WithoutCancel(ctx), params); err != nil {
+		log.Printf("audit: failed to record %s entry: %v", entry.Action, err)
+	}
+}
+
+// params converts the entry to query parameters
+func (e Entry) params() (sqlc.CreateAuditLogParams, error) {
+	params := sqlc.CreateAuditLogParams{
+		TenantID:   nullUUID(e.TenantID),
+		UserID:     nullUUID(e.UserID),
+		ApiKeyID:   nullUUID(e.APIKeyID),
+		Action:     e.Action,
+		TableName:  nullString(e.Table),
+		ItemID:     nullString(e.ItemID),
+		Result:     e.Result,
+		StatusCode: sql.NullInt32{Int32: int32(e.StatusCode), Valid: e.StatusCode != 0},
+		IpAddress:  nullString(e.IP),
+		UserAgent:  nullString(e.UserAgent),
+		RequestID:  nullString(e.RequestID),
+	}
+	if params.Result == "" {
+		params.Result = ResultSuccess
+	}
+
+	details := e.Details
+	if e.ImpersonatorID != uuid.Nil {
+		details = make(map[string]interface{}, len(e.Details)+1)
+		for k, v := range e.Details {
+			details[k] = v
+		}
+		details["impersonator_id"] = e.ImpersonatorID
+	}
+	if len(details) > 0 {
+		raw, err := json.Marshal(details)
+		if err != nil {
+			return params, fmt.Errorf("invalid details: %w", err)
+		}
+		params.Details = pqtype.NullRawMessage{RawMessage: raw, Valid: true}
+	}
+
+	return params, nil
+}
+
+// nullUUID maps uuid.Nil to NULL
+func nullUUID(id uuid.UUID) uuid.NullUUID {
+	return uuid.NullUUID{UUID: id, Valid: id != uuid.Nil}
+}
+
+// nullString maps "" to NULL
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
@@ -0,0 +1,59 @@
+package audit
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEntryParams(t *testing.T) {
+	userID := uuid.New()
+	params, err := Entry{
+		UserID:     userID,
+		Action:     ActionLoginFailed,
+		Result:     ResultFailure,
+		StatusCode: 401,
+		IP:         "203.0.113.7",
+		Details:    map[string]interface{}{"email": "a@example.com"},
+	}.params()
+	require.NoError(t, err)
+
+	assert.False(t, params.TenantID.Valid)
+	assert.Equal(t, userID, params.UserID.UUID)
+	assert.True(t, params.UserID.Valid)
+	assert.False(t, params.ApiKeyID.Valid)
+	assert.Equal(t, "login_failed", params.Action)
+	assert.False(t, params.TableName.Valid)
+	assert.Equal(t, "failure", params.Result)
+	assert.Equal(t, int32(401), params.StatusCode.Int32)
+	assert.Equal(t, "203.0.113.7", params.IpAddress.String)
+	assert.False(t, params.RequestID.Valid)
+	assert.JSONEq(t, `{"email":"a@example.com"}`, string(params.Details.RawMessage))
+}
+
+func TestEntryParamsDefaults(t *testing.T) {
+	params, err := Entry{Action: "create"}.params()
+	require.NoError(t, err)
+
+	assert.Equal(t, ResultSuccess, params.Result)
+	assert.False(t, params.StatusCode.Valid)
+	assert.False(t, params.Details.Valid)
+}
+
+func TestEntryParamsImpersonator(t *testing.T) {
+	impersonator := uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+	details := map[string]interface{}{"reason": "support"}
+	params, err := Entry{Action: "update", ImpersonatorID: impersonator, Details: details}.params()
+	require.NoError(t, err)
+
+	assert.JSONEq(t, `{"reason":"support","impersonator_id":"6ba7b810-9dad-11d1-80b4-00c04fd430c8"}`, string(params.Details.RawMessage))
+	assert.Len(t, details, 1, "the caller's details are not modified")
+}
+
+func TestNilLogger(t *testing.T) {
+	var logger *Logger
+	assert.NotPanics(t, func() { logger.Record(context.Background(), Entry{Action: "create"}) })
+}
@@ -0,0 +1,180 @@
+// Package cachebus broadcasts cache invalidations between Basin instances.
+//
+// Every instance keeps permission checks and schema metadata in memory. When one
+// instance drops entries, for example because a role or a field changed, it publishes
+// the invalidation on the bus and every other instance drops the same entries. The bus
+// runs over Postgres LISTEN/NOTIFY by default, or over Redis pub/sub for deployments
+// whose connection pooler does not support LISTEN.
+package cachebus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// Caches that share invalidations over the bus
+const (
+	Permissions = "permissions" // Keyed by user
+	Schema      = "schema"      // Keyed by tenant
+	Items       = "items"       // Keyed by collection (api.CollectionKey)
+)
+
+// Transports of the bus (CACHE_BUS)
+const (
+	TransportPostgres = "postgres"
+	TransportRedis    = "redis"
+	TransportNone     = "none"
+)
+
+const (
+	channel        = "basin_cache"
+	publishTimeout = 2 * time.Second // How long publishing one invalidation may take
+)
+
+// Message announces that an instance dropped entries of a cache
+type Message struct {
+	Cache  string    `json:"cache"`  // One of the cache constants
+	Key    uuid.UUID `json:"key"`    // User, tenant or collection whose entries were dropped; uuid.Nil for every entry
+	Origin uuid.UUID `json:"origin"` // Instance that published the message
+}
+
+// Transport carries messages between instances. *db.DB is the Postgres transport.
+type Transport interface {
+	Notify(ctx context.Context, channel, payload string) error
+	// Listen calls fn with every payload sent on channel until ctx is done, and with
+	// reconnected set after messages may have been lost
+	Listen(ctx context.Context, channel string, fn func(payload string, reconnected bool)) error
+}
+
+// Handler drops the entries of one key, or every entry for uuid.Nil
+type Handler func(key uuid.UUID)
+
+// Bus publishes the invalidations of this instance and applies those of others.
+//
+// A nil *Bus is valid and publishes nothing.
+type Bus struct {
+	transport Transport
+	closer    io.Closer // Connections the bus owns; nil when it shares the database's
+	origin    uuid.UUID
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// New creates a bus over transport
+func New(transport Transport) *Bus {
+	return &Bus{
+		transport: transport,
+		origin:    uuid.New(),
+		handlers:  make(map[string]Handler),
+	}
+}
+
+// Open creates the bus cfg.CacheBus selects, or nil when it is "none"
+func Open(cfg *config.Config, database *db.DB) (*Bus, error) {
+	switch cfg.CacheBus {
+	case TransportPostgres:
+		return New(database), nil
+	case TransportRedis:
+		transport, err := NewRedis(cfg.RedisURL)
+		if err != nil {
+			return nil, err
+		}
+		bus := New(transport)
+		bus.closer = transport
+		return bus, nil
+	case TransportNone:
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unknown CACHE_BUS %q: use %s, %s or %s", cfg.CacheBus, TransportPostgres, TransportRedis, TransportNone)
+}
+
+// Handle registers the handler applying invalidations of cache published by other
+// instances. Register handlers before calling Run.
+func (b *Bus) Handle(cache string, handler Handler) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers[cache] = handler
+}
+
+// Publish announces that this instance dropped the entries of key, or every entry for
+// uuid.Nil. Failures are logged; the other instances then rely on their TTL.
+func (b *Bus) Publish(cache string, key uuid.UUID) {
+	if b == nil {
+		return
+	}
+
+	payload, err := json.Marshal(Message{Cache: cache, Key: key, Origin: b.origin})
+	if err != nil {
+		slog.Error("failed to encode cache invalidation", "cache", cache, "error", err)
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+	if err := b.transport.Notify(ctx, channel, string(payload)); err != nil {
+		slog.Warn("failed to publish cache invalidation", "cache", cache, "key", key, "error", err)
+	}
+}
+
+// Publisher returns a function publishing invalidations of cache, for the cache to call
+func (b *Bus) Publisher(cache string) func(key uuid.UUID) {
+	return func(key uuid.UUID) {
+		b.Publish(cache, key)
+	}
+}
+
+// Run applies the invalidations published by other instances until ctx is done
+func (b *Bus) Run(ctx context.Context) error {
+	if b == nil {
+		return nil
+	}
+	return b.transport.Listen(ctx, channel, b.receive)
+}
+
+// Close releases the connections the bus opened
+func (b *Bus) Close() error {
+	if b == nil || b.closer == nil {
+		return nil
+	}
+	return b.closer.Close()
+}
+
+// receive applies one message. Messages of this instance were applied when they were
+// published; after a reconnect every cache is dropped, since messages may have been lost.
+func (b *Bus) receive(payload string, reconnected bool) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	if reconnected {
+		for _, handler := range b.handlers {
+			handler(uuid.Nil)
+		}
+		return
+	}
+
+	var msg Message
+	if err := json.Unmarshal([]byte(payload), &msg); err != nil {
+		slog.Warn("ignoring malformed cache invalidation", "payload", payload, "error", err)
+		return
+	}
+	if msg.Origin == b.origin {
+		return
+	}
+	if handler, ok := b.handlers[msg.Cache]; ok {
+		handler(msg.Key)
+	}
+}
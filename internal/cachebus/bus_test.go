@@ -0,0 +1,119 @@
+package cachebus
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// loopback delivers every notification to the listeners of every bus sharing it, like
+// one Postgres database or Redis server would
+type loopback struct {
+	listeners []func(payload string, reconnected bool)
+	err       error
+}
+
+func (l *loopback) Notify(ctx context.Context, channel, payload string) error {
+	if l.err != nil {
+		return l.err
+	}
+	for _, fn := range l.listeners {
+		fn(payload, false)
+	}
+	return nil
+}
+
+func (l *loopback) Listen(ctx context.Context, channel string, fn func(payload string, reconnected bool)) error {
+	l.listeners = append(l.listeners, fn)
+	return nil
+}
+
+func TestBus(t *testing.T) {
+	transport := &loopback{}
+	local, remote := New(transport), New(transport)
+	require.NoError(t, local.Run(context.Background()))
+	require.NoError(t, remote.Run(context.Background()))
+
+	handled := map[string][]uuid.UUID{}
+	handler := func(bus, cache string) Handler {
+		return func(key uuid.UUID) { handled[bus+"/"+cache] = append(handled[bus+"/"+cache], key) }
+	}
+	local.Handle(Permissions, handler("local", Permissions))
+	remote.Handle(Permissions, handler("remote", Permissions))
+	remote.Handle(Schema, handler("remote", Schema))
+
+	// Other instances apply an invalidation, the publishing one does not
+	userID, tenantID := uuid.New(), uuid.New()
+	local.Publish(Permissions, userID)
+	local.Publisher(Schema)(tenantID)
+	local.Publish(Permissions, uuid.Nil)
+	assert.Equal(t, map[string][]uuid.UUID{
+		"remote/permissions": {userID, uuid.Nil},
+		"remote/schema":      {tenantID},
+	}, handled)
+
+	// Malformed messages and messages for caches without a handler are ignored
+	handled = map[string][]uuid.UUID{}
+	assert.NotPanics(t, func() {
+		remote.receive("not json", false)
+		remote.receive(`{"cache":"unknown","key":"00000000-0000-0000-0000-000000000000"}`, false)
+	})
+	assert.Empty(t, handled)
+
+	// After a reconnect every cache is dropped
+	remote.receive("", true)
+	assert.Equal(t, map[string][]uuid.UUID{
+		"remote/permissions": {uuid.Nil},
+		"remote/schema":      {uuid.Nil},
+	}, handled)
+
+	// Publishing failures leave the publisher alone
+	transport.err = errors.New("connection refused")
+	assert.NotPanics(t, func() { local.Publish(Permissions, userID) })
+}
+
+func TestNilBus(t *testing.T) {
+	var bus *Bus
+	assert.NotPanics(t, func() {
+		bus.Handle(Permissions, func(uuid.UUID) {})
+		bus.Publish(Permissions, uuid.New())
+		bus.Publisher(Schema)(uuid.New())
+	})
+	assert.NoError(t, bus.Run(context.Background()))
+	assert.NoError(t, bus.Close())
+}
+
+func TestOpen(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantBus bool
+		wantErr bool
+	}{
+		{"Postgres", config.Config{CacheBus: TransportPostgres}, true, false},
+		{"Redis", config.Config{CacheBus: TransportRedis, RedisURL: "redis://localhost:6379/0"}, true, false},
+		{"Redis Without URL", config.Config{CacheBus: TransportRedis}, false, true},
+		{"Invalid Redis URL", config.Config{CacheBus: TransportRedis, RedisURL: "http://localhost"}, false, true},
+		{"None", config.Config{CacheBus: TransportNone}, false, false},
+		{"Unknown", config.Config{CacheBus: "kafka"}, false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bus, err := Open(&tt.cfg, nil)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			assert.Equal(t, tt.wantBus, bus != nil)
+			assert.NoError(t, bus.Close())
+		})
+	}
+}
@@ -2,6 +2,8 @@ package config
 
 import (
 	"fmt"
+	"log/slog"
+	"net"
 	"net/url"
 	"os"
 	"strconv"
@@ -22,6 +24,9 @@ const (
 type Config struct {
 	DeploymentMode DeploymentMode
 
+	DBDriver   string // postgres, mysql (or mariadb), or sqlite for development and embedded use
+	SQLitePath string // Database file of the sqlite driver
+
 	DBHost            string
 	DBPort            int
 	DBUser            string
@@ -31,30 +36,213 @@ type Config struct {
 	DatabaseURL       string // For Railway compatibility
 	DatabasePublicURL string // Railway provides this for external access
 
+	DatabaseReplicaURLs []string // Read replicas serving item reads and permission lookups
+
+	// Connection pool limits, applied to the primary and to each replica
+	DBMaxOpenConns    int
+	DBMaxIdleConns    int
+	DBConnMaxLifetime time.Duration
+
 	JWTSecret string
 	JWTExpiry time.Duration
 
+	// Access token signing: HS256 with JWTSecret, or RS256/EdDSA with the PEM private keys
+	// of JWTSigningKeys, the first of which signs (the others still verify)
+	JWTAlgorithm   string
+	JWTSigningKeys []string
+
+	ImpersonationExpiry time.Duration // Lifetime of the tokens instance admins get to act as another user
+
 	ServerPort int
 	ServerMode string
+
+	LogLevel  string // debug, info, warn or error
+	LogFormat string // json or text
+
+	MigrationsDir    string
+	MigrateOnStartup bool // Apply pending migrations when the server starts
+
+	SeedsDir      string
+	SeedEnv       string // Seeds in SeedsDir/<SeedEnv> are applied after the shared ones
+	SeedOnStartup bool
+
+	// Initial admin, created by seeding when no user with AdminEmail exists. Without
+	// AdminPassword a random password is generated and logged once.
+	AdminEmail     string
+	AdminPassword  string
+	AdminFirstName string
+	AdminLastName  string
+
+	// Password policy applied to signup, user creation and seeding
+	PasswordMinLength     int
+	PasswordRequireUpper  bool
+	PasswordRequireLower  bool
+	PasswordRequireDigit  bool
+	PasswordRequireSymbol bool
+
+	// Password reset links
+	PasswordResetTTL time.Duration
+	PasswordResetURL string // Page that receives ?token=; without it the email contains the bare token
+
+	// Two-factor authentication
+	TOTPIssuer string // Account issuer shown in authenticator apps
+
+	// Outgoing email
+	MailDriver         string // "log", "smtp", "sendgrid" or "ses"
+	MailFrom           string // Default sender; tenants can override it in their settings
+	MailFromName       string
+	SMTPHost           string
+	SMTPPort           int
+	SMTPUsername       string
+	SMTPPassword       string
+	SendGridAPIKey     string
+	SESRegion          string
+	SESAccessKeyID     string
+	SESSecretAccessKey string
+
+	// Sign in with external identity providers; a provider is enabled by its client ID
+	OAuthRedirectBaseURL    string // Public URL of the API; callbacks go to <base>/auth/oauth/<provider>/callback
+	OAuthSuccessURL         string // Page that receives #token=... after sign in; without it the callback returns JSON
+	OAuthGoogleClientID     string
+	OAuthGoogleClientSecret string
+	OAuthGitHubClientID     string
+	OAuthGitHubClientSecret string
+	OAuthOIDCIssuer         string
+	OAuthOIDCClientID       string
+	OAuthOIDCClientSecret   string
+	OAuthOIDCScopes         []string
+	OAuthAutoProvision      bool     // Create users for unknown identities
+	OAuthDefaultTenant      string   // Slug of the tenant provisioned users join
+	OAuthDefaultRoles       []string // Roles of every provisioned user in that tenant
+	OAuthRoleClaim          string   // Identity claim matched against OAUTH_ROLE_MAPPING
+	OAuthRoleMapping        []string // "claim value=role" entries
+
+	// Access tokens of a trusted external identity provider (Auth0, Clerk, Keycloak, ...),
+	// accepted next to Basin's own. Users are resolved and provisioned like OAuth sign ins.
+	ExternalJWTIssuer      string   // iss of the tokens; enables them
+	ExternalJWTJWKSURL     string   // Public keys; discovered from the issuer when empty
+	ExternalJWTAudience    string   // Required aud; required with ExternalJWTIssuer
+	ExternalJWTRoleClaim   string   // Claim matched against EXTERNAL_JWT_ROLE_MAPPING
+	ExternalJWTRoleMapping []string // "claim value=role" entries
+
+	// Outgoing webhook delivery
+	WebhookTimeout     time.Duration
+	WebhookMaxAttempts int
+	WebhookRetryDelay  time.Duration // Delay before the first retry; doubles on every attempt
+
+	// Background job queue
+	JobMaxAttempts int
+	JobRetryDelay  time.Duration // Delay before the first retry; doubles on every attempt
+	JobRetention   time.Duration // How long completed jobs are kept
+
+	// Item imports
+	ImportMaxSize int64 // Largest CSV or XLSX file accepted by POST /items/:table/import
+
+	// Request bodies (multipart uploads have their own limits)
+	MaxBodySize     int64    // Largest JSON body of most endpoints
+	MaxBulkBodySize int64    // Largest JSON body of bulk, upsert and schema apply requests
+	JSONMaxDepth    int      // How deeply objects and arrays may nest; 0 for no limit
+	JSONStrict      []string // Kinds of bodies whose unknown fields are rejected (auth, admin)
+
+	// How long responses of requests with an Idempotency-Key are replayed; 0 ignores the header
+	IdempotencyTTL time.Duration
+
+	// Response format of the item routes: "basin", or "directus" for Directus envelopes
+	APIDialect string
+
+	// Whether the embedded admin app is served under /admin
+	AdminUI bool
+
+	// Asset storage
+	StorageDriver      string // "local" or "s3"
+	StorageLocalPath   string
+	AssetMaxUploadSize int64
+	S3Bucket           string
+	S3Region           string
+	S3Endpoint         string // Custom endpoint for S3-compatible services (MinIO, R2, ...)
+	S3AccessKeyID      string
+	S3SecretAccessKey  string
+	S3ForcePathStyle   bool
+
+	// Permission check caching
+	PermissionCacheEnabled bool
+	PermissionCacheTTL     time.Duration
+
+	// Postgres RLS policies generated from permissions on every data table
+	RLSPoliciesEnabled bool
+
+	// Schema metadata caching; changes are announced, so the TTL only bounds missed ones
+	SchemaCacheEnabled bool
+	SchemaCacheTTL     time.Duration
+
+	// Caching of collection list responses; writes through the API drop them
+	ItemsCacheEnabled bool
+	ItemsCacheTTL     time.Duration
+
+	// Cache invalidations shared between instances
+	CacheBus string // postgres, redis or none
+	RedisURL string // Redis server of the redis cache bus
+
+	// API key lifecycle
+	APIKeyRotationGracePeriod time.Duration // How long the old secret keeps working after a rotation
+	APIKeyExpiryWarning       time.Duration // How long before expiry a key counts as expiring soon
+	ServiceAccountKeyRotation time.Duration // How long a service account key may go unrotated; 0 disables the policy
+
+	// Default tenant limits; tenants can get their own in their settings. 0 means unlimited.
+	TenantMaxCollections        int
+	TenantMaxItemsPerCollection int
+	TenantMaxAPIKeys            int
+	TenantMaxStorage            int64 // Bytes of uploaded assets
+	TenantRequestsPerMinute     int
+
+	TenantImportMaxSize int64         // Largest archive accepted by POST /tenants/import
+	TenantPurgeAfter    time.Duration // How long a deleted tenant can be restored before it is purged
+	TenantBaseDomain    string        // Requests to <slug>.TenantBaseDomain are served in that tenant
+
+	// Deleted fields: "drop" removes their column, "archive" keeps it as _deleted_<name>
+	FieldDeleteMode string
+
+	// Encrypted fields: base64 AES-256 keys, the first of which encrypts (the others still
+	// decrypt), and a file with more of them, one per line, such as one written by a KMS
+	FieldEncryptionKeys    []string
+	FieldEncryptionKeyFile string
+
+	// "role:action" permissions granted on every new collection besides the admin role's CRUD
+	CollectionPermissionPreset []string
+
+	// Response compression (Brotli or gzip)
+	CompressionEnabled bool
+	CompressionMinSize int      // Smaller responses are sent as is
+	CompressionTypes   []string // Media types worth compressing
+
+	// Cross-origin requests from browsers
+	CORSAllowedOrigins   []string // Exact origins, "*" or wildcard subdomains such as https://*.example.com
+	CORSAllowedMethods   []string
+	CORSAllowedHeaders   []string
+	CORSExposedHeaders   []string
+	CORSAllowCredentials bool
+	CORSMaxAge           time.Duration // How long browsers may cache a preflight response
 }
 
 func Load() (*Config, error) {
 	// Always try to load .env file first (for both local and Railway)
 	// This ensures DATABASE_URL is available for auto-detection
 	if err := godotenv.Load(".env"); err != nil {
-		fmt.Printf("Warning: Could not load .env file: %v\n", err)
+		slog.Debug("no .env file loaded", "error", err)
 	}
 
 	// Determine deployment mode AFTER loading .env
 	deploymentMode := getDeploymentMode()
-
-	fmt.Printf("=== DEPLOYMENT MODE: %s ===\n", deploymentMode)
+	dbDriver := strings.ToLower(getEnv("DB_DRIVER", "postgres"))
 
 	config := &Config{
 		DeploymentMode: deploymentMode,
 
+		DBDriver:   dbDriver,
+		SQLitePath: getEnv("SQLITE_PATH", "basin.db"),
+
 		DBHost:            getEnv("DB_HOST", "localhost"),
-		DBPort:            getEnvAsInt("DB_PORT", 5432),
+		DBPort:            getEnvAsInt("DB_PORT", defaultDBPort(dbDriver)),
 		DBUser:            getEnv("DB_USER", "postgres"),
 		DBPassword:        getEnv("DB_PASSWORD", "postgres"),
 		DBName:            getEnv("DB_NAME", "go_rbac_db"),
@@ -62,40 +250,209 @@ func Load() (*Config, error) {
 		DatabaseURL:       getEnv("DATABASE_URL", ""),        // Railway provides this
 		DatabasePublicURL: getEnv("DATABASE_PUBLIC_URL", ""), // Railway provides this for external access
 
+		DatabaseReplicaURLs: getEnvAsList("DATABASE_REPLICA_URL", nil),
+
+		DBMaxOpenConns:    getEnvAsInt("DB_MAX_OPEN_CONNS", 25),
+		DBMaxIdleConns:    getEnvAsInt("DB_MAX_IDLE_CONNS", 10),
+		DBConnMaxLifetime: getEnvAsDuration("DB_CONN_MAX_LIFETIME", 30*time.Minute),
+
 		JWTSecret: getEnv("JWT_SECRET", "your-super-secret-jwt-key-change-in-production"),
 		JWTExpiry: getEnvAsDuration("JWT_EXPIRY", 24*time.Hour),
 
+		JWTAlgorithm:   getEnv("JWT_ALGORITHM", "HS256"),
+		JWTSigningKeys: getEnvAsList("JWT_SIGNING_KEYS", nil),
+
+		ImpersonationExpiry: getEnvAsDuration("IMPERSONATION_EXPIRY", 15*time.Minute),
+
 		ServerPort: getEnvAsInt("SERVER_PORT", 8080),
 		ServerMode: getEnv("SERVER_MODE", "debug"),
-	}
 
-	// Debug: Print all environment variables at startup
-	fmt.Printf("=== ENVIRONMENT VARIABLES DEBUG ===\n")
-	fmt.Printf("DATABASE_URL: %s\n", os.Getenv("DATABASE_URL"))
-	fmt.Printf("DATABASE_PUBLIC_URL: %s\n", os.Getenv("DATABASE_PUBLIC_URL"))
-	fmt.Printf("DB_HOST: %s\n", os.Getenv("DB_HOST"))
-	fmt.Printf("DB_PORT: %s\n", os.Getenv("DB_PORT"))
-	fmt.Printf("DB_USER: %s\n", os.Getenv("DB_USER"))
-	fmt.Printf("DB_PASSWORD: %s\n", os.Getenv("DB_PASSWORD"))
-	fmt.Printf("DB_NAME: %s\n", os.Getenv("DB_NAME"))
-	fmt.Printf("DB_SSLMODE: %s\n", os.Getenv("DB_SSLMODE"))
-	fmt.Printf("PORT: %s\n", os.Getenv("PORT"))
-	fmt.Printf("===================================\n")
+		LogLevel:  getEnv("LOG_LEVEL", "info"),
+		LogFormat: getEnv("LOG_FORMAT", "json"),
+
+		MigrationsDir:    getEnv("MIGRATIONS_DIR", defaultMigrationsDir(dbDriver)),
+		MigrateOnStartup: getEnvAsBool("MIGRATE_ON_STARTUP", true),
+
+		SeedsDir:      getEnv("SEEDS_DIR", "seeds"),
+		SeedEnv:       getEnv("SEED_ENV", defaultSeedEnv()),
+		SeedOnStartup: getEnvAsBool("SEED_ON_STARTUP", true),
+
+		AdminEmail:     getEnv("ADMIN_EMAIL", "admin@example.com"),
+		AdminPassword:  getEnv("ADMIN_PASSWORD", ""),
+		AdminFirstName: getEnv("ADMIN_FIRST_NAME", "Admin"),
+		AdminLastName:  getEnv("ADMIN_LAST_NAME", "User"),
+
+		PasswordMinLength:     getEnvAsInt("PASSWORD_MIN_LENGTH", 8),
+		PasswordRequireUpper:  getEnvAsBool("PASSWORD_REQUIRE_UPPERCASE", false),
+		PasswordRequireLower:  getEnvAsBool("PASSWORD_REQUIRE_LOWERCASE", false),
+		PasswordRequireDigit:  getEnvAsBool("PASSWORD_REQUIRE_DIGIT", false),
+		PasswordRequireSymbol: getEnvAsBool("PASSWORD_REQUIRE_SYMBOL", false),
+
+		PasswordResetTTL: getEnvAsDuration("PASSWORD_RESET_TTL", time.Hour),
+		PasswordResetURL: getEnv("PASSWORD_RESET_URL", ""),
+
+		TOTPIssuer: getEnv("TOTP_ISSUER", "Basin"),
+
+		MailDriver:         getEnv("MAIL_DRIVER", "log"),
+		MailFrom:           getEnv("MAIL_FROM", "noreply@example.com"),
+		MailFromName:       getEnv("MAIL_FROM_NAME", "Basin"),
+		SMTPHost:           getEnv("SMTP_HOST", ""),
+		SMTPPort:           getEnvAsInt("SMTP_PORT", 587),
+		SMTPUsername:       getEnv("SMTP_USERNAME", ""),
+		SMTPPassword:       getEnv("SMTP_PASSWORD", ""),
+		SendGridAPIKey:     getEnv("SENDGRID_API_KEY", ""),
+		SESRegion:          getEnv("SES_REGION", "us-east-1"),
+		SESAccessKeyID:     getEnv("SES_ACCESS_KEY_ID", ""),
+		SESSecretAccessKey: getEnv("SES_SECRET_ACCESS_KEY", ""),
+
+		OAuthRedirectBaseURL:    getEnv("OAUTH_REDIRECT_BASE_URL", ""),
+		OAuthSuccessURL:         getEnv("OAUTH_SUCCESS_URL", ""),
+		OAuthGoogleClientID:     getEnv("OAUTH_GOOGLE_CLIENT_ID", ""),
+		OAuthGoogleClientSecret: getEnv("OAUTH_GOOGLE_CLIENT_SECRET", ""),
+		OAuthGitHubClientID:     getEnv("OAUTH_GITHUB_CLIENT_ID", ""),
+		OAuthGitHubClientSecret: getEnv("OAUTH_GITHUB_CLIENT_SECRET", ""),
+		OAuthOIDCIssuer:         getEnv("OAUTH_OIDC_ISSUER", ""),
+		OAuthOIDCClientID:       getEnv("OAUTH_OIDC_CLIENT_ID", ""),
+		OAuthOIDCClientSecret:   getEnv("OAUTH_OIDC_CLIENT_SECRET", ""),
+		OAuthOIDCScopes:         getEnvAsList("OAUTH_OIDC_SCOPES", []string{"openid", "email", "profile"}),
+		OAuthAutoProvision:      getEnvAsBool("OAUTH_AUTO_PROVISION", true),
+		OAuthDefaultTenant:      getEnv("OAUTH_DEFAULT_TENANT", "main"),
+		OAuthDefaultRoles:       getEnvAsList("OAUTH_DEFAULT_ROLES", []string{"viewer"}),
+		OAuthRoleClaim:          getEnv("OAUTH_ROLE_CLAIM", "groups"),
+		OAuthRoleMapping:        getEnvAsList("OAUTH_ROLE_MAPPING", nil),
+
+		ExternalJWTIssuer:      getEnv("EXTERNAL_JWT_ISSUER", ""),
+		ExternalJWTJWKSURL:     getEnv("EXTERNAL_JWT_JWKS_URL", ""),
+		ExternalJWTAudience:    getEnv("EXTERNAL_JWT_AUDIENCE", ""),
+		ExternalJWTRoleClaim:   getEnv("EXTERNAL_JWT_ROLE_CLAIM", "roles"),
+		ExternalJWTRoleMapping: getEnvAsList("EXTERNAL_JWT_ROLE_MAPPING", nil),
+
+		WebhookTimeout:     getEnvAsDuration("WEBHOOK_TIMEOUT", 10*time.Second),
+		WebhookMaxAttempts: getEnvAsInt("WEBHOOK_MAX_ATTEMPTS", 5),
+		WebhookRetryDelay:  getEnvAsDuration("WEBHOOK_RETRY_DELAY", 30*time.Second),
+
+		JobMaxAttempts: getEnvAsInt("JOB_MAX_ATTEMPTS", 5),
+		JobRetryDelay:  getEnvAsDuration("JOB_RETRY_DELAY", 30*time.Second),
+		JobRetention:   getEnvAsDuration("JOB_RETENTION", 7*24*time.Hour),
+
+		ImportMaxSize: int64(getEnvAsInt("IMPORT_MAX_SIZE", 20<<20)),
+
+		MaxBodySize:     int64(getEnvAsInt("MAX_BODY_SIZE", 1<<20)),
+		MaxBulkBodySize: int64(getEnvAsInt("MAX_BULK_BODY_SIZE", 10<<20)),
+		JSONMaxDepth:    getEnvAsInt("JSON_MAX_DEPTH", 32),
+		JSONStrict:      getEnvAsList("JSON_STRICT", nil),
+
+		IdempotencyTTL: getEnvAsDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+
+		APIDialect: getEnv("API_DIALECT", "basin"),
+
+		AdminUI: getEnvAsBool("ADMIN_UI", true),
+
+		StorageDriver:      getEnv("STORAGE_DRIVER", "local"),
+		StorageLocalPath:   getEnv("STORAGE_LOCAL_PATH", "./uploads"),
+		AssetMaxUploadSize: int64(getEnvAsInt("ASSET_MAX_UPLOAD_SIZE", 25<<20)),
+		S3Bucket:           getEnv("S3_BUCKET", ""),
+		S3Region:           getEnv("S3_REGION", "us-east-1"),
+		S3Endpoint:         getEnv("S3_ENDPOINT", ""),
+		S3AccessKeyID:      getEnv("S3_ACCESS_KEY_ID", ""),
+		S3SecretAccessKey:  getEnv("S3_SECRET_ACCESS_KEY", ""),
+		S3ForcePathStyle:   getEnvAsBool("S3_FORCE_PATH_STYLE", false),
+
+		PermissionCacheEnabled: getEnvAsBool("PERMISSION_CACHE_ENABLED", true),
+		PermissionCacheTTL:     getEnvAsDuration("PERMISSION_CACHE_TTL", 30*time.Second),
+
+		RLSPoliciesEnabled: getEnvAsBool("RLS_POLICIES_ENABLED", false),
+
+		SchemaCacheEnabled: getEnvAsBool("SCHEMA_CACHE_ENABLED", true),
+		SchemaCacheTTL:     getEnvAsDuration("SCHEMA_CACHE_TTL", 5*time.Minute),
+
+		ItemsCacheEnabled: getEnvAsBool("ITEMS_CACHE_ENABLED", false),
+		ItemsCacheTTL:     getEnvAsDuration("ITEMS_CACHE_TTL", 30*time.Second),
+
+		CacheBus: getEnv("CACHE_BUS", "postgres"),
+		RedisURL: getEnv("REDIS_URL", ""),
+
+		APIKeyRotationGracePeriod: getEnvAsDuration("API_KEY_ROTATION_GRACE_PERIOD", 24*time.Hour),
+		APIKeyExpiryWarning:       getEnvAsDuration("API_KEY_EXPIRY_WARNING", 7*24*time.Hour),
+		ServiceAccountKeyRotation: getEnvAsDuration("SERVICE_ACCOUNT_KEY_ROTATION", 90*24*time.Hour),
+
+		TenantMaxCollections:        getEnvAsInt("TENANT_MAX_COLLECTIONS", 0),
+		TenantMaxItemsPerCollection: getEnvAsInt("TENANT_MAX_ITEMS_PER_COLLECTION", 0),
+		TenantMaxAPIKeys:            getEnvAsInt("TENANT_MAX_API_KEYS", 0),
+		TenantMaxStorage:            int64(getEnvAsInt("TENANT_MAX_STORAGE", 0)),
+		TenantRequestsPerMinute:     getEnvAsInt("TENANT_REQUESTS_PER_MINUTE", 0),
+
+		TenantImportMaxSize: int64(getEnvAsInt("TENANT_IMPORT_MAX_SIZE", 100<<20)),
+		TenantPurgeAfter:    getEnvAsDuration("TENANT_PURGE_AFTER", 30*24*time.Hour),
+		TenantBaseDomain:    getEnv("TENANT_BASE_DOMAIN", ""),
+
+		FieldDeleteMode: getEnv("FIELD_DELETE_MODE", "drop"),
+
+		FieldEncryptionKeys:    getEnvAsList("FIELD_ENCRYPTION_KEYS", nil),
+		FieldEncryptionKeyFile: getEnv("FIELD_ENCRYPTION_KEY_FILE", ""),
+
+		CollectionPermissionPreset: getEnvAsList("COLLECTION_PERMISSION_PRESET", nil),
+
+		CompressionEnabled: getEnvAsBool("COMPRESSION_ENABLED", true),
+		CompressionMinSize: getEnvAsInt("COMPRESSION_MIN_SIZE", 1024),
+		CompressionTypes: getEnvAsList("COMPRESSION_TYPES", []string{
+			"application/json", "application/x-ndjson", "application/javascript", "application/xml",
+			"text/csv", "text/plain", "text/html", "text/css", "image/svg+xml",
+		}),
+
+		CORSAllowedOrigins:   getEnvAsList("CORS_ALLOWED_ORIGINS", []string{"*"}),
+		CORSAllowedMethods:   getEnvAsList("CORS_ALLOWED_METHODS", []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"}),
+		CORSAllowedHeaders:   getEnvAsList("CORS_ALLOWED_HEADERS", []string{"Origin", "Content-Type", "Content-Length", "Accept-Encoding", "X-CSRF-Token", "Authorization", "X-Request-ID", "X-Tenant"}),
+		CORSExposedHeaders:   getEnvAsList("CORS_EXPOSED_HEADERS", []string{"X-Request-ID"}),
+		CORSAllowCredentials: getEnvAsBool("CORS_ALLOW_CREDENTIALS", false),
+		CORSMaxAge:           getEnvAsDuration("CORS_MAX_AGE", 12*time.Hour),
+	}
 
 	// Handle database configuration based on deployment mode
 	if err := config.configureDatabase(); err != nil {
 		return nil, fmt.Errorf("failed to configure database: %w", err)
 	}
 
-	// Debug: Print the actual values being loaded
-	fmt.Printf("DEBUG: Final configuration:\n")
-	fmt.Printf("  DEPLOYMENT_MODE: %s\n", config.DeploymentMode)
-	fmt.Printf("  DB_HOST=%s, DB_PORT=%d, DB_USER=%s, DB_NAME=%s, DB_SSLMODE=%s\n",
-		config.DBHost, config.DBPort, config.DBUser, config.DBName, config.DBSSLMode)
+	slog.Debug("configuration loaded", "deployment_mode", config.DeploymentMode,
+		"db_host", config.DBHost, "db_port", config.DBPort, "db_user", config.DBUser,
+		"db_name", config.DBName, "db_sslmode", config.DBSSLMode)
 
 	return config, nil
 }
 
+// defaultSeedEnv is "production" for servers in gin's release mode and "development" otherwise
+func defaultSeedEnv() string {
+	if getEnv("SERVER_MODE", "debug") == "release" {
+		return "production"
+	}
+	return "development"
+}
+
+// defaultMigrationsDir is where the migrations of a database driver live: the SQLite and
+// MySQL schemas have directories of their own within the Postgres migrations
+func defaultMigrationsDir(driver string) string {
+	if strings.HasPrefix(driver, "sqlite") {
+		return "migrations/sqlite"
+	}
+	if isMySQL(driver) {
+		return "migrations/mysql"
+	}
+	return "migrations"
+}
+
+// defaultDBPort is the port the database of a driver listens on by default
+func defaultDBPort(driver string) int {
+	if isMySQL(driver) {
+		return 3306
+	}
+	return 5432
+}
+
+// isMySQL reports whether a DB_DRIVER name is MySQL's, which MariaDB shares
+func isMySQL(driver string) bool {
+	return driver == "mysql" || driver == "mariadb"
+}
+
 // getDeploymentMode determines the current deployment environment
 func getDeploymentMode() DeploymentMode {
 	// Check for explicit override
@@ -131,8 +488,6 @@ func (c *Config) configureDatabase() error {
 
 // configureRailwayDatabase sets up Railway-specific database configuration
 func (c *Config) configureRailwayDatabase() error {
-	fmt.Printf("Configuring database for Railway deployment...\n")
-
 	if c.DatabaseURL == "" {
 		return fmt.Errorf("DATABASE_URL is required for Railway deployment")
 	}
@@ -145,22 +500,14 @@ func (c *Config) configureRailwayDatabase() error {
 	// Force SSL mode for Railway
 	c.DBSSLMode = "require"
 
-	fmt.Printf("Railway database configured: host=%s, port=%d, db=%s, ssl=%s\n",
-		c.DBHost, c.DBPort, c.DBName, c.DBSSLMode)
-
 	return nil
 }
 
 // configureLocalDatabase sets up local development database configuration
 func (c *Config) configureLocalDatabase() error {
-	fmt.Printf("Configuring database for local development...\n")
-
 	// Use individual environment variables or defaults
 	// .env file should have been loaded if it exists
 
-	fmt.Printf("Local database configured: host=%s, port=%d, db=%s, ssl=%s\n",
-		c.DBHost, c.DBPort, c.DBName, c.DBSSLMode)
-
 	return nil
 }
 
@@ -205,32 +552,56 @@ func (c *Config) parseDatabaseURL() error {
 }
 
 func (c *Config) GetDBConnString() string {
-	fmt.Printf("DEBUG: GetDBConnString called with DEPLOYMENT_MODE=%s\n", c.DeploymentMode)
-
 	// For Railway deployment, we should always have DATABASE_URL
 	if c.DeploymentMode == DeploymentModeRailway {
 		if c.DatabaseURL != "" {
-			fmt.Printf("DEBUG: Using Railway DATABASE_URL (internal)\n")
+			slog.Debug("using DATABASE_URL")
 			return c.DatabaseURL
 		}
 		// If we're in Railway mode but don't have DATABASE_URL, this is an error
-		fmt.Printf("ERROR: Railway mode requires DATABASE_URL but none was provided\n")
+		slog.Error("railway mode requires DATABASE_URL but none was provided")
 		return ""
 	}
 
 	// For local development, try DATABASE_PUBLIC_URL first (for testing Railway from local)
 	if c.DatabasePublicURL != "" {
-		fmt.Printf("DEBUG: Using Railway DATABASE_PUBLIC_URL (external)\n")
+		slog.Debug("using DATABASE_PUBLIC_URL")
 		return c.DatabasePublicURL
 	}
 
 	// Fallback to local database configuration
+	if isMySQL(c.DBDriver) {
+		slog.Debug("using local database", "host", c.DBHost, "port", c.DBPort, "database", c.DBName)
+		return c.mysqlURL()
+	}
 	connStr := fmt.Sprintf("host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
 		c.DBHost, c.DBPort, c.DBUser, c.DBPassword, c.DBName, c.DBSSLMode)
-	fmt.Printf("DEBUG: Local connection string: %s\n", connStr)
+	slog.Debug("using local database", "host", c.DBHost, "port", c.DBPort, "database", c.DBName)
 	return connStr
 }
 
+// mysqlURL is the mysql:// URL of the local database configuration. DB_SSLMODE keeps its
+// Postgres values: require encrypts without verifying, as it does in libpq.
+func (c *Config) mysqlURL() string {
+	tls := "false"
+	switch c.DBSSLMode {
+	case "allow", "prefer":
+		tls = "preferred"
+	case "require":
+		tls = "skip-verify"
+	case "verify-ca", "verify-full":
+		tls = "true"
+	}
+	dsn := url.URL{
+		Scheme:   "mysql",
+		User:     url.UserPassword(c.DBUser, c.DBPassword),
+		Host:     net.JoinHostPort(c.DBHost, strconv.Itoa(c.DBPort)),
+		Path:     "/" + c.DBName,
+		RawQuery: url.Values{"tls": {tls}}.Encode(),
+	}
+	return dsn.String()
+}
+
 func getEnv(key, defaultValue string) string {
 	if value := os.Getenv(key); value != "" {
 		return value
@@ -255,3 +626,31 @@ func getEnvAsDuration(key string, defaultValue time.Duration) time.Duration {
 	}
 	return defaultValue
 }
+
+// getEnvAsList splits a comma-separated variable into its trimmed, non-empty items
+func getEnvAsList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	if len(items) == 0 {
+		return defaultValue
+	}
+	return items
+}
+
+func getEnvAsBool(key string, defaultValue bool) bool {
+	if value := os.Getenv(key); value != "" {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return defaultValue
+}
@@ -0,0 +1,138 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// Dialect is a database Basin runs on. Basin's SQL is written for Postgres; a dialect
+// names tables and translates column types the way its database needs them, and reports
+// what the database cannot do. Statements themselves are translated by the dialect's
+// driver (see sqlite.go and mysql.go).
+type Dialect interface {
+	// Name identifies the dialect, as in DB_DRIVER
+	Name() string
+	// Table returns the quoted reference to table name in schema
+	Table(schema, name string) string
+	// Index returns the quoted reference to index name of a table in schema, as DROP INDEX
+	// takes it
+	Index(schema, name string) string
+	// ColumnType returns the column definition storing what the Postgres definition does
+	ColumnType(definition string) string
+	// TableExists reports whether table name exists in schema
+	TableExists(ctx context.Context, q Executor, schema, name string) (bool, error)
+	// UniqueColumn reports whether a unique index on column alone exists on table, given
+	// as Table returns it
+	UniqueColumn(ctx context.Context, q Executor, table, column string) (bool, error)
+	// Indexes returns the indexes in schemas whose names start with prefix, with whether
+	// the database considers them valid
+	Indexes(ctx context.Context, q Executor, prefix string, schemas []string) (map[string]bool, error)
+	// RowLevelSecurity reports whether the database enforces row-level security policies
+	RowLevelSecurity() bool
+	// RowJSON reports whether to_jsonb turns a row into a JSON object
+	RowJSON() bool
+	// FullTextSearch reports whether the database has text search (to_tsvector and
+	// friends)
+	FullTextSearch() bool
+	// CreatesDataTables reports whether the database creates the data table of a new
+	// collection itself, with the trigger of the base schema
+	CreatesDataTables() bool
+}
+
+// Dialect names
+const (
+	DriverPostgres = "postgres"
+	DriverSQLite   = "sqlite"
+	DriverMySQL    = "mysql"
+)
+
+// DialectFor returns the dialect of a DB_DRIVER name
+func DialectFor(driver string) (Dialect, error) {
+	switch strings.ToLower(driver) {
+	case "", DriverPostgres, "postgresql":
+		return Postgres, nil
+	case DriverSQLite, "sqlite3":
+		return SQLite, nil
+	case DriverMySQL, "mariadb":
+		return MySQL, nil
+	}
+	return nil, fmt.Errorf("unknown database driver %q (use %s, %s or %s)", driver, DriverPostgres, DriverSQLite, DriverMySQL)
+}
+
+// Postgres is the dialect of PostgreSQL, which Basin's SQL is written for
+var Postgres Dialect = postgresDialect{}
+
+type postgresDialect struct{}
+
+func (postgresDialect) Name() string { return DriverPostgres }
+
+func (postgresDialect) Table(schema, name string) string {
+	return fmt.Sprintf(`"%s"."%s"`, schema, name)
+}
+
+func (postgresDialect) Index(schema, name string) string {
+	return fmt.Sprintf(`"%s"."%s"`, schema, name)
+}
+
+func (postgresDialect) ColumnType(definition string) string { return definition }
+
+func (postgresDialect) TableExists(ctx context.Context, q Executor, schema, name string) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT FROM information_schema.tables
+			WHERE table_schema = $1 AND table_name = $2
+		)`, schema, name).Scan(&exists)
+	return exists, err
+}
+
+func (postgresDialect) UniqueColumn(ctx context.Context, q Executor, table, column string) (bool, error) {
+	var unique bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pg_index i
+			JOIN pg_attribute a ON a.attrelid = i.indrelid AND a.attnum = i.indkey[0]
+			WHERE i.indrelid = $1::regclass AND i.indisunique AND i.indnatts = 1 AND a.attname = $2
+		)`, table, column).Scan(&unique)
+	return unique, err
+}
+
+func (postgresDialect) Indexes(ctx context.Context, q Executor, prefix string, schemas []string) (map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT c.relname, i.indisvalid
+		FROM pg_index i
+		JOIN pg_class c ON c.oid = i.indexrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE n.nspname = ANY($1) AND left(c.relname, length($2)) = $2`, pq.Array(schemas), prefix)
+	if err != nil {
+		return nil, err
+	}
+	return scanIndexes(rows)
+}
+
+func (postgresDialect) RowLevelSecurity() bool { return true }
+
+func (postgresDialect) RowJSON() bool { return true }
+
+func (postgresDialect) FullTextSearch() bool { return true }
+
+func (postgresDialect) CreatesDataTables() bool { return true }
+
+// scanIndexes reads rows of index names and validity into a map
+func scanIndexes(rows *sql.Rows) (map[string]bool, error) {
+	defer rows.Close()
+	indexes := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		var valid bool
+		if err := rows.Scan(&name, &valid); err != nil {
+			return nil, err
+		}
+		indexes[name] = valid
+	}
+	return indexes, rows.Err()
+}
@@ -0,0 +1,79 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Reconnect delays of listeners whose connection was lost
+const (
+	listenMinReconnect = time.Second
+	listenMaxReconnect = time.Minute
+	listenPingInterval = 90 * time.Second // How often an idle listener checks its connection
+)
+
+// Listen calls fn with the payload of every notification sent on channel with NOTIFY or
+// pg_notify, until ctx is done. Listening uses a connection of its own, which is
+// re-established when lost; fn is then called with reconnected set, since notifications
+// sent in the meantime are lost.
+//
+// SQLite has no notifications; a SQLite database serves a single instance, which learns of
+// its own changes without them, so Listen just waits for ctx. MySQL has none either, and
+// Listen fails: instances sharing a MySQL database need another cache bus.
+func (db *DB) Listen(ctx context.Context, channel string, fn func(payload string, reconnected bool)) error {
+	switch db.Dialect() {
+	case SQLite:
+		<-ctx.Done()
+		return nil
+	case MySQL:
+		return errors.New("MySQL has no notifications; use CACHE_BUS=redis to share changes between instances")
+	}
+	if db.dsn == "" {
+		return errors.New("listening requires a database opened with Open")
+	}
+
+	listener := pq.NewListener(db.dsn, listenMinReconnect, listenMaxReconnect, func(event pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Warn("database listener connection problem", "channel", channel, "error", err)
+		}
+	})
+	defer listener.Close()
+
+	if err := listener.Listen(channel); err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", channel, err)
+	}
+
+	ping := time.NewTicker(listenPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ping.C:
+			// A failed ping makes the listener reconnect
+			go listener.Ping()
+		case notification := <-listener.Notify:
+			// A nil notification follows a reconnect
+			if notification == nil {
+				fn("", true)
+				continue
+			}
+			fn(notification.Extra, false)
+		}
+	}
+}
+
+// Notify sends payload on channel to every listener, like NOTIFY. Inside a transaction
+// the notification is only delivered on commit; this sends it on the primary right away.
+// Databases without notifications ignore it.
+func (db *DB) Notify(ctx context.Context, channel, payload string) error {
+	if _, err := db.ExecContext(ctx, "SELECT pg_notify($1, $2)", channel, payload); err != nil {
+		return fmt.Errorf("failed to notify %s: %w", channel, err)
+	}
+	return nil
+}
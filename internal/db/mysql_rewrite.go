@@ -0,0 +1,646 @@
+package db
+
+import (
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/lib/pq"
+)
+
+// Basin's statements are written for Postgres. mysqlRewrite translates them for MySQL and
+// MariaDB, whose connections run with ANSI_QUOTES and PIPES_AS_CONCAT (see OpenMySQL) so
+// that "identifiers" and || mean what they do in Postgres:
+//   - ::type casts and the OF of FOR UPDATE OF are dropped
+//   - x = ANY($n) becomes x IN (...) over the elements of the array, x @> $n and x && $n
+//     JSON_CONTAINS over them, and unnest($n) WITH ORDINALITY a derived table of them;
+//     mysqlBind expands these once the array is known
+//   - x->'key' and x->>'key' become JSON_EXTRACT and JSON_UNQUOTE of a JSON path
+//   - ON CONFLICT becomes ON DUPLICATE KEY UPDATE. EXCLUDED.x becomes VALUES(x), and the
+//     WHERE of DO UPDATE guards every assignment.
+//   - UPDATE t ... FROM (SELECT ...) x becomes UPDATE t JOIN (SELECT ...) x, and
+//     IN (SELECT ...) in UPDATE and DELETE reads a derived table, as MySQL does not read
+//     the table it writes otherwise
+//   - a IS DISTINCT FROM b becomes NOT (a <=> b), and NULLS LAST and NULLS FIRST sort on
+//     x IS NULL first
+//   - NOW() and CURRENT_TIMESTAMP keep microseconds, pg_advisory_lock and
+//     pg_advisory_unlock become GET_LOCK and RELEASE_LOCK, and pg_notify and
+//     set_user_context do nothing
+//   - current_setting('app.current_user_id', true) and app.current_tenant_id become the
+//     values set_user_context set in the transaction
+//   - Postgres column types in CREATE TABLE and ADD COLUMN become MySQL's (see
+//     mysqlColumnType), and REFERENCES in a column definition a foreign key, which MySQL
+//     would otherwise ignore. On MariaDB, whose JSON is text, JSON columns are LONGBLOB.
+//   - public. is dropped, the column names MySQL reserves (key, trigger, interval) are
+//     quoted, and names longer than MySQL allows are shortened (see mysqlIdentifier)
+//   - ILIKE becomes LIKE, which ignores case in MySQL's default collations, SELECT FROM
+//     SELECT 1 FROM, and OFFSET without LIMIT gets the largest LIMIT there is
+//
+// RETURNING is cut off; the connection reads the rows back itself (see
+// mysqlConn.returning). String literals, quoted identifiers and comments are left alone.
+
+var (
+	mysqlReservedPattern     = regexp.MustCompile(`(?i)(\b(?:PRIMARY|FOREIGN|UNIQUE|DUPLICATE)\s+)?\b(key|trigger|interval)\b`)
+	mysqlNoOpCallPattern     = regexp.MustCompile(`(?i)\b(?:set_user_context|pg_notify)\s*\([^()]*\)`)
+	mysqlAdvisoryLockPattern = regexp.MustCompile(`(?i)\bpg_advisory_(lock|unlock)\s*\(([^()]*)\)`)
+	mysqlForUpdateOfPattern  = regexp.MustCompile(`(?i)(\bFOR\s+UPDATE)\s+OF\s+\w+(?:\s*,\s*\w+)*`)
+	mysqlJSONPathPattern     = regexp.MustCompile(`((?:\w+\.)?(?:\w+|\x00\d+\x00))((?:\s*->>?\s*(?:\x00\d+\x00|\d+))+)`)
+	mysqlJSONStepPattern     = regexp.MustCompile(`(->>?)\s*(\x00\d+\x00|\d+)`)
+	mysqlDistinctPattern     = regexp.MustCompile(`(?i)(\$\d+|(?:\w+\.)?(?:\w+|\x00\d+\x00))\s+IS\s+(NOT\s+)?DISTINCT\s+FROM\s+(\$\d+|(?:\w+\.)?(?:\w+|\x00\d+\x00))`)
+	mysqlNullsPattern        = regexp.MustCompile(`(?i)((?:\w+\.)?(?:\w+|\x00\d+\x00))(\s+(?:ASC|DESC))?\s+NULLS\s+(LAST|FIRST)\b`)
+	mysqlNowPattern          = regexp.MustCompile(`(?i)\bNOW\s*\(\s*\d*\s*\)`)
+	mysqlCurrentTimePattern  = regexp.MustCompile(`(?i)\bCURRENT_TIMESTAMP\b(?:\s*\(\s*\d*\s*\))?`)
+	mysqlUUIDCallPattern     = regexp.MustCompile(`(?i)\b(?:uuid_generate_v4|gen_random_uuid)\s*\(\s*\)`)
+	mysqlDefaultCallPattern  = regexp.MustCompile(`(?i)\bDEFAULT\s+(\w+\s*\([^()]*\))`)
+	mysqlOffsetPattern       = regexp.MustCompile(`(?i)(\bLIMIT\s+(?:\$\d+|\d+)\s+)?\bOFFSET\b`)
+	mysqlCreateTablePattern  = regexp.MustCompile(`(?is)^((?:\s*\x00\d+\x00)*\s*CREATE\s+TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?\S+?\s*\()(.*)(\)\s*;?\s*)$`)
+	mysqlAddColumnPattern    = regexp.MustCompile(`(?is)^(\s*ALTER\s+TABLE\s+\S+\s+ADD\s+COLUMN\s+(?:IF\s+NOT\s+EXISTS\s+)?)(\S+)\s+(.*?)\s*;?\s*$`)
+	mysqlTableElementPattern = regexp.MustCompile(`(?i)^(PRIMARY|UNIQUE|CONSTRAINT|FOREIGN|CHECK|KEY|INDEX)\b`)
+	mysqlReferencesPattern   = regexp.MustCompile(`(?is)\s+REFERENCES\s+(.*)$`)
+	mysqlReturningPattern    = regexp.MustCompile(`(?is)\s+RETURNING\s+(.+?)\s*;?\s*$`)
+	mysqlConflictPattern     = regexp.MustCompile(`(?is)\s+ON\s+CONFLICT\s*(?:\(([^)]*)\))?\s*DO\s+(?:(NOTHING)|UPDATE\s+SET\s+(.*?)(?:\s+WHERE\s+(.*?))?)\s*;?\s*$`)
+	mysqlInsertPattern       = regexp.MustCompile(`(?is)^((?:\s*\x00\d+\x00)*\s*INSERT\s+INTO\s+)(\S+?)\s*\(([^)]*)\)\s*`)
+	mysqlExcludedPattern     = regexp.MustCompile(`(?i)\bEXCLUDED\.(\w+|\x00\d+\x00)`)
+	mysqlUpdateFromPattern   = regexp.MustCompile(`(?is)^((?:\s*\x00\d+\x00)*\s*UPDATE\s+\S+\s+\w+)\s+SET\s+(.*?)\s+FROM\s+(\(.*\)\s*(?:AS\s+)?\w+)\s+WHERE\s+(.*)$`)
+	mysqlWritePattern        = regexp.MustCompile(`(?i)^(?:\s*\x00\d+\x00)*\s*(UPDATE|DELETE)\b`)
+	mysqlInSelectPattern     = regexp.MustCompile(`(?i)\bIN\s*\(\s*SELECT\b`)
+	mysqlValuesPattern       = regexp.MustCompile(`(?i)\)\s*VALUES\s*\(`)
+	mysqlSubqueryPattern     = regexp.MustCompile(`(?i)\(\s*SELECT\b`)
+	mysqlColumnTypePatterns  = []struct {
+		pattern     *regexp.Regexp
+		replacement string
+	}{
+		{regexp.MustCompile(`(?i)\b\w+(?:\s*\(\s*\d+\s*\))?\s*\[\]`), "JSON"},
+		{regexp.MustCompile(`(?i)\bTIMESTAMPTZ\b|\bTIMESTAMP\b(?:\s+WITH(?:OUT)?\s+TIME\s+ZONE)?`), "DATETIME(6)"},
+		{regexp.MustCompile(`(?i)\bJSONB\b`), "JSON"},
+		{regexp.MustCompile(`(?i)\bDOUBLE\s+PRECISION\b`), "DOUBLE"},
+		{regexp.MustCompile(`(?i)\bBYTEA\b`), "LONGBLOB"},
+		{regexp.MustCompile(`(?i)\bTEXT\b`), "LONGTEXT"},
+	}
+	mysqlUUIDTypePattern      = regexp.MustCompile(`(?i)\bUUID\b(\s*\()?`)
+	mysqlNumericPattern       = regexp.MustCompile(`(?i)\bNUMERIC\b(\s*\([^)]*\))?`)
+	mysqlJSONTypePattern      = regexp.MustCompile(`(?i)\bJSON\b`)
+	mysqlArrayTypePattern     = regexp.MustCompile(`(?i)\b(\w+)(?:\s*\(\s*\d+\s*\))?\s*\[\]`)
+	mysqlTextTypePattern      = regexp.MustCompile(`(?i)^(TEXT|VARCHAR|CHAR|UUID)$`)
+	mysqlLargeTypePattern     = regexp.MustCompile(`(?i)^\s*(JSON|LONGTEXT|LONGBLOB)\b`)
+	mysqlLiteralDefaultRegexp = regexp.MustCompile(`(?i)\bDEFAULT\s+(\x00\d+\x00|-?\d+(?:\.\d+)?|TRUE|FALSE)`)
+	mysqlBindPattern          = regexp.MustCompile(`basin_(in|all|some|unnest|setting)\(([^()]*)\)|\$(\d+)`)
+)
+
+// mysqlStatement is a statement translated for MySQL, kept with its literals, quoted
+// identifiers and comments masked (see maskQuoted) until its arguments are bound
+type mysqlStatement struct {
+	masked string
+	quoted []string
+
+	returning string   // What RETURNING returned, cut off masked
+	conflict  []string // Columns of ON CONFLICT
+	skipsRows bool     // ON CONFLICT may leave the row alone: DO NOTHING, or DO UPDATE with a WHERE
+}
+
+// mysqlMaxIdentifier is the longest name MySQL allows for a table, column, index or
+// constraint
+const mysqlMaxIdentifier = 64
+
+// mysqlIdentifier shortens names MySQL would reject as too long: the start of the name is
+// kept, followed by a hash of all of it
+func mysqlIdentifier(name string) string {
+	if len(name) <= mysqlMaxIdentifier {
+		return name
+	}
+	end := mysqlMaxIdentifier - 9
+	for end > 0 && !utf8.RuneStart(name[end]) {
+		end--
+	}
+	sum := sha256.Sum256([]byte(name))
+	return name[:end] + "_" + hex.EncodeToString(sum[:4])
+}
+
+// mysqlRewrites caches translated statements, separately for MySQL and MariaDB
+var mysqlRewrites [2]sync.Map
+
+// mysqlRewrite translates a statement written for Postgres for MySQL, or for MariaDB
+func mysqlRewrite(query string, mariadb bool) *mysqlStatement {
+	cache := &mysqlRewrites[0]
+	if mariadb {
+		cache = &mysqlRewrites[1]
+	}
+	if cached, ok := cache.Load(query); ok {
+		return cached.(*mysqlStatement)
+	}
+
+	masked, quoted := maskQuoted(query)
+	for i, part := range quoted {
+		if len(part) > mysqlMaxIdentifier+2 && part[0] == '"' && part[len(part)-1] == '"' {
+			quoted[i] = `"` + mysqlIdentifier(part[1:len(part)-1]) + `"`
+		}
+	}
+	st := &mysqlStatement{quoted: quoted}
+
+	masked = publicPattern.ReplaceAllString(masked, "")
+	masked = mysqlReservedPattern.ReplaceAllStringFunc(masked, func(word string) string {
+		match := mysqlReservedPattern.FindStringSubmatch(word)
+		if match[1] != "" {
+			return word
+		}
+		return st.literal(`"` + strings.ToLower(match[2]) + `"`)
+	})
+	masked = mysqlNoOpCallPattern.ReplaceAllString(masked, "NULL")
+	masked = mysqlAdvisoryLockPattern.ReplaceAllStringFunc(masked, func(call string) string {
+		match := mysqlAdvisoryLockPattern.FindStringSubmatch(call)
+		name := "CONCAT(" + st.literal("'basin_'") + ", " + match[2] + ")"
+		if strings.EqualFold(match[1], "unlock") {
+			return "RELEASE_LOCK(" + name + ")"
+		}
+		return "GET_LOCK(" + name + ", -1)"
+	})
+	masked = mysqlForUpdateOfPattern.ReplaceAllString(masked, "$1")
+	masked = castPattern.ReplaceAllString(masked, "")
+	masked = anyPattern.ReplaceAllString(masked, "$1 IN (basin_in($2))")
+	masked = arrayOperatorPattern.ReplaceAllStringFunc(masked, func(comparison string) string {
+		match := arrayOperatorPattern.FindStringSubmatch(comparison)
+		function := "basin_all"
+		if match[2] == "&&" {
+			function = "basin_some"
+		}
+		return function + "(" + match[1] + ", " + match[3] + ")"
+	})
+	masked = unnestPattern.ReplaceAllString(masked, "basin_unnest($1, $3, $4) AS $2")
+	masked = currentSettingRegexp.ReplaceAllString(masked, "basin_setting($1)")
+	masked = mysqlJSONPathPattern.ReplaceAllStringFunc(masked, func(operand string) string {
+		match := mysqlJSONPathPattern.FindStringSubmatch(operand)
+		return st.jsonPath(match[1], match[2])
+	})
+	masked = mysqlDistinctPattern.ReplaceAllStringFunc(masked, func(comparison string) string {
+		match := mysqlDistinctPattern.FindStringSubmatch(comparison)
+		if match[2] != "" {
+			return "(" + match[1] + " <=> " + match[3] + ")"
+		}
+		return "NOT (" + match[1] + " <=> " + match[3] + ")"
+	})
+	masked = mysqlNullsPattern.ReplaceAllStringFunc(masked, func(order string) string {
+		match := mysqlNullsPattern.FindStringSubmatch(order)
+		nulls := " IS NULL, "
+		if strings.EqualFold(match[3], "FIRST") {
+			nulls = " IS NOT NULL, "
+		}
+		return match[1] + nulls + match[1] + match[2]
+	})
+	masked = ilikePattern.ReplaceAllString(masked, "LIKE")
+	masked = selectFromPattern.ReplaceAllString(masked, "SELECT 1 FROM")
+	masked = mysqlNowPattern.ReplaceAllString(masked, "NOW(6)")
+	masked = mysqlCurrentTimePattern.ReplaceAllString(masked, "CURRENT_TIMESTAMP(6)")
+	masked = mysqlUUIDCallPattern.ReplaceAllString(masked, "UUID()")
+	masked = mysqlDefaultCallPattern.ReplaceAllStringFunc(masked, func(clause string) string {
+		call := mysqlDefaultCallPattern.FindStringSubmatch(clause)[1]
+		if strings.HasPrefix(strings.ToUpper(call), "CURRENT_TIMESTAMP") {
+			return clause // The one function MySQL takes as a default without parentheses
+		}
+		return "DEFAULT (" + call + ")"
+	})
+	masked = mysqlOffsetPattern.ReplaceAllStringFunc(masked, func(offset string) string {
+		if mysqlOffsetPattern.FindStringSubmatch(offset)[1] != "" {
+			return offset
+		}
+		return "LIMIT 18446744073709551615 OFFSET"
+	})
+	masked = st.columnDefinitions(masked)
+	if mariadb && mysqlSchemaChangePattern.MatchString(masked) {
+		// MariaDB's JSON is text, which would be read back as a string rather than as JSON
+		masked = mysqlJSONTypePattern.ReplaceAllString(masked, "LONGBLOB")
+	}
+
+	if match := mysqlReturningPattern.FindStringSubmatchIndex(masked); match != nil {
+		st.returning = masked[match[2]:match[3]]
+		masked = masked[:match[0]]
+	}
+	masked = st.onConflict(masked)
+	if match := mysqlUpdateFromPattern.FindStringSubmatch(masked); match != nil {
+		masked = match[1] + " JOIN " + match[3] + " ON " + match[4] + " SET " + match[2]
+	}
+	if st.returning == "" {
+		masked = mysqlDerivedSubqueries(masked)
+		masked = mysqlInsertSelect(masked)
+	}
+
+	st.masked = masked
+	cache.Store(query, st)
+	return st
+}
+
+// literal adds a literal or quoted identifier to the statement and returns its placeholder
+func (st *mysqlStatement) literal(text string) string {
+	st.quoted = append(st.quoted, text)
+	return "\x00" + strconv.Itoa(len(st.quoted)-1) + "\x00"
+}
+
+// jsonPath translates operand followed by a chain of -> and ->> steps
+func (st *mysqlStatement) jsonPath(operand, steps string) string {
+	path := "$"
+	unquote := false
+	for _, step := range mysqlJSONStepPattern.FindAllStringSubmatch(steps, -1) {
+		unquote = step[1] == "->>"
+		if strings.HasPrefix(step[2], "\x00") {
+			key := strings.ReplaceAll(strings.Trim(st.quoted[placeholderIndex(step[2])], "'"), "''", "'")
+			encoded, _ := json.Marshal(key)
+			path += "." + string(encoded)
+		} else {
+			path += "[" + step[2] + "]"
+		}
+	}
+	extract := "JSON_EXTRACT(CONVERT(" + operand + " USING utf8mb4), " + st.literal("'"+strings.ReplaceAll(path, "'", "''")+"'") + ")"
+	if unquote {
+		return "JSON_UNQUOTE(" + extract + ")"
+	}
+	return extract
+}
+
+// columnDefinitions translates the column types of a CREATE TABLE or ADD COLUMN statement
+func (st *mysqlStatement) columnDefinitions(masked string) string {
+	if match := mysqlCreateTablePattern.FindStringSubmatch(masked); match != nil && !strings.Contains(match[2], ";") {
+		elements := splitTopLevel(match[2], ',')
+		var foreignKeys []string
+		for i, element := range elements {
+			element = strings.TrimSpace(element)
+			fields := strings.Fields(element)
+			if len(fields) < 2 || mysqlTableElementPattern.MatchString(element) {
+				continue
+			}
+			name := fields[0]
+			definition, references := st.columnDefinition(strings.TrimSpace(element[len(name):]))
+			elements[i] = "\n\t" + name + " " + definition
+			if references != "" {
+				foreignKeys = append(foreignKeys, "\n\tFOREIGN KEY ("+name+") "+references)
+			}
+		}
+		return match[1] + strings.Join(append(elements, foreignKeys...), ",") + match[3]
+	}
+	if match := mysqlAddColumnPattern.FindStringSubmatch(masked); match != nil && !strings.Contains(match[3], ";") {
+		definition, references := st.columnDefinition(match[3])
+		masked = match[1] + match[2] + " " + definition
+		if references != "" {
+			masked += ", ADD FOREIGN KEY (" + match[2] + ") " + references
+		}
+	}
+	return masked
+}
+
+// columnDefinition translates a column definition, returning the REFERENCES clause it
+// ended with apart
+func (st *mysqlStatement) columnDefinition(definition string) (string, string) {
+	var references string
+	if match := mysqlReferencesPattern.FindStringSubmatchIndex(definition); match != nil {
+		references = "REFERENCES " + definition[match[2]:match[3]]
+		definition = definition[:match[0]]
+	}
+	if match := mysqlArrayTypePattern.FindStringSubmatch(definition); match != nil {
+		// The default of an array is a Postgres array, and the column holds JSON
+		numeric := !mysqlTextTypePattern.MatchString(match[1])
+		definition = mysqlLiteralDefaultRegexp.ReplaceAllStringFunc(definition, func(clause string) string {
+			literal := mysqlLiteralDefaultRegexp.FindStringSubmatch(clause)[1]
+			if !strings.HasPrefix(literal, "\x00") {
+				return clause
+			}
+			text := st.quoted[placeholderIndex(literal)]
+			array, err := newMySQLArray(strings.ReplaceAll(text[1:len(text)-1], "''", "'"), numeric)
+			if err != nil {
+				return clause
+			}
+			return "DEFAULT " + st.literal("'"+strings.ReplaceAll(array.json(), "'", "''")+"'")
+		})
+	}
+	definition = mysqlColumnType(definition)
+	if mysqlLargeTypePattern.MatchString(definition) {
+		// Only expressions can be the default of these types
+		definition = mysqlLiteralDefaultRegexp.ReplaceAllString(definition, "DEFAULT ($1)")
+	}
+	return definition, references
+}
+
+// mysqlColumnType translates the Postgres types of a column definition:
+//   - UUID becomes CHAR(36), and arrays JSON
+//   - TIMESTAMP [WITH TIME ZONE] becomes DATETIME(6), which Basin writes in UTC
+//   - JSONB becomes JSON, TEXT LONGTEXT and BYTEA LONGBLOB
+//   - NUMERIC without a precision becomes DECIMAL(65,30), the widest there is
+func mysqlColumnType(definition string) string {
+	for _, translation := range mysqlColumnTypePatterns {
+		definition = translation.pattern.ReplaceAllString(definition, translation.replacement)
+	}
+	definition = mysqlUUIDTypePattern.ReplaceAllStringFunc(definition, func(name string) string {
+		if strings.HasSuffix(name, "(") {
+			return name // The UUID() function
+		}
+		return "CHAR(36)"
+	})
+	return mysqlNumericPattern.ReplaceAllStringFunc(definition, func(name string) string {
+		if precision := mysqlNumericPattern.FindStringSubmatch(name)[1]; precision != "" {
+			return "DECIMAL" + precision
+		}
+		return "DECIMAL(65,30)"
+	})
+}
+
+// onConflict translates the ON CONFLICT clause of an INSERT
+func (st *mysqlStatement) onConflict(masked string) string {
+	match := mysqlConflictPattern.FindStringSubmatchIndex(masked)
+	insert := mysqlInsertPattern.FindStringSubmatch(masked)
+	if match == nil || insert == nil {
+		return masked
+	}
+	group := func(i int) string {
+		if match[2*i] < 0 {
+			return ""
+		}
+		return masked[match[2*i]:match[2*i+1]]
+	}
+	table := insert[2]
+	for _, column := range splitTopLevel(group(1), ',') {
+		if column = strings.TrimSpace(column); column != "" {
+			st.conflict = append(st.conflict, column)
+		}
+	}
+
+	var assignments []string
+	if group(2) != "" {
+		// A no-op assignment makes MySQL skip the row and report it unchanged
+		column := strings.TrimSpace(splitTopLevel(insert[3], ',')[0])
+		assignments = []string{table + "." + column + " = " + table + "." + column}
+		st.skipsRows = true
+	} else {
+		condition := mysqlExcludedPattern.ReplaceAllString(group(4), "VALUES($1)")
+		for i, assignment := range splitTopLevel(group(3), ',') {
+			column, value, _ := strings.Cut(assignment, "=")
+			column, value = strings.TrimSpace(column), mysqlExcludedPattern.ReplaceAllString(strings.TrimSpace(value), "VALUES($1)")
+			if condition != "" {
+				// Assignments see the columns earlier ones set, so the condition is evaluated
+				// once, by the first, and remembered for the others
+				guard := "@basin_upsert"
+				if i == 0 {
+					guard = "@basin_upsert := (" + condition + ")"
+				}
+				value = "IF(" + guard + ", " + value + ", " + column + ")"
+				st.skipsRows = true
+			}
+			assignments = append(assignments, column+" = "+value)
+		}
+	}
+	return masked[:match[0]] + " ON DUPLICATE KEY UPDATE " + strings.Join(assignments, ", ")
+}
+
+// mysqlDerivedSubqueries makes IN (SELECT ...) of UPDATE and DELETE statements read a
+// derived table, which MySQL materializes first; it refuses subqueries reading the table
+// being written, and LIMIT in them
+func mysqlDerivedSubqueries(masked string) string {
+	if !mysqlWritePattern.MatchString(masked) {
+		return masked
+	}
+	var out strings.Builder
+	for {
+		match := mysqlInSelectPattern.FindStringIndex(masked)
+		if match == nil {
+			break
+		}
+		open := strings.IndexByte(masked[match[0]:], '(') + match[0]
+		end := matchingParen(masked, open)
+		if end < 0 {
+			break
+		}
+		out.WriteString(masked[:open])
+		out.WriteString("(SELECT * FROM (" + masked[open+1:end] + ") AS basin_in)")
+		masked = masked[end+1:]
+	}
+	out.WriteString(masked)
+	return out.String()
+}
+
+// mysqlInsertSelect turns an INSERT of one row of VALUES with a subquery into INSERT ...
+// SELECT, as MySQL refuses subqueries reading the table being written in VALUES
+func mysqlInsertSelect(masked string) string {
+	insert := mysqlInsertPattern.FindStringIndex(masked)
+	if insert == nil {
+		return masked
+	}
+	match := mysqlValuesPattern.FindStringIndex(masked[insert[1]-1:])
+	if match == nil || match[0] != 0 {
+		return masked
+	}
+	open := insert[1] - 1 + match[1] - 1
+	end := matchingParen(masked, open)
+	if end < 0 || !mysqlSubqueryPattern.MatchString(masked[open+1:end]) || strings.HasPrefix(strings.TrimSpace(masked[end+1:]), ",") {
+		return masked
+	}
+	return masked[:insert[1]-1] + ") SELECT " + masked[open+1:end] + masked[end+1:]
+}
+
+// matchingParen returns the index of the parenthesis closing the one at open, or -1
+func matchingParen(s string, open int) int {
+	depth := 0
+	for i := open; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// splitTopLevel splits s at the separators outside parentheses
+func splitTopLevel(s string, separator byte) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case separator:
+			if depth == 0 {
+				parts = append(parts, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// mysqlArray is an array argument. Whether it is compared element by element, stored in a
+// JSON column or stored as the text of a Postgres array is only known once the statement
+// it is bound to is.
+type mysqlArray struct {
+	text     string // Text form of the Postgres array, as lib/pq writes it
+	elements []string
+	numeric  bool
+}
+
+// newMySQLArray parses the text form of an array argument. Its elements are numbers when
+// numeric is set and they all are.
+func newMySQLArray(text string, numeric bool) (mysqlArray, error) {
+	var elements pq.StringArray
+	if err := elements.Scan(text); err != nil {
+		return mysqlArray{}, fmt.Errorf("not an array: %w", err)
+	}
+	for _, element := range elements {
+		if _, err := strconv.ParseFloat(element, 64); err != nil {
+			numeric = false
+		}
+	}
+	return mysqlArray{text: text, elements: elements, numeric: numeric}, nil
+}
+
+// json returns the array as a JSON array
+func (a mysqlArray) json() string {
+	if a.numeric {
+		return "[" + strings.Join(a.elements, ",") + "]"
+	}
+	encoded, _ := json.Marshal([]string(a.elements))
+	return string(encoded)
+}
+
+// mysqlBind replaces the placeholders of a translated statement with MySQL's, returning
+// the statement and its arguments in the order the placeholders now take them. The
+// constructs mysqlRewrite left for arrays and settings are expanded; array arguments at
+// the ordinals in jsonArgs are bound as JSON, others as the text of Postgres arrays.
+func mysqlBind(st *mysqlStatement, masked string, args []driver.NamedValue, settings map[string]string, jsonArgs map[int]bool) (string, []driver.NamedValue, error) {
+	var out strings.Builder
+	var bound []driver.NamedValue
+	bind := func(value driver.Value) {
+		bound = append(bound, driver.NamedValue{Ordinal: len(bound) + 1, Value: value})
+	}
+	argument := func(placeholder string) (driver.Value, error) {
+		n, _ := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(placeholder), "$"))
+		if n < 1 || n > len(args) {
+			return nil, fmt.Errorf("missing argument %s", strings.TrimSpace(placeholder))
+		}
+		return args[n-1].Value, nil
+	}
+	elements := func(placeholder string) (mysqlArray, bool, error) {
+		value, err := argument(placeholder)
+		if err != nil || value == nil {
+			return mysqlArray{}, false, err
+		}
+		if array, ok := value.(mysqlArray); ok {
+			return array, true, nil
+		}
+		array, err := newMySQLArray(fmt.Sprint(value), false)
+		return array, true, err
+	}
+
+	last := 0
+	for _, match := range mysqlBindPattern.FindAllStringSubmatchIndex(masked, -1) {
+		out.WriteString(masked[last:match[0]])
+		last = match[1]
+
+		if match[6] >= 0 {
+			n, _ := strconv.Atoi(masked[match[6]:match[7]])
+			value, err := argument("$" + strconv.Itoa(n))
+			if err != nil {
+				return "", nil, err
+			}
+			if array, ok := value.(mysqlArray); ok {
+				value = array.text
+				if jsonArgs[n] {
+					value = array.json()
+				}
+			}
+			out.WriteString("?")
+			bind(value)
+			continue
+		}
+
+		params := splitTopLevel(masked[match[4]:match[5]], ',')
+		switch function := masked[match[2]:match[3]]; function {
+		case "setting":
+			value, ok := settings[strings.Trim(st.quoted[placeholderIndex(strings.TrimSpace(params[0]))], "'")]
+			if !ok {
+				out.WriteString("NULL")
+				continue
+			}
+			out.WriteString("?")
+			bind(value)
+		case "in":
+			array, ok, err := elements(params[0])
+			if err != nil {
+				return "", nil, err
+			}
+			if !ok || len(array.elements) == 0 {
+				out.WriteString("NULL")
+				continue
+			}
+			for i, element := range array.elements {
+				if i > 0 {
+					out.WriteString(", ")
+				}
+				out.WriteString("?")
+				bind(element)
+			}
+		case "all", "some":
+			array, ok, err := elements(params[1])
+			if err != nil {
+				return "", nil, err
+			}
+			if !ok {
+				out.WriteString("NULL")
+				continue
+			}
+			column := "CONVERT(" + strings.TrimSpace(params[0]) + " USING utf8mb4)"
+			operator, empty := " AND ", "TRUE"
+			if function == "some" {
+				operator, empty = " OR ", "FALSE"
+			}
+			if len(array.elements) == 0 {
+				out.WriteString(empty)
+				continue
+			}
+			out.WriteString("(")
+			for i, element := range array.elements {
+				if i > 0 {
+					out.WriteString(operator)
+				}
+				// Elements of text arrays are JSON strings, those of int[] numbers
+				encoded, _ := json.Marshal(element)
+				out.WriteString("(JSON_CONTAINS(" + column + ", ?)")
+				bind(string(encoded))
+				if _, err := strconv.ParseFloat(element, 64); err == nil {
+					out.WriteString(" OR JSON_CONTAINS(" + column + ", ?)")
+					bind(element)
+				}
+				out.WriteString(")")
+			}
+			out.WriteString(")")
+		case "unnest":
+			array, _, err := elements(params[0])
+			if err != nil {
+				return "", nil, err
+			}
+			value, position := strings.TrimSpace(params[1]), strings.TrimSpace(params[2])
+			if len(array.elements) == 0 {
+				out.WriteString("(SELECT NULL AS " + value + ", NULL AS " + position + " FROM DUAL WHERE FALSE)")
+				continue
+			}
+			out.WriteString("(")
+			for i, element := range array.elements {
+				if i > 0 {
+					out.WriteString(" UNION ALL ")
+				}
+				out.WriteString("SELECT ? AS " + value + ", " + strconv.Itoa(i+1) + " AS " + position)
+				bind(element)
+			}
+			out.WriteString(")")
+		}
+	}
+	out.WriteString(masked[last:])
+	return unmaskQuoted(out.String(), st.quoted), bound, nil
+}
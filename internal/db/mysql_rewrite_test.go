@@ -0,0 +1,218 @@
+package db
+
+import (
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMySQLRewrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "row locks",
+			query: "SELECT id FROM jobs WHERE run_at <= $1 FOR UPDATE OF jobs SKIP LOCKED",
+			want:  "SELECT id FROM jobs WHERE run_at <= $1 FOR UPDATE SKIP LOCKED",
+		},
+		{
+			name:  "casts",
+			query: "SELECT $1::uuid, created_at::timestamp with time zone, name::varchar(255), $2::text[]",
+			want:  "SELECT $1, created_at, name, $2",
+		},
+		{
+			name:  "arrays",
+			query: `SELECT * FROM users WHERE id = ANY($1) AND "tags" @> $2 OR "tags" && $3`,
+			want:  `SELECT * FROM users WHERE id IN (basin_in($1)) AND basin_all("tags", $2) OR basin_some("tags", $3)`,
+		},
+		{
+			name:  "json paths",
+			query: `SELECT f.relation_config->>'type', data->'a'->0 FROM fields f`,
+			want:  `SELECT JSON_UNQUOTE(JSON_EXTRACT(CONVERT(f.relation_config USING utf8mb4), '$."type"')), JSON_EXTRACT(CONVERT(data USING utf8mb4), '$."a"[0]') FROM fields f`,
+		},
+		{
+			name:  "do nothing",
+			query: "INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON CONFLICT DO NOTHING",
+			want:  "INSERT INTO user_roles (user_id, role_id) VALUES ($1, $2) ON DUPLICATE KEY UPDATE user_roles.user_id = user_roles.user_id",
+		},
+		{
+			name:  "do update with a where",
+			query: "INSERT INTO counters (id, n) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET n = EXCLUDED.n, m = 1 WHERE counters.n < EXCLUDED.n",
+			want:  "INSERT INTO counters (id, n) VALUES ($1, $2) ON DUPLICATE KEY UPDATE n = IF(@basin_upsert := (counters.n < VALUES(n)), VALUES(n), n), m = IF(@basin_upsert, 1, m)",
+		},
+		{
+			name:  "distinct and nulls last",
+			query: `SELECT * FROM t WHERE a IS DISTINCT FROM $1 ORDER BY "sort" NULLS LAST`,
+			want:  `SELECT * FROM t WHERE NOT (a <=> $1) ORDER BY "sort" IS NULL, "sort"`,
+		},
+		{
+			name:  "offset without limit",
+			query: "SELECT * FROM t ORDER BY id OFFSET $1; SELECT * FROM t LIMIT 10 OFFSET 20",
+			want:  "SELECT * FROM t ORDER BY id LIMIT 18446744073709551615 OFFSET $1; SELECT * FROM t LIMIT 10 OFFSET 20",
+		},
+		{
+			name:  "reserved words",
+			query: `SELECT key, trigger FROM t WHERE interval = $1 AND "key" <> ''`,
+			want:  `SELECT "key", "trigger" FROM t WHERE "interval" = $1 AND "key" <> ''`,
+		},
+		{
+			name:  "update from",
+			query: `UPDATE "posts" t SET "sort" = n.position FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY "sort") AS position FROM "posts") n WHERE t.id = n.id`,
+			want:  `UPDATE "posts" t JOIN (SELECT id, ROW_NUMBER() OVER (ORDER BY "sort") AS position FROM "posts") n ON t.id = n.id SET "sort" = n.position`,
+		},
+		{
+			name:  "subqueries of the table written",
+			query: "DELETE FROM t WHERE id IN (SELECT id FROM t WHERE n > $1)",
+			want:  "DELETE FROM t WHERE id IN (SELECT * FROM (SELECT id FROM t WHERE n > $1) AS basin_in)",
+		},
+		{
+			name:  "advisory locks and notifications",
+			query: "SELECT pg_advisory_lock($1); SELECT pg_advisory_unlock($1); SELECT pg_notify('basin', $2)",
+			want:  "SELECT GET_LOCK(CONCAT('basin_', $1), -1); SELECT RELEASE_LOCK(CONCAT('basin_', $1)); SELECT NULL",
+		},
+		{
+			name:  "settings",
+			query: "SELECT * FROM t WHERE tenant_id = current_setting('app.current_tenant_id', true)::uuid",
+			want:  "SELECT * FROM t WHERE tenant_id = basin_setting('app.current_tenant_id')",
+		},
+		{
+			name:  "created tables",
+			query: `CREATE TABLE "data__posts" (id UUID PRIMARY KEY DEFAULT uuid_generate_v4(), tags TEXT[] DEFAULT '{}', meta JSONB, at TIMESTAMP WITH TIME ZONE DEFAULT NOW(), author UUID REFERENCES public.users(id) ON DELETE SET NULL)`,
+			want: `CREATE TABLE "data__posts" (
+	id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+	tags JSON DEFAULT ('[]'),
+	meta JSON,
+	at DATETIME(6) DEFAULT (NOW(6)),
+	author CHAR(36),
+	FOREIGN KEY (author) REFERENCES users(id) ON DELETE SET NULL)`,
+		},
+		{
+			name:  "added columns",
+			query: `ALTER TABLE "posts" ADD COLUMN "file" UUID REFERENCES public.assets(id) ON DELETE SET NULL`,
+			want:  `ALTER TABLE "posts" ADD COLUMN "file" CHAR(36), ADD FOREIGN KEY ("file") REFERENCES assets(id) ON DELETE SET NULL`,
+		},
+		{
+			name:  "array defaults",
+			query: `ALTER TABLE "posts" ADD COLUMN "tags" TEXT[] NOT NULL DEFAULT '{"it''s",b}'`,
+			want:  `ALTER TABLE "posts" ADD COLUMN "tags" JSON NOT NULL DEFAULT ('["it''s","b"]')`,
+		},
+		{
+			name:  "ilike and empty selects",
+			query: "SELECT EXISTS (SELECT FROM users WHERE email ILIKE $1)",
+			want:  "SELECT EXISTS (SELECT 1 FROM users WHERE email LIKE $1)",
+		},
+		{
+			name:  "quoted text is left alone",
+			query: `SELECT 'a::text ->> x', "public.name" FROM t -- ILIKE $1::uuid`,
+			want:  `SELECT 'a::text ->> x', "public.name" FROM t -- ILIKE $1::uuid`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			st := mysqlRewrite(tt.query, false)
+			assert.Equal(t, tt.want, unmaskQuoted(st.masked, st.quoted))
+		})
+	}
+}
+
+func TestMySQLRewrite_MariaDB(t *testing.T) {
+	st := mysqlRewrite(`CREATE TABLE "t" (meta JSON, at DATETIME(6)); SELECT JSON_EXTRACT(meta, '$.a') FROM "t"`, true)
+	assert.Equal(t, `CREATE TABLE "t" (meta LONGBLOB, at DATETIME(6)); SELECT JSON_EXTRACT(meta, '$.a') FROM "t"`,
+		unmaskQuoted(st.masked, st.quoted), "JSON columns of scripts are binary too")
+
+	st = mysqlRewrite(`SELECT JSON_EXTRACT(meta, '$.a') FROM "t"`, true)
+	assert.Equal(t, `SELECT JSON_EXTRACT(meta, '$.a') FROM "t"`, unmaskQuoted(st.masked, st.quoted))
+}
+
+func TestMySQLRewrite_Returning(t *testing.T) {
+	st := mysqlRewrite("INSERT INTO counters (id, n) VALUES ($1, $2) ON CONFLICT (id) DO UPDATE SET n = EXCLUDED.n WHERE counters.n < EXCLUDED.n RETURNING id, n", false)
+	assert.Equal(t, "id, n", st.returning)
+	assert.Equal(t, []string{"id"}, st.conflict)
+	assert.True(t, st.skipsRows)
+
+	st = mysqlRewrite(`UPDATE "t" SET n = $1 WHERE id = $2 RETURNING *`, false)
+	assert.Equal(t, `UPDATE "t" SET n = $1 WHERE id = $2`, unmaskQuoted(st.masked, st.quoted))
+	assert.Equal(t, "*", st.returning)
+	assert.False(t, st.skipsRows)
+}
+
+func TestMySQLBind(t *testing.T) {
+	array := func(value interface{}) driver.Value {
+		array, ok, err := mysqlArrayArgument(value)
+		require.NoError(t, err)
+		require.True(t, ok)
+		return array
+	}
+	values := func(args []driver.NamedValue) []driver.Value {
+		var values []driver.Value
+		for i, arg := range args {
+			assert.Equal(t, i+1, arg.Ordinal)
+			values = append(values, arg.Value)
+		}
+		return values
+	}
+
+	st := mysqlRewrite(`SELECT x.v FROM unnest($1::uuid[]) WITH ORDINALITY AS x(v, p) WHERE x.v = ANY($2) AND "tags" @> $3 AND "n" && $4 AND tenant_id = current_setting('app.current_tenant_id', true)::uuid AND user_id = current_setting('app.current_user_id', true)::uuid`, false)
+	query, args, err := mysqlBind(st, st.masked, []driver.NamedValue{
+		{Ordinal: 1, Value: array(pq.StringArray{"a", "b"})},
+		{Ordinal: 2, Value: array(pq.StringArray{})},
+		{Ordinal: 3, Value: array(pq.StringArray{"x y"})},
+		{Ordinal: 4, Value: array(pq.Int64Array{1})},
+	}, map[string]string{"app.current_tenant_id": "acme"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, `SELECT x.v FROM (SELECT ? AS v, 1 AS p UNION ALL SELECT ? AS v, 2 AS p) AS x`+
+		` WHERE x.v IN (NULL)`+
+		` AND ((JSON_CONTAINS(CONVERT("tags" USING utf8mb4), ?)))`+
+		` AND ((JSON_CONTAINS(CONVERT("n" USING utf8mb4), ?) OR JSON_CONTAINS(CONVERT("n" USING utf8mb4), ?)))`+
+		` AND tenant_id = ? AND user_id = NULL`, query)
+	assert.Equal(t, []driver.Value{"a", "b", `"x y"`, `"1"`, "1", "acme"}, values(args))
+
+	// Arrays go into JSON columns as JSON, and into the others as Postgres arrays
+	st = mysqlRewrite(`INSERT INTO p (tags, scopes) VALUES ($1, $2)`, false)
+	query, args, err = mysqlBind(st, st.masked, []driver.NamedValue{
+		{Ordinal: 1, Value: array(pq.StringArray{"a", "b"})},
+		{Ordinal: 2, Value: array(pq.StringArray{"c"})},
+	}, nil, map[int]bool{1: true})
+	require.NoError(t, err)
+	assert.Equal(t, `INSERT INTO p (tags, scopes) VALUES (?, ?)`, query)
+	assert.Equal(t, []driver.Value{`["a","b"]`, `{"c"}`}, values(args))
+
+	_, _, err = mysqlBind(st, st.masked, nil, nil, nil)
+	assert.EqualError(t, err, "missing argument $1")
+}
+
+func TestMySQLColumnType(t *testing.T) {
+	for definition, want := range map[string]string{
+		"UUID":                     "CHAR(36)",
+		"TEXT[]":                   "JSON",
+		"VARCHAR(20)[] NOT NULL":   "JSON NOT NULL",
+		"TIMESTAMP WITH TIME ZONE": "DATETIME(6)",
+		"TIMESTAMPTZ":              "DATETIME(6)",
+		"JSONB":                    "JSON",
+		"DOUBLE PRECISION":         "DOUBLE",
+		"BYTEA":                    "LONGBLOB",
+		"TEXT NOT NULL":            "LONGTEXT NOT NULL",
+		"VARCHAR(255)":             "VARCHAR(255)",
+		"NUMERIC":                  "DECIMAL(65,30)",
+		"NUMERIC(10,2)":            "DECIMAL(10,2)",
+		"BOOLEAN DEFAULT false":    "BOOLEAN DEFAULT false",
+	} {
+		assert.Equal(t, want, mysqlColumnType(definition), definition)
+	}
+}
+
+func TestMySQLIdentifier(t *testing.T) {
+	assert.Equal(t, "short", mysqlIdentifier("short"))
+	assert.Equal(t, strings.Repeat("x", 64), mysqlIdentifier(strings.Repeat("x", 64)))
+
+	long := mysqlIdentifier(strings.Repeat("x", 65))
+	assert.Len(t, long, 64)
+	assert.Equal(t, strings.Repeat("x", 55)+"_", long[:56])
+	assert.NotEqual(t, long, mysqlIdentifier(strings.Repeat("x", 66)), "shortened names stay apart")
+}
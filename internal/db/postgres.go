@@ -3,7 +3,8 @@ package db
 import (
 	"database/sql"
 	"fmt"
-	"log"
+	"log/slog"
+	"time"
 
 	"go-rbac-api/internal/config"
 	sqlc "go-rbac-api/internal/db/sqlc"
@@ -14,9 +15,41 @@ import (
 type DB struct {
 	*sql.DB
 	*sqlc.Queries
+	dialect Dialect
+	read    *ReadPool
+	dsn     string // Connection string of the primary, for listeners
 }
 
+// Dialect returns the dialect of the database the statements run on
+func (db *DB) Dialect() Dialect {
+	if db.dialect == nil {
+		return Postgres
+	}
+	return db.dialect
+}
+
+// NewDB connects to the database cfg points to and to its read replicas, if any
 func NewDB(cfg *config.Config) (*DB, error) {
+	pool := PoolConfig{
+		MaxOpenConns:    cfg.DBMaxOpenConns,
+		MaxIdleConns:    cfg.DBMaxIdleConns,
+		ConnMaxLifetime: cfg.DBConnMaxLifetime,
+	}
+
+	dialect, err := DialectFor(cfg.DBDriver)
+	if err != nil {
+		return nil, err
+	}
+	if dialect == SQLite {
+		if len(cfg.DatabaseReplicaURLs) > 0 {
+			return nil, fmt.Errorf("read replicas need Postgres")
+		}
+		return OpenSQLite(pool, cfg.SQLitePath)
+	}
+	if dialect == MySQL && len(cfg.DatabaseReplicaURLs) > 0 {
+		return nil, fmt.Errorf("read replicas need Postgres")
+	}
+
 	connStr := cfg.GetDBConnString()
 
 	// Check if we have a valid connection string
@@ -26,27 +59,83 @@ func NewDB(cfg *config.Config) (*DB, error) {
 		}
 		return nil, fmt.Errorf("invalid database connection string")
 	}
+	if dialect == MySQL {
+		return OpenMySQL(pool, connStr)
+	}
+	return Open(pool, connStr, cfg.DatabaseReplicaURLs...)
+}
 
-	db, err := sql.Open("postgres", connStr)
+// PoolConfig sizes the connection pools of the primary and of each replica. Zero values
+// keep database/sql's defaults.
+type PoolConfig struct {
+	MaxOpenConns    int           // 0 means unlimited
+	MaxIdleConns    int           // 0 means database/sql's default of 2
+	ConnMaxLifetime time.Duration // 0 means connections are reused forever
+}
+
+// apply sets the limits on conn
+func (p PoolConfig) apply(conn *sql.DB) {
+	if p.MaxOpenConns > 0 {
+		conn.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns > 0 {
+		conn.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime > 0 {
+		conn.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+}
+
+// Open connects to the primary Postgres database and to read replicas. The primary must be
+// reachable; replicas that are not are skipped until they are.
+func Open(pool PoolConfig, primary string, replicas ...string) (*DB, error) {
+	db, err := sql.Open("postgres", primary)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
+	pool.apply(db)
 
 	// Test the connection
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("failed to ping database: %w", err)
 	}
 
-	log.Println("Successfully connected to database")
+	replicaConns := make([]*sql.DB, 0, len(replicas))
+	for i, dsn := range replicas {
+		conn, err := sql.Open("postgres", dsn)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open read replica %d: %w", i+1, err)
+		}
+		pool.apply(conn)
+		if err := conn.Ping(); err != nil {
+			slog.Warn("read replica unreachable; reads fall back to the primary", "replica", i+1, "error", err)
+		}
+		replicaConns = append(replicaConns, conn)
+	}
 
 	queries := sqlc.New(db)
 
 	return &DB{
 		DB:      db,
 		Queries: queries,
+		dialect: Postgres,
+		read:    newReadPool(db, replicaConns),
+		dsn:     primary,
 	}, nil
 }
 
+// Reader returns the pool read-only queries run on: the read replicas when configured,
+// otherwise the primary
+func (db *DB) Reader() *ReadPool {
+	if db.read == nil {
+		return newReadPool(db.DB, nil)
+	}
+	return db.read
+}
+
 func (db *DB) Close() error {
+	if db.read != nil {
+		db.read.Close()
+	}
 	return db.DB.Close()
 }
@@ -0,0 +1,31 @@
+-- API Key Lifecycle Queries
+-- name: RotateAPIKey :one
+UPDATE api_keys
+SET previous_key_hash = key_hash, previous_key_expires_at = $3, key_hash = $2,
+    rotated_at = CURRENT_TIMESTAMP, rotation_notified_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 RETURNING *;
+
+-- name: ClaimExpiringAPIKeys :many
+UPDATE api_keys
+SET expiry_notified_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT k.id FROM api_keys k
+    WHERE k.is_active = true AND k.expiry_notified_at IS NULL
+      AND k.expires_at IS NOT NULL AND k.expires_at > NOW() AND k.expires_at <= $1
+    ORDER BY k.expires_at
+    LIMIT $2
+    FOR UPDATE SKIP LOCKED
+) RETURNING *;
+
+-- name: ClaimRotationDueAPIKeys :many
+UPDATE api_keys
+SET rotation_notified_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT k.id FROM api_keys k
+    JOIN users u ON u.id = k.user_id
+    WHERE u.is_service_account = true AND k.is_active = true AND k.rotation_notified_at IS NULL
+      AND COALESCE(k.rotated_at, k.created_at) <= $1
+    ORDER BY COALESCE(k.rotated_at, k.created_at)
+    LIMIT $2
+    FOR UPDATE OF k SKIP LOCKED
+) RETURNING *;
@@ -0,0 +1,15 @@
+-- Asset Queries
+-- name: CreateAsset :one
+INSERT INTO assets (id, tenant_id, storage, storage_key, filename_download, title, type, filesize, width, height, uploaded_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING *;
+
+-- name: GetAssetByID :one
+SELECT * FROM assets WHERE id = $1;
+
+-- name: UpdateAsset :one
+UPDATE assets
+SET title = $2, filename_download = $3, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 RETURNING *;
+
+-- name: DeleteAsset :exec
+DELETE FROM assets WHERE id = $1;
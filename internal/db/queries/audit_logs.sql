@@ -0,0 +1,5 @@
+-- Audit Log Queries
+-- name: CreateAuditLog :exec
+-- Entries without a tenant (API keys, failed logins) fall back to the user's home tenant
+INSERT INTO audit_logs (tenant_id, user_id, api_key_id, action, table_name, item_id, result, status_code, ip_address, user_agent, request_id, details)
+VALUES (COALESCE($1, (SELECT u.tenant_id FROM users u WHERE u.id = $2)), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12);
@@ -0,0 +1,3 @@
+-- Content Workflow Queries
+-- name: GetWorkflowCollections :many
+SELECT * FROM collections WHERE workflow = true ORDER BY tenant_id, slug;
@@ -0,0 +1,3 @@
+-- Field Queries
+-- name: RenameField :exec
+UPDATE fields SET name = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1;
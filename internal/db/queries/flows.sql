@@ -0,0 +1,54 @@
+-- Flow Queries
+-- name: CreateFlow :one
+INSERT INTO flows (tenant_id, name, description, trigger, trigger_options, operations, secret, is_active, next_run_at, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) RETURNING *;
+
+-- name: GetFlowByID :one
+SELECT * FROM flows WHERE id = $1;
+
+-- name: GetActiveEventFlowsByTenant :many
+SELECT * FROM flows WHERE tenant_id = $1 AND trigger = 'event' AND is_active = true ORDER BY created_at;
+
+-- name: UpdateFlow :one
+UPDATE flows
+SET name = $2, description = $3, trigger = $4, trigger_options = $5, operations = $6, is_active = $7, next_run_at = $8, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 RETURNING *;
+
+-- name: DeleteFlow :exec
+DELETE FROM flows WHERE id = $1;
+
+-- name: GetDueScheduledFlows :many
+SELECT * FROM flows
+WHERE trigger = 'schedule' AND is_active = true AND next_run_at <= NOW()
+ORDER BY next_run_at
+LIMIT $1;
+
+-- Moves a schedule forward only if no other worker already did, so each slot runs once
+-- name: AdvanceFlowSchedule :execrows
+UPDATE flows SET next_run_at = $3
+WHERE id = $1 AND next_run_at = $2;
+
+-- Flow Run Queries
+-- name: CreateFlowRun :one
+INSERT INTO flow_runs (flow_id, tenant_id, trigger, payload)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: ClaimPendingFlowRuns :many
+UPDATE flow_runs
+SET status = 'processing', started_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT fr.id FROM flow_runs fr
+    WHERE fr.status = 'pending'
+    ORDER BY fr.created_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+) RETURNING *;
+
+-- name: FinishFlowRun :exec
+UPDATE flow_runs
+SET status = $2, steps = $3, error = $4, finished_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ReleaseStaleFlowRuns :exec
+UPDATE flow_runs SET status = 'pending', updated_at = CURRENT_TIMESTAMP
+WHERE status = 'processing' AND updated_at < $1;
@@ -0,0 +1,25 @@
+-- Idempotency Key Queries
+-- Inserts the key, or takes over one that expired or whose request was abandoned while
+-- in progress; returns no row while the key is held by a live request or response
+-- name: ClaimIdempotencyKey :one
+INSERT INTO idempotency_keys (user_id, key, request_hash, expires_at)
+VALUES ($1, $2, $3, $4)
+ON CONFLICT (user_id, key) DO UPDATE
+SET request_hash = EXCLUDED.request_hash, status_code = NULL, content_type = NULL,
+    response_body = NULL, created_at = NOW(), expires_at = EXCLUDED.expires_at
+WHERE idempotency_keys.expires_at <= NOW()
+   OR (idempotency_keys.status_code IS NULL AND idempotency_keys.created_at < $5)
+RETURNING *;
+
+-- name: GetIdempotencyKey :one
+SELECT * FROM idempotency_keys WHERE user_id = $1 AND key = $2;
+
+-- name: CompleteIdempotencyKey :exec
+UPDATE idempotency_keys SET status_code = $3, content_type = $4, response_body = $5
+WHERE user_id = $1 AND key = $2;
+
+-- name: DeleteIdempotencyKey :exec
+DELETE FROM idempotency_keys WHERE user_id = $1 AND key = $2;
+
+-- name: DeleteExpiredIdempotencyKeys :execrows
+DELETE FROM idempotency_keys WHERE expires_at < $1;
@@ -0,0 +1,19 @@
+-- Item Share Queries
+-- name: CreateItemShare :one
+INSERT INTO item_shares (tenant_id, collection, item_id, fields, created_by, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING *;
+
+-- name: GetItemShare :one
+SELECT * FROM item_shares WHERE id = $1;
+
+-- name: ListItemShares :many
+SELECT * FROM item_shares
+WHERE tenant_id = $1 AND collection = $2 AND item_id = $3
+ORDER BY created_at DESC;
+
+-- name: TouchItemShare :exec
+UPDATE item_shares SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1;
+
+-- name: RevokeItemShare :execrows
+UPDATE item_shares SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND tenant_id = $2 AND revoked_at IS NULL;
@@ -0,0 +1,45 @@
+-- Job Queries
+-- name: EnqueueJob :one
+INSERT INTO jobs (tenant_id, kind, payload, max_attempts, run_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetJobByID :one
+SELECT * FROM jobs WHERE id = $1;
+
+-- name: ClaimDueJobs :many
+UPDATE jobs
+SET status = 'processing', attempts = attempts + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT j.id FROM jobs j
+    WHERE j.status = 'pending' AND j.run_at <= NOW()
+    ORDER BY j.run_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+) RETURNING *;
+
+-- name: CompleteJob :exec
+UPDATE jobs
+SET status = 'completed', last_error = NULL, completed_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- Records a failed attempt: back to 'pending' with a later run_at, or 'dead'
+-- name: FailJob :exec
+UPDATE jobs
+SET status = $2, last_error = $3, run_at = $4, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- Moves a dead job back into the queue with a fresh set of attempts
+-- name: RetryJob :one
+UPDATE jobs
+SET status = 'pending', attempts = 0, run_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND status = 'dead' RETURNING *;
+
+-- name: ReleaseStaleJobs :exec
+UPDATE jobs SET status = 'pending', updated_at = CURRENT_TIMESTAMP
+WHERE status = 'processing' AND updated_at < $1;
+
+-- name: DeleteCompletedJobs :execrows
+DELETE FROM jobs WHERE status = 'completed' AND completed_at < $1;
+
+-- name: SetJobProgress :exec
+UPDATE jobs SET progress = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1;
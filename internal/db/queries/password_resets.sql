@@ -0,0 +1,15 @@
+-- Password Reset Queries
+-- name: CreatePasswordReset :one
+INSERT INTO password_resets (user_id, expires_at, ip)
+VALUES ($1, $2, $3) RETURNING *;
+
+-- name: GetPasswordReset :one
+SELECT * FROM password_resets WHERE id = $1;
+
+-- name: UsePasswordReset :execrows
+UPDATE password_resets SET used_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND used_at IS NULL AND expires_at > CURRENT_TIMESTAMP;
+
+-- name: InvalidatePasswordResets :exec
+UPDATE password_resets SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND used_at IS NULL;
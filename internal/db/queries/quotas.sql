@@ -0,0 +1,11 @@
+-- Tenant Usage Queries
+-- name: CountTenantCollections :one
+SELECT COUNT(*) FROM collections WHERE tenant_id = $1;
+
+-- name: CountTenantAPIKeys :one
+SELECT COUNT(*) FROM api_keys k
+JOIN users u ON u.id = k.user_id
+WHERE u.tenant_id = $1 AND k.is_active = true;
+
+-- name: SumTenantAssetSize :one
+SELECT COALESCE(SUM(filesize), 0)::bigint AS total FROM assets WHERE tenant_id = $1;
@@ -0,0 +1,22 @@
+-- Revision Queries
+-- name: CreateRevision :one
+INSERT INTO revisions (tenant_id, collection, item_id, action, old_data, new_data, user_id)
+VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+
+-- name: DeleteItemRevisions :execrows
+DELETE FROM revisions WHERE tenant_id = $1 AND collection = $2 AND item_id = $3;
+
+-- name: GetRevisionByID :one
+SELECT * FROM revisions WHERE id = $1;
+
+-- Changes to a collection between two times, oldest first
+-- name: ListCollectionRevisions :many
+SELECT * FROM revisions
+WHERE tenant_id = $1 AND collection = $2 AND created_at >= $3 AND created_at <= $4
+ORDER BY created_at, id;
+
+-- name: ListItemRevisions :many
+SELECT * FROM revisions
+WHERE tenant_id = $1 AND collection = $2 AND item_id = $3
+ORDER BY created_at DESC, id DESC
+LIMIT $4 OFFSET $5;
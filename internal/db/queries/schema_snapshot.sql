@@ -0,0 +1,6 @@
+-- Schema Snapshot Queries
+-- name: GetCollectionsByTenant :many
+SELECT * FROM collections WHERE tenant_id = $1 ORDER BY slug;
+
+-- name: GetPermissionsByTenant :many
+SELECT * FROM permissions WHERE tenant_id = $1 ORDER BY table_name, action;
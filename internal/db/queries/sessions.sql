@@ -0,0 +1,23 @@
+-- Session Queries
+-- name: CreateSession :one
+INSERT INTO sessions (user_id, tenant_id, user_agent, ip, expires_at)
+VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetSession :one
+SELECT * FROM sessions WHERE id = $1;
+
+-- name: ListUserSessions :many
+SELECT * FROM sessions
+WHERE user_id = $1 AND revoked_at IS NULL AND expires_at > CURRENT_TIMESTAMP
+ORDER BY last_seen_at DESC;
+
+-- name: TouchSession :exec
+UPDATE sessions SET last_seen_at = CURRENT_TIMESTAMP WHERE id = $1;
+
+-- name: RevokeSession :execrows
+UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL;
+
+-- name: RevokeUserSessions :exec
+UPDATE sessions SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL;
@@ -0,0 +1,63 @@
+-- Tenant Backup Queries
+-- name: CreateTenantBackup :one
+INSERT INTO tenant_backups (id, tenant_id, trigger, storage_key, created_by)
+VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: GetTenantBackup :one
+SELECT * FROM tenant_backups WHERE id = $1 AND tenant_id = $2;
+
+-- name: ListTenantBackups :many
+SELECT * FROM tenant_backups WHERE tenant_id = $1
+ORDER BY created_at DESC;
+
+-- name: CompleteTenantBackup :one
+UPDATE tenant_backups
+SET status = 'completed', size = $2, checksum = $3, collections = $4, items = $5, error = NULL, completed_at = CURRENT_TIMESTAMP
+WHERE id = $1 RETURNING *;
+
+-- name: FailTenantBackup :exec
+UPDATE tenant_backups SET status = $2, error = $3
+WHERE id = $1;
+
+-- name: DeleteTenantBackup :exec
+DELETE FROM tenant_backups WHERE id = $1;
+
+-- The newest backup completed by $2, where point-in-time restores start
+-- name: GetLatestCompletedTenantBackup :one
+SELECT * FROM tenant_backups
+WHERE tenant_id = $1 AND status = 'completed' AND completed_at <= $2
+ORDER BY completed_at DESC
+LIMIT 1;
+
+-- Scheduled backups past the newest $2, which the schedule no longer keeps
+-- name: GetExpiredScheduledBackups :many
+SELECT * FROM tenant_backups
+WHERE tenant_id = $1 AND trigger = 'schedule' AND status <> 'pending'
+ORDER BY created_at DESC
+OFFSET $2;
+
+-- Tenant Backup Schedule Queries
+-- name: UpsertTenantBackupSchedule :one
+INSERT INTO tenant_backup_schedules (tenant_id, interval, retain, next_run_at, created_by)
+VALUES ($1, $2, $3, $4, $5)
+ON CONFLICT (tenant_id) DO UPDATE
+SET interval = EXCLUDED.interval, retain = EXCLUDED.retain, next_run_at = EXCLUDED.next_run_at, updated_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetTenantBackupSchedule :one
+SELECT * FROM tenant_backup_schedules WHERE tenant_id = $1;
+
+-- name: DeleteTenantBackupSchedule :execrows
+DELETE FROM tenant_backup_schedules WHERE tenant_id = $1;
+
+-- name: GetDueTenantBackupSchedules :many
+SELECT * FROM tenant_backup_schedules
+WHERE next_run_at <= NOW()
+  AND tenant_id IN (SELECT id FROM tenants WHERE deleted_at IS NULL)
+ORDER BY next_run_at
+LIMIT $1;
+
+-- Moves a schedule forward only if no other worker already did, so each slot runs once
+-- name: AdvanceTenantBackupSchedule :execrows
+UPDATE tenant_backup_schedules SET next_run_at = $3
+WHERE tenant_id = $1 AND next_run_at = $2;
@@ -0,0 +1,3 @@
+-- Tenant Domain Queries
+-- name: GetTenantByDomain :one
+SELECT * FROM tenants WHERE lower(domain) = lower($1);
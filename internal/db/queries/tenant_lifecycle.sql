@@ -0,0 +1,22 @@
+-- Tenant Lifecycle Queries
+-- name: SuspendTenant :one
+UPDATE tenants SET is_active = false, suspended_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL RETURNING *;
+
+-- name: ResumeTenant :one
+UPDATE tenants SET is_active = true, suspended_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL RETURNING *;
+
+-- name: SoftDeleteTenant :one
+UPDATE tenants SET is_active = false, deleted_at = CURRENT_TIMESTAMP, purge_at = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NULL RETURNING *;
+
+-- name: RestoreTenant :one
+UPDATE tenants SET is_active = suspended_at IS NULL, deleted_at = NULL, purge_at = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND deleted_at IS NOT NULL RETURNING *;
+
+-- name: GetTenantsDueForPurge :many
+SELECT * FROM tenants
+WHERE deleted_at IS NOT NULL AND purge_at <= CURRENT_TIMESTAMP
+ORDER BY purge_at
+LIMIT $1;
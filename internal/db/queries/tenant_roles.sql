@@ -0,0 +1,13 @@
+-- Tenant Role Queries
+-- name: GetUserTenantRoles :many
+-- The roles a user holds in one tenant: the role of the membership, plus granted roles
+-- of that tenant and global roles (tenant_id IS NULL)
+SELECT r.* FROM roles r
+WHERE r.id IN (
+    SELECT ut.role_id FROM user_tenants ut
+    WHERE ut.user_id = @user_id AND ut.tenant_id = @tenant_id AND ut.is_active = true
+    UNION
+    SELECT ur.role_id FROM user_roles ur WHERE ur.user_id = @user_id
+)
+AND (r.tenant_id = @tenant_id OR r.tenant_id IS NULL)
+ORDER BY r.name;
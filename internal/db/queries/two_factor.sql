@@ -0,0 +1,38 @@
+-- Two-Factor Queries
+-- name: SetTwoFactorSecret :one
+INSERT INTO user_two_factor (user_id, secret) VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE SET secret = EXCLUDED.secret, enabled = false, last_used_step = 0,
+    failed_attempts = 0, last_failed_at = NULL, enabled_at = NULL, created_at = CURRENT_TIMESTAMP
+RETURNING *;
+
+-- name: GetTwoFactor :one
+SELECT * FROM user_two_factor WHERE user_id = $1;
+
+-- name: EnableTwoFactor :exec
+UPDATE user_two_factor SET enabled = true, enabled_at = CURRENT_TIMESTAMP, last_used_step = $2, failed_attempts = 0
+WHERE user_id = $1;
+
+-- name: UseTwoFactorStep :execrows
+UPDATE user_two_factor SET last_used_step = $2, failed_attempts = 0
+WHERE user_id = $1 AND last_used_step < $2;
+
+-- name: RecordTwoFactorFailure :exec
+UPDATE user_two_factor SET failed_attempts = failed_attempts + 1, last_failed_at = CURRENT_TIMESTAMP
+WHERE user_id = $1;
+
+-- name: DeleteTwoFactor :exec
+DELETE FROM user_two_factor WHERE user_id = $1;
+
+-- Recovery Code Queries
+-- name: CreateRecoveryCode :exec
+INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2);
+
+-- name: DeleteRecoveryCodes :exec
+DELETE FROM user_recovery_codes WHERE user_id = $1;
+
+-- name: UseRecoveryCode :execrows
+UPDATE user_recovery_codes SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL;
+
+-- name: CountRecoveryCodes :one
+SELECT COUNT(*) FROM user_recovery_codes WHERE user_id = $1 AND used_at IS NULL;
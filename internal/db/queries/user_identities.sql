@@ -0,0 +1,14 @@
+-- User Identity Queries
+-- name: CreateUserIdentity :one
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, $4) RETURNING *;
+
+-- name: GetUserIdentity :one
+SELECT * FROM user_identities WHERE provider = $1 AND subject = $2;
+
+-- name: ListUserIdentities :many
+SELECT * FROM user_identities WHERE user_id = $1 ORDER BY created_at;
+
+-- name: TouchUserIdentity :exec
+UPDATE user_identities SET email = $2, last_login_at = CURRENT_TIMESTAMP
+WHERE id = $1;
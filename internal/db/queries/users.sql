@@ -0,0 +1,19 @@
+-- User Password Queries
+-- name: SetUserPassword :exec
+UPDATE users
+SET password_hash = $2, must_change_password = $3, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: SetUserSuperAdmin :exec
+UPDATE users SET is_super_admin = $2, updated_at = CURRENT_TIMESTAMP WHERE id = $1;
+
+-- Service Account Queries
+-- name: CreateServiceAccount :one
+INSERT INTO users (id, email, password_hash, first_name, tenant_id, is_service_account)
+VALUES ($1, $2, $3, $4, $5, true) RETURNING *;
+
+-- name: ListServiceAccounts :many
+SELECT * FROM users WHERE tenant_id = $1 AND is_service_account = true ORDER BY created_at;
+
+-- name: RemoveUserRole :exec
+DELETE FROM user_roles WHERE user_id = $1 AND role_id = $2;
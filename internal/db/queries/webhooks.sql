@@ -0,0 +1,43 @@
+-- Webhook Queries
+-- name: CreateWebhook :one
+INSERT INTO webhooks (tenant_id, name, url, events, collections, secret, is_active, created_by)
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+
+-- name: GetWebhookByID :one
+SELECT * FROM webhooks WHERE id = $1;
+
+-- name: GetActiveWebhooksByTenant :many
+SELECT * FROM webhooks WHERE tenant_id = $1 AND is_active = true ORDER BY created_at;
+
+-- name: UpdateWebhook :one
+UPDATE webhooks
+SET name = $2, url = $3, events = $4, collections = $5, is_active = $6, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 RETURNING *;
+
+-- name: DeleteWebhook :exec
+DELETE FROM webhooks WHERE id = $1;
+
+-- Webhook Delivery Queries
+-- name: CreateWebhookDelivery :one
+INSERT INTO webhook_deliveries (webhook_id, tenant_id, event, collection, payload)
+VALUES ($1, $2, $3, $4, $5) RETURNING *;
+
+-- name: ClaimDueWebhookDeliveries :many
+UPDATE webhook_deliveries
+SET status = 'processing', attempts = attempts + 1, updated_at = CURRENT_TIMESTAMP
+WHERE id IN (
+    SELECT wd.id FROM webhook_deliveries wd
+    WHERE wd.status = 'pending' AND wd.next_attempt_at <= NOW()
+    ORDER BY wd.next_attempt_at
+    LIMIT $1
+    FOR UPDATE SKIP LOCKED
+) RETURNING *;
+
+-- name: FinishWebhookDelivery :exec
+UPDATE webhook_deliveries
+SET status = $2, response_status = $3, response_body = $4, error = $5, next_attempt_at = $6, delivered_at = $7, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1;
+
+-- name: ReleaseStaleWebhookDeliveries :exec
+UPDATE webhook_deliveries SET status = 'pending', updated_at = CURRENT_TIMESTAMP
+WHERE status = 'processing' AND updated_at < $1;
@@ -25,19 +25,24 @@ SELECT * FROM permissions WHERE role_id = $1 AND table_name = $2 AND action = $3
 
 -- API Key Management Queries
 -- name: GetAPIKeyByHash :one
-SELECT * FROM api_keys WHERE key_hash = $1 AND is_active = true;
+SELECT * FROM api_keys
+WHERE (key_hash = $1 OR (previous_key_hash = $1 AND previous_key_expires_at > NOW())) AND is_active = true;
 
 -- name: GetAPIKeysByUser :many
 SELECT * FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC;
 
 -- name: CreateAPIKey :one
-INSERT INTO api_keys (user_id, name, key_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING *;
+INSERT INTO api_keys (user_id, name, key_hash, expires_at, scopes) VALUES ($1, $2, $3, $4, $5) RETURNING *;
 
 -- name: UpdateAPIKeyLastUsed :exec
 UPDATE api_keys SET last_used_at = CURRENT_TIMESTAMP WHERE id = $1;
 
 -- name: UpdateAPIKey :one
-UPDATE api_keys SET name = $2, is_active = $3, expires_at = $4, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING *;
+UPDATE api_keys
+SET name = $2, is_active = $3, expires_at = $4, scopes = $5,
+    expiry_notified_at = CASE WHEN expires_at IS DISTINCT FROM $4 THEN NULL ELSE expiry_notified_at END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 RETURNING *;
 
 -- name: DeleteAPIKey :exec
 DELETE FROM api_keys WHERE id = $1;
@@ -56,12 +61,12 @@ SELECT * FROM collections WHERE id = $1;
 SELECT * FROM collections WHERE slug = $1 AND tenant_id = $2;
 
 -- name: CreateCollection :one
-INSERT INTO collections (id, name, slug, display_name, description, icon, is_system, tenant_id, created_by) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING *;
+INSERT INTO collections (id, name, slug, display_name, description, icon, is_system, tenant_id, created_by, soft_delete, workflow, timestamps, user_stamps, sortable) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING *;
 
 -- name: UpdateCollection :one
 UPDATE collections 
-SET display_name = $2, description = $3, icon = $4, updated_at = CURRENT_TIMESTAMP, updated_by = $5
+SET display_name = $2, description = $3, icon = $4, updated_at = CURRENT_TIMESTAMP, updated_by = $5, soft_delete = $6, workflow = $7, timestamps = $8, user_stamps = $9, sortable = $10
 WHERE id = $1 RETURNING *;
 
 -- name: DeleteCollection :exec
@@ -77,12 +82,12 @@ SELECT * FROM fields WHERE collection_id = $1 ORDER BY sort_order;
 SELECT * FROM fields WHERE id = $1;
 
 -- name: CreateField :one
-INSERT INTO fields (id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, relation_config, sort_order, tenant_id) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING *;
+INSERT INTO fields (id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, relation_config, sort_order, tenant_id, is_indexed, computed_config, is_encrypted, pii) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) RETURNING *;
 
 -- name: UpdateField :one
 UPDATE fields 
-SET display_name = $2, type = $3, is_primary = $4, is_required = $5, is_unique = $6, default_value = $7, validation_rules = $8, relation_config = $9, sort_order = $10, updated_at = CURRENT_TIMESTAMP
+SET display_name = $2, type = $3, is_primary = $4, is_required = $5, is_unique = $6, default_value = $7, validation_rules = $8, relation_config = $9, sort_order = $10, is_indexed = $11, computed_config = $12, is_encrypted = $13, pii = $14, updated_at = CURRENT_TIMESTAMP
 WHERE id = $1 RETURNING *;
 
 -- name: DeleteField :exec
@@ -139,12 +144,12 @@ JOIN user_roles ur ON p.role_id = ur.role_id
 WHERE ur.user_id = $1 AND p.tenant_id = $2;
 
 -- name: CreatePermission :one
-INSERT INTO permissions (id, role_id, table_name, action, field_filter, allowed_fields, tenant_id) 
-VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING *;
+INSERT INTO permissions (id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, read_fields, write_fields, scope, field_transforms) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING *;
 
 -- name: UpdatePermission :one
 UPDATE permissions 
-SET field_filter = $2, allowed_fields = $3, updated_at = CURRENT_TIMESTAMP 
+SET field_filter = $2, allowed_fields = $3, read_fields = $4, write_fields = $5, scope = $6, field_transforms = $7, updated_at = CURRENT_TIMESTAMP 
 WHERE id = $1 RETURNING *;
 
 -- name: DeletePermission :exec
@@ -169,23 +174,26 @@ SELECT * FROM user_tenants WHERE user_id = $1 AND tenant_id = $2;
 -- name: GetUserDefaultTenant :one
 SELECT t.* FROM tenants t 
 JOIN user_tenants ut ON t.id = ut.tenant_id 
-WHERE ut.user_id = $1 AND ut.is_active = true 
+WHERE ut.user_id = $1 AND ut.is_active = true AND t.deleted_at IS NULL 
 ORDER BY ut.created_at LIMIT 1;
 
 -- name: GetUserTenants :many
 SELECT t.* FROM tenants t 
 JOIN user_tenants ut ON t.id = ut.tenant_id 
-WHERE ut.user_id = $1 AND ut.is_active = true 
+WHERE ut.user_id = $1 AND ut.is_active = true AND t.deleted_at IS NULL 
 ORDER BY ut.created_at;
 
 -- Role Management Queries
 -- name: CreateRole :one
-INSERT INTO roles (id, name, description, tenant_id) 
-VALUES ($1, $2, $3, $4) RETURNING *;
+INSERT INTO roles (id, name, description, tenant_id, parent_id) 
+VALUES ($1, $2, $3, $4, $5) RETURNING *;
 
 -- name: GetRolesByTenant :many
 SELECT * FROM roles WHERE tenant_id = $1 ORDER BY name;
 
+-- name: GetRoleByID :one
+SELECT * FROM roles WHERE id = $1;
+
 -- name: GetRoleByNameAndTenant :one
 SELECT * FROM roles WHERE name = $1 AND tenant_id = $2;
 
@@ -0,0 +1,160 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"net"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/lib/pq"
+)
+
+// replicaRetryAfter is how long a replica that failed to connect is skipped before reads
+// try it again
+const replicaRetryAfter = 30 * time.Second
+
+// replica is a read replica connection pool
+type replica struct {
+	*sql.DB
+	downUntil atomic.Int64 // Unix nanoseconds until which the replica is skipped
+}
+
+// ReadPool runs read-only queries on the read replicas in turn, falling back to the
+// primary when a replica cannot be reached or none is configured. Replicas lag behind
+// the primary, so reads that must see a write just made belong on the primary.
+type ReadPool struct {
+	*sqlc.Queries // Generated queries running on the pool
+	primary       *sql.DB
+	replicas      []*replica
+	next          atomic.Uint32
+}
+
+// newReadPool returns a pool reading from replicas, or from primary alone without them
+func newReadPool(primary *sql.DB, replicas []*sql.DB) *ReadPool {
+	pool := &ReadPool{primary: primary}
+	for _, conn := range replicas {
+		pool.replicas = append(pool.replicas, &replica{DB: conn})
+	}
+	pool.Queries = sqlc.New(pool)
+	return pool
+}
+
+// pick returns the next replica that is not marked down, or nil when there is none
+func (p *ReadPool) pick() *replica {
+	now := time.Now().UnixNano()
+	for range p.replicas {
+		r := p.replicas[int(p.next.Add(1))%len(p.replicas)]
+		if r.downUntil.Load() <= now {
+			return r
+		}
+	}
+	return nil
+}
+
+// failed reports whether err means r could not be reached, marking it down if so
+func (p *ReadPool) failed(r *replica, err error) bool {
+	if !isConnectionError(err) {
+		return false
+	}
+	r.downUntil.Store(time.Now().Add(replicaRetryAfter).UnixNano())
+	return true
+}
+
+// QueryContext runs a query on a replica, or on the primary if the replica fails to connect
+func (p *ReadPool) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	if r := p.pick(); r != nil {
+		rows, err := r.QueryContext(ctx, query, args...)
+		if !p.failed(r, err) {
+			return rows, err
+		}
+	}
+	return p.primary.QueryContext(ctx, query, args...)
+}
+
+// QueryRowContext runs a single-row query on a replica, or on the primary if the replica
+// fails to connect
+func (p *ReadPool) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	if r := p.pick(); r != nil {
+		row := r.QueryRowContext(ctx, query, args...)
+		if !p.failed(r, row.Err()) {
+			return row
+		}
+	}
+	return p.primary.QueryRowContext(ctx, query, args...)
+}
+
+// ExecContext runs on the primary; replicas do not accept writes
+func (p *ReadPool) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	return p.primary.ExecContext(ctx, query, args...)
+}
+
+// PrepareContext prepares a statement on a replica, or on the primary if the replica fails
+// to connect
+func (p *ReadPool) PrepareContext(ctx context.Context, query string) (*sql.Stmt, error) {
+	if r := p.pick(); r != nil {
+		stmt, err := r.PrepareContext(ctx, query)
+		if !p.failed(r, err) {
+			return stmt, err
+		}
+	}
+	return p.primary.PrepareContext(ctx, query)
+}
+
+// ReplicaHealth is the state of a read replica as of a health check
+type ReplicaHealth struct {
+	Up    bool
+	Error error // Why the replica is down
+	Stats sql.DBStats
+}
+
+// CheckReplicas pings every replica. Unreachable replicas are marked down and ones that
+// answer again are put back into rotation.
+func (p *ReadPool) CheckReplicas(ctx context.Context) []ReplicaHealth {
+	health := make([]ReplicaHealth, len(p.replicas))
+	for i, r := range p.replicas {
+		err := r.PingContext(ctx)
+		if err != nil {
+			r.downUntil.Store(time.Now().Add(replicaRetryAfter).UnixNano())
+		} else {
+			r.downUntil.Store(0)
+		}
+		health[i] = ReplicaHealth{Up: err == nil, Error: err, Stats: r.Stats()}
+	}
+	return health
+}
+
+// Close closes the replica connection pools
+func (p *ReadPool) Close() error {
+	var errs []error
+	for _, r := range p.replicas {
+		errs = append(errs, r.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// isConnectionError reports whether err means the server could not be reached or is not
+// accepting queries, as opposed to the query itself failing
+func isConnectionError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, driver.ErrBadConn) {
+		return true
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		return true
+	}
+	// Class 08 is connection exceptions; 57P0x a server shutting down or still starting up
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code.Class() == "08" || strings.HasPrefix(string(pqErr.Code), "57P")
+	}
+	return false
+}
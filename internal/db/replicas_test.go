@@ -0,0 +1,78 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unreachable opens a pool to a port nothing listens on
+func unreachable(t *testing.T) *sql.DB {
+	conn, err := sql.Open("postgres", "host=127.0.0.1 port=1 user=postgres dbname=basin sslmode=disable connect_timeout=1")
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestReadPool_pick(t *testing.T) {
+	first, second := unreachable(t), unreachable(t)
+	pool := newReadPool(unreachable(t), []*sql.DB{first, second})
+
+	// Replicas take turns
+	picked := map[*sql.DB]bool{pool.pick().DB: true, pool.pick().DB: true}
+	assert.Equal(t, map[*sql.DB]bool{first: true, second: true}, picked)
+
+	// Replicas marked down are skipped until they may be retried
+	pool.replicas[0].downUntil.Store(time.Now().Add(time.Minute).UnixNano())
+	assert.Same(t, second, pool.pick().DB)
+	assert.Same(t, second, pool.pick().DB)
+
+	pool.replicas[1].downUntil.Store(time.Now().Add(time.Minute).UnixNano())
+	assert.Nil(t, pool.pick())
+
+	pool.replicas[0].downUntil.Store(time.Now().Add(-time.Second).UnixNano())
+	assert.Same(t, first, pool.pick().DB)
+
+	// Without replicas every read goes to the primary
+	assert.Nil(t, newReadPool(unreachable(t), nil).pick())
+}
+
+func TestReadPool_MarksUnreachableReplicaDown(t *testing.T) {
+	pool := newReadPool(unreachable(t), []*sql.DB{unreachable(t)})
+
+	_, err := pool.QueryContext(context.Background(), "SELECT 1")
+	require.Error(t, err, "the primary is unreachable as well")
+	assert.Greater(t, pool.replicas[0].downUntil.Load(), time.Now().UnixNano())
+	assert.Nil(t, pool.pick())
+}
+
+func TestIsConnectionError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"No Error", nil, false},
+		{"Bad Connection", fmt.Errorf("query: %w", driver.ErrBadConn), true},
+		{"Connection Failure", &pq.Error{Code: "08006"}, true},
+		{"Starting Up", &pq.Error{Code: "57P03"}, true},
+		{"Statement Timeout", &pq.Error{Code: "57014"}, false},
+		{"Syntax Error", &pq.Error{Code: "42601"}, false},
+		{"No Rows", sql.ErrNoRows, false},
+		{"Other", errors.New("boom"), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, isConnectionError(tt.err))
+		})
+	}
+}
@@ -6,6 +6,8 @@ package db
 
 import (
 	"database/sql"
+	"encoding/json"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sqlc-dev/pqtype"
@@ -13,30 +15,78 @@ import (
 
 // API keys for programmatic access
 type ApiKey struct {
-	ID         uuid.UUID    `json:"id"`
-	UserID     uuid.UUID    `json:"user_id"`
-	Name       string       `json:"name"`
-	KeyHash    string       `json:"key_hash"`
-	IsActive   sql.NullBool `json:"is_active"`
-	ExpiresAt  sql.NullTime `json:"expires_at"`
-	LastUsedAt sql.NullTime `json:"last_used_at"`
-	CreatedAt  sql.NullTime `json:"created_at"`
-	UpdatedAt  sql.NullTime `json:"updated_at"`
+	ID                   uuid.UUID      `json:"id"`
+	UserID               uuid.UUID      `json:"user_id"`
+	Name                 string         `json:"name"`
+	KeyHash              string         `json:"key_hash"`
+	IsActive             sql.NullBool   `json:"is_active"`
+	ExpiresAt            sql.NullTime   `json:"expires_at"`
+	LastUsedAt           sql.NullTime   `json:"last_used_at"`
+	CreatedAt            sql.NullTime   `json:"created_at"`
+	UpdatedAt            sql.NullTime   `json:"updated_at"`
+	Scopes               []string       `json:"scopes"`
+	PreviousKeyHash      sql.NullString `json:"previous_key_hash"`
+	PreviousKeyExpiresAt sql.NullTime   `json:"previous_key_expires_at"`
+	ExpiryNotifiedAt     sql.NullTime   `json:"expiry_notified_at"`
+	RotatedAt            sql.NullTime   `json:"rotated_at"`
+	RotationNotifiedAt   sql.NullTime   `json:"rotation_notified_at"`
+}
+
+// Audit trail of authentication events and item writes
+type AuditLog struct {
+	ID         uuid.UUID             `json:"id"`
+	TenantID   uuid.NullUUID         `json:"tenant_id"`
+	UserID     uuid.NullUUID         `json:"user_id"`
+	ApiKeyID   uuid.NullUUID         `json:"api_key_id"`
+	Action     string                `json:"action"`
+	TableName  sql.NullString        `json:"table_name"`
+	ItemID     sql.NullString        `json:"item_id"`
+	Result     string                `json:"result"`
+	StatusCode sql.NullInt32         `json:"status_code"`
+	IpAddress  sql.NullString        `json:"ip_address"`
+	UserAgent  sql.NullString        `json:"user_agent"`
+	RequestID  sql.NullString        `json:"request_id"`
+	Details    pqtype.NullRawMessage `json:"details"`
+	CreatedAt  sql.NullTime          `json:"created_at"`
+}
+
+// Uploaded files and their storage location
+type Asset struct {
+	ID               uuid.UUID      `json:"id"`
+	TenantID         uuid.UUID      `json:"tenant_id"`
+	Storage          string         `json:"storage"`
+	StorageKey       string         `json:"storage_key"`
+	FilenameDownload string         `json:"filename_download"`
+	Title            sql.NullString `json:"title"`
+	Type             string         `json:"type"`
+	Filesize         int64          `json:"filesize"`
+	Width            sql.NullInt32  `json:"width"`
+	Height           sql.NullInt32  `json:"height"`
+	UploadedBy       uuid.NullUUID  `json:"uploaded_by"`
+	CreatedAt        sql.NullTime   `json:"created_at"`
+	UpdatedAt        sql.NullTime   `json:"updated_at"`
 }
 
 // Dynamic collections that can be created by users
 type Collection struct {
-	ID          uuid.UUID      `json:"id"`
-	Name        string         `json:"name"`
-	DisplayName sql.NullString `json:"display_name"`
-	Description sql.NullString `json:"description"`
-	Icon        sql.NullString `json:"icon"`
-	IsSystem    sql.NullBool   `json:"is_system"`
-	TenantID    uuid.NullUUID  `json:"tenant_id"`
-	CreatedBy   uuid.NullUUID  `json:"created_by"`
-	UpdatedBy   uuid.NullUUID  `json:"updated_by"`
-	CreatedAt   sql.NullTime   `json:"created_at"`
-	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	ID            uuid.UUID      `json:"id"`
+	Name          string         `json:"name"`
+	Slug          string         `json:"slug"`
+	DataTableName string         `json:"data_table_name"`
+	DisplayName   sql.NullString `json:"display_name"`
+	Description   sql.NullString `json:"description"`
+	Icon          sql.NullString `json:"icon"`
+	IsSystem      sql.NullBool   `json:"is_system"`
+	TenantID      uuid.NullUUID  `json:"tenant_id"`
+	CreatedBy     uuid.NullUUID  `json:"created_by"`
+	UpdatedBy     uuid.NullUUID  `json:"updated_by"`
+	CreatedAt     sql.NullTime   `json:"created_at"`
+	UpdatedAt     sql.NullTime   `json:"updated_at"`
+	SoftDelete    bool           `json:"soft_delete"`
+	Workflow      bool           `json:"workflow"`
+	Timestamps    bool           `json:"timestamps"`
+	UserStamps    bool           `json:"user_stamps"`
+	Sortable      bool           `json:"sortable"`
 }
 
 // Field definitions for dynamic collections
@@ -56,19 +106,126 @@ type Field struct {
 	TenantID        uuid.NullUUID         `json:"tenant_id"`
 	CreatedAt       sql.NullTime          `json:"created_at"`
 	UpdatedAt       sql.NullTime          `json:"updated_at"`
+	IsIndexed       bool                  `json:"is_indexed"`
+	ComputedConfig  pqtype.NullRawMessage `json:"computed_config"`
+	IsEncrypted     bool                  `json:"is_encrypted"`
+	Pii             sql.NullString        `json:"pii"`
+}
+
+// Tenant automations connecting a trigger to a list of operations
+type Flow struct {
+	ID             uuid.UUID       `json:"id"`
+	TenantID       uuid.UUID       `json:"tenant_id"`
+	Name           string          `json:"name"`
+	Description    sql.NullString  `json:"description"`
+	Trigger        string          `json:"trigger"`
+	TriggerOptions json.RawMessage `json:"trigger_options"`
+	Operations     json.RawMessage `json:"operations"`
+	Secret         string          `json:"secret"`
+	IsActive       sql.NullBool    `json:"is_active"`
+	NextRunAt      sql.NullTime    `json:"next_run_at"`
+	CreatedBy      uuid.NullUUID   `json:"created_by"`
+	CreatedAt      sql.NullTime    `json:"created_at"`
+	UpdatedAt      sql.NullTime    `json:"updated_at"`
+}
+
+// Flow run log with the result of every operation
+type FlowRun struct {
+	ID         uuid.UUID       `json:"id"`
+	FlowID     uuid.UUID       `json:"flow_id"`
+	TenantID   uuid.UUID       `json:"tenant_id"`
+	Trigger    string          `json:"trigger"`
+	Payload    json.RawMessage `json:"payload"`
+	Status     string          `json:"status"`
+	Steps      json.RawMessage `json:"steps"`
+	Error      sql.NullString  `json:"error"`
+	StartedAt  sql.NullTime    `json:"started_at"`
+	FinishedAt sql.NullTime    `json:"finished_at"`
+	CreatedAt  sql.NullTime    `json:"created_at"`
+	UpdatedAt  sql.NullTime    `json:"updated_at"`
+}
+
+// Stored responses of write requests, replayed for retries with the same Idempotency-Key
+type IdempotencyKey struct {
+	UserID       uuid.UUID      `json:"user_id"`
+	Key          string         `json:"key"`
+	RequestHash  string         `json:"request_hash"`
+	StatusCode   sql.NullInt32  `json:"status_code"`
+	ContentType  sql.NullString `json:"content_type"`
+	ResponseBody []byte         `json:"response_body"`
+	CreatedAt    time.Time      `json:"created_at"`
+	ExpiresAt    time.Time      `json:"expires_at"`
+}
+
+// Share links granting read access to one item without an account
+type ItemShare struct {
+	ID         uuid.UUID    `json:"id"`
+	TenantID   uuid.UUID    `json:"tenant_id"`
+	Collection string       `json:"collection"`
+	ItemID     uuid.UUID    `json:"item_id"`
+	Fields     []string     `json:"fields"`
+	CreatedBy  uuid.UUID    `json:"created_by"`
+	ExpiresAt  sql.NullTime `json:"expires_at"`
+	RevokedAt  sql.NullTime `json:"revoked_at"`
+	LastUsedAt sql.NullTime `json:"last_used_at"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// Background job queue with retries and a dead letter
+type Job struct {
+	ID          uuid.UUID       `json:"id"`
+	TenantID    uuid.NullUUID   `json:"tenant_id"`
+	Kind        string          `json:"kind"`
+	Payload     json.RawMessage `json:"payload"`
+	Status      string          `json:"status"`
+	Attempts    int32           `json:"attempts"`
+	MaxAttempts int32           `json:"max_attempts"`
+	RunAt       time.Time       `json:"run_at"`
+	LastError   sql.NullString  `json:"last_error"`
+	CompletedAt sql.NullTime    `json:"completed_at"`
+	CreatedAt   sql.NullTime    `json:"created_at"`
+	UpdatedAt   sql.NullTime    `json:"updated_at"`
+	Progress    json.RawMessage `json:"progress"`
+}
+
+// Single-use password reset links
+type PasswordReset struct {
+	ID        uuid.UUID      `json:"id"`
+	UserID    uuid.UUID      `json:"user_id"`
+	ExpiresAt time.Time      `json:"expires_at"`
+	UsedAt    sql.NullTime   `json:"used_at"`
+	Ip        sql.NullString `json:"ip"`
+	CreatedAt sql.NullTime   `json:"created_at"`
 }
 
 // Role-based permissions for table access
 type Permission struct {
-	ID            uuid.UUID             `json:"id"`
-	RoleID        uuid.NullUUID         `json:"role_id"`
-	TableName     string                `json:"table_name"`
-	Action        string                `json:"action"`
-	FieldFilter   pqtype.NullRawMessage `json:"field_filter"`
-	AllowedFields []string              `json:"allowed_fields"`
-	TenantID      uuid.NullUUID         `json:"tenant_id"`
-	CreatedAt     sql.NullTime          `json:"created_at"`
-	UpdatedAt     sql.NullTime          `json:"updated_at"`
+	ID              uuid.UUID             `json:"id"`
+	RoleID          uuid.NullUUID         `json:"role_id"`
+	TableName       string                `json:"table_name"`
+	Action          string                `json:"action"`
+	FieldFilter     pqtype.NullRawMessage `json:"field_filter"`
+	AllowedFields   []string              `json:"allowed_fields"`
+	TenantID        uuid.NullUUID         `json:"tenant_id"`
+	CreatedAt       sql.NullTime          `json:"created_at"`
+	UpdatedAt       sql.NullTime          `json:"updated_at"`
+	ReadFields      []string              `json:"read_fields"`
+	WriteFields     []string              `json:"write_fields"`
+	Scope           string                `json:"scope"`
+	FieldTransforms pqtype.NullRawMessage `json:"field_transforms"`
+}
+
+// Item change history with old and new values
+type Revision struct {
+	ID         uuid.UUID             `json:"id"`
+	TenantID   uuid.UUID             `json:"tenant_id"`
+	Collection string                `json:"collection"`
+	ItemID     uuid.UUID             `json:"item_id"`
+	Action     string                `json:"action"`
+	OldData    pqtype.NullRawMessage `json:"old_data"`
+	NewData    pqtype.NullRawMessage `json:"new_data"`
+	UserID     uuid.NullUUID         `json:"user_id"`
+	CreatedAt  sql.NullTime          `json:"created_at"`
 }
 
 // Role definitions with tenant isolation
@@ -79,31 +236,99 @@ type Role struct {
 	TenantID    uuid.NullUUID  `json:"tenant_id"`
 	CreatedAt   sql.NullTime   `json:"created_at"`
 	UpdatedAt   sql.NullTime   `json:"updated_at"`
+	ParentID    uuid.NullUUID  `json:"parent_id"`
+}
+
+// Login sessions behind access tokens
+type Session struct {
+	ID         uuid.UUID      `json:"id"`
+	UserID     uuid.UUID      `json:"user_id"`
+	TenantID   uuid.NullUUID  `json:"tenant_id"`
+	UserAgent  sql.NullString `json:"user_agent"`
+	Ip         sql.NullString `json:"ip"`
+	ExpiresAt  time.Time      `json:"expires_at"`
+	RevokedAt  sql.NullTime   `json:"revoked_at"`
+	LastSeenAt sql.NullTime   `json:"last_seen_at"`
+	CreatedAt  sql.NullTime   `json:"created_at"`
 }
 
 // Multi-tenant support - each tenant has isolated data
 type Tenant struct {
-	ID        uuid.UUID             `json:"id"`
-	Name      string                `json:"name"`
-	Slug      string                `json:"slug"`
-	Domain    sql.NullString        `json:"domain"`
-	Settings  pqtype.NullRawMessage `json:"settings"`
-	IsActive  sql.NullBool          `json:"is_active"`
-	CreatedAt sql.NullTime          `json:"created_at"`
-	UpdatedAt sql.NullTime          `json:"updated_at"`
+	ID          uuid.UUID             `json:"id"`
+	Name        string                `json:"name"`
+	Slug        string                `json:"slug"`
+	Domain      sql.NullString        `json:"domain"`
+	Settings    pqtype.NullRawMessage `json:"settings"`
+	IsActive    sql.NullBool          `json:"is_active"`
+	CreatedAt   sql.NullTime          `json:"created_at"`
+	UpdatedAt   sql.NullTime          `json:"updated_at"`
+	SuspendedAt sql.NullTime          `json:"suspended_at"`
+	DeletedAt   sql.NullTime          `json:"deleted_at"`
+	PurgeAt     sql.NullTime          `json:"purge_at"`
+}
+
+// Backups of tenant schema and data kept in asset storage
+type TenantBackup struct {
+	ID          uuid.UUID      `json:"id"`
+	TenantID    uuid.UUID      `json:"tenant_id"`
+	Trigger     string         `json:"trigger"`
+	Status      string         `json:"status"`
+	StorageKey  string         `json:"storage_key"`
+	Size        int64          `json:"size"`
+	Checksum    sql.NullString `json:"checksum"`
+	Collections int32          `json:"collections"`
+	Items       int32          `json:"items"`
+	Error       sql.NullString `json:"error"`
+	CreatedBy   uuid.NullUUID  `json:"created_by"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+	CompletedAt sql.NullTime   `json:"completed_at"`
+}
+
+// Intervals at which tenants are backed up
+type TenantBackupSchedule struct {
+	TenantID  uuid.UUID     `json:"tenant_id"`
+	Interval  string        `json:"interval"`
+	Retain    int32         `json:"retain"`
+	NextRunAt time.Time     `json:"next_run_at"`
+	CreatedBy uuid.NullUUID `json:"created_by"`
+	CreatedAt sql.NullTime  `json:"created_at"`
+	UpdatedAt sql.NullTime  `json:"updated_at"`
 }
 
 // User accounts with tenant isolation
 type User struct {
-	ID           uuid.UUID      `json:"id"`
-	Email        string         `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	FirstName    sql.NullString `json:"first_name"`
-	LastName     sql.NullString `json:"last_name"`
-	IsActive     sql.NullBool   `json:"is_active"`
-	TenantID     uuid.NullUUID  `json:"tenant_id"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
+	ID                 uuid.UUID      `json:"id"`
+	Email              string         `json:"email"`
+	PasswordHash       string         `json:"password_hash"`
+	FirstName          sql.NullString `json:"first_name"`
+	LastName           sql.NullString `json:"last_name"`
+	IsActive           sql.NullBool   `json:"is_active"`
+	TenantID           uuid.NullUUID  `json:"tenant_id"`
+	CreatedAt          sql.NullTime   `json:"created_at"`
+	UpdatedAt          sql.NullTime   `json:"updated_at"`
+	MustChangePassword bool           `json:"must_change_password"`
+	IsSuperAdmin       bool           `json:"is_super_admin"`
+	IsServiceAccount   bool           `json:"is_service_account"`
+}
+
+// External identity provider accounts linked to users
+type UserIdentity struct {
+	ID          uuid.UUID      `json:"id"`
+	UserID      uuid.UUID      `json:"user_id"`
+	Provider    string         `json:"provider"`
+	Subject     string         `json:"subject"`
+	Email       sql.NullString `json:"email"`
+	LastLoginAt sql.NullTime   `json:"last_login_at"`
+	CreatedAt   sql.NullTime   `json:"created_at"`
+}
+
+// Single-use codes that replace a TOTP code when the authenticator is lost
+type UserRecoveryCode struct {
+	ID        uuid.UUID    `json:"id"`
+	UserID    uuid.UUID    `json:"user_id"`
+	CodeHash  string       `json:"code_hash"`
+	UsedAt    sql.NullTime `json:"used_at"`
+	CreatedAt sql.NullTime `json:"created_at"`
 }
 
 type UserRole struct {
@@ -119,3 +344,49 @@ type UserTenant struct {
 	IsActive  sql.NullBool  `json:"is_active"`
 	CreatedAt sql.NullTime  `json:"created_at"`
 }
+
+// TOTP second factor of users
+type UserTwoFactor struct {
+	UserID         uuid.UUID    `json:"user_id"`
+	Secret         string       `json:"secret"`
+	Enabled        bool         `json:"enabled"`
+	LastUsedStep   int64        `json:"last_used_step"`
+	FailedAttempts int32        `json:"failed_attempts"`
+	LastFailedAt   sql.NullTime `json:"last_failed_at"`
+	EnabledAt      sql.NullTime `json:"enabled_at"`
+	CreatedAt      sql.NullTime `json:"created_at"`
+}
+
+// Outgoing webhook subscriptions for item events
+type Webhook struct {
+	ID          uuid.UUID     `json:"id"`
+	TenantID    uuid.UUID     `json:"tenant_id"`
+	Name        string        `json:"name"`
+	Url         string        `json:"url"`
+	Events      []string      `json:"events"`
+	Collections []string      `json:"collections"`
+	Secret      string        `json:"secret"`
+	IsActive    sql.NullBool  `json:"is_active"`
+	CreatedBy   uuid.NullUUID `json:"created_by"`
+	CreatedAt   sql.NullTime  `json:"created_at"`
+	UpdatedAt   sql.NullTime  `json:"updated_at"`
+}
+
+// Webhook delivery log with retry state
+type WebhookDelivery struct {
+	ID             uuid.UUID       `json:"id"`
+	WebhookID      uuid.UUID       `json:"webhook_id"`
+	TenantID       uuid.UUID       `json:"tenant_id"`
+	Event          string          `json:"event"`
+	Collection     string          `json:"collection"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int32           `json:"attempts"`
+	ResponseStatus sql.NullInt32   `json:"response_status"`
+	ResponseBody   sql.NullString  `json:"response_body"`
+	Error          sql.NullString  `json:"error"`
+	NextAttemptAt  time.Time       `json:"next_attempt_at"`
+	DeliveredAt    sql.NullTime    `json:"delivered_at"`
+	CreatedAt      sql.NullTime    `json:"created_at"`
+	UpdatedAt      sql.NullTime    `json:"updated_at"`
+}
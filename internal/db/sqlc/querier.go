@@ -6,6 +6,8 @@ package db
 
 import (
 	"context"
+	"database/sql"
+	"time"
 
 	"github.com/google/uuid"
 )
@@ -13,65 +15,213 @@ import (
 type Querier interface {
 	AddUserRole(ctx context.Context, arg AddUserRoleParams) error
 	AddUserToTenant(ctx context.Context, arg AddUserToTenantParams) error
+	// Moves a schedule forward only if no other worker already did, so each slot runs once
+	AdvanceFlowSchedule(ctx context.Context, arg AdvanceFlowScheduleParams) (int64, error)
+	// Moves a schedule forward only if no other worker already did, so each slot runs once
+	AdvanceTenantBackupSchedule(ctx context.Context, arg AdvanceTenantBackupScheduleParams) (int64, error)
+	ClaimDueWebhookDeliveries(ctx context.Context, limit int32) ([]WebhookDelivery, error)
+	ClaimDueJobs(ctx context.Context, limit int32) ([]Job, error)
+	ClaimExpiringAPIKeys(ctx context.Context, arg ClaimExpiringAPIKeysParams) ([]ApiKey, error)
+	ClaimRotationDueAPIKeys(ctx context.Context, arg ClaimRotationDueAPIKeysParams) ([]ApiKey, error)
+	// Idempotency Key Queries
+	// Inserts the key, or takes over one that expired or whose request was abandoned while
+	// in progress; returns no row while the key is held by a live request or response
+	ClaimIdempotencyKey(ctx context.Context, arg ClaimIdempotencyKeyParams) (IdempotencyKey, error)
+	ClaimPendingFlowRuns(ctx context.Context, limit int32) ([]FlowRun, error)
+	CompleteIdempotencyKey(ctx context.Context, arg CompleteIdempotencyKeyParams) error
+	CompleteJob(ctx context.Context, id uuid.UUID) error
+	CompleteTenantBackup(ctx context.Context, arg CompleteTenantBackupParams) (TenantBackup, error)
+	CountRecoveryCodes(ctx context.Context, userID uuid.UUID) (int64, error)
+	CountTenantAPIKeys(ctx context.Context, tenantID uuid.NullUUID) (int64, error)
+	// Tenant Usage Queries
+	CountTenantCollections(ctx context.Context, tenantID uuid.NullUUID) (int64, error)
 	CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error)
+	// Audit Log Queries
+	// Entries without a tenant (API keys, failed logins) fall back to the user's home tenant
+	CreateAuditLog(ctx context.Context, arg CreateAuditLogParams) error
+	// Asset Queries
+	CreateAsset(ctx context.Context, arg CreateAssetParams) (Asset, error)
 	CreateCollection(ctx context.Context, arg CreateCollectionParams) (Collection, error)
 	CreateField(ctx context.Context, arg CreateFieldParams) (Field, error)
+	// Flow Queries
+	CreateFlow(ctx context.Context, arg CreateFlowParams) (Flow, error)
+	// Flow Run Queries
+	CreateFlowRun(ctx context.Context, arg CreateFlowRunParams) (FlowRun, error)
+	// Item Share Queries
+	CreateItemShare(ctx context.Context, arg CreateItemShareParams) (ItemShare, error)
+	// Password Reset Queries
+	CreatePasswordReset(ctx context.Context, arg CreatePasswordResetParams) (PasswordReset, error)
 	CreatePermission(ctx context.Context, arg CreatePermissionParams) (Permission, error)
+	// Recovery Code Queries
+	CreateRecoveryCode(ctx context.Context, arg CreateRecoveryCodeParams) error
+	// Revision Queries
+	CreateRevision(ctx context.Context, arg CreateRevisionParams) (Revision, error)
 	// Role Management Queries
 	CreateRole(ctx context.Context, arg CreateRoleParams) (Role, error)
+	// Service Account Queries
+	CreateServiceAccount(ctx context.Context, arg CreateServiceAccountParams) (User, error)
+	// Session Queries
+	CreateSession(ctx context.Context, arg CreateSessionParams) (Session, error)
 	CreateTenant(ctx context.Context, arg CreateTenantParams) (Tenant, error)
+	// Tenant Backup Queries
+	CreateTenantBackup(ctx context.Context, arg CreateTenantBackupParams) (TenantBackup, error)
 	CreateUser(ctx context.Context, arg CreateUserParams) (User, error)
+	// User Identity Queries
+	CreateUserIdentity(ctx context.Context, arg CreateUserIdentityParams) (UserIdentity, error)
+	// Webhook Queries
+	CreateWebhook(ctx context.Context, arg CreateWebhookParams) (Webhook, error)
+	// Webhook Delivery Queries
+	CreateWebhookDelivery(ctx context.Context, arg CreateWebhookDeliveryParams) (WebhookDelivery, error)
 	DeleteAPIKey(ctx context.Context, id uuid.UUID) error
+	DeleteAsset(ctx context.Context, id uuid.UUID) error
 	DeleteCollection(ctx context.Context, id uuid.UUID) error
+	DeleteCompletedJobs(ctx context.Context, completedAt sql.NullTime) (int64, error)
+	DeleteExpiredIdempotencyKeys(ctx context.Context, expiresAt time.Time) (int64, error)
 	DeleteField(ctx context.Context, id uuid.UUID) error
+	DeleteFlow(ctx context.Context, id uuid.UUID) error
+	DeleteIdempotencyKey(ctx context.Context, arg DeleteIdempotencyKeyParams) error
+	DeleteItemRevisions(ctx context.Context, arg DeleteItemRevisionsParams) (int64, error)
 	DeletePermission(ctx context.Context, id uuid.UUID) error
+	DeleteRecoveryCodes(ctx context.Context, userID uuid.UUID) error
 	DeleteTenant(ctx context.Context, id uuid.UUID) error
+	DeleteTenantBackup(ctx context.Context, id uuid.UUID) error
+	DeleteTenantBackupSchedule(ctx context.Context, tenantID uuid.UUID) (int64, error)
+	DeleteTwoFactor(ctx context.Context, userID uuid.UUID) error
 	DeleteUser(ctx context.Context, id uuid.UUID) error
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	EnableTwoFactor(ctx context.Context, arg EnableTwoFactorParams) error
+	// Job Queries
+	EnqueueJob(ctx context.Context, arg EnqueueJobParams) (Job, error)
+	// Records a failed attempt: back to 'pending' with a later run_at, or 'dead'
+	FailJob(ctx context.Context, arg FailJobParams) error
+	FailTenantBackup(ctx context.Context, arg FailTenantBackupParams) error
+	FinishFlowRun(ctx context.Context, arg FinishFlowRunParams) error
+	FinishWebhookDelivery(ctx context.Context, arg FinishWebhookDeliveryParams) error
 	// Note: Customer queries removedm - customers are now managed through dynamic collections
 	// The data_customers table is created automatically when the customers collection is created
 	// API Key Management Queries
 	GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey, error)
 	GetAPIKeyByID(ctx context.Context, id uuid.UUID) (ApiKey, error)
 	GetAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]ApiKey, error)
+	GetActiveEventFlowsByTenant(ctx context.Context, tenantID uuid.UUID) ([]Flow, error)
+	GetActiveWebhooksByTenant(ctx context.Context, tenantID uuid.UUID) ([]Webhook, error)
+	GetAssetByID(ctx context.Context, id uuid.UUID) (Asset, error)
 	// User-Tenant Relationship Queries
 	GetAllTenants(ctx context.Context) ([]Tenant, error)
 	GetCollection(ctx context.Context, id uuid.UUID) (Collection, error)
 	GetCollectionByNameAndTenant(ctx context.Context, arg GetCollectionByNameAndTenantParams) (Collection, error)
 	// Schema Management Queries
 	GetCollections(ctx context.Context) ([]Collection, error)
+	// Schema Snapshot Queries
+	GetCollectionsByTenant(ctx context.Context, tenantID uuid.NullUUID) ([]Collection, error)
+	GetDueScheduledFlows(ctx context.Context, limit int32) ([]Flow, error)
+	GetDueTenantBackupSchedules(ctx context.Context, limit int32) ([]TenantBackupSchedule, error)
+	// Scheduled backups past the newest $2, which the schedule no longer keeps
+	GetExpiredScheduledBackups(ctx context.Context, arg GetExpiredScheduledBackupsParams) ([]TenantBackup, error)
 	GetField(ctx context.Context, id uuid.UUID) (Field, error)
 	GetFields(ctx context.Context) ([]Field, error)
 	GetFieldsByCollection(ctx context.Context, collectionID uuid.NullUUID) ([]Field, error)
+	GetFlowByID(ctx context.Context, id uuid.UUID) (Flow, error)
+	GetIdempotencyKey(ctx context.Context, arg GetIdempotencyKeyParams) (IdempotencyKey, error)
+	GetItemShare(ctx context.Context, id uuid.UUID) (ItemShare, error)
+	GetJobByID(ctx context.Context, id uuid.UUID) (Job, error)
+	// The newest backup completed by $2, where point-in-time restores start
+	GetLatestCompletedTenantBackup(ctx context.Context, arg GetLatestCompletedTenantBackupParams) (TenantBackup, error)
+	GetPasswordReset(ctx context.Context, id uuid.UUID) (PasswordReset, error)
 	GetPermissionsByRole(ctx context.Context, roleID uuid.NullUUID) ([]Permission, error)
 	GetPermissionsByRoleAndAction(ctx context.Context, arg GetPermissionsByRoleAndActionParams) ([]Permission, error)
 	GetPermissionsByRoleAndTable(ctx context.Context, arg GetPermissionsByRoleAndTableParams) ([]Permission, error)
 	// Enhanced Permission Queries with Tenant Support
 	GetPermissionsByRoleAndTenant(ctx context.Context, arg GetPermissionsByRoleAndTenantParams) ([]Permission, error)
+	GetPermissionsByTenant(ctx context.Context, tenantID uuid.NullUUID) ([]Permission, error)
 	GetPermissionsByUserAndTenant(ctx context.Context, arg GetPermissionsByUserAndTenantParams) ([]Permission, error)
+	GetRevisionByID(ctx context.Context, id uuid.UUID) (Revision, error)
+	GetRoleByID(ctx context.Context, id uuid.UUID) (Role, error)
 	GetRoleByNameAndTenant(ctx context.Context, arg GetRoleByNameAndTenantParams) (Role, error)
 	GetRolesByTenant(ctx context.Context, tenantID uuid.NullUUID) ([]Role, error)
+	GetSession(ctx context.Context, id uuid.UUID) (Session, error)
 	GetTenant(ctx context.Context, id uuid.UUID) (Tenant, error)
+	GetTenantBackup(ctx context.Context, arg GetTenantBackupParams) (TenantBackup, error)
+	GetTenantBackupSchedule(ctx context.Context, tenantID uuid.UUID) (TenantBackupSchedule, error)
+	// Tenant Domain Queries
+	GetTenantByDomain(ctx context.Context, lower string) (Tenant, error)
 	GetTenantByID(ctx context.Context, id uuid.UUID) (Tenant, error)
 	GetTenantBySlug(ctx context.Context, slug string) (Tenant, error)
 	// Tenant Management Queries
 	GetTenants(ctx context.Context) ([]Tenant, error)
+	GetTenantsDueForPurge(ctx context.Context, limit int32) ([]Tenant, error)
+	GetTwoFactor(ctx context.Context, userID uuid.UUID) (UserTwoFactor, error)
 	GetUserByEmail(ctx context.Context, email string) (User, error)
 	GetUserByID(ctx context.Context, id uuid.UUID) (User, error)
 	GetUserDefaultTenant(ctx context.Context, userID uuid.UUID) (Tenant, error)
+	GetUserIdentity(ctx context.Context, arg GetUserIdentityParams) (UserIdentity, error)
 	GetUserRoles(ctx context.Context, userID uuid.UUID) ([]Role, error)
 	GetUserTenant(ctx context.Context, arg GetUserTenantParams) (UserTenant, error)
+	// Tenant Role Queries
+	// The roles a user holds in one tenant: the role of the membership, plus granted roles
+	// of that tenant and global roles (tenant_id IS NULL)
+	GetUserTenantRoles(ctx context.Context, arg GetUserTenantRolesParams) ([]Role, error)
 	GetUserTenants(ctx context.Context, userID uuid.UUID) ([]Tenant, error)
 	GetUserWithTenant(ctx context.Context, id uuid.UUID) (GetUserWithTenantRow, error)
 	// Enhanced User Queries with Tenant Support
 	GetUsersByTenant(ctx context.Context, tenantID uuid.NullUUID) ([]User, error)
+	GetWebhookByID(ctx context.Context, id uuid.UUID) (Webhook, error)
+	// Content Workflow Queries
+	GetWorkflowCollections(ctx context.Context) ([]Collection, error)
+	InvalidatePasswordResets(ctx context.Context, userID uuid.UUID) error
+	// Changes to a collection between two times, oldest first
+	ListCollectionRevisions(ctx context.Context, arg ListCollectionRevisionsParams) ([]Revision, error)
+	ListItemRevisions(ctx context.Context, arg ListItemRevisionsParams) ([]Revision, error)
+	ListItemShares(ctx context.Context, arg ListItemSharesParams) ([]ItemShare, error)
+	ListServiceAccounts(ctx context.Context, tenantID uuid.NullUUID) ([]User, error)
+	ListTenantBackups(ctx context.Context, tenantID uuid.UUID) ([]TenantBackup, error)
+	ListUserIdentities(ctx context.Context, userID uuid.UUID) ([]UserIdentity, error)
+	ListUserSessions(ctx context.Context, userID uuid.UUID) ([]Session, error)
+	RecordTwoFactorFailure(ctx context.Context, userID uuid.UUID) error
+	ReleaseStaleFlowRuns(ctx context.Context, updatedAt sql.NullTime) error
+	ReleaseStaleJobs(ctx context.Context, updatedAt sql.NullTime) error
+	ReleaseStaleWebhookDeliveries(ctx context.Context, updatedAt sql.NullTime) error
 	RemoveUserFromTenant(ctx context.Context, arg RemoveUserFromTenantParams) error
+	RemoveUserRole(ctx context.Context, arg RemoveUserRoleParams) error
+	// Field Queries
+	RenameField(ctx context.Context, arg RenameFieldParams) error
+	RestoreTenant(ctx context.Context, id uuid.UUID) (Tenant, error)
+	ResumeTenant(ctx context.Context, id uuid.UUID) (Tenant, error)
+	// Moves a dead job back into the queue with a fresh set of attempts
+	RetryJob(ctx context.Context, id uuid.UUID) (Job, error)
+	RevokeItemShare(ctx context.Context, arg RevokeItemShareParams) (int64, error)
+	RevokeSession(ctx context.Context, arg RevokeSessionParams) (int64, error)
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) error
+	// API Key Lifecycle Queries
+	RotateAPIKey(ctx context.Context, arg RotateAPIKeyParams) (ApiKey, error)
+	SetJobProgress(ctx context.Context, arg SetJobProgressParams) error
+	// Two-Factor Queries
+	SetTwoFactorSecret(ctx context.Context, arg SetTwoFactorSecretParams) (UserTwoFactor, error)
+	// User Password Queries
+	SetUserPassword(ctx context.Context, arg SetUserPasswordParams) error
+	SetUserSuperAdmin(ctx context.Context, arg SetUserSuperAdminParams) error
+	SoftDeleteTenant(ctx context.Context, arg SoftDeleteTenantParams) (Tenant, error)
+	SumTenantAssetSize(ctx context.Context, tenantID uuid.UUID) (int64, error)
+	// Tenant Lifecycle Queries
+	SuspendTenant(ctx context.Context, id uuid.UUID) (Tenant, error)
+	TouchItemShare(ctx context.Context, id uuid.UUID) error
+	TouchSession(ctx context.Context, id uuid.UUID) error
+	TouchUserIdentity(ctx context.Context, arg TouchUserIdentityParams) error
 	UpdateAPIKey(ctx context.Context, arg UpdateAPIKeyParams) (ApiKey, error)
 	UpdateAPIKeyLastUsed(ctx context.Context, id uuid.UUID) error
+	UpdateAsset(ctx context.Context, arg UpdateAssetParams) (Asset, error)
 	UpdateCollection(ctx context.Context, arg UpdateCollectionParams) (Collection, error)
 	UpdateField(ctx context.Context, arg UpdateFieldParams) (Field, error)
+	UpdateFlow(ctx context.Context, arg UpdateFlowParams) (Flow, error)
 	UpdatePermission(ctx context.Context, arg UpdatePermissionParams) (Permission, error)
 	UpdateTenant(ctx context.Context, arg UpdateTenantParams) (Tenant, error)
 	UpdateUser(ctx context.Context, arg UpdateUserParams) (User, error)
+	UpdateWebhook(ctx context.Context, arg UpdateWebhookParams) (Webhook, error)
+	// Tenant Backup Schedule Queries
+	UpsertTenantBackupSchedule(ctx context.Context, arg UpsertTenantBackupScheduleParams) (TenantBackupSchedule, error)
+	UsePasswordReset(ctx context.Context, id uuid.UUID) (int64, error)
+	UseRecoveryCode(ctx context.Context, arg UseRecoveryCodeParams) (int64, error)
+	UseTwoFactorStep(ctx context.Context, arg UseTwoFactorStepParams) (int64, error)
 }
 
 var _ Querier = (*Queries)(nil)
@@ -44,7 +44,7 @@ func (q *Queries) AddUserToTenant(ctx context.Context, arg AddUserToTenantParams
 }
 
 const createAPIKey = `-- name: CreateAPIKey :one
-INSERT INTO api_keys (user_id, name, key_hash, expires_at) VALUES ($1, $2, $3, $4) RETURNING id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at
+INSERT INTO api_keys (user_id, name, key_hash, expires_at, scopes) VALUES ($1, $2, $3, $4, $5) RETURNING id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at, scopes, previous_key_hash, previous_key_expires_at, expiry_notified_at, rotated_at, rotation_notified_at
 `
 
 type CreateAPIKeyParams struct {
@@ -52,6 +52,7 @@ type CreateAPIKeyParams struct {
 	Name      string       `json:"name"`
 	KeyHash   string       `json:"key_hash"`
 	ExpiresAt sql.NullTime `json:"expires_at"`
+	Scopes    []string     `json:"scopes"`
 }
 
 func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (ApiKey, error) {
@@ -60,6 +61,7 @@ func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (Api
 		arg.Name,
 		arg.KeyHash,
 		arg.ExpiresAt,
+		pq.Array(arg.Scopes),
 	)
 	var i ApiKey
 	err := row.Scan(
@@ -72,41 +74,61 @@ func (q *Queries) CreateAPIKey(ctx context.Context, arg CreateAPIKeyParams) (Api
 		&i.LastUsedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.Scopes),
+		&i.PreviousKeyHash,
+		&i.PreviousKeyExpiresAt,
+		&i.ExpiryNotifiedAt,
+		&i.RotatedAt,
+		&i.RotationNotifiedAt,
 	)
 	return i, err
 }
 
 const createCollection = `-- name: CreateCollection :one
-INSERT INTO collections (id, name, display_name, description, icon, is_system, tenant_id, created_by) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8) RETURNING id, name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at
+INSERT INTO collections (id, name, slug, display_name, description, icon, is_system, tenant_id, created_by, soft_delete, workflow, timestamps, user_stamps, sortable) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) RETURNING id, name, slug, data_table_name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at, soft_delete, workflow, timestamps, user_stamps, sortable
 `
 
 type CreateCollectionParams struct {
 	ID          uuid.UUID      `json:"id"`
 	Name        string         `json:"name"`
+	Slug        string         `json:"slug"`
 	DisplayName sql.NullString `json:"display_name"`
 	Description sql.NullString `json:"description"`
 	Icon        sql.NullString `json:"icon"`
 	IsSystem    sql.NullBool   `json:"is_system"`
 	TenantID    uuid.NullUUID  `json:"tenant_id"`
 	CreatedBy   uuid.NullUUID  `json:"created_by"`
+	SoftDelete  bool           `json:"soft_delete"`
+	Workflow    bool           `json:"workflow"`
+	Timestamps  bool           `json:"timestamps"`
+	UserStamps  bool           `json:"user_stamps"`
+	Sortable    bool           `json:"sortable"`
 }
 
 func (q *Queries) CreateCollection(ctx context.Context, arg CreateCollectionParams) (Collection, error) {
 	row := q.db.QueryRowContext(ctx, createCollection,
 		arg.ID,
 		arg.Name,
+		arg.Slug,
 		arg.DisplayName,
 		arg.Description,
 		arg.Icon,
 		arg.IsSystem,
 		arg.TenantID,
 		arg.CreatedBy,
+		arg.SoftDelete,
+		arg.Workflow,
+		arg.Timestamps,
+		arg.UserStamps,
+		arg.Sortable,
 	)
 	var i Collection
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
+		&i.Slug,
+		&i.DataTableName,
 		&i.DisplayName,
 		&i.Description,
 		&i.Icon,
@@ -116,13 +138,18 @@ func (q *Queries) CreateCollection(ctx context.Context, arg CreateCollectionPara
 		&i.UpdatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SoftDelete,
+		&i.Workflow,
+		&i.Timestamps,
+		&i.UserStamps,
+		&i.Sortable,
 	)
 	return i, err
 }
 
 const createField = `-- name: CreateField :one
-INSERT INTO fields (id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, relation_config, sort_order, tenant_id) 
-VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) RETURNING id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at
+INSERT INTO fields (id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, relation_config, sort_order, tenant_id, is_indexed, computed_config, is_encrypted, pii) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) RETURNING id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at, is_indexed, computed_config, is_encrypted, pii
 `
 
 type CreateFieldParams struct {
@@ -139,6 +166,10 @@ type CreateFieldParams struct {
 	RelationConfig  pqtype.NullRawMessage `json:"relation_config"`
 	SortOrder       sql.NullInt32         `json:"sort_order"`
 	TenantID        uuid.NullUUID         `json:"tenant_id"`
+	IsIndexed       bool                  `json:"is_indexed"`
+	ComputedConfig  pqtype.NullRawMessage `json:"computed_config"`
+	IsEncrypted     bool                  `json:"is_encrypted"`
+	Pii             sql.NullString        `json:"pii"`
 }
 
 func (q *Queries) CreateField(ctx context.Context, arg CreateFieldParams) (Field, error) {
@@ -156,6 +187,10 @@ func (q *Queries) CreateField(ctx context.Context, arg CreateFieldParams) (Field
 		arg.RelationConfig,
 		arg.SortOrder,
 		arg.TenantID,
+		arg.IsIndexed,
+		arg.ComputedConfig,
+		arg.IsEncrypted,
+		arg.Pii,
 	)
 	var i Field
 	err := row.Scan(
@@ -174,23 +209,31 @@ func (q *Queries) CreateField(ctx context.Context, arg CreateFieldParams) (Field
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsIndexed,
+		&i.ComputedConfig,
+		&i.IsEncrypted,
+		&i.Pii,
 	)
 	return i, err
 }
 
 const createPermission = `-- name: CreatePermission :one
-INSERT INTO permissions (id, role_id, table_name, action, field_filter, allowed_fields, tenant_id) 
-VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at
+INSERT INTO permissions (id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, read_fields, write_fields, scope, field_transforms) 
+VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at, read_fields, write_fields, scope, field_transforms
 `
 
 type CreatePermissionParams struct {
-	ID            uuid.UUID             `json:"id"`
-	RoleID        uuid.NullUUID         `json:"role_id"`
-	TableName     string                `json:"table_name"`
-	Action        string                `json:"action"`
-	FieldFilter   pqtype.NullRawMessage `json:"field_filter"`
-	AllowedFields []string              `json:"allowed_fields"`
-	TenantID      uuid.NullUUID         `json:"tenant_id"`
+	ID              uuid.UUID             `json:"id"`
+	RoleID          uuid.NullUUID         `json:"role_id"`
+	TableName       string                `json:"table_name"`
+	Action          string                `json:"action"`
+	FieldFilter     pqtype.NullRawMessage `json:"field_filter"`
+	AllowedFields   []string              `json:"allowed_fields"`
+	TenantID        uuid.NullUUID         `json:"tenant_id"`
+	ReadFields      []string              `json:"read_fields"`
+	WriteFields     []string              `json:"write_fields"`
+	Scope           string                `json:"scope"`
+	FieldTransforms pqtype.NullRawMessage `json:"field_transforms"`
 }
 
 func (q *Queries) CreatePermission(ctx context.Context, arg CreatePermissionParams) (Permission, error) {
@@ -202,6 +245,10 @@ func (q *Queries) CreatePermission(ctx context.Context, arg CreatePermissionPara
 		arg.FieldFilter,
 		pq.Array(arg.AllowedFields),
 		arg.TenantID,
+		pq.Array(arg.ReadFields),
+		pq.Array(arg.WriteFields),
+		arg.Scope,
+		arg.FieldTransforms,
 	)
 	var i Permission
 	err := row.Scan(
@@ -214,13 +261,17 @@ func (q *Queries) CreatePermission(ctx context.Context, arg CreatePermissionPara
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.ReadFields),
+		pq.Array(&i.WriteFields),
+		&i.Scope,
+		&i.FieldTransforms,
 	)
 	return i, err
 }
 
 const createRole = `-- name: CreateRole :one
-INSERT INTO roles (id, name, description, tenant_id) 
-VALUES ($1, $2, $3, $4) RETURNING id, name, description, tenant_id, created_at, updated_at
+INSERT INTO roles (id, name, description, tenant_id, parent_id) 
+VALUES ($1, $2, $3, $4, $5) RETURNING id, name, description, tenant_id, created_at, updated_at, parent_id
 `
 
 type CreateRoleParams struct {
@@ -228,6 +279,7 @@ type CreateRoleParams struct {
 	Name        string         `json:"name"`
 	Description sql.NullString `json:"description"`
 	TenantID    uuid.NullUUID  `json:"tenant_id"`
+	ParentID    uuid.NullUUID  `json:"parent_id"`
 }
 
 // Role Management Queries
@@ -237,6 +289,7 @@ func (q *Queries) CreateRole(ctx context.Context, arg CreateRoleParams) (Role, e
 		arg.Name,
 		arg.Description,
 		arg.TenantID,
+		arg.ParentID,
 	)
 	var i Role
 	err := row.Scan(
@@ -246,12 +299,13 @@ func (q *Queries) CreateRole(ctx context.Context, arg CreateRoleParams) (Role, e
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
 
 const createTenant = `-- name: CreateTenant :one
-INSERT INTO tenants (id, name, slug, domain, settings) VALUES ($1, $2, $3, $4, $5) RETURNING id, name, slug, domain, settings, is_active, created_at, updated_at
+INSERT INTO tenants (id, name, slug, domain, settings) VALUES ($1, $2, $3, $4, $5) RETURNING id, name, slug, domain, settings, is_active, created_at, updated_at, suspended_at, deleted_at, purge_at
 `
 
 type CreateTenantParams struct {
@@ -280,13 +334,16 @@ func (q *Queries) CreateTenant(ctx context.Context, arg CreateTenantParams) (Ten
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SuspendedAt,
+		&i.DeletedAt,
+		&i.PurgeAt,
 	)
 	return i, err
 }
 
 const createUser = `-- name: CreateUser :one
 INSERT INTO users (id, email, password_hash, first_name, last_name, tenant_id) 
-VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at
+VALUES ($1, $2, $3, $4, $5, $6) RETURNING id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at, must_change_password, is_super_admin, is_service_account
 `
 
 type CreateUserParams struct {
@@ -318,6 +375,9 @@ func (q *Queries) CreateUser(ctx context.Context, arg CreateUserParams) (User, e
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MustChangePassword,
+		&i.IsSuperAdmin,
+		&i.IsServiceAccount,
 	)
 	return i, err
 }
@@ -378,7 +438,8 @@ func (q *Queries) DeleteUser(ctx context.Context, id uuid.UUID) error {
 
 const getAPIKeyByHash = `-- name: GetAPIKeyByHash :one
 
-SELECT id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at FROM api_keys WHERE key_hash = $1 AND is_active = true
+SELECT id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at, scopes, previous_key_hash, previous_key_expires_at, expiry_notified_at, rotated_at, rotation_notified_at FROM api_keys
+WHERE (key_hash = $1 OR (previous_key_hash = $1 AND previous_key_expires_at > NOW())) AND is_active = true
 `
 
 // Note: Customer queries removedm - customers are now managed through dynamic collections
@@ -397,12 +458,18 @@ func (q *Queries) GetAPIKeyByHash(ctx context.Context, keyHash string) (ApiKey,
 		&i.LastUsedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.Scopes),
+		&i.PreviousKeyHash,
+		&i.PreviousKeyExpiresAt,
+		&i.ExpiryNotifiedAt,
+		&i.RotatedAt,
+		&i.RotationNotifiedAt,
 	)
 	return i, err
 }
 
 const getAPIKeyByID = `-- name: GetAPIKeyByID :one
-SELECT id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at FROM api_keys WHERE id = $1
+SELECT id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at, scopes, previous_key_hash, previous_key_expires_at, expiry_notified_at, rotated_at, rotation_notified_at FROM api_keys WHERE id = $1
 `
 
 func (q *Queries) GetAPIKeyByID(ctx context.Context, id uuid.UUID) (ApiKey, error) {
@@ -418,12 +485,18 @@ func (q *Queries) GetAPIKeyByID(ctx context.Context, id uuid.UUID) (ApiKey, erro
 		&i.LastUsedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.Scopes),
+		&i.PreviousKeyHash,
+		&i.PreviousKeyExpiresAt,
+		&i.ExpiryNotifiedAt,
+		&i.RotatedAt,
+		&i.RotationNotifiedAt,
 	)
 	return i, err
 }
 
 const getAPIKeysByUser = `-- name: GetAPIKeysByUser :many
-SELECT id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC
+SELECT id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at, scopes, previous_key_hash, previous_key_expires_at, expiry_notified_at, rotated_at, rotation_notified_at FROM api_keys WHERE user_id = $1 ORDER BY created_at DESC
 `
 
 func (q *Queries) GetAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]ApiKey, error) {
@@ -445,6 +518,12 @@ func (q *Queries) GetAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]Api
 			&i.LastUsedAt,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			pq.Array(&i.Scopes),
+			&i.PreviousKeyHash,
+			&i.PreviousKeyExpiresAt,
+			&i.ExpiryNotifiedAt,
+			&i.RotatedAt,
+			&i.RotationNotifiedAt,
 		); err != nil {
 			return nil, err
 		}
@@ -460,7 +539,7 @@ func (q *Queries) GetAPIKeysByUser(ctx context.Context, userID uuid.UUID) ([]Api
 }
 
 const getAllTenants = `-- name: GetAllTenants :many
-SELECT id, name, slug, domain, settings, is_active, created_at, updated_at FROM tenants ORDER BY created_at
+SELECT id, name, slug, domain, settings, is_active, created_at, updated_at, suspended_at, deleted_at, purge_at FROM tenants ORDER BY created_at
 `
 
 // User-Tenant Relationship Queries
@@ -482,6 +561,9 @@ func (q *Queries) GetAllTenants(ctx context.Context) ([]Tenant, error) {
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SuspendedAt,
+			&i.DeletedAt,
+			&i.PurgeAt,
 		); err != nil {
 			return nil, err
 		}
@@ -497,7 +579,7 @@ func (q *Queries) GetAllTenants(ctx context.Context) ([]Tenant, error) {
 }
 
 const getCollection = `-- name: GetCollection :one
-SELECT id, name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at FROM collections WHERE id = $1
+SELECT id, name, slug, data_table_name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at, soft_delete, workflow, timestamps, user_stamps, sortable FROM collections WHERE id = $1
 `
 
 func (q *Queries) GetCollection(ctx context.Context, id uuid.UUID) (Collection, error) {
@@ -506,6 +588,8 @@ func (q *Queries) GetCollection(ctx context.Context, id uuid.UUID) (Collection,
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
+		&i.Slug,
+		&i.DataTableName,
 		&i.DisplayName,
 		&i.Description,
 		&i.Icon,
@@ -515,25 +599,32 @@ func (q *Queries) GetCollection(ctx context.Context, id uuid.UUID) (Collection,
 		&i.UpdatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SoftDelete,
+		&i.Workflow,
+		&i.Timestamps,
+		&i.UserStamps,
+		&i.Sortable,
 	)
 	return i, err
 }
 
 const getCollectionByNameAndTenant = `-- name: GetCollectionByNameAndTenant :one
-SELECT id, name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at FROM collections WHERE name = $1 AND tenant_id = $2
+SELECT id, name, slug, data_table_name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at, soft_delete, workflow, timestamps, user_stamps, sortable FROM collections WHERE slug = $1 AND tenant_id = $2
 `
 
 type GetCollectionByNameAndTenantParams struct {
-	Name     string        `json:"name"`
+	Slug     string        `json:"slug"`
 	TenantID uuid.NullUUID `json:"tenant_id"`
 }
 
 func (q *Queries) GetCollectionByNameAndTenant(ctx context.Context, arg GetCollectionByNameAndTenantParams) (Collection, error) {
-	row := q.db.QueryRowContext(ctx, getCollectionByNameAndTenant, arg.Name, arg.TenantID)
+	row := q.db.QueryRowContext(ctx, getCollectionByNameAndTenant, arg.Slug, arg.TenantID)
 	var i Collection
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
+		&i.Slug,
+		&i.DataTableName,
 		&i.DisplayName,
 		&i.Description,
 		&i.Icon,
@@ -543,12 +634,17 @@ func (q *Queries) GetCollectionByNameAndTenant(ctx context.Context, arg GetColle
 		&i.UpdatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SoftDelete,
+		&i.Workflow,
+		&i.Timestamps,
+		&i.UserStamps,
+		&i.Sortable,
 	)
 	return i, err
 }
 
 const getCollections = `-- name: GetCollections :many
-SELECT id, name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at FROM collections ORDER BY name
+SELECT id, name, slug, data_table_name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at, soft_delete, workflow, timestamps, user_stamps, sortable FROM collections ORDER BY name
 `
 
 // Schema Management Queries
@@ -564,6 +660,8 @@ func (q *Queries) GetCollections(ctx context.Context) ([]Collection, error) {
 		if err := rows.Scan(
 			&i.ID,
 			&i.Name,
+			&i.Slug,
+			&i.DataTableName,
 			&i.DisplayName,
 			&i.Description,
 			&i.Icon,
@@ -573,6 +671,11 @@ func (q *Queries) GetCollections(ctx context.Context) ([]Collection, error) {
 			&i.UpdatedBy,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SoftDelete,
+			&i.Workflow,
+			&i.Timestamps,
+			&i.UserStamps,
+			&i.Sortable,
 		); err != nil {
 			return nil, err
 		}
@@ -588,7 +691,7 @@ func (q *Queries) GetCollections(ctx context.Context) ([]Collection, error) {
 }
 
 const getField = `-- name: GetField :one
-SELECT id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at FROM fields WHERE id = $1
+SELECT id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at, is_indexed, computed_config, is_encrypted, pii FROM fields WHERE id = $1
 `
 
 func (q *Queries) GetField(ctx context.Context, id uuid.UUID) (Field, error) {
@@ -610,12 +713,16 @@ func (q *Queries) GetField(ctx context.Context, id uuid.UUID) (Field, error) {
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsIndexed,
+		&i.ComputedConfig,
+		&i.IsEncrypted,
+		&i.Pii,
 	)
 	return i, err
 }
 
 const getFields = `-- name: GetFields :many
-SELECT id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at FROM fields ORDER BY sort_order
+SELECT id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at, is_indexed, computed_config, is_encrypted, pii FROM fields ORDER BY sort_order
 `
 
 func (q *Queries) GetFields(ctx context.Context) ([]Field, error) {
@@ -643,6 +750,10 @@ func (q *Queries) GetFields(ctx context.Context) ([]Field, error) {
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsIndexed,
+			&i.ComputedConfig,
+			&i.IsEncrypted,
+			&i.Pii,
 		); err != nil {
 			return nil, err
 		}
@@ -658,7 +769,7 @@ func (q *Queries) GetFields(ctx context.Context) ([]Field, error) {
 }
 
 const getFieldsByCollection = `-- name: GetFieldsByCollection :many
-SELECT id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at FROM fields WHERE collection_id = $1 ORDER BY sort_order
+SELECT id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at, is_indexed, computed_config, is_encrypted, pii FROM fields WHERE collection_id = $1 ORDER BY sort_order
 `
 
 func (q *Queries) GetFieldsByCollection(ctx context.Context, collectionID uuid.NullUUID) ([]Field, error) {
@@ -686,6 +797,10 @@ func (q *Queries) GetFieldsByCollection(ctx context.Context, collectionID uuid.N
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.IsIndexed,
+			&i.ComputedConfig,
+			&i.IsEncrypted,
+			&i.Pii,
 		); err != nil {
 			return nil, err
 		}
@@ -701,7 +816,7 @@ func (q *Queries) GetFieldsByCollection(ctx context.Context, collectionID uuid.N
 }
 
 const getPermissionsByRole = `-- name: GetPermissionsByRole :many
-SELECT id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at FROM permissions WHERE role_id = $1
+SELECT id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at, read_fields, write_fields, scope, field_transforms FROM permissions WHERE role_id = $1
 `
 
 func (q *Queries) GetPermissionsByRole(ctx context.Context, roleID uuid.NullUUID) ([]Permission, error) {
@@ -723,6 +838,10 @@ func (q *Queries) GetPermissionsByRole(ctx context.Context, roleID uuid.NullUUID
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			pq.Array(&i.ReadFields),
+			pq.Array(&i.WriteFields),
+			&i.Scope,
+			&i.FieldTransforms,
 		); err != nil {
 			return nil, err
 		}
@@ -738,7 +857,7 @@ func (q *Queries) GetPermissionsByRole(ctx context.Context, roleID uuid.NullUUID
 }
 
 const getPermissionsByRoleAndAction = `-- name: GetPermissionsByRoleAndAction :many
-SELECT id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at FROM permissions WHERE role_id = $1 AND table_name = $2 AND action = $3
+SELECT id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at, read_fields, write_fields, scope, field_transforms FROM permissions WHERE role_id = $1 AND table_name = $2 AND action = $3
 `
 
 type GetPermissionsByRoleAndActionParams struct {
@@ -766,6 +885,10 @@ func (q *Queries) GetPermissionsByRoleAndAction(ctx context.Context, arg GetPerm
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			pq.Array(&i.ReadFields),
+			pq.Array(&i.WriteFields),
+			&i.Scope,
+			&i.FieldTransforms,
 		); err != nil {
 			return nil, err
 		}
@@ -781,7 +904,7 @@ func (q *Queries) GetPermissionsByRoleAndAction(ctx context.Context, arg GetPerm
 }
 
 const getPermissionsByRoleAndTable = `-- name: GetPermissionsByRoleAndTable :many
-SELECT id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at FROM permissions WHERE role_id = $1 AND table_name = $2
+SELECT id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at, read_fields, write_fields, scope, field_transforms FROM permissions WHERE role_id = $1 AND table_name = $2
 `
 
 type GetPermissionsByRoleAndTableParams struct {
@@ -808,6 +931,10 @@ func (q *Queries) GetPermissionsByRoleAndTable(ctx context.Context, arg GetPermi
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			pq.Array(&i.ReadFields),
+			pq.Array(&i.WriteFields),
+			&i.Scope,
+			&i.FieldTransforms,
 		); err != nil {
 			return nil, err
 		}
@@ -823,7 +950,7 @@ func (q *Queries) GetPermissionsByRoleAndTable(ctx context.Context, arg GetPermi
 }
 
 const getPermissionsByRoleAndTenant = `-- name: GetPermissionsByRoleAndTenant :many
-SELECT id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at FROM permissions WHERE role_id = $1 AND tenant_id = $2
+SELECT id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at, read_fields, write_fields, scope, field_transforms FROM permissions WHERE role_id = $1 AND tenant_id = $2
 `
 
 type GetPermissionsByRoleAndTenantParams struct {
@@ -851,6 +978,10 @@ func (q *Queries) GetPermissionsByRoleAndTenant(ctx context.Context, arg GetPerm
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			pq.Array(&i.ReadFields),
+			pq.Array(&i.WriteFields),
+			&i.Scope,
+			&i.FieldTransforms,
 		); err != nil {
 			return nil, err
 		}
@@ -866,7 +997,7 @@ func (q *Queries) GetPermissionsByRoleAndTenant(ctx context.Context, arg GetPerm
 }
 
 const getPermissionsByUserAndTenant = `-- name: GetPermissionsByUserAndTenant :many
-SELECT p.id, p.role_id, p.table_name, p.action, p.field_filter, p.allowed_fields, p.tenant_id, p.created_at, p.updated_at FROM permissions p
+SELECT p.id, p.role_id, p.table_name, p.action, p.field_filter, p.allowed_fields, p.tenant_id, p.created_at, p.updated_at, p.read_fields, p.write_fields, p.scope, p.field_transforms FROM permissions p
 JOIN user_roles ur ON p.role_id = ur.role_id
 WHERE ur.user_id = $1 AND p.tenant_id = $2
 `
@@ -895,6 +1026,10 @@ func (q *Queries) GetPermissionsByUserAndTenant(ctx context.Context, arg GetPerm
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			pq.Array(&i.ReadFields),
+			pq.Array(&i.WriteFields),
+			&i.Scope,
+			&i.FieldTransforms,
 		); err != nil {
 			return nil, err
 		}
@@ -909,8 +1044,27 @@ func (q *Queries) GetPermissionsByUserAndTenant(ctx context.Context, arg GetPerm
 	return items, nil
 }
 
+const getRoleByID = `-- name: GetRoleByID :one
+SELECT id, name, description, tenant_id, created_at, updated_at, parent_id FROM roles WHERE id = $1
+`
+
+func (q *Queries) GetRoleByID(ctx context.Context, id uuid.UUID) (Role, error) {
+	row := q.db.QueryRowContext(ctx, getRoleByID, id)
+	var i Role
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.Description,
+		&i.TenantID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.ParentID,
+	)
+	return i, err
+}
+
 const getRoleByNameAndTenant = `-- name: GetRoleByNameAndTenant :one
-SELECT id, name, description, tenant_id, created_at, updated_at FROM roles WHERE name = $1 AND tenant_id = $2
+SELECT id, name, description, tenant_id, created_at, updated_at, parent_id FROM roles WHERE name = $1 AND tenant_id = $2
 `
 
 type GetRoleByNameAndTenantParams struct {
@@ -928,12 +1082,13 @@ func (q *Queries) GetRoleByNameAndTenant(ctx context.Context, arg GetRoleByNameA
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.ParentID,
 	)
 	return i, err
 }
 
 const getRolesByTenant = `-- name: GetRolesByTenant :many
-SELECT id, name, description, tenant_id, created_at, updated_at FROM roles WHERE tenant_id = $1 ORDER BY name
+SELECT id, name, description, tenant_id, created_at, updated_at, parent_id FROM roles WHERE tenant_id = $1 ORDER BY name
 `
 
 func (q *Queries) GetRolesByTenant(ctx context.Context, tenantID uuid.NullUUID) ([]Role, error) {
@@ -952,6 +1107,7 @@ func (q *Queries) GetRolesByTenant(ctx context.Context, tenantID uuid.NullUUID)
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ParentID,
 		); err != nil {
 			return nil, err
 		}
@@ -967,7 +1123,7 @@ func (q *Queries) GetRolesByTenant(ctx context.Context, tenantID uuid.NullUUID)
 }
 
 const getTenant = `-- name: GetTenant :one
-SELECT id, name, slug, domain, settings, is_active, created_at, updated_at FROM tenants WHERE id = $1
+SELECT id, name, slug, domain, settings, is_active, created_at, updated_at, suspended_at, deleted_at, purge_at FROM tenants WHERE id = $1
 `
 
 func (q *Queries) GetTenant(ctx context.Context, id uuid.UUID) (Tenant, error) {
@@ -982,12 +1138,15 @@ func (q *Queries) GetTenant(ctx context.Context, id uuid.UUID) (Tenant, error) {
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SuspendedAt,
+		&i.DeletedAt,
+		&i.PurgeAt,
 	)
 	return i, err
 }
 
 const getTenantByID = `-- name: GetTenantByID :one
-SELECT id, name, slug, domain, settings, is_active, created_at, updated_at FROM tenants WHERE id = $1
+SELECT id, name, slug, domain, settings, is_active, created_at, updated_at, suspended_at, deleted_at, purge_at FROM tenants WHERE id = $1
 `
 
 func (q *Queries) GetTenantByID(ctx context.Context, id uuid.UUID) (Tenant, error) {
@@ -1002,12 +1161,15 @@ func (q *Queries) GetTenantByID(ctx context.Context, id uuid.UUID) (Tenant, erro
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SuspendedAt,
+		&i.DeletedAt,
+		&i.PurgeAt,
 	)
 	return i, err
 }
 
 const getTenantBySlug = `-- name: GetTenantBySlug :one
-SELECT id, name, slug, domain, settings, is_active, created_at, updated_at FROM tenants WHERE slug = $1
+SELECT id, name, slug, domain, settings, is_active, created_at, updated_at, suspended_at, deleted_at, purge_at FROM tenants WHERE slug = $1
 `
 
 func (q *Queries) GetTenantBySlug(ctx context.Context, slug string) (Tenant, error) {
@@ -1022,12 +1184,15 @@ func (q *Queries) GetTenantBySlug(ctx context.Context, slug string) (Tenant, err
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SuspendedAt,
+		&i.DeletedAt,
+		&i.PurgeAt,
 	)
 	return i, err
 }
 
 const getTenants = `-- name: GetTenants :many
-SELECT id, name, slug, domain, settings, is_active, created_at, updated_at FROM tenants ORDER BY name
+SELECT id, name, slug, domain, settings, is_active, created_at, updated_at, suspended_at, deleted_at, purge_at FROM tenants ORDER BY name
 `
 
 // Tenant Management Queries
@@ -1049,6 +1214,9 @@ func (q *Queries) GetTenants(ctx context.Context) ([]Tenant, error) {
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SuspendedAt,
+			&i.DeletedAt,
+			&i.PurgeAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1064,7 +1232,7 @@ func (q *Queries) GetTenants(ctx context.Context) ([]Tenant, error) {
 }
 
 const getUserByEmail = `-- name: GetUserByEmail :one
-SELECT id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at FROM users WHERE email = $1
+SELECT id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at, must_change_password, is_super_admin, is_service_account FROM users WHERE email = $1
 `
 
 func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error) {
@@ -1080,12 +1248,15 @@ func (q *Queries) GetUserByEmail(ctx context.Context, email string) (User, error
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MustChangePassword,
+		&i.IsSuperAdmin,
+		&i.IsServiceAccount,
 	)
 	return i, err
 }
 
 const getUserByID = `-- name: GetUserByID :one
-SELECT id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at FROM users WHERE id = $1
+SELECT id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at, must_change_password, is_super_admin, is_service_account FROM users WHERE id = $1
 `
 
 func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
@@ -1101,14 +1272,17 @@ func (q *Queries) GetUserByID(ctx context.Context, id uuid.UUID) (User, error) {
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MustChangePassword,
+		&i.IsSuperAdmin,
+		&i.IsServiceAccount,
 	)
 	return i, err
 }
 
 const getUserDefaultTenant = `-- name: GetUserDefaultTenant :one
-SELECT t.id, t.name, t.slug, t.domain, t.settings, t.is_active, t.created_at, t.updated_at FROM tenants t 
+SELECT t.id, t.name, t.slug, t.domain, t.settings, t.is_active, t.created_at, t.updated_at, t.suspended_at, t.deleted_at, t.purge_at FROM tenants t 
 JOIN user_tenants ut ON t.id = ut.tenant_id 
-WHERE ut.user_id = $1 AND ut.is_active = true 
+WHERE ut.user_id = $1 AND ut.is_active = true AND t.deleted_at IS NULL 
 ORDER BY ut.created_at LIMIT 1
 `
 
@@ -1124,12 +1298,15 @@ func (q *Queries) GetUserDefaultTenant(ctx context.Context, userID uuid.UUID) (T
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SuspendedAt,
+		&i.DeletedAt,
+		&i.PurgeAt,
 	)
 	return i, err
 }
 
 const getUserRoles = `-- name: GetUserRoles :many
-SELECT r.id, r.name, r.description, r.tenant_id, r.created_at, r.updated_at FROM roles r
+SELECT r.id, r.name, r.description, r.tenant_id, r.created_at, r.updated_at, r.parent_id FROM roles r
 JOIN user_roles ur ON r.id = ur.role_id
 WHERE ur.user_id = $1
 `
@@ -1150,6 +1327,7 @@ func (q *Queries) GetUserRoles(ctx context.Context, userID uuid.UUID) ([]Role, e
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.ParentID,
 		); err != nil {
 			return nil, err
 		}
@@ -1187,9 +1365,9 @@ func (q *Queries) GetUserTenant(ctx context.Context, arg GetUserTenantParams) (U
 }
 
 const getUserTenants = `-- name: GetUserTenants :many
-SELECT t.id, t.name, t.slug, t.domain, t.settings, t.is_active, t.created_at, t.updated_at FROM tenants t 
+SELECT t.id, t.name, t.slug, t.domain, t.settings, t.is_active, t.created_at, t.updated_at, t.suspended_at, t.deleted_at, t.purge_at FROM tenants t 
 JOIN user_tenants ut ON t.id = ut.tenant_id 
-WHERE ut.user_id = $1 AND ut.is_active = true 
+WHERE ut.user_id = $1 AND ut.is_active = true AND t.deleted_at IS NULL 
 ORDER BY ut.created_at
 `
 
@@ -1211,6 +1389,9 @@ func (q *Queries) GetUserTenants(ctx context.Context, userID uuid.UUID) ([]Tenan
 			&i.IsActive,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.SuspendedAt,
+			&i.DeletedAt,
+			&i.PurgeAt,
 		); err != nil {
 			return nil, err
 		}
@@ -1226,24 +1407,27 @@ func (q *Queries) GetUserTenants(ctx context.Context, userID uuid.UUID) ([]Tenan
 }
 
 const getUserWithTenant = `-- name: GetUserWithTenant :one
-SELECT u.id, u.email, u.password_hash, u.first_name, u.last_name, u.is_active, u.tenant_id, u.created_at, u.updated_at, t.name as tenant_name, t.slug as tenant_slug 
+SELECT u.id, u.email, u.password_hash, u.first_name, u.last_name, u.is_active, u.tenant_id, u.created_at, u.updated_at, u.must_change_password, u.is_super_admin, u.is_service_account, t.name as tenant_name, t.slug as tenant_slug 
 FROM users u 
 JOIN tenants t ON u.tenant_id = t.id 
 WHERE u.id = $1
 `
 
 type GetUserWithTenantRow struct {
-	ID           uuid.UUID      `json:"id"`
-	Email        string         `json:"email"`
-	PasswordHash string         `json:"password_hash"`
-	FirstName    sql.NullString `json:"first_name"`
-	LastName     sql.NullString `json:"last_name"`
-	IsActive     sql.NullBool   `json:"is_active"`
-	TenantID     uuid.NullUUID  `json:"tenant_id"`
-	CreatedAt    sql.NullTime   `json:"created_at"`
-	UpdatedAt    sql.NullTime   `json:"updated_at"`
-	TenantName   string         `json:"tenant_name"`
-	TenantSlug   string         `json:"tenant_slug"`
+	ID                 uuid.UUID      `json:"id"`
+	Email              string         `json:"email"`
+	PasswordHash       string         `json:"password_hash"`
+	FirstName          sql.NullString `json:"first_name"`
+	LastName           sql.NullString `json:"last_name"`
+	IsActive           sql.NullBool   `json:"is_active"`
+	TenantID           uuid.NullUUID  `json:"tenant_id"`
+	CreatedAt          sql.NullTime   `json:"created_at"`
+	UpdatedAt          sql.NullTime   `json:"updated_at"`
+	MustChangePassword bool           `json:"must_change_password"`
+	IsSuperAdmin       bool           `json:"is_super_admin"`
+	IsServiceAccount   bool           `json:"is_service_account"`
+	TenantName         string         `json:"tenant_name"`
+	TenantSlug         string         `json:"tenant_slug"`
 }
 
 func (q *Queries) GetUserWithTenant(ctx context.Context, id uuid.UUID) (GetUserWithTenantRow, error) {
@@ -1259,6 +1443,9 @@ func (q *Queries) GetUserWithTenant(ctx context.Context, id uuid.UUID) (GetUserW
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MustChangePassword,
+		&i.IsSuperAdmin,
+		&i.IsServiceAccount,
 		&i.TenantName,
 		&i.TenantSlug,
 	)
@@ -1266,7 +1453,7 @@ func (q *Queries) GetUserWithTenant(ctx context.Context, id uuid.UUID) (GetUserW
 }
 
 const getUsersByTenant = `-- name: GetUsersByTenant :many
-SELECT id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at FROM users WHERE tenant_id = $1 ORDER BY email
+SELECT id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at, must_change_password, is_super_admin, is_service_account FROM users WHERE tenant_id = $1 ORDER BY email
 `
 
 // Enhanced User Queries with Tenant Support
@@ -1289,6 +1476,9 @@ func (q *Queries) GetUsersByTenant(ctx context.Context, tenantID uuid.NullUUID)
 			&i.TenantID,
 			&i.CreatedAt,
 			&i.UpdatedAt,
+			&i.MustChangePassword,
+			&i.IsSuperAdmin,
+			&i.IsServiceAccount,
 		); err != nil {
 			return nil, err
 		}
@@ -1318,7 +1508,11 @@ func (q *Queries) RemoveUserFromTenant(ctx context.Context, arg RemoveUserFromTe
 }
 
 const updateAPIKey = `-- name: UpdateAPIKey :one
-UPDATE api_keys SET name = $2, is_active = $3, expires_at = $4, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at
+UPDATE api_keys
+SET name = $2, is_active = $3, expires_at = $4, scopes = $5,
+    expiry_notified_at = CASE WHEN expires_at IS DISTINCT FROM $4 THEN NULL ELSE expiry_notified_at END,
+    updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 RETURNING id, user_id, name, key_hash, is_active, expires_at, last_used_at, created_at, updated_at, scopes, previous_key_hash, previous_key_expires_at, expiry_notified_at, rotated_at, rotation_notified_at
 `
 
 type UpdateAPIKeyParams struct {
@@ -1326,6 +1520,7 @@ type UpdateAPIKeyParams struct {
 	Name      string       `json:"name"`
 	IsActive  sql.NullBool `json:"is_active"`
 	ExpiresAt sql.NullTime `json:"expires_at"`
+	Scopes    []string     `json:"scopes"`
 }
 
 func (q *Queries) UpdateAPIKey(ctx context.Context, arg UpdateAPIKeyParams) (ApiKey, error) {
@@ -1334,6 +1529,7 @@ func (q *Queries) UpdateAPIKey(ctx context.Context, arg UpdateAPIKeyParams) (Api
 		arg.Name,
 		arg.IsActive,
 		arg.ExpiresAt,
+		pq.Array(arg.Scopes),
 	)
 	var i ApiKey
 	err := row.Scan(
@@ -1346,6 +1542,12 @@ func (q *Queries) UpdateAPIKey(ctx context.Context, arg UpdateAPIKeyParams) (Api
 		&i.LastUsedAt,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.Scopes),
+		&i.PreviousKeyHash,
+		&i.PreviousKeyExpiresAt,
+		&i.ExpiryNotifiedAt,
+		&i.RotatedAt,
+		&i.RotationNotifiedAt,
 	)
 	return i, err
 }
@@ -1361,8 +1563,8 @@ func (q *Queries) UpdateAPIKeyLastUsed(ctx context.Context, id uuid.UUID) error
 
 const updateCollection = `-- name: UpdateCollection :one
 UPDATE collections 
-SET display_name = $2, description = $3, icon = $4, updated_at = CURRENT_TIMESTAMP, updated_by = $5
-WHERE id = $1 RETURNING id, name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at
+SET display_name = $2, description = $3, icon = $4, updated_at = CURRENT_TIMESTAMP, updated_by = $5, soft_delete = $6, workflow = $7, timestamps = $8, user_stamps = $9, sortable = $10
+WHERE id = $1 RETURNING id, name, slug, data_table_name, display_name, description, icon, is_system, tenant_id, created_by, updated_by, created_at, updated_at, soft_delete, workflow, timestamps, user_stamps, sortable
 `
 
 type UpdateCollectionParams struct {
@@ -1371,6 +1573,11 @@ type UpdateCollectionParams struct {
 	Description sql.NullString `json:"description"`
 	Icon        sql.NullString `json:"icon"`
 	UpdatedBy   uuid.NullUUID  `json:"updated_by"`
+	SoftDelete  bool           `json:"soft_delete"`
+	Workflow    bool           `json:"workflow"`
+	Timestamps  bool           `json:"timestamps"`
+	UserStamps  bool           `json:"user_stamps"`
+	Sortable    bool           `json:"sortable"`
 }
 
 func (q *Queries) UpdateCollection(ctx context.Context, arg UpdateCollectionParams) (Collection, error) {
@@ -1380,11 +1587,18 @@ func (q *Queries) UpdateCollection(ctx context.Context, arg UpdateCollectionPara
 		arg.Description,
 		arg.Icon,
 		arg.UpdatedBy,
+		arg.SoftDelete,
+		arg.Workflow,
+		arg.Timestamps,
+		arg.UserStamps,
+		arg.Sortable,
 	)
 	var i Collection
 	err := row.Scan(
 		&i.ID,
 		&i.Name,
+		&i.Slug,
+		&i.DataTableName,
 		&i.DisplayName,
 		&i.Description,
 		&i.Icon,
@@ -1394,14 +1608,19 @@ func (q *Queries) UpdateCollection(ctx context.Context, arg UpdateCollectionPara
 		&i.UpdatedBy,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SoftDelete,
+		&i.Workflow,
+		&i.Timestamps,
+		&i.UserStamps,
+		&i.Sortable,
 	)
 	return i, err
 }
 
 const updateField = `-- name: UpdateField :one
 UPDATE fields 
-SET display_name = $2, type = $3, is_primary = $4, is_required = $5, is_unique = $6, default_value = $7, validation_rules = $8, relation_config = $9, sort_order = $10, updated_at = CURRENT_TIMESTAMP
-WHERE id = $1 RETURNING id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at
+SET display_name = $2, type = $3, is_primary = $4, is_required = $5, is_unique = $6, default_value = $7, validation_rules = $8, relation_config = $9, sort_order = $10, is_indexed = $11, computed_config = $12, is_encrypted = $13, pii = $14, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 RETURNING id, collection_id, name, display_name, type, is_primary, is_required, is_unique, default_value, validation_rules, sort_order, relation_config, tenant_id, created_at, updated_at, is_indexed, computed_config, is_encrypted, pii
 `
 
 type UpdateFieldParams struct {
@@ -1415,6 +1634,10 @@ type UpdateFieldParams struct {
 	ValidationRules pqtype.NullRawMessage `json:"validation_rules"`
 	RelationConfig  pqtype.NullRawMessage `json:"relation_config"`
 	SortOrder       sql.NullInt32         `json:"sort_order"`
+	IsIndexed       bool                  `json:"is_indexed"`
+	ComputedConfig  pqtype.NullRawMessage `json:"computed_config"`
+	IsEncrypted     bool                  `json:"is_encrypted"`
+	Pii             sql.NullString        `json:"pii"`
 }
 
 func (q *Queries) UpdateField(ctx context.Context, arg UpdateFieldParams) (Field, error) {
@@ -1429,6 +1652,10 @@ func (q *Queries) UpdateField(ctx context.Context, arg UpdateFieldParams) (Field
 		arg.ValidationRules,
 		arg.RelationConfig,
 		arg.SortOrder,
+		arg.IsIndexed,
+		arg.ComputedConfig,
+		arg.IsEncrypted,
+		arg.Pii,
 	)
 	var i Field
 	err := row.Scan(
@@ -1447,24 +1674,40 @@ func (q *Queries) UpdateField(ctx context.Context, arg UpdateFieldParams) (Field
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.IsIndexed,
+		&i.ComputedConfig,
+		&i.IsEncrypted,
+		&i.Pii,
 	)
 	return i, err
 }
 
 const updatePermission = `-- name: UpdatePermission :one
 UPDATE permissions 
-SET field_filter = $2, allowed_fields = $3, updated_at = CURRENT_TIMESTAMP 
-WHERE id = $1 RETURNING id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at
+SET field_filter = $2, allowed_fields = $3, read_fields = $4, write_fields = $5, scope = $6, field_transforms = $7, updated_at = CURRENT_TIMESTAMP 
+WHERE id = $1 RETURNING id, role_id, table_name, action, field_filter, allowed_fields, tenant_id, created_at, updated_at, read_fields, write_fields, scope, field_transforms
 `
 
 type UpdatePermissionParams struct {
-	ID            uuid.UUID             `json:"id"`
-	FieldFilter   pqtype.NullRawMessage `json:"field_filter"`
-	AllowedFields []string              `json:"allowed_fields"`
+	ID              uuid.UUID             `json:"id"`
+	FieldFilter     pqtype.NullRawMessage `json:"field_filter"`
+	AllowedFields   []string              `json:"allowed_fields"`
+	ReadFields      []string              `json:"read_fields"`
+	WriteFields     []string              `json:"write_fields"`
+	Scope           string                `json:"scope"`
+	FieldTransforms pqtype.NullRawMessage `json:"field_transforms"`
 }
 
 func (q *Queries) UpdatePermission(ctx context.Context, arg UpdatePermissionParams) (Permission, error) {
-	row := q.db.QueryRowContext(ctx, updatePermission, arg.ID, arg.FieldFilter, pq.Array(arg.AllowedFields))
+	row := q.db.QueryRowContext(ctx, updatePermission,
+		arg.ID,
+		arg.FieldFilter,
+		pq.Array(arg.AllowedFields),
+		pq.Array(arg.ReadFields),
+		pq.Array(arg.WriteFields),
+		arg.Scope,
+		arg.FieldTransforms,
+	)
 	var i Permission
 	err := row.Scan(
 		&i.ID,
@@ -1476,12 +1719,16 @@ func (q *Queries) UpdatePermission(ctx context.Context, arg UpdatePermissionPara
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		pq.Array(&i.ReadFields),
+		pq.Array(&i.WriteFields),
+		&i.Scope,
+		&i.FieldTransforms,
 	)
 	return i, err
 }
 
 const updateTenant = `-- name: UpdateTenant :one
-UPDATE tenants SET name = $2, slug = $3, domain = $4, settings = $5, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING id, name, slug, domain, settings, is_active, created_at, updated_at
+UPDATE tenants SET name = $2, slug = $3, domain = $4, settings = $5, updated_at = CURRENT_TIMESTAMP WHERE id = $1 RETURNING id, name, slug, domain, settings, is_active, created_at, updated_at, suspended_at, deleted_at, purge_at
 `
 
 type UpdateTenantParams struct {
@@ -1510,6 +1757,9 @@ func (q *Queries) UpdateTenant(ctx context.Context, arg UpdateTenantParams) (Ten
 		&i.IsActive,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.SuspendedAt,
+		&i.DeletedAt,
+		&i.PurgeAt,
 	)
 	return i, err
 }
@@ -1517,7 +1767,7 @@ func (q *Queries) UpdateTenant(ctx context.Context, arg UpdateTenantParams) (Ten
 const updateUser = `-- name: UpdateUser :one
 UPDATE users 
 SET email = $2, first_name = $3, last_name = $4, is_active = $5, updated_at = CURRENT_TIMESTAMP 
-WHERE id = $1 RETURNING id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at
+WHERE id = $1 RETURNING id, email, password_hash, first_name, last_name, is_active, tenant_id, created_at, updated_at, must_change_password, is_super_admin, is_service_account
 `
 
 type UpdateUserParams struct {
@@ -1547,6 +1797,9 @@ func (q *Queries) UpdateUser(ctx context.Context, arg UpdateUserParams) (User, e
 		&i.TenantID,
 		&i.CreatedAt,
 		&i.UpdatedAt,
+		&i.MustChangePassword,
+		&i.IsSuperAdmin,
+		&i.IsServiceAccount,
 	)
 	return i, err
 }
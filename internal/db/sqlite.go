@@ -0,0 +1,613 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// SQLite runs Basin on a single SQLite file, for development and embedded use. Statements
+// written for Postgres are translated by the basin-sqlite driver (see sqlite_rewrite.go);
+// what SQLite cannot do, it reports:
+//   - Tables live in one namespace, so schema-qualified tables become "<schema>__<table>"
+//   - UUIDs are stored as text and generated by uuid_generate_v4() and gen_random_uuid()
+//   - There is no row-level security; set_user_context only feeds current_setting
+var SQLite Dialect = sqliteDialect{}
+
+// sqliteDriverName is the database/sql driver of SQLite databases
+const sqliteDriverName = "basin-sqlite"
+
+// sqliteTimeFormat is how times are written, which sorts like the times it holds as long
+// as they are in UTC
+const sqliteTimeFormat = "2006-01-02 15:04:05.999999999-07:00"
+
+// sqliteTimeFormats are the formats times are read in: Basin's, then the ones SQLite's
+// own date functions and CURRENT_TIMESTAMP produce
+var sqliteTimeFormats = []string{
+	sqliteTimeFormat,
+	time.RFC3339Nano,
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	"2006-01-02",
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) Name() string { return DriverSQLite }
+
+func (sqliteDialect) Table(schema, name string) string {
+	return fmt.Sprintf(`"%s"`, sqliteTableName(schema, name))
+}
+
+// Index names are global to the database rather than to a schema
+func (sqliteDialect) Index(schema, name string) string {
+	return fmt.Sprintf(`"%s"`, name)
+}
+
+// sqliteArrayTypes are the Postgres names of arrays, as lib/pq reports array columns
+var sqliteArrayTypes = map[string]string{
+	"TEXT[]":    "_TEXT",
+	"VARCHAR[]": "_VARCHAR",
+	"UUID[]":    "_UUID",
+	"BIGINT[]":  "_INT8",
+	"INTEGER[]": "_INT4",
+}
+
+func (sqliteDialect) ColumnType(definition string) string {
+	for array, name := range sqliteArrayTypes {
+		definition = strings.ReplaceAll(definition, array, name)
+	}
+	// Foreign keys cannot name a schema; everything is in one
+	return strings.ReplaceAll(definition, "REFERENCES public.", "REFERENCES ")
+}
+
+func (sqliteDialect) TableExists(ctx context.Context, q Executor, schema, name string) (bool, error) {
+	var exists bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (SELECT 1 FROM sqlite_master WHERE type = 'table' AND name = $1)
+	`, sqliteTableName(schema, name)).Scan(&exists)
+	return exists, err
+}
+
+func (sqliteDialect) UniqueColumn(ctx context.Context, q Executor, table, column string) (bool, error) {
+	var unique bool
+	err := q.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM pragma_index_list($1) l
+			WHERE l."unique" AND (SELECT COUNT(*) FROM pragma_index_info(l.name)) = 1
+			AND (SELECT name FROM pragma_index_info(l.name)) = $2
+		)`, strings.Trim(table, `"`), column).Scan(&unique)
+	return unique, err
+}
+
+// Index names are global, so the schemas do not narrow them down. SQLite indexes are
+// always valid.
+func (sqliteDialect) Indexes(ctx context.Context, q Executor, prefix string, schemas []string) (map[string]bool, error) {
+	rows, err := q.QueryContext(ctx, `
+		SELECT name, true FROM sqlite_master
+		WHERE type = 'index' AND substr(name, 1, length($1)) = $1`, prefix)
+	if err != nil {
+		return nil, err
+	}
+	return scanIndexes(rows)
+}
+
+func (sqliteDialect) RowLevelSecurity() bool { return false }
+
+func (sqliteDialect) RowJSON() bool { return false }
+
+func (sqliteDialect) FullTextSearch() bool { return false }
+
+func (sqliteDialect) CreatesDataTables() bool { return false }
+
+// sqliteTableName returns the name table name of schema has in SQLite's single namespace.
+// Tables of the public schema keep their name.
+func sqliteTableName(schema, name string) string {
+	if schema == "" || schema == "public" {
+		return name
+	}
+	return schema + "__" + name
+}
+
+// OpenSQLite opens the SQLite database in the file at path, creating it when missing
+func OpenSQLite(pool PoolConfig, path string) (*DB, error) {
+	if path == "" || path == ":memory:" {
+		// Every connection of a pool would get a database of its own
+		return nil, fmt.Errorf("SQLite needs a database file")
+	}
+
+	// Foreign keys are off by default in SQLite. Transactions take the write lock when
+	// they begin, as two that read first and write later would fail on one another.
+	params := url.Values{}
+	params.Add("_pragma", "foreign_keys(1)")
+	params.Add("_pragma", "journal_mode(WAL)")
+	params.Add("_pragma", "busy_timeout(10000)")
+	params.Set("_time_format", "sqlite")
+	params.Set("_txlock", "immediate")
+
+	conn, err := sql.Open(sqliteDriverName, "file:"+path+"?"+params.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	pool.apply(conn)
+	if err := conn.Ping(); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+
+	return &DB{
+		DB:      conn,
+		Queries: sqlc.New(conn),
+		dialect: SQLite,
+		read:    newReadPool(conn, nil),
+	}, nil
+}
+
+func init() {
+	// Postgres functions Basin's statements call
+	sqlite.MustRegisterScalarFunction("now", 0, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return time.Now().UTC().Format(sqliteTimeFormat), nil
+	})
+	newUUID := func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return uuid.NewString(), nil
+	}
+	sqlite.MustRegisterScalarFunction("uuid_generate_v4", 0, newUUID)
+	sqlite.MustRegisterScalarFunction("gen_random_uuid", 0, newUUID)
+	// The context it sets is kept by the connection (see sqliteConn.settings)
+	sqlite.MustRegisterScalarFunction("set_user_context", -1, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+		return nil, nil
+	})
+	// Notifications only matter between instances, of which SQLite has one (see
+	// listen.go), and advisory locks are not needed with a single writer
+	for name, args := range map[string]int32{"pg_notify": 2, "pg_advisory_lock": 1, "pg_advisory_unlock": 1} {
+		sqlite.MustRegisterScalarFunction(name, args, func(*sqlite.FunctionContext, []driver.Value) (driver.Value, error) {
+			return nil, nil
+		})
+	}
+
+	// Arrays are stored in the text form of Postgres arrays; these stand in for = ANY,
+	// @>, && and unnest
+	sqlite.MustRegisterDeterministicScalarFunction("array_contains_all", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		have, want, err := sqliteArrays(args)
+		if err != nil || have == nil {
+			return nil, err
+		}
+		for _, element := range want {
+			if !have[element] {
+				return false, nil
+			}
+		}
+		return true, nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("array_overlaps", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		have, want, err := sqliteArrays(args)
+		if err != nil || have == nil {
+			return nil, err
+		}
+		for _, element := range want {
+			if have[element] {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("array_contains", 2, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		elements, err := sqliteArray(args[0])
+		if err != nil || args[1] == nil {
+			return nil, err
+		}
+		value := fmt.Sprint(args[1])
+		for _, element := range elements {
+			if element == value {
+				return true, nil
+			}
+		}
+		return false, nil
+	})
+	sqlite.MustRegisterDeterministicScalarFunction("array_to_json", 1, func(_ *sqlite.FunctionContext, args []driver.Value) (driver.Value, error) {
+		elements, err := sqliteArray(args[0])
+		if err != nil {
+			return nil, err
+		}
+		encoded, err := json.Marshal(elements)
+		return string(encoded), err
+	})
+
+	sql.Register(sqliteDriverName, &sqliteDriver{base: sqliteBaseDriver()})
+}
+
+// sqliteArray parses the text form of a Postgres array
+func sqliteArray(value driver.Value) ([]string, error) {
+	var elements pq.StringArray
+	if value == nil {
+		return nil, nil
+	}
+	if err := elements.Scan(fmt.Sprint(value)); err != nil {
+		return nil, fmt.Errorf("not an array: %w", err)
+	}
+	return elements, nil
+}
+
+// sqliteArrays parses the two array arguments of an array operator into the set of
+// elements of the first and the elements of the second, or nils when either is NULL
+func sqliteArrays(args []driver.Value) (map[string]bool, []string, error) {
+	if args[0] == nil || args[1] == nil {
+		return nil, nil, nil
+	}
+	elements, err := sqliteArray(args[0])
+	if err != nil {
+		return nil, nil, err
+	}
+	want, err := sqliteArray(args[1])
+	if err != nil {
+		return nil, nil, err
+	}
+	have := make(map[string]bool, len(elements))
+	for _, element := range elements {
+		have[element] = true
+	}
+	return have, want, nil
+}
+
+// sqliteBaseDriver returns the driver modernc.org/sqlite registers, which is the one
+// adding the functions above to its connections
+func sqliteBaseDriver() driver.Driver {
+	conn, err := sql.Open("sqlite", "")
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+	return conn.Driver()
+}
+
+// sqliteDriver opens connections of the driver registered by modernc.org/sqlite that
+// translate statements written for Postgres
+type sqliteDriver struct {
+	base driver.Driver
+}
+
+func (d *sqliteDriver) Open(dsn string) (driver.Conn, error) {
+	conn, err := d.base.Open(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteConn{base: conn.(sqliteBaseConn)}, nil
+}
+
+// sqliteBaseConn is what connections of modernc.org/sqlite implement
+type sqliteBaseConn interface {
+	driver.Conn
+	driver.ConnBeginTx
+	driver.ConnPrepareContext
+	driver.ExecerContext
+	driver.QueryerContext
+	driver.Pinger
+	driver.SessionResetter
+	driver.Validator
+}
+
+// sqliteConn is a connection translating statements before they run. The settings of
+// set_user_context last until the transaction setting them ends, as in Postgres.
+type sqliteConn struct {
+	base     sqliteBaseConn
+	inTx     bool
+	settings map[string]string
+}
+
+var (
+	_ driver.NamedValueChecker = (*sqliteConn)(nil)
+	_ driver.SessionResetter   = (*sqliteConn)(nil)
+)
+
+func (c *sqliteConn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *sqliteConn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	stmt, err := c.base.PrepareContext(ctx, rewrite(query, c.settings))
+	if err != nil {
+		return nil, err
+	}
+	return &sqliteStmt{Stmt: stmt, query: query}, nil
+}
+
+func (c *sqliteConn) Close() error { return c.base.Close() }
+
+func (c *sqliteConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *sqliteConn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	// SQLite transactions are serializable whatever the level asked for
+	opts.Isolation = driver.IsolationLevel(sql.LevelDefault)
+	tx, err := c.base.BeginTx(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	c.inTx = true
+	return &sqliteTx{Tx: tx, conn: c}, nil
+}
+
+func (c *sqliteConn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	c.setUserContext(query, args)
+	query, skip, err := c.alterColumn(ctx, query)
+	if err != nil || skip {
+		return driver.RowsAffected(0), err
+	}
+	result, err := c.base.ExecContext(ctx, rewrite(query, c.settings), args)
+	return result, sqliteError(query, err)
+}
+
+func (c *sqliteConn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	c.setUserContext(query, args)
+	rows, err := c.base.QueryContext(ctx, rewrite(query, c.settings), args)
+	if err != nil {
+		return nil, sqliteError(query, err)
+	}
+	return newSQLiteRows(rows, query), nil
+}
+
+func (c *sqliteConn) Ping(ctx context.Context) error { return c.base.Ping(ctx) }
+
+func (c *sqliteConn) ResetSession(ctx context.Context) error { return c.base.ResetSession(ctx) }
+
+func (c *sqliteConn) IsValid() bool { return c.base.IsValid() }
+
+// CheckNamedValue converts arguments the way SQLite stores them: times in UTC, so that
+// they sort, and JSON as text, which SQLite's JSON functions read
+func (c *sqliteConn) CheckNamedValue(nv *driver.NamedValue) error {
+	value, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil {
+		return err
+	}
+	switch v := value.(type) {
+	case time.Time:
+		value = v.UTC()
+	case []byte:
+		if json.Valid(v) {
+			value = string(v)
+		}
+	}
+	nv.Value = value
+	return nil
+}
+
+// setUserContextPattern matches the statement setting the user and tenant of a transaction
+var setUserContextPattern = regexp.MustCompile(`(?i)^\s*SELECT\s+set_user_context\(\s*\$1\s*(,\s*\$2\s*)?\)\s*;?\s*$`)
+
+// setUserContext records the settings of a set_user_context statement inside a
+// transaction; outside one they would end with the statement
+func (c *sqliteConn) setUserContext(query string, args []driver.NamedValue) {
+	if c.inTx && setUserContextPattern.MatchString(query) {
+		c.settings = userContextSettings(args)
+	}
+}
+
+// userContextSettings returns the settings the arguments of set_user_context set
+func userContextSettings(args []driver.NamedValue) map[string]string {
+	settings := make(map[string]string, 2)
+	if len(args) > 0 && args[0].Value != nil {
+		settings["app.current_user_id"] = fmt.Sprint(args[0].Value)
+	}
+	if len(args) > 1 && args[1].Value != nil {
+		settings["app.current_tenant_id"] = fmt.Sprint(args[1].Value)
+	}
+	return settings
+}
+
+// alterColumnPattern matches ADD COLUMN and DROP COLUMN statements
+var alterColumnPattern = regexp.MustCompile(`(?is)^(\s*ALTER\s+TABLE\s+"?([^"\s]+)"?\s+(ADD|DROP)\s+COLUMN\s+)(IF\s+(NOT\s+)?EXISTS\s+)?("?([^"\s]+)"?)(.*)$`)
+
+// alterColumn prepares ADD COLUMN and DROP COLUMN statements for SQLite. IF [NOT] EXISTS,
+// which SQLite does not have, is removed, and the statement skipped when the column
+// already is or is not there. Dropped columns first lose their indexes, which Postgres
+// drops with them and SQLite refuses to drop the column for.
+func (c *sqliteConn) alterColumn(ctx context.Context, query string) (string, bool, error) {
+	match := alterColumnPattern.FindStringSubmatch(query)
+	if match == nil || strings.Contains(match[8], ";") {
+		return query, false, nil
+	}
+	table, column := match[2], match[7]
+	adding := strings.EqualFold(match[3], "ADD")
+
+	if match[4] != "" {
+		exists, err := c.count(ctx, `SELECT COUNT(*) FROM pragma_table_info($1) WHERE name = $2`, table, column)
+		if err != nil {
+			return "", false, err
+		}
+		if adding == (exists > 0) {
+			return "", true, nil
+		}
+	}
+
+	if !adding {
+		indexes, err := c.base.QueryContext(ctx, `
+			SELECT l.name FROM pragma_index_list($1) l
+			WHERE l.origin = 'c' AND EXISTS (SELECT 1 FROM pragma_index_info(l.name) WHERE name = $2)`,
+			[]driver.NamedValue{{Ordinal: 1, Value: table}, {Ordinal: 2, Value: column}})
+		if err != nil {
+			return "", false, err
+		}
+		var names []string
+		values := make([]driver.Value, 1)
+		for indexes.Next(values) == nil {
+			names = append(names, fmt.Sprint(values[0]))
+		}
+		indexes.Close()
+		for _, name := range names {
+			if _, err := c.base.ExecContext(ctx, `DROP INDEX "`+name+`"`, nil); err != nil {
+				return "", false, err
+			}
+		}
+	}
+	return match[1] + match[6] + match[8], false, nil
+}
+
+// count runs a query returning a single number on the underlying connection
+func (c *sqliteConn) count(ctx context.Context, query string, args ...interface{}) (int64, error) {
+	named := make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	rows, err := c.base.QueryContext(ctx, query, named)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	values := make([]driver.Value, 1)
+	if err := rows.Next(values); err != nil && err != io.EOF {
+		return 0, err
+	}
+	count, _ := values[0].(int64)
+	return count, nil
+}
+
+// sqliteTx ends the settings of its transaction with it
+type sqliteTx struct {
+	driver.Tx
+	conn *sqliteConn
+}
+
+func (t *sqliteTx) Commit() error {
+	defer t.end()
+	return t.Tx.Commit()
+}
+
+func (t *sqliteTx) Rollback() error {
+	defer t.end()
+	return t.Tx.Rollback()
+}
+
+func (t *sqliteTx) end() {
+	t.conn.inTx = false
+	t.conn.settings = nil
+}
+
+// sqliteStmt is a prepared statement whose rows and errors are converted like the
+// connection's
+type sqliteStmt struct {
+	driver.Stmt
+	query string
+}
+
+func (s *sqliteStmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	result, err := s.Stmt.(driver.StmtExecContext).ExecContext(ctx, args)
+	return result, sqliteError(s.query, err)
+}
+
+func (s *sqliteStmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	rows, err := s.Stmt.(driver.StmtQueryContext).QueryContext(ctx, args)
+	if err != nil {
+		return nil, sqliteError(s.query, err)
+	}
+	return newSQLiteRows(rows, s.query), nil
+}
+
+// sqliteRows returns column values the way lib/pq does for the declared Postgres type:
+// booleans as bool, timestamps as time.Time, and JSON and arrays as []byte
+type sqliteRows struct {
+	driver.Rows
+	query string
+	types []string // Declared type of each column
+}
+
+func newSQLiteRows(rows driver.Rows, query string) *sqliteRows {
+	r := &sqliteRows{Rows: rows, query: query, types: make([]string, len(rows.Columns()))}
+	if typed, ok := rows.(driver.RowsColumnTypeDatabaseTypeName); ok {
+		for i := range r.types {
+			r.types[i] = typed.ColumnTypeDatabaseTypeName(i)
+		}
+	}
+	return r
+}
+
+func (r *sqliteRows) ColumnTypeDatabaseTypeName(index int) string {
+	return r.types[index]
+}
+
+func (r *sqliteRows) Next(dest []driver.Value) error {
+	if err := r.Rows.Next(dest); err != nil {
+		return sqliteError(r.query, err)
+	}
+	for i, value := range dest {
+		dest[i] = sqliteValue(r.types[i], value)
+	}
+	return nil
+}
+
+// sqliteValue converts a value read from a column declared with dbType
+func sqliteValue(dbType string, value driver.Value) driver.Value {
+	switch v := value.(type) {
+	case int64:
+		if dbType == "BOOLEAN" || dbType == "BOOL" {
+			return v != 0
+		}
+	case string:
+		switch {
+		case dbType == "JSON" || dbType == "JSONB" || strings.HasPrefix(dbType, "_"):
+			return []byte(v)
+		case strings.HasPrefix(dbType, "TIMESTAMP") || dbType == "DATE" || dbType == "DATETIME":
+			for _, format := range sqliteTimeFormats {
+				if t, err := time.Parse(format, v); err == nil {
+					return t
+				}
+			}
+		}
+	}
+	return value
+}
+
+// sqliteConstraintPattern matches the kind of constraint SQLite reports as failing, and
+// the column or constraint it names
+var sqliteConstraintPattern = regexp.MustCompile(`(UNIQUE|NOT NULL|CHECK|FOREIGN KEY) constraint failed(?:: ([^\s(]+))?`)
+
+// deletePattern matches statements deleting rows
+var deletePattern = regexp.MustCompile(`(?i)\bDELETE\s+FROM\b`)
+
+// sqliteError converts a failed constraint of query to the lib/pq error Postgres would
+// have returned, which is what Basin looks at to answer with a conflict or a validation
+// error. Other errors are returned unchanged.
+func sqliteError(query string, err error) error {
+	var sqliteErr *sqlite.Error
+	if !errors.As(err, &sqliteErr) || sqliteErr.Code()&0xff != sqlite3.SQLITE_CONSTRAINT {
+		return err
+	}
+	match := sqliteConstraintPattern.FindStringSubmatch(sqliteErr.Error())
+	if match == nil {
+		return err
+	}
+
+	pqErr := &pq.Error{Message: match[0]}
+	switch match[1] {
+	case "UNIQUE":
+		pqErr.Code = "23505"
+	case "NOT NULL":
+		pqErr.Code = "23502"
+		// Columns are named as table.column
+		pqErr.Column = match[2][strings.LastIndexByte(match[2], '.')+1:]
+	case "CHECK":
+		pqErr.Code = "23514"
+		pqErr.Constraint = match[2]
+	case "FOREIGN KEY":
+		pqErr.Code = "23503"
+		if deletePattern.MatchString(query) {
+			pqErr.Detail = "Key is still referenced from another table."
+		}
+	}
+	return pqErr
+}
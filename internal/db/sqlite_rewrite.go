@@ -0,0 +1,186 @@
+package db
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Basin's statements are written for Postgres. rewrite translates the Postgres constructs
+// they use that SQLite lacks:
+//   - FOR UPDATE [OF ...] [SKIP LOCKED | NOWAIT] is dropped; SQLite has a single writer
+//   - ::type casts are dropped, SQLite converting values by column affinity instead
+//   - x = ANY($n) becomes array_contains($n, x) and unnest($n) WITH ORDINALITY a table of
+//     json_each over the array
+//   - x @> $n and x && $n become array_contains_all(x, $n) and array_overlaps(x, $n)
+//   - current_setting('app.current_user_id', true) and app.current_tenant_id become the
+//     values set_user_context set in the transaction
+//   - ADD CONSTRAINT ... UNIQUE becomes a unique index, and DROP CONSTRAINT drops it
+//   - Index methods, schema prefixes of indexes and public., CASCADE on DROP TABLE, and
+//     NOT NULL on added columns without a default are dropped
+//   - CURRENT_TIMESTAMP becomes now(), which keeps fractions of seconds and writes times
+//     in the format of the others, and function defaults such as DEFAULT NOW() become
+//     expressions
+//   - INSERT ... SELECT without a WHERE gets WHERE true before ON CONFLICT, which SQLite
+//     would otherwise read as the ON of a join
+//   - ILIKE becomes LIKE, which ignores case in SQLite, and SELECT FROM SELECT 1 FROM
+//
+// String literals, quoted identifiers and comments are left alone.
+
+var (
+	forUpdatePattern     = regexp.MustCompile(`(?i)\s+FOR\s+UPDATE(\s+OF\s+\w+(\s*,\s*\w+)*)?(\s+SKIP\s+LOCKED|\s+NOWAIT)?\b`)
+	castPattern          = regexp.MustCompile(`(?i)::\s*\w+(\s+(varying|precision|with(out)?\s+time\s+zone))*(\s*\(\s*\d+(\s*,\s*\d+)?\s*\))?(\s*\[\])?`)
+	anyPattern           = regexp.MustCompile(`(?i)(\$\d+|(?:\w+\.)?(?:\w+|\x00\d+\x00))\s*=\s*ANY\s*\(\s*(\$\d+)\s*\)`)
+	arrayOperatorPattern = regexp.MustCompile(`((?:\w+\.)?(?:\w+|\x00\d+\x00))\s*(@>|&&)\s*(\$\d+)`)
+	unnestPattern        = regexp.MustCompile(`(?i)unnest\(\s*(\$\d+)\s*\)\s+WITH\s+ORDINALITY\s+AS\s+(\w+)\s*\(\s*(\w+)\s*,\s*(\w+)\s*\)`)
+	currentSettingRegexp = regexp.MustCompile(`(?i)current_setting\(\s*(\x00\d+\x00)\s*,\s*true\s*\)`)
+	addUniquePattern     = regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(\S+)\s+ADD\s+CONSTRAINT\s+(\S+)\s+UNIQUE\s*(\([^)]*\))`)
+	dropConstraintRegexp = regexp.MustCompile(`(?i)ALTER\s+TABLE\s+\S+\s+DROP\s+CONSTRAINT\s+IF\s+EXISTS\s+(\S+)`)
+	dropIndexPattern     = regexp.MustCompile(`(?i)(DROP\s+INDEX\s+(IF\s+EXISTS\s+)?)(?:\w+|\x00\d+\x00)\.`)
+	indexMethodPattern   = regexp.MustCompile(`(?i)\s+USING\s+(btree|gin|gist|hash|brin)\b`)
+	publicPattern        = regexp.MustCompile(`(?i)\bpublic\.`)
+	dropCascadePattern   = regexp.MustCompile(`(?i)(DROP\s+TABLE\s[^;]*?)\s+CASCADE\b`)
+	addNotNullPattern    = regexp.MustCompile(`(?is)^(\s*ALTER\s+TABLE\s+\S+\s+ADD\s+COLUMN\s.*?)\s+NOT\s+NULL\b`)
+	currentTimePattern   = regexp.MustCompile(`(?i)\bCURRENT_TIMESTAMP\b`)
+	defaultCallPattern   = regexp.MustCompile(`(?i)\bDEFAULT\s+(\w+\(\))`)
+	insertSelectPattern  = regexp.MustCompile(`(?is)^((?:\s*\x00\d+\x00)*\s*INSERT\s+INTO\s+\S+\s*(?:\([^)]*\))?\s*SELECT\s.*?)(\s+ON\s+CONFLICT\b)`)
+	wherePattern         = regexp.MustCompile(`(?i)\bWHERE\b`)
+	ilikePattern         = regexp.MustCompile(`(?i)\bILIKE\b`)
+	selectFromPattern    = regexp.MustCompile(`(?i)\bSELECT\s+FROM\b`)
+)
+
+// rewrites caches translated statements, which do not depend on the settings
+var rewrites sync.Map
+
+// rewrite translates a statement written for Postgres for SQLite, with the settings of
+// the transaction it runs in
+func rewrite(query string, settings map[string]string) string {
+	if cached, ok := rewrites.Load(query); ok {
+		return cached.(string)
+	}
+
+	masked, quoted := maskQuoted(query)
+	usesSettings := currentSettingRegexp.MatchString(masked)
+
+	masked = forUpdatePattern.ReplaceAllString(masked, "")
+	masked = castPattern.ReplaceAllString(masked, "")
+	masked = anyPattern.ReplaceAllString(masked, "array_contains($2, $1)")
+	masked = arrayOperatorPattern.ReplaceAllStringFunc(masked, func(comparison string) string {
+		match := arrayOperatorPattern.FindStringSubmatch(comparison)
+		function := "array_contains_all"
+		if match[2] == "&&" {
+			function = "array_overlaps"
+		}
+		return function + "(" + match[1] + ", " + match[3] + ")"
+	})
+	masked = unnestPattern.ReplaceAllString(masked, "(SELECT value AS $3, key + 1 AS $4 FROM json_each(array_to_json($1))) AS $2")
+	masked = currentSettingRegexp.ReplaceAllStringFunc(masked, func(call string) string {
+		name := quoted[placeholderIndex(currentSettingRegexp.FindStringSubmatch(call)[1])]
+		value, ok := settings[strings.Trim(name, "'")]
+		if !ok {
+			return "NULL"
+		}
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+	})
+	masked = addUniquePattern.ReplaceAllString(masked, "CREATE UNIQUE INDEX $2 ON $1 $3")
+	masked = dropConstraintRegexp.ReplaceAllString(masked, "DROP INDEX IF EXISTS $1")
+	masked = dropIndexPattern.ReplaceAllString(masked, "$1")
+	masked = indexMethodPattern.ReplaceAllString(masked, "")
+	masked = publicPattern.ReplaceAllString(masked, "")
+	masked = dropCascadePattern.ReplaceAllString(masked, "$1")
+	if !strings.Contains(strings.ToUpper(masked), "DEFAULT") {
+		masked = addNotNullPattern.ReplaceAllString(masked, "$1")
+	}
+	masked = currentTimePattern.ReplaceAllString(masked, "now()")
+	masked = defaultCallPattern.ReplaceAllString(masked, "DEFAULT ($1)")
+	if match := insertSelectPattern.FindStringSubmatchIndex(masked); match != nil && !wherePattern.MatchString(masked[match[2]:match[3]]) {
+		masked = masked[:match[3]] + " WHERE true" + masked[match[3]:]
+	}
+	masked = ilikePattern.ReplaceAllString(masked, "LIKE")
+	masked = selectFromPattern.ReplaceAllString(masked, "SELECT 1 FROM")
+
+	rewritten := unmaskQuoted(masked, quoted)
+	if !usesSettings {
+		rewrites.Store(query, rewritten)
+	}
+	return rewritten
+}
+
+// maskQuoted replaces the string literals, quoted identifiers and comments of query with
+// placeholders, so that the patterns above only see SQL. It returns the masked query and
+// what each placeholder stands for.
+func maskQuoted(query string) (string, []string) {
+	var masked strings.Builder
+	var quoted []string
+	for i := 0; i < len(query); {
+		end := quotedEnd(query, i)
+		if end == i {
+			masked.WriteByte(query[i])
+			i++
+			continue
+		}
+		masked.WriteString("\x00" + strconv.Itoa(len(quoted)) + "\x00")
+		quoted = append(quoted, query[i:end])
+		i = end
+	}
+	return masked.String(), quoted
+}
+
+// quotedEnd returns where the literal, identifier or comment starting at i ends, or i
+// when none starts there. Unterminated ones run to the end of the query.
+func quotedEnd(query string, i int) int {
+	switch {
+	case query[i] == '\'' || query[i] == '"':
+		quote := query[i]
+		for j := i + 1; j < len(query); j++ {
+			if query[j] != quote {
+				continue
+			}
+			// A doubled quote stands for itself
+			if j+1 < len(query) && query[j+1] == quote {
+				j++
+				continue
+			}
+			return j + 1
+		}
+		return len(query)
+	case strings.HasPrefix(query[i:], "--"):
+		if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+			return i + end
+		}
+		return len(query)
+	case strings.HasPrefix(query[i:], "/*"):
+		if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+			return i + 2 + end + 2
+		}
+		return len(query)
+	}
+	return i
+}
+
+// unmaskQuoted puts back what maskQuoted replaced
+func unmaskQuoted(masked string, quoted []string) string {
+	if len(quoted) == 0 {
+		return masked
+	}
+	var query strings.Builder
+	for {
+		start := strings.IndexByte(masked, 0)
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(masked[start+1:], 0) + start + 1
+		query.WriteString(masked[:start])
+		query.WriteString(quoted[placeholderIndex(masked[start:end+1])])
+		masked = masked[end+1:]
+	}
+	query.WriteString(masked)
+	return query.String()
+}
+
+// placeholderIndex returns the index into quoted of a placeholder
+func placeholderIndex(placeholder string) int {
+	index, _ := strconv.Atoi(strings.Trim(placeholder, "\x00"))
+	return index
+}
@@ -0,0 +1,110 @@
+package db
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRewrite(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{
+			name:  "row locks",
+			query: "SELECT id FROM jobs WHERE run_at <= $1 FOR UPDATE SKIP LOCKED",
+			want:  "SELECT id FROM jobs WHERE run_at <= $1",
+		},
+		{
+			name:  "casts",
+			query: "SELECT $1::uuid, created_at::timestamp with time zone, name::varchar(255), $2::text[]",
+			want:  "SELECT $1, created_at, name, $2",
+		},
+		{
+			name:  "any",
+			query: `SELECT * FROM users WHERE id = ANY($1) AND "role" = ANY($2::text[]) OR $3 = ANY($4)`,
+			want:  `SELECT * FROM users WHERE array_contains($1, id) AND array_contains($2, "role") OR array_contains($4, $3)`,
+		},
+		{
+			name:  "array operators",
+			query: `SELECT * FROM t WHERE "tags" @> $1 OR "tags" && $2`,
+			want:  `SELECT * FROM t WHERE array_contains_all("tags", $1) OR array_overlaps("tags", $2)`,
+		},
+		{
+			name:  "unnest",
+			query: "SELECT link.id FROM unnest($1::uuid[]) WITH ORDINALITY AS link(id, position)",
+			want:  "SELECT link.id FROM (SELECT value AS id, key + 1 AS position FROM json_each(array_to_json($1))) AS link",
+		},
+		{
+			name:  "unique constraints",
+			query: `ALTER TABLE "data"."posts" ADD CONSTRAINT "posts_slug_key" UNIQUE ("slug")`,
+			want:  `CREATE UNIQUE INDEX "posts_slug_key" ON "data"."posts" ("slug")`,
+		},
+		{
+			name:  "dropped constraints",
+			query: `ALTER TABLE "posts" DROP CONSTRAINT IF EXISTS "posts_slug_key"`,
+			want:  `DROP INDEX IF EXISTS "posts_slug_key"`,
+		},
+		{
+			name:  "index schema and method",
+			query: `DROP INDEX IF EXISTS "acme"."search_idx"; CREATE INDEX idx ON public.users USING btree (email)`,
+			want:  `DROP INDEX IF EXISTS "search_idx"; CREATE INDEX idx ON users (email)`,
+		},
+		{
+			name:  "cascade",
+			query: `DROP TABLE IF EXISTS "junction_tags" CASCADE`,
+			want:  `DROP TABLE IF EXISTS "junction_tags"`,
+		},
+		{
+			name:  "added required column",
+			query: `ALTER TABLE "posts" ADD COLUMN "title" TEXT NOT NULL`,
+			want:  `ALTER TABLE "posts" ADD COLUMN "title" TEXT`,
+		},
+		{
+			name:  "added required column with a default",
+			query: `ALTER TABLE "posts" ADD COLUMN "views" NUMERIC NOT NULL DEFAULT 0`,
+			want:  `ALTER TABLE "posts" ADD COLUMN "views" NUMERIC NOT NULL DEFAULT 0`,
+		},
+		{
+			name:  "defaults and times",
+			query: "CREATE TABLE t (id UUID DEFAULT uuid_generate_v4(), at TIMESTAMP DEFAULT NOW()); UPDATE t SET at = CURRENT_TIMESTAMP",
+			want:  "CREATE TABLE t (id UUID DEFAULT (uuid_generate_v4()), at TIMESTAMP DEFAULT (NOW())); UPDATE t SET at = now()",
+		},
+		{
+			name:  "insert select with conflict",
+			query: "-- name: Link\nINSERT INTO \"j\" (\"a\", \"b\") SELECT $1, x FROM y ON CONFLICT DO NOTHING",
+			want:  "-- name: Link\nINSERT INTO \"j\" (\"a\", \"b\") SELECT $1, x FROM y WHERE true ON CONFLICT DO NOTHING",
+		},
+		{
+			name:  "insert select with a where",
+			query: "INSERT INTO j SELECT $1 FROM y WHERE z ON CONFLICT DO NOTHING",
+			want:  "INSERT INTO j SELECT $1 FROM y WHERE z ON CONFLICT DO NOTHING",
+		},
+		{
+			name:  "ilike and empty selects",
+			query: "SELECT EXISTS (SELECT FROM users WHERE email ILIKE $1)",
+			want:  "SELECT EXISTS (SELECT 1 FROM users WHERE email LIKE $1)",
+		},
+		{
+			name:  "quoted text is left alone",
+			query: `SELECT 'a::text FOR UPDATE', "public.name" FROM t -- ILIKE $1::uuid`,
+			want:  `SELECT 'a::text FOR UPDATE', "public.name" FROM t -- ILIKE $1::uuid`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, rewrite(tt.query, nil))
+		})
+	}
+}
+
+func TestRewrite_CurrentSetting(t *testing.T) {
+	query := "SELECT * FROM t WHERE tenant_id = current_setting('app.current_tenant_id', true)::uuid"
+
+	assert.Equal(t, "SELECT * FROM t WHERE tenant_id = 'acme''s'",
+		rewrite(query, map[string]string{"app.current_tenant_id": "acme's"}))
+	// Statements reading settings are not cached
+	assert.Equal(t, "SELECT * FROM t WHERE tenant_id = NULL", rewrite(query, nil))
+}
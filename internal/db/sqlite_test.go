@@ -0,0 +1,187 @@
+package db
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// openSQLite opens a SQLite database in a temporary file with a posts table
+func openSQLite(t *testing.T) *DB {
+	database, err := OpenSQLite(PoolConfig{}, filepath.Join(t.TempDir(), "basin.db"))
+	require.NoError(t, err)
+	t.Cleanup(func() { database.Close() })
+
+	_, err = database.Exec(`CREATE TABLE "data__posts" (
+		id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+		title TEXT NOT NULL,
+		published BOOLEAN DEFAULT false,
+		tags TEXT[],
+		meta JSONB,
+		created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+		CONSTRAINT title_not_empty CHECK (title <> '')
+	)`)
+	require.NoError(t, err)
+	return database
+}
+
+func TestDialectFor(t *testing.T) {
+	for driver, want := range map[string]Dialect{"": Postgres, "postgres": Postgres, "PostgreSQL": Postgres, "sqlite": SQLite, "sqlite3": SQLite, "mysql": MySQL, "MariaDB": MySQL} {
+		dialect, err := DialectFor(driver)
+		require.NoError(t, err, driver)
+		assert.Equal(t, want, dialect, driver)
+	}
+
+	_, err := DialectFor("oracle")
+	assert.ErrorContains(t, err, `unknown database driver "oracle"`)
+}
+
+func TestDialect_Names(t *testing.T) {
+	assert.Equal(t, `"data"."posts-data-1"`, Postgres.Table("data", "posts-data-1"))
+	assert.Equal(t, `"acme"."field_1_idx"`, Postgres.Index("acme", "field_1_idx"))
+	assert.Equal(t, "TEXT[]", Postgres.ColumnType("TEXT[]"))
+
+	assert.Equal(t, `"data__posts-data-1"`, SQLite.Table("data", "posts-data-1"))
+	assert.Equal(t, `"users"`, SQLite.Table("public", "users"))
+	assert.Equal(t, `"field_1_idx"`, SQLite.Index("acme", "field_1_idx"))
+	assert.Equal(t, "_TEXT", SQLite.ColumnType("TEXT[]"))
+	assert.Equal(t, "UUID REFERENCES assets(id) ON DELETE SET NULL",
+		SQLite.ColumnType("UUID REFERENCES public.assets(id) ON DELETE SET NULL"))
+
+	assert.Equal(t, `"data__posts-data-1"`, MySQL.Table("data", "posts-data-1"))
+	assert.Equal(t, `"users"`, MySQL.Table("public", "users"))
+	assert.Equal(t, `"field_1_idx"`, MySQL.Index("acme", "field_1_idx"))
+	assert.Equal(t, "TEXT[]", MySQL.ColumnType("TEXT[]"))
+	assert.Len(t, MySQL.Table("data", "a-very-long-collection-name-data-6e68062f-c4c6-42df-9e01-e2d1081664f4"), 64+2,
+		"names are shortened to what MySQL allows")
+}
+
+func TestSQLite_Catalog(t *testing.T) {
+	database := openSQLite(t)
+	ctx := context.Background()
+	assert.Equal(t, SQLite, database.Dialect())
+
+	exists, err := SQLite.TableExists(ctx, database, "data", "posts")
+	require.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = SQLite.TableExists(ctx, database, "data", "missing")
+	require.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = database.Exec(`ALTER TABLE "data__posts" ADD CONSTRAINT "field_1_key" UNIQUE ("title")`)
+	require.NoError(t, err)
+	unique, err := SQLite.UniqueColumn(ctx, database, SQLite.Table("data", "posts"), "title")
+	require.NoError(t, err)
+	assert.True(t, unique)
+	unique, err = SQLite.UniqueColumn(ctx, database, SQLite.Table("data", "posts"), "published")
+	require.NoError(t, err)
+	assert.False(t, unique)
+
+	indexes, err := SQLite.Indexes(ctx, database, "field_", []string{"data"})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"field_1_key": true}, indexes)
+}
+
+func TestSQLite_AlterColumn(t *testing.T) {
+	database := openSQLite(t)
+
+	// IF NOT EXISTS and IF EXISTS skip statements with nothing to do
+	for i := 0; i < 2; i++ {
+		_, err := database.Exec(`ALTER TABLE "data__posts" ADD COLUMN IF NOT EXISTS "views" NUMERIC`)
+		require.NoError(t, err)
+	}
+	_, err := database.Exec(`CREATE INDEX "field_views_idx" ON "data__posts" ("views")`)
+	require.NoError(t, err)
+
+	// Indexes go with their column
+	for i := 0; i < 2; i++ {
+		_, err := database.Exec(`ALTER TABLE "data__posts" DROP COLUMN IF EXISTS "views"`)
+		require.NoError(t, err)
+	}
+	indexes, err := SQLite.Indexes(context.Background(), database, "field_", nil)
+	require.NoError(t, err)
+	assert.Empty(t, indexes)
+}
+
+func TestSQLite_Values(t *testing.T) {
+	database := openSQLite(t)
+
+	created := time.Date(2026, 1, 2, 3, 4, 5, 600, time.FixedZone("CET", 3600))
+	_, err := database.Exec(`INSERT INTO "data__posts" (title, published, tags, meta, created_at) VALUES ($1, $2, $3, $4, $5)`,
+		"Hello", true, pq.StringArray{"new", "sale"}, json.RawMessage(`{"a":1}`), created)
+	require.NoError(t, err)
+
+	var (
+		id        string
+		published bool
+		tags      pq.StringArray
+		meta      json.RawMessage
+		createdAt time.Time
+	)
+	err = database.QueryRow(`SELECT id, published, tags, meta, created_at FROM "data__posts" WHERE tags @> $1`,
+		pq.StringArray{"sale"}).Scan(&id, &published, &tags, &meta, &createdAt)
+	require.NoError(t, err)
+	assert.Len(t, id, 36)
+	assert.True(t, published)
+	assert.Equal(t, pq.StringArray{"new", "sale"}, tags)
+	assert.JSONEq(t, `{"a":1}`, string(meta))
+	assert.True(t, created.Equal(createdAt))
+
+	var matches bool
+	require.NoError(t, database.QueryRow(`SELECT $1 = ANY($2::text[])`, "new", pq.StringArray{"new"}).Scan(&matches))
+	assert.True(t, matches)
+	require.NoError(t, database.QueryRow(`SELECT tags && $1 FROM "data__posts"`, pq.StringArray{"old"}).Scan(&matches))
+	assert.False(t, matches)
+}
+
+func TestSQLite_ConstraintErrors(t *testing.T) {
+	database := openSQLite(t)
+	_, err := database.Exec(`CREATE UNIQUE INDEX "posts_title_key" ON "data__posts" (title)`)
+	require.NoError(t, err)
+	_, err = database.Exec(`INSERT INTO "data__posts" (title) VALUES ('Hello')`)
+	require.NoError(t, err)
+
+	code := func(err error) string {
+		var pqErr *pq.Error
+		require.True(t, errors.As(err, &pqErr), "%v is not a pq.Error", err)
+		return pqErr.Code.Name()
+	}
+
+	_, err = database.Exec(`INSERT INTO "data__posts" (title) VALUES ('Hello')`)
+	assert.Equal(t, "unique_violation", code(err))
+
+	var id string
+	err = database.QueryRow(`INSERT INTO "data__posts" (title) VALUES (NULL) RETURNING id`).Scan(&id)
+	assert.Equal(t, "not_null_violation", code(err))
+	assert.Equal(t, "title", err.(*pq.Error).Column)
+
+	_, err = database.Exec(`INSERT INTO "data__posts" (title) VALUES ('')`)
+	assert.Equal(t, "check_violation", code(err))
+	assert.Equal(t, "title_not_empty", err.(*pq.Error).Constraint)
+}
+
+func TestSQLite_UserContext(t *testing.T) {
+	database := openSQLite(t)
+	ctx := context.Background()
+	query := `SELECT COALESCE(current_setting('app.current_tenant_id', true), 'none')`
+
+	tx, err := database.BeginTx(ctx, nil)
+	require.NoError(t, err)
+	_, err = tx.ExecContext(ctx, `SELECT set_user_context($1, $2)`, "user-1", "tenant-1")
+	require.NoError(t, err)
+	var tenant string
+	require.NoError(t, tx.QueryRowContext(ctx, query).Scan(&tenant))
+	assert.Equal(t, "tenant-1", tenant)
+	require.NoError(t, tx.Commit())
+
+	// The settings end with the transaction
+	require.NoError(t, database.QueryRowContext(ctx, query).Scan(&tenant))
+	assert.Equal(t, "none", tenant)
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+)
+
+// Executor runs statements on the connection pool or inside a transaction. DB and Tx both
+// implement it.
+type Executor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// Tx is a transaction with the generated queries bound to it
+type Tx struct {
+	*sql.Tx
+	*sqlc.Queries
+}
+
+// InTransaction runs fn inside a transaction. The transaction is committed when fn returns
+// nil and rolled back when it returns an error or panics, so every statement fn runs,
+// schema changes included, takes effect together or not at all.
+func (db *DB) InTransaction(ctx context.Context, fn func(tx *Tx) error) error {
+	sqlTx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	// Rolling back after a commit is a no-op
+	defer sqlTx.Rollback()
+
+	if err := fn(&Tx{Tx: sqlTx, Queries: db.Queries.WithTx(sqlTx)}); err != nil {
+		return err
+	}
+	if err := sqlTx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
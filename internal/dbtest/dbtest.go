@@ -0,0 +1,40 @@
+// Package dbtest provides real databases to tests that cannot reach a Postgres server.
+package dbtest
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/migrate"
+)
+
+// SQLite returns a database in a temporary SQLite file with the SQLite schema migrated,
+// seed data included. It is closed when the test ends.
+func SQLite(t testing.TB) *db.DB {
+	t.Helper()
+
+	database, err := db.OpenSQLite(db.PoolConfig{}, filepath.Join(t.TempDir(), "basin.db"))
+	if err != nil {
+		t.Fatalf("failed to open SQLite database: %v", err)
+	}
+	t.Cleanup(func() { database.Close() })
+
+	migrations, err := migrate.Load(os.DirFS(migrationsDir()))
+	if err != nil {
+		t.Fatalf("failed to load migrations: %v", err)
+	}
+	if _, err := migrate.New(database, migrations).Up(context.Background()); err != nil {
+		t.Fatalf("failed to migrate SQLite database: %v", err)
+	}
+	return database
+}
+
+// migrationsDir returns the SQLite migrations of the repository, wherever the test runs from
+func migrationsDir() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(file), "..", "..", "migrations", "sqlite")
+}
@@ -0,0 +1,89 @@
+// Package events provides an in-process publish/subscribe bus for item change events.
+//
+// Handlers that write items publish an Event after the write has been committed.
+// Subsystems that react to changes (webhooks, realtime streams, audit) subscribe to
+// the bus instead of being called directly by the handlers.
+package events
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Item event types
+const (
+	ItemCreate = "item.create"
+	ItemUpdate = "item.update"
+	ItemDelete = "item.delete"
+)
+
+// APIKeyExpiring is published once for an API key when it enters the expiry warning window
+const APIKeyExpiring = "api_key.expiring"
+
+// APIKeyRotationDue is published once for an API key of a service account when it has gone
+// unrotated for longer than the rotation policy allows
+const APIKeyRotationDue = "api_key.rotation_due"
+
+// Event describes a change to one or more items of a collection
+type Event struct {
+	Type       string      `json:"event"`      // One of the Item* or APIKey* constants
+	TenantID   uuid.UUID   `json:"tenant_id"`  // Tenant that owns the collection
+	Collection string      `json:"collection"` // Collection slug or schema table name
+	Keys       []string    `json:"keys"`       // IDs of the affected items
+	Data       interface{} `json:"data"`       // Item payload(s); nil for deletes
+	UserID     uuid.UUID   `json:"user_id"`    // User that made the change
+	Timestamp  time.Time   `json:"timestamp"`
+}
+
+// Handler receives published events. Handlers run synchronously on the publishing
+// goroutine, so anything slow must be handed off to a worker.
+type Handler func(ctx context.Context, event Event)
+
+// Bus fans events out to every subscribed handler
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewBus creates an empty event bus
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// Subscribe registers a handler for all future events
+func (b *Bus) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+// Publish delivers event to every subscriber. A nil bus is a no-op so components can be
+// constructed without one in tests. Panicking handlers are logged and do not affect the
+// publisher or other handlers.
+func (b *Bus) Publish(ctx context.Context, event Event) {
+	if b == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+
+	b.mu.RLock()
+	handlers := append([]Handler(nil), b.handlers...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					log.Printf("events: handler panicked on %s: %v", event.Type, r)
+				}
+			}()
+			handler(ctx, event)
+		}()
+	}
+}
@@ -0,0 +1,34 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBusPublish(t *testing.T) {
+	bus := NewBus()
+
+	var received []Event
+	bus.Subscribe(func(ctx context.Context, event Event) {
+		panic("broken subscriber")
+	})
+	bus.Subscribe(func(ctx context.Context, event Event) {
+		received = append(received, event)
+	})
+
+	bus.Publish(context.Background(), Event{Type: ItemCreate, Collection: "products", Keys: []string{"1"}})
+
+	if assert.Len(t, received, 1) {
+		assert.Equal(t, ItemCreate, received[0].Type)
+		assert.False(t, received[0].Timestamp.IsZero())
+	}
+}
+
+func TestNilBusPublish(t *testing.T) {
+	var bus *Bus
+	assert.NotPanics(t, func() {
+		bus.Publish(context.Background(), Event{Type: ItemDelete})
+	})
+}
@@ -0,0 +1,168 @@
+// Package fakedata replaces personal data with realistic fake values, for copies of
+// production data that are safe to load into staging or development.
+//
+// Values are replaced by the PII kind of their field (email, name, phone, address, ip,
+// date, identifier or other). A Generator maps equal values to equal fakes, so an email
+// appearing in two collections still matches after the replacement, while a different
+// seed gives different fakes. Fakes stay clear of real people where the Internet has
+// reserved room for that: emails use example.com, example.net and example.org, and IP
+// addresses the documentation ranges of RFC 5737.
+package fakedata
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	mathrand "math/rand"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// SeedSize is the size of a generator seed in bytes
+const SeedSize = 32
+
+var (
+	firstNames = []string{
+		"Ada", "Alan", "Amara", "Anna", "Ben", "Carla", "Chen", "Clara", "David", "Elena",
+		"Emil", "Farah", "Grace", "Hana", "Hugo", "Ines", "Jamal", "Jane", "John", "Julia",
+		"Kai", "Lea", "Leo", "Lina", "Luca", "Maya", "Mateo", "Mia", "Nadia", "Noah",
+		"Omar", "Paula", "Priya", "Rafael", "Rosa", "Sam", "Sara", "Theo", "Yara", "Zoe",
+	}
+	lastNames = []string{
+		"Almeida", "Becker", "Brown", "Costa", "Dubois", "Eriksen", "Fischer", "Garcia", "Hansen", "Ito",
+		"Jensen", "Kim", "Kowalski", "Larsen", "Lopez", "Martin", "Meyer", "Moreau", "Nakamura", "Novak",
+		"Okafor", "Olsen", "Patel", "Petrov", "Rossi", "Santos", "Schmidt", "Silva", "Smith", "Tanaka",
+		"Taylor", "Weber", "Williams", "Wilson", "Yilmaz", "Zhang",
+	}
+	streets = []string{
+		"Maple", "Oak", "Cedar", "Elm", "Pine", "Birch", "Willow", "Chestnut", "Harbor", "Meadow",
+		"River", "Hill", "Lake", "Park", "Station", "Market", "Garden", "Church", "Mill", "Orchard",
+	}
+	streetTypes = []string{"Street", "Avenue", "Road", "Lane", "Way", "Drive", "Court", "Place"}
+	cities      = []string{
+		"Springfield", "Riverton", "Fairview", "Lakeside", "Greenville", "Brookfield", "Clearwater",
+		"Oakridge", "Milton", "Ashford", "Westport", "Northbridge", "Kingston", "Bayview",
+	}
+	emailDomains = []string{"example.com", "example.net", "example.org"}
+	ipRanges     = []string{"192.0.2", "198.51.100", "203.0.113"}
+	words        = strings.Fields(`lorem ipsum dolor sit amet consectetur adipiscing elit sed do eiusmod
+		tempor incididunt ut labore et dolore magna aliqua enim ad minim veniam quis nostrud
+		exercitation ullamco laboris nisi aliquip ex ea commodo consequat`)
+)
+
+// Birth dates and other dates fall between these
+var (
+	minDate = time.Date(1940, 1, 1, 0, 0, 0, 0, time.UTC)
+	maxDate = time.Date(2005, 12, 31, 0, 0, 0, 0, time.UTC)
+)
+
+// Generator produces the fake values of one copy of the data
+type Generator struct {
+	seed []byte
+}
+
+// New returns a generator whose fakes are derived from seed. Generators with the same seed
+// produce the same fakes.
+func New(seed []byte) *Generator {
+	return &Generator{seed: seed}
+}
+
+// NewRandom returns a generator with a random seed
+func NewRandom() (*Generator, error) {
+	seed := make([]byte, SeedSize)
+	if _, err := rand.Read(seed); err != nil {
+		return nil, fmt.Errorf("failed to generate seed: %w", err)
+	}
+	return New(seed), nil
+}
+
+// Value returns the fake replacing value in a field of the PII kind kind. NULL stays NULL.
+// Strings get a fake string; dates may also be time.Time and identifiers numbers, which
+// keep their type. Values of other types cannot be faked and become NULL.
+func (g *Generator) Value(kind string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case string:
+		return g.text(kind, v)
+	case time.Time:
+		if kind == "date" {
+			return g.date(v.Format(time.RFC3339Nano))
+		}
+	case float64, int, int32, int64, json.Number:
+		if kind == "identifier" {
+			return g.rand(kind, fmt.Sprint(v)).Int63n(1_000_000_000) + 1
+		}
+	}
+	return nil
+}
+
+// text fakes a string value
+func (g *Generator) text(kind, value string) string {
+	r := g.rand(kind, strings.ToLower(strings.TrimSpace(value)))
+	switch kind {
+	case "email":
+		first, last := pick(r, firstNames), pick(r, lastNames)
+		return fmt.Sprintf("%s.%s.%08x@%s", strings.ToLower(first), strings.ToLower(last), r.Uint32(), pick(r, emailDomains))
+	case "name":
+		return pick(r, firstNames) + " " + pick(r, lastNames)
+	case "phone":
+		return fmt.Sprintf("+1-%03d-555-%04d", 201+r.Intn(789), r.Intn(10000))
+	case "address":
+		return fmt.Sprintf("%d %s %s, %s", 1+r.Intn(9999), pick(r, streets), pick(r, streetTypes), pick(r, cities))
+	case "ip":
+		return fmt.Sprintf("%s.%d", pick(r, ipRanges), 1+r.Intn(254))
+	case "date":
+		return g.date(value).Format("2006-01-02")
+	case "identifier":
+		if _, err := uuid.Parse(value); err == nil {
+			var id uuid.UUID
+			binary.BigEndian.PutUint64(id[:8], r.Uint64())
+			binary.BigEndian.PutUint64(id[8:], r.Uint64())
+			id[6] = (id[6] & 0x0f) | 0x40 // Version 4
+			id[8] = (id[8] & 0x3f) | 0x80 // RFC 4122 variant
+			return id.String()
+		}
+		return fmt.Sprintf("ID-%010X", r.Int63n(1<<40))
+	}
+	return lorem(r, len(value))
+}
+
+// date fakes a date, at midnight UTC
+func (g *Generator) date(value string) time.Time {
+	days := int(maxDate.Sub(minDate).Hours() / 24)
+	return minDate.AddDate(0, 0, g.rand("date", value).Intn(days+1))
+}
+
+// rand returns a random source determined by the seed, the kind and the value
+func (g *Generator) rand(kind, value string) *mathrand.Rand {
+	mac := hmac.New(sha256.New, g.seed)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	sum := mac.Sum(nil)
+	return mathrand.New(mathrand.NewSource(int64(binary.BigEndian.Uint64(sum[:8]))))
+}
+
+// pick returns a random element of list
+func pick(r *mathrand.Rand, list []string) string {
+	return list[r.Intn(len(list))]
+}
+
+// lorem returns filler words about length characters long, at least one word
+func lorem(r *mathrand.Rand, length int) string {
+	text := pick(r, words)
+	for len(text) < length {
+		next := text + " " + pick(r, words)
+		if len(next) > length {
+			break
+		}
+		text = next
+	}
+	return text
+}
@@ -0,0 +1,65 @@
+package fakedata
+
+import (
+	"bytes"
+	"encoding/json"
+	"net"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValueIsStable(t *testing.T) {
+	g := New(bytes.Repeat([]byte{1}, SeedSize))
+
+	email := g.Value("email", "Ada@Example.com")
+	assert.Equal(t, email, g.Value("email", "ada@example.com "), "equal values get equal fakes")
+	assert.NotEqual(t, email, g.Value("email", "grace@example.com"))
+	assert.Equal(t, email, New(bytes.Repeat([]byte{1}, SeedSize)).Value("email", "ada@example.com"))
+	assert.NotEqual(t, email, New(bytes.Repeat([]byte{2}, SeedSize)).Value("email", "ada@example.com"), "another seed, other fakes")
+}
+
+func TestValueKinds(t *testing.T) {
+	g, err := NewRandom()
+	require.NoError(t, err)
+
+	email := g.Value("email", "ada@lovelace.dev").(string)
+	assert.Regexp(t, regexp.MustCompile(`^[a-z]+\.[a-z]+\.[0-9a-f]{8}@example\.(com|net|org)$`), email)
+
+	name := g.Value("name", "Ada Lovelace").(string)
+	assert.Len(t, strings.Fields(name), 2)
+
+	assert.Regexp(t, regexp.MustCompile(`^\+1-\d{3}-555-\d{4}$`), g.Value("phone", "+44 20 7946 0000"))
+	assert.Regexp(t, regexp.MustCompile(`^\d+ \w+ \w+, \w+$`), g.Value("address", "12 Baker Street, London"))
+
+	ip := net.ParseIP(g.Value("ip", "81.2.69.160").(string))
+	require.NotNil(t, ip)
+	documented := false
+	for _, cidr := range []string{"192.0.2.0/24", "198.51.100.0/24", "203.0.113.0/24"} {
+		_, network, _ := net.ParseCIDR(cidr)
+		documented = documented || network.Contains(ip)
+	}
+	assert.True(t, documented, "IP addresses come from the documentation ranges")
+
+	date, err := time.Parse("2006-01-02", g.Value("date", "1815-12-10").(string))
+	require.NoError(t, err)
+	assert.False(t, date.Before(minDate) || date.After(maxDate))
+	assert.IsType(t, time.Time{}, g.Value("date", time.Now()))
+
+	_, err = uuid.Parse(g.Value("identifier", uuid.NewString()).(string))
+	assert.NoError(t, err, "UUIDs stay UUIDs")
+	assert.Regexp(t, regexp.MustCompile(`^ID-[0-9A-F]{10}$`), g.Value("identifier", "cus_123"))
+	assert.IsType(t, int64(0), g.Value("identifier", json.Number("42")))
+
+	other := g.Value("other", "Allergic to penicillin").(string)
+	assert.NotContains(t, other, "penicillin")
+	assert.NotEmpty(t, other)
+
+	assert.Nil(t, g.Value("email", nil))
+	assert.Nil(t, g.Value("other", map[string]interface{}{"a": 1}), "values that cannot be faked are cleared")
+}
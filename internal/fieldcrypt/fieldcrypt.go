@@ -0,0 +1,154 @@
+// Package fieldcrypt encrypts the values of encrypted fields at rest with AES-256-GCM.
+//
+// An encrypted value is stored as text:
+//
+//	enc:v1:<key id>:<base64 of nonce and ciphertext>
+//
+// The key id is derived from the key, so a keyring holding the current key and the keys it
+// replaced can still open values written before a rotation. Values are always sealed with
+// the first key of the keyring.
+package fieldcrypt
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Prefix starts every encrypted value
+const Prefix = "enc:v1:"
+
+// KeySize is the size of an AES-256 key in bytes
+const KeySize = 32
+
+// ErrNoKeys is returned when values are encrypted without any key configured
+var ErrNoKeys = errors.New("no field encryption key is configured")
+
+// Keyring holds the keys encrypted values are sealed and opened with
+type Keyring struct {
+	keys []key
+}
+
+// key is one AES-256-GCM key and its id
+type key struct {
+	id   string
+	aead cipher.AEAD
+}
+
+// NewKeyring creates a keyring from raw AES-256 keys. The first key seals new values; the
+// others only open values sealed with them.
+func NewKeyring(secrets [][]byte) (*Keyring, error) {
+	ring := &Keyring{}
+	for i, secret := range secrets {
+		if len(secret) != KeySize {
+			return nil, fmt.Errorf("field encryption key %d has %d bytes, expected %d", i+1, len(secret), KeySize)
+		}
+		block, err := aes.NewCipher(secret)
+		if err != nil {
+			return nil, err
+		}
+		aead, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		ring.keys = append(ring.keys, key{id: keyID(secret), aead: aead})
+	}
+	return ring, nil
+}
+
+// Load creates a keyring from base64 encoded keys and the keys in file, one per line, such
+// as one written by a KMS or secret manager. It returns nil when there are no keys.
+func Load(encodedKeys []string, file string) (*Keyring, error) {
+	encoded := append([]string{}, encodedKeys...)
+	if file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read field encryption keys: %w", err)
+		}
+		scanner := bufio.NewScanner(bytes.NewReader(raw))
+		for scanner.Scan() {
+			if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+				encoded = append(encoded, line)
+			}
+		}
+	}
+	if len(encoded) == 0 {
+		return nil, nil
+	}
+
+	secrets := make([][]byte, len(encoded))
+	for i, value := range encoded {
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("field encryption key %d is not valid base64: %w", i+1, err)
+		}
+		secrets[i] = secret
+	}
+	return NewKeyring(secrets)
+}
+
+// keyID returns the id of a key: the start of its SHA-256 hash
+func keyID(secret []byte) string {
+	sum := sha256.Sum256(secret)
+	return hex.EncodeToString(sum[:4])
+}
+
+// IsEncrypted reports whether a stored value was sealed by a keyring
+func IsEncrypted(value string) bool {
+	return strings.HasPrefix(value, Prefix)
+}
+
+// Encrypt seals plaintext with the first key
+func (r *Keyring) Encrypt(plaintext string) (string, error) {
+	if r == nil || len(r.keys) == 0 {
+		return "", ErrNoKeys
+	}
+	current := r.keys[0]
+
+	nonce := make([]byte, current.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := current.aead.Seal(nonce, nonce, []byte(plaintext), nil)
+	return Prefix + current.id + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt opens a value sealed with any key of the keyring
+func (r *Keyring) Decrypt(value string) (string, error) {
+	if !IsEncrypted(value) {
+		return "", errors.New("value is not encrypted")
+	}
+	if r == nil {
+		return "", ErrNoKeys
+	}
+
+	id, encoded, found := strings.Cut(strings.TrimPrefix(value, Prefix), ":")
+	if !found {
+		return "", errors.New("malformed encrypted value")
+	}
+	for _, k := range r.keys {
+		if k.id != id {
+			continue
+		}
+		sealed, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil || len(sealed) < k.aead.NonceSize() {
+			return "", errors.New("malformed encrypted value")
+		}
+		nonce, ciphertext := sealed[:k.aead.NonceSize()], sealed[k.aead.NonceSize():]
+		plaintext, err := k.aead.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			return "", fmt.Errorf("failed to decrypt value: %w", err)
+		}
+		return string(plaintext), nil
+	}
+	return "", fmt.Errorf("value was encrypted with unknown key %s", id)
+}
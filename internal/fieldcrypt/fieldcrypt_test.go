@@ -0,0 +1,79 @@
+package fieldcrypt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKeyringRoundTrip(t *testing.T) {
+	ring, err := NewKeyring([][]byte{bytes.Repeat([]byte{1}, KeySize)})
+	require.NoError(t, err)
+
+	sealed, err := ring.Encrypt("4111 1111 1111 1111")
+	require.NoError(t, err)
+	assert.True(t, IsEncrypted(sealed))
+	assert.NotContains(t, sealed, "4111")
+
+	again, err := ring.Encrypt("4111 1111 1111 1111")
+	require.NoError(t, err)
+	assert.NotEqual(t, sealed, again, "every value gets its own nonce")
+
+	plaintext, err := ring.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "4111 1111 1111 1111", plaintext)
+
+	_, err = ring.Decrypt(sealed[:len(sealed)-4] + "AAAA")
+	assert.Error(t, err, "tampered values are refused")
+	_, err = ring.Decrypt("plain")
+	assert.Error(t, err)
+}
+
+func TestKeyringRotation(t *testing.T) {
+	oldKey, newKey := bytes.Repeat([]byte{1}, KeySize), bytes.Repeat([]byte{2}, KeySize)
+	before, err := NewKeyring([][]byte{oldKey})
+	require.NoError(t, err)
+	sealed, err := before.Encrypt("secret")
+	require.NoError(t, err)
+
+	after, err := NewKeyring([][]byte{newKey, oldKey})
+	require.NoError(t, err)
+	plaintext, err := after.Decrypt(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "secret", plaintext, "retired keys still open their values")
+
+	resealed, err := after.Encrypt("secret")
+	require.NoError(t, err)
+	assert.NotEqual(t, strings.Split(sealed, ":")[2], strings.Split(resealed, ":")[2], "new values use the first key")
+
+	onlyNew, err := NewKeyring([][]byte{newKey})
+	require.NoError(t, err)
+	_, err = onlyNew.Decrypt(sealed)
+	assert.ErrorContains(t, err, "unknown key")
+}
+
+func TestLoad(t *testing.T) {
+	ring, err := Load(nil, "")
+	require.NoError(t, err)
+	assert.Nil(t, ring)
+	_, err = ring.Encrypt("x")
+	assert.ErrorIs(t, err, ErrNoKeys)
+
+	file := filepath.Join(t.TempDir(), "keys")
+	encoded := base64.StdEncoding.EncodeToString(bytes.Repeat([]byte{3}, KeySize))
+	require.NoError(t, os.WriteFile(file, []byte("# current key\n"+encoded+"\n"), 0o600))
+	ring, err = Load(nil, file)
+	require.NoError(t, err)
+	require.Len(t, ring.keys, 1)
+
+	_, err = Load([]string{base64.StdEncoding.EncodeToString([]byte("short"))}, "")
+	assert.ErrorContains(t, err, "expected 32")
+	_, err = Load([]string{"not base64!"}, "")
+	assert.Error(t, err)
+}
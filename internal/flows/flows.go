@@ -0,0 +1,298 @@
+// Package flows runs tenant automations.
+//
+// A flow connects a trigger to an ordered list of operations. Triggers are:
+//
+//	event:    an item event (item.create, item.update, item.delete, api_key.expiring,
+//	          api_key.rotation_due) of the flow's tenant, optionally limited to some collections
+//	schedule: a fixed interval such as "15m" or "24h"
+//	webhook:  a POST to /flows/:id/trigger carrying the flow's secret
+//
+// Every trigger queues a flow_runs row; a background worker claims queued runs and
+// executes the operations in order, stopping at the first one that fails. The result of
+// every operation is stored on the run, which doubles as the run log.
+//
+// Operation options are text/template strings evaluated against:
+//
+//	.trigger     the event, schedule or request payload that started the run
+//	.steps.<key> the result of an earlier operation
+//	.last        the result of the previous operation
+//	.flow        the flow's id and name
+//
+// Referencing a missing key fails the operation; use index for optional values, as in
+// {{ index .trigger.data "title" }}. The json function encodes a value as JSON.
+package flows
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"text/template"
+	"time"
+
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/rbac"
+)
+
+// Trigger types stored in flows.trigger
+const (
+	TriggerEvent    = "event"
+	TriggerSchedule = "schedule"
+	TriggerWebhook  = "webhook"
+)
+
+// Operation types
+const (
+	OperationRequest    = "request"     // HTTP request
+	OperationCreateItem = "create_item" // Create an item in a collection
+	OperationSendEmail  = "send_email"  // Send a plain text email
+	OperationTemplate   = "template"    // Render a template for later operations
+)
+
+// Run statuses stored in flow_runs.status; operation steps use success and failed
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusSuccess    = "success"
+	StatusFailed     = "failed"
+)
+
+const (
+	minScheduleInterval = time.Minute // Shortest interval of a schedule trigger
+	maxOperations       = 20          // Operations allowed per flow
+)
+
+// triggerEvents are the event names an event trigger may listen to
+var triggerEvents = []string{events.ItemCreate, events.ItemUpdate, events.ItemDelete, events.APIKeyExpiring, events.APIKeyRotationDue, "*"}
+
+// operationKeyPattern restricts operation keys to names usable in templates
+var operationKeyPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// TriggerOptions configures a trigger; the fields used depend on the trigger type
+type TriggerOptions struct {
+	Events      []string `json:"events,omitempty"`      // event: event names or "*"
+	Collections []string `json:"collections,omitempty"` // event: empty matches every collection
+	Interval    string   `json:"interval,omitempty"`    // schedule: a Go duration such as "1h"
+}
+
+// Operation is one step of a flow
+type Operation struct {
+	Key     string                 `json:"key"`
+	Type    string                 `json:"type"`
+	Options map[string]interface{} `json:"options"`
+}
+
+// Step is the logged result of one operation of a run
+type Step struct {
+	Key        string      `json:"key"`
+	Type       string      `json:"type"`
+	Status     string      `json:"status"`
+	Result     interface{} `json:"result,omitempty"`
+	Error      string      `json:"error,omitempty"`
+	DurationMs int64       `json:"duration_ms"`
+}
+
+// ParseTrigger validates the options of a trigger
+func ParseTrigger(trigger string, raw json.RawMessage) (TriggerOptions, error) {
+	var options TriggerOptions
+	if len(raw) > 0 {
+		if err := json.Unmarshal(raw, &options); err != nil {
+			return TriggerOptions{}, fmt.Errorf("invalid trigger_options: %w", err)
+		}
+	}
+
+	switch trigger {
+	case TriggerEvent:
+		if len(options.Events) == 0 {
+			return TriggerOptions{}, fmt.Errorf("event triggers need at least one event")
+		}
+		for _, event := range options.Events {
+			if !slices.Contains(triggerEvents, event) {
+				return TriggerOptions{}, fmt.Errorf("unsupported trigger event '%s'", event)
+			}
+		}
+		for _, collection := range options.Collections {
+			if collection != "*" && !rbac.ValidateTableName(collection) {
+				return TriggerOptions{}, fmt.Errorf("invalid collection name '%s'", collection)
+			}
+		}
+	case TriggerSchedule:
+		interval, err := time.ParseDuration(options.Interval)
+		if err != nil {
+			return TriggerOptions{}, fmt.Errorf("schedule triggers need an interval such as \"15m\" or \"24h\"")
+		}
+		if interval < minScheduleInterval {
+			return TriggerOptions{}, fmt.Errorf("schedule interval must be at least %s", minScheduleInterval)
+		}
+	case TriggerWebhook:
+	default:
+		return TriggerOptions{}, fmt.Errorf("trigger must be one of %s, %s or %s", TriggerEvent, TriggerSchedule, TriggerWebhook)
+	}
+	return options, nil
+}
+
+// NextRun returns when a schedule trigger with options next fires after now
+func NextRun(options TriggerOptions, now time.Time) time.Time {
+	interval, _ := time.ParseDuration(options.Interval)
+	if interval < minScheduleInterval {
+		interval = minScheduleInterval
+	}
+	return now.Add(interval)
+}
+
+// Matches reports whether an event trigger with options listens to event. An empty
+// collections list or a "*" entry matches everything.
+func Matches(options TriggerOptions, event events.Event) bool {
+	if !slices.Contains(options.Events, "*") && !slices.Contains(options.Events, event.Type) {
+		return false
+	}
+	return len(options.Collections) == 0 || slices.Contains(options.Collections, "*") || slices.Contains(options.Collections, event.Collection)
+}
+
+// ParseOperations validates the operations of a flow: unique keys, known types, the
+// options every type needs and template syntax
+func ParseOperations(raw json.RawMessage) ([]Operation, error) {
+	var operations []Operation
+	if err := json.Unmarshal(raw, &operations); err != nil {
+		return nil, fmt.Errorf("operations must be a list of {key, type, options} objects")
+	}
+	if len(operations) == 0 {
+		return nil, fmt.Errorf("a flow needs at least one operation")
+	}
+	if len(operations) > maxOperations {
+		return nil, fmt.Errorf("a flow can have at most %d operations", maxOperations)
+	}
+
+	seen := make(map[string]bool, len(operations))
+	for i, op := range operations {
+		if !operationKeyPattern.MatchString(op.Key) {
+			return nil, fmt.Errorf("operation %d needs a key of lowercase letters, digits and underscores", i+1)
+		}
+		if seen[op.Key] {
+			return nil, fmt.Errorf("duplicate operation key '%s'", op.Key)
+		}
+		seen[op.Key] = true
+
+		if err := validateOptions(op); err != nil {
+			return nil, fmt.Errorf("operation '%s': %w", op.Key, err)
+		}
+		if err := checkTemplates(op.Options); err != nil {
+			return nil, fmt.Errorf("operation '%s': %w", op.Key, err)
+		}
+	}
+	return operations, nil
+}
+
+// validateOptions checks that an operation has the options its type needs
+func validateOptions(op Operation) error {
+	var required []string
+	switch op.Type {
+	case OperationRequest:
+		required = []string{"url"}
+		if method, ok := op.Options["method"]; ok {
+			if m, _ := method.(string); !slices.Contains([]string{"GET", "POST", "PUT", "PATCH", "DELETE"}, m) {
+				return fmt.Errorf("method must be GET, POST, PUT, PATCH or DELETE")
+			}
+		}
+		if headers, ok := op.Options["headers"]; ok {
+			if _, ok := headers.(map[string]interface{}); !ok {
+				return fmt.Errorf("headers must be an object")
+			}
+		}
+	case OperationCreateItem:
+		required = []string{"collection"}
+		if _, ok := op.Options["data"].(map[string]interface{}); !ok {
+			return fmt.Errorf("data must be an object")
+		}
+	case OperationSendEmail:
+		required = []string{"to", "subject", "body"}
+	case OperationTemplate:
+		required = []string{"template"}
+	default:
+		return fmt.Errorf("unsupported operation type '%s'", op.Type)
+	}
+
+	for _, option := range required {
+		if value, _ := op.Options[option].(string); value == "" {
+			return fmt.Errorf("%s is required", option)
+		}
+	}
+	return nil
+}
+
+// checkTemplates parses every string in value to report syntax errors up front
+func checkTemplates(value interface{}) error {
+	switch v := value.(type) {
+	case string:
+		_, err := newTemplate(v)
+		return err
+	case map[string]interface{}:
+		for _, item := range v {
+			if err := checkTemplates(item); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, item := range v {
+			if err := checkTemplates(item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// newTemplate parses one option string
+func newTemplate(text string) (*template.Template, error) {
+	return template.New("option").Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			encoded, err := json.Marshal(value)
+			return string(encoded), err
+		},
+	}).Parse(text)
+}
+
+// render evaluates every string in value against scope, keeping the structure of maps
+// and lists
+func render(value interface{}, scope map[string]interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		return renderString(v, scope)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			out, err := render(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			rendered[key] = out
+		}
+		return rendered, nil
+	case []interface{}:
+		rendered := make([]interface{}, len(v))
+		for i, item := range v {
+			out, err := render(item, scope)
+			if err != nil {
+				return nil, err
+			}
+			rendered[i] = out
+		}
+		return rendered, nil
+	default:
+		return value, nil
+	}
+}
+
+// renderString evaluates one template string
+func renderString(text string, scope map[string]interface{}) (string, error) {
+	tmpl, err := newTemplate(text)
+	if err != nil {
+		return "", err
+	}
+	var out bytes.Buffer
+	if err := tmpl.Execute(&out, scope); err != nil {
+		return "", err
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,209 @@
+package flows
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseTrigger(t *testing.T) {
+	tests := []struct {
+		name    string
+		trigger string
+		options string
+		wantErr string
+	}{
+		{"Event", TriggerEvent, `{"events": ["item.create"], "collections": ["orders"]}`, ""},
+		{"Event Without Events", TriggerEvent, `{}`, "at least one event"},
+		{"Unknown Event", TriggerEvent, `{"events": ["item.publish"]}`, "unsupported trigger event"},
+		{"Invalid Collection", TriggerEvent, `{"events": ["*"], "collections": ["bad name"]}`, "invalid collection name"},
+		{"Schedule", TriggerSchedule, `{"interval": "15m"}`, ""},
+		{"Schedule Without Interval", TriggerSchedule, `{}`, "need an interval"},
+		{"Schedule Too Often", TriggerSchedule, `{"interval": "10s"}`, "at least 1m0s"},
+		{"Webhook", TriggerWebhook, `{}`, ""},
+		{"Unknown Trigger", "cron", `{}`, "trigger must be one of"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseTrigger(tt.trigger, json.RawMessage(tt.options))
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	event := events.Event{Type: events.ItemCreate, Collection: "orders"}
+
+	assert.True(t, Matches(TriggerOptions{Events: []string{"item.create"}}, event))
+	assert.True(t, Matches(TriggerOptions{Events: []string{"*"}, Collections: []string{"orders"}}, event))
+	assert.False(t, Matches(TriggerOptions{Events: []string{"item.delete"}}, event))
+	assert.False(t, Matches(TriggerOptions{Events: []string{"*"}, Collections: []string{"customers"}}, event))
+}
+
+func TestNextRun(t *testing.T) {
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	assert.Equal(t, now.Add(time.Hour), NextRun(TriggerOptions{Interval: "1h"}, now))
+	assert.Equal(t, now.Add(time.Minute), NextRun(TriggerOptions{}, now))
+}
+
+func TestParseOperations(t *testing.T) {
+	valid := `[
+		{"key": "summary", "type": "template", "options": {"template": "New order {{ .trigger.keys }}"}},
+		{"key": "notify", "type": "send_email", "options": {"to": "ops@example.com", "subject": "Order", "body": "{{ .last }}"}}
+	]`
+	operations, err := ParseOperations(json.RawMessage(valid))
+	require.NoError(t, err)
+	assert.Len(t, operations, 2)
+
+	tests := []struct {
+		name       string
+		operations string
+		wantErr    string
+	}{
+		{"Not A List", `{}`, "must be a list"},
+		{"Empty", `[]`, "at least one operation"},
+		{"Bad Key", `[{"key": "Notify", "type": "template", "options": {"template": "x"}}]`, "needs a key"},
+		{"Duplicate Key", `[{"key": "a", "type": "template", "options": {"template": "x"}}, {"key": "a", "type": "template", "options": {"template": "y"}}]`, "duplicate operation key"},
+		{"Unknown Type", `[{"key": "a", "type": "run_script", "options": {}}]`, "unsupported operation type"},
+		{"Missing Option", `[{"key": "a", "type": "request", "options": {}}]`, "url is required"},
+		{"Bad Method", `[{"key": "a", "type": "request", "options": {"url": "https://example.com", "method": "TRACE"}}]`, "method must be"},
+		{"Item Data", `[{"key": "a", "type": "create_item", "options": {"collection": "orders", "data": "x"}}]`, "data must be an object"},
+		{"Template Syntax", `[{"key": "a", "type": "template", "options": {"template": "{{ .trigger"}}]`, "operation 'a'"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseOperations(json.RawMessage(tt.operations))
+			assert.ErrorContains(t, err, tt.wantErr)
+		})
+	}
+}
+
+type itemRecorder struct {
+	tenantID, userID uuid.UUID
+	collection       string
+	data             map[string]interface{}
+}
+
+func (r *itemRecorder) CreateFlowItem(ctx context.Context, tenantID, userID uuid.UUID, collection string, data map[string]interface{}) (map[string]interface{}, error) {
+	r.tenantID, r.userID, r.collection, r.data = tenantID, userID, collection, data
+	return map[string]interface{}{"id": "item-1", "title": data["title"]}, nil
+}
+
+type mailRecorder struct{ to, subject, text string }
+
+func (m *mailRecorder) SendText(ctx context.Context, tenantID uuid.UUID, to string, subject string, text string) error {
+	m.to, m.subject, m.text = to, subject, text
+	return nil
+}
+
+func TestExecute(t *testing.T) {
+	var received map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &received))
+		assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+		assert.Equal(t, "Bearer abc", r.Header.Get("Authorization"))
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"ticket": 42}`))
+	}))
+	defer server.Close()
+
+	items, mailer := &itemRecorder{}, &mailRecorder{}
+	runner := &Runner{client: server.Client(), items: items, mailer: mailer}
+	owner := uuid.New()
+	flow := sqlc.Flow{
+		ID:        uuid.New(),
+		TenantID:  uuid.New(),
+		Name:      "Escalate",
+		CreatedBy: uuid.NullUUID{UUID: owner, Valid: true},
+		Operations: json.RawMessage(`[
+			{"key": "title", "type": "template", "options": {"template": "Order {{ index .trigger.data \"number\" }}"}},
+			{"key": "ticket", "type": "request", "options": {
+				"url": "` + server.URL + `",
+				"headers": {"Authorization": "Bearer abc"},
+				"body": {"summary": "{{ .steps.title }}"}
+			}},
+			{"key": "task", "type": "create_item", "options": {"collection": "tasks", "data": {"title": "{{ .steps.title }} (#{{ .last.body.ticket }})"}}},
+			{"key": "notify", "type": "send_email", "options": {"to": "ops@example.com", "subject": "{{ .flow.name }}", "body": "Created {{ .steps.task.id }}"}}
+		]`),
+	}
+	payload := map[string]interface{}{"data": map[string]interface{}{"number": "A-7"}}
+
+	steps, err := runner.Execute(context.Background(), flow, payload)
+	require.NoError(t, err)
+	require.Len(t, steps, 4)
+	for _, step := range steps {
+		assert.Equal(t, StatusSuccess, step.Status, step.Key)
+	}
+
+	assert.Equal(t, "Order A-7", steps[0].Result)
+	assert.Equal(t, map[string]interface{}{"summary": "Order A-7"}, received)
+	assert.Equal(t, owner, items.userID)
+	assert.Equal(t, flow.TenantID, items.tenantID)
+	assert.Equal(t, "tasks", items.collection)
+	assert.Equal(t, map[string]interface{}{"title": "Order A-7 (#42)"}, items.data)
+	assert.Equal(t, "ops@example.com", mailer.to)
+	assert.Equal(t, "Escalate", mailer.subject)
+	assert.Equal(t, "Created item-1", mailer.text)
+}
+
+func TestExecute_StopsAtFailure(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream down"))
+	}))
+	defer server.Close()
+
+	mailer := &mailRecorder{}
+	runner := &Runner{client: server.Client(), mailer: mailer}
+	flow := sqlc.Flow{Operations: json.RawMessage(`[
+		{"key": "call", "type": "request", "options": {"url": "` + server.URL + `", "method": "GET"}},
+		{"key": "notify", "type": "send_email", "options": {"to": "ops@example.com", "subject": "s", "body": "b"}}
+	]`)}
+
+	steps, err := runner.Execute(context.Background(), flow, nil)
+	assert.ErrorContains(t, err, "operation 'call' failed: unexpected status 502")
+	require.Len(t, steps, 1)
+	assert.Equal(t, StatusFailed, steps[0].Status)
+	assert.Equal(t, map[string]interface{}{"status": 502, "body": "upstream down"}, steps[0].Result)
+	assert.Empty(t, mailer.to)
+}
+
+func TestExecute_MissingKey(t *testing.T) {
+	runner := &Runner{}
+	flow := sqlc.Flow{Operations: json.RawMessage(`[{"key": "a", "type": "template", "options": {"template": "{{ .trigger.missing }}"}}]`)}
+
+	_, err := runner.Execute(context.Background(), flow, map[string]interface{}{})
+	assert.ErrorContains(t, err, "missing")
+
+	steps, err := runner.Execute(context.Background(), sqlc.Flow{CreatedBy: uuid.NullUUID{}, Operations: json.RawMessage(
+		`[{"key": "a", "type": "create_item", "options": {"collection": "tasks", "data": {}}}]`)}, nil)
+	assert.ErrorContains(t, err, "cannot be created")
+	assert.Len(t, steps, 1)
+}
+
+func TestHandleEvent_IgnoresFlowEvents(t *testing.T) {
+	// A nil database would panic if the event were handled
+	runner := &Runner{}
+	ctx := context.WithValue(context.Background(), runKey{}, uuid.New())
+	assert.NotPanics(t, func() {
+		runner.HandleEvent(ctx, events.Event{Type: events.ItemCreate})
+	})
+}
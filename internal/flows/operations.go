@@ -0,0 +1,130 @@
+package flows
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+)
+
+// maxResponseBody is how many bytes of a request operation's response are kept
+const maxResponseBody = 64 * 1024
+
+// run renders the options of op against scope and carries it out
+func (r *Runner) run(ctx context.Context, flow sqlc.Flow, op Operation, scope map[string]interface{}) (interface{}, error) {
+	rendered, err := render(op.Options, scope)
+	if err != nil {
+		return nil, err
+	}
+	options, _ := rendered.(map[string]interface{})
+
+	switch op.Type {
+	case OperationRequest:
+		return r.request(ctx, options)
+	case OperationCreateItem:
+		return r.createItem(ctx, flow, options)
+	case OperationSendEmail:
+		return r.sendEmail(ctx, flow, options)
+	case OperationTemplate:
+		return options["template"], nil
+	default:
+		return nil, fmt.Errorf("unsupported operation type '%s'", op.Type)
+	}
+}
+
+// request sends an HTTP request. String bodies are sent as they are; any other body is
+// encoded as JSON. The result holds the status and the body, decoded when it is JSON.
+func (r *Runner) request(ctx context.Context, options map[string]interface{}) (interface{}, error) {
+	target, _ := options["url"].(string)
+	parsed, err := url.Parse(target)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("url must be an absolute http or https URL")
+	}
+
+	method, _ := options["method"].(string)
+	if method == "" {
+		method = http.MethodPost
+	}
+
+	var body io.Reader
+	contentType := ""
+	switch b := options["body"].(type) {
+	case nil:
+	case string:
+		body = bytes.NewReader([]byte(b))
+	default:
+		encoded, err := json.Marshal(b)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode body: %w", err)
+		}
+		body = bytes.NewReader(encoded)
+		contentType = "application/json"
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid request: %w", err)
+	}
+	req.Header.Set("User-Agent", "Basin-Flows/1.0")
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	if headers, ok := options["headers"].(map[string]interface{}); ok {
+		for name, value := range headers {
+			req.Header.Set(name, fmt.Sprint(value))
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	raw, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	var decoded interface{} = string(raw)
+	var parsedBody interface{}
+	if json.Unmarshal(raw, &parsedBody) == nil {
+		decoded = parsedBody
+	}
+
+	result := map[string]interface{}{"status": resp.StatusCode, "body": decoded}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return result, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return result, nil
+}
+
+// createItem creates an item as the flow's owner and returns it
+func (r *Runner) createItem(ctx context.Context, flow sqlc.Flow, options map[string]interface{}) (interface{}, error) {
+	if r.items == nil {
+		return nil, fmt.Errorf("items cannot be created by this server")
+	}
+	if !flow.CreatedBy.Valid {
+		return nil, fmt.Errorf("flow has no owner to create items as")
+	}
+
+	collection, _ := options["collection"].(string)
+	data, _ := options["data"].(map[string]interface{})
+	return r.items.CreateFlowItem(ctx, flow.TenantID, flow.CreatedBy.UUID, collection, data)
+}
+
+// sendEmail sends a plain text email from the tenant's sender
+func (r *Runner) sendEmail(ctx context.Context, flow sqlc.Flow, options map[string]interface{}) (interface{}, error) {
+	if r.mailer == nil {
+		return nil, fmt.Errorf("email is not configured on this server")
+	}
+
+	to, _ := options["to"].(string)
+	subject, _ := options["subject"].(string)
+	body, _ := options["body"].(string)
+	if err := r.mailer.SendText(ctx, flow.TenantID, to, subject, body); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"to": to, "subject": subject}, nil
+}
@@ -0,0 +1,276 @@
+package flows
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+)
+
+const (
+	pollInterval      = 5 * time.Second  // How often the worker looks for due schedules and queued runs
+	claimBatchSize    = 10               // Runs claimed per worker pass
+	staleClaimTimeout = 30 * time.Minute // Claims older than this are assumed abandoned
+)
+
+// ItemCreator creates collection items for create_item operations. Items are created as
+// userID, the flow's owner, and only where that user may create items.
+type ItemCreator interface {
+	CreateFlowItem(ctx context.Context, tenantID, userID uuid.UUID, collection string, data map[string]interface{}) (map[string]interface{}, error)
+}
+
+// Mailer sends the emails of send_email operations; *mail.Mailer implements it
+type Mailer interface {
+	SendText(ctx context.Context, tenantID uuid.UUID, to string, subject string, text string) error
+}
+
+// runKey marks the context of a running flow
+type runKey struct{}
+
+// Runner queues flow runs for triggers and executes them
+type Runner struct {
+	db     *db.DB
+	client *http.Client
+	items  ItemCreator
+	mailer Mailer
+	wake   chan struct{}
+}
+
+// NewRunner creates a Runner. Request operations use the webhook timeout from cfg.
+func NewRunner(db *db.DB, cfg *config.Config, items ItemCreator, mailer Mailer) *Runner {
+	return &Runner{
+		db:     db,
+		client: &http.Client{Timeout: cfg.WebhookTimeout},
+		items:  items,
+		mailer: mailer,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// HandleEvent queues a run for every active event flow of the tenant that listens to
+// event. It is meant to be subscribed to the events bus. Events caused by a running flow
+// are ignored, so a flow that creates items cannot trigger itself in a loop.
+func (r *Runner) HandleEvent(ctx context.Context, event events.Event) {
+	if ctx.Value(runKey{}) != nil {
+		return
+	}
+	// The request that triggered the event may finish before the runs are queued
+	ctx = context.WithoutCancel(ctx)
+
+	flows, err := r.db.Queries.GetActiveEventFlowsByTenant(ctx, event.TenantID)
+	if err != nil {
+		slog.Error("failed to load event flows", "tenant_id", event.TenantID, "error", err)
+		return
+	}
+
+	queued := false
+	for _, flow := range flows {
+		options, err := ParseTrigger(flow.Trigger, flow.TriggerOptions)
+		if err != nil || !Matches(options, event) {
+			continue
+		}
+		if _, err := r.queue(ctx, flow, event); err != nil {
+			slog.Error("failed to queue flow run", "flow_id", flow.ID, "error", err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		r.notify()
+	}
+}
+
+// Trigger queues a run of flow with the given payload, as for an incoming webhook
+func (r *Runner) Trigger(ctx context.Context, flow sqlc.Flow, payload interface{}) (sqlc.FlowRun, error) {
+	run, err := r.queue(ctx, flow, payload)
+	if err != nil {
+		return sqlc.FlowRun{}, err
+	}
+	r.notify()
+	return run, nil
+}
+
+// queue records a pending run of flow
+func (r *Runner) queue(ctx context.Context, flow sqlc.Flow, payload interface{}) (sqlc.FlowRun, error) {
+	encoded, err := json.Marshal(payload)
+	if err != nil {
+		return sqlc.FlowRun{}, fmt.Errorf("failed to encode trigger payload: %w", err)
+	}
+
+	return r.db.Queries.CreateFlowRun(ctx, sqlc.CreateFlowRunParams{
+		FlowID:   flow.ID,
+		TenantID: flow.TenantID,
+		Trigger:  flow.Trigger,
+		Payload:  encoded,
+	})
+}
+
+// notify wakes the worker without blocking
+func (r *Runner) notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Start runs the schedule and run workers until ctx is cancelled
+func (r *Runner) Start(ctx context.Context) {
+	// Runs claimed by a worker that died mid-run would otherwise stay stuck
+	staleBefore := sql.NullTime{Time: time.Now().Add(-staleClaimTimeout), Valid: true}
+	if err := r.db.Queries.ReleaseStaleFlowRuns(ctx, staleBefore); err != nil {
+		slog.Error("failed to release stale flow runs", "error", err)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		r.scheduleDue(ctx)
+		r.processPending(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-r.wake:
+		}
+	}
+}
+
+// scheduleDue queues a run for every schedule flow whose next run has come. The schedule
+// is moved forward first, so only one instance queues each run.
+func (r *Runner) scheduleDue(ctx context.Context) {
+	flows, err := r.db.Queries.GetDueScheduledFlows(ctx, claimBatchSize)
+	if err != nil {
+		slog.Error("failed to load due flow schedules", "error", err)
+		return
+	}
+
+	now := time.Now()
+	for _, flow := range flows {
+		options, err := ParseTrigger(flow.Trigger, flow.TriggerOptions)
+		if err != nil {
+			slog.Warn("skipping invalid flow schedule", "flow_id", flow.ID, "error", err)
+			continue
+		}
+
+		advanced, err := r.db.Queries.AdvanceFlowSchedule(ctx, sqlc.AdvanceFlowScheduleParams{
+			ID:          flow.ID,
+			NextRunAt:   flow.NextRunAt,
+			NextRunAt_2: sql.NullTime{Time: NextRun(options, now), Valid: true},
+		})
+		if err != nil {
+			slog.Error("failed to advance flow schedule", "flow_id", flow.ID, "error", err)
+			continue
+		}
+		if advanced == 0 {
+			continue // Another instance took this run
+		}
+
+		payload := map[string]interface{}{"scheduled_at": flow.NextRunAt.Time, "interval": options.Interval}
+		if _, err := r.queue(ctx, flow, payload); err != nil {
+			slog.Error("failed to queue flow run", "flow_id", flow.ID, "error", err)
+		}
+	}
+}
+
+// processPending claims and executes queued runs until none are left
+func (r *Runner) processPending(ctx context.Context) {
+	for ctx.Err() == nil {
+		runs, err := r.db.Queries.ClaimPendingFlowRuns(ctx, claimBatchSize)
+		if err != nil {
+			slog.Error("failed to claim flow runs", "error", err)
+			return
+		}
+		if len(runs) == 0 {
+			return
+		}
+
+		for _, run := range runs {
+			r.process(ctx, run)
+		}
+	}
+}
+
+// process executes one claimed run and records its outcome
+func (r *Runner) process(ctx context.Context, run sqlc.FlowRun) {
+	result := sqlc.FinishFlowRunParams{ID: run.ID, Status: StatusFailed, Steps: json.RawMessage("[]")}
+
+	flow, err := r.db.Queries.GetFlowByID(ctx, run.FlowID)
+	if err != nil {
+		result.Error = sql.NullString{String: "flow not found: " + err.Error(), Valid: true}
+		r.finish(ctx, run, result)
+		return
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(run.Payload, &payload); err != nil {
+		result.Error = sql.NullString{String: "invalid trigger payload: " + err.Error(), Valid: true}
+		r.finish(ctx, run, result)
+		return
+	}
+
+	steps, err := r.Execute(ctx, flow, payload)
+	if encoded, encodeErr := json.Marshal(steps); encodeErr == nil {
+		result.Steps = encoded
+	}
+	if err != nil {
+		result.Error = sql.NullString{String: err.Error(), Valid: true}
+	} else {
+		result.Status = StatusSuccess
+	}
+	r.finish(ctx, run, result)
+}
+
+// Execute runs the operations of flow in order for a trigger payload and returns the
+// result of each. It stops at the first operation that fails and returns its error.
+func (r *Runner) Execute(ctx context.Context, flow sqlc.Flow, payload interface{}) ([]Step, error) {
+	operations, err := ParseOperations(flow.Operations)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = context.WithValue(ctx, runKey{}, flow.ID)
+	results := make(map[string]interface{}, len(operations))
+	scope := map[string]interface{}{
+		"trigger": payload,
+		"steps":   results,
+		"last":    nil,
+		"flow":    map[string]interface{}{"id": flow.ID.String(), "name": flow.Name},
+	}
+
+	steps := make([]Step, 0, len(operations))
+	for _, op := range operations {
+		started := time.Now()
+		result, err := r.run(ctx, flow, op, scope)
+		step := Step{Key: op.Key, Type: op.Type, Status: StatusSuccess, Result: result, DurationMs: time.Since(started).Milliseconds()}
+		if err != nil {
+			step.Status = StatusFailed
+			step.Error = err.Error()
+			return append(steps, step), fmt.Errorf("operation '%s' failed: %w", op.Key, err)
+		}
+		steps = append(steps, step)
+
+		results[op.Key] = result
+		scope["last"] = result
+	}
+	return steps, nil
+}
+
+// finish stores the result of run
+func (r *Runner) finish(ctx context.Context, run sqlc.FlowRun, result sqlc.FinishFlowRunParams) {
+	if err := r.db.Queries.FinishFlowRun(ctx, result); err != nil {
+		slog.Error("failed to record flow run", "flow_id", run.FlowID, "run_id", run.ID, "error", err)
+	}
+}
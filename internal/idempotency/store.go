@@ -0,0 +1,134 @@
+// Package idempotency stores the responses of write requests sent with an Idempotency-Key
+// header, so a retry of one (e.g. after a dropped connection) is answered with the first
+// response instead of being carried out again.
+//
+// Keys are scoped to the user sending them. The first request with a key claims it and
+// runs; its response is stored for the TTL (IDEMPOTENCY_TTL). A retry with the same key
+// and the same request gets the stored response, one with a different request is refused,
+// and one sent while the first is still running is told to try again. Expired keys are
+// removed periodically by Start.
+package idempotency
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+)
+
+const (
+	// pendingTimeout is how long a claimed key without a response is held. A request still
+	// running after that is assumed abandoned (e.g. the server stopped), so a retry can
+	// claim the key again.
+	pendingTimeout = 5 * time.Minute
+	purgeInterval  = time.Hour // How often expired keys are deleted
+)
+
+var (
+	// ErrInProgress is returned while the first request with a key has not finished
+	ErrInProgress = errors.New("a request with this idempotency key is still in progress")
+	// ErrKeyReused is returned when a key is sent again with a different request
+	ErrKeyReused = errors.New("idempotency key was already used for a different request")
+)
+
+// Response is a stored response
+type Response struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+// Store keeps idempotency keys and their responses in the database
+type Store struct {
+	db  *db.DB
+	ttl time.Duration
+}
+
+// NewStore creates a Store keeping responses for ttl
+func NewStore(db *db.DB, ttl time.Duration) *Store {
+	return &Store{db: db, ttl: ttl}
+}
+
+// Begin claims key for a request identified by requestHash. It returns nil when the key
+// was claimed and the request should run, the stored response when the request already
+// ran, or ErrInProgress or ErrKeyReused.
+func (s *Store) Begin(ctx context.Context, userID uuid.UUID, key, requestHash string) (*Response, error) {
+	now := time.Now()
+	_, err := s.db.Queries.ClaimIdempotencyKey(ctx, sqlc.ClaimIdempotencyKeyParams{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: requestHash,
+		ExpiresAt:   now.Add(s.ttl),
+		CreatedAt:   now.Add(-pendingTimeout),
+	})
+	if err == nil {
+		return nil, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	record, err := s.db.Queries.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{UserID: userID, Key: key})
+	if errors.Is(err, sql.ErrNoRows) {
+		// Released by a failed first request in the meantime
+		return nil, ErrInProgress
+	}
+	if err != nil {
+		return nil, err
+	}
+	if record.RequestHash != requestHash {
+		return nil, ErrKeyReused
+	}
+	if !record.StatusCode.Valid {
+		return nil, ErrInProgress
+	}
+	return &Response{
+		StatusCode:  int(record.StatusCode.Int32),
+		ContentType: record.ContentType.String,
+		Body:        record.ResponseBody,
+	}, nil
+}
+
+// Complete stores the response of the request that claimed key
+func (s *Store) Complete(ctx context.Context, userID uuid.UUID, key string, response Response) error {
+	return s.db.Queries.CompleteIdempotencyKey(ctx, sqlc.CompleteIdempotencyKeyParams{
+		UserID:       userID,
+		Key:          key,
+		StatusCode:   sql.NullInt32{Int32: int32(response.StatusCode), Valid: true},
+		ContentType:  sql.NullString{String: response.ContentType, Valid: response.ContentType != ""},
+		ResponseBody: response.Body,
+	})
+}
+
+// Release gives up key without storing a response, so the request can be retried
+func (s *Store) Release(ctx context.Context, userID uuid.UUID, key string) error {
+	return s.db.Queries.DeleteIdempotencyKey(ctx, sqlc.DeleteIdempotencyKeyParams{UserID: userID, Key: key})
+}
+
+// Start deletes expired keys periodically until ctx is cancelled
+func (s *Store) Start(ctx context.Context) {
+	ticker := time.NewTicker(purgeInterval)
+	defer ticker.Stop()
+
+	for {
+		if deleted, err := s.db.Queries.DeleteExpiredIdempotencyKeys(ctx, time.Now()); err != nil {
+			if ctx.Err() == nil {
+				slog.Error("failed to delete expired idempotency keys", "error", err)
+			}
+		} else if deleted > 0 {
+			slog.Info("deleted expired idempotency keys", "count", deleted)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package idempotency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// adminID is the user seeded by the SQLite schema; keys belong to an existing user
+var adminID = uuid.MustParse("38eae290-37b8-46a7-82ee-ae842d85c894")
+
+func TestStore(t *testing.T) {
+	database := dbtest.SQLite(t)
+	store := NewStore(database, time.Hour)
+	ctx := context.Background()
+
+	response, err := store.Begin(ctx, adminID, "create-order", "hash-a")
+	require.NoError(t, err)
+	assert.Nil(t, response, "the first request claims the key and runs")
+
+	t.Run("In Progress Until Completed", func(t *testing.T) {
+		_, err := store.Begin(ctx, adminID, "create-order", "hash-a")
+		assert.ErrorIs(t, err, ErrInProgress)
+	})
+
+	t.Run("Key Reused For Another Request", func(t *testing.T) {
+		_, err := store.Begin(ctx, adminID, "create-order", "hash-b")
+		assert.ErrorIs(t, err, ErrKeyReused)
+	})
+
+	t.Run("Replay After Complete", func(t *testing.T) {
+		stored := Response{StatusCode: 201, ContentType: "application/json", Body: []byte(`{"id":1}`)}
+		require.NoError(t, store.Complete(ctx, adminID, "create-order", stored))
+
+		response, err := store.Begin(ctx, adminID, "create-order", "hash-a")
+		require.NoError(t, err)
+		assert.Equal(t, &stored, response)
+
+		_, err = store.Begin(ctx, adminID, "create-order", "hash-b")
+		assert.ErrorIs(t, err, ErrKeyReused, "a completed key still refuses other requests")
+	})
+
+	t.Run("Released Keys Can Be Claimed Again", func(t *testing.T) {
+		_, err := store.Begin(ctx, adminID, "upload", "hash-a")
+		require.NoError(t, err)
+		require.NoError(t, store.Release(ctx, adminID, "upload"))
+
+		response, err := store.Begin(ctx, adminID, "upload", "hash-b")
+		require.NoError(t, err)
+		assert.Nil(t, response)
+	})
+}
+
+func TestStore_Expiry(t *testing.T) {
+	database := dbtest.SQLite(t)
+	// A negative TTL stores keys that have expired already
+	store := NewStore(database, -time.Minute)
+	ctx := context.Background()
+
+	_, err := store.Begin(ctx, adminID, "create-order", "hash-a")
+	require.NoError(t, err)
+	require.NoError(t, store.Complete(ctx, adminID, "create-order", Response{StatusCode: 201}))
+
+	// After the TTL the key is free for any request, and is not replayed
+	response, err := store.Begin(ctx, adminID, "create-order", "hash-b")
+	require.NoError(t, err)
+	assert.Nil(t, response)
+
+	// Expired keys are deleted
+	_, err = store.Begin(ctx, adminID, "stale", "hash-a")
+	require.NoError(t, err)
+	deleted, err := database.Queries.DeleteExpiredIdempotencyKeys(ctx, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), deleted)
+	_, err = database.Queries.GetIdempotencyKey(ctx, sqlc.GetIdempotencyKeyParams{UserID: adminID, Key: "stale"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,201 @@
+// Package imaging resizes uploaded images on the fly. Only formats supported by the
+// standard library are handled: JPEG, PNG and GIF (GIFs are re-encoded as PNG).
+package imaging
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/gif" // Register the GIF decoder
+	"image/jpeg"
+	"image/png"
+	"io"
+)
+
+// Fit modes, following the CSS object-fit vocabulary
+const (
+	FitCover   = "cover"   // Fill the box, cropping the overflow (default)
+	FitContain = "contain" // Fit inside the box, keeping the aspect ratio
+	FitInside  = "inside"  // Like contain, but never enlarge the image
+	FitFill    = "fill"    // Stretch to exactly the requested size
+)
+
+const (
+	// MaxDimension bounds requested widths and heights
+	MaxDimension = 4096
+	// DefaultQuality is the JPEG quality used when none is requested
+	DefaultQuality = 85
+)
+
+// Options describes a resize request. A zero Width or Height is derived from the other
+// dimension so the aspect ratio is kept.
+type Options struct {
+	Width   int
+	Height  int
+	Fit     string
+	Quality int
+}
+
+// Validate checks the options and fills in defaults
+func (o *Options) Validate() error {
+	if o.Width < 0 || o.Height < 0 || o.Width > MaxDimension || o.Height > MaxDimension {
+		return fmt.Errorf("width and height must be between 1 and %d", MaxDimension)
+	}
+	switch o.Fit {
+	case "":
+		o.Fit = FitCover
+	case FitCover, FitContain, FitInside, FitFill:
+	default:
+		return fmt.Errorf("fit must be one of cover, contain, inside, fill")
+	}
+	if o.Quality == 0 {
+		o.Quality = DefaultQuality
+	}
+	if o.Quality < 1 || o.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100")
+	}
+	return nil
+}
+
+// Supported reports whether contentType can be resized
+func Supported(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Dimensions reads the width and height from an image header without decoding the pixels
+func Dimensions(r io.Reader) (int, int, error) {
+	config, _, err := image.DecodeConfig(r)
+	if err != nil {
+		return 0, 0, err
+	}
+	return config.Width, config.Height, nil
+}
+
+// Transform decodes the image in r, resizes it and re-encodes it. It returns the encoded
+// image and its content type.
+func Transform(r io.Reader, contentType string, opts Options) ([]byte, string, error) {
+	if !Supported(contentType) {
+		return nil, "", fmt.Errorf("images of type %s cannot be transformed", contentType)
+	}
+	if err := opts.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	dst := Resize(src, opts)
+
+	var buf bytes.Buffer
+	if contentType == "image/jpeg" {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: opts.Quality})
+	} else {
+		contentType = "image/png"
+		err = png.Encode(&buf, dst)
+	}
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to encode image: %w", err)
+	}
+	return buf.Bytes(), contentType, nil
+}
+
+// Resize returns src scaled according to opts
+func Resize(src image.Image, opts Options) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW == 0 || srcH == 0 || (opts.Width == 0 && opts.Height == 0) {
+		return src
+	}
+
+	// Derive a missing dimension from the aspect ratio
+	boxW, boxH := opts.Width, opts.Height
+	if boxW == 0 {
+		boxW = max(1, srcW*boxH/srcH)
+	}
+	if boxH == 0 {
+		boxH = max(1, srcH*boxW/srcW)
+	}
+
+	crop := bounds
+	outW, outH := boxW, boxH
+
+	switch opts.Fit {
+	case FitFill:
+		// Stretch the whole image into the box
+	case FitContain, FitInside:
+		// Scale by the tighter ratio so the whole image fits
+		if srcW*boxH > srcH*boxW {
+			outH = max(1, srcH*boxW/srcW)
+		} else {
+			outW = max(1, srcW*boxH/srcH)
+		}
+		if opts.Fit == FitInside && outW > srcW {
+			outW, outH = srcW, srcH
+		}
+	default:
+		// Cover: crop the source to the box's aspect ratio around the center
+		if srcW*boxH > srcH*boxW {
+			cropW := srcH * boxW / boxH
+			x := bounds.Min.X + (srcW-cropW)/2
+			crop = image.Rect(x, bounds.Min.Y, x+cropW, bounds.Max.Y)
+		} else {
+			cropH := srcW * boxH / boxW
+			y := bounds.Min.Y + (srcH-cropH)/2
+			crop = image.Rect(bounds.Min.X, y, bounds.Max.X, y+cropH)
+		}
+	}
+
+	return scale(src, crop, outW, outH)
+}
+
+// scale resamples the crop rectangle of src to w x h. Each destination pixel is the
+// average of the source pixels it covers, which avoids aliasing when shrinking.
+func scale(src image.Image, crop image.Rectangle, w, h int) *image.RGBA {
+	// Work on RGBA pixels directly; converting once is much faster than At() per sample
+	rgba, ok := src.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(src.Bounds())
+		draw.Draw(rgba, rgba.Bounds(), src, src.Bounds().Min, draw.Src)
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	cropW, cropH := crop.Dx(), crop.Dy()
+
+	for y := 0; y < h; y++ {
+		y0 := crop.Min.Y + y*cropH/h
+		y1 := max(y0+1, crop.Min.Y+(y+1)*cropH/h)
+
+		for x := 0; x < w; x++ {
+			x0 := crop.Min.X + x*cropW/w
+			x1 := max(x0+1, crop.Min.X+(x+1)*cropW/w)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				offset := rgba.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += uint32(rgba.Pix[offset])
+					g += uint32(rgba.Pix[offset+1])
+					b += uint32(rgba.Pix[offset+2])
+					a += uint32(rgba.Pix[offset+3])
+					offset += 4
+					n++
+				}
+			}
+
+			i := dst.PixOffset(x, y)
+			dst.Pix[i] = uint8(r / n)
+			dst.Pix[i+1] = uint8(g / n)
+			dst.Pix[i+2] = uint8(b / n)
+			dst.Pix[i+3] = uint8(a / n)
+		}
+	}
+
+	return dst
+}
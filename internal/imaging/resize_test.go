@@ -0,0 +1,80 @@
+package imaging
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResize(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 200))
+
+	tests := []struct {
+		name          string
+		opts          Options
+		width, height int
+	}{
+		{"Cover", Options{Width: 100, Height: 100, Fit: FitCover}, 100, 100},
+		{"Contain", Options{Width: 100, Height: 100, Fit: FitContain}, 100, 50},
+		{"Inside Never Enlarges", Options{Width: 1000, Height: 1000, Fit: FitInside}, 400, 200},
+		{"Fill", Options{Width: 50, Height: 80, Fit: FitFill}, 50, 80},
+		{"Width Only", Options{Width: 200, Fit: FitCover}, 200, 100},
+		{"Height Only", Options{Height: 50, Fit: FitCover}, 100, 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			bounds := Resize(src, tt.opts).Bounds()
+			assert.Equal(t, tt.width, bounds.Dx())
+			assert.Equal(t, tt.height, bounds.Dy())
+		})
+	}
+}
+
+func TestResizeAveragesPixels(t *testing.T) {
+	// Left half black, right half white: shrinking to 1x1 gives mid grey
+	src := image.NewRGBA(image.Rect(0, 0, 2, 1))
+	src.Set(0, 0, color.RGBA{0, 0, 0, 255})
+	src.Set(1, 0, color.RGBA{254, 254, 254, 255})
+
+	dst := Resize(src, Options{Width: 1, Height: 1, Fit: FitFill})
+	assert.Equal(t, color.RGBA{127, 127, 127, 255}, dst.At(0, 0))
+}
+
+func TestTransform(t *testing.T) {
+	var buf bytes.Buffer
+	require.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 64, 32))))
+
+	width, height, err := Dimensions(bytes.NewReader(buf.Bytes()))
+	require.NoError(t, err)
+	assert.Equal(t, 64, width)
+	assert.Equal(t, 32, height)
+
+	out, contentType, err := Transform(bytes.NewReader(buf.Bytes()), "image/png", Options{Width: 16})
+	require.NoError(t, err)
+	assert.Equal(t, "image/png", contentType)
+
+	width, height, err = Dimensions(bytes.NewReader(out))
+	require.NoError(t, err)
+	assert.Equal(t, 16, width)
+	assert.Equal(t, 8, height)
+
+	_, _, err = Transform(bytes.NewReader(buf.Bytes()), "application/pdf", Options{Width: 16})
+	assert.Error(t, err)
+}
+
+func TestOptionsValidate(t *testing.T) {
+	opts := Options{Width: 10}
+	require.NoError(t, opts.Validate())
+	assert.Equal(t, FitCover, opts.Fit)
+	assert.Equal(t, DefaultQuality, opts.Quality)
+
+	assert.Error(t, (&Options{Width: MaxDimension + 1}).Validate())
+	assert.Error(t, (&Options{Width: 10, Fit: "stretch"}).Validate())
+	assert.Error(t, (&Options{Width: 10, Quality: 101}).Validate())
+}
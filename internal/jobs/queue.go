@@ -0,0 +1,289 @@
+// Package jobs runs background work outside of requests.
+//
+// Jobs are stored in the jobs table, so queued work survives restarts and is shared by
+// every server instance: workers claim due jobs with FOR UPDATE SKIP LOCKED, so each job
+// is run by one instance at a time. Every job has a kind that selects the Handler
+// registered for it. A handler that returns an error (or panics) is retried with
+// exponential backoff; once a job has failed max_attempts times it is marked dead and
+// kept, with its last error, until an admin retries it through /items/jobs.
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+)
+
+// Job statuses stored in jobs.status
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusCompleted  = "completed"
+	StatusDead       = "dead"
+)
+
+const (
+	pollInterval      = 5 * time.Second  // How often the worker looks for due jobs
+	claimBatchSize    = 20               // Jobs claimed per worker pass
+	staleClaimTimeout = 30 * time.Minute // Claims older than this are assumed abandoned
+	maxRetryDelay     = 6 * time.Hour    // Upper bound for the exponential backoff
+	cleanupInterval   = time.Hour        // How often completed jobs past retention are deleted
+	maxErrorLength    = 2048             // Bytes of a handler error kept on the job
+)
+
+// Handler runs one job. Returning an error schedules a retry.
+type Handler func(ctx context.Context, job sqlc.Job) error
+
+// NewJob describes a job to enqueue
+type NewJob struct {
+	Kind        string
+	TenantID    uuid.UUID   // uuid.Nil for jobs that belong to no tenant
+	Payload     interface{} // Encoded as JSON
+	RunAt       time.Time   // Zero runs the job as soon as possible
+	MaxAttempts int         // Zero uses JOB_MAX_ATTEMPTS
+}
+
+// Queue enqueues jobs and runs them with the registered handlers
+type Queue struct {
+	db          *db.DB
+	maxAttempts int
+	retryDelay  time.Duration
+	retention   time.Duration
+	wake        chan struct{}
+
+	mu       sync.RWMutex
+	handlers map[string]Handler
+}
+
+// NewQueue creates a Queue using the job settings from cfg
+func NewQueue(db *db.DB, cfg *config.Config) *Queue {
+	maxAttempts := cfg.JobMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &Queue{
+		db:          db,
+		maxAttempts: maxAttempts,
+		retryDelay:  cfg.JobRetryDelay,
+		retention:   cfg.JobRetention,
+		wake:        make(chan struct{}, 1),
+		handlers:    make(map[string]Handler),
+	}
+}
+
+// Register sets the handler for jobs of kind. Handlers should be registered before
+// Start, and on every instance, so any instance can run any job.
+func (q *Queue) Register(kind string, handler Handler) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.handlers[kind] = handler
+}
+
+// Enqueue stores a job and wakes the worker
+func (q *Queue) Enqueue(ctx context.Context, job NewJob) (sqlc.Job, error) {
+	params, err := q.params(job, time.Now())
+	if err != nil {
+		return sqlc.Job{}, err
+	}
+
+	queued, err := q.db.Queries.EnqueueJob(ctx, params)
+	if err != nil {
+		return sqlc.Job{}, fmt.Errorf("failed to enqueue %s job: %w", job.Kind, err)
+	}
+
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return queued, nil
+}
+
+// params validates job and fills in its defaults
+func (q *Queue) params(job NewJob, now time.Time) (sqlc.EnqueueJobParams, error) {
+	if job.Kind == "" {
+		return sqlc.EnqueueJobParams{}, fmt.Errorf("job kind is required")
+	}
+
+	payload, err := json.Marshal(job.Payload)
+	if err != nil {
+		return sqlc.EnqueueJobParams{}, fmt.Errorf("failed to encode %s job payload: %w", job.Kind, err)
+	}
+	if job.Payload == nil {
+		payload = json.RawMessage("{}")
+	}
+
+	params := sqlc.EnqueueJobParams{
+		Kind:        job.Kind,
+		Payload:     payload,
+		MaxAttempts: int32(q.maxAttempts),
+		RunAt:       job.RunAt,
+	}
+	if job.TenantID != uuid.Nil {
+		params.TenantID = uuid.NullUUID{UUID: job.TenantID, Valid: true}
+	}
+	if job.MaxAttempts > 0 {
+		params.MaxAttempts = int32(job.MaxAttempts)
+	}
+	if params.RunAt.IsZero() {
+		params.RunAt = now
+	}
+	return params, nil
+}
+
+// SetProgress stores how far a running job has got, encoded as JSON. Handlers of long
+// jobs call it so clients can follow them.
+func (q *Queue) SetProgress(ctx context.Context, jobID uuid.UUID, progress interface{}) error {
+	encoded, err := json.Marshal(progress)
+	if err != nil {
+		return fmt.Errorf("failed to encode job progress: %w", err)
+	}
+	return q.db.Queries.SetJobProgress(ctx, sqlc.SetJobProgressParams{ID: jobID, Progress: encoded})
+}
+
+// Start runs the job worker until ctx is cancelled
+func (q *Queue) Start(ctx context.Context) {
+	// Jobs claimed by a worker that died mid-run would otherwise stay stuck
+	staleBefore := sql.NullTime{Time: time.Now().Add(-staleClaimTimeout), Valid: true}
+	if err := q.db.Queries.ReleaseStaleJobs(ctx, staleBefore); err != nil {
+		slog.Error("failed to release stale jobs", "error", err)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	var lastCleanup time.Time
+	for {
+		q.processDue(ctx)
+
+		if time.Since(lastCleanup) >= cleanupInterval {
+			q.cleanup(ctx)
+			lastCleanup = time.Now()
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-q.wake:
+		}
+	}
+}
+
+// processDue claims and runs due jobs until none are left
+func (q *Queue) processDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		jobs, err := q.db.Queries.ClaimDueJobs(ctx, claimBatchSize)
+		if err != nil {
+			slog.Error("failed to claim jobs", "error", err)
+			return
+		}
+		if len(jobs) == 0 {
+			return
+		}
+
+		for _, job := range jobs {
+			q.process(ctx, job)
+		}
+	}
+}
+
+// process runs one claimed job and records the outcome
+func (q *Queue) process(ctx context.Context, job sqlc.Job) {
+	if err := q.execute(ctx, job); err != nil {
+		result := q.failure(job, err, time.Now())
+		if result.Status == StatusDead {
+			slog.Error("job failed for good", "job_id", job.ID, "type", job.Kind, "attempt", job.Attempts, "error", err)
+		}
+		if err := q.db.Queries.FailJob(ctx, result); err != nil {
+			slog.Error("failed to record job failure", "job_id", job.ID, "type", job.Kind, "attempt", job.Attempts, "error", err)
+		}
+		return
+	}
+
+	if err := q.db.Queries.CompleteJob(ctx, job.ID); err != nil {
+		slog.Error("failed to complete job", "job_id", job.ID, "type", job.Kind, "attempt", job.Attempts, "error", err)
+	}
+}
+
+// execute runs the handler of job, turning a panic into an error
+func (q *Queue) execute(ctx context.Context, job sqlc.Job) (err error) {
+	q.mu.RLock()
+	handler, ok := q.handlers[job.Kind]
+	q.mu.RUnlock()
+	if !ok {
+		return fmt.Errorf("no handler registered for job kind '%s'", job.Kind)
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job handler panicked: %v", r)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// failure returns how a failed attempt of job is recorded: a retry after the backoff
+// delay, or the dead letter once its attempts are used up
+func (q *Queue) failure(job sqlc.Job, err error, now time.Time) sqlc.FailJobParams {
+	message := err.Error()
+	if len(message) > maxErrorLength {
+		message = message[:maxErrorLength]
+	}
+
+	result := sqlc.FailJobParams{
+		ID:        job.ID,
+		Status:    StatusPending,
+		LastError: sql.NullString{String: message, Valid: true},
+		RunAt:     now.Add(RetryDelay(q.retryDelay, int(job.Attempts))),
+	}
+	if job.Attempts >= job.MaxAttempts {
+		result.Status = StatusDead
+		result.RunAt = now
+	}
+	return result
+}
+
+// cleanup deletes completed jobs older than the retention period
+func (q *Queue) cleanup(ctx context.Context) {
+	if q.retention <= 0 {
+		return
+	}
+
+	before := sql.NullTime{Time: time.Now().Add(-q.retention), Valid: true}
+	deleted, err := q.db.Queries.DeleteCompletedJobs(ctx, before)
+	if err != nil {
+		slog.Error("failed to delete completed jobs", "error", err)
+		return
+	}
+	if deleted > 0 {
+		slog.Info("deleted completed jobs", "count", deleted)
+	}
+}
+
+// RetryDelay returns the wait before the next attempt after the given number of failed
+// attempts: base, 2*base, 4*base, ... capped at maxRetryDelay.
+func RetryDelay(base time.Duration, attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
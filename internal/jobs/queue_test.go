@@ -0,0 +1,86 @@
+package jobs
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestQueue() *Queue {
+	return NewQueue(nil, &config.Config{JobMaxAttempts: 3, JobRetryDelay: 30 * time.Second})
+}
+
+func TestParams(t *testing.T) {
+	q := newTestQueue()
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	tenantID := uuid.New()
+
+	params, err := q.params(NewJob{Kind: "mail.send", TenantID: tenantID, Payload: map[string]string{"to": "a@example.com"}}, now)
+	require.NoError(t, err)
+	assert.Equal(t, uuid.NullUUID{UUID: tenantID, Valid: true}, params.TenantID)
+	assert.JSONEq(t, `{"to": "a@example.com"}`, string(params.Payload))
+	assert.Equal(t, int32(3), params.MaxAttempts)
+	assert.Equal(t, now, params.RunAt)
+
+	later := now.Add(time.Hour)
+	params, err = q.params(NewJob{Kind: "report", RunAt: later, MaxAttempts: 1}, now)
+	require.NoError(t, err)
+	assert.False(t, params.TenantID.Valid)
+	assert.Equal(t, json.RawMessage("{}"), params.Payload)
+	assert.Equal(t, int32(1), params.MaxAttempts)
+	assert.Equal(t, later, params.RunAt)
+
+	_, err = q.params(NewJob{}, now)
+	assert.ErrorContains(t, err, "kind is required")
+}
+
+func TestExecute(t *testing.T) {
+	q := newTestQueue()
+	var ran sqlc.Job
+	q.Register("ok", func(ctx context.Context, job sqlc.Job) error {
+		ran = job
+		return nil
+	})
+	q.Register("panics", func(ctx context.Context, job sqlc.Job) error {
+		panic("boom")
+	})
+
+	job := sqlc.Job{ID: uuid.New(), Kind: "ok"}
+	assert.NoError(t, q.execute(context.Background(), job))
+	assert.Equal(t, job.ID, ran.ID)
+
+	assert.ErrorContains(t, q.execute(context.Background(), sqlc.Job{Kind: "panics"}), "panicked: boom")
+	assert.ErrorContains(t, q.execute(context.Background(), sqlc.Job{Kind: "missing"}), "no handler registered")
+}
+
+func TestFailure(t *testing.T) {
+	q := newTestQueue()
+	now := time.Date(2026, 5, 1, 9, 0, 0, 0, time.UTC)
+	job := sqlc.Job{ID: uuid.New(), Attempts: 2, MaxAttempts: 3}
+
+	retry := q.failure(job, errors.New("smtp unavailable"), now)
+	assert.Equal(t, StatusPending, retry.Status)
+	assert.Equal(t, "smtp unavailable", retry.LastError.String)
+	assert.Equal(t, now.Add(time.Minute), retry.RunAt)
+
+	job.Attempts = 3
+	dead := q.failure(job, errors.New(strings.Repeat("x", 5000)), now)
+	assert.Equal(t, StatusDead, dead.Status)
+	assert.Len(t, dead.LastError.String, maxErrorLength)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, RetryDelay(30*time.Second, 1))
+	assert.Equal(t, 120*time.Second, RetryDelay(30*time.Second, 3))
+	assert.Equal(t, maxRetryDelay, RetryDelay(30*time.Second, 50))
+}
@@ -0,0 +1,60 @@
+// Package logging sets up the application's structured logger.
+//
+// Logs are written with log/slog, as JSON (the default) or as text for local
+// development. Every HTTP request gets a child logger carrying its request ID, which the
+// request logging middleware stores in the request context; code handling the request
+// retrieves it with FromContext so its log lines can be correlated with the access log
+// and audit entries.
+package logging
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+
+	"go-rbac-api/internal/config"
+)
+
+// New creates a logger writing to w with the configured level and format
+func New(cfg *config.Config, w io.Writer) *slog.Logger {
+	options := &slog.HandlerOptions{Level: ParseLevel(cfg.LogLevel)}
+
+	var handler slog.Handler
+	if strings.EqualFold(cfg.LogFormat, "text") {
+		handler = slog.NewTextHandler(w, options)
+	} else {
+		handler = slog.NewJSONHandler(w, options)
+	}
+	return slog.New(handler)
+}
+
+// ParseLevel converts debug, info, warn or error to a level; anything else is info
+func ParseLevel(level string) slog.Level {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// loggerKey is the context key under which the request logger is stored
+type loggerKey struct{}
+
+// WithLogger returns a context carrying logger
+func WithLogger(ctx context.Context, logger *slog.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, logger)
+}
+
+// FromContext returns the logger stored in ctx, or the default logger
+func FromContext(ctx context.Context) *slog.Logger {
+	if logger, ok := ctx.Value(loggerKey{}).(*slog.Logger); ok {
+		return logger
+	}
+	return slog.Default()
+}
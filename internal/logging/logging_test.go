@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseLevel(t *testing.T) {
+	assert.Equal(t, slog.LevelDebug, ParseLevel("DEBUG"))
+	assert.Equal(t, slog.LevelWarn, ParseLevel("warning"))
+	assert.Equal(t, slog.LevelError, ParseLevel("error"))
+	assert.Equal(t, slog.LevelInfo, ParseLevel(""))
+	assert.Equal(t, slog.LevelInfo, ParseLevel("verbose"))
+}
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger := New(&config.Config{LogLevel: "warn", LogFormat: "json"}, &buf)
+
+	logger.Info("hidden")
+	logger.Warn("shown", "table", "products")
+
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "shown", entry["msg"])
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Equal(t, "products", entry["table"])
+
+	buf.Reset()
+	New(&config.Config{LogFormat: "text"}, &buf).Info("started", "port", 8080)
+	assert.Contains(t, buf.String(), "msg=started port=8080")
+}
+
+func TestFromContext(t *testing.T) {
+	assert.Equal(t, slog.Default(), FromContext(context.Background()))
+
+	logger := slog.New(slog.NewTextHandler(&bytes.Buffer{}, nil))
+	assert.Same(t, logger, FromContext(WithLogger(context.Background(), logger)))
+}
@@ -0,0 +1,112 @@
+// Package mail delivers the emails Basin sends to users, such as password reset links.
+//
+// Senders are pluggable: handlers depend on the Sender interface and the server picks
+// the implementation at startup with MAIL_DRIVER. Handlers usually go through a Mailer,
+// which renders one of the built-in templates and fills in the sender of the tenant.
+package mail
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	netmail "net/mail"
+	"strings"
+
+	"go-rbac-api/internal/config"
+)
+
+// Driver names accepted in MAIL_DRIVER
+const (
+	DriverLog      = "log"
+	DriverSMTP     = "smtp"
+	DriverSendGrid = "sendgrid"
+	DriverSES      = "ses"
+)
+
+// Address is an email address with an optional display name
+type Address struct {
+	Name  string
+	Email string
+}
+
+// String formats the address for a message header, e.g. "Basin <noreply@example.com>"
+func (a Address) String() string {
+	if a.Email == "" {
+		return ""
+	}
+	return (&netmail.Address{Name: a.Name, Address: a.Email}).String()
+}
+
+// Message is one email. HTML is optional; every message has a plain text body.
+type Message struct {
+	From    Address
+	ReplyTo string
+	To      []string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// validate checks the fields every driver needs
+func (m Message) validate() error {
+	if m.From.Email == "" {
+		return fmt.Errorf("message has no sender")
+	}
+	if len(m.To) == 0 {
+		return fmt.Errorf("message has no recipients")
+	}
+	for _, field := range append([]string{m.From.Name, m.From.Email, m.ReplyTo, m.Subject}, m.To...) {
+		if strings.ContainsAny(field, "\r\n") {
+			return fmt.Errorf("message header contains a line break")
+		}
+	}
+	return nil
+}
+
+// Sender delivers messages
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New creates the sender selected by cfg.MailDriver
+func New(cfg *config.Config, logger *slog.Logger) (Sender, error) {
+	switch strings.ToLower(cfg.MailDriver) {
+	case "", DriverLog:
+		return NewLogSender(logger), nil
+	case DriverSMTP:
+		return NewSMTPSender(SMTPOptions{
+			Host:     cfg.SMTPHost,
+			Port:     cfg.SMTPPort,
+			Username: cfg.SMTPUsername,
+			Password: cfg.SMTPPassword,
+		})
+	case DriverSendGrid:
+		return NewSendGridSender(SendGridOptions{APIKey: cfg.SendGridAPIKey})
+	case DriverSES:
+		return NewSESSender(SESOptions{
+			Region:          cfg.SESRegion,
+			AccessKeyID:     cfg.SESAccessKeyID,
+			SecretAccessKey: cfg.SESSecretAccessKey,
+		})
+	default:
+		return nil, fmt.Errorf("unknown mail driver '%s'", cfg.MailDriver)
+	}
+}
+
+// LogSender writes messages to the log instead of delivering them. It is meant for
+// development, where the links in a message can be copied from the server output.
+type LogSender struct {
+	logger *slog.Logger
+}
+
+// NewLogSender creates a LogSender
+func NewLogSender(logger *slog.Logger) *LogSender {
+	return &LogSender{logger: logger}
+}
+
+// Send logs the message
+func (s *LogSender) Send(ctx context.Context, msg Message) error {
+	s.logger.InfoContext(ctx, "email not delivered (log sender)",
+		"from", msg.From.String(), "to", strings.Join(msg.To, ", "), "subject", msg.Subject, "text", msg.Text)
+	return nil
+}
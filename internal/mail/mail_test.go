@@ -0,0 +1,236 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/jobs"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	msg, err := Render(TemplateWelcome, Data{Name: "Ada", Email: "ada@example.com", Tenant: "Acme"})
+	require.NoError(t, err)
+	assert.Equal(t, "Welcome to Acme", msg.Subject)
+	assert.Contains(t, msg.Text, "Hi Ada,")
+	assert.Contains(t, msg.Text, "sign in with ada@example.com")
+
+	msg, err = Render(TemplateInvite, Data{Email: "ada@example.com", Tenant: "<Acme & Co>", InvitedBy: "bob@example.com"})
+	require.NoError(t, err)
+	assert.Equal(t, "You have been added to <Acme & Co>", msg.Subject)
+	assert.Contains(t, msg.Text, "Hi there,")
+	assert.Contains(t, msg.Text, "bob@example.com added you to <Acme & Co>")
+	assert.Contains(t, msg.HTML, "<strong>&lt;Acme &amp; Co&gt;</strong>")
+
+	_, err = Render("missing", Data{})
+	assert.ErrorContains(t, err, "unknown mail template")
+}
+
+type tenantStore map[uuid.UUID]sqlc.Tenant
+
+func (s tenantStore) GetTenantByID(ctx context.Context, id uuid.UUID) (sqlc.Tenant, error) {
+	return s[id], nil
+}
+
+type recorder struct{ sent []Message }
+
+func (r *recorder) Send(ctx context.Context, msg Message) error {
+	r.sent = append(r.sent, msg)
+	return nil
+}
+
+func TestMailer_TenantSender(t *testing.T) {
+	custom, named, plain := uuid.New(), uuid.New(), uuid.New()
+	store := tenantStore{
+		custom: {ID: custom, Name: "Acme", Settings: pqtype.NullRawMessage{Valid: true,
+			RawMessage: json.RawMessage(`{"theme": "dark", "mail": {"from_email": "hello@acme.test", "reply_to": "support@acme.test"}}`)}},
+		named: {ID: named, Name: "Globex", Settings: pqtype.NullRawMessage{Valid: true,
+			RawMessage: json.RawMessage(`{"mail": {"from_name": "Globex Team"}}`)}},
+		plain: {ID: plain, Name: "Initech"},
+	}
+	sender := &recorder{}
+	mailer := NewMailer(sender, Address{Name: "Basin", Email: "noreply@example.com"}, store)
+
+	for _, tenantID := range []uuid.UUID{custom, named, plain, uuid.Nil} {
+		require.NoError(t, mailer.Send(context.Background(), tenantID, "ada@example.com", TemplateWelcome, Data{}))
+	}
+	require.Len(t, sender.sent, 4)
+
+	assert.Equal(t, Address{Name: "Acme", Email: "hello@acme.test"}, sender.sent[0].From)
+	assert.Equal(t, "support@acme.test", sender.sent[0].ReplyTo)
+	assert.Equal(t, "Welcome to Acme", sender.sent[0].Subject)
+	assert.Equal(t, []string{"ada@example.com"}, sender.sent[0].To)
+
+	assert.Equal(t, Address{Name: "Globex Team", Email: "noreply@example.com"}, sender.sent[1].From)
+	assert.Equal(t, Address{Name: "Basin", Email: "noreply@example.com"}, sender.sent[2].From)
+	assert.Equal(t, "Welcome", sender.sent[3].Subject)
+}
+
+func TestMailer_SendText(t *testing.T) {
+	tenantID := uuid.New()
+	store := tenantStore{tenantID: {ID: tenantID, Name: "Acme", Settings: pqtype.NullRawMessage{Valid: true,
+		RawMessage: json.RawMessage(`{"mail": {"from_email": "hello@acme.test"}}`)}}}
+	sender := &recorder{}
+	mailer := NewMailer(sender, Address{Name: "Basin", Email: "noreply@example.com"}, store)
+
+	require.NoError(t, mailer.SendText(context.Background(), tenantID, "ada@example.com", "Order shipped", "Your order is on its way.\n\n"))
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, Address{Name: "Acme", Email: "hello@acme.test"}, sender.sent[0].From)
+	assert.Equal(t, "Order shipped", sender.sent[0].Subject)
+	assert.Equal(t, "Your order is on its way.\n", sender.sent[0].Text)
+	assert.Empty(t, sender.sent[0].HTML)
+}
+
+func TestQueuedSender(t *testing.T) {
+	sender := &recorder{}
+	queued := NewQueuedSender(sender, jobs.NewQueue(nil, &config.Config{}))
+
+	// Invalid messages are rejected before they reach the queue
+	assert.ErrorContains(t, queued.Send(context.Background(), Message{To: []string{"ada@example.com"}}), "no sender")
+
+	msg := Message{From: Address{Name: "Acme", Email: "hello@acme.test"}, To: []string{"ada@example.com"}, Subject: "Hi", Text: "Hello\n"}
+	payload, err := json.Marshal(msg)
+	require.NoError(t, err)
+	require.NoError(t, queued.deliver(context.Background(), sqlc.Job{Kind: JobKind, Payload: payload}))
+	require.Len(t, sender.sent, 1)
+	assert.Equal(t, msg, sender.sent[0])
+
+	assert.ErrorContains(t, queued.deliver(context.Background(), sqlc.Job{Payload: json.RawMessage("[]")}), "invalid queued message")
+}
+
+func TestTenantSettings(t *testing.T) {
+	settings, err := ParseTenantSettings(nil)
+	require.NoError(t, err)
+	assert.Equal(t, TenantSettings{}, settings)
+
+	_, err = ParseTenantSettings([]byte(`{"mail": "x"}`))
+	assert.Error(t, err)
+
+	assert.NoError(t, TenantSettings{FromEmail: "Acme <hello@acme.test>"}.Validate())
+	assert.ErrorContains(t, TenantSettings{ReplyTo: "not an address"}.Validate(), "reply_to")
+}
+
+func TestMessageMIME(t *testing.T) {
+	msg := Message{
+		From:    Address{Name: "Basin", Email: "noreply@example.com"},
+		To:      []string{"ada@example.com"},
+		Subject: "Grüße",
+		Text:    "plain body",
+		HTML:    "<p>html body</p>",
+	}
+	body, err := msg.mime(time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC))
+	require.NoError(t, err)
+
+	s := string(body)
+	assert.Contains(t, s, "From: \"Basin\" <noreply@example.com>\r\n")
+	assert.Contains(t, s, "Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=\r\n")
+	assert.Contains(t, s, "Message-ID: <")
+	assert.Contains(t, s, "@example.com>\r\n")
+	assert.Contains(t, s, "Content-Type: multipart/alternative; boundary=")
+	assert.Contains(t, s, "plain body")
+	assert.Contains(t, s, "<p>html body</p>")
+
+	msg.HTML = ""
+	body, err = msg.mime(time.Now())
+	require.NoError(t, err)
+	assert.Contains(t, string(body), "Content-Type: text/plain; charset=utf-8\r\n")
+
+	msg.Subject = "Hi\r\nBcc: eve@example.com"
+	assert.ErrorContains(t, msg.validate(), "line break")
+}
+
+func TestSendGridSender(t *testing.T) {
+	var got sendGridRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer sg-key", r.Header.Get("Authorization"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	sender, err := NewSendGridSender(SendGridOptions{APIKey: "sg-key", Endpoint: server.URL})
+	require.NoError(t, err)
+	err = sender.Send(context.Background(), Message{
+		From:    Address{Name: "Acme", Email: "hello@acme.test"},
+		ReplyTo: "support@acme.test",
+		To:      []string{"a@example.com", "b@example.com"},
+		Subject: "Hi",
+		Text:    "text",
+		HTML:    "<p>html</p>",
+	})
+	require.NoError(t, err)
+
+	assert.Len(t, got.Personalizations, 2)
+	assert.Equal(t, sendGridAddress{Email: "hello@acme.test", Name: "Acme"}, got.From)
+	assert.Equal(t, "support@acme.test", got.ReplyTo.Email)
+	assert.Equal(t, []sendGridContent{{"text/plain", "text"}, {"text/html", "<p>html</p>"}}, got.Content)
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"errors":[{"message":"bad key"}]}`, http.StatusUnauthorized)
+	}))
+	defer failing.Close()
+	sender.opts.Endpoint = failing.URL
+	err = sender.Send(context.Background(), Message{From: Address{Email: "a@example.com"}, To: []string{"b@example.com"}})
+	assert.ErrorContains(t, err, "status 401")
+}
+
+func TestSESSender(t *testing.T) {
+	var got sesRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/email/outbound-emails", r.URL.Path)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"),
+			"AWS4-HMAC-SHA256 Credential=AKID/20300102/eu-west-1/ses/aws4_request, SignedHeaders=content-type;host;x-amz-content-sha256;x-amz-date, Signature="))
+		body, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(body, &got))
+		w.Write([]byte(`{"MessageId":"1"}`))
+	}))
+	defer server.Close()
+
+	sender, err := NewSESSender(SESOptions{Region: "eu-west-1", Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	require.NoError(t, err)
+	sender.now = func() time.Time { return time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC) }
+
+	err = sender.Send(context.Background(), Message{
+		From:    Address{Name: "Acme", Email: "hello@acme.test"},
+		To:      []string{"a@example.com"},
+		Subject: "Hi",
+		Text:    "text",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, `"Acme" <hello@acme.test>`, got.FromEmailAddress)
+	assert.Equal(t, []string{"a@example.com"}, got.Destination.ToAddresses)
+	assert.Equal(t, "text", got.Content.Simple.Body.Text.Data)
+	assert.Nil(t, got.Content.Simple.Body.Html)
+}
+
+func TestNew(t *testing.T) {
+	sender, err := New(&config.Config{}, slog.Default())
+	require.NoError(t, err)
+	assert.IsType(t, &LogSender{}, sender)
+
+	sender, err = New(&config.Config{MailDriver: "SMTP", SMTPHost: "smtp.example.com"}, slog.Default())
+	require.NoError(t, err)
+	assert.IsType(t, &SMTPSender{}, sender)
+
+	_, err = New(&config.Config{MailDriver: DriverSendGrid}, slog.Default())
+	assert.ErrorContains(t, err, "SENDGRID_API_KEY")
+
+	_, err = New(&config.Config{MailDriver: DriverSES}, slog.Default())
+	assert.ErrorContains(t, err, "SES_ACCESS_KEY_ID")
+
+	_, err = New(&config.Config{MailDriver: "pigeon"}, slog.Default())
+	assert.ErrorContains(t, err, "unknown mail driver")
+}
@@ -0,0 +1,142 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	netmail "net/mail"
+	"strings"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+)
+
+// TenantSettings is the "mail" object of a tenant's settings. Empty fields fall back to
+// the server's MAIL_FROM and MAIL_FROM_NAME.
+type TenantSettings struct {
+	FromEmail string `json:"from_email,omitempty"`
+	FromName  string `json:"from_name,omitempty"`
+	ReplyTo   string `json:"reply_to,omitempty"`
+}
+
+// Validate checks the addresses
+func (s TenantSettings) Validate() error {
+	for field, address := range map[string]string{"from_email": s.FromEmail, "reply_to": s.ReplyTo} {
+		if address == "" {
+			continue
+		}
+		if _, err := netmail.ParseAddress(address); err != nil {
+			return fmt.Errorf("invalid mail %s '%s'", field, address)
+		}
+	}
+	return nil
+}
+
+// ParseTenantSettings reads the mail settings out of a tenant's settings JSON
+func ParseTenantSettings(settings []byte) (TenantSettings, error) {
+	var parsed struct {
+		Mail TenantSettings `json:"mail"`
+	}
+	if len(settings) == 0 {
+		return parsed.Mail, nil
+	}
+	if err := json.Unmarshal(settings, &parsed); err != nil {
+		return TenantSettings{}, fmt.Errorf("invalid tenant settings: %w", err)
+	}
+	return parsed.Mail, nil
+}
+
+// TenantStore looks up tenants; *sqlc.Queries implements it
+type TenantStore interface {
+	GetTenantByID(ctx context.Context, id uuid.UUID) (sqlc.Tenant, error)
+}
+
+// Mailer renders templates and sends them from the configured sender of a tenant
+type Mailer struct {
+	sender  Sender
+	from    Address
+	tenants TenantStore
+}
+
+// NewMailer creates a Mailer that sends from the default address unless the tenant of a
+// message configures its own. tenants may be nil to always use the default.
+func NewMailer(sender Sender, from Address, tenants TenantStore) *Mailer {
+	return &Mailer{sender: sender, from: from, tenants: tenants}
+}
+
+// Send renders the template for one recipient and delivers it. tenantID selects the
+// sender and the tenant name in the message; uuid.Nil uses the defaults.
+func (m *Mailer) Send(ctx context.Context, tenantID uuid.UUID, to string, template string, data Data) error {
+	msg, err := m.compose(ctx, tenantID, to, template, data)
+	if err != nil {
+		return err
+	}
+	return m.sender.Send(context.WithValue(ctx, tenantKey{}, tenantID), msg)
+}
+
+// SendText delivers a message with the given subject and plain text body, such as one
+// written by a flow, from the sender of the tenant
+func (m *Mailer) SendText(ctx context.Context, tenantID uuid.UUID, to string, subject string, text string) error {
+	from, replyTo, _, err := m.tenantSender(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	return m.sender.Send(context.WithValue(ctx, tenantKey{}, tenantID), Message{
+		From:    from,
+		ReplyTo: replyTo,
+		To:      []string{to},
+		Subject: subject,
+		Text:    strings.TrimSpace(text) + "\n",
+	})
+}
+
+// compose builds the message Send delivers
+func (m *Mailer) compose(ctx context.Context, tenantID uuid.UUID, to string, template string, data Data) (Message, error) {
+	from, replyTo, tenantName, err := m.tenantSender(ctx, tenantID)
+	if err != nil {
+		return Message{}, err
+	}
+	if data.Tenant == "" {
+		data.Tenant = tenantName
+	}
+
+	if data.Email == "" {
+		data.Email = to
+	}
+	msg, err := Render(template, data)
+	if err != nil {
+		return Message{}, err
+	}
+	msg.From = from
+	msg.ReplyTo = replyTo
+	msg.To = []string{to}
+	return msg, nil
+}
+
+// tenantSender returns the sender and reply-to address of a tenant along with its name.
+// uuid.Nil returns the default sender.
+func (m *Mailer) tenantSender(ctx context.Context, tenantID uuid.UUID) (Address, string, string, error) {
+	from := m.from
+	if tenantID == uuid.Nil || m.tenants == nil {
+		return from, "", "", nil
+	}
+
+	tenant, err := m.tenants.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return Address{}, "", "", fmt.Errorf("load tenant: %w", err)
+	}
+	settings, err := ParseTenantSettings(tenant.Settings.RawMessage)
+	if err != nil {
+		return Address{}, "", "", err
+	}
+	if settings.FromEmail != "" {
+		from = Address{Name: settings.FromName, Email: settings.FromEmail}
+		if from.Name == "" {
+			from.Name = tenant.Name
+		}
+	} else if settings.FromName != "" {
+		from.Name = settings.FromName
+	}
+	return from, settings.ReplyTo, tenant.Name, nil
+}
@@ -0,0 +1,55 @@
+package mail
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/jobs"
+
+	"github.com/google/uuid"
+)
+
+// JobKind is the kind of the background jobs that deliver queued messages
+const JobKind = "mail.send"
+
+// tenantKey carries the tenant a Mailer sends for, so a QueuedSender can file the job
+// under it
+type tenantKey struct{}
+
+// QueuedSender hands messages to the job queue instead of delivering them during the
+// request. A slow or failing mail provider then delays nobody, and failed deliveries are
+// retried by the queue.
+type QueuedSender struct {
+	sender Sender
+	queue  *jobs.Queue
+}
+
+// NewQueuedSender creates a QueuedSender that delivers through sender, and registers
+// the handler for its jobs with queue
+func NewQueuedSender(sender Sender, queue *jobs.Queue) *QueuedSender {
+	s := &QueuedSender{sender: sender, queue: queue}
+	queue.Register(JobKind, s.deliver)
+	return s
+}
+
+// Send validates msg and queues it for delivery
+func (s *QueuedSender) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	tenantID, _ := ctx.Value(tenantKey{}).(uuid.UUID)
+	_, err := s.queue.Enqueue(ctx, jobs.NewJob{Kind: JobKind, TenantID: tenantID, Payload: msg})
+	return err
+}
+
+// deliver sends the message of a queued job
+func (s *QueuedSender) deliver(ctx context.Context, job sqlc.Job) error {
+	var msg Message
+	if err := json.Unmarshal(job.Payload, &msg); err != nil {
+		return fmt.Errorf("invalid queued message: %w", err)
+	}
+	return s.sender.Send(ctx, msg)
+}
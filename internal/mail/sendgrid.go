@@ -0,0 +1,116 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	sendGridEndpoint = "https://api.sendgrid.com/v3/mail/send"
+	maxAPIErrorBody  = 1024
+)
+
+// SendGridOptions configures a SendGrid sender
+type SendGridOptions struct {
+	APIKey   string
+	Endpoint string // Defaults to the SendGrid v3 mail send API
+}
+
+// SendGridSender delivers messages through the SendGrid v3 API
+type SendGridSender struct {
+	opts   SendGridOptions
+	client *http.Client
+}
+
+// NewSendGridSender creates a SendGrid sender
+func NewSendGridSender(opts SendGridOptions) (*SendGridSender, error) {
+	if opts.APIKey == "" {
+		return nil, fmt.Errorf("SENDGRID_API_KEY is required for sendgrid mail")
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = sendGridEndpoint
+	}
+	return &SendGridSender{opts: opts, client: &http.Client{Timeout: 30 * time.Second}}, nil
+}
+
+type sendGridAddress struct {
+	Email string `json:"email"`
+	Name  string `json:"name,omitempty"`
+}
+
+type sendGridContent struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+type sendGridPersonalization struct {
+	To []sendGridAddress `json:"to"`
+}
+
+type sendGridRequest struct {
+	Personalizations []sendGridPersonalization `json:"personalizations"`
+	From             sendGridAddress           `json:"from"`
+	ReplyTo          *sendGridAddress          `json:"reply_to,omitempty"`
+	Subject          string                    `json:"subject"`
+	Content          []sendGridContent         `json:"content"`
+}
+
+// Send posts the message to SendGrid
+func (s *SendGridSender) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	payload := sendGridRequest{
+		From:    sendGridAddress{Email: msg.From.Email, Name: msg.From.Name},
+		Subject: msg.Subject,
+		Content: []sendGridContent{{Type: "text/plain", Value: msg.Text}},
+	}
+	// Every recipient gets their own copy and cannot see the others
+	for _, to := range msg.To {
+		payload.Personalizations = append(payload.Personalizations, sendGridPersonalization{
+			To: []sendGridAddress{{Email: to}},
+		})
+	}
+	if msg.ReplyTo != "" {
+		payload.ReplyTo = &sendGridAddress{Email: msg.ReplyTo}
+	}
+	if msg.HTML != "" {
+		payload.Content = append(payload.Content, sendGridContent{Type: "text/html", Value: msg.HTML})
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+s.opts.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+
+	return doAPIRequest(s.client, req, "sendgrid")
+}
+
+// doAPIRequest sends req to a mail API, turning non-2xx responses into errors
+func doAPIRequest(client *http.Client, req *http.Request, provider string) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%s request failed: %w", provider, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxAPIErrorBody))
+	return fmt.Errorf("%s: status %d: %s", provider, resp.StatusCode, strings.TrimSpace(string(body)))
+}
@@ -0,0 +1,103 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"go-rbac-api/internal/awsv4"
+)
+
+// SESOptions configures an Amazon SES sender
+type SESOptions struct {
+	Region          string
+	Endpoint        string // Defaults to https://email.<region>.amazonaws.com
+	AccessKeyID     string
+	SecretAccessKey string
+}
+
+// SESSender delivers messages through the Amazon SES v2 API. Requests are signed with
+// AWS Signature Version 4.
+type SESSender struct {
+	opts   SESOptions
+	client *http.Client
+	now    func() time.Time
+}
+
+// NewSESSender creates an SES sender
+func NewSESSender(opts SESOptions) (*SESSender, error) {
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, fmt.Errorf("SES_ACCESS_KEY_ID and SES_SECRET_ACCESS_KEY are required for ses mail")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = fmt.Sprintf("https://email.%s.amazonaws.com", opts.Region)
+	}
+	return &SESSender{opts: opts, client: &http.Client{Timeout: 30 * time.Second}, now: time.Now}, nil
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesBody struct {
+	Text *sesContent `json:"Text,omitempty"`
+	Html *sesContent `json:"Html,omitempty"`
+}
+
+type sesRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	ReplyToAddresses []string `json:"ReplyToAddresses,omitempty"`
+	Content          struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    sesBody    `json:"Body"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+// Send calls the SES SendEmail operation
+func (s *SESSender) Send(ctx context.Context, msg Message) error {
+	if err := msg.validate(); err != nil {
+		return err
+	}
+
+	var payload sesRequest
+	payload.FromEmailAddress = msg.From.String()
+	payload.Destination.ToAddresses = msg.To
+	if msg.ReplyTo != "" {
+		payload.ReplyToAddresses = []string{msg.ReplyTo}
+	}
+	payload.Content.Simple.Subject = sesContent{Data: msg.Subject, Charset: "UTF-8"}
+	payload.Content.Simple.Body.Text = &sesContent{Data: msg.Text, Charset: "UTF-8"}
+	if msg.HTML != "" {
+		payload.Content.Simple.Body.Html = &sesContent{Data: msg.HTML, Charset: "UTF-8"}
+	}
+
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.opts.Endpoint+"/v2/email/outbound-emails", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	awsv4.Sign(req, awsv4.Credentials{
+		AccessKeyID:     s.opts.AccessKeyID,
+		SecretAccessKey: s.opts.SecretAccessKey,
+		Region:          s.opts.Region,
+		Service:         "ses",
+	}, awsv4.PayloadHash(body), s.now())
+
+	return doAPIRequest(s.client, req, "ses")
+}
@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+)
+
+// Built-in templates
+const (
+	TemplateInvite        = "invite"
+	TemplatePasswordReset = "password_reset"
+	TemplateWelcome       = "welcome"
+)
+
+// Each template file defines a "subject", a "text" and an "html" block. The HTML block is
+// executed with html/template so values are escaped.
+//
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// templates holds the parsed files by template name. Every file is parsed on its own, as
+// all of them define the same block names.
+var templates = loadTemplates()
+
+type template struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+func loadTemplates() map[string]template {
+	loaded := make(map[string]template)
+	for _, name := range []string{TemplateInvite, TemplatePasswordReset, TemplateWelcome} {
+		pattern := "templates/" + name + ".tmpl"
+		loaded[name] = template{
+			text: texttemplate.Must(texttemplate.ParseFS(templateFS, pattern)),
+			html: htmltemplate.Must(htmltemplate.ParseFS(templateFS, pattern)),
+		}
+	}
+	return loaded
+}
+
+// Data holds the values available to templates. Fields a template does not use may be empty.
+type Data struct {
+	Name      string // Display name of the recipient
+	Email     string // Email address of the recipient
+	Tenant    string // Name of the tenant the message is about
+	InvitedBy string // Who added the recipient to the tenant (invite)
+	Link      string // Action link, e.g. the password reset page (password_reset)
+	ExpiresAt string // When the link stops working (password_reset)
+}
+
+// Render builds the subject and bodies of a message from a built-in template
+func Render(name string, data Data) (Message, error) {
+	tmpl, ok := templates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown mail template '%s'", name)
+	}
+
+	var subject, textBody, htmlBody bytes.Buffer
+	if err := tmpl.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", name, err)
+	}
+	if err := tmpl.text.ExecuteTemplate(&textBody, "text", data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", name, err)
+	}
+	if err := tmpl.html.ExecuteTemplate(&htmlBody, "html", data); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", name, err)
+	}
+
+	return Message{
+		Subject: strings.TrimSpace(subject.String()),
+		Text:    strings.TrimSpace(textBody.String()) + "\n",
+		HTML:    strings.TrimSpace(htmlBody.String()) + "\n",
+	}, nil
+}
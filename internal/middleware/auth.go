@@ -3,15 +3,21 @@ package middleware
 import (
 	"context"
 	"crypto/sha256"
+	"database/sql"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"strings"
 	"time"
 
+	"go-rbac-api/internal/audit"
 	"go-rbac-api/internal/config"
 	"go-rbac-api/internal/db"
 	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/rbac"
 
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
@@ -20,15 +26,23 @@ import (
 
 // AuthProvider provides centralized authentication context and session management
 type AuthProvider struct {
-	UserID      uuid.UUID `json:"user_id"`
-	Email       string    `json:"email"`
-	TenantID    uuid.UUID `json:"tenant_id"`
-	TenantSlug  string    `json:"tenant_slug"`
-	IsAdmin     bool      `json:"is_admin"`
-	Roles       []string  `json:"roles"`
-	Permissions []string  `json:"permissions"`
-	SessionID   string    `json:"session_id"`
-	ExpiresAt   time.Time `json:"expires_at"`
+	UserID       uuid.UUID `json:"user_id"`
+	Email        string    `json:"email"`
+	TenantID     uuid.UUID `json:"tenant_id"`
+	TenantSlug   string    `json:"tenant_slug"`
+	IsAdmin      bool      `json:"is_admin"`       // Admin of TenantID
+	IsSuperAdmin bool      `json:"is_super_admin"` // Instance admin, who manages every tenant
+	Roles        []string  `json:"roles"`
+	Permissions  []string  `json:"permissions"`
+	Scopes       []string  `json:"scopes,omitempty"` // API key scopes; empty means unrestricted
+	SessionID    string    `json:"session_id"`
+	ExpiresAt    time.Time `json:"expires_at"`
+
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
+
+	// ImpersonatorID is the instance admin acting as the user with an impersonation token
+	ImpersonatorID *uuid.UUID `json:"impersonator_id,omitempty"`
 }
 
 // Claims represents the JWT claims structure
@@ -38,9 +52,63 @@ type Claims struct {
 	TenantID   uuid.UUID `json:"tenant_id"`
 	TenantSlug string    `json:"tenant_slug"`
 	SessionID  string    `json:"session_id"`
+
+	// PasswordChange limits the token to changing the password (see passwordChangeRoutes)
+	PasswordChange bool `json:"pwd_change,omitempty"`
+	// TwoFactorSetup limits the token to enabling 2FA, which the tenant requires (see
+	// twoFactorSetupRoutes)
+	TwoFactorSetup bool `json:"2fa_setup,omitempty"`
+	// Impersonator is the instance admin the token was issued to (see
+	// GenerateImpersonationToken)
+	Impersonator *uuid.UUID `json:"impersonator,omitempty"`
 	jwt.RegisteredClaims
 }
 
+// passwordChangeRoutes are the routes a token with a pending password change may use
+var passwordChangeRoutes = map[string]bool{
+	"/auth/me":              true,
+	"/auth/logout":          true,
+	"/auth/change-password": true,
+}
+
+// twoFactorSetupRoutes are the routes a token may use before its user enables the 2FA
+// its tenant requires
+var twoFactorSetupRoutes = map[string]bool{
+	"/auth/me":          true,
+	"/auth/logout":      true,
+	"/auth/2fa":         true,
+	"/auth/2fa/enable":  true,
+	"/auth/2fa/confirm": true,
+}
+
+// impersonationBlockedRoutes are the routes an impersonation token may not use: they would
+// change the user's credentials or outlive the impersonation
+var impersonationBlockedRoutes = map[string]bool{
+	"/auth/switch-tenant":        true,
+	"/auth/change-password":      true,
+	"/auth/2fa/enable":           true,
+	"/auth/2fa/confirm":          true,
+	"/auth/2fa/disable":          true,
+	"/auth/2fa/recovery-codes":   true,
+	"/auth/impersonate/:user_id": true,
+	"/items/:table/:id/rotate":   true,
+	"/items/:table/:id/share":    true,
+}
+
+// impersonationBlocked reports whether an impersonation token may not make the request:
+// besides impersonationBlockedRoutes, it may not write API keys, which outlive the
+// impersonation like share links do
+func impersonationBlocked(c *gin.Context) bool {
+	if impersonationBlockedRoutes[c.FullPath()] {
+		return true
+	}
+	if c.Param("table") != "api_keys" {
+		return false
+	}
+	method := c.Request.Method
+	return method != http.MethodGet && method != http.MethodHead
+}
+
 // Session represents a tenant-scoped authentication session
 type Session struct {
 	ID        string    `json:"id"`
@@ -51,50 +119,125 @@ type Session struct {
 	IsActive  bool      `json:"is_active"`
 }
 
-// GenerateTokenWithTenant creates a JWT token that includes user and tenant information
-func GenerateTokenWithTenant(user sqlc.User, tenant sqlc.Tenant, cfg *config.Config) (string, error) {
-	expirationTime := time.Now().Add(cfg.JWTExpiry)
-	sessionID := uuid.New().String()
+// sessionTouchInterval limits how often a session's last_seen_at is updated
+const sessionTouchInterval = time.Minute
 
+// GenerateTokenWithTenant creates a JWT token for a session that includes user and tenant information
+func GenerateTokenWithTenant(user sqlc.User, tenant sqlc.Tenant, session *Session, cfg *config.Config) (string, error) {
 	claims := &Claims{
-		UserID:     user.ID,
-		Email:      user.Email,
-		TenantID:   tenant.ID,
-		TenantSlug: tenant.Slug,
-		SessionID:  sessionID,
+		UserID:         user.ID,
+		Email:          user.Email,
+		TenantID:       tenant.ID,
+		TenantSlug:     tenant.Slug,
+		SessionID:      session.ID,
+		PasswordChange: user.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JWTSecret))
+	return CurrentSigningKeys(cfg).Sign(claims)
 }
 
-// GenerateToken creates a JWT token without tenant context (for system-wide operations)
-func GenerateToken(user sqlc.User, cfg *config.Config) (string, error) {
-	expirationTime := time.Now().Add(cfg.JWTExpiry)
-	sessionID := uuid.New().String()
+// GenerateTwoFactorSetupToken creates a tenant token that can only be used to enable
+// two-factor authentication, for users of tenants that require it
+func GenerateTwoFactorSetupToken(user sqlc.User, tenant sqlc.Tenant, session *Session, cfg *config.Config) (string, error) {
+	claims := &Claims{
+		UserID:         user.ID,
+		Email:          user.Email,
+		TenantID:       tenant.ID,
+		TenantSlug:     tenant.Slug,
+		SessionID:      session.ID,
+		TwoFactorSetup: true,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+
+	return CurrentSigningKeys(cfg).Sign(claims)
+}
 
+// GenerateToken creates a JWT token for a session without tenant context (for system-wide operations)
+func GenerateToken(user sqlc.User, session *Session, cfg *config.Config) (string, error) {
 	claims := &Claims{
-		UserID:    user.ID,
-		Email:     user.Email,
-		SessionID: sessionID,
+		UserID:         user.ID,
+		Email:          user.Email,
+		SessionID:      session.ID,
+		PasswordChange: user.MustChangePassword,
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(expirationTime),
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 		},
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return token.SignedString([]byte(cfg.JWTSecret))
+	return CurrentSigningKeys(cfg).Sign(claims)
+}
+
+// GenerateImpersonationToken creates a JWT token that lets an instance admin act as the user
+// for the session, which is kept short. tenant may be nil for users without a home tenant.
+func GenerateImpersonationToken(user sqlc.User, tenant *sqlc.Tenant, impersonator uuid.UUID, session *Session, cfg *config.Config) (string, error) {
+	claims := &Claims{
+		UserID:       user.ID,
+		Email:        user.Email,
+		SessionID:    session.ID,
+		Impersonator: &impersonator,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(session.ExpiresAt),
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			NotBefore: jwt.NewNumericDate(time.Now()),
+		},
+	}
+	if tenant != nil {
+		claims.TenantID = tenant.ID
+		claims.TenantSlug = tenant.Slug
+	}
+
+	return CurrentSigningKeys(cfg).Sign(claims)
+}
+
+// authTypeExternal is the auth_type of requests made with a token of the external issuer
+const authTypeExternal = "external"
+
+// ExternalTokens authenticates access tokens minted by a trusted external identity provider
+type ExternalTokens interface {
+	// Issues reports whether a token claims to come from the provider, without verifying it
+	Issues(token string) bool
+	// Authenticate verifies a token and returns the user it acts as and when it expires
+	Authenticate(c *gin.Context, token string) (sqlc.User, time.Time, error)
+}
+
+// externalTokens are accepted next to Basin's own tokens; nil accepts none
+var externalTokens ExternalTokens
+
+// UseExternalTokens makes AuthMiddleware accept the tokens of an external identity
+// provider. Call it once at startup.
+func UseExternalTokens(tokens ExternalTokens) {
+	externalTokens = tokens
+}
+
+// QueryTokenAuth lets clients that cannot set request headers (such as the browser
+// EventSource API) pass their token as ?access_token=. It must run before AuthMiddleware
+// and only fills in the Authorization header when none was sent.
+func QueryTokenAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") == "" {
+			if token := c.Query("access_token"); token != "" {
+				c.Request.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+		c.Next()
+	}
 }
 
 // AuthMiddleware creates a middleware that validates JWT tokens or API keys and provides auth context
 func AuthMiddleware(cfg *config.Config, db *db.DB) gin.HandlerFunc {
+	auditLog := audit.NewLogger(db)
+
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader("Authorization")
 		if authHeader == "" {
@@ -111,7 +254,12 @@ func AuthMiddleware(cfg *config.Config, db *db.DB) gin.HandlerFunc {
 
 		// Try API key authentication first (if it looks like an API key)
 		if strings.HasPrefix(tokenString, "basin_") {
-			if authProvider, err := authenticateWithAPIKey(c, db, tokenString); err == nil {
+			authProvider, err := authenticateWithAPIKey(c, db, tokenString)
+			if errors.Is(err, ErrTenantInactive) {
+				abortTenantInactive(c)
+				return
+			}
+			if err == nil {
 				// Store auth provider in context
 				c.Set("auth", authProvider)
 				c.Set("user_id", authProvider.UserID)
@@ -119,16 +267,78 @@ func AuthMiddleware(cfg *config.Config, db *db.DB) gin.HandlerFunc {
 				c.Set("tenant_id", authProvider.TenantID)
 				c.Set("tenant_slug", authProvider.TenantSlug)
 				c.Set("is_admin", authProvider.IsAdmin)
+				c.Set("is_super_admin", authProvider.IsSuperAdmin)
 				c.Set("auth_type", "api_key")
+				if apiKeyID, err := uuid.Parse(authProvider.SessionID); err == nil {
+					c.Set("api_key_id", apiKeyID)
+				}
+
+				// Scoped keys carry their scopes to every permission check of the request
+				if len(authProvider.Scopes) > 0 {
+					c.Set("api_key_scopes", authProvider.Scopes)
+					c.Request = c.Request.WithContext(rbac.WithScopes(c.Request.Context(), authProvider.Scopes))
+				}
+
+				// Every use of an API key is audited
+				auditLog.Record(c.Request.Context(), NewAuditEntry(c, audit.ActionAPIKey))
 
 				c.Next()
 				return
 			}
+
+			entry := NewAuditEntry(c, audit.ActionAPIKeyFailed)
+			entry.Result = audit.ResultFailure
+			entry.StatusCode = http.StatusUnauthorized
+			entry.Details = map[string]interface{}{"reason": err.Error()}
+			auditLog.Record(c.Request.Context(), entry)
 			// If API key auth fails, continue to JWT validation
 		}
 
-		// Try JWT token authentication
-		if authProvider, err := authenticateWithJWT(c, cfg, db, tokenString); err == nil {
+		// Try JWT token authentication; tokens of the external issuer are verified against
+		// its published keys
+		authenticate, authType := authenticateWithJWT, "jwt"
+		if externalTokens != nil && externalTokens.Issues(tokenString) {
+			authenticate, authType = authenticateWithExternalJWT, authTypeExternal
+		}
+		authProvider, err := authenticate(c, cfg, db, tokenString)
+		if errors.Is(err, ErrTenantInactive) {
+			abortTenantInactive(c)
+			return
+		}
+		if errors.Is(err, ErrNotTenantMember) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not a member of the requested tenant"})
+			c.Abort()
+			return
+		}
+		if errors.Is(err, ErrImpersonationSwitch) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating"})
+			c.Abort()
+			return
+		}
+		if err == nil {
+			// Until the password is changed the token only reaches the password change routes
+			if authProvider.PasswordChangeRequired && !passwordChangeRoutes[c.FullPath()] {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":                    "Password change required",
+					"password_change_required": true,
+				})
+				c.Abort()
+				return
+			}
+			if authProvider.TwoFactorSetupRequired && !twoFactorSetupRoutes[c.FullPath()] {
+				c.JSON(http.StatusForbidden, gin.H{
+					"error":                     "Two-factor authentication required by the tenant",
+					"two_factor_setup_required": true,
+				})
+				c.Abort()
+				return
+			}
+			if authProvider.ImpersonatorID != nil && impersonationBlocked(c) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Not allowed while impersonating"})
+				c.Abort()
+				return
+			}
+
 			// Store auth provider in context
 			c.Set("auth", authProvider)
 			c.Set("user_id", authProvider.UserID)
@@ -136,7 +346,12 @@ func AuthMiddleware(cfg *config.Config, db *db.DB) gin.HandlerFunc {
 			c.Set("tenant_id", authProvider.TenantID)
 			c.Set("tenant_slug", authProvider.TenantSlug)
 			c.Set("is_admin", authProvider.IsAdmin)
-			c.Set("auth_type", "jwt")
+			c.Set("is_super_admin", authProvider.IsSuperAdmin)
+			c.Set("auth_type", authType)
+			if authProvider.ImpersonatorID != nil {
+				c.Set("impersonator_id", *authProvider.ImpersonatorID)
+			}
+			setActingTenant(c, authProvider.TenantID)
 
 			c.Next()
 			return
@@ -178,9 +393,17 @@ func authenticateWithAPIKey(c *gin.Context, db *db.DB, apiKey string) (*AuthProv
 	if !user.IsActive.Bool {
 		return nil, fmt.Errorf("user account is disabled")
 	}
+	if user.TenantID.Valid {
+		if err := CheckTenantActive(c.Request.Context(), db, user.TenantID.UUID); err != nil {
+			return nil, err
+		}
+	}
 
-	// Get user roles
-	userRoles, err := db.Queries.GetUserRoles(c.Request.Context(), apiKeyRecord.UserID)
+	// API keys act in the user's home tenant, so its roles apply
+	userRoles, err := db.Queries.GetUserTenantRoles(c.Request.Context(), sqlc.GetUserTenantRolesParams{
+		UserID:   apiKeyRecord.UserID,
+		TenantID: user.TenantID.UUID,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to get user roles: %w", err)
 	}
@@ -202,22 +425,24 @@ func authenticateWithAPIKey(c *gin.Context, db *db.DB, apiKey string) (*AuthProv
 
 	// Create auth provider
 	authProvider := &AuthProvider{
-		UserID:      apiKeyRecord.UserID,
-		Email:       user.Email,
-		TenantID:    uuid.Nil, // API keys don't have tenant context by default
-		TenantSlug:  "",       // API keys don't have tenant context by default
-		IsAdmin:     isAdmin,
-		Roles:       roles,
-		Permissions: permissions,
-		SessionID:   apiKeyRecord.ID.String(),
-		ExpiresAt:   time.Now().Add(24 * time.Hour), // API keys don't expire in the same way as JWT
+		UserID:       apiKeyRecord.UserID,
+		Email:        user.Email,
+		TenantID:     uuid.Nil, // API keys don't have tenant context by default
+		TenantSlug:   "",       // API keys don't have tenant context by default
+		IsAdmin:      isAdmin,
+		IsSuperAdmin: user.IsSuperAdmin,
+		Roles:        roles,
+		Permissions:  permissions,
+		Scopes:       apiKeyRecord.Scopes,
+		SessionID:    apiKeyRecord.ID.String(),
+		ExpiresAt:    time.Now().Add(24 * time.Hour), // API keys don't expire in the same way as JWT
 	}
 
 	// Update last used timestamp
 	go func() {
 		if err := db.Queries.UpdateAPIKeyLastUsed(context.Background(), apiKeyRecord.ID); err != nil {
 			// Log error but don't fail the request
-			fmt.Printf("Failed to update API key last used: %v\n", err)
+			slog.Warn("failed to update API key last used", "api_key_id", apiKeyRecord.ID, "error", err)
 		}
 	}()
 
@@ -227,61 +452,61 @@ func authenticateWithAPIKey(c *gin.Context, db *db.DB, apiKey string) (*AuthProv
 // authenticateWithJWT validates a JWT token and returns an AuthProvider
 func authenticateWithJWT(c *gin.Context, cfg *config.Config, db *db.DB, tokenString string) (*AuthProvider, error) {
 	// Parse and validate JWT token
-	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
-		return []byte(cfg.JWTSecret), nil
-	})
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, CurrentSigningKeys(cfg).Keyfunc)
 
 	if err != nil {
 		return nil, fmt.Errorf("invalid JWT token: %w", err)
 	}
 
 	if claims, ok := token.Claims.(*Claims); ok && token.Valid {
-		// Get user roles and permissions
-		userRoles, err := db.Queries.GetUserRoles(c.Request.Context(), claims.UserID)
+		// Tokens are only as good as their session, which logout revokes
+		if err := checkSession(c, db, claims); err != nil {
+			return nil, err
+		}
+		// Super admin rights are read on every request, so revoking them takes effect at once
+		user, err := db.Queries.GetUserByID(c.Request.Context(), claims.UserID)
 		if err != nil {
-			return nil, fmt.Errorf("failed to get user roles: %w", err)
+			return nil, fmt.Errorf("user not found: %w", err)
 		}
-
-		// Check if user is admin
-		isAdmin := false
-		roles := make([]string, 0, len(userRoles))
-		for _, role := range userRoles {
-			roles = append(roles, role.Name)
-			if role.Name == "admin" {
-				isAdmin = true
+		if claims.Impersonator != nil {
+			if err := checkImpersonator(c.Request.Context(), db, *claims.Impersonator); err != nil {
+				return nil, err
 			}
 		}
 
-		// Get user permissions if tenant context exists
-		var permissions []string
-		if claims.TenantID != uuid.Nil {
-			userPermissions, err := db.Queries.GetPermissionsByUserAndTenant(c.Request.Context(), sqlc.GetPermissionsByUserAndTenantParams{
-				UserID:   claims.UserID,
-				TenantID: uuid.NullUUID{UUID: claims.TenantID, Valid: true},
-			})
-			if err == nil {
-				permissions = make([]string, 0, len(userPermissions))
-				for _, perm := range userPermissions {
-					permissions = append(permissions, fmt.Sprintf("%s:%s", perm.TableName, perm.Action))
-				}
+		// X-Tenant switches the token to another tenant of its user, so a dashboard can work
+		// with several tenants without logging in again
+		tenantID, tenantSlug := claims.TenantID, claims.TenantSlug
+		setupRequired := claims.TwoFactorSetup
+		if tenant, ok := switchedTenant(c, claims.TenantID); ok {
+			// Impersonation is limited to the tenant it was started in
+			if claims.Impersonator != nil {
+				return nil, ErrImpersonationSwitch
 			}
+			if err := checkTenantMember(c.Request.Context(), db, user, tenant.ID); err != nil {
+				return nil, err
+			}
+			// The token only vouches for the 2FA its own tenant requires
+			if setupRequired, err = twoFactorSetupRequired(c.Request.Context(), db, user.ID, tenant); err != nil {
+				return nil, err
+			}
+			tenantID, tenantSlug = tenant.ID, tenant.Slug
+		} else if err := checkRequestTenant(c, claims.TenantID); err != nil {
+			return nil, err
+		}
+		if err := CheckTenantActive(c.Request.Context(), db, tenantID); err != nil {
+			return nil, err
 		}
 
-		// Create auth provider
-		authProvider := &AuthProvider{
-			UserID:      claims.UserID,
-			Email:       claims.Email,
-			TenantID:    claims.TenantID,
-			TenantSlug:  claims.TenantSlug,
-			IsAdmin:     isAdmin,
-			Roles:       roles,
-			Permissions: permissions,
-			SessionID:   claims.SessionID,
-			ExpiresAt:   time.Unix(int64(claims.ExpiresAt.Unix()), 0),
+		authProvider, err := newAuthProvider(c.Request.Context(), db, user, tenantID, tenantSlug)
+		if err != nil {
+			return nil, err
 		}
+		authProvider.SessionID = claims.SessionID
+		authProvider.ExpiresAt = time.Unix(int64(claims.ExpiresAt.Unix()), 0)
+		authProvider.PasswordChangeRequired = claims.PasswordChange
+		authProvider.TwoFactorSetupRequired = setupRequired
+		authProvider.ImpersonatorID = claims.Impersonator
 
 		return authProvider, nil
 	}
@@ -289,6 +514,190 @@ func authenticateWithJWT(c *gin.Context, cfg *config.Config, db *db.DB, tokenStr
 	return nil, fmt.Errorf("invalid JWT claims")
 }
 
+// authenticateWithExternalJWT validates a token of the external issuer and returns an
+// AuthProvider for the user it maps to. The token acts in the request's tenant (X-Tenant or
+// the domain) if the user is a member of it, and otherwise in the user's default tenant.
+func authenticateWithExternalJWT(c *gin.Context, cfg *config.Config, db *db.DB, tokenString string) (*AuthProvider, error) {
+	ctx := c.Request.Context()
+	user, expiresAt, err := externalTokens.Authenticate(c, tokenString)
+	if err != nil {
+		return nil, fmt.Errorf("invalid external token: %w", err)
+	}
+	if !user.IsActive.Bool {
+		return nil, fmt.Errorf("user account is disabled")
+	}
+
+	var tenantID uuid.UUID
+	var tenantSlug string
+	if tenant, ok := GetRequestTenant(c); ok {
+		if err := checkTenantMember(ctx, db, user, tenant.ID); err != nil {
+			return nil, err
+		}
+		tenantID, tenantSlug = tenant.ID, tenant.Slug
+	} else if tenant, err := db.Queries.GetUserDefaultTenant(ctx, user.ID); err == nil {
+		tenantID, tenantSlug = tenant.ID, tenant.Slug
+	}
+	if err := CheckTenantActive(ctx, db, tenantID); err != nil {
+		return nil, err
+	}
+
+	authProvider, err := newAuthProvider(ctx, db, user, tenantID, tenantSlug)
+	if err != nil {
+		return nil, err
+	}
+	authProvider.ExpiresAt = expiresAt
+	return authProvider, nil
+}
+
+// newAuthProvider loads the roles and permissions of a user acting in a tenant, which may
+// be uuid.Nil
+func newAuthProvider(ctx context.Context, db *db.DB, user sqlc.User, tenantID uuid.UUID, tenantSlug string) (*AuthProvider, error) {
+	// Roles come from the acting tenant; an admin of one tenant is no admin of another
+	userRoles, err := db.Queries.GetUserTenantRoles(ctx, sqlc.GetUserTenantRolesParams{
+		UserID:   user.ID,
+		TenantID: tenantID,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+
+	// Check if user is admin
+	isAdmin := false
+	roles := make([]string, 0, len(userRoles))
+	for _, role := range userRoles {
+		roles = append(roles, role.Name)
+		if role.Name == "admin" {
+			isAdmin = true
+		}
+	}
+
+	// Get user permissions if tenant context exists
+	var permissions []string
+	if tenantID != uuid.Nil {
+		userPermissions, err := db.Queries.GetPermissionsByUserAndTenant(ctx, sqlc.GetPermissionsByUserAndTenantParams{
+			UserID:   user.ID,
+			TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
+		})
+		if err == nil {
+			permissions = make([]string, 0, len(userPermissions))
+			for _, perm := range userPermissions {
+				permissions = append(permissions, fmt.Sprintf("%s:%s", perm.TableName, perm.Action))
+			}
+		}
+	}
+
+	return &AuthProvider{
+		UserID:       user.ID,
+		Email:        user.Email,
+		TenantID:     tenantID,
+		TenantSlug:   tenantSlug,
+		IsAdmin:      isAdmin,
+		IsSuperAdmin: user.IsSuperAdmin,
+		Roles:        roles,
+		Permissions:  permissions,
+	}, nil
+}
+
+// checkSession verifies that the session of a token exists and has not been revoked
+func checkSession(c *gin.Context, db *db.DB, claims *Claims) error {
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		return fmt.Errorf("invalid session ID")
+	}
+	session, err := db.Queries.GetSession(c.Request.Context(), sessionID)
+	if err != nil {
+		return fmt.Errorf("session not found: %w", err)
+	}
+	if session.UserID != claims.UserID {
+		return fmt.Errorf("session belongs to another user")
+	}
+	if session.RevokedAt.Valid {
+		return fmt.Errorf("session has been revoked")
+	}
+
+	if !session.LastSeenAt.Valid || time.Since(session.LastSeenAt.Time) > sessionTouchInterval {
+		go func() {
+			if err := db.Queries.TouchSession(context.Background(), sessionID); err != nil {
+				slog.Warn("failed to update session last seen", "session_id", sessionID, "error", err)
+			}
+		}()
+	}
+	return nil
+}
+
+// checkImpersonator verifies that the admin of an impersonation token still may impersonate,
+// so revoking their super admin rights also ends their impersonations
+func checkImpersonator(ctx context.Context, db *db.DB, impersonatorID uuid.UUID) error {
+	impersonator, err := db.Queries.GetUserByID(ctx, impersonatorID)
+	if err != nil {
+		return fmt.Errorf("impersonator not found: %w", err)
+	}
+	if !impersonator.IsSuperAdmin || !impersonator.IsActive.Bool {
+		return fmt.Errorf("impersonator is no longer an instance admin")
+	}
+	return nil
+}
+
+// TwoFactorEnabled reports whether the user has confirmed a TOTP secret
+func TwoFactorEnabled(ctx context.Context, db *db.DB, userID uuid.UUID) (bool, error) {
+	twoFactor, err := db.Queries.GetTwoFactor(ctx, userID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return twoFactor.Enabled, nil
+}
+
+// TenantRequiresTwoFactor reads the require_2fa flag of a tenant's settings
+func TenantRequiresTwoFactor(tenant sqlc.Tenant) bool {
+	var settings struct {
+		Require2FA bool `json:"require_2fa"`
+	}
+	if len(tenant.Settings.RawMessage) == 0 {
+		return false
+	}
+	return json.Unmarshal(tenant.Settings.RawMessage, &settings) == nil && settings.Require2FA
+}
+
+// twoFactorSetupRequired reports whether the user must enable 2FA before acting in tenant
+func twoFactorSetupRequired(ctx context.Context, db *db.DB, userID uuid.UUID, tenant sqlc.Tenant) (bool, error) {
+	if !TenantRequiresTwoFactor(tenant) {
+		return false, nil
+	}
+	enabled, err := TwoFactorEnabled(ctx, db, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to check two-factor authentication: %w", err)
+	}
+	return !enabled, nil
+}
+
+// ErrTenantInactive is returned for tenants that are suspended or deleted
+var ErrTenantInactive = errors.New("tenant is suspended or deleted")
+
+// CheckTenantActive returns ErrTenantInactive when a tenant is suspended or deleted.
+// uuid.Nil (no tenant) passes.
+func CheckTenantActive(ctx context.Context, db *db.DB, tenantID uuid.UUID) error {
+	if tenantID == uuid.Nil {
+		return nil
+	}
+	tenant, err := db.Queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return fmt.Errorf("tenant not found: %w", err)
+	}
+	if !tenant.IsActive.Bool {
+		return ErrTenantInactive
+	}
+	return nil
+}
+
+// abortTenantInactive rejects a request authenticated for an inactive tenant
+func abortTenantInactive(c *gin.Context) {
+	c.JSON(http.StatusForbidden, gin.H{"error": "Tenant is suspended or deleted", "tenant_inactive": true})
+	c.Abort()
+}
+
 // hashAPIKey creates a SHA-256 hash of the API key for secure storage
 func hashAPIKey(apiKey string) string {
 	hash := sha256.Sum256([]byte(apiKey))
@@ -323,6 +732,18 @@ func GetUserID(c *gin.Context) (uuid.UUID, bool) {
 	return uuid.Nil, false
 }
 
+// GetImpersonatorID returns the instance admin acting as the user, for requests made with
+// an impersonation token
+func GetImpersonatorID(c *gin.Context) (uuid.UUID, bool) {
+	impersonatorID, exists := c.Get("impersonator_id")
+	if !exists {
+		return uuid.Nil, false
+	}
+
+	id, ok := impersonatorID.(uuid.UUID)
+	return id, ok
+}
+
 // GetTenantID retrieves the tenant ID from the context
 func GetTenantID(c *gin.Context) (uuid.UUID, bool) {
 	tenantID, exists := c.Get("tenant_id")
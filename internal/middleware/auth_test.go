@@ -0,0 +1,166 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/dbtest"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerateImpersonationToken(t *testing.T) {
+	cfg := &config.Config{JWTSecret: "secret"}
+	user := sqlc.User{ID: uuid.New(), Email: "user@example.com", MustChangePassword: true}
+	tenant := sqlc.Tenant{ID: uuid.New(), Slug: "acme"}
+	admin := uuid.New()
+	session := &Session{ID: uuid.NewString(), ExpiresAt: time.Now().Add(15 * time.Minute)}
+
+	token, err := GenerateImpersonationToken(user, &tenant, admin, session, cfg)
+	require.NoError(t, err)
+
+	claims := &Claims{}
+	_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return []byte(cfg.JWTSecret), nil })
+	require.NoError(t, err)
+	assert.Equal(t, user.ID, claims.UserID)
+	assert.Equal(t, tenant.ID, claims.TenantID)
+	require.NotNil(t, claims.Impersonator)
+	assert.Equal(t, admin, *claims.Impersonator)
+	assert.False(t, claims.PasswordChange, "impersonation does not stop at the user's pending password change")
+
+	t.Run("No Tenant", func(t *testing.T) {
+		token, err := GenerateImpersonationToken(user, nil, admin, session, cfg)
+		require.NoError(t, err)
+		claims := &Claims{}
+		_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return []byte(cfg.JWTSecret), nil })
+		require.NoError(t, err)
+		assert.Equal(t, uuid.Nil, claims.TenantID)
+	})
+
+	t.Run("Regular Tokens", func(t *testing.T) {
+		token, err := GenerateToken(user, session, cfg)
+		require.NoError(t, err)
+		claims := &Claims{}
+		_, err = jwt.ParseWithClaims(token, claims, func(*jwt.Token) (interface{}, error) { return []byte(cfg.JWTSecret), nil })
+		require.NoError(t, err)
+		assert.Nil(t, claims.Impersonator)
+	})
+}
+
+func TestGetImpersonatorID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+
+	_, ok := GetImpersonatorID(c)
+	assert.False(t, ok)
+
+	admin := uuid.New()
+	c.Set("impersonator_id", admin)
+	id, ok := GetImpersonatorID(c)
+	assert.True(t, ok)
+	assert.Equal(t, admin, id)
+}
+
+func TestImpersonationBlocked(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	blocked := func(method, route, path string) bool {
+		var result bool
+		router := gin.New()
+		router.Handle(method, route, func(c *gin.Context) { result = impersonationBlocked(c) })
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(method, path, nil))
+		return result
+	}
+
+	assert.True(t, blocked("POST", "/auth/change-password", "/auth/change-password"))
+	assert.True(t, blocked("POST", "/items/:table", "/items/api_keys"))
+	assert.True(t, blocked("PATCH", "/items/:table/:id", "/items/api_keys/1"))
+	assert.True(t, blocked("PUT", "/items/:table/:id", "/items/api_keys/1"))
+	assert.True(t, blocked("POST", "/items/:table/:id/rotate", "/items/api_keys/1/rotate"))
+	assert.True(t, blocked("POST", "/items/:table/:id/share", "/items/posts/1/share"))
+
+	assert.False(t, blocked("GET", "/items/:table", "/items/api_keys"), "listing keys is allowed")
+	assert.False(t, blocked("POST", "/items/:table", "/items/posts"))
+	assert.False(t, blocked("PATCH", "/items/:table/:id", "/items/posts/1"))
+}
+
+func TestTenantRequiresTwoFactor(t *testing.T) {
+	settings := func(raw string) sqlc.Tenant {
+		return sqlc.Tenant{Settings: pqtype.NullRawMessage{RawMessage: json.RawMessage(raw), Valid: raw != ""}}
+	}
+	assert.False(t, TenantRequiresTwoFactor(settings("")))
+	assert.False(t, TenantRequiresTwoFactor(settings(`{"mail": {}}`)))
+	assert.False(t, TenantRequiresTwoFactor(settings(`{"require_2fa": false}`)))
+	assert.True(t, TenantRequiresTwoFactor(settings(`{"require_2fa": true}`)))
+}
+
+func TestAuthenticateWithJWT_TenantSwitch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	database := dbtest.SQLite(t)
+	ctx := context.Background()
+	cfg := &config.Config{JWTSecret: "secret"}
+
+	// The seeded admin's home tenant does not require 2FA; the other tenant does
+	home, err := database.Queries.GetTenantBySlug(ctx, "main")
+	require.NoError(t, err)
+	user, err := database.Queries.GetUserByEmail(ctx, "admin@example.com")
+	require.NoError(t, err)
+	strict, err := database.Queries.CreateTenant(ctx, sqlc.CreateTenantParams{
+		ID:       uuid.New(),
+		Name:     "Strict",
+		Slug:     "strict",
+		Settings: pqtype.NullRawMessage{RawMessage: json.RawMessage(`{"require_2fa": true}`), Valid: true},
+	})
+	require.NoError(t, err)
+	require.NoError(t, database.Queries.AddUserToTenant(ctx, sqlc.AddUserToTenantParams{UserID: user.ID, TenantID: strict.ID}))
+
+	record, err := database.Queries.CreateSession(ctx, sqlc.CreateSessionParams{UserID: user.ID, TenantID: uuid.NullUUID{UUID: home.ID, Valid: true}, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	session := &Session{ID: record.ID.String(), ExpiresAt: record.ExpiresAt}
+
+	authenticate := func(token string) (*AuthProvider, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest("GET", "/items/products", nil)
+		c.Set("request_tenant", strict)
+		c.Set("request_tenant_header", true)
+		return authenticateWithJWT(c, cfg, database, token)
+	}
+
+	token, err := GenerateTokenWithTenant(user, home, session, cfg)
+	require.NoError(t, err)
+
+	t.Run("Switch Requires The Tenant's 2FA", func(t *testing.T) {
+		provider, err := authenticate(token)
+		require.NoError(t, err)
+		assert.Equal(t, strict.ID, provider.TenantID)
+		assert.True(t, provider.TwoFactorSetupRequired, "the home tenant's token does not skip the 2FA of the tenant switched to")
+
+		_, err = database.Queries.SetTwoFactorSecret(ctx, sqlc.SetTwoFactorSecretParams{UserID: user.ID, Secret: "JBSWY3DPEHPK3PXP"})
+		require.NoError(t, err)
+		require.NoError(t, database.Queries.EnableTwoFactor(ctx, sqlc.EnableTwoFactorParams{UserID: user.ID, LastUsedStep: 1}))
+		provider, err = authenticate(token)
+		require.NoError(t, err)
+		assert.False(t, provider.TwoFactorSetupRequired)
+	})
+
+	t.Run("Impersonation Cannot Switch", func(t *testing.T) {
+		admin, err := database.Queries.CreateUser(ctx, sqlc.CreateUserParams{ID: uuid.New(), Email: "root@example.com", PasswordHash: "x"})
+		require.NoError(t, err)
+		_, err = database.ExecContext(ctx, `UPDATE users SET is_super_admin = true WHERE id = $1`, admin.ID)
+		require.NoError(t, err)
+
+		token, err := GenerateImpersonationToken(user, &home, admin.ID, session, cfg)
+		require.NoError(t, err)
+		_, err = authenticate(token)
+		assert.ErrorIs(t, err, ErrImpersonationSwitch)
+	})
+}
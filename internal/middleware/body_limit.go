@@ -0,0 +1,45 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// BodyLimit bounds the size of request bodies: routes listed in routeLimits (by their
+// full path, e.g. "/items/:table/bulk") may send up to that many bytes, every other route
+// up to limit. Requests declaring a larger Content-Length are answered with 413 right
+// away; larger bodies sent without one fail once the limit is read past.
+//
+// Multipart uploads are left to the handlers accepting them, which enforce their own
+// limits (ASSET_MAX_UPLOAD_SIZE, IMPORT_MAX_SIZE, TENANT_IMPORT_MAX_SIZE).
+func BodyLimit(limit int64, routeLimits map[string]int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Body == nil || c.Request.Body == http.NoBody ||
+			strings.HasPrefix(c.ContentType(), "multipart/") {
+			c.Next()
+			return
+		}
+
+		max := limit
+		if routeLimit, ok := routeLimits[c.FullPath()]; ok {
+			max = routeLimit
+		}
+		if max <= 0 {
+			c.Next()
+			return
+		}
+
+		if c.Request.ContentLength > max {
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+				"error": fmt.Sprintf("Request body exceeds the maximum size of %d bytes", max),
+				"code":  "payload_too_large",
+			})
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, max)
+		c.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimit(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(BodyLimit(10, map[string]int64{"/items/:table/bulk": 100}))
+	read := func(c *gin.Context) {
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.String(http.StatusBadRequest, err.Error())
+			return
+		}
+		c.String(http.StatusOK, "%d", len(body))
+	}
+	router.POST("/items/:table", read)
+	router.POST("/items/:table/bulk", read)
+	router.POST("/assets", read)
+
+	tests := []struct {
+		name        string
+		path        string
+		body        string
+		contentType string
+		chunked     bool
+		want        int
+	}{
+		{"Within Limit", "/items/products", `{"a":1}`, "application/json", false, http.StatusOK},
+		{"Declared Too Large", "/items/products", strings.Repeat("x", 11), "application/json", false, http.StatusRequestEntityTooLarge},
+		{"Chunked Too Large", "/items/products", strings.Repeat("x", 11), "application/json", true, http.StatusBadRequest},
+		{"Route Limit", "/items/products/bulk", strings.Repeat("x", 50), "application/json", false, http.StatusOK},
+		{"Beyond Route Limit", "/items/products/bulk", strings.Repeat("x", 101), "application/json", false, http.StatusRequestEntityTooLarge},
+		{"Multipart", "/assets", strings.Repeat("x", 50), "multipart/form-data; boundary=x", false, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.chunked {
+				req.ContentLength = -1
+			}
+			w := httptest.NewRecorder()
+			router.ServeHTTP(w, req)
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusRequestEntityTooLarge {
+				assert.Contains(t, w.Body.String(), "payload_too_large")
+			}
+		})
+	}
+}
@@ -0,0 +1,210 @@
+package middleware
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+)
+
+// Content codings Compress can produce, in order of preference
+const (
+	encodingBrotli = "br"
+	encodingGzip   = "gzip"
+)
+
+// compressWriter holds back the start of a response until it knows whether to compress
+// it: the response is compressed once MinSize bytes have been written, and sent as is if
+// it ends or is flushed before then.
+type compressWriter struct {
+	gin.ResponseWriter
+	encoding string // Content coding accepted by the client
+	minSize  int
+	types    map[string]bool
+
+	buffer  bytes.Buffer
+	decided bool
+	encoder io.WriteCloser // nil when the response is sent as is
+}
+
+func (w *compressWriter) WriteHeaderNow() {
+	if w.decided {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buffer.Write(data)
+		if w.buffer.Len() < w.minSize {
+			return len(data), nil
+		}
+		if err := w.decide(true); err != nil {
+			return 0, err
+		}
+		return len(data), nil
+	}
+	if w.encoder != nil {
+		return w.encoder.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *compressWriter) WriteString(data string) (int, error) {
+	return w.Write([]byte(data))
+}
+
+func (w *compressWriter) Written() bool {
+	return w.buffer.Len() > 0 || w.ResponseWriter.Written()
+}
+
+func (w *compressWriter) Size() int {
+	if !w.decided {
+		return w.buffer.Len()
+	}
+	return w.ResponseWriter.Size()
+}
+
+// Flush sends what was written so far; a response flushed before it reaches MinSize is
+// a stream, which is not compressed
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if flusher, ok := w.encoder.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	w.ResponseWriter.Flush()
+}
+
+// decide starts the response, compressed if it is large enough and of a compressible
+// type, and writes the buffered start of it
+func (w *compressWriter) decide(large bool) error {
+	w.decided = true
+	header := w.Header()
+	if large && w.compressible(header) {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+		header.Add("Vary", "Accept-Encoding")
+		// The compressed body differs from the one the ETag was computed from
+		if etag := header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+			header.Set("ETag", "W/"+etag)
+		}
+		if w.encoding == encodingBrotli {
+			w.encoder = brotli.NewWriterLevel(w.ResponseWriter, brotli.DefaultCompression)
+		} else {
+			w.encoder, _ = gzip.NewWriterLevel(w.ResponseWriter, gzip.DefaultCompression)
+		}
+	}
+
+	if w.buffer.Len() == 0 {
+		return nil
+	}
+	start := w.buffer.Bytes()
+	w.buffer = bytes.Buffer{}
+	if w.encoder != nil {
+		_, err := w.encoder.Write(start)
+		return err
+	}
+	_, err := w.ResponseWriter.Write(start)
+	return err
+}
+
+// compressible reports whether the response may be compressed: a full response of one of
+// the configured types that is not encoded already
+func (w *compressWriter) compressible(header http.Header) bool {
+	status := w.Status()
+	if status == http.StatusPartialContent || status == http.StatusNoContent || status == http.StatusNotModified {
+		return false
+	}
+	if header.Get("Content-Encoding") != "" || header.Get("Content-Range") != "" {
+		return false
+	}
+	mediaType, _, err := mime.ParseMediaType(header.Get("Content-Type"))
+	return err == nil && w.types[mediaType]
+}
+
+// finish sends a response that ended before reaching MinSize, or completes the
+// compressed one
+func (w *compressWriter) finish() {
+	if !w.decided {
+		w.decide(false)
+	}
+	if w.encoder != nil {
+		w.encoder.Close()
+	}
+}
+
+// Compress compresses responses with Brotli or gzip, whichever the client prefers in
+// Accept-Encoding (Brotli on a tie). Only responses of COMPRESSION_TYPES that reach
+// COMPRESSION_MIN_SIZE bytes are compressed; smaller ones are not worth it, and streams
+// such as realtime events are flushed before they get that far. Compressed responses
+// carry a weak ETag, since their bytes differ from the ones it was computed from.
+func Compress(cfg *config.Config) gin.HandlerFunc {
+	types := make(map[string]bool, len(cfg.CompressionTypes))
+	for _, contentType := range cfg.CompressionTypes {
+		types[strings.ToLower(strings.TrimSpace(contentType))] = true
+	}
+
+	return func(c *gin.Context) {
+		encoding := acceptedEncoding(c.GetHeader("Accept-Encoding"))
+		if !cfg.CompressionEnabled || encoding == "" || c.Request.Method == http.MethodHead {
+			c.Next()
+			return
+		}
+
+		writer := &compressWriter{
+			ResponseWriter: c.Writer,
+			encoding:       encoding,
+			minSize:        cfg.CompressionMinSize,
+			types:          types,
+		}
+		c.Writer = writer
+		defer func() {
+			writer.finish()
+			c.Writer = writer.ResponseWriter
+		}()
+		c.Next()
+	}
+}
+
+// acceptedEncoding picks the content coding to use from an Accept-Encoding header, or
+// returns "" when the client accepts neither Brotli nor gzip
+func acceptedEncoding(header string) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		quality := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			quality = parsed
+		}
+
+		switch coding {
+		case encodingBrotli, encodingGzip:
+		case "*":
+			coding = encodingBrotli
+		default:
+			continue
+		}
+		if quality > bestQuality || (quality == bestQuality && quality > 0 && coding == encodingBrotli) {
+			best, bestQuality = coding, quality
+		}
+	}
+	if bestQuality == 0 {
+		return ""
+	}
+	return best
+}
@@ -0,0 +1,131 @@
+package middleware
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/andybalholm/brotli"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var largeJSON = `{"data":"` + strings.Repeat("basin ", 500) + `"}`
+
+func newCompressRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(Compress(&config.Config{
+		CompressionEnabled: true,
+		CompressionMinSize: 1024,
+		CompressionTypes:   []string{"application/json", "text/csv"},
+	}))
+	router.GET("/large", func(c *gin.Context) { c.Data(http.StatusOK, "application/json; charset=utf-8", []byte(largeJSON)) })
+	router.GET("/small", func(c *gin.Context) { c.JSON(http.StatusOK, gin.H{"data": "basin"}) })
+	router.GET("/binary", func(c *gin.Context) { c.Data(http.StatusOK, "application/zip", []byte(largeJSON)) })
+	router.GET("/etag", ETag(), func(c *gin.Context) { c.Data(http.StatusOK, "application/json", []byte(largeJSON)) })
+	router.GET("/stream", func(c *gin.Context) {
+		c.Header("Content-Type", "text/csv")
+		c.Writer.WriteString("id,name\n")
+		c.Writer.Flush()
+		c.Writer.WriteString(strings.Repeat("1,basin\n", 500))
+	})
+	return router
+}
+
+func compressRequest(router *gin.Engine, path, acceptEncoding string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if acceptEncoding != "" {
+		req.Header.Set("Accept-Encoding", acceptEncoding)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCompress(t *testing.T) {
+	router := newCompressRouter()
+
+	w := compressRequest(router, "/large", "gzip, deflate")
+	assert.Equal(t, "gzip", w.Header().Get("Content-Encoding"))
+	assert.Equal(t, "Accept-Encoding", w.Header().Get("Vary"))
+	reader, err := gzip.NewReader(w.Body)
+	require.NoError(t, err)
+	body, err := io.ReadAll(reader)
+	require.NoError(t, err)
+	assert.Equal(t, largeJSON, string(body))
+	assert.Less(t, w.Body.Len(), len(largeJSON))
+
+	w = compressRequest(router, "/large", "gzip, br")
+	assert.Equal(t, "br", w.Header().Get("Content-Encoding"))
+	body, err = io.ReadAll(brotli.NewReader(w.Body))
+	require.NoError(t, err)
+	assert.Equal(t, largeJSON, string(body))
+
+	// Small responses, other types and clients without support get the body as is
+	for _, tt := range []struct{ path, acceptEncoding string }{
+		{"/small", "gzip"},
+		{"/binary", "gzip"},
+		{"/large", ""},
+		{"/large", "deflate"},
+		{"/large", "gzip;q=0"},
+	} {
+		w := compressRequest(router, tt.path, tt.acceptEncoding)
+		assert.Empty(t, w.Header().Get("Content-Encoding"), tt.path+" "+tt.acceptEncoding)
+		assert.Equal(t, http.StatusOK, w.Code)
+	}
+	assert.Equal(t, largeJSON, compressRequest(router, "/large", "identity").Body.String())
+
+	// Streams flushed before they reach the minimum size are not compressed
+	w = compressRequest(router, "/stream", "gzip")
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "id,name\n1,basin\n"))
+}
+
+func TestCompress_ETag(t *testing.T) {
+	router := newCompressRouter()
+
+	w := compressRequest(router, "/etag", "gzip")
+	etag := w.Header().Get("ETag")
+	assert.True(t, strings.HasPrefix(etag, `W/"`), etag)
+
+	// The weak ETag of a compressed response still validates
+	req := httptest.NewRequest(http.MethodGet, "/etag", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	req.Header.Set("If-None-Match", etag)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Header().Get("Content-Encoding"))
+	assert.Empty(t, w.Body.String())
+}
+
+func TestAcceptedEncoding(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"gzip, deflate, br", "br"},
+		{"br;q=0.5, gzip", "gzip"},
+		{"gzip;q=0.8, br;q=0.9", "br"},
+		{"*", "br"},
+		{"br;q=0, gzip;q=0", ""},
+		{"deflate, identity", ""},
+		{"gzip;q=abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			assert.Equal(t, tt.want, acceptedEncoding(tt.header))
+		})
+	}
+}
@@ -0,0 +1,111 @@
+package middleware
+
+import (
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORS answers preflight requests and adds the Access-Control-* headers for allowed
+// origins, configured through the CORS_* settings. Requests from other origins get no CORS
+// headers (so browsers block them) and their preflights are rejected with 403.
+//
+// "*" allows any origin. Without credentials it is sent as is; with credentials browsers
+// reject "*", so the request's origin is echoed instead. Prefer listing origins when
+// CORS_ALLOW_CREDENTIALS is on.
+func CORS(cfg *config.Config) gin.HandlerFunc {
+	allowMethods := strings.Join(cfg.CORSAllowedMethods, ", ")
+	allowHeaders := strings.Join(cfg.CORSAllowedHeaders, ", ")
+	exposeHeaders := strings.Join(cfg.CORSExposedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.CORSMaxAge.Seconds()))
+
+	allowAll := false
+	for _, origin := range cfg.CORSAllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+		}
+	}
+	if allowAll && cfg.CORSAllowCredentials {
+		slog.Warn("CORS allows credentials from any origin; set CORS_ALLOWED_ORIGINS to restrict it")
+	}
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if origin == "" {
+			// Not a cross-origin browser request
+			if c.Request.Method == http.MethodOptions {
+				c.AbortWithStatus(http.StatusNoContent)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		// The response depends on the Origin header whenever it is not a plain "*"
+		c.Header("Vary", "Origin")
+
+		if !originAllowed(cfg.CORSAllowedOrigins, origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if allowAll && !cfg.CORSAllowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if cfg.CORSAllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if c.Request.Method == http.MethodOptions {
+			c.Header("Access-Control-Allow-Methods", allowMethods)
+			c.Header("Access-Control-Allow-Headers", allowHeaders)
+			if cfg.CORSMaxAge > 0 {
+				c.Header("Access-Control-Max-Age", maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if exposeHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", exposeHeaders)
+		}
+		c.Next()
+	}
+}
+
+// originAllowed matches an origin against the allowed list. Entries are compared without
+// regard to case and may use one leading "*." in the host for subdomains, e.g.
+// https://*.example.com matches https://app.example.com but not https://example.com.
+func originAllowed(allowed []string, origin string) bool {
+	origin = strings.ToLower(origin)
+	for _, pattern := range allowed {
+		pattern = strings.ToLower(pattern)
+		if pattern == "*" || pattern == origin {
+			return true
+		}
+
+		scheme, host, found := strings.Cut(pattern, "://*.")
+		if !found {
+			continue
+		}
+		prefix := scheme + "://"
+		suffix := "." + host
+		if strings.HasPrefix(origin, prefix) && strings.HasSuffix(origin, suffix) && len(origin) > len(prefix)+len(suffix) {
+			return true
+		}
+	}
+	return false
+}
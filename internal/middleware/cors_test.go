@@ -0,0 +1,97 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newCORSRouter(cfg *config.Config) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(CORS(cfg))
+	router.GET("/items/products", func(c *gin.Context) { c.Status(http.StatusOK) })
+	return router
+}
+
+func corsRequest(router *gin.Engine, method, origin string, preflight bool) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/items/products", nil)
+	if origin != "" {
+		req.Header.Set("Origin", origin)
+	}
+	if preflight {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCORS_Wildcard(t *testing.T) {
+	router := newCORSRouter(&config.Config{
+		CORSAllowedOrigins: []string{"*"},
+		CORSAllowedMethods: []string{"GET", "POST"},
+		CORSAllowedHeaders: []string{"Authorization"},
+		CORSExposedHeaders: []string{"X-Request-ID"},
+		CORSMaxAge:         time.Hour,
+	})
+
+	w := corsRequest(router, http.MethodGet, "https://app.example.com", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "X-Request-ID", w.Header().Get("Access-Control-Expose-Headers"))
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Credentials"))
+
+	w = corsRequest(router, http.MethodOptions, "https://app.example.com", true)
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+	assert.Equal(t, "Authorization", w.Header().Get("Access-Control-Allow-Headers"))
+	assert.Equal(t, "3600", w.Header().Get("Access-Control-Max-Age"))
+}
+
+func TestCORS_AllowedOriginsWithCredentials(t *testing.T) {
+	router := newCORSRouter(&config.Config{
+		CORSAllowedOrigins:   []string{"https://admin.example.com", "https://*.shop.example.com"},
+		CORSAllowCredentials: true,
+	})
+
+	w := corsRequest(router, http.MethodGet, "https://admin.example.com", false)
+	assert.Equal(t, "https://admin.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+	assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	assert.Equal(t, "Origin", w.Header().Get("Vary"))
+
+	w = corsRequest(router, http.MethodGet, "https://eu.shop.example.com", false)
+	assert.Equal(t, "https://eu.shop.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	// Other origins get no CORS headers and their preflights are refused
+	w = corsRequest(router, http.MethodGet, "https://evil.example.net", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = corsRequest(router, http.MethodOptions, "https://shop.example.com", true)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestCORS_NoOrigin(t *testing.T) {
+	router := newCORSRouter(&config.Config{CORSAllowedOrigins: []string{"https://admin.example.com"}})
+
+	w := corsRequest(router, http.MethodGet, "", false)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+}
+
+func TestOriginAllowed(t *testing.T) {
+	allowed := []string{"https://App.example.com", "https://*.example.org"}
+
+	assert.True(t, originAllowed(allowed, "https://app.example.com"))
+	assert.True(t, originAllowed(allowed, "https://a.b.example.org"))
+	assert.False(t, originAllowed(allowed, "https://example.org"))
+	assert.False(t, originAllowed(allowed, "http://a.example.org"))
+	assert.False(t, originAllowed(allowed, "https://app.example.com.evil.net"))
+}
@@ -0,0 +1,145 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// directusMetaFields are the meta entries Directus returns; Basin's others are dropped
+var directusMetaFields = []string{"total_count", "filter_count"}
+
+// directusErrorCodes maps Basin's error codes to the extensions.code of Directus errors
+var directusErrorCodes = map[string]string{
+	"validation_failed":       "FAILED_VALIDATION",
+	"invalid_body":            "INVALID_PAYLOAD",
+	"payload_too_large":       "INVALID_PAYLOAD",
+	"forbidden":               "FORBIDDEN",
+	"not_found":               "ROUTE_NOT_FOUND",
+	"conflict":                "RECORD_NOT_UNIQUE",
+	"quota_exceeded":          "LIMIT_EXCEEDED",
+	"idempotency_key_reused":  "INVALID_PAYLOAD",
+	"invalid_idempotency_key": "INVALID_PAYLOAD",
+	"internal_error":          "INTERNAL_SERVER_ERROR",
+}
+
+// directusStatusCodes are the Directus codes of errors without a Basin code, by status
+var directusStatusCodes = map[int]string{
+	http.StatusBadRequest:          "INVALID_QUERY",
+	http.StatusUnauthorized:        "INVALID_CREDENTIALS",
+	http.StatusForbidden:           "FORBIDDEN",
+	http.StatusNotFound:            "ROUTE_NOT_FOUND",
+	http.StatusConflict:            "RECORD_NOT_UNIQUE",
+	http.StatusUnprocessableEntity: "FAILED_VALIDATION",
+	http.StatusTooManyRequests:     "REQUESTS_EXCEEDED",
+	http.StatusServiceUnavailable:  "SERVICE_UNAVAILABLE",
+}
+
+// DirectusDialect rewrites JSON responses into the envelopes of the Directus REST API, so
+// Directus SDKs and frontends can use Basin's item routes (API_DIALECT=directus):
+//
+//   - meta keeps only total_count and filter_count, and is left out when neither was
+//     requested
+//   - successful deletes answer 204 No Content
+//   - errors become {"errors": [{"message": ..., "extensions": {"code": ...}}]}, one entry
+//     per invalid field when there are field errors
+//
+// The query syntax (filter, deep, fields, sort, meta) is understood without it. It must
+// run before AuthMiddleware so authentication errors are rewritten too. Responses are
+// buffered, so only use it on routes whose responses fit in memory.
+func DirectusDialect() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		body := writer.body.Bytes()
+		status := writer.status
+		// Numbers are kept as written, so large IDs do not lose precision
+		var response map[string]interface{}
+		decoder := json.NewDecoder(bytes.NewReader(body))
+		decoder.UseNumber()
+		if !strings.HasPrefix(original.Header().Get("Content-Type"), "application/json") ||
+			decoder.Decode(&response) != nil {
+			writeBuffered(original, status, body)
+			return
+		}
+
+		if status >= http.StatusBadRequest {
+			response = directusError(status, response)
+		} else {
+			if meta, ok := response["meta"].(map[string]interface{}); ok {
+				kept := map[string]interface{}{}
+				for _, field := range directusMetaFields {
+					if value, exists := meta[field]; exists {
+						kept[field] = value
+					}
+				}
+				if len(kept) > 0 {
+					response["meta"] = kept
+				} else {
+					delete(response, "meta")
+				}
+			}
+			if _, hasData := response["data"]; !hasData && c.Request.Method == http.MethodDelete {
+				original.Header().Del("Content-Type")
+				original.WriteHeader(http.StatusNoContent)
+				original.WriteHeaderNow()
+				return
+			}
+		}
+
+		rewritten, err := json.Marshal(response)
+		if err != nil {
+			writeBuffered(original, status, body)
+			return
+		}
+		writeBuffered(original, status, rewritten)
+	}
+}
+
+// directusError converts a Basin error body ({"error", "code", "errors"}) to a Directus one
+func directusError(status int, response map[string]interface{}) map[string]interface{} {
+	message, _ := response["error"].(string)
+	if message == "" {
+		message = http.StatusText(status)
+	}
+	basinCode, _ := response["code"].(string)
+	code, ok := directusErrorCodes[basinCode]
+	if !ok {
+		if code, ok = directusStatusCodes[status]; !ok {
+			code = "INTERNAL_SERVER_ERROR"
+		}
+	}
+
+	var errs []interface{}
+	if fieldErrors, ok := response["errors"].([]interface{}); ok {
+		for _, entry := range fieldErrors {
+			fieldErr, _ := entry.(map[string]interface{})
+			if fieldErr == nil {
+				continue
+			}
+			fieldMessage, _ := fieldErr["message"].(string)
+			errs = append(errs, gin.H{
+				"message":    fieldMessage,
+				"extensions": gin.H{"code": code, "field": fieldErr["field"], "type": fieldErr["code"]},
+			})
+		}
+	}
+	if len(errs) == 0 {
+		errs = []interface{}{gin.H{"message": message, "extensions": gin.H{"code": code}}}
+	}
+	return map[string]interface{}{"errors": errs}
+}
+
+// writeBuffered sends a buffered response
+func writeBuffered(w gin.ResponseWriter, status int, body []byte) {
+	w.Header().Del("Content-Length")
+	w.WriteHeader(status)
+	w.Write(body)
+}
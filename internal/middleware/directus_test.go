@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDirectusDialect(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.Use(DirectusDialect())
+	router.GET("/items/products", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{
+			"data": []gin.H{{"id": 9007199254740993}},
+			"meta": gin.H{"table": "products", "count": 1, "limit": 50, "total_count": 12},
+		})
+	})
+	router.GET("/items/products/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{"id": c.Param("id")}, "meta": gin.H{"table": "products"}})
+	})
+	router.DELETE("/items/products/:id", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"meta": gin.H{"table": "products", "id": c.Param("id")}})
+	})
+	router.POST("/items/products", func(c *gin.Context) {
+		c.JSON(http.StatusUnprocessableEntity, gin.H{
+			"error":  "Failed to create item: field 'name': is required",
+			"code":   "validation_failed",
+			"errors": []gin.H{{"field": "name", "code": "required", "message": "is required"}},
+		})
+	})
+	router.PATCH("/items/products/:id", func(c *gin.Context) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Insufficient permissions"})
+	})
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+
+	w := send(http.MethodGet, "/items/products")
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"data":[{"id":9007199254740993}],"meta":{"total_count":12}}`, w.Body.String())
+
+	w = send(http.MethodGet, "/items/products/1")
+	assert.JSONEq(t, `{"data":{"id":"1"}}`, w.Body.String())
+
+	w = send(http.MethodDelete, "/items/products/1")
+	assert.Equal(t, http.StatusNoContent, w.Code)
+	assert.Empty(t, w.Body.String())
+
+	w = send(http.MethodPost, "/items/products")
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	assert.JSONEq(t, `{"errors":[{"message":"is required","extensions":{"code":"FAILED_VALIDATION","field":"name","type":"required"}}]}`, w.Body.String())
+
+	w = send(http.MethodPatch, "/items/products/1")
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.JSONEq(t, `{"errors":[{"message":"Insufficient permissions","extensions":{"code":"FORBIDDEN"}}]}`, w.Body.String())
+}
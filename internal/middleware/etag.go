@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Columns holding the modification time of an item, in order of preference
+var modifiedColumns = []string{"updated_at", "created_at"}
+
+// bufferedWriter holds back the status and body of a response until the handlers are done
+type bufferedWriter struct {
+	gin.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *bufferedWriter) WriteHeader(code int)                 { w.status = code }
+func (w *bufferedWriter) WriteHeaderNow()                      {}
+func (w *bufferedWriter) Write(data []byte) (int, error)       { return w.body.Write(data) }
+func (w *bufferedWriter) WriteString(data string) (int, error) { return w.body.WriteString(data) }
+func (w *bufferedWriter) Status() int                          { return w.status }
+func (w *bufferedWriter) Size() int                            { return w.body.Len() }
+func (w *bufferedWriter) Written() bool                        { return w.body.Len() > 0 }
+
+// ETag makes GET responses conditional. Successful responses carry an ETag computed from
+// their body, and single items a Last-Modified taken from their updated_at (or
+// created_at). Requests whose If-None-Match names the current ETag, or without one whose
+// If-Modified-Since is not older than Last-Modified, are answered with 304 and no body.
+//
+// Lists get no Last-Modified, since removing an item does not make the remaining ones
+// newer. Responses are buffered, so only use it on routes whose responses fit in memory.
+func ETag() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if c.Request.Method != http.MethodGet {
+			c.Next()
+			return
+		}
+
+		original := c.Writer
+		writer := &bufferedWriter{ResponseWriter: original, status: http.StatusOK}
+		c.Writer = writer
+		c.Next()
+		c.Writer = original
+
+		body := writer.body.Bytes()
+		if writer.status != http.StatusOK {
+			original.WriteHeader(writer.status)
+			original.Write(body)
+			return
+		}
+
+		sum := sha256.Sum256(body)
+		etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+		modified := lastModified(body)
+
+		header := original.Header()
+		header.Set("ETag", etag)
+		// Responses depend on the caller, so only the caller may keep them and must check
+		// they are still current before reusing them
+		header.Set("Cache-Control", "private, no-cache")
+		if !modified.IsZero() {
+			header.Set("Last-Modified", modified.UTC().Format(http.TimeFormat))
+		}
+
+		if notModified(c.Request, etag, modified) {
+			header.Del("Content-Type")
+			header.Del("Content-Length")
+			original.WriteHeader(http.StatusNotModified)
+			original.WriteHeaderNow()
+			return
+		}
+		original.WriteHeader(http.StatusOK)
+		original.Write(body)
+	}
+}
+
+// notModified reports whether the client's copy of a response is current. If-None-Match
+// takes precedence over If-Modified-Since, and is compared weakly.
+func notModified(r *http.Request, etag string, modified time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); match != "" {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+			if candidate == "*" || candidate == etag {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || modified.IsZero() {
+		return false
+	}
+	// HTTP dates have whole seconds
+	return !modified.Truncate(time.Second).After(since)
+}
+
+// lastModified returns the modification time of the item a response body holds in
+// "data", or the zero time for lists and items without one
+func lastModified(body []byte) time.Time {
+	var response struct {
+		Data json.RawMessage `json:"data"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || !bytes.HasPrefix(response.Data, []byte("{")) {
+		return time.Time{}
+	}
+
+	var item map[string]interface{}
+	if err := json.Unmarshal(response.Data, &item); err != nil {
+		return time.Time{}
+	}
+	for _, column := range modifiedColumns {
+		if value, ok := item[column].(string); ok {
+			if modified, err := time.Parse(time.RFC3339Nano, value); err == nil {
+				return modified
+			}
+		}
+	}
+	return time.Time{}
+}
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func newETagRouter() *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/items/products/:id", ETag(), func(c *gin.Context) {
+		if c.Param("id") == "missing" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Item not found"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"data": gin.H{"id": c.Param("id"), "updated_at": "2026-03-04T05:06:07.5Z"}})
+	})
+	router.GET("/items/products", ETag(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"data": []gin.H{{"id": "1", "updated_at": "2026-03-04T05:06:07Z"}}})
+	})
+	return router
+}
+
+func etagRequest(router *gin.Engine, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestETag(t *testing.T) {
+	router := newETagRouter()
+
+	w := etagRequest(router, "/items/products/1", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	assert.Equal(t, "Wed, 04 Mar 2026 05:06:07 GMT", w.Header().Get("Last-Modified"))
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+	assert.Contains(t, w.Body.String(), `"id":"1"`)
+
+	// The same response gets the same ETag, another one a different ETag
+	assert.Equal(t, etag, etagRequest(router, "/items/products/1", nil).Header().Get("ETag"))
+	assert.NotEqual(t, etag, etagRequest(router, "/items/products/2", nil).Header().Get("ETag"))
+
+	// Lists get no Last-Modified
+	list := etagRequest(router, "/items/products", nil)
+	assert.NotEmpty(t, list.Header().Get("ETag"))
+	assert.Empty(t, list.Header().Get("Last-Modified"))
+
+	// Errors are passed through unchanged
+	missing := etagRequest(router, "/items/products/missing", nil)
+	assert.Equal(t, http.StatusNotFound, missing.Code)
+	assert.Empty(t, missing.Header().Get("ETag"))
+	assert.Contains(t, missing.Body.String(), "Item not found")
+}
+
+func TestETag_Conditional(t *testing.T) {
+	router := newETagRouter()
+	etag := etagRequest(router, "/items/products/1", nil).Header().Get("ETag")
+
+	tests := []struct {
+		name    string
+		path    string
+		headers map[string]string
+		want    int
+	}{
+		{"Matching ETag", "/items/products/1", map[string]string{"If-None-Match": etag}, http.StatusNotModified},
+		{"Weak ETag In List", "/items/products/1", map[string]string{"If-None-Match": `"other", W/` + etag}, http.StatusNotModified},
+		{"Any ETag", "/items/products/1", map[string]string{"If-None-Match": "*"}, http.StatusNotModified},
+		{"Changed ETag", "/items/products/2", map[string]string{"If-None-Match": etag}, http.StatusOK},
+		{"Not Modified Since", "/items/products/1", map[string]string{"If-Modified-Since": "Wed, 04 Mar 2026 05:06:07 GMT"}, http.StatusNotModified},
+		{"Modified Since", "/items/products/1", map[string]string{"If-Modified-Since": "Wed, 04 Mar 2026 05:06:06 GMT"}, http.StatusOK},
+		{"ETag Takes Precedence", "/items/products/1", map[string]string{"If-None-Match": `"other"`, "If-Modified-Since": "Wed, 04 Mar 2026 05:06:07 GMT"}, http.StatusOK},
+		{"List Modified Since", "/items/products", map[string]string{"If-Modified-Since": "Wed, 04 Mar 2026 05:06:07 GMT"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := etagRequest(router, tt.path, tt.headers)
+			assert.Equal(t, tt.want, w.Code)
+			if tt.want == http.StatusNotModified {
+				assert.Empty(t, w.Body.String())
+				assert.Equal(t, etag, w.Header().Get("ETag"))
+			}
+		})
+	}
+}
@@ -0,0 +1,155 @@
+package middleware
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"go-rbac-api/internal/idempotency"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// maxIdempotencyKeyLength is the longest Idempotency-Key accepted
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyStore claims idempotency keys and keeps the responses of their requests
+type IdempotencyStore interface {
+	Begin(ctx context.Context, userID uuid.UUID, key, requestHash string) (*idempotency.Response, error)
+	Complete(ctx context.Context, userID uuid.UUID, key string, response idempotency.Response) error
+	Release(ctx context.Context, userID uuid.UUID, key string) error
+}
+
+// responseTee passes a response through while keeping a copy of its body
+type responseTee struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseTee) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseTee) WriteString(data string) (int, error) {
+	w.body.WriteString(data)
+	return w.ResponseWriter.WriteString(data)
+}
+
+// Idempotency honors the Idempotency-Key header of write requests. The response of the
+// first request with a key is stored, and retries with the same key and request are
+// answered with it (marked Idempotent-Replayed: true) instead of running again. Reusing a
+// key for a different request is refused with 422, and a retry arriving while the first
+// request is still running with 409.
+//
+// Server errors (5xx) are not stored, so those requests can be retried. Requests without
+// the header pass through. It must run after AuthMiddleware, as keys are per user.
+func Idempotency(store IdempotencyStore) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("Idempotency-Key")
+		userID, ok := GetUserID(c)
+		if key == "" || !ok {
+			c.Next()
+			return
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("Idempotency-Key must be at most %d characters", maxIdempotencyKeyLength),
+				"code":  "invalid_idempotency_key",
+			})
+			return
+		}
+
+		var body []byte
+		if c.Request.Body != nil {
+			var err error
+			if body, err = io.ReadAll(c.Request.Body); err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, gin.H{
+						"error": fmt.Sprintf("Request body exceeds the maximum size of %d bytes", tooLarge.Limit),
+						"code":  "payload_too_large",
+					})
+					return
+				}
+				c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Failed to read request body", "code": "invalid_body"})
+				return
+			}
+			c.Request.Body = io.NopCloser(bytes.NewReader(body))
+		}
+
+		// Responses are stored even when the client is gone, which is when retries happen
+		ctx := context.WithoutCancel(c.Request.Context())
+		stored, err := store.Begin(ctx, userID, key, requestHash(c, body))
+		switch {
+		case errors.Is(err, idempotency.ErrKeyReused):
+			c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{
+				"error": "Idempotency-Key was already used for a different request",
+				"code":  "idempotency_key_reused",
+			})
+			return
+		case errors.Is(err, idempotency.ErrInProgress):
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusConflict, gin.H{
+				"error": "A request with this Idempotency-Key is still in progress",
+				"code":  "conflict",
+			})
+			return
+		case err != nil:
+			// Without the store the request runs unprotected rather than not at all
+			GetLogger(c).Warn("failed to claim idempotency key", "error", err)
+			c.Next()
+			return
+		case stored != nil:
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		tee := &responseTee{ResponseWriter: c.Writer}
+		c.Writer = tee
+		completed := false
+		defer func() {
+			c.Writer = tee.ResponseWriter
+			if completed {
+				return
+			}
+			// The handler panicked; let the request be retried
+			if err := store.Release(ctx, userID, key); err != nil {
+				GetLogger(c).Warn("failed to release idempotency key", "error", err)
+			}
+		}()
+		c.Next()
+		completed = true
+
+		if status := tee.Status(); status >= http.StatusInternalServerError {
+			err = store.Release(ctx, userID, key)
+		} else {
+			err = store.Complete(ctx, userID, key, idempotency.Response{
+				StatusCode:  status,
+				ContentType: tee.Header().Get("Content-Type"),
+				Body:        tee.body.Bytes(),
+			})
+		}
+		if err != nil {
+			GetLogger(c).Warn("failed to store idempotent response", "error", err)
+		}
+	}
+}
+
+// requestHash identifies a request by its method, URL, tenant and body, so a key cannot
+// replay the response of a different request
+func requestHash(c *gin.Context, body []byte) string {
+	hash := sha256.New()
+	tenantID, _ := GetTenantID(c)
+	fmt.Fprintf(hash, "%s\n%s\n%s\n", c.Request.Method, c.Request.URL.RequestURI(), tenantID)
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
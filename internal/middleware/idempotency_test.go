@@ -0,0 +1,148 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"go-rbac-api/internal/idempotency"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryIdempotencyStore keeps keys in memory, like idempotency.Store does in the database
+type memoryIdempotencyStore struct {
+	mu      sync.Mutex
+	hashes  map[string]string
+	results map[string]*idempotency.Response
+}
+
+func newMemoryIdempotencyStore() *memoryIdempotencyStore {
+	return &memoryIdempotencyStore{hashes: map[string]string{}, results: map[string]*idempotency.Response{}}
+}
+
+func (s *memoryIdempotencyStore) Begin(_ context.Context, userID uuid.UUID, key, requestHash string) (*idempotency.Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	id := userID.String() + "/" + key
+	hash, ok := s.hashes[id]
+	switch {
+	case !ok:
+		s.hashes[id] = requestHash
+		return nil, nil
+	case hash != requestHash:
+		return nil, idempotency.ErrKeyReused
+	case s.results[id] == nil:
+		return nil, idempotency.ErrInProgress
+	}
+	return s.results[id], nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, userID uuid.UUID, key string, response idempotency.Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.results[userID.String()+"/"+key] = &response
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, userID uuid.UUID, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.hashes, userID.String()+"/"+key)
+	return nil
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	alice, bob := uuid.New(), uuid.New()
+	created := 0
+	status := http.StatusCreated
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) {
+		if c.GetHeader("X-User") == "bob" {
+			c.Set("user_id", bob)
+		} else {
+			c.Set("user_id", alice)
+		}
+	})
+	router.POST("/items/:table", Idempotency(newMemoryIdempotencyStore()), func(c *gin.Context) {
+		created++
+		c.JSON(status, gin.H{"data": gin.H{"number": created}})
+	})
+
+	send := func(key, user, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/items/orders", strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		req.Header.Set("X-User", user)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	first := send("order-1", "alice", `{"total":10}`)
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+
+	// A retry gets the stored response without creating the order again
+	retry := send("order-1", "alice", `{"total":10}`)
+	assert.Equal(t, http.StatusCreated, retry.Code)
+	assert.Equal(t, "true", retry.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Body.String(), retry.Body.String())
+	assert.Equal(t, "application/json; charset=utf-8", retry.Header().Get("Content-Type"))
+	assert.Equal(t, 1, created)
+
+	// The key cannot be reused for another request, but other users have their own keys
+	reused := send("order-1", "alice", `{"total":20}`)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	assert.Contains(t, reused.Body.String(), "idempotency_key_reused")
+	assert.Equal(t, http.StatusCreated, send("order-1", "bob", `{"total":20}`).Code)
+	assert.Equal(t, 2, created)
+
+	// Requests without a key always run
+	send("", "alice", `{"total":10}`)
+	send("", "alice", `{"total":10}`)
+	assert.Equal(t, 4, created)
+
+	// Server errors are not stored, so the retry runs again
+	status = http.StatusInternalServerError
+	assert.Equal(t, http.StatusInternalServerError, send("order-2", "alice", `{}`).Code)
+	status = http.StatusCreated
+	assert.Equal(t, http.StatusCreated, send("order-2", "alice", `{}`).Code)
+	assert.Equal(t, 6, created)
+
+	assert.Equal(t, http.StatusBadRequest, send(strings.Repeat("k", 256), "alice", `{}`).Code)
+}
+
+func TestIdempotency_InProgress(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	store := newMemoryIdempotencyStore()
+	userID := uuid.New()
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set("user_id", userID) })
+	router.POST("/items/:table", Idempotency(store), func(c *gin.Context) {
+		c.Status(http.StatusCreated)
+	})
+
+	// The same request arriving while the first one runs
+	req := httptest.NewRequest(http.MethodPost, "/items/orders", strings.NewReader(`{}`))
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = req
+	_, err := store.Begin(context.Background(), userID, "order-1", requestHash(c, []byte(`{}`)))
+	assert.NoError(t, err)
+
+	req = httptest.NewRequest(http.MethodPost, "/items/orders", strings.NewReader(`{}`))
+	req.Header.Set("Idempotency-Key", "order-1")
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, "1", w.Header().Get("Retry-After"))
+}
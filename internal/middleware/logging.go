@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/logging"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// RequestLogger attaches a logger carrying the request ID to the request context and
+// writes one access log line per request with its method, route, status, latency, user
+// and tenant. It must run after RequestID. Server errors are logged at error level,
+// client errors at warn and health checks, failed ones included, at debug.
+func RequestLogger(logger *slog.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+
+		requestLogger := logger.With("request_id", GetRequestID(c))
+		c.Request = c.Request.WithContext(logging.WithLogger(c.Request.Context(), requestLogger))
+
+		c.Next()
+
+		status := c.Writer.Status()
+		attrs := []any{
+			"method", c.Request.Method,
+			"path", c.Request.URL.Path,
+			"route", c.FullPath(),
+			"status", status,
+			"latency_ms", float64(time.Since(start).Microseconds()) / 1000,
+			"client_ip", c.ClientIP(),
+			"bytes", c.Writer.Size(),
+		}
+		if userID, ok := GetUserID(c); ok {
+			attrs = append(attrs, "user_id", userID)
+		}
+		if tenantID, ok := GetTenantID(c); ok && tenantID != uuid.Nil {
+			attrs = append(attrs, "tenant_id", tenantID)
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, "errors", c.Errors.String())
+		}
+
+		level := slog.LevelInfo
+		switch {
+		case strings.HasPrefix(c.FullPath(), "/health"):
+			// Probes run every few seconds; a failing one is answered with 503 by design
+			level = slog.LevelDebug
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		requestLogger.Log(c.Request.Context(), level, "request", attrs...)
+	}
+}
+
+// GetLogger returns the request's logger, or the default logger outside a request
+func GetLogger(c *gin.Context) *slog.Logger {
+	return logging.FromContext(c.Request.Context())
+}
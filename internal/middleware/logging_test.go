@@ -0,0 +1,54 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestLogger(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, nil))
+	userID := uuid.New()
+
+	router := gin.New()
+	router.Use(RequestID(), RequestLogger(logger))
+	router.GET("/items/:table", func(c *gin.Context) {
+		c.Set("user_id", userID)
+		GetLogger(c).Info("handler")
+		c.JSON(http.StatusNotFound, gin.H{"error": "Table not found"})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/items/products", nil)
+	req.Header.Set(RequestIDHeader, "req-123")
+	router.ServeHTTP(httptest.NewRecorder(), req)
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var handlerLine, accessLine map[string]interface{}
+	require.NoError(t, json.Unmarshal(lines[0], &handlerLine))
+	require.NoError(t, json.Unmarshal(lines[1], &accessLine))
+
+	assert.Equal(t, "req-123", handlerLine["request_id"])
+	assert.Equal(t, "request", accessLine["msg"])
+	assert.Equal(t, "WARN", accessLine["level"])
+	assert.Equal(t, "req-123", accessLine["request_id"])
+	assert.Equal(t, "GET", accessLine["method"])
+	assert.Equal(t, "/items/products", accessLine["path"])
+	assert.Equal(t, "/items/:table", accessLine["route"])
+	assert.Equal(t, float64(http.StatusNotFound), accessLine["status"])
+	assert.Equal(t, userID.String(), accessLine["user_id"])
+	assert.Contains(t, accessLine, "latency_ms")
+	assert.NotContains(t, accessLine, "tenant_id")
+}
@@ -0,0 +1,47 @@
+package middleware
+
+import (
+	"net/http"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+
+	"github.com/gin-gonic/gin"
+)
+
+// authTypePublic is the auth_type of requests made without credentials
+const authTypePublic = "public"
+
+// PublicAuthMiddleware authenticates like AuthMiddleware, except that reads sent without an
+// Authorization header to a tenant (see ResolveTenant) pass as public requests: they carry
+// the tenant but no user, and handlers answer them with the permissions of the tenant's
+// public role. Anything else without credentials is rejected as before.
+func PublicAuthMiddleware(cfg *config.Config, db *db.DB) gin.HandlerFunc {
+	auth := AuthMiddleware(cfg, db)
+
+	return func(c *gin.Context) {
+		if c.GetHeader("Authorization") != "" || (c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead) {
+			auth(c)
+			return
+		}
+		tenant, ok := GetRequestTenant(c)
+		if !ok {
+			auth(c)
+			return
+		}
+
+		c.Set("tenant_id", tenant.ID)
+		c.Set("tenant_slug", tenant.Slug)
+		c.Set("is_admin", false)
+		c.Set("is_super_admin", false)
+		c.Set("auth_type", authTypePublic)
+		setActingTenant(c, tenant.ID)
+		c.Next()
+	}
+}
+
+// IsPublicRequest reports whether the request was let through without credentials by
+// PublicAuthMiddleware
+func IsPublicRequest(c *gin.Context) bool {
+	return c.GetString("auth_type") == authTypePublic
+}
@@ -0,0 +1,46 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go-rbac-api/internal/config"
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicAuthMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tenant := sqlc.Tenant{ID: uuid.New(), Slug: "acme"}
+
+	serve := func(method string, withTenant bool) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.Use(func(c *gin.Context) {
+			if withTenant {
+				c.Set("request_tenant", tenant)
+			}
+		})
+		router.Use(PublicAuthMiddleware(&config.Config{}, nil))
+		router.Handle(method, "/items/:table", func(c *gin.Context) {
+			tenantID, _ := GetTenantID(c)
+			_, hasUser := GetUserID(c)
+			c.JSON(http.StatusOK, gin.H{"public": IsPublicRequest(c), "tenant_id": tenantID, "user": hasUser})
+		})
+
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(method, "/items/posts", nil))
+		return w
+	}
+
+	w := serve(http.MethodGet, true)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"public": true, "tenant_id": "`+tenant.ID.String()+`", "user": false}`, w.Body.String())
+
+	// Without a tenant, or for writes, credentials are still required
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodGet, false).Code)
+	assert.Equal(t, http.StatusUnauthorized, serve(http.MethodPost, true).Code)
+}
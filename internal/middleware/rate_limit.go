@@ -0,0 +1,49 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-rbac-api/internal/quota"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TenantRateLimit counts every request against the requests_per_minute limit of the
+// request's tenant and rejects it with 429 once the limit is reached. It must be used
+// after AuthMiddleware; requests without a tenant pass through. Limited tenants get
+// X-RateLimit-Limit, X-RateLimit-Remaining and X-RateLimit-Reset headers, and a
+// Retry-After with the 429.
+func TenantRateLimit(checker *quota.Checker) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenantID, ok := GetTenantID(c)
+		if !ok || tenantID == uuid.Nil {
+			c.Next()
+			return
+		}
+
+		allowed, limit, remaining, reset, err := checker.AllowRequest(c.Request.Context(), tenantID)
+		if err != nil {
+			// A tenant whose limits cannot be loaded is not blocked
+			GetLogger(c).Warn("failed to check tenant rate limit", "tenant_id", tenantID, "error", err)
+			c.Next()
+			return
+		}
+		if limit > 0 {
+			c.Header("X-RateLimit-Limit", strconv.Itoa(limit))
+			c.Header("X-RateLimit-Remaining", strconv.Itoa(remaining))
+			c.Header("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		}
+		if !allowed {
+			retryAfter := int(time.Until(reset).Seconds()) + 1
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.JSON(http.StatusTooManyRequests, gin.H{"error": "Tenant request limit reached", "limit": quota.LimitRequestsPerMinute})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
@@ -0,0 +1,228 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"os"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Access token signing algorithms (JWT_ALGORITHM)
+const (
+	AlgorithmHS256 = "HS256"
+	AlgorithmRS256 = "RS256"
+	AlgorithmEdDSA = "EdDSA"
+)
+
+// SigningKeys sign and verify access tokens. With HS256 they use JWT_SECRET; with RS256 or
+// EdDSA the first key of JWT_SIGNING_KEYS signs and every key verifies, so a key can be
+// rotated by putting a new one first and dropping the old one once its tokens have expired.
+// The public keys are published as a JWK set for other services.
+type SigningKeys struct {
+	method jwt.SigningMethod
+	secret []byte
+	keys   []signingKey // The first one signs
+}
+
+// signingKey is an asymmetric key pair, identified by the RFC 7638 thumbprint of its public key
+type signingKey struct {
+	id      string
+	private crypto.Signer
+	jwk     JWK
+}
+
+// JWK is a public key in JSON Web Key format
+type JWK struct {
+	KeyType   string `json:"kty"`
+	KeyID     string `json:"kid"`
+	Use       string `json:"use"`
+	Algorithm string `json:"alg"`
+	Curve     string `json:"crv,omitempty"` // OKP
+	X         string `json:"x,omitempty"`   // OKP
+	N         string `json:"n,omitempty"`   // RSA
+	E         string `json:"e,omitempty"`   // RSA
+}
+
+// JWKSet is the document served at /.well-known/jwks.json
+type JWKSet struct {
+	Keys []JWK `json:"keys"`
+}
+
+// LoadSigningKeys reads the keys of the configured algorithm. Without JWT_SIGNING_KEYS an
+// asymmetric algorithm gets a key generated at startup, which only suits development:
+// tokens then stop working on restart and are not accepted by other instances.
+func LoadSigningKeys(cfg *config.Config) (*SigningKeys, error) {
+	switch cfg.JWTAlgorithm {
+	case "", AlgorithmHS256:
+		return &SigningKeys{method: jwt.SigningMethodHS256, secret: []byte(cfg.JWTSecret)}, nil
+	case AlgorithmRS256, AlgorithmEdDSA:
+	default:
+		return nil, fmt.Errorf("unsupported JWT_ALGORITHM %q (use HS256, RS256 or EdDSA)", cfg.JWTAlgorithm)
+	}
+
+	keys := &SigningKeys{method: jwt.GetSigningMethod(cfg.JWTAlgorithm)}
+	for _, path := range cfg.JWTSigningKeys {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read signing key: %w", err)
+		}
+		private, err := parsePrivateKey(cfg.JWTAlgorithm, raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid signing key %s: %w", path, err)
+		}
+		if err := keys.add(private); err != nil {
+			return nil, err
+		}
+	}
+
+	if len(keys.keys) == 0 {
+		private, err := generatePrivateKey(cfg.JWTAlgorithm)
+		if err != nil {
+			return nil, fmt.Errorf("failed to generate signing key: %w", err)
+		}
+		if err := keys.add(private); err != nil {
+			return nil, err
+		}
+		slog.Warn("JWT_SIGNING_KEYS is not set; tokens are signed with a temporary key that changes on restart", "algorithm", cfg.JWTAlgorithm)
+	}
+	return keys, nil
+}
+
+// parsePrivateKey reads a PEM private key of the algorithm
+func parsePrivateKey(algorithm string, raw []byte) (crypto.Signer, error) {
+	if algorithm == AlgorithmRS256 {
+		return jwt.ParseRSAPrivateKeyFromPEM(raw)
+	}
+	key, err := jwt.ParseEdPrivateKeyFromPEM(raw)
+	if err != nil {
+		return nil, err
+	}
+	signer, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("not an Ed25519 key")
+	}
+	return signer, nil
+}
+
+// generatePrivateKey creates a key for the algorithm
+func generatePrivateKey(algorithm string) (crypto.Signer, error) {
+	if algorithm == AlgorithmRS256 {
+		return rsa.GenerateKey(rand.Reader, 2048)
+	}
+	_, private, err := ed25519.GenerateKey(rand.Reader)
+	return private, err
+}
+
+// add appends a key pair, identified by its thumbprint
+func (k *SigningKeys) add(private crypto.Signer) error {
+	jwk := JWK{Use: "sig", Algorithm: k.method.Alg()}
+	var thumbprint any
+	switch public := private.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.KeyType = "RSA"
+		jwk.E = base64.RawURLEncoding.EncodeToString(big.NewInt(int64(public.E)).Bytes())
+		jwk.N = base64.RawURLEncoding.EncodeToString(public.N.Bytes())
+		thumbprint = struct {
+			E   string `json:"e"`
+			Kty string `json:"kty"`
+			N   string `json:"n"`
+		}{jwk.E, jwk.KeyType, jwk.N}
+	case ed25519.PublicKey:
+		jwk.KeyType = "OKP"
+		jwk.Curve = "Ed25519"
+		jwk.X = base64.RawURLEncoding.EncodeToString(public)
+		thumbprint = struct {
+			Crv string `json:"crv"`
+			Kty string `json:"kty"`
+			X   string `json:"x"`
+		}{jwk.Curve, jwk.KeyType, jwk.X}
+	default:
+		return fmt.Errorf("unsupported key type %T", public)
+	}
+
+	// The thumbprint hashes the required members in lexicographic order (RFC 7638)
+	canonical, err := json.Marshal(thumbprint)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(canonical)
+	jwk.KeyID = base64.RawURLEncoding.EncodeToString(sum[:])
+
+	for _, existing := range k.keys {
+		if existing.id == jwk.KeyID {
+			return fmt.Errorf("signing key %s is listed twice", jwk.KeyID)
+		}
+	}
+	k.keys = append(k.keys, signingKey{id: jwk.KeyID, private: private, jwk: jwk})
+	return nil
+}
+
+// Algorithm returns the algorithm tokens are signed with
+func (k *SigningKeys) Algorithm() string {
+	return k.method.Alg()
+}
+
+// Sign creates a signed token of the claims. Asymmetric tokens name their key in the kid
+// header.
+func (k *SigningKeys) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.method, claims)
+	if k.method == jwt.SigningMethodHS256 {
+		return token.SignedString(k.secret)
+	}
+	token.Header["kid"] = k.keys[0].id
+	return token.SignedString(k.keys[0].private)
+}
+
+// Keyfunc returns the key that verifies a token, rejecting tokens of other algorithms
+func (k *SigningKeys) Keyfunc(token *jwt.Token) (interface{}, error) {
+	if token.Method.Alg() != k.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	if k.method == jwt.SigningMethodHS256 {
+		return k.secret, nil
+	}
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range k.keys {
+		if key.id == kid {
+			return key.private.Public(), nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// JWKS returns the public keys; it is empty for HS256, whose secret cannot be published
+func (k *SigningKeys) JWKS() JWKSet {
+	set := JWKSet{Keys: make([]JWK, 0, len(k.keys))}
+	for _, key := range k.keys {
+		set.Keys = append(set.Keys, key.jwk)
+	}
+	return set
+}
+
+// defaultSigningKeys sign every access token; nil falls back to HS256 with JWT_SECRET
+var defaultSigningKeys *SigningKeys
+
+// UseSigningKeys installs the keys access tokens are signed and verified with. Call it
+// once at startup.
+func UseSigningKeys(keys *SigningKeys) {
+	defaultSigningKeys = keys
+}
+
+// CurrentSigningKeys returns the installed keys, or HS256 keys of the configured secret
+func CurrentSigningKeys(cfg *config.Config) *SigningKeys {
+	if defaultSigningKeys != nil {
+		return defaultSigningKeys
+	}
+	return &SigningKeys{method: jwt.SigningMethodHS256, secret: []byte(cfg.JWTSecret)}
+}
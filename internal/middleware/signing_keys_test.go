@@ -0,0 +1,104 @@
+package middleware
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeKey stores a private key as a PKCS#8 PEM file
+func writeKey(t *testing.T, key crypto.Signer) string {
+	t.Helper()
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600))
+	return path
+}
+
+func TestSigningKeys(t *testing.T) {
+	_, oldKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	_, newKey, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	oldPath, newPath := writeKey(t, oldKey), writeKey(t, newKey)
+
+	before, err := LoadSigningKeys(&config.Config{JWTAlgorithm: AlgorithmEdDSA, JWTSigningKeys: []string{oldPath}})
+	require.NoError(t, err)
+	rotated, err := LoadSigningKeys(&config.Config{JWTAlgorithm: AlgorithmEdDSA, JWTSigningKeys: []string{newPath, oldPath}})
+	require.NoError(t, err)
+
+	oldToken, err := before.Sign(&Claims{Email: "old@example.com"})
+	require.NoError(t, err)
+	newToken, err := rotated.Sign(&Claims{Email: "new@example.com"})
+	require.NoError(t, err)
+
+	// Tokens of the old key stay valid after the rotation; the new key signs
+	for _, token := range []string{oldToken, newToken} {
+		claims := &Claims{}
+		_, err := jwt.ParseWithClaims(token, claims, rotated.Keyfunc)
+		assert.NoError(t, err)
+	}
+	_, err = jwt.ParseWithClaims(newToken, &Claims{}, before.Keyfunc)
+	assert.Error(t, err, "the new key is unknown before the rotation")
+
+	set := rotated.JWKS()
+	require.Len(t, set.Keys, 2)
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &Claims{})
+	require.NoError(t, err)
+	assert.Equal(t, set.Keys[0].KeyID, parsed.Header["kid"])
+	assert.Equal(t, "OKP", set.Keys[0].KeyType)
+	assert.Equal(t, "EdDSA", set.Keys[0].Algorithm)
+	assert.Equal(t, before.JWKS().Keys[0], set.Keys[1], "key IDs are thumbprints, independent of the order")
+
+	t.Run("Algorithm Mismatch", func(t *testing.T) {
+		hmac, err := LoadSigningKeys(&config.Config{JWTSecret: "secret"})
+		require.NoError(t, err)
+		assert.Empty(t, hmac.JWKS().Keys)
+		_, err = jwt.ParseWithClaims(newToken, &Claims{}, hmac.Keyfunc)
+		assert.Error(t, err)
+
+		hmacToken, err := hmac.Sign(&Claims{})
+		require.NoError(t, err)
+		_, err = jwt.ParseWithClaims(hmacToken, &Claims{}, rotated.Keyfunc)
+		assert.Error(t, err, "HS256 tokens are not accepted once the algorithm is asymmetric")
+	})
+
+	t.Run("RS256", func(t *testing.T) {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		keys, err := LoadSigningKeys(&config.Config{JWTAlgorithm: AlgorithmRS256, JWTSigningKeys: []string{writeKey(t, key)}})
+		require.NoError(t, err)
+
+		jwk := keys.JWKS().Keys[0]
+		assert.Equal(t, "RSA", jwk.KeyType)
+		assert.Equal(t, "AQAB", jwk.E)
+
+		token, err := keys.Sign(&Claims{})
+		require.NoError(t, err)
+		_, err = jwt.ParseWithClaims(token, &Claims{}, keys.Keyfunc)
+		assert.NoError(t, err)
+
+		_, err = LoadSigningKeys(&config.Config{JWTAlgorithm: AlgorithmRS256, JWTSigningKeys: []string{oldPath}})
+		assert.Error(t, err, "an Ed25519 key cannot sign RS256")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := LoadSigningKeys(&config.Config{JWTAlgorithm: "none"})
+		assert.Error(t, err)
+		_, err = LoadSigningKeys(&config.Config{JWTAlgorithm: AlgorithmEdDSA, JWTSigningKeys: []string{oldPath, oldPath}})
+		assert.Error(t, err)
+	})
+}
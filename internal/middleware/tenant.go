@@ -0,0 +1,231 @@
+package middleware
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// TenantHeader names the tenant of a request by slug or ID, overriding its host
+const TenantHeader = "X-Tenant"
+
+const (
+	tenantCacheTTL        = time.Minute // How long a host or slug keeps resolving to the same tenant
+	maxTenantCacheEntries = 10000       // When the resolver cache is full it starts over
+)
+
+// ResolveTenant determines the tenant a request is addressed to, so that each tenant can
+// serve the API on its own domain. In order of precedence the tenant is named by:
+//
+//   - the X-Tenant header, holding a tenant slug or ID
+//   - the request's host, when it is the domain of a tenant
+//   - the request's host, when it is <slug>.TENANT_BASE_DOMAIN
+//
+// The tenant is available through GetRequestTenant; login and signup use it when the body
+// names no tenant. AuthMiddleware rejects tokens of other tenants sent to a tenant's host,
+// while X-Tenant switches a token to any tenant its user is a member of. An unknown
+// X-Tenant is answered with 404 and a suspended or deleted tenant with 403; hosts that
+// match no tenant leave the request without one.
+func ResolveTenant(cfg *config.Config, db *db.DB) gin.HandlerFunc {
+	resolver := &tenantResolver{
+		db:         db,
+		baseDomain: strings.ToLower(strings.TrimPrefix(cfg.TenantBaseDomain, ".")),
+		entries:    make(map[string]tenantCacheEntry),
+	}
+
+	return func(c *gin.Context) {
+		ctx := c.Request.Context()
+		var tenant *sqlc.Tenant
+		var err error
+
+		if name := strings.TrimSpace(c.GetHeader(TenantHeader)); name != "" {
+			tenant, err = resolver.byName(ctx, name)
+			if err == nil && tenant == nil {
+				c.JSON(http.StatusNotFound, gin.H{"error": "Tenant not found"})
+				c.Abort()
+				return
+			}
+			c.Set("request_tenant_header", true)
+		} else {
+			tenant, err = resolver.byHost(ctx, c.Request.Host)
+		}
+		if err != nil {
+			GetLogger(c).Error("failed to resolve tenant", "error", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to resolve tenant"})
+			c.Abort()
+			return
+		}
+
+		if tenant != nil {
+			if !tenant.IsActive.Bool {
+				abortTenantInactive(c)
+				return
+			}
+			c.Set("request_tenant", *tenant)
+		}
+		c.Next()
+	}
+}
+
+// GetRequestTenant retrieves the tenant found by ResolveTenant
+func GetRequestTenant(c *gin.Context) (sqlc.Tenant, bool) {
+	value, exists := c.Get("request_tenant")
+	if !exists {
+		return sqlc.Tenant{}, false
+	}
+	tenant, ok := value.(sqlc.Tenant)
+	return tenant, ok
+}
+
+// checkRequestTenant rejects a token of one tenant sent to another tenant's domain.
+// Tokens without a tenant pass.
+func checkRequestTenant(c *gin.Context, tenantID uuid.UUID) error {
+	tenant, ok := GetRequestTenant(c)
+	if !ok || tenantID == uuid.Nil || tenant.ID == tenantID {
+		return nil
+	}
+	return errors.New("token was issued for another tenant")
+}
+
+// ErrNotTenantMember is returned when X-Tenant names a tenant the user is no member of
+var ErrNotTenantMember = errors.New("not a member of the requested tenant")
+
+// ErrImpersonationSwitch is returned when X-Tenant is sent with an impersonation token,
+// which only acts in the tenant it was issued for
+var ErrImpersonationSwitch = errors.New("impersonation tokens cannot switch tenants")
+
+// switchedTenant returns the tenant named by the X-Tenant header when it is not the
+// token's tenant
+func switchedTenant(c *gin.Context, tokenTenant uuid.UUID) (sqlc.Tenant, bool) {
+	tenant, ok := GetRequestTenant(c)
+	if !ok || !c.GetBool("request_tenant_header") || tenant.ID == tokenTenant {
+		return sqlc.Tenant{}, false
+	}
+	return tenant, true
+}
+
+// checkTenantMember returns ErrNotTenantMember unless the tenant is the user's home tenant
+// or the user has an active membership of it. Super admins may act in any tenant.
+func checkTenantMember(ctx context.Context, db *db.DB, user sqlc.User, tenantID uuid.UUID) error {
+	if user.IsSuperAdmin || (user.TenantID.Valid && user.TenantID.UUID == tenantID) {
+		return nil
+	}
+	membership, err := db.Queries.GetUserTenant(ctx, sqlc.GetUserTenantParams{UserID: user.ID, TenantID: tenantID})
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && !membership.IsActive.Bool) {
+		return ErrNotTenantMember
+	}
+	return err
+}
+
+// actingTenantKey is the context key of the tenant an authenticated request acts in
+type actingTenantKey struct{}
+
+// setActingTenant records the tenant the request acts in on its context, so data access
+// further down the call chain reads and writes that tenant
+func setActingTenant(c *gin.Context, tenantID uuid.UUID) {
+	if tenantID == uuid.Nil {
+		return
+	}
+	c.Request = c.Request.WithContext(context.WithValue(c.Request.Context(), actingTenantKey{}, tenantID))
+}
+
+// ActingTenant returns the tenant an authenticated request acts in: the tenant of its
+// token, the one it switched to with X-Tenant or the tenant of a public request. API key
+// requests have none and act in the user's home tenant.
+func ActingTenant(ctx context.Context) (uuid.UUID, bool) {
+	tenantID, ok := ctx.Value(actingTenantKey{}).(uuid.UUID)
+	return tenantID, ok
+}
+
+// tenantCacheEntry is a cached lookup; a nil tenant means no tenant matched
+type tenantCacheEntry struct {
+	tenant  *sqlc.Tenant
+	expires time.Time
+}
+
+// tenantResolver looks tenants up by slug, ID or host and caches the answers briefly, as
+// every request is resolved
+type tenantResolver struct {
+	db         *db.DB
+	baseDomain string
+
+	mu      sync.Mutex
+	entries map[string]tenantCacheEntry
+}
+
+// byName finds a tenant by ID or slug
+func (r *tenantResolver) byName(ctx context.Context, name string) (*sqlc.Tenant, error) {
+	if id, err := uuid.Parse(name); err == nil {
+		return r.lookup("id:"+id.String(), func() (sqlc.Tenant, error) {
+			return r.db.Queries.GetTenantByID(ctx, id)
+		})
+	}
+	return r.lookup("slug:"+name, func() (sqlc.Tenant, error) {
+		return r.db.Queries.GetTenantBySlug(ctx, name)
+	})
+}
+
+// byHost finds the tenant whose domain is host, or the tenant of a subdomain of the base
+// domain
+func (r *tenantResolver) byHost(ctx context.Context, host string) (*sqlc.Tenant, error) {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "" || net.ParseIP(host) != nil || host == "localhost" {
+		return nil, nil
+	}
+
+	tenant, err := r.lookup("domain:"+host, func() (sqlc.Tenant, error) {
+		return r.db.Queries.GetTenantByDomain(ctx, host)
+	})
+	if tenant != nil || err != nil || r.baseDomain == "" {
+		return tenant, err
+	}
+
+	slug, ok := strings.CutSuffix(host, "."+r.baseDomain)
+	if !ok || slug == "" || strings.Contains(slug, ".") {
+		return nil, nil
+	}
+	return r.byName(ctx, slug)
+}
+
+// lookup returns the cached answer for key or loads it with load
+func (r *tenantResolver) lookup(key string, load func() (sqlc.Tenant, error)) (*sqlc.Tenant, error) {
+	now := time.Now()
+	r.mu.Lock()
+	entry, ok := r.entries[key]
+	r.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.tenant, nil
+	}
+
+	var tenant *sqlc.Tenant
+	loaded, err := load()
+	switch {
+	case err == nil:
+		tenant = &loaded
+	case !errors.Is(err, sql.ErrNoRows):
+		return nil, err
+	}
+
+	r.mu.Lock()
+	if len(r.entries) >= maxTenantCacheEntries {
+		r.entries = make(map[string]tenantCacheEntry)
+	}
+	r.entries[key] = tenantCacheEntry{tenant: tenant, expires: now.Add(tenantCacheTTL)}
+	r.mu.Unlock()
+	return tenant, nil
+}
@@ -0,0 +1,105 @@
+package middleware
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTenantResolver_ByHost(t *testing.T) {
+	acme := &sqlc.Tenant{ID: uuid.New(), Slug: "acme"}
+	custom := &sqlc.Tenant{ID: uuid.New(), Slug: "custom"}
+	expires := time.Now().Add(time.Minute)
+
+	// Lookups are answered from the cache, so no database is needed
+	resolver := &tenantResolver{
+		baseDomain: "basin.test",
+		entries: map[string]tenantCacheEntry{
+			"domain:api.custom.test":  {tenant: custom, expires: expires},
+			"domain:acme.basin.test":  {expires: expires},
+			"slug:acme":               {tenant: acme, expires: expires},
+			"domain:other.basin.test": {expires: expires},
+			"slug:other":              {expires: expires},
+		},
+	}
+
+	tests := map[string]*sqlc.Tenant{
+		"api.custom.test":      custom,
+		"API.Custom.test:8443": custom,
+		"acme.basin.test":      acme,
+		"other.basin.test":     nil,
+		"localhost:8080":       nil,
+		"127.0.0.1":            nil,
+	}
+	for host, want := range tests {
+		got, err := resolver.byHost(context.Background(), host)
+		require.NoError(t, err, host)
+		assert.Equal(t, want, got, host)
+	}
+}
+
+func TestCheckRequestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tenantID := uuid.New()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	assert.NoError(t, checkRequestTenant(c, uuid.New()), "no tenant resolved")
+
+	c.Set("request_tenant", sqlc.Tenant{ID: tenantID})
+	assert.NoError(t, checkRequestTenant(c, tenantID))
+	assert.NoError(t, checkRequestTenant(c, uuid.Nil), "token without tenant")
+	assert.Error(t, checkRequestTenant(c, uuid.New()))
+}
+
+func TestSwitchedTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tokenTenant := uuid.New()
+	other := sqlc.Tenant{ID: uuid.New(), Slug: "other"}
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Set("request_tenant", other)
+	_, ok := switchedTenant(c, tokenTenant)
+	assert.False(t, ok, "a tenant's host does not switch tokens")
+
+	c.Set("request_tenant_header", true)
+	tenant, ok := switchedTenant(c, tokenTenant)
+	assert.True(t, ok)
+	assert.Equal(t, other, tenant)
+
+	_, ok = switchedTenant(c, other.ID)
+	assert.False(t, ok, "already the token's tenant")
+}
+
+func TestCheckTenantMember(t *testing.T) {
+	home := uuid.New()
+	user := sqlc.User{ID: uuid.New(), TenantID: uuid.NullUUID{UUID: home, Valid: true}}
+
+	// Neither needs a membership lookup, so no database is needed
+	assert.NoError(t, checkTenantMember(context.Background(), nil, user, home), "home tenant")
+	user.IsSuperAdmin = true
+	assert.NoError(t, checkTenantMember(context.Background(), nil, user, uuid.New()), "super admin")
+}
+
+func TestActingTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	tenantID := uuid.New()
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/items/products", nil)
+	setActingTenant(c, uuid.Nil)
+	_, ok := ActingTenant(c.Request.Context())
+	assert.False(t, ok, "no tenant")
+
+	setActingTenant(c, tenantID)
+	acting, ok := ActingTenant(c.Request.Context())
+	require.True(t, ok)
+	assert.Equal(t, tenantID, acting)
+}
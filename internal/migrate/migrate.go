@@ -0,0 +1,485 @@
+// Package migrate applies versioned SQL migrations and records them in the
+// schema_migrations table.
+//
+// Migrations live in one directory, named after their version:
+//
+//	011_widgets.sql       (or 011_widgets.up.sql) applied by Up
+//	011_widgets.down.sql  optional, applied by Down
+//
+// Each migration runs in its own transaction and is applied exactly once. The checksum of
+// every applied file is stored, and Up refuses to run when an applied file was edited
+// afterwards. A migration is marked dirty before it starts and clean once it commits, so a
+// process that dies half way leaves a dirty row behind; it must be inspected and cleared
+// with Force before migrations run again.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+
+	"go-rbac-api/internal/db"
+)
+
+// lockKey identifies the advisory lock held while migrating, so that several instances
+// starting at once do not apply the same migration twice
+const lockKey = 7_452_019_311
+
+var (
+	// ErrDirty is returned when a migration was interrupted and needs manual attention
+	ErrDirty = errors.New("database is dirty")
+	// ErrChecksum is returned when an applied migration file has been modified
+	ErrChecksum = errors.New("migration checksum mismatch")
+	// ErrNoDown is returned when rolling back a migration without a down file
+	ErrNoDown = errors.New("migration has no down file")
+)
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+?)(\.up|\.down)?\.sql$`)
+
+// Migration is one versioned schema change
+type Migration struct {
+	Version  int64
+	Name     string
+	Up       string
+	Down     string // Empty when the migration cannot be rolled back
+	Checksum string // SHA-256 of Up
+}
+
+// Status describes a migration and whether it has been applied
+type Status struct {
+	Migration
+	Applied   bool
+	AppliedAt time.Time
+	Dirty     bool
+	Modified  bool // The file changed after it was applied
+}
+
+// applied is a row of schema_migrations
+type applied struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	Dirty     bool
+	AppliedAt time.Time
+}
+
+// Load reads the migrations in the root of fsys, sorted by version. Files that do not
+// look like migrations are ignored.
+func Load(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	downs := make(map[int64]string)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("invalid migration version in %s", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", entry.Name(), err)
+		}
+
+		if match[3] == ".down" {
+			if _, exists := downs[version]; exists {
+				return nil, fmt.Errorf("duplicate down migration for version %d", version)
+			}
+			downs[version] = string(content)
+			continue
+		}
+
+		if existing, exists := byVersion[version]; exists {
+			return nil, fmt.Errorf("duplicate migration version %d (%s and %s)", version, existing.Name, match[2])
+		}
+		byVersion[version] = &Migration{
+			Version:  version,
+			Name:     match[2],
+			Up:       string(content),
+			Checksum: Checksum(string(content)),
+		}
+	}
+
+	for version, down := range downs {
+		m, exists := byVersion[version]
+		if !exists {
+			return nil, fmt.Errorf("down migration for version %d has no up migration", version)
+		}
+		m.Down = down
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+// Checksum returns the hex SHA-256 of a migration's SQL
+func Checksum(sql string) string {
+	sum := sha256.Sum256([]byte(sql))
+	return hex.EncodeToString(sum[:])
+}
+
+// FileName returns the up file name for a migration version, as used by the create command
+func FileName(version int64, name string) string {
+	return fmt.Sprintf("%03d_%s.sql", version, name)
+}
+
+// pending validates the recorded state against the migration files and returns the
+// migrations that still have to be applied, in order
+func pending(migrations []Migration, done map[int64]applied) ([]Migration, error) {
+	known := make(map[int64]Migration, len(migrations))
+	for _, m := range migrations {
+		known[m.Version] = m
+	}
+
+	for _, row := range done {
+		if row.Dirty {
+			return nil, fmt.Errorf("%w: migration %d (%s) did not finish; fix the schema by hand, then run migrate force %d", ErrDirty, row.Version, row.Name, row.Version)
+		}
+		if m, exists := known[row.Version]; exists && m.Checksum != row.Checksum {
+			return nil, fmt.Errorf("%w: %s was modified after it was applied", ErrChecksum, FileName(m.Version, m.Name))
+		}
+	}
+
+	var todo []Migration
+	for _, m := range migrations {
+		if _, exists := done[m.Version]; !exists {
+			todo = append(todo, m)
+		}
+	}
+	return todo, nil
+}
+
+// Migrator applies migrations to a database
+type Migrator struct {
+	db         *sql.DB
+	dialect    db.Dialect
+	migrations []Migration
+	logger     *slog.Logger
+}
+
+// New creates a Migrator applying the given migrations to database. The migrations must be
+// written for the database's dialect.
+func New(database *db.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: database.DB, dialect: database.Dialect(), migrations: migrations, logger: slog.Default()}
+}
+
+// Up applies every pending migration in order and returns how many were applied. It stops
+// at the first failure; the failed migration is rolled back and not recorded.
+func (m *Migrator) Up(ctx context.Context) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		todo, err := pending(m.migrations, done)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range todo {
+			if err := m.apply(ctx, conn, migration); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Down rolls back the last steps applied migrations, newest first, and returns how many
+// were rolled back
+func (m *Migrator) Down(ctx context.Context, steps int) (int, error) {
+	count := 0
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if _, err := pending(m.migrations, done); err != nil {
+			return err
+		}
+
+		known := make(map[int64]Migration, len(m.migrations))
+		for _, migration := range m.migrations {
+			known[migration.Version] = migration
+		}
+		versions := make([]int64, 0, len(done))
+		for version := range done {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+
+		for _, version := range versions {
+			if count == steps {
+				break
+			}
+			migration, exists := known[version]
+			if !exists {
+				return fmt.Errorf("migration %d is applied but its file is missing", version)
+			}
+			if err := m.revert(ctx, conn, migration); err != nil {
+				return err
+			}
+			count++
+		}
+		return nil
+	})
+	return count, err
+}
+
+// Status lists every known migration with its recorded state, followed by applied
+// versions whose files are missing
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var statuses []Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		done, err := m.applied(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		seen := make(map[int64]bool, len(m.migrations))
+		for _, migration := range m.migrations {
+			seen[migration.Version] = true
+			status := Status{Migration: migration}
+			if row, exists := done[migration.Version]; exists {
+				status.Applied = true
+				status.AppliedAt = row.AppliedAt
+				status.Dirty = row.Dirty
+				status.Modified = row.Checksum != migration.Checksum
+			}
+			statuses = append(statuses, status)
+		}
+		for version, row := range done {
+			if !seen[version] {
+				statuses = append(statuses, Status{
+					Migration: Migration{Version: row.Version, Name: row.Name, Checksum: row.Checksum},
+					Applied:   true,
+					AppliedAt: row.AppliedAt,
+					Dirty:     row.Dirty,
+				})
+			}
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i].Version < statuses[j].Version })
+		return nil
+	})
+	return statuses, err
+}
+
+// Pending returns the migrations Up would apply, failing like Up on dirty or modified
+// migrations. It does not take the migration lock, so it answers while another instance
+// is migrating, and it does not create schema_migrations.
+func (m *Migrator) Pending(ctx context.Context) ([]Migration, error) {
+	done, err := m.applied(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	return pending(m.migrations, done)
+}
+
+// Force records version as applied and clean with the current file's checksum, without
+// running any SQL. Use it after repairing a dirty migration by hand, or to accept an
+// intentional edit to an applied file.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	var migration *Migration
+	for i := range m.migrations {
+		if m.migrations[i].Version == version {
+			migration = &m.migrations[i]
+		}
+	}
+	if migration == nil {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		_, err := conn.ExecContext(ctx, `
+			INSERT INTO schema_migrations (version, name, checksum, dirty, applied_at)
+			VALUES ($1, $2, $3, false, NOW())
+			ON CONFLICT (version) DO UPDATE SET name = EXCLUDED.name, checksum = EXCLUDED.checksum, dirty = false
+		`, migration.Version, migration.Name, migration.Checksum)
+		if err != nil {
+			return fmt.Errorf("failed to force version %d: %w", version, err)
+		}
+		m.logger.Info("migration forced", "version", migration.Version, "name", migration.Name)
+		return nil
+	})
+}
+
+// withLock runs fn on a dedicated connection holding the migration lock, after making
+// sure schema_migrations exists
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get a database connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockKey); err != nil {
+		return fmt.Errorf("failed to acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockKey)
+
+	if err := m.ensureTable(ctx, conn); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+// ensureTable creates schema_migrations. Databases created before migrations were tracked
+// already contain the base schema, which is not idempotent, so version 1 is recorded as
+// applied for them; the later migrations are idempotent and simply run again once.
+func (m *Migrator) ensureTable(ctx context.Context, conn *sql.Conn) error {
+	_, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version BIGINT PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			checksum VARCHAR(64) NOT NULL,
+			dirty BOOLEAN NOT NULL DEFAULT false,
+			applied_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+		)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	if len(m.migrations) == 0 || m.migrations[0].Version != 1 {
+		return nil
+	}
+
+	var tracked, legacy bool
+	if err := conn.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM schema_migrations)").Scan(&tracked); err != nil {
+		return fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	if tracked {
+		return nil
+	}
+	legacy, err = m.dialect.TableExists(ctx, conn, "public", "users")
+	if err != nil {
+		return fmt.Errorf("failed to inspect existing schema: %w", err)
+	}
+	if !legacy {
+		return nil
+	}
+
+	base := m.migrations[0]
+	_, err = conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, name, checksum) VALUES ($1, $2, $3)
+	`, base.Version, base.Name, base.Checksum)
+	if err != nil {
+		return fmt.Errorf("failed to baseline existing schema: %w", err)
+	}
+	m.logger.Info("existing schema baselined", "version", base.Version, "name", base.Name)
+	return nil
+}
+
+// querier runs queries on a connection or the pool
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+}
+
+// applied loads schema_migrations keyed by version
+func (m *Migrator) applied(ctx context.Context, conn querier) (map[int64]applied, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, checksum, dirty, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	done := make(map[int64]applied)
+	for rows.Next() {
+		var row applied
+		if err := rows.Scan(&row.Version, &row.Name, &row.Checksum, &row.Dirty, &row.AppliedAt); err != nil {
+			return nil, fmt.Errorf("failed to read schema_migrations: %w", err)
+		}
+		done[row.Version] = row
+	}
+	return done, rows.Err()
+}
+
+// apply runs one up migration. The dirty marker is committed first so that a crash while
+// the migration runs remains visible; the migration itself and clearing the marker share
+// one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	_, err := conn.ExecContext(ctx, `
+		INSERT INTO schema_migrations (version, name, checksum, dirty) VALUES ($1, $2, $3, true)
+	`, migration.Version, migration.Name, migration.Checksum)
+	if err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", migration.Version, err)
+	}
+
+	start := time.Now()
+	err = m.inTx(ctx, conn, migration.Up, `
+		UPDATE schema_migrations SET dirty = false, applied_at = NOW() WHERE version = $1
+	`, migration.Version)
+	if err != nil {
+		// Nothing was changed, so the marker can go
+		conn.ExecContext(context.Background(), "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+		return fmt.Errorf("migration %s failed: %w", FileName(migration.Version, migration.Name), err)
+	}
+
+	m.logger.Info("migration applied", "version", migration.Version, "name", migration.Name, "duration_ms", time.Since(start).Milliseconds())
+	return nil
+}
+
+// revert runs one down migration and removes its record in the same transaction
+func (m *Migrator) revert(ctx context.Context, conn *sql.Conn, migration Migration) error {
+	if migration.Down == "" {
+		return fmt.Errorf("%w: %s", ErrNoDown, FileName(migration.Version, migration.Name))
+	}
+
+	_, err := conn.ExecContext(ctx, "UPDATE schema_migrations SET dirty = true WHERE version = $1", migration.Version)
+	if err != nil {
+		return fmt.Errorf("failed to mark migration %d: %w", migration.Version, err)
+	}
+
+	err = m.inTx(ctx, conn, migration.Down, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+	if err != nil {
+		conn.ExecContext(context.Background(), "UPDATE schema_migrations SET dirty = false WHERE version = $1", migration.Version)
+		return fmt.Errorf("rollback of %s failed: %w", FileName(migration.Version, migration.Name), err)
+	}
+
+	m.logger.Info("migration rolled back", "version", migration.Version, "name", migration.Name)
+	return nil
+}
+
+// inTx runs a migration script followed by a bookkeeping statement in one transaction
+func (m *Migrator) inTx(ctx context.Context, conn *sql.Conn, script, record string, version int64) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Scripts hold several statements, so they are sent without parameters
+	if _, err := tx.ExecContext(ctx, script); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, version); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,144 @@
+package migrate
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"002_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id INT);")},
+		"002_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+		"001_base.sql":         {Data: []byte("CREATE TABLE base (id INT);")},
+		"010_late.sql":         {Data: []byte("SELECT 1;")},
+		"README.md":            {Data: []byte("not a migration")},
+		"migrate.go.bak":       {Data: []byte("package main")},
+	}
+
+	migrations, err := Load(fsys)
+	require.NoError(t, err)
+	require.Len(t, migrations, 3)
+
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "base", migrations[0].Name)
+	assert.Empty(t, migrations[0].Down)
+
+	assert.Equal(t, int64(2), migrations[1].Version)
+	assert.Equal(t, "widgets", migrations[1].Name)
+	assert.Equal(t, "DROP TABLE widgets;", migrations[1].Down)
+	assert.Equal(t, Checksum("CREATE TABLE widgets (id INT);"), migrations[1].Checksum)
+
+	assert.Equal(t, int64(10), migrations[2].Version)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	_, err := Load(fstest.MapFS{
+		"001_a.sql": {Data: []byte("SELECT 1;")},
+		"001_b.sql": {Data: []byte("SELECT 2;")},
+	})
+	assert.ErrorContains(t, err, "duplicate migration version 1")
+
+	_, err = Load(fstest.MapFS{"003_orphan.down.sql": {Data: []byte("SELECT 1;")}})
+	assert.ErrorContains(t, err, "has no up migration")
+}
+
+func TestLoad_RepositoryMigrations(t *testing.T) {
+	migrations, err := Load(os.DirFS("../../migrations"))
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+
+	for i, m := range migrations {
+		assert.Equal(t, int64(i+1), m.Version, "migration versions must not have gaps")
+		if m.Version > 1 {
+			assert.NotEmpty(t, m.Down, "%s has no down file", FileName(m.Version, m.Name))
+		}
+	}
+}
+
+func TestLoad_MySQLMigrations(t *testing.T) {
+	// Applying them needs a MySQL server; loading them checks their names
+	migrations, err := Load(os.DirFS("../../migrations/mysql"))
+	require.NoError(t, err)
+	require.NotEmpty(t, migrations)
+	assert.Equal(t, "complete_schema", migrations[0].Name)
+}
+
+func TestMigrator_SQLite(t *testing.T) {
+	migrations, err := Load(os.DirFS("../../migrations/sqlite"))
+	require.NoError(t, err)
+	database, err := db.OpenSQLite(db.PoolConfig{}, filepath.Join(t.TempDir(), "basin.db"))
+	require.NoError(t, err)
+	defer database.Close()
+	ctx := context.Background()
+
+	migrator := New(database, migrations)
+	applied, err := migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, len(migrations), applied)
+
+	applied, err = migrator.Up(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, applied)
+	statuses, err := migrator.Status(ctx)
+	require.NoError(t, err)
+	for _, status := range statuses {
+		assert.True(t, status.Applied && !status.Dirty && !status.Modified, FileName(status.Version, status.Name))
+	}
+
+	// The generated queries run against the schema
+	tenant, err := database.Queries.GetTenantBySlug(ctx, "main")
+	require.NoError(t, err)
+	role, err := database.Queries.GetRoleByNameAndTenant(ctx, sqlc.GetRoleByNameAndTenantParams{
+		Name:     "admin",
+		TenantID: uuid.NullUUID{UUID: tenant.ID, Valid: true},
+	})
+	require.NoError(t, err)
+	user, err := database.Queries.GetUserByEmail(ctx, "admin@example.com")
+	require.NoError(t, err)
+	assert.True(t, user.MustChangePassword)
+	assert.Equal(t, tenant.ID, user.TenantID.UUID)
+
+	roles, err := database.Queries.GetUserRoles(ctx, user.ID)
+	require.NoError(t, err)
+	require.Len(t, roles, 1)
+	assert.Equal(t, role.ID, roles[0].ID)
+}
+
+func TestPending(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "base", Checksum: Checksum("a")},
+		{Version: 2, Name: "widgets", Checksum: Checksum("b")},
+		{Version: 3, Name: "gadgets", Checksum: Checksum("c")},
+	}
+
+	todo, err := pending(migrations, map[int64]applied{
+		1: {Version: 1, Name: "base", Checksum: Checksum("a")},
+	})
+	require.NoError(t, err)
+	require.Len(t, todo, 2)
+	assert.Equal(t, int64(2), todo[0].Version)
+	assert.Equal(t, int64(3), todo[1].Version)
+
+	_, err = pending(migrations, map[int64]applied{
+		1: {Version: 1, Name: "base", Checksum: Checksum("edited")},
+	})
+	assert.True(t, errors.Is(err, ErrChecksum))
+
+	_, err = pending(migrations, map[int64]applied{
+		1: {Version: 1, Name: "base", Checksum: Checksum("a")},
+		2: {Version: 2, Name: "widgets", Checksum: Checksum("b"), Dirty: true},
+	})
+	assert.True(t, errors.Is(err, ErrDirty))
+	assert.ErrorContains(t, err, "migrate force 2")
+}
@@ -0,0 +1,94 @@
+package models
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"go-rbac-api/internal/config"
+)
+
+// PasswordPolicy describes the strength requirements every new password must meet.
+// The same policy is enforced for signup, user creation through the items API and seeding.
+type PasswordPolicy struct {
+	MinLength     int
+	RequireUpper  bool
+	RequireLower  bool
+	RequireDigit  bool
+	RequireSymbol bool
+}
+
+// bcryptMaxLength is the number of bytes bcrypt actually hashes; longer passwords are rejected
+const bcryptMaxLength = 72
+
+// NewPasswordPolicy builds the password policy from configuration
+func NewPasswordPolicy(cfg *config.Config) PasswordPolicy {
+	return PasswordPolicy{
+		MinLength:     cfg.PasswordMinLength,
+		RequireUpper:  cfg.PasswordRequireUpper,
+		RequireLower:  cfg.PasswordRequireLower,
+		RequireDigit:  cfg.PasswordRequireDigit,
+		RequireSymbol: cfg.PasswordRequireSymbol,
+	}
+}
+
+// Validate checks a password against the policy and reports every unmet requirement
+func (p PasswordPolicy) Validate(password string) error {
+	var problems []string
+
+	if len([]rune(password)) < p.MinLength {
+		problems = append(problems, fmt.Sprintf("at least %d characters", p.MinLength))
+	}
+	if len(password) > bcryptMaxLength {
+		problems = append(problems, fmt.Sprintf("at most %d bytes", bcryptMaxLength))
+	}
+
+	var hasUpper, hasLower, hasDigit, hasSymbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			hasUpper = true
+		case unicode.IsLower(r):
+			hasLower = true
+		case unicode.IsDigit(r):
+			hasDigit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r):
+			hasSymbol = true
+		}
+	}
+
+	if p.RequireUpper && !hasUpper {
+		problems = append(problems, "an uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		problems = append(problems, "a lowercase letter")
+	}
+	if p.RequireDigit && !hasDigit {
+		problems = append(problems, "a digit")
+	}
+	if p.RequireSymbol && !hasSymbol {
+		problems = append(problems, "a symbol")
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("password must contain %s", strings.Join(problems, ", "))
+	}
+	return nil
+}
+
+// Generate returns a random password that meets the policy
+func (p PasswordPolicy) Generate() (string, error) {
+	length := p.MinLength
+	if length < 20 {
+		length = 20
+	}
+
+	buf := make([]byte, length)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	// One character of every class keeps the password valid under any policy
+	return base64.RawURLEncoding.EncodeToString(buf)[:length-4] + "aZ7!", nil
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordPolicy_Validate(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 10, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	tests := []struct {
+		name     string
+		policy   PasswordPolicy
+		password string
+		wantErr  string
+	}{
+		{"length only ok", PasswordPolicy{MinLength: 8}, "password", ""},
+		{"too short", PasswordPolicy{MinLength: 8}, "short", "at least 8 characters"},
+		{"strict ok", strict, "Str0ng!Passw0rd", ""},
+		{"missing upper", strict, "str0ng!passw0rd", "an uppercase letter"},
+		{"missing digit and symbol", strict, "StrongPassword", "a digit, a symbol"},
+		{"too long for bcrypt", PasswordPolicy{MinLength: 8}, strings.Repeat("a", 73), "at most 72 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.policy.Validate(tt.password)
+			if tt.wantErr == "" {
+				assert.NoError(t, err)
+				return
+			}
+			if assert.Error(t, err) {
+				assert.Contains(t, err.Error(), tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	hash, err := HashPassword("correct horse")
+	assert.NoError(t, err)
+	assert.NotEqual(t, "correct horse", hash)
+	assert.True(t, CheckPassword("correct horse", hash))
+	assert.False(t, CheckPassword("wrong", hash))
+}
+
+func TestPasswordPolicy_Generate(t *testing.T) {
+	strict := PasswordPolicy{MinLength: 32, RequireUpper: true, RequireLower: true, RequireDigit: true, RequireSymbol: true}
+
+	first, err := strict.Generate()
+	assert.NoError(t, err)
+	assert.Len(t, first, 32)
+	assert.NoError(t, strict.Validate(first))
+
+	second, err := strict.Generate()
+	assert.NoError(t, err)
+	assert.NotEqual(t, first, second)
+}
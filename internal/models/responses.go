@@ -6,8 +6,9 @@ import "time"
 
 // HealthResponse represents the health check response
 type HealthResponse struct {
-	Status string    `json:"status" example:"ok"`
-	Time   time.Time `json:"time" example:"2024-01-01T00:00:00Z"`
+	Status string                 `json:"status" example:"ok"` // ok, degraded or down
+	Time   time.Time              `json:"time" example:"2024-01-01T00:00:00Z"`
+	Checks map[string]interface{} `json:"checks,omitempty"` // database, pool, replicas, migrations and schema_cache
 }
 
 // APIInfoResponse represents the root endpoint response
@@ -16,7 +17,6 @@ type APIInfoResponse struct {
 	Version    string                 `json:"version" example:"1.0.0"`
 	Endpoints  map[string]interface{} `json:"endpoints"`
 	SampleData []string               `json:"sample_tables" example:"customers,blog_posts,collections,fields"`
-	AdminInfo  map[string]string      `json:"default_admin"`
 }
 
 // ItemsListResponse represents a paginated list of items
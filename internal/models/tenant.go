@@ -3,23 +3,39 @@ package models
 import (
 	"time"
 
+	"go-rbac-api/internal/mail"
+	"go-rbac-api/internal/quota"
+
 	"github.com/google/uuid"
 )
 
+// Tenant statuses
+const (
+	TenantStatusActive    = "active"
+	TenantStatusSuspended = "suspended"
+	TenantStatusDeleted   = "deleted" // Restorable until purge_at
+)
+
 type Tenant struct {
-	ID        uuid.UUID `json:"id"`
-	Name      string    `json:"name"`
-	Slug      string    `json:"slug"`
-	Domain    string    `json:"domain,omitempty"`
-	IsActive  bool      `json:"is_active"`
-	CreatedAt time.Time `json:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"`
+	ID          uuid.UUID  `json:"id"`
+	Name        string     `json:"name"`
+	Slug        string     `json:"slug"`
+	Domain      string     `json:"domain,omitempty"`
+	IsActive    bool       `json:"is_active"`
+	Status      string     `json:"status"`
+	SuspendedAt *time.Time `json:"suspended_at,omitempty"`
+	DeletedAt   *time.Time `json:"deleted_at,omitempty"`
+	PurgeAt     *time.Time `json:"purge_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type CreateTenantRequest struct {
 	Name   string `json:"name" binding:"required"`
 	Slug   string `json:"slug" binding:"required"`
 	Domain string `json:"domain,omitempty"`
+	// Collection template to start from: commerce (default), blog, crm, inventory or none
+	Template string `json:"template,omitempty"`
 }
 
 type UpdateTenantRequest struct {
@@ -27,6 +43,12 @@ type UpdateTenantRequest struct {
 	Slug     *string `json:"slug,omitempty"`
 	Domain   *string `json:"domain,omitempty"`
 	IsActive *bool   `json:"is_active,omitempty"`
+	// Sender of the tenant's emails; replaces the stored mail settings
+	Mail *mail.TenantSettings `json:"mail,omitempty"`
+	// Require two-factor authentication of the tenant's users
+	RequireTwoFactor *bool `json:"require_2fa,omitempty"`
+	// Usage limits of the tenant; replaces the stored limits, omitted ones use the defaults
+	Limits *quota.Overrides `json:"limits,omitempty"`
 }
 
 type TenantResponse struct {
@@ -34,8 +56,77 @@ type TenantResponse struct {
 	Tenant  Tenant `json:"tenant"`
 }
 
+// TenantUsageResponse compares what a tenant consumes with its limits (0 is unlimited)
+type TenantUsageResponse struct {
+	TenantID uuid.UUID    `json:"tenant_id"`
+	Limits   quota.Limits `json:"limits"`
+	Usage    quota.Usage  `json:"usage"`
+}
+
+// TenantImportResponse describes a tenant created from an export archive
+type TenantImportResponse struct {
+	Message       string         `json:"message"`
+	Tenant        Tenant         `json:"tenant"`
+	SchemaChanges int            `json:"schema_changes"`
+	Items         map[string]int `json:"items"` // Imported items per collection
+}
+
+// TenantBackup is a backup of a tenant's schema and data in asset storage
+type TenantBackup struct {
+	ID          uuid.UUID  `json:"id"`
+	TenantID    uuid.UUID  `json:"tenant_id"`
+	Trigger     string     `json:"trigger"` // manual or schedule
+	Status      string     `json:"status"`  // pending, completed or failed
+	Size        int64      `json:"size"`
+	Checksum    string     `json:"checksum,omitempty"` // SHA-256 of the archive
+	Collections int        `json:"collections"`
+	Items       int        `json:"items"`
+	Error       string     `json:"error,omitempty"`
+	CreatedBy   *uuid.UUID `json:"created_by,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	CompletedAt *time.Time `json:"completed_at,omitempty"`
+}
+
+// TenantBackupSchedule is how often a tenant is backed up
+type TenantBackupSchedule struct {
+	Interval  string    `json:"interval"`
+	Retain    int       `json:"retain"` // Scheduled backups kept
+	NextRunAt time.Time `json:"next_run_at"`
+}
+
+// TenantBackupScheduleRequest sets the backup schedule of a tenant
+type TenantBackupScheduleRequest struct {
+	Interval string `json:"interval" binding:"required"` // A duration such as "24h", at least 1h
+	Retain   int    `json:"retain,omitempty"`            // Scheduled backups kept (default 7)
+}
+
+// TenantBackupRestoreRequest names what a restore brings back
+type TenantBackupRestoreRequest struct {
+	Collections []string `json:"collections,omitempty"` // Every collection of the backup when empty
+}
+
+// TenantBackupRestoreResponse describes a restored backup
+type TenantBackupRestoreResponse struct {
+	Message       string         `json:"message"`
+	Backup        TenantBackup   `json:"backup"`
+	SchemaChanges int            `json:"schema_changes"`
+	Items         map[string]int `json:"items"` // Restored items per collection
+}
+
+// PointInTimeRestoreResponse describes the items of a collection restored to a point in time
+type PointInTimeRestoreResponse struct {
+	Message   string       `json:"message"`
+	At        time.Time    `json:"at"`
+	Backup    TenantBackup `json:"backup"`    // The backup the restore started from
+	Revisions int          `json:"revisions"` // Changes replayed on top of the backup
+	Created   int          `json:"created"`
+	Updated   int          `json:"updated"`
+	Deleted   int          `json:"deleted"`
+}
+
 type DeleteTenantResponse struct {
-	Message string `json:"message"`
+	Message string     `json:"message"`
+	PurgeAt *time.Time `json:"purge_at,omitempty"` // When a soft-deleted tenant is removed for good
 }
 
 // User-Tenant relationship
@@ -29,6 +29,73 @@ type LoginResponse struct {
 	User       User      `json:"user"`
 	TenantID   uuid.UUID `json:"tenant_id,omitempty"`
 	TenantSlug string    `json:"tenant_slug,omitempty"`
+
+	// PasswordChangeRequired means the token only works for POST /auth/change-password
+	PasswordChangeRequired bool `json:"password_change_required,omitempty"`
+	// TwoFactorSetupRequired means the tenant requires 2FA and the token only works for
+	// enabling it
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
+}
+
+// TwoFactorChallengeResponse is returned by POST /auth/login instead of a token when the
+// user has 2FA enabled. The challenge is completed with POST /auth/2fa/verify.
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired bool      `json:"two_factor_required"`
+	ChallengeToken    string    `json:"challenge_token"`
+	ExpiresAt         time.Time `json:"expires_at"`
+}
+
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" binding:"required"`
+	Code           string `json:"code"`          // Code from the authenticator app
+	RecoveryCode   string `json:"recovery_code"` // Or one of the recovery codes
+}
+
+type TwoFactorEnableResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"` // PNG data URL of OTPAuthURL
+}
+
+type TwoFactorConfirmRequest struct {
+	Code string `json:"code" binding:"required"`
+}
+
+type TwoFactorConfirmResponse struct {
+	Message       string   `json:"message"`
+	RecoveryCodes []string `json:"recovery_codes"` // Shown once; each works once
+	// Token replaces a token that was limited to enabling 2FA
+	Token string `json:"token,omitempty"`
+}
+
+type TwoFactorDisableRequest struct {
+	Password string `json:"password" binding:"required"`
+	Code     string `json:"code" binding:"required"` // TOTP or recovery code
+}
+
+type TwoFactorStatusResponse struct {
+	Enabled           bool  `json:"enabled"`
+	RecoveryCodesLeft int64 `json:"recovery_codes_left"`
+	RequiredByTenant  bool  `json:"required_by_tenant"`
+}
+
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"current_password" binding:"required"`
+	NewPassword     string `json:"new_password" binding:"required"`
+}
+
+type ChangePasswordResponse struct {
+	Message string `json:"message"`
+	Token   string `json:"token"` // Replaces the token used for the request
+}
+
+type PasswordResetRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+type ResetPasswordRequest struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required"`
 }
 
 type SignUpRequest struct {
@@ -63,6 +130,58 @@ type SwitchTenantResponse struct {
 	TenantID   uuid.UUID `json:"tenant_id"`
 	TenantSlug string    `json:"tenant_slug"`
 	Message    string    `json:"message"`
+
+	// TwoFactorSetupRequired means the new tenant requires 2FA and the token only works
+	// for enabling it
+	TwoFactorSetupRequired bool `json:"two_factor_setup_required,omitempty"`
+}
+
+// Session is a login session of the current user, listed by GET /auth/sessions
+type Session struct {
+	ID         uuid.UUID  `json:"id"`
+	TenantID   *uuid.UUID `json:"tenant_id,omitempty"`
+	UserAgent  string     `json:"user_agent,omitempty"`
+	IP         string     `json:"ip,omitempty"`
+	Current    bool       `json:"current"` // The session of the request's token
+	CreatedAt  time.Time  `json:"created_at"`
+	LastSeenAt time.Time  `json:"last_seen_at"`
+	ExpiresAt  time.Time  `json:"expires_at"`
+}
+
+// ImpersonationResponse carries the token of POST /auth/impersonate/:user_id
+type ImpersonationResponse struct {
+	Token     string     `json:"token"`
+	UserID    uuid.UUID  `json:"user_id"`
+	Email     string     `json:"email"`
+	TenantID  *uuid.UUID `json:"tenant_id,omitempty"`
+	SessionID uuid.UUID  `json:"session_id"`
+	ExpiresAt time.Time  `json:"expires_at"`
+}
+
+// ServiceAccount is a machine account of a tenant. It has no password and authenticates
+// only with its API keys.
+type ServiceAccount struct {
+	ID        uuid.UUID           `json:"id"`
+	Name      string              `json:"name"`
+	Email     string              `json:"email"` // Generated; it cannot receive mail or sign in
+	IsActive  bool                `json:"is_active"`
+	Roles     []string            `json:"roles"`
+	Keys      []ServiceAccountKey `json:"keys"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// ServiceAccountKey is an API key of a service account, without its secret
+type ServiceAccountKey struct {
+	ID            uuid.UUID  `json:"id"`
+	Name          string     `json:"name"`
+	IsActive      bool       `json:"is_active"`
+	Scopes        []string   `json:"scopes"`
+	ExpiresAt     *time.Time `json:"expires_at"`
+	LastUsedAt    *time.Time `json:"last_used_at"`
+	RotatedAt     *time.Time `json:"rotated_at"`
+	RotationDueAt *time.Time `json:"rotation_due_at"` // Nil when SERVICE_ACCOUNT_KEY_ROTATION is 0
+	CreatedAt     time.Time  `json:"created_at"`
 }
 
 func HashPassword(password string) (string, error) {
@@ -0,0 +1,293 @@
+package oauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// ProviderExternal is the provider of identities from tokens of the external issuer
+const ProviderExternal = "external"
+
+// jwksRefreshInterval limits how often an unknown kid makes the key set be fetched again
+const jwksRefreshInterval = time.Minute
+
+// externalAlgorithms are the signing algorithms accepted from the external issuer; HMAC
+// is not, as its key would have to be shared
+var externalAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "EdDSA"}
+
+// ExternalIssuer verifies access tokens minted by a trusted identity provider such as
+// Auth0, Clerk or Keycloak, against the public keys it publishes as a JWK set
+type ExternalIssuer struct {
+	Issuer   string
+	JWKSURL  string // Discovered from the issuer's OpenID configuration when empty
+	Audience string // Required aud claim
+
+	client    *http.Client
+	mu        sync.Mutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	fetching  chan struct{} // Closed when the running key set fetch is done; nil when none runs
+}
+
+// NewExternalIssuer returns the issuer configured in cfg, or nil when none is. The audience
+// is required: an issuer signs the tokens of every application it serves, and only the aud
+// claim tells those meant for Basin apart.
+func NewExternalIssuer(cfg *config.Config) (*ExternalIssuer, error) {
+	if cfg.ExternalJWTIssuer == "" {
+		return nil, nil
+	}
+	if cfg.ExternalJWTAudience == "" {
+		return nil, errors.New("EXTERNAL_JWT_AUDIENCE is required with EXTERNAL_JWT_ISSUER")
+	}
+	return &ExternalIssuer{
+		Issuer:   cfg.ExternalJWTIssuer,
+		JWKSURL:  cfg.ExternalJWTJWKSURL,
+		Audience: cfg.ExternalJWTAudience,
+		client:   &http.Client{Timeout: 15 * time.Second},
+	}, nil
+}
+
+// Issues reports whether a token claims to come from the issuer. The token is not
+// verified; Verify does that.
+func (e *ExternalIssuer) Issues(token string) bool {
+	var claims jwt.RegisteredClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token, &claims); err != nil {
+		return false
+	}
+	return claims.Issuer == e.Issuer
+}
+
+// Verify checks the signature, issuer, audience and lifetime of a token and returns the
+// identity it carries and when it expires
+func (e *ExternalIssuer) Verify(ctx context.Context, token string) (Identity, time.Time, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(externalAlgorithms),
+		jwt.WithIssuer(e.Issuer),
+		jwt.WithAudience(e.Audience),
+		jwt.WithExpirationRequired(),
+	}
+
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		return e.key(ctx, kid)
+	}, options...)
+	if err != nil {
+		return Identity{}, time.Time{}, err
+	}
+
+	expiresAt, err := claims.GetExpirationTime()
+	if err != nil {
+		return Identity{}, time.Time{}, err
+	}
+	identity, err := externalIdentity(claims)
+	return identity, expiresAt.Time, err
+}
+
+// externalIdentity reads the standard OpenID Connect claims of a token
+func externalIdentity(claims jwt.MapClaims) (Identity, error) {
+	subject, _ := claims["sub"].(string)
+	if subject == "" {
+		return Identity{}, errors.New("token has no subject")
+	}
+	identity := Identity{Provider: ProviderExternal, Subject: subject, Claims: claims}
+	identity.Email, _ = claims["email"].(string)
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	identity.FirstName, _ = claims["given_name"].(string)
+	identity.LastName, _ = claims["family_name"].(string)
+	if name, _ := claims["name"].(string); identity.FirstName == "" && name != "" {
+		identity.FirstName, identity.LastName, _ = strings.Cut(name, " ")
+	}
+	return identity, nil
+}
+
+// key returns the public key of a kid, fetching the key set again when the kid is unknown
+// so that keys rotated in at the issuer are picked up. The fetch runs without the lock;
+// requests arriving meanwhile wait for it rather than fetching too.
+func (e *ExternalIssuer) key(ctx context.Context, kid string) (crypto.PublicKey, error) {
+	e.mu.Lock()
+	if key, ok := e.lookup(kid); ok {
+		e.mu.Unlock()
+		return key, nil
+	}
+	if fetching := e.fetching; fetching != nil {
+		e.mu.Unlock()
+		select {
+		case <-fetching:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		return e.cachedKey(kid)
+	}
+	if time.Since(e.fetchedAt) < jwksRefreshInterval {
+		e.mu.Unlock()
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	fetching := make(chan struct{})
+	e.fetching, e.fetchedAt = fetching, time.Now()
+	jwksURL := e.JWKSURL
+	e.mu.Unlock()
+
+	keys, jwksURL, err := e.fetchKeys(ctx, jwksURL)
+
+	e.mu.Lock()
+	if err == nil {
+		e.keys, e.JWKSURL = keys, jwksURL
+	}
+	e.fetching = nil
+	close(fetching)
+	e.mu.Unlock()
+
+	if err != nil {
+		return nil, err
+	}
+	return e.cachedKey(kid)
+}
+
+// cachedKey returns the public key of a kid from the key set fetched last
+func (e *ExternalIssuer) cachedKey(kid string) (crypto.PublicKey, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if key, ok := e.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup finds the key of a kid in the key set. Issuers with a single key may leave kid
+// out. The caller holds e.mu.
+func (e *ExternalIssuer) lookup(kid string) (crypto.PublicKey, bool) {
+	if key, ok := e.keys[kid]; ok {
+		return key, true
+	}
+	if kid == "" && len(e.keys) == 1 {
+		for _, key := range e.keys {
+			return key, true
+		}
+	}
+	return nil, false
+}
+
+// fetchKeys loads the issuer's key set from jwksURL, discovering the URL first when it is
+// empty, and returns the keys and the URL. Keys of unsupported types are skipped.
+func (e *ExternalIssuer) fetchKeys(ctx context.Context, jwksURL string) (map[string]crypto.PublicKey, string, error) {
+	provider := &Provider{Name: ProviderExternal, client: e.client}
+
+	if jwksURL == "" {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(e.Issuer, "/")+"/.well-known/openid-configuration", nil)
+		if err != nil {
+			return nil, "", err
+		}
+		var doc struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := provider.getJSON(req, &doc); err != nil {
+			return nil, "", fmt.Errorf("external issuer discovery: %w", err)
+		}
+		if doc.JWKSURI == "" {
+			return nil, "", errors.New("external issuer discovery: missing jwks_uri")
+		}
+		jwksURL = doc.JWKSURI
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, jwksURL, nil)
+	if err != nil {
+		return nil, "", err
+	}
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	if err := provider.getJSON(req, &set); err != nil {
+		return nil, "", fmt.Errorf("external issuer keys: %w", err)
+	}
+
+	keys := make(map[string]crypto.PublicKey, len(set.Keys))
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		if key, err := jwk.publicKey(); err == nil {
+			keys[jwk.KeyID] = key
+		}
+	}
+	return keys, jwksURL, nil
+}
+
+// jsonWebKey is a public key of a JWK set (RFC 7517)
+type jsonWebKey struct {
+	KeyType string `json:"kty"`
+	KeyID   string `json:"kid"`
+	Use     string `json:"use"`
+	Curve   string `json:"crv"`
+	N       string `json:"n"`
+	E       string `json:"e"`
+	X       string `json:"x"`
+	Y       string `json:"y"`
+}
+
+// publicKey decodes an RSA, EC (P-256, P-384) or Ed25519 key
+func (k jsonWebKey) publicKey() (crypto.PublicKey, error) {
+	decode := base64.RawURLEncoding.DecodeString
+	switch k.KeyType {
+	case "RSA":
+		n, err := decode(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decode(k.E)
+		if err != nil {
+			return nil, err
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Curve {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := decode(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Curve != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Curve)
+		}
+		x, err := decode(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid Ed25519 key")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.KeyType)
+}
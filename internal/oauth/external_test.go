@@ -0,0 +1,174 @@
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newJWKSServer fakes an issuer publishing the keys in *keys, read on every request
+func newJWKSServer(t *testing.T, keys *map[string]*rsa.PrivateKey) *httptest.Server {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{"issuer": server.URL, "jwks_uri": server.URL + "/jwks"})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		var set []map[string]string
+		for kid, key := range *keys {
+			set = append(set, map[string]string{
+				"kty": "RSA",
+				"kid": kid,
+				"use": "sig",
+				"n":   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"keys": set})
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestExternalIssuer(t *testing.T) {
+	first, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys := map[string]*rsa.PrivateKey{"first": first}
+	server := newJWKSServer(t, &keys)
+
+	issuer, err := NewExternalIssuer(&config.Config{ExternalJWTIssuer: server.URL, ExternalJWTAudience: "basin"})
+	require.NoError(t, err)
+	require.NotNil(t, issuer)
+	none, err := NewExternalIssuer(&config.Config{})
+	assert.NoError(t, err)
+	assert.Nil(t, none)
+	_, err = NewExternalIssuer(&config.Config{ExternalJWTIssuer: server.URL})
+	assert.Error(t, err, "tokens the issuer minted for other applications must not be accepted")
+
+	sign := func(kid string, key *rsa.PrivateKey, claims jwt.MapClaims) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+		token.Header["kid"] = kid
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+	expiresAt := time.Now().Add(time.Hour).Truncate(time.Second)
+	claims := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"iss":            server.URL,
+			"aud":            "basin",
+			"sub":            "auth0|42",
+			"exp":            expiresAt.Unix(),
+			"email":          "ada@example.com",
+			"email_verified": true,
+			"name":           "Ada Lovelace",
+			"roles":          []string{"editors"},
+		}
+	}
+
+	token := sign("first", first, claims())
+	assert.True(t, issuer.Issues(token))
+	identity, exp, err := issuer.Verify(context.Background(), token)
+	require.NoError(t, err)
+	assert.Equal(t, ProviderExternal, identity.Provider)
+	assert.Equal(t, "auth0|42", identity.Subject)
+	assert.Equal(t, "ada@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "Ada", identity.FirstName)
+	assert.Equal(t, "Lovelace", identity.LastName)
+	assert.True(t, exp.Equal(expiresAt))
+
+	mapping, err := ParseRoleMapping("roles", []string{"editors=editor"}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"editor"}, mapping.MappedRoles(identity))
+
+	t.Run("Rejected", func(t *testing.T) {
+		other := claims()
+		other["aud"] = "another-api"
+		_, _, err := issuer.Verify(context.Background(), sign("first", first, other))
+		assert.Error(t, err, "wrong audience")
+
+		other = claims()
+		delete(other, "exp")
+		_, _, err = issuer.Verify(context.Background(), sign("first", first, other))
+		assert.Error(t, err, "tokens must expire")
+
+		hmac, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims()).SignedString([]byte("secret"))
+		require.NoError(t, err)
+		_, _, err = issuer.Verify(context.Background(), hmac)
+		assert.Error(t, err, "HMAC tokens are not accepted")
+
+		other = claims()
+		other["iss"] = "https://elsewhere.example.com"
+		foreign := sign("first", first, other)
+		assert.False(t, issuer.Issues(foreign))
+		_, _, err = issuer.Verify(context.Background(), foreign)
+		assert.Error(t, err)
+	})
+
+	t.Run("Key Rotation", func(t *testing.T) {
+		second, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		keys["second"] = second
+		issuer.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+
+		_, _, err = issuer.Verify(context.Background(), sign("second", second, claims()))
+		assert.NoError(t, err, "an unknown kid fetches the key set again")
+
+		_, _, err = issuer.Verify(context.Background(), sign("third", second, claims()))
+		assert.Error(t, err, "the key set is not fetched again right away")
+	})
+}
+
+func TestExternalIssuer_FetchWithoutLock(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	keys := map[string]*rsa.PrivateKey{"first": key}
+	jwks := newJWKSServer(t, &keys)
+
+	// The second fetch of the key set hangs until released
+	fetches, release := 0, make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fetches++; fetches > 1 {
+			<-release
+		}
+		http.Redirect(w, r, jwks.URL+"/jwks", http.StatusFound)
+	}))
+	t.Cleanup(server.Close)
+	t.Cleanup(func() { close(release) })
+
+	issuer, err := NewExternalIssuer(&config.Config{ExternalJWTIssuer: jwks.URL, ExternalJWTJWKSURL: server.URL, ExternalJWTAudience: "basin"})
+	require.NoError(t, err)
+	_, err = issuer.key(context.Background(), "first")
+	require.NoError(t, err)
+
+	issuer.fetchedAt = time.Now().Add(-jwksRefreshInterval)
+	go issuer.key(context.Background(), "rotated")
+	require.Eventually(t, func() bool {
+		issuer.mu.Lock()
+		defer issuer.mu.Unlock()
+		return issuer.fetching != nil
+	}, time.Second, time.Millisecond)
+
+	// Known keys are served while the fetch hangs, and waiting for it stops with the request
+	_, err = issuer.key(context.Background(), "first")
+	assert.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = issuer.key(ctx, "rotated")
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
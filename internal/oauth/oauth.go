@@ -0,0 +1,328 @@
+// Package oauth signs users in with external identity providers using the OAuth 2.0
+// authorization code flow with PKCE.
+//
+// Google and generic OpenID Connect providers are identified through their userinfo
+// endpoint, which is called with the access token from the token endpoint, so ID tokens
+// never need to be verified locally. GitHub is plain OAuth 2.0 and is identified
+// through its REST API.
+package oauth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/config"
+)
+
+// Provider names accepted in /auth/oauth/:provider
+const (
+	ProviderGoogle = "google"
+	ProviderGitHub = "github"
+	ProviderOIDC   = "oidc"
+)
+
+const maxResponseBody = 1 << 20
+
+// Identity is the account a user signed in with
+type Identity struct {
+	Provider      string
+	Subject       string // Stable account ID at the provider
+	Email         string
+	EmailVerified bool
+	FirstName     string
+	LastName      string
+	Claims        map[string]interface{} // Everything the provider returned, for role mapping
+}
+
+// Provider is one configured identity provider
+type Provider struct {
+	Name         string
+	ClientID     string
+	ClientSecret string
+	Scopes       []string
+
+	// Endpoints; for OIDC providers they are discovered from Issuer when empty
+	Issuer      string
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+
+	github    bool
+	githubAPI string
+	client    *http.Client
+	discover  sync.Mutex
+}
+
+// Providers returns the providers configured in cfg by name. A provider is enabled by
+// setting its client ID.
+func Providers(cfg *config.Config) map[string]*Provider {
+	client := &http.Client{Timeout: 15 * time.Second}
+	providers := make(map[string]*Provider)
+
+	if cfg.OAuthGoogleClientID != "" {
+		providers[ProviderGoogle] = &Provider{
+			Name:         ProviderGoogle,
+			ClientID:     cfg.OAuthGoogleClientID,
+			ClientSecret: cfg.OAuthGoogleClientSecret,
+			Scopes:       []string{"openid", "email", "profile"},
+			Issuer:       "https://accounts.google.com",
+			AuthURL:      "https://accounts.google.com/o/oauth2/v2/auth",
+			TokenURL:     "https://oauth2.googleapis.com/token",
+			UserInfoURL:  "https://openidconnect.googleapis.com/v1/userinfo",
+			client:       client,
+		}
+	}
+	if cfg.OAuthGitHubClientID != "" {
+		providers[ProviderGitHub] = &Provider{
+			Name:         ProviderGitHub,
+			ClientID:     cfg.OAuthGitHubClientID,
+			ClientSecret: cfg.OAuthGitHubClientSecret,
+			Scopes:       []string{"read:user", "user:email"},
+			AuthURL:      "https://github.com/login/oauth/authorize",
+			TokenURL:     "https://github.com/login/oauth/access_token",
+			github:       true,
+			githubAPI:    "https://api.github.com",
+			client:       client,
+		}
+	}
+	if cfg.OAuthOIDCClientID != "" && cfg.OAuthOIDCIssuer != "" {
+		providers[ProviderOIDC] = &Provider{
+			Name:         ProviderOIDC,
+			ClientID:     cfg.OAuthOIDCClientID,
+			ClientSecret: cfg.OAuthOIDCClientSecret,
+			Scopes:       cfg.OAuthOIDCScopes,
+			Issuer:       strings.TrimSuffix(cfg.OAuthOIDCIssuer, "/"),
+			client:       client,
+		}
+	}
+	return providers
+}
+
+// Names lists the providers sorted by name
+func Names(providers map[string]*Provider) []string {
+	names := make([]string, 0, len(providers))
+	for name := range providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewVerifier creates a random PKCE code verifier (also used for state values)
+func NewVerifier() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// challenge derives the S256 PKCE code challenge of a verifier
+func challenge(verifier string) string {
+	sum := sha256.Sum256([]byte(verifier))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// AuthCodeURL returns the provider page the user is sent to
+func (p *Provider) AuthCodeURL(ctx context.Context, state, verifier, redirectURL string) (string, error) {
+	if err := p.discoverEndpoints(ctx); err != nil {
+		return "", err
+	}
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.ClientID},
+		"redirect_uri":          {redirectURL},
+		"scope":                 {strings.Join(p.Scopes, " ")},
+		"state":                 {state},
+		"code_challenge":        {challenge(verifier)},
+		"code_challenge_method": {"S256"},
+	}
+	separator := "?"
+	if strings.Contains(p.AuthURL, "?") {
+		separator = "&"
+	}
+	return p.AuthURL + separator + query.Encode(), nil
+}
+
+// Authenticate exchanges the authorization code from the callback and loads the identity
+// of the signed in account
+func (p *Provider) Authenticate(ctx context.Context, code, verifier, redirectURL string) (Identity, error) {
+	if err := p.discoverEndpoints(ctx); err != nil {
+		return Identity{}, err
+	}
+	accessToken, err := p.exchange(ctx, code, verifier, redirectURL)
+	if err != nil {
+		return Identity{}, err
+	}
+	if p.github {
+		return p.githubIdentity(ctx, accessToken)
+	}
+	return p.userInfo(ctx, accessToken)
+}
+
+// exchange trades the authorization code for an access token
+func (p *Provider) exchange(ctx context.Context, code, verifier, redirectURL string) (string, error) {
+	form := url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {code},
+		"redirect_uri":  {redirectURL},
+		"client_id":     {p.ClientID},
+		"client_secret": {p.ClientSecret},
+		"code_verifier": {verifier},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	var token struct {
+		AccessToken      string `json:"access_token"`
+		Error            string `json:"error"`
+		ErrorDescription string `json:"error_description"`
+	}
+	if err := p.getJSON(req, &token); err != nil {
+		return "", fmt.Errorf("%s token exchange: %w", p.Name, err)
+	}
+	// GitHub reports errors with status 200
+	if token.Error != "" {
+		return "", fmt.Errorf("%s token exchange: %s %s", p.Name, token.Error, token.ErrorDescription)
+	}
+	if token.AccessToken == "" {
+		return "", fmt.Errorf("%s token exchange: no access token", p.Name)
+	}
+	return token.AccessToken, nil
+}
+
+// userInfo identifies the account of an OpenID Connect access token
+func (p *Provider) userInfo(ctx context.Context, accessToken string) (Identity, error) {
+	var claims map[string]interface{}
+	if err := p.getAPI(ctx, p.UserInfoURL, accessToken, &claims); err != nil {
+		return Identity{}, fmt.Errorf("%s userinfo: %w", p.Name, err)
+	}
+
+	identity := Identity{Provider: p.Name, Claims: claims}
+	identity.Subject, _ = claims["sub"].(string)
+	identity.Email, _ = claims["email"].(string)
+	identity.FirstName, _ = claims["given_name"].(string)
+	identity.LastName, _ = claims["family_name"].(string)
+	// Some providers send email_verified as a string
+	switch verified := claims["email_verified"].(type) {
+	case bool:
+		identity.EmailVerified = verified
+	case string:
+		identity.EmailVerified = verified == "true"
+	}
+	if identity.Subject == "" {
+		return Identity{}, fmt.Errorf("%s userinfo: no subject", p.Name)
+	}
+	return identity, nil
+}
+
+// githubIdentity identifies the GitHub account of an access token. The email is the
+// account's primary address when it is verified.
+func (p *Provider) githubIdentity(ctx context.Context, accessToken string) (Identity, error) {
+	var user map[string]interface{}
+	if err := p.getAPI(ctx, p.githubAPI+"/user", accessToken, &user); err != nil {
+		return Identity{}, fmt.Errorf("github user: %w", err)
+	}
+	id, ok := user["id"].(float64)
+	if !ok {
+		return Identity{}, fmt.Errorf("github user: no id")
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := p.getAPI(ctx, p.githubAPI+"/user/emails", accessToken, &emails); err != nil {
+		return Identity{}, fmt.Errorf("github emails: %w", err)
+	}
+
+	identity := Identity{Provider: p.Name, Subject: fmt.Sprintf("%.0f", id), Claims: user}
+	for _, email := range emails {
+		if email.Primary {
+			identity.Email = email.Email
+			identity.EmailVerified = email.Verified
+		}
+	}
+	if name, _ := user["name"].(string); name != "" {
+		first, last, _ := strings.Cut(name, " ")
+		identity.FirstName, identity.LastName = first, last
+	}
+	return identity, nil
+}
+
+// discoverEndpoints fills in missing endpoints from the OpenID Connect discovery
+// document of the issuer
+func (p *Provider) discoverEndpoints(ctx context.Context) error {
+	p.discover.Lock()
+	defer p.discover.Unlock()
+	if p.AuthURL != "" && p.TokenURL != "" && (p.UserInfoURL != "" || p.github) {
+		return nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, p.Issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return err
+	}
+	var doc struct {
+		Issuer                string `json:"issuer"`
+		AuthorizationEndpoint string `json:"authorization_endpoint"`
+		TokenEndpoint         string `json:"token_endpoint"`
+		UserinfoEndpoint      string `json:"userinfo_endpoint"`
+	}
+	if err := p.getJSON(req, &doc); err != nil {
+		return fmt.Errorf("%s discovery: %w", p.Name, err)
+	}
+	if strings.TrimSuffix(doc.Issuer, "/") != p.Issuer {
+		return fmt.Errorf("%s discovery: issuer '%s' does not match '%s'", p.Name, doc.Issuer, p.Issuer)
+	}
+	if doc.AuthorizationEndpoint == "" || doc.TokenEndpoint == "" || doc.UserinfoEndpoint == "" {
+		return fmt.Errorf("%s discovery: missing endpoints", p.Name)
+	}
+	p.AuthURL, p.TokenURL, p.UserInfoURL = doc.AuthorizationEndpoint, doc.TokenEndpoint, doc.UserinfoEndpoint
+	return nil
+}
+
+// getAPI calls a provider API with the access token
+func (p *Provider) getAPI(ctx context.Context, url, accessToken string, v interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+	return p.getJSON(req, v)
+}
+
+// getJSON sends req and decodes the JSON response, turning non-2xx responses into errors
+func (p *Provider) getJSON(req *http.Request, v interface{}) error {
+	req.Header.Set("Accept", "application/json")
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		if len(body) > 256 {
+			body = body[:256]
+		}
+		return fmt.Errorf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
+	return json.Unmarshal(body, v)
+}
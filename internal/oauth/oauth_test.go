@@ -0,0 +1,143 @@
+package oauth
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"go-rbac-api/internal/config"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newOIDCServer fakes an OpenID Connect provider that accepts the code "good-code"
+func newOIDCServer(t *testing.T, userinfo map[string]interface{}) *httptest.Server {
+	var server *httptest.Server
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]string{
+			"issuer":                 server.URL,
+			"authorization_endpoint": server.URL + "/authorize",
+			"token_endpoint":         server.URL + "/token",
+			"userinfo_endpoint":      server.URL + "/userinfo",
+		})
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		if r.PostForm.Get("code") != "good-code" || r.PostForm.Get("code_verifier") != "the-verifier" {
+			w.WriteHeader(http.StatusBadRequest)
+			json.NewEncoder(w).Encode(map[string]string{"error": "invalid_grant"})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "access", "token_type": "Bearer"})
+	})
+	mux.HandleFunc("/userinfo", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer access" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		json.NewEncoder(w).Encode(userinfo)
+	})
+	server = httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestProvider_OIDC(t *testing.T) {
+	server := newOIDCServer(t, map[string]interface{}{
+		"sub":            "user-1",
+		"email":          "ada@example.com",
+		"email_verified": "true",
+		"given_name":     "Ada",
+		"family_name":    "Lovelace",
+		"groups":         []string{"staff"},
+	})
+
+	providers := Providers(&config.Config{
+		OAuthOIDCIssuer:   server.URL + "/",
+		OAuthOIDCClientID: "client",
+		OAuthOIDCScopes:   []string{"openid", "email"},
+	})
+	require.Equal(t, []string{ProviderOIDC}, Names(providers))
+	provider := providers[ProviderOIDC]
+
+	authURL, err := provider.AuthCodeURL(context.Background(), "the-state", "the-verifier", "https://api.example.com/cb")
+	require.NoError(t, err)
+	parsed, err := url.Parse(authURL)
+	require.NoError(t, err)
+	assert.Equal(t, "/authorize", parsed.Path)
+	query := parsed.Query()
+	assert.Equal(t, "the-state", query.Get("state"))
+	assert.Equal(t, "openid email", query.Get("scope"))
+	assert.Equal(t, challenge("the-verifier"), query.Get("code_challenge"))
+	assert.NotContains(t, authURL, "the-verifier")
+
+	identity, err := provider.Authenticate(context.Background(), "good-code", "the-verifier", "https://api.example.com/cb")
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", identity.Subject)
+	assert.Equal(t, "ada@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "Lovelace", identity.LastName)
+
+	_, err = provider.Authenticate(context.Background(), "bad-code", "the-verifier", "https://api.example.com/cb")
+	assert.ErrorContains(t, err, "invalid_grant")
+}
+
+func TestProvider_OIDCIssuerMismatch(t *testing.T) {
+	server := newOIDCServer(t, nil)
+	provider := Providers(&config.Config{OAuthOIDCIssuer: server.URL + "/tenant", OAuthOIDCClientID: "client"})[ProviderOIDC]
+
+	_, err := provider.AuthCodeURL(context.Background(), "s", "v", "https://api.example.com/cb")
+	assert.Error(t, err)
+}
+
+func TestProvider_GitHub(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/login/oauth/access_token", func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/json", r.Header.Get("Accept"))
+		json.NewEncoder(w).Encode(map[string]string{"access_token": "gh-token"})
+	})
+	mux.HandleFunc("/user", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(map[string]interface{}{"id": 583231, "login": "octocat", "name": "The Octocat"})
+	})
+	mux.HandleFunc("/user/emails", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode([]map[string]interface{}{
+			{"email": "old@example.com", "primary": false, "verified": true},
+			{"email": "octocat@example.com", "primary": true, "verified": true},
+		})
+	})
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	provider := Providers(&config.Config{OAuthGitHubClientID: "client", OAuthGitHubClientSecret: "secret"})[ProviderGitHub]
+	provider.TokenURL = server.URL + "/login/oauth/access_token"
+	provider.githubAPI = server.URL
+
+	identity, err := provider.Authenticate(context.Background(), "code", "verifier", "https://api.example.com/cb")
+	require.NoError(t, err)
+	assert.Equal(t, "583231", identity.Subject)
+	assert.Equal(t, "octocat@example.com", identity.Email)
+	assert.True(t, identity.EmailVerified)
+	assert.Equal(t, "The", identity.FirstName)
+	assert.Equal(t, "octocat", identity.Claims["login"])
+}
+
+func TestRoleMapping(t *testing.T) {
+	mapping, err := ParseRoleMapping("groups", []string{"basin-admins=admin", "staff=editor", "staff=viewer"}, []string{"viewer"})
+	require.NoError(t, err)
+
+	identity := Identity{Claims: map[string]interface{}{"groups": []interface{}{"staff", "basin-admins", 7}}}
+	assert.Equal(t, []string{"admin", "editor", "viewer"}, mapping.MappedRoles(identity))
+
+	identity.Claims["groups"] = "staff"
+	assert.Equal(t, []string{"editor", "viewer"}, mapping.MappedRoles(identity))
+
+	assert.Empty(t, mapping.MappedRoles(Identity{}))
+
+	_, err = ParseRoleMapping("groups", []string{"admin"}, nil)
+	assert.ErrorContains(t, err, "expected value=role")
+}
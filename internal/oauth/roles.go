@@ -0,0 +1,62 @@
+package oauth
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RoleMapping maps values of an identity claim to Basin roles, e.g. members of the
+// "basin-admins" group to the admin role
+type RoleMapping struct {
+	Claim    string              // Claim holding a string or a list of strings, e.g. "groups"
+	Roles    map[string][]string // Claim value -> role names
+	Defaults []string            // Roles of every provisioned user
+}
+
+// ParseRoleMapping reads OAUTH_ROLE_MAPPING entries of the form "value=role"
+func ParseRoleMapping(claim string, entries []string, defaults []string) (RoleMapping, error) {
+	mapping := RoleMapping{Claim: claim, Roles: make(map[string][]string), Defaults: defaults}
+	for _, entry := range entries {
+		value, role, ok := strings.Cut(entry, "=")
+		value, role = strings.TrimSpace(value), strings.TrimSpace(role)
+		if !ok || value == "" || role == "" {
+			return RoleMapping{}, fmt.Errorf("invalid role mapping '%s', expected value=role", entry)
+		}
+		mapping.Roles[value] = append(mapping.Roles[value], role)
+	}
+	return mapping, nil
+}
+
+// MappedRoles returns the roles the claims of an identity map to, sorted and without
+// duplicates. The defaults are not included.
+func (m RoleMapping) MappedRoles(identity Identity) []string {
+	if m.Claim == "" {
+		return nil
+	}
+
+	var values []string
+	switch claim := identity.Claims[m.Claim].(type) {
+	case string:
+		values = []string{claim}
+	case []interface{}:
+		for _, value := range claim {
+			if s, ok := value.(string); ok {
+				values = append(values, s)
+			}
+		}
+	}
+
+	seen := make(map[string]bool)
+	var roles []string
+	for _, value := range values {
+		for _, role := range m.Roles[value] {
+			if !seen[role] {
+				seen[role] = true
+				roles = append(roles, role)
+			}
+		}
+	}
+	sort.Strings(roles)
+	return roles
+}
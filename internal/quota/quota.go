@@ -0,0 +1,294 @@
+// Package quota enforces per-tenant usage limits: how many collections, items per
+// collection and API keys a tenant may have, how many bytes of assets it may store and how
+// many requests it may make per minute.
+//
+// Defaults come from the TENANT_* settings. A tenant can be given its own limits under
+// "limits" in its settings (PUT /tenants/:id); the limits it leaves out keep the default.
+// A limit of 0 means unlimited.
+package quota
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+
+	"github.com/google/uuid"
+)
+
+// Names of the limits, as used in tenant settings and error responses
+const (
+	LimitCollections        = "max_collections"
+	LimitItemsPerCollection = "max_items_per_collection"
+	LimitAPIKeys            = "max_api_keys"
+	LimitStorage            = "max_storage_bytes"
+	LimitRequestsPerMinute  = "requests_per_minute"
+)
+
+// limitsCacheTTL is how long AllowRequest reuses a tenant's limits; changed limits apply
+// to the request rate within this time
+const limitsCacheTTL = time.Minute
+
+// Limits are the usage limits of one tenant. 0 means unlimited.
+type Limits struct {
+	MaxCollections        int   `json:"max_collections"`
+	MaxItemsPerCollection int   `json:"max_items_per_collection"`
+	MaxAPIKeys            int   `json:"max_api_keys"`
+	MaxStorageBytes       int64 `json:"max_storage_bytes"`
+	RequestsPerMinute     int   `json:"requests_per_minute"`
+}
+
+// Overrides are the limits set for one tenant in its settings; nil fields keep the default
+type Overrides struct {
+	MaxCollections        *int   `json:"max_collections,omitempty"`
+	MaxItemsPerCollection *int   `json:"max_items_per_collection,omitempty"`
+	MaxAPIKeys            *int   `json:"max_api_keys,omitempty"`
+	MaxStorageBytes       *int64 `json:"max_storage_bytes,omitempty"`
+	RequestsPerMinute     *int   `json:"requests_per_minute,omitempty"`
+}
+
+// Validate rejects negative limits
+func (o Overrides) Validate() error {
+	for name, value := range map[string]*int{
+		LimitCollections:        o.MaxCollections,
+		LimitItemsPerCollection: o.MaxItemsPerCollection,
+		LimitAPIKeys:            o.MaxAPIKeys,
+		LimitRequestsPerMinute:  o.RequestsPerMinute,
+	} {
+		if value != nil && *value < 0 {
+			return fmt.Errorf("%s must not be negative", name)
+		}
+	}
+	if o.MaxStorageBytes != nil && *o.MaxStorageBytes < 0 {
+		return fmt.Errorf("%s must not be negative", LimitStorage)
+	}
+	return nil
+}
+
+// Usage is what a tenant currently consumes of each limit
+type Usage struct {
+	Collections        int64            `json:"collections"`
+	Items              map[string]int64 `json:"items"` // Items per collection slug
+	APIKeys            int64            `json:"api_keys"`
+	StorageBytes       int64            `json:"storage_bytes"`
+	RequestsThisMinute int              `json:"requests_this_minute"`
+}
+
+// ExceededError is returned when an operation would take a tenant past one of its limits
+type ExceededError struct {
+	Limit string // One of the Limit* names
+	Max   int64
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("tenant limit reached: %s is %d", e.Limit, e.Max)
+}
+
+// Defaults returns the limits configured for tenants without their own
+func Defaults(cfg *config.Config) Limits {
+	return Limits{
+		MaxCollections:        cfg.TenantMaxCollections,
+		MaxItemsPerCollection: cfg.TenantMaxItemsPerCollection,
+		MaxAPIKeys:            cfg.TenantMaxAPIKeys,
+		MaxStorageBytes:       cfg.TenantMaxStorage,
+		RequestsPerMinute:     cfg.TenantRequestsPerMinute,
+	}
+}
+
+// ForTenant applies the "limits" of a tenant's settings JSON to the defaults
+func ForTenant(defaults Limits, settings json.RawMessage) (Limits, error) {
+	if len(settings) == 0 {
+		return defaults, nil
+	}
+	var parsed struct {
+		Limits json.RawMessage `json:"limits"`
+	}
+	if err := json.Unmarshal(settings, &parsed); err != nil {
+		return defaults, fmt.Errorf("invalid tenant settings: %w", err)
+	}
+	if len(parsed.Limits) == 0 || string(parsed.Limits) == "null" {
+		return defaults, nil
+	}
+
+	// Keys missing from the settings keep their default value
+	limits := defaults
+	if err := json.Unmarshal(parsed.Limits, &limits); err != nil {
+		return defaults, fmt.Errorf("invalid tenant limits: %w", err)
+	}
+	return limits, nil
+}
+
+// Check returns an ExceededError when adding to current would pass max (0 is unlimited)
+func Check(limit string, max, current, adding int64) error {
+	if max > 0 && current+adding > max {
+		return &ExceededError{Limit: limit, Max: max}
+	}
+	return nil
+}
+
+// Checker loads the limits and usage of tenants.
+//
+// A nil *Checker is valid and enforces no limits.
+type Checker struct {
+	db       *db.DB
+	defaults Limits
+
+	mu     sync.Mutex
+	cached map[uuid.UUID]cachedLimits
+}
+
+// cachedLimits are the limits of a tenant loaded for AllowRequest
+type cachedLimits struct {
+	limits  Limits
+	expires time.Time
+}
+
+// NewChecker creates a Checker that falls back to defaults for tenants without limits
+func NewChecker(database *db.DB, defaults Limits) *Checker {
+	return &Checker{
+		db:       database,
+		defaults: defaults,
+		cached:   make(map[uuid.UUID]cachedLimits),
+	}
+}
+
+// Limits returns the limits of a tenant
+func (c *Checker) Limits(ctx context.Context, tenantID uuid.UUID) (Limits, error) {
+	if c == nil {
+		return Limits{}, nil
+	}
+	tenant, err := c.db.Queries.GetTenantByID(ctx, tenantID)
+	if err != nil {
+		return Limits{}, fmt.Errorf("failed to load tenant limits: %w", err)
+	}
+	return ForTenant(c.defaults, tenant.Settings.RawMessage)
+}
+
+// CheckCollections fails when the tenant cannot have another collection
+func (c *Checker) CheckCollections(ctx context.Context, tenantID uuid.UUID) error {
+	limits, err := c.Limits(ctx, tenantID)
+	if err != nil || limits.MaxCollections == 0 {
+		return err
+	}
+	count, err := c.db.Queries.CountTenantCollections(ctx, uuid.NullUUID{UUID: tenantID, Valid: true})
+	if err != nil {
+		return err
+	}
+	return Check(LimitCollections, int64(limits.MaxCollections), count, 1)
+}
+
+// CheckAPIKeys fails when the tenant cannot have another active API key
+func (c *Checker) CheckAPIKeys(ctx context.Context, tenantID uuid.UUID) error {
+	limits, err := c.Limits(ctx, tenantID)
+	if err != nil || limits.MaxAPIKeys == 0 {
+		return err
+	}
+	count, err := c.db.Queries.CountTenantAPIKeys(ctx, uuid.NullUUID{UUID: tenantID, Valid: true})
+	if err != nil {
+		return err
+	}
+	return Check(LimitAPIKeys, int64(limits.MaxAPIKeys), count, 1)
+}
+
+// CheckStorage fails when storing size more bytes would take the tenant past its storage limit
+func (c *Checker) CheckStorage(ctx context.Context, tenantID uuid.UUID, size int64) error {
+	limits, err := c.Limits(ctx, tenantID)
+	if err != nil || limits.MaxStorageBytes == 0 {
+		return err
+	}
+	used, err := c.db.Queries.SumTenantAssetSize(ctx, tenantID)
+	if err != nil {
+		return err
+	}
+	return Check(LimitStorage, limits.MaxStorageBytes, used, size)
+}
+
+// AllowRequest counts a request of the tenant and reports whether it is within the
+// tenant's requests per minute, along with the limit (0 when unlimited), the requests
+// left and when the current minute ends
+func (c *Checker) AllowRequest(ctx context.Context, tenantID uuid.UUID) (allowed bool, limit, remaining int, reset time.Time, err error) {
+	if c == nil {
+		return true, 0, 0, time.Time{}, nil
+	}
+	limits, err := c.cachedLimits(ctx, tenantID)
+	if err != nil {
+		return false, 0, 0, time.Time{}, err
+	}
+	count, reset := requests.add(tenantID)
+	limit = limits.RequestsPerMinute
+	if limit == 0 {
+		return true, 0, 0, reset, nil
+	}
+	if count > limit {
+		return false, limit, 0, reset, nil
+	}
+	return true, limit, limit - count, reset, nil
+}
+
+// cachedLimits returns the limits of a tenant, loading them at most once per limitsCacheTTL
+func (c *Checker) cachedLimits(ctx context.Context, tenantID uuid.UUID) (Limits, error) {
+	now := time.Now()
+	c.mu.Lock()
+	entry, ok := c.cached[tenantID]
+	c.mu.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry.limits, nil
+	}
+
+	limits, err := c.Limits(ctx, tenantID)
+	if err != nil {
+		return Limits{}, err
+	}
+	c.mu.Lock()
+	c.cached[tenantID] = cachedLimits{limits: limits, expires: now.Add(limitsCacheTTL)}
+	c.mu.Unlock()
+	return limits, nil
+}
+
+// Usage reports what a tenant currently consumes. Items are counted in the data table of
+// every collection of the tenant.
+func (c *Checker) Usage(ctx context.Context, tenantID uuid.UUID) (Usage, error) {
+	queries := c.db.Queries
+	tenant := uuid.NullUUID{UUID: tenantID, Valid: true}
+	usage := Usage{Items: make(map[string]int64), RequestsThisMinute: requests.current(tenantID)}
+
+	collections, err := queries.GetCollectionsByTenant(ctx, tenant)
+	if err != nil {
+		return Usage{}, err
+	}
+	usage.Collections = int64(len(collections))
+	for _, collection := range collections {
+		count, err := CountItems(ctx, c.db, c.db.Dialect().Table("data", collection.DataTableName))
+		if err != nil {
+			// Collections without a data table (yet) hold no items
+			continue
+		}
+		usage.Items[collection.Slug] = count
+	}
+
+	if usage.APIKeys, err = queries.CountTenantAPIKeys(ctx, tenant); err != nil {
+		return Usage{}, err
+	}
+	if usage.StorageBytes, err = queries.SumTenantAssetSize(ctx, tenantID); err != nil {
+		return Usage{}, err
+	}
+	return usage, nil
+}
+
+// rowQuerier is satisfied by *sql.DB, *sql.Tx and *db.DB
+type rowQuerier interface {
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+// CountItems counts the rows of a data table. Inside a transaction, pass the *sql.Tx so
+// the count includes the transaction's own writes.
+func CountItems(ctx context.Context, exec rowQuerier, dataTableName string) (int64, error) {
+	var count int64
+	err := exec.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", dataTableName)).Scan(&count)
+	return count, err
+}
@@ -0,0 +1,83 @@
+package quota
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForTenant(t *testing.T) {
+	defaults := Limits{MaxCollections: 10, MaxItemsPerCollection: 1000, RequestsPerMinute: 600}
+
+	limits, err := ForTenant(defaults, nil)
+	require.NoError(t, err)
+	assert.Equal(t, defaults, limits)
+
+	limits, err = ForTenant(defaults, json.RawMessage(`{"mail": {"from_email": "a@example.com"}}`))
+	require.NoError(t, err)
+	assert.Equal(t, defaults, limits)
+
+	// Limits the tenant sets replace the defaults, the others are kept
+	limits, err = ForTenant(defaults, json.RawMessage(`{"limits": {"max_collections": 50, "requests_per_minute": 0, "max_storage_bytes": 1048576}}`))
+	require.NoError(t, err)
+	assert.Equal(t, Limits{MaxCollections: 50, MaxItemsPerCollection: 1000, MaxStorageBytes: 1 << 20}, limits)
+
+	_, err = ForTenant(defaults, json.RawMessage(`{"limits": {"max_collections": "many"}}`))
+	assert.ErrorContains(t, err, "invalid tenant limits")
+}
+
+func TestOverrides(t *testing.T) {
+	negative, zero := -1, 0
+	assert.NoError(t, Overrides{MaxAPIKeys: &zero}.Validate())
+	assert.ErrorContains(t, Overrides{MaxAPIKeys: &negative}.Validate(), LimitAPIKeys)
+
+	// Omitted limits are not stored, so they keep following the defaults
+	encoded, err := json.Marshal(Overrides{MaxCollections: &zero})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"max_collections": 0}`, string(encoded))
+}
+
+func TestCheck(t *testing.T) {
+	assert.NoError(t, Check(LimitCollections, 0, 1000, 1))
+	assert.NoError(t, Check(LimitCollections, 5, 4, 1))
+
+	err := Check(LimitItemsPerCollection, 5, 4, 2)
+	var exceeded *ExceededError
+	require.True(t, errors.As(err, &exceeded))
+	assert.Equal(t, LimitItemsPerCollection, exceeded.Limit)
+	assert.Equal(t, int64(5), exceeded.Max)
+}
+
+func TestRequestCounter(t *testing.T) {
+	now := time.Date(2025, 1, 1, 12, 0, 10, 0, time.UTC)
+	counter := newRequestCounter(func() time.Time { return now })
+	tenant, other := uuid.New(), uuid.New()
+
+	count, reset := counter.add(tenant)
+	assert.Equal(t, 1, count)
+	assert.Equal(t, time.Date(2025, 1, 1, 12, 1, 0, 0, time.UTC), reset)
+	count, _ = counter.add(tenant)
+	assert.Equal(t, 2, count)
+	assert.Equal(t, 0, counter.current(other))
+
+	// A new minute starts over
+	now = now.Add(time.Minute)
+	assert.Equal(t, 0, counter.current(tenant))
+	count, _ = counter.add(tenant)
+	assert.Equal(t, 1, count)
+}
+
+func TestNilChecker(t *testing.T) {
+	var checker *Checker
+	allowed, limit, _, _, err := checker.AllowRequest(context.Background(), uuid.New())
+	require.NoError(t, err)
+	assert.True(t, allowed)
+	assert.Zero(t, limit)
+	assert.NoError(t, checker.CheckCollections(context.Background(), uuid.New()))
+}
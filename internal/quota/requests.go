@@ -0,0 +1,53 @@
+package quota
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// requests counts the requests of every tenant in this process. It is shared by all
+// Checkers so the usage endpoint sees the counts of the rate limit middleware.
+var requests = newRequestCounter(time.Now)
+
+// requestCounter counts requests per tenant in fixed one-minute windows. Counts are kept
+// in memory, so with several API instances each one enforces the limit on its own.
+type requestCounter struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	window time.Time // Start of the current minute
+	counts map[uuid.UUID]int
+}
+
+func newRequestCounter(now func() time.Time) *requestCounter {
+	return &requestCounter{now: now, counts: make(map[uuid.UUID]int)}
+}
+
+// add counts a request of the tenant and returns its requests in the current minute,
+// including this one, and when the minute ends
+func (r *requestCounter) add(tenantID uuid.UUID) (int, time.Time) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advance()
+	r.counts[tenantID]++
+	return r.counts[tenantID], r.window.Add(time.Minute)
+}
+
+// current returns the tenant's requests in the current minute
+func (r *requestCounter) current(tenantID uuid.UUID) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.advance()
+	return r.counts[tenantID]
+}
+
+// advance starts over when a new minute has begun. The caller holds r.mu.
+func (r *requestCounter) advance() {
+	window := r.now().Truncate(time.Minute)
+	if !window.Equal(r.window) {
+		r.window = window
+		r.counts = make(map[uuid.UUID]int)
+	}
+}
@@ -0,0 +1,109 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/lib/pq"
+)
+
+// Array rules
+//
+// Array fields hold Postgres arrays. Two operators compare them with a list of values:
+//
+//	{"tags": {"_contains": ["new", "sale"]}}  // every value is in the array
+//	{"tags": {"_overlaps": ["new", "sale"]}}  // at least one value is in the array
+//
+// A single value stands for a list of one. The list is bound as an array literal, which the
+// column's type decides how to read, so the rules work for text, UUID and integer arrays.
+
+// arrayOperators maps array rule operators to their SQL operator
+var arrayOperators = map[string]string{
+	"_contains": "@>",
+	"_overlaps": "&&",
+}
+
+// compileArray compiles a _contains or _overlaps operator
+func compileArray(column, operator string, value interface{}, vars RuleVars) (ruleNode, error) {
+	list, ok := value.([]interface{})
+	if !ok {
+		list = []interface{}{value}
+	}
+
+	elements := make(pq.StringArray, len(list))
+	for i, item := range list {
+		resolved, err := resolveRuleValue(item, vars)
+		if err != nil {
+			return ruleNode{}, err
+		}
+		if resolved == nil {
+			return ruleNode{}, fmt.Errorf("%s on '%s' cannot hold null", operator, column)
+		}
+		if _, ok := resolved.(sessionVariable); ok {
+			return ruleNode{}, fmt.Errorf("%s on '%s' cannot hold rule variables in policies", operator, column)
+		}
+		elements[i] = valueString(resolved)
+	}
+
+	literal, err := elements.Value()
+	if err != nil {
+		return ruleNode{}, fmt.Errorf("%s on '%s': %w", operator, column, err)
+	}
+	return ruleNode{op: operator, column: column, values: []interface{}{literal}}, nil
+}
+
+// arraySQL renders an array node, writing its array literal with placeholder
+func (n ruleNode) arraySQL(placeholder func(value interface{}) string) string {
+	return fmt.Sprintf(`"%s" %s %s`, n.column, arrayOperators[n.op], placeholder(n.values[0]))
+}
+
+// arrayMatches evaluates an array node against a record value, comparing elements by
+// their string form
+func (n ruleNode) arrayMatches(value interface{}) bool {
+	have, ok := arrayStrings(value)
+	if !ok {
+		return false
+	}
+	var want pq.StringArray
+	if err := want.Scan(n.values[0]); err != nil {
+		return false
+	}
+
+	elements := make(map[string]bool, len(have))
+	for _, element := range have {
+		elements[element] = true
+	}
+	found := 0
+	for _, element := range want {
+		if elements[element] {
+			found++
+		}
+	}
+	if n.op == "_overlaps" {
+		return found > 0
+	}
+	return found == len(want)
+}
+
+// arrayStrings returns the elements of a record's array value by their string form. Arrays
+// read as JSON text are decoded first.
+func arrayStrings(value interface{}) ([]string, bool) {
+	if text, ok := value.(string); ok {
+		var list []interface{}
+		if err := json.Unmarshal([]byte(text), &list); err != nil {
+			return nil, false
+		}
+		value = list
+	}
+
+	v := reflect.ValueOf(value)
+	if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	elements := make([]string, v.Len())
+	for i := range elements {
+		elements[i] = valueString(v.Index(i).Interface())
+	}
+	return elements, true
+}
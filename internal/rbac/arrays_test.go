@@ -0,0 +1,37 @@
+package rbac
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArrayRules(t *testing.T) {
+	filter, err := CompileRowFilter(json.RawMessage(`{"tags": {"_contains": ["new", "on sale"]}, "sizes": {"_overlaps": [38, 40]}}`), RuleVars{})
+	require.NoError(t, err)
+
+	condition, args := filter.SQL(1)
+	assert.Equal(t, `("sizes" && $1 AND "tags" @> $2)`, condition)
+	assert.Equal(t, []interface{}{`{"38","40"}`, `{"new","on sale"}`}, args)
+	assert.Equal(t, `("sizes" && '{"38","40"}' AND "tags" @> '{"new","on sale"}')`, filter.PolicySQL())
+
+	assert.True(t, filter.Matches(map[string]interface{}{"tags": []string{"on sale", "new", "used"}, "sizes": []int64{40, 42}}))
+	assert.True(t, filter.Matches(map[string]interface{}{"tags": []interface{}{"new", "on sale"}, "sizes": `[36, 38]`}))
+	assert.False(t, filter.Matches(map[string]interface{}{"tags": []string{"new"}, "sizes": []int64{40}}))
+	assert.False(t, filter.Matches(map[string]interface{}{"tags": []string{"new", "on sale"}, "sizes": []int64{42}}))
+	assert.False(t, filter.Matches(map[string]interface{}{"tags": nil, "sizes": []int64{40}}))
+}
+
+func TestArrayRuleSingleValue(t *testing.T) {
+	filter, err := CompileRowFilter(json.RawMessage(`{"owners": {"_contains": "$CURRENT_USER"}}`), RuleVars{})
+	require.NoError(t, err)
+	_, args := filter.SQL(1)
+	assert.Len(t, args, 1)
+
+	_, err = CompileRowFilter(json.RawMessage(`{"tags": {"_contains": [null]}}`), RuleVars{})
+	assert.Error(t, err)
+	_, err = CompilePolicyRule(json.RawMessage(`{"owners": {"_contains": "$CURRENT_USER"}}`))
+	assert.Error(t, err)
+}
@@ -0,0 +1,211 @@
+package rbac
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+)
+
+// maxCacheEntries bounds the cache size; when it is reached expired entries are swept and,
+// if that is not enough, the cache starts over
+const maxCacheEntries = 100000
+
+// Tables whose changes can alter the outcome of a permission check. Item events on these
+// tables clear the permission cache.
+var permissionTables = map[string]bool{
+	"permissions":  true,
+	"roles":        true,
+	"user_roles":   true,
+	"users":        true,
+	"user_tenants": true,
+}
+
+// permissionKey identifies one cached permission check
+type permissionKey struct {
+	userID   uuid.UUID
+	tenantID uuid.UUID // uuid.Nil when the check fell back to the user's default tenant
+	table    string
+	action   string
+}
+
+// permissionResult is the cached outcome of a permission check
+type permissionResult struct {
+	allowed       bool
+	allowedFields []string
+	rowFilter     *RowFilter
+	transforms    FieldTransforms
+	expires       time.Time
+}
+
+// PermissionCache keeps the results of recent permission checks in memory for a short
+// time, so a request does not have to load the user's roles and permissions again for
+// every table it touches. Entries expire after the TTL and are dropped explicitly when
+// roles, permissions or role assignments change, on this instance and, through the
+// broadcast function, on every other.
+//
+// A nil *PermissionCache is valid and caches nothing.
+type PermissionCache struct {
+	ttl       time.Duration
+	now       func() time.Time
+	broadcast func(userID uuid.UUID) // Announces invalidations to other instances; may be nil
+
+	mu      sync.RWMutex
+	entries map[permissionKey]permissionResult
+}
+
+// NewPermissionCache creates a cache whose entries live for ttl
+func NewPermissionCache(ttl time.Duration) *PermissionCache {
+	return &PermissionCache{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[permissionKey]permissionResult),
+	}
+}
+
+// defaultCache is shared by every PolicyChecker; nil disables caching
+var defaultCache *PermissionCache
+
+// UseCache installs the cache used by PolicyCheckers created afterwards. Call it once at
+// startup; passing nil disables caching.
+func UseCache(cache *PermissionCache) {
+	defaultCache = cache
+}
+
+// InvalidatePermissions clears every cached permission check, on every instance
+func InvalidatePermissions() {
+	defaultCache.Invalidate()
+	defaultCache.announce(uuid.Nil)
+}
+
+// permissionListeners are told which tenant's permissions changed
+var permissionListeners []func(tenantID uuid.UUID)
+
+// OnPermissionsChanged registers fn to be called by PermissionsChanged. Call it at startup.
+func OnPermissionsChanged(fn func(tenantID uuid.UUID)) {
+	permissionListeners = append(permissionListeners, fn)
+}
+
+// PermissionsChanged clears every cached permission check like InvalidatePermissions and
+// tells the listeners registered with OnPermissionsChanged that the roles or permissions
+// of a tenant changed
+func PermissionsChanged(tenantID uuid.UUID) {
+	InvalidatePermissions()
+	for _, fn := range permissionListeners {
+		fn(tenantID)
+	}
+}
+
+// InvalidateUserPermissions clears the cached permission checks of one user, on every
+// instance
+func InvalidateUserPermissions(userID uuid.UUID) {
+	defaultCache.InvalidateUser(userID)
+	defaultCache.announce(userID)
+}
+
+// Broadcast sets the function that announces invalidations to other instances, with
+// uuid.Nil when every entry was dropped. Call it at startup, before the cache is used.
+func (pc *PermissionCache) Broadcast(fn func(userID uuid.UUID)) {
+	if pc == nil {
+		return
+	}
+	pc.broadcast = fn
+}
+
+// announce passes an invalidation on to other instances
+func (pc *PermissionCache) announce(userID uuid.UUID) {
+	if pc == nil || pc.broadcast == nil {
+		return
+	}
+	pc.broadcast(userID)
+}
+
+// get returns a live cached result
+func (pc *PermissionCache) get(key permissionKey) (permissionResult, bool) {
+	if pc == nil {
+		return permissionResult{}, false
+	}
+
+	pc.mu.RLock()
+	result, ok := pc.entries[key]
+	pc.mu.RUnlock()
+
+	if !ok || pc.now().After(result.expires) {
+		return permissionResult{}, false
+	}
+	return result, true
+}
+
+// set stores a result and opportunistically drops expired entries
+func (pc *PermissionCache) set(key permissionKey, result permissionResult) {
+	if pc == nil {
+		return
+	}
+
+	now := pc.now()
+	result.expires = now.Add(pc.ttl)
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	// Keep the map from growing with entries nobody asks for again
+	if len(pc.entries) >= maxCacheEntries {
+		for k, entry := range pc.entries {
+			if now.After(entry.expires) {
+				delete(pc.entries, k)
+			}
+		}
+		if len(pc.entries) >= maxCacheEntries {
+			pc.entries = make(map[permissionKey]permissionResult)
+		}
+	}
+	pc.entries[key] = result
+}
+
+// Invalidate drops every entry
+func (pc *PermissionCache) Invalidate() {
+	if pc == nil {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	pc.entries = make(map[permissionKey]permissionResult)
+}
+
+// InvalidateUser drops the entries of one user
+func (pc *PermissionCache) InvalidateUser(userID uuid.UUID) {
+	if pc == nil {
+		return
+	}
+
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	for key := range pc.entries {
+		if key.userID == userID {
+			delete(pc.entries, key)
+		}
+	}
+}
+
+// HandleEvent clears the cache when roles, permissions or role assignments are written
+// through the API. Subscribe it to the event bus.
+func (pc *PermissionCache) HandleEvent(ctx context.Context, event events.Event) {
+	if permissionTables[event.Collection] {
+		pc.Invalidate()
+		pc.announce(uuid.Nil)
+	}
+}
+
+// HandleInvalidation drops the entries another instance invalidated: those of one user,
+// or every entry for uuid.Nil. Register it with the cache bus.
+func (pc *PermissionCache) HandleInvalidation(userID uuid.UUID) {
+	if userID == uuid.Nil {
+		pc.Invalidate()
+		return
+	}
+	pc.InvalidateUser(userID)
+}
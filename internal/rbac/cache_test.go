@@ -0,0 +1,106 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPermissionCache(t *testing.T) {
+	now := time.Now()
+	cache := NewPermissionCache(time.Minute)
+	cache.now = func() time.Time { return now }
+
+	userID := uuid.New()
+	key := permissionKey{userID: userID, table: "orders", action: "read"}
+	otherKey := permissionKey{userID: uuid.New(), table: "orders", action: "read"}
+
+	_, ok := cache.get(key)
+	assert.False(t, ok)
+
+	cache.set(key, permissionResult{allowed: true, allowedFields: []string{"*"}})
+	cache.set(otherKey, permissionResult{allowed: false})
+
+	result, ok := cache.get(key)
+	assert.True(t, ok)
+	assert.True(t, result.allowed)
+	assert.Equal(t, []string{"*"}, result.allowedFields)
+
+	// Entries expire after the TTL
+	now = now.Add(2 * time.Minute)
+	_, ok = cache.get(key)
+	assert.False(t, ok)
+
+	// Invalidation by user leaves other users alone
+	cache.set(key, permissionResult{allowed: true})
+	cache.set(otherKey, permissionResult{allowed: true})
+	cache.InvalidateUser(userID)
+	_, ok = cache.get(key)
+	assert.False(t, ok)
+	_, ok = cache.get(otherKey)
+	assert.True(t, ok)
+
+	// Permission-related events clear everything, other events do not
+	cache.HandleEvent(context.Background(), events.Event{Collection: "orders"})
+	_, ok = cache.get(otherKey)
+	assert.True(t, ok)
+	cache.HandleEvent(context.Background(), events.Event{Collection: "permissions"})
+	_, ok = cache.get(otherKey)
+	assert.False(t, ok)
+}
+
+func TestPermissionCache_Broadcast(t *testing.T) {
+	cache := NewPermissionCache(time.Minute)
+	var announced []uuid.UUID
+	cache.Broadcast(func(userID uuid.UUID) { announced = append(announced, userID) })
+
+	previous := defaultCache
+	UseCache(cache)
+	defer UseCache(previous)
+
+	userID, otherUserID := uuid.New(), uuid.New()
+	key := permissionKey{userID: userID, table: "orders", action: "read"}
+	otherKey := permissionKey{userID: otherUserID, table: "orders", action: "read"}
+
+	// Local invalidations are announced to other instances
+	InvalidateUserPermissions(userID)
+	InvalidatePermissions()
+	cache.HandleEvent(context.Background(), events.Event{Collection: "roles"})
+	cache.HandleEvent(context.Background(), events.Event{Collection: "orders"})
+	assert.Equal(t, []uuid.UUID{userID, uuid.Nil, uuid.Nil}, announced)
+
+	// Invalidations of other instances are applied but not announced again
+	announced = nil
+	cache.set(key, permissionResult{allowed: true})
+	cache.set(otherKey, permissionResult{allowed: true})
+	cache.HandleInvalidation(userID)
+	_, ok := cache.get(key)
+	assert.False(t, ok)
+	_, ok = cache.get(otherKey)
+	assert.True(t, ok)
+	cache.HandleInvalidation(uuid.Nil)
+	_, ok = cache.get(otherKey)
+	assert.False(t, ok)
+	assert.Empty(t, announced)
+}
+
+func TestNilPermissionCache(t *testing.T) {
+	var cache *PermissionCache
+	key := permissionKey{userID: uuid.New(), table: "orders", action: "read"}
+
+	assert.NotPanics(t, func() {
+		cache.set(key, permissionResult{allowed: true})
+		cache.Invalidate()
+		cache.InvalidateUser(key.userID)
+		cache.HandleEvent(context.Background(), events.Event{Collection: "roles"})
+		cache.HandleInvalidation(key.userID)
+		cache.Broadcast(func(uuid.UUID) {})
+	})
+	_, ok := cache.get(key)
+	assert.False(t, ok)
+}
@@ -2,7 +2,9 @@ package rbac
 
 import (
 	"context"
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -12,29 +14,66 @@ import (
 )
 
 type PolicyChecker struct {
-	db *sqlc.Queries
+	db    *sqlc.Queries
+	cache *PermissionCache // Shared permission cache; nil when caching is disabled
 }
 
 func NewPolicyChecker(db *sqlc.Queries) *PolicyChecker {
-	return &PolicyChecker{db: db}
+	return &PolicyChecker{db: db, cache: defaultCache}
 }
 
 // CheckPermission checks if a user has permission to perform an action on a table
 func (pc *PolicyChecker) CheckPermission(ctx context.Context, userID uuid.UUID, tableName, action string) (bool, []string, error) {
-	// Get user roles
-	roles, err := pc.db.GetUserRoles(ctx, userID)
+	hasPermission, allowedFields, _, err := pc.CheckPermissionWithFilter(ctx, userID, tableName, action)
+	return hasPermission, allowedFields, err
+}
+
+// CheckPermissionWithFilter checks a permission like CheckPermission and also returns the
+// compiled row filter (field_filter) of the matching permission. The filter is nil when the
+// permission applies to every row; admins are never filtered. Results are served from the
+// permission cache when it is enabled. Requests made with a scoped API key are denied
+// anything outside the key's scopes, whatever the user's roles allow.
+func (pc *PolicyChecker) CheckPermissionWithFilter(ctx context.Context, userID uuid.UUID, tableName, action string) (bool, []string, *RowFilter, error) {
+	allowed, allowedFields, rowFilter, _, err := pc.CheckPermissionWithTransforms(ctx, userID, tableName, action)
+	return allowed, allowedFields, rowFilter, err
+}
+
+// CheckPermissionWithTransforms checks a permission like CheckPermissionWithFilter and also
+// returns the field transforms (field_transforms) of a matching read permission, which
+// FilterFields applies to the rows read. Admins read every field in clear.
+func (pc *PolicyChecker) CheckPermissionWithTransforms(ctx context.Context, userID uuid.UUID, tableName, action string) (bool, []string, *RowFilter, FieldTransforms, error) {
+	if !ScopesAllow(ScopesFromContext(ctx), tableName, action) {
+		return false, nil, nil, nil, nil
+	}
+
+	key := permissionKey{userID: userID, table: tableName, action: action}
+	if tenantID, ok := ctx.Value("tenant_id").(uuid.UUID); ok {
+		key.tenantID = tenantID
+	}
+	if cached, ok := pc.cache.get(key); ok {
+		return cached.allowed, cached.allowedFields, cached.rowFilter, cached.transforms, nil
+	}
+
+	explanation, err := pc.checkPermission(ctx, userID, tableName, action)
 	if err != nil {
-		return false, nil, fmt.Errorf("failed to get user roles: %w", err)
+		return false, nil, nil, nil, err
+	}
+	result := permissionResult{
+		allowed:       explanation.Allowed,
+		allowedFields: explanation.Fields,
+		rowFilter:     explanation.RowFilter,
+		transforms:    explanation.Transforms,
 	}
 
-	// Check if user is admin (admin role bypasses all permission checks)
-	for _, role := range roles {
-		if role.Name == "admin" {
-			// Admin gets full access to everything
-			return true, []string{"*"}, nil
-		}
+	// Rules that use $NOW are compiled fresh on every check
+	if result.rowFilter == nil || !result.rowFilter.volatile {
+		pc.cache.set(key, result)
 	}
+	return result.allowed, result.allowedFields, result.rowFilter, result.transforms, nil
+}
 
+// checkPermission resolves a permission check against the database
+func (pc *PolicyChecker) checkPermission(ctx context.Context, userID uuid.UUID, tableName, action string) (*Explanation, error) {
 	// Get user's current tenant context from the request context
 	// This should be set by the auth middleware
 	var currentTenantID uuid.UUID
@@ -44,47 +83,106 @@ func (pc *PolicyChecker) CheckPermission(ctx context.Context, userID uuid.UUID,
 		// Fallback to user's default tenant if no current context
 		user, err := pc.db.GetUserByID(ctx, userID)
 		if err != nil {
-			return false, nil, fmt.Errorf("failed to get user: %w", err)
+			return nil, fmt.Errorf("failed to get user: %w", err)
 		}
-		if user.TenantID.Valid {
-			currentTenantID = user.TenantID.UUID
-		} else {
-			return false, nil, fmt.Errorf("no tenant context available")
+		currentTenantID = user.TenantID.UUID
+	}
+
+	explanation, err := pc.ExplainPermission(ctx, userID, currentTenantID, tableName, action)
+	if err != nil {
+		return nil, err
+	}
+	// A broken rule denies access rather than silently exposing every row or field
+	if explanation.RuleError != nil {
+		return nil, fmt.Errorf("invalid permission on %s/%s: %w", tableName, action, explanation.RuleError)
+	}
+	return explanation, nil
+}
+
+// Explanation tells how a permission check was decided
+type Explanation struct {
+	Allowed    bool
+	Admin      bool             // Allowed because the user holds the admin role
+	Roles      []sqlc.Role      // The user's roles in the tenant, in the order they were consulted
+	Role       *sqlc.Role       // The role whose permission matched, if any
+	Permission *sqlc.Permission // The matching permission, if any
+	Fields     []string         // Fields the permission grants (see PermittedFields)
+	RowFilter  *RowFilter       // Compiled row rule (see PermissionRule); nil when every row is allowed
+	Transforms FieldTransforms  // Redactions of the fields read (see PermittedTransforms)
+	RuleError  error            // Why the row rule or the field transforms are invalid; access is denied
+}
+
+// ExplainPermission resolves whether a user may perform an action on a table of a tenant,
+// reporting the role and permission that decided it. Unlike CheckPermissionWithFilter it
+// bypasses the permission cache and API key scopes.
+func (pc *PolicyChecker) ExplainPermission(ctx context.Context, userID, tenantID uuid.UUID, tableName, action string) (*Explanation, error) {
+	// Get the user's roles in that tenant, including the roles they inherit from
+	roles, err := pc.TenantRoles(ctx, userID, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	explanation := &Explanation{Roles: roles}
+
+	// Check if user is admin (admin role bypasses all permission checks)
+	for i, role := range roles {
+		if role.Name == "admin" {
+			// Admin gets full access to everything
+			explanation.Allowed = true
+			explanation.Admin = true
+			explanation.Role = &roles[i]
+			explanation.Fields = []string{"*"}
+			return explanation, nil
 		}
 	}
+	if tenantID == uuid.Nil {
+		return nil, fmt.Errorf("no tenant context available")
+	}
 
 	// Check permissions for each role with tenant isolation
-	for _, role := range roles {
+	for i, role := range roles {
 		// Check permissions for this role and current tenant
 		permissions, err := pc.db.GetPermissionsByRoleAndTenant(ctx, sqlc.GetPermissionsByRoleAndTenantParams{
 			RoleID:   uuid.NullUUID{UUID: role.ID, Valid: true},
-			TenantID: uuid.NullUUID{UUID: currentTenantID, Valid: true},
+			TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
 		})
 		if err != nil {
 			continue // Skip this role if there's an error
 		}
 
-		for _, permission := range permissions {
+		for j, permission := range permissions {
 			// Check if permission matches table and action
-			if permission.TableName == tableName && permission.Action == action {
-				allowedFields := permission.AllowedFields
-				if len(allowedFields) == 0 {
-					allowedFields = []string{"*"} // Default to all fields
+			if permission.TableName != tableName || permission.Action != action {
+				continue
+			}
+			explanation.Role = &roles[i]
+			explanation.Permission = &permissions[j]
+			if rule := PermissionRule(permission); rule != nil {
+				explanation.RowFilter, explanation.RuleError = CompileRowFilter(rule, RuleVars{UserID: userID, TenantID: tenantID})
+				if explanation.RuleError != nil {
+					explanation.RowFilter = nil
+					return explanation, nil
 				}
-				return true, allowedFields, nil
 			}
+			explanation.Transforms, explanation.RuleError = PermittedTransforms(permission)
+			if explanation.RuleError != nil {
+				explanation.RowFilter = nil
+				return explanation, nil
+			}
+			explanation.Allowed = true
+			explanation.Fields = PermittedFields(permission)
+			return explanation, nil
 		}
 	}
 
-	return false, nil, nil
+	return explanation, nil
 }
 
 // CheckPermissionWithTenant checks if a user has permission with explicit tenant context
 func (pc *PolicyChecker) CheckPermissionWithTenant(ctx context.Context, userID, tenantID uuid.UUID, tableName, action string) (bool, []string, error) {
-	// Get user roles
-	roles, err := pc.db.GetUserRoles(ctx, userID)
+	// Get the user's roles in the tenant, including the roles they inherit from
+	roles, err := pc.TenantRoles(ctx, userID, tenantID)
 	if err != nil {
-		return false, nil, fmt.Errorf("failed to get user roles: %w", err)
+		return false, nil, err
 	}
 
 	// Check permissions for each role with specific tenant
@@ -100,10 +198,7 @@ func (pc *PolicyChecker) CheckPermissionWithTenant(ctx context.Context, userID,
 		for _, permission := range permissions {
 			// Check if permission matches table and action
 			if permission.TableName == tableName && permission.Action == action {
-				allowedFields := permission.AllowedFields
-				if len(allowedFields) == 0 {
-					allowedFields = []string{"*"} // Default to all fields
-				}
+				allowedFields := PermittedFields(permission)
 				return true, allowedFields, nil
 			}
 		}
@@ -112,16 +207,207 @@ func (pc *PolicyChecker) CheckPermissionWithTenant(ctx context.Context, userID,
 	return false, nil, nil
 }
 
-// FilterFields filters the data based on allowed fields for the user
-func (pc *PolicyChecker) FilterFields(data map[string]interface{}, allowedFields []string) map[string]interface{} {
+// PublicRole is the role of a tenant whose permissions apply to requests made without
+// credentials
+const PublicRole = "public"
+
+// PublicUser is the user ID permissions are checked for on requests made without
+// credentials; it holds only the tenant's public role
+var PublicUser = uuid.Nil
+
+// TenantRoles returns the roles a user holds in a tenant (see GetUserTenantRoles) with
+// their inheritance chains. uuid.Nil yields only the user's global roles. PublicUser holds
+// the tenant's public role, if it has one.
+func (pc *PolicyChecker) TenantRoles(ctx context.Context, userID, tenantID uuid.UUID) ([]sqlc.Role, error) {
+	if userID == PublicUser {
+		return pc.publicRoles(ctx, tenantID)
+	}
+	roles, err := pc.db.GetUserTenantRoles(ctx, sqlc.GetUserTenantRolesParams{UserID: userID, TenantID: tenantID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user roles: %w", err)
+	}
+	return pc.ResolveRoles(ctx, roles)
+}
+
+// publicRoles returns the public role of a tenant with its inheritance chain
+func (pc *PolicyChecker) publicRoles(ctx context.Context, tenantID uuid.UUID) ([]sqlc.Role, error) {
+	if tenantID == uuid.Nil {
+		return nil, nil
+	}
+	role, err := pc.db.GetRoleByNameAndTenant(ctx, sqlc.GetRoleByNameAndTenantParams{
+		Name:     PublicRole,
+		TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public role: %w", err)
+	}
+	return pc.ResolveRoles(ctx, []sqlc.Role{role})
+}
+
+// ResolveRoles expands roles with their inheritance chains. Each role is followed by its
+// parent, grandparent and so on, so a role's own permissions are found before the ones it
+// inherits. Roles reachable through several paths are listed once. A cycle, or a parent
+// in another tenant, is reported as an error.
+func (pc *PolicyChecker) ResolveRoles(ctx context.Context, roles []sqlc.Role) ([]sqlc.Role, error) {
+	return resolveRoleChains(roles, func(id uuid.UUID) (sqlc.Role, error) {
+		return pc.db.GetRoleByID(ctx, id)
+	})
+}
+
+// resolveRoleChains implements ResolveRoles with a pluggable parent lookup
+func resolveRoleChains(roles []sqlc.Role, getRole func(id uuid.UUID) (sqlc.Role, error)) ([]sqlc.Role, error) {
+	resolved := make([]sqlc.Role, 0, len(roles))
+	seen := make(map[uuid.UUID]bool)
+
+	for _, role := range roles {
+		chain := map[uuid.UUID]bool{}
+		for current := role; ; {
+			if chain[current.ID] {
+				return nil, fmt.Errorf("role inheritance cycle at role %q (%s)", current.Name, current.ID)
+			}
+			chain[current.ID] = true
+
+			if !seen[current.ID] {
+				seen[current.ID] = true
+				resolved = append(resolved, current)
+			}
+
+			if !current.ParentID.Valid {
+				break
+			}
+			parent, err := getRole(current.ParentID.UUID)
+			if err != nil {
+				return nil, fmt.Errorf("failed to get parent of role %q: %w", current.Name, err)
+			}
+			if parent.TenantID != current.TenantID {
+				return nil, fmt.Errorf("role %q inherits from a role in another tenant", current.Name)
+			}
+			current = parent
+		}
+	}
+
+	return resolved, nil
+}
+
+// Permission scopes: a permission applies to every row its field_filter admits, or only to
+// the rows the user created
+const (
+	ScopeAll = "all"
+	ScopeOwn = "own"
+)
+
+// OwnerColumn holds the user who created a row of a collection
+const OwnerColumn = "created_by"
+
+// ValidScope reports whether scope is a permission scope
+func ValidScope(scope string) bool {
+	return scope == ScopeAll || scope == ScopeOwn
+}
+
+// PermissionRule returns the row rule a permission is enforced with: its field_filter and,
+// for reads, updates and deletes of an own-scoped permission, created_by = $CURRENT_USER.
+// It is nil when the permission applies to every row.
+func PermissionRule(permission sqlc.Permission) json.RawMessage {
+	var rule json.RawMessage
+	if permission.FieldFilter.Valid {
+		rule = permission.FieldFilter.RawMessage
+	}
+	if permission.Scope != ScopeOwn || permission.Action == "create" {
+		return rule
+	}
+
+	owner := json.RawMessage(`{"` + OwnerColumn + `": "` + VarCurrentUser + `"}`)
+	switch strings.TrimSpace(string(rule)) {
+	case "", "null", "{}":
+		return owner
+	}
+	return json.RawMessage(`{"_and": [` + string(rule) + `, ` + string(owner) + `]}`)
+}
+
+// PermittedFields returns the fields a permission grants access to. Reads use read_fields and
+// writes (create, update) use write_fields; when the specific list is not set, allowed_fields
+// applies to both, and a permission without any list grants every field.
+func PermittedFields(permission sqlc.Permission) []string {
+	fields := permission.AllowedFields
+	switch permission.Action {
+	case "read":
+		if len(permission.ReadFields) > 0 {
+			fields = permission.ReadFields
+		}
+	case "create", "update":
+		if len(permission.WriteFields) > 0 {
+			fields = permission.WriteFields
+		}
+	}
+
+	if len(fields) == 0 {
+		return []string{"*"} // Default to all fields
+	}
+	return fields
+}
+
+// PermittedTransforms returns the field transforms a permission reads its fields through.
+// They only apply to reads; writes are never transformed.
+func PermittedTransforms(permission sqlc.Permission) (FieldTransforms, error) {
+	if permission.Action != "read" || !permission.FieldTransforms.Valid {
+		return nil, nil
+	}
+	transforms, err := ParseFieldTransforms(permission.FieldTransforms.RawMessage)
+	if err != nil {
+		return nil, fmt.Errorf("field_transforms: %w", err)
+	}
+	return transforms, nil
+}
+
+// ProjectFields narrows allowedFields to the fields a client asked for (?fields=). Requested
+// fields the user may not read, and names that are not valid identifiers, are dropped, so
+// an empty result means none of them can be read. With no request allowedFields is
+// returned unchanged.
+func (pc *PolicyChecker) ProjectFields(allowedFields, requested []string) []string {
+	if requested == nil {
+		return allowedFields
+	}
+
+	allFields := len(allowedFields) == 0
+	for _, field := range allowedFields {
+		if field == "*" {
+			allFields = true
+		}
+	}
+
+	projected := make([]string, 0, len(requested))
+	for _, field := range requested {
+		if !ValidateTableName(field) {
+			continue
+		}
+		if allFields {
+			projected = append(projected, field)
+			continue
+		}
+		for _, allowed := range allowedFields {
+			if allowed == field {
+				projected = append(projected, field)
+				break
+			}
+		}
+	}
+	return projected
+}
+
+// FilterFields filters the data based on allowed fields for the user and redacts the
+// fields named in transforms (see FieldTransforms). Writes pass nil transforms.
+func (pc *PolicyChecker) FilterFields(data map[string]interface{}, allowedFields []string, transforms FieldTransforms) map[string]interface{} {
 	if len(allowedFields) == 0 {
-		return data
+		return transforms.Apply(data)
 	}
 
 	// Check if all fields are allowed
 	for _, field := range allowedFields {
 		if field == "*" {
-			return data // All fields allowed
+			return transforms.Apply(data) // All fields allowed
 		}
 	}
 
@@ -133,7 +419,7 @@ func (pc *PolicyChecker) FilterFields(data map[string]interface{}, allowedFields
 		}
 	}
 
-	return filtered
+	return transforms.Apply(filtered)
 }
 
 // FilterRecords applies row-level filtering based on field filters
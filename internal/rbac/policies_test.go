@@ -0,0 +1,197 @@
+package rbac
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/dbtest"
+
+	"github.com/google/uuid"
+	"github.com/sqlc-dev/pqtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPermittedFields(t *testing.T) {
+	tests := []struct {
+		name       string
+		permission sqlc.Permission
+		want       []string
+	}{
+		{"No Lists", sqlc.Permission{Action: "read"}, []string{"*"}},
+		{"Allowed Fields", sqlc.Permission{Action: "update", AllowedFields: []string{"name"}}, []string{"name"}},
+		{"Read Fields", sqlc.Permission{Action: "read", AllowedFields: []string{"name"}, ReadFields: []string{"name", "price"}, WriteFields: []string{"name"}}, []string{"name", "price"}},
+		{"Write Fields On Update", sqlc.Permission{Action: "update", ReadFields: []string{"name", "price"}, WriteFields: []string{"name"}}, []string{"name"}},
+		{"Write Fields On Create", sqlc.Permission{Action: "create", AllowedFields: []string{"*"}, WriteFields: []string{"name"}}, []string{"name"}},
+		{"Write Fields Ignored On Read", sqlc.Permission{Action: "read", WriteFields: []string{"name"}}, []string{"*"}},
+		{"Delete Uses Allowed Fields", sqlc.Permission{Action: "delete", AllowedFields: []string{"id"}, ReadFields: []string{"name"}}, []string{"id"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, PermittedFields(tt.permission))
+		})
+	}
+}
+
+func TestPermissionRule(t *testing.T) {
+	filter := pqtype.NullRawMessage{RawMessage: json.RawMessage(`{"status": "draft"}`), Valid: true}
+
+	assert.Nil(t, PermissionRule(sqlc.Permission{Action: "read", Scope: ScopeAll}))
+	assert.JSONEq(t, `{"status": "draft"}`, string(PermissionRule(sqlc.Permission{Action: "read", Scope: ScopeAll, FieldFilter: filter})))
+	assert.JSONEq(t, `{"created_by": "$CURRENT_USER"}`, string(PermissionRule(sqlc.Permission{Action: "update", Scope: ScopeOwn})))
+	assert.JSONEq(t, `{"_and": [{"status": "draft"}, {"created_by": "$CURRENT_USER"}]}`, string(PermissionRule(sqlc.Permission{Action: "delete", Scope: ScopeOwn, FieldFilter: filter})))
+	assert.Nil(t, PermissionRule(sqlc.Permission{Action: "create", Scope: ScopeOwn}), "new rows have no owner yet")
+
+	compiled, err := CompileRowFilter(PermissionRule(sqlc.Permission{Action: "read", Scope: ScopeOwn}), RuleVars{UserID: uuid.New()})
+	require.NoError(t, err)
+	condition, _ := compiled.SQL(1)
+	assert.Contains(t, condition, "created_by")
+}
+
+func TestFilterFields(t *testing.T) {
+	pc := NewPolicyChecker(nil)
+	data := map[string]interface{}{"name": "Widget", "price": 10}
+
+	assert.Equal(t, data, pc.FilterFields(data, []string{"*"}, nil))
+	assert.Equal(t, map[string]interface{}{"name": "Widget"}, pc.FilterFields(data, []string{"name", "sku"}, nil))
+
+	customer := map[string]interface{}{"email": "jane@example.com", "card": "4111111111111111", "note": nil}
+	transforms := FieldTransforms{"email": TransformMaskEmail, "card": TransformLast4, "note": TransformHash}
+	assert.Equal(t, map[string]interface{}{"email": "j***@example.com", "card": "************1111", "note": nil}, pc.FilterFields(customer, []string{"*"}, transforms))
+	assert.Equal(t, map[string]interface{}{"email": "j***@example.com"}, pc.FilterFields(customer, []string{"email"}, transforms), "transforms do not grant fields")
+	assert.Equal(t, "jane@example.com", customer["email"], "the input is left alone")
+}
+
+func TestPermittedTransforms(t *testing.T) {
+	transforms := pqtype.NullRawMessage{RawMessage: json.RawMessage(`{"email": "mask_email"}`), Valid: true}
+
+	got, err := PermittedTransforms(sqlc.Permission{Action: "read", FieldTransforms: transforms})
+	require.NoError(t, err)
+	assert.Equal(t, FieldTransforms{"email": TransformMaskEmail}, got)
+
+	got, err = PermittedTransforms(sqlc.Permission{Action: "update", FieldTransforms: transforms})
+	require.NoError(t, err)
+	assert.Nil(t, got, "writes are not transformed")
+
+	_, err = PermittedTransforms(sqlc.Permission{Action: "read", FieldTransforms: pqtype.NullRawMessage{RawMessage: json.RawMessage(`{"email": "rot13"}`), Valid: true}})
+	assert.ErrorContains(t, err, "unknown transform")
+}
+
+func TestProjectFields(t *testing.T) {
+	pc := NewPolicyChecker(nil)
+
+	assert.Equal(t, []string{"name", "sku"}, pc.ProjectFields([]string{"name", "sku"}, nil), "no projection")
+	assert.Equal(t, []string{"name", "price"}, pc.ProjectFields([]string{"*"}, []string{"name", "price"}))
+	assert.Equal(t, []string{"name"}, pc.ProjectFields([]string{"name", "sku"}, []string{"name", "cost"}))
+	assert.Equal(t, []string{"name"}, pc.ProjectFields(nil, []string{"name", `name"; DROP TABLE users; --`}))
+	assert.Empty(t, pc.ProjectFields([]string{"name"}, []string{"cost"}))
+}
+
+func TestResolveRoleChains(t *testing.T) {
+	tenantID := uuid.NullUUID{UUID: uuid.New(), Valid: true}
+	viewer := sqlc.Role{ID: uuid.New(), Name: "viewer", TenantID: tenantID}
+	editor := sqlc.Role{ID: uuid.New(), Name: "editor", TenantID: tenantID, ParentID: uuid.NullUUID{UUID: viewer.ID, Valid: true}}
+	manager := sqlc.Role{ID: uuid.New(), Name: "manager", TenantID: tenantID, ParentID: uuid.NullUUID{UUID: editor.ID, Valid: true}}
+	byID := map[uuid.UUID]sqlc.Role{viewer.ID: viewer, editor.ID: editor, manager.ID: manager}
+
+	getRole := func(id uuid.UUID) (sqlc.Role, error) {
+		role, ok := byID[id]
+		if !ok {
+			return sqlc.Role{}, sql.ErrNoRows
+		}
+		return role, nil
+	}
+
+	t.Run("Chain", func(t *testing.T) {
+		resolved, err := resolveRoleChains([]sqlc.Role{manager}, getRole)
+		require.NoError(t, err)
+		assert.Equal(t, []sqlc.Role{manager, editor, viewer}, resolved)
+	})
+
+	t.Run("Shared Ancestors Listed Once", func(t *testing.T) {
+		resolved, err := resolveRoleChains([]sqlc.Role{editor, manager}, getRole)
+		require.NoError(t, err)
+		assert.Equal(t, []sqlc.Role{editor, viewer, manager}, resolved)
+	})
+
+	t.Run("Cycle", func(t *testing.T) {
+		a := sqlc.Role{ID: uuid.New(), Name: "a", TenantID: tenantID}
+		b := sqlc.Role{ID: uuid.New(), Name: "b", TenantID: tenantID, ParentID: uuid.NullUUID{UUID: a.ID, Valid: true}}
+		a.ParentID = uuid.NullUUID{UUID: b.ID, Valid: true}
+		byID[a.ID], byID[b.ID] = a, b
+
+		_, err := resolveRoleChains([]sqlc.Role{a}, getRole)
+		assert.ErrorContains(t, err, "cycle")
+	})
+
+	t.Run("Other Tenant", func(t *testing.T) {
+		foreign := sqlc.Role{ID: uuid.New(), Name: "foreign", TenantID: uuid.NullUUID{UUID: uuid.New(), Valid: true}}
+		byID[foreign.ID] = foreign
+		child := sqlc.Role{ID: uuid.New(), Name: "child", TenantID: tenantID, ParentID: uuid.NullUUID{UUID: foreign.ID, Valid: true}}
+
+		_, err := resolveRoleChains([]sqlc.Role{child}, getRole)
+		assert.ErrorContains(t, err, "another tenant")
+	})
+
+	t.Run("Missing Parent", func(t *testing.T) {
+		orphan := sqlc.Role{ID: uuid.New(), Name: "orphan", TenantID: tenantID, ParentID: uuid.NullUUID{UUID: uuid.New(), Valid: true}}
+		_, err := resolveRoleChains([]sqlc.Role{orphan}, getRole)
+		assert.Error(t, err)
+	})
+}
+
+func TestTenantRoles(t *testing.T) {
+	database := dbtest.SQLite(t)
+	ctx := context.Background()
+	pc := NewPolicyChecker(database.Queries)
+
+	// The seeded tenant and its admin role, and a second tenant with an editor role
+	tenantA := uuid.MustParse("6e68062f-c4c6-42df-9e01-e2d1081664f4")
+	adminA := uuid.MustParse("550e8400-e29b-41d4-a716-446655440001")
+	tenantB, err := database.Queries.CreateTenant(ctx, sqlc.CreateTenantParams{ID: uuid.New(), Name: "Other", Slug: "other"})
+	require.NoError(t, err)
+	editorB, err := database.Queries.CreateRole(ctx, sqlc.CreateRoleParams{ID: uuid.New(), Name: "editor", TenantID: uuid.NullUUID{UUID: tenantB.ID, Valid: true}})
+	require.NoError(t, err)
+
+	user, err := database.Queries.CreateUser(ctx, sqlc.CreateUserParams{ID: uuid.New(), Email: "member@example.com", PasswordHash: "x", TenantID: uuid.NullUUID{UUID: tenantA, Valid: true}})
+	require.NoError(t, err)
+	require.NoError(t, database.Queries.AddUserToTenant(ctx, sqlc.AddUserToTenantParams{UserID: user.ID, TenantID: tenantA, RoleID: uuid.NullUUID{UUID: adminA, Valid: true}}))
+	require.NoError(t, database.Queries.AddUserToTenant(ctx, sqlc.AddUserToTenantParams{UserID: user.ID, TenantID: tenantB.ID, RoleID: uuid.NullUUID{UUID: editorB.ID, Valid: true}}))
+
+	roleNames := func(tenantID uuid.UUID) []string {
+		roles, err := pc.TenantRoles(ctx, user.ID, tenantID)
+		require.NoError(t, err)
+		var names []string
+		for _, role := range roles {
+			names = append(names, role.Name)
+		}
+		return names
+	}
+
+	t.Run("Roles Resolve Per Acting Tenant", func(t *testing.T) {
+		assert.Equal(t, []string{"admin"}, roleNames(tenantA))
+		assert.Equal(t, []string{"editor"}, roleNames(tenantB.ID))
+		assert.Empty(t, roleNames(uuid.New()), "no membership, no roles")
+	})
+
+	t.Run("Admin Of Another Tenant Grants Nothing", func(t *testing.T) {
+		allowed, fields, err := pc.CheckPermission(context.WithValue(ctx, "tenant_id", tenantA), user.ID, "products", "delete")
+		require.NoError(t, err)
+		assert.True(t, allowed)
+		assert.Equal(t, []string{"*"}, fields)
+
+		allowed, _, err = pc.CheckPermission(context.WithValue(ctx, "tenant_id", tenantB.ID), user.ID, "products", "delete")
+		require.NoError(t, err)
+		assert.False(t, allowed, "the admin membership of tenant A does not apply in tenant B")
+	})
+
+	t.Run("Global Roles Of Another Tenant Are Ignored", func(t *testing.T) {
+		// A user_roles grant of tenant A's admin role only counts in tenant A
+		require.NoError(t, database.Queries.AddUserRole(ctx, sqlc.AddUserRoleParams{UserID: user.ID, RoleID: adminA}))
+		assert.Equal(t, []string{"editor"}, roleNames(tenantB.ID))
+	})
+}
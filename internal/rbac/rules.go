@@ -0,0 +1,475 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Row-level permission rules
+//
+// A permission's field_filter limits the rows the permission applies to. It is a JSON
+// object whose column rules must all hold:
+//
+//	{"created_by": "$CURRENT_USER"}
+//	{"status": {"_eq": "published"}, "priority": {"_gte": 3}}
+//	{"_or": [{"owner_id": "$CURRENT_USER"}, {"public": true}]}
+//
+// A bare value is shorthand for _eq. Column operators are _eq, _neq, _in, _nin, _gt, _gte,
+// _lt, _lte, _null and _nnull, plus _contains and _overlaps for arrays (see arrays.go) and
+// the spatial _within_radius and _intersects (see spatial.go); _and and _or take an array
+// of nested rules. The variables $CURRENT_USER, $CURRENT_TENANT and $NOW are replaced with
+// the caller's user ID, the tenant ID and the current time when the rule is compiled.
+
+// Rule variables that may appear as values in a field_filter
+const (
+	VarCurrentUser   = "$CURRENT_USER"
+	VarCurrentTenant = "$CURRENT_TENANT"
+	VarNow           = "$NOW"
+)
+
+// comparisonOperators maps rule operators to their SQL operator
+var comparisonOperators = map[string]string{
+	"_eq":  "=",
+	"_neq": "<>",
+	"_gt":  ">",
+	"_gte": ">=",
+	"_lt":  "<",
+	"_lte": "<=",
+}
+
+// RuleVars holds the values substituted for rule variables
+type RuleVars struct {
+	UserID   uuid.UUID
+	TenantID uuid.UUID
+	Now      time.Time
+
+	policy bool // Leave variables to the database (CompilePolicyRule)
+}
+
+// sessionVariable is a rule variable left for the database to resolve, in rules compiled
+// with CompilePolicyRule
+type sessionVariable string
+
+// policyVariables are the SQL expressions rule variables stand for in row-level security
+// policies: the settings of set_user_context and the statement time
+var policyVariables = map[string]sessionVariable{
+	VarCurrentUser:   "NULLIF(current_setting('app.current_user_id', true), '')::uuid",
+	VarCurrentTenant: "NULLIF(current_setting('app.current_tenant_id', true), '')::uuid",
+	VarNow:           "now()",
+}
+
+// ruleNode is one compiled condition: a group (_and/_or) of children or a column comparison
+type ruleNode struct {
+	op       string
+	column   string
+	values   []interface{}
+	children []ruleNode
+}
+
+// RowFilter is a compiled field_filter. A nil RowFilter matches every row.
+type RowFilter struct {
+	root     ruleNode
+	volatile bool // Depends on the time of compilation ($NOW)
+}
+
+// CompileRowFilter parses a field_filter and substitutes rule variables. An empty or null
+// filter compiles to nil (no restriction).
+func CompileRowFilter(raw json.RawMessage, vars RuleVars) (*RowFilter, error) {
+	trimmed := strings.TrimSpace(string(raw))
+	if trimmed == "" || trimmed == "null" || trimmed == "{}" {
+		return nil, nil
+	}
+
+	var rule map[string]interface{}
+	if err := json.Unmarshal(raw, &rule); err != nil {
+		return nil, fmt.Errorf("field filter must be a JSON object: %w", err)
+	}
+
+	root, err := compileGroup("_and", rule, vars)
+	if err != nil {
+		return nil, err
+	}
+	return &RowFilter{root: root, volatile: strings.Contains(trimmed, VarNow)}, nil
+}
+
+// CompilePolicyRule compiles a field_filter for a row-level security policy. Rule variables
+// are left to the database, as the settings of set_user_context and now(), so the result
+// holds for every user; render it with PolicySQL.
+func CompilePolicyRule(raw json.RawMessage) (*RowFilter, error) {
+	return CompileRowFilter(raw, RuleVars{policy: true})
+}
+
+// compileGroup compiles the column rules of one filter object, combined with op
+func compileGroup(op string, rule map[string]interface{}, vars RuleVars) (ruleNode, error) {
+	// Sort keys so the generated SQL is stable
+	keys := make([]string, 0, len(rule))
+	for key := range rule {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	group := ruleNode{op: op}
+	for _, key := range keys {
+		value := rule[key]
+
+		if key == "_and" || key == "_or" {
+			list, ok := value.([]interface{})
+			if !ok || len(list) == 0 {
+				return ruleNode{}, fmt.Errorf("%s must be a non-empty array of rules", key)
+			}
+			nested := ruleNode{op: key}
+			for _, item := range list {
+				object, ok := item.(map[string]interface{})
+				if !ok {
+					return ruleNode{}, fmt.Errorf("%s must be a non-empty array of rules", key)
+				}
+				child, err := compileGroup("_and", object, vars)
+				if err != nil {
+					return ruleNode{}, err
+				}
+				nested.children = append(nested.children, child)
+			}
+			group.children = append(group.children, nested)
+			continue
+		}
+
+		if !ValidateTableName(key) || key == "" {
+			return ruleNode{}, fmt.Errorf("invalid column name '%s' in field filter", key)
+		}
+
+		conditions, ok := value.(map[string]interface{})
+		if !ok {
+			// A bare value is shorthand for _eq
+			conditions = map[string]interface{}{"_eq": value}
+		}
+
+		operators := make([]string, 0, len(conditions))
+		for operator := range conditions {
+			operators = append(operators, operator)
+		}
+		sort.Strings(operators)
+
+		for _, operator := range operators {
+			node, err := compileComparison(key, operator, conditions[operator], vars)
+			if err != nil {
+				return ruleNode{}, err
+			}
+			group.children = append(group.children, node)
+		}
+	}
+
+	return group, nil
+}
+
+// compileComparison compiles one column operator
+func compileComparison(column, operator string, value interface{}, vars RuleVars) (ruleNode, error) {
+	node := ruleNode{op: operator, column: column}
+
+	switch operator {
+	case "_null", "_nnull":
+		if _, ok := value.(bool); !ok {
+			return ruleNode{}, fmt.Errorf("%s on '%s' expects true or false", operator, column)
+		}
+		// {"_null": false} is the same as {"_nnull": true}
+		if value == false {
+			node.op = map[string]string{"_null": "_nnull", "_nnull": "_null"}[operator]
+		}
+	case "_contains", "_overlaps":
+		return compileArray(column, operator, value, vars)
+	case "_within_radius", "_intersects":
+		return compileSpatial(column, operator, value)
+	case "_in", "_nin":
+		list, ok := value.([]interface{})
+		if !ok {
+			return ruleNode{}, fmt.Errorf("%s on '%s' expects an array", operator, column)
+		}
+		for _, item := range list {
+			resolved, err := resolveRuleValue(item, vars)
+			if err != nil {
+				return ruleNode{}, err
+			}
+			node.values = append(node.values, resolved)
+		}
+	default:
+		if _, ok := comparisonOperators[operator]; !ok {
+			return ruleNode{}, fmt.Errorf("unsupported operator '%s' in field filter", operator)
+		}
+		resolved, err := resolveRuleValue(value, vars)
+		if err != nil {
+			return ruleNode{}, err
+		}
+		if resolved == nil {
+			// Comparisons with NULL never match; spell out the intent instead
+			return ruleNode{}, fmt.Errorf("%s on '%s' cannot compare with null, use _null", operator, column)
+		}
+		node.values = []interface{}{resolved}
+	}
+
+	return node, nil
+}
+
+// resolveRuleValue substitutes rule variables and rejects values that cannot be bound
+func resolveRuleValue(value interface{}, vars RuleVars) (interface{}, error) {
+	switch v := value.(type) {
+	case string:
+		if expression, ok := policyVariables[v]; ok && vars.policy {
+			return expression, nil
+		}
+		switch v {
+		case VarCurrentUser:
+			return vars.UserID.String(), nil
+		case VarCurrentTenant:
+			return vars.TenantID.String(), nil
+		case VarNow:
+			now := vars.Now
+			if now.IsZero() {
+				now = time.Now()
+			}
+			return now.UTC().Format(time.RFC3339Nano), nil
+		}
+		return v, nil
+	case float64, bool, nil:
+		return v, nil
+	default:
+		return nil, fmt.Errorf("unsupported value %v in field filter", value)
+	}
+}
+
+// Volatile reports whether the filter depends on the time it was compiled at ($NOW), so
+// its outcome must not be reused later. A nil filter is not volatile.
+func (f *RowFilter) Volatile() bool {
+	return f != nil && f.volatile
+}
+
+// Columns returns the columns the filter compares, sorted and without duplicates. A nil
+// filter has none.
+func (f *RowFilter) Columns() []string {
+	if f == nil {
+		return nil
+	}
+
+	seen := make(map[string]bool)
+	var columns []string
+	var walk func(n ruleNode)
+	walk = func(n ruleNode) {
+		if n.column != "" && !seen[n.column] {
+			seen[n.column] = true
+			columns = append(columns, n.column)
+		}
+		for _, child := range n.children {
+			walk(child)
+		}
+	}
+	walk(f.root)
+	sort.Strings(columns)
+	return columns
+}
+
+// SQL returns the filter as a WHERE condition with placeholders numbered from paramIndex,
+// along with the values to bind. Conditions that combine several rules are parenthesised,
+// so the result can be joined with AND as is. A nil filter returns an empty condition.
+func (f *RowFilter) SQL(paramIndex int) (string, []interface{}) {
+	if f == nil {
+		return "", nil
+	}
+
+	var args []interface{}
+	condition := f.root.sql(func(value interface{}) string {
+		args = append(args, value)
+		paramIndex++
+		return fmt.Sprintf("$%d", paramIndex-1)
+	})
+	return condition, args
+}
+
+// PolicySQL returns the filter as a condition with its values written out as SQL literals,
+// for places where nothing can be bound, such as a policy definition. Values are quoted
+// like bound parameters, so the column decides their type. A nil filter returns TRUE.
+func (f *RowFilter) PolicySQL() string {
+	if f == nil {
+		return "TRUE"
+	}
+	return f.root.sql(sqlLiteral)
+}
+
+// sqlLiteral writes a rule value as an untyped SQL literal, or the expression of a rule
+// variable left to the database
+func sqlLiteral(value interface{}) string {
+	if expression, ok := value.(sessionVariable); ok {
+		return string(expression)
+	}
+	return "'" + strings.ReplaceAll(valueString(value), "'", "''") + "'"
+}
+
+// sql renders the node, writing values with placeholder
+func (n ruleNode) sql(placeholder func(value interface{}) string) string {
+	switch n.op {
+	case "_and", "_or":
+		if len(n.children) == 0 {
+			return "TRUE"
+		}
+		if len(n.children) == 1 {
+			return n.children[0].sql(placeholder)
+		}
+		parts := make([]string, len(n.children))
+		for i, child := range n.children {
+			parts[i] = child.sql(placeholder)
+		}
+		joiner := " AND "
+		if n.op == "_or" {
+			joiner = " OR "
+		}
+		return "(" + strings.Join(parts, joiner) + ")"
+	case "_contains", "_overlaps":
+		return n.arraySQL(placeholder)
+	case "_within_radius", "_intersects":
+		return n.spatialSQL(placeholder)
+	case "_null":
+		return fmt.Sprintf(`"%s" IS NULL`, n.column)
+	case "_nnull":
+		return fmt.Sprintf(`"%s" IS NOT NULL`, n.column)
+	case "_in", "_nin":
+		if len(n.values) == 0 {
+			// Nothing is in an empty list
+			if n.op == "_in" {
+				return "FALSE"
+			}
+			return "TRUE"
+		}
+		placeholders := make([]string, len(n.values))
+		for i, value := range n.values {
+			placeholders[i] = placeholder(value)
+		}
+		operator := "IN"
+		if n.op == "_nin" {
+			operator = "NOT IN"
+		}
+		return fmt.Sprintf(`"%s" %s (%s)`, n.column, operator, strings.Join(placeholders, ", "))
+	default:
+		return fmt.Sprintf(`"%s" %s %s`, n.column, comparisonOperators[n.op], placeholder(n.values[0]))
+	}
+}
+
+// Matches evaluates the filter against an in-memory record. Values are compared by their
+// string form, and numerically for range operators when both sides are numbers. A column
+// the record does not contain fails the rule, so partial records never match by accident.
+func (f *RowFilter) Matches(record map[string]interface{}) bool {
+	if f == nil {
+		return true
+	}
+	return f.root.matches(record)
+}
+
+// matches evaluates the node against record
+func (n ruleNode) matches(record map[string]interface{}) bool {
+	switch n.op {
+	case "_and":
+		for _, child := range n.children {
+			if !child.matches(record) {
+				return false
+			}
+		}
+		return true
+	case "_or":
+		for _, child := range n.children {
+			if child.matches(record) {
+				return true
+			}
+		}
+		return false
+	}
+
+	value, exists := record[n.column]
+	if !exists {
+		return false
+	}
+
+	switch n.op {
+	case "_null":
+		return value == nil
+	case "_nnull":
+		return value != nil
+	}
+	if value == nil {
+		return false
+	}
+
+	switch n.op {
+	case "_contains", "_overlaps":
+		return n.arrayMatches(value)
+	case "_within_radius", "_intersects":
+		return n.spatialMatches(value)
+	case "_in", "_nin":
+		found := false
+		for _, candidate := range n.values {
+			if candidate != nil && compareValues(value, candidate) == 0 {
+				found = true
+				break
+			}
+		}
+		return found == (n.op == "_in")
+	case "_eq":
+		return compareValues(value, n.values[0]) == 0
+	case "_neq":
+		return compareValues(value, n.values[0]) != 0
+	case "_gt":
+		return compareValues(value, n.values[0]) > 0
+	case "_gte":
+		return compareValues(value, n.values[0]) >= 0
+	case "_lt":
+		return compareValues(value, n.values[0]) < 0
+	case "_lte":
+		return compareValues(value, n.values[0]) <= 0
+	}
+	return false
+}
+
+// compareValues orders two non-nil values, numerically when both are numbers
+func compareValues(a, b interface{}) int {
+	as, bs := valueString(a), valueString(b)
+	if af, err := strconv.ParseFloat(as, 64); err == nil {
+		if bf, err := strconv.ParseFloat(bs, 64); err == nil {
+			switch {
+			case af < bf:
+				return -1
+			case af > bf:
+				return 1
+			}
+			return 0
+		}
+	}
+	return strings.Compare(as, bs)
+}
+
+// valueString formats a record or rule value for comparison
+func valueString(value interface{}) string {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC().Format(time.RFC3339Nano)
+	}
+	return fmt.Sprint(value)
+}
+
+// rowFilterKey is the context key under which row filters are stored
+type rowFilterKey struct{ table string }
+
+// WithRowFilter returns a context carrying the row filter for table, so that data access
+// further down the call chain can add it to its queries
+func WithRowFilter(ctx context.Context, table string, filter *RowFilter) context.Context {
+	if filter == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, rowFilterKey{table: table}, filter)
+}
+
+// RowFilterFromContext returns the row filter stored for table, or nil
+func RowFilterFromContext(ctx context.Context, table string) *RowFilter {
+	filter, _ := ctx.Value(rowFilterKey{table: table}).(*RowFilter)
+	return filter
+}
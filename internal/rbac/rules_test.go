@@ -0,0 +1,145 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCompileRowFilter_Empty(t *testing.T) {
+	for _, raw := range []string{"", "null", "{}"} {
+		filter, err := CompileRowFilter(json.RawMessage(raw), RuleVars{})
+		require.NoError(t, err)
+		assert.Nil(t, filter)
+
+		condition, args := filter.SQL(1)
+		assert.Empty(t, condition)
+		assert.Nil(t, args)
+		assert.True(t, filter.Matches(map[string]interface{}{}))
+	}
+}
+
+func TestRowFilterSQL(t *testing.T) {
+	userID := uuid.New()
+	tenantID := uuid.New()
+	vars := RuleVars{UserID: userID, TenantID: tenantID, Now: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)}
+
+	tests := []struct {
+		name      string
+		filter    string
+		paramFrom int
+		want      string
+		wantArgs  []interface{}
+	}{
+		{"Current User", `{"created_by": "$CURRENT_USER"}`, 1, `"created_by" = $1`, []interface{}{userID.String()}},
+		{"Operator", `{"status": {"_eq": "published"}}`, 3, `"status" = $3`, []interface{}{"published"}},
+		{"Several Columns", `{"status": {"_neq": "draft"}, "priority": {"_gte": 3}}`, 1, `("priority" >= $1 AND "status" <> $2)`, []interface{}{float64(3), "draft"}},
+		{"In", `{"tenant": {"_in": ["$CURRENT_TENANT", "shared"]}}`, 1, `"tenant" IN ($1, $2)`, []interface{}{tenantID.String(), "shared"}},
+		{"Empty In", `{"status": {"_in": []}}`, 1, `FALSE`, nil},
+		{"Null", `{"archived_at": {"_null": true}, "owner": {"_null": false}}`, 1, `("archived_at" IS NULL AND "owner" IS NOT NULL)`, nil},
+		{"Now", `{"publish_at": {"_lte": "$NOW"}}`, 1, `"publish_at" <= $1`, []interface{}{"2024-01-02T03:04:05Z"}},
+		{"Or", `{"_or": [{"created_by": "$CURRENT_USER"}, {"public": true}]}`, 2, `("created_by" = $2 OR "public" = $3)`, []interface{}{userID.String(), true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := CompileRowFilter(json.RawMessage(tt.filter), vars)
+			require.NoError(t, err)
+
+			condition, args := filter.SQL(tt.paramFrom)
+			assert.Equal(t, tt.want, condition)
+			assert.Equal(t, tt.wantArgs, args)
+		})
+	}
+}
+
+func TestRowFilterPolicySQL(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		want   string
+	}{
+		{"Empty", `{}`, `TRUE`},
+		{"Current User", `{"created_by": "$CURRENT_USER"}`, `"created_by" = NULLIF(current_setting('app.current_user_id', true), '')::uuid`},
+		{"Quoted", `{"title": "it's", "priority": {"_gte": 3}}`, `("priority" >= '3' AND "title" = 'it''s')`},
+		{"Or", `{"_or": [{"public": true}, {"expires_at": {"_gt": "$NOW"}}]}`, `("public" = 'true' OR "expires_at" > now())`},
+		{"In", `{"status": {"_in": ["draft", "$CURRENT_TENANT"]}}`, `"status" IN ('draft', NULLIF(current_setting('app.current_tenant_id', true), '')::uuid)`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filter, err := CompilePolicyRule(json.RawMessage(tt.filter))
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, filter.PolicySQL())
+		})
+	}
+}
+
+func TestCompileRowFilter_Invalid(t *testing.T) {
+	for _, raw := range []string{
+		`[1, 2]`,
+		`{"bad column": 1}`,
+		`{"status": {"_like": "a%"}}`,
+		`{"status": {"_eq": null}}`,
+		`{"status": {"_in": "published"}}`,
+		`{"status": {"_null": "yes"}}`,
+		`{"_or": []}`,
+		`{"_and": [1]}`,
+		`{"meta": {"_eq": {"nested": true}}}`,
+	} {
+		_, err := CompileRowFilter(json.RawMessage(raw), RuleVars{})
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestRowFilterMatches(t *testing.T) {
+	userID := uuid.New()
+	filter, err := CompileRowFilter(json.RawMessage(`{
+		"_or": [{"created_by": "$CURRENT_USER"}, {"status": {"_in": ["published", "archived"]}}],
+		"priority": {"_gt": 2}
+	}`), RuleVars{UserID: userID})
+	require.NoError(t, err)
+
+	tests := []struct {
+		name   string
+		record map[string]interface{}
+		want   bool
+	}{
+		{"Own Item", map[string]interface{}{"created_by": userID, "status": "draft", "priority": 3}, true},
+		{"Published", map[string]interface{}{"created_by": uuid.New(), "status": "published", "priority": json.Number("5")}, true},
+		{"Other Draft", map[string]interface{}{"created_by": uuid.New(), "status": "draft", "priority": 3}, false},
+		{"Low Priority", map[string]interface{}{"created_by": userID, "priority": 2.0}, false},
+		{"Missing Column", map[string]interface{}{"created_by": userID}, false},
+		{"Null Value", map[string]interface{}{"created_by": userID, "priority": nil}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, filter.Matches(tt.record))
+		})
+	}
+}
+
+func TestRowFilterContext(t *testing.T) {
+	filter, err := CompileRowFilter(json.RawMessage(`{"status": "published"}`), RuleVars{})
+	require.NoError(t, err)
+
+	ctx := WithRowFilter(context.Background(), "posts", filter)
+	assert.Same(t, filter, RowFilterFromContext(ctx, "posts"))
+	assert.Nil(t, RowFilterFromContext(ctx, "comments"))
+	assert.Nil(t, RowFilterFromContext(context.Background(), "posts"))
+}
+
+func TestRowFilterColumns(t *testing.T) {
+	filter, err := CompileRowFilter(json.RawMessage(`{"status": "published", "_or": [{"owner": "$CURRENT_USER"}, {"status": {"_null": true}}]}`), RuleVars{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"owner", "status"}, filter.Columns())
+
+	var none *RowFilter
+	assert.Nil(t, none.Columns())
+}
@@ -0,0 +1,131 @@
+package rbac
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// API key scopes
+//
+// A scope is a "table:action" string such as products:read or orders:create. Either part
+// may be "*", so products:* allows every action on products and *:read allows reading
+// every table. Scopes never grant anything by themselves: a scoped key can do what its
+// scopes allow AND its user's roles permit. A key without scopes has the full power of
+// its user.
+
+// ScopeWildcard matches any table or action in a scope
+const ScopeWildcard = "*"
+
+// scopeActions are the actions a scope can name
+var scopeActions = map[string]bool{
+	"create":      true,
+	"read":        true,
+	"update":      true,
+	"delete":      true,
+	"decrypt":     true,
+	ScopeWildcard: true,
+}
+
+// ParseScope splits a scope into its table and action, validating both
+func ParseScope(scope string) (string, string, error) {
+	table, action, found := strings.Cut(strings.TrimSpace(scope), ":")
+	if !found {
+		return "", "", fmt.Errorf("invalid scope '%s': expected table:action", scope)
+	}
+	if table != ScopeWildcard && (table == "" || !ValidateTableName(table)) {
+		return "", "", fmt.Errorf("invalid scope '%s': invalid table name", scope)
+	}
+	if !scopeActions[action] {
+		return "", "", fmt.Errorf("invalid scope '%s': action must be create, read, update, delete, decrypt or *", scope)
+	}
+	return table, action, nil
+}
+
+// NormalizeScopes validates a list of scopes and returns them trimmed and de-duplicated
+func NormalizeScopes(scopes []string) ([]string, error) {
+	normalized := make([]string, 0, len(scopes))
+	seen := make(map[string]bool, len(scopes))
+	for _, scope := range scopes {
+		table, action, err := ParseScope(scope)
+		if err != nil {
+			return nil, err
+		}
+		scope = table + ":" + action
+		if !seen[scope] {
+			seen[scope] = true
+			normalized = append(normalized, scope)
+		}
+	}
+	return normalized, nil
+}
+
+// ScopesAllow reports whether scopes permit action on table. An empty list allows
+// everything.
+func ScopesAllow(scopes []string, table, action string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+	for _, scope := range scopes {
+		scopeTable, scopeAction, err := ParseScope(scope)
+		if err != nil {
+			continue
+		}
+		if (scopeTable == ScopeWildcard || scopeTable == table) && (scopeAction == ScopeWildcard || scopeAction == action) {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopesCover reports whether every scope in requested is allowed by granted, so a scoped
+// key cannot hand out more than it holds. An empty granted list covers everything; an
+// empty requested list (unrestricted) is only covered by an empty granted list.
+func ScopesCover(granted, requested []string) bool {
+	if len(granted) == 0 {
+		return true
+	}
+	if len(requested) == 0 {
+		return false
+	}
+	for _, scope := range requested {
+		table, action, err := ParseScope(scope)
+		if err != nil {
+			return false
+		}
+		covered := false
+		for _, g := range granted {
+			grantedTable, grantedAction, err := ParseScope(g)
+			if err != nil {
+				continue
+			}
+			if (grantedTable == ScopeWildcard || grantedTable == table) && (grantedAction == ScopeWildcard || grantedAction == action) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			return false
+		}
+	}
+	return true
+}
+
+// scopesKey is the context key under which API key scopes are stored
+type scopesKey struct{}
+
+// WithScopes returns a context carrying the scopes of the API key making the request.
+// Permission checks made with the context are limited to those scopes.
+func WithScopes(ctx context.Context, scopes []string) context.Context {
+	if len(scopes) == 0 {
+		return ctx
+	}
+	return context.WithValue(ctx, scopesKey{}, scopes)
+}
+
+// ScopesFromContext returns the scopes stored in ctx, or nil when the request is not
+// limited by scopes
+func ScopesFromContext(ctx context.Context) []string {
+	scopes, _ := ctx.Value(scopesKey{}).([]string)
+	return scopes
+}
@@ -0,0 +1,58 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeScopes(t *testing.T) {
+	scopes, err := NormalizeScopes([]string{" products:read", "orders:*", "products:read", "*:read"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"products:read", "orders:*", "*:read"}, scopes)
+
+	for _, invalid := range []string{"products", "products:write", ":read", "bad-table:read", "products:"} {
+		_, err := NormalizeScopes([]string{invalid})
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestScopesAllow(t *testing.T) {
+	scopes := []string{"products:read", "orders:*", "*:create"}
+
+	assert.True(t, ScopesAllow(scopes, "products", "read"))
+	assert.False(t, ScopesAllow(scopes, "products", "update"))
+	assert.True(t, ScopesAllow(scopes, "orders", "delete"))
+	assert.True(t, ScopesAllow(scopes, "customers", "create"))
+	assert.False(t, ScopesAllow(scopes, "customers", "read"))
+
+	// No scopes means the key is not restricted
+	assert.True(t, ScopesAllow(nil, "customers", "delete"))
+}
+
+func TestScopesCover(t *testing.T) {
+	granted := []string{"products:*", "orders:read"}
+
+	assert.True(t, ScopesCover(granted, []string{"products:update", "orders:read"}))
+	assert.False(t, ScopesCover(granted, []string{"orders:update"}))
+	assert.False(t, ScopesCover(granted, []string{"*:read"}))
+	assert.False(t, ScopesCover(granted, nil), "a scoped key cannot create an unrestricted one")
+	assert.True(t, ScopesCover(nil, nil))
+}
+
+func TestCheckPermission_OutOfScope(t *testing.T) {
+	// The scope check runs before any role is loaded
+	pc := NewPolicyChecker(nil)
+	ctx := WithScopes(context.Background(), []string{"products:read"})
+
+	allowed, fields, err := pc.CheckPermission(ctx, uuid.New(), "orders", "read")
+	require.NoError(t, err)
+	assert.False(t, allowed)
+	assert.Nil(t, fields)
+
+	assert.Equal(t, []string{"products:read"}, ScopesFromContext(ctx))
+	assert.Nil(t, ScopesFromContext(context.Background()))
+}
@@ -0,0 +1,228 @@
+package rbac
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Spatial rules
+//
+// Geo fields hold GeoJSON geometries. Two operators compare them:
+//
+//	{"location": {"_within_radius": {"lat": 52.52, "lng": 13.405, "radius": 1000}}}
+//	{"area": {"_intersects": {"type": "Polygon", "coordinates": [[[13.3, 52.5], ...]]}}}
+//
+// _within_radius matches geometries within radius meters of a point and _intersects
+// geometries sharing at least one point with the given one. With the PostGIS extension
+// (UsePostGIS) both are answered by PostGIS on the geography of the values. Without it,
+// _within_radius measures the great-circle distance to points and matches no other
+// geometry, and _intersects is refused.
+
+// earthRadiusMeters is the mean radius of the Earth used for distances without PostGIS
+const earthRadiusMeters = 6371008.8
+
+// postGIS tells whether spatial rules are rendered for PostGIS
+var postGIS bool
+
+// UsePostGIS tells whether the database has the PostGIS extension, which decides how
+// spatial rules compiled afterwards are answered. Call it once at startup.
+func UsePostGIS(enabled bool) {
+	postGIS = enabled
+}
+
+// PostGIS reports whether spatial rules are answered by PostGIS
+func PostGIS() bool {
+	return postGIS
+}
+
+// SpatialExpression returns the PostGIS expression spatial rules compare column with, so
+// that an index on it serves them
+func SpatialExpression(column string) string {
+	return fmt.Sprintf(`(ST_GeomFromGeoJSON("%s")::geography)`, column)
+}
+
+// compileSpatial compiles a _within_radius or _intersects operator
+func compileSpatial(column, operator string, value interface{}) (ruleNode, error) {
+	node := ruleNode{op: operator, column: column}
+
+	if operator == "_intersects" {
+		if !postGIS {
+			return ruleNode{}, fmt.Errorf("_intersects on '%s' needs the PostGIS extension", column)
+		}
+		geometry, ok := value.(map[string]interface{})
+		if _, typed := geometry["type"].(string); !ok || !typed {
+			return ruleNode{}, fmt.Errorf("_intersects on '%s' expects a GeoJSON geometry", column)
+		}
+		raw, err := json.Marshal(geometry)
+		if err != nil {
+			return ruleNode{}, fmt.Errorf("_intersects on '%s' expects a GeoJSON geometry", column)
+		}
+		node.values = []interface{}{string(raw)}
+		return node, nil
+	}
+
+	circle, ok := value.(map[string]interface{})
+	if !ok {
+		return ruleNode{}, fmt.Errorf("_within_radius on '%s' expects lat, lng and radius", column)
+	}
+	lat, latOK := spatialNumber(circle["lat"])
+	lng, lngOK := spatialNumber(circle["lng"])
+	radius, radiusOK := spatialNumber(circle["radius"])
+	switch {
+	case !latOK || !lngOK || !radiusOK:
+		return ruleNode{}, fmt.Errorf("_within_radius on '%s' expects lat, lng and radius", column)
+	case lat < -90 || lat > 90 || lng < -180 || lng > 180:
+		return ruleNode{}, fmt.Errorf("_within_radius on '%s' has a point outside -90..90 latitude and -180..180 longitude", column)
+	case radius <= 0:
+		return ruleNode{}, fmt.Errorf("_within_radius on '%s' expects a radius above 0 meters", column)
+	}
+	node.values = []interface{}{lng, lat, radius}
+	return node, nil
+}
+
+// spatialNumber reads a number of a spatial rule, sent as a number or a string
+func spatialNumber(value interface{}) (float64, bool) {
+	switch v := value.(type) {
+	case float64:
+		return v, !math.IsNaN(v) && !math.IsInf(v, 0)
+	case json.Number:
+		f, err := v.Float64()
+		return f, err == nil
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		return f, err == nil && !math.IsNaN(f) && !math.IsInf(f, 0)
+	}
+	return 0, false
+}
+
+// spatialSQL renders a spatial node, writing values with placeholder
+func (n ruleNode) spatialSQL(placeholder func(value interface{}) string) string {
+	if n.op == "_intersects" {
+		return fmt.Sprintf(`ST_Intersects(%s, ST_GeomFromGeoJSON(%s)::geography)`, SpatialExpression(n.column), placeholder(n.values[0]))
+	}
+
+	if postGIS {
+		return fmt.Sprintf(`ST_DWithin(%s, ST_SetSRID(ST_MakePoint(%s::float8, %s::float8), 4326)::geography, %s::float8)`,
+			SpatialExpression(n.column), placeholder(n.values[0]), placeholder(n.values[1]), placeholder(n.values[2]))
+	}
+
+	// Haversine distance to the coordinates of points; other geometries have none
+	lng := fmt.Sprintf(`("%s"->'coordinates'->>0)::float8`, n.column)
+	lat := fmt.Sprintf(`("%s"->'coordinates'->>1)::float8`, n.column)
+	centerLng, centerLat, centerLat2 := placeholder(n.values[0]), placeholder(n.values[1]), placeholder(n.values[1])
+	distance := fmt.Sprintf(`2 * %.1f * asin(sqrt(power(sin(radians(%s - %s::float8) / 2), 2) + cos(radians(%s::float8)) * cos(radians(%s)) * power(sin(radians(%s - %s::float8) / 2), 2)))`,
+		earthRadiusMeters, lat, centerLat, centerLat2, lat, lng, centerLng)
+	return fmt.Sprintf(`(CASE WHEN "%s"->>'type' = 'Point' THEN %s END) <= %s::float8`, n.column, distance, placeholder(n.values[2]))
+}
+
+// spatialMatches evaluates a spatial node against a record value. _within_radius measures
+// the great-circle distance to points; _intersects compares bounding boxes, which may match
+// geometries that only come close.
+func (n ruleNode) spatialMatches(value interface{}) bool {
+	geometry, ok := spatialGeometry(value)
+	if !ok {
+		return false
+	}
+
+	if n.op == "_intersects" {
+		other, ok := spatialGeometry(n.values[0])
+		if !ok {
+			return false
+		}
+		a, aOK := geometryBounds(geometry)
+		b, bOK := geometryBounds(other)
+		return aOK && bOK && a[0] <= b[2] && b[0] <= a[2] && a[1] <= b[3] && b[1] <= a[3]
+	}
+
+	if geometry["type"] != "Point" {
+		return false
+	}
+	position, ok := geometry["coordinates"].([]interface{})
+	if !ok || len(position) < 2 {
+		return false
+	}
+	lng, lngOK := spatialNumber(position[0])
+	lat, latOK := spatialNumber(position[1])
+	if !lngOK || !latOK {
+		return false
+	}
+	return haversine(lng, lat, n.values[0].(float64), n.values[1].(float64)) <= n.values[2].(float64)
+}
+
+// spatialGeometry reads a GeoJSON geometry from a record value or a rule value
+func spatialGeometry(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case string:
+		var geometry map[string]interface{}
+		err := json.Unmarshal([]byte(v), &geometry)
+		return geometry, err == nil
+	case []byte:
+		var geometry map[string]interface{}
+		err := json.Unmarshal(v, &geometry)
+		return geometry, err == nil
+	}
+
+	// Other map types, such as the values of geo fields, go through their JSON form
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, false
+	}
+	var geometry map[string]interface{}
+	err = json.Unmarshal(raw, &geometry)
+	return geometry, err == nil
+}
+
+// geometryBounds returns the bounding box (min lng, min lat, max lng, max lat) of a GeoJSON
+// geometry
+func geometryBounds(geometry map[string]interface{}) ([4]float64, bool) {
+	bounds := [4]float64{math.Inf(1), math.Inf(1), math.Inf(-1), math.Inf(-1)}
+	found := false
+
+	var walk func(value interface{})
+	walk = func(value interface{}) {
+		list, ok := value.([]interface{})
+		if !ok {
+			return
+		}
+		if len(list) >= 2 {
+			lng, lngOK := spatialNumber(list[0])
+			lat, latOK := spatialNumber(list[1])
+			if lngOK && latOK {
+				bounds = [4]float64{math.Min(bounds[0], lng), math.Min(bounds[1], lat), math.Max(bounds[2], lng), math.Max(bounds[3], lat)}
+				found = true
+				return
+			}
+		}
+		for _, item := range list {
+			walk(item)
+		}
+	}
+
+	walk(geometry["coordinates"])
+	if members, ok := geometry["geometries"].([]interface{}); ok {
+		for _, member := range members {
+			if object, ok := member.(map[string]interface{}); ok {
+				if memberBounds, ok := geometryBounds(object); ok {
+					walk([]interface{}{
+						[]interface{}{memberBounds[0], memberBounds[1]},
+						[]interface{}{memberBounds[2], memberBounds[3]},
+					})
+				}
+			}
+		}
+	}
+	return bounds, found
+}
+
+// haversine returns the great-circle distance in meters between two points
+func haversine(lng1, lat1, lng2, lat2 float64) float64 {
+	toRadians := func(degrees float64) float64 { return degrees * math.Pi / 180 }
+	dLat := toRadians(lat2 - lat1)
+	dLng := toRadians(lng2 - lng1)
+	a := math.Pow(math.Sin(dLat/2), 2) + math.Cos(toRadians(lat1))*math.Cos(toRadians(lat2))*math.Pow(math.Sin(dLng/2), 2)
+	return 2 * earthRadiusMeters * math.Asin(math.Sqrt(a))
+}
@@ -0,0 +1,86 @@
+package rbac
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// usePostGIS turns PostGIS on for the duration of a test
+func usePostGIS(t *testing.T) {
+	UsePostGIS(true)
+	t.Cleanup(func() { UsePostGIS(false) })
+}
+
+func TestSpatialWithinRadius(t *testing.T) {
+	filter, err := CompileRowFilter(json.RawMessage(`{"location": {"_within_radius": {"lat": 52.52, "lng": "13.405", "radius": 1000}}}`), RuleVars{})
+	require.NoError(t, err)
+
+	condition, args := filter.SQL(1)
+	assert.Contains(t, condition, `(CASE WHEN "location"->>'type' = 'Point' THEN 2 * 6371008.8 * asin(`)
+	assert.Contains(t, condition, `END) <= $4::float8`)
+	assert.Equal(t, []interface{}{13.405, 52.52, 52.52, float64(1000)}, args)
+
+	// About 1.9 and 4.3 kilometers from the center
+	near := map[string]interface{}{"location": map[string]interface{}{"type": "Point", "coordinates": []interface{}{13.3777, 52.5163}}}
+	far := map[string]interface{}{"location": `{"type": "Point", "coordinates": [13.35, 52.5]}`}
+	line := map[string]interface{}{"location": map[string]interface{}{"type": "LineString", "coordinates": []interface{}{[]interface{}{13.405, 52.52}, []interface{}{13.41, 52.53}}}}
+	assert.False(t, filter.Matches(near))
+	assert.False(t, filter.Matches(far))
+	assert.False(t, filter.Matches(line))
+	assert.False(t, filter.Matches(map[string]interface{}{"location": nil}))
+
+	wider, err := CompileRowFilter(json.RawMessage(`{"location": {"_within_radius": {"lat": 52.52, "lng": 13.405, "radius": 2000}}}`), RuleVars{})
+	require.NoError(t, err)
+	assert.True(t, wider.Matches(near))
+	assert.False(t, wider.Matches(far))
+
+	usePostGIS(t)
+	filter, err = CompileRowFilter(json.RawMessage(`{"location": {"_within_radius": {"lat": 52.52, "lng": 13.405, "radius": 1000}}}`), RuleVars{})
+	require.NoError(t, err)
+	condition, args = filter.SQL(1)
+	assert.Equal(t, `ST_DWithin((ST_GeomFromGeoJSON("location")::geography), ST_SetSRID(ST_MakePoint($1::float8, $2::float8), 4326)::geography, $3::float8)`, condition)
+	assert.Equal(t, []interface{}{13.405, 52.52, float64(1000)}, args)
+}
+
+func TestSpatialIntersects(t *testing.T) {
+	rule := json.RawMessage(`{"area": {"_intersects": {"type": "Point", "coordinates": [13.405, 52.52]}}}`)
+
+	_, err := CompileRowFilter(rule, RuleVars{})
+	assert.ErrorContains(t, err, "needs the PostGIS extension")
+
+	usePostGIS(t)
+	filter, err := CompileRowFilter(rule, RuleVars{})
+	require.NoError(t, err)
+	condition, args := filter.SQL(1)
+	assert.Equal(t, `ST_Intersects((ST_GeomFromGeoJSON("area")::geography), ST_GeomFromGeoJSON($1)::geography)`, condition)
+	assert.Equal(t, []interface{}{`{"coordinates":[13.405,52.52],"type":"Point"}`}, args)
+
+	square := map[string]interface{}{"type": "Polygon", "coordinates": []interface{}{[]interface{}{
+		[]interface{}{13.4, 52.5}, []interface{}{13.41, 52.5}, []interface{}{13.41, 52.53}, []interface{}{13.4, 52.53}, []interface{}{13.4, 52.5},
+	}}}
+	assert.True(t, filter.Matches(map[string]interface{}{"area": square}))
+	assert.False(t, filter.Matches(map[string]interface{}{"area": map[string]interface{}{"type": "Point", "coordinates": []interface{}{0.0, 0.0}}}))
+}
+
+func TestSpatialRejectsInvalidValues(t *testing.T) {
+	usePostGIS(t)
+	for _, rule := range []string{
+		`{"location": {"_within_radius": {"lat": 52.52, "lng": 13.405}}}`,
+		`{"location": {"_within_radius": {"lat": 95, "lng": 13.405, "radius": 10}}}`,
+		`{"location": {"_within_radius": {"lat": 52.52, "lng": 13.405, "radius": 0}}}`,
+		`{"location": {"_within_radius": "52.52,13.405,10"}}`,
+		`{"area": {"_intersects": [13.405, 52.52]}}`,
+	} {
+		_, err := CompileRowFilter(json.RawMessage(rule), RuleVars{})
+		assert.Error(t, err, rule)
+	}
+}
+
+func TestHaversine(t *testing.T) {
+	// Berlin to Paris is about 878 kilometers
+	assert.InDelta(t, 878000, haversine(13.405, 52.52, 2.3522, 48.8566), 2000)
+	assert.Zero(t, haversine(13.405, 52.52, 13.405, 52.52))
+}
@@ -0,0 +1,66 @@
+package rbac
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseFieldTransforms(t *testing.T) {
+	for _, raw := range []string{``, `null`, `{}`} {
+		transforms, err := ParseFieldTransforms(json.RawMessage(raw))
+		require.NoError(t, err, raw)
+		assert.Nil(t, transforms, raw)
+	}
+
+	transforms, err := ParseFieldTransforms(json.RawMessage(`{"email": "mask_email", "card": "last4", "ssn": "hash"}`))
+	require.NoError(t, err)
+	assert.Equal(t, FieldTransforms{"email": TransformMaskEmail, "card": TransformLast4, "ssn": TransformHash}, transforms)
+
+	for _, raw := range []string{`["email"]`, `{"email": "rot13"}`, `{"e mail": "hash"}`, `{"email": 1}`} {
+		_, err := ParseFieldTransforms(json.RawMessage(raw))
+		assert.Error(t, err, raw)
+	}
+}
+
+func TestTransformValue(t *testing.T) {
+	tests := []struct {
+		transform, text, want string
+	}{
+		{TransformMaskEmail, "jane.doe@example.com", "j*******@example.com"},
+		{TransformMaskEmail, "j@example.com", "*@example.com"},
+		{TransformMaskEmail, "not-an-address", "n*************"},
+		{TransformLast4, "4111 1111 1111 1234", "***************1234"},
+		{TransformLast4, "1234", "****"},
+		{TransformLast4, "ünïcødé", "***cødé"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, transformValue(tt.transform, tt.text), tt.transform+" "+tt.text)
+	}
+
+	hashed := transformValue(TransformHash, "jane@example.com")
+	assert.Len(t, hashed, 64)
+	assert.Equal(t, hashed, transformValue(TransformHash, "jane@example.com"), "hashes are stable")
+	assert.NotEqual(t, hashed, transformValue(TransformHash, "john@example.com"))
+}
+
+func TestFieldTransformsApply(t *testing.T) {
+	transforms := FieldTransforms{"card": TransformLast4, "missing": TransformHash}
+	data := map[string]interface{}{"card": float64(4111111111111111), "name": "Jane"}
+
+	applied := transforms.Apply(data)
+	assert.Equal(t, map[string]interface{}{"card": "************1111", "name": "Jane"}, applied, "numbers are redacted as text")
+	assert.Equal(t, float64(4111111111111111), data["card"])
+
+	assert.Equal(t, data, FieldTransforms(nil).Apply(data))
+}
+
+func TestFieldTransformsContext(t *testing.T) {
+	ctx := WithFieldTransforms(context.Background(), "customers", FieldTransforms{"email": TransformHash})
+	assert.Equal(t, FieldTransforms{"email": TransformHash}, FieldTransformsFromContext(ctx, "customers"))
+	assert.Nil(t, FieldTransformsFromContext(ctx, "orders"))
+	assert.Equal(t, context.Background(), WithFieldTransforms(context.Background(), "orders", nil))
+}
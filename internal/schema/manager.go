@@ -21,6 +21,9 @@ type Collection struct {
 	Description string    `json:"description"`
 	Icon        string    `json:"icon"`
 	IsSystem    bool      `json:"is_system"`
+	Timestamps  bool      `json:"timestamps"`  // created_at and updated_at columns
+	UserStamps  bool      `json:"user_stamps"` // created_by and updated_by columns
+	Sortable    bool      `json:"sortable"`    // sort column for manual ordering
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 	Fields      []Field   `json:"fields,omitempty"`
@@ -58,25 +61,35 @@ func (sm *SchemaManager) CreateCollection(ctx context.Context, collection Collec
 
 	// Insert collection record
 	collectionQuery := `
-		INSERT INTO collections (id, name, display_name, description, icon, is_system)
-		VALUES ($1, $2, $3, $4, $5, $6)
+		INSERT INTO collections (id, name, display_name, description, icon, is_system, timestamps, user_stamps, sortable)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
 	`
 	_, err = tx.ExecContext(ctx, collectionQuery,
 		collection.ID, collection.Name, collection.DisplayName,
-		collection.Description, collection.Icon, collection.IsSystem)
+		collection.Description, collection.Icon, collection.IsSystem,
+		collection.Timestamps, collection.UserStamps, collection.Sortable)
 	if err != nil {
 		return fmt.Errorf("failed to insert collection: %w", err)
 	}
 
 	// Create corresponding data table
 	dataTableName := "data_" + collection.Name
-	createTableQuery := sm.buildCreateTableQuery(dataTableName, collection.Fields)
+	createTableQuery := sm.buildCreateTableQuery(dataTableName, collection)
 
 	_, err = tx.ExecContext(ctx, createTableQuery)
 	if err != nil {
 		return fmt.Errorf("failed to create data table: %w", err)
 	}
 
+	// Many-to-many relations keep their links in junction tables
+	for _, field := range collection.Fields {
+		if query, ok := buildJunctionTableQuery(field); ok {
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return fmt.Errorf("failed to create junction table for %s: %w", field.Name, err)
+			}
+		}
+	}
+
 	// Insert fields
 	for _, field := range collection.Fields {
 		fieldQuery := `
@@ -177,12 +190,13 @@ func (sm *SchemaManager) GetCollection(ctx context.Context, collectionID uuid.UU
 	// Get collection
 	var collection Collection
 	collectionQuery := `
-		SELECT id, name, display_name, description, icon, is_system, created_at, updated_at
+		SELECT id, name, display_name, description, icon, is_system, timestamps, user_stamps, sortable, created_at, updated_at
 		FROM collections WHERE id = $1
 	`
 	err := sm.db.QueryRowContext(ctx, collectionQuery, collectionID).Scan(
 		&collection.ID, &collection.Name, &collection.DisplayName, &collection.Description,
-		&collection.Icon, &collection.IsSystem, &collection.CreatedAt, &collection.UpdatedAt)
+		&collection.Icon, &collection.IsSystem, &collection.Timestamps, &collection.UserStamps,
+		&collection.Sortable, &collection.CreatedAt, &collection.UpdatedAt)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get collection: %w", err)
 	}
@@ -218,7 +232,7 @@ func (sm *SchemaManager) GetCollection(ctx context.Context, collectionID uuid.UU
 // ListCollections retrieves all collections
 func (sm *SchemaManager) ListCollections(ctx context.Context) ([]Collection, error) {
 	query := `
-		SELECT id, name, display_name, description, icon, is_system, created_at, updated_at
+		SELECT id, name, display_name, description, icon, is_system, timestamps, user_stamps, sortable, created_at, updated_at
 		FROM collections ORDER BY name
 	`
 	rows, err := sm.db.QueryContext(ctx, query)
@@ -232,7 +246,8 @@ func (sm *SchemaManager) ListCollections(ctx context.Context) ([]Collection, err
 		var collection Collection
 		err := rows.Scan(
 			&collection.ID, &collection.Name, &collection.DisplayName, &collection.Description,
-			&collection.Icon, &collection.IsSystem, &collection.CreatedAt, &collection.UpdatedAt)
+			&collection.Icon, &collection.IsSystem, &collection.Timestamps, &collection.UserStamps,
+			&collection.Sortable, &collection.CreatedAt, &collection.UpdatedAt)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan collection: %w", err)
 		}
@@ -242,17 +257,30 @@ func (sm *SchemaManager) ListCollections(ctx context.Context) ([]Collection, err
 	return collections, nil
 }
 
-// buildCreateTableQuery builds the SQL to create a data table based on fields
-func (sm *SchemaManager) buildCreateTableQuery(tableName string, fields []Field) string {
+// buildCreateTableQuery builds the SQL to create a data table based on the collection's
+// flags and fields
+func (sm *SchemaManager) buildCreateTableQuery(tableName string, collection Collection) string {
 	var columns []string
 
-	// Always add standard columns
+	// Always add the ID, then the standard columns the collection keeps
 	columns = append(columns, "id UUID PRIMARY KEY DEFAULT uuid_generate_v4()")
-	columns = append(columns, "created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()")
-	columns = append(columns, "updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()")
+	if collection.Timestamps {
+		columns = append(columns, "created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()")
+		columns = append(columns, "updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()")
+	}
+	if collection.UserStamps {
+		columns = append(columns, "created_by UUID")
+		columns = append(columns, "updated_by UUID")
+	}
+	if collection.Sortable {
+		columns = append(columns, "sort INTEGER")
+	}
 
-	// Add field columns
-	for _, field := range fields {
+	// Add field columns; o2m and m2m relations have none
+	for _, field := range collection.Fields {
+		if isRelationAlias(field) {
+			continue
+		}
 		columnDef := sm.buildColumnDefinition(field)
 		columns = append(columns, columnDef)
 	}
@@ -282,14 +310,22 @@ func (sm *SchemaManager) buildColumnDefinition(field Field) string {
 		parts = append(parts, "BOOLEAN")
 	case "datetime":
 		parts = append(parts, "TIMESTAMP WITH TIME ZONE")
-	case "json":
+	case "json", "point", "geometry":
 		parts = append(parts, "JSONB")
+	case "string[]":
+		parts = append(parts, "TEXT[]")
+	case "uuid[]":
+		parts = append(parts, "UUID[]")
+	case "int[]":
+		parts = append(parts, "BIGINT[]")
 	case "uuid":
 		parts = append(parts, "UUID")
+	case "file":
+		parts = append(parts, "UUID REFERENCES public.assets(id) ON DELETE SET NULL")
 	case "relation":
 		// Handle relation fields - would need to reference the related table
 		if relConfig, ok := field.RelationConfig["related_collection"].(string); ok {
-			parts = append(parts, fmt.Sprintf("UUID REFERENCES data_%s(id)", relConfig))
+			parts = append(parts, fmt.Sprintf("UUID REFERENCES data_%s(id)%s", relConfig, onDeleteClause(field)))
 		} else {
 			parts = append(parts, "UUID")
 		}
@@ -312,3 +348,56 @@ func (sm *SchemaManager) buildColumnDefinition(field Field) string {
 
 	return strings.Join(parts, " ")
 }
+
+// onDeleteClause returns the ON DELETE clause for the on_delete rule of a relation field
+// ("cascade", "set_null" or "restrict"), or "" when it has none
+func onDeleteClause(field Field) string {
+	switch field.RelationConfig["on_delete"] {
+	case "cascade":
+		return " ON DELETE CASCADE"
+	case "set_null":
+		return " ON DELETE SET NULL"
+	case "restrict":
+		return " ON DELETE RESTRICT"
+	default:
+		return ""
+	}
+}
+
+// isRelationAlias reports whether a field is a relation without a column of its own
+func isRelationAlias(field Field) bool {
+	if field.Type != "relation" {
+		return false
+	}
+	relationType, _ := field.RelationConfig["type"].(string)
+	return relationType == "o2m" || relationType == "m2m"
+}
+
+// buildJunctionTableQuery builds the SQL creating the junction table of an m2m relation
+// field. Its relation_config names the junction and optionally its two columns.
+func buildJunctionTableQuery(field Field) (string, bool) {
+	if field.Type != "relation" || field.RelationConfig["type"] != "m2m" {
+		return "", false
+	}
+	junction, _ := field.RelationConfig["junction"].(string)
+	if junction == "" {
+		return "", false
+	}
+	itemColumn, _ := field.RelationConfig["junction_field"].(string)
+	if itemColumn == "" {
+		itemColumn = "item_id"
+	}
+	relatedColumn, _ := field.RelationConfig["related_junction_field"].(string)
+	if relatedColumn == "" {
+		relatedColumn = "related_id"
+	}
+
+	query := fmt.Sprintf(`CREATE TABLE IF NOT EXISTS junction_%s (
+  "%s" UUID NOT NULL,
+  "%s" UUID NOT NULL,
+  sort_order INTEGER NOT NULL DEFAULT 0,
+  created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+  PRIMARY KEY ("%s", "%s")
+)`, junction, itemColumn, relatedColumn, itemColumn, relatedColumn)
+	return query, true
+}
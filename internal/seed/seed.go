@@ -0,0 +1,520 @@
+// Package seed loads fixture files (tenants, users, schemas and items) from a seeds
+// directory and writes whatever is missing to the database.
+//
+// Files directly in the directory apply to every environment; files in a subdirectory
+// named after the environment (e.g. seeds/development) are read after them. Files are
+// YAML (.yaml, .yml) or JSON (.json) and are read in name order. Values may reference
+// environment variables as ${NAME}.
+//
+// Seeding is idempotent: tenants are matched by slug, users by email, schema records as in
+// schema snapshots and items by their key field. Existing records are never modified, so
+// seeds only fill in what is missing.
+//
+// Besides the files, seeding creates the initial admin from configuration (ADMIN_EMAIL,
+// ADMIN_PASSWORD) in the AdminTenant tenant. The admin must change the password on first
+// login.
+package seed
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"go-rbac-api/internal/api"
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+	"go-rbac-api/internal/models"
+	"go-rbac-api/internal/rbac"
+
+	"github.com/google/uuid"
+	"gopkg.in/yaml.v3"
+)
+
+// AdminTenant is the slug of the tenant the initial admin belongs to
+const AdminTenant = "main"
+
+var (
+	envPattern    = regexp.MustCompile(`^[a-z0-9_-]+$`)
+	columnPattern = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+)
+
+// Seeds is the merged content of the seed files for one environment
+type Seeds struct {
+	Tenants []Tenant `json:"tenants" yaml:"tenants"`
+	Users   []User   `json:"users" yaml:"users"`
+	Items   []Items  `json:"items" yaml:"items"`
+}
+
+// Tenant is a tenant and, optionally, the schema it should have
+type Tenant struct {
+	Slug   string              `json:"slug" yaml:"slug"`
+	Name   string              `json:"name" yaml:"name"`
+	Domain string              `json:"domain,omitempty" yaml:"domain,omitempty"`
+	Schema *api.SchemaSnapshot `json:"schema,omitempty" yaml:"schema,omitempty"`
+}
+
+// User is a user, the tenant it belongs to and its roles in that tenant
+type User struct {
+	Email     string   `json:"email" yaml:"email"`
+	Password  string   `json:"password" yaml:"password"`
+	FirstName string   `json:"first_name,omitempty" yaml:"first_name,omitempty"`
+	LastName  string   `json:"last_name,omitempty" yaml:"last_name,omitempty"`
+	Tenant    string   `json:"tenant,omitempty" yaml:"tenant,omitempty"`
+	Roles     []string `json:"roles,omitempty" yaml:"roles,omitempty"`
+
+	MustChangePassword bool `json:"must_change_password,omitempty" yaml:"must_change_password,omitempty"`
+	// SuperAdmin lets the user see and manage every tenant
+	SuperAdmin bool `json:"super_admin,omitempty" yaml:"super_admin,omitempty"`
+}
+
+// Items are rows of a collection; Key names the field that identifies a row
+type Items struct {
+	Tenant     string                   `json:"tenant" yaml:"tenant"`
+	Collection string                   `json:"collection" yaml:"collection"`
+	Key        string                   `json:"key" yaml:"key"`
+	Rows       []map[string]interface{} `json:"rows" yaml:"rows"`
+}
+
+// Result counts the records a run created
+type Result struct {
+	Tenants       int
+	Users         int
+	SchemaChanges int
+	Items         int
+
+	// AdminPassword is set when the initial admin was created with a generated password
+	AdminPassword string
+}
+
+// Load reads the shared seed files of fsys and those of env. A missing directory holds
+// no seeds. Tenants and users defined again in a later file replace the earlier definition;
+// items are appended.
+func Load(fsys fs.FS, env string) (*Seeds, error) {
+	if env != "" && !envPattern.MatchString(env) {
+		return nil, fmt.Errorf("invalid seed environment %q", env)
+	}
+
+	seeds := &Seeds{}
+	for _, dir := range []string{".", env} {
+		if dir == "" {
+			continue
+		}
+		entries, err := fs.ReadDir(fsys, dir)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+		for _, entry := range entries {
+			format := fileFormat(entry.Name())
+			if entry.IsDir() || format == "" {
+				continue
+			}
+			name := path.Join(dir, entry.Name())
+			data, err := fs.ReadFile(fsys, name)
+			if err != nil {
+				return nil, err
+			}
+			file, err := parse(data, format)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", name, err)
+			}
+			seeds.merge(file)
+		}
+	}
+
+	if err := seeds.validate(); err != nil {
+		return nil, err
+	}
+	return seeds, nil
+}
+
+// fileFormat returns "yaml" or "json" for seed files and "" for anything else
+func fileFormat(name string) string {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".yaml", ".yml":
+		return "yaml"
+	case ".json":
+		return "json"
+	}
+	return ""
+}
+
+// parse expands environment variables in a seed file and decodes it
+func parse(data []byte, format string) (*Seeds, error) {
+	expanded := []byte(os.Expand(string(data), os.Getenv))
+
+	var file Seeds
+	var err error
+	if format == "yaml" {
+		err = yaml.Unmarshal(expanded, &file)
+	} else {
+		err = json.Unmarshal(expanded, &file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("invalid seed file: %w", err)
+	}
+	return &file, nil
+}
+
+// merge adds the records of file, replacing tenants and users defined before
+func (seeds *Seeds) merge(file *Seeds) {
+	for _, tenant := range file.Tenants {
+		replaced := false
+		for i := range seeds.Tenants {
+			if seeds.Tenants[i].Slug == tenant.Slug {
+				seeds.Tenants[i], replaced = tenant, true
+			}
+		}
+		if !replaced {
+			seeds.Tenants = append(seeds.Tenants, tenant)
+		}
+	}
+	for _, user := range file.Users {
+		replaced := false
+		for i := range seeds.Users {
+			if strings.EqualFold(seeds.Users[i].Email, user.Email) {
+				seeds.Users[i], replaced = user, true
+			}
+		}
+		if !replaced {
+			seeds.Users = append(seeds.Users, user)
+		}
+	}
+	seeds.Items = append(seeds.Items, file.Items...)
+}
+
+// validate checks that every record can be identified
+func (seeds *Seeds) validate() error {
+	for _, tenant := range seeds.Tenants {
+		if tenant.Slug == "" || tenant.Name == "" {
+			return fmt.Errorf("invalid seeds: tenants need a slug and a name")
+		}
+		if tenant.Schema != nil {
+			if tenant.Schema.Version == 0 {
+				tenant.Schema.Version = api.SchemaSnapshotVersion
+			}
+			if err := tenant.Schema.Validate(); err != nil {
+				return fmt.Errorf("schema of tenant %s: %w", tenant.Slug, err)
+			}
+		}
+	}
+
+	for _, user := range seeds.Users {
+		if user.Email == "" || user.Password == "" {
+			return fmt.Errorf("invalid seeds: users need an email and a password")
+		}
+		if len(user.Roles) > 0 && user.Tenant == "" {
+			return fmt.Errorf("invalid seeds: roles of %s need a tenant", user.Email)
+		}
+	}
+
+	for _, items := range seeds.Items {
+		if items.Tenant == "" || items.Collection == "" {
+			return fmt.Errorf("invalid seeds: items need a tenant and a collection")
+		}
+		if !columnPattern.MatchString(items.Key) {
+			return fmt.Errorf("invalid seeds: items of %s need a key field", items.Collection)
+		}
+		for _, row := range items.Rows {
+			if row[items.Key] == nil {
+				return fmt.Errorf("invalid seeds: an item of %s has no %s", items.Collection, items.Key)
+			}
+		}
+	}
+
+	return nil
+}
+
+// Seeder writes seeds to the database
+type Seeder struct {
+	db      *db.DB
+	cfg     *config.Config
+	schema  *api.SchemaHandlers
+	dynamic *api.DynamicHandlers
+	utils   *api.ItemsUtils
+}
+
+// New creates a Seeder
+func New(database *db.DB, cfg *config.Config) *Seeder {
+	bus := events.NewBus()
+	utils := api.NewItemsUtils(database)
+	return &Seeder{
+		db:      database,
+		cfg:     cfg,
+		schema:  api.NewSchemaHandlers(api.NewItemsHandler(database, cfg, bus), utils),
+		dynamic: api.NewDynamicHandlers(database, utils, bus),
+		utils:   utils,
+	}
+}
+
+// Run creates the missing tenants, then users, then each tenant's schema, then the users'
+// tenant roles and finally the items. Schemas and items are written on behalf of a user
+// whose home tenant is the target tenant.
+func (s *Seeder) Run(ctx context.Context, seeds *Seeds) (Result, error) {
+	var result Result
+	queries := s.db.Queries
+
+	tenants := make(map[string]uuid.UUID)
+	tenantID := func(slug string) (uuid.UUID, error) {
+		if id, ok := tenants[slug]; ok {
+			return id, nil
+		}
+		tenant, err := queries.GetTenantBySlug(ctx, slug)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("tenant %q not found", slug)
+		}
+		tenants[slug] = tenant.ID
+		return tenant.ID, nil
+	}
+
+	for _, tenant := range seeds.Tenants {
+		if existing, err := queries.GetTenantBySlug(ctx, tenant.Slug); err == nil {
+			tenants[tenant.Slug] = existing.ID
+			continue
+		}
+		created, err := queries.CreateTenant(ctx, sqlc.CreateTenantParams{
+			ID:     uuid.New(),
+			Name:   tenant.Name,
+			Slug:   tenant.Slug,
+			Domain: sql.NullString{String: tenant.Domain, Valid: tenant.Domain != ""},
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to create tenant %s: %w", tenant.Slug, err)
+		}
+		tenants[tenant.Slug] = created.ID
+		result.Tenants++
+	}
+
+	seedUsers := seeds.Users
+	if admin, ok := s.admin(seeds); ok {
+		seedUsers = append([]User{admin}, seedUsers...)
+	}
+
+	users := make(map[string]uuid.UUID)
+	policy := models.NewPasswordPolicy(s.cfg)
+	for _, user := range seedUsers {
+		if existing, err := queries.GetUserByEmail(ctx, user.Email); err == nil {
+			users[user.Email] = existing.ID
+			continue
+		}
+
+		var home uuid.NullUUID
+		if user.Tenant != "" {
+			id, err := tenantID(user.Tenant)
+			if err != nil {
+				return result, fmt.Errorf("user %s: %w", user.Email, err)
+			}
+			home = uuid.NullUUID{UUID: id, Valid: true}
+		}
+		if user.Password == "" {
+			// Only the configured admin comes without a password
+			password, err := policy.Generate()
+			if err != nil {
+				return result, fmt.Errorf("failed to generate a password for %s: %w", user.Email, err)
+			}
+			user.Password, result.AdminPassword = password, password
+		}
+		if err := policy.Validate(user.Password); err != nil {
+			return result, fmt.Errorf("password of %s does not meet the password policy: %w", user.Email, err)
+		}
+		passwordHash, err := models.HashPassword(user.Password)
+		if err != nil {
+			return result, fmt.Errorf("failed to hash password of %s: %w", user.Email, err)
+		}
+
+		created, err := queries.CreateUser(ctx, sqlc.CreateUserParams{
+			ID:           uuid.New(),
+			Email:        user.Email,
+			PasswordHash: passwordHash,
+			FirstName:    sql.NullString{String: user.FirstName, Valid: true},
+			LastName:     sql.NullString{String: user.LastName, Valid: true},
+			TenantID:     home,
+		})
+		if err != nil {
+			return result, fmt.Errorf("failed to create user %s: %w", user.Email, err)
+		}
+		if user.MustChangePassword {
+			if err := queries.SetUserPassword(ctx, sqlc.SetUserPasswordParams{
+				ID:                 created.ID,
+				PasswordHash:       passwordHash,
+				MustChangePassword: true,
+			}); err != nil {
+				return result, fmt.Errorf("failed to create user %s: %w", user.Email, err)
+			}
+		}
+		if user.SuperAdmin {
+			if err := queries.SetUserSuperAdmin(ctx, sqlc.SetUserSuperAdminParams{
+				ID:           created.ID,
+				IsSuperAdmin: true,
+			}); err != nil {
+				return result, fmt.Errorf("failed to create user %s: %w", user.Email, err)
+			}
+		}
+		users[user.Email] = created.ID
+		result.Users++
+	}
+
+	for _, tenant := range seeds.Tenants {
+		if tenant.Schema == nil {
+			continue
+		}
+		actor, err := s.tenantUser(ctx, tenants[tenant.Slug])
+		if err != nil {
+			return result, fmt.Errorf("schema of tenant %s: %w", tenant.Slug, err)
+		}
+		changes, err := s.schema.ApplySnapshot(ctx, actor, tenant.Schema, false)
+		result.SchemaChanges += len(changes)
+		if err != nil {
+			return result, fmt.Errorf("schema of tenant %s: %w", tenant.Slug, err)
+		}
+	}
+
+	for _, user := range seedUsers {
+		if user.Tenant == "" {
+			continue
+		}
+		if err := s.assignRoles(ctx, users[user.Email], tenants[user.Tenant], user); err != nil {
+			return result, err
+		}
+	}
+
+	for _, items := range seeds.Items {
+		id, err := tenantID(items.Tenant)
+		if err != nil {
+			return result, fmt.Errorf("items of %s: %w", items.Collection, err)
+		}
+		created, err := s.createItems(ctx, id, items)
+		result.Items += created
+		if err != nil {
+			return result, err
+		}
+	}
+
+	return result, nil
+}
+
+// admin describes the configured initial admin, unless the seed files define that user
+func (s *Seeder) admin(seeds *Seeds) (User, bool) {
+	if s.cfg.AdminEmail == "" {
+		return User{}, false
+	}
+	for _, user := range seeds.Users {
+		if strings.EqualFold(user.Email, s.cfg.AdminEmail) {
+			return User{}, false
+		}
+	}
+
+	return User{
+		Email:              s.cfg.AdminEmail,
+		Password:           s.cfg.AdminPassword,
+		FirstName:          s.cfg.AdminFirstName,
+		LastName:           s.cfg.AdminLastName,
+		Tenant:             AdminTenant,
+		Roles:              []string{"admin"},
+		MustChangePassword: true,
+		SuperAdmin:         true,
+	}, true
+}
+
+// tenantUser returns the oldest active user whose home tenant is tenantID
+func (s *Seeder) tenantUser(ctx context.Context, tenantID uuid.UUID) (uuid.UUID, error) {
+	var userID uuid.UUID
+	err := s.db.QueryRowContext(ctx,
+		`SELECT id FROM users WHERE tenant_id = $1 AND is_active = true ORDER BY created_at LIMIT 1`,
+		tenantID,
+	).Scan(&userID)
+	if err == sql.ErrNoRows {
+		return uuid.Nil, fmt.Errorf("the tenant has no users to act as")
+	}
+	return userID, err
+}
+
+// assignRoles makes the user a member of its tenant and gives it the listed roles
+func (s *Seeder) assignRoles(ctx context.Context, userID, tenantID uuid.UUID, user User) error {
+	queries := s.db.Queries
+
+	var roleIDs []uuid.UUID
+	for _, name := range user.Roles {
+		role, err := queries.GetRoleByNameAndTenant(ctx, sqlc.GetRoleByNameAndTenantParams{
+			Name:     name,
+			TenantID: uuid.NullUUID{UUID: tenantID, Valid: true},
+		})
+		if err != nil {
+			return fmt.Errorf("user %s: tenant %s has no %s role", user.Email, user.Tenant, name)
+		}
+		roleIDs = append(roleIDs, role.ID)
+	}
+
+	var member bool
+	if err := s.db.QueryRowContext(ctx,
+		`SELECT EXISTS(SELECT 1 FROM user_tenants WHERE user_id = $1 AND tenant_id = $2)`,
+		userID, tenantID,
+	).Scan(&member); err != nil {
+		return err
+	}
+	if !member {
+		var roleID uuid.NullUUID
+		if len(roleIDs) > 0 {
+			roleID = uuid.NullUUID{UUID: roleIDs[0], Valid: true}
+		}
+		if err := queries.AddUserToTenant(ctx, sqlc.AddUserToTenantParams{
+			UserID:   userID,
+			TenantID: tenantID,
+			RoleID:   roleID,
+		}); err != nil {
+			return fmt.Errorf("failed to add %s to tenant %s: %w", user.Email, user.Tenant, err)
+		}
+	}
+
+	for _, roleID := range roleIDs {
+		if err := queries.AddUserRole(ctx, sqlc.AddUserRoleParams{UserID: userID, RoleID: roleID}); err != nil {
+			return fmt.Errorf("failed to assign roles to %s: %w", user.Email, err)
+		}
+	}
+	rbac.InvalidateUserPermissions(userID)
+	return nil
+}
+
+// createItems inserts the rows whose key is not in the collection yet
+func (s *Seeder) createItems(ctx context.Context, tenantID uuid.UUID, items Items) (int, error) {
+	table, err := s.utils.ResolveDataTable(ctx, tenantID, items.Collection)
+	if err != nil {
+		return 0, fmt.Errorf("collection %s not found in tenant %s", items.Collection, items.Tenant)
+	}
+
+	actor, err := s.tenantUser(ctx, tenantID)
+	if err != nil {
+		return 0, fmt.Errorf("items of %s: %w", items.Collection, err)
+	}
+
+	exists := fmt.Sprintf(`SELECT EXISTS(SELECT 1 FROM %s WHERE "%s"::text = $1)`, table, items.Key)
+	created := 0
+	for _, row := range items.Rows {
+		var found bool
+		if err := s.db.QueryRowContext(ctx, exists, fmt.Sprint(row[items.Key])).Scan(&found); err != nil {
+			return created, fmt.Errorf("failed to look up %s item %v: %w", items.Collection, row[items.Key], err)
+		}
+		if found {
+			continue
+		}
+		if _, err := s.dynamic.CreateDynamicItem(ctx, actor, items.Collection, row); err != nil {
+			return created, fmt.Errorf("failed to create %s item %v: %w", items.Collection, row[items.Key], err)
+		}
+		created++
+	}
+	return created, nil
+}
@@ -0,0 +1,97 @@
+package seed
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Setenv("SEED_TEST_PASSWORD", "s3cret-pass")
+
+	fsys := fstest.MapFS{
+		"base.yaml": {Data: []byte(`
+tenants:
+  - slug: main
+    name: Main
+users:
+  - email: admin@example.com
+    password: ${SEED_TEST_PASSWORD}
+    tenant: main
+    roles: [admin]
+`)},
+		"README.md": {Data: []byte("ignored")},
+		"development/users.json": {Data: []byte(`{
+			"users": [{"email": "dev@example.com", "password": "devpassword", "tenant": "main"}],
+			"items": [{"tenant": "main", "collection": "posts", "key": "slug", "rows": [{"slug": "a"}]}]
+		}`)},
+		"development/tenants.yaml": {Data: []byte(`
+tenants:
+  - slug: main
+    name: Main (development)
+    schema:
+      collections:
+        - slug: posts
+          name: posts
+`)},
+		"production/users.yaml": {Data: []byte(`
+users:
+  - email: ops@example.com
+    password: opspassword
+`)},
+	}
+
+	seeds, err := Load(fsys, "development")
+	require.NoError(t, err)
+
+	// The environment's tenant replaces the shared one
+	require.Len(t, seeds.Tenants, 1)
+	assert.Equal(t, "Main (development)", seeds.Tenants[0].Name)
+	assert.Equal(t, 1, seeds.Tenants[0].Schema.Version)
+
+	require.Len(t, seeds.Users, 2)
+	assert.Equal(t, "s3cret-pass", seeds.Users[0].Password)
+	assert.Equal(t, "dev@example.com", seeds.Users[1].Email)
+	require.Len(t, seeds.Items, 1)
+
+	// Only the shared files without an environment
+	seeds, err = Load(fsys, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Main", seeds.Tenants[0].Name)
+	assert.Len(t, seeds.Users, 1)
+
+	// A missing directory holds no seeds
+	seeds, err = Load(fstest.MapFS{}, "staging")
+	require.NoError(t, err)
+	assert.Empty(t, seeds.Tenants)
+
+	_, err = Load(fsys, "../etc")
+	assert.ErrorContains(t, err, "invalid seed environment")
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	invalid := map[string]string{
+		"tenants need a slug":        `tenants: [{name: Main}]`,
+		"users need an email":        `users: [{email: a@example.com}]`,
+		"roles of a@example.com":     `users: [{email: a@example.com, password: x, roles: [admin]}]`,
+		"items of posts need a key":  `items: [{tenant: main, collection: posts, rows: [{title: a}]}]`,
+		"an item of posts has no id": `items: [{tenant: main, collection: posts, key: id, rows: [{title: a}]}]`,
+		"duplicate field posts.x":    `tenants: [{slug: main, name: Main, schema: {collections: [{slug: posts, name: posts, fields: [{name: x, type: text}, {name: x, type: text}]}]}}]`,
+		"invalid seed file":          `tenants: {slug: main}`,
+	}
+	for message, body := range invalid {
+		_, err := Load(fstest.MapFS{"seeds.yaml": {Data: []byte(body)}}, "")
+		assert.ErrorContains(t, err, message)
+	}
+}
+
+func TestLoad_RepositorySeeds(t *testing.T) {
+	for _, env := range []string{"development", "production"} {
+		seeds, err := Load(os.DirFS("../../seeds"), env)
+		require.NoError(t, err, env)
+		assert.NotEmpty(t, seeds.Tenants, env)
+	}
+}
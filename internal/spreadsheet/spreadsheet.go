@@ -0,0 +1,70 @@
+// Package spreadsheet reads tabular files uploaded for item imports and writes the files
+// of item exports. CSV is handled by the standard library; XLSX workbooks are read and
+// written directly as their XML parts, so only the first worksheet and plain cell values
+// (no formulas, merged cells or rich formatting) are supported.
+package spreadsheet
+
+import (
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+)
+
+// Formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// utf8BOM is written by spreadsheet programs at the start of UTF-8 CSV files
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// DetectFormat picks the format of an uploaded file from its name, falling back to its
+// content: XLSX files are ZIP archives.
+func DetectFormat(filename string, head []byte) (string, error) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".csv", ".txt":
+		return FormatCSV, nil
+	case ".xlsx":
+		return FormatXLSX, nil
+	case ".xls":
+		return "", fmt.Errorf("legacy .xls workbooks are not supported; save the file as .xlsx or .csv")
+	}
+
+	if bytes.HasPrefix(head, []byte("PK\x03\x04")) {
+		return FormatXLSX, nil
+	}
+	return FormatCSV, nil
+}
+
+// Read returns the rows of a file in the given format. Rows may have different lengths.
+func Read(format string, data []byte) ([][]string, error) {
+	switch format {
+	case FormatCSV:
+		return ReadCSV(bytes.NewReader(data))
+	case FormatXLSX:
+		return ReadXLSX(bytes.NewReader(data), int64(len(data)))
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// ReadCSV returns the records of a CSV file, skipping a leading byte order mark
+func ReadCSV(r io.Reader) ([][]string, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	reader := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM)))
+	reader.FieldsPerRecord = -1
+
+	rows, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSV: %w", err)
+	}
+	return rows, nil
+}
@@ -0,0 +1,154 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectFormat(t *testing.T) {
+	tests := []struct {
+		filename string
+		head     []byte
+		want     string
+		wantErr  bool
+	}{
+		{"orders.csv", nil, FormatCSV, false},
+		{"Orders.XLSX", nil, FormatXLSX, false},
+		{"upload", []byte("PK\x03\x04rest"), FormatXLSX, false},
+		{"upload", []byte("name,price"), FormatCSV, false},
+		{"orders.xls", nil, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.filename, func(t *testing.T) {
+			got, err := DetectFormat(tt.filename, tt.head)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestReadCSV(t *testing.T) {
+	rows, err := Read(FormatCSV, []byte("\xEF\xBB\xBFname,price\n\"Desk, oak\",120\nLamp\n"))
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{{"name", "price"}, {"Desk, oak", "120"}, {"Lamp"}}, rows)
+
+	_, err = Read(FormatCSV, []byte("name\n\"unterminated\n"))
+	assert.ErrorContains(t, err, "invalid CSV")
+}
+
+// buildXLSX zips the given parts into a workbook
+func buildXLSX(t *testing.T, parts map[string]string) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range parts {
+		w, err := zw.Create(name)
+		require.NoError(t, err)
+		_, err = w.Write([]byte(content))
+		require.NoError(t, err)
+	}
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestReadXLSX(t *testing.T) {
+	data := buildXLSX(t, map[string]string{
+		"xl/workbook.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">
+  <sheets><sheet name="Orders" sheetId="1" r:id="rId7"/></sheets>
+</workbook>`,
+		"xl/_rels/workbook.xml.rels": `<?xml version="1.0" encoding="UTF-8"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+  <Relationship Id="rId7" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/orders.xml"/>
+</Relationships>`,
+		"xl/sharedStrings.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <si><t>name</t></si><si><t>price</t></si><si><t>paid</t></si><si><t>due</t></si>
+  <si><r><t>Desk, </t></r><r><t>oak</t></r></si>
+</sst>`,
+		"xl/styles.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <numFmts><numFmt numFmtId="164" formatCode="yyyy\-mm\-dd hh:mm"/></numFmts>
+  <cellXfs><xf numFmtId="0"/><xf numFmtId="14"/><xf numFmtId="164"/></cellXfs>
+</styleSheet>`,
+		"xl/worksheets/orders.xml": `<?xml version="1.0" encoding="UTF-8"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">
+  <sheetData>
+    <row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c><c r="C1" t="s"><v>2</v></c><c r="D1" t="s"><v>3</v></c><c r="E1" t="s"><v>3</v></c></row>
+    <row r="2"><c r="A2" t="s"><v>4</v></c><c r="B2"><v>120.5</v></c><c r="C2" t="b"><v>1</v></c><c r="D2" s="1"><v>45413</v></c><c r="E2" s="2"><v>45413.5</v></c></row>
+    <row r="3"><c r="A3" t="inlineStr"><is><t>Lamp</t></is></c><c r="D3" s="1"><v>45414</v></c></row>
+  </sheetData>
+</worksheet>`,
+	})
+
+	rows, err := Read(FormatXLSX, data)
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"name", "price", "paid", "due", "due"},
+		{"Desk, oak", "120.5", "true", "2024-05-01", "2024-05-01T12:00:00Z"},
+		{"Lamp", "", "", "2024-05-02"},
+	}, rows)
+}
+
+func TestReadXLSX_Invalid(t *testing.T) {
+	_, err := Read(FormatXLSX, []byte("not a zip"))
+	assert.ErrorContains(t, err, "invalid XLSX file")
+
+	_, err = Read(FormatXLSX, buildXLSX(t, map[string]string{"docProps/app.xml": "<x/>"}))
+	assert.ErrorContains(t, err, "xl/workbook.xml is missing")
+}
+
+func TestColumnIndex(t *testing.T) {
+	for ref, want := range map[string]int{"A1": 0, "Z9": 25, "AA10": 26, "AB3": 27} {
+		got, err := columnIndex(ref)
+		require.NoError(t, err)
+		assert.Equal(t, want, got, ref)
+	}
+	_, err := columnIndex("12")
+	assert.Error(t, err)
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewCSVWriter(&buf)
+	require.NoError(t, w.Write([]interface{}{"name", "price", "tags"}))
+	require.NoError(t, w.Write([]interface{}{"Desk, oak", 120.5, []interface{}{"office"}}))
+	require.NoError(t, w.Write([]interface{}{"Lamp", nil, nil}))
+	require.NoError(t, w.Close())
+
+	assert.Equal(t, "name,price,tags\n\"Desk, oak\",120.5,\"[\"\"office\"\"]\"\nLamp,,\n", buf.String())
+}
+
+func TestWriteXLSX_RoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := NewWriter(FormatXLSX, &buf)
+	require.NoError(t, err)
+	require.NoError(t, w.Write([]interface{}{"name", "price", "active", "notes"}))
+	require.NoError(t, w.Write([]interface{}{"Desk <oak>", 120, true, nil}))
+	require.NoError(t, w.Write([]interface{}{"Lamp", 12.25, false, "line\none"}))
+	require.NoError(t, w.Close())
+
+	rows, err := Read(FormatXLSX, buf.Bytes())
+	require.NoError(t, err)
+	assert.Equal(t, [][]string{
+		{"name", "price", "active", "notes"},
+		{"Desk <oak>", "120", "true"},
+		{"Lamp", "12.25", "false", "line\none"},
+	}, rows)
+}
+
+func TestColumnName(t *testing.T) {
+	assert.Equal(t, "A", columnName(0))
+	assert.Equal(t, "Z", columnName(25))
+	assert.Equal(t, "AA", columnName(26))
+	assert.Equal(t, "AZ", columnName(51))
+	assert.Equal(t, "BA", columnName(52))
+}
@@ -0,0 +1,191 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Writer writes rows of a tabular file one at a time, so exports can be streamed
+type Writer interface {
+	// Write writes one row. Values are formatted with CellText; XLSX writers keep numbers
+	// and booleans typed.
+	Write(row []interface{}) error
+	// Close flushes the file. The underlying writer is not closed.
+	Close() error
+}
+
+// NewWriter creates a Writer for the given format
+func NewWriter(format string, w io.Writer) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return NewCSVWriter(w), nil
+	case FormatXLSX:
+		return NewXLSXWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported format '%s'", format)
+	}
+}
+
+// CellText formats a value read from the database as cell text: nil is empty, times are
+// RFC 3339, and maps and slices (JSON columns) are encoded as JSON
+func CellText(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case float32:
+		return strconv.FormatFloat(float64(v), 'f', -1, 32)
+	case map[string]interface{}, []interface{}:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(encoded)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// csvWriter writes CSV files
+type csvWriter struct {
+	writer *csv.Writer
+	record []string
+}
+
+// NewCSVWriter creates a Writer that writes CSV to w
+func NewCSVWriter(w io.Writer) Writer {
+	return &csvWriter{writer: csv.NewWriter(w)}
+}
+
+func (w *csvWriter) Write(row []interface{}) error {
+	w.record = w.record[:0]
+	for _, value := range row {
+		w.record = append(w.record, CellText(value))
+	}
+	return w.writer.Write(w.record)
+}
+
+func (w *csvWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// Static parts of a workbook with a single worksheet
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types"><Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/><Default Extension="xml" ContentType="application/xml"/><Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/><Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/></Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/></Relationships>`
+	xlsxWorkbookPart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets></workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships"><Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/></Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxWriter writes a workbook with one worksheet. Strings are stored inline rather than
+// in a shared string table, so rows can be written as they come.
+type xlsxWriter struct {
+	archive *zip.Writer
+	sheet   io.Writer
+	rows    int
+}
+
+// NewXLSXWriter creates a Writer that writes an XLSX workbook to w
+func NewXLSXWriter(w io.Writer) (Writer, error) {
+	archive := zip.NewWriter(w)
+	parts := []struct{ name, content string }{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbookPart},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, part := range parts {
+		file, err := archive.Create(part.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := io.WriteString(file, part.content); err != nil {
+			return nil, err
+		}
+	}
+
+	// The worksheet is the last part, so its rows can be streamed into it
+	sheet, err := archive.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.WriteString(sheet, xlsxSheetStart); err != nil {
+		return nil, err
+	}
+	return &xlsxWriter{archive: archive, sheet: sheet}, nil
+}
+
+func (w *xlsxWriter) Write(row []interface{}) error {
+	w.rows++
+	if _, err := fmt.Fprintf(w.sheet, `<row r="%d">`, w.rows); err != nil {
+		return err
+	}
+	for i, value := range row {
+		if value == nil {
+			continue
+		}
+		ref := columnName(i) + strconv.Itoa(w.rows)
+
+		var err error
+		switch v := value.(type) {
+		case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s"><v>%s</v></c>`, ref, CellText(v))
+		case bool:
+			flag := "0"
+			if v {
+				flag = "1"
+			}
+			_, err = fmt.Fprintf(w.sheet, `<c r="%s" t="b"><v>%s</v></c>`, ref, flag)
+		default:
+			if _, err = fmt.Fprintf(w.sheet, `<c r="%s" t="inlineStr"><is><t xml:space="preserve">`, ref); err != nil {
+				return err
+			}
+			if err = xml.EscapeText(w.sheet, []byte(CellText(v))); err != nil {
+				return err
+			}
+			_, err = io.WriteString(w.sheet, `</t></is></c>`)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w.sheet, `</row>`)
+	return err
+}
+
+func (w *xlsxWriter) Close() error {
+	if _, err := io.WriteString(w.sheet, xlsxSheetEnd); err != nil {
+		return err
+	}
+	return w.archive.Close()
+}
+
+// columnName converts a zero-based column index to its letters: 0 is "A", 26 is "AA"
+func columnName(index int) string {
+	name := ""
+	for index++; index > 0; index = (index - 1) / 26 {
+		name = string(rune('A'+(index-1)%26)) + name
+	}
+	return name
+}
@@ -0,0 +1,294 @@
+package spreadsheet
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// maxXLSXPartSize bounds the uncompressed size of a single workbook part, so a small
+// upload cannot expand into gigabytes of XML
+const maxXLSXPartSize = 256 << 20
+
+// xlsxWorkbook is xl/workbook.xml
+type xlsxWorkbook struct {
+	Properties struct {
+		Date1904 bool `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+// xlsxRelationships is xl/_rels/workbook.xml.rels
+type xlsxRelationships struct {
+	Relationships []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// xlsxText is a shared or inline string: plain text, or runs of formatted text
+type xlsxText struct {
+	Text string `xml:"t"`
+	Runs []struct {
+		Text string `xml:"t"`
+	} `xml:"r"`
+}
+
+// String returns the text, joining formatted runs
+func (t xlsxText) String() string {
+	if len(t.Runs) == 0 {
+		return t.Text
+	}
+	var b strings.Builder
+	for _, run := range t.Runs {
+		b.WriteString(run.Text)
+	}
+	return b.String()
+}
+
+// xlsxSharedStrings is xl/sharedStrings.xml
+type xlsxSharedStrings struct {
+	Items []xlsxText `xml:"si"`
+}
+
+// xlsxStyles is xl/styles.xml; only number formats are needed to recognise dates
+type xlsxStyles struct {
+	NumberFormats []struct {
+		ID   int    `xml:"numFmtId,attr"`
+		Code string `xml:"formatCode,attr"`
+	} `xml:"numFmts>numFmt"`
+	CellFormats []struct {
+		NumberFormatID int `xml:"numFmtId,attr"`
+	} `xml:"cellXfs>xf"`
+}
+
+// xlsxWorksheet is a worksheet part
+type xlsxWorksheet struct {
+	Rows []struct {
+		Cells []struct {
+			Ref    string   `xml:"r,attr"`
+			Type   string   `xml:"t,attr"`
+			Style  int      `xml:"s,attr"`
+			Value  string   `xml:"v"`
+			Inline xlsxText `xml:"is"`
+		} `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+// ReadXLSX returns the rows of the first worksheet of an XLSX workbook. Cells hold their
+// displayed text for strings and booleans ("true"/"false"), the stored number for numbers,
+// and dates formatted as "2006-01-02" or RFC 3339 date-times.
+func ReadXLSX(r io.ReaderAt, size int64) ([][]string, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	parts := make(map[string]*zip.File, len(archive.File))
+	for _, file := range archive.File {
+		parts[strings.TrimPrefix(file.Name, "/")] = file
+	}
+
+	var workbook xlsxWorkbook
+	if err := decodePart(parts, "xl/workbook.xml", &workbook, true); err != nil {
+		return nil, err
+	}
+	sheetPath, err := firstSheetPath(parts, workbook)
+	if err != nil {
+		return nil, err
+	}
+
+	var shared xlsxSharedStrings
+	if err := decodePart(parts, "xl/sharedStrings.xml", &shared, false); err != nil {
+		return nil, err
+	}
+	var styles xlsxStyles
+	if err := decodePart(parts, "xl/styles.xml", &styles, false); err != nil {
+		return nil, err
+	}
+	dateStyles := dateStyleSet(styles)
+
+	var sheet xlsxWorksheet
+	if err := decodePart(parts, sheetPath, &sheet, true); err != nil {
+		return nil, err
+	}
+
+	rows := make([][]string, 0, len(sheet.Rows))
+	for _, sheetRow := range sheet.Rows {
+		var row []string
+		for i, cell := range sheetRow.Cells {
+			column := i
+			if cell.Ref != "" {
+				if column, err = columnIndex(cell.Ref); err != nil {
+					return nil, err
+				}
+			}
+			for len(row) <= column {
+				row = append(row, "")
+			}
+
+			value, err := cellValue(cell.Type, cell.Value, cell.Inline, shared, dateStyles[cell.Style], workbook.Properties.Date1904)
+			if err != nil {
+				return nil, fmt.Errorf("cell %s: %w", cell.Ref, err)
+			}
+			row[column] = value
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// cellValue converts the stored value of a cell to text
+func cellValue(cellType, raw string, inline xlsxText, shared xlsxSharedStrings, isDate, date1904 bool) (string, error) {
+	switch cellType {
+	case "s":
+		index, err := strconv.Atoi(raw)
+		if err != nil || index < 0 || index >= len(shared.Items) {
+			return "", fmt.Errorf("invalid shared string reference '%s'", raw)
+		}
+		return shared.Items[index].String(), nil
+	case "inlineStr":
+		return inline.String(), nil
+	case "b":
+		return strconv.FormatBool(raw == "1"), nil
+	case "str", "e":
+		return raw, nil
+	default:
+		if isDate && raw != "" {
+			serial, err := strconv.ParseFloat(raw, 64)
+			if err != nil {
+				return "", fmt.Errorf("invalid date '%s'", raw)
+			}
+			return formatSerialDate(serial, date1904), nil
+		}
+		return raw, nil
+	}
+}
+
+// formatSerialDate converts an Excel serial date (days since the workbook's epoch) to a
+// date or an RFC 3339 date-time
+func formatSerialDate(serial float64, date1904 bool) string {
+	epoch := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		epoch = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	}
+
+	days := math.Floor(serial)
+	seconds := math.Round((serial - days) * 86400)
+	t := epoch.AddDate(0, 0, int(days)).Add(time.Duration(seconds) * time.Second)
+	if seconds == 0 {
+		return t.Format("2006-01-02")
+	}
+	return t.Format(time.RFC3339)
+}
+
+// dateStyleSet returns which cell styles format numbers as dates or times
+func dateStyleSet(styles xlsxStyles) map[int]bool {
+	customDates := make(map[int]bool, len(styles.NumberFormats))
+	for _, format := range styles.NumberFormats {
+		customDates[format.ID] = isDateFormat(format.Code)
+	}
+
+	dates := make(map[int]bool)
+	for i, format := range styles.CellFormats {
+		id := format.NumberFormatID
+		if (id >= 14 && id <= 22) || (id >= 45 && id <= 47) || customDates[id] {
+			dates[i] = true
+		}
+	}
+	return dates
+}
+
+// isDateFormat reports whether a custom number format code shows a date or time: it uses
+// y, m, d, h or s outside of quoted text and [bracketed] sections
+func isDateFormat(code string) bool {
+	inQuote, inBracket := false, false
+	for _, r := range strings.ToLower(code) {
+		switch {
+		case r == '"':
+			inQuote = !inQuote
+		case inQuote:
+		case r == '[':
+			inBracket = true
+		case r == ']':
+			inBracket = false
+		case inBracket:
+		case strings.ContainsRune("ymdhs", r):
+			return true
+		}
+	}
+	return false
+}
+
+// columnIndex converts the column letters of a cell reference such as "AB12" to a
+// zero-based index
+func columnIndex(ref string) (int, error) {
+	index := 0
+	letters := 0
+	for _, r := range ref {
+		if r < 'A' || r > 'Z' {
+			break
+		}
+		index = index*26 + int(r-'A'+1)
+		letters++
+	}
+	if letters == 0 || letters > 3 {
+		return 0, fmt.Errorf("invalid cell reference '%s'", ref)
+	}
+	return index - 1, nil
+}
+
+// firstSheetPath finds the part of the first worksheet listed in the workbook
+func firstSheetPath(parts map[string]*zip.File, workbook xlsxWorkbook) (string, error) {
+	if len(workbook.Sheets) == 0 {
+		return "", fmt.Errorf("workbook has no worksheets")
+	}
+
+	var rels xlsxRelationships
+	if err := decodePart(parts, "xl/_rels/workbook.xml.rels", &rels, false); err != nil {
+		return "", err
+	}
+	for _, rel := range rels.Relationships {
+		if rel.ID != workbook.Sheets[0].ID {
+			continue
+		}
+		if strings.HasPrefix(rel.Target, "/") {
+			return strings.TrimPrefix(rel.Target, "/"), nil
+		}
+		return path.Join("xl", rel.Target), nil
+	}
+	return "xl/worksheets/sheet1.xml", nil
+}
+
+// decodePart decodes the XML part name into v. Missing optional parts leave v empty.
+func decodePart(parts map[string]*zip.File, name string, v interface{}, required bool) error {
+	file, ok := parts[name]
+	if !ok {
+		if required {
+			return fmt.Errorf("invalid XLSX file: %s is missing", name)
+		}
+		return nil
+	}
+	if file.UncompressedSize64 > maxXLSXPartSize {
+		return fmt.Errorf("invalid XLSX file: %s is too large", name)
+	}
+
+	rc, err := file.Open()
+	if err != nil {
+		return fmt.Errorf("invalid XLSX file: %w", err)
+	}
+	defer rc.Close()
+
+	if err := xml.NewDecoder(io.LimitReader(rc, maxXLSXPartSize)).Decode(v); err != nil {
+		return fmt.Errorf("invalid XLSX file: %s: %w", name, err)
+	}
+	return nil
+}
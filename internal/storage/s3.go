@@ -0,0 +1,166 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"go-rbac-api/internal/awsv4"
+)
+
+const (
+	emptyPayloadHash    = awsv4.EmptyPayloadHash
+	unsignedPayloadHash = "UNSIGNED-PAYLOAD"
+	maxS3ErrorBody      = 1024
+)
+
+// S3Options configures an S3 storage backend
+type S3Options struct {
+	Bucket          string
+	Region          string
+	Endpoint        string // Defaults to https://s3.<region>.amazonaws.com
+	AccessKeyID     string
+	SecretAccessKey string
+	ForcePathStyle  bool // Address objects as <endpoint>/<bucket>/<key> (MinIO and most S3-compatible services)
+}
+
+// S3 stores files in an S3 (or S3-compatible) bucket. Requests are signed with AWS
+// Signature Version 4.
+type S3 struct {
+	opts     S3Options
+	endpoint *url.URL
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewS3 creates an S3 storage backend
+func NewS3(opts S3Options) (*S3, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("S3_BUCKET is required for s3 storage")
+	}
+	if opts.AccessKeyID == "" || opts.SecretAccessKey == "" {
+		return nil, fmt.Errorf("S3_ACCESS_KEY_ID and S3_SECRET_ACCESS_KEY are required for s3 storage")
+	}
+	if opts.Region == "" {
+		opts.Region = "us-east-1"
+	}
+	if opts.Endpoint == "" {
+		opts.Endpoint = fmt.Sprintf("https://s3.%s.amazonaws.com", opts.Region)
+	}
+
+	endpoint, err := url.Parse(opts.Endpoint)
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint '%s'", opts.Endpoint)
+	}
+
+	return &S3{
+		opts:     opts,
+		endpoint: endpoint,
+		client:   &http.Client{Timeout: 5 * time.Minute},
+		now:      time.Now,
+	}, nil
+}
+
+// Name returns the driver name
+func (s *S3) Name() string {
+	return DriverS3
+}
+
+// Put uploads the file with a single PUT request
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := s.newRequest(ctx, http.MethodPut, key, r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := s.do(req, unsignedPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Get downloads the file
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := s.newRequest(ctx, http.MethodGet, key, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+// Delete removes the file. S3 reports success for keys that do not exist.
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	resp, err := s.do(req, emptyPayloadHash)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// newRequest builds the request URL for key using virtual-hosted or path-style addressing
+func (s *S3) newRequest(ctx context.Context, method, key string, body io.Reader) (*http.Request, error) {
+	if err := validateKey(key); err != nil {
+		return nil, err
+	}
+
+	u := *s.endpoint
+	if s.opts.ForcePathStyle {
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + s.opts.Bucket + "/" + key
+	} else {
+		u.Host = s.opts.Bucket + "." + u.Host
+		u.Path = strings.TrimSuffix(u.Path, "/") + "/" + key
+	}
+
+	return http.NewRequestWithContext(ctx, method, u.String(), body)
+}
+
+// do signs and sends req, turning non-2xx responses into errors
+func (s *S3) do(req *http.Request, payloadHash string) (*http.Response, error) {
+	s.sign(req, payloadHash, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("s3 request failed: %w", err)
+	}
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxS3ErrorBody))
+	return nil, fmt.Errorf("s3 %s %s: status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(body)))
+}
+
+// sign adds the AWS Signature Version 4 Authorization header to req
+func (s *S3) sign(req *http.Request, payloadHash string, now time.Time) {
+	awsv4.Sign(req, awsv4.Credentials{
+		AccessKeyID:     s.opts.AccessKeyID,
+		SecretAccessKey: s.opts.SecretAccessKey,
+		Region:          s.opts.Region,
+		Service:         "s3",
+	}, payloadHash, now)
+}
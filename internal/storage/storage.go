@@ -0,0 +1,66 @@
+// Package storage stores uploaded asset files. Files are addressed by a key such as
+// "<tenant_id>/<asset_id>.png"; the backend decides where the bytes actually live.
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go-rbac-api/internal/config"
+)
+
+// Driver names accepted in STORAGE_DRIVER
+const (
+	DriverLocal = "local"
+	DriverS3    = "s3"
+)
+
+// ErrNotFound is returned by Get when no file is stored under the key
+var ErrNotFound = errors.New("file not found")
+
+// Storage is implemented by every asset storage backend
+type Storage interface {
+	// Name returns the driver name recorded with each asset
+	Name() string
+	// Put stores size bytes read from r under key
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Get opens the file stored under key. The caller must close it.
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	// Delete removes the file stored under key. Deleting a missing file is not an error.
+	Delete(ctx context.Context, key string) error
+}
+
+// New creates the storage backend selected by cfg.StorageDriver
+func New(cfg *config.Config) (Storage, error) {
+	switch strings.ToLower(cfg.StorageDriver) {
+	case "", DriverLocal:
+		return NewLocal(cfg.StorageLocalPath)
+	case DriverS3:
+		return NewS3(S3Options{
+			Bucket:          cfg.S3Bucket,
+			Region:          cfg.S3Region,
+			Endpoint:        cfg.S3Endpoint,
+			AccessKeyID:     cfg.S3AccessKeyID,
+			SecretAccessKey: cfg.S3SecretAccessKey,
+			ForcePathStyle:  cfg.S3ForcePathStyle,
+		})
+	default:
+		return nil, fmt.Errorf("unknown storage driver '%s'", cfg.StorageDriver)
+	}
+}
+
+// validateKey rejects keys that could escape the storage root
+func validateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, "\\") {
+		return fmt.Errorf("invalid storage key '%s'", key)
+	}
+	for _, part := range strings.Split(key, "/") {
+		if part == "" || part == "." || part == ".." {
+			return fmt.Errorf("invalid storage key '%s'", key)
+		}
+	}
+	return nil
+}
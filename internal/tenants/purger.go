@@ -0,0 +1,78 @@
+// Package tenants runs background tasks for the tenant lifecycle.
+//
+// The Purger periodically removes tenants whose deletion grace period (purge_at) has
+// passed. Until then DELETE /tenants/:id only deactivates a tenant, so it can still be
+// restored.
+package tenants
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"go-rbac-api/internal/db"
+	"go-rbac-api/internal/rbac"
+)
+
+const (
+	pollInterval   = time.Hour // How often deleted tenants are looked for
+	purgeBatchSize = 20        // Tenants purged per pass
+)
+
+// Purger removes deleted tenants once they are due
+type Purger struct {
+	db *db.DB
+}
+
+// NewPurger creates a Purger
+func NewPurger(db *db.DB) *Purger {
+	return &Purger{db: db}
+}
+
+// Start runs the purger until ctx is cancelled
+func (p *Purger) Start(ctx context.Context) {
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		p.PurgeDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// PurgeDue removes every tenant whose purge_at has passed and returns how many it removed
+func (p *Purger) PurgeDue(ctx context.Context) int {
+	purged := 0
+	for ctx.Err() == nil {
+		tenants, err := p.db.Queries.GetTenantsDueForPurge(ctx, purgeBatchSize)
+		if err != nil {
+			slog.Error("failed to load tenants due for purge", "error", err)
+			break
+		}
+
+		failed := 0
+		for _, tenant := range tenants {
+			if err := p.db.Queries.DeleteTenant(ctx, tenant.ID); err != nil {
+				slog.Error("failed to purge tenant", "tenant_id", tenant.ID, "error", err)
+				failed++
+				continue
+			}
+			purged++
+		}
+		// Tenants that cannot be deleted would be returned again; retry them next pass
+		if len(tenants) < purgeBatchSize || failed > 0 {
+			break
+		}
+	}
+
+	if purged > 0 {
+		// The purged tenants' roles and permissions are gone with them
+		rbac.InvalidatePermissions()
+	}
+	return purged
+}
@@ -0,0 +1,297 @@
+// Package webhooks delivers item events to tenant-configured HTTP endpoints.
+//
+// When an item event is published, the Dispatcher records one webhook_deliveries row for
+// every active webhook of the tenant whose events and collections match. A background
+// worker then POSTs the payload to each endpoint, signing the body with the webhook's
+// secret. Failed deliveries are retried with exponential backoff until the configured
+// number of attempts is exhausted. Because deliveries are queued in the database, pending
+// retries survive restarts.
+//
+// Every request carries:
+//
+//	X-Basin-Event:     item.create | item.update | item.delete | api_key.expiring | api_key.rotation_due
+//	X-Basin-Delivery:  the delivery ID (stable across retries)
+//	X-Basin-Signature: t=<unix timestamp>,v1=<hex HMAC-SHA256 of "<timestamp>.<body>">
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"go-rbac-api/internal/config"
+	"go-rbac-api/internal/db"
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+)
+
+// Delivery statuses stored in webhook_deliveries.status
+const (
+	StatusPending    = "pending"
+	StatusProcessing = "processing"
+	StatusSuccess    = "success"
+	StatusFailed     = "failed"
+)
+
+const (
+	pollInterval      = 5 * time.Second  // How often the worker looks for due retries
+	claimBatchSize    = 20               // Deliveries claimed per worker pass
+	staleClaimTimeout = 10 * time.Minute // Claims older than this are assumed abandoned
+	maxRetryDelay     = 6 * time.Hour    // Upper bound for the exponential backoff
+	maxResponseBody   = 2048             // Bytes of the response body kept in the log
+)
+
+// Dispatcher queues and sends webhook deliveries
+type Dispatcher struct {
+	db          *db.DB
+	client      *http.Client
+	maxAttempts int
+	retryDelay  time.Duration
+	wake        chan struct{}
+}
+
+// NewDispatcher creates a Dispatcher using the webhook settings from cfg
+func NewDispatcher(db *db.DB, cfg *config.Config) *Dispatcher {
+	maxAttempts := cfg.WebhookMaxAttempts
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	return &Dispatcher{
+		db:          db,
+		client:      &http.Client{Timeout: cfg.WebhookTimeout},
+		maxAttempts: maxAttempts,
+		retryDelay:  cfg.WebhookRetryDelay,
+		wake:        make(chan struct{}, 1),
+	}
+}
+
+// HandleEvent queues a delivery for every matching webhook. It is meant to be
+// subscribed to the events bus.
+func (d *Dispatcher) HandleEvent(ctx context.Context, event events.Event) {
+	// The request that triggered the event may finish before the deliveries are queued
+	ctx = context.WithoutCancel(ctx)
+
+	hooks, err := d.db.Queries.GetActiveWebhooksByTenant(ctx, event.TenantID)
+	if err != nil {
+		log.Printf("webhooks: failed to load webhooks for tenant %s: %v", event.TenantID, err)
+		return
+	}
+
+	payload, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("webhooks: failed to encode %s event: %v", event.Type, err)
+		return
+	}
+
+	queued := false
+	for _, hook := range hooks {
+		if !Matches(hook, event) {
+			continue
+		}
+
+		_, err := d.db.Queries.CreateWebhookDelivery(ctx, sqlc.CreateWebhookDeliveryParams{
+			WebhookID:  hook.ID,
+			TenantID:   event.TenantID,
+			Event:      event.Type,
+			Collection: event.Collection,
+			Payload:    payload,
+		})
+		if err != nil {
+			log.Printf("webhooks: failed to queue delivery for webhook %s: %v", hook.ID, err)
+			continue
+		}
+		queued = true
+	}
+
+	if queued {
+		select {
+		case d.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Start runs the delivery worker until ctx is cancelled
+func (d *Dispatcher) Start(ctx context.Context) {
+	// Deliveries claimed by a worker that died mid-send would otherwise stay stuck
+	staleBefore := sql.NullTime{Time: time.Now().Add(-staleClaimTimeout), Valid: true}
+	if err := d.db.Queries.ReleaseStaleWebhookDeliveries(ctx, staleBefore); err != nil {
+		log.Printf("webhooks: failed to release stale deliveries: %v", err)
+	}
+
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		d.processDue(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-d.wake:
+		}
+	}
+}
+
+// processDue claims and sends due deliveries until none are left
+func (d *Dispatcher) processDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := d.db.Queries.ClaimDueWebhookDeliveries(ctx, claimBatchSize)
+		if err != nil {
+			log.Printf("webhooks: failed to claim deliveries: %v", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		for _, delivery := range deliveries {
+			d.deliver(ctx, delivery)
+		}
+	}
+}
+
+// deliver sends one delivery attempt and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery sqlc.WebhookDelivery) {
+	result := sqlc.FinishWebhookDeliveryParams{
+		ID:            delivery.ID,
+		NextAttemptAt: time.Now(),
+	}
+
+	hook, err := d.db.Queries.GetWebhookByID(ctx, delivery.WebhookID)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = sql.NullString{String: "webhook not found: " + err.Error(), Valid: true}
+		d.finish(ctx, result)
+		return
+	}
+
+	statusCode, body, err := d.send(ctx, hook, delivery)
+	if statusCode != 0 {
+		result.ResponseStatus = sql.NullInt32{Int32: int32(statusCode), Valid: true}
+		result.ResponseBody = sql.NullString{String: body, Valid: true}
+	}
+
+	switch {
+	case err == nil && statusCode >= 200 && statusCode < 300:
+		result.Status = StatusSuccess
+		result.DeliveredAt = sql.NullTime{Time: time.Now(), Valid: true}
+	default:
+		if err != nil {
+			result.Error = sql.NullString{String: err.Error(), Valid: true}
+		} else {
+			result.Error = sql.NullString{String: fmt.Sprintf("unexpected status %d", statusCode), Valid: true}
+		}
+
+		if int(delivery.Attempts) >= d.maxAttempts {
+			result.Status = StatusFailed
+		} else {
+			result.Status = StatusPending
+			result.NextAttemptAt = time.Now().Add(RetryDelay(d.retryDelay, int(delivery.Attempts)))
+		}
+	}
+
+	d.finish(ctx, result)
+}
+
+// send POSTs the signed payload and returns the response status and (truncated) body
+func (d *Dispatcher) send(ctx context.Context, hook sqlc.Webhook, delivery sqlc.WebhookDelivery) (int, string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.Url, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, "", fmt.Errorf("invalid webhook request: %w", err)
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Basin-Webhooks/1.0")
+	req.Header.Set("X-Basin-Event", delivery.Event)
+	req.Header.Set("X-Basin-Delivery", delivery.ID.String())
+	req.Header.Set("X-Basin-Signature", Sign(hook.Secret, timestamp, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, "", err
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	return resp.StatusCode, string(body), nil
+}
+
+// finish stores the result of a delivery attempt
+func (d *Dispatcher) finish(ctx context.Context, result sqlc.FinishWebhookDeliveryParams) {
+	if err := d.db.Queries.FinishWebhookDelivery(ctx, result); err != nil {
+		log.Printf("webhooks: failed to record delivery %s: %v", result.ID, err)
+	}
+}
+
+// Matches reports whether hook subscribes to event. An empty collections list or a "*"
+// event matches everything.
+func Matches(hook sqlc.Webhook, event events.Event) bool {
+	if hook.TenantID != event.TenantID {
+		return false
+	}
+
+	eventMatch := false
+	for _, e := range hook.Events {
+		if e == "*" || e == event.Type {
+			eventMatch = true
+			break
+		}
+	}
+	if !eventMatch {
+		return false
+	}
+
+	if len(hook.Collections) == 0 {
+		return true
+	}
+	for _, c := range hook.Collections {
+		if c == "*" || c == event.Collection {
+			return true
+		}
+	}
+	return false
+}
+
+// Sign returns the X-Basin-Signature header value for body. Receivers verify a delivery by
+// recomputing the HMAC over "<t>.<body>" with their secret and rejecting stale timestamps.
+func Sign(secret string, timestamp int64, body []byte) string {
+	ts := strconv.FormatInt(timestamp, 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(ts))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return "t=" + ts + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// RetryDelay returns the wait before the next attempt after the given number of failed
+// attempts: base, 2*base, 4*base, ... capped at maxRetryDelay.
+func RetryDelay(base time.Duration, attempts int) time.Duration {
+	if attempts < 1 {
+		attempts = 1
+	}
+
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
@@ -0,0 +1,54 @@
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+	"time"
+
+	sqlc "go-rbac-api/internal/db/sqlc"
+	"go-rbac-api/internal/events"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatches(t *testing.T) {
+	tenantID := uuid.New()
+	event := events.Event{Type: events.ItemCreate, TenantID: tenantID, Collection: "orders"}
+
+	tests := []struct {
+		name string
+		hook sqlc.Webhook
+		want bool
+	}{
+		{"All Collections", sqlc.Webhook{TenantID: tenantID, Events: []string{"item.create"}}, true},
+		{"Wildcard Event", sqlc.Webhook{TenantID: tenantID, Events: []string{"*"}, Collections: []string{"orders"}}, true},
+		{"Other Event", sqlc.Webhook{TenantID: tenantID, Events: []string{"item.delete"}}, false},
+		{"Other Collection", sqlc.Webhook{TenantID: tenantID, Events: []string{"item.create"}, Collections: []string{"customers"}}, false},
+		{"Other Tenant", sqlc.Webhook{TenantID: uuid.New(), Events: []string{"*"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, Matches(tt.hook, event))
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"event":"item.create"}`)
+	signature := Sign("secret", 1700000000, body)
+
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte("1700000000." + string(body)))
+	assert.Equal(t, "t=1700000000,v1="+hex.EncodeToString(mac.Sum(nil)), signature)
+}
+
+func TestRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, RetryDelay(30*time.Second, 1))
+	assert.Equal(t, 60*time.Second, RetryDelay(30*time.Second, 2))
+	assert.Equal(t, 120*time.Second, RetryDelay(30*time.Second, 3))
+	assert.Equal(t, maxRetryDelay, RetryDelay(30*time.Second, 50))
+}
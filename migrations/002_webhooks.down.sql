@@ -0,0 +1,6 @@
+-- Reverts 002_webhooks.sql
+
+DELETE FROM permissions WHERE table_name IN ('webhooks', 'webhook_deliveries');
+
+DROP TABLE IF EXISTS webhook_deliveries;
+DROP TABLE IF EXISTS webhooks;
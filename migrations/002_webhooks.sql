@@ -0,0 +1,57 @@
+-- Webhooks Migration
+-- Adds outgoing webhooks for item events and a delivery log used for retries and auditing
+
+-- Webhook subscriptions, one row per endpoint per tenant
+CREATE TABLE IF NOT EXISTS webhooks (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL DEFAULT '{}', -- 'item.create', 'item.update', 'item.delete' or '*'
+    collections TEXT[] NOT NULL DEFAULT '{}', -- empty means every collection
+    secret VARCHAR(255) NOT NULL, -- HMAC-SHA256 signing secret
+    is_active BOOLEAN DEFAULT true,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- One row per event sent to a webhook, updated on every attempt
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    collection VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'processing', 'success', 'failed'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhooks_tenant_id ON webhooks(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_tenant_id ON webhook_deliveries(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+
+-- Admin permissions for the main tenant
+INSERT INTO permissions (role_id, table_name, action, tenant_id)
+SELECT '550e8400-e29b-41d4-a716-446655440001', p.table_name, p.action, '6e68062f-c4c6-42df-9e01-e2d1081664f4'
+FROM (VALUES
+    ('webhooks', 'create'),
+    ('webhooks', 'read'),
+    ('webhooks', 'update'),
+    ('webhooks', 'delete'),
+    ('webhook_deliveries', 'read')
+) AS p(table_name, action)
+WHERE EXISTS (SELECT 1 FROM roles WHERE id = '550e8400-e29b-41d4-a716-446655440001')
+ON CONFLICT (role_id, table_name, action) DO NOTHING;
+
+COMMENT ON TABLE webhooks IS 'Outgoing webhook subscriptions for item events';
+COMMENT ON TABLE webhook_deliveries IS 'Webhook delivery log with retry state';
@@ -0,0 +1,6 @@
+-- Reverts 003_assets.sql
+-- Stored files are not removed from disk or S3
+
+DELETE FROM permissions WHERE table_name = 'assets';
+
+DROP TABLE IF EXISTS assets;
@@ -0,0 +1,35 @@
+-- Assets Migration
+-- Adds uploaded files (assets) stored on local disk or S3, referenced by "file" fields
+
+-- One row per uploaded file; the bytes live in the configured storage backend
+CREATE TABLE IF NOT EXISTS assets (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    storage VARCHAR(20) NOT NULL, -- 'local' or 's3'
+    storage_key TEXT NOT NULL, -- '<tenant_id>/<asset_id><ext>'
+    filename_download VARCHAR(255) NOT NULL,
+    title VARCHAR(255),
+    type VARCHAR(255) NOT NULL, -- MIME type
+    filesize BIGINT NOT NULL DEFAULT 0,
+    width INTEGER, -- images only
+    height INTEGER, -- images only
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_assets_tenant_id ON assets(tenant_id);
+
+-- Admin permissions for the main tenant
+INSERT INTO permissions (role_id, table_name, action, tenant_id)
+SELECT '550e8400-e29b-41d4-a716-446655440001', p.table_name, p.action, '6e68062f-c4c6-42df-9e01-e2d1081664f4'
+FROM (VALUES
+    ('assets', 'create'),
+    ('assets', 'read'),
+    ('assets', 'update'),
+    ('assets', 'delete')
+) AS p(table_name, action)
+WHERE EXISTS (SELECT 1 FROM roles WHERE id = '550e8400-e29b-41d4-a716-446655440001')
+ON CONFLICT (role_id, table_name, action) DO NOTHING;
+
+COMMENT ON TABLE assets IS 'Uploaded files and their storage location';
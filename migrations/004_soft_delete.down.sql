@@ -0,0 +1,4 @@
+-- Reverts 004_soft_delete.sql
+-- Rows already soft deleted keep their deleted_at value
+
+ALTER TABLE collections DROP COLUMN IF EXISTS soft_delete;
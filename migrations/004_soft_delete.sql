@@ -0,0 +1,6 @@
+-- Soft Delete Migration
+-- Lets collections keep deleted items in a trash so they can be restored
+
+-- When true, DELETE /items/:table/:id sets deleted_at on the item instead of removing it.
+-- The deleted_at column is added to the collection's data table when the flag is enabled.
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS soft_delete BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,3 @@
+-- Reverts 005_revisions.sql
+
+DROP TABLE IF EXISTS revisions;
@@ -0,0 +1,19 @@
+-- Revisions Migration
+-- Adds the item revision history used by GET /items/:table/:id/revisions and revert
+
+-- One row per create, update or delete of an item in a dynamic data table
+CREATE TABLE IF NOT EXISTS revisions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    collection VARCHAR(100) NOT NULL,
+    item_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL, -- 'create', 'update' or 'delete'
+    old_data JSONB, -- update: previous values of the changed fields; delete: the whole row
+    new_data JSONB, -- create: the inserted values; update: the changed fields
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_revisions_item ON revisions(tenant_id, collection, item_id, created_at DESC);
+
+COMMENT ON TABLE revisions IS 'Item change history with old and new values';
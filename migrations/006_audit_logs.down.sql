@@ -0,0 +1,5 @@
+-- Reverts 006_audit_logs.sql
+
+DELETE FROM permissions WHERE table_name = 'audit_logs';
+
+DROP TABLE IF EXISTS audit_logs;
@@ -0,0 +1,32 @@
+-- Audit Logs Migration
+-- Adds the audit trail of authentication events and item writes, readable by admins
+-- through GET /items/audit_logs
+
+-- One row per audited event; rows are never updated or deleted by the API
+CREATE TABLE IF NOT EXISTS audit_logs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID REFERENCES tenants(id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    api_key_id UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    action VARCHAR(50) NOT NULL, -- 'login', 'login_failed', 'api_key', 'api_key_failed', 'create', 'update', ...
+    table_name VARCHAR(100),
+    item_id VARCHAR(255),
+    result VARCHAR(20) NOT NULL, -- 'success' or 'failure'
+    status_code INTEGER,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    request_id VARCHAR(100),
+    details JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_tenant_created ON audit_logs(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs(user_id);
+
+-- Admin permissions for the main tenant (audit logs are read-only)
+INSERT INTO permissions (role_id, table_name, action, tenant_id)
+SELECT '550e8400-e29b-41d4-a716-446655440001', 'audit_logs', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'
+WHERE EXISTS (SELECT 1 FROM roles WHERE id = '550e8400-e29b-41d4-a716-446655440001')
+ON CONFLICT (role_id, table_name, action) DO NOTHING;
+
+COMMENT ON TABLE audit_logs IS 'Audit trail of authentication events and item writes';
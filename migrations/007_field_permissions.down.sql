@@ -0,0 +1,5 @@
+-- Reverts 007_field_permissions.sql
+-- Permissions become table-wide again
+
+ALTER TABLE permissions DROP COLUMN IF EXISTS write_fields;
+ALTER TABLE permissions DROP COLUMN IF EXISTS read_fields;
@@ -0,0 +1,8 @@
+-- Field Permissions Migration
+-- Splits field-level access into separate read and write lists so a role can see a
+-- field without being able to change it
+
+-- Fields returned by reads and accepted by writes. NULL (or empty) falls back to
+-- allowed_fields, so existing permissions keep working unchanged.
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS read_fields TEXT[];
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS write_fields TEXT[];
@@ -0,0 +1,6 @@
+-- Reverts 008_role_inheritance.sql
+-- Roles lose the permissions they inherited from their parents
+
+DROP INDEX IF EXISTS idx_roles_parent_id;
+ALTER TABLE roles DROP CONSTRAINT IF EXISTS roles_parent_not_self;
+ALTER TABLE roles DROP COLUMN IF EXISTS parent_id;
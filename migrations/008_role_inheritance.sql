@@ -0,0 +1,25 @@
+-- Role Inheritance Migration
+-- Lets a role declare a parent role whose permissions it inherits
+
+-- A role has every permission of its parent (and the parent's parent, ...); its own
+-- permissions take precedence for the same table and action. Parents must belong to the
+-- same tenant. Longer cycles are rejected when permissions are resolved.
+ALTER TABLE roles ADD COLUMN IF NOT EXISTS parent_id UUID REFERENCES roles(id) ON DELETE SET NULL;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'roles_parent_not_self') THEN
+        ALTER TABLE roles ADD CONSTRAINT roles_parent_not_self CHECK (parent_id IS NULL OR parent_id <> id);
+    END IF;
+END $$;
+
+CREATE INDEX IF NOT EXISTS idx_roles_parent_id ON roles(parent_id);
+
+-- Arrange the default roles of existing tenants as viewer <- editor <- manager <- admin.
+-- Roles that already have a parent are left alone.
+UPDATE roles child
+SET parent_id = parent.id, updated_at = NOW()
+FROM roles parent
+WHERE child.parent_id IS NULL
+  AND child.tenant_id = parent.tenant_id
+  AND (child.name, parent.name) IN (('editor', 'viewer'), ('manager', 'editor'), ('admin', 'manager'));
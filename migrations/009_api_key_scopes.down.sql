@@ -0,0 +1,4 @@
+-- Reverts 009_api_key_scopes.sql
+-- Scoped keys become unrestricted; revoke them first if that is not acceptable
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS scopes;
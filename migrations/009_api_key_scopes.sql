@@ -0,0 +1,7 @@
+-- API Key Scopes Migration
+-- Lets an API key be limited to a subset of its user's permissions
+
+-- Scopes are "table:action" strings such as products:read or orders:create, where either
+-- part may be "*". A key with NULL (or empty) scopes keeps every permission of its user.
+-- Scopes only narrow access; the user's roles are still checked for every request.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS scopes TEXT[];
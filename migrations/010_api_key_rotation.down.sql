@@ -0,0 +1,8 @@
+-- Reverts 010_api_key_rotation.sql
+-- Secrets replaced during a grace period stop working immediately
+
+DROP INDEX IF EXISTS idx_api_keys_previous_key_hash;
+DROP INDEX IF EXISTS idx_api_keys_key_hash;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS expiry_notified_at;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS previous_key_expires_at;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS previous_key_hash;
@@ -0,0 +1,13 @@
+-- API Key Rotation Migration
+-- Supports rotating a key's secret with a grace period and warning before keys expire
+
+-- A rotation moves the current hash to previous_key_hash; the old secret keeps working
+-- until previous_key_expires_at so clients can switch over without downtime.
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS previous_key_hash VARCHAR(255);
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS previous_key_expires_at TIMESTAMP WITH TIME ZONE;
+
+-- Set once the api_key.expiring event has been published; cleared when expires_at changes
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS expiry_notified_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX IF NOT EXISTS idx_api_keys_previous_key_hash ON api_keys(previous_key_hash) WHERE previous_key_hash IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Reverts 011_password_change.sql
+
+ALTER TABLE users DROP COLUMN IF EXISTS must_change_password;
@@ -0,0 +1,8 @@
+-- Password Change Migration
+-- Users with must_change_password set can only change their password until they do
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS must_change_password BOOLEAN NOT NULL DEFAULT false;
+
+-- The admin created by 001_complete_schema.sql still has the well-known default password
+UPDATE users SET must_change_password = true
+WHERE password_hash = '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi';
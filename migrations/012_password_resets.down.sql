@@ -0,0 +1,3 @@
+-- Reverts 012_password_resets.sql
+
+DROP TABLE IF EXISTS password_resets;
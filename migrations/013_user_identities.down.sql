@@ -0,0 +1,3 @@
+-- Reverts 013_user_identities.sql
+
+DROP TABLE IF EXISTS user_identities;
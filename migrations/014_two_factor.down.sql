@@ -0,0 +1,4 @@
+-- Reverts 014_two_factor.sql
+
+DROP TABLE IF EXISTS user_recovery_codes;
+DROP TABLE IF EXISTS user_two_factor;
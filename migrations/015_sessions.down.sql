@@ -0,0 +1,3 @@
+-- Reverts 015_sessions.sql
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,6 @@
+-- Reverts 016_tenant_lifecycle.sql
+
+DROP INDEX IF EXISTS idx_tenants_purge_at;
+ALTER TABLE tenants DROP COLUMN IF EXISTS purge_at;
+ALTER TABLE tenants DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE tenants DROP COLUMN IF EXISTS suspended_at;
@@ -0,0 +1,12 @@
+-- Tenant Lifecycle Migration
+-- Tenants can be suspended, and deleting a tenant only marks it until it is purged
+
+-- A suspended tenant keeps its data, but its members cannot sign in to it and its API
+-- keys stop working. is_active is false while a tenant is suspended or deleted.
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS suspended_at TIMESTAMP WITH TIME ZONE;
+
+-- A deleted tenant can be restored until purge_at, when it is removed for good
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE tenants ADD COLUMN IF NOT EXISTS purge_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_tenants_purge_at ON tenants(purge_at) WHERE purge_at IS NOT NULL;
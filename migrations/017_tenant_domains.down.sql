@@ -0,0 +1,3 @@
+-- Reverts 017_tenant_domains.sql
+
+DROP INDEX IF EXISTS idx_tenants_domain;
@@ -0,0 +1,5 @@
+-- Tenant Domains Migration
+-- Requests to a tenant's custom domain are served in that tenant, so a domain can only
+-- belong to one tenant. Domains are compared case-insensitively.
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_tenants_domain ON tenants(lower(domain)) WHERE domain IS NOT NULL;
@@ -0,0 +1,3 @@
+-- Reverts 018_super_admins.sql
+
+ALTER TABLE users DROP COLUMN IF EXISTS is_super_admin;
@@ -0,0 +1,6 @@
+-- Super Admin Migration
+-- Instance-level administrators manage every tenant (list, suspend, restore and delete
+-- them), unlike tenant admins whose rights end at their tenant. They are granted through
+-- seeding or `basin user super-admin`, never through the API.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_super_admin BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+-- Reverts 019_field_indexes.sql
+-- Indexes already created on data tables are left in place
+
+ALTER TABLE fields DROP COLUMN IF EXISTS is_indexed;
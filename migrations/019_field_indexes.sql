@@ -0,0 +1,6 @@
+-- Field Index Migration
+-- Lets fields ask for an index on their column in the collection's data table
+
+-- When true, the field's column gets a btree index (GIN for json fields) named after the
+-- field's ID; unique fields get a UNIQUE constraint named the same way.
+ALTER TABLE fields ADD COLUMN IF NOT EXISTS is_indexed BOOLEAN NOT NULL DEFAULT false;
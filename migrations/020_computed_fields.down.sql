@@ -0,0 +1,4 @@
+-- Reverts 020_computed_fields.sql
+-- Generated columns of computed fields stay in their data tables
+
+ALTER TABLE fields DROP COLUMN IF EXISTS computed_config;
@@ -0,0 +1,6 @@
+-- Computed Field Migration
+-- Lets fields derive their value from an expression over other fields of the collection
+
+-- For fields of type 'computed': {"expression": "price * quantity", "type": "number"}. The
+-- field's column is a generated column of the data table that Postgres keeps up to date.
+ALTER TABLE fields ADD COLUMN IF NOT EXISTS computed_config JSONB;
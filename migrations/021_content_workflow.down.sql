@@ -0,0 +1,4 @@
+-- Reverts 021_content_workflow.sql
+-- Workflow columns stay in their data tables
+
+ALTER TABLE collections DROP COLUMN IF EXISTS workflow;
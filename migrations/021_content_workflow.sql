@@ -0,0 +1,7 @@
+-- Content Workflow Migration
+-- Lets collections move items through draft, published and archived states
+
+-- When true, the collection's data table has Basin-managed status, publish_at and
+-- unpublish_at columns. A scheduler publishes drafts whose publish_at has passed and
+-- archives published items whose unpublish_at has passed.
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS workflow BOOLEAN NOT NULL DEFAULT false;
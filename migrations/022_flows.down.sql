@@ -0,0 +1,6 @@
+-- Reverts 022_flows.sql
+
+DELETE FROM permissions WHERE table_name IN ('flows', 'flow_runs');
+
+DROP TABLE IF EXISTS flow_runs;
+DROP TABLE IF EXISTS flows;
@@ -0,0 +1,59 @@
+-- Flows Migration
+-- Adds tenant automations: a trigger (item events, a schedule or an incoming webhook)
+-- starts a run that executes a list of operations, and every run is logged
+
+-- Automations, one row per flow per tenant
+CREATE TABLE IF NOT EXISTS flows (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    trigger VARCHAR(20) NOT NULL, -- 'event', 'schedule' or 'webhook'
+    trigger_options JSONB NOT NULL DEFAULT '{}', -- events and collections, or the schedule interval
+    operations JSONB NOT NULL DEFAULT '[]', -- ordered list of {key, type, options}
+    secret VARCHAR(255) NOT NULL, -- required by POST /flows/:id/trigger
+    is_active BOOLEAN DEFAULT true,
+    next_run_at TIMESTAMP WITH TIME ZONE, -- next run of a schedule flow
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL, -- operations run with this user's permissions
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    CONSTRAINT flows_trigger_check CHECK (trigger IN ('event', 'schedule', 'webhook'))
+);
+
+-- One row per triggered run with the result of every operation
+CREATE TABLE IF NOT EXISTS flow_runs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL,
+    payload JSONB NOT NULL, -- what started the run: the event, the schedule or the request
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'processing', 'success', 'failed'
+    steps JSONB NOT NULL DEFAULT '[]', -- {key, type, status, result, error, duration_ms} per operation
+    error TEXT,
+    started_at TIMESTAMP WITH TIME ZONE,
+    finished_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_flows_tenant_id ON flows(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_flows_due ON flows(next_run_at) WHERE trigger = 'schedule' AND is_active = true;
+CREATE INDEX IF NOT EXISTS idx_flow_runs_tenant_id ON flow_runs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_flow_runs_flow_id ON flow_runs(flow_id);
+CREATE INDEX IF NOT EXISTS idx_flow_runs_pending ON flow_runs(created_at) WHERE status = 'pending';
+
+-- Admin permissions for the main tenant
+INSERT INTO permissions (role_id, table_name, action, tenant_id)
+SELECT '550e8400-e29b-41d4-a716-446655440001', p.table_name, p.action, '6e68062f-c4c6-42df-9e01-e2d1081664f4'
+FROM (VALUES
+    ('flows', 'create'),
+    ('flows', 'read'),
+    ('flows', 'update'),
+    ('flows', 'delete'),
+    ('flow_runs', 'read')
+) AS p(table_name, action)
+WHERE EXISTS (SELECT 1 FROM roles WHERE id = '550e8400-e29b-41d4-a716-446655440001')
+ON CONFLICT (role_id, table_name, action) DO NOTHING;
+
+COMMENT ON TABLE flows IS 'Tenant automations connecting a trigger to a list of operations';
+COMMENT ON TABLE flow_runs IS 'Flow run log with the result of every operation';
@@ -0,0 +1,5 @@
+-- Reverts 023_jobs.sql
+
+DELETE FROM permissions WHERE table_name = 'jobs';
+
+DROP TABLE IF EXISTS jobs;
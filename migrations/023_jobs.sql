@@ -0,0 +1,34 @@
+-- Jobs Migration
+-- Adds a background job queue for async work such as sending emails. Workers claim due
+-- jobs with FOR UPDATE SKIP LOCKED, so any number of server instances can share it.
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE, -- NULL for system jobs
+    kind VARCHAR(100) NOT NULL, -- selects the handler, e.g. 'mail.send'
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'processing', 'completed', 'dead'
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5, -- jobs that fail this often move to the dead letter ('dead')
+    run_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(), -- when the job (or its next retry) is due
+    last_error TEXT,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_tenant_id ON jobs(tenant_id);
+CREATE INDEX IF NOT EXISTS idx_jobs_due ON jobs(run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_completed ON jobs(completed_at) WHERE status = 'completed';
+
+-- Admin permissions for the main tenant (jobs are read, and dead jobs retried, by admins)
+INSERT INTO permissions (role_id, table_name, action, tenant_id)
+SELECT '550e8400-e29b-41d4-a716-446655440001', p.table_name, p.action, '6e68062f-c4c6-42df-9e01-e2d1081664f4'
+FROM (VALUES
+    ('jobs', 'read'),
+    ('jobs', 'update')
+) AS p(table_name, action)
+WHERE EXISTS (SELECT 1 FROM roles WHERE id = '550e8400-e29b-41d4-a716-446655440001')
+ON CONFLICT (role_id, table_name, action) DO NOTHING;
+
+COMMENT ON TABLE jobs IS 'Background job queue with retries and a dead letter';
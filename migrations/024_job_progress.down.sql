@@ -0,0 +1,3 @@
+-- Reverts 024_job_progress.sql
+
+ALTER TABLE jobs DROP COLUMN IF EXISTS progress;
@@ -0,0 +1,5 @@
+-- Job Progress Migration
+-- Lets long-running jobs such as item imports report how far they have got
+
+-- Written by the job's handler while it runs, e.g. {"total": 5000, "processed": 1500}
+ALTER TABLE jobs ADD COLUMN IF NOT EXISTS progress JSONB NOT NULL DEFAULT '{}';
@@ -0,0 +1,6 @@
+-- Reverts 025_schema_notify.sql
+
+DROP TRIGGER IF EXISTS trigger_notify_schema_change ON tenants;
+DROP TRIGGER IF EXISTS trigger_notify_schema_change ON fields;
+DROP TRIGGER IF EXISTS trigger_notify_schema_change ON collections;
+DROP FUNCTION IF EXISTS notify_schema_change();
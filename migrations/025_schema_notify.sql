@@ -0,0 +1,46 @@
+-- Schema Change Notifications
+-- Announces changes to collections, fields and tenants on the basin_schema channel, with
+-- the tenant's ID as payload, so every server instance can drop its cached schema
+-- metadata of that tenant. Notifications are delivered when the transaction commits.
+
+CREATE OR REPLACE FUNCTION notify_schema_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    changed RECORD;
+    tenant UUID;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+
+    IF TG_TABLE_NAME = 'tenants' THEN
+        tenant := changed.id;
+    ELSE
+        tenant := changed.tenant_id;
+    END IF;
+
+    -- Rows without a tenant (system collections) affect every tenant
+    PERFORM pg_notify('basin_schema', COALESCE(tenant::text, ''));
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS trigger_notify_schema_change ON collections;
+CREATE TRIGGER trigger_notify_schema_change
+    AFTER INSERT OR UPDATE OR DELETE ON collections
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_schema_change();
+
+DROP TRIGGER IF EXISTS trigger_notify_schema_change ON fields;
+CREATE TRIGGER trigger_notify_schema_change
+    AFTER INSERT OR UPDATE OR DELETE ON fields
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_schema_change();
+
+DROP TRIGGER IF EXISTS trigger_notify_schema_change ON tenants;
+CREATE TRIGGER trigger_notify_schema_change
+    AFTER UPDATE OR DELETE ON tenants
+    FOR EACH ROW
+    EXECUTE FUNCTION notify_schema_change();
@@ -0,0 +1,3 @@
+-- Reverts 026_idempotency_keys.sql
+
+DROP TABLE IF EXISTS idempotency_keys;
@@ -0,0 +1,20 @@
+-- Idempotency Keys Migration
+-- Stores the responses of write requests sent with an Idempotency-Key header, so a client
+-- retrying one (e.g. after a dropped connection) gets the first response replayed instead
+-- of creating the records again. Rows expire after IDEMPOTENCY_TTL.
+
+CREATE TABLE IF NOT EXISTS idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL, -- SHA-256 of the method, path, tenant and body
+    status_code INTEGER, -- NULL while the first request is still in progress
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+
+COMMENT ON TABLE idempotency_keys IS 'Stored responses of write requests, replayed for retries with the same Idempotency-Key';
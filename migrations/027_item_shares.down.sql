@@ -0,0 +1,3 @@
+-- Reverts 027_item_shares.sql
+
+DROP TABLE IF EXISTS item_shares;
@@ -0,0 +1,21 @@
+-- Item Shares Migration
+-- Share links: signed tokens that let anyone holding them read one item, limited to a set
+-- of fields, without an account. Each token names a row here, so shares can be listed and
+-- revoked before they expire.
+
+CREATE TABLE IF NOT EXISTS item_shares (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    collection VARCHAR(255) NOT NULL,
+    item_id UUID NOT NULL,
+    fields TEXT[] NOT NULL DEFAULT '{*}', -- Fields the link reveals; '*' for every field
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP WITH TIME ZONE, -- NULL for links that do not expire
+    revoked_at TIMESTAMP WITH TIME ZONE,
+    last_used_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_item_shares_item ON item_shares(tenant_id, collection, item_id);
+
+COMMENT ON TABLE item_shares IS 'Share links granting read access to one item without an account';
@@ -0,0 +1,5 @@
+-- Reverts 028_permission_scope.sql
+-- Ownership is no longer enforced; own-scoped permissions apply to every row
+
+ALTER TABLE permissions DROP CONSTRAINT IF EXISTS permissions_scope_check;
+ALTER TABLE permissions DROP COLUMN IF EXISTS scope;
@@ -0,0 +1,7 @@
+-- Permission Scope Migration
+-- A permission applies to every row its field_filter admits ('all') or only to rows the
+-- user created ('own'), enforced as created_by = $CURRENT_USER for reads, updates and
+-- deletes.
+
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS scope VARCHAR(16) NOT NULL DEFAULT 'all';
+ALTER TABLE permissions ADD CONSTRAINT permissions_scope_check CHECK (scope IN ('all', 'own'));
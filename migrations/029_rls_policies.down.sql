@@ -0,0 +1,6 @@
+-- Reverts 029_rls_policies.sql
+-- Generated policies use these functions and are dropped with them
+
+DROP FUNCTION IF EXISTS basin_is_admin() CASCADE;
+DROP FUNCTION IF EXISTS basin_has_role(UUID) CASCADE;
+DROP FUNCTION IF EXISTS basin_current_roles() CASCADE;
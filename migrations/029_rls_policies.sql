@@ -0,0 +1,43 @@
+-- Functions used by the row-level security policies generated from permissions (see
+-- internal/api/rls_policies.go). They read the user and tenant set by set_user_context and
+-- resolve the user's roles the way the API does.
+
+-- The roles of the current user in the current tenant, with the roles they inherit. A
+-- request without a user (the nil UUID) holds the tenant's public role.
+CREATE OR REPLACE FUNCTION basin_current_roles()
+RETURNS TABLE (id UUID, name TEXT) AS $$
+    WITH RECURSIVE context AS (
+        SELECT NULLIF(current_setting('app.current_user_id', true), '')::uuid AS user_id,
+               NULLIF(current_setting('app.current_tenant_id', true), '')::uuid AS tenant_id
+    ),
+    held AS (
+        SELECT r.id, r.parent_id FROM roles r, context c
+        WHERE (r.tenant_id = c.tenant_id OR r.tenant_id IS NULL)
+        AND (
+            r.id IN (
+                SELECT ut.role_id FROM user_tenants ut
+                WHERE ut.user_id = c.user_id AND ut.tenant_id = c.tenant_id AND ut.is_active = true
+                UNION
+                SELECT ur.role_id FROM user_roles ur WHERE ur.user_id = c.user_id
+            )
+            OR (c.user_id = '00000000-0000-0000-0000-000000000000' AND r.name = 'public' AND r.tenant_id = c.tenant_id)
+        )
+        UNION
+        SELECT parent.id, parent.parent_id FROM roles parent JOIN held ON parent.id = held.parent_id
+    )
+    SELECT r.id, r.name::text FROM roles r WHERE r.id IN (SELECT held.id FROM held);
+$$ LANGUAGE sql STABLE SECURITY DEFINER SET search_path = public;
+
+-- Whether the current user holds a role, directly or through a role inheriting it
+CREATE OR REPLACE FUNCTION basin_has_role(p_role_id UUID)
+RETURNS BOOLEAN AS $$
+    SELECT EXISTS (SELECT 1 FROM basin_current_roles() r WHERE r.id = p_role_id);
+$$ LANGUAGE sql STABLE SET search_path = public;
+
+-- Whether the current user is an admin, who passes every policy
+CREATE OR REPLACE FUNCTION basin_is_admin()
+RETURNS BOOLEAN AS $$
+    SELECT EXISTS (SELECT 1 FROM basin_current_roles() r WHERE r.name = 'admin');
+$$ LANGUAGE sql STABLE SET search_path = public;
+
+COMMENT ON FUNCTION basin_current_roles() IS 'Roles of the user and tenant set by set_user_context, with inherited roles';
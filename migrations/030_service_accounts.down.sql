@@ -0,0 +1,7 @@
+-- Reverts 030_service_accounts.sql
+
+ALTER TABLE api_keys DROP COLUMN IF EXISTS rotation_notified_at;
+ALTER TABLE api_keys DROP COLUMN IF EXISTS rotated_at;
+
+DROP INDEX IF EXISTS idx_users_service_accounts;
+ALTER TABLE users DROP COLUMN IF EXISTS is_service_account;
@@ -0,0 +1,14 @@
+-- Service Account Migration
+-- Machine users owned by a tenant instead of a person. They have no usable password and
+-- authenticate only with API keys, which do not expire but are due for rotation after
+-- SERVICE_ACCOUNT_KEY_ROTATION.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS is_service_account BOOLEAN NOT NULL DEFAULT false;
+
+CREATE INDEX IF NOT EXISTS idx_users_service_accounts ON users(tenant_id) WHERE is_service_account;
+
+-- Set by every rotation; keys that were never rotated are due counting from created_at
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS rotated_at TIMESTAMP WITH TIME ZONE;
+
+-- Set once the api_key.rotation_due event has been published; cleared by a rotation
+ALTER TABLE api_keys ADD COLUMN IF NOT EXISTS rotation_notified_at TIMESTAMP WITH TIME ZONE;
@@ -0,0 +1,6 @@
+-- Reverts 031_collection_flags.sql
+-- Columns already added to or dropped from data tables are left as they are
+
+ALTER TABLE collections DROP COLUMN IF EXISTS sortable;
+ALTER TABLE collections DROP COLUMN IF EXISTS user_stamps;
+ALTER TABLE collections DROP COLUMN IF EXISTS timestamps;
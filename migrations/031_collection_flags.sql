@@ -0,0 +1,13 @@
+-- Collection Flags Migration
+-- Lets collections choose which automatic columns their data tables carry
+
+-- When false, the data table has no created_at/updated_at columns (the _version
+-- precondition needs them)
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS timestamps BOOLEAN NOT NULL DEFAULT true;
+
+-- When false, the data table has no created_by/updated_by columns and items cannot be
+-- shared with the "own" permission scope
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS user_stamps BOOLEAN NOT NULL DEFAULT true;
+
+-- When true, the data table has a "sort" integer column for manual (drag) ordering
+ALTER TABLE collections ADD COLUMN IF NOT EXISTS sortable BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+-- Reverts 032_encrypted_fields.sql
+-- Values already encrypted in data tables stay encrypted
+
+ALTER TABLE fields DROP COLUMN IF EXISTS is_encrypted;
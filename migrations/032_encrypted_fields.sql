@@ -0,0 +1,6 @@
+-- Encrypted Fields Migration
+-- Lets fields keep their values encrypted at rest
+
+-- When true, values of the field are stored AES-GCM encrypted (as "enc:v1:..." text) and
+-- only returned in clear to users with the decrypt permission on the collection
+ALTER TABLE fields ADD COLUMN IF NOT EXISTS is_encrypted BOOLEAN NOT NULL DEFAULT false;
@@ -0,0 +1,4 @@
+-- Reverts 033_permission_field_transforms.sql
+-- Fields that were redacted are returned in clear again where the permission grants them
+
+ALTER TABLE permissions DROP COLUMN IF EXISTS field_transforms;
@@ -0,0 +1,6 @@
+-- Permission Field Transforms Migration
+-- Lets read permissions return fields partially redacted instead of leaving them out
+
+-- Maps field names to the transform their values are read through, such as
+-- {"email": "mask_email", "card_number": "last4", "customer_id": "hash"}
+ALTER TABLE permissions ADD COLUMN IF NOT EXISTS field_transforms JSONB;
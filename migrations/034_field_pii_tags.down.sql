@@ -0,0 +1,4 @@
+-- Reverts 034_field_pii_tags.sql
+-- Values of the fields are kept; only the tags are dropped
+
+ALTER TABLE fields DROP COLUMN IF EXISTS pii;
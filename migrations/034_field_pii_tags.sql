@@ -0,0 +1,7 @@
+-- Field PII Tags Migration
+-- Lets fields declare the kind of personal data they hold
+
+-- The kind of personal data in the field (email, name, phone, address, ip, date,
+-- identifier or other), or NULL. Privacy erasure finds and clears a person's data
+-- through these tags.
+ALTER TABLE fields ADD COLUMN IF NOT EXISTS pii VARCHAR(20);
@@ -0,0 +1,5 @@
+-- Reverts 035_tenant_backups.sql
+-- Archives already written to storage are left there
+
+DROP TABLE IF EXISTS tenant_backup_schedules;
+DROP TABLE IF EXISTS tenant_backups;
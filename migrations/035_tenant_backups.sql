@@ -0,0 +1,39 @@
+-- Tenant Backups Migration
+-- Adds backups of a tenant's schema and data, written as tenant archives to asset storage
+-- on demand or on a schedule, and restored in place
+
+-- One row per backup; the archive itself lives in storage under storage_key
+CREATE TABLE IF NOT EXISTS tenant_backups (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL, -- 'manual' or 'schedule'
+    status VARCHAR(20) NOT NULL DEFAULT 'pending', -- 'pending', 'completed', 'failed'
+    storage_key TEXT NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0, -- bytes of the archive
+    checksum VARCHAR(64), -- SHA-256 of the archive, hex encoded
+    collections INTEGER NOT NULL DEFAULT 0,
+    items INTEGER NOT NULL DEFAULT 0,
+    error TEXT, -- last failure, kept while the job is retried
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    CONSTRAINT tenant_backups_trigger_check CHECK (trigger IN ('manual', 'schedule')),
+    CONSTRAINT tenant_backups_status_check CHECK (status IN ('pending', 'completed', 'failed'))
+);
+
+-- At most one schedule per tenant
+CREATE TABLE IF NOT EXISTS tenant_backup_schedules (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    interval VARCHAR(20) NOT NULL, -- a Go duration such as '24h'
+    retain INTEGER NOT NULL, -- scheduled backups kept; older ones are deleted
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_tenant_backups_tenant_id ON tenant_backups(tenant_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_tenant_backup_schedules_due ON tenant_backup_schedules(next_run_at);
+
+COMMENT ON TABLE tenant_backups IS 'Backups of tenant schema and data kept in asset storage';
+COMMENT ON TABLE tenant_backup_schedules IS 'Intervals at which tenants are backed up';
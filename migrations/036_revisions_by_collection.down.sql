@@ -0,0 +1,3 @@
+-- Reverts 036_revisions_by_collection.sql
+
+DROP INDEX IF EXISTS idx_revisions_collection;
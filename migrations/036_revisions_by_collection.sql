@@ -0,0 +1,4 @@
+-- Revisions By Collection Migration
+-- Indexes the change history of whole collections, which point-in-time restores replay
+
+CREATE INDEX IF NOT EXISTS idx_revisions_collection ON revisions(tenant_id, collection, created_at);
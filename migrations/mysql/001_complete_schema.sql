@@ -0,0 +1,527 @@
+-- Complete Basin API Schema Migration for MySQL and MariaDB
+-- The schema of migrations 001 to 036 in one file, for databases opened with DB_DRIVER=mysql.
+-- MySQL has no schemas within a database, so data tables are created by Basin as
+-- "data__<collection>-data-<tenant>" rather than by a trigger, and there is no row-level
+-- security. UUIDs are CHAR(36), timestamps DATETIME(6) in UTC, and the text arrays of the
+-- system tables LONGTEXT in Postgres' array syntax. On MariaDB the driver creates the JSON
+-- columns as LONGBLOB, as its JSON is text.
+-- MySQL commits DDL as it goes, so a failed run leaves the tables created before it; drop
+-- them before running it again.
+
+CREATE TABLE tenants (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    domain VARCHAR(255),
+    settings JSON DEFAULT ('{}'),
+    is_active BOOLEAN DEFAULT true,
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    suspended_at DATETIME(6),
+    deleted_at DATETIME(6),
+    purge_at DATETIME(6)
+);
+
+CREATE TABLE users (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    email VARCHAR(255) UNIQUE NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    is_active BOOLEAN DEFAULT true,
+    tenant_id CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    must_change_password BOOLEAN NOT NULL DEFAULT false,
+    is_super_admin BOOLEAN NOT NULL DEFAULT false,
+    is_service_account BOOLEAN NOT NULL DEFAULT false,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+);
+
+CREATE TABLE roles (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    name VARCHAR(100) NOT NULL,
+    description LONGTEXT,
+    tenant_id CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    parent_id CHAR(36),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id),
+    FOREIGN KEY (parent_id) REFERENCES roles(id) ON DELETE SET NULL
+);
+
+CREATE TABLE user_roles (
+    user_id CHAR(36),
+    role_id CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (user_id, role_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_tenants (
+    user_id CHAR(36),
+    tenant_id CHAR(36),
+    role_id CHAR(36),
+    is_active BOOLEAN DEFAULT true,
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    PRIMARY KEY (user_id, tenant_id),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (role_id) REFERENCES roles(id)
+);
+
+CREATE TABLE permissions (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    role_id CHAR(36),
+    table_name VARCHAR(100) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    field_filter JSON,
+    allowed_fields LONGTEXT,
+    tenant_id CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    read_fields LONGTEXT,
+    write_fields LONGTEXT,
+    scope VARCHAR(16) NOT NULL DEFAULT 'all',
+    field_transforms JSON,
+    UNIQUE (role_id, table_name, action),
+    CONSTRAINT permissions_scope_check CHECK (scope IN ('all', 'own')),
+    FOREIGN KEY (role_id) REFERENCES roles(id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+);
+
+CREATE TABLE collections (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    data_table_name VARCHAR(255) NOT NULL DEFAULT (UUID()), -- Set by Basin when it creates the data table
+    display_name VARCHAR(255),
+    description LONGTEXT,
+    icon VARCHAR(100),
+    is_system BOOLEAN DEFAULT false,
+    tenant_id CHAR(36),
+    created_by CHAR(36),
+    updated_by CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    soft_delete BOOLEAN NOT NULL DEFAULT false,
+    workflow BOOLEAN NOT NULL DEFAULT false,
+    timestamps BOOLEAN NOT NULL DEFAULT true,
+    user_stamps BOOLEAN NOT NULL DEFAULT true,
+    sortable BOOLEAN NOT NULL DEFAULT false,
+    UNIQUE (tenant_id, slug),
+    UNIQUE (data_table_name),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id),
+    FOREIGN KEY (created_by) REFERENCES users(id),
+    FOREIGN KEY (updated_by) REFERENCES users(id)
+);
+
+CREATE TABLE fields (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    collection_id CHAR(36),
+    name VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255),
+    type VARCHAR(50) NOT NULL,
+    is_primary BOOLEAN DEFAULT false,
+    is_required BOOLEAN DEFAULT false,
+    is_unique BOOLEAN DEFAULT false,
+    default_value LONGTEXT,
+    validation_rules JSON,
+    relation_config JSON,
+    sort_order INTEGER DEFAULT 0,
+    tenant_id CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    is_indexed BOOLEAN NOT NULL DEFAULT false,
+    computed_config JSON,
+    is_encrypted BOOLEAN NOT NULL DEFAULT false,
+    pii VARCHAR(20),
+    FOREIGN KEY (collection_id) REFERENCES collections(id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id)
+);
+
+CREATE TABLE api_keys (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    name VARCHAR(255) NOT NULL,
+    key_hash VARCHAR(255) NOT NULL,
+    permissions JSON NOT NULL,
+    expires_at DATETIME(6),
+    is_active BOOLEAN DEFAULT true,
+    tenant_id CHAR(36),
+    created_by CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    scopes LONGTEXT,
+    previous_key_hash VARCHAR(255),
+    previous_key_expires_at DATETIME(6),
+    expiry_notified_at DATETIME(6),
+    rotated_at DATETIME(6),
+    rotation_notified_at DATETIME(6),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id),
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+CREATE TABLE webhooks (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    tenant_id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    url LONGTEXT NOT NULL,
+    events LONGTEXT NOT NULL DEFAULT ('{}'),
+    collections LONGTEXT NOT NULL DEFAULT ('{}'),
+    secret VARCHAR(255) NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_by CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id)
+);
+
+CREATE TABLE webhook_deliveries (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    webhook_id CHAR(36) NOT NULL,
+    tenant_id CHAR(36) NOT NULL,
+    event VARCHAR(50) NOT NULL,
+    collection VARCHAR(100) NOT NULL,
+    payload JSON NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    response_body LONGTEXT,
+    error LONGTEXT,
+    next_attempt_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    delivered_at DATETIME(6),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (webhook_id) REFERENCES webhooks(id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+
+CREATE TABLE assets (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    tenant_id CHAR(36) NOT NULL,
+    storage VARCHAR(20) NOT NULL,
+    storage_key LONGTEXT NOT NULL,
+    filename_download VARCHAR(255) NOT NULL,
+    title VARCHAR(255),
+    type VARCHAR(255) NOT NULL,
+    filesize BIGINT NOT NULL DEFAULT 0,
+    width INTEGER,
+    height INTEGER,
+    uploaded_by CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (uploaded_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE revisions (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    tenant_id CHAR(36) NOT NULL,
+    collection VARCHAR(100) NOT NULL,
+    item_id CHAR(36) NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    old_data JSON,
+    new_data JSON,
+    user_id CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE audit_logs (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    tenant_id CHAR(36),
+    user_id CHAR(36),
+    api_key_id CHAR(36),
+    action VARCHAR(50) NOT NULL,
+    table_name VARCHAR(100),
+    item_id VARCHAR(255),
+    result VARCHAR(20) NOT NULL,
+    status_code INTEGER,
+    ip_address VARCHAR(45),
+    user_agent LONGTEXT,
+    request_id VARCHAR(100),
+    details JSON,
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE SET NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (api_key_id) REFERENCES api_keys(id) ON DELETE SET NULL
+);
+
+CREATE TABLE password_resets (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    user_id CHAR(36) NOT NULL,
+    expires_at DATETIME(6) NOT NULL,
+    used_at DATETIME(6),
+    ip VARCHAR(64),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_identities (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    user_id CHAR(36) NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    last_login_at DATETIME(6),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE (provider, subject),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_two_factor (
+    user_id CHAR(36) PRIMARY KEY,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at DATETIME(6),
+    enabled_at DATETIME(6),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_recovery_codes (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    user_id CHAR(36) NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at DATETIME(6),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    UNIQUE (user_id, code_hash),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE sessions (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    user_id CHAR(36) NOT NULL,
+    tenant_id CHAR(36),
+    user_agent LONGTEXT,
+    ip VARCHAR(64),
+    expires_at DATETIME(6) NOT NULL,
+    revoked_at DATETIME(6),
+    last_seen_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+
+CREATE TABLE flows (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    tenant_id CHAR(36) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description LONGTEXT,
+    "trigger" VARCHAR(20) NOT NULL,
+    trigger_options JSON NOT NULL DEFAULT ('{}'),
+    operations JSON NOT NULL DEFAULT ('[]'),
+    secret VARCHAR(255) NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    next_run_at DATETIME(6),
+    created_by CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    CONSTRAINT flows_trigger_check CHECK ("trigger" IN ('event', 'schedule', 'webhook')),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE flow_runs (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    flow_id CHAR(36) NOT NULL,
+    tenant_id CHAR(36) NOT NULL,
+    "trigger" VARCHAR(20) NOT NULL,
+    payload JSON NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    steps JSON NOT NULL DEFAULT ('[]'),
+    error LONGTEXT,
+    started_at DATETIME(6),
+    finished_at DATETIME(6),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (flow_id) REFERENCES flows(id) ON DELETE CASCADE,
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+
+CREATE TABLE jobs (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    tenant_id CHAR(36),
+    kind VARCHAR(100) NOT NULL,
+    payload JSON NOT NULL DEFAULT ('{}'),
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    run_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    last_error LONGTEXT,
+    completed_at DATETIME(6),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    progress JSON NOT NULL DEFAULT ('{}'),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE
+);
+
+CREATE TABLE idempotency_keys (
+    user_id CHAR(36) NOT NULL,
+    "key" VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    content_type VARCHAR(255),
+    response_body LONGBLOB,
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    expires_at DATETIME(6) NOT NULL,
+    PRIMARY KEY (user_id, "key"),
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE item_shares (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    tenant_id CHAR(36) NOT NULL,
+    collection VARCHAR(255) NOT NULL,
+    item_id CHAR(36) NOT NULL,
+    fields LONGTEXT NOT NULL DEFAULT ('{*}'),
+    created_by CHAR(36) NOT NULL,
+    expires_at DATETIME(6),
+    revoked_at DATETIME(6),
+    last_used_at DATETIME(6),
+    created_at DATETIME(6) NOT NULL DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE tenant_backups (
+    id CHAR(36) PRIMARY KEY DEFAULT (UUID()),
+    tenant_id CHAR(36) NOT NULL,
+    "trigger" VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    storage_key LONGTEXT NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    checksum VARCHAR(64),
+    collections INTEGER NOT NULL DEFAULT 0,
+    items INTEGER NOT NULL DEFAULT 0,
+    error LONGTEXT,
+    created_by CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    completed_at DATETIME(6),
+    CONSTRAINT tenant_backups_trigger_check CHECK ("trigger" IN ('manual', 'schedule')),
+    CONSTRAINT tenant_backups_status_check CHECK (status IN ('pending', 'completed', 'failed')),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE TABLE tenant_backup_schedules (
+    tenant_id CHAR(36) PRIMARY KEY,
+    "interval" VARCHAR(20) NOT NULL,
+    retain INTEGER NOT NULL,
+    next_run_at DATETIME(6) NOT NULL,
+    created_by CHAR(36),
+    created_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    updated_at DATETIME(6) DEFAULT CURRENT_TIMESTAMP(6),
+    FOREIGN KEY (tenant_id) REFERENCES tenants(id) ON DELETE CASCADE,
+    FOREIGN KEY (created_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX idx_users_tenant_id ON users(tenant_id);
+CREATE INDEX idx_users_email ON users(email);
+CREATE INDEX idx_users_service_accounts ON users(tenant_id, is_service_account);
+-- Unique ignores case in MySQL's default collations, and allows any number of NULLs
+CREATE UNIQUE INDEX idx_tenants_domain ON tenants(domain);
+CREATE INDEX idx_tenants_purge_at ON tenants(purge_at);
+CREATE INDEX idx_roles_tenant_id ON roles(tenant_id);
+CREATE INDEX idx_roles_parent_id ON roles(parent_id);
+CREATE INDEX idx_permissions_tenant_id ON permissions(tenant_id);
+CREATE INDEX idx_collections_tenant_id ON collections(tenant_id);
+CREATE INDEX idx_collections_slug ON collections(tenant_id, slug);
+CREATE INDEX idx_fields_tenant_id ON fields(tenant_id);
+CREATE INDEX idx_fields_collection_id ON fields(collection_id);
+CREATE INDEX idx_api_keys_tenant_id ON api_keys(tenant_id);
+CREATE INDEX idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX idx_api_keys_previous_key_hash ON api_keys(previous_key_hash);
+CREATE INDEX idx_webhooks_tenant_id ON webhooks(tenant_id);
+CREATE INDEX idx_webhook_deliveries_tenant_id ON webhook_deliveries(tenant_id);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt_at);
+CREATE INDEX idx_assets_tenant_id ON assets(tenant_id);
+CREATE INDEX idx_revisions_item ON revisions(tenant_id, collection, item_id, created_at DESC);
+CREATE INDEX idx_revisions_collection ON revisions(tenant_id, collection, created_at);
+CREATE INDEX idx_audit_logs_tenant_created ON audit_logs(tenant_id, created_at DESC);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX idx_password_resets_user_id ON password_resets(user_id);
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_flows_tenant_id ON flows(tenant_id);
+CREATE INDEX idx_flows_due ON flows("trigger", is_active, next_run_at);
+CREATE INDEX idx_flow_runs_tenant_id ON flow_runs(tenant_id);
+CREATE INDEX idx_flow_runs_flow_id ON flow_runs(flow_id);
+CREATE INDEX idx_flow_runs_pending ON flow_runs(status, created_at);
+CREATE INDEX idx_jobs_tenant_id ON jobs(tenant_id);
+CREATE INDEX idx_jobs_due ON jobs(status, run_at);
+CREATE INDEX idx_jobs_completed ON jobs(status, completed_at);
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+CREATE INDEX idx_item_shares_item ON item_shares(tenant_id, collection, item_id);
+CREATE INDEX idx_tenant_backups_tenant_id ON tenant_backups(tenant_id, created_at DESC);
+CREATE INDEX idx_tenant_backup_schedules_due ON tenant_backup_schedules(next_run_at);
+
+-- Default tenant, admin user and roles, as in the Postgres migrations
+INSERT INTO tenants (id, name, slug, domain, is_active)
+VALUES ('6e68062f-c4c6-42df-9e01-e2d1081664f4', 'Main Tenant', 'main', 'localhost', true);
+
+INSERT INTO users (id, email, password_hash, first_name, last_name, tenant_id, is_active, must_change_password)
+VALUES ('38eae290-37b8-46a7-82ee-ae842d85c894', 'admin@example.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'Admin', 'User', '6e68062f-c4c6-42df-9e01-e2d1081664f4', true, true);
+
+INSERT INTO roles (id, name, description, tenant_id)
+VALUES
+    ('550e8400-e29b-41d4-a716-446655440001', 'admin', 'Administrator with full access', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440002', 'user', 'Regular user with limited access', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440003', 'viewer', 'Read-only access', '6e68062f-c4c6-42df-9e01-e2d1081664f4');
+
+INSERT INTO user_tenants (user_id, tenant_id, role_id, is_active)
+VALUES ('38eae290-37b8-46a7-82ee-ae842d85c894', '6e68062f-c4c6-42df-9e01-e2d1081664f4', '550e8400-e29b-41d4-a716-446655440001', true);
+
+INSERT INTO user_roles (user_id, role_id)
+VALUES ('38eae290-37b8-46a7-82ee-ae842d85c894', '550e8400-e29b-41d4-a716-446655440001');
+
+-- Admin permissions on the system tables
+INSERT INTO permissions (role_id, table_name, action, tenant_id)
+VALUES
+    ('550e8400-e29b-41d4-a716-446655440001', 'users', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'users', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'users', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'users', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'roles', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'roles', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'roles', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'roles', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'permissions', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'permissions', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'permissions', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'permissions', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'collections', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'collections', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'collections', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'collections', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'fields', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'fields', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'fields', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'fields', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'api_keys', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'api_keys', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'api_keys', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'api_keys', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'webhooks', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'webhooks', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'webhooks', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'webhooks', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'webhook_deliveries', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'assets', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'assets', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'assets', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'assets', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'audit_logs', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'flows', 'create', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'flows', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'flows', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'flows', 'delete', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'flow_runs', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'jobs', 'read', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440001', 'jobs', 'update', '6e68062f-c4c6-42df-9e01-e2d1081664f4');
@@ -0,0 +1,452 @@
+-- Complete Basin API Schema Migration for SQLite
+-- The schema of migrations 001 to 036 in one file, for databases opened with DB_DRIVER=sqlite.
+-- SQLite has a single namespace, so data tables are created by Basin as "data__<collection>-data-<tenant>"
+-- rather than by a trigger, and there is no row-level security. UUIDs are stored as text.
+-- Types keep their Postgres names, which is how the basin-sqlite driver knows to return
+-- booleans, timestamps, JSON and arrays (_TEXT) the way lib/pq does.
+
+CREATE TABLE tenants (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(100) UNIQUE NOT NULL,
+    domain VARCHAR(255),
+    settings JSONB DEFAULT '{}',
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    suspended_at TIMESTAMP,
+    deleted_at TIMESTAMP,
+    purge_at TIMESTAMP
+);
+
+CREATE TABLE users (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    email VARCHAR(255) UNIQUE NOT NULL,
+    password_hash VARCHAR(255) NOT NULL,
+    first_name VARCHAR(100),
+    last_name VARCHAR(100),
+    is_active BOOLEAN DEFAULT true,
+    tenant_id UUID REFERENCES tenants(id),
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    must_change_password BOOLEAN NOT NULL DEFAULT false,
+    is_super_admin BOOLEAN NOT NULL DEFAULT false,
+    is_service_account BOOLEAN NOT NULL DEFAULT false
+);
+
+CREATE TABLE roles (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    name VARCHAR(100) NOT NULL,
+    description TEXT,
+    tenant_id UUID REFERENCES tenants(id),
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    parent_id UUID REFERENCES roles(id) ON DELETE SET NULL,
+    CONSTRAINT roles_parent_not_self CHECK (parent_id IS NULL OR parent_id <> id)
+);
+
+CREATE TABLE user_roles (
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    role_id UUID REFERENCES roles(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT (now()),
+    PRIMARY KEY (user_id, role_id)
+);
+
+CREATE TABLE user_tenants (
+    user_id UUID REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    role_id UUID REFERENCES roles(id),
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT (now()),
+    PRIMARY KEY (user_id, tenant_id)
+);
+
+CREATE TABLE permissions (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    role_id UUID REFERENCES roles(id) ON DELETE CASCADE,
+    table_name VARCHAR(100) NOT NULL,
+    action VARCHAR(50) NOT NULL,
+    field_filter JSONB,
+    allowed_fields _TEXT,
+    tenant_id UUID REFERENCES tenants(id),
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    read_fields _TEXT,
+    write_fields _TEXT,
+    scope VARCHAR(16) NOT NULL DEFAULT 'all',
+    field_transforms JSONB,
+    UNIQUE (role_id, table_name, action),
+    CONSTRAINT permissions_scope_check CHECK (scope IN ('all', 'own'))
+);
+
+CREATE TABLE collections (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    name VARCHAR(255) NOT NULL,
+    slug VARCHAR(255) NOT NULL,
+    data_table_name VARCHAR(255) NOT NULL DEFAULT (uuid_generate_v4()), -- Set by Basin when it creates the data table
+    display_name VARCHAR(255),
+    description TEXT,
+    icon VARCHAR(100),
+    is_system BOOLEAN DEFAULT false,
+    tenant_id UUID REFERENCES tenants(id),
+    created_by UUID REFERENCES users(id),
+    updated_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    soft_delete BOOLEAN NOT NULL DEFAULT false,
+    workflow BOOLEAN NOT NULL DEFAULT false,
+    timestamps BOOLEAN NOT NULL DEFAULT true,
+    user_stamps BOOLEAN NOT NULL DEFAULT true,
+    sortable BOOLEAN NOT NULL DEFAULT false,
+    UNIQUE (tenant_id, slug),
+    UNIQUE (data_table_name)
+);
+
+CREATE TABLE fields (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    collection_id UUID REFERENCES collections(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    display_name VARCHAR(255),
+    type VARCHAR(50) NOT NULL,
+    is_primary BOOLEAN DEFAULT false,
+    is_required BOOLEAN DEFAULT false,
+    is_unique BOOLEAN DEFAULT false,
+    default_value TEXT,
+    validation_rules JSONB,
+    relation_config JSONB,
+    sort_order INTEGER DEFAULT 0,
+    tenant_id UUID REFERENCES tenants(id),
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    is_indexed BOOLEAN NOT NULL DEFAULT false,
+    computed_config JSONB,
+    is_encrypted BOOLEAN NOT NULL DEFAULT false,
+    pii VARCHAR(20)
+);
+
+CREATE TABLE api_keys (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    name VARCHAR(255) NOT NULL,
+    key_hash VARCHAR(255) NOT NULL,
+    permissions JSONB NOT NULL,
+    expires_at TIMESTAMP,
+    is_active BOOLEAN DEFAULT true,
+    tenant_id UUID REFERENCES tenants(id),
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    scopes _TEXT,
+    previous_key_hash VARCHAR(255),
+    previous_key_expires_at TIMESTAMP,
+    expiry_notified_at TIMESTAMP,
+    rotated_at TIMESTAMP,
+    rotation_notified_at TIMESTAMP
+);
+
+CREATE TABLE webhooks (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    url TEXT NOT NULL,
+    events _TEXT NOT NULL DEFAULT '{}',
+    collections _TEXT NOT NULL DEFAULT '{}',
+    secret VARCHAR(255) NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    created_by UUID REFERENCES users(id),
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE webhook_deliveries (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    webhook_id UUID NOT NULL REFERENCES webhooks(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    event VARCHAR(50) NOT NULL,
+    collection VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    response_status INTEGER,
+    response_body TEXT,
+    error TEXT,
+    next_attempt_at TIMESTAMP NOT NULL DEFAULT (now()),
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE assets (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    storage VARCHAR(20) NOT NULL,
+    storage_key TEXT NOT NULL,
+    filename_download VARCHAR(255) NOT NULL,
+    title VARCHAR(255),
+    type VARCHAR(255) NOT NULL,
+    filesize BIGINT NOT NULL DEFAULT 0,
+    width INTEGER,
+    height INTEGER,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE revisions (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    collection VARCHAR(100) NOT NULL,
+    item_id UUID NOT NULL,
+    action VARCHAR(20) NOT NULL,
+    old_data JSONB,
+    new_data JSONB,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE audit_logs (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    tenant_id UUID REFERENCES tenants(id) ON DELETE SET NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    api_key_id UUID REFERENCES api_keys(id) ON DELETE SET NULL,
+    action VARCHAR(50) NOT NULL,
+    table_name VARCHAR(100),
+    item_id VARCHAR(255),
+    result VARCHAR(20) NOT NULL,
+    status_code INTEGER,
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    request_id VARCHAR(100),
+    details JSONB,
+    created_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE password_resets (
+    id UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    ip VARCHAR(64),
+    created_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE user_identities (
+    id UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    last_login_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT (now()),
+    UNIQUE (provider, subject)
+);
+
+CREATE TABLE user_two_factor (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret VARCHAR(64) NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT false,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    failed_attempts INTEGER NOT NULL DEFAULT 0,
+    last_failed_at TIMESTAMP,
+    enabled_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE user_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT (now()),
+    UNIQUE (user_id, code_hash)
+);
+
+CREATE TABLE sessions (
+    id UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    user_agent TEXT,
+    ip VARCHAR(64),
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    last_seen_at TIMESTAMP DEFAULT (now()),
+    created_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE flows (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    trigger VARCHAR(20) NOT NULL,
+    trigger_options JSONB NOT NULL DEFAULT '{}',
+    operations JSONB NOT NULL DEFAULT '[]',
+    secret VARCHAR(255) NOT NULL,
+    is_active BOOLEAN DEFAULT true,
+    next_run_at TIMESTAMP,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    CONSTRAINT flows_trigger_check CHECK (trigger IN ('event', 'schedule', 'webhook'))
+);
+
+CREATE TABLE flow_runs (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    flow_id UUID NOT NULL REFERENCES flows(id) ON DELETE CASCADE,
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL,
+    payload JSONB NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    steps JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    started_at TIMESTAMP,
+    finished_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE TABLE jobs (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    tenant_id UUID REFERENCES tenants(id) ON DELETE CASCADE,
+    kind VARCHAR(100) NOT NULL,
+    payload JSONB NOT NULL DEFAULT '{}',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 5,
+    run_at TIMESTAMP NOT NULL DEFAULT (now()),
+    last_error TEXT,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now()),
+    progress JSONB NOT NULL DEFAULT '{}'
+);
+
+CREATE TABLE idempotency_keys (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    key VARCHAR(255) NOT NULL,
+    request_hash VARCHAR(64) NOT NULL,
+    status_code INTEGER,
+    content_type VARCHAR(255),
+    response_body BYTEA,
+    created_at TIMESTAMP NOT NULL DEFAULT (now()),
+    expires_at TIMESTAMP NOT NULL,
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE TABLE item_shares (
+    id UUID PRIMARY KEY DEFAULT (gen_random_uuid()),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    collection VARCHAR(255) NOT NULL,
+    item_id UUID NOT NULL,
+    fields _TEXT NOT NULL DEFAULT '{*}',
+    created_by UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    expires_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    last_used_at TIMESTAMP,
+    created_at TIMESTAMP NOT NULL DEFAULT (now())
+);
+
+CREATE TABLE tenant_backups (
+    id UUID PRIMARY KEY DEFAULT (uuid_generate_v4()),
+    tenant_id UUID NOT NULL REFERENCES tenants(id) ON DELETE CASCADE,
+    trigger VARCHAR(20) NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    storage_key TEXT NOT NULL,
+    size BIGINT NOT NULL DEFAULT 0,
+    checksum VARCHAR(64),
+    collections INTEGER NOT NULL DEFAULT 0,
+    items INTEGER NOT NULL DEFAULT 0,
+    error TEXT,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT (now()),
+    completed_at TIMESTAMP,
+    CONSTRAINT tenant_backups_trigger_check CHECK (trigger IN ('manual', 'schedule')),
+    CONSTRAINT tenant_backups_status_check CHECK (status IN ('pending', 'completed', 'failed'))
+);
+
+CREATE TABLE tenant_backup_schedules (
+    tenant_id UUID PRIMARY KEY REFERENCES tenants(id) ON DELETE CASCADE,
+    interval VARCHAR(20) NOT NULL,
+    retain INTEGER NOT NULL,
+    next_run_at TIMESTAMP NOT NULL,
+    created_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT (now()),
+    updated_at TIMESTAMP DEFAULT (now())
+);
+
+CREATE INDEX idx_users_tenant_id ON users(tenant_id);
+CREATE INDEX idx_users_email ON users(email);
+CREATE INDEX idx_users_service_accounts ON users(tenant_id) WHERE is_service_account;
+CREATE UNIQUE INDEX idx_tenants_domain ON tenants(lower(domain)) WHERE domain IS NOT NULL;
+CREATE INDEX idx_tenants_purge_at ON tenants(purge_at) WHERE purge_at IS NOT NULL;
+CREATE INDEX idx_roles_tenant_id ON roles(tenant_id);
+CREATE INDEX idx_roles_parent_id ON roles(parent_id);
+CREATE INDEX idx_permissions_tenant_id ON permissions(tenant_id);
+CREATE INDEX idx_collections_tenant_id ON collections(tenant_id);
+CREATE INDEX idx_collections_slug ON collections(tenant_id, slug);
+CREATE INDEX idx_fields_tenant_id ON fields(tenant_id);
+CREATE INDEX idx_fields_collection_id ON fields(collection_id);
+CREATE INDEX idx_api_keys_tenant_id ON api_keys(tenant_id);
+CREATE INDEX idx_api_keys_key_hash ON api_keys(key_hash);
+CREATE INDEX idx_api_keys_previous_key_hash ON api_keys(previous_key_hash) WHERE previous_key_hash IS NOT NULL;
+CREATE INDEX idx_webhooks_tenant_id ON webhooks(tenant_id);
+CREATE INDEX idx_webhook_deliveries_tenant_id ON webhook_deliveries(tenant_id);
+CREATE INDEX idx_webhook_deliveries_webhook_id ON webhook_deliveries(webhook_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries(next_attempt_at) WHERE status = 'pending';
+CREATE INDEX idx_assets_tenant_id ON assets(tenant_id);
+CREATE INDEX idx_revisions_item ON revisions(tenant_id, collection, item_id, created_at DESC);
+CREATE INDEX idx_revisions_collection ON revisions(tenant_id, collection, created_at);
+CREATE INDEX idx_audit_logs_tenant_created ON audit_logs(tenant_id, created_at DESC);
+CREATE INDEX idx_audit_logs_user_id ON audit_logs(user_id);
+CREATE INDEX idx_password_resets_user_id ON password_resets(user_id);
+CREATE INDEX idx_user_identities_user_id ON user_identities(user_id);
+CREATE INDEX idx_sessions_user_id ON sessions(user_id);
+CREATE INDEX idx_flows_tenant_id ON flows(tenant_id);
+CREATE INDEX idx_flows_due ON flows(next_run_at) WHERE trigger = 'schedule' AND is_active = true;
+CREATE INDEX idx_flow_runs_tenant_id ON flow_runs(tenant_id);
+CREATE INDEX idx_flow_runs_flow_id ON flow_runs(flow_id);
+CREATE INDEX idx_flow_runs_pending ON flow_runs(created_at) WHERE status = 'pending';
+CREATE INDEX idx_jobs_tenant_id ON jobs(tenant_id);
+CREATE INDEX idx_jobs_due ON jobs(run_at) WHERE status = 'pending';
+CREATE INDEX idx_jobs_completed ON jobs(completed_at) WHERE status = 'completed';
+CREATE INDEX idx_idempotency_keys_expires_at ON idempotency_keys(expires_at);
+CREATE INDEX idx_item_shares_item ON item_shares(tenant_id, collection, item_id);
+CREATE INDEX idx_tenant_backups_tenant_id ON tenant_backups(tenant_id, created_at DESC);
+CREATE INDEX idx_tenant_backup_schedules_due ON tenant_backup_schedules(next_run_at);
+
+-- Default tenant, admin user and roles, as in the Postgres migrations
+INSERT INTO tenants (id, name, slug, domain, is_active)
+VALUES ('6e68062f-c4c6-42df-9e01-e2d1081664f4', 'Main Tenant', 'main', 'localhost', true);
+
+INSERT INTO users (id, email, password_hash, first_name, last_name, tenant_id, is_active, must_change_password)
+VALUES ('38eae290-37b8-46a7-82ee-ae842d85c894', 'admin@example.com', '$2a$10$92IXUNpkjO0rOQ5byMi.Ye4oKoEa3Ro9llC/.og/at2.uheWG/igi', 'Admin', 'User', '6e68062f-c4c6-42df-9e01-e2d1081664f4', true, true);
+
+INSERT INTO roles (id, name, description, tenant_id)
+VALUES
+    ('550e8400-e29b-41d4-a716-446655440001', 'admin', 'Administrator with full access', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440002', 'user', 'Regular user with limited access', '6e68062f-c4c6-42df-9e01-e2d1081664f4'),
+    ('550e8400-e29b-41d4-a716-446655440003', 'viewer', 'Read-only access', '6e68062f-c4c6-42df-9e01-e2d1081664f4');
+
+INSERT INTO user_tenants (user_id, tenant_id, role_id, is_active)
+VALUES ('38eae290-37b8-46a7-82ee-ae842d85c894', '6e68062f-c4c6-42df-9e01-e2d1081664f4', '550e8400-e29b-41d4-a716-446655440001', true);
+
+INSERT INTO user_roles (user_id, role_id)
+VALUES ('38eae290-37b8-46a7-82ee-ae842d85c894', '550e8400-e29b-41d4-a716-446655440001');
+
+-- Admin permissions on the system tables
+INSERT INTO permissions (role_id, table_name, action, tenant_id)
+SELECT '550e8400-e29b-41d4-a716-446655440001', p.column1, p.column2, '6e68062f-c4c6-42df-9e01-e2d1081664f4'
+FROM (VALUES
+    ('users', 'create'), ('users', 'read'), ('users', 'update'), ('users', 'delete'),
+    ('roles', 'create'), ('roles', 'read'), ('roles', 'update'), ('roles', 'delete'),
+    ('permissions', 'create'), ('permissions', 'read'), ('permissions', 'update'), ('permissions', 'delete'),
+    ('collections', 'create'), ('collections', 'read'), ('collections', 'update'), ('collections', 'delete'),
+    ('fields', 'create'), ('fields', 'read'), ('fields', 'update'), ('fields', 'delete'),
+    ('api_keys', 'create'), ('api_keys', 'read'), ('api_keys', 'update'), ('api_keys', 'delete'),
+    ('webhooks', 'create'), ('webhooks', 'read'), ('webhooks', 'update'), ('webhooks', 'delete'),
+    ('webhook_deliveries', 'read'),
+    ('assets', 'create'), ('assets', 'read'), ('assets', 'update'), ('assets', 'delete'),
+    ('audit_logs', 'read'),
+    ('flows', 'create'), ('flows', 'read'), ('flows', 'update'), ('flows', 'delete'),
+    ('flow_runs', 'read'),
+    ('jobs', 'read'), ('jobs', 'update')
+) AS p;